@@ -0,0 +1,258 @@
+package acl
+
+import (
+	"path"
+	"strings"
+)
+
+// ACL is the set of permissions compiled from the policies of a token. A
+// management ACL is allowed everything, and so is a nil ACL, which is the ACL
+// of the requests when ACLs are disabled.
+type ACL struct {
+	// management is set for management tokens
+	management bool
+
+	// namespaces is the rule of each namespace with one. The "*" entry
+	// applies to the namespaces without one.
+	namespaces map[string]*namespaceRule
+
+	node     string
+	agent    string
+	operator string
+
+	// workload is the job of the ACLs of workload identities
+	workload *workload
+}
+
+// namespaceRule is the policy level and the capabilities granted on a
+// namespace. The deny level denies every capability.
+type namespaceRule struct {
+	policy       string
+	capabilities map[string]struct{}
+
+	// hostVolumes is the policy level of each host volume path pattern
+	hostVolumes map[string]string
+}
+
+// workload is the job a workload identity was issued for
+type workload struct {
+	namespace string
+	jobID     string
+}
+
+// WorkloadVariablesPrefix is the path prefix of the variables of the jobs.
+// The variables of a job are at WorkloadVariablesPrefix/<job ID> and below.
+const WorkloadVariablesPrefix = "nomad/jobs"
+
+// ManagementACL is the ACL of management tokens
+var ManagementACL = &ACL{management: true}
+
+// NewACL compiles the policies into an ACL. The most permissive level of the
+// policies applies to each rule, unless one of them denies it.
+func NewACL(management bool, policies []*Policy) *ACL {
+	if management {
+		return ManagementACL
+	}
+
+	acl := &ACL{namespaces: make(map[string]*namespaceRule)}
+	for _, policy := range policies {
+		for _, ns := range policy.Namespaces {
+			rule, ok := acl.namespaces[ns.Name]
+			if !ok {
+				rule = &namespaceRule{
+					capabilities: make(map[string]struct{}),
+					hostVolumes:  make(map[string]string),
+				}
+				acl.namespaces[ns.Name] = rule
+			}
+			rule.policy = maxPrivilege(rule.policy, ns.Policy)
+			for _, capability := range expandNamespacePolicy(ns.Policy) {
+				rule.capabilities[capability] = struct{}{}
+			}
+			for _, capability := range ns.Capabilities {
+				rule.capabilities[capability] = struct{}{}
+			}
+			if _, ok := rule.capabilities[NamespaceCapabilityDeny]; ok {
+				rule.policy = PolicyDeny
+			}
+			for _, hv := range ns.HostVolumes {
+				rule.hostVolumes[hv.Path] = maxPrivilege(rule.hostVolumes[hv.Path], hv.Policy)
+			}
+		}
+		if policy.Node != nil {
+			acl.node = maxPrivilege(acl.node, policy.Node.Policy)
+		}
+		if policy.Agent != nil {
+			acl.agent = maxPrivilege(acl.agent, policy.Agent.Policy)
+		}
+		if policy.Operator != nil {
+			acl.operator = maxPrivilege(acl.operator, policy.Operator.Policy)
+		}
+	}
+	return acl
+}
+
+// NewWorkloadACL returns the ACL of the workload identities of the job. It may
+// only read the job and its variables.
+func NewWorkloadACL(namespace, jobID string) *ACL {
+	return &ACL{
+		namespaces: make(map[string]*namespaceRule),
+		workload:   &workload{namespace: namespace, jobID: jobID},
+	}
+}
+
+// maxPrivilege returns the most permissive of two policy levels. Deny takes
+// precedence over the other levels.
+func maxPrivilege(a, b string) string {
+	if a == PolicyDeny || b == PolicyDeny {
+		return PolicyDeny
+	}
+	if a == PolicyWrite || b == PolicyWrite {
+		return PolicyWrite
+	}
+	if a == PolicyRead || b == PolicyRead {
+		return PolicyRead
+	}
+	return ""
+}
+
+// IsManagement returns whether the ACL is a management ACL
+func (a *ACL) IsManagement() bool {
+	return a == nil || a.management
+}
+
+// namespaceRule returns the rule of a namespace, which is nil if neither the
+// namespace nor "*" has one
+func (a *ACL) namespaceRule(ns string) *namespaceRule {
+	if rule, ok := a.namespaces[ns]; ok {
+		return rule
+	}
+	return a.namespaces["*"]
+}
+
+// namespacePolicy returns the policy level of a namespace
+func (a *ACL) namespacePolicy(ns string) string {
+	if rule := a.namespaceRule(ns); rule != nil {
+		return rule.policy
+	}
+	return ""
+}
+
+// AllowNamespaceRead returns whether the objects of the namespace can be read
+func (a *ACL) AllowNamespaceRead(ns string) bool {
+	return a == nil || a.management || allowRead(a.namespacePolicy(ns))
+}
+
+// AllowNamespaceWrite returns whether the objects of the namespace can be
+// written
+func (a *ACL) AllowNamespaceWrite(ns string) bool {
+	return a == nil || a.management || a.namespacePolicy(ns) == PolicyWrite
+}
+
+// AllowNamespaceOperation returns whether the capability is granted on the
+// namespace
+func (a *ACL) AllowNamespaceOperation(ns, capability string) bool {
+	if a == nil || a.management {
+		return true
+	}
+	rule := a.namespaceRule(ns)
+	if rule == nil || rule.policy == PolicyDeny {
+		return false
+	}
+	_, ok := rule.capabilities[capability]
+	return ok
+}
+
+// AllowHostVolume returns whether the jobs of the namespace may mount the host
+// path, read-only or read-write. Every pattern matching the path or one of
+// its parents applies, a deny taking precedence.
+func (a *ACL) AllowHostVolume(ns, hostPath string, readOnly bool) bool {
+	if a == nil || a.management {
+		return true
+	}
+	rule := a.namespaceRule(ns)
+	if rule == nil || rule.policy == PolicyDeny {
+		return false
+	}
+
+	policy := ""
+	for pattern, level := range rule.hostVolumes {
+		if matchHostPath(pattern, hostPath) {
+			policy = maxPrivilege(policy, level)
+		}
+	}
+	if readOnly {
+		return allowRead(policy)
+	}
+	return policy == PolicyWrite
+}
+
+// matchHostPath returns whether the pattern matches the path or one of its
+// parents
+func matchHostPath(pattern, hostPath string) bool {
+	for p := path.Clean(hostPath); ; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if p == "/" || p == "." {
+			return false
+		}
+	}
+}
+
+// AllowJobRead returns whether the job of the namespace can be read. Workload
+// ACLs may read their own job.
+func (a *ACL) AllowJobRead(ns, jobID string) bool {
+	if a.AllowNamespaceRead(ns) {
+		return true
+	}
+	return a.workload != nil && a.workload.namespace == ns && a.workload.jobID == jobID
+}
+
+// AllowVariableRead returns whether the variable at the path of the namespace
+// can be read. Workload ACLs may read the variables of their own job.
+func (a *ACL) AllowVariableRead(ns, path string) bool {
+	if a.AllowNamespaceRead(ns) {
+		return true
+	}
+	if a.workload == nil || a.workload.namespace != ns {
+		return false
+	}
+	prefix := WorkloadVariablesPrefix + "/" + a.workload.jobID
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// AllowNodeRead returns whether the nodes can be read
+func (a *ACL) AllowNodeRead() bool {
+	return a == nil || a.management || allowRead(a.node)
+}
+
+// AllowNodeWrite returns whether the nodes can be written
+func (a *ACL) AllowNodeWrite() bool {
+	return a == nil || a.management || a.node == PolicyWrite
+}
+
+// AllowAgentRead returns whether the agents can be read
+func (a *ACL) AllowAgentRead() bool {
+	return a == nil || a.management || allowRead(a.agent)
+}
+
+// AllowAgentWrite returns whether the agents can be written
+func (a *ACL) AllowAgentWrite() bool {
+	return a == nil || a.management || a.agent == PolicyWrite
+}
+
+// AllowOperatorRead returns whether the operator endpoints can be read
+func (a *ACL) AllowOperatorRead() bool {
+	return a == nil || a.management || allowRead(a.operator)
+}
+
+// AllowOperatorWrite returns whether the operator endpoints can be written
+func (a *ACL) AllowOperatorWrite() bool {
+	return a == nil || a.management || a.operator == PolicyWrite
+}
+
+// allowRead returns whether the policy level allows reads
+func allowRead(policy string) bool {
+	return policy == PolicyRead || policy == PolicyWrite
+}
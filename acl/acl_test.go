@@ -0,0 +1,327 @@
+package acl
+
+import (
+	"testing"
+)
+
+func TestACL_Management(t *testing.T) {
+	t.Parallel()
+	acl := NewACL(true, nil)
+	if !acl.IsManagement() {
+		t.Fatalf("expected a management ACL")
+	}
+	if !acl.AllowNamespaceWrite("web") || !acl.AllowNodeWrite() || !acl.AllowAgentWrite() || !acl.AllowOperatorWrite() {
+		t.Fatalf("management ACL denied")
+	}
+}
+
+func TestACL_Policies(t *testing.T) {
+	t.Parallel()
+	p1, err := Parse(`
+namespace "default" {
+  policy = "read"
+}
+namespace "*" {
+  policy = "read"
+}
+node {
+  policy = "read"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p2, err := Parse(`
+namespace "default" {
+  policy = "write"
+}
+namespace "web" {
+  policy = "read"
+}
+agent {
+  policy = "read"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	acl := NewACL(false, []*Policy{p1, p2})
+	if acl.IsManagement() {
+		t.Fatalf("unexpected management ACL")
+	}
+
+	// The most permissive level applies
+	if !acl.AllowNamespaceRead("default") || !acl.AllowNamespaceWrite("default") {
+		t.Fatalf("default namespace denied")
+	}
+
+	// The wildcard applies to the namespaces without a rule
+	if !acl.AllowNamespaceRead("other") || acl.AllowNamespaceWrite("other") {
+		t.Fatalf("bad wildcard namespace permissions")
+	}
+	if !acl.AllowNamespaceRead("web") || acl.AllowNamespaceWrite("web") {
+		t.Fatalf("bad web namespace permissions")
+	}
+
+	if !acl.AllowNodeRead() || acl.AllowNodeWrite() {
+		t.Fatalf("bad node permissions")
+	}
+	if !acl.AllowAgentRead() || acl.AllowAgentWrite() {
+		t.Fatalf("bad agent permissions")
+	}
+	if acl.AllowOperatorRead() || acl.AllowOperatorWrite() {
+		t.Fatalf("bad operator permissions")
+	}
+
+	// An ACL without policies denies everything
+	acl = NewACL(false, nil)
+	if acl.AllowNamespaceRead("default") || acl.AllowNodeRead() || acl.AllowAgentRead() || acl.AllowOperatorRead() {
+		t.Fatalf("empty ACL allowed")
+	}
+}
+
+func TestACL_Capabilities(t *testing.T) {
+	t.Parallel()
+	p1, err := Parse(`
+namespace "default" {
+  policy = "read"
+}
+namespace "deploy" {
+  capabilities = ["submit-job"]
+}
+namespace "*" {
+  policy = "write"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p2, err := Parse(`
+namespace "default" {
+  capabilities = ["alloc-exec", "alloc-node-exec"]
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl := NewACL(false, []*Policy{p1, p2})
+
+	cases := []struct {
+		ns      string
+		allowed []string
+		denied  []string
+	}{
+		{
+			// Read implies reading the files and logs, and the
+			// capabilities listed add up across policies
+			ns: "default",
+			allowed: []string{NamespaceCapabilityReadFS, NamespaceCapabilityReadLogs,
+				NamespaceCapabilityAllocExec, NamespaceCapabilityAllocNodeExec},
+			denied: []string{NamespaceCapabilitySubmitJob, NamespaceCapabilityDispatchJob,
+				NamespaceCapabilityScaleJob, NamespaceCapabilityReadSecrets},
+		},
+		{
+			// A deploy bot submits jobs but can't exec or read logs
+			ns:      "deploy",
+			allowed: []string{NamespaceCapabilitySubmitJob},
+			denied: []string{NamespaceCapabilityAllocExec, NamespaceCapabilityReadLogs,
+				NamespaceCapabilityReadFS, NamespaceCapabilityDispatchJob},
+		},
+		{
+			// Write implies everything but exec on the nodes and policy
+			// overrides
+			ns: "other",
+			allowed: []string{NamespaceCapabilityReadFS, NamespaceCapabilityReadLogs,
+				NamespaceCapabilityReadSecrets, NamespaceCapabilityAllocExec,
+				NamespaceCapabilitySubmitJob, NamespaceCapabilityDispatchJob,
+				NamespaceCapabilityScaleJob},
+			denied: []string{NamespaceCapabilityAllocNodeExec, NamespaceCapabilityPolicyOverride},
+		},
+	}
+	for _, c := range cases {
+		for _, capability := range c.allowed {
+			if !acl.AllowNamespaceOperation(c.ns, capability) {
+				t.Fatalf("%s denied on namespace %q", capability, c.ns)
+			}
+		}
+		for _, capability := range c.denied {
+			if acl.AllowNamespaceOperation(c.ns, capability) {
+				t.Fatalf("%s allowed on namespace %q", capability, c.ns)
+			}
+		}
+	}
+
+	// Capabilities alone don't grant reading the objects of the namespace
+	if acl.AllowNamespaceRead("deploy") {
+		t.Fatalf("deploy namespace readable")
+	}
+
+	var nilACL *ACL
+	if !nilACL.AllowNamespaceOperation("default", NamespaceCapabilityAllocNodeExec) ||
+		!ManagementACL.AllowNamespaceOperation("default", NamespaceCapabilityAllocNodeExec) {
+		t.Fatalf("capability denied")
+	}
+}
+
+func TestACL_Deny(t *testing.T) {
+	t.Parallel()
+	p1, err := Parse(`
+namespace "*" {
+  policy = "write"
+}
+namespace "default" {
+  policy = "write"
+}
+node {
+  policy = "write"
+}
+agent {
+  policy = "write"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p2, err := Parse(`
+namespace "prod" {
+  policy = "deny"
+}
+namespace "default" {
+  capabilities = ["deny"]
+}
+node {
+  policy = "deny"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Deny overrides the levels and capabilities of the other policies
+	// regardless of their order
+	for _, acl := range []*ACL{NewACL(false, []*Policy{p1, p2}), NewACL(false, []*Policy{p2, p1})} {
+		for _, ns := range []string{"prod", "default"} {
+			if acl.AllowNamespaceRead(ns) || acl.AllowNamespaceWrite(ns) ||
+				acl.AllowNamespaceOperation(ns, NamespaceCapabilitySubmitJob) {
+				t.Fatalf("denied namespace %q allowed", ns)
+			}
+		}
+		if acl.AllowNodeRead() || acl.AllowNodeWrite() {
+			t.Fatalf("denied node allowed")
+		}
+
+		// The rest of the policies still apply
+		if !acl.AllowNamespaceWrite("web") || !acl.AllowNamespaceOperation("web", NamespaceCapabilitySubmitJob) {
+			t.Fatalf("web namespace denied")
+		}
+		if !acl.AllowAgentWrite() {
+			t.Fatalf("agent denied")
+		}
+	}
+}
+
+func TestACL_HostVolumes(t *testing.T) {
+	t.Parallel()
+	p1, err := Parse(`
+namespace "default" {
+  policy = "write"
+  host_volume "/var/log/*" {
+    policy = "read"
+  }
+  host_volume "/srv/data" {
+    policy = "write"
+  }
+}
+namespace "prod" {
+  policy = "write"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p2, err := Parse(`
+namespace "default" {
+  host_volume "/srv/data/secrets" {
+    policy = "deny"
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl := NewACL(false, []*Policy{p1, p2})
+
+	cases := []struct {
+		ns       string
+		path     string
+		readOnly bool
+		allowed  bool
+	}{
+		{"default", "/var/log/nginx", true, true},
+		{"default", "/var/log/nginx/access.log", true, true},
+		{"default", "/var/log/nginx", false, false},
+		{"default", "/var/log", true, false},
+		{"default", "/srv/data", false, true},
+		{"default", "/srv/data/db", false, true},
+		{"default", "/srv/data/../../etc", true, false},
+		{"default", "/srv/data/secrets", true, false},
+		{"default", "/srv/data/secrets/key", true, false},
+		{"default", "/", true, false},
+		{"default", "relative", true, false},
+
+		// Writing a namespace doesn't grant mounting host volumes
+		{"prod", "/var/log/nginx", true, false},
+	}
+	for _, c := range cases {
+		if act := acl.AllowHostVolume(c.ns, c.path, c.readOnly); act != c.allowed {
+			t.Fatalf("host volume %q (read-only %v) of namespace %q: expected allowed %v, got %v",
+				c.path, c.readOnly, c.ns, c.allowed, act)
+		}
+	}
+
+	var nilACL *ACL
+	if !nilACL.AllowHostVolume("default", "/", false) || !ManagementACL.AllowHostVolume("default", "/", false) {
+		t.Fatalf("host volume denied")
+	}
+}
+
+func TestACL_Nil(t *testing.T) {
+	t.Parallel()
+	var acl *ACL
+	if !acl.IsManagement() || !acl.AllowNamespaceWrite("web") || !acl.AllowNodeWrite() ||
+		!acl.AllowAgentWrite() || !acl.AllowOperatorWrite() {
+		t.Fatalf("nil ACL denied")
+	}
+}
+
+func TestACL_Workload(t *testing.T) {
+	t.Parallel()
+	acl := NewWorkloadACL("web", "frontend")
+	if acl.IsManagement() || acl.AllowNamespaceRead("web") || acl.AllowNodeRead() {
+		t.Fatalf("workload ACL allowed more than its workload")
+	}
+
+	// The job itself can be read, but no other job
+	if !acl.AllowJobRead("web", "frontend") {
+		t.Fatalf("workload ACL denied its job")
+	}
+	if acl.AllowJobRead("web", "backend") || acl.AllowJobRead("default", "frontend") {
+		t.Fatalf("workload ACL allowed another job")
+	}
+
+	// Only the variables of the job can be read
+	for _, path := range []string{"nomad/jobs/frontend", "nomad/jobs/frontend/web/server"} {
+		if !acl.AllowVariableRead("web", path) {
+			t.Fatalf("workload ACL denied %q", path)
+		}
+	}
+	for _, path := range []string{"nomad/jobs", "nomad/jobs/frontend-canary", "nomad/jobs/backend", "app/frontend"} {
+		if acl.AllowVariableRead("web", path) {
+			t.Fatalf("workload ACL allowed %q", path)
+		}
+	}
+	if acl.AllowVariableRead("default", "nomad/jobs/frontend") {
+		t.Fatalf("workload ACL allowed another namespace")
+	}
+}
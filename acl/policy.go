@@ -0,0 +1,202 @@
+package acl
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/hashicorp/hcl"
+)
+
+const (
+	// The following levels are the only valid values for the policy of the
+	// rules of a policy. Write implies read, and deny overrides the levels
+	// granted by the other policies of a token.
+	PolicyDeny  = "deny"
+	PolicyRead  = "read"
+	PolicyWrite = "write"
+)
+
+const (
+	// The following capabilities are the only valid capabilities of the
+	// rules of namespaces. They grant the operations of the endpoints that
+	// need more than reading or writing the objects of the namespace. The
+	// deny capability denies the namespace like the deny policy level.
+	NamespaceCapabilityDeny           = "deny"
+	NamespaceCapabilityAllocExec      = "alloc-exec"
+	NamespaceCapabilityAllocNodeExec  = "alloc-node-exec"
+	NamespaceCapabilityReadFS         = "read-fs"
+	NamespaceCapabilityReadLogs       = "read-logs"
+	NamespaceCapabilityReadSecrets    = "read-secrets"
+	NamespaceCapabilitySubmitJob      = "submit-job"
+	NamespaceCapabilityDispatchJob    = "dispatch-job"
+	NamespaceCapabilityScaleJob       = "scale-job"
+	NamespaceCapabilityPolicyOverride = "policy-override"
+)
+
+var (
+	// validNamespace matches the namespaces a rule may be defined for. A
+	// single "*" matches every namespace without a rule of its own.
+	validNamespace = regexp.MustCompile("^([a-zA-Z0-9-]{1,128}|\\*)$")
+)
+
+// Policy is a parsed ACL policy. Policies grant access to the objects of the
+// namespaces and to the nodes, agents and operator endpoints of the cluster,
+// and are written in HCL or JSON:
+//
+//	namespace "default" {
+//	  policy       = "read"
+//	  capabilities = ["submit-job"]
+//
+//	  host_volume "/var/log/*" {
+//	    policy = "read"
+//	  }
+//	}
+//
+//	node {
+//	  policy = "read"
+//	}
+//
+// A rule with the deny level overrides the rules of the other policies of a
+// token, so that exceptions can be carved out of broad policies.
+type Policy struct {
+	Namespaces []*NamespacePolicy `hcl:"namespace,expand"`
+	Node       *NodePolicy        `hcl:"node"`
+	Agent      *AgentPolicy       `hcl:"agent"`
+	Operator   *OperatorPolicy    `hcl:"operator"`
+	Raw        string             `hcl:"-"`
+}
+
+// NamespacePolicy is the policy of a namespace. The policy level grants the
+// capabilities it implies on top of the capabilities listed.
+type NamespacePolicy struct {
+	Name         string `hcl:",key"`
+	Policy       string
+	Capabilities []string
+	HostVolumes  []*HostVolumePolicy `hcl:"host_volume,expand"`
+}
+
+// HostVolumePolicy is the policy of the host paths the jobs of a namespace
+// may mount. The path is a glob pattern covering the paths it matches and
+// the paths below them. Read allows read-only mounts and write allows
+// read-write mounts.
+type HostVolumePolicy struct {
+	Path   string `hcl:",key"`
+	Policy string
+}
+
+// NodePolicy is the policy of the nodes
+type NodePolicy struct {
+	Policy string
+}
+
+// AgentPolicy is the policy of the agents
+type AgentPolicy struct {
+	Policy string
+}
+
+// OperatorPolicy is the policy of the operator endpoints
+type OperatorPolicy struct {
+	Policy string
+}
+
+// isPolicyValid returns whether the level is a valid policy level
+func isPolicyValid(policy string) bool {
+	switch policy {
+	case PolicyDeny, PolicyRead, PolicyWrite:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNamespaceCapabilityValid returns whether the capability is a valid
+// namespace capability
+func isNamespaceCapabilityValid(capability string) bool {
+	switch capability {
+	case NamespaceCapabilityDeny,
+		NamespaceCapabilityAllocExec, NamespaceCapabilityAllocNodeExec,
+		NamespaceCapabilityReadFS, NamespaceCapabilityReadLogs,
+		NamespaceCapabilityReadSecrets, NamespaceCapabilitySubmitJob, NamespaceCapabilityDispatchJob,
+		NamespaceCapabilityScaleJob, NamespaceCapabilityPolicyOverride:
+		return true
+	default:
+		return false
+	}
+}
+
+// expandNamespacePolicy returns the capabilities implied by the policy level
+// of a namespace. Read doesn't imply read-secrets, which reads the files of
+// the secret directories of the tasks. Write doesn't imply alloc-node-exec,
+// which runs commands on the nodes of the tasks without filesystem
+// isolation, nor policy-override, which registers jobs despite the denials
+// of soft-mandatory admission policies. Deny implies the deny capability.
+func expandNamespacePolicy(policy string) []string {
+	read := []string{
+		NamespaceCapabilityReadFS,
+		NamespaceCapabilityReadLogs,
+	}
+	switch policy {
+	case PolicyDeny:
+		return []string{NamespaceCapabilityDeny}
+	case PolicyRead:
+		return read
+	case PolicyWrite:
+		return append(read,
+			NamespaceCapabilityReadSecrets,
+			NamespaceCapabilityAllocExec,
+			NamespaceCapabilitySubmitJob,
+			NamespaceCapabilityDispatchJob,
+			NamespaceCapabilityScaleJob)
+	default:
+		return nil
+	}
+}
+
+// Parse parses the rules of a policy and returns an error if they are
+// invalid.
+func Parse(rules string) (*Policy, error) {
+	if rules == "" {
+		return nil, fmt.Errorf("policy has no rules")
+	}
+
+	p := &Policy{Raw: rules}
+	if err := hcl.Decode(p, rules); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %v", err)
+	}
+
+	for _, ns := range p.Namespaces {
+		if !validNamespace.MatchString(ns.Name) {
+			return nil, fmt.Errorf("invalid namespace name %q", ns.Name)
+		}
+		if ns.Policy == "" && len(ns.Capabilities) == 0 && len(ns.HostVolumes) == 0 {
+			return nil, fmt.Errorf("namespace %q has no policy, capabilities or host volumes", ns.Name)
+		}
+		if ns.Policy != "" && !isPolicyValid(ns.Policy) {
+			return nil, fmt.Errorf("invalid policy %q for namespace %q", ns.Policy, ns.Name)
+		}
+		for _, capability := range ns.Capabilities {
+			if !isNamespaceCapabilityValid(capability) {
+				return nil, fmt.Errorf("invalid capability %q for namespace %q", capability, ns.Name)
+			}
+		}
+		for _, hv := range ns.HostVolumes {
+			if _, err := path.Match(hv.Path, ""); err != nil || !path.IsAbs(hv.Path) {
+				return nil, fmt.Errorf("invalid host volume path %q for namespace %q", hv.Path, ns.Name)
+			}
+			if !isPolicyValid(hv.Policy) {
+				return nil, fmt.Errorf("invalid policy %q for host volume %q of namespace %q", hv.Policy, hv.Path, ns.Name)
+			}
+		}
+	}
+	if p.Node != nil && !isPolicyValid(p.Node.Policy) {
+		return nil, fmt.Errorf("invalid node policy %q", p.Node.Policy)
+	}
+	if p.Agent != nil && !isPolicyValid(p.Agent.Policy) {
+		return nil, fmt.Errorf("invalid agent policy %q", p.Agent.Policy)
+	}
+	if p.Operator != nil && !isPolicyValid(p.Operator.Policy) {
+		return nil, fmt.Errorf("invalid operator policy %q", p.Operator.Policy)
+	}
+	return p, nil
+}
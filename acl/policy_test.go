@@ -0,0 +1,113 @@
+package acl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	p, err := Parse(`
+namespace "default" {
+  policy = "write"
+}
+
+namespace "*" {
+  policy = "read"
+}
+
+node {
+  policy = "read"
+}
+
+agent {
+  policy = "write"
+}
+
+operator {
+  policy = "read"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(p.Namespaces) != 2 {
+		t.Fatalf("bad namespaces: %#v", p.Namespaces)
+	}
+	if ns := p.Namespaces[0]; ns.Name != "default" || ns.Policy != PolicyWrite {
+		t.Fatalf("bad namespace: %#v", ns)
+	}
+	if ns := p.Namespaces[1]; ns.Name != "*" || ns.Policy != PolicyRead {
+		t.Fatalf("bad namespace: %#v", ns)
+	}
+	if p.Node.Policy != PolicyRead || p.Agent.Policy != PolicyWrite || p.Operator.Policy != PolicyRead {
+		t.Fatalf("bad policy: %#v", p)
+	}
+
+	// JSON is accepted too
+	p, err = Parse(`{"namespace": {"web": {"policy": "read"}}}`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(p.Namespaces) != 1 || p.Namespaces[0].Name != "web" {
+		t.Fatalf("bad namespaces: %#v", p.Namespaces)
+	}
+
+	// Rules may deny
+	p, err = Parse(`
+namespace "prod" {
+  policy = "deny"
+}
+namespace "default" {
+  capabilities = ["deny"]
+}
+operator {
+  policy = "deny"
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if p.Namespaces[0].Policy != PolicyDeny || p.Operator.Policy != PolicyDeny {
+		t.Fatalf("bad policy: %#v", p)
+	}
+
+	// Namespace rules may only govern host volumes
+	p, err = Parse(`
+namespace "default" {
+  host_volume "/var/log/*" {
+    policy = "read"
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if hv := p.Namespaces[0].HostVolumes; len(hv) != 1 || hv[0].Path != "/var/log/*" || hv[0].Policy != PolicyRead {
+		t.Fatalf("bad host volumes: %#v", hv)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		``: "no rules",
+		`namespace "default" { policy = "admin" }`:                        "invalid policy",
+		`namespace "default" { capabilities = ["sudo"] }`:                 "invalid capability",
+		`namespace "default" {}`:                                          "no policy, capabilities or host volumes",
+		`namespace "default" { host_volume "var" { policy = "read" } }`:   "invalid host volume path",
+		`namespace "default" { host_volume "/[" { policy = "read" } }`:    "invalid host volume path",
+		`namespace "default" { host_volume "/var" { policy = "mount" } }`: "invalid policy",
+		`namespace "web*" { policy = "read" }`:                            "invalid namespace name",
+		`node { policy = "" }`:                                            "invalid node policy",
+		`agent { policy = "list" }`:                                       "invalid agent policy",
+		`operator { policy = "all" }`:                                     "invalid operator policy",
+		`namespace "default" {`:                                           "failed to parse",
+	}
+	for rules, expected := range cases {
+		if _, err := Parse(rules); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("rules %q: got error %v; want %q", rules, err, expected)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// ACLPolicies is used to query the ACL policy endpoints.
+type ACLPolicies struct {
+	client *Client
+}
+
+// ACLPolicies returns a new handle on the ACL policies.
+func (c *Client) ACLPolicies() *ACLPolicies {
+	return &ACLPolicies{client: c}
+}
+
+// List is used to dump all of the policies.
+func (a *ACLPolicies) List(q *QueryOptions) ([]*ACLPolicyListStub, *QueryMeta, error) {
+	var resp []*ACLPolicyListStub
+	qm, err := a.client.query("/v1/acl/policies", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Upsert is used to create or update a policy
+func (a *ACLPolicies) Upsert(policy *ACLPolicy, q *WriteOptions) (*WriteMeta, error) {
+	if policy == nil || policy.Name == "" {
+		return nil, fmt.Errorf("missing policy name")
+	}
+	wm, err := a.client.write("/v1/acl/policy/"+policy.Name, policy, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Delete is used to delete a policy
+func (a *ACLPolicies) Delete(policyName string, q *WriteOptions) (*WriteMeta, error) {
+	if policyName == "" {
+		return nil, fmt.Errorf("missing policy name")
+	}
+	wm, err := a.client.delete("/v1/acl/policy/"+policyName, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Info is used to query a single policy by its name.
+func (a *ACLPolicies) Info(policyName string, q *QueryOptions) (*ACLPolicy, *QueryMeta, error) {
+	if policyName == "" {
+		return nil, nil, fmt.Errorf("missing policy name")
+	}
+	var resp ACLPolicy
+	qm, err := a.client.query("/v1/acl/policy/"+policyName, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// ACLRoles is used to query the ACL role endpoints.
+type ACLRoles struct {
+	client *Client
+}
+
+// ACLRoles returns a new handle on the ACL roles.
+func (c *Client) ACLRoles() *ACLRoles {
+	return &ACLRoles{client: c}
+}
+
+// List is used to dump all of the roles.
+func (a *ACLRoles) List(q *QueryOptions) ([]*ACLRoleListStub, *QueryMeta, error) {
+	var resp []*ACLRoleListStub
+	qm, err := a.client.query("/v1/acl/roles", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Upsert is used to create or update a role
+func (a *ACLRoles) Upsert(role *ACLRole, q *WriteOptions) (*WriteMeta, error) {
+	if role == nil || role.Name == "" {
+		return nil, fmt.Errorf("missing role name")
+	}
+	wm, err := a.client.write("/v1/acl/role/"+role.Name, role, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Delete is used to delete a role
+func (a *ACLRoles) Delete(roleName string, q *WriteOptions) (*WriteMeta, error) {
+	if roleName == "" {
+		return nil, fmt.Errorf("missing role name")
+	}
+	wm, err := a.client.delete("/v1/acl/role/"+roleName, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Info is used to query a single role by its name.
+func (a *ACLRoles) Info(roleName string, q *QueryOptions) (*ACLRole, *QueryMeta, error) {
+	if roleName == "" {
+		return nil, nil, fmt.Errorf("missing role name")
+	}
+	var resp ACLRole
+	qm, err := a.client.query("/v1/acl/role/"+roleName, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// ACLAuthMethods is used to query the ACL auth method endpoints.
+type ACLAuthMethods struct {
+	client *Client
+}
+
+// ACLAuthMethods returns a new handle on the ACL auth methods.
+func (c *Client) ACLAuthMethods() *ACLAuthMethods {
+	return &ACLAuthMethods{client: c}
+}
+
+// List is used to dump all of the auth methods.
+func (a *ACLAuthMethods) List(q *QueryOptions) ([]*ACLAuthMethodListStub, *QueryMeta, error) {
+	var resp []*ACLAuthMethodListStub
+	qm, err := a.client.query("/v1/acl/auth-methods", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Upsert is used to create or update an auth method
+func (a *ACLAuthMethods) Upsert(method *ACLAuthMethod, q *WriteOptions) (*WriteMeta, error) {
+	if method == nil || method.Name == "" {
+		return nil, fmt.Errorf("missing auth method name")
+	}
+	wm, err := a.client.write("/v1/acl/auth-method/"+method.Name, method, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Delete is used to delete an auth method along with its binding rules
+func (a *ACLAuthMethods) Delete(methodName string, q *WriteOptions) (*WriteMeta, error) {
+	if methodName == "" {
+		return nil, fmt.Errorf("missing auth method name")
+	}
+	wm, err := a.client.delete("/v1/acl/auth-method/"+methodName, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Info is used to query a single auth method by its name.
+func (a *ACLAuthMethods) Info(methodName string, q *QueryOptions) (*ACLAuthMethod, *QueryMeta, error) {
+	if methodName == "" {
+		return nil, nil, fmt.Errorf("missing auth method name")
+	}
+	var resp ACLAuthMethod
+	qm, err := a.client.query("/v1/acl/auth-method/"+methodName, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Login is used to exchange a JWT for an ACL token with a JWT auth method.
+func (a *ACLAuthMethods) Login(req *ACLLoginRequest, q *WriteOptions) (*ACLToken, *WriteMeta, error) {
+	if req == nil || req.LoginToken == "" {
+		return nil, nil, fmt.Errorf("missing login token")
+	}
+	var resp ACLToken
+	wm, err := a.client.write("/v1/acl/login", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// ACLBindingRules is used to query the ACL binding rule endpoints.
+type ACLBindingRules struct {
+	client *Client
+}
+
+// ACLBindingRules returns a new handle on the ACL binding rules.
+func (c *Client) ACLBindingRules() *ACLBindingRules {
+	return &ACLBindingRules{client: c}
+}
+
+// List is used to dump all of the binding rules.
+func (a *ACLBindingRules) List(q *QueryOptions) ([]*ACLBindingRule, *QueryMeta, error) {
+	var resp []*ACLBindingRule
+	qm, err := a.client.query("/v1/acl/binding-rules", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Create is used to create a binding rule
+func (a *ACLBindingRules) Create(rule *ACLBindingRule, q *WriteOptions) (*ACLBindingRule, *WriteMeta, error) {
+	if rule.ID != "" {
+		return nil, nil, fmt.Errorf("cannot specify ID")
+	}
+	var resp ACLBindingRule
+	wm, err := a.client.write("/v1/acl/binding-rule", rule, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// Update is used to update an existing binding rule
+func (a *ACLBindingRules) Update(rule *ACLBindingRule, q *WriteOptions) (*ACLBindingRule, *WriteMeta, error) {
+	if rule.ID == "" {
+		return nil, nil, fmt.Errorf("missing binding rule ID")
+	}
+	var resp ACLBindingRule
+	wm, err := a.client.write("/v1/acl/binding-rule/"+rule.ID, rule, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// Delete is used to delete a binding rule
+func (a *ACLBindingRules) Delete(ruleID string, q *WriteOptions) (*WriteMeta, error) {
+	if ruleID == "" {
+		return nil, fmt.Errorf("missing binding rule ID")
+	}
+	wm, err := a.client.delete("/v1/acl/binding-rule/"+ruleID, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Info is used to query a binding rule by its ID.
+func (a *ACLBindingRules) Info(ruleID string, q *QueryOptions) (*ACLBindingRule, *QueryMeta, error) {
+	if ruleID == "" {
+		return nil, nil, fmt.Errorf("missing binding rule ID")
+	}
+	var resp ACLBindingRule
+	qm, err := a.client.query("/v1/acl/binding-rule/"+ruleID, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// ACLOIDC is used to log in with the OIDC auth methods.
+type ACLOIDC struct {
+	client *Client
+}
+
+// ACLOIDC returns a new handle on the OIDC login endpoints.
+func (c *Client) ACLOIDC() *ACLOIDC {
+	return &ACLOIDC{client: c}
+}
+
+// GetAuthURL starts logging in with an OIDC auth method and returns the URL
+// of the OIDC provider the user logs in at.
+func (a *ACLOIDC) GetAuthURL(req *ACLOIDCAuthURLRequest, q *WriteOptions) (*ACLOIDCAuthURLResponse, *WriteMeta, error) {
+	var resp ACLOIDCAuthURLResponse
+	wm, err := a.client.write("/v1/acl/oidc/auth-url", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// CompleteAuth completes logging in with an OIDC auth method with the
+// authorization code the OIDC provider redirected the user with, and returns
+// the ACL token created.
+func (a *ACLOIDC) CompleteAuth(req *ACLOIDCCompleteAuthRequest, q *WriteOptions) (*ACLToken, *WriteMeta, error) {
+	var resp ACLToken
+	wm, err := a.client.write("/v1/acl/oidc/complete-auth", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// ACLTokens is used to query the ACL token endpoints.
+type ACLTokens struct {
+	client *Client
+}
+
+// ACLTokens returns a new handle on the ACL tokens.
+func (c *Client) ACLTokens() *ACLTokens {
+	return &ACLTokens{client: c}
+}
+
+// Bootstrap is used to create the initial management token. It can only be
+// done once.
+func (a *ACLTokens) Bootstrap(q *WriteOptions) (*ACLToken, *WriteMeta, error) {
+	var resp ACLToken
+	wm, err := a.client.write("/v1/acl/bootstrap", nil, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// List is used to dump all of the tokens.
+func (a *ACLTokens) List(q *QueryOptions) ([]*ACLTokenListStub, *QueryMeta, error) {
+	var resp []*ACLTokenListStub
+	qm, err := a.client.query("/v1/acl/tokens", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Create is used to create a token
+func (a *ACLTokens) Create(token *ACLToken, q *WriteOptions) (*ACLToken, *WriteMeta, error) {
+	if token.AccessorID != "" {
+		return nil, nil, fmt.Errorf("cannot specify accessor ID")
+	}
+	var resp ACLToken
+	wm, err := a.client.write("/v1/acl/token", token, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// Update is used to update an existing token
+func (a *ACLTokens) Update(token *ACLToken, q *WriteOptions) (*ACLToken, *WriteMeta, error) {
+	if token.AccessorID == "" {
+		return nil, nil, fmt.Errorf("missing accessor ID")
+	}
+	var resp ACLToken
+	wm, err := a.client.write("/v1/acl/token/"+token.AccessorID, token, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// Delete is used to delete a token
+func (a *ACLTokens) Delete(accessorID string, q *WriteOptions) (*WriteMeta, error) {
+	if accessorID == "" {
+		return nil, fmt.Errorf("missing accessor ID")
+	}
+	wm, err := a.client.delete("/v1/acl/token/"+accessorID, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Info is used to query a token by its accessor ID.
+func (a *ACLTokens) Info(accessorID string, q *QueryOptions) (*ACLToken, *QueryMeta, error) {
+	if accessorID == "" {
+		return nil, nil, fmt.Errorf("missing accessor ID")
+	}
+	var resp ACLToken
+	qm, err := a.client.query("/v1/acl/token/"+accessorID, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Self is used to query the token the requests are made with.
+func (a *ACLTokens) Self(q *QueryOptions) (*ACLToken, *QueryMeta, error) {
+	var resp ACLToken
+	qm, err := a.client.query("/v1/acl/token/self", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// ACLPolicyListStub is used for listing ACL policies
+type ACLPolicyListStub struct {
+	Name        string
+	Description string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLPolicy is used to represent an ACL policy
+type ACLPolicy struct {
+	Name        string
+	Description string
+	Rules       string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLRoleListStub is used for listing ACL roles
+type ACLRoleListStub struct {
+	Name        string
+	Description string
+	Policies    []string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLRole is used to represent an ACL role, a named set of policies
+type ACLRole struct {
+	Name        string
+	Description string
+	Policies    []string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLAuthMethodListStub is used for listing ACL auth methods
+type ACLAuthMethodListStub struct {
+	Name        string
+	Type        string
+	Default     bool
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLAuthMethod is used to represent an ACL auth method
+type ACLAuthMethod struct {
+	Name          string
+	Type          string
+	TokenLocality string
+	MaxTokenTTL   time.Duration
+	Default       bool
+	Config        *ACLAuthMethodConfig
+	CreateIndex   uint64
+	ModifyIndex   uint64
+}
+
+// ACLAuthMethodConfig is the configuration of the identity provider of an
+// ACL auth method
+type ACLAuthMethodConfig struct {
+	OIDCDiscoveryURL     string
+	OIDCClientID         string
+	OIDCClientSecret     string
+	OIDCScopes           []string
+	JWKSURL              string
+	JWTValidationPubKeys []string
+	BoundIssuer          string
+	BoundAudiences       []string
+	BoundClaims          map[string][]string
+	AllowedRedirectURIs  []string
+	DiscoveryCaPem       []string
+	SigningAlgs          []string
+	ClaimMappings        map[string]string
+	ListClaimMappings    map[string]string
+}
+
+// ACLBindingRule is used to represent an ACL binding rule
+type ACLBindingRule struct {
+	ID          string
+	Description string
+	AuthMethod  string
+	Selector    string
+	BindType    string
+	BindName    string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLLoginRequest is used to log in with a JWT auth method
+type ACLLoginRequest struct {
+	AuthMethodName string
+	LoginToken     string
+}
+
+// ACLOIDCAuthURLRequest is used to start logging in with an OIDC auth method
+type ACLOIDCAuthURLRequest struct {
+	AuthMethodName string
+	RedirectURI    string
+	ClientNonce    string
+}
+
+// ACLOIDCAuthURLResponse is the URL of the OIDC provider to log in at
+type ACLOIDCAuthURLResponse struct {
+	AuthURL string
+}
+
+// ACLOIDCCompleteAuthRequest is used to complete logging in with an OIDC
+// auth method
+type ACLOIDCCompleteAuthRequest struct {
+	AuthMethodName string
+	ClientNonce    string
+	State          string
+	Code           string
+	RedirectURI    string
+}
+
+// ACLToken represents a client token which is used to authenticate
+type ACLToken struct {
+	AccessorID string
+	SecretID   string
+	Name       string
+	Type       string
+	Policies   []string
+	Roles      []string
+	Global     bool
+	CreateTime time.Time
+
+	// AllowedCIDRs are the address ranges the token may be used from. It may
+	// be used from anywhere if it has none.
+	AllowedCIDRs []string
+
+	// ExpirationTime is the time after which the token is no longer valid.
+	// ExpirationTTL sets it relative to the creation of a new token.
+	ExpirationTime *time.Time
+	ExpirationTTL  time.Duration
+
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLTokenListStub is used for listing ACL tokens
+type ACLTokenListStub struct {
+	AccessorID     string
+	Name           string
+	Type           string
+	Policies       []string
+	Roles          []string
+	Global         bool
+	CreateTime     time.Time
+	ExpirationTime *time.Time
+	CreateIndex    uint64
+	ModifyIndex    uint64
+}
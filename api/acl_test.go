@@ -0,0 +1,291 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// makeACLClient returns a client of a server with ACLs enabled, made with the
+// bootstrap token
+func makeACLClient(t *testing.T) (*Client, *testutil.TestServer, *ACLToken) {
+	c, s := makeClient(t, nil, func(c *testutil.TestServerConfig) {
+		c.ACL = &testutil.ACLConfig{Enabled: true}
+	})
+
+	// Bootstrap the ACLs once a leader is elected
+	var root *ACLToken
+	testutil.WaitForResult(func() (bool, error) {
+		var err error
+		root, _, err = c.ACLTokens().Bootstrap(nil)
+		return err == nil, err
+	}, func(err error) {
+		s.Stop()
+		t.Fatalf("failed to bootstrap: %v", err)
+	})
+	c.SetSecretID(root.SecretID)
+	return c, s, root
+}
+
+func TestACLPolicies_Upsert_Info_Delete(t *testing.T) {
+	t.Parallel()
+	c, s, _ := makeACLClient(t)
+	defer s.Stop()
+	policies := c.ACLPolicies()
+
+	policy := &ACLPolicy{
+		Name:        "readonly",
+		Description: "Read the default namespace",
+		Rules:       `namespace "default" { policy = "read" }`,
+	}
+	wm, err := policies.Upsert(policy, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertWriteMeta(t, wm)
+
+	out, qm, err := policies.Info(policy.Name, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertQueryMeta(t, qm)
+	if out.Rules != policy.Rules {
+		t.Fatalf("bad rules: %q", out.Rules)
+	}
+
+	list, _, err := policies.List(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(list) != 1 || list[0].Name != policy.Name {
+		t.Fatalf("bad policies: %#v", list)
+	}
+
+	if _, err := policies.Delete(policy.Name, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if list, _, err := policies.List(nil); err != nil || len(list) != 0 {
+		t.Fatalf("expected no policies, got %#v, %v", list, err)
+	}
+}
+
+func TestACLRoles_Upsert_Info_Delete(t *testing.T) {
+	t.Parallel()
+	c, s, _ := makeACLClient(t)
+	defer s.Stop()
+	roles := c.ACLRoles()
+
+	policy := &ACLPolicy{
+		Name:  "readonly",
+		Rules: `namespace "default" { policy = "read" }`,
+	}
+	if _, err := c.ACLPolicies().Upsert(policy, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	role := &ACLRole{
+		Name:     "reader",
+		Policies: []string{policy.Name},
+	}
+	wm, err := roles.Upsert(role, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertWriteMeta(t, wm)
+
+	out, qm, err := roles.Info(role.Name, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(out.Policies) != 1 || out.Policies[0] != policy.Name {
+		t.Fatalf("bad policies: %#v", out.Policies)
+	}
+
+	// Tokens can be granted the role
+	token, _, err := c.ACLTokens().Create(&ACLToken{Type: "client", Roles: []string{role.Name}}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(token.Roles) != 1 || token.Roles[0] != role.Name {
+		t.Fatalf("bad token: %#v", token)
+	}
+
+	if _, err := roles.Delete(role.Name, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if list, _, err := roles.List(nil); err != nil || len(list) != 0 {
+		t.Fatalf("expected no roles, got %#v, %v", list, err)
+	}
+}
+
+func TestACLTokens_Create_Self_Delete(t *testing.T) {
+	t.Parallel()
+	c, s, root := makeACLClient(t)
+	defer s.Stop()
+	tokens := c.ACLTokens()
+
+	// The client is made with the bootstrap token
+	self, _, err := tokens.Self(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if self.AccessorID != root.AccessorID {
+		t.Fatalf("bad self token: %#v", self)
+	}
+
+	// Create an expiring management token
+	token, wm, err := tokens.Create(&ACLToken{
+		Name:          "expiring",
+		Type:          "management",
+		ExpirationTTL: time.Hour,
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertWriteMeta(t, wm)
+	if token.SecretID == "" || token.ExpirationTime == nil {
+		t.Fatalf("bad token: %#v", token)
+	}
+
+	list, _, err := tokens.List(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 tokens, got %#v", list)
+	}
+
+	if _, err := tokens.Delete(token.AccessorID, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, _, err := tokens.Info(token.AccessorID, nil); err == nil {
+		t.Fatalf("expected the deleted token not to be found")
+	}
+
+	// Requests without a token are denied
+	c.SetSecretID("")
+	if _, err := c.Agent().Self(); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestACLAuthMethods_BindingRules(t *testing.T) {
+	t.Parallel()
+	c, s, _ := makeACLClient(t)
+	defer s.Stop()
+	methods := c.ACLAuthMethods()
+	rules := c.ACLBindingRules()
+
+	method := &ACLAuthMethod{
+		Name:          "okta",
+		Type:          "OIDC",
+		TokenLocality: "local",
+		MaxTokenTTL:   time.Hour,
+		Default:       true,
+		Config: &ACLAuthMethodConfig{
+			OIDCDiscoveryURL:    "https://example.okta.com",
+			OIDCClientID:        "nomad",
+			AllowedRedirectURIs: []string{"http://localhost:4649/oidc/callback"},
+		},
+	}
+	wm, err := methods.Upsert(method, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertWriteMeta(t, wm)
+
+	out, qm, err := methods.Info(method.Name, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertQueryMeta(t, qm)
+	if !out.Default || out.Config == nil || out.Config.OIDCClientID != "nomad" {
+		t.Fatalf("bad auth method: %#v", out)
+	}
+
+	rule, _, err := rules.Create(&ACLBindingRule{
+		AuthMethod: method.Name,
+		Selector:   `"engineering" in list.groups`,
+		BindType:   "policy",
+		BindName:   "engineering",
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if rule.ID == "" {
+		t.Fatalf("bad binding rule: %#v", rule)
+	}
+
+	rule.BindName = "eng-${value.team}"
+	updated, _, err := rules.Update(rule, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if updated.ID != rule.ID || updated.BindName != rule.BindName {
+		t.Fatalf("bad binding rule: %#v", updated)
+	}
+
+	// Deleting the auth method deletes its binding rules
+	if _, err := methods.Delete(method.Name, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if list, _, err := methods.List(nil); err != nil || len(list) != 0 {
+		t.Fatalf("expected no auth methods, got %#v, %v", list, err)
+	}
+	if list, _, err := rules.List(nil); err != nil || len(list) != 0 {
+		t.Fatalf("expected no binding rules, got %#v, %v", list, err)
+	}
+}
+
+func TestACLAuthMethods_Login(t *testing.T) {
+	t.Parallel()
+	c, s, _ := makeACLClient(t)
+	defer s.Stop()
+
+	provider := testutil.NewTestOIDCProvider(t)
+	defer provider.Stop()
+
+	policy := &ACLPolicy{Name: "ci", Rules: `namespace "default" { policy = "write" }`}
+	if _, err := c.ACLPolicies().Upsert(policy, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	method := &ACLAuthMethod{
+		Name:          "ci",
+		Type:          "JWT",
+		TokenLocality: "local",
+		MaxTokenTTL:   time.Hour,
+		Config: &ACLAuthMethodConfig{
+			JWTValidationPubKeys: []string{provider.PublicKeyPEM()},
+			BoundClaims:          map[string][]string{"ref": {"refs/heads/main"}},
+		},
+	}
+	if _, err := c.ACLAuthMethods().Upsert(method, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, _, err := c.ACLBindingRules().Create(&ACLBindingRule{
+		AuthMethod: "ci",
+		BindType:   "policy",
+		BindName:   "ci",
+	}, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	jwt, err := provider.SignJWT(map[string]interface{}{
+		"ref": "refs/heads/main",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token, wm, err := c.ACLAuthMethods().Login(&ACLLoginRequest{AuthMethodName: "ci", LoginToken: jwt}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertWriteMeta(t, wm)
+	if len(token.Policies) != 1 || token.Policies[0] != "ci" || token.SecretID == "" {
+		t.Fatalf("bad: %#v", token)
+	}
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"bufio"
 	"fmt"
 	"net/url"
+	"time"
 )
 
 // Agent encapsulates an API client which talks to Nomad's
@@ -172,6 +174,93 @@ func (a *Agent) SetServers(addrs []string) error {
 	return err
 }
 
+// NodeMeta is used to query the meta of a client node, along with the static
+// and dynamic meta it is built from.
+func (a *Agent) NodeMeta() (*NodeMetaResponse, error) {
+	var resp NodeMetaResponse
+	_, err := a.client.query("/v1/client/metadata", &resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ApplyNodeMeta is used to apply changes to the dynamic meta of a client
+// node. A nil value unsets the key. The changes are persisted by the client
+// and registered with the servers shortly after.
+func (a *Agent) ApplyNodeMeta(meta map[string]*string) (*NodeMetaResponse, error) {
+	req := NodeMetaApplyRequest{Meta: meta}
+	var resp NodeMetaResponse
+	_, err := a.client.write("/v1/client/metadata", &req, &resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Metrics returns the agent's recent metrics, one entry per aggregation
+// interval.
+func (a *Agent) Metrics() ([]*MetricsInterval, error) {
+	var resp []*MetricsInterval
+	_, err := a.client.query("/v1/agent/metrics", &resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Logs returns the agent's most recent log lines.
+func (a *Agent) Logs() ([]string, error) {
+	var resp []string
+	_, err := a.client.query("/v1/agent/logs", &resp, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Monitor streams the agent's logs at or above the log level, which defaults
+// to INFO if empty. The returned channel is closed when the stream ends or
+// the stop channel is closed.
+func (a *Agent) Monitor(logLevel string, stopCh <-chan struct{}, q *QueryOptions) (<-chan string, error) {
+	endpoint := "/v1/agent/monitor"
+	if logLevel != "" {
+		endpoint += "?log_level=" + url.QueryEscape(logLevel)
+	}
+	body, err := a.client.rawQuery(endpoint, q)
+	if err != nil {
+		return nil, err
+	}
+
+	logCh := make(chan string, 64)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		defer body.Close()
+		defer close(logCh)
+
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			select {
+			case logCh <- scanner.Text():
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	// Close the body when stopped to unblock the scanner
+	go func() {
+		select {
+		case <-stopCh:
+			body.Close()
+		case <-doneCh:
+		}
+	}()
+
+	return logCh, nil
+}
+
 // ListKeys returns the list of installed keys
 func (a *Agent) ListKeys() (*KeyringResponse, error) {
 	var resp KeyringResponse
@@ -232,6 +321,25 @@ type AgentSelf struct {
 	Stats  map[string]map[string]string `json:"stats"`
 }
 
+// MetricsInterval holds the metrics aggregated by the agent over an interval.
+type MetricsInterval struct {
+	Interval time.Time
+	Gauges   map[string]float32
+	Points   map[string][]float32
+	Counters map[string]*AggregateSample
+	Samples  map[string]*AggregateSample
+}
+
+// AggregateSample is the aggregation of the values of a counter or sample.
+type AggregateSample struct {
+	Count       int
+	Sum         float64
+	SumSq       float64
+	Min         float64
+	Max         float64
+	LastUpdated time.Time
+}
+
 // AgentMember represents a cluster member known to the agent
 type AgentMember struct {
 	Name        string
@@ -247,6 +355,26 @@ type AgentMember struct {
 	DelegateCur uint8
 }
 
+// NodeMetaApplyRequest is used to apply changes to the dynamic meta of a
+// client node.
+type NodeMetaApplyRequest struct {
+	// Meta maps the keys to set to their value. A nil value unsets the key.
+	Meta map[string]*string
+}
+
+// NodeMetaResponse is the meta of a client node along with the static and
+// dynamic meta it is built from.
+type NodeMetaResponse struct {
+	// Meta is the static meta overlaid with the dynamic meta
+	Meta map[string]string
+
+	// Static is the meta set in the agent's configuration
+	Static map[string]string
+
+	// Dynamic is the meta applied at runtime. A nil value unsets the key.
+	Dynamic map[string]*string
+}
+
 // AgentMembersNameSort implements sort.Interface for []*AgentMembersNameSort
 // based on the Name, DC and Region
 type AgentMembersNameSort []*AgentMember
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/testutil"
 )
 
@@ -95,6 +96,69 @@ func TestAgent_Join(t *testing.T) {
 	}
 }
 
+func TestAgent_NodeMeta(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, func(c *testutil.TestServerConfig) {
+		c.DevMode = true
+	})
+	defer s.Stop()
+	a := c.Agent()
+
+	// Set a key and unset another one
+	out, err := a.ApplyNodeMeta(map[string]*string{
+		"rack":  helper.StringToPtr("r1"),
+		"other": nil,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out.Meta["rack"] != "r1" {
+		t.Fatalf("bad: %#v", out.Meta)
+	}
+	if _, ok := out.Dynamic["other"]; !ok {
+		t.Fatalf("expected unset key in dynamic meta: %#v", out.Dynamic)
+	}
+
+	// The changes are returned when reading the meta
+	meta, err := a.NodeMeta()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(meta.Meta, out.Meta) {
+		t.Fatalf("expected %#v; got %#v", out.Meta, meta.Meta)
+	}
+}
+
+func TestAgent_Metrics(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	a := c.Agent()
+
+	metrics, err := a.Metrics()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(metrics) == 0 {
+		t.Fatalf("expected metrics")
+	}
+}
+
+func TestAgent_Logs(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	a := c.Agent()
+
+	logs, err := a.Logs()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(logs) == 0 {
+		t.Fatalf("expected logs")
+	}
+}
+
 func TestAgent_Members(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t, nil, nil)
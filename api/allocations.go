@@ -12,6 +12,15 @@ var (
 	NodeDownErr = fmt.Errorf("node down")
 )
 
+const (
+	AllocClientStatusPending  = "pending"
+	AllocClientStatusRunning  = "running"
+	AllocClientStatusComplete = "complete"
+	AllocClientStatusFailed   = "failed"
+	AllocClientStatusLost     = "lost"
+	AllocClientStatusUnknown  = "unknown"
+)
+
 // Allocations is used to query the alloc-related endpoints.
 type Allocations struct {
 	client *Client
@@ -47,6 +56,27 @@ func (a *Allocations) Info(allocID string, q *QueryOptions) (*Allocation, *Query
 	return &resp, qm, nil
 }
 
+// WaitForCompletion blocks until the allocation has stopped running on its
+// client and returns its final state. The wait can be canceled using the
+// context of the query options.
+func (a *Allocations) WaitForCompletion(allocID string, q *QueryOptions) (*Allocation, error) {
+	wq := &QueryOptions{}
+	if q != nil {
+		*wq = *q
+	}
+
+	for {
+		alloc, qm, err := a.Info(allocID, wq)
+		if err != nil {
+			return nil, err
+		}
+		if alloc.ClientTerminalStatus() {
+			return alloc, nil
+		}
+		wq.WaitIndex = qm.LastIndex
+	}
+}
+
 func (a *Allocations) Stats(alloc *Allocation, q *QueryOptions) (*AllocResourceUsage, error) {
 	node, _, err := a.client.Nodes().Info(alloc.NodeID, q)
 	if err != nil {
@@ -88,6 +118,132 @@ func (a *Allocations) GC(alloc *Allocation, q *QueryOptions) error {
 	return err
 }
 
+// Stop stops the allocation and creates an evaluation so that the scheduler
+// replaces it. The ID of the evaluation is returned.
+func (a *Allocations) Stop(alloc *Allocation, q *WriteOptions) (*AllocStopResponse, error) {
+	var resp AllocStopResponse
+	wm, err := a.client.write("/v1/allocation/"+alloc.ID+"/stop", nil, &resp, q)
+	if err != nil {
+		return nil, err
+	}
+	resp.WriteMeta = *wm
+	return &resp, nil
+}
+
+// AllocStopResponse is the response to stopping an allocation.
+type AllocStopResponse struct {
+	// EvalID is the evaluation that replaces the stopped allocation.
+	EvalID          string
+	EvalCreateIndex uint64
+	WriteMeta
+}
+
+// Signal sends the signal to the named task of the allocation, or to all of
+// its tasks if the task is empty. The servers relay the signal to the client
+// running the allocation.
+func (a *Allocations) Signal(alloc *Allocation, q *WriteOptions, task, signal string) error {
+	req := AllocSignalRequest{
+		Task:   task,
+		Signal: signal,
+	}
+	_, err := a.client.write("/v1/allocation/"+alloc.ID+"/signal", &req, nil, q)
+	return err
+}
+
+// AllocSignalRequest is used to signal the tasks of an allocation.
+type AllocSignalRequest struct {
+	// Task is the task to signal. If empty, all tasks are signalled.
+	Task string
+
+	// Signal is the name of the signal to send, for example SIGHUP.
+	Signal string
+}
+
+// Restart restarts the named task of the allocation in place, or all of its
+// tasks if the task is empty. The request is sent directly to the client
+// running the allocation.
+func (a *Allocations) Restart(alloc *Allocation, task string, q *QueryOptions) error {
+	node, _, err := a.client.Nodes().Info(alloc.NodeID, q)
+	if err != nil {
+		return err
+	}
+	if node.Status == "down" {
+		return NodeDownErr
+	}
+	if node.HTTPAddr == "" {
+		return fmt.Errorf("http addr of the node where alloc %q is running is not advertised", alloc.ID)
+	}
+	client, err := NewClient(a.client.config.CopyConfig(node.HTTPAddr, node.TLSEnabled))
+	if err != nil {
+		return err
+	}
+
+	req := AllocRestartRequest{
+		Task: task,
+	}
+	_, err = client.write("/v1/client/allocation/"+alloc.ID+"/restart", &req, nil, nil)
+	return err
+}
+
+// Exec runs the command inside the running task of the allocation and
+// returns its output and exit code. The timeout bounds how long the command
+// may run, the client agent applying its own maximum if it is zero.
+func (a *Allocations) Exec(alloc *Allocation, task, command string, args []string,
+	timeout time.Duration, q *QueryOptions) (*AllocExecResponse, error) {
+
+	node, _, err := a.client.Nodes().Info(alloc.NodeID, q)
+	if err != nil {
+		return nil, err
+	}
+	if node.Status == "down" {
+		return nil, NodeDownErr
+	}
+	if node.HTTPAddr == "" {
+		return nil, fmt.Errorf("http addr of the node where alloc %q is running is not advertised", alloc.ID)
+	}
+	client, err := NewClient(a.client.config.CopyConfig(node.HTTPAddr, node.TLSEnabled))
+	if err != nil {
+		return nil, err
+	}
+
+	req := AllocExecRequest{
+		Task:    task,
+		Command: command,
+		Args:    args,
+		Timeout: timeout,
+	}
+	var resp AllocExecResponse
+	if _, err := client.write("/v1/client/allocation/"+alloc.ID+"/exec", &req, &resp, nil); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AllocExecRequest is used to run a command inside a task of an allocation.
+type AllocExecRequest struct {
+	// Task is the task to run the command in.
+	Task string
+
+	// Command and Args are the command to run and its arguments.
+	Command string
+	Args    []string
+
+	// Timeout bounds how long the command may run.
+	Timeout time.Duration
+}
+
+// AllocExecResponse is the result of a command run inside a task.
+type AllocExecResponse struct {
+	Output   string
+	ExitCode int
+}
+
+// AllocRestartRequest is used to restart the tasks of an allocation.
+type AllocRestartRequest struct {
+	// Task is the task to restart. If empty, all tasks are restarted.
+	Task string
+}
+
 // Allocation is used for serialization of allocations.
 type Allocation struct {
 	ID                 string
@@ -108,13 +264,26 @@ type Allocation struct {
 	TaskStates         map[string]*TaskState
 	DeploymentID       string
 	DeploymentStatus   *AllocDeploymentStatus
+	NetworkStatus      *AllocNetworkStatus
 	PreviousAllocation string
+	ReplacedAllocation string
 	CreateIndex        uint64
 	ModifyIndex        uint64
 	AllocModifyIndex   uint64
 	CreateTime         int64
 }
 
+// ClientTerminalStatus returns whether the allocation has stopped running on
+// its client, either because its tasks finished or because it was lost.
+func (a *Allocation) ClientTerminalStatus() bool {
+	switch a.ClientStatus {
+	case AllocClientStatusComplete, AllocClientStatusFailed, AllocClientStatusLost:
+		return true
+	default:
+		return false
+	}
+}
+
 // AllocationMetric is used to deserialize allocation metrics.
 type AllocationMetric struct {
 	NodesEvaluated     int
@@ -156,9 +325,17 @@ type AllocationListStub struct {
 // heatlhy.
 type AllocDeploymentStatus struct {
 	Healthy     *bool
+	Canary      bool
 	ModifyIndex uint64
 }
 
+// AllocNetworkStatus is the status of the group network of an allocation.
+type AllocNetworkStatus struct {
+	InterfaceName string
+	Address       string
+	AddressIPv6   string
+}
+
 // AllocIndexSort reverse sorts allocs by CreateIndex.
 type AllocIndexSort []*AllocationListStub
 
@@ -119,3 +119,19 @@ func TestAllocations_CreateIndexSort(t *testing.T) {
 		t.Fatalf("\n\n%#v\n\n%#v", allocs, expect)
 	}
 }
+
+func TestAllocations_ClientTerminalStatus(t *testing.T) {
+	t.Parallel()
+	for status, terminal := range map[string]bool{
+		AllocClientStatusPending:  false,
+		AllocClientStatusRunning:  false,
+		AllocClientStatusComplete: true,
+		AllocClientStatusFailed:   true,
+		AllocClientStatusLost:     true,
+	} {
+		alloc := &Allocation{ClientStatus: status}
+		if alloc.ClientTerminalStatus() != terminal {
+			t.Fatalf("status %q: expected terminal %v", status, terminal)
+		}
+	}
+}
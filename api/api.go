@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -39,8 +40,51 @@ type QueryOptions struct {
 	// If set, used as prefix for resource list searches
 	Prefix string
 
+	// Namespace restricts list queries to the objects of the namespace. If
+	// unset, objects of all namespaces are returned.
+	Namespace string
+
+	// PerPage is the number of entries to be returned by a list query. If
+	// unset, all entries are returned.
+	PerPage int32
+
+	// NextToken is the token used to resume a paginated list query. It is the
+	// QueryMeta.NextToken returned with the previous page.
+	NextToken string
+
+	// Filter is a boolean expression used to filter the results of a list
+	// query on the server, e.g. `Status == "running"`.
+	Filter string
+
 	// Set HTTP parameters on the query.
 	Params map[string]string
+
+	// AuthToken is the secret ID of the ACL token of the query. It overrides
+	// the SecretID of the Config.
+	AuthToken string
+
+	// ctx is an optional context used to cancel the query
+	ctx context.Context
+}
+
+// WithContext returns a copy of the query options using the given context to
+// cancel the query, including any retries or blocking wait.
+func (o *QueryOptions) WithContext(ctx context.Context) *QueryOptions {
+	o2 := new(QueryOptions)
+	if o != nil {
+		*o2 = *o
+	}
+	o2.ctx = ctx
+	return o2
+}
+
+// Context returns the context of the query, or the background context if none
+// was set.
+func (o *QueryOptions) Context() context.Context {
+	if o != nil && o.ctx != nil {
+		return o.ctx
+	}
+	return context.Background()
 }
 
 // WriteOptions are used to parameterize a write
@@ -48,6 +92,33 @@ type WriteOptions struct {
 	// Providing a datacenter overwrites the region provided
 	// by the Config
 	Region string
+
+	// AuthToken is the secret ID of the ACL token of the write. It overrides
+	// the SecretID of the Config.
+	AuthToken string
+
+	// ctx is an optional context used to cancel the write
+	ctx context.Context
+}
+
+// WithContext returns a copy of the write options using the given context to
+// cancel the write.
+func (o *WriteOptions) WithContext(ctx context.Context) *WriteOptions {
+	o2 := new(WriteOptions)
+	if o != nil {
+		*o2 = *o
+	}
+	o2.ctx = ctx
+	return o2
+}
+
+// Context returns the context of the write, or the background context if none
+// was set.
+func (o *WriteOptions) Context() context.Context {
+	if o != nil && o.ctx != nil {
+		return o.ctx
+	}
+	return context.Background()
 }
 
 // QueryMeta is used to return meta data about a query
@@ -65,6 +136,10 @@ type QueryMeta struct {
 
 	// How long did the request take
 	RequestTime time.Duration
+
+	// NextToken is used to request the next page of a paginated list query.
+	// It is empty if there are no more results.
+	NextToken string
 }
 
 // WriteMeta is used to return meta data about a write
@@ -94,6 +169,9 @@ type Config struct {
 	// Region to use. If not provided, the default agent region is used.
 	Region string
 
+	// SecretID is the secret ID of the ACL token the requests are made with
+	SecretID string
+
 	// HttpClient is the client to use. Default will be
 	// used if not provided.
 	HttpClient *http.Client
@@ -108,6 +186,48 @@ type Config struct {
 	// TLSConfig provides the various TLS related configurations for the http
 	// client
 	TLSConfig *TLSConfig
+
+	// Retry configures how idempotent requests are retried when they fail. If
+	// nil, requests are not retried.
+	Retry *RetryConfig
+}
+
+// RetryConfig configures the retries of idempotent requests, such as queries
+// and blocking queries. Requests are retried on connection errors, rate
+// limiting and server errors, waiting an exponentially increasing time
+// between attempts.
+type RetryConfig struct {
+	// MaxRetries is the maximum number of times a request is retried.
+	MaxRetries int
+
+	// MinWait is the time waited before the first retry. It defaults to one
+	// second.
+	MinWait time.Duration
+
+	// MaxWait bounds the time waited between retries. It defaults to thirty
+	// seconds.
+	MaxWait time.Duration
+}
+
+// backoff returns the time to wait before retrying after the given attempt,
+// starting at zero.
+func (r *RetryConfig) backoff(attempt int) time.Duration {
+	min, max := r.MinWait, r.MaxWait
+	if min <= 0 {
+		min = time.Second
+	}
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+
+	wait := min
+	for i := 0; i < attempt && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
 }
 
 // CopyConfig copies the configuration with a new address
@@ -119,10 +239,12 @@ func (c *Config) CopyConfig(address string, tlsEnabled bool) *Config {
 	config := &Config{
 		Address:    fmt.Sprintf("%s://%s", scheme, address),
 		Region:     c.Region,
+		SecretID:   c.SecretID,
 		HttpClient: c.HttpClient,
 		HttpAuth:   c.HttpAuth,
 		WaitTime:   c.WaitTime,
 		TLSConfig:  c.TLSConfig,
+		Retry:      c.Retry,
 	}
 
 	return config
@@ -169,6 +291,9 @@ func DefaultConfig() *Config {
 	if addr := os.Getenv("NOMAD_ADDR"); addr != "" {
 		config.Address = addr
 	}
+	if token := os.Getenv("NOMAD_TOKEN"); token != "" {
+		config.SecretID = token
+	}
 	if auth := os.Getenv("NOMAD_HTTP_AUTH"); auth != "" {
 		var username, password string
 		if strings.Contains(auth, ":") {
@@ -285,6 +410,11 @@ func (c *Client) SetRegion(region string) {
 	c.config.Region = region
 }
 
+// SetSecretID sets the secret ID of the ACL token the requests are made with
+func (c *Client) SetSecretID(secretID string) {
+	c.config.SecretID = secretID
+}
+
 // request is used to help build up a request
 type request struct {
 	config *Config
@@ -293,6 +423,8 @@ type request struct {
 	params url.Values
 	body   io.Reader
 	obj    interface{}
+	ctx    context.Context
+	token  string
 }
 
 // setQueryOptions is used to annotate the request with
@@ -301,9 +433,13 @@ func (r *request) setQueryOptions(q *QueryOptions) {
 	if q == nil {
 		return
 	}
+	r.ctx = q.ctx
 	if q.Region != "" {
 		r.params.Set("region", q.Region)
 	}
+	if q.AuthToken != "" {
+		r.token = q.AuthToken
+	}
 	if q.AllowStale {
 		r.params.Set("stale", "")
 	}
@@ -316,6 +452,18 @@ func (r *request) setQueryOptions(q *QueryOptions) {
 	if q.Prefix != "" {
 		r.params.Set("prefix", q.Prefix)
 	}
+	if q.Namespace != "" {
+		r.params.Set("namespace", q.Namespace)
+	}
+	if q.PerPage != 0 {
+		r.params.Set("per_page", strconv.FormatInt(int64(q.PerPage), 10))
+	}
+	if q.NextToken != "" {
+		r.params.Set("next_token", q.NextToken)
+	}
+	if q.Filter != "" {
+		r.params.Set("filter", q.Filter)
+	}
 	for k, v := range q.Params {
 		r.params.Set(k, v)
 	}
@@ -332,9 +480,13 @@ func (r *request) setWriteOptions(q *WriteOptions) {
 	if q == nil {
 		return
 	}
+	r.ctx = q.ctx
 	if q.Region != "" {
 		r.params.Set("region", q.Region)
 	}
+	if q.AuthToken != "" {
+		r.token = q.AuthToken
+	}
 }
 
 // toHTTP converts the request to an HTTP request
@@ -366,10 +518,18 @@ func (r *request) toHTTP() (*http.Request, error) {
 		req.SetBasicAuth(r.config.HttpAuth.Username, r.config.HttpAuth.Password)
 	}
 
+	if r.token != "" {
+		req.Header.Set("X-Nomad-Token", r.token)
+	}
+
 	req.Header.Add("Accept-Encoding", "gzip")
 	req.URL.Host = r.url.Host
 	req.URL.Scheme = r.url.Scheme
 	req.Host = r.url.Host
+
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
 	return req, nil
 }
 
@@ -390,6 +550,7 @@ func (c *Client) newRequest(method, path string) (*request, error) {
 			Path:   u.Path,
 		},
 		params: make(map[string][]string),
+		token:  c.config.SecretID,
 	}
 	if c.config.Region != "" {
 		r.params.Set("region", c.config.Region)
@@ -463,6 +624,57 @@ func (c *Client) doRequest(r *request) (time.Duration, *http.Response, error) {
 	return diff, resp, err
 }
 
+// doIdempotentRequest runs a request that can safely be sent several times,
+// retrying it as configured by the client and checking for a 200.
+func (c *Client) doIdempotentRequest(r *request) (time.Duration, *http.Response, error) {
+	retry := c.config.Retry
+	for attempt := 0; ; attempt++ {
+		rtt, resp, err := requireOK(c.doRequest(r))
+		if err == nil || retry == nil || attempt >= retry.MaxRetries || !r.retryable(err) {
+			return rtt, resp, err
+		}
+
+		wait := retry.backoff(attempt)
+		if e, ok := err.(*UnexpectedResponseError); ok && e.retryAfter > wait {
+			wait = e.retryAfter
+		}
+		if err := r.wait(wait); err != nil {
+			return rtt, nil, err
+		}
+	}
+}
+
+// retryable returns whether the request can be retried after the given error.
+func (r *request) retryable(err error) bool {
+	if r.ctx != nil && r.ctx.Err() != nil {
+		return false
+	}
+
+	if e, ok := err.(*UnexpectedResponseError); ok {
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+	}
+
+	// Connection errors
+	return true
+}
+
+// wait waits for the given duration or until the request is canceled.
+func (r *request) wait(d time.Duration) error {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // rawQuery makes a GET request to the specified endpoint but returns just the
 // response body.
 func (c *Client) rawQuery(endpoint string, q *QueryOptions) (io.ReadCloser, error) {
@@ -471,7 +683,7 @@ func (c *Client) rawQuery(endpoint string, q *QueryOptions) (io.ReadCloser, erro
 		return nil, err
 	}
 	r.setQueryOptions(q)
-	_, resp, err := requireOK(c.doRequest(r))
+	_, resp, err := c.doIdempotentRequest(r)
 	if err != nil {
 		return nil, err
 	}
@@ -488,7 +700,7 @@ func (c *Client) query(endpoint string, out interface{}, q *QueryOptions) (*Quer
 		return nil, err
 	}
 	r.setQueryOptions(q)
-	rtt, resp, err := requireOK(c.doRequest(r))
+	rtt, resp, err := c.doIdempotentRequest(r)
 	if err != nil {
 		return nil, err
 	}
@@ -580,6 +792,9 @@ func parseQueryMeta(resp *http.Response, q *QueryMeta) error {
 	default:
 		q.KnownLeader = false
 	}
+
+	// Parse the X-Nomad-NextToken
+	q.NextToken = header.Get("X-Nomad-NextToken")
 	return nil
 }
 
@@ -624,7 +839,55 @@ func requireOK(d time.Duration, resp *http.Response, e error) (time.Duration, *h
 		var buf bytes.Buffer
 		io.Copy(&buf, resp.Body)
 		resp.Body.Close()
-		return d, nil, fmt.Errorf("Unexpected response code: %d (%s)", resp.StatusCode, buf.Bytes())
+
+		err := &UnexpectedResponseError{
+			StatusCode: resp.StatusCode,
+			Body:       buf.String(),
+		}
+		if secs, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil {
+			err.retryAfter = time.Duration(secs) * time.Second
+		}
+		return d, nil, err
 	}
 	return d, resp, nil
 }
+
+// UnexpectedResponseError is returned when the API responds with a status
+// code other than 200.
+type UnexpectedResponseError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Body is the body of the response, usually the error message
+	Body string
+
+	// retryAfter is the value of the Retry-After header, if any
+	retryAfter time.Duration
+}
+
+func (e *UnexpectedResponseError) Error() string {
+	return fmt.Sprintf("Unexpected response code: %d (%s)", e.StatusCode, e.Body)
+}
+
+// IsNotFound returns whether the error is an API response indicating that the
+// requested object doesn't exist.
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsForbidden returns whether the error is an API response indicating that
+// the request isn't allowed.
+func IsForbidden(err error) bool {
+	return hasStatusCode(err, http.StatusForbidden)
+}
+
+// IsConflict returns whether the error is an API response indicating that the
+// request conflicts with the current state, such as a check-and-set failure.
+func IsConflict(err error) bool {
+	return hasStatusCode(err, http.StatusConflict)
+}
+
+func hasStatusCode(err error, code int) bool {
+	e, ok := err.(*UnexpectedResponseError)
+	return ok && e.StatusCode == code
+}
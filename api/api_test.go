@@ -1,11 +1,13 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -90,6 +92,123 @@ func TestRequestTime(t *testing.T) {
 	}
 }
 
+func TestQuery_Retry(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "No cluster leader", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	conf := DefaultConfig()
+	conf.Address = srv.URL
+	conf.Retry = &RetryConfig{MaxRetries: 2, MinWait: time.Millisecond}
+	client, err := NewClient(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var out interface{}
+	if _, err := client.query("/", &out, nil); err != nil {
+		t.Fatalf("query err: %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("expected 3 calls; got %d", n)
+	}
+
+	// Writes are never retried
+	atomic.StoreInt32(&calls, 0)
+	if _, err := client.write("/", nil, &out, nil); err == nil {
+		t.Fatalf("expected write error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected 1 call; got %d", n)
+	}
+}
+
+func TestQuery_RetryNotFound(t *testing.T) {
+	t.Parallel()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "job not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	conf := DefaultConfig()
+	conf.Address = srv.URL
+	conf.Retry = &RetryConfig{MaxRetries: 3, MinWait: time.Millisecond}
+	client, err := NewClient(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var out interface{}
+	_, err = client.query("/", &out, nil)
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found error; got %v", err)
+	}
+	if IsConflict(err) || IsForbidden(err) {
+		t.Fatalf("bad error type: %v", err)
+	}
+	if !strings.Contains(err.Error(), "Unexpected response code: 404 (job not found") {
+		t.Fatalf("bad error message: %v", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected 1 call; got %d", n)
+	}
+}
+
+func TestQuery_Context(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	conf := DefaultConfig()
+	conf.Address = srv.URL
+	conf.Retry = &RetryConfig{MaxRetries: 100, MinWait: time.Hour}
+	client, err := NewClient(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var out interface{}
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.query("/", &out, (&QueryOptions{}).WithContext(ctx))
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("expected deadline exceeded; got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("query not canceled")
+	}
+}
+
+func TestRetryConfig_Backoff(t *testing.T) {
+	t.Parallel()
+	r := &RetryConfig{MinWait: time.Second, MaxWait: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, exp := range expected {
+		if d := r.backoff(i); d != exp {
+			t.Fatalf("attempt %d: expected %v; got %v", i, exp, d)
+		}
+	}
+}
+
 func TestDefaultConfig_env(t *testing.T) {
 	t.Parallel()
 	url := "http://1.2.3.4:5678"
@@ -2,6 +2,7 @@ package api
 
 import (
 	"sort"
+	"time"
 )
 
 // Deployments is used to query the deployments endpoints.
@@ -124,28 +125,31 @@ func (d *Deployments) SetAllocHealth(deploymentID string, healthy, unhealthy []s
 
 // Deployment is used to serialize an deployment.
 type Deployment struct {
-	ID                string
-	JobID             string
-	JobVersion        uint64
-	JobModifyIndex    uint64
-	JobCreateIndex    uint64
-	TaskGroups        map[string]*DeploymentState
-	Status            string
-	StatusDescription string
-	CreateIndex       uint64
-	ModifyIndex       uint64
+	ID                 string
+	JobID              string
+	JobVersion         uint64
+	JobModifyIndex     uint64
+	JobCreateIndex     uint64
+	TaskGroups         map[string]*DeploymentState
+	Status             string
+	StatusDescription  string
+	RevertedJobVersion *uint64
+	CreateIndex        uint64
+	ModifyIndex        uint64
 }
 
 // DeploymentState tracks the state of a deployment for a given task group.
 type DeploymentState struct {
-	PlacedCanaries  []string
-	AutoRevert      bool
-	Promoted        bool
-	DesiredCanaries int
-	DesiredTotal    int
-	PlacedAllocs    int
-	HealthyAllocs   int
-	UnhealthyAllocs int
+	PlacedCanaries   []string
+	AutoRevert       bool
+	AutoPromote      bool
+	ProgressDeadline time.Duration
+	Promoted         bool
+	DesiredCanaries  int
+	DesiredTotal     int
+	PlacedAllocs     int
+	HealthyAllocs    int
+	UnhealthyAllocs  int
 }
 
 // DeploymentIndexSort is a wrapper to sort deployments by CreateIndex. We
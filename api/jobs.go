@@ -7,7 +7,6 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/gorhill/cronexpr"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -52,26 +51,42 @@ func (j *Jobs) Validate(job *Job, q *WriteOptions) (*JobValidateResponse, *Write
 // Register is used to register a new job. It returns the ID
 // of the evaluation, along with any errors encountered.
 func (j *Jobs) Register(job *Job, q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
-
-	var resp JobRegisterResponse
-
-	req := &RegisterJobRequest{Job: job}
-	wm, err := j.client.write("/v1/jobs", req, &resp, q)
-	if err != nil {
-		return nil, nil, err
-	}
-	return &resp, wm, nil
+	return j.RegisterOpts(job, nil, q)
 }
 
 // EnforceRegister is used to register a job enforcing its job modify index.
 func (j *Jobs) EnforceRegister(job *Job, modifyIndex uint64, q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
+	opts := &RegisterOptions{
+		EnforceIndex: true,
+		ModifyIndex:  modifyIndex,
+	}
+	return j.RegisterOpts(job, opts, q)
+}
+
+// RegisterOptions is used to pass through job registration parameters
+type RegisterOptions struct {
+	// EnforceIndex registers the job only if ModifyIndex is its job modify
+	// index, or if it doesn't exist when ModifyIndex is zero.
+	EnforceIndex bool
+	ModifyIndex  uint64
+
+	// PolicyOverride admits the job despite the denials of soft-mandatory
+	// admission policies. A PolicyOverrideJustification is required.
+	PolicyOverride              bool
+	PolicyOverrideJustification string
+}
+
+// RegisterOpts is used to register a job with the given options.
+func (j *Jobs) RegisterOpts(job *Job, opts *RegisterOptions, q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
 
 	var resp JobRegisterResponse
 
-	req := &RegisterJobRequest{
-		Job:            job,
-		EnforceIndex:   true,
-		JobModifyIndex: modifyIndex,
+	req := &RegisterJobRequest{Job: job}
+	if opts != nil {
+		req.EnforceIndex = opts.EnforceIndex
+		req.JobModifyIndex = opts.ModifyIndex
+		req.PolicyOverride = opts.PolicyOverride
+		req.PolicyOverrideJustification = opts.PolicyOverrideJustification
 	}
 	wm, err := j.client.write("/v1/jobs", req, &resp, q)
 	if err != nil {
@@ -118,6 +133,17 @@ func (j *Jobs) Versions(jobID string, diffs bool, q *QueryOptions) ([]*Job, []*J
 	return resp.Versions, resp.Diffs, qm, nil
 }
 
+// VersionsDiff is used to retrieve all versions of a particular job given its
+// unique ID, along with the diff of each version against the given version.
+func (j *Jobs) VersionsDiff(jobID string, diffVersion uint64, q *QueryOptions) ([]*Job, []*JobDiff, *QueryMeta, error) {
+	var resp JobVersionsResponse
+	qm, err := j.client.query(fmt.Sprintf("/v1/job/%s/versions?diffs=true&diff_version=%d", jobID, diffVersion), &resp, q)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return resp.Versions, resp.Diffs, qm, nil
+}
+
 // Allocations is used to return the allocs for a given job ID.
 func (j *Jobs) Allocations(jobID string, allAllocs bool, q *QueryOptions) ([]*AllocationListStub, *QueryMeta, error) {
 	var resp []*AllocationListStub
@@ -173,6 +199,17 @@ func (j *Jobs) Evaluations(jobID string, q *QueryOptions) ([]*Evaluation, *Query
 	return resp, qm, nil
 }
 
+// Dependencies is used to query the jobs connected to the given job ID by
+// dependencies, ordered so that jobs come after their dependencies.
+func (j *Jobs) Dependencies(jobID string, q *QueryOptions) ([]*JobDAGNode, *QueryMeta, error) {
+	var resp []*JobDAGNode
+	qm, err := j.client.query("/v1/job/"+jobID+"/dependencies", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
 // Deregister is used to remove an existing job. If purge is set to true, the job
 // is deregistered and purged from the system versus still being queryable and
 // eventually GC'ed from the system. Most callers should not specify purge.
@@ -197,14 +234,24 @@ func (j *Jobs) ForceEvaluate(jobID string, q *WriteOptions) (string, *WriteMeta,
 
 // PeriodicForce spawns a new instance of the periodic job and returns the eval ID
 func (j *Jobs) PeriodicForce(jobID string, q *WriteOptions) (string, *WriteMeta, error) {
-	var resp periodicForceResponse
-	wm, err := j.client.write("/v1/job/"+jobID+"/periodic/force", nil, &resp, q)
+	resp, wm, err := j.PeriodicForceLaunch(jobID, q)
 	if err != nil {
 		return "", nil, err
 	}
 	return resp.EvalID, wm, nil
 }
 
+// PeriodicForceLaunch spawns a new instance of the periodic job and returns
+// the launched child job and its evaluation.
+func (j *Jobs) PeriodicForceLaunch(jobID string, q *WriteOptions) (*PeriodicForceResponse, *WriteMeta, error) {
+	var resp PeriodicForceResponse
+	wm, err := j.client.write("/v1/job/"+jobID+"/periodic/force", nil, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
 func (j *Jobs) Plan(job *Job, diff bool, q *WriteOptions) (*JobPlanResponse, *WriteMeta, error) {
 	if job == nil {
 		return nil, nil, fmt.Errorf("must pass non-nil job")
@@ -234,13 +281,19 @@ func (j *Jobs) Summary(jobID string, q *QueryOptions) (*JobSummary, *QueryMeta,
 
 func (j *Jobs) Dispatch(jobID string, meta map[string]string,
 	payload []byte, q *WriteOptions) (*JobDispatchResponse, *WriteMeta, error) {
-	var resp JobDispatchResponse
 	req := &JobDispatchRequest{
 		JobID:   jobID,
 		Meta:    meta,
 		Payload: payload,
 	}
-	wm, err := j.client.write("/v1/job/"+jobID+"/dispatch", req, &resp, q)
+	return j.DispatchOpts(req, q)
+}
+
+// DispatchOpts is used to dispatch a new job using all the options of the
+// dispatch request.
+func (j *Jobs) DispatchOpts(req *JobDispatchRequest, q *WriteOptions) (*JobDispatchResponse, *WriteMeta, error) {
+	var resp JobDispatchResponse
+	wm, err := j.client.write("/v1/job/"+req.JobID+"/dispatch", req, &resp, q)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -253,13 +306,19 @@ func (j *Jobs) Dispatch(jobID string, meta map[string]string,
 func (j *Jobs) Revert(jobID string, version uint64, enforcePriorVersion *uint64,
 	q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
 
-	var resp JobRegisterResponse
 	req := &JobRevertRequest{
 		JobID:               jobID,
 		JobVersion:          version,
 		EnforcePriorVersion: enforcePriorVersion,
 	}
-	wm, err := j.client.write("/v1/job/"+jobID+"/revert", req, &resp, q)
+	return j.RevertOpts(req, q)
+}
+
+// RevertOpts is used to revert a job to a prior version using all the
+// options of the revert request.
+func (j *Jobs) RevertOpts(req *JobRevertRequest, q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
+	var resp JobRegisterResponse
+	wm, err := j.client.write("/v1/job/"+req.JobID+"/revert", req, &resp, q)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -283,20 +342,61 @@ func (j *Jobs) Stable(jobID string, version uint64, stable bool,
 	return &resp, wm, nil
 }
 
-// periodicForceResponse is used to deserialize a force response
-type periodicForceResponse struct {
-	EvalID string
+// UpdatePriority is used to change the priority of a job without changing its
+// placements.
+func (j *Jobs) UpdatePriority(jobID string, priority int,
+	q *WriteOptions) (*JobUpdatePriorityResponse, *WriteMeta, error) {
+
+	var resp JobUpdatePriorityResponse
+	req := &JobUpdatePriorityRequest{
+		JobID:    jobID,
+		Priority: priority,
+	}
+	wm, err := j.client.write("/v1/job/"+jobID+"/priority", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// Scale is used to change the count of a task group of a job.
+func (j *Jobs) Scale(jobID, group string, count int,
+	q *WriteOptions) (*JobRegisterResponse, *WriteMeta, error) {
+
+	var resp JobRegisterResponse
+	req := &JobScaleRequest{
+		JobID:     jobID,
+		TaskGroup: group,
+		Count:     count,
+	}
+	wm, err := j.client.write("/v1/job/"+jobID+"/scale", req, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, wm, nil
+}
+
+// PeriodicForceResponse is used to deserialize a force response
+type PeriodicForceResponse struct {
+	EvalID          string
+	EvalCreateIndex uint64
+
+	// JobID is the ID of the child job that was launched
+	JobID string
+	WriteMeta
 }
 
 // UpdateStrategy defines a task groups update strategy.
 type UpdateStrategy struct {
-	Stagger         *time.Duration `mapstructure:"stagger"`
-	MaxParallel     *int           `mapstructure:"max_parallel"`
-	HealthCheck     *string        `mapstructure:"health_check"`
-	MinHealthyTime  *time.Duration `mapstructure:"min_healthy_time"`
-	HealthyDeadline *time.Duration `mapstructure:"healthy_deadline"`
-	AutoRevert      *bool          `mapstructure:"auto_revert"`
-	Canary          *int           `mapstructure:"canary"`
+	Stagger          *time.Duration `mapstructure:"stagger"`
+	MaxParallel      *int           `mapstructure:"max_parallel"`
+	HealthCheck      *string        `mapstructure:"health_check"`
+	MinHealthyTime   *time.Duration `mapstructure:"min_healthy_time"`
+	HealthyDeadline  *time.Duration `mapstructure:"healthy_deadline"`
+	AutoRevert       *bool          `mapstructure:"auto_revert"`
+	Canary           *int           `mapstructure:"canary"`
+	AutoPromote      *bool          `mapstructure:"auto_promote"`
+	ProgressDeadline *time.Duration `mapstructure:"progress_deadline"`
 }
 
 func (u *UpdateStrategy) Copy() *UpdateStrategy {
@@ -334,6 +434,14 @@ func (u *UpdateStrategy) Copy() *UpdateStrategy {
 		copy.Canary = helper.IntToPtr(*u.Canary)
 	}
 
+	if u.AutoPromote != nil {
+		copy.AutoPromote = helper.BoolToPtr(*u.AutoPromote)
+	}
+
+	if u.ProgressDeadline != nil {
+		copy.ProgressDeadline = helper.TimeToPtr(*u.ProgressDeadline)
+	}
+
 	return copy
 }
 
@@ -369,6 +477,14 @@ func (u *UpdateStrategy) Merge(o *UpdateStrategy) {
 	if o.Canary != nil {
 		u.Canary = helper.IntToPtr(*o.Canary)
 	}
+
+	if o.AutoPromote != nil {
+		u.AutoPromote = helper.BoolToPtr(*o.AutoPromote)
+	}
+
+	if o.ProgressDeadline != nil {
+		u.ProgressDeadline = helper.TimeToPtr(*o.ProgressDeadline)
+	}
 }
 
 func (u *UpdateStrategy) Canonicalize() {
@@ -401,15 +517,25 @@ func (u *UpdateStrategy) Canonicalize() {
 	if u.Canary == nil {
 		u.Canary = helper.IntToPtr(d.Canary)
 	}
+
+	if u.AutoPromote == nil {
+		u.AutoPromote = helper.BoolToPtr(d.AutoPromote)
+	}
+
+	if u.ProgressDeadline == nil {
+		u.ProgressDeadline = helper.TimeToPtr(d.ProgressDeadline)
+	}
 }
 
 // PeriodicConfig is for serializing periodic config for a job.
 type PeriodicConfig struct {
 	Enabled         *bool
 	Spec            *string
+	Specs           []string `mapstructure:"crons"`
 	SpecType        *string
 	ProhibitOverlap *bool   `mapstructure:"prohibit_overlap"`
 	TimeZone        *string `mapstructure:"time_zone"`
+	DSTMode         *string `mapstructure:"dst_mode"`
 }
 
 func (p *PeriodicConfig) Canonicalize() {
@@ -436,9 +562,15 @@ func (p *PeriodicConfig) Canonicalize() {
 // passed time.
 func (p *PeriodicConfig) Next(fromTime time.Time) time.Time {
 	if *p.SpecType == PeriodicSpecCron {
-		if e, err := cronexpr.Parse(*p.Spec); err == nil {
-			return e.Next(fromTime)
+		sp := &structs.PeriodicConfig{
+			SpecType: structs.PeriodicSpecCron,
+			Spec:     *p.Spec,
+			Specs:    p.Specs,
+		}
+		if p.DSTMode != nil {
+			sp.DSTMode = *p.DSTMode
 		}
+		return sp.Next(fromTime)
 	}
 
 	return time.Time{}
@@ -454,38 +586,116 @@ func (p *PeriodicConfig) GetLocation() (*time.Location, error) {
 
 // ParameterizedJobConfig is used to configure the parameterized job.
 type ParameterizedJobConfig struct {
-	Payload      string
-	MetaRequired []string `mapstructure:"meta_required"`
-	MetaOptional []string `mapstructure:"meta_optional"`
+	Payload       string
+	MetaRequired  []string                       `mapstructure:"meta_required"`
+	MetaOptional  []string                       `mapstructure:"meta_optional"`
+	MetaSchema    map[string]*DispatchMetaSchema `mapstructure:"meta_schema"`
+	PayloadSchema *DispatchPayloadSchema         `mapstructure:"payload_schema"`
+}
+
+// DispatchMetaSchema constrains the value of a dispatch metadata key.
+type DispatchMetaSchema struct {
+	Type    string
+	Pattern string
+	Allowed []string
+}
+
+// DispatchPayloadSchema constrains the payload of dispatch requests.
+type DispatchPayloadSchema struct {
+	MaxSize        int      `mapstructure:"max_size"`
+	ContentType    string   `mapstructure:"content_type"`
+	RequiredFields []string `mapstructure:"required_fields"`
+}
+
+// JobNotification is a webhook called when a batch job, or a job dispatched or
+// launched from it, reaches a terminal state.
+type JobNotification struct {
+	Webhook *string           `mapstructure:"webhook"`
+	Method  *string           `mapstructure:"method"`
+	Headers map[string]string `mapstructure:"headers"`
+	Payload *string           `mapstructure:"payload"`
+	Timeout *time.Duration    `mapstructure:"timeout"`
+}
+
+func (n *JobNotification) Canonicalize() {
+	if n.Webhook == nil {
+		n.Webhook = helper.StringToPtr("")
+	}
+	if n.Method == nil {
+		n.Method = helper.StringToPtr("POST")
+	}
+	if n.Payload == nil {
+		n.Payload = helper.StringToPtr("")
+	}
+	if n.Timeout == nil {
+		n.Timeout = helper.TimeToPtr(10 * time.Second)
+	}
+}
+
+// JobGCConfig is used to override the server's garbage collection thresholds
+// for a job.
+type JobGCConfig struct {
+	AllocThreshold      *time.Duration `mapstructure:"alloc_threshold"`
+	EvalThreshold       *time.Duration `mapstructure:"eval_threshold"`
+	DeploymentThreshold *time.Duration `mapstructure:"deployment_threshold"`
+}
+
+func (g *JobGCConfig) Canonicalize() {
+	if g.AllocThreshold == nil {
+		g.AllocThreshold = helper.TimeToPtr(0)
+	}
+	if g.EvalThreshold == nil {
+		g.EvalThreshold = helper.TimeToPtr(0)
+	}
+	if g.DeploymentThreshold == nil {
+		g.DeploymentThreshold = helper.TimeToPtr(0)
+	}
+}
+
+// JobDAGNode is a job of a graph of job dependencies.
+type JobDAGNode struct {
+	JobID     string
+	Status    string
+	DependsOn []string
+	Held      bool
 }
 
 // Job is used to serialize a job.
 type Job struct {
-	Stop              *bool
-	Region            *string
-	ID                *string
-	ParentID          *string
-	Name              *string
-	Type              *string
-	Priority          *int
-	AllAtOnce         *bool `mapstructure:"all_at_once"`
-	Datacenters       []string
-	Constraints       []*Constraint
-	TaskGroups        []*TaskGroup
-	Update            *UpdateStrategy
-	Periodic          *PeriodicConfig
-	ParameterizedJob  *ParameterizedJobConfig
-	Payload           []byte
-	Meta              map[string]string
-	VaultToken        *string `mapstructure:"vault_token"`
-	Status            *string
-	StatusDescription *string
-	Stable            *bool
-	Version           *uint64
-	SubmitTime        *int64
-	CreateIndex       *uint64
-	ModifyIndex       *uint64
-	JobModifyIndex    *uint64
+	Stop                        *bool
+	Region                      *string
+	ID                          *string
+	ParentID                    *string
+	Namespace                   *string
+	Name                        *string
+	Type                        *string
+	Priority                    *int
+	AllAtOnce                   *bool `mapstructure:"all_at_once"`
+	Datacenters                 []string
+	DependsOn                   []string `mapstructure:"depends_on"`
+	Constraints                 []*Constraint
+	TaskGroups                  []*TaskGroup
+	Update                      *UpdateStrategy
+	Periodic                    *PeriodicConfig
+	ParameterizedJob            *ParameterizedJobConfig
+	GC                          *JobGCConfig
+	Notification                *JobNotification
+	Payload                     []byte
+	Meta                        map[string]string
+	VaultToken                  *string `mapstructure:"vault_token"`
+	DispatchIdempotencyToken    *string
+	Status                      *string
+	StatusDescription           *string
+	Stable                      *bool
+	Version                     *uint64
+	SubmitTime                  *int64
+	RevertVersion               *uint64
+	RevertReason                *string
+	PolicyOverrideJustification *string
+	NoPlacementChange           *bool
+	CreateIndex                 *uint64
+	ModifyIndex                 *uint64
+	JobModifyIndex              *uint64
 }
 
 // IsPeriodic returns whether a job is periodic.
@@ -508,6 +718,9 @@ func (j *Job) Canonicalize() {
 	if j.ParentID == nil {
 		j.ParentID = helper.StringToPtr("")
 	}
+	if j.Namespace == nil {
+		j.Namespace = helper.StringToPtr("default")
+	}
 	if j.Priority == nil {
 		j.Priority = helper.IntToPtr(50)
 	}
@@ -553,6 +766,12 @@ func (j *Job) Canonicalize() {
 	if j.Update != nil {
 		j.Update.Canonicalize()
 	}
+	if j.GC != nil {
+		j.GC.Canonicalize()
+	}
+	if j.Notification != nil {
+		j.Notification.Canonicalize()
+	}
 
 	for _, tg := range j.TaskGroups {
 		tg.Canonicalize(j)
@@ -594,6 +813,7 @@ type TaskGroupSummary struct {
 	Running  int
 	Starting int
 	Lost     int
+	Unknown  int
 }
 
 // JobListStub is used to return a subset of information about
@@ -601,6 +821,7 @@ type TaskGroupSummary struct {
 type JobListStub struct {
 	ID                string
 	ParentID          string
+	Namespace         string
 	Name              string
 	Type              string
 	Priority          int
@@ -692,6 +913,9 @@ func (j *Job) AddPeriodicConfig(cfg *PeriodicConfig) *Job {
 type WriteRequest struct {
 	// The target region for this write
 	Region string
+
+	// SecretID is the secret ID of the ACL token of the write
+	SecretID string
 }
 
 // JobValidateRequest is used to validate a job
@@ -729,6 +953,10 @@ type JobRevertRequest struct {
 	// version before reverting.
 	EnforcePriorVersion *uint64
 
+	// RequireStable if set only allows reverting to a version of the job
+	// that is marked as stable.
+	RequireStable bool
+
 	WriteRequest
 }
 
@@ -741,14 +969,29 @@ type JobRegisterRequest struct {
 	EnforceIndex   bool
 	JobModifyIndex uint64
 
+	// PolicyOverride admits the job despite the denials of soft-mandatory
+	// admission policies, given a PolicyOverrideJustification.
+	PolicyOverride              bool
+	PolicyOverrideJustification string
+
 	WriteRequest
 }
 
 // RegisterJobRequest is used to serialize a job registration
 type RegisterJobRequest struct {
-	Job            *Job
-	EnforceIndex   bool   `json:",omitempty"`
-	JobModifyIndex uint64 `json:",omitempty"`
+	Job                         *Job
+	EnforceIndex                bool   `json:",omitempty"`
+	JobModifyIndex              uint64 `json:",omitempty"`
+	PolicyOverride              bool   `json:",omitempty"`
+	PolicyOverrideJustification string `json:",omitempty"`
+}
+
+// JobScaleRequest is used to change the count of a task group of a job.
+type JobScaleRequest struct {
+	JobID     string
+	TaskGroup string
+	Count     int
+	WriteRequest
 }
 
 // JobRegisterResponse is used to respond to a job registration
@@ -848,6 +1091,14 @@ type JobDispatchRequest struct {
 	JobID   string
 	Payload []byte
 	Meta    map[string]string
+
+	// PayloadCompressed marks the payload as already snappy compressed.
+	PayloadCompressed bool
+
+	// IdempotencyToken deduplicates dispatches. If a job was already
+	// dispatched with the token, it is returned instead of dispatching
+	// another one.
+	IdempotencyToken string
 }
 
 type JobDispatchResponse struct {
@@ -881,3 +1132,18 @@ type JobStabilityResponse struct {
 	JobModifyIndex uint64
 	WriteMeta
 }
+
+// JobUpdatePriorityRequest is used to change the priority of a job.
+type JobUpdatePriorityRequest struct {
+	JobID    string
+	Priority int
+	WriteRequest
+}
+
+// JobUpdatePriorityResponse is the response when changing the priority of a
+// job.
+type JobUpdatePriorityResponse struct {
+	// JobVersion is the version of the job with the new priority.
+	JobVersion uint64
+	WriteMeta
+}
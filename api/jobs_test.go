@@ -106,6 +106,7 @@ func TestJobs_Canonicalize(t *testing.T) {
 				Region:            helper.StringToPtr("global"),
 				Type:              helper.StringToPtr("service"),
 				ParentID:          helper.StringToPtr(""),
+				Namespace:         helper.StringToPtr("default"),
 				Priority:          helper.IntToPtr(50),
 				AllAtOnce:         helper.BoolToPtr(false),
 				VaultToken:        helper.StringToPtr(""),
@@ -166,6 +167,7 @@ func TestJobs_Canonicalize(t *testing.T) {
 				Region:            helper.StringToPtr("global"),
 				Type:              helper.StringToPtr("service"),
 				ParentID:          helper.StringToPtr("lol"),
+				Namespace:         helper.StringToPtr("default"),
 				Priority:          helper.IntToPtr(50),
 				AllAtOnce:         helper.BoolToPtr(false),
 				VaultToken:        helper.StringToPtr(""),
@@ -287,6 +289,7 @@ func TestJobs_Canonicalize(t *testing.T) {
 				ID:                helper.StringToPtr("example_template"),
 				Name:              helper.StringToPtr("example_template"),
 				ParentID:          helper.StringToPtr(""),
+				Namespace:         helper.StringToPtr("default"),
 				Priority:          helper.IntToPtr(50),
 				Region:            helper.StringToPtr("global"),
 				Type:              helper.StringToPtr("service"),
@@ -302,13 +305,15 @@ func TestJobs_Canonicalize(t *testing.T) {
 				JobModifyIndex:    helper.Uint64ToPtr(0),
 				Datacenters:       []string{"dc1"},
 				Update: &UpdateStrategy{
-					Stagger:         helper.TimeToPtr(30 * time.Second),
-					MaxParallel:     helper.IntToPtr(1),
-					HealthCheck:     helper.StringToPtr("checks"),
-					MinHealthyTime:  helper.TimeToPtr(10 * time.Second),
-					HealthyDeadline: helper.TimeToPtr(5 * time.Minute),
-					AutoRevert:      helper.BoolToPtr(false),
-					Canary:          helper.IntToPtr(0),
+					Stagger:          helper.TimeToPtr(30 * time.Second),
+					MaxParallel:      helper.IntToPtr(1),
+					HealthCheck:      helper.StringToPtr("checks"),
+					MinHealthyTime:   helper.TimeToPtr(10 * time.Second),
+					HealthyDeadline:  helper.TimeToPtr(5 * time.Minute),
+					AutoRevert:       helper.BoolToPtr(false),
+					Canary:           helper.IntToPtr(0),
+					AutoPromote:      helper.BoolToPtr(false),
+					ProgressDeadline: helper.TimeToPtr(10 * time.Minute),
 				},
 				TaskGroups: []*TaskGroup{
 					{
@@ -327,13 +332,15 @@ func TestJobs_Canonicalize(t *testing.T) {
 						},
 
 						Update: &UpdateStrategy{
-							Stagger:         helper.TimeToPtr(30 * time.Second),
-							MaxParallel:     helper.IntToPtr(1),
-							HealthCheck:     helper.StringToPtr("checks"),
-							MinHealthyTime:  helper.TimeToPtr(10 * time.Second),
-							HealthyDeadline: helper.TimeToPtr(5 * time.Minute),
-							AutoRevert:      helper.BoolToPtr(false),
-							Canary:          helper.IntToPtr(0),
+							Stagger:          helper.TimeToPtr(30 * time.Second),
+							MaxParallel:      helper.IntToPtr(1),
+							HealthCheck:      helper.StringToPtr("checks"),
+							MinHealthyTime:   helper.TimeToPtr(10 * time.Second),
+							HealthyDeadline:  helper.TimeToPtr(5 * time.Minute),
+							AutoRevert:       helper.BoolToPtr(false),
+							Canary:           helper.IntToPtr(0),
+							AutoPromote:      helper.BoolToPtr(false),
+							ProgressDeadline: helper.TimeToPtr(10 * time.Minute),
 						},
 						Tasks: []*Task{
 							{
@@ -380,30 +387,32 @@ func TestJobs_Canonicalize(t *testing.T) {
 								LogConfig:   DefaultLogConfig(),
 								Templates: []*Template{
 									{
-										SourcePath:   helper.StringToPtr(""),
-										DestPath:     helper.StringToPtr("local/file.yml"),
-										EmbeddedTmpl: helper.StringToPtr("---"),
-										ChangeMode:   helper.StringToPtr("restart"),
-										ChangeSignal: helper.StringToPtr(""),
-										Splay:        helper.TimeToPtr(5 * time.Second),
-										Perms:        helper.StringToPtr("0644"),
-										LeftDelim:    helper.StringToPtr("{{"),
-										RightDelim:   helper.StringToPtr("}}"),
-										Envvars:      helper.BoolToPtr(false),
-										VaultGrace:   helper.TimeToPtr(5 * time.Minute),
+										SourcePath:    helper.StringToPtr(""),
+										DestPath:      helper.StringToPtr("local/file.yml"),
+										EmbeddedTmpl:  helper.StringToPtr("---"),
+										ChangeMode:    helper.StringToPtr("restart"),
+										ChangeSignal:  helper.StringToPtr(""),
+										Splay:         helper.TimeToPtr(5 * time.Second),
+										Perms:         helper.StringToPtr("0644"),
+										LeftDelim:     helper.StringToPtr("{{"),
+										RightDelim:    helper.StringToPtr("}}"),
+										Envvars:       helper.BoolToPtr(false),
+										ErrMissingKey: helper.BoolToPtr(false),
+										VaultGrace:    helper.TimeToPtr(5 * time.Minute),
 									},
 									{
-										SourcePath:   helper.StringToPtr(""),
-										DestPath:     helper.StringToPtr("local/file.env"),
-										EmbeddedTmpl: helper.StringToPtr("FOO=bar\n"),
-										ChangeMode:   helper.StringToPtr("restart"),
-										ChangeSignal: helper.StringToPtr(""),
-										Splay:        helper.TimeToPtr(5 * time.Second),
-										Perms:        helper.StringToPtr("0644"),
-										LeftDelim:    helper.StringToPtr("{{"),
-										RightDelim:   helper.StringToPtr("}}"),
-										Envvars:      helper.BoolToPtr(true),
-										VaultGrace:   helper.TimeToPtr(3 * time.Second),
+										SourcePath:    helper.StringToPtr(""),
+										DestPath:      helper.StringToPtr("local/file.env"),
+										EmbeddedTmpl:  helper.StringToPtr("FOO=bar\n"),
+										ChangeMode:    helper.StringToPtr("restart"),
+										ChangeSignal:  helper.StringToPtr(""),
+										Splay:         helper.TimeToPtr(5 * time.Second),
+										Perms:         helper.StringToPtr("0644"),
+										LeftDelim:     helper.StringToPtr("{{"),
+										RightDelim:    helper.StringToPtr("}}"),
+										Envvars:       helper.BoolToPtr(true),
+										ErrMissingKey: helper.BoolToPtr(false),
+										VaultGrace:    helper.TimeToPtr(3 * time.Second),
 									},
 								},
 							},
@@ -422,6 +431,7 @@ func TestJobs_Canonicalize(t *testing.T) {
 			expected: &Job{
 				ID:                helper.StringToPtr("bar"),
 				ParentID:          helper.StringToPtr(""),
+				Namespace:         helper.StringToPtr("default"),
 				Name:              helper.StringToPtr("bar"),
 				Region:            helper.StringToPtr("global"),
 				Type:              helper.StringToPtr("service"),
@@ -494,6 +504,7 @@ func TestJobs_Canonicalize(t *testing.T) {
 				Region:            helper.StringToPtr("global"),
 				Type:              helper.StringToPtr("service"),
 				ParentID:          helper.StringToPtr("lol"),
+				Namespace:         helper.StringToPtr("default"),
 				Priority:          helper.IntToPtr(50),
 				AllAtOnce:         helper.BoolToPtr(false),
 				VaultToken:        helper.StringToPtr(""),
@@ -506,13 +517,15 @@ func TestJobs_Canonicalize(t *testing.T) {
 				ModifyIndex:       helper.Uint64ToPtr(0),
 				JobModifyIndex:    helper.Uint64ToPtr(0),
 				Update: &UpdateStrategy{
-					Stagger:         helper.TimeToPtr(1 * time.Second),
-					MaxParallel:     helper.IntToPtr(1),
-					HealthCheck:     helper.StringToPtr("checks"),
-					MinHealthyTime:  helper.TimeToPtr(10 * time.Second),
-					HealthyDeadline: helper.TimeToPtr(6 * time.Minute),
-					AutoRevert:      helper.BoolToPtr(false),
-					Canary:          helper.IntToPtr(0),
+					Stagger:          helper.TimeToPtr(1 * time.Second),
+					MaxParallel:      helper.IntToPtr(1),
+					HealthCheck:      helper.StringToPtr("checks"),
+					MinHealthyTime:   helper.TimeToPtr(10 * time.Second),
+					HealthyDeadline:  helper.TimeToPtr(6 * time.Minute),
+					AutoRevert:       helper.BoolToPtr(false),
+					Canary:           helper.IntToPtr(0),
+					AutoPromote:      helper.BoolToPtr(false),
+					ProgressDeadline: helper.TimeToPtr(10 * time.Minute),
 				},
 				TaskGroups: []*TaskGroup{
 					{
@@ -530,13 +543,15 @@ func TestJobs_Canonicalize(t *testing.T) {
 							Mode:     helper.StringToPtr("delay"),
 						},
 						Update: &UpdateStrategy{
-							Stagger:         helper.TimeToPtr(2 * time.Second),
-							MaxParallel:     helper.IntToPtr(2),
-							HealthCheck:     helper.StringToPtr("manual"),
-							MinHealthyTime:  helper.TimeToPtr(1 * time.Second),
-							HealthyDeadline: helper.TimeToPtr(6 * time.Minute),
-							AutoRevert:      helper.BoolToPtr(true),
-							Canary:          helper.IntToPtr(1),
+							Stagger:          helper.TimeToPtr(2 * time.Second),
+							MaxParallel:      helper.IntToPtr(2),
+							HealthCheck:      helper.StringToPtr("manual"),
+							MinHealthyTime:   helper.TimeToPtr(1 * time.Second),
+							HealthyDeadline:  helper.TimeToPtr(6 * time.Minute),
+							AutoRevert:       helper.BoolToPtr(true),
+							Canary:           helper.IntToPtr(1),
+							AutoPromote:      helper.BoolToPtr(false),
+							ProgressDeadline: helper.TimeToPtr(10 * time.Minute),
 						},
 						Tasks: []*Task{
 							{
@@ -562,13 +577,15 @@ func TestJobs_Canonicalize(t *testing.T) {
 							Mode:     helper.StringToPtr("delay"),
 						},
 						Update: &UpdateStrategy{
-							Stagger:         helper.TimeToPtr(1 * time.Second),
-							MaxParallel:     helper.IntToPtr(1),
-							HealthCheck:     helper.StringToPtr("checks"),
-							MinHealthyTime:  helper.TimeToPtr(10 * time.Second),
-							HealthyDeadline: helper.TimeToPtr(6 * time.Minute),
-							AutoRevert:      helper.BoolToPtr(false),
-							Canary:          helper.IntToPtr(0),
+							Stagger:          helper.TimeToPtr(1 * time.Second),
+							MaxParallel:      helper.IntToPtr(1),
+							HealthCheck:      helper.StringToPtr("checks"),
+							MinHealthyTime:   helper.TimeToPtr(10 * time.Second),
+							HealthyDeadline:  helper.TimeToPtr(6 * time.Minute),
+							AutoRevert:       helper.BoolToPtr(false),
+							Canary:           helper.IntToPtr(0),
+							AutoPromote:      helper.BoolToPtr(false),
+							ProgressDeadline: helper.TimeToPtr(10 * time.Minute),
 						},
 						Tasks: []*Task{
 							{
@@ -713,6 +730,43 @@ func TestJobs_Revert(t *testing.T) {
 	assertWriteMeta(t, wm)
 }
 
+func TestJobs_Scale(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	jobs := c.Jobs()
+
+	job := testJob()
+	_, wm, err := jobs.Register(job, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Fails on an unknown group
+	_, _, err = jobs.Scale(*job.ID, "foo", 3, nil)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error: %v", err)
+	}
+
+	scaleResp, wm, err := jobs.Scale(*job.ID, *job.TaskGroups[0].Name, 3, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if scaleResp.EvalID == "" {
+		t.Fatalf("missing eval id")
+	}
+	assertWriteMeta(t, wm)
+
+	result, _, err := jobs.Info(*job.ID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if *result.TaskGroups[0].Count != 3 {
+		t.Fatalf("bad count: %d", *result.TaskGroups[0].Count)
+	}
+}
+
 func TestJobs_Info(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t, nil, nil)
@@ -780,6 +834,37 @@ func TestJobs_Versions(t *testing.T) {
 	}
 }
 
+func TestJobs_VersionsDiff(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	jobs := c.Jobs()
+
+	// Register two versions of the job
+	job := testJob()
+	if _, _, err := jobs.Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	job.Priority = helper.IntToPtr(*job.Priority + 1)
+	if _, _, err := jobs.Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Diff both versions against the first one
+	versions, diffs, qm, err := jobs.VersionsDiff("job1", 0, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+
+	if len(versions) != 2 || len(diffs) != 2 {
+		t.Fatalf("expected a diff per version; got %d versions and %d diffs", len(versions), len(diffs))
+	}
+	if diffs[0].Type != "Edited" || diffs[1].Type != "None" {
+		t.Fatalf("bad diffs: %#v %#v", diffs[0], diffs[1])
+	}
+}
+
 func TestJobs_PrefixList(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t, nil, nil)
@@ -1069,10 +1154,19 @@ func TestJobs_PeriodicForce(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 	assertQueryMeta(t, qm)
-	if eval.ID == evalID {
-		return
+	if eval.ID != evalID {
+		t.Fatalf("evaluation %q missing", evalID)
+	}
+
+	// Force again and check the launched job is returned
+	resp, wm, err := jobs.PeriodicForceLaunch(*job.ID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if resp.EvalID == "" || !strings.HasPrefix(resp.JobID, *job.ID+"/periodic-") {
+		t.Fatalf("bad: %#v", resp)
 	}
-	t.Fatalf("evaluation %q missing", evalID)
 }
 
 func TestJobs_Plan(t *testing.T) {
@@ -73,7 +73,6 @@ func MockJob() *Job {
 							MemoryMB: helper.IntToPtr(256),
 							Networks: []*NetworkResource{
 								&NetworkResource{
-									MBits:        helper.IntToPtr(50),
 									DynamicPorts: []Port{{Label: "http"}, {Label: "admin"}},
 								},
 							},
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+)
+
+// Namespaces is used to query the namespace endpoints.
+type Namespaces struct {
+	client *Client
+}
+
+// Namespaces returns a new handle on the namespaces.
+func (c *Client) Namespaces() *Namespaces {
+	return &Namespaces{client: c}
+}
+
+// List is used to dump all of the namespaces, sorted by name.
+func (n *Namespaces) List(q *QueryOptions) ([]*Namespace, *QueryMeta, error) {
+	var resp []*Namespace
+	qm, err := n.client.query("/v1/namespaces", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// PrefixList is used to do a PrefixList search over namespaces
+func (n *Namespaces) PrefixList(prefix string, q *QueryOptions) ([]*Namespace, *QueryMeta, error) {
+	if q == nil {
+		q = &QueryOptions{Prefix: prefix}
+	} else {
+		q.Prefix = prefix
+	}
+
+	return n.List(q)
+}
+
+// Info is used to query a single namespace by its name.
+func (n *Namespaces) Info(name string, q *QueryOptions) (*Namespace, *QueryMeta, error) {
+	var resp Namespace
+	qm, err := n.client.query("/v1/namespace/"+name, &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Register is used to register a namespace, creating it or updating an
+// existing one.
+func (n *Namespaces) Register(namespace *Namespace, q *WriteOptions) (*WriteMeta, error) {
+	if namespace == nil || namespace.Name == "" {
+		return nil, fmt.Errorf("missing namespace name")
+	}
+	wm, err := n.client.write("/v1/namespace/"+namespace.Name, namespace, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Delete is used to delete a namespace
+func (n *Namespaces) Delete(name string, q *WriteOptions) (*WriteMeta, error) {
+	wm, err := n.client.delete("/v1/namespace/"+name, nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// Namespace is used to serialize a namespace.
+type Namespace struct {
+	Name         string                 `mapstructure:"name"`
+	Description  string                 `mapstructure:"description"`
+	Quota        string                 `mapstructure:"quota"`
+	Capabilities *NamespaceCapabilities `mapstructure:"-"`
+	GC           *JobGCConfig           `mapstructure:"-"`
+	Meta         map[string]string      `mapstructure:"-"`
+	CreateIndex  uint64
+	ModifyIndex  uint64
+}
+
+// NamespaceCapabilities restricts the task drivers available to the jobs of
+// a namespace.
+type NamespaceCapabilities struct {
+	EnabledTaskDrivers  []string `mapstructure:"enabled_task_drivers"`
+	DisabledTaskDrivers []string `mapstructure:"disabled_task_drivers"`
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testNamespace() *Namespace {
+	return &Namespace{
+		Name:        "team-a",
+		Description: "Namespace of team A",
+		Capabilities: &NamespaceCapabilities{
+			DisabledTaskDrivers: []string{"raw_exec"},
+		},
+		Meta: map[string]string{
+			"owner": "ops",
+		},
+	}
+}
+
+func TestNamespaces_Register(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	namespaces := c.Namespaces()
+
+	// Create a namespace and register it
+	ns := testNamespace()
+	wm, err := namespaces.Register(ns, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Query the namespace back out
+	out, qm, err := namespaces.Info(ns.Name, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+
+	if out.Description != ns.Description {
+		t.Fatalf("bad description: %q", out.Description)
+	}
+	if !reflect.DeepEqual(out.Capabilities, ns.Capabilities) {
+		t.Fatalf("bad capabilities: %#v", out.Capabilities)
+	}
+	if !reflect.DeepEqual(out.Meta, ns.Meta) {
+		t.Fatalf("bad meta: %#v", out.Meta)
+	}
+
+	// Registering without a name fails
+	if _, err := namespaces.Register(&Namespace{}, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestNamespaces_List(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	namespaces := c.Namespaces()
+
+	// Listing when nothing exists returns empty
+	result, _, err := namespaces.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n := len(result); n != 0 {
+		t.Fatalf("expected 0 namespaces, got: %d", n)
+	}
+
+	// Register two namespaces
+	for _, name := range []string{"web", "batch"} {
+		ns := testNamespace()
+		ns.Name = name
+		if _, err := namespaces.Register(ns, nil); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The namespaces are sorted by name
+	result, qm, err := namespaces.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if len(result) != 2 || result[0].Name != "batch" || result[1].Name != "web" {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// Query by prefix
+	result, _, err = namespaces.PrefixList("we", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(result) != 1 || result[0].Name != "web" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestNamespaces_Delete(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	namespaces := c.Namespaces()
+
+	// Register a namespace
+	ns := testNamespace()
+	if _, err := namespaces.Register(ns, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Delete it
+	wm, err := namespaces.Delete(ns.Name, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+
+	// Check that it is gone
+	if _, _, err := namespaces.Info(ns.Name, nil); err == nil || !IsNotFound(err) {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+}
@@ -72,6 +72,19 @@ func (n *Nodes) ForceEvaluate(nodeID string, q *WriteOptions) (string, *WriteMet
 	return resp.EvalID, wm, nil
 }
 
+// GetNodeClient returns a client for the HTTP API of the agent running on the
+// node.
+func (c *Client) GetNodeClient(nodeID string, q *QueryOptions) (*Client, error) {
+	node, _, err := c.Nodes().Info(nodeID, q)
+	if err != nil {
+		return nil, err
+	}
+	if node.HTTPAddr == "" {
+		return nil, fmt.Errorf("http addr of the node %q is running is not advertised", nodeID)
+	}
+	return NewClient(c.config.CopyConfig(node.HTTPAddr, node.TLSEnabled))
+}
+
 func (n *Nodes) Stats(nodeID string, q *QueryOptions) (*HostStats, error) {
 	node, _, err := n.client.Nodes().Info(nodeID, q)
 	if err != nil {
@@ -118,6 +131,8 @@ type Node struct {
 	Attributes        map[string]string
 	Resources         *Resources
 	Reserved          *Resources
+	MinDynamicPort    int
+	MaxDynamicPort    int
 	Links             map[string]string
 	Meta              map[string]string
 	NodeClass         string
@@ -134,6 +149,8 @@ type HostStats struct {
 	Memory           *HostMemoryStats
 	CPU              []*HostCPUStats
 	DiskStats        []*HostDiskStats
+	NetworkStats     []*HostNetworkStats
+	Pressure         *HostPressureStats
 	Uptime           uint64
 	CPUTicksConsumed float64
 }
@@ -160,6 +177,41 @@ type HostDiskStats struct {
 	Available         uint64
 	UsedPercent       float64
 	InodesUsedPercent float64
+	InodesUsed        uint64
+	InodesFree        uint64
+
+	ReadBytes            float64
+	WriteBytes           float64
+	IOUtilizationPercent float64
+}
+
+type HostNetworkStats struct {
+	Interface string
+	RxBytes   float64
+	TxBytes   float64
+	RxPackets float64
+	TxPackets float64
+	RxErrors  uint64
+	TxErrors  uint64
+	RxDropped uint64
+	TxDropped uint64
+}
+
+// HostPressureStats is the pressure stall information of the host, only
+// available on Linux 4.20 and later.
+type HostPressureStats struct {
+	CPU    *HostPressureStat
+	Memory *HostPressureStat
+	IO     *HostPressureStat
+}
+
+type HostPressureStat struct {
+	SomeAvg10  float64
+	SomeAvg60  float64
+	SomeAvg300 float64
+	FullAvg10  float64
+	FullAvg60  float64
+	FullAvg300 float64
 }
 
 // NodeListStub is a subset of information returned during
@@ -0,0 +1,3 @@
+package nomadpb
+
+//go:generate protoc --go_out=plugins=grpc:. nomad.proto
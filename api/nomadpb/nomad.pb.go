@@ -0,0 +1,959 @@
+// Code generated by protoc-gen-go.
+// source: nomad.proto
+// DO NOT EDIT!
+
+/*
+Package nomadpb is a generated protocol buffer package.
+
+It is generated from these files:
+	nomad.proto
+
+It has these top-level messages:
+	QueryOptions
+	QueryMeta
+	WriteOptions
+	JobStub
+	Allocation
+	Evaluation
+	Deployment
+	Node
+	JobRegisterRequest
+	JobRegisterResponse
+	JobDeregisterRequest
+	JobDeregisterResponse
+	JobListRequest
+	JobListResponse
+	AllocationListRequest
+	AllocationListResponse
+	AllocationGetRequest
+	AllocationGetResponse
+	LogsRequest
+	LogFrame
+	EventStreamRequest
+	Event
+*/
+package nomadpb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type QueryOptions struct {
+	Region     string `protobuf:"bytes,1,opt,name=region" json:"region,omitempty"`
+	AllowStale bool   `protobuf:"varint,2,opt,name=allow_stale,json=allowStale" json:"allow_stale,omitempty"`
+	Prefix     string `protobuf:"bytes,3,opt,name=prefix" json:"prefix,omitempty"`
+	Filter     string `protobuf:"bytes,4,opt,name=filter" json:"filter,omitempty"`
+	PerPage    int32  `protobuf:"varint,5,opt,name=per_page,json=perPage" json:"per_page,omitempty"`
+	NextToken  string `protobuf:"bytes,6,opt,name=next_token,json=nextToken" json:"next_token,omitempty"`
+}
+
+func (m *QueryOptions) Reset()                    { *m = QueryOptions{} }
+func (m *QueryOptions) String() string            { return proto.CompactTextString(m) }
+func (*QueryOptions) ProtoMessage()               {}
+func (*QueryOptions) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type QueryMeta struct {
+	Index       uint64 `protobuf:"varint,1,opt,name=index" json:"index,omitempty"`
+	KnownLeader bool   `protobuf:"varint,2,opt,name=known_leader,json=knownLeader" json:"known_leader,omitempty"`
+	NextToken   string `protobuf:"bytes,3,opt,name=next_token,json=nextToken" json:"next_token,omitempty"`
+}
+
+func (m *QueryMeta) Reset()                    { *m = QueryMeta{} }
+func (m *QueryMeta) String() string            { return proto.CompactTextString(m) }
+func (*QueryMeta) ProtoMessage()               {}
+func (*QueryMeta) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type WriteOptions struct {
+	Region string `protobuf:"bytes,1,opt,name=region" json:"region,omitempty"`
+}
+
+func (m *WriteOptions) Reset()                    { *m = WriteOptions{} }
+func (m *WriteOptions) String() string            { return proto.CompactTextString(m) }
+func (*WriteOptions) ProtoMessage()               {}
+func (*WriteOptions) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+type JobStub struct {
+	Id                string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	ParentId          string `protobuf:"bytes,2,opt,name=parent_id,json=parentId" json:"parent_id,omitempty"`
+	Name              string `protobuf:"bytes,3,opt,name=name" json:"name,omitempty"`
+	Type              string `protobuf:"bytes,4,opt,name=type" json:"type,omitempty"`
+	Priority          int32  `protobuf:"varint,5,opt,name=priority" json:"priority,omitempty"`
+	Periodic          bool   `protobuf:"varint,6,opt,name=periodic" json:"periodic,omitempty"`
+	Parameterized     bool   `protobuf:"varint,7,opt,name=parameterized" json:"parameterized,omitempty"`
+	Stop              bool   `protobuf:"varint,8,opt,name=stop" json:"stop,omitempty"`
+	Status            string `protobuf:"bytes,9,opt,name=status" json:"status,omitempty"`
+	StatusDescription string `protobuf:"bytes,10,opt,name=status_description,json=statusDescription" json:"status_description,omitempty"`
+	SubmitTime        int64  `protobuf:"varint,11,opt,name=submit_time,json=submitTime" json:"submit_time,omitempty"`
+	CreateIndex       uint64 `protobuf:"varint,12,opt,name=create_index,json=createIndex" json:"create_index,omitempty"`
+	ModifyIndex       uint64 `protobuf:"varint,13,opt,name=modify_index,json=modifyIndex" json:"modify_index,omitempty"`
+	JobModifyIndex    uint64 `protobuf:"varint,14,opt,name=job_modify_index,json=jobModifyIndex" json:"job_modify_index,omitempty"`
+}
+
+func (m *JobStub) Reset()                    { *m = JobStub{} }
+func (m *JobStub) String() string            { return proto.CompactTextString(m) }
+func (*JobStub) ProtoMessage()               {}
+func (*JobStub) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+type Allocation struct {
+	Id                 string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	EvalId             string `protobuf:"bytes,2,opt,name=eval_id,json=evalId" json:"eval_id,omitempty"`
+	Name               string `protobuf:"bytes,3,opt,name=name" json:"name,omitempty"`
+	NodeId             string `protobuf:"bytes,4,opt,name=node_id,json=nodeId" json:"node_id,omitempty"`
+	JobId              string `protobuf:"bytes,5,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+	JobVersion         uint64 `protobuf:"varint,6,opt,name=job_version,json=jobVersion" json:"job_version,omitempty"`
+	TaskGroup          string `protobuf:"bytes,7,opt,name=task_group,json=taskGroup" json:"task_group,omitempty"`
+	DesiredStatus      string `protobuf:"bytes,8,opt,name=desired_status,json=desiredStatus" json:"desired_status,omitempty"`
+	DesiredDescription string `protobuf:"bytes,9,opt,name=desired_description,json=desiredDescription" json:"desired_description,omitempty"`
+	ClientStatus       string `protobuf:"bytes,10,opt,name=client_status,json=clientStatus" json:"client_status,omitempty"`
+	ClientDescription  string `protobuf:"bytes,11,opt,name=client_description,json=clientDescription" json:"client_description,omitempty"`
+	CreateTime         int64  `protobuf:"varint,12,opt,name=create_time,json=createTime" json:"create_time,omitempty"`
+	CreateIndex        uint64 `protobuf:"varint,13,opt,name=create_index,json=createIndex" json:"create_index,omitempty"`
+	ModifyIndex        uint64 `protobuf:"varint,14,opt,name=modify_index,json=modifyIndex" json:"modify_index,omitempty"`
+}
+
+func (m *Allocation) Reset()                    { *m = Allocation{} }
+func (m *Allocation) String() string            { return proto.CompactTextString(m) }
+func (*Allocation) ProtoMessage()               {}
+func (*Allocation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+type Evaluation struct {
+	Id                string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Priority          int32  `protobuf:"varint,2,opt,name=priority" json:"priority,omitempty"`
+	Type              string `protobuf:"bytes,3,opt,name=type" json:"type,omitempty"`
+	TriggeredBy       string `protobuf:"bytes,4,opt,name=triggered_by,json=triggeredBy" json:"triggered_by,omitempty"`
+	JobId             string `protobuf:"bytes,5,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+	NodeId            string `protobuf:"bytes,6,opt,name=node_id,json=nodeId" json:"node_id,omitempty"`
+	DeploymentId      string `protobuf:"bytes,7,opt,name=deployment_id,json=deploymentId" json:"deployment_id,omitempty"`
+	Status            string `protobuf:"bytes,8,opt,name=status" json:"status,omitempty"`
+	StatusDescription string `protobuf:"bytes,9,opt,name=status_description,json=statusDescription" json:"status_description,omitempty"`
+	CreateIndex       uint64 `protobuf:"varint,10,opt,name=create_index,json=createIndex" json:"create_index,omitempty"`
+	ModifyIndex       uint64 `protobuf:"varint,11,opt,name=modify_index,json=modifyIndex" json:"modify_index,omitempty"`
+}
+
+func (m *Evaluation) Reset()                    { *m = Evaluation{} }
+func (m *Evaluation) String() string            { return proto.CompactTextString(m) }
+func (*Evaluation) ProtoMessage()               {}
+func (*Evaluation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+type Deployment struct {
+	Id                string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	JobId             string `protobuf:"bytes,2,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+	JobVersion        uint64 `protobuf:"varint,3,opt,name=job_version,json=jobVersion" json:"job_version,omitempty"`
+	Status            string `protobuf:"bytes,4,opt,name=status" json:"status,omitempty"`
+	StatusDescription string `protobuf:"bytes,5,opt,name=status_description,json=statusDescription" json:"status_description,omitempty"`
+	CreateIndex       uint64 `protobuf:"varint,6,opt,name=create_index,json=createIndex" json:"create_index,omitempty"`
+	ModifyIndex       uint64 `protobuf:"varint,7,opt,name=modify_index,json=modifyIndex" json:"modify_index,omitempty"`
+}
+
+func (m *Deployment) Reset()                    { *m = Deployment{} }
+func (m *Deployment) String() string            { return proto.CompactTextString(m) }
+func (*Deployment) ProtoMessage()               {}
+func (*Deployment) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+type Node struct {
+	Id                string `protobuf:"bytes,1,opt,name=id" json:"id,omitempty"`
+	Datacenter        string `protobuf:"bytes,2,opt,name=datacenter" json:"datacenter,omitempty"`
+	Name              string `protobuf:"bytes,3,opt,name=name" json:"name,omitempty"`
+	NodeClass         string `protobuf:"bytes,4,opt,name=node_class,json=nodeClass" json:"node_class,omitempty"`
+	Drain             bool   `protobuf:"varint,5,opt,name=drain" json:"drain,omitempty"`
+	Status            string `protobuf:"bytes,6,opt,name=status" json:"status,omitempty"`
+	StatusDescription string `protobuf:"bytes,7,opt,name=status_description,json=statusDescription" json:"status_description,omitempty"`
+	CreateIndex       uint64 `protobuf:"varint,8,opt,name=create_index,json=createIndex" json:"create_index,omitempty"`
+	ModifyIndex       uint64 `protobuf:"varint,9,opt,name=modify_index,json=modifyIndex" json:"modify_index,omitempty"`
+}
+
+func (m *Node) Reset()                    { *m = Node{} }
+func (m *Node) String() string            { return proto.CompactTextString(m) }
+func (*Node) ProtoMessage()               {}
+func (*Node) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+type JobRegisterRequest struct {
+	Job            []byte        `protobuf:"bytes,1,opt,name=job" json:"job,omitempty"`
+	EnforceIndex   bool          `protobuf:"varint,2,opt,name=enforce_index,json=enforceIndex" json:"enforce_index,omitempty"`
+	JobModifyIndex uint64        `protobuf:"varint,3,opt,name=job_modify_index,json=jobModifyIndex" json:"job_modify_index,omitempty"`
+	Options        *WriteOptions `protobuf:"bytes,4,opt,name=options" json:"options,omitempty"`
+}
+
+func (m *JobRegisterRequest) Reset()                    { *m = JobRegisterRequest{} }
+func (m *JobRegisterRequest) String() string            { return proto.CompactTextString(m) }
+func (*JobRegisterRequest) ProtoMessage()               {}
+func (*JobRegisterRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *JobRegisterRequest) GetOptions() *WriteOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type JobRegisterResponse struct {
+	EvalId          string `protobuf:"bytes,1,opt,name=eval_id,json=evalId" json:"eval_id,omitempty"`
+	EvalCreateIndex uint64 `protobuf:"varint,2,opt,name=eval_create_index,json=evalCreateIndex" json:"eval_create_index,omitempty"`
+	JobModifyIndex  uint64 `protobuf:"varint,3,opt,name=job_modify_index,json=jobModifyIndex" json:"job_modify_index,omitempty"`
+	Warnings        string `protobuf:"bytes,4,opt,name=warnings" json:"warnings,omitempty"`
+	Index           uint64 `protobuf:"varint,5,opt,name=index" json:"index,omitempty"`
+}
+
+func (m *JobRegisterResponse) Reset()                    { *m = JobRegisterResponse{} }
+func (m *JobRegisterResponse) String() string            { return proto.CompactTextString(m) }
+func (*JobRegisterResponse) ProtoMessage()               {}
+func (*JobRegisterResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+type JobDeregisterRequest struct {
+	JobId   string        `protobuf:"bytes,1,opt,name=job_id,json=jobId" json:"job_id,omitempty"`
+	Purge   bool          `protobuf:"varint,2,opt,name=purge" json:"purge,omitempty"`
+	Options *WriteOptions `protobuf:"bytes,3,opt,name=options" json:"options,omitempty"`
+}
+
+func (m *JobDeregisterRequest) Reset()                    { *m = JobDeregisterRequest{} }
+func (m *JobDeregisterRequest) String() string            { return proto.CompactTextString(m) }
+func (*JobDeregisterRequest) ProtoMessage()               {}
+func (*JobDeregisterRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *JobDeregisterRequest) GetOptions() *WriteOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type JobDeregisterResponse struct {
+	EvalId          string `protobuf:"bytes,1,opt,name=eval_id,json=evalId" json:"eval_id,omitempty"`
+	EvalCreateIndex uint64 `protobuf:"varint,2,opt,name=eval_create_index,json=evalCreateIndex" json:"eval_create_index,omitempty"`
+	JobModifyIndex  uint64 `protobuf:"varint,3,opt,name=job_modify_index,json=jobModifyIndex" json:"job_modify_index,omitempty"`
+	Index           uint64 `protobuf:"varint,4,opt,name=index" json:"index,omitempty"`
+}
+
+func (m *JobDeregisterResponse) Reset()                    { *m = JobDeregisterResponse{} }
+func (m *JobDeregisterResponse) String() string            { return proto.CompactTextString(m) }
+func (*JobDeregisterResponse) ProtoMessage()               {}
+func (*JobDeregisterResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+type JobListRequest struct {
+	Options *QueryOptions `protobuf:"bytes,1,opt,name=options" json:"options,omitempty"`
+}
+
+func (m *JobListRequest) Reset()                    { *m = JobListRequest{} }
+func (m *JobListRequest) String() string            { return proto.CompactTextString(m) }
+func (*JobListRequest) ProtoMessage()               {}
+func (*JobListRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *JobListRequest) GetOptions() *QueryOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type JobListResponse struct {
+	Jobs []*JobStub `protobuf:"bytes,1,rep,name=jobs" json:"jobs,omitempty"`
+	Meta *QueryMeta `protobuf:"bytes,2,opt,name=meta" json:"meta,omitempty"`
+}
+
+func (m *JobListResponse) Reset()                    { *m = JobListResponse{} }
+func (m *JobListResponse) String() string            { return proto.CompactTextString(m) }
+func (*JobListResponse) ProtoMessage()               {}
+func (*JobListResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *JobListResponse) GetJobs() []*JobStub {
+	if m != nil {
+		return m.Jobs
+	}
+	return nil
+}
+
+func (m *JobListResponse) GetMeta() *QueryMeta {
+	if m != nil {
+		return m.Meta
+	}
+	return nil
+}
+
+type AllocationListRequest struct {
+	Options *QueryOptions `protobuf:"bytes,1,opt,name=options" json:"options,omitempty"`
+}
+
+func (m *AllocationListRequest) Reset()                    { *m = AllocationListRequest{} }
+func (m *AllocationListRequest) String() string            { return proto.CompactTextString(m) }
+func (*AllocationListRequest) ProtoMessage()               {}
+func (*AllocationListRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *AllocationListRequest) GetOptions() *QueryOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type AllocationListResponse struct {
+	Allocations []*Allocation `protobuf:"bytes,1,rep,name=allocations" json:"allocations,omitempty"`
+	Meta        *QueryMeta    `protobuf:"bytes,2,opt,name=meta" json:"meta,omitempty"`
+}
+
+func (m *AllocationListResponse) Reset()                    { *m = AllocationListResponse{} }
+func (m *AllocationListResponse) String() string            { return proto.CompactTextString(m) }
+func (*AllocationListResponse) ProtoMessage()               {}
+func (*AllocationListResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *AllocationListResponse) GetAllocations() []*Allocation {
+	if m != nil {
+		return m.Allocations
+	}
+	return nil
+}
+
+func (m *AllocationListResponse) GetMeta() *QueryMeta {
+	if m != nil {
+		return m.Meta
+	}
+	return nil
+}
+
+type AllocationGetRequest struct {
+	AllocId string        `protobuf:"bytes,1,opt,name=alloc_id,json=allocId" json:"alloc_id,omitempty"`
+	Options *QueryOptions `protobuf:"bytes,2,opt,name=options" json:"options,omitempty"`
+}
+
+func (m *AllocationGetRequest) Reset()                    { *m = AllocationGetRequest{} }
+func (m *AllocationGetRequest) String() string            { return proto.CompactTextString(m) }
+func (*AllocationGetRequest) ProtoMessage()               {}
+func (*AllocationGetRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *AllocationGetRequest) GetOptions() *QueryOptions {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type AllocationGetResponse struct {
+	Allocation *Allocation `protobuf:"bytes,1,opt,name=allocation" json:"allocation,omitempty"`
+	Meta       *QueryMeta  `protobuf:"bytes,2,opt,name=meta" json:"meta,omitempty"`
+}
+
+func (m *AllocationGetResponse) Reset()                    { *m = AllocationGetResponse{} }
+func (m *AllocationGetResponse) String() string            { return proto.CompactTextString(m) }
+func (*AllocationGetResponse) ProtoMessage()               {}
+func (*AllocationGetResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *AllocationGetResponse) GetAllocation() *Allocation {
+	if m != nil {
+		return m.Allocation
+	}
+	return nil
+}
+
+func (m *AllocationGetResponse) GetMeta() *QueryMeta {
+	if m != nil {
+		return m.Meta
+	}
+	return nil
+}
+
+type LogsRequest struct {
+	AllocId string `protobuf:"bytes,1,opt,name=alloc_id,json=allocId" json:"alloc_id,omitempty"`
+	Task    string `protobuf:"bytes,2,opt,name=task" json:"task,omitempty"`
+	Type    string `protobuf:"bytes,3,opt,name=type" json:"type,omitempty"`
+	Follow  bool   `protobuf:"varint,4,opt,name=follow" json:"follow,omitempty"`
+	Origin  string `protobuf:"bytes,5,opt,name=origin" json:"origin,omitempty"`
+	Offset  int64  `protobuf:"varint,6,opt,name=offset" json:"offset,omitempty"`
+}
+
+func (m *LogsRequest) Reset()                    { *m = LogsRequest{} }
+func (m *LogsRequest) String() string            { return proto.CompactTextString(m) }
+func (*LogsRequest) ProtoMessage()               {}
+func (*LogsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+type LogFrame struct {
+	Data []byte `protobuf:"bytes,1,opt,name=data" json:"data,omitempty"`
+}
+
+func (m *LogFrame) Reset()                    { *m = LogFrame{} }
+func (m *LogFrame) String() string            { return proto.CompactTextString(m) }
+func (*LogFrame) ProtoMessage()               {}
+func (*LogFrame) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+type EventStreamRequest struct {
+	Topics []string `protobuf:"bytes,1,rep,name=topics" json:"topics,omitempty"`
+	Index  uint64   `protobuf:"varint,2,opt,name=index" json:"index,omitempty"`
+	Region string   `protobuf:"bytes,3,opt,name=region" json:"region,omitempty"`
+}
+
+func (m *EventStreamRequest) Reset()                    { *m = EventStreamRequest{} }
+func (m *EventStreamRequest) String() string            { return proto.CompactTextString(m) }
+func (*EventStreamRequest) ProtoMessage()               {}
+func (*EventStreamRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+type Event struct {
+	Topic      string      `protobuf:"bytes,1,opt,name=topic" json:"topic,omitempty"`
+	Index      uint64      `protobuf:"varint,2,opt,name=index" json:"index,omitempty"`
+	Job        *JobStub    `protobuf:"bytes,3,opt,name=job" json:"job,omitempty"`
+	Allocation *Allocation `protobuf:"bytes,4,opt,name=allocation" json:"allocation,omitempty"`
+	Evaluation *Evaluation `protobuf:"bytes,5,opt,name=evaluation" json:"evaluation,omitempty"`
+	Deployment *Deployment `protobuf:"bytes,6,opt,name=deployment" json:"deployment,omitempty"`
+	Node       *Node       `protobuf:"bytes,7,opt,name=node" json:"node,omitempty"`
+}
+
+func (m *Event) Reset()                    { *m = Event{} }
+func (m *Event) String() string            { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()               {}
+func (*Event) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{21} }
+
+func (m *Event) GetJob() *JobStub {
+	if m != nil {
+		return m.Job
+	}
+	return nil
+}
+
+func (m *Event) GetAllocation() *Allocation {
+	if m != nil {
+		return m.Allocation
+	}
+	return nil
+}
+
+func (m *Event) GetEvaluation() *Evaluation {
+	if m != nil {
+		return m.Evaluation
+	}
+	return nil
+}
+
+func (m *Event) GetDeployment() *Deployment {
+	if m != nil {
+		return m.Deployment
+	}
+	return nil
+}
+
+func (m *Event) GetNode() *Node {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*QueryOptions)(nil), "nomad.v1.QueryOptions")
+	proto.RegisterType((*QueryMeta)(nil), "nomad.v1.QueryMeta")
+	proto.RegisterType((*WriteOptions)(nil), "nomad.v1.WriteOptions")
+	proto.RegisterType((*JobStub)(nil), "nomad.v1.JobStub")
+	proto.RegisterType((*Allocation)(nil), "nomad.v1.Allocation")
+	proto.RegisterType((*Evaluation)(nil), "nomad.v1.Evaluation")
+	proto.RegisterType((*Deployment)(nil), "nomad.v1.Deployment")
+	proto.RegisterType((*Node)(nil), "nomad.v1.Node")
+	proto.RegisterType((*JobRegisterRequest)(nil), "nomad.v1.JobRegisterRequest")
+	proto.RegisterType((*JobRegisterResponse)(nil), "nomad.v1.JobRegisterResponse")
+	proto.RegisterType((*JobDeregisterRequest)(nil), "nomad.v1.JobDeregisterRequest")
+	proto.RegisterType((*JobDeregisterResponse)(nil), "nomad.v1.JobDeregisterResponse")
+	proto.RegisterType((*JobListRequest)(nil), "nomad.v1.JobListRequest")
+	proto.RegisterType((*JobListResponse)(nil), "nomad.v1.JobListResponse")
+	proto.RegisterType((*AllocationListRequest)(nil), "nomad.v1.AllocationListRequest")
+	proto.RegisterType((*AllocationListResponse)(nil), "nomad.v1.AllocationListResponse")
+	proto.RegisterType((*AllocationGetRequest)(nil), "nomad.v1.AllocationGetRequest")
+	proto.RegisterType((*AllocationGetResponse)(nil), "nomad.v1.AllocationGetResponse")
+	proto.RegisterType((*LogsRequest)(nil), "nomad.v1.LogsRequest")
+	proto.RegisterType((*LogFrame)(nil), "nomad.v1.LogFrame")
+	proto.RegisterType((*EventStreamRequest)(nil), "nomad.v1.EventStreamRequest")
+	proto.RegisterType((*Event)(nil), "nomad.v1.Event")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Jobs service
+
+type JobsClient interface {
+	Register(ctx context.Context, in *JobRegisterRequest, opts ...grpc.CallOption) (*JobRegisterResponse, error)
+	Deregister(ctx context.Context, in *JobDeregisterRequest, opts ...grpc.CallOption) (*JobDeregisterResponse, error)
+	List(ctx context.Context, in *JobListRequest, opts ...grpc.CallOption) (*JobListResponse, error)
+}
+
+type jobsClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewJobsClient(cc *grpc.ClientConn) JobsClient {
+	return &jobsClient{cc}
+}
+
+func (c *jobsClient) Register(ctx context.Context, in *JobRegisterRequest, opts ...grpc.CallOption) (*JobRegisterResponse, error) {
+	out := new(JobRegisterResponse)
+	err := grpc.Invoke(ctx, "/nomad.v1.Jobs/Register", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobsClient) Deregister(ctx context.Context, in *JobDeregisterRequest, opts ...grpc.CallOption) (*JobDeregisterResponse, error) {
+	out := new(JobDeregisterResponse)
+	err := grpc.Invoke(ctx, "/nomad.v1.Jobs/Deregister", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobsClient) List(ctx context.Context, in *JobListRequest, opts ...grpc.CallOption) (*JobListResponse, error) {
+	out := new(JobListResponse)
+	err := grpc.Invoke(ctx, "/nomad.v1.Jobs/List", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Jobs service
+
+type JobsServer interface {
+	Register(context.Context, *JobRegisterRequest) (*JobRegisterResponse, error)
+	Deregister(context.Context, *JobDeregisterRequest) (*JobDeregisterResponse, error)
+	List(context.Context, *JobListRequest) (*JobListResponse, error)
+}
+
+func RegisterJobsServer(s *grpc.Server, srv JobsServer) {
+	s.RegisterService(&_Jobs_serviceDesc, srv)
+}
+
+func _Jobs_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobRegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobsServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nomad.v1.Jobs/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobsServer).Register(ctx, req.(*JobRegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jobs_Deregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobDeregisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobsServer).Deregister(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nomad.v1.Jobs/Deregister",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobsServer).Deregister(ctx, req.(*JobDeregisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jobs_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobsServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nomad.v1.Jobs/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobsServer).List(ctx, req.(*JobListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Jobs_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "nomad.v1.Jobs",
+	HandlerType: (*JobsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Jobs_Register_Handler,
+		},
+		{
+			MethodName: "Deregister",
+			Handler:    _Jobs_Deregister_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _Jobs_List_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "nomad.proto",
+}
+
+// Client API for Allocations service
+
+type AllocationsClient interface {
+	List(ctx context.Context, in *AllocationListRequest, opts ...grpc.CallOption) (*AllocationListResponse, error)
+	Get(ctx context.Context, in *AllocationGetRequest, opts ...grpc.CallOption) (*AllocationGetResponse, error)
+	Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (Allocations_LogsClient, error)
+}
+
+type allocationsClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAllocationsClient(cc *grpc.ClientConn) AllocationsClient {
+	return &allocationsClient{cc}
+}
+
+func (c *allocationsClient) List(ctx context.Context, in *AllocationListRequest, opts ...grpc.CallOption) (*AllocationListResponse, error) {
+	out := new(AllocationListResponse)
+	err := grpc.Invoke(ctx, "/nomad.v1.Allocations/List", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *allocationsClient) Get(ctx context.Context, in *AllocationGetRequest, opts ...grpc.CallOption) (*AllocationGetResponse, error) {
+	out := new(AllocationGetResponse)
+	err := grpc.Invoke(ctx, "/nomad.v1.Allocations/Get", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *allocationsClient) Logs(ctx context.Context, in *LogsRequest, opts ...grpc.CallOption) (Allocations_LogsClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Allocations_serviceDesc.Streams[0], c.cc, "/nomad.v1.Allocations/Logs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &allocationsLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Allocations_LogsClient interface {
+	Recv() (*LogFrame, error)
+	grpc.ClientStream
+}
+
+type allocationsLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *allocationsLogsClient) Recv() (*LogFrame, error) {
+	m := new(LogFrame)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Allocations service
+
+type AllocationsServer interface {
+	List(context.Context, *AllocationListRequest) (*AllocationListResponse, error)
+	Get(context.Context, *AllocationGetRequest) (*AllocationGetResponse, error)
+	Logs(*LogsRequest, Allocations_LogsServer) error
+}
+
+func RegisterAllocationsServer(s *grpc.Server, srv AllocationsServer) {
+	s.RegisterService(&_Allocations_serviceDesc, srv)
+}
+
+func _Allocations_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocationListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AllocationsServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nomad.v1.Allocations/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AllocationsServer).List(ctx, req.(*AllocationListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Allocations_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocationGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AllocationsServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/nomad.v1.Allocations/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AllocationsServer).Get(ctx, req.(*AllocationGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Allocations_Logs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(LogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AllocationsServer).Logs(m, &allocationsLogsServer{stream})
+}
+
+type Allocations_LogsServer interface {
+	Send(*LogFrame) error
+	grpc.ServerStream
+}
+
+type allocationsLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *allocationsLogsServer) Send(m *LogFrame) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Allocations_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "nomad.v1.Allocations",
+	HandlerType: (*AllocationsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _Allocations_List_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _Allocations_Get_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Logs",
+			Handler:       _Allocations_Logs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "nomad.proto",
+}
+
+// Client API for Events service
+
+type EventsClient interface {
+	Stream(ctx context.Context, in *EventStreamRequest, opts ...grpc.CallOption) (Events_StreamClient, error)
+}
+
+type eventsClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewEventsClient(cc *grpc.ClientConn) EventsClient {
+	return &eventsClient{cc}
+}
+
+func (c *eventsClient) Stream(ctx context.Context, in *EventStreamRequest, opts ...grpc.CallOption) (Events_StreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Events_serviceDesc.Streams[0], c.cc, "/nomad.v1.Events/Stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &eventsStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Events_StreamClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type eventsStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *eventsStreamClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Events service
+
+type EventsServer interface {
+	Stream(*EventStreamRequest, Events_StreamServer) error
+}
+
+func RegisterEventsServer(s *grpc.Server, srv EventsServer) {
+	s.RegisterService(&_Events_serviceDesc, srv)
+}
+
+func _Events_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EventStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventsServer).Stream(m, &eventsStreamServer{stream})
+}
+
+type Events_StreamServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type eventsStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *eventsStreamServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Events_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "nomad.v1.Events",
+	HandlerType: (*EventsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _Events_Stream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "nomad.proto",
+}
+
+func init() { proto.RegisterFile("nomad.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 1430 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xc5, 0x58, 0xcd, 0x72, 0xe3, 0x44,
+	0x10, 0x2e, 0x59, 0xfe, 0x91, 0xdb, 0x8e, 0xb3, 0x99, 0xfc, 0xe0, 0x35, 0x6c, 0xb2, 0x78, 0x59,
+	0xd8, 0xa2, 0x8a, 0x10, 0xb2, 0x14, 0x55, 0x14, 0xa7, 0x4d, 0xb2, 0x9b, 0xca, 0x56, 0x76, 0x01,
+	0x65, 0x0b, 0xaa, 0xf6, 0xe2, 0x92, 0xad, 0x89, 0x4b, 0xc4, 0x91, 0x84, 0x24, 0x27, 0x31, 0xcf,
+	0xc0, 0x03, 0x70, 0xa0, 0xb8, 0xc2, 0x95, 0x0b, 0x4f, 0xc1, 0x85, 0x03, 0x27, 0x4e, 0xbc, 0x09,
+	0x3d, 0x3d, 0x23, 0x6b, 0x24, 0xdb, 0xc4, 0x55, 0x1c, 0xb8, 0xa9, 0xbf, 0xee, 0xe9, 0xe9, 0xfe,
+	0x7a, 0xa6, 0xa7, 0x6d, 0x68, 0xf8, 0xc1, 0xa5, 0xe3, 0xee, 0x86, 0x51, 0x90, 0x04, 0xcc, 0x92,
+	0xc2, 0xd5, 0x47, 0xdd, 0x5f, 0x0d, 0x68, 0x7e, 0x39, 0xe6, 0xd1, 0xe4, 0xf3, 0x30, 0xf1, 0x02,
+	0x3f, 0x66, 0x5b, 0x50, 0x8d, 0xf8, 0x10, 0x3f, 0xdb, 0xc6, 0x7d, 0xe3, 0x51, 0xdd, 0x56, 0x12,
+	0xdb, 0x81, 0x86, 0x33, 0x1a, 0x05, 0xd7, 0xbd, 0x38, 0x71, 0x46, 0xbc, 0x5d, 0x42, 0xa5, 0x65,
+	0x03, 0x41, 0x67, 0x02, 0x11, 0x0b, 0xc3, 0x88, 0x9f, 0x7b, 0x37, 0x6d, 0x53, 0x2e, 0x94, 0x92,
+	0xc0, 0xcf, 0xbd, 0x51, 0xc2, 0xa3, 0x76, 0x59, 0xe2, 0x52, 0x62, 0x77, 0xc1, 0x0a, 0x79, 0xd4,
+	0x0b, 0x9d, 0x21, 0x6f, 0x57, 0x50, 0x53, 0xb1, 0x6b, 0x28, 0x7f, 0x81, 0x22, 0xbb, 0x07, 0xe0,
+	0xf3, 0x9b, 0xa4, 0x97, 0x04, 0x17, 0xdc, 0x6f, 0x57, 0x69, 0x59, 0x5d, 0x20, 0xaf, 0x04, 0xd0,
+	0x1d, 0x40, 0x9d, 0x42, 0x7e, 0xc1, 0x13, 0x87, 0x6d, 0x40, 0xc5, 0xf3, 0x5d, 0x7e, 0x43, 0xe1,
+	0x96, 0x6d, 0x29, 0xb0, 0xb7, 0xa1, 0x79, 0xe1, 0x07, 0xd7, 0x7e, 0x6f, 0xc4, 0x1d, 0x17, 0xb7,
+	0x96, 0xe1, 0x36, 0x08, 0x3b, 0x25, 0xa8, 0xb0, 0x89, 0x59, 0xdc, 0xe4, 0x5d, 0x68, 0x7e, 0x1d,
+	0x79, 0x09, 0xbf, 0x85, 0x97, 0xee, 0x2f, 0x26, 0xd4, 0x9e, 0x07, 0xfd, 0xb3, 0x64, 0xdc, 0x67,
+	0x2d, 0x28, 0x79, 0xae, 0xd2, 0xe3, 0x17, 0x7b, 0x13, 0xea, 0xa1, 0x13, 0x71, 0x3f, 0xe9, 0x21,
+	0x5c, 0x22, 0xd8, 0x92, 0xc0, 0x89, 0xcb, 0x18, 0x94, 0x7d, 0xe7, 0x92, 0xab, 0x9d, 0xe9, 0x5b,
+	0x60, 0xc9, 0x24, 0xe4, 0x8a, 0x29, 0xfa, 0x66, 0x1d, 0xe4, 0x29, 0xf2, 0x02, 0x8c, 0x65, 0xa2,
+	0x78, 0x9a, 0xca, 0xa4, 0xe3, 0x28, 0xb8, 0xde, 0x80, 0x68, 0xb2, 0xec, 0xa9, 0xcc, 0xde, 0x81,
+	0x15, 0xdc, 0x0b, 0xbd, 0x22, 0xd9, 0xde, 0x77, 0xdc, 0x6d, 0xd7, 0xc8, 0x20, 0x0f, 0x8a, 0x1d,
+	0xe3, 0x24, 0x08, 0xdb, 0x16, 0x29, 0xe9, 0x5b, 0xa4, 0x8a, 0x45, 0x4e, 0xc6, 0x71, 0xbb, 0x2e,
+	0x53, 0x95, 0x12, 0xfb, 0x00, 0x98, 0xfc, 0xea, 0xb9, 0x3c, 0x1e, 0x44, 0x1e, 0x31, 0xd3, 0x06,
+	0xb2, 0x59, 0x93, 0x9a, 0xa3, 0x4c, 0x21, 0x4e, 0x4c, 0x3c, 0xee, 0x5f, 0x7a, 0x48, 0xb1, 0x87,
+	0x79, 0x36, 0xd0, 0xce, 0xb4, 0x41, 0x42, 0xaf, 0x10, 0x11, 0x45, 0x1a, 0x44, 0xdc, 0x49, 0x78,
+	0x4f, 0x56, 0xb0, 0x49, 0x15, 0x6c, 0x48, 0xec, 0x24, 0xad, 0xe3, 0x25, 0x66, 0x73, 0x3e, 0x51,
+	0x26, 0x2b, 0xd2, 0x44, 0x62, 0xd2, 0xe4, 0x11, 0xdc, 0xf9, 0x26, 0xe8, 0xf7, 0x72, 0x66, 0x2d,
+	0x32, 0x6b, 0x21, 0xfe, 0x22, 0xb3, 0xec, 0xfe, 0x6e, 0x02, 0x3c, 0xc1, 0x03, 0x3b, 0x70, 0x28,
+	0xbe, 0x62, 0xb5, 0xde, 0x80, 0x1a, 0xbf, 0x72, 0x46, 0x59, 0xad, 0xaa, 0x42, 0x5c, 0x50, 0x29,
+	0x34, 0xf6, 0x03, 0x97, 0x0b, 0x63, 0x75, 0xac, 0x85, 0x88, 0xc6, 0x9b, 0x50, 0x15, 0xe1, 0x20,
+	0x5e, 0x21, 0xbc, 0x82, 0x12, 0xc2, 0x48, 0x86, 0x80, 0xaf, 0x78, 0x14, 0x0b, 0xd2, 0xaa, 0x14,
+	0x20, 0x20, 0xf4, 0x95, 0x44, 0xc4, 0x71, 0x4c, 0x9c, 0xf8, 0xa2, 0x37, 0x8c, 0x82, 0x71, 0x48,
+	0xb5, 0xc2, 0xe3, 0x28, 0x90, 0x63, 0x01, 0xb0, 0x87, 0xd0, 0x42, 0xd2, 0xbd, 0x88, 0xbb, 0x3d,
+	0x55, 0x1b, 0x8b, 0x4c, 0x56, 0x14, 0x7a, 0x26, 0x4b, 0xf4, 0x21, 0xac, 0xa7, 0x66, 0x7a, 0x8d,
+	0x64, 0x1d, 0x99, 0x52, 0xe9, 0x45, 0x7a, 0x00, 0x2b, 0x83, 0x91, 0x27, 0x8e, 0xa8, 0x72, 0x2b,
+	0xcb, 0xd9, 0x94, 0xe0, 0xd9, 0xb4, 0xf0, 0xca, 0x48, 0x77, 0xda, 0x90, 0x85, 0x97, 0x9a, 0x42,
+	0xe1, 0x55, 0x5d, 0xa9, 0xf0, 0x4d, 0x59, 0x78, 0x09, 0xcd, 0x2d, 0xfc, 0xca, 0xed, 0x85, 0x6f,
+	0xcd, 0x14, 0xbe, 0xfb, 0x47, 0x09, 0xe0, 0x29, 0x56, 0x68, 0x3c, 0xbf, 0x9c, 0xfa, 0xbd, 0x29,
+	0x15, 0xee, 0x4d, 0x7a, 0xcf, 0x4c, 0xed, 0x9e, 0xe1, 0x8e, 0x49, 0xe4, 0x0d, 0x87, 0x5c, 0x90,
+	0xd7, 0x9f, 0xa8, 0xb2, 0x36, 0xa6, 0xd8, 0xc1, 0x64, 0x51, 0x6d, 0xb5, 0xb3, 0x50, 0xcd, 0x9d,
+	0x05, 0x24, 0xd7, 0xe5, 0xe1, 0x28, 0x98, 0x5c, 0xaa, 0x1e, 0x20, 0xcb, 0xda, 0xcc, 0x40, 0x34,
+	0xca, 0x6e, 0x9b, 0xb5, 0xc4, 0x6d, 0xab, 0x2f, 0xba, 0x6d, 0x45, 0x4e, 0xe1, 0x76, 0x4e, 0x1b,
+	0xb3, 0x9c, 0xfe, 0x6d, 0x00, 0x1c, 0x4d, 0xa3, 0x9b, 0xe1, 0x34, 0x23, 0xa0, 0xf4, 0x2f, 0x87,
+	0xdb, 0x9c, 0x39, 0xdc, 0x59, 0x8e, 0xe5, 0x25, 0x72, 0xac, 0x2c, 0x9b, 0x63, 0xf5, 0xf6, 0x1c,
+	0x6b, 0xb3, 0x39, 0x7e, 0x5f, 0x82, 0xf2, 0x4b, 0x2c, 0xd0, 0x4c, 0x76, 0xdb, 0x00, 0xae, 0x93,
+	0x38, 0x03, 0xcc, 0x5c, 0x3d, 0x19, 0x75, 0x5b, 0x43, 0xe6, 0xf6, 0x01, 0xf1, 0x8a, 0x88, 0xda,
+	0x0f, 0x46, 0x4e, 0x9c, 0x66, 0x57, 0x17, 0xc8, 0xa1, 0x00, 0xc4, 0xeb, 0xe4, 0x46, 0x8e, 0x27,
+	0x73, 0xb2, 0x6c, 0x29, 0x68, 0x74, 0x54, 0x97, 0xa0, 0xa3, 0xb6, 0x2c, 0x1d, 0xd6, 0xed, 0x74,
+	0xd4, 0x67, 0xe9, 0xf8, 0xd9, 0x00, 0x86, 0x0f, 0x98, 0x8d, 0xcf, 0x59, 0x8c, 0x59, 0xda, 0xfc,
+	0xdb, 0x31, 0x8f, 0x13, 0x76, 0x07, 0x4c, 0x2c, 0x20, 0xb1, 0xd3, 0xb4, 0xc5, 0xa7, 0x38, 0xcd,
+	0xdc, 0x3f, 0x0f, 0xa2, 0x41, 0xba, 0x9f, 0x7c, 0x54, 0x9b, 0x0a, 0x5c, 0xdc, 0x8d, 0xcd, 0x79,
+	0xdd, 0x98, 0xed, 0x41, 0x2d, 0x90, 0x6f, 0x2b, 0xd1, 0xd6, 0xd8, 0xdf, 0xda, 0x4d, 0xa7, 0x92,
+	0x5d, 0xfd, 0xe5, 0xb5, 0x53, 0xb3, 0xee, 0x6f, 0x06, 0xac, 0xe7, 0x22, 0x8d, 0x43, 0x84, 0xb9,
+	0xde, 0xb8, 0x8d, 0x5c, 0xe3, 0x7e, 0x1f, 0xd6, 0x48, 0x91, 0x63, 0xa9, 0x44, 0xd1, 0xac, 0x0a,
+	0xc5, 0xa1, 0xc6, 0xd4, 0xf2, 0x81, 0x63, 0x63, 0xb9, 0x76, 0x22, 0xdf, 0xf3, 0x87, 0x69, 0xc1,
+	0xa7, 0x72, 0x36, 0x8d, 0x54, 0xb4, 0x69, 0xa4, 0x3b, 0x86, 0x0d, 0x8c, 0xfb, 0x88, 0x47, 0x05,
+	0x8e, 0xb3, 0xeb, 0x64, 0xe8, 0xd7, 0x09, 0x9d, 0x84, 0xe3, 0x68, 0x98, 0x0e, 0x59, 0x52, 0xd0,
+	0xf9, 0x32, 0x97, 0xe3, 0xeb, 0x47, 0x03, 0x36, 0x0b, 0xfb, 0xfe, 0x3f, 0x8c, 0x4d, 0x59, 0x29,
+	0xeb, 0xac, 0x1c, 0x40, 0x0b, 0xa3, 0x3b, 0xc5, 0xc8, 0x52, 0x3e, 0xb4, 0x14, 0x8d, 0x62, 0x8a,
+	0xfa, 0x90, 0x9a, 0xa5, 0xe8, 0xc0, 0xea, 0xd4, 0x87, 0xca, 0xed, 0x21, 0x94, 0x71, 0x7b, 0xe1,
+	0xc1, 0x44, 0x0f, 0x6b, 0x99, 0x07, 0x35, 0xa5, 0xd9, 0xa4, 0x66, 0xef, 0x41, 0x19, 0xa7, 0x20,
+	0x87, 0x92, 0x6b, 0xec, 0xaf, 0x17, 0x36, 0x12, 0xa3, 0xa5, 0x4d, 0x06, 0xdd, 0x13, 0xd8, 0xcc,
+	0x86, 0x86, 0xff, 0x16, 0xed, 0x04, 0xb6, 0x8a, 0xae, 0x54, 0xd0, 0x9f, 0xc8, 0xe9, 0x5a, 0x6a,
+	0xd2, 0xd8, 0x37, 0x32, 0x7f, 0xd9, 0x32, 0x5b, 0x37, 0x5c, 0x3e, 0x8b, 0x01, 0x6c, 0x64, 0x3e,
+	0x8e, 0xf9, 0x34, 0x09, 0x9c, 0xc2, 0xc9, 0x5f, 0x76, 0x14, 0x6a, 0x24, 0xe3, 0x59, 0xd0, 0xf2,
+	0x2b, 0x2d, 0x97, 0xdf, 0x95, 0x4e, 0x15, 0x6d, 0xa2, 0xd2, 0xfb, 0x18, 0x20, 0x8b, 0x5a, 0xb1,
+	0x35, 0x3f, 0x3b, 0xcd, 0x6e, 0xf9, 0xe4, 0x7e, 0x30, 0xa0, 0x71, 0x1a, 0x0c, 0xe3, 0x25, 0x92,
+	0x12, 0x2f, 0x3f, 0x0e, 0x55, 0xaa, 0xbb, 0xd3, 0xf7, 0xdc, 0x69, 0x40, 0xfc, 0x6a, 0x09, 0xc4,
+	0x8f, 0x1b, 0x3a, 0xb3, 0x96, 0xad, 0x24, 0x81, 0xe3, 0x08, 0x31, 0xf4, 0xd2, 0x57, 0x4a, 0x49,
+	0x84, 0x9f, 0x9f, 0xc7, 0x3c, 0xa1, 0x96, 0x6e, 0xda, 0x4a, 0xea, 0x6e, 0x83, 0x85, 0x91, 0x3d,
+	0x8b, 0xd4, 0x74, 0x2f, 0x5e, 0x13, 0xd5, 0x53, 0xe9, 0xbb, 0xfb, 0x1a, 0xd8, 0xd3, 0x2b, 0x9a,
+	0xb4, 0xf0, 0x66, 0x5d, 0xa6, 0x09, 0xa0, 0x37, 0x1c, 0xc4, 0xbd, 0x81, 0x3c, 0x09, 0xb8, 0x8b,
+	0x94, 0xb2, 0x8b, 0x54, 0xd2, 0x7f, 0xec, 0x64, 0x3f, 0x4d, 0xcc, 0xdc, 0x4f, 0x93, 0x9f, 0x4a,
+	0x50, 0x21, 0xe7, 0x62, 0x1d, 0x79, 0x48, 0xfb, 0x0c, 0x09, 0x0b, 0xbc, 0x3d, 0x90, 0x8d, 0x5f,
+	0xf6, 0x98, 0x39, 0xd7, 0x87, 0xde, 0x82, 0x7c, 0x41, 0xcb, 0x4b, 0x16, 0x14, 0x57, 0xf1, 0xe9,
+	0xc0, 0x46, 0x04, 0xe6, 0x56, 0x65, 0xc3, 0x9c, 0xad, 0xd9, 0x89, 0x55, 0xd9, 0xc0, 0x44, 0xf4,
+	0xe6, 0x56, 0x65, 0xe3, 0x8a, 0xad, 0xd9, 0xb1, 0x2e, 0x3e, 0xd6, 0xf8, 0x0c, 0xd3, 0xeb, 0xd9,
+	0xd8, 0x6f, 0x65, 0xf6, 0xe2, 0xe9, 0xb7, 0x49, 0xb7, 0xff, 0x97, 0x01, 0xe5, 0xe7, 0xa2, 0x19,
+	0x1c, 0x83, 0x95, 0xbe, 0x2a, 0xec, 0xad, 0x5c, 0xca, 0x85, 0x67, 0xb1, 0x73, 0x6f, 0x81, 0x56,
+	0x1d, 0xf4, 0x17, 0x62, 0x7c, 0x4a, 0xdb, 0x2d, 0xdb, 0xce, 0x19, 0xcf, 0xf4, 0xff, 0xce, 0xce,
+	0x42, 0xbd, 0x72, 0xf7, 0x19, 0x94, 0x45, 0x9b, 0x60, 0xed, 0x9c, 0xa1, 0xd6, 0x84, 0x3a, 0x77,
+	0xe7, 0x68, 0xe4, 0xe2, 0xfd, 0x3f, 0xf1, 0x56, 0x3c, 0xd1, 0x7a, 0xc5, 0x89, 0x72, 0xb6, 0x33,
+	0xaf, 0x4e, 0xba, 0xcf, 0xfb, 0x8b, 0x0d, 0x54, 0x5c, 0xcf, 0xc0, 0xc4, 0xeb, 0xad, 0xe7, 0x37,
+	0xaf, 0xb9, 0x74, 0x76, 0x16, 0xea, 0x95, 0x9f, 0xc7, 0x18, 0x12, 0xde, 0x5b, 0xb6, 0x99, 0x19,
+	0x6a, 0xf7, 0xb8, 0xc3, 0x72, 0x30, 0x5d, 0xa2, 0x3d, 0x63, 0xff, 0x10, 0xaa, 0x74, 0xaa, 0x63,
+	0xf6, 0x29, 0x54, 0xe5, 0xbd, 0xd1, 0x8b, 0x36, 0x7b, 0x9d, 0x3a, 0xab, 0x05, 0xed, 0x9e, 0x71,
+	0x50, 0x7f, 0x5d, 0x23, 0x2c, 0xec, 0xf7, 0xab, 0xf4, 0x9f, 0xc8, 0xe3, 0x7f, 0x00, 0xf8, 0x37,
+	0xe4, 0x7a, 0x22, 0x11, 0x00, 0x00,
+}
@@ -0,0 +1,957 @@
+package openapi
+
+import (
+	"github.com/hashicorp/nomad/api"
+)
+
+// Endpoint describes an HTTP API endpoint and the api package types it
+// exchanges. The request and response are given as values of those types.
+type Endpoint struct {
+	// ID is the unique operation ID, used as the method name by most
+	// client generators.
+	ID string
+
+	// Method and Path of the endpoint. Path parameters are written as
+	// "{name}" segments.
+	Method string
+	Path   string
+
+	// Tag groups the endpoint with related ones.
+	Tag     string
+	Summary string
+
+	// Query lists the endpoint specific query parameters.
+	Query []Parameter
+
+	// Blocking is set if the endpoint supports blocking queries and List if
+	// it supports prefix lookups, pagination and filtering.
+	Blocking bool
+	List     bool
+
+	Request  interface{}
+	Response interface{}
+}
+
+// Endpoints is the list of endpoints included in the specification. It must
+// be updated along with the HTTP server and the api package.
+var Endpoints = []*Endpoint{
+	// Jobs
+	{
+		ID:       "ListJobs",
+		Method:   "GET",
+		Path:     "/v1/jobs",
+		Tag:      "Jobs",
+		Summary:  "List all jobs",
+		Blocking: true,
+		List:     true,
+		Response: []*api.JobListStub{},
+	},
+	{
+		ID:       "RegisterJob",
+		Method:   "PUT",
+		Path:     "/v1/jobs",
+		Tag:      "Jobs",
+		Summary:  "Register a job",
+		Request:  &api.JobRegisterRequest{},
+		Response: &api.JobRegisterResponse{},
+	},
+	{
+		ID:       "ValidateJob",
+		Method:   "PUT",
+		Path:     "/v1/validate/job",
+		Tag:      "Jobs",
+		Summary:  "Validate a job",
+		Request:  &api.JobValidateRequest{},
+		Response: &api.JobValidateResponse{},
+	},
+	{
+		ID:       "GetJob",
+		Method:   "GET",
+		Path:     "/v1/job/{jobID}",
+		Tag:      "Jobs",
+		Summary:  "Read a job",
+		Blocking: true,
+		Response: &api.Job{},
+	},
+	{
+		ID:       "UpdateJob",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}",
+		Tag:      "Jobs",
+		Summary:  "Update a job",
+		Request:  &api.JobRegisterRequest{},
+		Response: &api.JobRegisterResponse{},
+	},
+	{
+		ID:      "DeregisterJob",
+		Method:  "DELETE",
+		Path:    "/v1/job/{jobID}",
+		Tag:     "Jobs",
+		Summary: "Deregister a job",
+		Query: []Parameter{
+			{Name: "purge", Type: "boolean", Description: "Purge the job from the state store."},
+		},
+		Response: &api.JobDeregisterResponse{},
+	},
+	{
+		ID:      "GetJobVersions",
+		Method:  "GET",
+		Path:    "/v1/job/{jobID}/versions",
+		Tag:     "Jobs",
+		Summary: "List the versions of a job",
+		Query: []Parameter{
+			{Name: "diffs", Type: "boolean", Description: "Include the diffs between versions."},
+			{Name: "diff_version", Type: "integer", Description: "Diff every version against the given version instead of its predecessor."},
+		},
+		Blocking: true,
+		Response: &api.JobVersionsResponse{},
+	},
+	{
+		ID:      "GetJobAllocations",
+		Method:  "GET",
+		Path:    "/v1/job/{jobID}/allocations",
+		Tag:     "Jobs",
+		Summary: "List the allocations of a job",
+		Query: []Parameter{
+			{Name: "all", Type: "boolean", Description: "Include allocations of previous job instances with the same ID."},
+		},
+		Blocking: true,
+		Response: []*api.AllocationListStub{},
+	},
+	{
+		ID:       "GetJobEvaluations",
+		Method:   "GET",
+		Path:     "/v1/job/{jobID}/evaluations",
+		Tag:      "Jobs",
+		Summary:  "List the evaluations of a job",
+		Blocking: true,
+		Response: []*api.Evaluation{},
+	},
+	{
+		ID:       "GetJobDependencies",
+		Method:   "GET",
+		Path:     "/v1/job/{jobID}/dependencies",
+		Tag:      "Jobs",
+		Summary:  "Read the graph of dependencies a job belongs to",
+		Blocking: true,
+		Response: []*api.JobDAGNode{},
+	},
+	{
+		ID:       "GetJobDeployments",
+		Method:   "GET",
+		Path:     "/v1/job/{jobID}/deployments",
+		Tag:      "Jobs",
+		Summary:  "List the deployments of a job",
+		Blocking: true,
+		Response: []*api.Deployment{},
+	},
+	{
+		ID:       "GetJobLatestDeployment",
+		Method:   "GET",
+		Path:     "/v1/job/{jobID}/deployment",
+		Tag:      "Jobs",
+		Summary:  "Read the most recent deployment of a job",
+		Blocking: true,
+		Response: &api.Deployment{},
+	},
+	{
+		ID:       "GetJobSummary",
+		Method:   "GET",
+		Path:     "/v1/job/{jobID}/summary",
+		Tag:      "Jobs",
+		Summary:  "Read the summary of a job",
+		Blocking: true,
+		Response: &api.JobSummary{},
+	},
+	{
+		ID:       "EvaluateJob",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}/evaluate",
+		Tag:      "Jobs",
+		Summary:  "Create a new evaluation for a job",
+		Response: &api.JobRegisterResponse{},
+	},
+	{
+		ID:       "PlanJob",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}/plan",
+		Tag:      "Jobs",
+		Summary:  "Dry-run the scheduler for a job",
+		Request:  &api.JobPlanRequest{},
+		Response: &api.JobPlanResponse{},
+	},
+	{
+		ID:       "DispatchJob",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}/dispatch",
+		Tag:      "Jobs",
+		Summary:  "Dispatch an instance of a parameterized job",
+		Request:  &api.JobDispatchRequest{},
+		Response: &api.JobDispatchResponse{},
+	},
+	{
+		ID:       "RevertJob",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}/revert",
+		Tag:      "Jobs",
+		Summary:  "Revert a job to a previous version",
+		Request:  &api.JobRevertRequest{},
+		Response: &api.JobRegisterResponse{},
+	},
+	{
+		ID:       "SetJobStability",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}/stable",
+		Tag:      "Jobs",
+		Summary:  "Set the stability of a job version",
+		Request:  &api.JobStabilityRequest{},
+		Response: &api.JobStabilityResponse{},
+	},
+	{
+		ID:       "UpdateJobPriority",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}/priority",
+		Tag:      "Jobs",
+		Summary:  "Change the priority of a job without changing its placements",
+		Request:  &api.JobUpdatePriorityRequest{},
+		Response: &api.JobUpdatePriorityResponse{},
+	},
+	{
+		ID:       "ScaleJob",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}/scale",
+		Tag:      "Jobs",
+		Summary:  "Change the count of a task group of a job",
+		Request:  &api.JobScaleRequest{},
+		Response: &api.JobRegisterResponse{},
+	},
+	{
+		ID:       "ForcePeriodicJob",
+		Method:   "PUT",
+		Path:     "/v1/job/{jobID}/periodic/force",
+		Tag:      "Jobs",
+		Summary:  "Force a new instance of a periodic job",
+		Response: &api.PeriodicForceResponse{},
+	},
+
+	// Allocations
+	{
+		ID:       "ListAllocations",
+		Method:   "GET",
+		Path:     "/v1/allocations",
+		Tag:      "Allocations",
+		Summary:  "List all allocations",
+		Blocking: true,
+		List:     true,
+		Response: []*api.AllocationListStub{},
+	},
+	{
+		ID:       "GetAllocation",
+		Method:   "GET",
+		Path:     "/v1/allocation/{allocID}",
+		Tag:      "Allocations",
+		Summary:  "Read an allocation",
+		Blocking: true,
+		Response: &api.Allocation{},
+	},
+	{
+		ID:       "StopAllocation",
+		Method:   "PUT",
+		Path:     "/v1/allocation/{allocID}/stop",
+		Tag:      "Allocations",
+		Summary:  "Stop an allocation and have it replaced",
+		Response: &api.AllocStopResponse{},
+	},
+	{
+		ID:      "SignalAllocation",
+		Method:  "PUT",
+		Path:    "/v1/allocation/{allocID}/signal",
+		Tag:     "Allocations",
+		Summary: "Signal the tasks of an allocation through its client",
+		Request: &api.AllocSignalRequest{},
+	},
+
+	// Evaluations
+	{
+		ID:       "ListEvaluations",
+		Method:   "GET",
+		Path:     "/v1/evaluations",
+		Tag:      "Evaluations",
+		Summary:  "List all evaluations",
+		Blocking: true,
+		List:     true,
+		Response: []*api.Evaluation{},
+	},
+	{
+		ID:       "GetEvaluation",
+		Method:   "GET",
+		Path:     "/v1/evaluation/{evalID}",
+		Tag:      "Evaluations",
+		Summary:  "Read an evaluation",
+		Blocking: true,
+		Response: &api.Evaluation{},
+	},
+	{
+		ID:       "GetEvaluationAllocations",
+		Method:   "GET",
+		Path:     "/v1/evaluation/{evalID}/allocations",
+		Tag:      "Evaluations",
+		Summary:  "List the allocations created by an evaluation",
+		Blocking: true,
+		Response: []*api.AllocationListStub{},
+	},
+
+	// Deployments
+	{
+		ID:       "ListDeployments",
+		Method:   "GET",
+		Path:     "/v1/deployments",
+		Tag:      "Deployments",
+		Summary:  "List all deployments",
+		Blocking: true,
+		List:     true,
+		Response: []*api.Deployment{},
+	},
+	{
+		ID:       "GetDeployment",
+		Method:   "GET",
+		Path:     "/v1/deployment/{deploymentID}",
+		Tag:      "Deployments",
+		Summary:  "Read a deployment",
+		Blocking: true,
+		Response: &api.Deployment{},
+	},
+	{
+		ID:       "GetDeploymentAllocations",
+		Method:   "GET",
+		Path:     "/v1/deployment/allocations/{deploymentID}",
+		Tag:      "Deployments",
+		Summary:  "List the allocations of a deployment",
+		Blocking: true,
+		Response: []*api.AllocationListStub{},
+	},
+	{
+		ID:       "FailDeployment",
+		Method:   "PUT",
+		Path:     "/v1/deployment/fail/{deploymentID}",
+		Tag:      "Deployments",
+		Summary:  "Mark a deployment as failed",
+		Request:  &api.DeploymentFailRequest{},
+		Response: &api.DeploymentUpdateResponse{},
+	},
+	{
+		ID:       "PauseDeployment",
+		Method:   "PUT",
+		Path:     "/v1/deployment/pause/{deploymentID}",
+		Tag:      "Deployments",
+		Summary:  "Pause or resume a deployment",
+		Request:  &api.DeploymentPauseRequest{},
+		Response: &api.DeploymentUpdateResponse{},
+	},
+	{
+		ID:       "PromoteDeployment",
+		Method:   "PUT",
+		Path:     "/v1/deployment/promote/{deploymentID}",
+		Tag:      "Deployments",
+		Summary:  "Promote the canaries of a deployment",
+		Request:  &api.DeploymentPromoteRequest{},
+		Response: &api.DeploymentUpdateResponse{},
+	},
+	{
+		ID:       "SetDeploymentAllocHealth",
+		Method:   "PUT",
+		Path:     "/v1/deployment/allocation-health/{deploymentID}",
+		Tag:      "Deployments",
+		Summary:  "Set the health of allocations in a deployment",
+		Request:  &api.DeploymentAllocHealthRequest{},
+		Response: &api.DeploymentUpdateResponse{},
+	},
+
+	// Namespaces
+	{
+		ID:       "ListNamespaces",
+		Method:   "GET",
+		Path:     "/v1/namespaces",
+		Tag:      "Namespaces",
+		Summary:  "List all namespaces",
+		Blocking: true,
+		List:     true,
+		Response: []*api.Namespace{},
+	},
+	{
+		ID:       "GetNamespace",
+		Method:   "GET",
+		Path:     "/v1/namespace/{namespaceName}",
+		Tag:      "Namespaces",
+		Summary:  "Read a namespace",
+		Blocking: true,
+		Response: &api.Namespace{},
+	},
+	{
+		ID:      "ApplyNamespace",
+		Method:  "PUT",
+		Path:    "/v1/namespace/{namespaceName}",
+		Tag:     "Namespaces",
+		Summary: "Create or update a namespace",
+		Request: &api.Namespace{},
+	},
+	{
+		ID:      "DeleteNamespace",
+		Method:  "DELETE",
+		Path:    "/v1/namespace/{namespaceName}",
+		Tag:     "Namespaces",
+		Summary: "Delete a namespace",
+	},
+
+	// ACLs
+	{
+		ID:       "BootstrapACL",
+		Method:   "PUT",
+		Path:     "/v1/acl/bootstrap",
+		Tag:      "ACL",
+		Summary:  "Create the initial management token",
+		Response: &api.ACLToken{},
+	},
+	{
+		ID:       "ListACLPolicies",
+		Method:   "GET",
+		Path:     "/v1/acl/policies",
+		Tag:      "ACL",
+		Summary:  "List all ACL policies",
+		Blocking: true,
+		List:     true,
+		Response: []*api.ACLPolicyListStub{},
+	},
+	{
+		ID:       "GetACLPolicy",
+		Method:   "GET",
+		Path:     "/v1/acl/policy/{policyName}",
+		Tag:      "ACL",
+		Summary:  "Read an ACL policy",
+		Blocking: true,
+		Response: &api.ACLPolicy{},
+	},
+	{
+		ID:      "UpsertACLPolicy",
+		Method:  "PUT",
+		Path:    "/v1/acl/policy/{policyName}",
+		Tag:     "ACL",
+		Summary: "Create or update an ACL policy",
+		Request: &api.ACLPolicy{},
+	},
+	{
+		ID:      "DeleteACLPolicy",
+		Method:  "DELETE",
+		Path:    "/v1/acl/policy/{policyName}",
+		Tag:     "ACL",
+		Summary: "Delete an ACL policy",
+	},
+	{
+		ID:       "ListACLRoles",
+		Method:   "GET",
+		Path:     "/v1/acl/roles",
+		Tag:      "ACL",
+		Summary:  "List all ACL roles",
+		Blocking: true,
+		List:     true,
+		Response: []*api.ACLRoleListStub{},
+	},
+	{
+		ID:       "GetACLRole",
+		Method:   "GET",
+		Path:     "/v1/acl/role/{roleName}",
+		Tag:      "ACL",
+		Summary:  "Read an ACL role",
+		Blocking: true,
+		Response: &api.ACLRole{},
+	},
+	{
+		ID:      "UpsertACLRole",
+		Method:  "PUT",
+		Path:    "/v1/acl/role/{roleName}",
+		Tag:     "ACL",
+		Summary: "Create or update an ACL role",
+		Request: &api.ACLRole{},
+	},
+	{
+		ID:      "DeleteACLRole",
+		Method:  "DELETE",
+		Path:    "/v1/acl/role/{roleName}",
+		Tag:     "ACL",
+		Summary: "Delete an ACL role",
+	},
+	{
+		ID:       "ListACLAuthMethods",
+		Method:   "GET",
+		Path:     "/v1/acl/auth-methods",
+		Tag:      "ACL",
+		Summary:  "List all ACL auth methods",
+		Blocking: true,
+		List:     true,
+		Response: []*api.ACLAuthMethodListStub{},
+	},
+	{
+		ID:       "GetACLAuthMethod",
+		Method:   "GET",
+		Path:     "/v1/acl/auth-method/{methodName}",
+		Tag:      "ACL",
+		Summary:  "Read an ACL auth method",
+		Blocking: true,
+		Response: &api.ACLAuthMethod{},
+	},
+	{
+		ID:      "UpsertACLAuthMethod",
+		Method:  "PUT",
+		Path:    "/v1/acl/auth-method/{methodName}",
+		Tag:     "ACL",
+		Summary: "Create or update an ACL auth method",
+		Request: &api.ACLAuthMethod{},
+	},
+	{
+		ID:      "DeleteACLAuthMethod",
+		Method:  "DELETE",
+		Path:    "/v1/acl/auth-method/{methodName}",
+		Tag:     "ACL",
+		Summary: "Delete an ACL auth method and its binding rules",
+	},
+	{
+		ID:       "ListACLBindingRules",
+		Method:   "GET",
+		Path:     "/v1/acl/binding-rules",
+		Tag:      "ACL",
+		Summary:  "List all ACL binding rules",
+		Blocking: true,
+		List:     true,
+		Response: []*api.ACLBindingRule{},
+	},
+	{
+		ID:       "CreateACLBindingRule",
+		Method:   "PUT",
+		Path:     "/v1/acl/binding-rule",
+		Tag:      "ACL",
+		Summary:  "Create an ACL binding rule",
+		Request:  &api.ACLBindingRule{},
+		Response: &api.ACLBindingRule{},
+	},
+	{
+		ID:       "GetACLBindingRule",
+		Method:   "GET",
+		Path:     "/v1/acl/binding-rule/{ruleID}",
+		Tag:      "ACL",
+		Summary:  "Read an ACL binding rule",
+		Blocking: true,
+		Response: &api.ACLBindingRule{},
+	},
+	{
+		ID:       "UpdateACLBindingRule",
+		Method:   "PUT",
+		Path:     "/v1/acl/binding-rule/{ruleID}",
+		Tag:      "ACL",
+		Summary:  "Update an ACL binding rule",
+		Request:  &api.ACLBindingRule{},
+		Response: &api.ACLBindingRule{},
+	},
+	{
+		ID:      "DeleteACLBindingRule",
+		Method:  "DELETE",
+		Path:    "/v1/acl/binding-rule/{ruleID}",
+		Tag:     "ACL",
+		Summary: "Delete an ACL binding rule",
+	},
+	{
+		ID:       "GetACLOIDCAuthURL",
+		Method:   "PUT",
+		Path:     "/v1/acl/oidc/auth-url",
+		Tag:      "ACL",
+		Summary:  "Start logging in with an OIDC auth method",
+		Request:  &api.ACLOIDCAuthURLRequest{},
+		Response: &api.ACLOIDCAuthURLResponse{},
+	},
+	{
+		ID:       "CompleteACLOIDCAuth",
+		Method:   "PUT",
+		Path:     "/v1/acl/oidc/complete-auth",
+		Tag:      "ACL",
+		Summary:  "Complete logging in with an OIDC auth method",
+		Request:  &api.ACLOIDCCompleteAuthRequest{},
+		Response: &api.ACLToken{},
+	},
+	{
+		ID:       "LoginACL",
+		Method:   "PUT",
+		Path:     "/v1/acl/login",
+		Tag:      "ACL",
+		Summary:  "Log in with a JWT auth method",
+		Request:  &api.ACLLoginRequest{},
+		Response: &api.ACLToken{},
+	},
+	{
+		ID:      "ListACLTokens",
+		Method:  "GET",
+		Path:    "/v1/acl/tokens",
+		Tag:     "ACL",
+		Summary: "List all ACL tokens",
+		Query: []Parameter{
+			{Name: "global", Type: "boolean", Description: "Only list the global tokens."},
+		},
+		Blocking: true,
+		List:     true,
+		Response: []*api.ACLTokenListStub{},
+	},
+	{
+		ID:       "CreateACLToken",
+		Method:   "PUT",
+		Path:     "/v1/acl/token",
+		Tag:      "ACL",
+		Summary:  "Create an ACL token",
+		Request:  &api.ACLToken{},
+		Response: &api.ACLToken{},
+	},
+	{
+		ID:       "GetACLTokenSelf",
+		Method:   "GET",
+		Path:     "/v1/acl/token/self",
+		Tag:      "ACL",
+		Summary:  "Read the ACL token of the request",
+		Blocking: true,
+		Response: &api.ACLToken{},
+	},
+	{
+		ID:       "GetACLToken",
+		Method:   "GET",
+		Path:     "/v1/acl/token/{accessorID}",
+		Tag:      "ACL",
+		Summary:  "Read an ACL token",
+		Blocking: true,
+		Response: &api.ACLToken{},
+	},
+	{
+		ID:       "UpdateACLToken",
+		Method:   "PUT",
+		Path:     "/v1/acl/token/{accessorID}",
+		Tag:      "ACL",
+		Summary:  "Update an ACL token",
+		Request:  &api.ACLToken{},
+		Response: &api.ACLToken{},
+	},
+	{
+		ID:      "DeleteACLToken",
+		Method:  "DELETE",
+		Path:    "/v1/acl/token/{accessorID}",
+		Tag:     "ACL",
+		Summary: "Delete an ACL token",
+	},
+
+	// Nodes
+	{
+		ID:       "ListNodes",
+		Method:   "GET",
+		Path:     "/v1/nodes",
+		Tag:      "Nodes",
+		Summary:  "List all nodes",
+		Blocking: true,
+		List:     true,
+		Response: []*api.NodeListStub{},
+	},
+	{
+		ID:       "GetNode",
+		Method:   "GET",
+		Path:     "/v1/node/{nodeID}",
+		Tag:      "Nodes",
+		Summary:  "Read a node",
+		Blocking: true,
+		Response: &api.Node{},
+	},
+	{
+		ID:       "GetNodeAllocations",
+		Method:   "GET",
+		Path:     "/v1/node/{nodeID}/allocations",
+		Tag:      "Nodes",
+		Summary:  "List the allocations of a node",
+		Blocking: true,
+		Response: []*api.Allocation{},
+	},
+	{
+		ID:      "DrainNode",
+		Method:  "PUT",
+		Path:    "/v1/node/{nodeID}/drain",
+		Tag:     "Nodes",
+		Summary: "Toggle the drain mode of a node",
+		Query: []Parameter{
+			{Name: "enable", Type: "boolean", Required: true, Description: "Whether to enable drain mode."},
+		},
+	},
+	{
+		ID:      "EvaluateNode",
+		Method:  "PUT",
+		Path:    "/v1/node/{nodeID}/evaluate",
+		Tag:     "Nodes",
+		Summary: "Create new evaluations for the jobs on a node",
+	},
+
+	// Client
+	{
+		ID:       "GetClientStats",
+		Method:   "GET",
+		Path:     "/v1/client/stats",
+		Tag:      "Client",
+		Summary:  "Read the resource usage of the client",
+		Response: &api.HostStats{},
+	},
+	{
+		ID:       "GetClientMeta",
+		Method:   "GET",
+		Path:     "/v1/client/metadata",
+		Tag:      "Client",
+		Summary:  "Read the static, dynamic and merged meta of the client node",
+		Response: &api.NodeMetaResponse{},
+	},
+	{
+		ID:       "ApplyClientMeta",
+		Method:   "PUT",
+		Path:     "/v1/client/metadata",
+		Tag:      "Client",
+		Summary:  "Apply changes to the dynamic meta of the client node",
+		Request:  &api.NodeMetaApplyRequest{},
+		Response: &api.NodeMetaResponse{},
+	},
+	{
+		ID:       "GetClientAllocationStats",
+		Method:   "GET",
+		Path:     "/v1/client/allocation/{allocID}/stats",
+		Tag:      "Client",
+		Summary:  "Read the resource usage of an allocation",
+		Response: &api.AllocResourceUsage{},
+	},
+	{
+		ID:      "SignalClientAllocation",
+		Method:  "PUT",
+		Path:    "/v1/client/allocation/{allocID}/signal",
+		Tag:     "Client",
+		Summary: "Signal the tasks of an allocation",
+		Request: &api.AllocSignalRequest{},
+	},
+	{
+		ID:      "RestartClientAllocation",
+		Method:  "PUT",
+		Path:    "/v1/client/allocation/{allocID}/restart",
+		Tag:     "Client",
+		Summary: "Restart the tasks of an allocation in place",
+		Request: &api.AllocRestartRequest{},
+	},
+	{
+		ID:       "ExecClientAllocation",
+		Method:   "PUT",
+		Path:     "/v1/client/allocation/{allocID}/exec",
+		Tag:      "Client",
+		Summary:  "Run a command inside a task of an allocation",
+		Request:  &api.AllocExecRequest{},
+		Response: &api.AllocExecResponse{},
+	},
+	{
+		ID:      "ListClientAllocationFiles",
+		Method:  "GET",
+		Path:    "/v1/client/fs/ls/{allocID}",
+		Tag:     "Client",
+		Summary: "List the files of an allocation directory",
+		Query: []Parameter{
+			{Name: "path", Description: "The path relative to the allocation directory."},
+		},
+		Response: []*api.AllocFileInfo{},
+	},
+	{
+		ID:      "StatClientAllocationFile",
+		Method:  "GET",
+		Path:    "/v1/client/fs/stat/{allocID}",
+		Tag:     "Client",
+		Summary: "Stat a file in an allocation directory",
+		Query: []Parameter{
+			{Name: "path", Description: "The path relative to the allocation directory."},
+		},
+		Response: &api.AllocFileInfo{},
+	},
+
+	// Services
+	{
+		ID:       "ListServices",
+		Method:   "GET",
+		Path:     "/v1/services",
+		Tag:      "Services",
+		Summary:  "List the services registered with the Nomad provider",
+		Blocking: true,
+		List:     true,
+		Response: []*api.ServiceRegistrationListStub{},
+	},
+	{
+		ID:       "GetService",
+		Method:   "GET",
+		Path:     "/v1/service/{serviceName}",
+		Tag:      "Services",
+		Summary:  "Read the registrations of a service",
+		Blocking: true,
+		Response: []*api.ServiceRegistration{},
+	},
+	{
+		ID:      "DeleteServiceRegistration",
+		Method:  "DELETE",
+		Path:    "/v1/service/{serviceName}/{serviceID}",
+		Tag:     "Services",
+		Summary: "Delete a registration of a service",
+	},
+
+	// Search
+	{
+		ID:      "FuzzySearch",
+		Method:  "GET",
+		Path:    "/v1/search/fuzzy",
+		Tag:     "Search",
+		Summary: "Search objects by partial names and IDs",
+		Query: []Parameter{
+			{Name: "text", Required: true, Description: "The text to search for."},
+			{Name: "context", Description: "The type of objects to search."},
+		},
+		Blocking: true,
+		Response: &api.FuzzySearchResponse{},
+	},
+
+	// Agent
+	{
+		ID:       "GetAgentSelf",
+		Method:   "GET",
+		Path:     "/v1/agent/self",
+		Tag:      "Agent",
+		Summary:  "Read the configuration and statistics of the agent",
+		Response: &api.AgentSelf{},
+	},
+	{
+		ID:       "GetAgentMembers",
+		Method:   "GET",
+		Path:     "/v1/agent/members",
+		Tag:      "Agent",
+		Summary:  "List the server members of the gossip pool",
+		Response: &api.ServerMembers{},
+	},
+	{
+		ID:       "GetAgentServers",
+		Method:   "GET",
+		Path:     "/v1/agent/servers",
+		Tag:      "Agent",
+		Summary:  "List the servers known to the client",
+		Response: []string{},
+	},
+	{
+		ID:      "SetAgentServers",
+		Method:  "PUT",
+		Path:    "/v1/agent/servers",
+		Tag:     "Agent",
+		Summary: "Update the servers known to the client",
+		Query: []Parameter{
+			{Name: "address", Required: true, Description: "The address of a server, may be repeated."},
+		},
+	},
+	{
+		ID:      "ForceLeave",
+		Method:  "PUT",
+		Path:    "/v1/agent/force-leave",
+		Tag:     "Agent",
+		Summary: "Force a failed member into the left state",
+		Query: []Parameter{
+			{Name: "node", Required: true, Description: "The name of the member."},
+		},
+	},
+	{
+		ID:       "ListKeys",
+		Method:   "GET",
+		Path:     "/v1/agent/keyring/list",
+		Tag:      "Agent",
+		Summary:  "List the gossip encryption keys",
+		Response: &api.KeyringResponse{},
+	},
+	{
+		ID:       "GetAgentMetrics",
+		Method:   "GET",
+		Path:     "/v1/agent/metrics",
+		Tag:      "Agent",
+		Summary:  "Read the recent metrics of the agent",
+		Response: []*api.MetricsInterval{},
+	},
+	{
+		ID:       "GetAgentLogs",
+		Method:   "GET",
+		Path:     "/v1/agent/logs",
+		Tag:      "Agent",
+		Summary:  "Read the recent logs of the agent",
+		Response: []string{},
+	},
+
+	// Status, regions, operator and system
+	{
+		ID:       "GetLeader",
+		Method:   "GET",
+		Path:     "/v1/status/leader",
+		Tag:      "Status",
+		Summary:  "Read the address of the leader",
+		Response: "",
+	},
+	{
+		ID:       "GetPeers",
+		Method:   "GET",
+		Path:     "/v1/status/peers",
+		Tag:      "Status",
+		Summary:  "List the Raft peers",
+		Response: []string{},
+	},
+	{
+		ID:       "ListRegions",
+		Method:   "GET",
+		Path:     "/v1/regions",
+		Tag:      "Regions",
+		Summary:  "List the known regions",
+		Response: []string{},
+	},
+	{
+		ID:       "GetRaftConfiguration",
+		Method:   "GET",
+		Path:     "/v1/operator/raft/configuration",
+		Tag:      "Operator",
+		Summary:  "Read the Raft configuration",
+		Response: &api.RaftConfiguration{},
+	},
+	{
+		ID:       "GetSchedulerConfiguration",
+		Method:   "GET",
+		Path:     "/v1/operator/scheduler/configuration",
+		Tag:      "Operator",
+		Summary:  "Read the scheduler configuration",
+		Response: &api.SchedulerConfigurationResponse{},
+	},
+	{
+		ID:       "SetSchedulerConfiguration",
+		Method:   "PUT",
+		Path:     "/v1/operator/scheduler/configuration",
+		Tag:      "Operator",
+		Summary:  "Update the scheduler configuration",
+		Request:  &api.SchedulerConfiguration{},
+		Response: &api.SchedulerSetConfigurationResponse{},
+		Query: []Parameter{
+			{Name: "cas", Type: "integer", Description: "Only update the configuration if its modify index matches this value."},
+		},
+	},
+	{
+		ID:      "RunGarbageCollection",
+		Method:  "PUT",
+		Path:    "/v1/system/gc",
+		Tag:     "System",
+		Summary: "Run a garbage collection",
+		Query: []Parameter{
+			{Name: "scope", Description: "Comma separated object types to collect: jobs, evals, deployments or nodes."},
+		},
+	},
+	{
+		ID:      "ReconcileJobSummaries",
+		Method:  "PUT",
+		Path:    "/v1/system/reconcile/summaries",
+		Tag:     "System",
+		Summary: "Reconcile the summaries of all jobs",
+	},
+}
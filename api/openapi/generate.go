@@ -0,0 +1,33 @@
+//go:build ignore
+// +build ignore
+
+// This program writes the OpenAPI specification of the HTTP API to
+// openapi.json. It is invoked by go generate.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/hashicorp/nomad/api/openapi"
+)
+
+func main() {
+	spec, err := openapi.Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate specification: %v\n", err)
+		os.Exit(1)
+	}
+
+	out, err := spec.JSON()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode specification: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := ioutil.WriteFile("openapi.json", out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write specification: %v\n", err)
+		os.Exit(1)
+	}
+}
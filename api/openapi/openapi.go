@@ -0,0 +1,284 @@
+// Package openapi generates an OpenAPI 2.0 (Swagger) specification of the
+// Nomad HTTP API from the types of the api package. Deriving the schemas from
+// the Go client keeps the published specification and the client in sync, and
+// allows HTTP clients for other languages to be generated from it.
+package openapi
+
+//go:generate go run generate.go
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Version is the version of the HTTP API described by the specification.
+const Version = "1"
+
+// Spec is the root document of an OpenAPI 2.0 specification.
+type Spec struct {
+	Swagger     string                `json:"swagger"`
+	Info        Info                  `json:"info"`
+	BasePath    string                `json:"basePath"`
+	Schemes     []string              `json:"schemes"`
+	Consumes    []string              `json:"consumes"`
+	Produces    []string              `json:"produces"`
+	Paths       map[string]PathItem   `json:"paths"`
+	Definitions map[string]*Schema    `json:"definitions"`
+	Parameters  map[string]*Parameter `json:"parameters"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// PathItem maps the lower case HTTP methods of a path to their operation.
+type PathItem map[string]*Operation
+
+// Operation describes a single method on a path.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Tags        []string             `json:"tags"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a path, query or body parameter. Parameters shared by
+// many operations are referenced through Ref.
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Type        string  `json:"type,omitempty"`
+	Format      string  `json:"format,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// Response describes the response of an operation.
+type Response struct {
+	Description string             `json:"description"`
+	Schema      *Schema            `json:"schema,omitempty"`
+	Headers     map[string]*Header `json:"headers,omitempty"`
+}
+
+// Header describes a response header.
+type Header struct {
+	Description string `json:"description"`
+	Type        string `json:"type"`
+}
+
+// Generate returns the specification of the endpoints listed in Endpoints.
+func Generate() (*Spec, error) {
+	s := &Spec{
+		Swagger: "2.0",
+		Info: Info{
+			Title:       "Nomad",
+			Description: "The Nomad HTTP API.",
+			Version:     Version,
+		},
+		BasePath:    "/v1",
+		Schemes:     []string{"http", "https"},
+		Consumes:    []string{"application/json"},
+		Produces:    []string{"application/json"},
+		Paths:       make(map[string]PathItem),
+		Definitions: make(map[string]*Schema),
+		Parameters:  commonParameters(),
+	}
+
+	gen := newSchemaGenerator(s.Definitions)
+	for _, e := range Endpoints {
+		path := strings.TrimPrefix(e.Path, s.BasePath)
+		item, ok := s.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			s.Paths[path] = item
+		}
+
+		method := strings.ToLower(e.Method)
+		if _, ok := item[method]; ok {
+			return nil, fmt.Errorf("duplicate endpoint %s %s", e.Method, e.Path)
+		}
+
+		op, err := e.operation(gen)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s %s: %v", e.Method, e.Path, err)
+		}
+		item[method] = op
+	}
+
+	return s, nil
+}
+
+// JSON returns the indented JSON encoding of the specification.
+func (s *Spec) JSON() ([]byte, error) {
+	out, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// operation builds the operation for the endpoint, registering the schemas of
+// its request and response types.
+func (e *Endpoint) operation(gen *schemaGenerator) (*Operation, error) {
+	op := &Operation{
+		OperationID: e.ID,
+		Summary:     e.Summary,
+		Tags:        []string{e.Tag},
+		Responses:   make(map[string]*Response),
+	}
+
+	// Path parameters are the "{name}" segments of the path
+	for _, seg := range strings.Split(e.Path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     strings.Trim(seg, "{}"),
+				In:       "path",
+				Required: true,
+				Type:     "string",
+			})
+		}
+	}
+
+	for _, p := range e.Query {
+		q := p
+		q.In = "query"
+		if q.Type == "" {
+			q.Type = "string"
+		}
+		op.Parameters = append(op.Parameters, &q)
+	}
+
+	switch e.Method {
+	case "GET":
+		op.Parameters = append(op.Parameters, refParameter("region"), refParameter("stale"))
+		if e.Blocking {
+			op.Parameters = append(op.Parameters, refParameter("index"), refParameter("wait"))
+		}
+		if e.List {
+			op.Parameters = append(op.Parameters,
+				refParameter("prefix"), refParameter("per_page"),
+				refParameter("next_token"), refParameter("filter"))
+		}
+	default:
+		op.Parameters = append(op.Parameters, refParameter("region"))
+	}
+
+	if e.Request != nil {
+		schema, err := gen.schema(reflect.TypeOf(e.Request))
+		if err != nil {
+			return nil, err
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:     "body",
+			In:       "body",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	resp := &Response{Description: "OK"}
+	if e.Response != nil {
+		schema, err := gen.schema(reflect.TypeOf(e.Response))
+		if err != nil {
+			return nil, err
+		}
+		resp.Schema = schema
+	}
+	if e.Blocking {
+		resp.Headers = map[string]*Header{
+			"X-Nomad-Index": {
+				Description: "The index of the returned state, used for blocking queries.",
+				Type:        "integer",
+			},
+			"X-Nomad-KnownLeader": {
+				Description: "Whether the cluster has a known leader.",
+				Type:        "boolean",
+			},
+			"X-Nomad-LastContact": {
+				Description: "Milliseconds since the server last contacted the leader.",
+				Type:        "integer",
+			},
+		}
+	}
+	if e.List {
+		if resp.Headers == nil {
+			resp.Headers = make(map[string]*Header)
+		}
+		resp.Headers["X-Nomad-NextToken"] = &Header{
+			Description: "The token of the next page, if any.",
+			Type:        "string",
+		}
+	}
+	op.Responses["200"] = resp
+	op.Responses["default"] = &Response{Description: "Error"}
+	return op, nil
+}
+
+// refParameter references a parameter defined in commonParameters.
+func refParameter(name string) *Parameter {
+	return &Parameter{Ref: "#/parameters/" + name}
+}
+
+// commonParameters returns the query parameters shared by many endpoints.
+func commonParameters() map[string]*Parameter {
+	return map[string]*Parameter{
+		"region": {
+			Name:        "region",
+			In:          "query",
+			Description: "The region to forward the request to.",
+			Type:        "string",
+		},
+		"stale": {
+			Name:        "stale",
+			In:          "query",
+			Description: "Allow any server to service the read.",
+			Type:        "boolean",
+		},
+		"index": {
+			Name:        "index",
+			In:          "query",
+			Description: "Block until the state index exceeds this value.",
+			Type:        "integer",
+			Format:      "int64",
+		},
+		"wait": {
+			Name:        "wait",
+			In:          "query",
+			Description: "The maximum duration of a blocking query, e.g. \"10s\".",
+			Type:        "string",
+		},
+		"prefix": {
+			Name:        "prefix",
+			In:          "query",
+			Description: "Only return objects whose ID has this prefix.",
+			Type:        "string",
+		},
+		"per_page": {
+			Name:        "per_page",
+			In:          "query",
+			Description: "The maximum number of results to return.",
+			Type:        "integer",
+			Format:      "int32",
+		},
+		"next_token": {
+			Name:        "next_token",
+			In:          "query",
+			Description: "The token of the page to return.",
+			Type:        "string",
+		},
+		"filter": {
+			Name:        "filter",
+			In:          "query",
+			Description: "A filter expression applied to the results.",
+			Type:        "string",
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package openapi
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestGenerate_UpToDate(t *testing.T) {
+	spec, err := Generate()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := spec.JSON()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	existing, err := ioutil.ReadFile("openapi.json")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(out, existing) {
+		t.Fatalf("openapi.json is out of date, run go generate")
+	}
+}
+
+func TestGenerate_Refs(t *testing.T) {
+	spec, err := Generate()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Every referenced definition must exist
+	var check func(s *Schema)
+	check = func(s *Schema) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			name := s.Ref[len("#/definitions/"):]
+			if _, ok := spec.Definitions[name]; !ok {
+				t.Fatalf("missing definition %q", name)
+			}
+		}
+		check(s.Items)
+		check(s.AdditionalProperties)
+		for _, p := range s.Properties {
+			check(p)
+		}
+	}
+	for _, d := range spec.Definitions {
+		check(d)
+	}
+	for _, item := range spec.Paths {
+		for _, op := range item {
+			for _, p := range op.Parameters {
+				check(p.Schema)
+			}
+			for _, r := range op.Responses {
+				check(r.Schema)
+			}
+		}
+	}
+}
+
+type testRecursive struct {
+	Name     string `json:"name"`
+	Children []*testRecursive
+	Skipped  string `json:"-"`
+	Tags     map[string]string
+	Data     []byte
+	private  int
+}
+
+func TestSchemaGenerator_Recursive(t *testing.T) {
+	defs := make(map[string]*Schema)
+	s, err := newSchemaGenerator(defs).schema(reflect.TypeOf(&testRecursive{}))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if s.Ref != "#/definitions/testRecursive" {
+		t.Fatalf("bad ref: %#v", s)
+	}
+
+	def := defs["testRecursive"]
+	if def == nil {
+		t.Fatalf("missing definition")
+	}
+	expected := map[string]*Schema{
+		"name":     {Type: "string"},
+		"Children": {Type: "array", Items: &Schema{Ref: "#/definitions/testRecursive"}},
+		"Tags":     {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		"Data":     {Type: "string", Format: "byte"},
+	}
+	if !reflect.DeepEqual(def.Properties, expected) {
+		t.Fatalf("got %#v; want %#v", def.Properties, expected)
+	}
+}
@@ -0,0 +1,161 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI schema object.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// schemaGenerator derives schemas from Go types. Named struct types are
+// registered as definitions and referenced, which also handles recursive
+// types.
+type schemaGenerator struct {
+	defs map[string]*Schema
+}
+
+func newSchemaGenerator(defs map[string]*Schema) *schemaGenerator {
+	return &schemaGenerator{defs: defs}
+}
+
+// schema returns the schema of the given type.
+func (g *schemaGenerator) schema(t reflect.Type) (*Schema, error) {
+	switch t {
+	case durationType:
+		// Durations are encoded as their nanosecond count
+		return &Schema{Type: "integer", Format: "int64"}, nil
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}, nil
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}, nil
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		// Byte slices are encoded as base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %v", t.Key())
+		}
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	default:
+		return nil, fmt.Errorf("unsupported type %v", t)
+	}
+}
+
+// ref registers the named struct type as a definition and returns a reference
+// to it.
+func (g *schemaGenerator) ref(t reflect.Type) (*Schema, error) {
+	name := t.Name()
+	ref := &Schema{Ref: "#/definitions/" + name}
+	if _, ok := g.defs[name]; ok {
+		return ref, nil
+	}
+
+	// Register a placeholder first so recursive references terminate
+	g.defs[name] = &Schema{}
+	obj, err := g.object(t)
+	if err != nil {
+		delete(g.defs, name)
+		return nil, err
+	}
+	g.defs[name] = obj
+	return ref, nil
+}
+
+// object returns the object schema of a struct type, following the field
+// naming rules of encoding/json.
+func (g *schemaGenerator) object(t reflect.Type) (*Schema, error) {
+	obj := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+
+		// Embedded structs without a name have their fields promoted
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded, err := g.object(ft)
+				if err != nil {
+					return nil, err
+				}
+				for k, v := range embedded.Properties {
+					if _, ok := obj.Properties[k]; !ok {
+						obj.Properties[k] = v
+					}
+				}
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		schema, err := g.schema(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %v", t.Name(), f.Name, err)
+		}
+		obj.Properties[name] = schema
+	}
+	return obj, nil
+}
@@ -1,5 +1,10 @@
 package api
 
+import (
+	"strconv"
+	"time"
+)
+
 // Operator can be used to perform low-level operator tasks for Nomad.
 type Operator struct {
 	c *Client
@@ -85,3 +90,127 @@ func (op *Operator) RaftRemovePeerByAddress(address string, q *WriteOptions) err
 	resp.Body.Close()
 	return nil
 }
+
+const (
+	// SchedulerAlgorithmBinpack places allocations on the most utilized
+	// nodes that fit them.
+	SchedulerAlgorithmBinpack = "binpack"
+
+	// SchedulerAlgorithmSpread places allocations on the least utilized
+	// nodes.
+	SchedulerAlgorithmSpread = "spread"
+)
+
+// SchedulerConfiguration is the configuration of the schedulers that can be
+// changed at runtime.
+type SchedulerConfiguration struct {
+	// SchedulerAlgorithm is the algorithm used to score the nodes that fit
+	// an allocation, either binpack or spread.
+	SchedulerAlgorithm string
+
+	// CreateIndex/ModifyIndex store the create/modify indexes of this
+	// configuration.
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// SchedulerConfigurationResponse is the response object that wraps the
+// SchedulerConfiguration.
+type SchedulerConfigurationResponse struct {
+	// SchedulerConfig contains the scheduler config
+	SchedulerConfig *SchedulerConfiguration
+
+	QueryMeta
+}
+
+// SchedulerSetConfigurationResponse is the response object used when
+// updating the scheduler configuration.
+type SchedulerSetConfigurationResponse struct {
+	// Updated returns whether the config was actually updated. It is only
+	// false when a check-and-set update was rejected.
+	Updated bool
+
+	WriteMeta
+}
+
+// SchedulerGetConfiguration is used to query the current scheduler
+// configuration.
+func (op *Operator) SchedulerGetConfiguration(q *QueryOptions) (*SchedulerConfigurationResponse, *QueryMeta, error) {
+	var resp SchedulerConfigurationResponse
+	qm, err := op.c.query("/v1/operator/scheduler/configuration", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// SchedulerSetConfiguration is used to set the current scheduler
+// configuration.
+func (op *Operator) SchedulerSetConfiguration(conf *SchedulerConfiguration, q *WriteOptions) (*SchedulerSetConfigurationResponse, *WriteMeta, error) {
+	var out SchedulerSetConfigurationResponse
+	wm, err := op.c.write("/v1/operator/scheduler/configuration", conf, &out, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &out, wm, nil
+}
+
+// SchedulerCASConfiguration is used to perform a Check-And-Set update on the
+// scheduler configuration. The ModifyIndex value will be respected. Returns
+// true on success or false on failures.
+func (op *Operator) SchedulerCASConfiguration(conf *SchedulerConfiguration, q *WriteOptions) (*SchedulerSetConfigurationResponse, *WriteMeta, error) {
+	var out SchedulerSetConfigurationResponse
+	path := "/v1/operator/scheduler/configuration?cas=" + strconv.FormatUint(conf.ModifyIndex, 10)
+	wm, err := op.c.write(path, conf, &out, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &out, wm, nil
+}
+
+// CapacityResources are amounts of CPU, in MHz, memory and disk, in MB.
+type CapacityResources struct {
+	CPU      int
+	MemoryMB int
+	DiskMB   int
+}
+
+// CapacityStats is the capacity of a set of nodes and the resources reserved
+// and used by the allocations running on them.
+type CapacityStats struct {
+	Capacity    CapacityResources
+	Reserved    CapacityResources
+	Used        CapacityResources
+	Allocations int
+}
+
+// CapacityRollup is the capacity of the cluster at a point in time, or
+// averaged over an interval.
+type CapacityRollup struct {
+	Time        time.Time
+	Total       *CapacityStats
+	NodeClasses map[string]*CapacityStats
+}
+
+// CapacityReport is the capacity of the cluster and the resources reserved
+// and used per node class, namespace and job, along with the minute rollups
+// of the last hour and the hourly rollups of the last week.
+type CapacityReport struct {
+	Total         *CapacityStats
+	NodeClasses   map[string]*CapacityStats
+	Namespaces    map[string]*CapacityStats
+	Jobs          map[string]*CapacityStats
+	MinuteRollups []*CapacityRollup
+	HourlyRollups []*CapacityRollup
+}
+
+// CapacityReport is used to query the capacity and utilization of the
+// cluster.
+func (op *Operator) CapacityReport(q *QueryOptions) (*CapacityReport, *QueryMeta, error) {
+	var resp CapacityReport
+	qm, err := op.c.query("/v1/operator/capacity", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
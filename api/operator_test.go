@@ -36,3 +36,61 @@ func TestOperator_RaftRemovePeerByAddress(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+func TestOperator_SchedulerConfiguration(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+
+	operator := c.Operator()
+	resp, _, err := operator.SchedulerGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.SchedulerConfig.SchedulerAlgorithm != SchedulerAlgorithmBinpack {
+		t.Fatalf("bad: %#v", resp.SchedulerConfig)
+	}
+
+	// Switch to the spread algorithm
+	config := resp.SchedulerConfig
+	config.SchedulerAlgorithm = SchedulerAlgorithmSpread
+	setResp, wm, err := operator.SchedulerSetConfiguration(config, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertWriteMeta(t, wm)
+	if !setResp.Updated {
+		t.Fatalf("bad: %#v", setResp)
+	}
+
+	resp, qm, err := operator.SchedulerGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertQueryMeta(t, qm)
+	if resp.SchedulerConfig.SchedulerAlgorithm != SchedulerAlgorithmSpread {
+		t.Fatalf("bad: %#v", resp.SchedulerConfig)
+	}
+
+	// A check-and-set with a stale index fails
+	config = resp.SchedulerConfig
+	config.ModifyIndex--
+	config.SchedulerAlgorithm = SchedulerAlgorithmBinpack
+	setResp, _, err = operator.SchedulerCASConfiguration(config, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if setResp.Updated {
+		t.Fatalf("bad: %#v", setResp)
+	}
+
+	// The current index succeeds
+	config.ModifyIndex++
+	setResp, _, err = operator.SchedulerCASConfiguration(config, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !setResp.Updated {
+		t.Fatalf("bad: %#v", setResp)
+	}
+}
@@ -61,14 +61,18 @@ func (r *Resources) Merge(other *Resources) {
 type Port struct {
 	Label string
 	Value int `mapstructure:"static"`
+	To    int `mapstructure:"to"`
 }
 
 // NetworkResource is used to describe required network
-// resources of a given task.
+// resources of a given task, or the network of a task group.
 type NetworkResource struct {
+	Mode          string
+	HostNetwork   string `mapstructure:"host_network"`
 	Device        string
 	CIDR          string
 	IP            string
+	IPv6          string
 	MBits         *int
 	ReservedPorts []Port
 	DynamicPorts  []Port
@@ -76,6 +80,6 @@ type NetworkResource struct {
 
 func (n *NetworkResource) Canonicalize() {
 	if n.MBits == nil {
-		n.MBits = helper.IntToPtr(10)
+		n.MBits = helper.IntToPtr(0)
 	}
 }
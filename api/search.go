@@ -0,0 +1,44 @@
+package api
+
+import "net/url"
+
+// Search is used to query the search endpoints.
+type Search struct {
+	client *Client
+}
+
+// Search returns a handle on the search endpoints.
+func (c *Client) Search() *Search {
+	return &Search{client: c}
+}
+
+// FuzzySearch returns the allocations, deployments, jobs and nodes whose name
+// or ID contains the given text. If a context is given, only objects of that
+// type are searched.
+func (s *Search) FuzzySearch(text, context string, q *QueryOptions) (*FuzzySearchResponse, *QueryMeta, error) {
+	v := url.Values{}
+	v.Set("text", text)
+	if context != "" {
+		v.Set("context", context)
+	}
+
+	var resp FuzzySearchResponse
+	qm, err := s.client.query("/v1/search/fuzzy?"+v.Encode(), &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// FuzzyMatch is an object matching a fuzzy search.
+type FuzzyMatch struct {
+	ID   string
+	Name string
+}
+
+// FuzzySearchResponse is used to return the matches of a fuzzy search.
+type FuzzySearchResponse struct {
+	Matches     map[string][]FuzzyMatch
+	Truncations map[string]bool
+	QueryMeta
+}
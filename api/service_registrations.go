@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/url"
+)
+
+// ServiceRegistrations is used to query the catalog of the Nomad service
+// discovery provider.
+type ServiceRegistrations struct {
+	client *Client
+}
+
+// ServiceRegistrations returns a new handle on the services registered with
+// the Nomad provider.
+func (c *Client) ServiceRegistrations() *ServiceRegistrations {
+	return &ServiceRegistrations{client: c}
+}
+
+// List is used to list the registered services along with their tags, sorted
+// by name.
+func (s *ServiceRegistrations) List(q *QueryOptions) ([]*ServiceRegistrationListStub, *QueryMeta, error) {
+	var resp []*ServiceRegistrationListStub
+	qm, err := s.client.query("/v1/services", &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// PrefixList is used to list the services whose name starts with the given
+// prefix.
+func (s *ServiceRegistrations) PrefixList(prefix string, q *QueryOptions) ([]*ServiceRegistrationListStub, *QueryMeta, error) {
+	if q == nil {
+		q = &QueryOptions{Prefix: prefix}
+	} else {
+		q.Prefix = prefix
+	}
+
+	return s.List(q)
+}
+
+// Get is used to query the registrations of a service by its name.
+func (s *ServiceRegistrations) Get(name string, q *QueryOptions) ([]*ServiceRegistration, *QueryMeta, error) {
+	var resp []*ServiceRegistration
+	qm, err := s.client.query("/v1/service/"+url.PathEscape(name), &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// Delete is used to delete a registration of a service. Registrations are
+// removed by the clients when their allocation stops so this is only needed
+// to clean up after a client that can't reach the servers anymore.
+func (s *ServiceRegistrations) Delete(name, id string, q *WriteOptions) (*WriteMeta, error) {
+	wm, err := s.client.delete("/v1/service/"+url.PathEscape(name)+"/"+url.PathEscape(id), nil, q)
+	if err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// ServiceRegistrationListStub summarizes a registered service.
+type ServiceRegistrationListStub struct {
+	ServiceName string
+	Tags        []string
+}
+
+// ServiceRegistration is an instance of a service registered with the Nomad
+// provider.
+type ServiceRegistration struct {
+	ID          string
+	ServiceName string
+	JobID       string
+	AllocID     string
+	TaskName    string
+	NodeID      string
+	Datacenter  string
+	Tags        []string
+	Address     string
+	Port        int
+	AddressIPv6 string
+	Status      string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
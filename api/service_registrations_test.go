@@ -0,0 +1,40 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestServiceRegistrations_List(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	services := c.ServiceRegistrations()
+
+	// No services are registered yet
+	out, qm, err := services.List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if qm.LastIndex != 0 {
+		t.Fatalf("bad index: %d", qm.LastIndex)
+	}
+	if n := len(out); n != 0 {
+		t.Fatalf("expected 0 services, got: %d", n)
+	}
+}
+
+func TestServiceRegistrations_Get(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	services := c.ServiceRegistrations()
+
+	// An unknown service has no registrations
+	out, _, err := services.Get("web", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n := len(out); n != 0 {
+		t.Fatalf("expected 0 registrations, got: %d", n)
+	}
+}
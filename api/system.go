@@ -1,5 +1,7 @@
 package api
 
+import "strings"
+
 // Status is used to query the status-related endpoints.
 type System struct {
 	client *Client
@@ -15,3 +17,32 @@ func (s *System) GarbageCollect() error {
 	_, err := s.client.write("/v1/system/gc", &req, nil, nil)
 	return err
 }
+
+// GarbageCollectScopes forces a garbage collection limited to the given object
+// types: "jobs", "evals", "deployments" or "nodes". Every type is collected if
+// no scope is given.
+func (s *System) GarbageCollectScopes(scopes []string, q *WriteOptions) error {
+	r, err := s.client.newRequest("PUT", "/v1/system/gc")
+	if err != nil {
+		return err
+	}
+	r.setWriteOptions(q)
+	if len(scopes) != 0 {
+		r.params.Set("scope", strings.Join(scopes, ","))
+	}
+
+	_, resp, err := requireOK(s.client.doRequest(r))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ReconcileSummaries recomputes the summaries of every job from their
+// allocations.
+func (s *System) ReconcileSummaries(q *WriteOptions) error {
+	var req struct{}
+	_, err := s.client.write("/v1/system/reconcile/summaries", &req, nil, q)
+	return err
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -13,3 +14,27 @@ func TestSystem_GarbageCollect(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestSystem_GarbageCollectScopes(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	e := c.System()
+	if err := e.GarbageCollectScopes([]string{"jobs", "nodes"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	err := e.GarbageCollectScopes([]string{"allocs"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "allocs") {
+		t.Fatalf("expected unknown scope error; got %v", err)
+	}
+}
+
+func TestSystem_ReconcileSummaries(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, nil)
+	defer s.Stop()
+	if err := c.System().ReconcileSummaries(nil); err != nil {
+		t.Fatal(err)
+	}
+}
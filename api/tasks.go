@@ -79,6 +79,69 @@ func (r *RestartPolicy) Merge(rp *RestartPolicy) {
 	}
 }
 
+// CheckRestart describes if and when a task should be restarted based on
+// failing health checks.
+type CheckRestart struct {
+	Limit          int            `mapstructure:"limit"`
+	Grace          *time.Duration `mapstructure:"grace"`
+	IgnoreWarnings bool           `mapstructure:"ignore_warnings"`
+}
+
+// Canonicalize CheckRestart fields if not nil.
+func (c *CheckRestart) Canonicalize() {
+	if c == nil {
+		return
+	}
+
+	if c.Grace == nil {
+		c.Grace = helper.TimeToPtr(1 * time.Second)
+	}
+}
+
+// Copy returns a copy of CheckRestart or nil if unset.
+func (c *CheckRestart) Copy() *CheckRestart {
+	if c == nil {
+		return nil
+	}
+
+	nc := new(CheckRestart)
+	*nc = *c
+	if c.Grace != nil {
+		nc.Grace = helper.TimeToPtr(*c.Grace)
+	}
+	return nc
+}
+
+// Merge values from other CheckRestart over default values on this
+// CheckRestart and return merged copy.
+func (c *CheckRestart) Merge(o *CheckRestart) *CheckRestart {
+	if c == nil {
+		// Just return other
+		return o.Copy()
+	}
+
+	nc := c.Copy()
+
+	if o == nil {
+		// Nothing to merge
+		return nc
+	}
+
+	if o.Limit > 0 {
+		nc.Limit = o.Limit
+	}
+
+	if o.Grace != nil {
+		nc.Grace = helper.TimeToPtr(*o.Grace)
+	}
+
+	if o.IgnoreWarnings {
+		nc.IgnoreWarnings = o.IgnoreWarnings
+	}
+
+	return nc
+}
+
 // The ServiceCheck data model represents the consul health check that
 // Nomad registers for a Task
 type ServiceCheck struct {
@@ -92,18 +155,124 @@ type ServiceCheck struct {
 	PortLabel     string `mapstructure:"port"`
 	Interval      time.Duration
 	Timeout       time.Duration
-	InitialStatus string `mapstructure:"initial_status"`
-	TLSSkipVerify bool   `mapstructure:"tls_skip_verify"`
+	InitialStatus string        `mapstructure:"initial_status"`
+	TLSSkipVerify bool          `mapstructure:"tls_skip_verify"`
+	CheckRestart  *CheckRestart `mapstructure:"check_restart"`
 }
 
 // The Service model represents a Consul service definition
 type Service struct {
-	Id          string
-	Name        string
-	Tags        []string
-	PortLabel   string `mapstructure:"port"`
-	AddressMode string `mapstructure:"address_mode"`
-	Checks      []ServiceCheck
+	Id              string
+	Name            string
+	Tags            []string
+	CanaryTags      []string `mapstructure:"canary_tags"`
+	PortLabel       string   `mapstructure:"port"`
+	AddressMode     string   `mapstructure:"address_mode"`
+	Checks          []ServiceCheck
+	Connect         *ConsulConnect
+	Provider        string            `mapstructure:"provider"`
+	Meta            map[string]string `mapstructure:"meta"`
+	TaggedAddresses map[string]string `mapstructure:"tagged_addresses"`
+	CheckRestart    *CheckRestart     `mapstructure:"check_restart"`
+}
+
+// ConsulConnect enables a service to join the Consul Connect service mesh,
+// either through a sidecar proxy injected by Nomad or as a gateway.
+type ConsulConnect struct {
+	SidecarService *ConsulSidecarService `mapstructure:"sidecar_service"`
+	SidecarTask    *SidecarTask          `mapstructure:"sidecar_task"`
+	Gateway        *ConsulGateway
+}
+
+// ConsulGateway makes the task defining the service a Connect ingress,
+// terminating or mesh gateway.
+type ConsulGateway struct {
+	Proxy       *ConsulGatewayProxy
+	Ingress     *ConsulIngressConfigEntry
+	Terminating *ConsulTerminatingConfigEntry
+	Mesh        *ConsulMeshConfigEntry
+}
+
+// ConsulGatewayProxy configures the Envoy proxy of a gateway.
+type ConsulGatewayProxy struct {
+	ConnectTimeout *time.Duration `mapstructure:"connect_timeout"`
+}
+
+// ConsulIngressConfigEntry is the Consul configuration entry of an ingress
+// gateway.
+type ConsulIngressConfigEntry struct {
+	Listeners []*ConsulIngressListener
+}
+
+// ConsulIngressListener is a port an ingress gateway listens on.
+type ConsulIngressListener struct {
+	Port     int
+	Protocol string
+	Services []*ConsulIngressService
+}
+
+// ConsulIngressService is a service exposed by an ingress listener.
+type ConsulIngressService struct {
+	Name  string
+	Hosts []string
+}
+
+// ConsulTerminatingConfigEntry is the Consul configuration entry of a
+// terminating gateway.
+type ConsulTerminatingConfigEntry struct {
+	Services []*ConsulLinkedService
+}
+
+// ConsulLinkedService is an external service a terminating gateway routes
+// connections to.
+type ConsulLinkedService struct {
+	Name     string
+	CAFile   string `mapstructure:"ca_file"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	SNI      string `mapstructure:"sni"`
+}
+
+// ConsulMeshConfigEntry makes a gateway a mesh gateway.
+type ConsulMeshConfigEntry struct{}
+
+// ConsulSidecarService is the sidecar proxy service registered in Consul.
+type ConsulSidecarService struct {
+	Proxy *ConsulProxy
+}
+
+// ConsulProxy is the configuration of a Connect sidecar proxy.
+type ConsulProxy struct {
+	Upstreams   []*ConsulUpstream
+	MeshGateway *ConsulMeshGateway `mapstructure:"mesh_gateway"`
+}
+
+// ConsulUpstream is a service the sidecar proxy exposes locally to the task.
+type ConsulUpstream struct {
+	DestinationName      string             `mapstructure:"destination_name"`
+	DestinationNamespace string             `mapstructure:"destination_namespace"`
+	Datacenter           string             `mapstructure:"datacenter"`
+	LocalBindAddress     string             `mapstructure:"local_bind_address"`
+	LocalBindPort        int                `mapstructure:"local_bind_port"`
+	MeshGateway          *ConsulMeshGateway `mapstructure:"mesh_gateway"`
+}
+
+// ConsulMeshGateway configures how a proxy reaches upstreams in other
+// datacenters. Mode is one of local, remote or none.
+type ConsulMeshGateway struct {
+	Mode string `mapstructure:"mode"`
+}
+
+// SidecarTask overrides the defaults of the sidecar proxy task.
+type SidecarTask struct {
+	Driver      string
+	User        string
+	Config      map[string]interface{}
+	Env         map[string]string
+	Resources   *Resources
+	Meta        map[string]string
+	KillTimeout *time.Duration `mapstructure:"kill_timeout"`
+	LogConfig   *LogConfig     `mapstructure:"logs"`
 }
 
 func (s *Service) Canonicalize(t *Task, tg *TaskGroup, job *Job) {
@@ -115,6 +284,23 @@ func (s *Service) Canonicalize(t *Task, tg *TaskGroup, job *Job) {
 	if s.AddressMode == "" {
 		s.AddressMode = "auto"
 	}
+
+	// Merge the check_restart of the service into its checks so that
+	// checks only need to override it
+	s.CheckRestart.Canonicalize()
+	for i, check := range s.Checks {
+		s.Checks[i].CheckRestart = s.CheckRestart.Merge(check.CheckRestart)
+		s.Checks[i].CheckRestart.Canonicalize()
+	}
+
+	// Default ingress listeners to TCP
+	if s.Connect != nil && s.Connect.Gateway != nil && s.Connect.Gateway.Ingress != nil {
+		for _, l := range s.Connect.Gateway.Ingress.Listeners {
+			if l.Protocol == "" {
+				l.Protocol = "tcp"
+			}
+		}
+	}
 }
 
 // EphemeralDisk is an ephemeral disk object
@@ -144,16 +330,27 @@ func (e *EphemeralDisk) Canonicalize() {
 	}
 }
 
+// Consul is the Consul Enterprise namespace and admin partition the services
+// of a task group are registered in.
+type Consul struct {
+	Namespace string
+	Partition string
+}
+
 // TaskGroup is the unit of scheduling.
 type TaskGroup struct {
-	Name          *string
-	Count         *int
-	Constraints   []*Constraint
-	Tasks         []*Task
-	RestartPolicy *RestartPolicy
-	EphemeralDisk *EphemeralDisk
-	Update        *UpdateStrategy
-	Meta          map[string]string
+	Name                      *string
+	Count                     *int
+	Constraints               []*Constraint
+	Tasks                     []*Task
+	RestartPolicy             *RestartPolicy
+	EphemeralDisk             *EphemeralDisk
+	Update                    *UpdateStrategy
+	Consul                    *Consul
+	StopAfterClientDisconnect *time.Duration `mapstructure:"stop_after_client_disconnect"`
+	MaxClientDisconnect       *time.Duration `mapstructure:"max_client_disconnect"`
+	Networks                  []*NetworkResource
+	Meta                      map[string]string
 }
 
 // NewTaskGroup creates a new TaskGroup.
@@ -274,6 +471,12 @@ type DispatchPayloadConfig struct {
 	File string
 }
 
+// TaskLifecycle orders a task relative to the main tasks of its group.
+type TaskLifecycle struct {
+	Hook    string `mapstructure:"hook"`
+	Sidecar bool   `mapstructure:"sidecar"`
+}
+
 // Task is a single process in a task group.
 type Task struct {
 	Name            string
@@ -292,6 +495,8 @@ type Task struct {
 	Templates       []*Template
 	DispatchPayload *DispatchPayloadConfig
 	Leader          bool
+	Kind            string
+	Lifecycle       *TaskLifecycle
 }
 
 func (t *Task) Canonicalize(tg *TaskGroup, job *Job) {
@@ -353,18 +558,44 @@ func (a *TaskArtifact) Canonicalize() {
 	}
 }
 
+// WaitConfig is the minimum and maximum amount of time to wait for data to
+// settle before rendering a template.
+type WaitConfig struct {
+	Min *time.Duration `mapstructure:"min"`
+	Max *time.Duration `mapstructure:"max"`
+}
+
+// Canonicalize WaitConfig fields if not nil. A missing max defaults to four
+// times the min.
+func (w *WaitConfig) Canonicalize() {
+	if w == nil {
+		return
+	}
+
+	if w.Min == nil {
+		w.Min = helper.TimeToPtr(0)
+	}
+	if w.Max == nil {
+		w.Max = helper.TimeToPtr(4 * *w.Min)
+	}
+}
+
 type Template struct {
-	SourcePath   *string        `mapstructure:"source"`
-	DestPath     *string        `mapstructure:"destination"`
-	EmbeddedTmpl *string        `mapstructure:"data"`
-	ChangeMode   *string        `mapstructure:"change_mode"`
-	ChangeSignal *string        `mapstructure:"change_signal"`
-	Splay        *time.Duration `mapstructure:"splay"`
-	Perms        *string        `mapstructure:"perms"`
-	LeftDelim    *string        `mapstructure:"left_delimiter"`
-	RightDelim   *string        `mapstructure:"right_delimiter"`
-	Envvars      *bool          `mapstructure:"env"`
-	VaultGrace   *time.Duration `mapstructure:"vault_grace"`
+	SourcePath    *string           `mapstructure:"source"`
+	DestPath      *string           `mapstructure:"destination"`
+	EmbeddedTmpl  *string           `mapstructure:"data"`
+	ChangeMode    *string           `mapstructure:"change_mode"`
+	ChangeSignal  *string           `mapstructure:"change_signal"`
+	ChangeScript  *ChangeScript     `mapstructure:"change_script"`
+	Splay         *time.Duration    `mapstructure:"splay"`
+	Wait          *WaitConfig       `mapstructure:"wait"`
+	Perms         *string           `mapstructure:"perms"`
+	LeftDelim     *string           `mapstructure:"left_delimiter"`
+	RightDelim    *string           `mapstructure:"right_delimiter"`
+	Envvars       *bool             `mapstructure:"env"`
+	EnvKeys       map[string]string `mapstructure:"env_keys"`
+	ErrMissingKey *bool             `mapstructure:"error_on_missing_key"`
+	VaultGrace    *time.Duration    `mapstructure:"vault_grace"`
 }
 
 func (tmpl *Template) Canonicalize() {
@@ -390,9 +621,11 @@ func (tmpl *Template) Canonicalize() {
 		sig := *tmpl.ChangeSignal
 		tmpl.ChangeSignal = helper.StringToPtr(strings.ToUpper(sig))
 	}
+	tmpl.ChangeScript.Canonicalize()
 	if tmpl.Splay == nil {
 		tmpl.Splay = helper.TimeToPtr(5 * time.Second)
 	}
+	tmpl.Wait.Canonicalize()
 	if tmpl.Perms == nil {
 		tmpl.Perms = helper.StringToPtr("0644")
 	}
@@ -405,19 +638,61 @@ func (tmpl *Template) Canonicalize() {
 	if tmpl.Envvars == nil {
 		tmpl.Envvars = helper.BoolToPtr(false)
 	}
+	if tmpl.ErrMissingKey == nil {
+		tmpl.ErrMissingKey = helper.BoolToPtr(false)
+	}
 	if tmpl.VaultGrace == nil {
 		tmpl.VaultGrace = helper.TimeToPtr(5 * time.Minute)
 	}
 }
 
+// ChangeScript is a command run inside the task when a template is
+// re-rendered or a new Vault token is retrieved.
+type ChangeScript struct {
+	Command     *string        `mapstructure:"command"`
+	Args        []string       `mapstructure:"args"`
+	Timeout     *time.Duration `mapstructure:"timeout"`
+	FailOnError *bool          `mapstructure:"fail_on_error"`
+}
+
+// Canonicalize ChangeScript fields if not nil.
+func (c *ChangeScript) Canonicalize() {
+	if c == nil {
+		return
+	}
+
+	if c.Command == nil {
+		c.Command = helper.StringToPtr("")
+	}
+	if c.Timeout == nil {
+		c.Timeout = helper.TimeToPtr(5 * time.Second)
+	}
+	if c.FailOnError == nil {
+		c.FailOnError = helper.BoolToPtr(false)
+	}
+}
+
 type Vault struct {
+	Enabled      *bool
 	Policies     []string
+	Role         *string
+	Namespace    *string
 	Env          *bool
-	ChangeMode   *string `mapstructure:"change_mode"`
-	ChangeSignal *string `mapstructure:"change_signal"`
+	ChangeMode   *string       `mapstructure:"change_mode"`
+	ChangeSignal *string       `mapstructure:"change_signal"`
+	ChangeScript *ChangeScript `mapstructure:"change_script"`
 }
 
 func (v *Vault) Canonicalize() {
+	if v.Enabled == nil {
+		v.Enabled = helper.BoolToPtr(true)
+	}
+	if v.Role == nil {
+		v.Role = helper.StringToPtr("")
+	}
+	if v.Namespace == nil {
+		v.Namespace = helper.StringToPtr("")
+	}
 	if v.Env == nil {
 		v.Env = helper.BoolToPtr(true)
 	}
@@ -427,6 +702,7 @@ func (v *Vault) Canonicalize() {
 	if v.ChangeSignal == nil {
 		v.ChangeSignal = helper.StringToPtr("SIGHUP")
 	}
+	v.ChangeScript.Canonicalize()
 }
 
 // NewTask creates and initializes a new Task.
@@ -486,6 +762,17 @@ type TaskState struct {
 	Events      []*TaskEvent
 }
 
+// ExitCode returns the exit code of the last run of the task, or zero if it
+// hasn't terminated.
+func (t *TaskState) ExitCode() int {
+	for i := len(t.Events) - 1; i >= 0; i-- {
+		if e := t.Events[i]; e.Type == TaskTerminated {
+			return e.ExitCode
+		}
+	}
+	return 0
+}
+
 const (
 	TaskSetup                  = "Task Setup"
 	TaskSetupFailure           = "Setup Failure"
@@ -505,7 +792,9 @@ const (
 	TaskSignaling              = "Signaling"
 	TaskRestartSignal          = "Restart Signaled"
 	TaskLeaderDead             = "Leader Task Dead"
+	TaskMainDead               = "Main Tasks Dead"
 	TaskBuildingTaskDir        = "Building Task Directory"
+	TaskChangeScript           = "Change Script"
 )
 
 // TaskEvent is an event that effects the state of a task and contains meta-data
@@ -3,6 +3,7 @@ package api
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/nomad/helper"
 )
@@ -180,7 +181,7 @@ func TestTask_Require(t *testing.T) {
 			&NetworkResource{
 				CIDR:          "0.0.0.0/0",
 				MBits:         helper.IntToPtr(100),
-				ReservedPorts: []Port{{"", 80}, {"", 443}},
+				ReservedPorts: []Port{{Value: 80}, {Value: 443}},
 			},
 		},
 	}
@@ -243,3 +244,75 @@ func TestTask_Artifact(t *testing.T) {
 		t.Errorf("expected local/foo.txt but found %q", *a.RelativeDest)
 	}
 }
+
+func TestService_CheckRestart(t *testing.T) {
+	job := &Job{Name: helper.StringToPtr("job")}
+	tg := &TaskGroup{Name: helper.StringToPtr("group")}
+	task := &Task{Name: "task"}
+	service := &Service{
+		CheckRestart: &CheckRestart{
+			Limit:          11,
+			Grace:          helper.TimeToPtr(11 * time.Second),
+			IgnoreWarnings: true,
+		},
+		Checks: []ServiceCheck{
+			{
+				Name: "all-set",
+				CheckRestart: &CheckRestart{
+					Limit:          22,
+					Grace:          helper.TimeToPtr(22 * time.Second),
+					IgnoreWarnings: true,
+				},
+			},
+			{
+				Name: "some-set",
+				CheckRestart: &CheckRestart{
+					Limit: 33,
+					Grace: helper.TimeToPtr(33 * time.Second),
+				},
+			},
+			{
+				Name: "unset",
+			},
+		},
+	}
+
+	service.Canonicalize(task, tg, job)
+	if cr := service.Checks[0].CheckRestart; cr.Limit != 22 || *cr.Grace != 22*time.Second || !cr.IgnoreWarnings {
+		t.Fatalf("bad all-set check restart: %#v", cr)
+	}
+	if cr := service.Checks[1].CheckRestart; cr.Limit != 33 || *cr.Grace != 33*time.Second || !cr.IgnoreWarnings {
+		t.Fatalf("bad some-set check restart: %#v", cr)
+	}
+	if cr := service.Checks[2].CheckRestart; cr.Limit != 11 || *cr.Grace != 11*time.Second || !cr.IgnoreWarnings {
+		t.Fatalf("bad unset check restart: %#v", cr)
+	}
+}
+
+func TestTemplate_Canonicalize_ChangeScript(t *testing.T) {
+	tmpl := &Template{
+		ChangeMode: helper.StringToPtr("script"),
+		ChangeScript: &ChangeScript{
+			Command: helper.StringToPtr("/bin/reload"),
+		},
+	}
+
+	tmpl.Canonicalize()
+	cs := tmpl.ChangeScript
+	if *cs.Command != "/bin/reload" || *cs.Timeout != 5*time.Second || *cs.FailOnError {
+		t.Fatalf("bad change script: %#v", cs)
+	}
+}
+
+func TestTemplate_Canonicalize_Wait(t *testing.T) {
+	tmpl := &Template{
+		Wait: &WaitConfig{
+			Min: helper.TimeToPtr(5 * time.Second),
+		},
+	}
+
+	tmpl.Canonicalize()
+	if w := tmpl.Wait; *w.Min != 5*time.Second || *w.Max != 20*time.Second {
+		t.Fatalf("bad wait: %#v", w)
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Variables is used to query the variables endpoints.
+type Variables struct {
+	client *Client
+}
+
+// Variables returns a new handle on the variables.
+func (c *Client) Variables() *Variables {
+	return &Variables{client: c}
+}
+
+// List is used to list the metadata of the variables of a namespace, sorted
+// by path. The default namespace is used if namespace is empty.
+func (v *Variables) List(namespace string, q *QueryOptions) ([]*VariableMetadata, *QueryMeta, error) {
+	var resp []*VariableMetadata
+	qm, err := v.client.query(variablesPath("/v1/vars", namespace, nil), &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, qm, nil
+}
+
+// PrefixList is used to list the variables of a namespace by path prefix.
+func (v *Variables) PrefixList(namespace, prefix string, q *QueryOptions) ([]*VariableMetadata, *QueryMeta, error) {
+	if q == nil {
+		q = &QueryOptions{Prefix: prefix}
+	} else {
+		q.Prefix = prefix
+	}
+
+	return v.List(namespace, q)
+}
+
+// Read is used to read a variable and its items.
+func (v *Variables) Read(namespace, path string, q *QueryOptions) (*Variable, *QueryMeta, error) {
+	var resp Variable
+	qm, err := v.client.query(variablesPath("/v1/var/"+path, namespace, nil), &resp, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &resp, qm, nil
+}
+
+// Put is used to create or update a variable.
+func (v *Variables) Put(variable *Variable, q *WriteOptions) (*VariableWriteResponse, *WriteMeta, error) {
+	return v.put(variable, nil, q)
+}
+
+// CheckedPut is used to perform a Check-And-Set update of a variable. The
+// ModifyIndex of the variable must be the one of the stored variable, or 0
+// if the variable must not exist. The response isn't updated on failures.
+func (v *Variables) CheckedPut(variable *Variable, q *WriteOptions) (*VariableWriteResponse, *WriteMeta, error) {
+	if variable == nil {
+		return nil, nil, fmt.Errorf("missing variable")
+	}
+	params := url.Values{"cas": []string{strconv.FormatUint(variable.ModifyIndex, 10)}}
+	return v.put(variable, params, q)
+}
+
+func (v *Variables) put(variable *Variable, params url.Values, q *WriteOptions) (*VariableWriteResponse, *WriteMeta, error) {
+	if variable == nil || variable.Path == "" {
+		return nil, nil, fmt.Errorf("missing variable path")
+	}
+	var out VariableWriteResponse
+	wm, err := v.client.write(variablesPath("/v1/var/"+variable.Path, variable.Namespace, params), variable, &out, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &out, wm, nil
+}
+
+// Delete is used to delete a variable.
+func (v *Variables) Delete(namespace, path string, q *WriteOptions) (*VariableWriteResponse, *WriteMeta, error) {
+	return v.delete(namespace, path, nil, q)
+}
+
+// CheckedDelete is used to perform a Check-And-Set delete of a variable. The
+// variable is only deleted if checkIndex is its ModifyIndex.
+func (v *Variables) CheckedDelete(namespace, path string, checkIndex uint64, q *WriteOptions) (*VariableWriteResponse, *WriteMeta, error) {
+	params := url.Values{"cas": []string{strconv.FormatUint(checkIndex, 10)}}
+	return v.delete(namespace, path, params, q)
+}
+
+func (v *Variables) delete(namespace, path string, params url.Values, q *WriteOptions) (*VariableWriteResponse, *WriteMeta, error) {
+	if path == "" {
+		return nil, nil, fmt.Errorf("missing variable path")
+	}
+	var out VariableWriteResponse
+	wm, err := v.client.delete(variablesPath("/v1/var/"+path, namespace, params), &out, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &out, wm, nil
+}
+
+// variablesPath returns the endpoint with the namespace and parameters set as
+// query parameters.
+func variablesPath(endpoint, namespace string, params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	if namespace != "" {
+		params.Set("namespace", namespace)
+	}
+	if len(params) == 0 {
+		return endpoint
+	}
+	return endpoint + "?" + params.Encode()
+}
+
+// Variable is a set of key/value items stored at a path of a namespace.
+type Variable struct {
+	Namespace   string
+	Path        string
+	Items       map[string]string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// VariableMetadata describes a variable without its items.
+type VariableMetadata struct {
+	Namespace   string
+	Path        string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// VariableWriteResponse is the response of a write. Updated is false if a
+// Check-And-Set write failed, in which case Conflict is the metadata of the
+// stored variable, if any.
+type VariableWriteResponse struct {
+	Updated  bool
+	Conflict *VariableMetadata
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestVariables_PutReadDelete(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t, nil, func(c *testutil.TestServerConfig) {
+		c.Server.VariablesEncryptionKey = "6ts1bGYxr/BuSUA3K5sh3Mo3X8AqnYyBUjB1nZtkm1s="
+	})
+	defer s.Stop()
+	variables := c.Variables()
+
+	// Write a variable
+	v := &Variable{
+		Path:  "app/db",
+		Items: map[string]string{"user": "app", "password": "secret"},
+	}
+	resp, wm, err := variables.Put(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertWriteMeta(t, wm)
+	if !resp.Updated {
+		t.Fatalf("expected variable to be written")
+	}
+
+	// Read it back
+	out, qm, err := variables.Read("", "app/db", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	assertQueryMeta(t, qm)
+	if out.Namespace != "default" || !reflect.DeepEqual(out.Items, v.Items) {
+		t.Fatalf("bad variable: %#v", out)
+	}
+
+	// A Check-And-Set update based on a stale index fails
+	v.Items["password"] = "rotated"
+	v.ModifyIndex = out.ModifyIndex - 1
+	resp, _, err = variables.CheckedPut(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.Updated || resp.Conflict == nil || resp.Conflict.ModifyIndex != out.ModifyIndex {
+		t.Fatalf("expected a conflict: %#v", resp)
+	}
+
+	// Succeeds with the current index
+	v.ModifyIndex = out.ModifyIndex
+	resp, _, err = variables.CheckedPut(v, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !resp.Updated {
+		t.Fatalf("expected variable to be updated")
+	}
+
+	// List the variables by prefix
+	list, _, err := variables.PrefixList("", "app/", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(list) != 1 || list[0].Path != "app/db" {
+		t.Fatalf("bad list: %#v", list)
+	}
+
+	// Delete the variable
+	if _, _, err := variables.Delete("", "app/db", nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, _, err := variables.Read("", "app/db", nil); err == nil {
+		t.Fatalf("expected variable to be deleted")
+	}
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// aclCacheSize is the number of ACLs compiled from sets of policies that
+	// are cached
+	aclCacheSize = 64
+
+	// tokenCacheSize is the number of resolved tokens that are cached
+	tokenCacheSize = 64
+)
+
+// cachedToken is the ACL of a token resolved by the servers and the time it
+// was resolved at
+type cachedToken struct {
+	acl       *acl.ACL
+	token     *structs.ACLToken
+	expiresAt *time.Time
+	cacheTime time.Time
+}
+
+// setupACLCaches creates the caches of the ACLs
+func (c *Client) setupACLCaches() error {
+	var err error
+	c.aclCache, err = lru.New2Q(aclCacheSize)
+	if err != nil {
+		return err
+	}
+	c.tokenCache, err = lru.New2Q(tokenCacheSize)
+	return err
+}
+
+// ResolveToken resolves the secret ID of a request made to the client to the
+// ACL of its token. It returns a nil ACL if ACLs are disabled, in which case
+// everything is allowed. Tokens are resolved by the servers and cached for
+// the token TTL of the client.
+func (c *Client) ResolveToken(secretID string) (*acl.ACL, error) {
+	if !c.config.ACLEnabled {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if raw, ok := c.tokenCache.Get(secretID); ok {
+		cached := raw.(*cachedToken)
+		if cached.expiresAt != nil && !now.Before(*cached.expiresAt) {
+			c.tokenCache.Remove(secretID)
+			return nil, structs.ErrTokenExpired
+		}
+		if now.Sub(cached.cacheTime) < c.config.ACLTokenTTL {
+			return cached.acl, nil
+		}
+	}
+
+	req := structs.ResolveACLTokenRequest{
+		SecretID: secretID,
+		QueryOptions: structs.QueryOptions{
+			Region:     c.Region(),
+			AllowStale: true,
+		},
+	}
+	var resp structs.ResolveACLTokenResponse
+	if err := c.RPC("ACL.ResolveToken", &req, &resp); err != nil {
+		// Keep using a cached token while the servers are unreachable, but
+		// not once it was deleted or expired
+		msg := err.Error()
+		if strings.Contains(msg, structs.ErrTokenNotFound.Error()) || strings.Contains(msg, structs.ErrTokenExpired.Error()) {
+			c.tokenCache.Remove(secretID)
+			return nil, err
+		}
+		if raw, ok := c.tokenCache.Get(secretID); ok {
+			c.logger.Printf("[WARN] client: failed to resolve ACL token, using the cached token: %v", err)
+			return raw.(*cachedToken).acl, nil
+		}
+		return nil, err
+	}
+
+	var aclObj *acl.ACL
+	if resp.Token.Type == structs.ACLManagementToken {
+		aclObj = acl.ManagementACL
+	} else {
+		var err error
+		aclObj, err = structs.CompileACLObject(c.aclCache, resp.Policies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.tokenCache.Add(secretID, &cachedToken{
+		acl:       aclObj,
+		token:     resp.Token,
+		expiresAt: resp.Token.ExpirationTime,
+		cacheTime: now,
+	})
+	return aclObj, nil
+}
+
+// ResolveACLToken returns the token of the secret ID of a request made to the
+// client, resolving it through the servers unless it is cached. It returns a
+// nil token if ACLs are disabled.
+func (c *Client) ResolveACLToken(secretID string) (*structs.ACLToken, error) {
+	if _, err := c.ResolveToken(secretID); err != nil || !c.config.ACLEnabled {
+		return nil, err
+	}
+	if raw, ok := c.tokenCache.Get(secretID); ok {
+		return raw.(*cachedToken).token, nil
+	}
+	return nil, nil
+}
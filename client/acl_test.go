@@ -0,0 +1,94 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestClient_ResolveToken_Disabled(t *testing.T) {
+	t.Parallel()
+	c := testClient(t, nil)
+	defer c.Shutdown()
+
+	aclObj, err := c.ResolveToken(structs.GenerateUUID())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj != nil {
+		t.Fatalf("expected a nil ACL, got %#v", aclObj)
+	}
+}
+
+func TestClient_ResolveToken(t *testing.T) {
+	t.Parallel()
+	s1, addr := testServer(t, func(c *nomad.Config) {
+		c.ACLEnabled = true
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	c := testClient(t, func(c *config.Config) {
+		c.Servers = []string{addr}
+		c.ACLEnabled = true
+	})
+	defer c.Shutdown()
+
+	state := s1.State()
+	policy := mock.ACLPolicy()
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	root := mock.ACLManagementToken()
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	expiring := mock.ACLToken()
+	expiration := time.Now().Add(500 * time.Millisecond)
+	expiring.ExpirationTime = &expiration
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{root, token, expiring}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Management tokens are allowed everything
+	aclObj, err := c.ResolveToken(root.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj != acl.ManagementACL {
+		t.Fatalf("expected the management ACL, got %#v", aclObj)
+	}
+
+	// Client tokens are granted their policies, and cached
+	aclObj, err = c.ResolveToken(token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !aclObj.AllowNamespaceRead(structs.DefaultNamespace) || aclObj.AllowNamespaceWrite(structs.DefaultNamespace) {
+		t.Fatalf("bad: %#v", aclObj)
+	}
+	if _, ok := c.tokenCache.Get(token.SecretID); !ok {
+		t.Fatalf("expected the token to be cached")
+	}
+
+	// Unknown tokens are rejected
+	_, err = c.ResolveToken(structs.GenerateUUID())
+	if err == nil || !strings.Contains(err.Error(), structs.ErrTokenNotFound.Error()) {
+		t.Fatalf("expected token not found, got %v", err)
+	}
+
+	// Cached tokens are rejected once they expire
+	if _, err := c.ResolveToken(expiring.SecretID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	time.Sleep(time.Until(expiration))
+	if _, err := c.ResolveToken(expiring.SecretID); err != structs.ErrTokenExpired {
+		t.Fatalf("expected token expired, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/nomad/client/cni"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// groupNetworkName returns the name of the network the CNI plugins join the
+// allocations of the task group to and whether it has one.
+func groupNetworkName(tg *structs.TaskGroup) (string, bool) {
+	for _, n := range tg.Networks {
+		if n.Mode == structs.NetworkModeBridge {
+			return cni.BridgeNetworkName, true
+		}
+		if name, ok := n.CNINetwork(); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// groupNetworkConfig returns the configuration of the network of the task
+// group: the built-in bridge network in bridge mode, or the named network
+// configuration of the client.
+func (r *AllocRunner) groupNetworkConfig(tg *structs.TaskGroup) (*cni.NetworkConfigList, error) {
+	for _, n := range tg.Networks {
+		if n.Mode == structs.NetworkModeBridge {
+			return cni.BridgeConfigList(r.config.BridgeNetworkName, r.config.BridgeNetworkSubnet, r.config.BridgeNetworkSubnetIPv6), nil
+		}
+		if name, ok := n.CNINetwork(); ok {
+			return cni.LoadConfigList(r.config.CNIConfigDir, name)
+		}
+	}
+	return nil, fmt.Errorf("task group %q has no CNI network", tg.Name)
+}
+
+// cniRuntime returns the CNI runtime of the allocation's network namespace.
+// The ports of its group network are mapped to the namespace and its traffic
+// is limited to the bandwidth the group network and its tasks reserved.
+func (r *AllocRunner) cniRuntime() *cni.Runtime {
+	rt := &cni.Runtime{
+		ContainerID: r.allocID,
+		NetNS:       cni.NetNSPath(r.allocID),
+		IfName:      cni.DefaultInterfaceName,
+		Path:        cni.SplitPath(r.config.CNIPath),
+	}
+
+	r.allocLock.Lock()
+	shared := r.alloc.SharedResources
+	tasks := r.alloc.TaskResources
+	r.allocLock.Unlock()
+	if shared == nil {
+		return rt
+	}
+
+	// The tasks share the namespace, so their bandwidth is limited along
+	// with the group's
+	mbits := 0
+	for _, res := range tasks {
+		for _, n := range res.Networks {
+			mbits += n.MBits
+		}
+	}
+
+	// The ports are mapped on both addresses of dual stack hosts
+	var mappings []cni.PortMapping
+	for _, n := range shared.Networks {
+		mbits += n.MBits
+		hostIPs := []string{n.IP}
+		if n.IPv6 != "" {
+			hostIPs = append(hostIPs, n.IPv6)
+		}
+		ports := n.PortMappings()
+		for _, list := range [][]structs.Port{n.ReservedPorts, n.DynamicPorts} {
+			for _, port := range list {
+				for _, hostIP := range hostIPs {
+					for _, proto := range []string{"tcp", "udp"} {
+						mappings = append(mappings, cni.PortMapping{
+							HostPort:      port.Value,
+							ContainerPort: ports[port.Label],
+							Protocol:      proto,
+							HostIP:        hostIP,
+						})
+					}
+				}
+			}
+		}
+	}
+	args := make(map[string]interface{})
+	if len(mappings) != 0 {
+		args["portMappings"] = mappings
+	}
+	if mbits > 0 {
+		args["bandwidth"] = cni.NewBandwidth(mbits)
+	}
+	if len(args) != 0 {
+		rt.CapabilityArgs = args
+	}
+	return rt
+}
+
+// setupNetwork creates the network namespace of the allocation and adds it
+// to the CNI network of its group, if it has one, recording the address the
+// plugins assigned. A network restored along with the allocation is kept.
+func (r *AllocRunner) setupNetwork(tg *structs.TaskGroup) error {
+	name, ok := groupNetworkName(tg)
+	if !ok {
+		return nil
+	}
+
+	rt := r.cniRuntime()
+	r.allocLock.Lock()
+	restored := r.alloc.NetworkStatus != nil
+	r.allocLock.Unlock()
+	if _, err := os.Stat(rt.NetNS); err == nil && restored {
+		return nil
+	}
+
+	list, err := r.groupNetworkConfig(tg)
+	if err != nil {
+		return err
+	}
+	if err := cni.CreateNetNS(rt.NetNS); err != nil {
+		return err
+	}
+
+	result, err := cni.AddNetworkList(list, rt)
+	if err != nil {
+		if err := cni.DelNetworkList(list, rt); err != nil {
+			r.logger.Printf("[WARN] client: failed to clean up CNI network of alloc %q: %v", r.allocID, err)
+		}
+		if err := cni.DeleteNetNS(rt.NetNS); err != nil {
+			r.logger.Printf("[WARN] client: failed to delete network namespace of alloc %q: %v", r.allocID, err)
+		}
+		return err
+	}
+
+	ip, ifName := result.ContainerIP(rt.IfName)
+	if ip == "" {
+		r.logger.Printf("[WARN] client: CNI network %q assigned no address to alloc %q", name, r.allocID)
+	} else {
+		r.logger.Printf("[DEBUG] client: alloc %q joined CNI network %q with address %s", r.allocID, name, ip)
+	}
+
+	r.allocLock.Lock()
+	status := &structs.AllocNetworkStatus{
+		InterfaceName: ifName,
+		Address:       ip,
+	}
+	if ipv6 := result.ContainerIPv6(rt.IfName); ipv6 != ip {
+		status.AddressIPv6 = ipv6
+	}
+	r.alloc.NetworkStatus = status
+	r.allocLock.Unlock()
+	return nil
+}
+
+// teardownNetwork removes the allocation from the CNI network of its group
+// and deletes its network namespace once its tasks are stopped. Errors are
+// logged.
+func (r *AllocRunner) teardownNetwork(tg *structs.TaskGroup) {
+	name, ok := groupNetworkName(tg)
+	if !ok {
+		return
+	}
+
+	rt := r.cniRuntime()
+	if _, err := os.Stat(rt.NetNS); os.IsNotExist(err) {
+		return
+	}
+
+	list, err := r.groupNetworkConfig(tg)
+	if err == nil {
+		err = cni.DelNetworkList(list, rt)
+	}
+	if err != nil {
+		r.logger.Printf("[ERR] client: failed to remove alloc %q from CNI network %q: %v", r.allocID, name, err)
+	}
+	if err := cni.DeleteNetNS(rt.NetNS); err != nil {
+		r.logger.Printf("[ERR] client: failed to delete network namespace of alloc %q: %v", r.allocID, err)
+	}
+}
@@ -73,12 +73,17 @@ type AllocRunner struct {
 	restored   map[string]struct{}
 	taskLock   sync.RWMutex
 
+	// lifecycle orders the tasks of the group according to their lifecycle
+	// hooks. It is nil if none of the tasks has a lifecycle hook.
+	lifecycle *taskLifecycle
+
 	taskStatusLock sync.RWMutex
 
 	updateCh chan *structs.Allocation
 
-	vaultClient  vaultclient.VaultClient
-	consulClient ConsulServiceAPI
+	vaultClient    vaultclient.VaultClient
+	consulClient   ConsulServiceAPI
+	siTokenDeriver SITokenDeriverFunc
 
 	otherAllocDir *allocdir.AllocDir
 
@@ -145,12 +150,13 @@ type allocRunnerMutableState struct {
 	AllocClientDescription string
 	TaskStates             map[string]*structs.TaskState
 	DeploymentStatus       *structs.AllocDeploymentStatus
+	NetworkStatus          *structs.AllocNetworkStatus
 }
 
 // NewAllocRunner is used to create a new allocation context
 func NewAllocRunner(logger *log.Logger, config *config.Config, stateDB *bolt.DB, updater AllocStateUpdater,
 	alloc *structs.Allocation, vaultClient vaultclient.VaultClient,
-	consulClient ConsulServiceAPI) *AllocRunner {
+	consulClient ConsulServiceAPI, siTokenDeriver SITokenDeriverFunc) *AllocRunner {
 
 	ar := &AllocRunner{
 		config:         config,
@@ -169,6 +175,7 @@ func NewAllocRunner(logger *log.Logger, config *config.Config, stateDB *bolt.DB,
 		waitCh:         make(chan struct{}),
 		vaultClient:    vaultClient,
 		consulClient:   consulClient,
+		siTokenDeriver: siTokenDeriver,
 	}
 
 	// TODO Should be passed a context
@@ -258,6 +265,7 @@ func (r *AllocRunner) RestoreState() error {
 			r.taskStates = mutable.TaskStates
 			r.alloc.ClientStatus = getClientStatus(r.taskStates)
 			r.alloc.DeploymentStatus = mutable.DeploymentStatus
+			r.alloc.NetworkStatus = mutable.NetworkStatus
 			return nil
 		})
 
@@ -282,6 +290,8 @@ func (r *AllocRunner) RestoreState() error {
 		return fmt.Errorf("restored allocation doesn't contain task group %q", r.alloc.TaskGroup)
 	}
 
+	r.lifecycle = newTaskLifecycle(tg)
+
 	// Restore the task runners
 	taskDestroyEvent := structs.NewTaskEvent(structs.TaskKilled)
 	var mErr multierror.Error
@@ -312,9 +322,16 @@ func (r *AllocRunner) RestoreState() error {
 			continue
 		}
 
-		tr := NewTaskRunner(r.logger, r.config, r.stateDB, r.setTaskState, td, r.Alloc(), task, r.vaultClient, r.consulClient)
+		tr := NewTaskRunner(r.logger, r.config, r.stateDB, r.setTaskState, td, r.Alloc(), task, r.vaultClient, r.consulClient, r.siTokenDeriver)
 		r.tasks[name] = tr
 
+		// Tasks that were waiting for their lifecycle hook keep waiting
+		if r.lifecycle != nil && state.State == structs.TaskStatePending {
+			if gate := r.lifecycle.gate(task.LifecycleHook()); gate != nil {
+				tr.SetStartGate(gate)
+			}
+		}
+
 		if restartReason, err := tr.RestoreState(); err != nil {
 			r.logger.Printf("[ERR] client: failed to restore state for alloc %s task %q: %v", r.allocID, name, err)
 			mErr.Errors = append(mErr.Errors, err)
@@ -331,7 +348,7 @@ func (r *AllocRunner) RestoreState() error {
 			// Restart task runner if RestoreState gave a reason
 			if restartReason != "" {
 				r.logger.Printf("[INFO] client: restarting alloc %s task %s: %v", r.allocID, name, restartReason)
-				tr.Restart("upgrade", restartReason)
+				tr.Restart("upgrade", restartReason, false)
 			}
 		} else {
 			tr.Destroy(taskDestroyEvent)
@@ -443,6 +460,7 @@ func (r *AllocRunner) saveAllocRunnerState() error {
 			AllocClientDescription: allocClientDescription,
 			TaskStates:             alloc.TaskStates,
 			DeploymentStatus:       alloc.DeploymentStatus,
+			NetworkStatus:          alloc.NetworkStatus,
 		}
 
 		if err := putObject(allocBkt, allocRunnerStateMutableKey, &mutable); err != nil {
@@ -662,10 +680,15 @@ func (r *AllocRunner) setTaskState(taskName, state string, event *structs.TaskEv
 
 		// Find all tasks that are not the one that is dead and check if the one
 		// that is dead is a leader
+		// Poststop tasks are not killed since they run once the other tasks
+		// are dead
 		var otherTaskRunners []*TaskRunner
 		var otherTaskNames []string
 		leader := false
 		for task, tr := range r.tasks {
+			if tr.task.LifecycleHook() == structs.TaskLifecycleHookPoststop {
+				continue
+			}
 			if task != taskName {
 				otherTaskRunners = append(otherTaskRunners, tr)
 				otherTaskNames = append(otherTaskNames, task)
@@ -700,6 +723,10 @@ func (r *AllocRunner) setTaskState(taskName, state string, event *structs.TaskEv
 	case r.dirtyCh <- struct{}{}:
 	default:
 	}
+
+	if r.lifecycle != nil {
+		r.lifecycle.notify()
+	}
 }
 
 // appendTaskEvent updates the task status by appending the new event.
@@ -760,16 +787,28 @@ func (r *AllocRunner) Run() {
 		r.logger.Printf("[DEBUG] client: alloc %q in terminal status, waiting for destroy", r.allocID)
 		// mark this allocation as completed.
 		r.setStatus(structs.AllocClientStatusComplete, "cancelled running tasks for allocation in terminal state")
+		r.teardownNetwork(tg)
 		r.handleDestroy()
 		r.logger.Printf("[DEBUG] client: terminating runner for alloc '%s'", r.allocID)
 		return
 	}
 
+	// Set up the network of the group before its tasks start
+	if err := r.setupNetwork(tg); err != nil {
+		r.logger.Printf("[ERR] client: failed to set up network of alloc %q: %v", r.allocID, err)
+		r.setStatus(structs.AllocClientStatusFailed, fmt.Sprintf("failed to set up network: %v", err))
+		return
+	}
+
 	// Start the watcher
 	wCtx, watcherCancel := context.WithCancel(r.ctx)
 	go r.watchHealth(wCtx)
 
-	// Start the task runners
+	// Start the task runners, gating their start according to their
+	// lifecycle hooks
+	if r.lifecycle == nil {
+		r.lifecycle = newTaskLifecycle(tg)
+	}
 	r.logger.Printf("[DEBUG] client: starting task runners for alloc '%s'", r.allocID)
 	r.taskLock.Lock()
 	for _, task := range tg.Tasks {
@@ -781,14 +820,25 @@ func (r *AllocRunner) Run() {
 		taskdir := r.allocDir.NewTaskDir(task.Name)
 		r.allocDirLock.Unlock()
 
-		tr := NewTaskRunner(r.logger, r.config, r.stateDB, r.setTaskState, taskdir, r.Alloc(), task.Copy(), r.vaultClient, r.consulClient)
+		tr := NewTaskRunner(r.logger, r.config, r.stateDB, r.setTaskState, taskdir, r.Alloc(), task.Copy(), r.vaultClient, r.consulClient, r.siTokenDeriver)
 		r.tasks[task.Name] = tr
 		tr.MarkReceived()
 
+		if r.lifecycle != nil {
+			if gate := r.lifecycle.gate(task.LifecycleHook()); gate != nil {
+				tr.SetStartGate(gate)
+			}
+		}
+
 		go tr.Run()
 	}
 	r.taskLock.Unlock()
 
+	lifecycleCtx, lifecycleCancel := context.WithCancel(r.ctx)
+	if r.lifecycle != nil {
+		go r.runLifecycle(lifecycleCtx)
+	}
+
 	// taskDestroyEvent contains an event that caused the destroyment of a task
 	// in the allocation.
 	var taskDestroyEvent *structs.TaskEvent
@@ -806,6 +856,9 @@ OUTER:
 				r.allocHealth = nil
 			}
 
+			// The client is the authority on the network of the allocation
+			update.NetworkStatus = r.alloc.NetworkStatus
+
 			r.alloc = update
 			r.allocLock.Unlock()
 
@@ -837,7 +890,9 @@ OUTER:
 	}
 
 	// Kill the task runners
+	lifecycleCancel()
 	r.destroyTaskRunners(taskDestroyEvent)
+	r.teardownNetwork(tg)
 
 	// Block until we should destroy the state of the alloc
 	r.handleDestroy()
@@ -877,10 +932,11 @@ func (r *AllocRunner) destroyTaskRunners(destroyEvent *structs.TaskEvent) {
 		<-tr.WaitCh()
 	}
 
-	// Then destroy non-leader tasks concurrently
+	// Then destroy non-leader tasks concurrently. Poststop tasks are left to
+	// run once the others are dead.
 	r.taskLock.RLock()
 	for name, tr := range r.tasks {
-		if name != leader {
+		if name != leader && tr.task.LifecycleHook() != structs.TaskLifecycleHookPoststop {
 			tr.Destroy(destroyEvent)
 		}
 	}
@@ -888,8 +944,12 @@ func (r *AllocRunner) destroyTaskRunners(destroyEvent *structs.TaskEvent) {
 
 	// Wait for termination of the task runners
 	for _, tr := range r.getTaskRunners() {
-		<-tr.WaitCh()
+		if tr.task.LifecycleHook() != structs.TaskLifecycleHookPoststop {
+			<-tr.WaitCh()
+		}
 	}
+
+	r.runPoststop()
 }
 
 // handleDestroy blocks till the AllocRunner should be destroyed and does the
@@ -945,6 +1005,81 @@ func (r *AllocRunner) StatsReporter() AllocStatsReporter {
 	return r
 }
 
+// Signal sends the signal to the named task of the allocation, or to all of
+// its tasks if the task is empty.
+func (r *AllocRunner) Signal(task string, s os.Signal) error {
+	reason := fmt.Sprintf("signal %v requested", s)
+	if task != "" {
+		r.taskLock.RLock()
+		tr, ok := r.tasks[task]
+		r.taskLock.RUnlock()
+		if !ok {
+			return fmt.Errorf("task %q not found in allocation %q", task, r.allocID)
+		}
+		return tr.Signal("user", reason, s)
+	}
+
+	var mErr multierror.Error
+	for _, tr := range r.getTaskRunners() {
+		if err := tr.Signal("user", reason, s); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("failed to signal task %q: %v", tr.task.Name, err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// Restart restarts the named task of the allocation in place, or all of its
+// tasks if the task is empty.
+func (r *AllocRunner) Restart(task string) error {
+	const reason = "restart requested"
+	if task != "" {
+		r.taskLock.RLock()
+		tr, ok := r.tasks[task]
+		r.taskLock.RUnlock()
+		if !ok {
+			return fmt.Errorf("task %q not found in allocation %q", task, r.allocID)
+		}
+		tr.Restart("user", reason, false)
+		return nil
+	}
+
+	for _, tr := range r.getTaskRunners() {
+		tr.Restart("user", reason, false)
+	}
+	return nil
+}
+
+// Exec runs the command inside the named running task of the allocation and
+// returns its output and exit code.
+func (r *AllocRunner) Exec(ctx context.Context, task, cmd string, args []string) ([]byte, int, error) {
+	tr, err := r.getTaskRunner(task)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tr.Exec(ctx, cmd, args)
+}
+
+// TaskFSIsolation returns the filesystem isolation of the named task of the
+// allocation.
+func (r *AllocRunner) TaskFSIsolation(task string) (cstructs.FSIsolation, error) {
+	tr, err := r.getTaskRunner(task)
+	if err != nil {
+		return cstructs.FSIsolationNone, err
+	}
+	return tr.FSIsolation(), nil
+}
+
+// getTaskRunner returns the runner of the named task of the allocation.
+func (r *AllocRunner) getTaskRunner(task string) (*TaskRunner, error) {
+	r.taskLock.RLock()
+	tr, ok := r.tasks[task]
+	r.taskLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("task %q not found in allocation %q", task, r.allocID)
+	}
+	return tr, nil
+}
+
 // getTaskRunners is a helper that returns a copy of the task runners list using
 // the taskLock.
 func (r *AllocRunner) getTaskRunners() []*TaskRunner {
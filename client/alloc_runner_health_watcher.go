@@ -50,6 +50,10 @@ func (r *AllocRunner) watchHealth(ctx context.Context) {
 		return
 	case u.HealthCheck == structs.UpdateStrategyHealthCheck_Checks:
 		for _, task := range tg.Tasks {
+			// Tasks that are expected to exit don't keep their checks
+			if !task.RunsWithMain() {
+				continue
+			}
 			for _, s := range task.Services {
 				desiredChecks += len(s.Checks)
 			}
@@ -164,9 +168,14 @@ OUTER:
 			continue OUTER
 		}
 
-		// If the task is dead or has restarted, fail
-		for _, tstate := range alloc.TaskStates {
-			if tstate.Failed || !tstate.FinishedAt.IsZero() || tstate.Restarts != 0 {
+		// If the task is dead or has restarted, fail. Tasks with a lifecycle
+		// hook that aren't sidecars are expected to exit.
+		for task, tstate := range alloc.TaskStates {
+			exits := false
+			if t := tg.LookupTask(task); t != nil {
+				exits = !t.RunsWithMain()
+			}
+			if tstate.Failed || (!exits && !tstate.FinishedAt.IsZero()) || tstate.Restarts != 0 {
 				r.logger.Printf("[TRACE] client.alloc_watcher: setting health to false for alloc %q", alloc.ID)
 				setHealth(false)
 				return
@@ -195,6 +204,9 @@ OUTER:
 
 		// Determine if the allocation is healthy
 		for task, tstate := range alloc.TaskStates {
+			if t := tg.LookupTask(task); t != nil && !t.RunsWithMain() {
+				continue
+			}
 			if tstate.State != structs.TaskStateRunning {
 				r.logger.Printf("[TRACE] client.alloc_watcher: continuing since task %q hasn't started for alloc %q", task, alloc.ID)
 				continue OUTER
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// taskLifecycle orders the tasks of a task group according to their lifecycle
+// hooks. The main tasks start once the prestart tasks have completed or, for
+// sidecars, are running, the poststart tasks once the main tasks are running
+// and the poststop tasks once the main tasks are dead.
+type taskLifecycle struct {
+	tg *structs.TaskGroup
+
+	// gates block the start of the main tasks, keyed by the empty hook, and
+	// of the poststart and poststop tasks until they are opened
+	gates  map[string]chan struct{}
+	opened map[string]bool
+	l      sync.Mutex
+
+	// notifyCh is signaled when the state of a task changes
+	notifyCh chan struct{}
+}
+
+// newTaskLifecycle returns the lifecycle of the task group or nil if none of
+// its tasks has a lifecycle hook.
+func newTaskLifecycle(tg *structs.TaskGroup) *taskLifecycle {
+	found := false
+	for _, task := range tg.Tasks {
+		if task.Lifecycle != nil {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	return &taskLifecycle{
+		tg: tg,
+		gates: map[string]chan struct{}{
+			"":                                 make(chan struct{}),
+			structs.TaskLifecycleHookPoststart: make(chan struct{}),
+			structs.TaskLifecycleHookPoststop:  make(chan struct{}),
+		},
+		opened:   make(map[string]bool),
+		notifyCh: make(chan struct{}, 1),
+	}
+}
+
+// gate returns the channel blocking the start of the tasks with the hook or
+// nil if they start right away.
+func (l *taskLifecycle) gate(hook string) <-chan struct{} {
+	return l.gates[hook]
+}
+
+// open unblocks the start of the tasks with the hook.
+func (l *taskLifecycle) open(hook string) {
+	l.l.Lock()
+	defer l.l.Unlock()
+	if !l.opened[hook] {
+		l.opened[hook] = true
+		close(l.gates[hook])
+	}
+}
+
+// notify signals that the state of a task changed without blocking.
+func (l *taskLifecycle) notify() {
+	select {
+	case l.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// lifecycleTaskState is the state of a task relevant to its lifecycle.
+type lifecycleTaskState struct {
+	state  string
+	failed bool
+}
+
+// runLifecycle opens the gates of the lifecycle of the task group as its
+// tasks progress and stops the sidecars once the main tasks are dead. It
+// returns once the poststop tasks are allowed to start or the context is
+// done.
+func (r *AllocRunner) runLifecycle(ctx context.Context) {
+	l := r.lifecycle
+	for {
+		r.taskStatusLock.RLock()
+		states := make(map[string]lifecycleTaskState, len(r.taskStates))
+		for name, s := range r.taskStates {
+			states[name] = lifecycleTaskState{state: s.State, failed: s.Failed}
+		}
+		r.taskStatusLock.RUnlock()
+
+		prestartDone, mainRunning, mainDead := true, true, true
+		for _, task := range l.tg.Tasks {
+			s := states[task.Name]
+			switch task.LifecycleHook() {
+			case structs.TaskLifecycleHookPrestart:
+				if task.Lifecycle.Sidecar {
+					prestartDone = prestartDone && s.state == structs.TaskStateRunning
+				} else {
+					prestartDone = prestartDone && s.state == structs.TaskStateDead && !s.failed
+				}
+			case "":
+				mainRunning = mainRunning && s.state == structs.TaskStateRunning
+				mainDead = mainDead && s.state == structs.TaskStateDead
+			}
+		}
+
+		if prestartDone {
+			l.open("")
+		}
+		if mainRunning {
+			l.open(structs.TaskLifecycleHookPoststart)
+		}
+		if mainDead {
+			r.stopSidecars()
+			l.open(structs.TaskLifecycleHookPoststop)
+			return
+		}
+
+		select {
+		case <-l.notifyCh:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stopSidecars destroys the sidecars once the main tasks are dead.
+func (r *AllocRunner) stopSidecars() {
+	var names []string
+	r.taskLock.RLock()
+	for name, tr := range r.tasks {
+		if tr.task.Lifecycle != nil && tr.task.Lifecycle.Sidecar {
+			tr.Destroy(structs.NewTaskEvent(structs.TaskMainDead))
+			names = append(names, name)
+		}
+	}
+	r.taskLock.RUnlock()
+
+	if len(names) > 0 {
+		r.logger.Printf("[DEBUG] client: main tasks of alloc %q are dead, destroying sidecars: %v", r.allocID, names)
+	}
+}
+
+// runPoststop starts the poststop tasks once the other tasks are dead and
+// waits for them to exit. They are destroyed if the alloc runner is.
+func (r *AllocRunner) runPoststop() {
+	if r.lifecycle == nil {
+		return
+	}
+	r.lifecycle.open(structs.TaskLifecycleHookPoststop)
+
+	var poststop []*TaskRunner
+	for _, tr := range r.getTaskRunners() {
+		if tr.task.LifecycleHook() == structs.TaskLifecycleHookPoststop {
+			poststop = append(poststop, tr)
+		}
+	}
+	for _, tr := range poststop {
+		select {
+		case <-tr.WaitCh():
+		case <-r.ctx.Done():
+			tr.Destroy(structs.NewTaskEvent(structs.TaskKilled))
+			<-tr.WaitCh()
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"text/template"
 	"time"
@@ -19,7 +22,9 @@ import (
 	"github.com/hashicorp/nomad/testutil"
 	"github.com/kr/pretty"
 
+	"github.com/hashicorp/nomad/client/cni"
 	"github.com/hashicorp/nomad/client/config"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/client/vaultclient"
 )
 
@@ -60,7 +65,7 @@ func testAllocRunnerFromAlloc(alloc *structs.Allocation, restarts bool) (*MockAl
 		alloc.Job.Type = structs.JobTypeBatch
 	}
 	vclient := vaultclient.NewMockVaultClient()
-	ar := NewAllocRunner(logger, conf, db, upd.Update, alloc, vclient, newMockConsulServiceClient())
+	ar := NewAllocRunner(logger, conf, db, upd.Update, alloc, vclient, newMockConsulServiceClient(), nil)
 	return upd, ar
 }
 
@@ -506,6 +511,161 @@ func TestAllocRunner_TerminalUpdate_Destroy(t *testing.T) {
 	})
 }
 
+func TestAllocRunner_Signal(t *testing.T) {
+	t.Parallel()
+	upd, ar := testAllocRunner(false)
+
+	// Ensure the task keeps running
+	task := ar.alloc.Job.TaskGroups[0].Tasks[0]
+	task.Config["run_for"] = "10s"
+	go ar.Run()
+	defer ar.Destroy()
+
+	testutil.WaitForResult(func() (bool, error) {
+		_, last := upd.Last()
+		if last == nil {
+			return false, fmt.Errorf("No updates")
+		}
+		if last.ClientStatus != structs.AllocClientStatusRunning {
+			return false, fmt.Errorf("got status %v; want %v", last.ClientStatus, structs.AllocClientStatusRunning)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	if err := ar.Signal("unknown", syscall.SIGHUP); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected unknown task error, got: %v", err)
+	}
+	if err := ar.Signal(task.Name, syscall.SIGHUP); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := ar.Signal("", syscall.SIGHUP); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The signals are recorded as task events
+	testutil.WaitForResult(func() (bool, error) {
+		state := ar.Alloc().TaskStates[task.Name]
+		if state == nil {
+			return false, fmt.Errorf("no task state")
+		}
+		count := 0
+		for _, e := range state.Events {
+			if e.Type == structs.TaskSignaling {
+				count++
+			}
+		}
+		if count != 2 {
+			return false, fmt.Errorf("got %d signal events; want 2", count)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestAllocRunner_Restart(t *testing.T) {
+	t.Parallel()
+	upd, ar := testAllocRunner(false)
+
+	// Ensure the task keeps running
+	task := ar.alloc.Job.TaskGroups[0].Tasks[0]
+	task.Config["run_for"] = "10s"
+	go ar.Run()
+	defer ar.Destroy()
+
+	testutil.WaitForResult(func() (bool, error) {
+		_, last := upd.Last()
+		if last == nil {
+			return false, fmt.Errorf("No updates")
+		}
+		if last.ClientStatus != structs.AllocClientStatusRunning {
+			return false, fmt.Errorf("got status %v; want %v", last.ClientStatus, structs.AllocClientStatusRunning)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	if err := ar.Restart("unknown"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected unknown task error, got: %v", err)
+	}
+	if err := ar.Restart(task.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The task is restarted in place
+	testutil.WaitForResult(func() (bool, error) {
+		state := ar.Alloc().TaskStates[task.Name]
+		if state == nil {
+			return false, fmt.Errorf("no task state")
+		}
+		signaled, started := false, 0
+		for _, e := range state.Events {
+			switch e.Type {
+			case structs.TaskRestartSignal:
+				signaled = true
+			case structs.TaskStarted:
+				started++
+			}
+		}
+		if !signaled || started != 2 {
+			return false, fmt.Errorf("got restart signal %v and %d starts; want true and 2", signaled, started)
+		}
+		if state.State != structs.TaskStateRunning {
+			return false, fmt.Errorf("got state %v; want %v", state.State, structs.TaskStateRunning)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestAllocRunner_Exec(t *testing.T) {
+	t.Parallel()
+	upd, ar := testAllocRunner(false)
+
+	// Ensure the task keeps running
+	task := ar.alloc.Job.TaskGroups[0].Tasks[0]
+	task.Config["run_for"] = "10s"
+	go ar.Run()
+	defer ar.Destroy()
+
+	testutil.WaitForResult(func() (bool, error) {
+		_, last := upd.Last()
+		if last == nil {
+			return false, fmt.Errorf("No updates")
+		}
+		if last.ClientStatus != structs.AllocClientStatusRunning {
+			return false, fmt.Errorf("got status %v; want %v", last.ClientStatus, structs.AllocClientStatusRunning)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	ctx := context.Background()
+	if _, _, err := ar.Exec(ctx, "unknown", "ls", nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected unknown task error, got: %v", err)
+	}
+	out, code, err := ar.Exec(ctx, task.Name, "ls", []string{"-l"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if code != 0 || string(out) != `Exec("ls", ["-l"])` {
+		t.Fatalf("bad exec: %d %q", code, out)
+	}
+
+	fsi, err := ar.TaskFSIsolation(task.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if fsi != cstructs.FSIsolationNone {
+		t.Fatalf("bad isolation: %v", fsi)
+	}
+}
+
 func TestAllocRunner_Destroy(t *testing.T) {
 	t.Parallel()
 	upd, ar := testAllocRunner(false)
@@ -621,7 +781,7 @@ func TestAllocRunner_SaveRestoreState(t *testing.T) {
 	l2 := prefixedTestLogger("----- ar2:  ")
 	ar2 := NewAllocRunner(l2, ar.config, ar.stateDB, upd.Update,
 		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient,
-		ar.consulClient)
+		ar.consulClient, ar.siTokenDeriver)
 	err = ar2.RestoreState()
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -713,7 +873,7 @@ func TestAllocRunner_SaveRestoreState_TerminalAlloc(t *testing.T) {
 
 	// Create a new alloc runner
 	ar2 := NewAllocRunner(ar.logger, ar.config, ar.stateDB, upd.Update,
-		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient, ar.consulClient)
+		&structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient, ar.consulClient, ar.siTokenDeriver)
 	ar2.logger = prefixedTestLogger("ar2: ")
 	err = ar2.RestoreState()
 	if err != nil {
@@ -826,7 +986,7 @@ func TestAllocRunner_SaveRestoreState_Upgrade(t *testing.T) {
 
 	// Create a new alloc runner
 	l2 := prefixedTestLogger("----- ar2:  ")
-	ar2 := NewAllocRunner(l2, origConfig, ar.stateDB, upd.Update, &structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient, ar.consulClient)
+	ar2 := NewAllocRunner(l2, origConfig, ar.stateDB, upd.Update, &structs.Allocation{ID: ar.alloc.ID}, ar.vaultClient, ar.consulClient, ar.siTokenDeriver)
 	err = ar2.RestoreState()
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -979,7 +1139,7 @@ func TestAllocRunner_RestoreOldState(t *testing.T) {
 	alloc.Job.Type = structs.JobTypeBatch
 	vclient := vaultclient.NewMockVaultClient()
 	cclient := newMockConsulServiceClient()
-	ar := NewAllocRunner(logger, conf, db, upd.Update, alloc, vclient, cclient)
+	ar := NewAllocRunner(logger, conf, db, upd.Update, alloc, vclient, cclient, nil)
 	defer ar.Destroy()
 
 	// RestoreState should fail on the task state since we only test the
@@ -1144,6 +1304,81 @@ func TestAllocRunner_TaskLeader_KillTG(t *testing.T) {
 	})
 }
 
+// TestAllocRunner_Lifecycle asserts that the main task starts once the
+// prestart task completed, that the sidecar is stopped once the main task is
+// dead and that the poststop task runs last.
+func TestAllocRunner_Lifecycle(t *testing.T) {
+	t.Parallel()
+	upd, ar := testAllocRunner(false)
+
+	main := ar.alloc.Job.TaskGroups[0].Tasks[0]
+	main.Config = map[string]interface{}{
+		"run_for": "500ms",
+	}
+
+	newTask := func(name, hook string, sidecar bool, runFor string) *structs.Task {
+		task := main.Copy()
+		task.Name = name
+		task.Services = nil
+		task.Lifecycle = &structs.TaskLifecycleConfig{
+			Hook:    hook,
+			Sidecar: sidecar,
+		}
+		task.KillTimeout = 10 * time.Millisecond
+		task.Config = map[string]interface{}{
+			"run_for": runFor,
+		}
+		ar.alloc.Job.TaskGroups[0].Tasks = append(ar.alloc.Job.TaskGroups[0].Tasks, task)
+		ar.alloc.TaskResources[task.Name] = task.Resources
+		return task
+	}
+	initTask := newTask("init", structs.TaskLifecycleHookPrestart, false, "100ms")
+	sidecar := newTask("sidecar", structs.TaskLifecycleHookPrestart, true, "10s")
+	cleanup := newTask("cleanup", structs.TaskLifecycleHookPoststop, false, "10ms")
+
+	go ar.Run()
+	defer ar.Destroy()
+
+	testutil.WaitForResult(func() (bool, error) {
+		_, last := upd.Last()
+		if last == nil {
+			return false, fmt.Errorf("No updates")
+		}
+		if last.ClientStatus != structs.AllocClientStatusComplete {
+			return false, fmt.Errorf("got status %v; want %v", last.ClientStatus, structs.AllocClientStatusComplete)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	_, last := upd.Last()
+	initState := last.TaskStates[initTask.Name]
+	mainState := last.TaskStates[main.Name]
+	sidecarState := last.TaskStates[sidecar.Name]
+	cleanupState := last.TaskStates[cleanup.Name]
+
+	if mainState.StartedAt.Before(initState.FinishedAt) {
+		t.Fatalf("main task started at %v before the prestart task finished at %v", mainState.StartedAt, initState.FinishedAt)
+	}
+	if mainState.StartedAt.Before(sidecarState.StartedAt) {
+		t.Fatalf("main task started at %v before the sidecar started at %v", mainState.StartedAt, sidecarState.StartedAt)
+	}
+	if cleanupState.StartedAt.Before(mainState.FinishedAt) {
+		t.Fatalf("poststop task started at %v before the main task finished at %v", cleanupState.StartedAt, mainState.FinishedAt)
+	}
+
+	found := false
+	for _, e := range sidecarState.Events {
+		if e.Type == structs.TaskMainDead {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("sidecar events %v don't include %v", sidecarState.Events, structs.TaskMainDead)
+	}
+}
+
 // TestAllocRunner_TaskLeader_StopTG asserts that when stopping a task group
 // with a leader the leader is stopped before other tasks.
 func TestAllocRunner_TaskLeader_StopTG(t *testing.T) {
@@ -1301,3 +1536,21 @@ func TestAllocRunner_MoveAllocDir(t *testing.T) {
 		t.Fatalf("file %v not found", dataFile)
 	}
 }
+
+func TestAllocRunner_CNIRuntime_Bandwidth(t *testing.T) {
+	alloc := mock.Alloc()
+	alloc.SharedResources.Networks = []*structs.NetworkResource{
+		{
+			Mode:  structs.NetworkModeBridge,
+			IP:    "192.168.0.100",
+			MBits: 100,
+		},
+	}
+	_, ar := testAllocRunnerFromAlloc(alloc, false)
+
+	// The namespace is limited to the bandwidth of the group and its tasks
+	rt := ar.cniRuntime()
+	if bw, ok := rt.CapabilityArgs["bandwidth"]; !ok || !reflect.DeepEqual(bw, cni.NewBandwidth(150)) {
+		t.Fatalf("bad: %#v", rt.CapabilityArgs)
+	}
+}
@@ -237,6 +237,39 @@ func (d *AllocDir) Destroy() error {
 	return mErr.ErrorOrNil()
 }
 
+// Size returns the total size in bytes of the files of the allocation
+// directory. The shared directory mounted in task directories and the secrets
+// directories, which are in memory, aren't counted.
+func (d *AllocDir) Size() (int64, error) {
+	skip := make(map[string]struct{}, 2*len(d.TaskDirs))
+	for _, dir := range d.TaskDirs {
+		skip[dir.SharedTaskDir] = struct{}{}
+		skip[dir.SecretsDir] = struct{}{}
+	}
+
+	var size int64
+	err := filepath.Walk(d.AllocDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files may be removed while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			if _, ok := skip[path]; ok {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
 // UnmountAll linked/mounted directories in task dirs.
 func (d *AllocDir) UnmountAll() error {
 	var mErr multierror.Error
@@ -301,6 +334,10 @@ func (d *AllocDir) Build() error {
 
 // List returns the list of files at a path relative to the alloc dir
 func (d *AllocDir) List(path string) ([]*AllocFileInfo, error) {
+	return d.list(path, false)
+}
+
+func (d *AllocDir) list(path string, secrets bool) ([]*AllocFileInfo, error) {
 	if escapes, err := structs.PathEscapesAllocDir("", path); err != nil {
 		return nil, fmt.Errorf("Failed to check if path escapes alloc directory: %v", err)
 	} else if escapes {
@@ -308,6 +345,12 @@ func (d *AllocDir) List(path string) ([]*AllocFileInfo, error) {
 	}
 
 	p := filepath.Join(d.AllocDir, path)
+
+	// Check if it is trying to list a secret directory
+	if !secrets && d.inSecretDir(p, true) {
+		return nil, fmt.Errorf("Listing secret directory prohibited: %s", path)
+	}
+
 	finfos, err := ioutil.ReadDir(p)
 	if err != nil {
 		return []*AllocFileInfo{}, err
@@ -327,6 +370,10 @@ func (d *AllocDir) List(path string) ([]*AllocFileInfo, error) {
 
 // Stat returns information about the file at a path relative to the alloc dir
 func (d *AllocDir) Stat(path string) (*AllocFileInfo, error) {
+	return d.stat(path, false)
+}
+
+func (d *AllocDir) stat(path string, secrets bool) (*AllocFileInfo, error) {
 	if escapes, err := structs.PathEscapesAllocDir("", path); err != nil {
 		return nil, fmt.Errorf("Failed to check if path escapes alloc directory: %v", err)
 	} else if escapes {
@@ -334,6 +381,12 @@ func (d *AllocDir) Stat(path string) (*AllocFileInfo, error) {
 	}
 
 	p := filepath.Join(d.AllocDir, path)
+
+	// Check if it is trying to stat a secret file
+	if !secrets && d.inSecretDir(p, false) {
+		return nil, fmt.Errorf("Reading secret file prohibited: %s", path)
+	}
+
 	info, err := os.Stat(p)
 	if err != nil {
 		return nil, err
@@ -350,6 +403,10 @@ func (d *AllocDir) Stat(path string) (*AllocFileInfo, error) {
 
 // ReadAt returns a reader for a file at the path relative to the alloc dir
 func (d *AllocDir) ReadAt(path string, offset int64) (io.ReadCloser, error) {
+	return d.readAt(path, offset, false)
+}
+
+func (d *AllocDir) readAt(path string, offset int64, secrets bool) (io.ReadCloser, error) {
 	if escapes, err := structs.PathEscapesAllocDir("", path); err != nil {
 		return nil, fmt.Errorf("Failed to check if path escapes alloc directory: %v", err)
 	} else if escapes {
@@ -359,10 +416,8 @@ func (d *AllocDir) ReadAt(path string, offset int64) (io.ReadCloser, error) {
 	p := filepath.Join(d.AllocDir, path)
 
 	// Check if it is trying to read into a secret directory
-	for _, dir := range d.TaskDirs {
-		if filepath.HasPrefix(p, dir.SecretsDir) {
-			return nil, fmt.Errorf("Reading secret file prohibited: %s", path)
-		}
+	if !secrets && d.inSecretDir(p, true) {
+		return nil, fmt.Errorf("Reading secret file prohibited: %s", path)
 	}
 
 	f, err := os.Open(p)
@@ -375,6 +430,45 @@ func (d *AllocDir) ReadAt(path string, offset int64) (io.ReadCloser, error) {
 	return f, nil
 }
 
+// inSecretDir returns whether the path is within the secret directory of a
+// task. The secret directories themselves are included if self is set.
+func (d *AllocDir) inSecretDir(p string, self bool) bool {
+	for _, dir := range d.TaskDirs {
+		if p == dir.SecretsDir {
+			return self
+		}
+		if filepath.HasPrefix(p, dir.SecretsDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretsFS returns a view of the alloc dir that may also list and read the
+// files of the secret directories of the tasks. It must only be handed to
+// callers explicitly allowed to read secrets.
+func (d *AllocDir) SecretsFS() AllocDirFS {
+	return &secretsFS{d}
+}
+
+// secretsFS is an AllocDirFS that may read the secret directories of the
+// tasks.
+type secretsFS struct {
+	*AllocDir
+}
+
+func (s *secretsFS) List(path string) ([]*AllocFileInfo, error) {
+	return s.list(path, true)
+}
+
+func (s *secretsFS) Stat(path string) (*AllocFileInfo, error) {
+	return s.stat(path, true)
+}
+
+func (s *secretsFS) ReadAt(path string, offset int64) (io.ReadCloser, error) {
+	return s.readAt(path, offset, true)
+}
+
 // BlockUntilExists blocks until the passed file relative the allocation
 // directory exists. The block can be cancelled with the passed tomb.
 func (d *AllocDir) BlockUntilExists(path string, t *tomb.Tomb) (chan error, error) {
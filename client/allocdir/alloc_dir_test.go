@@ -355,6 +355,106 @@ func TestAllocDir_ReadAt_SecretDir(t *testing.T) {
 	}
 }
 
+// Test that secrets can't be listed or stat'd, and can only be read through
+// the secrets view of the alloc dir
+func TestAllocDir_SecretsFS(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "AllocDir")
+	if err != nil {
+		t.Fatalf("Couldn't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	d := NewAllocDir(testLogger(), tmp)
+	if err := d.Build(); err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	defer d.Destroy()
+
+	td := d.NewTaskDir(t1.Name)
+	if err := td.Build(false, nil, cstructs.FSIsolationImage); err != nil {
+		t.Fatalf("TaskDir.Build() failed: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(td.SecretsDir, "test_file"), []byte("secret"), 0666); err != nil {
+		t.Fatalf("Couldn't write secret file: %v", err)
+	}
+
+	dir := filepath.Join(t1.Name, TaskSecrets)
+	secret := filepath.Join(dir, "test_file")
+	if _, err := d.Stat(dir); err != nil {
+		t.Fatalf("Stat of secret dir failed: %v", err)
+	}
+	if _, err := d.List(dir); err == nil || !strings.Contains(err.Error(), "secret directory prohibited") {
+		t.Fatalf("List of secret dir didn't error: %v", err)
+	}
+	if _, err := d.Stat(secret); err == nil || !strings.Contains(err.Error(), "secret file prohibited") {
+		t.Fatalf("Stat of secret file didn't error: %v", err)
+	}
+
+	fs := d.SecretsFS()
+	files, err := fs.List(dir)
+	if err != nil {
+		t.Fatalf("List of secret dir failed: %v", err)
+	}
+	// The secret dir may also hold the marker of its mount
+	found := false
+	for _, f := range files {
+		if f.Name == "test_file" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("List of secret dir missing secret file: %v", files)
+	}
+	if _, err := fs.Stat(secret); err != nil {
+		t.Fatalf("Stat of secret file failed: %v", err)
+	}
+	r, err := fs.ReadAt(secret, 0)
+	if err != nil {
+		t.Fatalf("ReadAt of secret file failed: %v", err)
+	}
+	defer r.Close()
+	if data, err := ioutil.ReadAll(r); err != nil || string(data) != "secret" {
+		t.Fatalf("ReadAt of secret file returned %q: %v", data, err)
+	}
+}
+
+// Test that the size of the alloc dir doesn't count the secrets
+func TestAllocDir_Size(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "AllocDir")
+	if err != nil {
+		t.Fatalf("Couldn't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	d := NewAllocDir(testLogger(), tmp)
+	if err := d.Build(); err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	defer d.Destroy()
+
+	td := d.NewTaskDir(t1.Name)
+	if err := td.Build(false, nil, cstructs.FSIsolationImage); err != nil {
+		t.Fatalf("TaskDir.Build() failed: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(td.SecretsDir, "test_file"), []byte("secret"), 0666); err != nil {
+		t.Fatalf("Couldn't write secret file: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(td.LocalDir, "test_file"), make([]byte, 1024), 0666); err != nil {
+		t.Fatalf("Couldn't write local file: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(d.SharedDir, "test_file"), make([]byte, 512), 0666); err != nil {
+		t.Fatalf("Couldn't write shared file: %v", err)
+	}
+
+	size, err := d.Size()
+	if err != nil {
+		t.Fatalf("Size() failed: %v", err)
+	}
+	if size != 1536 {
+		t.Fatalf("bad size: %d", size)
+	}
+}
+
 func TestAllocDir_SplitPath(t *testing.T) {
 	dir, err := ioutil.TempDir("", "tmpdirtest")
 	if err != nil {
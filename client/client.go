@@ -2,6 +2,7 @@ package client
 
 import (
 	"archive/tar"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -20,18 +21,22 @@ import (
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/go-multierror"
+	lru "github.com/hashicorp/golang-lru"
 	nomadapi "github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/driver"
 	"github.com/hashicorp/nomad/client/fingerprint"
 	"github.com/hashicorp/nomad/client/stats"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/client/vaultclient"
 	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/helper/tlsutil"
+	"github.com/hashicorp/nomad/helper/vaultutil"
 	"github.com/hashicorp/nomad/nomad"
 	"github.com/hashicorp/nomad/nomad/structs"
+	nconfig "github.com/hashicorp/nomad/nomad/structs/config"
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/mitchellh/hashstructure"
 	"github.com/shirou/gopsutil/host"
@@ -80,6 +85,11 @@ const (
 	// allocSyncRetryIntv is the interval on which we retry updating
 	// the status of the allocation
 	allocSyncRetryIntv = 5 * time.Second
+
+	// fsReadSecretsOption is the Client option that grants the capability to
+	// read the files of the secret directories of tasks through the alloc
+	// fs API. Reading secrets is prohibited if unset.
+	fsReadSecretsOption = "fs.read_secrets"
 )
 
 // ClientStatsReporter exposes all the APIs related to resource usage of a Nomad
@@ -107,6 +117,13 @@ type Client struct {
 	configCopy *config.Config
 	configLock sync.RWMutex
 
+	// staticNodeMeta is the node meta set in the configuration and
+	// dynamicNodeMeta the meta applied at runtime, in which a nil value
+	// unsets the key. The node's meta is the static meta overlaid with the
+	// dynamic meta. Both are protected by the configLock.
+	staticNodeMeta  map[string]string
+	dynamicNodeMeta map[string]*string
+
 	logger *log.Logger
 
 	connPool *nomad.ConnPool
@@ -114,11 +131,28 @@ type Client struct {
 	// servers is the (optionally prioritized) list of nomad servers
 	servers *serverlist
 
+	// availDrivers are the drivers detected when the client started
+	availDrivers []string
+
+	// allocDiskUsage caches the size of the allocation directories reported
+	// with the heartbeats, which is expensive to compute. It is guarded by
+	// the heartbeat lock.
+	allocDiskUsage map[string]*allocDiskSample
+
+	// aclCache caches the ACLs compiled from sets of policies and tokenCache
+	// the tokens resolved by the servers
+	aclCache   *lru.TwoQueueCache
+	tokenCache *lru.TwoQueueCache
+
 	// heartbeat related times for tracking how often to heartbeat
 	lastHeartbeat time.Time
 	heartbeatTTL  time.Duration
 	heartbeatLock sync.Mutex
 
+	// heartbeatStop stops the allocations once the client lost contact with
+	// the servers for too long
+	heartbeatStop *heartbeatStop
+
 	// triggerDiscoveryCh triggers Consul discovery; see triggerDiscovery
 	triggerDiscoveryCh chan struct{}
 
@@ -162,6 +196,9 @@ type Client struct {
 	// garbageCollector is used to garbage collect terminal allocations present
 	// in the node automatically
 	garbageCollector *AllocGarbageCollector
+
+	// dnsServer serves the Nomad services over DNS if it is enabled
+	dnsServer *dnsServer
 }
 
 // migrateAllocCtrl indicates whether migration is complete
@@ -229,6 +266,11 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulServic
 		serversDiscoveredCh: make(chan struct{}),
 	}
 
+	// Initialize the ACL caches
+	if err := c.setupACLCaches(); err != nil {
+		return nil, fmt.Errorf("failed to create the ACL caches: %v", err)
+	}
+
 	// Initialize the client
 	if err := c.init(); err != nil {
 		return nil, fmt.Errorf("failed to initialize client: %v", err)
@@ -248,6 +290,7 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulServic
 		ReservedDiskMB:      cfg.Node.Reserved.DiskMB,
 	}
 	c.garbageCollector = NewAllocGarbageCollector(logger, statsCollector, c, gcConfig)
+	c.heartbeatStop = newHeartbeatStop(c.getAllocRunners, logger, c.shutdownCh)
 	go c.garbageCollector.Run()
 
 	// Setup the node
@@ -255,6 +298,11 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulServic
 		return nil, fmt.Errorf("node setup failed: %v", err)
 	}
 
+	// Apply the node meta persisted at runtime
+	if err := c.restoreDynamicNodeMeta(); err != nil {
+		return nil, fmt.Errorf("failed to restore dynamic node meta: %v", err)
+	}
+
 	// Fingerprint the node
 	if err := c.fingerprint(); err != nil {
 		return nil, fmt.Errorf("fingerprinting failed: %v", err)
@@ -297,6 +345,14 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulServic
 		return nil, fmt.Errorf("failed to setup vault client: %v", err)
 	}
 
+	// Register the services using the Nomad provider with the servers and
+	// the others with Consul
+	nomadServices := newNomadServiceClient(c.Node().ID, c.Node().SecretID, c.Region(), c.Datacenter(), c.RPC, logger)
+	go nomadServices.Run(c.shutdownCh)
+	nomadVariables := newNomadVariableReader(c.Region(), c.Node().SecretID, c.RPC)
+	nomadIdentities := newNomadWorkloadIdentities(c.Node().ID, c.Node().SecretID, c.Region(), c.RPC)
+	c.consulService = newServiceProviders(c.consulService, nomadServices, nomadVariables, nomadIdentities)
+
 	// Restore the state
 	if err := c.restoreState(); err != nil {
 		logger.Printf("[ERR] client: failed to restore state: %v", err)
@@ -311,6 +367,14 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulServic
 		return nil, fmt.Errorf("failed to restore state")
 	}
 
+	// Serve the Nomad services over DNS
+	if cfg := c.config.DNSConfig; cfg != nil && cfg.Enabled {
+		c.dnsServer = newDNSServer(cfg, nomadServices.PassingServices, logger)
+		if err := c.dnsServer.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start DNS server: %v", err)
+		}
+	}
+
 	// Register and then start heartbeating to the servers.
 	go c.registerAndHeartbeat()
 
@@ -326,6 +390,9 @@ func NewClient(cfg *config.Config, consulCatalog consul.CatalogAPI, consulServic
 	// Start collecting stats
 	go c.emitStats()
 
+	// Stop the allocations if the servers can't be reached for too long
+	go c.heartbeatStop.watch()
+
 	c.logger.Printf("[INFO] client: Node ID %q", c.Node().ID)
 	return c, nil
 }
@@ -447,6 +514,11 @@ func (c *Client) Shutdown() error {
 	// Stop Garbage collector
 	c.garbageCollector.Stop()
 
+	// Stop serving DNS
+	if c.dnsServer != nil {
+		c.dnsServer.Shutdown()
+	}
+
 	// Destroy all the running allocations.
 	if c.config.DevMode {
 		for _, ar := range c.getAllocRunners() {
@@ -508,6 +580,53 @@ func (c *Client) Stats() map[string]map[string]string {
 	return stats
 }
 
+// ClientHealth is the health of the components of a client.
+type ClientHealth struct {
+	// KnownServers is the number of servers the client knows.
+	KnownServers int
+
+	// LastHeartbeat is the time of the last successful heartbeat and
+	// HeartbeatTTL the time the servers wait for the next one. Both are zero
+	// until the node is registered.
+	LastHeartbeat time.Time
+	HeartbeatTTL  time.Duration
+
+	// Drivers maps the drivers detected by the client, when it started or
+	// since, and the ones in the driver whitelist, to whether they are
+	// currently detected.
+	Drivers map[string]bool
+}
+
+// Health returns the health of the connection to the servers and of the
+// drivers of the client.
+func (c *Client) Health() *ClientHealth {
+	health := &ClientHealth{
+		KnownServers: len(c.servers.all()),
+		Drivers:      make(map[string]bool),
+	}
+
+	c.heartbeatLock.Lock()
+	health.LastHeartbeat = c.lastHeartbeat
+	health.HeartbeatTTL = c.heartbeatTTL
+	c.heartbeatLock.Unlock()
+
+	for name := range c.config.ReadStringListToMap("driver.whitelist") {
+		health.Drivers[name] = false
+	}
+	for _, name := range c.availDrivers {
+		health.Drivers[name] = false
+	}
+	c.configLock.RLock()
+	for name := range driver.BuiltinDrivers {
+		if v, ok := c.config.Node.Attributes["driver."+name]; ok {
+			detected, _ := strconv.ParseBool(v)
+			health.Drivers[name] = detected
+		}
+	}
+	c.configLock.RUnlock()
+	return health
+}
+
 // CollectAllocation garbage collects a single allocation
 func (c *Client) CollectAllocation(allocID string) error {
 	return c.garbageCollector.Collect(allocID)
@@ -526,6 +645,12 @@ func (c *Client) Node() *structs.Node {
 	return c.config.Node
 }
 
+// secretNodeID returns the secret ID of the node, which authenticates the
+// client's requests to the servers
+func (c *Client) secretNodeID() string {
+	return c.Node().SecretID
+}
+
 // StatsReporter exposes the various APIs related resource usage of a Nomad
 // client
 func (c *Client) StatsReporter() ClientStatsReporter {
@@ -559,6 +684,163 @@ func (c *Client) GetAllocFS(allocID string) (allocdir.AllocDirFS, error) {
 	return ar.GetAllocDir(), nil
 }
 
+// GetAllocSecretsFS returns the AllocDirFS of an allocation that may also
+// read the files of the secret directories of its tasks. An error is returned
+// if the client isn't configured to allow it.
+func (c *Client) GetAllocSecretsFS(allocID string) (allocdir.AllocDirFS, error) {
+	if !c.config.ReadBoolDefault(fsReadSecretsOption, false) {
+		return nil, fmt.Errorf("reading secret files is disabled by client config %q", fsReadSecretsOption)
+	}
+
+	c.allocLock.RLock()
+	defer c.allocLock.RUnlock()
+
+	ar, ok := c.allocs[allocID]
+	if !ok {
+		return nil, fmt.Errorf("unknown allocation ID %q", allocID)
+	}
+	return ar.GetAllocDir().SecretsFS(), nil
+}
+
+// SignalAllocation sends the signal to the named task of an allocation, or
+// to all of its tasks if the task is empty.
+func (c *Client) SignalAllocation(allocID, task string, s os.Signal) error {
+	c.allocLock.RLock()
+	ar, ok := c.allocs[allocID]
+	c.allocLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown allocation ID %q", allocID)
+	}
+	return ar.Signal(task, s)
+}
+
+// RestartAllocation restarts the named task of an allocation in place, or all
+// of its tasks if the task is empty.
+func (c *Client) RestartAllocation(allocID, task string) error {
+	c.allocLock.RLock()
+	ar, ok := c.allocs[allocID]
+	c.allocLock.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown allocation ID %q", allocID)
+	}
+	return ar.Restart(task)
+}
+
+// ExecAllocation runs the command inside the named running task of an
+// allocation and returns its output and exit code.
+func (c *Client) ExecAllocation(ctx context.Context, allocID, task, cmd string, args []string) ([]byte, int, error) {
+	c.allocLock.RLock()
+	ar, ok := c.allocs[allocID]
+	c.allocLock.RUnlock()
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown allocation ID %q", allocID)
+	}
+	return ar.Exec(ctx, task, cmd, args)
+}
+
+// AllocTaskFSIsolation returns the filesystem isolation of the named task of
+// an allocation. Tasks without isolation run directly on the node.
+func (c *Client) AllocTaskFSIsolation(allocID, task string) (cstructs.FSIsolation, error) {
+	c.allocLock.RLock()
+	ar, ok := c.allocs[allocID]
+	c.allocLock.RUnlock()
+	if !ok {
+		return cstructs.FSIsolationNone, fmt.Errorf("unknown allocation ID %q", allocID)
+	}
+	return ar.TaskFSIsolation(task)
+}
+
+// NodeMeta returns the meta of the node along with the static and dynamic
+// meta it is built from.
+func (c *Client) NodeMeta() *cstructs.NodeMetaResponse {
+	c.configLock.RLock()
+	defer c.configLock.RUnlock()
+	return c.nodeMetaLocked()
+}
+
+// ApplyNodeMeta applies changes to the dynamic node meta and persists them. A
+// nil value unsets the key, even if it is set in the configuration. The
+// updated meta is registered with the servers when the node changes are next
+// detected.
+func (c *Client) ApplyNodeMeta(meta map[string]*string) (*cstructs.NodeMetaResponse, error) {
+	for k := range meta {
+		if k == "" {
+			return nil, fmt.Errorf("node meta keys must not be empty")
+		}
+	}
+
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	dynamic := make(map[string]*string, len(c.dynamicNodeMeta)+len(meta))
+	for k, v := range c.dynamicNodeMeta {
+		dynamic[k] = v
+	}
+	for k, v := range meta {
+		dynamic[k] = v
+	}
+
+	err := c.stateDB.Update(func(tx *bolt.Tx) error {
+		return putDynamicNodeMeta(tx, dynamic)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to persist dynamic node meta: %v", err)
+	}
+
+	c.dynamicNodeMeta = dynamic
+	c.config.Node.Meta = mergeNodeMeta(c.staticNodeMeta, dynamic)
+	return c.nodeMetaLocked(), nil
+}
+
+// nodeMetaLocked returns the node meta response. The configLock must be held.
+func (c *Client) nodeMetaLocked() *cstructs.NodeMetaResponse {
+	dynamic := make(map[string]*string, len(c.dynamicNodeMeta))
+	for k, v := range c.dynamicNodeMeta {
+		dynamic[k] = v
+	}
+
+	return &cstructs.NodeMetaResponse{
+		Meta:    helper.CopyMapStringString(c.config.Node.Meta),
+		Static:  helper.CopyMapStringString(c.staticNodeMeta),
+		Dynamic: dynamic,
+	}
+}
+
+// restoreDynamicNodeMeta applies the persisted dynamic node meta to the node.
+func (c *Client) restoreDynamicNodeMeta() error {
+	var dynamic map[string]*string
+	err := c.stateDB.View(func(tx *bolt.Tx) error {
+		var err error
+		dynamic, err = getDynamicNodeMeta(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+	c.dynamicNodeMeta = dynamic
+	c.config.Node.Meta = mergeNodeMeta(c.staticNodeMeta, dynamic)
+	return nil
+}
+
+// mergeNodeMeta returns the static node meta overlaid with the dynamic meta.
+func mergeNodeMeta(static map[string]string, dynamic map[string]*string) map[string]string {
+	meta := helper.CopyMapStringString(static)
+	if meta == nil {
+		meta = make(map[string]string, len(dynamic))
+	}
+	for k, v := range dynamic {
+		if v == nil {
+			delete(meta, k)
+		} else {
+			meta[k] = *v
+		}
+	}
+	return meta
+}
+
 // GetClientAlloc returns the allocation from the client
 func (c *Client) GetClientAlloc(allocID string) (*structs.Allocation, error) {
 	all := c.allAllocs()
@@ -658,7 +940,7 @@ func (c *Client) restoreState() error {
 		alloc := &structs.Allocation{ID: id}
 
 		c.configLock.RLock()
-		ar := NewAllocRunner(c.logger, c.configCopy, c.stateDB, c.updateAllocStatus, alloc, c.vaultClient, c.consulService)
+		ar := NewAllocRunner(c.logger, c.configCopy, c.stateDB, c.updateAllocStatus, alloc, c.vaultClient, c.consulService, c.deriveSIToken)
 		c.configLock.RUnlock()
 
 		c.allocLock.Lock()
@@ -718,6 +1000,63 @@ func (c *Client) saveState() error {
 	return mErr.ErrorOrNil()
 }
 
+// allocDiskUsageInterval is the interval at which the size of the allocation
+// directories reported to the servers is computed.
+const allocDiskUsageInterval = 5 * time.Minute
+
+// allocDiskSample is the size of an allocation directory at a point in time.
+type allocDiskSample struct {
+	mb      int
+	sampled time.Time
+}
+
+// allocUsage returns the resources used by the running allocations, reported
+// to the servers with the heartbeats for capacity reporting. It must be
+// called with the heartbeat lock held.
+func (c *Client) allocUsage() map[string]*structs.AllocResourceUsed {
+	if c.allocDiskUsage == nil {
+		c.allocDiskUsage = make(map[string]*allocDiskSample)
+	}
+
+	usage := make(map[string]*structs.AllocResourceUsed)
+	now := time.Now()
+	for id, ar := range c.getAllocRunners() {
+		if ar.Alloc().TerminalStatus() {
+			continue
+		}
+
+		used := &structs.AllocResourceUsed{}
+		if stats, err := ar.LatestAllocStats(""); err == nil && stats.ResourceUsage != nil {
+			if ms := stats.ResourceUsage.MemoryStats; ms != nil {
+				used.MemoryMB = int(ms.RSS / 1024 / 1024)
+			}
+			if cs := stats.ResourceUsage.CpuStats; cs != nil {
+				used.CPU = int(cs.TotalTicks)
+			}
+		}
+
+		sample, ok := c.allocDiskUsage[id]
+		if !ok || now.Sub(sample.sampled) > allocDiskUsageInterval {
+			size, err := ar.GetAllocDir().Size()
+			if err != nil {
+				c.logger.Printf("[DEBUG] client: failed to compute the size of the directory of alloc %q: %v", id, err)
+			}
+			sample = &allocDiskSample{mb: int(size / 1024 / 1024), sampled: now}
+			c.allocDiskUsage[id] = sample
+		}
+		used.DiskMB = sample.mb
+		usage[id] = used
+	}
+
+	// Forget the allocations that stopped
+	for id := range c.allocDiskUsage {
+		if _, ok := usage[id]; !ok {
+			delete(c.allocDiskUsage, id)
+		}
+	}
+	return usage
+}
+
 // getAllocRunners returns a snapshot of the current set of alloc runners.
 func (c *Client) getAllocRunners() map[string]*AllocRunner {
 	c.allocLock.RLock()
@@ -835,6 +1174,7 @@ func (c *Client) setupNode() error {
 	if node.Meta == nil {
 		node.Meta = make(map[string]string)
 	}
+	c.staticNodeMeta = helper.CopyMapStringString(node.Meta)
 	if node.Resources == nil {
 		node.Resources = &structs.Resources{}
 	}
@@ -974,7 +1314,7 @@ func (c *Client) setupDrivers() error {
 
 	var avail []string
 	var skipped []string
-	driverCtx := driver.NewDriverContext("", "", c.config, c.config.Node, c.logger, nil)
+	driverCtx := driver.NewDriverContext("", "", "", c.config, c.config.Node, c.logger, nil)
 	for name := range driver.BuiltinDrivers {
 		// Skip fingerprinting drivers that are not in the whitelist if it is
 		// enabled.
@@ -1009,6 +1349,7 @@ func (c *Client) setupDrivers() error {
 
 	}
 
+	c.availDrivers = avail
 	c.logger.Printf("[DEBUG] client: available drivers %v", avail)
 
 	if len(skipped) != 0 {
@@ -1035,6 +1376,13 @@ func (c *Client) registerAndHeartbeat() {
 	// Start watching changes for node changes
 	go c.watchNodeUpdates()
 
+	// Open the connection the servers make RPCs to the node over, unless
+	// the RPCs are handled in process by something other than a server
+	_, localServer := c.config.RPCHandler.(config.NodeConnHandler)
+	if c.config.RPCHandler == nil || localServer {
+		go c.nodeConnLoop()
+	}
+
 	// Setup the heartbeat timer, for the initial registration
 	// we want to do this quickly. We want to do it extra quickly
 	// in development mode.
@@ -1045,6 +1393,8 @@ func (c *Client) registerAndHeartbeat() {
 		heartbeat = time.After(lib.RandomStagger(initialHeartbeatStagger))
 	}
 
+	// disconnected tracks whether heartbeating failed since the last success
+	disconnected := false
 	for {
 		select {
 		case <-c.serversDiscoveredCh:
@@ -1054,6 +1404,8 @@ func (c *Client) registerAndHeartbeat() {
 		}
 
 		if err := c.updateNodeStatus(); err != nil {
+			disconnected = true
+
 			// The servers have changed such that this node has not been
 			// registered before
 			if strings.Contains(err.Error(), "node not found") {
@@ -1073,10 +1425,28 @@ func (c *Client) registerAndHeartbeat() {
 			c.heartbeatLock.Lock()
 			heartbeat = time.After(c.heartbeatTTL)
 			c.heartbeatLock.Unlock()
+
+			// The servers may have marked the allocations unknown while
+			// the client was disconnected
+			if disconnected {
+				disconnected = false
+				c.resyncAllocs()
+			}
 		}
 	}
 }
 
+// resyncAllocs sends the status of the allocations to the servers again.
+func (c *Client) resyncAllocs() {
+	for _, ar := range c.getAllocRunners() {
+		alloc := ar.Alloc()
+		if alloc.ClientStatus == "" || alloc.ClientStatus == structs.AllocClientStatusUnknown {
+			continue
+		}
+		c.updateAllocStatus(alloc)
+	}
+}
+
 // periodicSnapshot is a long lived goroutine used to periodically snapshot the
 // state of the client
 func (c *Client) periodicSnapshot() {
@@ -1187,6 +1557,7 @@ func (c *Client) registerNode() error {
 	defer c.heartbeatLock.Unlock()
 	c.lastHeartbeat = time.Now()
 	c.heartbeatTTL = resp.HeartbeatTTL
+	c.heartbeatStop.setLastOk(c.lastHeartbeat)
 	return nil
 }
 
@@ -1199,6 +1570,7 @@ func (c *Client) updateNodeStatus() error {
 	req := structs.NodeUpdateStatusRequest{
 		NodeID:       node.ID,
 		Status:       structs.NodeStatusReady,
+		AllocUsage:   c.allocUsage(),
 		WriteRequest: structs.WriteRequest{Region: c.Region()},
 	}
 	var resp structs.NodeUpdateResponse
@@ -1216,6 +1588,7 @@ func (c *Client) updateNodeStatus() error {
 	// Update heartbeat time and ttl
 	c.lastHeartbeat = time.Now()
 	c.heartbeatTTL = resp.HeartbeatTTL
+	c.heartbeatStop.setLastOk(c.lastHeartbeat)
 
 	// Convert []*NodeServerInfo to []*endpoints
 	localdc := c.Datacenter()
@@ -1296,6 +1669,7 @@ func (c *Client) updateAllocStatus(alloc *structs.Allocation) {
 	stripped.ClientStatus = alloc.ClientStatus
 	stripped.ClientDescription = alloc.ClientDescription
 	stripped.DeploymentStatus = alloc.DeploymentStatus
+	stripped.NetworkStatus = alloc.NetworkStatus
 
 	select {
 	case c.allocUpdates <- stripped:
@@ -1384,6 +1758,7 @@ func (c *Client) watchAllocations(updates chan *allocUpdates) {
 	allocsReq := structs.AllocsGetRequest{
 		QueryOptions: structs.QueryOptions{
 			Region:     c.Region(),
+			AuthToken:  c.secretNodeID(),
 			AllowStale: true,
 		},
 	}
@@ -1707,6 +2082,7 @@ func (c *Client) waitForAllocTerminal(allocID string, stopCh *migrateAllocCtrl)
 		AllocID: allocID,
 		QueryOptions: structs.QueryOptions{
 			Region:     c.Region(),
+			AuthToken:  c.secretNodeID(),
 			AllowStale: true,
 		},
 	}
@@ -1925,6 +2301,7 @@ func (c *Client) getNode(nodeID string) (*structs.Node, error) {
 		NodeID: nodeID,
 		QueryOptions: structs.QueryOptions{
 			Region:     c.Region(),
+			AuthToken:  c.secretNodeID(),
 			AllowStale: true,
 		},
 	}
@@ -1993,7 +2370,7 @@ func (c *Client) addAlloc(alloc *structs.Allocation, prevAllocDir *allocdir.Allo
 	}
 
 	c.configLock.RLock()
-	ar := NewAllocRunner(c.logger, c.configCopy, c.stateDB, c.updateAllocStatus, alloc, c.vaultClient, c.consulService)
+	ar := NewAllocRunner(c.logger, c.configCopy, c.stateDB, c.updateAllocStatus, alloc, c.vaultClient, c.consulService, c.deriveSIToken)
 	ar.SetPreviousAllocDir(prevAllocDir)
 	c.configLock.RUnlock()
 
@@ -2036,6 +2413,36 @@ func (c *Client) setupVaultClient() error {
 	return nil
 }
 
+// deriveSIToken derives the Consul Service Identity tokens of the given
+// Connect sidecar proxy tasks of an allocation and returns them indexed by
+// task name, or the tokens of the Consul services of the tasks indexed by
+// task and service name if services is set.
+func (c *Client) deriveSIToken(alloc *structs.Allocation, taskNames []string, services bool) (
+	map[string]string, map[string]map[string]string, error) {
+	req := &structs.DeriveSITokenRequest{
+		NodeID:   c.Node().ID,
+		SecretID: c.Node().SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    taskNames,
+		Services: services,
+		QueryOptions: structs.QueryOptions{
+			Region:     c.Region(),
+			AllowStale: false,
+		},
+	}
+
+	var resp structs.DeriveSITokenResponse
+	if err := c.RPC("Node.DeriveSIToken", &req, &resp); err != nil {
+		c.logger.Printf("[ERR] client.consul: DeriveSIToken RPC failed: %v", err)
+		return nil, nil, structs.NewRecoverableError(fmt.Errorf("DeriveSIToken RPC failed: %v", err), true)
+	}
+	if resp.Error != nil {
+		c.logger.Printf("[ERR] client.consul: failed to derive Service Identity tokens: %v", resp.Error)
+		return nil, nil, resp.Error
+	}
+	return resp.Tokens, resp.ServiceTokens, nil
+}
+
 // deriveToken takes in an allocation and a set of tasks and derives vault
 // tokens for each of the tasks, unwraps all of them using the supplied vault
 // client and returns a map of unwrapped tokens, indexed by the task name.
@@ -2054,22 +2461,37 @@ func (c *Client) deriveToken(alloc *structs.Allocation, taskNames []string, vcli
 	}
 
 	verifiedTasks := []string{}
-	found := false
-	// Check if the given task names actually exist in the allocation
+	roles := make(map[string]*structs.Vault)
+	// Check if the given task names actually exist in the allocation. Tasks
+	// with a Vault role log in to Vault with their workload identity instead
+	// of having a token derived by the servers.
 	for _, taskName := range taskNames {
-		found = false
-		for _, task := range group.Tasks {
-			if task.Name == taskName {
-				found = true
-			}
-		}
-		if !found {
+		task := group.LookupTask(taskName)
+		if task == nil {
 			c.logger.Printf("[ERR] task %q not found in the allocation", taskName)
 			return nil, fmt.Errorf("task %q not found in the allocaition", taskName)
 		}
+		if task.Vault != nil && task.Vault.Role != "" {
+			roles[taskName] = task.Vault
+			continue
+		}
 		verifiedTasks = append(verifiedTasks, taskName)
 	}
 
+	unwrappedTokens := make(map[string]string)
+	if len(roles) != 0 {
+		tokens, err := c.loginVaultIdentities(alloc, roles, vclient)
+		if err != nil {
+			return nil, err
+		}
+		for taskName, token := range tokens {
+			unwrappedTokens[taskName] = token
+		}
+	}
+	if len(verifiedTasks) == 0 {
+		return unwrappedTokens, nil
+	}
+
 	// DeriveVaultToken of nomad server can take in a set of tasks and
 	// creates tokens for all the tasks.
 	req := &structs.DeriveVaultTokenRequest{
@@ -2098,8 +2520,6 @@ func (c *Client) deriveToken(alloc *structs.Allocation, taskNames []string, vcli
 		return nil, fmt.Errorf("failed to derive vault tokens: invalid response")
 	}
 
-	unwrappedTokens := make(map[string]string)
-
 	// Retrieve the wrapped tokens from the response and unwrap it
 	for _, taskName := range verifiedTasks {
 		// Get the wrapped token
@@ -2109,8 +2529,12 @@ func (c *Client) deriveToken(alloc *structs.Allocation, taskNames []string, vcli
 			return nil, fmt.Errorf("wrapped token missing for task %q", taskName)
 		}
 
-		// Unwrap the vault token
-		unwrapResp, err := vclient.Logical().Unwrap(wrappedToken)
+		// Unwrap the vault token within the namespace it was created in
+		var namespace string
+		if task := group.LookupTask(taskName); task.Vault != nil {
+			namespace = task.Vault.Namespace
+		}
+		unwrapResp, err := vaultutil.Unwrap(vclient, namespace, wrappedToken)
 		if err != nil {
 			return nil, fmt.Errorf("failed to unwrap the token for task %q: %v", taskName, err)
 		}
@@ -2125,6 +2549,69 @@ func (c *Client) deriveToken(alloc *structs.Allocation, taskNames []string, vcli
 	return unwrappedTokens, nil
 }
 
+// loginVaultIdentities takes in an allocation and its tasks mapped to their
+// Vault block, requests the workload identities of the tasks from the nomad
+// server and exchanges them for Vault tokens with the Vault JWT auth backend.
+// The tokens are returned indexed by the task name.
+func (c *Client) loginVaultIdentities(alloc *structs.Allocation, roles map[string]*structs.Vault, vclient *vaultapi.Client) (map[string]string, error) {
+	taskNames := make([]string, 0, len(roles))
+	for taskName := range roles {
+		taskNames = append(taskNames, taskName)
+	}
+
+	req := &structs.SignVaultIdentitiesRequest{
+		NodeID:   c.Node().ID,
+		SecretID: c.Node().SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    taskNames,
+		QueryOptions: structs.QueryOptions{
+			Region:     c.Region(),
+			AllowStale: false,
+		},
+	}
+
+	var resp structs.SignVaultIdentitiesResponse
+	if err := c.RPC("Node.SignVaultIdentities", &req, &resp); err != nil {
+		c.logger.Printf("[ERR] client.vault: SignVaultIdentities RPC failed: %v", err)
+		return nil, structs.NewRecoverableError(fmt.Errorf("SignVaultIdentities RPC failed: %v", err), true)
+	}
+	if resp.Error != nil {
+		c.logger.Printf("[ERR] client.vault: failed to sign vault identities: %v", resp.Error)
+		return nil, resp.Error
+	}
+
+	path := c.config.VaultConfig.JWTAuthBackendPath
+	if path == "" {
+		path = nconfig.DefaultVaultJWTAuthBackendPath
+	}
+
+	tokens := make(map[string]string, len(roles))
+	for taskName, taskVault := range roles {
+		role := taskVault.Role
+		jwt, ok := resp.Identities[taskName]
+		if !ok {
+			c.logger.Printf("[ERR] client.vault: identity missing for task %q", taskName)
+			return nil, fmt.Errorf("identity missing for task %q", taskName)
+		}
+
+		// Log in with the identity within the namespace of the task
+		secret, err := vaultutil.Write(vclient, taskVault.Namespace, fmt.Sprintf("auth/%s/login", path), map[string]interface{}{
+			"role": role,
+			"jwt":  jwt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to log in to Vault with role %q for task %q: %v", role, taskName, err)
+		}
+		if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+			return nil, fmt.Errorf("failed to log in to Vault with role %q for task %q", role, taskName)
+		}
+
+		tokens[taskName] = secret.Auth.ClientToken
+	}
+
+	return tokens, nil
+}
+
 // triggerDiscovery causes a Consul discovery to begin (if one hasn't alread)
 func (c *Client) triggerDiscovery() {
 	select {
@@ -2305,6 +2792,40 @@ func (c *Client) emitHostStats(hStats *stats.HostStats) {
 		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "available"}, float32(disk.Available))
 		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "used_percent"}, float32(disk.UsedPercent))
 		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "inodes_percent"}, float32(disk.InodesUsedPercent))
+		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "inodes_used"}, float32(disk.InodesUsed))
+		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "inodes_free"}, float32(disk.InodesFree))
+		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "read_bytes"}, float32(disk.ReadBytes))
+		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "write_bytes"}, float32(disk.WriteBytes))
+		metrics.SetGauge([]string{"client", "host", "disk", nodeID, disk.Device, "io_utilization_percent"}, float32(disk.IOUtilizationPercent))
+	}
+
+	for _, n := range hStats.NetworkStats {
+		metrics.SetGauge([]string{"client", "host", "network", nodeID, n.Interface, "rx_bytes"}, float32(n.RxBytes))
+		metrics.SetGauge([]string{"client", "host", "network", nodeID, n.Interface, "tx_bytes"}, float32(n.TxBytes))
+		metrics.SetGauge([]string{"client", "host", "network", nodeID, n.Interface, "rx_packets"}, float32(n.RxPackets))
+		metrics.SetGauge([]string{"client", "host", "network", nodeID, n.Interface, "tx_packets"}, float32(n.TxPackets))
+		metrics.SetGauge([]string{"client", "host", "network", nodeID, n.Interface, "rx_errors"}, float32(n.RxErrors))
+		metrics.SetGauge([]string{"client", "host", "network", nodeID, n.Interface, "tx_errors"}, float32(n.TxErrors))
+		metrics.SetGauge([]string{"client", "host", "network", nodeID, n.Interface, "rx_dropped"}, float32(n.RxDropped))
+		metrics.SetGauge([]string{"client", "host", "network", nodeID, n.Interface, "tx_dropped"}, float32(n.TxDropped))
+	}
+
+	// Pressure stall information is only available on recent Linux kernels
+	if p := hStats.Pressure; p != nil {
+		for _, r := range []struct {
+			name string
+			stat *stats.PressureStat
+		}{{"cpu", p.CPU}, {"memory", p.Memory}, {"io", p.IO}} {
+			if r.stat == nil {
+				continue
+			}
+			metrics.SetGauge([]string{"client", "host", "pressure", nodeID, r.name, "some_avg10"}, float32(r.stat.SomeAvg10))
+			metrics.SetGauge([]string{"client", "host", "pressure", nodeID, r.name, "some_avg60"}, float32(r.stat.SomeAvg60))
+			metrics.SetGauge([]string{"client", "host", "pressure", nodeID, r.name, "some_avg300"}, float32(r.stat.SomeAvg300))
+			metrics.SetGauge([]string{"client", "host", "pressure", nodeID, r.name, "full_avg10"}, float32(r.stat.FullAvg10))
+			metrics.SetGauge([]string{"client", "host", "pressure", nodeID, r.name, "full_avg60"}, float32(r.stat.FullAvg60))
+			metrics.SetGauge([]string{"client", "host", "pressure", nodeID, r.name, "full_avg300"}, float32(r.stat.FullAvg300))
+		}
 	}
 
 	// Get all the resources for the node
@@ -2335,16 +2856,19 @@ func (c *Client) emitHostStats(hStats *stats.HostStats) {
 	metrics.SetGauge([]string{"client", "unallocated", "cpu", nodeID}, float32(unallocatedCpu))
 	metrics.SetGauge([]string{"client", "unallocated", "iops", nodeID}, float32(unallocatedIops))
 
+	// Emit the bandwidth left on every device, including the unused ones
+	usedMbits := make(map[string]int)
+	for _, n := range res.Networks {
+		usedMbits[n.Device] += n.MBits
+	}
 	for _, n := range allocated.Networks {
-		totalMbits := 0
-
-		totalIdx := total.NetIndex(n)
-		if totalIdx != -1 {
-			totalMbits = total.Networks[totalIdx].MBits
+		usedMbits[n.Device] += n.MBits
+	}
+	for _, n := range total.Networks {
+		if n.Device == "" {
 			continue
 		}
-
-		unallocatedMbits := totalMbits - n.MBits
+		unallocatedMbits := n.MBits - usedMbits[n.Device]
 		metrics.SetGauge([]string{"client", "unallocated", "network", n.Device, nodeID}, float32(unallocatedMbits))
 	}
 }
@@ -2400,12 +2924,20 @@ func (c *Client) getAllocatedResources(selfNode *structs.Node) *structs.Resource
 	for _, alloc := range allocs {
 		if !alloc.TerminalStatus() {
 			allocated.Add(alloc.Resources)
-			for _, allocatedNetwork := range alloc.Resources.Networks {
-				for cidr, dev := range cidrToDevice {
-					ip := net.ParseIP(allocatedNetwork.IP)
-					if cidr.Contains(ip) {
-						allocatedDeviceMbits[dev] += allocatedNetwork.MBits
-						break
+
+			// The bandwidth of the group network is shared by the tasks
+			networks := [][]*structs.NetworkResource{alloc.Resources.Networks}
+			if alloc.SharedResources != nil {
+				networks = append(networks, alloc.SharedResources.Networks)
+			}
+			for _, list := range networks {
+				for _, allocatedNetwork := range list {
+					for cidr, dev := range cidrToDevice {
+						ip := net.ParseIP(allocatedNetwork.IP)
+						if cidr.Contains(ip) {
+							allocatedDeviceMbits[dev] += allocatedNetwork.MBits
+							break
+						}
 					}
 				}
 			}
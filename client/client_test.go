@@ -11,6 +11,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -72,7 +73,7 @@ func testServer(t *testing.T, cb func(*nomad.Config)) (*nomad.Server, string) {
 		config.SerfConfig.MemberlistConfig.BindPort = getPort()
 
 		// Create server
-		server, err := nomad.NewServer(config, catalog, logger)
+		server, err := nomad.NewServer(config, catalog, consul.NewMockACLs(logger), consul.NewMockConfigEntries(logger), logger)
 		if err == nil {
 			return server, config.RPCAddr.String()
 		} else if i == 0 {
@@ -144,6 +145,69 @@ func TestClient_RPC(t *testing.T) {
 	})
 }
 
+// testNodeConnSignal checks that the server relays signals to the client
+// over its node connection.
+func testNodeConnSignal(t *testing.T, s1 *nomad.Server, c1 *Client) {
+	waitTilNodeReady(c1, t)
+
+	// Place a long running allocation on its node
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Driver = "mock_driver"
+	job.TaskGroups[0].Tasks[0].Config = map[string]interface{}{
+		"run_for": "10s",
+	}
+	alloc := mock.Alloc()
+	alloc.NodeID = c1.Node().ID
+	alloc.Job = job
+	alloc.JobID = job.ID
+	state := s1.State()
+	if err := state.UpsertJob(100, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(101, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The server relays the signal once the node is connected and runs the
+	// allocation
+	req := &structs.AllocSignalRequest{
+		AllocID:      alloc.ID,
+		Signal:       "SIGHUP",
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	testutil.WaitForResult(func() (bool, error) {
+		var out structs.GenericResponse
+		err := c1.RPC("Alloc.Signal", req, &out)
+		return err == nil, err
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestClient_NodeConn(t *testing.T) {
+	t.Parallel()
+	s1, addr := testServer(t, nil)
+	defer s1.Shutdown()
+
+	c1 := testClient(t, func(c *config.Config) {
+		c.Servers = []string{addr}
+	})
+	defer c1.Shutdown()
+	testNodeConnSignal(t, s1, c1)
+}
+
+func TestClient_NodeConn_InProcess(t *testing.T) {
+	t.Parallel()
+	s1, _ := testServer(t, nil)
+	defer s1.Shutdown()
+
+	c1 := testClient(t, func(c *config.Config) {
+		c.RPCHandler = s1
+	})
+	defer c1.Shutdown()
+	testNodeConnSignal(t, s1, c1)
+}
+
 func TestClient_RPC_Passthrough(t *testing.T) {
 	t.Parallel()
 	s1, _ := testServer(t, nil)
@@ -692,6 +756,55 @@ func waitTilNodeReady(client *Client, t *testing.T) {
 	})
 }
 
+func TestClient_ApplyNodeMeta(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cb := func(c *config.Config) {
+		c.StateDir = dir
+		c.Node.Meta = map[string]string{"rack": "r0", "zone": "z0"}
+	}
+	c1 := testClient(t, cb)
+
+	// Empty keys are rejected
+	if _, err := c1.ApplyNodeMeta(map[string]*string{"": helper.StringToPtr("v")}); err == nil {
+		t.Fatalf("expected error for empty key")
+	}
+
+	out, err := c1.ApplyNodeMeta(map[string]*string{
+		"rack": helper.StringToPtr("r1"),
+		"zone": nil,
+		"new":  helper.StringToPtr("v"),
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[string]string{"rack": "r1", "new": "v"}
+	if !reflect.DeepEqual(out.Meta, expected) {
+		t.Fatalf("expected %#v; got %#v", expected, out.Meta)
+	}
+	if !reflect.DeepEqual(c1.Node().Meta, expected) {
+		t.Fatalf("expected node meta %#v; got %#v", expected, c1.Node().Meta)
+	}
+	if out.Static["zone"] != "z0" {
+		t.Fatalf("static meta should not change: %#v", out.Static)
+	}
+	if err := c1.Shutdown(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The dynamic meta is restored by a new client
+	c2 := testClient(t, cb)
+	defer c2.Shutdown()
+	if meta := c2.Node().Meta; !reflect.DeepEqual(meta, expected) {
+		t.Fatalf("expected restored meta %#v; got %#v", expected, meta)
+	}
+}
+
 func TestClient_SaveRestoreState(t *testing.T) {
 	t.Parallel()
 	ctestutil.ExecCompatible(t)
@@ -0,0 +1,106 @@
+package cni
+
+const (
+	// BridgeNetworkName is the name of the network of the allocations in
+	// bridge network mode.
+	BridgeNetworkName = "nomad"
+
+	// bridgeCNIVersion is the version of the specification the bridge
+	// network configuration follows.
+	bridgeCNIVersion = "0.4.0"
+)
+
+// PortMapping maps a port of the host to a port in the network namespace of
+// an allocation. It is passed to the portmap plugin as the "portMappings"
+// capability argument.
+type PortMapping struct {
+	HostPort      int    `json:"hostPort"`
+	ContainerPort int    `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"hostIP,omitempty"`
+}
+
+// Bandwidth limits the traffic of the network namespace of an allocation.
+// It is passed to the bandwidth plugin as the "bandwidth" capability
+// argument. The rates are in bits per second and the bursts in bits.
+type Bandwidth struct {
+	IngressRate  int64 `json:"ingressRate"`
+	IngressBurst int64 `json:"ingressBurst"`
+	EgressRate   int64 `json:"egressRate"`
+	EgressBurst  int64 `json:"egressBurst"`
+}
+
+// NewBandwidth returns the bandwidth limiting the traffic of both directions
+// to the given MBits. A tenth of a second of traffic may be sent in a burst.
+func NewBandwidth(mbits int) *Bandwidth {
+	rate := int64(mbits) * 1000 * 1000
+	return &Bandwidth{
+		IngressRate:  rate,
+		IngressBurst: rate / 10,
+		EgressRate:   rate,
+		EgressBurst:  rate / 10,
+	}
+}
+
+// BridgeConfigList returns the network configuration of the bridge network
+// mode. The network namespace of the allocation is joined to the bridge by a
+// veth pair and given an address of the subnet, and the ports passed in the
+// "portMappings" capability argument are forwarded to it with iptables. Its
+// traffic is shaped to the "bandwidth" capability argument, if any. The
+// namespace is also given an address of the IPv6 subnet if there is one.
+func BridgeConfigList(bridge, subnet, subnetIPv6 string) *NetworkConfigList {
+	ranges := []interface{}{
+		[]interface{}{
+			map[string]interface{}{"subnet": subnet},
+		},
+	}
+	routes := []interface{}{
+		map[string]interface{}{"dst": "0.0.0.0/0"},
+	}
+	if subnetIPv6 != "" {
+		ranges = append(ranges, []interface{}{
+			map[string]interface{}{"subnet": subnetIPv6},
+		})
+		routes = append(routes, map[string]interface{}{"dst": "::/0"})
+	}
+
+	return &NetworkConfigList{
+		Name:       BridgeNetworkName,
+		CNIVersion: bridgeCNIVersion,
+		Plugins: []map[string]interface{}{
+			{
+				"type": "loopback",
+			},
+			{
+				"type":         "bridge",
+				"bridge":       bridge,
+				"isGateway":    true,
+				"ipMasq":       true,
+				"forceAddress": true,
+				"ipam": map[string]interface{}{
+					"type":   "host-local",
+					"ranges": ranges,
+					"routes": routes,
+				},
+			},
+			{
+				"type":                   "firewall",
+				"backend":                "iptables",
+				"iptablesAdminChainName": "NOMAD-ADMIN",
+			},
+			{
+				"type": "portmap",
+				"capabilities": map[string]interface{}{
+					"portMappings": true,
+				},
+				"snat": true,
+			},
+			{
+				"type": "bandwidth",
+				"capabilities": map[string]interface{}{
+					"bandwidth": true,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,436 @@
+// Package cni sets up the networks of allocations by invoking the CNI plugins
+// of the network configurations of the client, following the CNI
+// specification: https://github.com/containernetworking/cni/blob/master/SPEC.md
+package cni
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultInterfaceName is the name of the interface the plugins create
+	// in the network namespace of an allocation.
+	DefaultInterfaceName = "eth0"
+
+	// defaultCNIVersion is the version of the specification of network
+	// configurations that don't set one.
+	defaultCNIVersion = "0.2.0"
+)
+
+// configExtensions are the extensions of the network configuration files,
+// in order of precedence.
+var configExtensions = []string{".conflist", ".conf", ".json"}
+
+// NetworkConfigList is a CNI network configuration list. Single plugin
+// network configurations are loaded as a list of one plugin.
+type NetworkConfigList struct {
+	// Name is the name of the network.
+	Name string
+
+	// CNIVersion is the version of the specification the configuration
+	// follows.
+	CNIVersion string
+
+	// Plugins are the configurations of the plugins of the network, in the
+	// order they are added.
+	Plugins []map[string]interface{}
+}
+
+// LoadConfigLists returns the network configurations of the directory by
+// name. Files that aren't valid network configurations are skipped, and the
+// first file in lexical order wins if several configure the same network.
+func LoadConfigLists(dir string) (map[string]*NetworkConfigList, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		if f.IsDir() || !isConfigFile(f.Name()) {
+			continue
+		}
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+
+	lists := make(map[string]*NetworkConfigList)
+	for _, name := range names {
+		list, err := readConfigList(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if _, ok := lists[list.Name]; !ok {
+			lists[list.Name] = list
+		}
+	}
+	return lists, nil
+}
+
+// LoadConfigList returns the named network configuration of the directory.
+func LoadConfigList(dir, name string) (*NetworkConfigList, error) {
+	lists, err := LoadConfigLists(dir)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := lists[name]
+	if !ok {
+		return nil, fmt.Errorf("no CNI network configuration %q in %q", name, dir)
+	}
+	return list, nil
+}
+
+// isConfigFile returns whether the file has the extension of a network
+// configuration.
+func isConfigFile(name string) bool {
+	ext := filepath.Ext(name)
+	for _, e := range configExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// readConfigList reads a network configuration file.
+func readConfigList(path string) (*NetworkConfigList, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigList(buf, filepath.Ext(path) == ".conflist")
+}
+
+// parseConfigList parses a network configuration list, or a single plugin
+// network configuration if list is false.
+func parseConfigList(buf []byte, list bool) (*NetworkConfigList, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return nil, fmt.Errorf("invalid network configuration: %v", err)
+	}
+
+	c := &NetworkConfigList{}
+	c.Name, _ = raw["name"].(string)
+	c.CNIVersion, _ = raw["cniVersion"].(string)
+	if c.Name == "" {
+		return nil, fmt.Errorf("network configuration is missing a name")
+	}
+	if c.CNIVersion == "" {
+		c.CNIVersion = defaultCNIVersion
+	}
+
+	if !list {
+		c.Plugins = []map[string]interface{}{raw}
+	} else {
+		plugins, _ := raw["plugins"].([]interface{})
+		for _, p := range plugins {
+			plugin, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("network configuration %q has an invalid plugin", c.Name)
+			}
+			c.Plugins = append(c.Plugins, plugin)
+		}
+	}
+	if len(c.Plugins) == 0 {
+		return nil, fmt.Errorf("network configuration %q has no plugins", c.Name)
+	}
+	for _, plugin := range c.Plugins {
+		if t, _ := plugin["type"].(string); t == "" {
+			return nil, fmt.Errorf("network configuration %q has a plugin without a type", c.Name)
+		}
+	}
+	return c, nil
+}
+
+// Runtime is the container a network is added to or deleted from.
+type Runtime struct {
+	// ContainerID identifies the container, unique on the client.
+	ContainerID string
+
+	// NetNS is the path of the network namespace of the container.
+	NetNS string
+
+	// IfName is the name of the interface to create in the network
+	// namespace, DefaultInterfaceName if empty.
+	IfName string
+
+	// Path are the directories the plugins are searched in.
+	Path []string
+
+	// CapabilityArgs are passed as runtime configuration to the plugins that
+	// declare the capability, such as "portMappings".
+	CapabilityArgs map[string]interface{}
+}
+
+// ifName returns the name of the interface of the container.
+func (rt *Runtime) ifName() string {
+	if rt.IfName == "" {
+		return DefaultInterfaceName
+	}
+	return rt.IfName
+}
+
+// Result is the result of adding a network to a container.
+type Result struct {
+	// Interfaces are the interfaces created by the plugins.
+	Interfaces []*Interface `json:"interfaces,omitempty"`
+
+	// IPs are the addresses assigned by the plugins.
+	IPs []*IPConfig `json:"ips,omitempty"`
+}
+
+// Interface is an interface created by a plugin.
+type Interface struct {
+	Name    string `json:"name"`
+	Mac     string `json:"mac,omitempty"`
+	Sandbox string `json:"sandbox,omitempty"`
+}
+
+// IPConfig is an address assigned by a plugin.
+type IPConfig struct {
+	// Interface is the index of the interface of the address in the
+	// interfaces of the result, if any.
+	Interface *int `json:"interface,omitempty"`
+
+	// Address is the address in CIDR notation.
+	Address string `json:"address"`
+
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// ContainerIP returns the first address of the interfaces of the container
+// and the name of its interface.
+func (r *Result) ContainerIP(ifName string) (string, string) {
+	return r.containerIP(ifName, false)
+}
+
+// ContainerIPv6 returns the first IPv6 address of the interfaces of the
+// container, if any.
+func (r *Result) ContainerIPv6(ifName string) string {
+	ip, _ := r.containerIP(ifName, true)
+	return ip
+}
+
+// containerIP returns the first address of the interfaces of the container,
+// or the first IPv6 one, and the name of its interface.
+func (r *Result) containerIP(ifName string, ipv6 bool) (string, string) {
+	for _, ip := range r.IPs {
+		addr, _, err := net.ParseCIDR(ip.Address)
+		if err != nil || (ipv6 && addr.To4() != nil) {
+			continue
+		}
+
+		// Addresses of interfaces outside of the container, such as
+		// bridges, are skipped
+		name := ifName
+		if ip.Interface != nil && *ip.Interface >= 0 && *ip.Interface < len(r.Interfaces) {
+			iface := r.Interfaces[*ip.Interface]
+			if iface.Sandbox == "" {
+				continue
+			}
+			name = iface.Name
+		}
+		return addr.String(), name
+	}
+	return "", ""
+}
+
+// legacyResult is the result of plugins implementing the 0.1.0 and 0.2.0
+// versions of the specification.
+type legacyResult struct {
+	IP4 *struct {
+		IP string `json:"ip"`
+	} `json:"ip4,omitempty"`
+	IP6 *struct {
+		IP string `json:"ip"`
+	} `json:"ip6,omitempty"`
+}
+
+// parseResult parses the result of a plugin.
+func parseResult(buf []byte) (*Result, error) {
+	var r Result
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return nil, fmt.Errorf("invalid result: %v", err)
+	}
+	if len(r.IPs) != 0 || len(r.Interfaces) != 0 {
+		return &r, nil
+	}
+
+	var legacy legacyResult
+	if err := json.Unmarshal(buf, &legacy); err != nil {
+		return nil, fmt.Errorf("invalid result: %v", err)
+	}
+	if legacy.IP4 != nil {
+		r.IPs = append(r.IPs, &IPConfig{Address: legacy.IP4.IP})
+	}
+	if legacy.IP6 != nil {
+		r.IPs = append(r.IPs, &IPConfig{Address: legacy.IP6.IP})
+	}
+	return &r, nil
+}
+
+// pluginError is the error a plugin reports when it fails.
+type pluginError struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	Details string `json:"details,omitempty"`
+}
+
+func (e *pluginError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("%s; %s", e.Msg, e.Details)
+	}
+	return e.Msg
+}
+
+// AddNetworkList adds the network to the container, invoking the ADD
+// command of its plugins in order, and returns the result of the last one.
+func AddNetworkList(list *NetworkConfigList, rt *Runtime) (*Result, error) {
+	var prevResult json.RawMessage
+	for _, plugin := range list.Plugins {
+		out, err := execPlugin("ADD", list, plugin, prevResult, rt)
+		if err != nil {
+			return nil, err
+		}
+		prevResult = out
+	}
+	return parseResult(prevResult)
+}
+
+// DelNetworkList deletes the network from the container, invoking the DEL
+// command of its plugins in reverse order. All the plugins are invoked even
+// if some of them fail.
+func DelNetworkList(list *NetworkConfigList, rt *Runtime) error {
+	var errs []string
+	for i := len(list.Plugins) - 1; i >= 0; i-- {
+		if _, err := execPlugin("DEL", list, list.Plugins[i], nil, rt); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to delete network %q: %s", list.Name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// execPlugin invokes the command of the plugin, passing it its
+// configuration and the result of the previous plugin, and returns its
+// output.
+func execPlugin(command string, list *NetworkConfigList, plugin map[string]interface{},
+	prevResult json.RawMessage, rt *Runtime) ([]byte, error) {
+
+	pluginType, _ := plugin["type"].(string)
+	path, err := findPlugin(pluginType, rt.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := make(map[string]interface{}, len(plugin)+3)
+	for k, v := range plugin {
+		conf[k] = v
+	}
+	conf["name"] = list.Name
+	conf["cniVersion"] = list.CNIVersion
+	if prevResult != nil {
+		conf["prevResult"] = prevResult
+	}
+	if rc := runtimeConfig(plugin, rt.CapabilityArgs); len(rc) != 0 {
+		conf["runtimeConfig"] = rc
+	}
+	stdin, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"CNI_COMMAND="+command,
+		"CNI_CONTAINERID="+rt.ContainerID,
+		"CNI_NETNS="+rt.NetNS,
+		"CNI_IFNAME="+rt.ifName(),
+		"CNI_PATH="+strings.Join(rt.Path, string(os.PathListSeparator)),
+	)
+	if err := cmd.Run(); err != nil {
+		var perr pluginError
+		if jerr := json.Unmarshal(stdout.Bytes(), &perr); jerr == nil && perr.Msg != "" {
+			err = &perr
+		} else if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, fmt.Errorf("CNI plugin %q failed to %s network %q: %v", pluginType, command, list.Name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// runtimeConfig returns the capability arguments of the capabilities the
+// plugin declares.
+func runtimeConfig(plugin map[string]interface{}, args map[string]interface{}) map[string]interface{} {
+	caps, _ := plugin["capabilities"].(map[string]interface{})
+	rc := make(map[string]interface{})
+	for name, enabled := range caps {
+		if enabled != true {
+			continue
+		}
+		if arg, ok := args[name]; ok {
+			rc[name] = arg
+		}
+	}
+	return rc
+}
+
+// MissingPlugins returns the plugins of the network, including their IPAM
+// plugins, that aren't found in the plugin directories.
+func (l *NetworkConfigList) MissingPlugins(paths []string) []string {
+	var missing []string
+	check := func(conf map[string]interface{}) {
+		pluginType, _ := conf["type"].(string)
+		if _, err := findPlugin(pluginType, paths); err != nil {
+			missing = append(missing, pluginType)
+		}
+	}
+	for _, plugin := range l.Plugins {
+		check(plugin)
+		if ipam, ok := plugin["ipam"].(map[string]interface{}); ok {
+			check(ipam)
+		}
+	}
+	return missing
+}
+
+// findPlugin returns the path of the plugin executable.
+func findPlugin(pluginType string, paths []string) (string, error) {
+	if pluginType == "" || strings.ContainsRune(pluginType, os.PathSeparator) {
+		return "", fmt.Errorf("invalid CNI plugin %q", pluginType)
+	}
+	for _, dir := range paths {
+		path := filepath.Join(dir, pluginType)
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() && fi.Mode()&0111 != 0 {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("CNI plugin %q not found in %v", pluginType, paths)
+}
+
+// SplitPath splits a list of plugin directories separated by colons.
+func SplitPath(path string) []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(path) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,258 @@
+package cni
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	if err := ioutil.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestLoadConfigLists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "10-mynet.conflist"),
+		`{"cniVersion": "0.4.0", "name": "mynet", "plugins": [{"type": "bridge"}, {"type": "portmap"}]}`, 0644)
+	writeFile(t, filepath.Join(dir, "20-mynet.conf"),
+		`{"cniVersion": "0.4.0", "name": "mynet", "type": "macvlan"}`, 0644)
+	writeFile(t, filepath.Join(dir, "30-single.conf"),
+		`{"name": "single", "type": "macvlan"}`, 0644)
+	writeFile(t, filepath.Join(dir, "40-invalid.conflist"), `{"name": "invalid", "plugins": []}`, 0644)
+	writeFile(t, filepath.Join(dir, "README"), `{"name": "readme", "type": "bridge"}`, 0644)
+
+	lists, err := LoadConfigLists(dir)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(lists) != 2 {
+		t.Fatalf("bad: %#v", lists)
+	}
+
+	mynet := lists["mynet"]
+	if mynet == nil || mynet.CNIVersion != "0.4.0" || len(mynet.Plugins) != 2 || mynet.Plugins[1]["type"] != "portmap" {
+		t.Fatalf("bad: %#v", mynet)
+	}
+	single := lists["single"]
+	if single == nil || single.CNIVersion != defaultCNIVersion || len(single.Plugins) != 1 || single.Plugins[0]["type"] != "macvlan" {
+		t.Fatalf("bad: %#v", single)
+	}
+
+	if _, err := LoadConfigList(dir, "invalid"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestResult_ContainerIP(t *testing.T) {
+	zero, one := 0, 1
+	cases := []struct {
+		Result *Result
+		IP     string
+		IPv6   string
+		IfName string
+	}{
+		{
+			Result: &Result{},
+		},
+		{
+			Result: &Result{
+				IPs: []*IPConfig{{Address: "10.1.0.5/16"}},
+			},
+			IP:     "10.1.0.5",
+			IfName: "eth0",
+		},
+		{
+			Result: &Result{
+				Interfaces: []*Interface{
+					{Name: "cni0"},
+					{Name: "eth1", Sandbox: "/var/run/netns/foo"},
+				},
+				IPs: []*IPConfig{
+					{Interface: &zero, Address: "10.1.0.1/16"},
+					{Interface: &one, Address: "10.1.0.6/16"},
+				},
+			},
+			IP:     "10.1.0.6",
+			IfName: "eth1",
+		},
+		{
+			Result: &Result{
+				IPs: []*IPConfig{
+					{Address: "10.1.0.5/16"},
+					{Address: "fd00:10::5/64"},
+				},
+			},
+			IP:     "10.1.0.5",
+			IPv6:   "fd00:10::5",
+			IfName: "eth0",
+		},
+	}
+
+	for i, c := range cases {
+		ip, ifName := c.Result.ContainerIP("eth0")
+		if ip != c.IP || ifName != c.IfName {
+			t.Fatalf("case %d: got %q %q; want %q %q", i, ip, ifName, c.IP, c.IfName)
+		}
+		if ipv6 := c.Result.ContainerIPv6("eth0"); ipv6 != c.IPv6 {
+			t.Fatalf("case %d: got IPv6 %q; want %q", i, ipv6, c.IPv6)
+		}
+	}
+}
+
+func TestParseResult_Legacy(t *testing.T) {
+	r, err := parseResult([]byte(`{"ip4": {"ip": "10.1.0.5/16", "gateway": "10.1.0.1"}}`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ip, _ := r.ContainerIP("eth0"); ip != "10.1.0.5" {
+		t.Fatalf("bad: %#v", r)
+	}
+}
+
+// testPlugin is a plugin that logs its invocations and, on ADD, adds an
+// address to the result of the previous plugin.
+const testPlugin = `#!/bin/sh
+conf=$(cat)
+echo "$CNI_COMMAND $CNI_CONTAINERID $CNI_NETNS $CNI_IFNAME $(basename $0)" >> "$(dirname $0)/calls"
+case "$conf" in
+*'"fail":true'*)
+	echo '{"code": 100, "msg": "plugin failed"}'
+	exit 1
+	;;
+esac
+if [ "$CNI_COMMAND" = "ADD" ]; then
+	case "$conf" in
+	*prevResult*)
+		echo '{"cniVersion": "0.4.0", "interfaces": [{"name": "eth0", "sandbox": "'$CNI_NETNS'"}], "ips": [{"interface": 0, "address": "10.1.0.5/16"}]}'
+		;;
+	*)
+		echo '{"cniVersion": "0.4.0", "interfaces": [{"name": "eth0", "sandbox": "'$CNI_NETNS'"}], "ips": []}'
+		;;
+	esac
+fi
+`
+
+func TestAddDelNetworkList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "first"), testPlugin, 0755)
+	writeFile(t, filepath.Join(dir, "second"), testPlugin, 0755)
+
+	list := &NetworkConfigList{
+		Name:       "mynet",
+		CNIVersion: "0.4.0",
+		Plugins: []map[string]interface{}{
+			{"type": "first"},
+			{"type": "second"},
+		},
+	}
+	rt := &Runtime{
+		ContainerID: "alloc",
+		NetNS:       "/var/run/netns/alloc",
+		Path:        []string{filepath.Join(dir, "missing"), dir},
+	}
+
+	result, err := AddNetworkList(list, rt)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ip, ifName := result.ContainerIP(rt.IfName); ip != "10.1.0.5" || ifName != "eth0" {
+		t.Fatalf("bad: %q %q", ip, ifName)
+	}
+
+	if err := DelNetworkList(list, rt); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	calls, err := ioutil.ReadFile(filepath.Join(dir, "calls"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := []string{
+		"ADD alloc /var/run/netns/alloc eth0 first",
+		"ADD alloc /var/run/netns/alloc eth0 second",
+		"DEL alloc /var/run/netns/alloc eth0 second",
+		"DEL alloc /var/run/netns/alloc eth0 first",
+	}
+	if act := strings.Split(strings.TrimSpace(string(calls)), "\n"); !reflect.DeepEqual(act, expected) {
+		t.Fatalf("got %q; want %q", act, expected)
+	}
+
+	// The errors of plugins are reported
+	list.Plugins[1]["fail"] = true
+	if _, err := AddNetworkList(list, rt); err == nil || !strings.Contains(err.Error(), "plugin failed") {
+		t.Fatalf("expected plugin error, got %v", err)
+	}
+
+	// Missing plugins are reported
+	list.Plugins[1]["type"] = "missing"
+	if _, err := AddNetworkList(list, rt); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing plugin error, got %v", err)
+	}
+}
+
+func TestRuntimeConfig(t *testing.T) {
+	mappings := []PortMapping{{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"}}
+	bandwidth := NewBandwidth(10)
+	args := map[string]interface{}{"portMappings": mappings, "bandwidth": bandwidth}
+
+	plugin := BridgeConfigList("nomad", "172.26.64.0/20", "").Plugins[3]
+	expected := map[string]interface{}{"portMappings": mappings}
+	if rc := runtimeConfig(plugin, args); !reflect.DeepEqual(rc, expected) {
+		t.Fatalf("got %#v; want %#v", rc, expected)
+	}
+
+	plugin = BridgeConfigList("nomad", "172.26.64.0/20", "").Plugins[4]
+	expected = map[string]interface{}{"bandwidth": bandwidth}
+	if rc := runtimeConfig(plugin, args); !reflect.DeepEqual(rc, expected) {
+		t.Fatalf("got %#v; want %#v", rc, expected)
+	}
+	if bandwidth.IngressRate != 10000000 || bandwidth.EgressBurst != 1000000 {
+		t.Fatalf("bad bandwidth %#v", bandwidth)
+	}
+
+	// Plugins without the capability aren't passed the arguments
+	plugin = map[string]interface{}{"type": "bridge"}
+	if rc := runtimeConfig(plugin, args); len(rc) != 0 {
+		t.Fatalf("unexpected runtime config %#v", rc)
+	}
+}
+
+func TestNetworkConfigList_MissingPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"loopback", "bridge", "portmap", "bandwidth"} {
+		writeFile(t, filepath.Join(dir, name), testPlugin, 0755)
+	}
+
+	list := BridgeConfigList("nomad", "172.26.64.0/20", "")
+	expected := []string{"host-local", "firewall"}
+	if missing := list.MissingPlugins([]string{dir}); !reflect.DeepEqual(missing, expected) {
+		t.Fatalf("got %q; want %q", missing, expected)
+	}
+}
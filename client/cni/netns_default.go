@@ -0,0 +1,35 @@
+// +build !linux
+
+package cni
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// NetNSDir is the directory the network namespaces of allocations are bind
+// mounted in.
+const NetNSDir = "/var/run/netns"
+
+// errNetNSUnsupported is returned on platforms without network namespaces.
+var errNetNSUnsupported = errors.New("network namespaces are only supported on Linux")
+
+// NetNSPath returns the path of the network namespace of the allocation.
+func NetNSPath(allocID string) string {
+	return filepath.Join(NetNSDir, allocID)
+}
+
+// CreateNetNS is unsupported on this platform.
+func CreateNetNS(path string) error {
+	return errNetNSUnsupported
+}
+
+// DeleteNetNS is unsupported on this platform.
+func DeleteNetNS(path string) error {
+	return errNetNSUnsupported
+}
+
+// WithNetNS is unsupported on this platform.
+func WithNetNS(path string, fn func() error) error {
+	return errNetNSUnsupported
+}
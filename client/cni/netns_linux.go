@@ -0,0 +1,117 @@
+package cni
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+// NetNSDir is the directory the network namespaces of allocations are bind
+// mounted in, shared with the ip tool.
+const NetNSDir = "/var/run/netns"
+
+// NetNSPath returns the path of the network namespace of the allocation.
+func NetNSPath(allocID string) string {
+	return filepath.Join(NetNSDir, allocID)
+}
+
+// CreateNetNS creates a network namespace and bind mounts it at the path so
+// that it outlives the processes in it.
+func CreateNetNS(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE|os.O_EXCL, 0444)
+	if err != nil {
+		return fmt.Errorf("failed to create network namespace file %q: %v", path, err)
+	}
+	f.Close()
+
+	// The namespace is created by a locked thread that returns to the
+	// namespace of the client once it is mounted
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- inThreadNetNS(func() error {
+			if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+				return fmt.Errorf("failed to create network namespace: %v", err)
+			}
+			if err := unix.Mount(threadNetNSPath(), path, "none", unix.MS_BIND, ""); err != nil {
+				return fmt.Errorf("failed to mount network namespace at %q: %v", path, err)
+			}
+			return nil
+		})
+	}()
+
+	if err := <-errCh; err != nil {
+		DeleteNetNS(path)
+		return err
+	}
+	return nil
+}
+
+// DeleteNetNS unmounts and removes the network namespace at the path. It
+// doesn't fail if the namespace doesn't exist.
+func DeleteNetNS(path string) error {
+	if err := unix.Unmount(path, unix.MNT_DETACH); err != nil && err != unix.EINVAL && err != unix.ENOENT {
+		return fmt.Errorf("failed to unmount network namespace %q: %v", path, err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove network namespace %q: %v", path, err)
+	}
+	return nil
+}
+
+// WithNetNS calls fn in the network namespace at the path, so that the
+// processes it starts run in the namespace. fn runs on a locked thread of its
+// own, and can't start goroutines expecting to share the namespace.
+func WithNetNS(path string, fn func() error) error {
+	ns, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open network namespace %q: %v", path, err)
+	}
+	defer ns.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- inThreadNetNS(func() error {
+			if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+				return fmt.Errorf("failed to enter network namespace %q: %v", path, err)
+			}
+			return fn()
+		})
+	}()
+	return <-errCh
+}
+
+// inThreadNetNS locks the goroutine to its thread and calls fn, which may
+// move the thread to another network namespace, before returning the thread
+// to its original namespace. If that fails the thread is left locked, so
+// that it exits along with the goroutine instead of being reused by others.
+func inThreadNetNS(fn func() error) error {
+	runtime.LockOSThread()
+	origNS, err := os.Open(threadNetNSPath())
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer origNS.Close()
+
+	err = fn()
+	if rerr := unix.Setns(int(origNS.Fd()), unix.CLONE_NEWNET); rerr != nil {
+		if err == nil {
+			err = fmt.Errorf("failed to restore network namespace: %v", rerr)
+		}
+		return err
+	}
+	runtime.UnlockOSThread()
+	return err
+}
+
+// threadNetNSPath returns the path of the network namespace of the current
+// thread.
+func threadNetNSPath() string {
+	return fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid())
+}
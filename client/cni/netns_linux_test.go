@@ -0,0 +1,65 @@
+package cni
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestNetNS(t *testing.T) {
+	if unix.Geteuid() != 0 {
+		t.Skip("Must be run as root")
+	}
+
+	dir, err := ioutil.TempDir("", "nomadtest-netns")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ns")
+
+	if err := CreateNetNS(path); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer DeleteNetNS(path)
+
+	var nsStat, stat unix.Stat_t
+	if err := unix.Stat(path, &nsStat); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	inode := func() uint64 {
+		if err := unix.Stat(threadNetNSPath(), &stat); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return stat.Ino
+	}
+	orig := inode()
+	if orig == nsStat.Ino {
+		t.Fatalf("namespace wasn't created")
+	}
+
+	// fn runs in the namespace, and the caller stays in its own
+	var inNS uint64
+	if err := WithNetNS(path, func() error {
+		inNS = inode()
+		return nil
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if inNS != nsStat.Ino {
+		t.Fatalf("fn didn't run in the namespace")
+	}
+	if inode() != orig {
+		t.Fatalf("caller left its namespace")
+	}
+
+	if err := DeleteNetNS(path); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("namespace not removed: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -41,6 +42,15 @@ var (
 		"java",
 	}, ",")
 
+	// DefaultAllocationMetricLabels are the labels of the allocation metrics
+	// emitted unless others are configured.
+	DefaultAllocationMetricLabels = []string{
+		AllocationMetricLabelJob,
+		AllocationMetricLabelGroup,
+		AllocationMetricLabelAllocID,
+		AllocationMetricLabelTask,
+	}
+
 	// A mapping of directories on the host OS to attempt to embed inside each
 	// task's chroot.
 	DefaultChrootEnv = map[string]string{
@@ -55,6 +65,41 @@ var (
 	}
 )
 
+const (
+	// The labels allocation metrics can be emitted with. Labels are included
+	// in the keys of the metrics, in the configured order.
+	AllocationMetricLabelJob       = "job"
+	AllocationMetricLabelGroup     = "group"
+	AllocationMetricLabelTask      = "task"
+	AllocationMetricLabelAllocID   = "alloc_id"
+	AllocationMetricLabelNamespace = "namespace"
+
+	// AllocationMetricLabelMetaPrefix prefixes the labels set to the value of
+	// a key of the job meta, for example "meta.team".
+	AllocationMetricLabelMetaPrefix = "meta."
+)
+
+// ValidateAllocationMetricLabels returns an error if a label of allocation
+// metrics is unknown or repeated.
+func ValidateAllocationMetricLabels(labels []string) error {
+	seen := make(map[string]struct{}, len(labels))
+	for _, label := range labels {
+		switch label {
+		case AllocationMetricLabelJob, AllocationMetricLabelGroup, AllocationMetricLabelTask,
+			AllocationMetricLabelAllocID, AllocationMetricLabelNamespace:
+		default:
+			if !strings.HasPrefix(label, AllocationMetricLabelMetaPrefix) || label == AllocationMetricLabelMetaPrefix {
+				return fmt.Errorf("unknown allocation metric label %q", label)
+			}
+		}
+		if _, ok := seen[label]; ok {
+			return fmt.Errorf("allocation metric label %q repeated", label)
+		}
+		seen[label] = struct{}{}
+	}
+	return nil
+}
+
 // RPCHandler can be provided to the Client if there is a local server
 // to avoid going over the network. If not provided, the Client will
 // maintain a connection pool to the servers
@@ -62,6 +107,13 @@ type RPCHandler interface {
 	RPC(method string, args interface{}, reply interface{}) error
 }
 
+// NodeConnHandler is implemented by RPCHandlers that accept the node
+// connection of the Client over one end of a pipe, so that the local server
+// can make RPCs to the Client.
+type NodeConnHandler interface {
+	ServeNodeConn(conn net.Conn)
+}
+
 // Config is used to parameterize and configure the behavior of the client
 type Config struct {
 	// DevMode controls if we are in a development mode which
@@ -74,6 +126,26 @@ type Config struct {
 	// AllocDir is where we store data for allocations
 	AllocDir string
 
+	// CNIPath is the list of directories, separated by colons, the CNI
+	// plugins are searched in
+	CNIPath string
+
+	// CNIConfigDir is the directory of the CNI network configurations group
+	// networks can use
+	CNIConfigDir string
+
+	// BridgeNetworkName is the name of the bridge the allocations in bridge
+	// network mode are joined to
+	BridgeNetworkName string
+
+	// BridgeNetworkSubnet is the subnet the addresses of the allocations in
+	// bridge network mode are assigned from
+	BridgeNetworkSubnet string
+
+	// BridgeNetworkSubnetIPv6 is the subnet the IPv6 addresses of the
+	// allocations in bridge network mode are assigned from, if any
+	BridgeNetworkSubnetIPv6 string
+
 	// LogOutput is the destination for logs
 	LogOutput io.Writer
 
@@ -87,6 +159,14 @@ type Config struct {
 	// be determined dynamically.
 	NetworkSpeed int
 
+	// HostNetworks are the named networks of the host jobs can reserve ports
+	// in
+	HostNetworks []*HostNetworkConfig
+
+	// DNSConfig configures the DNS interface to the Nomad services. It is
+	// disabled if nil.
+	DNSConfig *DNSConfig
+
 	// CpuCompute is the default total CPU compute if they can not be determined
 	// dynamically. It should be given as Cores * MHz (2 Cores * 2 Ghz = 4000)
 	CpuCompute int
@@ -152,6 +232,11 @@ type Config struct {
 	// allocation metrics to remote Telemetry sinks
 	PublishAllocationMetrics bool
 
+	// AllocationMetricLabels are the labels of the allocations included in
+	// the keys of allocation metrics, in order. The labels trade the
+	// cardinality of the metrics for the ability to break them down.
+	AllocationMetricLabels []string
+
 	// TLSConfig holds various TLS related configurations
 	TLSConfig *config.TLSConfig
 
@@ -181,6 +266,12 @@ type Config struct {
 	// NoHostUUID disables using the host's UUID and will force generation of a
 	// random UUID.
 	NoHostUUID bool
+
+	// ACLEnabled enforces the ACLs on the endpoints of the client
+	ACLEnabled bool
+
+	// ACLTokenTTL is how long the client caches the tokens it resolved
+	ACLTokenTTL time.Duration
 }
 
 func (c *Config) Copy() *Config {
@@ -190,11 +281,121 @@ func (c *Config) Copy() *Config {
 	nc.Servers = helper.CopySliceString(nc.Servers)
 	nc.Options = helper.CopyMapStringString(nc.Options)
 	nc.GloballyReservedPorts = helper.CopySliceInt(c.GloballyReservedPorts)
+	nc.AllocationMetricLabels = helper.CopySliceString(c.AllocationMetricLabels)
 	nc.ConsulConfig = c.ConsulConfig.Copy()
 	nc.VaultConfig = c.VaultConfig.Copy()
+	if c.HostNetworks != nil {
+		nc.HostNetworks = make([]*HostNetworkConfig, len(c.HostNetworks))
+		for i, n := range c.HostNetworks {
+			nc.HostNetworks[i] = n.Copy()
+		}
+	}
+	nc.DNSConfig = c.DNSConfig.Copy()
+	return nc
+}
+
+// HostNetworkConfig is a named network of the host, made of the addresses of
+// an interface, or of every interface if it is empty, in a CIDR.
+type HostNetworkConfig struct {
+	// Name is the name jobs request the network by.
+	Name string `mapstructure:"-"`
+
+	// Interface is the interface the addresses of the network are on.
+	Interface string `mapstructure:"interface"`
+
+	// CIDR is the range of the addresses of the network, every address of
+	// the interface if it is empty.
+	CIDR string `mapstructure:"cidr"`
+}
+
+// Copy returns a copy of the host network configuration.
+func (c *HostNetworkConfig) Copy() *HostNetworkConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(HostNetworkConfig)
+	*nc = *c
 	return nc
 }
 
+const (
+	// DefaultDNSAddr is the default address the DNS interface listens on
+	DefaultDNSAddr = "127.0.0.1:8600"
+
+	// DefaultDNSDomain is the default domain the DNS interface answers for
+	DefaultDNSDomain = "nomad."
+)
+
+// DNSConfig configures the DNS interface of the client, which answers
+// queries of the form <service>.service.<domain> with the passing instances
+// of the services registered with Nomad and forwards the other queries to
+// the recursors.
+type DNSConfig struct {
+	// Enabled starts the DNS interface.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Addr is the address the DNS interface listens on over UDP and TCP.
+	Addr string `mapstructure:"addr"`
+
+	// Domain is the domain the DNS interface answers for.
+	Domain string `mapstructure:"domain"`
+
+	// Recursors are the addresses of the DNS servers the queries outside of
+	// the domain are forwarded to. Those queries are refused if it is empty.
+	Recursors []string `mapstructure:"recursors"`
+
+	// TTL is the time to live of the answers.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+// DefaultDNSConfig returns the default configuration of the DNS interface,
+// which is disabled.
+func DefaultDNSConfig() *DNSConfig {
+	return &DNSConfig{
+		Addr:   DefaultDNSAddr,
+		Domain: DefaultDNSDomain,
+	}
+}
+
+// Copy returns a copy of the DNS configuration.
+func (c *DNSConfig) Copy() *DNSConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(DNSConfig)
+	*nc = *c
+	nc.Recursors = helper.CopySliceString(c.Recursors)
+	return nc
+}
+
+// Merge returns a copy of the configuration overridden by the set fields of
+// b.
+func (c *DNSConfig) Merge(b *DNSConfig) *DNSConfig {
+	result := c.Copy()
+	if result == nil {
+		return b.Copy()
+	}
+	if b == nil {
+		return result
+	}
+	if b.Enabled {
+		result.Enabled = true
+	}
+	if b.Addr != "" {
+		result.Addr = b.Addr
+	}
+	if b.Domain != "" {
+		result.Domain = b.Domain
+	}
+	if len(b.Recursors) != 0 {
+		result.Recursors = helper.CopySliceString(b.Recursors)
+	}
+	if b.TTL != 0 {
+		result.TTL = b.TTL
+	}
+	return result
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -203,6 +404,7 @@ func DefaultConfig() *Config {
 		LogOutput:               os.Stderr,
 		Region:                  "global",
 		StatsCollectionInterval: 1 * time.Second,
+		AllocationMetricLabels:  helper.CopySliceString(DefaultAllocationMetricLabels),
 		TLSConfig:               &config.TLSConfig{},
 		LogLevel:                "DEBUG",
 		GCInterval:              1 * time.Minute,
@@ -211,6 +413,11 @@ func DefaultConfig() *Config {
 		GCInodeUsageThreshold:   70,
 		GCMaxAllocs:             50,
 		NoHostUUID:              true,
+		CNIPath:                 "/opt/cni/bin",
+		CNIConfigDir:            "/opt/cni/config",
+		BridgeNetworkName:       "nomad",
+		BridgeNetworkSubnet:     "172.26.64.0/20",
+		ACLTokenTTL:             30 * time.Second,
 	}
 }
 
@@ -34,3 +34,25 @@ func TestConfigReadDefault(t *testing.T) {
 		t.Errorf("Expected %s, found %s", expected, actual)
 	}
 }
+
+func TestValidateAllocationMetricLabels(t *testing.T) {
+	cases := []struct {
+		labels []string
+		valid  bool
+	}{
+		{nil, true},
+		{DefaultAllocationMetricLabels, true},
+		{[]string{"namespace", "job", "meta.team"}, true},
+		{[]string{"node"}, false},
+		{[]string{"meta."}, false},
+		{[]string{"job", "job"}, false},
+	}
+	for _, c := range cases {
+		err := ValidateAllocationMetricLabels(c.labels)
+		if c.valid && err != nil {
+			t.Errorf("%v: unexpected error: %v", c.labels, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("%v: expected error", c.labels)
+		}
+	}
+}
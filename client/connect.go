@@ -0,0 +1,239 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// siTokenFile is the name of the file holding the Consul Service
+	// Identity token inside the secrets directory of sidecar proxy tasks
+	siTokenFile = "si_token"
+
+	// envoyBootstrapFile is the name of the Envoy bootstrap configuration
+	// inside the secrets directory of sidecar proxy tasks
+	envoyBootstrapFile = "envoy_bootstrap.json"
+
+	// envoyAdminSocket is the path of the Envoy admin API socket. It is
+	// interpolated with the environment of the task.
+	envoyAdminSocket = "${NOMAD_SECRETS_DIR}/envoy_admin.sock"
+)
+
+// SITokenDeriverFunc derives Consul Service Identity tokens for the Connect
+// sidecar proxy and gateway tasks of an allocation and returns them indexed
+// by task name. If services is set, tokens are instead derived for the Consul
+// services of the tasks and returned indexed by task and service name.
+type SITokenDeriverFunc func(alloc *structs.Allocation, tasks []string, services bool) (
+	taskTokens map[string]string, serviceTokens map[string]map[string]string, err error)
+
+// connectSidecarSetup prepares a Connect sidecar proxy or gateway task to
+// start: it derives the Service Identity token of the proxy when Consul ACLs
+// are enabled and writes the Envoy bootstrap configuration to the secrets
+// directory.
+func (r *TaskRunner) connectSidecarSetup(alloc *structs.Allocation, task *structs.Task) error {
+	serviceName := task.Kind.Value()
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		return fmt.Errorf("task group %q not found in the allocation", alloc.TaskGroup)
+	}
+
+	// Gateways define their service while sidecar proxies front the
+	// service of another task
+	var proxyID string
+	if task.Kind.IsConnectGateway() {
+		service := findConnectService(task, serviceName)
+		if service == nil {
+			return fmt.Errorf("Connect gateway service %q not found in task %q", serviceName, task.Name)
+		}
+		proxyID = consul.MakeConnectGatewayID(alloc.ID, task.Name, service)
+	} else {
+		var parent *structs.Task
+		for _, t := range tg.Tasks {
+			if findConnectService(t, serviceName) != nil {
+				parent = t
+				break
+			}
+		}
+		if parent == nil {
+			return fmt.Errorf("Connect service %q not found in task group %q", serviceName, tg.Name)
+		}
+		proxyID = consul.MakeConnectProxyID(alloc.ID, parent.Name, serviceName)
+	}
+
+	// Only derive a token when Nomad itself uses an ACL token with Consul
+	var token string
+	if r.config.ConsulConfig.Token != "" {
+		if r.siTokenDeriver == nil {
+			return fmt.Errorf("no Service Identity token deriver")
+		}
+		tokens, _, err := r.siTokenDeriver(alloc, []string{task.Name}, false)
+		if err != nil {
+			return err
+		}
+		token = tokens[task.Name]
+		if token == "" {
+			return fmt.Errorf("Service Identity token missing for task %q", task.Name)
+		}
+
+		tokenPath := filepath.Join(r.taskDir.SecretsDir, siTokenFile)
+		if err := ioutil.WriteFile(tokenPath, []byte(token), 0600); err != nil {
+			return fmt.Errorf("failed to write Service Identity token: %v", err)
+		}
+	}
+
+	adminSocket := r.envBuilder.Build().ReplaceEnv(envoyAdminSocket)
+	bootstrap, err := envoyBootstrap(serviceName, proxyID, tg.Consul, adminSocket, r.config.ConsulConfig.GRPCAddr, token)
+	if err != nil {
+		return err
+	}
+
+	bootstrapPath := filepath.Join(r.taskDir.SecretsDir, envoyBootstrapFile)
+	if err := ioutil.WriteFile(bootstrapPath, bootstrap, 0600); err != nil {
+		return fmt.Errorf("failed to write Envoy bootstrap configuration: %v", err)
+	}
+	return nil
+}
+
+// setServiceTokens derives the Service Identity tokens of the Consul services
+// of the task that don't have one yet and hands them to the service client
+// to register the services with. Tokens are only derived when Nomad itself
+// uses an ACL token with Consul. Services whose name is interpolated are
+// registered with the token of the agent.
+func (r *TaskRunner) setServiceTokens(task *structs.Task) error {
+	setter, ok := r.consul.(consulServiceTokens)
+	if !ok || r.config.ConsulConfig.Token == "" {
+		return nil
+	}
+
+	r.serviceTokensLock.Lock()
+	defer r.serviceTokensLock.Unlock()
+
+	missing := false
+	for _, service := range task.Services {
+		if isNomadService(service) || strings.Contains(service.Name, "${") {
+			continue
+		}
+		if _, ok := r.serviceTokens[service.Name]; !ok {
+			missing = true
+			break
+		}
+	}
+
+	if missing {
+		if r.siTokenDeriver == nil {
+			return fmt.Errorf("no Service Identity token deriver")
+		}
+		_, tokens, err := r.siTokenDeriver(r.alloc, []string{task.Name}, true)
+		if err != nil {
+			return err
+		}
+		if r.serviceTokens == nil {
+			r.serviceTokens = make(map[string]string)
+		}
+		for service, token := range tokens[task.Name] {
+			r.serviceTokens[service] = token
+		}
+	}
+
+	setter.SetServiceTokens(r.alloc.ID, task.Name, r.serviceTokens)
+	return nil
+}
+
+// findConnectService returns the Connect service of the task with the given
+// name or nil if there is none.
+func findConnectService(task *structs.Task, name string) *structs.Service {
+	for _, service := range task.Services {
+		if service.Connect != nil && service.Name == name {
+			return service
+		}
+	}
+	return nil
+}
+
+// envoyBootstrap returns the Envoy bootstrap configuration of the sidecar
+// proxy of a service. Envoy gets the rest of its configuration from the xDS
+// server of the local Consul agent at grpcAddr, authenticating with the
+// Service Identity token if one is given. The node metadata tells Consul the
+// namespace and partition the proxy is registered in.
+func envoyBootstrap(service, proxyID string, consul *structs.Consul, adminSocket, grpcAddr, token string) ([]byte, error) {
+	host, rawPort, err := net.SplitHostPort(grpcAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Consul gRPC address %q: %v", grpcAddr, err)
+	}
+	port, err := strconv.Atoi(rawPort)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Consul gRPC port %q: %v", rawPort, err)
+	}
+
+	grpcService := map[string]interface{}{
+		"envoy_grpc": map[string]interface{}{
+			"cluster_name": "local_agent",
+		},
+	}
+	if token != "" {
+		grpcService["initial_metadata"] = []interface{}{
+			map[string]interface{}{
+				"key":   "x-consul-token",
+				"value": token,
+			},
+		}
+	}
+
+	node := map[string]interface{}{
+		"cluster": service,
+		"id":      proxyID,
+	}
+	if consul.GetNamespace() != "" || consul.GetPartition() != "" {
+		node["metadata"] = map[string]interface{}{
+			"namespace": consul.GetNamespace(),
+			"partition": consul.GetPartition(),
+		}
+	}
+
+	config := map[string]interface{}{
+		"admin": map[string]interface{}{
+			"access_log_path": "/dev/null",
+			"address": map[string]interface{}{
+				"pipe": map[string]interface{}{
+					"path": adminSocket,
+				},
+			},
+		},
+		"node": node,
+		"static_resources": map[string]interface{}{
+			"clusters": []interface{}{
+				map[string]interface{}{
+					"name":                   "local_agent",
+					"connect_timeout":        "1s",
+					"type":                   "STATIC",
+					"http2_protocol_options": map[string]interface{}{},
+					"hosts": []interface{}{
+						map[string]interface{}{
+							"socket_address": map[string]interface{}{
+								"address":    host,
+								"port_value": port,
+							},
+						},
+					},
+				},
+			},
+		},
+		"dynamic_resources": map[string]interface{}{
+			"lds_config": map[string]interface{}{"ads": map[string]interface{}{}},
+			"cds_config": map[string]interface{}{"ads": map[string]interface{}{}},
+			"ads_config": map[string]interface{}{
+				"api_type":      "GRPC",
+				"grpc_services": grpcService,
+			},
+		},
+	}
+	return json.MarshalIndent(config, "", "  ")
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestEnvoyBootstrap(t *testing.T) {
+	t.Parallel()
+	raw, err := envoyBootstrap("web", "proxy-id", nil, "/secrets/envoy_admin.sock", "127.0.0.1:8502", "token")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var config struct {
+		Admin struct {
+			Address struct {
+				Pipe struct {
+					Path string
+				}
+			}
+		}
+		Node struct {
+			Cluster string
+			ID      string
+		}
+		StaticResources struct {
+			Clusters []struct {
+				Name  string
+				Hosts []struct {
+					SocketAddress struct {
+						Address   string
+						PortValue int `json:"port_value"`
+					} `json:"socket_address"`
+				}
+			}
+		} `json:"static_resources"`
+		DynamicResources struct {
+			AdsConfig struct {
+				GrpcServices struct {
+					InitialMetadata []struct {
+						Key   string
+						Value string
+					} `json:"initial_metadata"`
+				} `json:"grpc_services"`
+			} `json:"ads_config"`
+		} `json:"dynamic_resources"`
+	}
+	if err := json.Unmarshal(raw, &config); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if config.Admin.Address.Pipe.Path != "/secrets/envoy_admin.sock" {
+		t.Fatalf("bad admin: %#v", config.Admin)
+	}
+	if config.Node.Cluster != "web" || config.Node.ID != "proxy-id" {
+		t.Fatalf("bad node: %#v", config.Node)
+	}
+	clusters := config.StaticResources.Clusters
+	if len(clusters) != 1 || len(clusters[0].Hosts) != 1 {
+		t.Fatalf("bad clusters: %#v", clusters)
+	}
+	if addr := clusters[0].Hosts[0].SocketAddress; addr.Address != "127.0.0.1" || addr.PortValue != 8502 {
+		t.Fatalf("bad agent address: %#v", addr)
+	}
+	md := config.DynamicResources.AdsConfig.GrpcServices.InitialMetadata
+	if len(md) != 1 || md[0].Key != "x-consul-token" || md[0].Value != "token" {
+		t.Fatalf("bad metadata: %#v", md)
+	}
+
+	// No token and an invalid address
+	raw, err = envoyBootstrap("web", "proxy-id", nil, "/secrets/envoy_admin.sock", "127.0.0.1:8502", "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if bytes.Contains(raw, []byte("x-consul-token")) {
+		t.Fatalf("unexpected token metadata: %s", raw)
+	}
+	if bytes.Contains(raw, []byte("namespace")) {
+		t.Fatalf("unexpected namespace metadata: %s", raw)
+	}
+
+	// The namespace of the proxy is passed in the node metadata
+	raw, err = envoyBootstrap("web", "proxy-id", &structs.Consul{Namespace: "team"},
+		"/secrets/envoy_admin.sock", "127.0.0.1:8502", "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Contains(raw, []byte(`"namespace": "team"`)) {
+		t.Fatalf("missing namespace metadata: %s", raw)
+	}
+	if _, err := envoyBootstrap("web", "proxy-id", nil, "/secrets/envoy_admin.sock", "127.0.0.1", ""); err == nil {
+		t.Fatalf("expected invalid address error")
+	}
+}
+
+// fakeServiceTokens is a ConsulServiceAPI recording the Service Identity
+// tokens set for the services of tasks.
+type fakeServiceTokens struct {
+	*mockConsulServiceClient
+	tokens map[string]string
+}
+
+func (f *fakeServiceTokens) SetServiceTokens(allocID, taskName string, tokens map[string]string) {
+	f.tokens = tokens
+}
+
+func TestTaskRunner_SetServiceTokens(t *testing.T) {
+	t.Parallel()
+	alloc := mock.ConnectAlloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	for _, service := range task.Services {
+		if service.Name != "testconnect" {
+			service.Name = "${NOMAD_META_prefix}-" + service.Name
+		}
+	}
+	consul := &fakeServiceTokens{mockConsulServiceClient: newMockConsulServiceClient()}
+
+	derived := 0
+	r := &TaskRunner{
+		config: config.DefaultConfig(),
+		alloc:  alloc,
+		consul: consul,
+		siTokenDeriver: func(a *structs.Allocation, tasks []string, services bool) (
+			map[string]string, map[string]map[string]string, error) {
+			derived++
+			if !services || len(tasks) != 1 || tasks[0] != task.Name {
+				t.Fatalf("bad request: %v %v", tasks, services)
+			}
+			return nil, map[string]map[string]string{task.Name: {"testconnect": "secret"}}, nil
+		},
+	}
+
+	// Without Consul ACLs no tokens are derived
+	if err := r.setServiceTokens(task); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if derived != 0 || consul.tokens != nil {
+		t.Fatalf("unexpected tokens derived")
+	}
+
+	// The tokens are derived once and only the services whose name isn't
+	// interpolated get one
+	r.config.ConsulConfig.Token = "agent"
+	for i := 0; i < 2; i++ {
+		if err := r.setServiceTokens(task); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if derived != 1 {
+		t.Fatalf("expected tokens to be derived once; derived %d times", derived)
+	}
+	expected := map[string]string{"testconnect": "secret"}
+	if !reflect.DeepEqual(consul.tokens, expected) {
+		t.Fatalf("expected tokens %#v; got %#v", expected, consul.tokens)
+	}
+}
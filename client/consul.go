@@ -4,14 +4,24 @@ import (
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/nomad/client/driver"
 	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
 // ConsulServiceAPI is the interface the Nomad Client uses to register and
-// remove services and checks from Consul.
+// remove services and checks from Consul. Services are registered in the
+// Consul namespace and partition of their task group, and the restarter
+// restarts the task when its checks stay unhealthy.
 type ConsulServiceAPI interface {
-	RegisterTask(allocID string, task *structs.Task, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error
+	RegisterTask(allocID string, consul *structs.Consul, task *structs.Task, restarter consul.TaskRestarter, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error
 	RemoveTask(allocID string, task *structs.Task)
-	UpdateTask(allocID string, existing, newTask *structs.Task, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error
+	UpdateTask(allocID string, consul *structs.Consul, existing, newTask *structs.Task, restarter consul.TaskRestarter, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error
 	Checks(alloc *structs.Allocation) ([]*api.AgentCheck, error)
 }
+
+// consulServiceTokens is implemented by the ConsulServiceAPIs that register
+// the services of tasks with the Consul Service Identity tokens of the
+// services, indexed by service name, instead of the token of the agent.
+type consulServiceTokens interface {
+	SetServiceTokens(allocID, taskName string, tokens map[string]string)
+}
@@ -1,10 +1,13 @@
 package client
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,32 +18,70 @@ import (
 	"github.com/hashicorp/consul-template/signals"
 	envparse "github.com/hashicorp/go-envparse"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/driver/env"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
+	vaultapi "github.com/hashicorp/vault/api"
 )
 
 const (
 	// hostSrcOption is the Client option that determines whether the template
 	// source may be from the host
 	hostSrcOption = "template.allow_host_source"
+
+	// waitMinOption and waitMaxOption are the Client options that set the
+	// default minimum and maximum time to wait for data to settle before
+	// rendering templates that don't set their own wait
+	waitMinOption = "template.wait.min"
+	waitMaxOption = "template.wait.max"
+
+	// consulRetryOption and vaultRetryOption are the prefixes of the Client
+	// options that configure how templates retry failed Consul and Vault
+	// requests
+	consulRetryOption = "template.consul_retry"
+	vaultRetryOption  = "template.vault_retry"
+
+	// functionDenylistOption is the Client option listing the template
+	// functions templates may not use
+	functionDenylistOption = "template.function_denylist"
+
+	// functionAllowlistOption is the Client option listing the only template
+	// functions templates may use. Every function not denied is allowed if
+	// unset.
+	functionAllowlistOption = "template.function_allowlist"
+
+	// disableFileSandboxOption is the Client option that allows templates to
+	// render and read files outside of the task directory
+	disableFileSandboxOption = "template.disable_file_sandbox"
+
+	// defaultFunctionDenylist is the list of template functions denied by
+	// default. "plugin" runs arbitrary commands on the client.
+	defaultFunctionDenylist = "plugin"
 )
 
 var (
 	// testRetryRate is used to speed up tests by setting consul-templates retry
 	// rate to something low
 	testRetryRate time.Duration = 0
+
+	// secretTemplateFunctions are the template functions reading secrets from
+	// Vault or Nomad variables
+	secretTemplateFunctions = []string{"kvSecret", "nomadVar", "secret", "secrets"}
 )
 
 // TaskHooks is an interface which provides hooks into the tasks life-cycle
 type TaskHooks interface {
 	// Restart is used to restart the task
-	Restart(source, reason string)
+	Restart(source, reason string, failure bool)
 
 	// Signal is used to signal the task
 	Signal(source, reason string, s os.Signal) error
 
+	// RunScript is used to run a change script inside the task
+	RunScript(source, reason string, script *structs.ChangeScript)
+
 	// UnblockStart is used to unblock the starting of the task. This should be
 	// called after prestart work is completed
 	UnblockStart(source string)
@@ -48,6 +89,10 @@ type TaskHooks interface {
 	// Kill is used to kill the task because of the passed error. If fail is set
 	// to true, the task is marked as failed
 	Kill(source, reason string, fail bool)
+
+	// SetSecrets sets the secrets of the source that are redacted from the
+	// task's events and logs
+	SetSecrets(source string, secrets []string)
 }
 
 // TaskTemplateManager is used to run a set of templates for a given task
@@ -58,12 +103,20 @@ type TaskTemplateManager struct {
 	// lookup allows looking up the set of Nomad templates by their consul-template ID
 	lookup map[string][]*structs.Template
 
-	// hooks is used to signal/restart the task as templates are rendered
+	// hooks is used to signal/restart the task or run change scripts as
+	// templates are rendered
 	hook TaskHooks
 
 	// runner is the consul-template runner
 	runner *manager.Runner
 
+	// data fetches the data of the template functions implemented by Nomad
+	data *templateData
+
+	// sandbox restricts the functions and files of the templates and moves
+	// the templates rendered in its stage directory to their destination
+	sandbox *templateSandbox
+
 	// signals is a lookup map from the string representation of a signal to its
 	// actual signal
 	signals map[string]os.Signal
@@ -78,7 +131,8 @@ type TaskTemplateManager struct {
 
 func NewTaskTemplateManager(hook TaskHooks, tmpls []*structs.Template,
 	config *config.Config, vaultToken, taskDir string,
-	envBuilder *env.Builder) (*TaskTemplateManager, error) {
+	envBuilder *env.Builder, nomadServices templateServiceLister,
+	nomadVariables templateVariableReader) (*TaskTemplateManager, error) {
 
 	// Check pre-conditions
 	if hook == nil {
@@ -116,12 +170,14 @@ func NewTaskTemplateManager(hook TaskHooks, tmpls []*structs.Template,
 	}
 
 	// Build the consul-template runner
-	runner, lookup, err := templateRunner(tmpls, config, vaultToken, taskDir, envBuilder.Build())
+	tm.sandbox = newTemplateSandbox(config, taskDir)
+	runner, lookup, data, err := templateRunner(tmpls, config, tm.sandbox, vaultToken, taskDir, envBuilder.Build(), nomadServices, nomadVariables)
 	if err != nil {
 		return nil, err
 	}
 	tm.runner = runner
 	tm.lookup = lookup
+	tm.data = data
 
 	go tm.run(envBuilder, taskDir)
 	return tm, nil
@@ -143,6 +199,10 @@ func (tm *TaskTemplateManager) Stop() {
 	if tm.runner != nil {
 		tm.runner.Stop()
 	}
+
+	if tm.sandbox != nil {
+		tm.sandbox.cleanup()
+	}
 }
 
 // run is the long lived loop that handles errors and templates being rendered
@@ -154,6 +214,13 @@ func (tm *TaskTemplateManager) run(envBuilder *env.Builder, taskDir string) {
 		return
 	}
 
+	// Fetch the data of the template functions implemented by Nomad before
+	// the templates reading it are rendered
+	fail := func(err error) { tm.hook.Kill("consul-template", err.Error(), true) }
+	if !tm.data.run(tm.shutdownCh, fail) {
+		return
+	}
+
 	// Start the runner
 	go tm.runner.Start()
 
@@ -194,19 +261,36 @@ WAIT:
 		}
 	}
 
+	// Move the rendered templates to their destination
+	if err := tm.sandbox.install(); err != nil {
+		tm.hook.Kill("consul-template", err.Error(), true)
+		return
+	}
+
 	// Read environment variables from env templates
-	envMap, err := loadTemplateEnv(tm.templates, taskDir)
+	renderDir := tm.sandbox.renderDir()
+	envMap, err := loadTemplateEnv(tm.templates, renderDir)
 	if err != nil {
 		tm.hook.Kill("consul-template", err.Error(), true)
 		return
 	}
 	envBuilder.SetTemplateEnv(envMap)
 
+	// Redact the rendered secrets before the task is started
+	secrets, err := loadTemplateSecrets(tm.templates, renderDir, tm.sandbox.contents)
+	if err != nil {
+		tm.hook.Kill("consul-template", err.Error(), true)
+		return
+	}
+	tm.hook.SetSecrets(templateSecrets, secrets)
+
 	allRenderedTime = time.Now()
 	tm.hook.UnblockStart("consul-template")
 
 	// If all our templates are change mode no-op, then we can exit here
-	if tm.allTemplatesNoop() {
+	// unless the templates re-rendered in the stage directory must still be
+	// moved to their destination
+	if tm.allTemplatesNoop() && tm.sandbox.stageDir == "" {
 		return
 	}
 
@@ -225,9 +309,15 @@ WAIT:
 
 			tm.hook.Kill("consul-template", err.Error(), true)
 		case <-tm.runner.TemplateRenderedCh():
+			if err := tm.sandbox.install(); err != nil {
+				tm.hook.Kill("consul-template", err.Error(), true)
+				return
+			}
+
 			// A template has been rendered, figure out what to do
 			var handling []string
 			signals := make(map[string]struct{})
+			var scripts []*structs.ChangeScript
 			restart := false
 			var splay time.Duration
 
@@ -252,20 +342,30 @@ WAIT:
 					return
 				}
 
-				// Read environment variables from templates
-				envMap, err := loadTemplateEnv(tmpls, taskDir)
+				// Read environment variables from all templates so the
+				// variables of the other templates are kept
+				envMap, err := loadTemplateEnv(tm.templates, renderDir)
 				if err != nil {
 					tm.hook.Kill("consul-template", err.Error(), true)
 					return
 				}
 				envBuilder.SetTemplateEnv(envMap)
 
+				secrets, err := loadTemplateSecrets(tm.templates, renderDir, tm.sandbox.contents)
+				if err != nil {
+					tm.hook.Kill("consul-template", err.Error(), true)
+					return
+				}
+				tm.hook.SetSecrets(templateSecrets, secrets)
+
 				for _, tmpl := range tmpls {
 					switch tmpl.ChangeMode {
 					case structs.TemplateChangeModeSignal:
 						signals[tmpl.ChangeSignal] = struct{}{}
 					case structs.TemplateChangeModeRestart:
 						restart = true
+					case structs.TemplateChangeModeScript:
+						scripts = append(scripts, tmpl.ChangeScript)
 					case structs.TemplateChangeModeNoop:
 						continue
 					}
@@ -278,7 +378,7 @@ WAIT:
 				handling = append(handling, id)
 			}
 
-			if restart || len(signals) != 0 {
+			if restart || len(signals) != 0 || len(scripts) != 0 {
 				if splay != 0 {
 					ns := splay.Nanoseconds()
 					offset := rand.Int63n(ns)
@@ -297,8 +397,11 @@ WAIT:
 				}
 
 				if restart {
-					tm.hook.Restart("consul-template", "template with change_mode restart re-rendered")
-				} else if len(signals) != 0 {
+					tm.hook.Restart("consul-template", "template with change_mode restart re-rendered", false)
+					continue
+				}
+
+				if len(signals) != 0 {
 					var mErr multierror.Error
 					for signal := range signals {
 						err := tm.hook.Signal("consul-template", "template re-rendered", tm.signals[signal])
@@ -313,8 +416,13 @@ WAIT:
 							flat = append(flat, tm.signals[signal])
 						}
 						tm.hook.Kill("consul-template", fmt.Sprintf("Sending signals %v failed: %v", flat, err), true)
+						continue
 					}
 				}
+
+				for _, script := range scripts {
+					tm.hook.RunScript("consul-template", "template with change_mode script re-rendered", script)
+				}
 			}
 		}
 	}
@@ -331,33 +439,65 @@ func (tm *TaskTemplateManager) allTemplatesNoop() bool {
 	return true
 }
 
-// templateRunner returns a consul-template runner for the given templates and a
-// lookup by destination to the template. If no templates are given, a nil
-// template runner and lookup is returned.
-func templateRunner(tmpls []*structs.Template, config *config.Config,
-	vaultToken, taskDir string, taskEnv *env.TaskEnv) (
-	*manager.Runner, map[string][]*structs.Template, error) {
+// templateRunner returns a consul-template runner for the given templates, a
+// lookup by destination to the template and the fetcher of the data of the
+// template functions implemented by Nomad. The templates are checked by the
+// sandbox and rendered in its render directory. If no templates are given, a
+// nil template runner, lookup and fetcher are returned.
+func templateRunner(tmpls []*structs.Template, config *config.Config, sandbox *templateSandbox,
+	vaultToken, taskDir string, taskEnv *env.TaskEnv, nomadServices templateServiceLister,
+	nomadVariables templateVariableReader) (
+	*manager.Runner, map[string][]*structs.Template, *templateData, error) {
 
 	if len(tmpls) == 0 {
-		return nil, nil, nil
+		return nil, nil, nil, nil
 	}
 
 	// Parse the templates
-	allowAbs := config.ReadBoolDefault(hostSrcOption, true)
+	allowAbs := config.ReadBoolDefault(hostSrcOption, false)
 	ctmplMapping, err := parseTemplateConfigs(tmpls, taskDir, taskEnv, allowAbs)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	// Ensure the templates only use the functions and files allowed by the
+	// client, and render them in the stage directory of the sandbox
+	checked := make(map[ctconf.TemplateConfig]*structs.Template, len(ctmplMapping))
+	for ct, tmpl := range ctmplMapping {
+		if err := sandbox.check(&ct); err != nil {
+			return nil, nil, nil, err
+		}
+		sandbox.stage(&ct)
+		sandbox.contents[tmpl] = *ct.Contents
+		checked[ct] = tmpl
+	}
+	ctmplMapping = checked
+
+	// Replace the calls of the template functions implemented by Nomad with
+	// the parsing of the data fetched for them
+	vault, err := templateVaultClient(config, vaultToken)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data := newTemplateData(sandbox.renderDir(), nomadServices, nomadVariables, vault)
+	rewritten := make(map[ctconf.TemplateConfig]*structs.Template, len(ctmplMapping))
+	for ct, tmpl := range ctmplMapping {
+		if err := data.rewriteConfig(&ct); err != nil {
+			return nil, nil, nil, err
+		}
+		rewritten[ct] = tmpl
 	}
+	ctmplMapping = rewritten
 
 	// Create the runner configuration.
 	runnerConfig, err := newRunnerConfig(config, vaultToken, ctmplMapping)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	runner, err := manager.NewRunner(runnerConfig, false, false)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Set Nomad's environment variables
@@ -374,7 +514,26 @@ func templateRunner(tmpls []*structs.Template, config *config.Config,
 		}
 	}
 
-	return runner, lookup, nil
+	return runner, lookup, data, nil
+}
+
+// templateVaultClient returns the client reading Vault KV secrets with the
+// task's Vault token, or nil if Vault isn't enabled or the task has no token.
+func templateVaultClient(config *config.Config, vaultToken string) (*vaultapi.Client, error) {
+	if vaultToken == "" || config.VaultConfig == nil || !config.VaultConfig.IsEnabled() {
+		return nil, nil
+	}
+
+	apiConf, err := config.VaultConfig.ApiConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := vaultapi.NewClient(apiConf)
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken(vaultToken)
+	return client, nil
 }
 
 // parseTemplateConfigs converts the tasks templates into consul-templates
@@ -405,6 +564,12 @@ func parseTemplateConfigs(tmpls []*structs.Template, taskDir string,
 		ct.Contents = &tmpl.EmbeddedTmpl
 		ct.LeftDelim = &tmpl.LeftDelim
 		ct.RightDelim = &tmpl.RightDelim
+		ct.ErrMissingKey = helper.BoolToPtr(tmpl.ErrMissingKey)
+
+		// Set the wait, the runner's default wait is used otherwise
+		if tmpl.Wait != nil {
+			ct.Wait = waitConfig(tmpl.Wait.Min, tmpl.Wait.Max)
+		}
 
 		// Set the permissions
 		if tmpl.Perms != "" {
@@ -451,6 +616,13 @@ func newRunnerConfig(config *config.Config, vaultToken string,
 		}
 	}
 
+	// Set the default wait and retries from the client options
+	if min := config.ReadDurationDefault(waitMinOption, 0); min != 0 {
+		conf.Wait = waitConfig(min, config.ReadDurationDefault(waitMaxOption, 0))
+	}
+	conf.Consul.Retry = conf.Consul.Retry.Merge(retryConfig(config, consulRetryOption))
+	conf.Vault.Retry = conf.Vault.Retry.Merge(retryConfig(config, vaultRetryOption))
+
 	// Force faster retries
 	if testRetryRate != 0 {
 		rate := testRetryRate
@@ -526,9 +698,41 @@ func newRunnerConfig(config *config.Config, vaultToken string,
 	return conf, nil
 }
 
-// loadTemplateEnv loads task environment variables from all templates.
+// waitConfig returns a consul-template wait configuration. A zero max
+// defaults to four times the min.
+func waitConfig(min, max time.Duration) *ctconf.WaitConfig {
+	wait := &ctconf.WaitConfig{
+		Min: helper.TimeToPtr(min),
+	}
+	if max != 0 {
+		wait.Max = helper.TimeToPtr(max)
+	}
+	return wait
+}
+
+// retryConfig returns the consul-template retry configuration set by the
+// client options with the given prefix. Unset options keep consul-template's
+// defaults.
+func retryConfig(config *config.Config, prefix string) *ctconf.RetryConfig {
+	retry := &ctconf.RetryConfig{}
+	if attempts, err := config.ReadInt(prefix + ".attempts"); err == nil {
+		retry.Attempts = helper.IntToPtr(attempts)
+	}
+	if backoff, err := config.ReadDuration(prefix + ".backoff"); err == nil {
+		retry.Backoff = helper.TimeToPtr(backoff)
+	}
+	if maxBackoff, err := config.ReadDuration(prefix + ".max_backoff"); err == nil {
+		retry.MaxBackoff = helper.TimeToPtr(maxBackoff)
+	}
+	return retry
+}
+
+// loadTemplateEnv loads task environment variables from all templates. Only
+// the selected keys of templates with EnvKeys are loaded, renamed. An error is
+// returned if several templates set the same variable.
 func loadTemplateEnv(tmpls []*structs.Template, taskDir string) (map[string]string, error) {
 	all := make(map[string]string, 50)
+	setBy := make(map[string]string, 50)
 	for _, t := range tmpls {
 		if !t.Envvars {
 			continue
@@ -544,9 +748,124 @@ func loadTemplateEnv(tmpls []*structs.Template, taskDir string) (map[string]stri
 		if err != nil {
 			return nil, fmt.Errorf("error parsing env template %q: %v", t.DestPath, err)
 		}
-		for k, v := range vars {
-			all[k] = v
+
+		// Select and rename the keys. Selected keys missing from the
+		// template are skipped.
+		if len(t.EnvKeys) != 0 {
+			selected := make(map[string]string, len(t.EnvKeys))
+			for key, name := range t.EnvKeys {
+				if v, ok := vars[key]; ok {
+					selected[name] = v
+				}
+			}
+			vars = selected
+		}
+
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if other, ok := setBy[k]; ok {
+				return nil, fmt.Errorf("env templates %q and %q both set %q", other, t.DestPath, k)
+			}
+			setBy[k] = t.DestPath
+			all[k] = vars[k]
 		}
 	}
 	return all, nil
 }
+
+// loadTemplateSecrets returns the secrets rendered by the templates to be
+// redacted from the task's events and logs, given the contents of the
+// templates read from their source. Templates are secret if they are rendered
+// to the secrets directory or read secrets from Vault or Nomad variables. The
+// values of secret env templates are secrets. Of other templates, only the
+// outputs of the actions are secrets, and only of the actions reading secrets
+// unless rendered to the secrets directory. The whole contents are secrets if
+// they can't be matched to the template.
+func loadTemplateSecrets(tmpls []*structs.Template, taskDir string, contents map[*structs.Template]string) ([]string, error) {
+	var secrets []string
+	for _, t := range tmpls {
+		tmpl, ok := contents[t]
+		if !ok {
+			tmpl = t.EmbeddedTmpl
+		}
+		left, right := templateDelims(t)
+		inSecrets, readsSecrets, err := isSecretTemplate(t, tmpl, left, right)
+		if err != nil {
+			return nil, err
+		}
+		if !inSecrets && !readsSecrets {
+			continue
+		}
+
+		raw, err := ioutil.ReadFile(filepath.Join(taskDir, t.DestPath))
+		if err != nil {
+			return nil, fmt.Errorf("error reading secret template %q: %v", t.DestPath, err)
+		}
+
+		if t.Envvars {
+			vars, err := envparse.Parse(bytes.NewReader(raw))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing env template %q: %v", t.DestPath, err)
+			}
+			for _, v := range vars {
+				secrets = append(secrets, v)
+			}
+			continue
+		}
+
+		captures, ok, err := templateCaptures(tmpl, left, right, string(raw))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			captures = []templateCapture{{value: string(raw), secret: true}}
+		}
+		for _, c := range captures {
+			if !c.secret && !inSecrets {
+				continue
+			}
+			value := strings.TrimSpace(c.value)
+			secrets = append(secrets, value)
+			if strings.Contains(value, "\n") {
+				for _, line := range strings.Split(value, "\n") {
+					secrets = append(secrets, strings.TrimSpace(line))
+				}
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// isSecretTemplate returns whether the template is rendered to the secrets
+// directory and whether its contents read secrets from Vault or Nomad
+// variables.
+func isSecretTemplate(t *structs.Template, contents, leftDelim, rightDelim string) (bool, bool, error) {
+	dest := filepath.Clean(t.DestPath)
+	inSecrets := dest == allocdir.TaskSecrets || strings.HasPrefix(dest, allocdir.TaskSecrets+string(filepath.Separator))
+	if contents == "" {
+		return inSecrets, false, nil
+	}
+
+	used, err := TemplateFunctionsUsed(contents, leftDelim, rightDelim, secretTemplateFunctions...)
+	if err != nil {
+		return false, false, err
+	}
+	return inSecrets, len(used) != 0, nil
+}
+
+// templateDelims returns the delimiters of the template, defaulting to the
+// ones of Go templates.
+func templateDelims(t *structs.Template) (string, string) {
+	left, right := t.LeftDelim, t.RightDelim
+	if left == "" {
+		left = "{{"
+	}
+	if right == "" {
+		right = "}}"
+	}
+	return left, right
+}
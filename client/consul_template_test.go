@@ -6,7 +6,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -43,6 +46,12 @@ type MockTaskHooks struct {
 
 	KillReason string
 	KillCh     chan struct{}
+
+	Scripts  []*structs.ChangeScript
+	ScriptCh chan struct{}
+
+	Secrets     map[string][]string
+	secretsLock sync.Mutex
 }
 
 func NewMockTaskHooks() *MockTaskHooks {
@@ -51,9 +60,10 @@ func NewMockTaskHooks() *MockTaskHooks {
 		RestartCh: make(chan struct{}, 1),
 		SignalCh:  make(chan struct{}, 1),
 		KillCh:    make(chan struct{}, 1),
+		ScriptCh:  make(chan struct{}, 1),
 	}
 }
-func (m *MockTaskHooks) Restart(source, reason string) {
+func (m *MockTaskHooks) Restart(source, reason string, failure bool) {
 	m.Restarts++
 	select {
 	case m.RestartCh <- struct{}{}:
@@ -71,6 +81,14 @@ func (m *MockTaskHooks) Signal(source, reason string, s os.Signal) error {
 	return m.SignalError
 }
 
+func (m *MockTaskHooks) RunScript(source, reason string, script *structs.ChangeScript) {
+	m.Scripts = append(m.Scripts, script)
+	select {
+	case m.ScriptCh <- struct{}{}:
+	default:
+	}
+}
+
 func (m *MockTaskHooks) Kill(source, reason string, fail bool) {
 	m.KillReason = reason
 	select {
@@ -79,6 +97,15 @@ func (m *MockTaskHooks) Kill(source, reason string, fail bool) {
 	}
 }
 
+func (m *MockTaskHooks) SetSecrets(source string, secrets []string) {
+	m.secretsLock.Lock()
+	defer m.secretsLock.Unlock()
+	if m.Secrets == nil {
+		m.Secrets = make(map[string][]string)
+	}
+	m.Secrets[source] = secrets
+}
+
 func (m *MockTaskHooks) UnblockStart(source string) {
 	if !m.Unblocked {
 		close(m.UnblockCh)
@@ -100,6 +127,12 @@ type testHarness struct {
 	taskDir    string
 	vault      *testutil.TestVault
 	consul     *ctestutil.TestServer
+
+	// nomadServices lists the services registered with Nomad
+	nomadServices templateServiceLister
+
+	// nomadVariables reads the variables stored by Nomad
+	nomadVariables templateVariableReader
 }
 
 // newTestHarness returns a harness starting a dev consul and vault server,
@@ -147,7 +180,7 @@ func newTestHarness(t *testing.T, templates []*structs.Template, consul, vault b
 
 func (h *testHarness) start(t *testing.T) {
 	manager, err := NewTaskTemplateManager(h.mockHooks, h.templates,
-		h.config, h.vaultToken, h.taskDir, h.envBuilder)
+		h.config, h.vaultToken, h.taskDir, h.envBuilder, h.nomadServices, h.nomadVariables)
 	if err != nil {
 		t.Fatalf("failed to build task template manager: %v", err)
 	}
@@ -157,7 +190,7 @@ func (h *testHarness) start(t *testing.T) {
 
 func (h *testHarness) startWithErr() error {
 	manager, err := NewTaskTemplateManager(h.mockHooks, h.templates,
-		h.config, h.vaultToken, h.taskDir, h.envBuilder)
+		h.config, h.vaultToken, h.taskDir, h.envBuilder, h.nomadServices, h.nomadVariables)
 	h.manager = manager
 	return err
 }
@@ -189,32 +222,32 @@ func TestTaskTemplateManager_Invalid(t *testing.T) {
 	a := mock.Alloc()
 	envBuilder := env.NewBuilder(mock.Node(), a, a.Job.TaskGroups[0].Tasks[0], config.Region)
 
-	_, err := NewTaskTemplateManager(nil, nil, nil, "", "", nil)
+	_, err := NewTaskTemplateManager(nil, nil, nil, "", "", nil, nil, nil)
 	if err == nil {
 		t.Fatalf("Expected error")
 	}
 
-	_, err = NewTaskTemplateManager(nil, tmpls, config, vaultToken, taskDir, envBuilder)
+	_, err = NewTaskTemplateManager(nil, tmpls, config, vaultToken, taskDir, envBuilder, nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "task hook") {
 		t.Fatalf("Expected invalid task hook error: %v", err)
 	}
 
-	_, err = NewTaskTemplateManager(hooks, tmpls, nil, vaultToken, taskDir, envBuilder)
+	_, err = NewTaskTemplateManager(hooks, tmpls, nil, vaultToken, taskDir, envBuilder, nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "config") {
 		t.Fatalf("Expected invalid config error: %v", err)
 	}
 
-	_, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, "", envBuilder)
+	_, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, "", envBuilder, nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "task directory") {
 		t.Fatalf("Expected invalid task dir error: %v", err)
 	}
 
-	_, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, nil)
+	_, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, nil, nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "task environment") {
 		t.Fatalf("Expected invalid task environment error: %v", err)
 	}
 
-	tm, err := NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, envBuilder)
+	tm, err := NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, envBuilder, nil, nil)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	} else if tm == nil {
@@ -230,7 +263,7 @@ func TestTaskTemplateManager_Invalid(t *testing.T) {
 	}
 
 	tmpls = append(tmpls, tmpl)
-	tm, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, envBuilder)
+	tm, err = NewTaskTemplateManager(hooks, tmpls, config, vaultToken, taskDir, envBuilder, nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "Failed to parse signal") {
 		t.Fatalf("Expected signal parsing error: %v", err)
 	}
@@ -258,7 +291,17 @@ func TestTaskTemplateManager_HostPath(t *testing.T) {
 		ChangeMode: structs.TemplateChangeModeNoop,
 	}
 
+	// Host sources are disallowed by default
 	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	if err := harness.startWithErr(); err == nil || !strings.Contains(err.Error(), "absolute") {
+		t.Fatalf("Expected absolute template path disallowed: %v", err)
+	}
+
+	// Change the config to allow host sources
+	harness = newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.config.Options = map[string]string{
+		hostSrcOption: "true",
+	}
 	harness.start(t)
 	defer harness.stop()
 
@@ -279,15 +322,6 @@ func TestTaskTemplateManager_HostPath(t *testing.T) {
 	if s := string(raw); s != content {
 		t.Fatalf("Unexpected template data; got %q, want %q", s, content)
 	}
-
-	// Change the config to disallow host sources
-	harness = newTestHarness(t, []*structs.Template{template}, false, false)
-	harness.config.Options = map[string]string{
-		hostSrcOption: "false",
-	}
-	if err := harness.startWithErr(); err == nil || !strings.Contains(err.Error(), "absolute") {
-		t.Fatalf("Expected absolute template path disallowed: %v", err)
-	}
 }
 
 func TestTaskTemplateManager_Unblock_Static(t *testing.T) {
@@ -484,6 +518,120 @@ func TestTaskTemplateManager_Unblock_Consul(t *testing.T) {
 	}
 }
 
+// fakeNomadServices lists a static set of services registered with Nomad.
+type fakeNomadServices struct {
+	services []*structs.ServiceRegistration
+}
+
+func (f *fakeNomadServices) NomadServices(name string, index uint64) ([]*structs.ServiceRegistration, uint64, error) {
+	// Emulate a blocking query timing out once the services were returned
+	if index != 0 {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	var services []*structs.ServiceRegistration
+	for _, s := range f.services {
+		if s.ServiceName == name {
+			services = append(services, s)
+		}
+	}
+	return services, 1, nil
+}
+
+func TestTaskTemplateManager_Unblock_NomadService(t *testing.T) {
+	t.Parallel()
+	// Make a template that will render based on a service registered with
+	// Nomad
+	embedded := `{{ range nomadService "http.web" }}{{ .Address }}:{{ .Port }} {{ end }}`
+	file := "my.tmpl"
+	template := &structs.Template{
+		EmbeddedTmpl: embedded,
+		DestPath:     file,
+		ChangeMode:   structs.TemplateChangeModeNoop,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.nomadServices = &fakeNomadServices{
+		services: []*structs.ServiceRegistration{
+			{ServiceName: "web", Address: "10.0.0.1", Port: 8080, Tags: []string{"http"}},
+			{ServiceName: "web", Address: "10.0.0.2", Port: 8080, Tags: []string{"grpc"}},
+			{ServiceName: "db", Address: "10.0.0.3", Port: 5432, Tags: []string{"http"}},
+		},
+	}
+	harness.start(t)
+	defer harness.stop()
+
+	// Wait for the unblock
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+
+	// Check the file is there
+	path := filepath.Join(harness.taskDir, file)
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read rendered template from %q: %v", path, err)
+	}
+
+	if s, content := string(raw), "10.0.0.1:8080 "; s != content {
+		t.Fatalf("Unexpected template data; got %q, want %q", s, content)
+	}
+}
+
+// fakeNomadVariables reads a static set of variables stored by Nomad.
+type fakeNomadVariables struct {
+	variables map[string]map[string]string
+}
+
+func (f *fakeNomadVariables) NomadVariable(path string, index uint64) (map[string]string, uint64, error) {
+	// Emulate a blocking query timing out once the variable was returned
+	if index != 0 {
+		time.Sleep(100 * time.Millisecond)
+	}
+	return f.variables[path], 1, nil
+}
+
+func TestTaskTemplateManager_Unblock_NomadVar(t *testing.T) {
+	t.Parallel()
+	// Make a template that will render based on a variable stored by Nomad
+	embedded := `{{ with nomadVar "app/db" }}{{ .user }}:{{ .password }}{{ end }}`
+	file := "my.tmpl"
+	template := &structs.Template{
+		EmbeddedTmpl: embedded,
+		DestPath:     file,
+		ChangeMode:   structs.TemplateChangeModeNoop,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.nomadVariables = &fakeNomadVariables{
+		variables: map[string]map[string]string{
+			"app/db": {"user": "app", "password": "secret"},
+		},
+	}
+	harness.start(t)
+	defer harness.stop()
+
+	// Wait for the unblock
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+
+	// Check the file is there
+	path := filepath.Join(harness.taskDir, file)
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read rendered template from %q: %v", path, err)
+	}
+
+	if s, content := string(raw), "app:secret"; s != content {
+		t.Fatalf("Unexpected template data; got %q, want %q", s, content)
+	}
+}
+
 func TestTaskTemplateManager_Unblock_Vault(t *testing.T) {
 	t.Parallel()
 	// Make a template that will render based on a key in Vault
@@ -776,6 +924,65 @@ OUTER:
 	}
 }
 
+func TestTaskTemplateManager_Rerender_Script(t *testing.T) {
+	t.Parallel()
+	// Make a template that renders based on a key in Consul and runs a script
+	key1 := "bam"
+	content1_1 := "cat"
+	content1_2 := "dog"
+	embedded1 := fmt.Sprintf(`{{key "%s"}}`, key1)
+	file1 := "my.tmpl"
+	script := &structs.ChangeScript{
+		Command: "/bin/reload",
+		Timeout: time.Second,
+	}
+	template := &structs.Template{
+		EmbeddedTmpl: embedded1,
+		DestPath:     file1,
+		ChangeMode:   structs.TemplateChangeModeScript,
+		ChangeScript: script,
+	}
+
+	// Drop the retry rate
+	testRetryRate = 10 * time.Millisecond
+
+	harness := newTestHarness(t, []*structs.Template{template}, true, false)
+	harness.start(t)
+	defer harness.stop()
+
+	// Write the key to Consul
+	harness.consul.SetKV(t, key1, []byte(content1_1))
+
+	// Wait for the unblock
+	select {
+	case <-harness.mockHooks.UnblockCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Task unblock should have been called")
+	}
+
+	if len(harness.mockHooks.Scripts) != 0 {
+		t.Fatalf("Should not have run any scripts: %+v", harness.mockHooks)
+	}
+
+	// Update the key in Consul
+	harness.consul.SetKV(t, key1, []byte(content1_2))
+
+	// Wait for the script
+	select {
+	case <-harness.mockHooks.RestartCh:
+		t.Fatalf("Restart with script policy: %+v", harness.mockHooks)
+	case <-harness.mockHooks.SignalCh:
+		t.Fatalf("Signal with script policy: %+v", harness.mockHooks)
+	case <-harness.mockHooks.ScriptCh:
+	case <-time.After(time.Duration(1*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Should have run the change script: %+v", harness.mockHooks)
+	}
+
+	if harness.mockHooks.Scripts[0] != script {
+		t.Fatalf("Unexpected change script: %+v", harness.mockHooks.Scripts[0])
+	}
+}
+
 func TestTaskTemplateManager_Rerender_Restart(t *testing.T) {
 	t.Parallel()
 	// Make a template that renders based on a key in Consul and sends restart
@@ -928,6 +1135,32 @@ func TestTaskTemplateManager_Signal_Error(t *testing.T) {
 	}
 }
 
+func TestTaskTemplateManager_ErrMissingKey(t *testing.T) {
+	t.Parallel()
+	// Make a template referencing a key missing from a map
+	template := &structs.Template{
+		EmbeddedTmpl:  `{{ with parseJSON "{\"user\": \"admin\"}" }}{{ .password }}{{ end }}`,
+		DestPath:      "my.tmpl",
+		ChangeMode:    structs.TemplateChangeModeNoop,
+		ErrMissingKey: true,
+	}
+
+	harness := newTestHarness(t, []*structs.Template{template}, false, false)
+	harness.start(t)
+	defer harness.stop()
+
+	// Wait for kill channel
+	select {
+	case <-harness.mockHooks.KillCh:
+	case <-time.After(time.Duration(5*testutil.TestMultiplier()) * time.Second):
+		t.Fatalf("Should have received a kill: %+v", harness.mockHooks)
+	}
+
+	if !strings.Contains(harness.mockHooks.KillReason, "password") {
+		t.Fatalf("Unexpected error: %v", harness.mockHooks.KillReason)
+	}
+}
+
 // TestTaskTemplateManager_Env asserts templates with the env flag set are read
 // into the task's environment.
 func TestTaskTemplateManager_Env(t *testing.T) {
@@ -1007,7 +1240,7 @@ func TestTaskTemplateManager_Env_Missing(t *testing.T) {
 
 // TestTaskTemplateManager_Env_Multi asserts the core env
 // template processing function returns combined env vars from multiple
-// templates correctly.
+// templates correctly and errors when they set the same var.
 func TestTaskTemplateManager_Env_Multi(t *testing.T) {
 	t.Parallel()
 	d, err := ioutil.TempDir("", "ct_env_missing")
@@ -1039,8 +1272,18 @@ func TestTaskTemplateManager_Env_Multi(t *testing.T) {
 	}
 
 	vars, err := loadTemplateEnv(templates, d)
+	if err == nil || !strings.Contains(err.Error(), `both set "SHARED"`) {
+		t.Fatalf("expected a conflict error but instead got env vars: %#v %v", vars, err)
+	}
+
+	// Selecting keys resolves the conflict
+	templates[0].EnvKeys = map[string]string{
+		"FOO":    "FOO",
+		"SHARED": "OTHER_SHARED",
+	}
+	vars, err = loadTemplateEnv(templates, d)
 	if err != nil {
-		t.Fatalf("expected an error but instead got env vars: %#v", vars)
+		t.Fatalf("expected env vars but instead got an error: %v", err)
 	}
 	if vars["FOO"] != "bar" {
 		t.Errorf("expected FOO=bar but found %q", vars["FOO"])
@@ -1049,7 +1292,130 @@ func TestTaskTemplateManager_Env_Multi(t *testing.T) {
 		t.Errorf("expected BAR=foo but found %q", vars["BAR"])
 	}
 	if vars["SHARED"] != "yup" {
-		t.Errorf("expected FOO=bar but found %q", vars["yup"])
+		t.Errorf("expected SHARED=yup but found %q", vars["SHARED"])
+	}
+	if vars["OTHER_SHARED"] != "nope" {
+		t.Errorf("expected OTHER_SHARED=nope but found %q", vars["OTHER_SHARED"])
+	}
+}
+
+// TestTaskTemplateManager_Env_Keys asserts only the selected keys of env
+// templates are loaded.
+func TestTaskTemplateManager_Env_Keys(t *testing.T) {
+	t.Parallel()
+	d, err := ioutil.TempDir("", "ct_env_keys")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(d)
+
+	err = ioutil.WriteFile(filepath.Join(d, "db.env"), []byte("USER=app\nPASSWORD=secret\nHOST=db\n"), 0644)
+	if err != nil {
+		t.Fatalf("error writing template file: %v", err)
+	}
+
+	templates := []*structs.Template{
+		{
+			DestPath: "db.env",
+			Envvars:  true,
+			EnvKeys: map[string]string{
+				"USER":     "PGUSER",
+				"PASSWORD": "PGPASSWORD",
+				"MISSING":  "PGMISSING",
+			},
+		},
+	}
+
+	vars, err := loadTemplateEnv(templates, d)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[string]string{
+		"PGUSER":     "app",
+		"PGPASSWORD": "secret",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Fatalf("expected %#v but found %#v", expected, vars)
+	}
+}
+
+// TestTaskTemplateManager_Secrets asserts the rendered contents of templates
+// rendered to the secrets directory or reading Vault secrets are redacted.
+func TestTaskTemplateManager_Secrets(t *testing.T) {
+	t.Parallel()
+	d, err := ioutil.TempDir("", "ct_secrets")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(d)
+
+	files := map[string]string{
+		"secrets/db.env":   "PASSWORD=hunter22\n",
+		"secrets/app.conf": "user = admin\npass = hunter33\n",
+		"local/vault":      "token = s3cr3t-t0k3n\n",
+		"local/public":     "listen = 0.0.0.0\n",
+		"local/var":        "v4r-s3cr3t",
+		"local/decl":       "id = app-1234\nkey = k3y-s3cr3t\n",
+		"local/mismatch":   "cert = -----BEGIN-----\nc3rt\n",
+	}
+	for path, contents := range files {
+		path = filepath.Join(d, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("error making dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("error writing template file: %v", err)
+		}
+	}
+
+	source := &structs.Template{
+		SourcePath: "local/var.tmpl",
+		DestPath:   "local/var",
+	}
+	templates := []*structs.Template{
+		{
+			DestPath: "secrets/db.env",
+			Envvars:  true,
+		},
+		{
+			// Every output of templates rendered to the secrets directory
+			// is secret, but not their text
+			EmbeddedTmpl: "user = {{ env \"USER\" }}\npass = {{ key \"pass\" }}\n",
+			DestPath:     "secrets/app.conf",
+		},
+		{
+			EmbeddedTmpl: "token = {{ with secret \"secret/app\" }}{{ .Data.token }}{{ end }}\n",
+			DestPath:     "local/vault",
+		},
+		{
+			EmbeddedTmpl: "listen = {{ env \"ADDR\" }}\n",
+			DestPath:     "local/public",
+		},
+		source,
+		{
+			// Variables hold secrets if they are assigned from a secret
+			EmbeddedTmpl: "{{ $s := secret \"secret/app\" }}id = {{ env \"ID\" }}\nkey = {{ $s.Data.key }}\n",
+			DestPath:     "local/decl",
+		},
+		{
+			// Contents that don't match the template are secret as a whole
+			EmbeddedTmpl: "cert: {{ with secret \"pki/cert\" }}{{ .Data.cert }}{{ end }}",
+			DestPath:     "local/mismatch",
+		},
+	}
+	contents := map[*structs.Template]string{
+		source: `{{ with nomadVar "app" }}{{ .key }}{{ end }}`,
+	}
+
+	secrets, err := loadTemplateSecrets(templates, d, contents)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sort.Strings(secrets)
+	expected := []string{"admin", "c3rt", "cert = -----BEGIN-----", "cert = -----BEGIN-----\nc3rt",
+		"hunter22", "hunter33", "k3y-s3cr3t", "s3cr3t-t0k3n", "v4r-s3cr3t"}
+	if !reflect.DeepEqual(secrets, expected) {
+		t.Fatalf("expected %#v but found %#v", expected, secrets)
 	}
 }
 
@@ -1110,3 +1476,65 @@ func TestTaskTemplateManager_Config_VaultGrace(t *testing.T) {
 	assert.NotNil(ctconf.Vault.Grace, "Vault Grace Pointer")
 	assert.Equal(10*time.Second, *ctconf.Vault.Grace, "Vault Grace Value")
 }
+
+// TestTaskTemplateManager_Config_Wait asserts the wait of templates and the
+// client's default wait are propogated to consul-template's configuration.
+func TestTaskTemplateManager_Config_Wait(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	c := config.DefaultConfig()
+	c.Options = map[string]string{
+		waitMinOption: "2s",
+		waitMaxOption: "20s",
+	}
+
+	templates := []*structs.Template{
+		{
+			EmbeddedTmpl: "bar",
+			DestPath:     "foo",
+			ChangeMode:   structs.TemplateChangeModeNoop,
+			Wait: &structs.WaitConfig{
+				Min: 5 * time.Second,
+				Max: 10 * time.Second,
+			},
+		},
+	}
+
+	taskEnv := env.NewTaskEnv(nil, nil)
+	ctmplMapping, err := parseTemplateConfigs(templates, "/fake/dir", taskEnv, false)
+	assert.Nil(err, "Parsing Templates")
+
+	ctconf, err := newRunnerConfig(c, "token", ctmplMapping)
+	assert.Nil(err, "Building Runner Config")
+
+	// The client's default wait
+	assert.True(*ctconf.Wait.Enabled, "Default Wait Enabled")
+	assert.Equal(2*time.Second, *ctconf.Wait.Min, "Default Wait Min")
+	assert.Equal(20*time.Second, *ctconf.Wait.Max, "Default Wait Max")
+
+	// The template's wait
+	tmplWait := (*ctconf.Templates)[0].Wait
+	assert.True(*tmplWait.Enabled, "Template Wait Enabled")
+	assert.Equal(5*time.Second, *tmplWait.Min, "Template Wait Min")
+	assert.Equal(10*time.Second, *tmplWait.Max, "Template Wait Max")
+}
+
+// TestTaskTemplateManager_Config_Retry asserts the client's retry options are
+// propogated to consul-template's configuration.
+func TestTaskTemplateManager_Config_Retry(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	c := config.DefaultConfig()
+	c.Options = map[string]string{
+		consulRetryOption + ".attempts":    "0",
+		consulRetryOption + ".max_backoff": "30s",
+		vaultRetryOption + ".backoff":      "2s",
+	}
+
+	ctconf, err := newRunnerConfig(c, "token", nil)
+	assert.Nil(err, "Building Runner Config")
+
+	assert.Equal(0, *ctconf.Consul.Retry.Attempts, "Consul Retry Attempts")
+	assert.Equal(30*time.Second, *ctconf.Consul.Retry.MaxBackoff, "Consul Retry Max Backoff")
+	assert.Equal(2*time.Second, *ctconf.Vault.Retry.Backoff, "Vault Retry Backoff")
+}
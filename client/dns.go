@@ -0,0 +1,327 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/miekg/dns"
+)
+
+const (
+	// dnsServiceLabel is the label separating the service, and optionally
+	// its tag, from the datacenter in the queries of the services:
+	// [<tag>.]<service>.service[.<datacenter>].<domain>
+	dnsServiceLabel = "service"
+
+	// dnsAddrLabel is the label of the names of the addresses targeted by
+	// the SRV records: <hex encoded ip>.addr.<domain>
+	dnsAddrLabel = "addr"
+
+	// dnsRecursorTimeout is the time a recursor has to answer a forwarded
+	// query before the next one is tried.
+	dnsRecursorTimeout = 2 * time.Second
+)
+
+// dnsServiceLookup returns the passing instances of a service.
+type dnsServiceLookup func(name string) ([]*structs.ServiceRegistration, error)
+
+// dnsServer answers the DNS queries for the services registered with Nomad
+// and forwards the other queries to the recursors.
+type dnsServer struct {
+	config *config.DNSConfig
+	domain string
+	lookup dnsServiceLookup
+	logger *log.Logger
+
+	servers    []*dns.Server
+	addr       string
+	shutdownCh chan struct{}
+}
+
+// newDNSServer returns a DNS server answering for the domain of the
+// configuration with the services returned by lookup.
+func newDNSServer(cfg *config.DNSConfig, lookup dnsServiceLookup, logger *log.Logger) *dnsServer {
+	domain := cfg.Domain
+	if domain == "" {
+		domain = config.DefaultDNSDomain
+	}
+	return &dnsServer{
+		config:     cfg,
+		domain:     strings.ToLower(dns.Fqdn(domain)),
+		lookup:     lookup,
+		logger:     logger,
+		shutdownCh: make(chan struct{}),
+	}
+}
+
+// Start listens on the address of the configuration over UDP and TCP and
+// serves the queries in the background.
+func (d *dnsServer) Start() error {
+	mux := dns.NewServeMux()
+	mux.HandleFunc(d.domain, d.handleQuery)
+	mux.HandleFunc(".", d.handleRecurse)
+
+	addr := d.config.Addr
+	if addr == "" {
+		addr = config.DefaultDNSAddr
+	}
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %q over UDP: %v", addr, err)
+	}
+
+	// Listen on the UDP port over TCP in case it was picked by the system
+	d.addr = pc.LocalAddr().String()
+	l, err := net.Listen("tcp", d.addr)
+	if err != nil {
+		pc.Close()
+		return fmt.Errorf("failed to listen on %q over TCP: %v", d.addr, err)
+	}
+
+	d.servers = []*dns.Server{
+		{PacketConn: pc, Handler: mux},
+		{Listener: l, Handler: mux},
+	}
+	for _, srv := range d.servers {
+		started := make(chan struct{})
+		srv.NotifyStartedFunc = func() { close(started) }
+		go func(srv *dns.Server) {
+			err := srv.ActivateAndServe()
+			select {
+			case <-d.shutdownCh:
+			default:
+				d.logger.Printf("[ERR] client.dns: failed to serve: %v", err)
+			}
+		}(srv)
+		<-started
+	}
+
+	d.logger.Printf("[INFO] client.dns: serving %q on %s", d.domain, d.addr)
+	return nil
+}
+
+// Addr returns the address the server listens on.
+func (d *dnsServer) Addr() string {
+	return d.addr
+}
+
+// Shutdown stops serving the queries.
+func (d *dnsServer) Shutdown() {
+	close(d.shutdownCh)
+	for _, srv := range d.servers {
+		if err := srv.Shutdown(); err != nil {
+			d.logger.Printf("[WARN] client.dns: failed to shutdown: %v", err)
+		}
+	}
+}
+
+// handleQuery answers the queries in the domain of the server.
+func (d *dnsServer) handleQuery(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Authoritative = true
+	resp.RecursionAvailable = len(d.config.Recursors) != 0
+
+	q := req.Question[0]
+	name := strings.TrimSuffix(strings.ToLower(q.Name), d.domain)
+	labels := dns.SplitDomainName(name)
+
+	switch n := len(labels); {
+	case n == 2 && labels[1] == dnsAddrLabel:
+		ip := decodeDNSAddr(labels[0])
+		if ip == nil {
+			resp.SetRcode(req, dns.RcodeNameError)
+			break
+		}
+		if rr := d.addrRecord(q.Name, q.Qtype, ip); rr != nil {
+			resp.Answer = append(resp.Answer, rr)
+		}
+
+	case n >= 2 && n <= 4:
+		if err := d.serviceAnswer(resp, req, labels); err != nil {
+			d.logger.Printf("[ERR] client.dns: failed to answer %q: %v", q.Name, err)
+			resp.SetRcode(req, dns.RcodeServerFailure)
+		}
+
+	default:
+		resp.SetRcode(req, dns.RcodeNameError)
+	}
+
+	if err := w.WriteMsg(resp); err != nil {
+		d.logger.Printf("[WARN] client.dns: failed to respond to %q: %v", q.Name, err)
+	}
+}
+
+// serviceAnswer adds the records of the instances of the service queried by
+// labels to the response.
+func (d *dnsServer) serviceAnswer(resp, req *dns.Msg, labels []string) error {
+	var tag, service, datacenter string
+	switch {
+	case labels[len(labels)-1] == dnsServiceLabel && len(labels) == 2:
+		service = labels[0]
+	case labels[len(labels)-1] == dnsServiceLabel && len(labels) == 3:
+		tag, service = labels[0], labels[1]
+	case labels[len(labels)-2] == dnsServiceLabel && len(labels) == 3:
+		service, datacenter = labels[0], labels[2]
+	case labels[len(labels)-2] == dnsServiceLabel && len(labels) == 4:
+		tag, service, datacenter = labels[0], labels[1], labels[3]
+	default:
+		resp.SetRcode(req, dns.RcodeNameError)
+		return nil
+	}
+
+	regs, err := d.lookup(service)
+	if err != nil {
+		return err
+	}
+
+	// Filter the instances and shuffle them to spread the load
+	var instances []*structs.ServiceRegistration
+	for _, i := range rand.Perm(len(regs)) {
+		reg := regs[i]
+		if datacenter != "" && !strings.EqualFold(reg.Datacenter, datacenter) {
+			continue
+		}
+		if tag != "" && !hasTagFold(reg.Tags, tag) {
+			continue
+		}
+		instances = append(instances, reg)
+	}
+	if len(instances) == 0 {
+		resp.SetRcode(req, dns.RcodeNameError)
+		return nil
+	}
+
+	q := req.Question[0]
+	seen := make(map[string]struct{})
+	for _, reg := range instances {
+		for _, addr := range []string{reg.Address, reg.AddressIPv6} {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				continue
+			}
+
+			if q.Qtype == dns.TypeSRV {
+				target := encodeDNSAddr(ip) + "." + dnsAddrLabel + "." + d.domain
+				resp.Answer = append(resp.Answer, &dns.SRV{
+					Hdr:      d.header(q.Name, dns.TypeSRV),
+					Priority: 1,
+					Weight:   1,
+					Port:     uint16(reg.Port),
+					Target:   target,
+				})
+				if _, ok := seen[target]; !ok {
+					seen[target] = struct{}{}
+					resp.Extra = append(resp.Extra, d.addrRecord(target, dns.TypeANY, ip))
+				}
+				continue
+			}
+
+			if _, ok := seen[ip.String()]; ok {
+				continue
+			}
+			seen[ip.String()] = struct{}{}
+			if rr := d.addrRecord(q.Name, q.Qtype, ip); rr != nil {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+	}
+	return nil
+}
+
+// addrRecord returns the A or AAAA record of the address, or nil if the
+// query type does not match the family of the address.
+func (d *dnsServer) addrRecord(name string, qtype uint16, ip net.IP) dns.RR {
+	if ip4 := ip.To4(); ip4 != nil {
+		if qtype != dns.TypeA && qtype != dns.TypeANY {
+			return nil
+		}
+		return &dns.A{Hdr: d.header(name, dns.TypeA), A: ip4}
+	}
+	if qtype != dns.TypeAAAA && qtype != dns.TypeANY {
+		return nil
+	}
+	return &dns.AAAA{Hdr: d.header(name, dns.TypeAAAA), AAAA: ip}
+}
+
+// header returns the header of an answer record.
+func (d *dnsServer) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{
+		Name:   name,
+		Rrtype: rrtype,
+		Class:  dns.ClassINET,
+		Ttl:    uint32(d.config.TTL / time.Second),
+	}
+}
+
+// handleRecurse forwards the queries outside of the domain of the server to
+// the recursors, in order, until one answers.
+func (d *dnsServer) handleRecurse(w dns.ResponseWriter, req *dns.Msg) {
+	network := "udp"
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		network = "tcp"
+	}
+	c := &dns.Client{
+		Net:          network,
+		DialTimeout:  dnsRecursorTimeout,
+		ReadTimeout:  dnsRecursorTimeout,
+		WriteTimeout: dnsRecursorTimeout,
+	}
+
+	for _, recursor := range d.config.Recursors {
+		resp, _, err := c.Exchange(req, recursor)
+		if err != nil {
+			d.logger.Printf("[WARN] client.dns: recursor %q failed to answer: %v", recursor, err)
+			continue
+		}
+		resp.Compress = true
+		if err := w.WriteMsg(resp); err != nil {
+			d.logger.Printf("[WARN] client.dns: failed to forward the answer of recursor %q: %v", recursor, err)
+		}
+		return
+	}
+
+	resp := new(dns.Msg)
+	resp.SetRcode(req, dns.RcodeRefused)
+	if len(d.config.Recursors) != 0 {
+		resp.SetRcode(req, dns.RcodeServerFailure)
+	}
+	if err := w.WriteMsg(resp); err != nil {
+		d.logger.Printf("[WARN] client.dns: failed to respond: %v", err)
+	}
+}
+
+// encodeDNSAddr encodes the IP as a DNS label.
+func encodeDNSAddr(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return hex.EncodeToString(ip4)
+	}
+	return hex.EncodeToString(ip.To16())
+}
+
+// decodeDNSAddr decodes the IP of a label encoded by encodeDNSAddr, or
+// returns nil if it is invalid.
+func decodeDNSAddr(label string) net.IP {
+	b, err := hex.DecodeString(label)
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil
+	}
+	return net.IP(b)
+}
+
+// hasTagFold returns whether the tags contain the tag, ignoring case.
+func hasTagFold(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
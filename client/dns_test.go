@@ -0,0 +1,162 @@
+package client
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/miekg/dns"
+)
+
+// testDNSServer starts a DNS server answering with the registrations.
+func testDNSServer(t *testing.T, cfg *config.DNSConfig, regs []*structs.ServiceRegistration) *dnsServer {
+	lookup := func(name string) ([]*structs.ServiceRegistration, error) {
+		var out []*structs.ServiceRegistration
+		for _, reg := range regs {
+			if reg.ServiceName == name {
+				out = append(out, reg)
+			}
+		}
+		return out, nil
+	}
+	cfg.Addr = "127.0.0.1:0"
+	d := newDNSServer(cfg, lookup, testLogger())
+	if err := d.Start(); err != nil {
+		t.Fatalf("failed to start DNS server: %v", err)
+	}
+	return d
+}
+
+// testDNSQuery sends the query to the server over UDP.
+func testDNSQuery(t *testing.T, addr, name string, qtype uint16) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	c := &dns.Client{ReadTimeout: 5 * time.Second}
+	resp, _, err := c.Exchange(m, addr)
+	if err != nil {
+		t.Fatalf("failed to query %q: %v", name, err)
+	}
+	return resp
+}
+
+// answerAddrs returns the sorted addresses of the A and AAAA records.
+func answerAddrs(rrs []dns.RR) []string {
+	var addrs []string
+	for _, rr := range rrs {
+		switch r := rr.(type) {
+		case *dns.A:
+			addrs = append(addrs, r.A.String())
+		case *dns.AAAA:
+			addrs = append(addrs, r.AAAA.String())
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+func TestDNSServer_Services(t *testing.T) {
+	regs := []*structs.ServiceRegistration{
+		{ServiceName: "web", Datacenter: "dc1", Tags: []string{"v1"}, Address: "10.0.0.1", Port: 8080},
+		{ServiceName: "web", Datacenter: "dc1", Tags: []string{"v2"}, Address: "10.0.0.2", Port: 8080,
+			AddressIPv6: "fd00::2"},
+		{ServiceName: "web", Datacenter: "dc2", Tags: []string{"v1"}, Address: "10.0.1.1", Port: 9090},
+	}
+	d := testDNSServer(t, &config.DNSConfig{Domain: "nomad", TTL: 30 * time.Second}, regs)
+	defer d.Shutdown()
+
+	cases := []struct {
+		name  string
+		qtype uint16
+		addrs []string
+	}{
+		{"web.service.nomad.", dns.TypeA, []string{"10.0.0.1", "10.0.0.2", "10.0.1.1"}},
+		{"WEB.service.nomad.", dns.TypeA, []string{"10.0.0.1", "10.0.0.2", "10.0.1.1"}},
+		{"v1.web.service.nomad.", dns.TypeA, []string{"10.0.0.1", "10.0.1.1"}},
+		{"web.service.dc2.nomad.", dns.TypeA, []string{"10.0.1.1"}},
+		{"v1.web.service.dc1.nomad.", dns.TypeA, []string{"10.0.0.1"}},
+		{"web.service.nomad.", dns.TypeAAAA, []string{"fd00::2"}},
+		{"v2.web.service.nomad.", dns.TypeANY, []string{"10.0.0.2", "fd00::2"}},
+	}
+	for _, c := range cases {
+		resp := testDNSQuery(t, d.Addr(), c.name, c.qtype)
+		if resp.Rcode != dns.RcodeSuccess || !resp.Authoritative {
+			t.Fatalf("%s: bad response: %v", c.name, resp)
+		}
+		if addrs := answerAddrs(resp.Answer); !reflect.DeepEqual(addrs, c.addrs) {
+			t.Fatalf("%s: expected %v; got %v", c.name, c.addrs, addrs)
+		}
+		if ttl := resp.Answer[0].Header().Ttl; ttl != 30 {
+			t.Fatalf("%s: expected a TTL of 30; got %d", c.name, ttl)
+		}
+	}
+
+	// SRV records target the addresses of the instances
+	resp := testDNSQuery(t, d.Addr(), "web.service.dc2.nomad.", dns.TypeSRV)
+	if len(resp.Answer) != 1 {
+		t.Fatalf("expected 1 SRV record; got %v", resp.Answer)
+	}
+	srv := resp.Answer[0].(*dns.SRV)
+	if srv.Port != 9090 || srv.Target != "0a000101.addr.nomad." {
+		t.Fatalf("bad SRV record: %v", srv)
+	}
+	if addrs := answerAddrs(resp.Extra); !reflect.DeepEqual(addrs, []string{"10.0.1.1"}) {
+		t.Fatalf("bad SRV extra records: %v", resp.Extra)
+	}
+	resp = testDNSQuery(t, d.Addr(), srv.Target, dns.TypeA)
+	if addrs := answerAddrs(resp.Answer); !reflect.DeepEqual(addrs, []string{"10.0.1.1"}) {
+		t.Fatalf("bad addr answer: %v", resp.Answer)
+	}
+
+	// Unknown services and names don't exist
+	for _, name := range []string{"db.service.nomad.", "v3.web.service.nomad.", "web.nomad.", "zz.addr.nomad."} {
+		resp := testDNSQuery(t, d.Addr(), name, dns.TypeA)
+		if resp.Rcode != dns.RcodeNameError {
+			t.Fatalf("%s: expected NXDOMAIN; got %v", name, resp)
+		}
+	}
+}
+
+func TestDNSServer_Recurse(t *testing.T) {
+	// Start a recursor answering every query with the same address
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	started := make(chan struct{})
+	recursor := &dns.Server{
+		PacketConn:        pc,
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetReply(req)
+			resp.Answer = append(resp.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET},
+				A:   net.ParseIP("192.0.2.1"),
+			})
+			w.WriteMsg(resp)
+		}),
+	}
+	go recursor.ActivateAndServe()
+	<-started
+	defer recursor.Shutdown()
+
+	d := testDNSServer(t, &config.DNSConfig{Recursors: []string{pc.LocalAddr().String()}}, nil)
+	defer d.Shutdown()
+
+	resp := testDNSQuery(t, d.Addr(), "example.com.", dns.TypeA)
+	if addrs := answerAddrs(resp.Answer); resp.Rcode != dns.RcodeSuccess || !reflect.DeepEqual(addrs, []string{"192.0.2.1"}) {
+		t.Fatalf("bad forwarded response: %v", resp)
+	}
+
+	// Queries are refused without recursors
+	d2 := testDNSServer(t, &config.DNSConfig{}, nil)
+	defer d2.Shutdown()
+	resp = testDNSQuery(t, d2.Addr(), "example.com.", dns.TypeA)
+	if resp.Rcode != dns.RcodeRefused {
+		t.Fatalf("expected a refused query; got %v", resp)
+	}
+}
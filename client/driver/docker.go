@@ -328,6 +328,13 @@ func (d *DockerDriver) Fingerprint(cfg *config.Config, node *structs.Node) (bool
 		node.Attributes[dockerPrivilegedConfigOption] = "1"
 	}
 
+	// Advertise if this node verifies the images of every job
+	if d.verifiesAllNamespaces() {
+		node.Attributes[dockerVerifyAttr] = "1"
+	} else {
+		delete(node.Attributes, dockerVerifyAttr)
+	}
+
 	// Advertise if this node supports Docker volumes
 	if d.config.ReadBoolDefault(dockerVolumesConfigOption, dockerVolumesConfigDefault) {
 		node.Attributes["driver."+dockerVolumesConfigOption] = "1"
@@ -470,8 +477,9 @@ func (d *DockerDriver) Validate(config map[string]interface{}) error {
 
 func (d *DockerDriver) Abilities() DriverAbilities {
 	return DriverAbilities{
-		SendSignals: true,
-		Exec:        true,
+		SendSignals:      true,
+		Exec:             true,
+		NetworkNamespace: false,
 	}
 }
 
@@ -507,11 +515,22 @@ func (d *DockerDriver) Prestart(ctx *ExecContext, task *structs.Task) (*Prestart
 		return nil, fmt.Errorf("Failed to connect to docker daemon: %s", err)
 	}
 
+	// Verify the signature of the image if required
+	digest, err := d.verifyImageSignature(driverConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	// Ensure the image is available
 	id, err := d.createImage(driverConfig, client, ctx.TaskDir)
 	if err != nil {
 		return nil, err
 	}
+	if digest != "" {
+		if id, err = d.ensureVerifiedImage(client, driverConfig, id, digest); err != nil {
+			return nil, err
+		}
+	}
 	d.imageID = id
 
 	resp := NewPrestartResponse()
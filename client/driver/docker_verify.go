@@ -0,0 +1,245 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// dockerVerifyKeysConfigOption is the key for the comma separated list
+	// of cosign public keys images must be signed with. Images are not
+	// verified if unset.
+	dockerVerifyKeysConfigOption = "docker.verify.keys"
+
+	// dockerVerifyExemptConfigOption is the key for the comma separated list
+	// of images exempt from signature verification. Entries ending with a
+	// "*" match the images starting with the rest of the entry at a path,
+	// tag or digest boundary.
+	dockerVerifyExemptConfigOption = "docker.verify.exempt_images"
+
+	// dockerVerifyExemptNamespacesConfigOption is the key for the comma
+	// separated list of namespaces whose jobs may run unverified images.
+	dockerVerifyExemptNamespacesConfigOption = "docker.verify.exempt_namespaces"
+
+	// dockerVerifyAttr is the node attribute set when the images of the jobs
+	// of every namespace are verified, which the servers constrain jobs to
+	// when image verification is required.
+	dockerVerifyAttr = "driver.docker.verify"
+
+	// dockerVerifyCosignConfigOption is the key for the path of the cosign
+	// binary, looked up in the $PATH by default.
+	dockerVerifyCosignConfigOption  = "docker.verify.cosign"
+	dockerVerifyCosignConfigDefault = "cosign"
+
+	// dockerVerifyTimeout is the time the verification of an image with a
+	// key can take.
+	dockerVerifyTimeout = 2 * time.Minute
+)
+
+// cosignPayload is the payload of a verified cosign signature
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			Digest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// imageVerification returns whether the image of a job in the namespace must
+// be verified and the keys to verify it with.
+func (d *DockerDriver) imageVerification(namespace, image string) (bool, []string) {
+	keys := d.configList(dockerVerifyKeysConfigOption)
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	for _, exempt := range d.configList(dockerVerifyExemptNamespacesConfigOption) {
+		if namespace == exempt {
+			return false, nil
+		}
+	}
+	for _, exempt := range d.configList(dockerVerifyExemptConfigOption) {
+		if dockerImageMatches(image, exempt) {
+			return false, nil
+		}
+	}
+	return true, keys
+}
+
+// verifiesAllNamespaces returns whether the images of the jobs of every
+// namespace are verified.
+func (d *DockerDriver) verifiesAllNamespaces() bool {
+	return len(d.configList(dockerVerifyKeysConfigOption)) != 0 &&
+		len(d.configList(dockerVerifyExemptNamespacesConfigOption)) == 0
+}
+
+// configList returns the non-empty entries of the comma separated list of
+// the client option.
+func (d *DockerDriver) configList(option string) []string {
+	var list []string
+	for _, entry := range strings.Split(d.config.Read(option), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// dockerImageMatches returns whether the image matches the exemption. A
+// wildcard exemption only matches at a boundary of the image name, so that
+// "registry.internal/base*" doesn't match "registry.internal/base-evil".
+func dockerImageMatches(image, exempt string) bool {
+	switch {
+	case exempt == "":
+		return false
+	case strings.HasSuffix(exempt, "*"):
+		prefix := strings.TrimSuffix(exempt, "*")
+		if !strings.HasPrefix(image, prefix) {
+			return false
+		}
+		if prefix == "" || image == prefix || strings.ContainsAny(prefix[len(prefix)-1:], "/:@") {
+			return true
+		}
+		return strings.ContainsAny(image[len(prefix):len(prefix)+1], "/:@")
+	default:
+		return image == exempt
+	}
+}
+
+// verifyImageSignature verifies the signature of the image in its registry
+// with cosign before it is pulled, and returns the digest of the verified
+// manifest. An empty digest is returned if the image doesn't need to be
+// verified.
+func (d *DockerDriver) verifyImageSignature(driverConfig *DockerDriverConfig) (string, error) {
+	image := driverConfig.ImageName
+	required, keys := d.imageVerification(d.DriverContext.namespace, image)
+	if !required {
+		return "", nil
+	}
+
+	// Loaded images aren't in a registry to read their signatures from
+	if driverConfig.LoadImage != "" {
+		return "", structs.NewRecoverableError(
+			fmt.Errorf("image %q failed signature verification: images loaded from archives can't be verified", image), false)
+	}
+
+	d.emitEvent("Verifying signature of image %s", image)
+	cosign := d.config.ReadDefault(dockerVerifyCosignConfigOption, dockerVerifyCosignConfigDefault)
+	var failures []string
+	for _, key := range keys {
+		digest, err := cosignVerify(cosign, key, image)
+		if err == nil {
+			d.logger.Printf("[DEBUG] driver.docker: image %q verified with key %q: %s", image, key, digest)
+			return digest, nil
+		}
+		failures = append(failures, fmt.Sprintf("key %q: %v", key, err))
+	}
+
+	// Verification failures aren't recoverable since the image won't be
+	// signed by retrying
+	return "", structs.NewRecoverableError(
+		fmt.Errorf("image %q failed signature verification: %s", image, strings.Join(failures, "; ")), false)
+}
+
+// cosignVerify verifies the signatures of the image with the key and returns
+// the digest of the verified manifest.
+func cosignVerify(cosign, key, image string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerVerifyTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cosign, "verify", "--key", key, image)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return parseCosignDigest(stdout.Bytes())
+}
+
+// parseCosignDigest returns the manifest digest of the signatures verified by
+// cosign. The signatures of an image must all be for the same manifest.
+func parseCosignDigest(out []byte) (string, error) {
+	var payloads []cosignPayload
+	if err := json.Unmarshal(out, &payloads); err != nil {
+		return "", fmt.Errorf("failed to decode verified signatures: %v", err)
+	}
+
+	digest := ""
+	for _, p := range payloads {
+		d := p.Critical.Image.Digest
+		if d == "" || (digest != "" && d != digest) {
+			return "", fmt.Errorf("verified signatures have inconsistent digests")
+		}
+		digest = d
+	}
+	if digest == "" {
+		return "", fmt.Errorf("no verified signatures")
+	}
+	return digest, nil
+}
+
+// ensureVerifiedImage ensures the image used by the task is the verified
+// manifest, so that the tag of the image can't be moved between its
+// verification and the pull, and returns its ID. A cached image of an older
+// manifest of the tag is replaced by pulling the tag again.
+func (d *DockerDriver) ensureVerifiedImage(client *docker.Client, driverConfig *DockerDriverConfig, id, digest string) (string, error) {
+	coordinator, callerID := d.getDockerCoordinator(client)
+	match, err := imageMatchesDigest(client, id, digest)
+	if err != nil {
+		coordinator.RemoveImage(id, callerID)
+		return "", err
+	}
+	if match {
+		return id, nil
+	}
+
+	image := driverConfig.ImageName
+	d.logger.Printf("[DEBUG] driver.docker: image %q (%s) doesn't match the signed digest %s, pulling it again", image, id, digest)
+	repo, tag := docker.ParseRepositoryTag(image)
+	if tag == "" {
+		tag = "latest"
+	}
+	pulledID, err := d.pullImage(driverConfig, client, repo, tag)
+	if pulledID != id {
+		coordinator.RemoveImage(id, callerID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if match, err = imageMatchesDigest(client, pulledID, digest); err != nil || !match {
+		coordinator.RemoveImage(pulledID, callerID)
+		if err != nil {
+			return "", err
+		}
+		return "", structs.NewRecoverableError(
+			fmt.Errorf("image %q failed signature verification: image doesn't match the signed digest %s", image, digest), false)
+	}
+	return pulledID, nil
+}
+
+// imageMatchesDigest returns whether the image has the manifest digest.
+func imageMatchesDigest(client *docker.Client, id, digest string) (bool, error) {
+	dockerImage, err := client.InspectImage(id)
+	if err != nil {
+		return false, recoverableErrTimeouts(fmt.Errorf("Failed to inspect image %q: %v", id, err))
+	}
+	for _, repoDigest := range dockerImage.RepoDigests {
+		if strings.HasSuffix(repoDigest, "@"+digest) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,148 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestDockerDriver_VerifyImageSignature(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Test uses a shell script")
+	}
+	t.Parallel()
+
+	// The fake cosign only verifies images signed with good.pub
+	dir, err := ioutil.TempDir("", "nomad-cosign")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	cosign := filepath.Join(dir, "cosign")
+	script := `#!/bin/sh
+if [ "$3" = "good.pub" ]; then
+  echo '[{"critical":{"image":{"docker-manifest-digest":"sha256:abc"}}}]'
+  exit 0
+fi
+echo "no matching signatures" >&2
+exit 1
+`
+	if err := ioutil.WriteFile(cosign, []byte(script), 0755); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	conf := testConfig()
+	conf.Options = map[string]string{
+		dockerVerifyCosignConfigOption: cosign,
+		dockerVerifyKeysConfigOption:   "bad.pub, good.pub",
+		dockerVerifyExemptConfigOption: "busybox, registry.internal/base/*",
+	}
+	var events []string
+	d := &DockerDriver{DriverContext: DriverContext{
+		namespace: "default",
+		config:    conf,
+		logger:    testLogger(),
+		emitEvent: func(m string, args ...interface{}) { events = append(events, m) },
+	}}
+
+	digest, err := d.verifyImageSignature(&DockerDriverConfig{ImageName: "redis:3.2"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if digest != "sha256:abc" {
+		t.Fatalf("unexpected digest %q", digest)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected verification event; got %v", events)
+	}
+
+	// Exempt images aren't verified
+	for _, image := range []string{"busybox", "registry.internal/base/alpine:3.6"} {
+		digest, err := d.verifyImageSignature(&DockerDriverConfig{ImageName: image})
+		if err != nil || digest != "" {
+			t.Fatalf("%s: expected image to be exempt; got %q %v", image, digest, err)
+		}
+	}
+
+	// The images of exempt namespaces aren't verified
+	if !d.verifiesAllNamespaces() {
+		t.Fatalf("expected every namespace to be verified")
+	}
+	conf.Options[dockerVerifyExemptNamespacesConfigOption] = "dev, default"
+	if digest, err := d.verifyImageSignature(&DockerDriverConfig{ImageName: "redis:3.2"}); err != nil || digest != "" {
+		t.Fatalf("expected namespace to be exempt; got %q %v", digest, err)
+	}
+	if d.verifiesAllNamespaces() {
+		t.Fatalf("expected exempt namespaces")
+	}
+	delete(conf.Options, dockerVerifyExemptNamespacesConfigOption)
+
+	// Images without a trusted signature are rejected
+	conf.Options[dockerVerifyKeysConfigOption] = "bad.pub"
+	_, err = d.verifyImageSignature(&DockerDriverConfig{ImageName: "redis:3.2"})
+	if err == nil || !strings.Contains(err.Error(), "no matching signatures") {
+		t.Fatalf("expected verification failure; got %v", err)
+	}
+	if rerr, ok := err.(*structs.RecoverableError); !ok || rerr.IsRecoverable() {
+		t.Fatalf("expected unrecoverable error; got %#v", err)
+	}
+
+	// Loaded images can't be verified
+	_, err = d.verifyImageSignature(&DockerDriverConfig{ImageName: "redis:3.2", LoadImage: "redis.tar"})
+	if err == nil || !strings.Contains(err.Error(), "archives") {
+		t.Fatalf("expected verification failure; got %v", err)
+	}
+}
+
+func TestDockerImageMatches(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		Image   string
+		Exempt  string
+		Matches bool
+	}{
+		{"busybox", "busybox", true},
+		{"busybox:1.27", "busybox", false},
+		{"busybox:1.27", "busybox*", true},
+		{"busybox@sha256:abc", "busybox*", true},
+		{"busybox", "busybox*", true},
+		{"busyboxes", "busybox*", false},
+		{"registry.internal/base/alpine", "registry.internal/base*", true},
+		{"registry.internal/base-evil/alpine", "registry.internal/base*", false},
+		{"registry.internal/base/alpine", "registry.internal/base/*", true},
+		{"registry.internal/basement", "registry.internal/base/*", false},
+		{"redis:3.2", "redis:*", true},
+		{"redis", "*", true},
+		{"redis", "", false},
+	}
+
+	for _, c := range cases {
+		if m := dockerImageMatches(c.Image, c.Exempt); m != c.Matches {
+			t.Errorf("%q matching %q: got %v; want %v", c.Image, c.Exempt, m, c.Matches)
+		}
+	}
+}
+
+func TestParseCosignDigest(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		out    string
+		digest string
+	}{
+		{`[{"critical":{"image":{"docker-manifest-digest":"sha256:abc"}}}]`, "sha256:abc"},
+		{`[{"critical":{"image":{"docker-manifest-digest":"sha256:abc"}}},{"critical":{"image":{"docker-manifest-digest":"sha256:def"}}}]`, ""},
+		{`[]`, ""},
+		{`not json`, ""},
+	}
+	for _, c := range cases {
+		digest, err := parseCosignDigest([]byte(c.out))
+		if digest != c.digest || (c.digest == "") != (err != nil) {
+			t.Fatalf("%s: unexpected digest %q and error %v", c.out, digest, err)
+		}
+	}
+}
@@ -250,6 +250,10 @@ type DriverAbilities struct {
 	// Exec marks the driver as being able to execute arbitrary commands
 	// such as health checks. Used by the ScriptExecutor interface.
 	Exec bool
+
+	// NetworkNamespace marks the driver as being able to run tasks in the
+	// network namespace of the network of their group.
+	NetworkNamespace bool
 }
 
 // LogEventFn is a callback which allows Drivers to emit task events.
@@ -259,11 +263,12 @@ type LogEventFn func(message string, args ...interface{})
 // node attributes into a Driver without having to change the Driver interface
 // each time we do it. Used in conjection with Factory, above.
 type DriverContext struct {
-	taskName string
-	allocID  string
-	config   *config.Config
-	logger   *log.Logger
-	node     *structs.Node
+	taskName  string
+	allocID   string
+	namespace string
+	config    *config.Config
+	logger    *log.Logger
+	node      *structs.Node
 
 	emitEvent LogEventFn
 }
@@ -278,11 +283,12 @@ func NewEmptyDriverContext() *DriverContext {
 // This enables other packages to create DriverContexts but keeps the fields
 // private to the driver. If we want to change this later we can gorename all of
 // the fields in DriverContext.
-func NewDriverContext(taskName, allocID string, config *config.Config, node *structs.Node,
+func NewDriverContext(taskName, allocID, namespace string, config *config.Config, node *structs.Node,
 	logger *log.Logger, eventEmitter LogEventFn) *DriverContext {
 	return &DriverContext{
 		taskName:  taskName,
 		allocID:   allocID,
+		namespace: namespace,
 		config:    config,
 		node:      node,
 		logger:    logger,
@@ -330,6 +336,10 @@ type ExecContext struct {
 
 	// TaskEnv contains the task's environment variables.
 	TaskEnv *env.TaskEnv
+
+	// NetworkNamespace is the path of the network namespace of the network
+	// of the task's group, if it has one.
+	NetworkNamespace string
 }
 
 // NewExecContext is used to create a new execution context
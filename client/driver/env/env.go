@@ -70,6 +70,15 @@ const (
 	// to a task.
 	IpPrefix = "NOMAD_IP_"
 
+	// Ipv6Prefix is the prefix for passing the host IPv6 address of a port
+	// allocation to a task, when the host has one.
+	Ipv6Prefix = "NOMAD_IPV6_"
+
+	// AddrIpv6Prefix is the prefix for passing the host IPv6 address and
+	// port of a port allocation to a task, when the host has one.
+	// E.g $NOMAD_ADDRV6_http=[2001:db8::1]:80
+	AddrIpv6Prefix = "NOMAD_ADDRV6_"
+
 	// PortPrefix is the prefix for passing the port allocation to a task.
 	// It will be the task's port if a port map is specified. Task's should
 	// bind to this port.
@@ -84,6 +93,14 @@ const (
 
 	// VaultToken is the environment variable for passing the Vault token
 	VaultToken = "VAULT_TOKEN"
+
+	// VaultNamespace is the environment variable for passing the Vault
+	// namespace the token was created in
+	VaultNamespace = "VAULT_NAMESPACE"
+
+	// WorkloadToken is the environment variable for passing the workload
+	// identity the task calls the Nomad API with
+	WorkloadToken = "NOMAD_TOKEN"
 )
 
 // The node values that can be interpreted.
@@ -213,7 +230,9 @@ type Builder struct {
 	allocName        string
 	groupName        string
 	vaultToken       string
+	vaultNamespace   string
 	injectVaultToken bool
+	workloadToken    string
 	jobName          string
 
 	// otherPorts for tasks in the same alloc
@@ -228,6 +247,10 @@ type Builder struct {
 	// and affect network env vars.
 	networks []*structs.NetworkResource
 
+	// groupNetworks are the networks of the group of the alloc, whose ports
+	// are shared by its tasks.
+	groupNetworks []*structs.NetworkResource
+
 	mu *sync.RWMutex
 }
 
@@ -305,6 +328,7 @@ func (b *Builder) Build() *TaskEnv {
 
 	// Build the network related env vars
 	buildNetworkEnv(envMap, b.networks, b.driverNetwork)
+	buildNetworkEnv(envMap, b.groupNetworks, b.driverNetwork)
 
 	// Build the addr of the other tasks
 	for k, v := range b.otherPorts {
@@ -314,6 +338,14 @@ func (b *Builder) Build() *TaskEnv {
 	// Build the Vault Token
 	if b.injectVaultToken && b.vaultToken != "" {
 		envMap[VaultToken] = b.vaultToken
+		if b.vaultNamespace != "" {
+			envMap[VaultNamespace] = b.vaultNamespace
+		}
+	}
+
+	// Build the workload identity
+	if b.workloadToken != "" {
+		envMap[WorkloadToken] = b.workloadToken
 	}
 
 	// Copy task meta
@@ -362,6 +394,10 @@ func (b *Builder) UpdateTask(alloc *structs.Allocation, task *structs.Task) *Bui
 // variables.
 func (b *Builder) setTask(task *structs.Task) *Builder {
 	b.taskName = task.Name
+	b.vaultNamespace = ""
+	if task.Vault != nil {
+		b.vaultNamespace = task.Vault.Namespace
+	}
 	b.envvars = make(map[string]string, len(task.Env))
 	for k, v := range task.Env {
 		b.envvars[k] = v
@@ -399,6 +435,14 @@ func (b *Builder) setAlloc(alloc *structs.Allocation) *Builder {
 		b.taskMeta[fmt.Sprintf("%s%s", MetaPrefix, k)] = v
 	}
 
+	// Copy the group networks to prevent sharing
+	b.groupNetworks = nil
+	if alloc.SharedResources != nil {
+		for _, n := range alloc.SharedResources.Networks {
+			b.groupNetworks = append(b.groupNetworks, n.Copy())
+		}
+	}
+
 	// Add ports from other tasks
 	b.otherPorts = make(map[string]string, len(alloc.TaskResources)*2)
 	for taskName, resources := range alloc.TaskResources {
@@ -472,6 +516,7 @@ func (b *Builder) SetDriverNetwork(n *cstructs.DriverNetwork) *Builder {
 //
 //	Auto:   NOMAD_PORT_<label>
 //	Host:   NOMAD_IP_<label>, NOMAD_ADDR_<label>, NOMAD_HOST_PORT_<label>
+//	IPv6:   NOMAD_IPV6_<label>, NOMAD_ADDRV6_<label>
 //
 // Handled by setAlloc -> otherPorts:
 //
@@ -479,21 +524,26 @@ func (b *Builder) SetDriverNetwork(n *cstructs.DriverNetwork) *Builder {
 //
 func buildNetworkEnv(envMap map[string]string, nets structs.Networks, driverNet *cstructs.DriverNetwork) {
 	for _, n := range nets {
+		ipv6 := n.IPv6Address()
 		for _, p := range n.ReservedPorts {
-			buildPortEnv(envMap, p, n.IP, driverNet)
+			buildPortEnv(envMap, p, n.IP, ipv6, driverNet)
 		}
 		for _, p := range n.DynamicPorts {
-			buildPortEnv(envMap, p, n.IP, driverNet)
+			buildPortEnv(envMap, p, n.IP, ipv6, driverNet)
 		}
 	}
 }
 
-func buildPortEnv(envMap map[string]string, p structs.Port, ip string, driverNet *cstructs.DriverNetwork) {
+func buildPortEnv(envMap map[string]string, p structs.Port, ip, ipv6 string, driverNet *cstructs.DriverNetwork) {
 	// Host IP, port, and address
 	portStr := strconv.Itoa(p.Value)
 	envMap[IpPrefix+p.Label] = ip
 	envMap[HostPortPrefix+p.Label] = portStr
 	envMap[AddrPrefix+p.Label] = net.JoinHostPort(ip, portStr)
+	if ipv6 != "" {
+		envMap[Ipv6Prefix+p.Label] = ipv6
+		envMap[AddrIpv6Prefix+p.Label] = net.JoinHostPort(ipv6, portStr)
+	}
 
 	// Set Port to task's value if there's a port map
 	if driverNet != nil && driverNet.PortMap[p.Label] != 0 {
@@ -547,10 +597,19 @@ func (b *Builder) SetVaultToken(token string, inject bool) *Builder {
 	return b
 }
 
+// SetWorkloadToken sets the workload identity the task calls the Nomad API
+// with.
+func (b *Builder) SetWorkloadToken(token string) *Builder {
+	b.mu.Lock()
+	b.workloadToken = token
+	b.mu.Unlock()
+	return b
+}
+
 // addPort keys and values for other tasks to an env var map
 func addPort(m map[string]string, taskName, ip, portLabel string, port int) {
 	key := fmt.Sprintf("%s%s_%s", AddrPrefix, taskName, portLabel)
-	m[key] = fmt.Sprintf("%s:%d", ip, port)
+	m[key] = net.JoinHostPort(ip, strconv.Itoa(port))
 	key = fmt.Sprintf("%s%s_%s", IpPrefix, taskName, portLabel)
 	m[key] = ip
 	key = fmt.Sprintf("%s%s_%s", PortPrefix, taskName, portLabel)
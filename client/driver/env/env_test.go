@@ -248,6 +248,40 @@ func TestEnvironment_VaultToken(t *testing.T) {
 	}
 }
 
+func TestEnvironment_VaultNamespace(t *testing.T) {
+	n := mock.Node()
+	a := mock.Alloc()
+	task := a.Job.TaskGroups[0].Tasks[0]
+	task.Vault = &structs.Vault{Namespace: "team-a"}
+	env := NewBuilder(n, a, task, "global")
+
+	act := env.SetVaultToken("123", false).Build().All()
+	if _, ok := act[VaultNamespace]; ok {
+		t.Fatalf("Unexpected environment variables: %s=%q", VaultNamespace, act[VaultNamespace])
+	}
+
+	act = env.SetVaultToken("123", true).Build().All()
+	if act[VaultNamespace] != "team-a" {
+		t.Fatalf("expected %s=%q; got %q", VaultNamespace, "team-a", act[VaultNamespace])
+	}
+}
+
+func TestEnvironment_WorkloadToken(t *testing.T) {
+	n := mock.Node()
+	a := mock.Alloc()
+	env := NewBuilder(n, a, a.Job.TaskGroups[0].Tasks[0], "global")
+
+	act := env.Build().All()
+	if _, ok := act[WorkloadToken]; ok {
+		t.Fatalf("Unexpected environment variables: %s=%q", WorkloadToken, act[WorkloadToken])
+	}
+
+	act = env.SetWorkloadToken("a.b.c").Build().All()
+	if act[WorkloadToken] != "a.b.c" {
+		t.Fatalf("expected %s=%q; got %q", WorkloadToken, "a.b.c", act[WorkloadToken])
+	}
+}
+
 func TestEnvironment_Envvars(t *testing.T) {
 	envMap := map[string]string{"foo": "baz", "bar": "bang"}
 	n := mock.Node()
@@ -267,6 +301,68 @@ func TestEnvironment_Envvars(t *testing.T) {
 	}
 }
 
+func TestEnvironment_GroupNetwork(t *testing.T) {
+	n := mock.Node()
+	a := mock.Alloc()
+	a.SharedResources.Networks = []*structs.NetworkResource{
+		{
+			Mode:          structs.NetworkModeBridge,
+			IP:            "192.168.0.100",
+			ReservedPorts: []structs.Port{{Label: "api", Value: 25000, To: 8080}},
+		},
+	}
+	task := a.Job.TaskGroups[0].Tasks[0]
+	net := &cstructs.DriverNetwork{PortMap: map[string]int{"api": 8080}}
+	act := NewBuilder(n, a, task, "global").SetDriverNetwork(net).Build().Map()
+
+	exp := map[string]string{
+		"NOMAD_PORT_api":      "8080",
+		"NOMAD_HOST_PORT_api": "25000",
+		"NOMAD_IP_api":        "192.168.0.100",
+		"NOMAD_ADDR_api":      "192.168.0.100:25000",
+	}
+	for k, v := range exp {
+		if act[k] != v {
+			t.Fatalf("expected %s=%q but found %q", k, v, act[k])
+		}
+	}
+}
+
+func TestEnvironment_DualStack(t *testing.T) {
+	n := mock.Node()
+	a := mock.Alloc()
+	task := a.Job.TaskGroups[0].Tasks[0]
+	task.Resources.Networks = []*structs.NetworkResource{
+		{
+			IP:            "192.168.0.100",
+			IPv6:          "2001:db8::10",
+			ReservedPorts: []structs.Port{{Label: "http", Value: 80}},
+		},
+		{
+			IP:           "2001:db8::20",
+			DynamicPorts: []structs.Port{{Label: "admin", Value: 9000}},
+		},
+	}
+	act := NewBuilder(n, a, task, "global").Build().Map()
+
+	exp := map[string]string{
+		"NOMAD_IP_http":        "192.168.0.100",
+		"NOMAD_ADDR_http":      "192.168.0.100:80",
+		"NOMAD_IPV6_http":      "2001:db8::10",
+		"NOMAD_ADDRV6_http":    "[2001:db8::10]:80",
+		"NOMAD_IP_admin":       "2001:db8::20",
+		"NOMAD_ADDR_admin":     "[2001:db8::20]:9000",
+		"NOMAD_IPV6_admin":     "2001:db8::20",
+		"NOMAD_ADDRV6_admin":   "[2001:db8::20]:9000",
+		"NOMAD_HOST_PORT_http": "80",
+	}
+	for k, v := range exp {
+		if act[k] != v {
+			t.Fatalf("expected %s=%q but found %q", k, v, act[k])
+		}
+	}
+}
+
 func TestEnvironment_Interpolate(t *testing.T) {
 	n := mock.Node()
 	n.Attributes["arch"] = "x86"
@@ -85,8 +85,9 @@ func (d *ExecDriver) Validate(config map[string]interface{}) error {
 
 func (d *ExecDriver) Abilities() DriverAbilities {
 	return DriverAbilities{
-		SendSignals: true,
-		Exec:        true,
+		SendSignals:      true,
+		Exec:             true,
+		NetworkNamespace: true,
 	}
 }
 
@@ -124,12 +125,13 @@ func (d *ExecDriver) Start(ctx *ExecContext, task *structs.Task) (*StartResponse
 		return nil, err
 	}
 	executorCtx := &executor.ExecutorContext{
-		TaskEnv: ctx.TaskEnv,
-		Driver:  "exec",
-		AllocID: d.DriverContext.allocID,
-		LogDir:  ctx.TaskDir.LogDir,
-		TaskDir: ctx.TaskDir.Dir,
-		Task:    task,
+		TaskEnv:          ctx.TaskEnv,
+		Driver:           "exec",
+		AllocID:          d.DriverContext.allocID,
+		LogDir:           ctx.TaskDir.LogDir,
+		TaskDir:          ctx.TaskDir.Dir,
+		Task:             task,
+		NetworkNamespace: ctx.NetworkNamespace,
 	}
 	if err := exec.SetContext(executorCtx); err != nil {
 		pluginClient.Kill()
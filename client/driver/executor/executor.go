@@ -90,6 +90,10 @@ type ExecutorContext struct {
 	// PortLowerBound is the lower bound of the ports that we can use to start
 	// the syslog server
 	PortLowerBound uint
+
+	// NetworkNamespace is the path of the network namespace the command is
+	// started in, if it isn't started in the network of the host.
+	NetworkNamespace string
 }
 
 // ExecCommand holds the user command, args, and other isolation related
@@ -276,7 +280,7 @@ func (e *UniversalExecutor) LaunchCmd(command *ExecCommand) (*ProcessState, erro
 	e.cmd.Env = e.ctx.TaskEnv.List()
 
 	// Start the process
-	if err := e.cmd.Start(); err != nil {
+	if err := e.start(); err != nil {
 		return nil, fmt.Errorf("failed to start command path=%q --- args=%q: %v", path, e.cmd.Args, err)
 	}
 	go e.collectPids()
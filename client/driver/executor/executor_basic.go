@@ -3,6 +3,7 @@
 package executor
 
 import (
+	"fmt"
 	"os"
 
 	cstructs "github.com/hashicorp/nomad/client/structs"
@@ -29,6 +30,13 @@ func (e *UniversalExecutor) configureIsolation() error {
 	return nil
 }
 
+func (e *UniversalExecutor) start() error {
+	if e.ctx.NetworkNamespace != "" {
+		return fmt.Errorf("network namespaces are only supported on Linux")
+	}
+	return e.cmd.Start()
+}
+
 func (e *UniversalExecutor) Stats() (*cstructs.TaskResourceUsage, error) {
 	pidStats, err := e.pidStats()
 	if err != nil {
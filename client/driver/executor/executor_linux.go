@@ -16,6 +16,7 @@ import (
 	cgroupFs "github.com/opencontainers/runc/libcontainer/cgroups/fs"
 	cgroupConfig "github.com/opencontainers/runc/libcontainer/configs"
 
+	"github.com/hashicorp/nomad/client/cni"
 	"github.com/hashicorp/nomad/client/stats"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -43,6 +44,22 @@ func (e *UniversalExecutor) configureIsolation() error {
 	return nil
 }
 
+// start starts the command, in the network namespace of the context if it
+// has one
+func (e *UniversalExecutor) start() error {
+	if e.ctx.NetworkNamespace == "" {
+		return e.cmd.Start()
+	}
+	err := cni.WithNetNS(e.ctx.NetworkNamespace, e.cmd.Start)
+	if err != nil && e.cmd.Process != nil {
+		// The command started before the thread failed to leave the
+		// namespace
+		e.cmd.Process.Kill()
+		e.cmd.Wait()
+	}
+	return err
+}
+
 // applyLimits puts a process in a pre-configured cgroup
 func (e *UniversalExecutor) applyLimits(pid int) error {
 	if !e.command.ResourceLimits {
@@ -107,8 +107,9 @@ func (d *JavaDriver) Validate(config map[string]interface{}) error {
 
 func (d *JavaDriver) Abilities() DriverAbilities {
 	return DriverAbilities{
-		SendSignals: true,
-		Exec:        true,
+		SendSignals:      true,
+		Exec:             true,
+		NetworkNamespace: true,
 	}
 }
 
@@ -249,12 +250,13 @@ func (d *JavaDriver) Start(ctx *ExecContext, task *structs.Task) (*StartResponse
 
 	// Set the context
 	executorCtx := &executor.ExecutorContext{
-		TaskEnv: ctx.TaskEnv,
-		Driver:  "java",
-		AllocID: d.DriverContext.allocID,
-		Task:    task,
-		TaskDir: ctx.TaskDir.Dir,
-		LogDir:  ctx.TaskDir.LogDir,
+		TaskEnv:          ctx.TaskEnv,
+		Driver:           "java",
+		AllocID:          d.DriverContext.allocID,
+		Task:             task,
+		TaskDir:          ctx.TaskDir.Dir,
+		LogDir:           ctx.TaskDir.LogDir,
+		NetworkNamespace: ctx.NetworkNamespace,
 	}
 	if err := execIntf.SetContext(executorCtx); err != nil {
 		pluginClient.Kill()
@@ -149,8 +149,9 @@ func (d *LxcDriver) Validate(config map[string]interface{}) error {
 
 func (d *LxcDriver) Abilities() DriverAbilities {
 	return DriverAbilities{
-		SendSignals: false,
-		Exec:        false,
+		SendSignals:      false,
+		Exec:             false,
+		NetworkNamespace: false,
 	}
 }
 
@@ -78,8 +78,9 @@ func NewMockDriver(ctx *DriverContext) Driver {
 
 func (d *MockDriver) Abilities() DriverAbilities {
 	return DriverAbilities{
-		SendSignals: false,
-		Exec:        true,
+		SendSignals:      false,
+		Exec:             true,
+		NetworkNamespace: true,
 	}
 }
 
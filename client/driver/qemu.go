@@ -99,8 +99,9 @@ func (d *QemuDriver) Validate(config map[string]interface{}) error {
 
 func (d *QemuDriver) Abilities() DriverAbilities {
 	return DriverAbilities{
-		SendSignals: false,
-		Exec:        false,
+		SendSignals:      false,
+		Exec:             false,
+		NetworkNamespace: false,
 	}
 }
 
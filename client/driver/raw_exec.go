@@ -83,8 +83,9 @@ func (d *RawExecDriver) Validate(config map[string]interface{}) error {
 
 func (d *RawExecDriver) Abilities() DriverAbilities {
 	return DriverAbilities{
-		SendSignals: true,
-		Exec:        true,
+		SendSignals:      true,
+		Exec:             true,
+		NetworkNamespace: true,
 	}
 }
 
@@ -133,12 +134,13 @@ func (d *RawExecDriver) Start(ctx *ExecContext, task *structs.Task) (*StartRespo
 		return nil, err
 	}
 	executorCtx := &executor.ExecutorContext{
-		TaskEnv: ctx.TaskEnv,
-		Driver:  "raw_exec",
-		AllocID: d.DriverContext.allocID,
-		Task:    task,
-		TaskDir: ctx.TaskDir.Dir,
-		LogDir:  ctx.TaskDir.LogDir,
+		TaskEnv:          ctx.TaskEnv,
+		Driver:           "raw_exec",
+		AllocID:          d.DriverContext.allocID,
+		Task:             task,
+		TaskDir:          ctx.TaskDir.Dir,
+		LogDir:           ctx.TaskDir.LogDir,
+		NetworkNamespace: ctx.NetworkNamespace,
 	}
 	if err := exec.SetContext(executorCtx); err != nil {
 		pluginClient.Kill()
@@ -175,8 +175,9 @@ func (d *RktDriver) Validate(config map[string]interface{}) error {
 
 func (d *RktDriver) Abilities() DriverAbilities {
 	return DriverAbilities{
-		SendSignals: false,
-		Exec:        true,
+		SendSignals:      false,
+		Exec:             true,
+		NetworkNamespace: false,
 	}
 }
 
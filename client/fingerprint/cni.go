@@ -0,0 +1,60 @@
+package fingerprint
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/client/cni"
+	client "github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// CNIFingerprint is used to fingerprint the CNI network configurations group
+// networks can use, and whether the plugins of the bridge network mode are
+// installed
+type CNIFingerprint struct {
+	logger *log.Logger
+}
+
+// NewCNIFingerprint is used to create a CNI fingerprint
+func NewCNIFingerprint(logger *log.Logger) Fingerprint {
+	return &CNIFingerprint{logger: logger}
+}
+
+func (f *CNIFingerprint) Fingerprint(config *client.Config, node *structs.Node) (bool, error) {
+	// Clear the networks of a previous fingerprint as they may have been
+	// removed
+	for attr := range node.Attributes {
+		if strings.HasPrefix(attr, structs.NodeCNIConfigAttributePrefix) {
+			delete(node.Attributes, attr)
+		}
+	}
+	delete(node.Attributes, structs.NodeCNIBridgeAttribute)
+
+	applies := false
+	bridge := cni.BridgeConfigList(config.BridgeNetworkName, config.BridgeNetworkSubnet, config.BridgeNetworkSubnetIPv6)
+	if missing := bridge.MissingPlugins(cni.SplitPath(config.CNIPath)); len(missing) == 0 {
+		node.Attributes[structs.NodeCNIBridgeAttribute] = "1"
+		applies = true
+	}
+
+	if config.CNIConfigDir == "" {
+		return applies, nil
+	}
+	lists, err := cni.LoadConfigLists(config.CNIConfigDir)
+	if err != nil {
+		// The directory doesn't exist unless CNI networks are configured
+		return applies, nil
+	}
+
+	for name := range lists {
+		node.Attributes[structs.NodeCNIConfigAttributePrefix+name] = "1"
+		applies = true
+	}
+	return applies, nil
+}
+
+func (f *CNIFingerprint) Periodic() (bool, time.Duration) {
+	return true, 15 * time.Second
+}
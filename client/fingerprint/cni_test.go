@@ -0,0 +1,105 @@
+package fingerprint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestCNIFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	conflist := `{"cniVersion": "0.4.0", "name": "mynet", "plugins": [{"type": "bridge"}]}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "10-mynet.conflist"), []byte(conflist), 0644); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	fp := NewCNIFingerprint(testLogger())
+	node := &structs.Node{
+		Attributes: map[string]string{
+			"plugins.cni.config.removed": "1",
+		},
+	}
+	cfg := &config.Config{CNIConfigDir: dir}
+
+	ok, err := fp.Fingerprint(cfg, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("should apply")
+	}
+	assertNodeAttributeEquals(t, node, "plugins.cni.config.mynet", "1")
+	if _, ok := node.Attributes["plugins.cni.config.removed"]; ok {
+		t.Fatalf("removed network not cleared: %v", node.Attributes)
+	}
+
+	// Without network configurations the fingerprint doesn't apply
+	cfg.CNIConfigDir = filepath.Join(dir, "missing")
+	ok, err = fp.Fingerprint(cfg, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ok {
+		t.Fatalf("shouldn't apply")
+	}
+	if _, ok := node.Attributes["plugins.cni.config.mynet"]; ok {
+		t.Fatalf("network not cleared: %v", node.Attributes)
+	}
+}
+
+func TestCNIFingerprint_Bridge(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir, err := ioutil.TempDir("", "cni")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, plugin := range []string{"loopback", "bridge", "host-local", "firewall", "bandwidth"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, plugin), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	fp := NewCNIFingerprint(testLogger())
+	node := &structs.Node{
+		Attributes: make(map[string]string),
+	}
+	cfg := &config.Config{CNIPath: dir}
+
+	// The portmap plugin is missing
+	ok, err := fp.Fingerprint(cfg, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ok {
+		t.Fatalf("shouldn't apply")
+	}
+	if _, ok := node.Attributes["plugins.cni.bridge"]; ok {
+		t.Fatalf("bridge shouldn't be fingerprinted: %v", node.Attributes)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "portmap"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ok, err = fp.Fingerprint(cfg, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("should apply")
+	}
+	assertNodeAttributeEquals(t, node, "plugins.cni.bridge", "1")
+}
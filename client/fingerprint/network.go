@@ -69,22 +69,8 @@ func (f *NetworkFingerprint) Fingerprint(cfg *config.Config, node *structs.Node)
 		return false, nil
 	}
 
-	// Record the throughput of the interface
-	var mbits int
-	throughput := f.linkSpeed(intf.Name)
-	if cfg.NetworkSpeed != 0 {
-		mbits = cfg.NetworkSpeed
-		f.logger.Printf("[DEBUG] fingerprint.network: setting link speed to user configured speed: %d", mbits)
-	} else if throughput != 0 {
-		mbits = throughput
-		f.logger.Printf("[DEBUG] fingerprint.network: link speed for %v set to %v", intf.Name, mbits)
-	} else {
-		mbits = defaultNetworkSpeed
-		f.logger.Printf("[DEBUG] fingerprint.network: link speed could not be detected and no speed specified by user. Defaulting to %d", defaultNetworkSpeed)
-	}
-
 	// Create the network resources from the interface
-	nwResources, err := f.createNetworkResources(mbits, intf)
+	nwResources, err := f.createNetworkResources(f.interfaceSpeed(cfg, intf), intf)
 	if err != nil {
 		return false, err
 	}
@@ -100,10 +86,95 @@ func (f *NetworkFingerprint) Fingerprint(cfg *config.Config, node *structs.Node)
 		node.Attributes["unique.network.ip-address"] = nwResources[0].IP
 	}
 
+	// Add the network resources of the host networks
+	for _, hostNetwork := range cfg.HostNetworks {
+		hostResources, err := f.createHostNetworkResources(cfg, hostNetwork)
+		if err != nil {
+			return false, err
+		}
+		if len(hostResources) == 0 {
+			f.logger.Printf("[WARN] fingerprint.network: no addresses found for host network %q", hostNetwork.Name)
+		}
+		for _, nwResource := range hostResources {
+			f.logger.Printf("[DEBUG] fingerprint.network: Detected interface %v with IP %v in host network %q",
+				nwResource.Device, nwResource.IP, hostNetwork.Name)
+		}
+		node.Resources.Networks = append(node.Resources.Networks, hostResources...)
+	}
+
 	// return true, because we have a network connection
 	return true, nil
 }
 
+// interfaceSpeed returns the throughput of the interface, in order the one
+// configured by the user, the detected link speed or the default one.
+func (f *NetworkFingerprint) interfaceSpeed(cfg *config.Config, intf *net.Interface) int {
+	if cfg.NetworkSpeed != 0 {
+		f.logger.Printf("[DEBUG] fingerprint.network: setting link speed to user configured speed: %d", cfg.NetworkSpeed)
+		return cfg.NetworkSpeed
+	}
+	if throughput := f.linkSpeed(intf.Name); throughput != 0 {
+		f.logger.Printf("[DEBUG] fingerprint.network: link speed for %v set to %v", intf.Name, throughput)
+		return throughput
+	}
+	f.logger.Printf("[DEBUG] fingerprint.network: link speed could not be detected and no speed specified by user. Defaulting to %d", defaultNetworkSpeed)
+	return defaultNetworkSpeed
+}
+
+// createHostNetworkResources creates network resources for every IP of the
+// host network.
+func (f *NetworkFingerprint) createHostNetworkResources(cfg *config.Config, hostNetwork *config.HostNetworkConfig) ([]*structs.NetworkResource, error) {
+	var cidr *net.IPNet
+	if hostNetwork.CIDR != "" {
+		var err error
+		if _, cidr, err = net.ParseCIDR(hostNetwork.CIDR); err != nil {
+			return nil, fmt.Errorf("invalid cidr of host network %q: %v", hostNetwork.Name, err)
+		}
+	}
+
+	// Find the interfaces of the host network
+	var intfs []net.Interface
+	if hostNetwork.Interface != "" {
+		intf, err := f.interfaceDetector.InterfaceByName(hostNetwork.Interface)
+		if err != nil {
+			f.logger.Printf("[WARN] fingerprint.network: interface %q of host network %q not found: %v",
+				hostNetwork.Interface, hostNetwork.Name, err)
+			return nil, nil
+		}
+		intfs = append(intfs, *intf)
+	} else {
+		all, err := f.interfaceDetector.Interfaces()
+		if err != nil {
+			return nil, err
+		}
+		for _, intf := range all {
+			if f.isDeviceEnabled(&intf) && !f.isDeviceLoopBackOrPointToPoint(&intf) {
+				intfs = append(intfs, intf)
+			}
+		}
+	}
+
+	var nwResources []*structs.NetworkResource
+	for i := range intfs {
+		intf := &intfs[i]
+		if !f.deviceHasIpAddress(intf) {
+			continue
+		}
+		resources, err := f.createNetworkResources(f.interfaceSpeed(cfg, intf), intf)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range resources {
+			if cidr != nil && !cidr.Contains(net.ParseIP(n.IP)) {
+				continue
+			}
+			n.HostNetwork = hostNetwork.Name
+			nwResources = append(nwResources, n)
+		}
+	}
+	return nwResources, nil
+}
+
 // createNetworkResources creates network resources for every IP
 func (f *NetworkFingerprint) createNetworkResources(throughput int, intf *net.Interface) ([]*structs.NetworkResource, error) {
 	// Find the interface with the name
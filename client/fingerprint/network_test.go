@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/nomad/client/config"
@@ -321,3 +322,41 @@ func TestNetworkFingerPrint_excludelo_down_interfaces(t *testing.T) {
 		t.Fatalf("bad number of IPs %v", len(node.Resources.Networks))
 	}
 }
+
+func TestNetworkFingerPrint_host_networks(t *testing.T) {
+	f := &NetworkFingerprint{logger: testLogger(), interfaceDetector: &NetworkInterfaceDetectorMultipleInterfaces{}}
+	node := &structs.Node{
+		Attributes: make(map[string]string),
+	}
+	cfg := &config.Config{
+		NetworkSpeed: 100,
+		HostNetworks: []*config.HostNetworkConfig{
+			{Name: "private", CIDR: "100.64.0.0/10"},
+			{Name: "public", Interface: "eth0", CIDR: "2001:db8:85a3::/64"},
+			{Name: "missing", Interface: "eth3"},
+		},
+	}
+
+	ok, err := f.Fingerprint(cfg, node)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("should apply")
+	}
+
+	// The default networks are followed by the ones of the host networks
+	var hostNetworks []string
+	for _, n := range node.Resources.Networks {
+		hostNetworks = append(hostNetworks, n.HostNetwork+" "+n.Device+" "+n.CIDR)
+	}
+	expected := []string{
+		" eth0 100.64.0.0/32",
+		" eth0 2001:db8:85a3::/128",
+		"private eth0 100.64.0.0/32",
+		"public eth0 2001:db8:85a3::/128",
+	}
+	if !reflect.DeepEqual(hostNetworks, expected) {
+		t.Fatalf("bad: %#v", hostNetworks)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// heartbeatStopInterval is the interval at which the client checks for
+	// allocations to stop after losing contact with the servers
+	heartbeatStopInterval = time.Second
+)
+
+// heartbeatStop stops the allocations of the groups with a stop after client
+// disconnect once the client has lost contact with the servers for longer.
+// It tracks the last successful heartbeat itself since the heartbeat lock is
+// held while the client waits on the servers.
+type heartbeatStop struct {
+	lastOk time.Time
+	lock   sync.Mutex
+
+	// getRunners returns the current set of alloc runners
+	getRunners func() map[string]*AllocRunner
+
+	logger     *log.Logger
+	shutdownCh chan struct{}
+}
+
+func newHeartbeatStop(getRunners func() map[string]*AllocRunner, logger *log.Logger, shutdownCh chan struct{}) *heartbeatStop {
+	return &heartbeatStop{
+		lastOk:     time.Now(),
+		getRunners: getRunners,
+		logger:     logger,
+		shutdownCh: shutdownCh,
+	}
+}
+
+// setLastOk records a successful heartbeat
+func (h *heartbeatStop) setLastOk(t time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.lastOk = t
+}
+
+// getLastOk returns the time of the last successful heartbeat
+func (h *heartbeatStop) getLastOk() time.Time {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.lastOk
+}
+
+// watch is a long lived goroutine stopping the allocations once the client
+// lost contact with the servers for too long
+func (h *heartbeatStop) watch() {
+	ticker := time.NewTicker(heartbeatStopInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			h.stopAllocs(now)
+		case <-h.shutdownCh:
+			return
+		}
+	}
+}
+
+// stopAllocs stops the running allocations of the groups whose stop after
+// client disconnect is shorter than the time since the last successful
+// heartbeat.
+func (h *heartbeatStop) stopAllocs(now time.Time) {
+	disconnected := now.Sub(h.getLastOk())
+	for _, ar := range h.getRunners() {
+		alloc := ar.Alloc()
+		if alloc.TerminalStatus() || alloc.Job == nil {
+			continue
+		}
+		tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+		if tg == nil || tg.StopAfterClientDisconnect == nil || disconnected <= *tg.StopAfterClientDisconnect {
+			continue
+		}
+
+		h.logger.Printf("[WARN] client: stopping alloc %q after losing contact with the servers for %v", alloc.ID, disconnected)
+		alloc.DesiredStatus = structs.AllocDesiredStatusStop
+		alloc.DesiredDescription = "client lost contact with the servers"
+		ar.Update(alloc)
+	}
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestHeartbeatStop_StopAllocs(t *testing.T) {
+	t.Parallel()
+
+	// Only the first alloc stops after losing contact with the servers
+	upd, stopped := testAllocRunner(false)
+	stopped.alloc.Job.TaskGroups[0].StopAfterClientDisconnect = helper.TimeToPtr(time.Second)
+	_, running := testAllocRunner(false)
+	for _, ar := range []*AllocRunner{stopped, running} {
+		ar.alloc.Job.TaskGroups[0].Tasks[0].Config["run_for"] = "10s"
+		go ar.Run()
+		defer ar.Destroy()
+	}
+
+	runners := map[string]*AllocRunner{
+		stopped.Alloc().ID: stopped,
+		running.Alloc().ID: running,
+	}
+	h := newHeartbeatStop(func() map[string]*AllocRunner { return runners }, testLogger(), make(chan struct{}))
+
+	// Nothing is stopped while the client is in contact with the servers
+	h.stopAllocs(time.Now())
+	if stopped.Alloc().TerminalStatus() {
+		t.Fatalf("alloc stopped before the client lost contact with the servers")
+	}
+
+	h.setLastOk(time.Now().Add(-time.Minute))
+	h.stopAllocs(time.Now())
+
+	testutil.WaitForResult(func() (bool, error) {
+		_, last := upd.Last()
+		if last == nil {
+			return false, fmt.Errorf("No updates")
+		}
+		if last.ClientStatus != structs.AllocClientStatusComplete {
+			return false, fmt.Errorf("got status %v; want %v", last.ClientStatus, structs.AllocClientStatusComplete)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	if running.Alloc().TerminalStatus() {
+		t.Fatalf("alloc without a stop after client disconnect was stopped")
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"time"
+
+	"github.com/hashicorp/consul-template/signals"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/nomad"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/yamux"
+)
+
+const (
+	// nodeConnRetryIntv is the minimum interval on which the client retries
+	// opening its node connection. We pick a value between this and 2x this.
+	nodeConnRetryIntv = 5 * time.Second
+)
+
+// ClientAllocations is the endpoint the servers call over the node connection
+// to act on the allocations of the client.
+type ClientAllocations struct {
+	c *Client
+}
+
+// Signal sends the signal to the tasks of an allocation of the client.
+func (a *ClientAllocations) Signal(args *structs.AllocSignalRequest, reply *structs.GenericResponse) error {
+	sig, err := signals.Parse(args.Signal)
+	if err != nil {
+		return fmt.Errorf("invalid signal %q: %v", args.Signal, err)
+	}
+	return a.c.SignalAllocation(args.AllocID, args.Task, sig)
+}
+
+// nodeConnLoop keeps a node connection open to one of the servers, over which
+// the servers make RPCs to the client. The servers can't dial clients, which
+// may not be reachable from them, so the client opens the connection once its
+// node is registered and reopens it whenever it is lost.
+func (c *Client) nodeConnLoop() {
+	server := rpc.NewServer()
+	if err := server.Register(&ClientAllocations{c}); err != nil {
+		c.logger.Printf("[ERR] client: failed to register node connection endpoints: %v", err)
+		return
+	}
+
+	for {
+		session, err := c.dialNodeConn()
+		if err != nil {
+			intv := c.retryIntv(nodeConnRetryIntv)
+			c.logger.Printf("[ERR] client: opening node connection failed. Retrying in %v: %v", intv, err)
+			select {
+			case <-time.After(intv):
+				continue
+			case <-c.shutdownCh:
+				return
+			}
+		}
+
+		c.serveNodeConn(server, session)
+		select {
+		case <-c.shutdownCh:
+			return
+		default:
+			c.logger.Printf("[DEBUG] client: node connection closed, reopening")
+		}
+	}
+}
+
+// dialNodeConn opens a node connection to the local server if the client runs
+// in process with one, or else to the first server that accepts it.
+func (c *Client) dialNodeConn() (*yamux.Session, error) {
+	node := c.Node()
+	req := &structs.NodeConnRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+	}
+	if handler, ok := c.config.RPCHandler.(config.NodeConnHandler); ok {
+		local, remote := net.Pipe()
+		go handler.ServeNodeConn(remote)
+		return nomad.OpenNodeConn(local, req, c.config.LogOutput)
+	}
+
+	servers := c.servers.all()
+	if len(servers) == 0 {
+		return nil, noServersErr
+	}
+	var lastErr error
+	for _, s := range servers {
+		session, err := c.connPool.DialNodeConn(c.Region(), s.addr, req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c.logger.Printf("[DEBUG] client: opened node connection to server %s", s.addr)
+		return session, nil
+	}
+	return nil, lastErr
+}
+
+// serveNodeConn serves the RPCs the server makes over the node connection
+// until it is closed or the client shuts down.
+func (c *Client) serveNodeConn(server *rpc.Server, session *yamux.Session) {
+	done := make(chan struct{})
+	defer close(done)
+	defer session.Close()
+	go func() {
+		select {
+		case <-c.shutdownCh:
+			session.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		stream, err := session.Accept()
+		if err != nil {
+			return
+		}
+		go server.ServeCodec(nomad.NewServerCodec(stream))
+	}
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// redactedSecret replaces the secrets redacted from task events and logs
+	redactedSecret = "<redacted>"
+
+	// minRedactedLength is the length of the shortest secret redacted. Shorter
+	// values, such as booleans and ports, would redact unrelated text.
+	minRedactedLength = 6
+
+	// vaultSecrets, workloadIdentitySecrets and templateSecrets are the
+	// sources of the secrets of a task: its Vault token, its workload
+	// identity and the rendered contents of its secret templates.
+	vaultSecrets            = "vault"
+	workloadIdentitySecrets = "workload-identity"
+	templateSecrets         = "template"
+)
+
+// secretRedactor redacts the secrets of a task from its events and the logs
+// of the client, so that they aren't disclosed by the APIs and the agent's
+// log output. Secrets are set by source as they are retrieved or rendered.
+type secretRedactor struct {
+	sources  map[string][]string
+	replacer *strings.Replacer
+	lock     sync.RWMutex
+}
+
+// newSecretRedactor returns a secretRedactor without any secret.
+func newSecretRedactor() *secretRedactor {
+	return &secretRedactor{
+		sources: make(map[string][]string),
+	}
+}
+
+// setSecrets replaces the secrets of the source.
+func (r *secretRedactor) setSecrets(source string, secrets []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sources[source] = secrets
+
+	var all []string
+	seen := make(map[string]struct{})
+	for _, secrets := range r.sources {
+		for _, s := range secrets {
+			if _, ok := seen[s]; ok || len(s) < minRedactedLength {
+				continue
+			}
+			seen[s] = struct{}{}
+			all = append(all, s)
+		}
+	}
+	if len(all) == 0 {
+		r.replacer = nil
+		return
+	}
+
+	// Replace the longest secrets first so that secrets containing others
+	// are fully redacted
+	sort.Slice(all, func(i, j int) bool { return len(all[i]) > len(all[j]) })
+	pairs := make([]string, 0, 2*len(all))
+	for _, s := range all {
+		pairs = append(pairs, s, redactedSecret)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// redact returns the string with its secrets redacted.
+func (r *secretRedactor) redact(s string) string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.replacer == nil || s == "" {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// redactEvent returns a copy of the task event with the secrets of its
+// messages redacted.
+func (r *secretRedactor) redactEvent(e *structs.TaskEvent) *structs.TaskEvent {
+	if e == nil {
+		return nil
+	}
+	e = e.Copy()
+	for _, field := range []*string{
+		&e.RestartReason, &e.SetupError, &e.DriverError, &e.Message,
+		&e.KillError, &e.KillReason, &e.DownloadError, &e.ValidationError,
+		&e.VaultError, &e.TaskSignalReason, &e.DriverMessage,
+	} {
+		*field = r.redact(*field)
+	}
+	return e
+}
+
+// writer returns a writer redacting the secrets of what is written to w.
+// Loggers write a line at a time so secrets aren't split between writes.
+func (r *secretRedactor) writer(w io.Writer) io.Writer {
+	return &redactWriter{redactor: r, w: w}
+}
+
+type redactWriter struct {
+	redactor *secretRedactor
+	w        io.Writer
+}
+
+func (w *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.redactor.redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestSecretRedactor(t *testing.T) {
+	t.Parallel()
+	r := newSecretRedactor()
+	if out := r.redact("token s.abcdef"); out != "token s.abcdef" {
+		t.Fatalf("expected nothing to be redacted: %q", out)
+	}
+
+	r.setSecrets(vaultSecrets, []string{"s.abcdef"})
+	r.setSecrets(templateSecrets, []string{"password=s.abcdef123", "true"})
+
+	cases := map[string]string{
+		"token s.abcdef":            "token <redacted>",
+		"line password=s.abcdef123": "line <redacted>",
+		"enabled true":              "enabled true",
+	}
+	for in, expected := range cases {
+		if out := r.redact(in); out != expected {
+			t.Errorf("expected %q to be redacted to %q; got %q", in, expected, out)
+		}
+	}
+
+	e := structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(fmt.Errorf("bad token s.abcdef"))
+	redacted := r.redactEvent(e)
+	if redacted.SetupError != "bad token <redacted>" {
+		t.Fatalf("expected event to be redacted: %q", redacted.SetupError)
+	}
+	if e.SetupError != "bad token s.abcdef" {
+		t.Fatalf("expected original event to be unchanged: %q", e.SetupError)
+	}
+
+	var buf bytes.Buffer
+	logger := log.New(r.writer(&buf), "", 0)
+	logger.Printf("[DEBUG] driver: using token %s", "s.abcdef")
+	if out := buf.String(); out != "[DEBUG] driver: using token <redacted>\n" {
+		t.Fatalf("expected log line to be redacted: %q", out)
+	}
+
+	// Replacing the secrets of a source stops redacting the previous ones
+	r.setSecrets(vaultSecrets, nil)
+	if out := r.redact("token s.abcdef"); out != "token s.abcdef" {
+		t.Fatalf("expected nothing to be redacted: %q", out)
+	}
+}
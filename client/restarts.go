@@ -37,6 +37,7 @@ type RestartTracker struct {
 	waitRes          *dstructs.WaitResult
 	startErr         error
 	restartTriggered bool      // Whether the task has been signalled to be restarted
+	failure          bool      // Whether a failure triggered the restart
 	count            int       // Current number of attempts.
 	onSuccess        bool      // Whether to restart on successful exit code.
 	startTime        time.Time // When the interval began
@@ -71,11 +72,17 @@ func (r *RestartTracker) SetWaitResult(res *dstructs.WaitResult) *RestartTracker
 }
 
 // SetRestartTriggered is used to mark that the task has been signalled to be
-// restarted
-func (r *RestartTracker) SetRestartTriggered() *RestartTracker {
+// restarted. Setting the failure to true restarts according to the restart
+// policy. When failure is false the task is restarted without considering the
+// restart policy.
+func (r *RestartTracker) SetRestartTriggered(failure bool) *RestartTracker {
 	r.lock.Lock()
 	defer r.lock.Unlock()
-	r.restartTriggered = true
+	if failure {
+		r.failure = true
+	} else {
+		r.restartTriggered = true
+	}
 	return r
 }
 
@@ -106,6 +113,7 @@ func (r *RestartTracker) GetState() (string, time.Duration) {
 		r.startErr = nil
 		r.waitRes = nil
 		r.restartTriggered = false
+		r.failure = false
 	}()
 
 	// Hot path if a restart was triggered
@@ -136,6 +144,8 @@ func (r *RestartTracker) GetState() (string, time.Duration) {
 
 	if r.startErr != nil {
 		return r.handleStartError()
+	} else if r.failure {
+		return r.handleFailure()
 	} else if r.waitRes != nil {
 		return r.handleWaitResult()
 	}
@@ -196,6 +206,27 @@ func (r *RestartTracker) handleWaitResult() (string, time.Duration) {
 	return structs.TaskRestarting, r.jitter()
 }
 
+// handleFailure returns the new state and potential wait duration for
+// restarting the task after it was restarted because of a failure, such as a
+// health check staying unhealthy for too long. Like failed exits, these
+// restarts count against the restart policy.
+func (r *RestartTracker) handleFailure() (string, time.Duration) {
+	if r.count > r.policy.Attempts {
+		if r.policy.Mode == structs.RestartPolicyModeFail {
+			r.reason = fmt.Sprintf(
+				`Exceeded allowed attempts %d in interval %v and mode is "fail"`,
+				r.policy.Attempts, r.policy.Interval)
+			return structs.TaskNotRestarting, 0
+		} else {
+			r.reason = ReasonDelay
+			return structs.TaskRestarting, r.getDelay()
+		}
+	}
+
+	r.reason = ReasonWithinPolicy
+	return structs.TaskRestarting, r.jitter()
+}
+
 // getDelay returns the delay time to enter the next interval.
 func (r *RestartTracker) getDelay() time.Duration {
 	end := r.startTime.Add(r.policy.Interval)
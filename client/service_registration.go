@@ -0,0 +1,730 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/client/driver"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// nomadServicePrefix is the prefix of the IDs of the service
+	// registrations written by the client
+	nomadServicePrefix = "_nomad-task"
+
+	// serviceRegistrationRetryInterval is the time to wait before retrying
+	// to send the registrations to the servers after a failure
+	serviceRegistrationRetryInterval = 5 * time.Second
+)
+
+// serviceProviders dispatches the services of the tasks to their service
+// discovery provider: the services using the Nomad provider are handled by
+// the nomadServiceClient and the others by Consul.
+//
+// The variables stored by the servers are also read through it, as the
+// templates of the tasks get their Nomad data from the service client, and
+// so are the workload identities of the allocations.
+type serviceProviders struct {
+	consul     ConsulServiceAPI
+	nomad      *nomadServiceClient
+	variables  *nomadVariableReader
+	identities *nomadWorkloadIdentities
+}
+
+// newServiceProviders returns a ConsulServiceAPI registering the services of
+// the tasks with their provider.
+func newServiceProviders(consul ConsulServiceAPI, nomad *nomadServiceClient, variables *nomadVariableReader,
+	identities *nomadWorkloadIdentities) *serviceProviders {
+	return &serviceProviders{
+		consul:     consul,
+		nomad:      nomad,
+		variables:  variables,
+		identities: identities,
+	}
+}
+
+func (s *serviceProviders) RegisterTask(allocID string, consulConfig *structs.Consul, task *structs.Task,
+	restarter consul.TaskRestarter, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
+	// The services using the Nomad provider are registered first as they
+	// fail without side effects
+	if err := s.nomad.RegisterTask(allocID, task, restarter, exec, net); err != nil {
+		return err
+	}
+	if err := s.consul.RegisterTask(allocID, consulConfig, consulServicesTask(task), restarter, exec, net); err != nil {
+		s.nomad.RemoveTask(allocID, task)
+		return err
+	}
+	return nil
+}
+
+func (s *serviceProviders) RemoveTask(allocID string, task *structs.Task) {
+	s.consul.RemoveTask(allocID, consulServicesTask(task))
+	s.nomad.RemoveTask(allocID, task)
+}
+
+func (s *serviceProviders) UpdateTask(allocID string, consulConfig *structs.Consul, existing, newTask *structs.Task,
+	restarter consul.TaskRestarter, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
+	if err := s.consul.UpdateTask(allocID, consulConfig, consulServicesTask(existing), consulServicesTask(newTask),
+		restarter, exec, net); err != nil {
+		return err
+	}
+	return s.nomad.UpdateTask(allocID, existing, newTask, restarter, exec, net)
+}
+
+// Checks returns the checks of the services of the allocation with both
+// providers. Consul is only queried if the allocation has Consul checks.
+func (s *serviceProviders) Checks(alloc *structs.Allocation) ([]*api.AgentCheck, error) {
+	checks := s.nomad.Checks(alloc)
+	if !hasConsulChecks(alloc) {
+		return checks, nil
+	}
+	consulChecks, err := s.consul.Checks(alloc)
+	if err != nil {
+		return nil, err
+	}
+	return append(consulChecks, checks...), nil
+}
+
+// SetServiceTokens sets the Service Identity tokens the Consul services of the
+// task are registered with.
+func (s *serviceProviders) SetServiceTokens(allocID, taskName string, tokens map[string]string) {
+	if c, ok := s.consul.(consulServiceTokens); ok {
+		c.SetServiceTokens(allocID, taskName, tokens)
+	}
+}
+
+// NomadServices lists the services registered with Nomad for the templates
+// of the tasks.
+func (s *serviceProviders) NomadServices(name string, index uint64) ([]*structs.ServiceRegistration, uint64, error) {
+	return s.nomad.NomadServices(name, index)
+}
+
+// NomadVariable reads the variables stored by the servers for the templates
+// of the tasks.
+func (s *serviceProviders) NomadVariable(path string, index uint64) (map[string]string, uint64, error) {
+	return s.variables.NomadVariable(path, index)
+}
+
+// WorkloadIdentity requests the workload identity the tasks of the allocation
+// call the Nomad API with.
+func (s *serviceProviders) WorkloadIdentity(allocID string) (string, error) {
+	return s.identities.WorkloadIdentity(allocID)
+}
+
+// isNomadService returns whether the service uses the Nomad provider.
+func isNomadService(service *structs.Service) bool {
+	return service.Provider == structs.ServiceProviderNomad
+}
+
+// hasConsulChecks returns whether the allocation has checks registered in
+// Consul.
+func hasConsulChecks(alloc *structs.Allocation) bool {
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		return false
+	}
+	for _, task := range tg.Tasks {
+		for _, service := range task.Services {
+			if !isNomadService(service) && len(service.Checks) != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// consulServicesTask returns the task restricted to the services registered
+// in Consul. The task is returned as is if all its services use Consul.
+func consulServicesTask(task *structs.Task) *structs.Task {
+	services := make([]*structs.Service, 0, len(task.Services))
+	for _, service := range task.Services {
+		if !isNomadService(service) {
+			services = append(services, service)
+		}
+	}
+	if len(services) == len(task.Services) {
+		return task
+	}
+
+	t := new(structs.Task)
+	*t = *task
+	t.Services = services
+	return t
+}
+
+// nomadServiceClient registers the services using the Nomad provider with
+// the servers and runs their checks. Like the Consul service client, the
+// registrations are kept locally and sent to the servers by a background
+// loop so that tasks don't fail to start when the servers are unreachable.
+type nomadServiceClient struct {
+	nodeID     string
+	secretID   string
+	region     string
+	datacenter string
+	rpc        func(method string, args, reply interface{}) error
+	logger     *log.Logger
+
+	// services are the registrations of the running tasks by ID
+	services map[string]*structs.ServiceRegistration
+
+	// checks cancels the checks of the registrations by ID
+	checks map[string]context.CancelFunc
+
+	// checkStates are the states of the checks of the registrations by ID
+	checkStates map[string][]*api.AgentCheck
+
+	// upserts and deletes are the IDs of the registrations to send to the
+	// servers
+	upserts map[string]struct{}
+	deletes map[string]struct{}
+
+	l      sync.Mutex
+	syncCh chan struct{}
+}
+
+// newNomadServiceClient returns a client registering services of the tasks
+// running on the given node. Run must be called to send the registrations.
+func newNomadServiceClient(nodeID, secretID, region, datacenter string, rpc func(string, interface{}, interface{}) error,
+	logger *log.Logger) *nomadServiceClient {
+	return &nomadServiceClient{
+		nodeID:      nodeID,
+		secretID:    secretID,
+		region:      region,
+		datacenter:  datacenter,
+		rpc:         rpc,
+		logger:      logger,
+		services:    make(map[string]*structs.ServiceRegistration),
+		checks:      make(map[string]context.CancelFunc),
+		checkStates: make(map[string][]*api.AgentCheck),
+		upserts:     make(map[string]struct{}),
+		deletes:     make(map[string]struct{}),
+		syncCh:      make(chan struct{}, 1),
+	}
+}
+
+// Run sends the registrations to the servers until shutdownCh is closed.
+func (c *nomadServiceClient) Run(shutdownCh <-chan struct{}) {
+	var retryCh <-chan time.Time
+	for {
+		select {
+		case <-c.syncCh:
+		case <-retryCh:
+		case <-shutdownCh:
+			c.l.Lock()
+			for id, cancel := range c.checks {
+				cancel()
+				delete(c.checks, id)
+			}
+			c.l.Unlock()
+			return
+		}
+
+		retryCh = nil
+		if err := c.sync(); err != nil {
+			c.logger.Printf("[WARN] client: failed to sync service registrations, retrying in %s: %v",
+				serviceRegistrationRetryInterval, err)
+			retryCh = time.After(serviceRegistrationRetryInterval)
+		}
+	}
+}
+
+// triggerSync wakes up the Run loop without blocking.
+func (c *nomadServiceClient) triggerSync() {
+	select {
+	case c.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+// sync sends the pending registrations and deletions to the servers.
+func (c *nomadServiceClient) sync() error {
+	c.l.Lock()
+	upserts := make([]*structs.ServiceRegistration, 0, len(c.upserts))
+	for id := range c.upserts {
+		if service, ok := c.services[id]; ok {
+			upserts = append(upserts, service.Copy())
+		}
+	}
+	deletes := make([]string, 0, len(c.deletes))
+	for id := range c.deletes {
+		deletes = append(deletes, id)
+	}
+	c.l.Unlock()
+
+	if len(deletes) != 0 {
+		req := structs.ServiceRegistrationDeleteByIDRequest{
+			IDs:          deletes,
+			WriteRequest: structs.WriteRequest{Region: c.region, AuthToken: c.secretID},
+		}
+		var resp structs.GenericResponse
+		if err := c.rpc("ServiceRegistration.DeleteByID", &req, &resp); err != nil {
+			return err
+		}
+		c.l.Lock()
+		for _, id := range deletes {
+			delete(c.deletes, id)
+		}
+		c.l.Unlock()
+	}
+
+	// Registrations are sent one at a time so that the registration of an
+	// allocation the servers consider terminal doesn't block the others
+	var lastErr error
+	for _, service := range upserts {
+		req := structs.ServiceRegistrationUpsertRequest{
+			Services:     []*structs.ServiceRegistration{service},
+			WriteRequest: structs.WriteRequest{Region: c.region, AuthToken: c.secretID},
+		}
+		var resp structs.GenericResponse
+		if err := c.rpc("ServiceRegistration.Upsert", &req, &resp); err != nil {
+			lastErr = fmt.Errorf("failed to register service %q of alloc %q: %v", service.ServiceName, service.AllocID, err)
+			continue
+		}
+
+		// Only clear the upsert if the registration didn't change since
+		c.l.Lock()
+		if current, ok := c.services[service.ID]; ok && current.Status == service.Status {
+			delete(c.upserts, service.ID)
+		}
+		c.l.Unlock()
+	}
+	return lastErr
+}
+
+// NomadServices returns the passing instances of the service registered with
+// the servers of the client's region. It blocks until the registrations
+// change past the index, allowing templates to watch the service.
+func (c *nomadServiceClient) NomadServices(name string, index uint64) ([]*structs.ServiceRegistration, uint64, error) {
+	req := structs.ServiceRegistrationByNameRequest{
+		ServiceName: name,
+		QueryOptions: structs.QueryOptions{
+			Region:        c.region,
+			AuthToken:     c.secretID,
+			MinQueryIndex: index,
+			MaxQueryTime:  templateDataWaitTime,
+			AllowStale:    true,
+		},
+	}
+	var resp structs.ServiceRegistrationByNameResponse
+	if err := c.rpc("ServiceRegistration.GetService", &req, &resp); err != nil {
+		return nil, 0, err
+	}
+
+	services := make([]*structs.ServiceRegistration, 0, len(resp.Services))
+	for _, reg := range resp.Services {
+		if reg.Status == structs.ServiceRegistrationStatusCritical {
+			continue
+		}
+		services = append(services, reg)
+	}
+	return services, resp.Index, nil
+}
+
+// PassingServices returns the passing instances of the service registered
+// with the servers of the client's region.
+func (c *nomadServiceClient) PassingServices(name string) ([]*structs.ServiceRegistration, error) {
+	req := structs.ServiceRegistrationByNameRequest{
+		ServiceName: name,
+		QueryOptions: structs.QueryOptions{
+			Region:     c.region,
+			AuthToken:  c.secretID,
+			AllowStale: true,
+		},
+	}
+	var resp structs.ServiceRegistrationByNameResponse
+	if err := c.rpc("ServiceRegistration.GetService", &req, &resp); err != nil {
+		return nil, err
+	}
+
+	services := make([]*structs.ServiceRegistration, 0, len(resp.Services))
+	for _, reg := range resp.Services {
+		if reg.Status != structs.ServiceRegistrationStatusCritical {
+			services = append(services, reg)
+		}
+	}
+	return services, nil
+}
+
+// RegisterTask registers the services of the task using the Nomad provider
+// and starts their checks. The restarter restarts the task when a check with
+// a check_restart stanza stays unhealthy. Script checks are run inside the
+// task with exec; an error is returned if they can't be run.
+func (c *nomadServiceClient) RegisterTask(allocID string, task *structs.Task, restarter consul.TaskRestarter,
+	exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
+	if exec == nil {
+		for _, service := range task.Services {
+			if !isNomadService(service) {
+				continue
+			}
+			for _, check := range service.Checks {
+				if check.Type == structs.ServiceCheckScript {
+					return fmt.Errorf("driver doesn't support script checks")
+				}
+			}
+		}
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	registered := false
+	for _, service := range task.Services {
+		if !isNomadService(service) {
+			continue
+		}
+
+		reg := c.serviceReg(allocID, task, service, net)
+		c.removeLocked(reg.ID)
+		c.services[reg.ID] = reg
+		c.upserts[reg.ID] = struct{}{}
+		delete(c.deletes, reg.ID)
+		c.startChecksLocked(reg, task, service, restarter, exec)
+		registered = true
+	}
+
+	if registered {
+		c.triggerSync()
+	}
+	return nil
+}
+
+// UpdateTask removes the services of the existing task that are gone and
+// registers the services of the new task.
+func (c *nomadServiceClient) UpdateTask(allocID string, existing, newTask *structs.Task, restarter consul.TaskRestarter,
+	exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
+	newIDs := make(map[string]struct{}, len(newTask.Services))
+	for _, service := range newTask.Services {
+		if isNomadService(service) {
+			newIDs[makeNomadServiceID(allocID, newTask.Name, service)] = struct{}{}
+		}
+	}
+
+	c.l.Lock()
+	removed := false
+	for _, service := range existing.Services {
+		if !isNomadService(service) {
+			continue
+		}
+		id := makeNomadServiceID(allocID, existing.Name, service)
+		if _, ok := newIDs[id]; !ok {
+			c.removeLocked(id)
+			c.deletes[id] = struct{}{}
+			removed = true
+		}
+	}
+	c.l.Unlock()
+
+	if removed {
+		c.triggerSync()
+	}
+	return c.RegisterTask(allocID, newTask, restarter, exec, net)
+}
+
+// RemoveTask stops the checks of the services of the task and removes their
+// registrations.
+func (c *nomadServiceClient) RemoveTask(allocID string, task *structs.Task) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	removed := false
+	for _, service := range task.Services {
+		if !isNomadService(service) {
+			continue
+		}
+		id := makeNomadServiceID(allocID, task.Name, service)
+		c.removeLocked(id)
+		c.deletes[id] = struct{}{}
+		removed = true
+	}
+
+	if removed {
+		c.triggerSync()
+	}
+}
+
+// removeLocked stops the checks of the registration and forgets it. The lock
+// must be held.
+func (c *nomadServiceClient) removeLocked(id string) {
+	if cancel, ok := c.checks[id]; ok {
+		cancel()
+		delete(c.checks, id)
+	}
+	delete(c.checkStates, id)
+	delete(c.services, id)
+	delete(c.upserts, id)
+}
+
+// serviceReg returns the registration of the service of the task.
+func (c *nomadServiceClient) serviceReg(allocID string, task *structs.Task, service *structs.Service,
+	net *cstructs.DriverNetwork) *structs.ServiceRegistration {
+
+	ip, port := task.Resources.Networks.Port(service.PortLabel)
+	ipv6 := task.Resources.Networks.PortIPv6(service.PortLabel)
+	switch service.AddressMode {
+	case structs.AddressModeDriver:
+		if net != nil {
+			ip, port, ipv6 = net.IP, net.PortMap[service.PortLabel], ""
+		}
+	case "", structs.AddressModeAuto:
+		if net.Advertise() {
+			ip, port, ipv6 = net.IP, net.PortMap[service.PortLabel], ""
+		}
+	}
+	if ipv6 == ip {
+		ipv6 = ""
+	}
+
+	reg := &structs.ServiceRegistration{
+		ID:          makeNomadServiceID(allocID, task.Name, service),
+		ServiceName: service.Name,
+		AllocID:     allocID,
+		TaskName:    task.Name,
+		NodeID:      c.nodeID,
+		Datacenter:  c.datacenter,
+		Tags:        make([]string, len(service.Tags)),
+		Address:     ip,
+		Port:        port,
+		AddressIPv6: ipv6,
+	}
+	copy(reg.Tags, service.Tags)
+	if len(service.Checks) != 0 {
+		reg.Status = structs.ServiceRegistrationStatusCritical
+		if initialStatus(service.Checks) == api.HealthPassing {
+			reg.Status = structs.ServiceRegistrationStatusPassing
+		}
+	}
+	return reg
+}
+
+// initialStatus returns the status of a set of checks before they run.
+func initialStatus(checks []*structs.ServiceCheck) string {
+	for _, check := range checks {
+		if check.InitialStatus != api.HealthPassing {
+			return api.HealthCritical
+		}
+	}
+	return api.HealthPassing
+}
+
+// startChecksLocked runs the checks of the service until the registration is
+// removed, updating the status of the registration when it changes and
+// restarting the task when a check stays unhealthy. The lock must be held.
+func (c *nomadServiceClient) startChecksLocked(reg *structs.ServiceRegistration, task *structs.Task, service *structs.Service,
+	restarter consul.TaskRestarter, exec driver.ScriptExecutor) {
+	if len(service.Checks) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.checks[reg.ID] = cancel
+
+	// states holds the last state of each check, guarded by the lock
+	states := make([]*api.AgentCheck, len(service.Checks))
+	for i, check := range service.Checks {
+		states[i] = &api.AgentCheck{
+			Node:        c.nodeID,
+			CheckID:     check.Hash(reg.ID),
+			Name:        check.Name,
+			Status:      api.HealthCritical,
+			ServiceID:   reg.ID,
+			ServiceName: reg.ServiceName,
+		}
+		if reg.Status == structs.ServiceRegistrationStatusPassing {
+			states[i].Status = api.HealthPassing
+		}
+	}
+	c.checkStates[reg.ID] = states
+
+	for i, check := range service.Checks {
+		portLabel := check.PortLabel
+		if portLabel == "" {
+			portLabel = service.PortLabel
+		}
+
+		// Checks always use the host ip:port
+		ip, port := task.Resources.Networks.Port(portLabel)
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
+
+		// checkRestart is only used by the goroutine running the check
+		var checkRestart *consul.CheckRestart
+		if check.TriggersRestarts() {
+			checkRestart = consul.NewCheckRestart(reg.AllocID, task.Name, check, restarter, c.logger)
+		}
+
+		go c.runCheck(ctx, check, addr, exec, func(checkStatus string) {
+			if checkRestart != nil && ctx.Err() == nil {
+				if checkRestart.Apply(time.Now(), checkStatus) {
+					// The checks are started again when the task
+					// restarts
+					checkRestart = nil
+				}
+			}
+
+			c.l.Lock()
+			defer c.l.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Like Consul, checks with warnings don't make the service
+			// unhealthy
+			state := *states[i]
+			state.Status = checkStatus
+			states[i] = &state
+			status := structs.ServiceRegistrationStatusPassing
+			for _, s := range states {
+				if s.Status == api.HealthCritical {
+					status = structs.ServiceRegistrationStatusCritical
+				}
+			}
+
+			current, ok := c.services[reg.ID]
+			if !ok || current.Status == status {
+				return
+			}
+			current = current.Copy()
+			current.Status = status
+			c.services[reg.ID] = current
+			c.upserts[reg.ID] = struct{}{}
+			c.triggerSync()
+		})
+	}
+}
+
+// Checks returns the states of the checks of the Nomad services of the
+// allocation.
+func (c *nomadServiceClient) Checks(alloc *structs.Allocation) []*api.AgentCheck {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	var checks []*api.AgentCheck
+	for id, reg := range c.services {
+		if reg.AllocID != alloc.ID {
+			continue
+		}
+		for _, state := range c.checkStates[id] {
+			check := *state
+			checks = append(checks, &check)
+		}
+	}
+	return checks
+}
+
+// runCheck runs the check at every interval until the context is canceled
+// and reports its status. Script checks are run inside the task with exec and
+// the other checks against addr.
+func (c *nomadServiceClient) runCheck(ctx context.Context, check *structs.ServiceCheck, addr string,
+	exec driver.ScriptExecutor, report func(string)) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		status := api.HealthPassing
+		if check.Type == structs.ServiceCheckScript {
+			status = runScriptCheck(ctx, check, exec)
+		} else if err := checkService(ctx, check, addr); err != nil {
+			status = api.HealthCritical
+		}
+		report(status)
+		timer.Reset(check.Interval)
+	}
+}
+
+// runScriptCheck runs the command of a script check inside the task and
+// returns the check status matching its exit code: 0 is passing, 1 is warning
+// and any other code is critical. Commands failing to run or timing out are
+// critical.
+func runScriptCheck(ctx context.Context, check *structs.ServiceCheck, exec driver.ScriptExecutor) string {
+	execCtx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	_, code, err := exec.Exec(execCtx, check.Command, check.Args)
+	if err != nil || execCtx.Err() != nil {
+		return api.HealthCritical
+	}
+
+	switch code {
+	case 0:
+		return api.HealthPassing
+	case 1:
+		return api.HealthWarning
+	default:
+		return api.HealthCritical
+	}
+}
+
+// checkService runs an http or tcp check against addr and returns an error if
+// it failed.
+func checkService(ctx context.Context, check *structs.ServiceCheck, addr string) error {
+	switch check.Type {
+	case structs.ServiceCheckTCP:
+		conn, err := net.DialTimeout("tcp", addr, check.Timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case structs.ServiceCheckHTTP:
+		protocol := check.Protocol
+		if protocol == "" {
+			protocol = "http"
+		}
+		u := url.URL{
+			Scheme: protocol,
+			Host:   addr,
+			Path:   check.Path,
+		}
+		if base, err := url.Parse(check.Path); err == nil {
+			u.Path = base.Path
+			u.RawQuery = base.RawQuery
+		}
+
+		req, err := http.NewRequest("GET", u.String(), nil)
+		if err != nil {
+			return err
+		}
+		client := &http.Client{
+			Timeout: check.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: check.TLSSkipVerify},
+			},
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("check type %q is not supported", check.Type)
+	}
+}
+
+// makeNomadServiceID returns the ID of the registration of a service of a
+// task.
+//
+//	Example Service ID: _nomad-task-1234-web-echo-http
+func makeNomadServiceID(allocID, taskName string, service *structs.Service) string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s", nomadServicePrefix, allocID, taskName, service.Name, service.PortLabel)
+}
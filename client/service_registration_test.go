@@ -0,0 +1,280 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// fakeScriptExecutor runs script checks returning the configured exit code.
+type fakeScriptExecutor struct {
+	code int
+}
+
+func (f *fakeScriptExecutor) Exec(ctx context.Context, cmd string, args []string) ([]byte, int, error) {
+	return []byte("output"), f.code, nil
+}
+
+// fakeServiceRegistrationRPC records the registrations sent by the
+// nomadServiceClient.
+type fakeServiceRegistrationRPC struct {
+	l        sync.Mutex
+	services map[string]*structs.ServiceRegistration
+}
+
+func (f *fakeServiceRegistrationRPC) RPC(method string, args, reply interface{}) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+	switch method {
+	case "ServiceRegistration.Upsert":
+		for _, service := range args.(*structs.ServiceRegistrationUpsertRequest).Services {
+			f.services[service.ID] = service
+		}
+	case "ServiceRegistration.DeleteByID":
+		for _, id := range args.(*structs.ServiceRegistrationDeleteByIDRequest).IDs {
+			delete(f.services, id)
+		}
+	case "ServiceRegistration.GetService":
+		req := args.(*structs.ServiceRegistrationByNameRequest)
+		resp := reply.(*structs.ServiceRegistrationByNameResponse)
+		for _, service := range f.services {
+			if service.ServiceName == req.ServiceName {
+				resp.Services = append(resp.Services, service)
+			}
+		}
+		resp.Index = req.MinQueryIndex + 1
+	}
+	return nil
+}
+
+func TestConsulServicesTask(t *testing.T) {
+	t.Parallel()
+	task := mock.Job().TaskGroups[0].Tasks[0]
+	if out := consulServicesTask(task); out != task {
+		t.Fatalf("expected the task to be returned as is")
+	}
+
+	task.Services = append(task.Services, &structs.Service{
+		Name:      "nomad-web",
+		PortLabel: "http",
+		Provider:  structs.ServiceProviderNomad,
+	})
+	out := consulServicesTask(task)
+	if len(out.Services) != len(task.Services)-1 {
+		t.Fatalf("bad services: %#v", out.Services)
+	}
+	for _, service := range out.Services {
+		if service.Provider == structs.ServiceProviderNomad {
+			t.Fatalf("unexpected nomad service: %#v", service)
+		}
+	}
+}
+
+func TestNomadServiceClient_RegisterRemove(t *testing.T) {
+	t.Parallel()
+	rpc := &fakeServiceRegistrationRPC{services: make(map[string]*structs.ServiceRegistration)}
+	c := newNomadServiceClient("node", "", "global", "dc1", rpc.RPC, testLogger())
+
+	task := mock.Job().TaskGroups[0].Tasks[0]
+	task.Resources.Networks[0].IP = "192.168.0.100"
+	task.Resources.Networks[0].IPv6 = "2001:db8::1"
+	task.Services = []*structs.Service{
+		{
+			Name:      "web",
+			PortLabel: "http",
+			Tags:      []string{"foo"},
+			Provider:  structs.ServiceProviderNomad,
+		},
+		{
+			Name:      "consul-web",
+			PortLabel: "http",
+		},
+	}
+
+	if err := c.RegisterTask("alloc", task, nil, nil, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.sync(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	id := makeNomadServiceID("alloc", task.Name, task.Services[0])
+	if len(rpc.services) != 1 {
+		t.Fatalf("expected 1 registration; got %d", len(rpc.services))
+	}
+	reg, ok := rpc.services[id]
+	if !ok {
+		t.Fatalf("missing registration %q: %#v", id, rpc.services)
+	}
+	if reg.ServiceName != "web" || reg.NodeID != "node" || reg.Datacenter != "dc1" {
+		t.Fatalf("bad registration: %#v", reg)
+	}
+	if reg.Address != "192.168.0.100" || reg.AddressIPv6 != "2001:db8::1" {
+		t.Fatalf("bad addresses: %#v", reg)
+	}
+
+	c.RemoveTask("alloc", task)
+	if err := c.sync(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(rpc.services) != 0 {
+		t.Fatalf("expected registrations to be deleted: %#v", rpc.services)
+	}
+}
+
+func TestNomadServiceClient_NomadServices(t *testing.T) {
+	t.Parallel()
+	rpc := &fakeServiceRegistrationRPC{services: map[string]*structs.ServiceRegistration{
+		"a": {
+			ID:          "a",
+			ServiceName: "web",
+			Tags:        []string{"http"},
+			Address:     "10.0.0.1",
+			Port:        8080,
+			Status:      structs.ServiceRegistrationStatusPassing,
+		},
+		"b": {
+			ID:          "b",
+			ServiceName: "web",
+			Status:      structs.ServiceRegistrationStatusCritical,
+		},
+		"c": {
+			ID:          "c",
+			ServiceName: "db",
+		},
+	}}
+	c := newNomadServiceClient("node", "", "global", "dc1", rpc.RPC, testLogger())
+
+	// Only the passing instances are returned
+	services, index, err := c.NomadServices("web", 10)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("expected 1 service; got %#v", services)
+	}
+	if s := services[0]; s.ID != "a" || s.Address != "10.0.0.1" || s.Port != 8080 || len(s.Tags) != 1 {
+		t.Fatalf("bad service: %#v", s)
+	}
+	if index != 11 {
+		t.Fatalf("expected index 11; got %d", index)
+	}
+}
+
+func TestNomadServiceClient_ScriptCheck(t *testing.T) {
+	t.Parallel()
+	rpc := &fakeServiceRegistrationRPC{services: make(map[string]*structs.ServiceRegistration)}
+	c := newNomadServiceClient("node", "", "global", "dc1", rpc.RPC, testLogger())
+
+	task := mock.Job().TaskGroups[0].Tasks[0]
+	task.Services = []*structs.Service{
+		{
+			Name:     "web",
+			Provider: structs.ServiceProviderNomad,
+			Checks: []*structs.ServiceCheck{
+				{
+					Name:          "script",
+					Type:          structs.ServiceCheckScript,
+					Command:       "/bin/check",
+					Interval:      10 * time.Millisecond,
+					Timeout:       time.Second,
+					InitialStatus: structs.ServiceRegistrationStatusPassing,
+				},
+			},
+		},
+	}
+
+	// Script checks need the driver to support exec
+	if err := c.RegisterTask("alloc", task, nil, nil, nil); err == nil {
+		t.Fatalf("expected an error registering script checks without exec")
+	}
+
+	exec := &fakeScriptExecutor{code: 2}
+	if err := c.RegisterTask("alloc", task, nil, exec, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer c.RemoveTask("alloc", task)
+
+	id := makeNomadServiceID("alloc", task.Name, task.Services[0])
+	testutil.WaitForResult(func() (bool, error) {
+		c.l.Lock()
+		defer c.l.Unlock()
+		reg, ok := c.services[id]
+		if !ok {
+			return false, fmt.Errorf("missing registration %q", id)
+		}
+		if reg.Status != structs.ServiceRegistrationStatusCritical {
+			return false, fmt.Errorf("expected critical status; got %q", reg.Status)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestServiceProviders_Checks(t *testing.T) {
+	t.Parallel()
+	rpc := &fakeServiceRegistrationRPC{services: make(map[string]*structs.ServiceRegistration)}
+	nomad := newNomadServiceClient("node", "", "global", "dc1", rpc.RPC, testLogger())
+
+	// Consul is only queried for the checks of its services
+	consul := newMockConsulServiceClient()
+	consul.checksFn = func(*structs.Allocation) ([]*api.AgentCheck, error) {
+		return nil, fmt.Errorf("consul unreachable")
+	}
+	p := newServiceProviders(consul, nomad, nil, nil)
+
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Services = []*structs.Service{
+		{
+			Name:     "web",
+			Provider: structs.ServiceProviderNomad,
+			Checks: []*structs.ServiceCheck{
+				{
+					Name:     "script",
+					Type:     structs.ServiceCheckScript,
+					Command:  "/bin/check",
+					Interval: 10 * time.Millisecond,
+					Timeout:  time.Second,
+				},
+			},
+		},
+	}
+	if err := p.RegisterTask(alloc.ID, nil, task, nil, &fakeScriptExecutor{}, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer p.RemoveTask(alloc.ID, task)
+
+	testutil.WaitForResult(func() (bool, error) {
+		checks, err := p.Checks(alloc)
+		if err != nil {
+			return false, err
+		}
+		if len(checks) != 1 {
+			return false, fmt.Errorf("expected 1 check; got %d", len(checks))
+		}
+		if checks[0].Name != "script" || checks[0].Status != api.HealthPassing {
+			return false, fmt.Errorf("bad check: %#v", checks[0])
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// Checks of Consul services are looked up in Consul
+	task.Services = append(task.Services, &structs.Service{
+		Name:   "consul",
+		Checks: []*structs.ServiceCheck{{Name: "tcp", Type: structs.ServiceCheckTCP}},
+	})
+	if _, err := p.Checks(alloc); err == nil {
+		t.Fatalf("expected Consul to be queried")
+	}
+}
@@ -17,12 +17,22 @@ allocations/ (bucket)
     |--> alloc_runner persisted objects (k/v)
 	|--> <task-name>/ (bucket)
         |--> task_runner persisted objects (k/v)
+
+node_meta/ (bucket)
+|--> dynamic (k/v)
 */
 
 var (
 	// allocationsBucket is the bucket name containing all allocation related
 	// data
 	allocationsBucket = []byte("allocations")
+
+	// nodeMetaBucket is the bucket name containing the node meta applied at
+	// runtime
+	nodeMetaBucket = []byte("node_meta")
+
+	// dynamicNodeMetaKey is the key the dynamic node meta is stored at
+	dynamicNodeMetaKey = []byte("dynamic")
 )
 
 func putObject(bkt *bolt.Bucket, key []byte, obj interface{}) error {
@@ -212,3 +222,27 @@ func getAllAllocationIDs(tx *bolt.Tx) ([]string, error) {
 
 	return allocIDs, nil
 }
+
+// putDynamicNodeMeta persists the node meta applied at runtime.
+func putDynamicNodeMeta(tx *bolt.Tx, meta map[string]*string) error {
+	bkt, err := tx.CreateBucketIfNotExists(nodeMetaBucket)
+	if err != nil {
+		return err
+	}
+	return putObject(bkt, dynamicNodeMetaKey, meta)
+}
+
+// getDynamicNodeMeta returns the persisted node meta applied at runtime, or
+// nil if none was ever applied.
+func getDynamicNodeMeta(tx *bolt.Tx) (map[string]*string, error) {
+	bkt := tx.Bucket(nodeMetaBucket)
+	if bkt == nil || bkt.Get(dynamicNodeMetaKey) == nil {
+		return nil, nil
+	}
+
+	var meta map[string]*string
+	if err := getObject(bkt, dynamicNodeMetaKey, &meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
@@ -3,6 +3,7 @@ package stats
 import (
 	"log"
 	"math"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/shirou/gopsutil/disk"
 	"github.com/shirou/gopsutil/host"
 	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
 
 	shelpers "github.com/hashicorp/nomad/helper/stats"
 )
@@ -21,6 +23,8 @@ type HostStats struct {
 	CPU              []*CPUStats
 	DiskStats        []*DiskStats
 	AllocDirStats    *DiskStats
+	NetworkStats     []*NetworkStats
+	Pressure         *PressureStats
 	Uptime           uint64
 	Timestamp        int64
 	CPUTicksConsumed float64
@@ -52,6 +56,30 @@ type DiskStats struct {
 	Available         uint64
 	UsedPercent       float64
 	InodesUsedPercent float64
+	InodesUsed        uint64
+	InodesFree        uint64
+
+	// ReadBytes and WriteBytes are the bytes read from and written to the
+	// device per second, and IOUtilizationPercent the share of time the
+	// device was busy, since the previous collection
+	ReadBytes            float64
+	WriteBytes           float64
+	IOUtilizationPercent float64
+}
+
+// NetworkStats represents stats related to the traffic of a network interface.
+// Bytes and packets are per second since the previous collection; errors and
+// drops are totals since the interface came up.
+type NetworkStats struct {
+	Interface string
+	RxBytes   float64
+	TxBytes   float64
+	RxPackets float64
+	TxPackets float64
+	RxErrors  uint64
+	TxErrors  uint64
+	RxDropped uint64
+	TxDropped uint64
 }
 
 // NodeStatsCollector is an interface which is used for the puproses of mocking
@@ -70,6 +98,12 @@ type HostStatsCollector struct {
 	hostStats       *HostStats
 	hostStatsLock   sync.RWMutex
 	allocDir        string
+
+	// prevDiskIO and prevNetIO are the IO counters of the previous
+	// collection, at prevIOTime, the rates are computed from
+	prevDiskIO map[string]disk.IOCountersStat
+	prevNetIO  map[string]net.IOCountersStat
+	prevIOTime time.Time
 }
 
 // NewHostStatsCollector returns a HostStatsCollector. The allocDir is passed in
@@ -142,6 +176,66 @@ func (h *HostStatsCollector) Collect() error {
 	}
 	hs.DiskStats = diskStats
 
+	// The IO counters aren't available on every platform, in which case the
+	// rates are left empty
+	now := time.Now()
+	elapsed := now.Sub(h.prevIOTime).Seconds()
+	if h.prevIOTime.IsZero() {
+		elapsed = 0
+	}
+	diskIO, err := disk.IOCounters()
+	if err != nil {
+		diskIO = nil
+	}
+	for _, ds := range diskStats {
+		name := filepath.Base(ds.Device)
+		cur, ok := diskIO[name]
+		if !ok || elapsed <= 0 {
+			continue
+		}
+		prev, ok := h.prevDiskIO[name]
+		if !ok {
+			continue
+		}
+		ds.ReadBytes = counterRate(prev.ReadBytes, cur.ReadBytes, elapsed)
+		ds.WriteBytes = counterRate(prev.WriteBytes, cur.WriteBytes, elapsed)
+
+		// The IO time is in milliseconds
+		ds.IOUtilizationPercent = math.Min(counterRate(prev.IoTime, cur.IoTime, elapsed)/10, 100)
+	}
+
+	netIO, err := net.IOCounters(true)
+	if err != nil {
+		netIO = nil
+	}
+	netIOByName := make(map[string]net.IOCountersStat, len(netIO))
+	for _, cur := range netIO {
+		netIOByName[cur.Name] = cur
+		ns := &NetworkStats{
+			Interface: cur.Name,
+			RxErrors:  cur.Errin,
+			TxErrors:  cur.Errout,
+			RxDropped: cur.Dropin,
+			TxDropped: cur.Dropout,
+		}
+		if prev, ok := h.prevNetIO[cur.Name]; ok && elapsed > 0 {
+			ns.RxBytes = counterRate(prev.BytesRecv, cur.BytesRecv, elapsed)
+			ns.TxBytes = counterRate(prev.BytesSent, cur.BytesSent, elapsed)
+			ns.RxPackets = counterRate(prev.PacketsRecv, cur.PacketsRecv, elapsed)
+			ns.TxPackets = counterRate(prev.PacketsSent, cur.PacketsSent, elapsed)
+		}
+		hs.NetworkStats = append(hs.NetworkStats, ns)
+	}
+	h.prevDiskIO = diskIO
+	h.prevNetIO = netIOByName
+	h.prevIOTime = now
+
+	pressure, err := collectPressure(pressureDir)
+	if err != nil {
+		h.logger.Printf("[WARN] client: error fetching host pressure stats: %v", err)
+	}
+	hs.Pressure = pressure
+
 	// Getting the disk stats for the allocation directory
 	usage, err := disk.Usage(h.allocDir)
 	if err != nil {
@@ -179,6 +273,8 @@ func (h *HostStatsCollector) toDiskStats(usage *disk.UsageStat, partitionStat *d
 		Available:         usage.Free,
 		UsedPercent:       usage.UsedPercent,
 		InodesUsedPercent: usage.InodesUsedPercent,
+		InodesUsed:        usage.InodesUsed,
+		InodesFree:        usage.InodesFree,
 	}
 	if math.IsNaN(ds.UsedPercent) {
 		ds.UsedPercent = 0.0
@@ -195,6 +291,16 @@ func (h *HostStatsCollector) toDiskStats(usage *disk.UsageStat, partitionStat *d
 	return &ds
 }
 
+// counterRate returns the per second rate of a counter between two readings
+// elapsed seconds apart. A counter that went backwards was reset, and has no
+// rate.
+func counterRate(prev, cur uint64, elapsed float64) float64 {
+	if cur < prev || elapsed <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / elapsed
+}
+
 // HostCpuStatsCalculator calculates cpu usage percentages
 type HostCpuStatsCalculator struct {
 	prevIdle   float64
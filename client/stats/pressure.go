@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pressureDir is the directory of the pressure stall information files of the
+// Linux kernel
+const pressureDir = "/proc/pressure"
+
+// PressureStats represents the pressure stall information of the host: the
+// share of time tasks were stalled waiting for the CPU, memory or IO. It is
+// only available on Linux 4.20 and later.
+type PressureStats struct {
+	CPU    *PressureStat
+	Memory *PressureStat
+	IO     *PressureStat
+}
+
+// PressureStat represents the stall time of a resource, in percent, averaged
+// over 10, 60 and 300 seconds. Some is the share of time at least one task was
+// stalled; Full is the share of time all non-idle tasks were stalled at once.
+type PressureStat struct {
+	SomeAvg10  float64
+	SomeAvg60  float64
+	SomeAvg300 float64
+	FullAvg10  float64
+	FullAvg60  float64
+	FullAvg300 float64
+}
+
+// collectPressure reads the pressure stall information in dir. It returns nil
+// if it isn't available.
+func collectPressure(dir string) (*PressureStats, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, nil
+	}
+
+	var ps PressureStats
+	for _, resource := range []struct {
+		file string
+		stat **PressureStat
+	}{
+		{"cpu", &ps.CPU},
+		{"memory", &ps.Memory},
+		{"io", &ps.IO},
+	} {
+		f, err := os.Open(filepath.Join(dir, resource.file))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		stat, err := parsePressure(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s pressure: %v", resource.file, err)
+		}
+		*resource.stat = stat
+	}
+	return &ps, nil
+}
+
+// parsePressure parses a pressure stall information file, made of a "some"
+// and, except for the CPU on older kernels, a "full" line:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func parsePressure(r io.Reader) (*PressureStat, error) {
+	var ps PressureStat
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var avg10, avg60, avg300 *float64
+		switch fields[0] {
+		case "some":
+			avg10, avg60, avg300 = &ps.SomeAvg10, &ps.SomeAvg60, &ps.SomeAvg300
+		case "full":
+			avg10, avg60, avg300 = &ps.FullAvg10, &ps.FullAvg60, &ps.FullAvg300
+		default:
+			return nil, fmt.Errorf("unexpected line %q", scanner.Text())
+		}
+
+		for _, field := range fields[1:] {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("unexpected field %q", field)
+			}
+			var dst *float64
+			switch parts[0] {
+			case "avg10":
+				dst = avg10
+			case "avg60":
+				dst = avg60
+			case "avg300":
+				dst = avg300
+			default:
+				continue
+			}
+			v, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", parts[0], err)
+			}
+			*dst = v
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
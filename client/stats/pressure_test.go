@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePressure(t *testing.T) {
+	in := "some avg10=1.50 avg60=0.75 avg300=0.10 total=123456\n" +
+		"full avg10=0.50 avg60=0.25 avg300=0.00 total=4567\n"
+	ps, err := parsePressure(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := &PressureStat{
+		SomeAvg10:  1.5,
+		SomeAvg60:  0.75,
+		SomeAvg300: 0.1,
+		FullAvg10:  0.5,
+		FullAvg60:  0.25,
+	}
+	if !reflect.DeepEqual(ps, expected) {
+		t.Fatalf("expected %#v; got %#v", expected, ps)
+	}
+
+	if _, err := parsePressure(strings.NewReader("some avg10=high\n")); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestCollectPressure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pressure")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Missing pressure information isn't an error
+	ps, err := collectPressure(filepath.Join(dir, "missing"))
+	if err != nil || ps != nil {
+		t.Fatalf("expected no pressure: %#v %v", ps, err)
+	}
+
+	// The CPU has no full line on older kernels
+	cpu := "some avg10=2.00 avg60=1.00 avg300=0.50 total=1\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "cpu"), []byte(cpu), 0644); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ps, err = collectPressure(dir)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ps.CPU == nil || ps.CPU.SomeAvg10 != 2 || ps.Memory != nil || ps.IO != nil {
+		t.Fatalf("bad: %#v", ps)
+	}
+}
@@ -84,6 +84,20 @@ type AllocResourceUsage struct {
 	Timestamp int64
 }
 
+// NodeMetaResponse is the meta of the node along with the static and dynamic
+// meta it is built from.
+type NodeMetaResponse struct {
+	// Meta is the meta of the node: the static meta overlaid with the dynamic
+	// meta
+	Meta map[string]string
+
+	// Static is the meta set in the agent's configuration
+	Static map[string]string
+
+	// Dynamic is the meta applied at runtime. A nil value unsets the key.
+	Dynamic map[string]*string
+}
+
 // joinStringSet takes two slices of strings and joins them
 func joinStringSet(s1, s2 []string) []string {
 	lookup := make(map[string]struct{}, len(s1))
@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
@@ -21,6 +22,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	version "github.com/hashicorp/go-version"
 	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/cni"
 	"github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/client/driver"
 	"github.com/hashicorp/nomad/client/getter"
@@ -88,6 +90,10 @@ type TaskRunner struct {
 	// envBuilder is used to build the task's environment
 	envBuilder *env.Builder
 
+	// redactor redacts the secrets of the task from its events and from
+	// the logs of the task runner and its driver
+	redactor *secretRedactor
+
 	// driverNet is the network information returned by the driver
 	driverNet     *cstructs.DriverNetwork
 	driverNetLock sync.Mutex
@@ -98,6 +104,10 @@ type TaskRunner struct {
 	handle     driver.DriverHandle
 	handleLock sync.Mutex
 
+	// fsIsolation is the filesystem isolation of the driver of the task. It
+	// is guarded by the handleLock.
+	fsIsolation cstructs.FSIsolation
+
 	// artifactsDownloaded tracks whether the tasks artifacts have been
 	// downloaded
 	//
@@ -127,6 +137,21 @@ type TaskRunner struct {
 	// vaultClient is used to retrieve and renew any needed Vault token
 	vaultClient vaultclient.VaultClient
 
+	// siTokenDeriver is used to derive the Consul Service Identity token of
+	// Connect sidecar proxy tasks and of the Consul services of the task
+	siTokenDeriver SITokenDeriverFunc
+
+	// serviceTokens are the Service Identity tokens of the Consul services of
+	// the task by service name
+	serviceTokens     map[string]string
+	serviceTokensLock sync.Mutex
+
+	// connectBootstrapped tracks whether the Connect sidecar proxy has its
+	// token and bootstrap configuration written.
+	//
+	// Must acquire persistLock when accessing
+	connectBootstrapped bool
+
 	// templateManager is used to manage any consul-templates this task may have
 	templateManager *TaskTemplateManager
 
@@ -138,8 +163,12 @@ type TaskRunner struct {
 	unblocked   bool
 	unblockLock sync.Mutex
 
+	// startGate, if set, blocks the start of the task until it is closed so
+	// the lifecycle hooks of the task group can order its tasks
+	startGate <-chan struct{}
+
 	// restartCh is used to restart a task
-	restartCh chan *structs.TaskEvent
+	restartCh chan *RestartEvent
 
 	// signalCh is used to send a signal to a task
 	signalCh chan SignalEvent
@@ -203,11 +232,22 @@ type SignalEvent struct {
 	result chan<- error
 }
 
+// RestartEvent is a tuple of the restart event and whether it was caused by a
+// failure of the task
+type RestartEvent struct {
+	// e is the task event generating the restart
+	e *structs.TaskEvent
+
+	// failure is true if the restart counts against the restart policy
+	failure bool
+}
+
 // NewTaskRunner is used to create a new task context
 func NewTaskRunner(logger *log.Logger, config *config.Config,
 	stateDB *bolt.DB, updater TaskStateUpdater, taskDir *allocdir.TaskDir,
 	alloc *structs.Allocation, task *structs.Task,
-	vaultClient vaultclient.VaultClient, consulClient ConsulServiceAPI) *TaskRunner {
+	vaultClient vaultclient.VaultClient, consulClient ConsulServiceAPI,
+	siTokenDeriver SITokenDeriverFunc) *TaskRunner {
 
 	// Merge in the task resources
 	task.Resources = alloc.TaskResources[task.Name]
@@ -218,16 +258,30 @@ func NewTaskRunner(logger *log.Logger, config *config.Config,
 		logger.Printf("[ERR] client: alloc '%s' for missing task group '%s'", alloc.ID, alloc.TaskGroup)
 		return nil
 	}
-	restartTracker := newRestartTracker(tg.RestartPolicy, alloc.Job.Type)
+	// Tasks with a lifecycle hook that aren't sidecars are expected to exit,
+	// so they are only restarted on failure like batch tasks
+	jobType := alloc.Job.Type
+	if !task.RunsWithMain() {
+		jobType = structs.JobTypeBatch
+	}
+	restartTracker := newRestartTracker(tg.RestartPolicy, jobType)
 
 	// Initialize the environment builder
 	envBuilder := env.NewBuilder(config.Node, alloc, task, config.Region)
 
+	// Redact the secrets of the task from the logs of the task runner and
+	// its driver
+	redactor := newSecretRedactor()
+	if config.LogOutput != nil {
+		logger = log.New(redactor.writer(config.LogOutput), logger.Prefix(), logger.Flags())
+	}
+
 	tc := &TaskRunner{
 		config:           config,
 		stateDB:          stateDB,
 		updater:          updater,
 		logger:           logger,
+		redactor:         redactor,
 		restartTracker:   restartTracker,
 		alloc:            alloc,
 		task:             task,
@@ -236,13 +290,14 @@ func NewTaskRunner(logger *log.Logger, config *config.Config,
 		createdResources: driver.NewCreatedResources(),
 		consul:           consulClient,
 		vaultClient:      vaultClient,
+		siTokenDeriver:   siTokenDeriver,
 		vaultFuture:      NewTokenFuture().Set(""),
 		updateCh:         make(chan *structs.Allocation, 64),
 		destroyCh:        make(chan struct{}),
 		waitCh:           make(chan struct{}),
 		startCh:          make(chan struct{}, 1),
 		unblockCh:        make(chan struct{}),
-		restartCh:        make(chan *structs.TaskEvent),
+		restartCh:        make(chan *RestartEvent),
 		signalCh:         make(chan SignalEvent),
 	}
 
@@ -254,6 +309,12 @@ func (r *TaskRunner) MarkReceived() {
 	r.updater(r.task.Name, structs.TaskStatePending, structs.NewTaskEvent(structs.TaskReceived))
 }
 
+// SetStartGate sets a channel blocking the start of the task until it is
+// closed. It must be called before Run.
+func (r *TaskRunner) SetStartGate(gate <-chan struct{}) {
+	r.startGate = gate
+}
+
 // WaitCh returns a channel to wait for termination
 func (r *TaskRunner) WaitCh() <-chan struct{} {
 	return r.waitCh
@@ -341,6 +402,7 @@ func (r *TaskRunner) RestoreState() (string, error) {
 		} else {
 			// Store the recovered token
 			r.recoveredVaultToken = string(data)
+			r.SetSecrets(vaultSecrets, []string{r.recoveredVaultToken})
 		}
 	}
 
@@ -356,7 +418,7 @@ func (r *TaskRunner) RestoreState() (string, error) {
 		r.envBuilder.SetDriverNetwork(r.driverNet)
 
 		// Open a connection to the driver handle
-		ctx := driver.NewExecContext(r.taskDir, r.envBuilder.Build())
+		ctx := r.newExecContext()
 		handle, err := d.Open(ctx, snap.HandleID)
 
 		// In the case it fails, we relaunch the task in the Run() method.
@@ -504,8 +566,15 @@ func (r *TaskRunner) setState(state string, event *structs.TaskEvent) {
 		r.logger.Printf("[ERR] client: failed to save state of Task Runner for task %q: %v", r.task.Name, err)
 	}
 
-	// Indicate the task has been updated.
-	r.updater(r.task.Name, state, event)
+	// Indicate the task has been updated. Events are redacted as their
+	// messages may contain secrets, such as errors quoting templates.
+	r.updater(r.task.Name, state, r.redactor.redactEvent(event))
+}
+
+// SetSecrets sets the secrets of the source that are redacted from the task's
+// events and logs.
+func (r *TaskRunner) SetSecrets(source string, secrets []string) {
+	r.redactor.setSecrets(source, secrets)
 }
 
 // createDriver makes a driver for the task
@@ -518,7 +587,12 @@ func (r *TaskRunner) createDriver() (driver.Driver, error) {
 		r.setState(structs.TaskStatePending, structs.NewTaskEvent(structs.TaskDriverMessage).SetDriverMessage(msg))
 	}
 
-	driverCtx := driver.NewDriverContext(r.task.Name, r.alloc.ID, r.config, r.config.Node, r.logger, eventEmitter)
+	// Jobs registered before namespaces belong to the default namespace
+	namespace := r.alloc.Job.Namespace
+	if namespace == "" {
+		namespace = structs.DefaultNamespace
+	}
+	driverCtx := driver.NewDriverContext(r.task.Name, r.alloc.ID, namespace, r.config, r.config.Node, r.logger, eventEmitter)
 	d, err := driver.NewDriver(r.task.Driver, driverCtx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create driver '%s' for alloc %s: %v",
@@ -528,12 +602,82 @@ func (r *TaskRunner) createDriver() (driver.Driver, error) {
 	return d, err
 }
 
+// newExecContext returns the execution context of the task, which runs in
+// the network namespace of its allocation if its group has a CNI network.
+func (r *TaskRunner) newExecContext() *driver.ExecContext {
+	ctx := driver.NewExecContext(r.taskDir, r.envBuilder.Build())
+	if r.alloc.NetworkStatus != nil {
+		ctx.NetworkNamespace = cni.NetNSPath(r.alloc.ID)
+	}
+	return ctx
+}
+
+// allocDriverNetwork returns the network of a task in the CNI network of its
+// allocation, which advertises the address assigned to the allocation along
+// with the ports of the task. In bridge mode the address isn't reachable from
+// other hosts, so the host ports mapped to the ports of the group are
+// advertised instead.
+func (r *TaskRunner) allocDriverNetwork() *cstructs.DriverNetwork {
+	status := r.alloc.NetworkStatus
+	if status == nil || status.Address == "" {
+		return nil
+	}
+
+	portMap := make(map[string]int)
+	for _, n := range r.task.Resources.Networks {
+		for label, port := range n.PortLabels() {
+			portMap[label] = port
+		}
+	}
+
+	bridge := false
+	if r.alloc.SharedResources != nil {
+		for _, n := range r.alloc.SharedResources.Networks {
+			if n.Mode == structs.NetworkModeBridge {
+				bridge = true
+			}
+			for label, port := range n.PortMappings() {
+				portMap[label] = port
+			}
+		}
+	}
+	return &cstructs.DriverNetwork{
+		PortMap:       portMap,
+		IP:            status.Address,
+		AutoAdvertise: !bridge,
+	}
+}
+
+// addGroupNetworks adds the networks of the group of the allocation to the
+// resources of a copy of the task, so that its services can use their ports.
+func addGroupNetworks(task *structs.Task, alloc *structs.Allocation) {
+	if alloc.SharedResources == nil || len(alloc.SharedResources.Networks) == 0 || task.Resources == nil {
+		return
+	}
+	for _, n := range alloc.SharedResources.Networks {
+		task.Resources.Networks = append(task.Resources.Networks, n.Copy())
+	}
+}
+
 // Run is a long running routine used to manage the task
 func (r *TaskRunner) Run() {
 	defer close(r.waitCh)
 	r.logger.Printf("[DEBUG] client: starting task context for '%s' (alloc '%s')",
 		r.task.Name, r.alloc.ID)
 
+	// Wait for the lifecycle of the task group to allow the task to start
+	if r.startGate != nil {
+		select {
+		case <-r.startGate:
+		case <-r.destroyCh:
+			r.destroyLock.Lock()
+			event := r.destroyEvent
+			r.destroyLock.Unlock()
+			r.setState(structs.TaskStateDead, event)
+			return
+		}
+	}
+
 	if err := r.validateTask(); err != nil {
 		r.setState(
 			structs.TaskStateDead,
@@ -553,6 +697,10 @@ func (r *TaskRunner) Run() {
 		return
 	}
 
+	r.handleLock.Lock()
+	r.fsIsolation = tmpDrv.FSIsolation()
+	r.handleLock.Unlock()
+
 	// Build base task directory structure regardless of FS isolation abilities.
 	// This needs to happen before we start the Vault manager and call prestart
 	// as both those can write to the task directories
@@ -762,7 +910,9 @@ OUTER:
 					return
 				}
 			case structs.VaultChangeModeRestart:
-				r.Restart("vault", "new Vault token acquired")
+				r.Restart("vault", "new Vault token acquired", false)
+			case structs.VaultChangeModeScript:
+				r.RunScript("vault", "new Vault token acquired", r.task.Vault.ChangeScript)
 			case structs.VaultChangeModeNoop:
 				fallthrough
 			default:
@@ -842,12 +992,31 @@ func (r *TaskRunner) writeToken(token string) error {
 	return nil
 }
 
+// nomadServices returns the lister of the services registered with Nomad
+// for the templates of the task, or nil if the client can't list them.
+func (r *TaskRunner) nomadServices() templateServiceLister {
+	if lister, ok := r.consul.(templateServiceLister); ok {
+		return lister
+	}
+	return nil
+}
+
+// nomadVariables returns the reader of the variables stored by the servers
+// for the templates of the task, or nil if the client can't read them.
+func (r *TaskRunner) nomadVariables() templateVariableReader {
+	if reader, ok := r.consul.(templateVariableReader); ok {
+		return reader
+	}
+	return nil
+}
+
 // updatedTokenHandler is called when a new Vault token is retrieved. Things
 // that rely on the token should be updated here.
 func (r *TaskRunner) updatedTokenHandler() {
 
 	// Update the tasks environment
 	r.envBuilder.SetVaultToken(r.vaultFuture.Get(), r.task.Vault.Env)
+	r.SetSecrets(vaultSecrets, []string{r.vaultFuture.Get()})
 
 	if r.templateManager != nil {
 		r.templateManager.Stop()
@@ -855,7 +1024,7 @@ func (r *TaskRunner) updatedTokenHandler() {
 		// Create a new templateManager
 		var err error
 		r.templateManager, err = NewTaskTemplateManager(r, r.task.Templates,
-			r.config, r.vaultFuture.Get(), r.taskDir.Dir, r.envBuilder)
+			r.config, r.vaultFuture.Get(), r.taskDir.Dir, r.envBuilder, r.nomadServices(), r.nomadVariables())
 		if err != nil {
 			err := fmt.Errorf("failed to build task's template manager: %v", err)
 			r.setState(structs.TaskStateDead, structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(err).SetFailsTask())
@@ -882,6 +1051,7 @@ func (r *TaskRunner) prestart(alloc *structs.Allocation, task *structs.Task, res
 		}
 		r.logger.Printf("[DEBUG] client: retrieved Vault token for task %v in alloc %q", task.Name, alloc.ID)
 		r.envBuilder.SetVaultToken(r.vaultFuture.Get(), task.Vault.Env)
+		r.SetSecrets(vaultSecrets, []string{r.vaultFuture.Get()})
 	}
 
 	// If the job is a dispatch job and there is a payload write it to disk
@@ -920,8 +1090,36 @@ func (r *TaskRunner) prestart(alloc *structs.Allocation, task *structs.Task, res
 	for {
 		r.persistLock.Lock()
 		downloaded := r.artifactsDownloaded
+		bootstrapped := r.connectBootstrapped
 		r.persistLock.Unlock()
 
+		// Give the task the workload identity it calls the Nomad API with
+		if err := r.workloadIdentitySetup(alloc); err != nil {
+			wrapped := fmt.Errorf("failed to setup workload identity: %v", err)
+			r.logger.Printf("[DEBUG] client: alloc %q, task %q %v", alloc.ID, task.Name, wrapped)
+			r.setState(structs.TaskStatePending,
+				structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(wrapped))
+			r.restartTracker.SetStartError(structs.WrapRecoverable(wrapped.Error(), err))
+			goto RESTART
+		}
+
+		// Setup the token and bootstrap configuration of Connect sidecar
+		// proxies and gateways
+		if !bootstrapped && (task.Kind.IsConnectProxy() || task.Kind.IsConnectGateway()) {
+			if err := r.connectSidecarSetup(alloc, task); err != nil {
+				wrapped := fmt.Errorf("failed to setup Connect sidecar proxy: %v", err)
+				r.logger.Printf("[DEBUG] client: alloc %q, task %q %v", alloc.ID, task.Name, wrapped)
+				r.setState(structs.TaskStatePending,
+					structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(wrapped))
+				r.restartTracker.SetStartError(structs.WrapRecoverable(wrapped.Error(), err))
+				goto RESTART
+			}
+
+			r.persistLock.Lock()
+			r.connectBootstrapped = true
+			r.persistLock.Unlock()
+		}
+
 		// Download the task's artifacts
 		if !downloaded && len(task.Artifacts) > 0 {
 			r.setState(structs.TaskStatePending, structs.NewTaskEvent(structs.TaskDownloadingArtifacts))
@@ -958,7 +1156,7 @@ func (r *TaskRunner) prestart(alloc *structs.Allocation, task *structs.Task, res
 		if r.templateManager == nil {
 			var err error
 			r.templateManager, err = NewTaskTemplateManager(r, task.Templates,
-				r.config, r.vaultFuture.Get(), r.taskDir.Dir, r.envBuilder)
+				r.config, r.vaultFuture.Get(), r.taskDir.Dir, r.envBuilder, r.nomadServices(), r.nomadVariables())
 			if err != nil {
 				err := fmt.Errorf("failed to build task's template manager: %v", err)
 				r.setState(structs.TaskStateDead, structs.NewTaskEvent(structs.TaskSetupFailure).SetSetupError(err).SetFailsTask())
@@ -1107,7 +1305,7 @@ func (r *TaskRunner) run() {
 				res := r.handle.Signal(se.s)
 				se.result <- res
 
-			case event := <-r.restartCh:
+			case restartEvent := <-r.restartCh:
 				r.runningLock.Lock()
 				running := r.running
 				r.runningLock.Unlock()
@@ -1117,8 +1315,8 @@ func (r *TaskRunner) run() {
 					continue
 				}
 
-				r.logger.Printf("[DEBUG] client: restarting %s: %v", common, event.RestartReason)
-				r.setState(structs.TaskStateRunning, event)
+				r.logger.Printf("[DEBUG] client: restarting %s: %v", common, restartEvent.e.RestartReason)
+				r.setState(structs.TaskStateRunning, restartEvent.e)
 				r.killTask(nil)
 
 				close(stopCollection)
@@ -1127,9 +1325,9 @@ func (r *TaskRunner) run() {
 					<-handleWaitCh
 				}
 
-				// Since the restart isn't from a failure, restart immediately
+				// Restarts that aren't from a failure restart immediately
 				// and don't count against the restart policy
-				r.restartTracker.SetRestartTriggered()
+				r.restartTracker.SetRestartTriggered(restartEvent.failure)
 				break WAIT
 
 			case <-r.destroyCh:
@@ -1144,7 +1342,7 @@ func (r *TaskRunner) run() {
 
 				// Remove from consul before killing the task so that traffic
 				// can be rerouted
-				interpTask := interpolateServices(r.envBuilder.Build(), r.task)
+				interpTask := interpolateServices(r.envBuilder.Build(), r.task, r.alloc.DeploymentStatus.IsCanary())
 				r.consul.RemoveTask(r.alloc.ID, interpTask)
 
 				// Store the task event that provides context on the task
@@ -1193,7 +1391,7 @@ func (r *TaskRunner) run() {
 // stopping. Errors are logged.
 func (r *TaskRunner) cleanup() {
 	// Remove from Consul
-	interpTask := interpolateServices(r.envBuilder.Build(), r.task)
+	interpTask := interpolateServices(r.envBuilder.Build(), r.task, r.alloc.DeploymentStatus.IsCanary())
 	r.consul.RemoveTask(r.alloc.ID, interpTask)
 
 	drv, err := r.createDriver()
@@ -1204,7 +1402,7 @@ func (r *TaskRunner) cleanup() {
 
 	res := r.getCreatedResources()
 
-	ctx := driver.NewExecContext(r.taskDir, r.envBuilder.Build())
+	ctx := r.newExecContext()
 	attempts := 1
 	var cleanupErr error
 	for retry := true; retry; attempts++ {
@@ -1255,7 +1453,7 @@ func (r *TaskRunner) shouldRestart() bool {
 	}
 
 	// Unregister from Consul while waiting to restart.
-	interpTask := interpolateServices(r.envBuilder.Build(), r.task)
+	interpTask := interpolateServices(r.envBuilder.Build(), r.task, r.alloc.DeploymentStatus.IsCanary())
 	r.consul.RemoveTask(r.alloc.ID, interpTask)
 
 	// Sleep but watch for destroy events.
@@ -1331,7 +1529,11 @@ func (r *TaskRunner) startTask() error {
 	}
 
 	// Run prestart
-	ctx := driver.NewExecContext(r.taskDir, r.envBuilder.Build())
+	ctx := r.newExecContext()
+	if ctx.NetworkNamespace != "" && !drv.Abilities().NetworkNamespace {
+		err := fmt.Errorf("driver %q can't run tasks in the network of their group", r.task.Driver)
+		return structs.NewRecoverableError(err, false)
+	}
 	presp, err := drv.Prestart(ctx, r.task)
 
 	// Merge newly created resources into previously created resources
@@ -1344,6 +1546,14 @@ func (r *TaskRunner) startTask() error {
 		r.envBuilder.SetDriverNetwork(presp.Network)
 	}
 
+	// Tasks in the network of their allocation are given the ports of the
+	// allocation unless the driver set up its own network
+	if presp == nil || presp.Network == nil {
+		if net := r.allocDriverNetwork(); net != nil {
+			r.envBuilder.SetDriverNetwork(net)
+		}
+	}
+
 	if err != nil {
 		wrapped := fmt.Sprintf("failed to initialize task %q for alloc %q: %v",
 			r.task.Name, r.alloc.ID, err)
@@ -1352,7 +1562,7 @@ func (r *TaskRunner) startTask() error {
 	}
 
 	// Create a new context for Start since the environment may have been updated.
-	ctx = driver.NewExecContext(r.taskDir, r.envBuilder.Build())
+	ctx = r.newExecContext()
 
 	// Start the job
 	sresp, err := drv.Start(ctx, r.task)
@@ -1364,6 +1574,12 @@ func (r *TaskRunner) startTask() error {
 
 	}
 
+	// Tasks in the CNI network of their allocation are reached at its
+	// address unless the driver set up its own network
+	if sresp.Network == nil {
+		sresp.Network = r.allocDriverNetwork()
+	}
+
 	// Update environment with the network defined by the driver's Start method.
 	r.envBuilder.SetDriverNetwork(sresp.Network)
 
@@ -1399,15 +1615,33 @@ func (r *TaskRunner) registerServices(d driver.Driver, h driver.DriverHandle, n
 		// Allow set the script executor if the driver supports it
 		exec = h
 	}
-	interpolatedTask := interpolateServices(r.envBuilder.Build(), r.task)
-	return r.consul.RegisterTask(r.alloc.ID, interpolatedTask, exec, n)
+	if err := r.setServiceTokens(r.task); err != nil {
+		return err
+	}
+	interpolatedTask := interpolateServices(r.envBuilder.Build(), r.task, r.alloc.DeploymentStatus.IsCanary())
+	addGroupNetworks(interpolatedTask, r.alloc)
+	return r.consul.RegisterTask(r.alloc.ID, r.consulConfig(), interpolatedTask, r, exec, n)
+}
+
+// consulConfig returns the Consul namespace and partition the services of the
+// task group are registered in.
+func (r *TaskRunner) consulConfig() *structs.Consul {
+	tg := r.alloc.Job.LookupTaskGroup(r.alloc.TaskGroup)
+	if tg == nil {
+		return nil
+	}
+	return tg.Consul
 }
 
 // interpolateServices interpolates tags in a service and checks with values from the
-// task's environment.
-func interpolateServices(taskEnv *env.TaskEnv, task *structs.Task) *structs.Task {
+// task's environment. The services of canaries use their canary tags, if any.
+func interpolateServices(taskEnv *env.TaskEnv, task *structs.Task, canary bool) *structs.Task {
 	taskCopy := task.Copy()
 	for _, service := range taskCopy.Services {
+		if canary && len(service.CanaryTags) != 0 {
+			service.Tags = service.CanaryTags
+		}
+		service.CanaryTags = nil
 		for _, check := range service.Checks {
 			check.Name = taskEnv.ReplaceEnv(check.Name)
 			check.Type = taskEnv.ReplaceEnv(check.Type)
@@ -1421,10 +1655,21 @@ func interpolateServices(taskEnv *env.TaskEnv, task *structs.Task) *structs.Task
 		service.Name = taskEnv.ReplaceEnv(service.Name)
 		service.PortLabel = taskEnv.ReplaceEnv(service.PortLabel)
 		service.Tags = taskEnv.ParseAndReplace(service.Tags)
+		service.Meta = interpolateMap(taskEnv, service.Meta)
+		service.TaggedAddresses = interpolateMap(taskEnv, service.TaggedAddresses)
 	}
 	return taskCopy
 }
 
+// interpolateMap interpolates the values of the map with the task's
+// environment in place and returns it.
+func interpolateMap(taskEnv *env.TaskEnv, m map[string]string) map[string]string {
+	for k, v := range m {
+		m[k] = taskEnv.ReplaceEnv(v)
+	}
+	return m
+}
+
 // buildTaskDir creates the task directory before driver.Prestart. It is safe
 // to call multiple times as its state is persisted.
 func (r *TaskRunner) buildTaskDir(fsi cstructs.FSIsolation) error {
@@ -1560,7 +1805,7 @@ func (r *TaskRunner) handleUpdate(update *structs.Allocation) error {
 		}
 
 		// Update services in Consul
-		if err := r.updateServices(drv, r.handle, r.task, updatedTask); err != nil {
+		if err := r.updateServices(drv, r.handle, r.task, updatedTask, update); err != nil {
 			mErr.Errors = append(mErr.Errors, fmt.Errorf("error updating services and checks in Consul: %v", err))
 		}
 	}
@@ -1577,19 +1822,25 @@ func (r *TaskRunner) handleUpdate(update *structs.Allocation) error {
 	return mErr.ErrorOrNil()
 }
 
-// updateServices and checks with Consul.
-func (r *TaskRunner) updateServices(d driver.Driver, h driver.ScriptExecutor, old, new *structs.Task) error {
+// updateServices and checks with Consul. The update is the allocation of the
+// new task.
+func (r *TaskRunner) updateServices(d driver.Driver, h driver.ScriptExecutor, old, new *structs.Task, update *structs.Allocation) error {
 	var exec driver.ScriptExecutor
 	if d.Abilities().Exec {
 		// Allow set the script executor if the driver supports it
 		exec = h
 	}
-	newInterpolatedTask := interpolateServices(r.envBuilder.Build(), new)
-	oldInterpolatedTask := interpolateServices(r.envBuilder.Build(), old)
+	if err := r.setServiceTokens(new); err != nil {
+		return err
+	}
+	newInterpolatedTask := interpolateServices(r.envBuilder.Build(), new, update.DeploymentStatus.IsCanary())
+	oldInterpolatedTask := interpolateServices(r.envBuilder.Build(), old, r.alloc.DeploymentStatus.IsCanary())
+	addGroupNetworks(newInterpolatedTask, update)
+	addGroupNetworks(oldInterpolatedTask, r.alloc)
 	r.driverNetLock.Lock()
 	net := r.driverNet.Copy()
 	r.driverNetLock.Unlock()
-	return r.consul.UpdateTask(r.alloc.ID, oldInterpolatedTask, newInterpolatedTask, exec, net)
+	return r.consul.UpdateTask(r.alloc.ID, r.consulConfig(), oldInterpolatedTask, newInterpolatedTask, r, exec, net)
 }
 
 // handleDestroy kills the task handle. In the case that killing fails,
@@ -1617,10 +1868,14 @@ func (r *TaskRunner) handleDestroy(handle driver.DriverHandle) (destroyed bool,
 	return
 }
 
-// Restart will restart the task
-func (r *TaskRunner) Restart(source, reason string) {
+// Restart will restart the task. If failure is true the restart counts
+// against the restart policy of the task.
+func (r *TaskRunner) Restart(source, reason string, failure bool) {
 	reasonStr := fmt.Sprintf("%s: %s", source, reason)
-	event := structs.NewTaskEvent(structs.TaskRestartSignal).SetRestartReason(reasonStr)
+	event := &RestartEvent{
+		e:       structs.NewTaskEvent(structs.TaskRestartSignal).SetRestartReason(reasonStr),
+		failure: failure,
+	}
 
 	select {
 	case r.restartCh <- event:
@@ -1644,11 +1899,78 @@ func (r *TaskRunner) Signal(source, reason string, s os.Signal) error {
 	select {
 	case r.signalCh <- se:
 	case <-r.waitCh:
+		// The task has exited so there is nothing to signal
+		return nil
 	}
 
 	return <-resCh
 }
 
+// RunScript runs a change script inside the task. If the script fails and
+// FailOnError is set the task is killed.
+func (r *TaskRunner) RunScript(source, reason string, script *structs.ChangeScript) {
+	r.runningLock.Lock()
+	running := r.running
+	r.runningLock.Unlock()
+	h := r.getHandle()
+	if !running || h == nil {
+		r.logger.Printf("[DEBUG] client: skipping change script %q of task %v for alloc %q: task not running",
+			script.Command, r.task.Name, r.alloc.ID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), script.Timeout)
+	defer cancel()
+	_, code, err := h.Exec(ctx, script.Command, script.Args)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	reasonStr := fmt.Sprintf("%s: %s", source, reason)
+	var failure string
+	if err != nil {
+		failure = fmt.Sprintf("change script %q failed: %v", script.Command, err)
+	} else if code != 0 {
+		failure = fmt.Sprintf("change script %q failed with exit code %d", script.Command, code)
+	}
+
+	if failure == "" {
+		msg := fmt.Sprintf("%s: change script %q succeeded", reasonStr, script.Command)
+		r.setState(structs.TaskStateRunning, structs.NewTaskEvent(structs.TaskChangeScript).SetMessage(msg))
+		return
+	}
+
+	r.logger.Printf("[WARN] client: %s for task %v on alloc %q", failure, r.task.Name, r.alloc.ID)
+	if script.FailOnError {
+		r.Kill(source, failure, true)
+		return
+	}
+
+	msg := fmt.Sprintf("%s: %s", reasonStr, failure)
+	r.setState(structs.TaskStateRunning, structs.NewTaskEvent(structs.TaskChangeScript).SetMessage(msg))
+}
+
+// Exec runs the command inside the running task and returns its output and
+// exit code.
+func (r *TaskRunner) Exec(ctx context.Context, cmd string, args []string) ([]byte, int, error) {
+	r.runningLock.Lock()
+	running := r.running
+	r.runningLock.Unlock()
+	h := r.getHandle()
+	if !running || h == nil {
+		return nil, 0, fmt.Errorf("task %q is not running", r.task.Name)
+	}
+	return h.Exec(ctx, cmd, args)
+}
+
+// FSIsolation returns the filesystem isolation of the driver of the task,
+// which is FSIsolationNone until the task is started.
+func (r *TaskRunner) FSIsolation() cstructs.FSIsolation {
+	r.handleLock.Lock()
+	defer r.handleLock.Unlock()
+	return r.fsIsolation
+}
+
 // Kill will kill a task and store the error, no longer restarting the task. If
 // fail is set, the task is marked as having failed.
 func (r *TaskRunner) Kill(source, reason string, fail bool) {
@@ -1735,21 +2057,65 @@ func (r *TaskRunner) setCreatedResources(cr *driver.CreatedResources) {
 // emitStats emits resource usage stats of tasks to remote metrics collector
 // sinks
 func (r *TaskRunner) emitStats(ru *cstructs.TaskResourceUsage) {
-	if ru.ResourceUsage.MemoryStats != nil && r.config.PublishAllocationMetrics {
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "memory", "rss"}, float32(ru.ResourceUsage.MemoryStats.RSS))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "memory", "cache"}, float32(ru.ResourceUsage.MemoryStats.Cache))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "memory", "swap"}, float32(ru.ResourceUsage.MemoryStats.Swap))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "memory", "max_usage"}, float32(ru.ResourceUsage.MemoryStats.MaxUsage))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "memory", "kernel_usage"}, float32(ru.ResourceUsage.MemoryStats.KernelUsage))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "memory", "kernel_max_usage"}, float32(ru.ResourceUsage.MemoryStats.KernelMaxUsage))
-	}
-
-	if ru.ResourceUsage.CpuStats != nil && r.config.PublishAllocationMetrics {
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "cpu", "total_percent"}, float32(ru.ResourceUsage.CpuStats.Percent))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "cpu", "system"}, float32(ru.ResourceUsage.CpuStats.SystemMode))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "cpu", "user"}, float32(ru.ResourceUsage.CpuStats.UserMode))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "cpu", "throttled_time"}, float32(ru.ResourceUsage.CpuStats.ThrottledTime))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "cpu", "throttled_periods"}, float32(ru.ResourceUsage.CpuStats.ThrottledPeriods))
-		metrics.SetGauge([]string{"client", "allocs", r.alloc.Job.Name, r.alloc.TaskGroup, r.alloc.ID, r.task.Name, "cpu", "total_ticks"}, float32(ru.ResourceUsage.CpuStats.TotalTicks))
+	if !r.config.PublishAllocationMetrics {
+		return
+	}
+
+	if ms := ru.ResourceUsage.MemoryStats; ms != nil {
+		metrics.SetGauge(r.allocMetricKey("memory", "rss"), float32(ms.RSS))
+		metrics.SetGauge(r.allocMetricKey("memory", "cache"), float32(ms.Cache))
+		metrics.SetGauge(r.allocMetricKey("memory", "swap"), float32(ms.Swap))
+		metrics.SetGauge(r.allocMetricKey("memory", "max_usage"), float32(ms.MaxUsage))
+		metrics.SetGauge(r.allocMetricKey("memory", "kernel_usage"), float32(ms.KernelUsage))
+		metrics.SetGauge(r.allocMetricKey("memory", "kernel_max_usage"), float32(ms.KernelMaxUsage))
+	}
+
+	if cs := ru.ResourceUsage.CpuStats; cs != nil {
+		metrics.SetGauge(r.allocMetricKey("cpu", "total_percent"), float32(cs.Percent))
+		metrics.SetGauge(r.allocMetricKey("cpu", "system"), float32(cs.SystemMode))
+		metrics.SetGauge(r.allocMetricKey("cpu", "user"), float32(cs.UserMode))
+		metrics.SetGauge(r.allocMetricKey("cpu", "throttled_time"), float32(cs.ThrottledTime))
+		metrics.SetGauge(r.allocMetricKey("cpu", "throttled_periods"), float32(cs.ThrottledPeriods))
+		metrics.SetGauge(r.allocMetricKey("cpu", "total_ticks"), float32(cs.TotalTicks))
+	}
+}
+
+// allocMetricKey returns the key of an allocation metric of the task. The
+// configured labels of the allocation come before the name of the metric.
+func (r *TaskRunner) allocMetricKey(name ...string) []string {
+	labels := r.config.AllocationMetricLabels
+	key := make([]string, 0, 2+len(labels)+len(name))
+	key = append(key, "client", "allocs")
+	for _, label := range labels {
+		key = append(key, allocMetricLabel(label, r.alloc, r.task.Name))
+	}
+	return append(key, name...)
+}
+
+// allocMetricLabel returns the value of a label of the allocation metrics of
+// a task. Labels without a value, like missing job meta keys, are "none".
+func allocMetricLabel(label string, alloc *structs.Allocation, task string) string {
+	var value string
+	switch label {
+	case config.AllocationMetricLabelJob:
+		value = alloc.Job.Name
+	case config.AllocationMetricLabelGroup:
+		value = alloc.TaskGroup
+	case config.AllocationMetricLabelTask:
+		value = task
+	case config.AllocationMetricLabelAllocID:
+		value = alloc.ID
+	case config.AllocationMetricLabelNamespace:
+		// Jobs registered before namespaces belong to the default namespace
+		value = alloc.Job.Namespace
+		if value == "" {
+			value = structs.DefaultNamespace
+		}
+	default:
+		value = alloc.Job.Meta[strings.TrimPrefix(label, config.AllocationMetricLabelMetaPrefix)]
+	}
+	if value == "" {
+		return "none"
 	}
+	return value
 }
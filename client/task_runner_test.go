@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -16,7 +17,9 @@ import (
 	"github.com/boltdb/bolt"
 	"github.com/golang/snappy"
 	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/client/cni"
 	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver/env"
 	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/client/vaultclient"
 	"github.com/hashicorp/nomad/nomad/mock"
@@ -123,7 +126,7 @@ func testTaskRunnerFromAlloc(t *testing.T, restarts bool, alloc *structs.Allocat
 
 	vclient := vaultclient.NewMockVaultClient()
 	cclient := newMockConsulServiceClient()
-	tr := NewTaskRunner(logger, conf, db, upd.Update, taskDir, alloc, task, vclient, cclient)
+	tr := NewTaskRunner(logger, conf, db, upd.Update, taskDir, alloc, task, vclient, cclient, nil)
 	if !restarts {
 		tr.restartTracker = noRestartsTracker()
 	}
@@ -200,6 +203,52 @@ func TestTaskRunner_SimpleRun(t *testing.T) {
 	}
 }
 
+func TestTaskRunner_AllocNetwork(t *testing.T) {
+	t.Parallel()
+	ctx := testTaskRunner(t, false)
+	defer ctx.allocDir.Destroy()
+
+	// Without a CNI network the task uses the network of the host
+	if ns := ctx.tr.newExecContext().NetworkNamespace; ns != "" {
+		t.Fatalf("unexpected network namespace %q", ns)
+	}
+	if net := ctx.tr.allocDriverNetwork(); net != nil {
+		t.Fatalf("unexpected network %#v", net)
+	}
+
+	ctx.tr.task.Resources.Networks[0].DynamicPorts[0].Value = 9876
+	ctx.tr.alloc.NetworkStatus = &structs.AllocNetworkStatus{
+		InterfaceName: "eth0",
+		Address:       "10.1.0.5",
+	}
+	if ns := ctx.tr.newExecContext().NetworkNamespace; ns != cni.NetNSPath(ctx.tr.alloc.ID) {
+		t.Fatalf("bad network namespace %q", ns)
+	}
+
+	expected := &cstructs.DriverNetwork{
+		PortMap:       map[string]int{"main": 5000, "http": 9876},
+		IP:            "10.1.0.5",
+		AutoAdvertise: true,
+	}
+	if net := ctx.tr.allocDriverNetwork(); !reflect.DeepEqual(net, expected) {
+		t.Fatalf("got %#v; want %#v", net, expected)
+	}
+
+	// In bridge mode the host ports of the group are advertised
+	ctx.tr.alloc.SharedResources.Networks = []*structs.NetworkResource{
+		{
+			Mode:          structs.NetworkModeBridge,
+			IP:            "192.168.0.100",
+			ReservedPorts: []structs.Port{{Label: "api", Value: 25000, To: 8080}},
+		},
+	}
+	expected.PortMap["api"] = 8080
+	expected.AutoAdvertise = false
+	if net := ctx.tr.allocDriverNetwork(); !reflect.DeepEqual(net, expected) {
+		t.Fatalf("got %#v; want %#v", net, expected)
+	}
+}
+
 func TestTaskRunner_Run_RecoverableStartError(t *testing.T) {
 	t.Parallel()
 	alloc := mock.Alloc()
@@ -396,7 +445,7 @@ func TestTaskRunner_SaveRestoreState(t *testing.T) {
 	// Create a new task runner
 	task2 := &structs.Task{Name: ctx.tr.task.Name, Driver: ctx.tr.task.Driver, Vault: ctx.tr.task.Vault}
 	tr2 := NewTaskRunner(ctx.tr.logger, ctx.tr.config, ctx.tr.stateDB, ctx.upd.Update,
-		ctx.tr.taskDir, ctx.tr.alloc, task2, ctx.tr.vaultClient, ctx.tr.consul)
+		ctx.tr.taskDir, ctx.tr.alloc, task2, ctx.tr.vaultClient, ctx.tr.consul, ctx.tr.siTokenDeriver)
 	tr2.restartTracker = noRestartsTracker()
 	if _, err := tr2.RestoreState(); err != nil {
 		t.Fatalf("err: %v", err)
@@ -654,7 +703,7 @@ func TestTaskRunner_RestartTask(t *testing.T) {
 	// Wait for it to start
 	go func() {
 		testWaitForTaskToStart(t, ctx)
-		ctx.tr.Restart("test", "restart")
+		ctx.tr.Restart("test", "restart", false)
 
 		// Wait for it to restart then kill
 		go func() {
@@ -1428,6 +1477,66 @@ func TestTaskRunner_VaultManager_Signal(t *testing.T) {
 	})
 }
 
+func TestTaskRunner_VaultManager_Script(t *testing.T) {
+	t.Parallel()
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Driver = "mock_driver"
+	task.Config = map[string]interface{}{
+		"exit_code": "0",
+		"run_for":   "10s",
+	}
+	task.Vault = &structs.Vault{
+		Policies:   []string{"default"},
+		ChangeMode: structs.VaultChangeModeScript,
+		ChangeScript: &structs.ChangeScript{
+			Command: "/bin/reload",
+			Timeout: time.Second,
+		},
+	}
+
+	ctx := testTaskRunnerFromAlloc(t, false, alloc)
+	ctx.tr.MarkReceived()
+	go ctx.tr.Run()
+	defer ctx.Cleanup()
+
+	// Wait for the task to start
+	testWaitForTaskToStart(t, ctx)
+
+	// Error the token renewal
+	vc := ctx.tr.vaultClient.(*vaultclient.MockVaultClient)
+	renewalCh, ok := vc.RenewTokens[ctx.tr.vaultFuture.Get()]
+	if !ok {
+		t.Fatalf("no renewal channel")
+	}
+
+	renewalCh <- fmt.Errorf("Test killing")
+	close(renewalCh)
+
+	// Ensure the change script ran
+	testutil.WaitForResult(func() (bool, error) {
+		if l := len(ctx.upd.events); l != 4 {
+			return false, fmt.Errorf("Expect four events; got %#v", ctx.upd.events)
+		}
+
+		if ctx.upd.events[2].Type != structs.TaskStarted {
+			return false, fmt.Errorf("Third Event was %v; want %v", ctx.upd.events[2].Type, structs.TaskStarted)
+		}
+
+		if ctx.upd.events[3].Type != structs.TaskChangeScript {
+			return false, fmt.Errorf("Fourth Event was %v; want %v", ctx.upd.events[3].Type, structs.TaskChangeScript)
+		}
+
+		if msg := ctx.upd.events[3].Message; !strings.Contains(msg, "succeeded") {
+			return false, fmt.Errorf("Unexpected change script message %q", msg)
+		}
+
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
 // Test that the payload is written to disk
 func TestTaskRunner_SimpleRun_Dispatch(t *testing.T) {
 	t.Parallel()
@@ -1614,3 +1723,81 @@ func TestTaskRunner_Pre06ScriptCheck(t *testing.T) {
 	t.Run(run("0.5.6", "java", "tcp", false))
 	t.Run(run("0.5.6", "mock_driver", "tcp", false))
 }
+
+func TestTaskRunner_AllocMetricKey(t *testing.T) {
+	t.Parallel()
+	alloc := mock.Alloc()
+	alloc.Job.Meta = map[string]string{"team": "payments"}
+	r := &TaskRunner{
+		config: &config.Config{},
+		alloc:  alloc,
+		task:   alloc.Job.TaskGroups[0].Tasks[0],
+	}
+
+	// The default labels keep the historical keys
+	r.config.AllocationMetricLabels = config.DefaultAllocationMetricLabels
+	expected := []string{"client", "allocs", alloc.Job.Name, alloc.TaskGroup, alloc.ID, r.task.Name, "memory", "rss"}
+	if key := r.allocMetricKey("memory", "rss"); !reflect.DeepEqual(key, expected) {
+		t.Fatalf("expected %v; got %v", expected, key)
+	}
+
+	// Configured labels are emitted in order, without the allocation ID
+	r.config.AllocationMetricLabels = []string{"namespace", "meta.team", "job", "meta.missing"}
+	expected = []string{"client", "allocs", "default", "payments", alloc.Job.Name, "none", "cpu", "user"}
+	if key := r.allocMetricKey("cpu", "user"); !reflect.DeepEqual(key, expected) {
+		t.Fatalf("expected %v; got %v", expected, key)
+	}
+
+	// The namespace is the one of the job
+	alloc.Job.Namespace = "billing"
+	r.config.AllocationMetricLabels = []string{"namespace", "job"}
+	expected = []string{"client", "allocs", "billing", alloc.Job.Name, "cpu", "user"}
+	if key := r.allocMetricKey("cpu", "user"); !reflect.DeepEqual(key, expected) {
+		t.Fatalf("expected %v; got %v", expected, key)
+	}
+
+	// No labels aggregate the metrics of every allocation
+	r.config.AllocationMetricLabels = nil
+	expected = []string{"client", "allocs", "cpu", "user"}
+	if key := r.allocMetricKey("cpu", "user"); !reflect.DeepEqual(key, expected) {
+		t.Fatalf("expected %v; got %v", expected, key)
+	}
+}
+
+func TestTaskRunner_InterpolateServices_Canary(t *testing.T) {
+	t.Parallel()
+	task := &structs.Task{
+		Name: "web",
+		Services: []*structs.Service{
+			{
+				Name:       "web",
+				Tags:       []string{"live", "${NOMAD_META_version}"},
+				CanaryTags: []string{"canary", "${NOMAD_META_version}"},
+			},
+			{
+				Name: "admin",
+				Tags: []string{"admin"},
+			},
+		},
+	}
+	taskEnv := env.NewTaskEnv(map[string]string{"NOMAD_META_version": "v2"}, nil)
+
+	out := interpolateServices(taskEnv, task, false)
+	if tags := out.Services[0].Tags; !reflect.DeepEqual(tags, []string{"live", "v2"}) {
+		t.Fatalf("bad tags: %v", tags)
+	}
+
+	// Canaries use their canary tags, or their tags if they have none
+	out = interpolateServices(taskEnv, task, true)
+	if tags := out.Services[0].Tags; !reflect.DeepEqual(tags, []string{"canary", "v2"}) {
+		t.Fatalf("bad canary tags: %v", tags)
+	}
+	if tags := out.Services[1].Tags; !reflect.DeepEqual(tags, []string{"admin"}) {
+		t.Fatalf("bad tags: %v", tags)
+	}
+
+	// The task itself is left untouched
+	if tags := task.Services[0].Tags; !reflect.DeepEqual(tags, []string{"live", "${NOMAD_META_version}"}) {
+		t.Fatalf("task modified: %v", tags)
+	}
+}
@@ -0,0 +1,739 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	ctconf "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/nomad/client/allocdir"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/structs"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+const (
+	// templateDataDir is the directory of the task's secrets directory the
+	// data of the template functions implemented by Nomad is written to
+	templateDataDir = ".templates"
+
+	// templateDataWaitTime is the maximum time the blocking queries fetching
+	// the Nomad data of templates wait for a change
+	templateDataWaitTime = 5 * time.Minute
+
+	// templateDataMaxFailures is the number of consecutive failures fetching
+	// the data of a call after which the task is killed
+	templateDataMaxFailures = 12
+
+	// templateDataMaxBackoff is the maximum time waited between failed
+	// fetches of the data of a call
+	templateDataMaxBackoff = time.Minute
+
+	// templateKVPollInterval is the base interval Vault KV secrets are polled
+	// at, as they aren't leased. It is staggered by up to its value.
+	templateKVPollInterval = time.Minute
+)
+
+var (
+	// templateDataFunctions are the template functions implemented by Nomad
+	// rather than by consul-template. Their calls are replaced by the
+	// parsing of JSON files Nomad writes and keeps up to date before the
+	// templates are handed to consul-template.
+	templateDataFunctions = []string{"kvMetadata", "kvSecret", "nomadService", "nomadVar"}
+
+	// nomadServiceRe matches the argument of nomadService, an optional tag
+	// followed by the name of the service
+	nomadServiceRe = regexp.MustCompile(`\A(?:(?P<tag>[[:word:]=:.\-]+)\.)?(?P<name>[[:word:]\-]+)\z`)
+
+	// nomadVarRe matches the path of a variable
+	nomadVarRe = regexp.MustCompile(`\A[a-zA-Z0-9\-_.~]+(/[a-zA-Z0-9\-_.~]+)*\z`)
+)
+
+// TemplateDataCall is a call of a template function implemented by Nomad.
+// The arguments of the calls must be literals.
+type TemplateDataCall struct {
+	// Function is the name of the function called
+	Function string
+
+	// Args are the values of the arguments of the call
+	Args []string
+}
+
+// String returns the call as written in templates.
+func (c *TemplateDataCall) String() string {
+	parts := []string{c.Function}
+	for i, arg := range c.Args {
+		// The version of kvSecret is a number
+		if c.Function == "kvSecret" && i == 1 {
+			parts = append(parts, arg)
+			continue
+		}
+		parts = append(parts, strconv.Quote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// fileName returns the name of the file the data of the call is written to.
+// Calls with the same arguments share their file.
+func (c *TemplateDataCall) fileName() string {
+	sum := sha256.Sum256([]byte(c.String()))
+	return fmt.Sprintf("%s-%x.json", c.Function, sum[:8])
+}
+
+// validate returns an error if the arguments of the call are invalid.
+func (c *TemplateDataCall) validate() error {
+	switch c.Function {
+	case "nomadService":
+		if len(c.Args) != 1 || !nomadServiceRe.MatchString(c.Args[0]) {
+			return fmt.Errorf("%s: expected a service name, optionally prefixed by a tag", c)
+		}
+	case "nomadVar":
+		if len(c.Args) != 1 || !nomadVarRe.MatchString(c.Args[0]) {
+			return fmt.Errorf("%s: expected the path of a variable", c)
+		}
+	case "kvMetadata":
+		if len(c.Args) != 1 || kvPath(c.Args[0]) == "" {
+			return fmt.Errorf("%s: expected the path of a secret", c)
+		}
+	case "kvSecret":
+		if len(c.Args) < 1 || len(c.Args) > 2 || kvPath(c.Args[0]) == "" {
+			return fmt.Errorf("%s: expected the path of a secret and an optional version", c)
+		}
+		if len(c.Args) == 2 {
+			if v, err := strconv.Atoi(c.Args[1]); err != nil || v < 0 {
+				return fmt.Errorf("%s: invalid version %q", c, c.Args[1])
+			}
+		}
+	default:
+		return fmt.Errorf("%q isn't a template function implemented by Nomad", c.Function)
+	}
+	return nil
+}
+
+// templateDataSpan is the span of a call of a template function implemented
+// by Nomad in the contents of a template.
+type templateDataSpan struct {
+	start, end int
+	call       *TemplateDataCall
+}
+
+// RewriteTemplateDataCalls replaces the calls of the template functions
+// implemented by Nomad with the template expressions returned by replace,
+// which must evaluate to the data of the call. The calls must be the first
+// command of their pipeline and their arguments literals.
+func RewriteTemplateDataCalls(contents, leftDelim, rightDelim string,
+	replace func(*TemplateDataCall) (string, error)) (string, error) {
+
+	stubs := make(template.FuncMap, len(templateFunctions))
+	for _, f := range templateFunctions {
+		stubs[f] = func(...interface{}) interface{} { return nil }
+	}
+	t, err := template.New("").Delims(leftDelim, rightDelim).Funcs(stubs).Parse(contents)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %v", err)
+	}
+
+	v := &templateDataVisitor{seen: make(map[int]struct{})}
+	for _, defined := range t.Templates() {
+		if defined.Tree != nil {
+			v.walk(defined.Tree.Root)
+		}
+	}
+	if v.err != nil {
+		return "", v.err
+	}
+	if len(v.spans) == 0 {
+		return contents, nil
+	}
+
+	// Replace from the end so the offsets of the other spans stay valid
+	sort.Slice(v.spans, func(i, j int) bool { return v.spans[i].start > v.spans[j].start })
+	for _, span := range v.spans {
+		expr, err := replace(span.call)
+		if err != nil {
+			return "", err
+		}
+		contents = contents[:span.start] + expr + contents[span.end:]
+	}
+	return contents, nil
+}
+
+// templateDataVisitor walks the parse tree of a template and collects the
+// calls of the template functions implemented by Nomad.
+type templateDataVisitor struct {
+	spans []*templateDataSpan
+	seen  map[int]struct{}
+
+	// err is the first invalid call found
+	err error
+}
+
+func (v *templateDataVisitor) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			v.walk(child)
+		}
+	case *parse.ActionNode:
+		v.walk(n.Pipe)
+	case *parse.IfNode:
+		v.walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		v.walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		v.walkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		v.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for i, cmd := range n.Cmds {
+			if i == 0 && v.call(cmd) {
+				continue
+			}
+			v.walk(cmd)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			v.walk(arg)
+		}
+	case *parse.ChainNode:
+		v.walk(n.Node)
+	case *parse.IdentifierNode:
+		// Calls that aren't the first command of a pipeline are piped
+		// arguments or called without arguments
+		if helper.SliceStringContains(templateDataFunctions, n.Ident) && v.err == nil {
+			v.err = fmt.Errorf("%s must be called with literal arguments at the start of a pipeline", n.Ident)
+		}
+	}
+}
+
+func (v *templateDataVisitor) walkBranch(n *parse.BranchNode) {
+	v.walk(n.Pipe)
+	v.walk(n.List)
+	v.walk(n.ElseList)
+}
+
+// call records the command if it calls a template function implemented by
+// Nomad and returns whether it did.
+func (v *templateDataVisitor) call(cmd *parse.CommandNode) bool {
+	if len(cmd.Args) == 0 {
+		return false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok || !helper.SliceStringContains(templateDataFunctions, ident.Ident) {
+		return false
+	}
+
+	call := &TemplateDataCall{Function: ident.Ident}
+	end := int(ident.Position()) + len(ident.Ident)
+	for _, arg := range cmd.Args[1:] {
+		switch a := arg.(type) {
+		case *parse.StringNode:
+			call.Args = append(call.Args, a.Text)
+			end = int(a.Position()) + len(a.Quoted)
+		case *parse.NumberNode:
+			call.Args = append(call.Args, a.Text)
+			end = int(a.Position()) + len(a.Text)
+		default:
+			if v.err == nil {
+				v.err = fmt.Errorf("%s must be called with literal arguments", ident.Ident)
+			}
+			return true
+		}
+	}
+	if err := call.validate(); err != nil {
+		if v.err == nil {
+			v.err = err
+		}
+		return true
+	}
+
+	start := int(ident.Position())
+	if _, ok := v.seen[start]; !ok {
+		v.seen[start] = struct{}{}
+		v.spans = append(v.spans, &templateDataSpan{start: start, end: end, call: call})
+	}
+	return true
+}
+
+// templateServiceLister lists the passing instances of a service registered
+// with Nomad. The query blocks until the registrations change past the index.
+type templateServiceLister interface {
+	NomadServices(name string, index uint64) ([]*structs.ServiceRegistration, uint64, error)
+}
+
+// templateVariableReader reads the items of a variable stored by the servers,
+// which are nil if it doesn't exist. The query blocks until the variable
+// changes past the index.
+type templateVariableReader interface {
+	NomadVariable(path string, index uint64) (map[string]string, uint64, error)
+}
+
+// templateService is an instance of a service as seen by templates.
+type templateService struct {
+	ID         string
+	Name       string
+	Tags       []string
+	Address    string
+	Port       int
+	Datacenter string
+	NodeID     string
+	JobID      string
+	AllocID    string
+}
+
+// templateData fetches and watches the data of the calls of the template
+// functions implemented by Nomad. The data is written as JSON to the task's
+// secrets directory, where the rewritten templates read it with the file
+// function of consul-template.
+type templateData struct {
+	// dir is the directory the data is written to
+	dir string
+
+	services  templateServiceLister
+	variables templateVariableReader
+
+	// vault is the client reading Vault KV secrets with the task's token.
+	// It is nil if the task has no Vault token.
+	vault *vaultapi.Client
+
+	// calls are the calls to watch by the name of their file
+	calls map[string]*TemplateDataCall
+}
+
+// newTemplateData returns the templateData of the task directory.
+func newTemplateData(taskDir string, services templateServiceLister,
+	variables templateVariableReader, vault *vaultapi.Client) *templateData {
+	return &templateData{
+		dir:       filepath.Join(taskDir, allocdir.TaskSecrets, templateDataDir),
+		services:  services,
+		variables: variables,
+		vault:     vault,
+		calls:     make(map[string]*TemplateDataCall),
+	}
+}
+
+// rewrite replaces the calls of the template functions implemented by Nomad
+// in the template contents by the parsing of the files their data is written
+// to, and records the calls to watch.
+func (d *templateData) rewrite(contents, leftDelim, rightDelim string) (string, error) {
+	return RewriteTemplateDataCalls(contents, leftDelim, rightDelim, func(call *TemplateDataCall) (string, error) {
+		switch call.Function {
+		case "nomadService":
+			if d.services == nil {
+				return "", fmt.Errorf("%s: Nomad services are not available", call)
+			}
+		case "nomadVar":
+			if d.variables == nil {
+				return "", fmt.Errorf("%s: Nomad variables are not available", call)
+			}
+		case "kvSecret", "kvMetadata":
+			if d.vault == nil {
+				return "", fmt.Errorf("%s: the task has no Vault token", call)
+			}
+		}
+
+		name := call.fileName()
+		d.calls[name] = call
+		return fmt.Sprintf("parseJSON (file %s)", strconv.Quote(filepath.Join(d.dir, name))), nil
+	})
+}
+
+// rewriteConfig rewrites the contents of the consul-template. Templates read
+// from a source calling the template functions implemented by Nomad are
+// replaced by their rewritten contents.
+func (d *templateData) rewriteConfig(ct *ctconf.TemplateConfig) error {
+	contents := *ct.Contents
+	if *ct.Source != "" {
+		raw, err := ioutil.ReadFile(*ct.Source)
+		if err != nil {
+			return fmt.Errorf("failed to read template %q: %v", *ct.Source, err)
+		}
+		contents = string(raw)
+	}
+
+	rewritten, err := d.rewrite(contents, *ct.LeftDelim, *ct.RightDelim)
+	if err != nil {
+		return err
+	}
+	if rewritten == contents {
+		return nil
+	}
+
+	// The contents point to the job's template, so they are replaced rather
+	// than overwritten
+	source := ""
+	ct.Source = &source
+	ct.Contents = &rewritten
+	return nil
+}
+
+// run fetches the data of the calls and keeps it up to date until the
+// shutdown channel is closed. It returns once the data of every call was
+// written, or false if shutdown first. fail is called if the data of a call
+// can't be fetched, after which it isn't watched anymore.
+func (d *templateData) run(shutdownCh <-chan struct{}, fail func(error)) bool {
+	if len(d.calls) == 0 {
+		return true
+	}
+	if err := os.MkdirAll(d.dir, 0700); err != nil {
+		fail(fmt.Errorf("failed to create template data directory: %v", err))
+		return false
+	}
+
+	written := make(chan struct{}, len(d.calls))
+	for name, call := range d.calls {
+		go d.watch(name, call, shutdownCh, written, fail)
+	}
+	for range d.calls {
+		select {
+		case <-written:
+		case <-shutdownCh:
+			return false
+		}
+	}
+	return true
+}
+
+// watch fetches the data of the call and writes it to the file each time it
+// changes. The first write is signaled on the written channel.
+func (d *templateData) watch(name string, call *TemplateDataCall, shutdownCh <-chan struct{},
+	written chan<- struct{}, fail func(error)) {
+
+	var index uint64
+	var last []byte
+	failures := 0
+	for {
+		data, next, err := d.fetch(call, index)
+		if err == nil {
+			var raw []byte
+			if raw, err = json.Marshal(data); err == nil && !bytes.Equal(raw, last) {
+				if err = writeTemplateData(filepath.Join(d.dir, name), raw); err == nil {
+					if last == nil {
+						written <- struct{}{}
+					}
+					last = raw
+				}
+			}
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			failures++
+			if failures >= templateDataMaxFailures {
+				fail(fmt.Errorf("failed to fetch the data of %s: %v", call, err))
+				return
+			}
+			wait = time.Duration(1<<uint(failures-1)) * time.Second
+			if wait > templateDataMaxBackoff {
+				wait = templateDataMaxBackoff
+			}
+		case next == 0:
+			// Vault KV secrets are polled
+			failures = 0
+			wait = templateKVPollInterval + time.Duration(rand.Int63n(int64(templateKVPollInterval)))
+		default:
+			failures = 0
+
+			// Reset the index if it went backwards, as after a restore of
+			// the servers
+			if next < index {
+				next = 0
+			}
+			index = next
+		}
+
+		select {
+		case <-shutdownCh:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// fetch returns the data of the call, and the index to block on for the next
+// fetch or 0 if the data has to be polled.
+func (d *templateData) fetch(call *TemplateDataCall, index uint64) (interface{}, uint64, error) {
+	switch call.Function {
+	case "nomadService":
+		m := nomadServiceRe.FindStringSubmatch(call.Args[0])
+		tag, name := m[1], m[2]
+		regs, next, err := d.services.NomadServices(name, index)
+		if err != nil {
+			return nil, 0, err
+		}
+		services := make([]*templateService, 0, len(regs))
+		for _, reg := range regs {
+			if tag != "" && !helper.SliceStringContains(reg.Tags, tag) {
+				continue
+			}
+			services = append(services, &templateService{
+				ID:         reg.ID,
+				Name:       reg.ServiceName,
+				Tags:       reg.Tags,
+				Address:    reg.Address,
+				Port:       reg.Port,
+				Datacenter: reg.Datacenter,
+				NodeID:     reg.NodeID,
+				JobID:      reg.JobID,
+				AllocID:    reg.AllocID,
+			})
+		}
+		return services, next, nil
+	case "nomadVar":
+		items, next, err := d.variables.NomadVariable(call.Args[0], index)
+		if err != nil {
+			return nil, 0, err
+		}
+		if items == nil {
+			items = make(map[string]string)
+		}
+		return items, next, nil
+	default:
+		data, err := TemplateKVData(d.vault, call)
+		return data, 0, err
+	}
+}
+
+// writeTemplateData atomically writes the data to the file, so that
+// templates never read partial data.
+func writeTemplateData(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// kvSecret is a version of a secret of a Vault KV version 2 secrets engine
+// as seen by templates.
+type kvSecret struct {
+	// Data is the data of the secret, without the envelope of the KV
+	// version 2 API
+	Data map[string]interface{}
+
+	Version      int
+	CreatedTime  time.Time
+	DeletionTime time.Time
+	Destroyed    bool
+}
+
+// kvMetadata is the metadata of a secret of a Vault KV version 2 secrets
+// engine and of its versions as seen by templates.
+type kvMetadata struct {
+	CurrentVersion int
+	OldestVersion  int
+	MaxVersions    int
+
+	// CASRequired is set when writes of the secret must use check-and-set
+	// with the current version
+	CASRequired bool
+
+	CreatedTime time.Time
+	UpdatedTime time.Time
+
+	// Versions are the metadata of the versions that weren't pruned, by
+	// version number
+	Versions map[string]*kvVersionMetadata
+}
+
+// kvVersionMetadata is the metadata of a version of a secret.
+type kvVersionMetadata struct {
+	Version      int
+	CreatedTime  time.Time
+	DeletionTime time.Time
+	Destroyed    bool
+}
+
+// TemplateKVData reads the data of a call of kvSecret or kvMetadata from the
+// KV version 2 secrets engine of the secret. The path of the secret is its
+// logical path, without the "data" or "metadata" segment of the API.
+func TemplateKVData(vault *vaultapi.Client, call *TemplateDataCall) (interface{}, error) {
+	path := kvPath(call.Args[0])
+	mount, err := kvMount(vault, path)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.TrimPrefix(path, mount)
+
+	if call.Function == "kvMetadata" {
+		return readKVMetadata(vault, mount+"metadata/"+key)
+	}
+
+	params := make(url.Values)
+	if len(call.Args) == 2 && call.Args[1] != "0" {
+		params.Set("version", call.Args[1])
+	}
+	return readKVSecret(vault, mount+"data/"+key, params)
+}
+
+// kvPath returns the path of the secret without surrounding slashes.
+func kvPath(path string) string {
+	return strings.Trim(strings.TrimSpace(path), "/")
+}
+
+// kvMount returns the mount path, with a trailing slash, of the secrets
+// engine of the secret and ensures it is a KV version 2 engine.
+func kvMount(vault *vaultapi.Client, path string) (string, error) {
+	secret, err := vault.Logical().Read("sys/internal/ui/mounts/" + path)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the secrets engine of %s: %v", path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("no secrets engine is mounted at %s", path)
+	}
+
+	mount, _ := secret.Data["path"].(string)
+	var version string
+	if options, ok := secret.Data["options"].(map[string]interface{}); ok {
+		version, _ = options["version"].(string)
+	}
+	if mount == "" || version != "2" {
+		return "", fmt.Errorf("%s isn't in a KV version 2 secrets engine", path)
+	}
+	if !strings.HasSuffix(mount, "/") {
+		mount += "/"
+	}
+	return mount, nil
+}
+
+func readKVSecret(vault *vaultapi.Client, path string, params url.Values) (*kvSecret, error) {
+	secret, err := vaultRead(vault, path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleted and destroyed versions have no data
+	data, _ := secret.Data["data"].(map[string]interface{})
+	if data == nil {
+		return nil, fmt.Errorf("version of %s was deleted", path)
+	}
+	meta, _ := secret.Data["metadata"].(map[string]interface{})
+	return &kvSecret{
+		Data:         data,
+		Version:      kvInt(meta["version"]),
+		CreatedTime:  kvTime(meta["created_time"]),
+		DeletionTime: kvTime(meta["deletion_time"]),
+		Destroyed:    kvBool(meta["destroyed"]),
+	}, nil
+}
+
+func readKVMetadata(vault *vaultapi.Client, path string) (*kvMetadata, error) {
+	secret, err := vaultRead(vault, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data := secret.Data
+	meta := &kvMetadata{
+		CurrentVersion: kvInt(data["current_version"]),
+		OldestVersion:  kvInt(data["oldest_version"]),
+		MaxVersions:    kvInt(data["max_versions"]),
+		CASRequired:    kvBool(data["cas_required"]),
+		CreatedTime:    kvTime(data["created_time"]),
+		UpdatedTime:    kvTime(data["updated_time"]),
+		Versions:       make(map[string]*kvVersionMetadata),
+	}
+	versions, _ := data["versions"].(map[string]interface{})
+	for raw, v := range versions {
+		version, err := strconv.Atoi(raw)
+		if err != nil {
+			continue
+		}
+		m, _ := v.(map[string]interface{})
+		meta.Versions[raw] = &kvVersionMetadata{
+			Version:      version,
+			CreatedTime:  kvTime(m["created_time"]),
+			DeletionTime: kvTime(m["deletion_time"]),
+			Destroyed:    kvBool(m["destroyed"]),
+		}
+	}
+	return meta, nil
+}
+
+// vaultRead reads the path with the query parameters. The Vault API client
+// can't read with parameters so the request is made directly.
+func vaultRead(vault *vaultapi.Client, path string, params url.Values) (*vaultapi.Secret, error) {
+	r := vault.NewRequest("GET", "/v1/"+path)
+	for k, v := range params {
+		r.Params[k] = v
+	}
+	resp, err := vault.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		return nil, fmt.Errorf("no secret exists at %s", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := vaultapi.ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no secret exists at %s", path)
+	}
+	return secret, nil
+}
+
+// kvInt converts a number of the KV API to an int.
+func kvInt(v interface{}) int {
+	switch n := v.(type) {
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+// kvBool converts a boolean of the KV API to a bool.
+func kvBool(v interface{}) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+// kvTime parses a timestamp of the KV API, returning the zero time if it is
+// empty or invalid.
+func kvTime(v interface{}) time.Time {
+	s, _ := v.(string)
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
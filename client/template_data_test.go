@@ -0,0 +1,176 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestRewriteTemplateDataCalls(t *testing.T) {
+	t.Parallel()
+	replace := func(call *TemplateDataCall) (string, error) {
+		return "(" + strings.ToUpper(call.String()) + ")", nil
+	}
+
+	cases := []struct {
+		Name     string
+		Contents string
+		Left     string
+		Right    string
+		Expected string
+		Err      string
+	}{
+		{
+			Name:     "no calls",
+			Contents: `{{ range service "web" }}{{ .Address }}{{ end }}`,
+			Expected: `{{ range service "web" }}{{ .Address }}{{ end }}`,
+		},
+		{
+			Name:     "service",
+			Contents: `{{ range nomadService "http.web" }}{{ .Address }}{{ end }}`,
+			Expected: `{{ range (NOMADSERVICE "HTTP.WEB") }}{{ .Address }}{{ end }}`,
+		},
+		{
+			Name:     "variable",
+			Contents: `{{ with nomadVar "app/db" }}{{ .user }}{{ end }}`,
+			Expected: `{{ with (NOMADVAR "APP/DB") }}{{ .user }}{{ end }}`,
+		},
+		{
+			Name:     "secret",
+			Contents: `{{ (kvSecret "secret/app" 2).Data.password }}{{ kvMetadata "secret/app" | toJSON }}`,
+			Expected: `{{ ((KVSECRET "SECRET/APP" 2)).Data.password }}{{ (KVMETADATA "SECRET/APP") | toJSON }}`,
+		},
+		{
+			Name:     "chained",
+			Contents: `{{ (index (nomadService "web") 0).Address }}`,
+			Expected: `{{ (index ((NOMADSERVICE "WEB")) 0).Address }}`,
+		},
+		{
+			Name:     "delimiters and definitions",
+			Contents: `[[ define "x" ]][[ nomadService "web" | toJSON ]][[ end ]][[ template "x" ]]`,
+			Left:     "[[",
+			Right:    "]]",
+			Expected: `[[ define "x" ]][[ (NOMADSERVICE "WEB") | toJSON ]][[ end ]][[ template "x" ]]`,
+		},
+		{
+			Name:     "piped",
+			Contents: `{{ "web" | nomadService }}`,
+			Err:      "at the start of a pipeline",
+		},
+		{
+			Name:     "dynamic argument",
+			Contents: `{{ nomadService (env "SERVICE") }}`,
+			Err:      "literal arguments",
+		},
+		{
+			Name:     "invalid service",
+			Contents: `{{ nomadService "web/x" }}`,
+			Err:      "expected a service name",
+		},
+		{
+			Name:     "invalid variable",
+			Contents: `{{ nomadVar "app//db" }}`,
+			Err:      "expected the path of a variable",
+		},
+		{
+			Name:     "invalid version",
+			Contents: `{{ kvSecret "secret/app" -1 }}`,
+			Err:      "invalid version",
+		},
+	}
+
+	for _, tc := range cases {
+		left, right := tc.Left, tc.Right
+		if left == "" {
+			left, right = "{{", "}}"
+		}
+		out, err := RewriteTemplateDataCalls(tc.Contents, left, right, replace)
+		if tc.Err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Errorf("%s: expected error containing %q; got %v", tc.Name, tc.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.Name, err)
+		} else if out != tc.Expected {
+			t.Errorf("%s: got %q; want %q", tc.Name, out, tc.Expected)
+		}
+	}
+}
+
+func TestTemplateData_Run(t *testing.T) {
+	t.Parallel()
+	taskDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+	services := &fakeNomadServices{
+		services: []*structs.ServiceRegistration{
+			{ServiceName: "web", Address: "10.0.0.1", Port: 8080, Tags: []string{"http"}},
+			{ServiceName: "web", Address: "10.0.0.2", Port: 8080},
+		},
+	}
+	d := newTemplateData(taskDir, services, &fakeNomadVariables{}, nil)
+
+	// Calls reading Vault are rejected without a Vault token
+	if _, err := d.rewrite(`{{ kvSecret "secret/app" }}`, "{{", "}}"); err == nil || !strings.Contains(err.Error(), "no Vault token") {
+		t.Fatalf("expected Vault error; got %v", err)
+	}
+
+	out, err := d.rewrite(`{{ nomadService "http.web" }}{{ nomadService "web" }}{{ nomadService "web" }}{{ nomadVar "app/db" }}`, "{{", "}}")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(d.calls) != 3 || !strings.Contains(out, `parseJSON (file "`+d.dir) {
+		t.Fatalf("bad rewrite %q of %d calls", out, len(d.calls))
+	}
+
+	shutdownCh := make(chan struct{})
+	defer close(shutdownCh)
+	done := make(chan bool, 1)
+	go func() {
+		done <- d.run(shutdownCh, func(err error) { t.Errorf("unexpected failure: %v", err) })
+	}()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Fatalf("expected the data to be written")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for the data")
+	}
+
+	// Only the instances with the tag are written for the tagged call, and
+	// missing variables are empty
+	for name, call := range d.calls {
+		raw, err := ioutil.ReadFile(filepath.Join(d.dir, name))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if call.Function == "nomadVar" {
+			if string(raw) != "{}" {
+				t.Fatalf("bad variable: %s", raw)
+			}
+			continue
+		}
+		var out []*templateService
+		if err := json.Unmarshal(raw, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		expected := 2
+		if call.Args[0] == "http.web" {
+			expected = 1
+		}
+		if len(out) != expected || out[0].Address != "10.0.0.1" || out[0].Port != 8080 {
+			t.Fatalf("bad services for %s: %s", call, raw)
+		}
+	}
+}
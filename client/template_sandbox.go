@@ -0,0 +1,445 @@
+package client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	ctconf "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// templateFunctions is the set of functions consul-template provides to
+// templates. Templates are parsed with stubs of these functions to find the
+// functions they use before being handed to consul-template, so the set must
+// be kept in sync with the vendored consul-template.
+var templateFunctions = []string{
+	// API functions
+	"datacenters", "file", "key", "keyExists", "keyOrDefault", "kvMetadata",
+	"kvSecret", "ls", "node", "nodes", "nomadService", "nomadVar", "secret",
+	"secrets", "service", "services", "tree",
+
+	// Scratch
+	"scratch",
+
+	// Helper functions
+	"base64Decode", "base64Encode", "base64URLDecode", "base64URLEncode",
+	"byKey", "byTag", "contains", "containsAll", "containsAny", "containsNone",
+	"containsNotAll", "env", "executeTemplate", "explode", "in", "loop", "join",
+	"trimSpace", "parseBool", "parseFloat", "parseInt", "parseJSON", "parseUint",
+	"plugin", "regexReplaceAll", "regexMatch", "replaceAll", "timestamp",
+	"toLower", "toJSON", "toJSONPretty", "toTitle", "toTOML", "toUpper",
+	"toYAML", "split",
+
+	// Math functions
+	"add", "subtract", "multiply", "divide", "modulo",
+}
+
+// templateStageDir is the directory of the allocation directory the
+// templates of its tasks are rendered in before being moved to their
+// destination, when files are sandboxed. It is outside of the task
+// directories, so tasks can't redirect the rendering with symlinks.
+const templateStageDir = ".nomad-templates"
+
+// templateSandbox restricts the functions templates may use and the files
+// they may render and read. Templates are effectively code submitted by job
+// authors and run by the client, outside of the task's isolation.
+type templateSandbox struct {
+	// taskDir is the directory files are sandboxed to
+	taskDir string
+
+	// allowed is the set of functions templates may use. Every function is
+	// allowed if empty.
+	allowed map[string]struct{}
+
+	// denied is the set of functions templates may not use
+	denied map[string]struct{}
+
+	// sandboxFiles marks whether templates may only render and read files
+	// within the task directory
+	sandboxFiles bool
+
+	// allowHostSource marks whether templates may be read from the host
+	allowHostSource bool
+
+	// stageDir is the directory templates are rendered in before being moved
+	// to their destination. Templates are rendered in place if empty.
+	stageDir string
+
+	// staged maps the paths templates are rendered to in the stage directory
+	// to their destination, and installed the destinations to the hash of
+	// the contents last moved there.
+	staged    map[string]string
+	installed map[string][sha256.Size]byte
+
+	// contents maps the templates to the contents they were checked with,
+	// which were read from their source if they have one
+	contents map[*structs.Template]string
+}
+
+// newTemplateSandbox returns the sandbox of the templates of a task based on
+// the client's options.
+func newTemplateSandbox(config *config.Config, taskDir string) *templateSandbox {
+	s := &templateSandbox{
+		taskDir:         taskDir,
+		allowed:         config.ReadStringListToMap(functionAllowlistOption),
+		denied:          config.ReadStringListToMapDefault(functionDenylistOption, defaultFunctionDenylist),
+		sandboxFiles:    !config.ReadBoolDefault(disableFileSandboxOption, false),
+		allowHostSource: config.ReadBoolDefault(hostSrcOption, false),
+		staged:          make(map[string]string),
+		installed:       make(map[string][sha256.Size]byte),
+		contents:        make(map[*structs.Template]string),
+	}
+	if s.sandboxFiles {
+		s.stageDir = filepath.Join(filepath.Dir(taskDir), templateStageDir, filepath.Base(taskDir))
+	}
+	return s
+}
+
+// check returns an error if the consul-template renders to or is read from a
+// path outside of the sandbox, or if its contents use a function that isn't
+// allowed. The source of the template is replaced by the contents that were
+// checked, so that it can't be swapped before it is rendered.
+func (s *templateSandbox) check(ct *ctconf.TemplateConfig) error {
+	source, dest := *ct.Source, *ct.Destination
+	if s.sandboxFiles && dest != "" && !s.contains(dest) {
+		return fmt.Errorf("template destination %q escapes the task directory", dest)
+	}
+
+	contents := *ct.Contents
+	if source != "" {
+		raw, err := s.readSource(source)
+		if err != nil {
+			return err
+		}
+		contents = string(raw)
+	}
+	if err := s.checkContents(contents, *ct.LeftDelim, *ct.RightDelim); err != nil {
+		return err
+	}
+
+	// The contents point to the job's template, so they are replaced rather
+	// than overwritten. consul-template requires contents, so an empty source
+	// becomes a comment.
+	if contents == "" {
+		contents = *ct.LeftDelim + "/* */" + *ct.RightDelim
+	}
+	empty := ""
+	ct.Source = &empty
+	ct.Contents = &contents
+	return nil
+}
+
+// readSource reads the source of a template. Sources within the task
+// directory are read without following symlinks once resolved.
+func (s *templateSandbox) readSource(source string) ([]byte, error) {
+	var raw []byte
+	var err error
+	if !s.sandboxFiles {
+		raw, err = ioutil.ReadFile(source)
+	} else if taskDir, rel, ok := s.resolve(source); ok {
+		raw, err = readTaskFile(taskDir, rel)
+	} else if s.allowHostSource {
+		raw, err = ioutil.ReadFile(source)
+	} else {
+		return nil, fmt.Errorf("template source %q escapes the task directory", source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %q: %v", source, err)
+	}
+	return raw, nil
+}
+
+// stage makes the consul-template render to the stage directory rather than
+// to its destination, which it is moved to by install.
+func (s *templateSandbox) stage(ct *ctconf.TemplateConfig) {
+	dest := *ct.Destination
+	if s.stageDir == "" || dest == "" {
+		return
+	}
+	rel, err := filepath.Rel(s.taskDir, dest)
+	if err != nil {
+		return
+	}
+	staged := filepath.Join(s.stageDir, rel)
+	s.staged[staged] = dest
+	ct.Destination = &staged
+}
+
+// renderDir returns the directory templates are rendered in, laid out as the
+// task directory.
+func (s *templateSandbox) renderDir() string {
+	if s.stageDir != "" {
+		return s.stageDir
+	}
+	return s.taskDir
+}
+
+// install moves the templates rendered in the stage directory since the last
+// install to their destination. Destinations are resolved and checked again
+// and written without following symlinks, so that a symlink the task swapped
+// in since the template was checked can't redirect the write out of the task
+// directory.
+func (s *templateSandbox) install() error {
+	for staged, dest := range s.staged {
+		contents, err := ioutil.ReadFile(staged)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to read rendered template %q: %v", dest, err)
+		}
+		sum := sha256.Sum256(contents)
+		if installed, ok := s.installed[dest]; ok && installed == sum {
+			continue
+		}
+		info, err := os.Stat(staged)
+		if err != nil {
+			return fmt.Errorf("failed to read rendered template %q: %v", dest, err)
+		}
+
+		taskDir, rel, ok := s.resolve(dest)
+		if !ok {
+			return fmt.Errorf("template destination %q escapes the task directory", dest)
+		}
+		if err := writeTaskFile(taskDir, rel, contents, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write template %q: %v", dest, err)
+		}
+		s.installed[dest] = sum
+	}
+	return nil
+}
+
+// cleanup removes the stage directory.
+func (s *templateSandbox) cleanup() error {
+	if s.stageDir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.stageDir)
+}
+
+// checkContents parses the template contents and returns an error listing
+// the functions that aren't allowed and, when files are sandboxed, the files
+// read outside of the task directory.
+func (s *templateSandbox) checkContents(contents, leftDelim, rightDelim string) error {
+	v, err := s.visit(contents, leftDelim, rightDelim)
+	if err != nil {
+		return err
+	}
+	if denied := v.deniedFunctions(); len(denied) != 0 {
+		return fmt.Errorf("template functions disallowed by client config: %s", strings.Join(denied, ", "))
+	}
+	return v.err
+}
+
+// visit parses the template contents and walks them with a templateVisitor.
+func (s *templateSandbox) visit(contents, leftDelim, rightDelim string) (*templateVisitor, error) {
+	t, err := parseTemplate(contents, leftDelim, rightDelim)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &templateVisitor{sandbox: s, denied: make(map[string]struct{})}
+	for _, defined := range t.Templates() {
+		if defined.Tree != nil {
+			v.walk(defined.Tree.Root)
+		}
+	}
+	return v, nil
+}
+
+// parseTemplate parses the template contents with stubs of the functions of
+// consul-template.
+func parseTemplate(contents, leftDelim, rightDelim string) (*template.Template, error) {
+	stubs := make(template.FuncMap, len(templateFunctions))
+	for _, f := range templateFunctions {
+		stubs[f] = func(...interface{}) interface{} { return nil }
+	}
+
+	t, err := template.New("").Delims(leftDelim, rightDelim).Funcs(stubs).Parse(contents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %v", err)
+	}
+	return t, nil
+}
+
+// TemplateFunctionsUsed returns which of the given template functions the
+// template uses, sorted.
+func TemplateFunctionsUsed(contents, leftDelim, rightDelim string, functions ...string) ([]string, error) {
+	s := &templateSandbox{denied: make(map[string]struct{}, len(functions))}
+	for _, f := range functions {
+		s.denied[f] = struct{}{}
+	}
+	v, err := s.visit(contents, leftDelim, rightDelim)
+	if err != nil {
+		return nil, err
+	}
+	return v.deniedFunctions(), nil
+}
+
+// allows returns whether templates may use the function. The allowlist only
+// restricts the functions of consul-template, not the builtins of Go
+// templates.
+func (s *templateSandbox) allows(function string) bool {
+	if _, ok := s.denied[function]; ok {
+		return false
+	}
+	if len(s.allowed) == 0 || !helper.SliceStringContains(templateFunctions, function) {
+		return true
+	}
+	_, ok := s.allowed[function]
+	return ok
+}
+
+// contains returns whether the path is within the task directory. The
+// symlinks of both paths are resolved first, so that a link created by the
+// task or an artifact can't point templates outside of the task directory.
+func (s *templateSandbox) contains(path string) bool {
+	_, _, ok := s.resolve(path)
+	return ok
+}
+
+// resolve returns the task directory and the path relative to it with their
+// symlinks resolved, and whether the path is within the task directory.
+func (s *templateSandbox) resolve(path string) (string, string, bool) {
+	taskDir, err := resolvePath(s.taskDir)
+	if err != nil {
+		return "", "", false
+	}
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", "", false
+	}
+	rel, err := filepath.Rel(taskDir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", false
+	}
+	return taskDir, rel, true
+}
+
+// resolvePath returns the path with the symlinks of its longest existing
+// prefix resolved. The components that don't exist yet are appended as is.
+// Dangling symlinks are an error as the file they would create is unknown.
+func resolvePath(path string) (string, error) {
+	path = filepath.Clean(path)
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if _, lerr := os.Lstat(path); lerr == nil {
+			return "", fmt.Errorf("%q is a dangling symlink", path)
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
+// templateVisitor walks the parse tree of a template and collects the
+// functions it uses that the sandbox doesn't allow.
+type templateVisitor struct {
+	sandbox *templateSandbox
+	denied  map[string]struct{}
+
+	// err is the first file read outside of the sandbox
+	err error
+}
+
+// deniedFunctions returns the functions used that aren't allowed, sorted.
+func (v *templateVisitor) deniedFunctions() []string {
+	if len(v.denied) == 0 {
+		return nil
+	}
+	denied := make([]string, 0, len(v.denied))
+	for f := range v.denied {
+		denied = append(denied, f)
+	}
+	sort.Strings(denied)
+	return denied
+}
+
+func (v *templateVisitor) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			v.walk(child)
+		}
+	case *parse.ActionNode:
+		v.walk(n.Pipe)
+	case *parse.IfNode:
+		v.walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		v.walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		v.walkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		v.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			v.walk(cmd)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) != 0 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "file" {
+				v.checkFile(n)
+			}
+		}
+		for _, arg := range n.Args {
+			v.walk(arg)
+		}
+	case *parse.ChainNode:
+		v.walk(n.Node)
+	case *parse.IdentifierNode:
+		if !v.sandbox.allows(n.Ident) {
+			v.denied[n.Ident] = struct{}{}
+		}
+	}
+}
+
+func (v *templateVisitor) walkBranch(n *parse.BranchNode) {
+	v.walk(n.Pipe)
+	v.walk(n.List)
+	v.walk(n.ElseList)
+}
+
+// checkFile ensures a call of the file function reads a file within the task
+// directory. The path must be a string literal for it to be checked.
+func (v *templateVisitor) checkFile(cmd *parse.CommandNode) {
+	if !v.sandbox.sandboxFiles || v.err != nil {
+		return
+	}
+
+	var path *parse.StringNode
+	if len(cmd.Args) == 2 {
+		path, _ = cmd.Args[1].(*parse.StringNode)
+	}
+	if path == nil {
+		v.err = fmt.Errorf("template reads a file whose path isn't a string literal")
+		return
+	}
+	if !filepath.IsAbs(path.Text) || !v.sandbox.contains(path.Text) {
+		v.err = fmt.Errorf("template reads file %q outside of the task directory", path.Text)
+	}
+}
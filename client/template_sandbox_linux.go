@@ -0,0 +1,105 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openTaskDir opens the directory at the path relative to the task directory,
+// creating the missing directories if create is set. Each component is opened
+// relative to its parent without following symlinks, so that a symlink
+// swapped in after the path was resolved fails rather than being followed.
+func openTaskDir(taskDir, rel string, create bool) (int, error) {
+	fd, err := unix.Open(taskDir, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, &os.PathError{Op: "open", Path: taskDir, Err: err}
+	}
+
+	path := taskDir
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		if name == "" || name == "." {
+			continue
+		}
+		path = filepath.Join(path, name)
+		if name == ".." {
+			unix.Close(fd)
+			return -1, fmt.Errorf("%q escapes the task directory", path)
+		}
+
+		next, err := unix.Openat(fd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+		if err == unix.ENOENT && create {
+			if err = unix.Mkdirat(fd, name, 0755); err == nil || err == unix.EEXIST {
+				next, err = unix.Openat(fd, name, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+			}
+		}
+		unix.Close(fd)
+		if err != nil {
+			return -1, &os.PathError{Op: "open", Path: path, Err: err}
+		}
+		fd = next
+	}
+	return fd, nil
+}
+
+// writeTaskFile atomically writes the file at the path relative to the task
+// directory without following symlinks. The file is written to a temporary
+// file that is renamed over the path, which replaces a symlink at the path
+// rather than writing through it.
+func writeTaskFile(taskDir, rel string, contents []byte, perms os.FileMode) error {
+	dir, err := openTaskDir(taskDir, filepath.Dir(rel), true)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(dir)
+
+	name := filepath.Base(rel)
+	tmp := fmt.Sprintf(".%s.%d.tmp", name, time.Now().UnixNano())
+	fd, err := unix.Openat(dir, tmp, unix.O_WRONLY|unix.O_CREAT|unix.O_EXCL|unix.O_NOFOLLOW|unix.O_CLOEXEC, uint32(perms))
+	if err != nil {
+		return &os.PathError{Op: "open", Path: filepath.Join(taskDir, filepath.Dir(rel), tmp), Err: err}
+	}
+	f := os.NewFile(uintptr(fd), filepath.Join(taskDir, filepath.Dir(rel), tmp))
+	_, err = f.Write(contents)
+	if err == nil {
+		// The permissions are set again as they were masked by the umask
+		err = f.Chmod(perms)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = unix.Renameat(dir, tmp, dir, name)
+	}
+	if err != nil {
+		unix.Unlinkat(dir, tmp, 0)
+		return err
+	}
+	return nil
+}
+
+// readTaskFile reads the file at the path relative to the task directory
+// without following symlinks.
+func readTaskFile(taskDir, rel string) ([]byte, error) {
+	dir, err := openTaskDir(taskDir, filepath.Dir(rel), false)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(dir)
+
+	fd, err := unix.Openat(dir, filepath.Base(rel), unix.O_RDONLY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: filepath.Join(taskDir, rel), Err: err}
+	}
+	f := os.NewFile(uintptr(fd), filepath.Join(taskDir, rel))
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
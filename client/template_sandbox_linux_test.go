@@ -0,0 +1,52 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateSandbox_TaskFileSymlinks(t *testing.T) {
+	t.Parallel()
+	taskDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to make tmpdir: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+	outside, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to make tmpdir: %v", err)
+	}
+	defer os.RemoveAll(outside)
+
+	if err := os.Symlink(outside, filepath.Join(taskDir, "local")); err != nil {
+		t.Fatalf("failed to make symlink: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(taskDir, "secret")); err != nil {
+		t.Fatalf("failed to make symlink: %v", err)
+	}
+
+	// Symlinks are neither followed when writing nor when reading
+	if err := writeTaskFile(taskDir, filepath.Join("local", "out"), []byte("a"), 0644); err == nil {
+		t.Fatalf("expected write through a symlinked directory to fail")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "out")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written outside of the task directory: %v", err)
+	}
+	if _, err := readTaskFile(taskDir, "secret"); err == nil {
+		t.Fatalf("expected read of a symlink to fail")
+	}
+
+	// Missing directories are created
+	if err := writeTaskFile(taskDir, filepath.Join("a", "b", "out"), []byte("a"), 0640); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := readTaskFile(taskDir, filepath.Join("a", "b", "out"))
+	if err != nil || string(out) != "a" {
+		t.Fatalf("expected file to be written; got %q: %v", out, err)
+	}
+}
@@ -0,0 +1,47 @@
+// +build !linux
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// writeTaskFile atomically writes the file at the path relative to the task
+// directory. The path is written through its symlinks on this platform, and
+// is only protected by the check done when it was resolved.
+func writeTaskFile(taskDir, rel string, contents []byte, perms os.FileMode) error {
+	path := filepath.Join(taskDir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(contents)
+	if err == nil {
+		err = f.Chmod(perms)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// readTaskFile reads the file at the path relative to the task directory.
+func readTaskFile(taskDir, rel string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(taskDir, rel))
+}
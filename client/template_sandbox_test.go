@@ -0,0 +1,269 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ctconf "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestTemplateSandbox_CheckContents(t *testing.T) {
+	t.Parallel()
+	c := config.DefaultConfig()
+	s := newTemplateSandbox(c, "/alloc/task")
+
+	cases := []struct {
+		Name     string
+		Contents string
+		Err      string
+	}{
+		{
+			Name:     "allowed",
+			Contents: `{{ range service "web" }}{{ .Address | toUpper }}{{ end }}`,
+		},
+		{
+			Name:     "vault kv helpers",
+			Contents: `{{ with kvSecret "secret/app" 2 }}{{ .Data.password }}{{ end }}{{ (kvMetadata "secret/app").CurrentVersion }}`,
+		},
+		{
+			Name:     "denied by default",
+			Contents: `{{ plugin "/bin/rm" "-rf" "/" }}`,
+			Err:      "disallowed by client config: plugin",
+		},
+		{
+			Name:     "denied within definition",
+			Contents: `{{ define "x" }}{{ if true }}{{ "a" | plugin }}{{ end }}{{ end }}{{ template "x" }}`,
+			Err:      "disallowed by client config: plugin",
+		},
+		{
+			Name:     "file within task dir",
+			Contents: `{{ file "/alloc/task/local/config" }}`,
+		},
+		{
+			Name:     "file outside task dir",
+			Contents: `{{ file "/etc/passwd" }}`,
+			Err:      "outside of the task directory",
+		},
+		{
+			Name:     "relative file",
+			Contents: `{{ file "local/config" }}`,
+			Err:      "outside of the task directory",
+		},
+		{
+			Name:     "file with dynamic path",
+			Contents: `{{ env "HOME" | file }}`,
+			Err:      "isn't a string literal",
+		},
+		{
+			Name:     "invalid",
+			Contents: `{{ unknown }}`,
+			Err:      "failed to parse",
+		},
+	}
+
+	for _, tc := range cases {
+		err := s.checkContents(tc.Contents, "{{", "}}")
+		if tc.Err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.Name, err)
+		} else if tc.Err != "" && (err == nil || !strings.Contains(err.Error(), tc.Err)) {
+			t.Errorf("%s: expected error containing %q; got %v", tc.Name, tc.Err, err)
+		}
+	}
+}
+
+func TestTemplateSandbox_Options(t *testing.T) {
+	t.Parallel()
+	c := config.DefaultConfig()
+	c.Options = map[string]string{
+		functionAllowlistOption:  "key, plugin, service",
+		functionDenylistOption:   "service",
+		disableFileSandboxOption: "true",
+	}
+	s := newTemplateSandbox(c, "/alloc/task")
+
+	// Builtins aren't restricted by the allowlist, and setting the denylist
+	// replaces the default one
+	if err := s.checkContents(`{{ key "a" | len }}{{ plugin "a" }}`, "{{", "}}"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := s.checkContents(`{{ service "web" }}{{ toUpper "a" }}{{ file "/etc/passwd" }}`, "{{", "}}")
+	if err == nil || !strings.Contains(err.Error(), "file, service, toUpper") {
+		t.Fatalf("expected file, service and toUpper to be denied: %v", err)
+	}
+
+	// Files aren't sandboxed
+	s.allowed = nil
+	s.denied = nil
+	if err := s.checkContents(`{{ file "/etc/passwd" }}`, "{{", "}}"); err != nil {
+		t.Fatalf("expected file sandbox to be disabled: %v", err)
+	}
+}
+
+func TestTemplateSandbox_Paths(t *testing.T) {
+	t.Parallel()
+	taskDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to make tmpdir: %v", err)
+	}
+	defer os.RemoveAll(taskDir)
+
+	src := filepath.Join(taskDir, "local", "tmpl")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatalf("failed to make dir: %v", err)
+	}
+	if err := ioutil.WriteFile(src, []byte(`{{ plugin "a" }}`), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	c := config.DefaultConfig()
+	c.Options = map[string]string{
+		hostSrcOption: "false",
+	}
+	s := newTemplateSandbox(c, taskDir)
+
+	ct := ctconf.DefaultTemplateConfig()
+	ct.Destination = helper.StringToPtr(filepath.Join(taskDir, "..", "escape"))
+	ct.Finalize()
+	if err := s.check(ct); err == nil || !strings.Contains(err.Error(), "destination") {
+		t.Fatalf("expected destination escape error: %v", err)
+	}
+
+	ct.Destination = helper.StringToPtr(filepath.Join(taskDir, "local", "out"))
+	ct.Source = helper.StringToPtr(filepath.Join(taskDir, "..", "..", "etc", "passwd"))
+	if err := s.check(ct); err == nil || !strings.Contains(err.Error(), "source") {
+		t.Fatalf("expected source escape error: %v", err)
+	}
+
+	// The contents of sources are checked
+	ct.Source = helper.StringToPtr(src)
+	if err := s.check(ct); err == nil || !strings.Contains(err.Error(), "plugin") {
+		t.Fatalf("expected plugin to be denied: %v", err)
+	}
+
+	// Symlinks out of the task directory are resolved
+	outside, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to make tmpdir: %v", err)
+	}
+	defer os.RemoveAll(outside)
+	link := filepath.Join(taskDir, "local", "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatalf("failed to make symlink: %v", err)
+	}
+	dangling := filepath.Join(taskDir, "local", "dangling")
+	if err := os.Symlink(filepath.Join(outside, "missing"), dangling); err != nil {
+		t.Fatalf("failed to make symlink: %v", err)
+	}
+
+	ct.Source = helper.StringToPtr("")
+	for _, dest := range []string{filepath.Join(link, "out"), filepath.Join(link, "new", "out"), dangling} {
+		ct.Destination = helper.StringToPtr(dest)
+		if err := s.check(ct); err == nil || !strings.Contains(err.Error(), "destination") {
+			t.Fatalf("expected destination escape error for %q: %v", dest, err)
+		}
+	}
+	contents := fmt.Sprintf(`{{ file %q }}`, filepath.Join(link, "passwd"))
+	if err := s.checkContents(contents, "{{", "}}"); err == nil {
+		t.Fatalf("expected file escape error")
+	}
+
+	// Missing paths within the task directory are allowed
+	ct.Destination = helper.StringToPtr(filepath.Join(taskDir, "local", "new", "out"))
+	if err := s.check(ct); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTemplateSandbox_Install(t *testing.T) {
+	t.Parallel()
+	allocDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to make tmpdir: %v", err)
+	}
+	defer os.RemoveAll(allocDir)
+	taskDir := filepath.Join(allocDir, "web")
+	if err := os.MkdirAll(filepath.Join(taskDir, "local"), 0755); err != nil {
+		t.Fatalf("failed to make dir: %v", err)
+	}
+	src := filepath.Join(taskDir, "local", "tmpl")
+	if err := ioutil.WriteFile(src, []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	s := newTemplateSandbox(config.DefaultConfig(), taskDir)
+	defer s.cleanup()
+
+	ct := ctconf.DefaultTemplateConfig()
+	ct.Source = helper.StringToPtr(src)
+	ct.Destination = helper.StringToPtr(filepath.Join(taskDir, "local", "out"))
+	ct.Finalize()
+	if err := s.check(ct); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.stage(ct)
+
+	// The checked source is rendered from the stage directory, so swapping
+	// it afterwards has no effect
+	if *ct.Source != "" || *ct.Contents != "hello" {
+		t.Fatalf("expected source to be inlined; got %q and %q", *ct.Source, *ct.Contents)
+	}
+	staged := *ct.Destination
+	if !strings.HasPrefix(staged, filepath.Join(allocDir, templateStageDir)) {
+		t.Fatalf("expected template to be rendered in the stage directory; got %q", staged)
+	}
+
+	// Rendered templates are moved to their destination
+	render := func(contents string) {
+		if err := os.MkdirAll(filepath.Dir(staged), 0755); err != nil {
+			t.Fatalf("failed to make dir: %v", err)
+		}
+		if err := ioutil.WriteFile(staged, []byte(contents), 0600); err != nil {
+			t.Fatalf("failed to render template: %v", err)
+		}
+	}
+	render("hello")
+	if err := s.install(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := ioutil.ReadFile(filepath.Join(taskDir, "local", "out"))
+	if err != nil || string(out) != "hello" {
+		t.Fatalf("expected template to be installed; got %q: %v", out, err)
+	}
+	if info, err := os.Stat(filepath.Join(taskDir, "local", "out")); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected template perms to be kept: %v", err)
+	}
+
+	// Swapping a directory of the destination for a symlink out of the task
+	// directory after the template was checked doesn't redirect the write
+	outside, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to make tmpdir: %v", err)
+	}
+	defer os.RemoveAll(outside)
+	if err := os.RemoveAll(filepath.Join(taskDir, "local")); err != nil {
+		t.Fatalf("failed to remove dir: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(taskDir, "local")); err != nil {
+		t.Fatalf("failed to make symlink: %v", err)
+	}
+	render("swapped")
+	if err := s.install(); err == nil || !strings.Contains(err.Error(), "escapes") {
+		t.Fatalf("expected destination escape error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "out")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing to be written outside of the task directory: %v", err)
+	}
+
+	if err := s.cleanup(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(allocDir, templateStageDir, "web")); !os.IsNotExist(err) {
+		t.Fatalf("expected stage directory to be removed: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"regexp"
+	"strings"
+	"text/template/parse"
+)
+
+// templateCapture is the output of an action of a template
+type templateCapture struct {
+	// value is the contents the action rendered
+	value string
+
+	// secret marks whether the action reads secrets
+	secret bool
+}
+
+// templateCaptures matches the rendered contents of a template against its
+// parse tree and returns the outputs of its actions. The text of the template
+// is matched literally, so the output of an action is what was rendered
+// between the text around it. Conditions, loops and the templates it executes
+// are captured as a whole. false is returned if the rendered contents don't
+// match the template.
+func templateCaptures(contents, leftDelim, rightDelim, rendered string) ([]templateCapture, bool, error) {
+	t, err := parseTemplate(contents, leftDelim, rightDelim)
+	if err != nil {
+		return nil, false, err
+	}
+	if t.Tree == nil {
+		return nil, rendered == "", nil
+	}
+
+	// Templates may be executed by name, and read secrets if any template
+	// they execute does
+	s := &templateSecretRefs{vars: make(map[string]struct{}), defs: make(map[string]struct{})}
+	for changed := true; changed; {
+		changed = false
+		for _, defined := range t.Templates() {
+			if _, ok := s.defs[defined.Name()]; ok || defined.Tree == nil {
+				continue
+			}
+			if s.reads(defined.Tree.Root) {
+				s.defs[defined.Name()] = struct{}{}
+				changed = true
+			}
+		}
+	}
+	s.vars = make(map[string]struct{})
+
+	var pattern strings.Builder
+	var captures []templateCapture
+	captured := false
+	capture := func(secret bool) {
+		if captured {
+			captures[len(captures)-1].secret = captures[len(captures)-1].secret || secret
+			return
+		}
+		pattern.WriteString("(.*?)")
+		captures = append(captures, templateCapture{secret: secret})
+		captured = true
+	}
+	for _, node := range t.Tree.Root.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			if len(n.Text) != 0 {
+				pattern.WriteString(regexp.QuoteMeta(string(n.Text)))
+				captured = false
+			}
+		case *parse.ActionNode:
+			// Declarations and assignments render nothing, but the
+			// variables hold secrets if the pipeline reads them
+			if len(n.Pipe.Decl) != 0 {
+				if s.reads(n.Pipe) {
+					for _, v := range n.Pipe.Decl {
+						s.vars[v.Ident[0]] = struct{}{}
+					}
+				}
+				continue
+			}
+			capture(s.reads(n.Pipe))
+		default:
+			capture(s.reads(node))
+		}
+	}
+
+	re, err := regexp.Compile("(?s)^" + pattern.String() + "$")
+	if err != nil {
+		return nil, false, err
+	}
+	match := re.FindStringSubmatch(rendered)
+	if match == nil {
+		return nil, false, nil
+	}
+	for i := range captures {
+		captures[i].value = match[i+1]
+	}
+	return captures, true, nil
+}
+
+// templateSecretRefs tracks the variables and the defined templates of a
+// template that hold or render secrets.
+type templateSecretRefs struct {
+	vars map[string]struct{}
+	defs map[string]struct{}
+}
+
+// reads returns whether the node calls a function reading secrets, or uses a
+// variable or executes a template that does.
+func (s *templateSecretRefs) reads(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if s.reads(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return s.reads(n.Pipe)
+	case *parse.IfNode:
+		return s.readsBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return s.readsBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return s.readsBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		_, ok := s.defs[n.Name]
+		return ok || s.reads(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if s.reads(cmd) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if s.reads(arg) {
+				return true
+			}
+		}
+	case *parse.ChainNode:
+		return s.reads(n.Node)
+	case *parse.IdentifierNode:
+		for _, f := range secretTemplateFunctions {
+			if n.Ident == f {
+				return true
+			}
+		}
+	case *parse.VariableNode:
+		_, ok := s.vars[n.Ident[0]]
+		return ok
+	}
+	return false
+}
+
+func (s *templateSecretRefs) readsBranch(n *parse.BranchNode) bool {
+	return s.reads(n.Pipe) || s.reads(n.List) || s.reads(n.ElseList)
+}
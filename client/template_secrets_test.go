@@ -0,0 +1,66 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTemplateCaptures(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		Name     string
+		Contents string
+		Rendered string
+		Captures []templateCapture
+		NoMatch  bool
+	}{
+		{
+			Name:     "text only",
+			Contents: "a = 1\n",
+			Rendered: "a = 1\n",
+		},
+		{
+			Name:     "adjacent actions",
+			Contents: `url = {{ env "HOST" }}:{{ with secret "db" }}{{ .Data.port }}{{ end }}{{ env "PATH" }}`,
+			Rendered: "url = db:5432/app",
+			Captures: []templateCapture{{value: "db"}, {value: "5432/app", secret: true}},
+		},
+		{
+			Name:     "trimmed",
+			Contents: "a = {{- key \"a\" -}} \n b",
+			Rendered: "a =1b",
+			Captures: []templateCapture{{value: "1"}},
+		},
+		{
+			Name:     "defined template",
+			Contents: `{{ define "creds" }}{{ with secret "db" }}{{ .Data.password }}{{ end }}{{ end }}user = {{ env "USER" }} password = {{ template "creds" }}`,
+			Rendered: "user = admin password = hunter22",
+			Captures: []templateCapture{{value: "admin"}, {value: "hunter22", secret: true}},
+		},
+		{
+			Name:     "range over variable",
+			Contents: `{{ $all := secrets "kv/" }}{{ range $all }}{{ . }},{{ end }}`,
+			Rendered: "a,b,",
+			Captures: []templateCapture{{value: "a,b,", secret: true}},
+		},
+		{
+			Name:     "no match",
+			Contents: `a = {{ key "a" }}`,
+			Rendered: "b = 1",
+			NoMatch:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		captures, ok, err := templateCaptures(tc.Contents, "{{", "}}", tc.Rendered)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.Name, err)
+		}
+		if ok == tc.NoMatch {
+			t.Fatalf("%s: expected match %v", tc.Name, !tc.NoMatch)
+		}
+		if !reflect.DeepEqual(captures, tc.Captures) {
+			t.Fatalf("%s: expected %#v; got %#v", tc.Name, tc.Captures, captures)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// nomadVariableReader reads the variables stored by the servers for the
+// templates of the tasks. Jobs aren't namespaced, so the variables are read
+// from the default namespace.
+type nomadVariableReader struct {
+	region   string
+	secretID string
+	rpc      func(method string, args, reply interface{}) error
+}
+
+// newNomadVariableReader returns a reader of the variables of the region,
+// authenticated by the secret ID of the node.
+func newNomadVariableReader(region, secretID string, rpc func(string, interface{}, interface{}) error) *nomadVariableReader {
+	return &nomadVariableReader{
+		region:   region,
+		secretID: secretID,
+		rpc:      rpc,
+	}
+}
+
+// NomadVariable returns the items of the variable at the path, or nil if the
+// variable doesn't exist. The request blocks until the variable changes past
+// the index.
+func (r *nomadVariableReader) NomadVariable(path string, index uint64) (map[string]string, uint64, error) {
+	req := structs.VariablesReadRequest{
+		Namespace: structs.DefaultNamespace,
+		Path:      path,
+		QueryOptions: structs.QueryOptions{
+			Region:        r.region,
+			AuthToken:     r.secretID,
+			MinQueryIndex: index,
+			MaxQueryTime:  templateDataWaitTime,
+			AllowStale:    true,
+		},
+	}
+	var resp structs.VariablesReadResponse
+	if err := r.rpc("Variables.Read", &req, &resp); err != nil {
+		return nil, 0, err
+	}
+	if resp.Variable == nil {
+		return nil, resp.Index, nil
+	}
+	return resp.Variable.Items, resp.Index, nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// workloadIdentityFile is the name of the file holding the workload
+	// identity of the allocation inside the secrets directory of its tasks
+	workloadIdentityFile = "nomad_token"
+)
+
+// workloadIdentityReader reads the workload identity the tasks of an
+// allocation call the Nomad API with. The identity is empty if workload
+// identities aren't enabled on the servers.
+type workloadIdentityReader interface {
+	WorkloadIdentity(allocID string) (string, error)
+}
+
+// nomadWorkloadIdentities requests the workload identities of the allocations
+// from the servers.
+type nomadWorkloadIdentities struct {
+	nodeID   string
+	secretID string
+	region   string
+	rpc      func(method string, args, reply interface{}) error
+}
+
+// newNomadWorkloadIdentities returns a reader of the workload identities of
+// the allocations of the node, authenticated by the secret ID of the node.
+func newNomadWorkloadIdentities(nodeID, secretID, region string, rpc func(string, interface{}, interface{}) error) *nomadWorkloadIdentities {
+	return &nomadWorkloadIdentities{
+		nodeID:   nodeID,
+		secretID: secretID,
+		region:   region,
+		rpc:      rpc,
+	}
+}
+
+// WorkloadIdentity requests the workload identity of the allocation.
+func (w *nomadWorkloadIdentities) WorkloadIdentity(allocID string) (string, error) {
+	req := structs.SignWorkloadIdentityRequest{
+		NodeID:   w.nodeID,
+		SecretID: w.secretID,
+		AllocID:  allocID,
+		QueryOptions: structs.QueryOptions{
+			Region:     w.region,
+			AllowStale: false,
+		},
+	}
+	var resp structs.SignWorkloadIdentityResponse
+	if err := w.rpc("Node.SignWorkloadIdentity", &req, &resp); err != nil {
+		return "", structs.NewRecoverableError(fmt.Errorf("SignWorkloadIdentity RPC failed: %v", err), true)
+	}
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+	return resp.Identity, nil
+}
+
+// workloadIdentitySetup gives the task the workload identity of its
+// allocation, in the NOMAD_TOKEN environment variable and the secrets
+// directory, so that it can call the Nomad API. Nothing is done if the client
+// can't request identities or they aren't enabled on the servers.
+func (r *TaskRunner) workloadIdentitySetup(alloc *structs.Allocation) error {
+	reader, ok := r.consul.(workloadIdentityReader)
+	if !ok {
+		return nil
+	}
+	identity, err := reader.WorkloadIdentity(alloc.ID)
+	if err != nil {
+		return err
+	}
+	if identity == "" {
+		return nil
+	}
+
+	tokenPath := filepath.Join(r.taskDir.SecretsDir, workloadIdentityFile)
+	if err := ioutil.WriteFile(tokenPath, []byte(identity), 0600); err != nil {
+		return fmt.Errorf("failed to write workload identity: %v", err)
+	}
+	r.envBuilder.SetWorkloadToken(identity)
+	r.SetSecrets(workloadIdentitySecrets, []string{identity})
+	return nil
+}
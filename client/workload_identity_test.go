@@ -0,0 +1,58 @@
+package client
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/nomad/client/driver/env"
+)
+
+// mockWorkloadIdentityConsul is a service client also handing out workload
+// identities, like the serviceProviders of the client
+type mockWorkloadIdentityConsul struct {
+	*mockConsulServiceClient
+	identity string
+}
+
+func (m *mockWorkloadIdentityConsul) WorkloadIdentity(allocID string) (string, error) {
+	return m.identity, nil
+}
+
+func TestTaskRunner_WorkloadIdentitySetup(t *testing.T) {
+	t.Parallel()
+	ctx := testTaskRunner(t, false)
+	defer ctx.Cleanup()
+	tokenPath := filepath.Join(ctx.tr.taskDir.SecretsDir, workloadIdentityFile)
+
+	// Nothing is set up when workload identities aren't enabled
+	consul := &mockWorkloadIdentityConsul{mockConsulServiceClient: newMockConsulServiceClient()}
+	ctx.tr.consul = consul
+	if err := ctx.tr.workloadIdentitySetup(ctx.tr.alloc); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, ok := ctx.tr.envBuilder.Build().All()[env.WorkloadToken]; ok {
+		t.Fatalf("unexpected %s", env.WorkloadToken)
+	}
+	if _, err := ioutil.ReadFile(tokenPath); err == nil {
+		t.Fatalf("unexpected workload identity file")
+	}
+
+	consul.identity = "header.claims.signature"
+	if err := ctx.tr.workloadIdentitySetup(ctx.tr.alloc); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if act := ctx.tr.envBuilder.Build().All()[env.WorkloadToken]; act != consul.identity {
+		t.Fatalf("expected %s=%q; got %q", env.WorkloadToken, consul.identity, act)
+	}
+	data, err := ioutil.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(data) != consul.identity {
+		t.Fatalf("bad workload identity file: %q", data)
+	}
+	if act := ctx.tr.redactor.redact(consul.identity); act != redactedSecret {
+		t.Fatalf("workload identity not redacted: %q", act)
+	}
+}
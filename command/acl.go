@@ -0,0 +1,247 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+type ACLCommand struct {
+	Meta
+}
+
+func (f *ACLCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *ACLCommand) Synopsis() string {
+	return "Interact with ACL policies, roles, tokens and auth methods"
+}
+
+func (f *ACLCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+type ACLPolicyCommand struct {
+	Meta
+}
+
+func (f *ACLPolicyCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *ACLPolicyCommand) Synopsis() string {
+	return "Interact with ACL policies"
+}
+
+func (f *ACLPolicyCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+type ACLRoleCommand struct {
+	Meta
+}
+
+func (f *ACLRoleCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *ACLRoleCommand) Synopsis() string {
+	return "Interact with ACL roles"
+}
+
+func (f *ACLRoleCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+type ACLAuthMethodCommand struct {
+	Meta
+}
+
+func (f *ACLAuthMethodCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *ACLAuthMethodCommand) Synopsis() string {
+	return "Interact with ACL auth methods"
+}
+
+func (f *ACLAuthMethodCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+type ACLBindingRuleCommand struct {
+	Meta
+}
+
+func (f *ACLBindingRuleCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *ACLBindingRuleCommand) Synopsis() string {
+	return "Interact with ACL binding rules"
+}
+
+func (f *ACLBindingRuleCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+type ACLTokenCommand struct {
+	Meta
+}
+
+func (f *ACLTokenCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *ACLTokenCommand) Synopsis() string {
+	return "Interact with ACL tokens"
+}
+
+func (f *ACLTokenCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// formatACLPolicy formats a policy and its rules
+func formatACLPolicy(policy *api.ACLPolicy) string {
+	basic := []string{
+		fmt.Sprintf("Name|%s", policy.Name),
+		fmt.Sprintf("Description|%s", policy.Description),
+		fmt.Sprintf("Create Index|%d", policy.CreateIndex),
+		fmt.Sprintf("Modify Index|%d", policy.ModifyIndex),
+	}
+	return formatKV(basic) + "\n\nRules:\n\n" + policy.Rules
+}
+
+// formatACLRole formats a role and its policies
+func formatACLRole(role *api.ACLRole) string {
+	basic := []string{
+		fmt.Sprintf("Name|%s", role.Name),
+		fmt.Sprintf("Description|%s", role.Description),
+		fmt.Sprintf("Policies|%s", strings.Join(role.Policies, ", ")),
+		fmt.Sprintf("Create Index|%d", role.CreateIndex),
+		fmt.Sprintf("Modify Index|%d", role.ModifyIndex),
+	}
+	return formatKV(basic)
+}
+
+// formatACLAuthMethod formats an auth method and its configuration, leaving
+// out the client secret
+func formatACLAuthMethod(method *api.ACLAuthMethod) string {
+	basic := []string{
+		fmt.Sprintf("Name|%s", method.Name),
+		fmt.Sprintf("Type|%s", method.Type),
+		fmt.Sprintf("Token Locality|%s", method.TokenLocality),
+		fmt.Sprintf("Max Token TTL|%s", method.MaxTokenTTL),
+		fmt.Sprintf("Default|%v", method.Default),
+		fmt.Sprintf("Create Index|%d", method.CreateIndex),
+		fmt.Sprintf("Modify Index|%d", method.ModifyIndex),
+	}
+	out := formatKV(basic)
+	if config := method.Config; config != nil {
+		conf := []string{
+			fmt.Sprintf("OIDC Discovery URL|%s", config.OIDCDiscoveryURL),
+			fmt.Sprintf("OIDC Client ID|%s", config.OIDCClientID),
+			fmt.Sprintf("OIDC Scopes|%s", strings.Join(config.OIDCScopes, ", ")),
+			fmt.Sprintf("JWKS URL|%s", config.JWKSURL),
+			fmt.Sprintf("JWT Validation Public Keys|%d", len(config.JWTValidationPubKeys)),
+			fmt.Sprintf("Bound Issuer|%s", config.BoundIssuer),
+			fmt.Sprintf("Bound Audiences|%s", strings.Join(config.BoundAudiences, ", ")),
+			fmt.Sprintf("Bound Claims|%s", formatBoundClaims(config.BoundClaims)),
+			fmt.Sprintf("Allowed Redirect URIs|%s", strings.Join(config.AllowedRedirectURIs, ", ")),
+			fmt.Sprintf("Signing Algorithms|%s", strings.Join(config.SigningAlgs, ", ")),
+			fmt.Sprintf("Claim Mappings|%s", formatClaimMappings(config.ClaimMappings)),
+			fmt.Sprintf("List Claim Mappings|%s", formatClaimMappings(config.ListClaimMappings)),
+		}
+		out += "\n\nConfig:\n" + formatKV(conf)
+	}
+	return out
+}
+
+// formatClaimMappings formats the claim mappings of an auth method sorted by
+// claim
+func formatClaimMappings(mappings map[string]string) string {
+	claims := make([]string, 0, len(mappings))
+	for claim := range mappings {
+		claims = append(claims, claim)
+	}
+	sort.Strings(claims)
+	for i, claim := range claims {
+		claims[i] = fmt.Sprintf("%s=%s", claim, mappings[claim])
+	}
+	return strings.Join(claims, ", ")
+}
+
+// formatBoundClaims formats the bound claims of an auth method sorted by claim
+func formatBoundClaims(bound map[string][]string) string {
+	claims := make([]string, 0, len(bound))
+	for claim := range bound {
+		claims = append(claims, claim)
+	}
+	sort.Strings(claims)
+	for i, claim := range claims {
+		claims[i] = fmt.Sprintf("%s=%v", claim, bound[claim])
+	}
+	return strings.Join(claims, ", ")
+}
+
+// formatACLBindingRule formats a binding rule
+func formatACLBindingRule(rule *api.ACLBindingRule) string {
+	basic := []string{
+		fmt.Sprintf("ID|%s", rule.ID),
+		fmt.Sprintf("Description|%s", rule.Description),
+		fmt.Sprintf("Auth Method|%s", rule.AuthMethod),
+		fmt.Sprintf("Selector|%s", rule.Selector),
+		fmt.Sprintf("Bind Type|%s", rule.BindType),
+		fmt.Sprintf("Bind Name|%s", rule.BindName),
+		fmt.Sprintf("Create Index|%d", rule.CreateIndex),
+		fmt.Sprintf("Modify Index|%d", rule.ModifyIndex),
+	}
+	return formatKV(basic)
+}
+
+// formatACLToken formats a token, including its expiry time
+func formatACLToken(token *api.ACLToken) string {
+	policies, roles := "n/a", "n/a"
+	if token.Type != "management" {
+		policies = strings.Join(token.Policies, ", ")
+		roles = strings.Join(token.Roles, ", ")
+	}
+	basic := []string{
+		fmt.Sprintf("Accessor ID|%s", token.AccessorID),
+		fmt.Sprintf("Secret ID|%s", token.SecretID),
+		fmt.Sprintf("Name|%s", token.Name),
+		fmt.Sprintf("Type|%s", token.Type),
+		fmt.Sprintf("Global|%v", token.Global),
+		fmt.Sprintf("Policies|%s", policies),
+		fmt.Sprintf("Roles|%s", roles),
+		fmt.Sprintf("Source CIDRs|%s", formatAllowedCIDRs(token.AllowedCIDRs)),
+		fmt.Sprintf("Create Time|%s", formatTime(token.CreateTime)),
+		fmt.Sprintf("Expiry Time|%s", formatExpiryTime(token.ExpirationTime)),
+		fmt.Sprintf("Create Index|%d", token.CreateIndex),
+		fmt.Sprintf("Modify Index|%d", token.ModifyIndex),
+	}
+	return formatKV(basic)
+}
+
+// formatAllowedCIDRs formats the allowed CIDRs of a token, which may be used
+// from anywhere if it has none
+func formatAllowedCIDRs(cidrs []string) string {
+	if len(cidrs) == 0 {
+		return "<any>"
+	}
+	return strings.Join(cidrs, ", ")
+}
+
+// formatExpiryTime formats the expiration time of a token, which is nil for
+// the tokens that never expire
+func formatExpiryTime(t *time.Time) string {
+	if t == nil {
+		return "<none>"
+	}
+	return formatTime(*t)
+}
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type ACLAuthMethodApplyCommand struct {
+	Meta
+}
+
+func (c *ACLAuthMethodApplyCommand) Help() string {
+	helpText := `
+Usage: nomad acl auth-method apply [options] <name> <path>
+
+Apply is used to create or update an ACL auth method. An auth method lets
+users log in with an external identity provider, and grants them ACL tokens
+through its binding rules. The JSON configuration of the identity provider is
+read from the file at path, or from stdin if path is "-".
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Apply Options:
+
+  -type="OIDC"
+    The type of the auth method, either "OIDC" or "JWT".
+
+  -token-locality="local"
+    Whether the tokens created by logging in are "local" to the region or
+    "global" to all regions.
+
+  -max-token-ttl
+    How long the tokens created by logging in are valid for, such as "1h".
+
+  -default
+    Whether the auth method is used by logging in without naming an auth
+    method. At most one auth method is the default.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLAuthMethodApplyCommand) Synopsis() string {
+	return "Create or update an ACL auth method"
+}
+
+func (c *ACLAuthMethodApplyCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-type":           complete.PredictSet("OIDC", "JWT"),
+			"-token-locality": complete.PredictSet("local", "global"),
+			"-max-token-ttl":  complete.PredictAnything,
+			"-default":        complete.PredictNothing,
+		})
+}
+
+func (c *ACLAuthMethodApplyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictOr(c.PredictACLAuthMethods(), complete.PredictFiles("*.json"))
+}
+
+func (c *ACLAuthMethodApplyCommand) Run(args []string) int {
+	var methodType, locality, ttl string
+	var isDefault bool
+
+	flags := c.Meta.FlagSet("acl auth-method apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&methodType, "type", "OIDC", "")
+	flags.StringVar(&locality, "token-locality", "local", "")
+	flags.StringVar(&ttl, "max-token-ttl", "", "")
+	flags.BoolVar(&isDefault, "default", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got a name, a path and a TTL
+	args = flags.Args()
+	if len(args) != 2 || ttl == "" {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name, path := args[0], args[1]
+
+	maxTTL, err := time.ParseDuration(ttl)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error parsing max token TTL: %s", err))
+		return 1
+	}
+
+	// Read the config
+	var raw []byte
+	if path == "-" {
+		raw, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		raw, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading %q: %s", path, err))
+		return 1
+	}
+	var methodConfig api.ACLAuthMethodConfig
+	if err := json.Unmarshal(raw, &methodConfig); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error parsing auth method config: %s", err))
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	method := &api.ACLAuthMethod{
+		Name:          name,
+		Type:          methodType,
+		TokenLocality: locality,
+		MaxTokenTTL:   maxTTL,
+		Default:       isDefault,
+		Config:        &methodConfig,
+	}
+	if _, err := client.ACLAuthMethods().Upsert(method, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error applying auth method: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully applied ACL auth method %q!", name))
+	return 0
+}
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLAuthMethodApplyCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLAuthMethodApplyCommand{}
+}
+
+func TestACLAuthMethodApplyCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	f, err := ioutil.TempFile("", "nomad-test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(f.Name())
+	config := `{
+  "OIDCDiscoveryURL": "https://example.okta.com",
+  "OIDCClientID": "nomad",
+  "AllowedRedirectURIs": ["http://localhost:4649/oidc/callback"],
+  "ListClaimMappings": {"groups": "groups"}
+}`
+	if _, err := f.WriteString(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+
+	ui := new(cli.MockUi)
+	cmd := &ACLAuthMethodApplyCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without a max token TTL
+	if code := cmd.Run([]string{"-address=" + url, "okta", f.Name()}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	ui.ErrorWriter.Reset()
+
+	args := []string{"-address=" + url, "-token=" + root.SecretID, "-max-token-ttl=1h", "-default", "okta", f.Name()}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `Successfully applied ACL auth method "okta"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	method, _, err := client.ACLAuthMethods().Info("okta", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !method.Default || method.Config.OIDCClientID != "nomad" || method.Config.ListClaimMappings["groups"] != "groups" {
+		t.Fatalf("bad: %#v", method)
+	}
+}
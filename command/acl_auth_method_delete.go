@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLAuthMethodDeleteCommand struct {
+	Meta
+}
+
+func (c *ACLAuthMethodDeleteCommand) Help() string {
+	helpText := `
+Usage: nomad acl auth-method delete [options] <name>
+
+Delete is used to remove an ACL auth method along with its binding rules. The
+tokens created by logging in with the auth method are kept.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLAuthMethodDeleteCommand) Synopsis() string {
+	return "Delete an ACL auth method"
+}
+
+func (c *ACLAuthMethodDeleteCommand) AutocompleteFlags() complete.Flags {
+	return c.Meta.AutocompleteFlags(FlagSetClient)
+}
+
+func (c *ACLAuthMethodDeleteCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLAuthMethods()
+}
+
+func (c *ACLAuthMethodDeleteCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl auth-method delete", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one auth method
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.ACLAuthMethods().Delete(name, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting auth method: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deleted ACL auth method %q!", name))
+	return 0
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLAuthMethodDeleteCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLAuthMethodDeleteCommand{}
+}
+
+func TestACLAuthMethodDeleteCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLAuthMethod(t, client, "okta", "https://example.okta.com")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLAuthMethodDeleteCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without a token
+	if code := cmd.Run([]string{"-address=" + url, "okta"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Permission denied") {
+		t.Fatalf("expected permission denied, got: %s", out)
+	}
+
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "okta"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `Successfully deleted ACL auth method "okta"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	methods, _, err := client.ACLAuthMethods().List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(methods) != 0 {
+		t.Fatalf("bad: %#v", methods)
+	}
+}
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLAuthMethodInfoCommand struct {
+	Meta
+}
+
+func (c *ACLAuthMethodInfoCommand) Help() string {
+	helpText := `
+Usage: nomad acl auth-method info [options] <name>
+
+Info is used to display an ACL auth method and its configuration.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Info Options:
+
+  -json
+    Output the auth method in a JSON format.
+
+  -t
+    Format and display the auth method using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLAuthMethodInfoCommand) Synopsis() string {
+	return "Display an ACL auth method"
+}
+
+func (c *ACLAuthMethodInfoCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLAuthMethodInfoCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLAuthMethods()
+}
+
+func (c *ACLAuthMethodInfoCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl auth-method info", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one auth method
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	method, _, err := client.ACLAuthMethods().Info(args[0], nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving auth method: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, method)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLAuthMethod(method))
+	return 0
+}
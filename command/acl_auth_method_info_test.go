@@ -0,0 +1,49 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLAuthMethodInfoCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLAuthMethodInfoCommand{}
+}
+
+func TestACLAuthMethodInfoCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLAuthMethod(t, client, "okta", "https://example.okta.com")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLAuthMethodInfoCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "okta"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "okta") || !strings.Contains(out, "https://example.okta.com") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Output the auth method in a JSON format
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-json", "okta"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"OIDCDiscoveryURL": "https://example.okta.com"`) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format with a template
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-t", "{{.Name}}", "okta"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "okta" {
+		t.Fatalf("bad: %q", out)
+	}
+}
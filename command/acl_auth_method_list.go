@@ -0,0 +1,113 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type ACLAuthMethodListCommand struct {
+	Meta
+}
+
+func (c *ACLAuthMethodListCommand) Help() string {
+	helpText := `
+Usage: nomad acl auth-method list [options]
+
+List is used to list the ACL auth methods.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Options:
+
+  -json
+    Output the auth methods in a JSON format.
+
+  -t
+    Format and display the auth methods using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLAuthMethodListCommand) Synopsis() string {
+	return "List ACL auth methods"
+}
+
+func (c *ACLAuthMethodListCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLAuthMethodListCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLAuthMethodListCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl auth-method list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	methods, _, err := client.ACLAuthMethods().List(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving auth methods: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, methods)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLAuthMethods(methods))
+	return 0
+}
+
+func formatACLAuthMethods(methods []*api.ACLAuthMethodListStub) string {
+	if len(methods) == 0 {
+		return "No auth methods found"
+	}
+
+	rows := make([]string, len(methods)+1)
+	rows[0] = "Name|Type|Default"
+	for i, method := range methods {
+		rows[i+1] = fmt.Sprintf("%s|%s|%v",
+			method.Name,
+			method.Type,
+			method.Default)
+	}
+	return formatList(rows)
+}
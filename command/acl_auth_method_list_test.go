@@ -0,0 +1,38 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLAuthMethodListCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLAuthMethodListCommand{}
+}
+
+func TestACLAuthMethodListCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, _ := testACLServer(t)
+	defer srv.Shutdown()
+
+	// Auth methods are listed without a token
+	ui := new(cli.MockUi)
+	cmd := &ACLAuthMethodListCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "No auth methods found") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	testACLAuthMethod(t, client, "okta", "https://example.okta.com")
+	if code := cmd.Run([]string{"-address=" + url}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "okta") {
+		t.Fatalf("bad: %s", out)
+	}
+}
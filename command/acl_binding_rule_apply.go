@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type ACLBindingRuleApplyCommand struct {
+	Meta
+}
+
+func (c *ACLBindingRuleApplyCommand) Help() string {
+	helpText := `
+Usage: nomad acl binding-rule apply [options]
+
+Apply is used to create or update an ACL binding rule. The binding rules of
+an auth method decide which roles or policies are granted to the token of a
+user logging in with the auth method.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Apply Options:
+
+  -id
+    The ID of the binding rule to update. A new binding rule is created if
+    omitted.
+
+  -auth-method
+    The name of the auth method the binding rule applies to.
+
+  -selector
+    An expression matched against the identity of the user logging in. The
+    binding rule applies to all users if omitted.
+
+  -bind-type
+    What the binding rule grants: a "role", a "policy", or "management".
+
+  -bind-name
+    The name of the role or policy granted, which can interpolate the claims
+    of the identity such as "${value.team}".
+
+  -description
+    An optional human readable description for the binding rule.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLBindingRuleApplyCommand) Synopsis() string {
+	return "Create or update an ACL binding rule"
+}
+
+func (c *ACLBindingRuleApplyCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-id":          c.PredictACLBindingRules(),
+			"-auth-method": c.PredictACLAuthMethods(),
+			"-selector":    complete.PredictAnything,
+			"-bind-type":   complete.PredictSet("role", "policy", "management"),
+			"-bind-name":   complete.PredictAnything,
+			"-description": complete.PredictAnything,
+		})
+}
+
+func (c *ACLBindingRuleApplyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLBindingRuleApplyCommand) Run(args []string) int {
+	var rule api.ACLBindingRule
+
+	flags := c.Meta.FlagSet("acl binding-rule apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&rule.ID, "id", "", "")
+	flags.StringVar(&rule.AuthMethod, "auth-method", "", "")
+	flags.StringVar(&rule.Selector, "selector", "", "")
+	flags.StringVar(&rule.BindType, "bind-type", "", "")
+	flags.StringVar(&rule.BindName, "bind-name", "", "")
+	flags.StringVar(&rule.Description, "description", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments, an auth method and a bind type
+	if len(flags.Args()) != 0 || rule.AuthMethod == "" || rule.BindType == "" {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	var out *api.ACLBindingRule
+	if rule.ID == "" {
+		out, _, err = client.ACLBindingRules().Create(&rule, nil)
+	} else {
+		out, _, err = client.ACLBindingRules().Update(&rule, nil)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error applying binding rule: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(formatACLBindingRule(out))
+	return 0
+}
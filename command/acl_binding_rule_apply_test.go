@@ -0,0 +1,61 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLBindingRuleApplyCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLBindingRuleApplyCommand{}
+}
+
+func TestACLBindingRuleApplyCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLAuthMethod(t, client, "okta", "https://example.okta.com")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLBindingRuleApplyCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without a bind type
+	if code := cmd.Run([]string{"-address=" + url, "-auth-method=okta"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	ui.ErrorWriter.Reset()
+
+	args := []string{"-address=" + url, "-token=" + root.SecretID, "-auth-method=okta",
+		`-selector="engineering" in list.groups`, "-bind-type=policy", "-bind-name=engineering"}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+
+	rules, _, err := client.ACLBindingRules().List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(rules) != 1 || rules[0].BindName != "engineering" {
+		t.Fatalf("bad: %#v", rules)
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, rules[0].ID) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Update the binding rule by its ID
+	args = []string{"-address=" + url, "-token=" + root.SecretID, "-id=" + rules[0].ID, "-auth-method=okta",
+		"-bind-type=role", "-bind-name=engineering"}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	rule, _, err := client.ACLBindingRules().Info(rules[0].ID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if rule.BindType != "role" || rule.Selector != "" {
+		t.Fatalf("bad: %#v", rule)
+	}
+}
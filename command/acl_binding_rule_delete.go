@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLBindingRuleDeleteCommand struct {
+	Meta
+}
+
+func (c *ACLBindingRuleDeleteCommand) Help() string {
+	helpText := `
+Usage: nomad acl binding-rule delete [options] <id>
+
+Delete is used to remove an ACL binding rule. The tokens created by logging in
+are kept, along with the roles and policies they were granted.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLBindingRuleDeleteCommand) Synopsis() string {
+	return "Delete an ACL binding rule"
+}
+
+func (c *ACLBindingRuleDeleteCommand) AutocompleteFlags() complete.Flags {
+	return c.Meta.AutocompleteFlags(FlagSetClient)
+}
+
+func (c *ACLBindingRuleDeleteCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLBindingRules()
+}
+
+func (c *ACLBindingRuleDeleteCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl binding-rule delete", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one binding rule
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	id := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.ACLBindingRules().Delete(id, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting binding rule: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deleted ACL binding rule %q!", id))
+	return 0
+}
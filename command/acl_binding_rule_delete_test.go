@@ -0,0 +1,49 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLBindingRuleDeleteCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLBindingRuleDeleteCommand{}
+}
+
+func TestACLBindingRuleDeleteCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLAuthMethod(t, client, "okta", "https://example.okta.com")
+	rule := testACLBindingRule(t, client, "okta", "engineering")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLBindingRuleDeleteCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without a token
+	if code := cmd.Run([]string{"-address=" + url, rule.ID}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Permission denied") {
+		t.Fatalf("expected permission denied, got: %s", out)
+	}
+
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, rule.ID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, fmt.Sprintf("Successfully deleted ACL binding rule %q", rule.ID)) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	rules, _, err := client.ACLBindingRules().List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(rules) != 0 {
+		t.Fatalf("bad: %#v", rules)
+	}
+}
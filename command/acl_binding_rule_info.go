@@ -0,0 +1,97 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLBindingRuleInfoCommand struct {
+	Meta
+}
+
+func (c *ACLBindingRuleInfoCommand) Help() string {
+	helpText := `
+Usage: nomad acl binding-rule info [options] <id>
+
+Info is used to display an ACL binding rule.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Info Options:
+
+  -json
+    Output the binding rule in a JSON format.
+
+  -t
+    Format and display the binding rule using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLBindingRuleInfoCommand) Synopsis() string {
+	return "Display an ACL binding rule"
+}
+
+func (c *ACLBindingRuleInfoCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLBindingRuleInfoCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLBindingRules()
+}
+
+func (c *ACLBindingRuleInfoCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl binding-rule info", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one binding rule
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	rule, _, err := client.ACLBindingRules().Info(args[0], nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving binding rule: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, rule)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLBindingRule(rule))
+	return 0
+}
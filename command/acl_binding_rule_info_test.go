@@ -0,0 +1,50 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLBindingRuleInfoCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLBindingRuleInfoCommand{}
+}
+
+func TestACLBindingRuleInfoCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLAuthMethod(t, client, "okta", "https://example.okta.com")
+	rule := testACLBindingRule(t, client, "okta", "engineering")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLBindingRuleInfoCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, rule.ID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, rule.ID) || !strings.Contains(out, rule.Selector) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Output the binding rule in a JSON format
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-json", rule.ID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"ID": "`+rule.ID+`"`) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format with a template
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-t", "{{.AuthMethod}}", rule.ID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "okta" {
+		t.Fatalf("bad: %q", out)
+	}
+}
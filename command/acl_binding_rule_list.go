@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type ACLBindingRuleListCommand struct {
+	Meta
+}
+
+func (c *ACLBindingRuleListCommand) Help() string {
+	helpText := `
+Usage: nomad acl binding-rule list [options]
+
+List is used to list the ACL binding rules.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Options:
+
+  -json
+    Output the binding rules in a JSON format.
+
+  -t
+    Format and display the binding rules using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLBindingRuleListCommand) Synopsis() string {
+	return "List ACL binding rules"
+}
+
+func (c *ACLBindingRuleListCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLBindingRuleListCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLBindingRuleListCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl binding-rule list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	rules, _, err := client.ACLBindingRules().List(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving binding rules: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, rules)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLBindingRules(rules))
+	return 0
+}
+
+func formatACLBindingRules(rules []*api.ACLBindingRule) string {
+	if len(rules) == 0 {
+		return "No binding rules found"
+	}
+
+	rows := make([]string, len(rules)+1)
+	rows[0] = "ID|Auth Method|Bind Type|Bind Name|Description"
+	for i, rule := range rules {
+		rows[i+1] = fmt.Sprintf("%s|%s|%s|%s|%s",
+			rule.ID,
+			rule.AuthMethod,
+			rule.BindType,
+			rule.BindName,
+			rule.Description)
+	}
+	return formatList(rows)
+}
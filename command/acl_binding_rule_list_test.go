@@ -0,0 +1,38 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLBindingRuleListCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLBindingRuleListCommand{}
+}
+
+func TestACLBindingRuleListCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &ACLBindingRuleListCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "No binding rules found") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	testACLAuthMethod(t, client, "okta", "https://example.okta.com")
+	rule := testACLBindingRule(t, client, "okta", "engineering")
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, rule.ID) {
+		t.Fatalf("bad: %s", out)
+	}
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLBootstrapCommand struct {
+	Meta
+}
+
+func (c *ACLBootstrapCommand) Help() string {
+	helpText := `
+Usage: nomad acl bootstrap [options]
+
+Bootstrap is used to create the initial management token of the cluster. It
+can only be done once, and the token should be stored safely since it is
+required to create the other policies and tokens.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLBootstrapCommand) Synopsis() string {
+	return "Create the initial management token"
+}
+
+func (c *ACLBootstrapCommand) AutocompleteFlags() complete.Flags {
+	return c.Meta.AutocompleteFlags(FlagSetClient)
+}
+
+func (c *ACLBootstrapCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLBootstrapCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl bootstrap", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	token, _, err := client.ACLTokens().Bootstrap(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error bootstrapping ACLs: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(formatACLToken(token))
+	return 0
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/command/agent"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/mitchellh/cli"
+)
+
+func TestACLBootstrapCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLBootstrapCommand{}
+}
+
+func TestACLBootstrapCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, _, url := testServer(t, false, func(c *agent.Config) {
+		c.ACL.Enabled = true
+	})
+	defer srv.Shutdown()
+
+	// Retry until a leader is elected
+	var ui *cli.MockUi
+	var cmd *ACLBootstrapCommand
+	testutil.WaitForResult(func() (bool, error) {
+		ui = new(cli.MockUi)
+		cmd = &ACLBootstrapCommand{Meta: Meta{Ui: ui}}
+		code := cmd.Run([]string{"-address=" + url})
+		return code == 0, nil
+	}, func(error) {
+		t.Fatalf("failed to bootstrap: %s", ui.ErrorWriter.String())
+	})
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "management") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Bootstrapping can only be done once
+	if code := cmd.Run([]string{"-address=" + url}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error bootstrapping ACLs") {
+		t.Fatalf("expected bootstrap error, got: %s", out)
+	}
+}
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type ACLPolicyApplyCommand struct {
+	Meta
+}
+
+func (c *ACLPolicyApplyCommand) Help() string {
+	helpText := `
+Usage: nomad acl policy apply [options] <name> <path>
+
+Apply is used to create or update an ACL policy. The rules of the policy are
+read from the file at the given path, or from stdin if the path is "-".
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Apply Options:
+
+  -description
+    An optional human readable description for the policy.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLPolicyApplyCommand) Synopsis() string {
+	return "Create or update an ACL policy"
+}
+
+func (c *ACLPolicyApplyCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-description": complete.PredictAnything,
+		})
+}
+
+func (c *ACLPolicyApplyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictOr(c.PredictACLPolicies(), complete.PredictFiles("*.hcl"))
+}
+
+func (c *ACLPolicyApplyCommand) Run(args []string) int {
+	var description string
+
+	flags := c.Meta.FlagSet("acl policy apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&description, "description", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got a name and a path
+	args = flags.Args()
+	if len(args) != 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name, path := args[0], args[1]
+
+	// Read the rules
+	var rules []byte
+	var err error
+	if path == "-" {
+		rules, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		rules, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading %q: %s", path, err))
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	policy := &api.ACLPolicy{
+		Name:        name,
+		Description: description,
+		Rules:       string(rules),
+	}
+	if _, err := client.ACLPolicies().Upsert(policy, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error applying policy: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully applied ACL policy %q!", name))
+	return 0
+}
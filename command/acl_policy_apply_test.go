@@ -0,0 +1,57 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLPolicyApplyCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLPolicyApplyCommand{}
+}
+
+func TestACLPolicyApplyCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	f, err := ioutil.TempFile("", "nomad-acl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(f.Name())
+	rules := `namespace "default" { policy = "read" }`
+	if _, err := f.WriteString(rules); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+
+	ui := new(cli.MockUi)
+	cmd := &ACLPolicyApplyCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"-address=" + url, "readonly"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	ui.ErrorWriter.Reset()
+
+	args := []string{"-address=" + url, "-token=" + root.SecretID, "-description=Read only", "readonly", f.Name()}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `Successfully applied ACL policy "readonly"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	policy, _, err := client.ACLPolicies().Info("readonly", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if policy.Rules != rules || policy.Description != "Read only" {
+		t.Fatalf("bad: %#v", policy)
+	}
+}
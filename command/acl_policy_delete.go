@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLPolicyDeleteCommand struct {
+	Meta
+}
+
+func (c *ACLPolicyDeleteCommand) Help() string {
+	helpText := `
+Usage: nomad acl policy delete [options] <name>
+
+Delete is used to remove an ACL policy. The tokens referencing the policy are
+kept, but are no longer granted its rules.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLPolicyDeleteCommand) Synopsis() string {
+	return "Delete an ACL policy"
+}
+
+func (c *ACLPolicyDeleteCommand) AutocompleteFlags() complete.Flags {
+	return c.Meta.AutocompleteFlags(FlagSetClient)
+}
+
+func (c *ACLPolicyDeleteCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLPolicies()
+}
+
+func (c *ACLPolicyDeleteCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl policy delete", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one policy
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.ACLPolicies().Delete(name, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting policy: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deleted ACL policy %q!", name))
+	return 0
+}
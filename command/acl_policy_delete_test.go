@@ -0,0 +1,47 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLPolicyDeleteCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLPolicyDeleteCommand{}
+}
+
+func TestACLPolicyDeleteCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLPolicy(t, client, "readonly")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLPolicyDeleteCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without a token
+	if code := cmd.Run([]string{"-address=" + url, "readonly"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Permission denied") {
+		t.Fatalf("expected permission denied, got: %s", out)
+	}
+
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "readonly"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `Successfully deleted ACL policy "readonly"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	policies, _, err := client.ACLPolicies().List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(policies) != 0 {
+		t.Fatalf("bad: %#v", policies)
+	}
+}
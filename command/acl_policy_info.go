@@ -0,0 +1,97 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLPolicyInfoCommand struct {
+	Meta
+}
+
+func (c *ACLPolicyInfoCommand) Help() string {
+	helpText := `
+Usage: nomad acl policy info [options] <name>
+
+Info is used to display an ACL policy and its rules.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Info Options:
+
+  -json
+    Output the policy in a JSON format.
+
+  -t
+    Format and display the policy using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLPolicyInfoCommand) Synopsis() string {
+	return "Display an ACL policy"
+}
+
+func (c *ACLPolicyInfoCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLPolicyInfoCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLPolicies()
+}
+
+func (c *ACLPolicyInfoCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl policy info", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one policy
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	policy, _, err := client.ACLPolicies().Info(args[0], nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving policy: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, policy)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLPolicy(policy))
+	return 0
+}
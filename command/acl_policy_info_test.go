@@ -0,0 +1,48 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLPolicyInfoCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLPolicyInfoCommand{}
+}
+
+func TestACLPolicyInfoCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	policy := testACLPolicy(t, client, "readonly")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLPolicyInfoCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "readonly"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, policy.Rules) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Output the policy in a JSON format
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-json", "readonly"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"Name": "readonly"`) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format with a template
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-t", "{{.Name}}", "readonly"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "readonly" {
+		t.Fatalf("bad: %q", out)
+	}
+}
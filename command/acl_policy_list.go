@@ -0,0 +1,112 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type ACLPolicyListCommand struct {
+	Meta
+}
+
+func (c *ACLPolicyListCommand) Help() string {
+	helpText := `
+Usage: nomad acl policy list [options]
+
+List is used to list the ACL policies.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Options:
+
+  -json
+    Output the policies in a JSON format.
+
+  -t
+    Format and display the policies using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLPolicyListCommand) Synopsis() string {
+	return "List ACL policies"
+}
+
+func (c *ACLPolicyListCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLPolicyListCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLPolicyListCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl policy list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	policies, _, err := client.ACLPolicies().List(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving policies: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, policies)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLPolicies(policies))
+	return 0
+}
+
+func formatACLPolicies(policies []*api.ACLPolicyListStub) string {
+	if len(policies) == 0 {
+		return "No policies found"
+	}
+
+	rows := make([]string, len(policies)+1)
+	rows[0] = "Name|Description"
+	for i, policy := range policies {
+		rows[i+1] = fmt.Sprintf("%s|%s",
+			policy.Name,
+			policy.Description)
+	}
+	return formatList(rows)
+}
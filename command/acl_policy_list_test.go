@@ -0,0 +1,41 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestACLPolicyListCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLPolicyListCommand{}
+}
+
+func TestACLPolicyListCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &ACLPolicyListCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "No policies found") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	policy := &api.ACLPolicy{Name: "readonly", Description: "Read only", Rules: `namespace "default" { policy = "read" }`}
+	if _, err := client.ACLPolicies().Upsert(policy, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "readonly") || !strings.Contains(out, "Read only") {
+		t.Fatalf("bad: %s", out)
+	}
+}
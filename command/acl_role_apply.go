@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/posener/complete"
+)
+
+type ACLRoleApplyCommand struct {
+	Meta
+}
+
+func (c *ACLRoleApplyCommand) Help() string {
+	helpText := `
+Usage: nomad acl role apply [options] <name>
+
+Apply is used to create or update an ACL role. A role is a named set of
+policies, and tokens with the role are granted all of its policies.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Apply Options:
+
+  -description
+    An optional human readable description for the role.
+
+  -policy
+    The name of a policy of the role. It must be given at least once and can
+    be given multiple times.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLRoleApplyCommand) Synopsis() string {
+	return "Create or update an ACL role"
+}
+
+func (c *ACLRoleApplyCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-description": complete.PredictAnything,
+			"-policy":      c.PredictACLPolicies(),
+		})
+}
+
+func (c *ACLRoleApplyCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLRoles()
+}
+
+func (c *ACLRoleApplyCommand) Run(args []string) int {
+	var description string
+	var policies flaghelper.StringFlag
+
+	flags := c.Meta.FlagSet("acl role apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&description, "description", "", "")
+	flags.Var(&policies, "policy", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got a name and at least one policy
+	args = flags.Args()
+	if len(args) != 1 || len(policies) == 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	role := &api.ACLRole{
+		Name:        name,
+		Description: description,
+		Policies:    policies,
+	}
+	if _, err := client.ACLRoles().Upsert(role, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error applying role: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully applied ACL role %q!", name))
+	return 0
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLRoleApplyCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLRoleApplyCommand{}
+}
+
+func TestACLRoleApplyCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLPolicy(t, client, "readonly")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLRoleApplyCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without a policy
+	if code := cmd.Run([]string{"-address=" + url, "reader"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	ui.ErrorWriter.Reset()
+
+	args := []string{"-address=" + url, "-token=" + root.SecretID, "-description=Reader", "-policy=readonly", "reader"}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `Successfully applied ACL role "reader"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	role, _, err := client.ACLRoles().Info("reader", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(role.Policies) != 1 || role.Policies[0] != "readonly" || role.Description != "Reader" {
+		t.Fatalf("bad: %#v", role)
+	}
+}
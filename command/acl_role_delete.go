@@ -0,0 +1,69 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLRoleDeleteCommand struct {
+	Meta
+}
+
+func (c *ACLRoleDeleteCommand) Help() string {
+	helpText := `
+Usage: nomad acl role delete [options] <name>
+
+Delete is used to remove an ACL role. The tokens referencing the role are
+kept, but are no longer granted its policies.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLRoleDeleteCommand) Synopsis() string {
+	return "Delete an ACL role"
+}
+
+func (c *ACLRoleDeleteCommand) AutocompleteFlags() complete.Flags {
+	return c.Meta.AutocompleteFlags(FlagSetClient)
+}
+
+func (c *ACLRoleDeleteCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLRoles()
+}
+
+func (c *ACLRoleDeleteCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl role delete", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one role
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if _, err := client.ACLRoles().Delete(name, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting role: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deleted ACL role %q!", name))
+	return 0
+}
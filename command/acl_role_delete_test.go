@@ -0,0 +1,47 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLRoleDeleteCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLRoleDeleteCommand{}
+}
+
+func TestACLRoleDeleteCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLRole(t, client, "reader")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLRoleDeleteCommand{Meta: Meta{Ui: ui}}
+
+	// Fails without a token
+	if code := cmd.Run([]string{"-address=" + url, "reader"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Permission denied") {
+		t.Fatalf("expected permission denied, got: %s", out)
+	}
+
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "reader"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `Successfully deleted ACL role "reader"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	roles, _, err := client.ACLRoles().List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(roles) != 0 {
+		t.Fatalf("bad: %#v", roles)
+	}
+}
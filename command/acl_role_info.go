@@ -0,0 +1,97 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLRoleInfoCommand struct {
+	Meta
+}
+
+func (c *ACLRoleInfoCommand) Help() string {
+	helpText := `
+Usage: nomad acl role info [options] <name>
+
+Info is used to display an ACL role and its policies.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Info Options:
+
+  -json
+    Output the role in a JSON format.
+
+  -t
+    Format and display the role using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLRoleInfoCommand) Synopsis() string {
+	return "Display an ACL role"
+}
+
+func (c *ACLRoleInfoCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLRoleInfoCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLRoles()
+}
+
+func (c *ACLRoleInfoCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl role info", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one role
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	role, _, err := client.ACLRoles().Info(args[0], nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving role: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, role)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLRole(role))
+	return 0
+}
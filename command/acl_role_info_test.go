@@ -0,0 +1,48 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLRoleInfoCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLRoleInfoCommand{}
+}
+
+func TestACLRoleInfoCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLRole(t, client, "reader")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLRoleInfoCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "reader"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "reader") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Output the role in a JSON format
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-json", "reader"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"Name": "reader"`) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format with a template
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-t", "{{.Name}}", "reader"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "reader" {
+		t.Fatalf("bad: %q", out)
+	}
+}
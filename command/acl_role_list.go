@@ -0,0 +1,113 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type ACLRoleListCommand struct {
+	Meta
+}
+
+func (c *ACLRoleListCommand) Help() string {
+	helpText := `
+Usage: nomad acl role list [options]
+
+List is used to list the ACL roles.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Options:
+
+  -json
+    Output the roles in a JSON format.
+
+  -t
+    Format and display the roles using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLRoleListCommand) Synopsis() string {
+	return "List ACL roles"
+}
+
+func (c *ACLRoleListCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLRoleListCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLRoleListCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl role list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	roles, _, err := client.ACLRoles().List(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving roles: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, roles)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLRoles(roles))
+	return 0
+}
+
+func formatACLRoles(roles []*api.ACLRoleListStub) string {
+	if len(roles) == 0 {
+		return "No roles found"
+	}
+
+	rows := make([]string, len(roles)+1)
+	rows[0] = "Name|Description|Policies"
+	for i, role := range roles {
+		rows[i+1] = fmt.Sprintf("%s|%s|%s",
+			role.Name,
+			role.Description,
+			strings.Join(role.Policies, ","))
+	}
+	return formatList(rows)
+}
@@ -0,0 +1,37 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLRoleListCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLRoleListCommand{}
+}
+
+func TestACLRoleListCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &ACLRoleListCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "No roles found") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	testACLRole(t, client, "reader")
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "reader") {
+		t.Fatalf("bad: %s", out)
+	}
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/command/agent"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// testACLServer returns a server with ACLs enabled and its management token,
+// with which the returned client is made
+func testACLServer(t *testing.T) (*agent.TestAgent, *api.Client, string, *api.ACLToken) {
+	srv, client, url := testServer(t, false, func(c *agent.Config) {
+		c.ACL.Enabled = true
+	})
+
+	var root *api.ACLToken
+	testutil.WaitForResult(func() (bool, error) {
+		var err error
+		root, _, err = client.ACLTokens().Bootstrap(nil)
+		return err == nil, err
+	}, func(err error) {
+		srv.Shutdown()
+		t.Fatalf("failed to bootstrap: %v", err)
+	})
+	client.SetSecretID(root.SecretID)
+	return srv, client, url, root
+}
+
+// testACLPolicy creates a policy allowing to read the default namespace
+func testACLPolicy(t *testing.T, client *api.Client, name string) *api.ACLPolicy {
+	policy := &api.ACLPolicy{Name: name, Rules: `namespace "default" { policy = "read" }`}
+	if _, err := client.ACLPolicies().Upsert(policy, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return policy
+}
+
+// testACLRole creates a role granting a read policy of the same name
+func testACLRole(t *testing.T, client *api.Client, name string) *api.ACLRole {
+	policy := testACLPolicy(t, client, name)
+	role := &api.ACLRole{Name: name, Policies: []string{policy.Name}}
+	if _, err := client.ACLRoles().Upsert(role, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return role
+}
+
+// testACLAuthMethod creates an OIDC auth method of the provider at the
+// discovery URL
+func testACLAuthMethod(t *testing.T, client *api.Client, name, discoveryURL string) *api.ACLAuthMethod {
+	method := &api.ACLAuthMethod{
+		Name:          name,
+		Type:          "OIDC",
+		TokenLocality: "local",
+		MaxTokenTTL:   time.Hour,
+		Config: &api.ACLAuthMethodConfig{
+			OIDCDiscoveryURL:    discoveryURL,
+			OIDCClientID:        "nomad",
+			AllowedRedirectURIs: []string{"http://localhost:4649/oidc/callback"},
+			ListClaimMappings:   map[string]string{"groups": "groups"},
+		},
+	}
+	if _, err := client.ACLAuthMethods().Upsert(method, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return method
+}
+
+// testACLBindingRule creates a binding rule granting the engineers of the
+// auth method a read policy of the same name
+func testACLBindingRule(t *testing.T, client *api.Client, method, name string) *api.ACLBindingRule {
+	policy := testACLPolicy(t, client, name)
+	rule, _, err := client.ACLBindingRules().Create(&api.ACLBindingRule{
+		AuthMethod: method,
+		Selector:   `"engineering" in list.groups`,
+		BindType:   "policy",
+		BindName:   policy.Name,
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return rule
+}
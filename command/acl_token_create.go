@@ -0,0 +1,132 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/posener/complete"
+)
+
+type ACLTokenCreateCommand struct {
+	Meta
+}
+
+func (c *ACLTokenCreateCommand) Help() string {
+	helpText := `
+Usage: nomad acl token create [options]
+
+Create is used to issue a new ACL token. The secret ID of the token is only
+displayed here and by the token's own lookups.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Create Options:
+
+  -name
+    An optional human readable name for the token.
+
+  -type
+    The type of the token, either "client" or "management". Defaults to
+    "client".
+
+  -policy
+    The name of a policy granted to the token. It can be given multiple times
+    and is only used by client tokens.
+
+  -role
+    The name of a role granted to the token, which grants the token the
+    policies of the role. It can be given multiple times and is only used by
+    client tokens.
+
+  -global
+    Replicate the token to all of the regions.
+
+  -ttl
+    The duration after which the token expires, for example "8h". Tokens
+    without a TTL never expire.
+
+  -allowed-cidr
+    An address range the token may be used from, for example "10.0.0.0/8".
+    It can be given multiple times. Tokens without one may be used from
+    anywhere.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLTokenCreateCommand) Synopsis() string {
+	return "Create an ACL token"
+}
+
+func (c *ACLTokenCreateCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-name":         complete.PredictAnything,
+			"-type":         complete.PredictSet("client", "management"),
+			"-policy":       c.PredictACLPolicies(),
+			"-role":         c.PredictACLRoles(),
+			"-global":       complete.PredictNothing,
+			"-ttl":          complete.PredictAnything,
+			"-allowed-cidr": complete.PredictAnything,
+		})
+}
+
+func (c *ACLTokenCreateCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLTokenCreateCommand) Run(args []string) int {
+	var name, tokenType string
+	var global bool
+	var ttl time.Duration
+	var policies, roles, cidrs flaghelper.StringFlag
+
+	flags := c.Meta.FlagSet("acl token create", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&name, "name", "", "")
+	flags.StringVar(&tokenType, "type", "client", "")
+	flags.Var(&policies, "policy", "")
+	flags.Var(&roles, "role", "")
+	flags.BoolVar(&global, "global", false, "")
+	flags.DurationVar(&ttl, "ttl", 0, "")
+	flags.Var(&cidrs, "allowed-cidr", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	token := &api.ACLToken{
+		Name:          name,
+		Type:          tokenType,
+		Policies:      policies,
+		Roles:         roles,
+		Global:        global,
+		AllowedCIDRs:  cidrs,
+		ExpirationTTL: ttl,
+	}
+	token, _, err = client.ACLTokens().Create(token, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating token: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(formatACLToken(token))
+	return 0
+}
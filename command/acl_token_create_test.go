@@ -0,0 +1,73 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLTokenCreateCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLTokenCreateCommand{}
+}
+
+func TestACLTokenCreateCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLPolicy(t, client, "readonly")
+
+	ui := new(cli.MockUi)
+	cmd := &ACLTokenCreateCommand{Meta: Meta{Ui: ui}}
+	args := []string{"-address=" + url, "-token=" + root.SecretID, "-name=ci", "-policy=readonly", "-ttl=1h"}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "ci") || !strings.Contains(out, "readonly") {
+		t.Fatalf("bad: %s", out)
+	}
+	if strings.Contains(out, "Expiry Time  = <none>") {
+		t.Fatalf("expected an expiry time: %s", out)
+	}
+
+	tokens, _, err := client.ACLTokens().List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("bad: %#v", tokens)
+	}
+
+	// Tokens can be granted roles instead of policies
+	testACLRole(t, client, "reader")
+	ui.OutputWriter.Reset()
+	args = []string{"-address=" + url, "-token=" + root.SecretID, "-name=reader", "-role=reader"}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Roles        = reader") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Tokens can be bound to address ranges
+	ui.OutputWriter.Reset()
+	args = []string{"-address=" + url, "-token=" + root.SecretID, "-name=ci", "-policy=readonly",
+		"-allowed-cidr=10.0.0.0/8", "-allowed-cidr=192.168.0.0/16"}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Source CIDRs = 10.0.0.0/8, 192.168.0.0/16") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	args = []string{"-address=" + url, "-token=" + root.SecretID, "-policy=readonly", "-allowed-cidr=10.0.0.0"}
+	if code := cmd.Run(args); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "invalid allowed CIDR") {
+		t.Fatalf("bad: %s", out)
+	}
+}
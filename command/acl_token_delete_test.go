@@ -0,0 +1,38 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestACLTokenDeleteCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLTokenDeleteCommand{}
+}
+
+func TestACLTokenDeleteCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	token, _, err := client.ACLTokens().Create(&api.ACLToken{Name: "ci", Type: "management"}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &ACLTokenDeleteCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, token.AccessorID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Successfully deleted ACL token") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	if _, _, err := client.ACLTokens().Info(token.AccessorID, nil); err == nil {
+		t.Fatalf("expected the token to be deleted")
+	}
+}
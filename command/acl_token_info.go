@@ -0,0 +1,97 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLTokenInfoCommand struct {
+	Meta
+}
+
+func (c *ACLTokenInfoCommand) Help() string {
+	helpText := `
+Usage: nomad acl token info [options] <accessor_id>
+
+Info is used to display an ACL token, including its expiry time.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Info Options:
+
+  -json
+    Output the token in a JSON format.
+
+  -t
+    Format and display the token using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLTokenInfoCommand) Synopsis() string {
+	return "Display an ACL token"
+}
+
+func (c *ACLTokenInfoCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLTokenInfoCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLTokens()
+}
+
+func (c *ACLTokenInfoCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl token info", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one token
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	token, _, err := client.ACLTokens().Info(args[0], nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving token: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, token)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLToken(token))
+	return 0
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLTokenInfoCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLTokenInfoCommand{}
+}
+
+func TestACLTokenInfoCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, _, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &ACLTokenInfoCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, root.AccessorID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, root.AccessorID) || !strings.Contains(out, "Expiry Time  = <none>") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Output the token in a JSON format
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-json", root.AccessorID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"AccessorID": "`+root.AccessorID+`"`) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format with a template
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID, "-t", "{{.AccessorID}}", root.AccessorID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != root.AccessorID {
+		t.Fatalf("bad: %q", out)
+	}
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type ACLTokenListCommand struct {
+	Meta
+}
+
+func (c *ACLTokenListCommand) Help() string {
+	helpText := `
+Usage: nomad acl token list [options]
+
+List is used to list the ACL tokens and their expiry times.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Options:
+
+  -json
+    Output the tokens in a JSON format.
+
+  -t
+    Format and display the tokens using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLTokenListCommand) Synopsis() string {
+	return "List ACL tokens"
+}
+
+func (c *ACLTokenListCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *ACLTokenListCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLTokenListCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("acl token list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	tokens, _, err := client.ACLTokens().List(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving tokens: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, tokens)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatACLTokens(tokens))
+	return 0
+}
+
+func formatACLTokens(tokens []*api.ACLTokenListStub) string {
+	if len(tokens) == 0 {
+		return "No tokens found"
+	}
+
+	rows := make([]string, len(tokens)+1)
+	rows[0] = "Name|Type|Global|Accessor ID|Expiry Time"
+	for i, token := range tokens {
+		rows[i+1] = fmt.Sprintf("%s|%s|%v|%s|%s",
+			token.Name,
+			token.Type,
+			token.Global,
+			token.AccessorID,
+			formatExpiryTime(token.ExpirationTime))
+	}
+	return formatList(rows)
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLTokenListCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLTokenListCommand{}
+}
+
+func TestACLTokenListCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, _, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &ACLTokenListCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, root.AccessorID) || !strings.Contains(out, "Expiry Time") {
+		t.Fatalf("bad: %s", out)
+	}
+}
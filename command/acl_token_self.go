@@ -0,0 +1,68 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type ACLTokenSelfCommand struct {
+	Meta
+}
+
+func (c *ACLTokenSelfCommand) Help() string {
+	helpText := `
+Usage: nomad acl token self [options]
+
+Self is used to display the ACL token the command is run with, which is set
+with the -token flag or the NOMAD_TOKEN environment variable.
+
+General Options:
+
+  ` + generalOptionsUsage()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLTokenSelfCommand) Synopsis() string {
+	return "Display the current ACL token"
+}
+
+func (c *ACLTokenSelfCommand) AutocompleteFlags() complete.Flags {
+	return c.Meta.AutocompleteFlags(FlagSetClient)
+}
+
+func (c *ACLTokenSelfCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *ACLTokenSelfCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("acl token self", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	token, _, err := client.ACLTokens().Self(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving token: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(formatACLToken(token))
+	return 0
+}
@@ -0,0 +1,28 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestACLTokenSelfCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLTokenSelfCommand{}
+}
+
+func TestACLTokenSelfCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, _, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &ACLTokenSelfCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=" + root.SecretID}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, root.SecretID) {
+		t.Fatalf("bad: %s", out)
+	}
+}
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/posener/complete"
+)
+
+type ACLTokenUpdateCommand struct {
+	Meta
+}
+
+func (c *ACLTokenUpdateCommand) Help() string {
+	helpText := `
+Usage: nomad acl token update [options] <accessor_id>
+
+Update is used to change the name, type, policies, roles or allowed CIDRs of an
+ACL token. Only the fields set with the options below are updated. The global
+flag and the expiration of a token can't be changed.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Update Options:
+
+  -name
+    A human readable name for the token.
+
+  -type
+    The type of the token, either "client" or "management".
+
+  -policy
+    The name of a policy granted to the token. It can be given multiple times
+    and replaces the policies of the token.
+
+  -role
+    The name of a role granted to the token. It can be given multiple times
+    and replaces the roles of the token.
+
+  -allowed-cidr
+    An address range the token may be used from. It can be given multiple
+    times and replaces the allowed CIDRs of the token.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLTokenUpdateCommand) Synopsis() string {
+	return "Update an ACL token"
+}
+
+func (c *ACLTokenUpdateCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-name":         complete.PredictAnything,
+			"-type":         complete.PredictSet("client", "management"),
+			"-policy":       c.PredictACLPolicies(),
+			"-role":         c.PredictACLRoles(),
+			"-allowed-cidr": complete.PredictAnything,
+		})
+}
+
+func (c *ACLTokenUpdateCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictACLTokens()
+}
+
+func (c *ACLTokenUpdateCommand) Run(args []string) int {
+	var name, tokenType *string
+	var policies, roles, cidrs flaghelper.StringFlag
+
+	flags := c.Meta.FlagSet("acl token update", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.Var((flaghelper.FuncVar)(func(s string) error {
+		name = &s
+		return nil
+	}), "name", "")
+	flags.Var((flaghelper.FuncVar)(func(s string) error {
+		tokenType = &s
+		return nil
+	}), "type", "")
+	flags.Var(&policies, "policy", "")
+	flags.Var(&roles, "role", "")
+	flags.Var(&cidrs, "allowed-cidr", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one token
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	token, _, err := client.ACLTokens().Info(args[0], nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving token: %s", err))
+		return 1
+	}
+
+	if name != nil {
+		token.Name = *name
+	}
+	if tokenType != nil {
+		token.Type = *tokenType
+
+		// Management tokens have no policies or roles
+		if token.Type == "management" {
+			token.Policies = nil
+			token.Roles = nil
+		}
+	}
+	if len(policies) != 0 {
+		token.Policies = policies
+	}
+	if len(roles) != 0 {
+		token.Roles = roles
+	}
+	if len(cidrs) != 0 {
+		token.AllowedCIDRs = cidrs
+	}
+
+	token, _, err = client.ACLTokens().Update(token, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error updating token: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(formatACLToken(token))
+	return 0
+}
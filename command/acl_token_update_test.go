@@ -0,0 +1,47 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestACLTokenUpdateCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ACLTokenUpdateCommand{}
+}
+
+func TestACLTokenUpdateCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url, root := testACLServer(t)
+	defer srv.Shutdown()
+
+	testACLPolicy(t, client, "readonly")
+
+	token, _, err := client.ACLTokens().Create(&api.ACLToken{
+		Name:          "ci",
+		Type:          "client",
+		Policies:      []string{"readonly"},
+		ExpirationTTL: time.Hour,
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &ACLTokenUpdateCommand{Meta: Meta{Ui: ui}}
+	args := []string{"-address=" + url, "-token=" + root.SecretID, "-name=deploy", token.AccessorID}
+	if code := cmd.Run(args); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+
+	out, _, err := client.ACLTokens().Info(token.AccessorID, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if out.Name != "deploy" || len(out.Policies) != 1 || out.Policies[0] != "readonly" || out.ExpirationTime == nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
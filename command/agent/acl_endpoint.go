@@ -0,0 +1,668 @@
+package agent
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (s *HTTPServer) ACLBootstrapRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.ACLTokenBootstrapRequest{}
+	s.parseRegion(req, &args.Region)
+
+	var out structs.ACLTokenUpsertResponse
+	if err := s.agent.RPC("ACL.Bootstrap", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	if len(out.Tokens) == 0 {
+		return nil, nil
+	}
+	return out.Tokens[0], nil
+}
+
+func (s *HTTPServer) ACLPoliciesRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.ACLPolicyListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ACLPolicyListResponse
+	if err := s.agent.RPC("ACL.ListPolicies", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Policies == nil {
+		out.Policies = make([]*structs.ACLPolicyListStub, 0)
+	}
+	return out.Policies, nil
+}
+
+func (s *HTTPServer) ACLPolicySpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	name := strings.TrimPrefix(req.URL.Path, "/v1/acl/policy/")
+	if len(name) == 0 {
+		return nil, CodedError(400, "Missing Policy Name")
+	}
+	switch req.Method {
+	case "GET":
+		return s.aclPolicyQuery(resp, req, name)
+	case "PUT", "POST":
+		return s.aclPolicyUpdate(resp, req, name)
+	case "DELETE":
+		return s.aclPolicyDelete(resp, req, name)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) aclPolicyQuery(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+	args := structs.ACLPolicySpecificRequest{
+		Name: name,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.SingleACLPolicyResponse
+	if err := s.agent.RPC("ACL.GetPolicy", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Policy == nil {
+		return nil, CodedError(404, "ACL policy not found")
+	}
+	return out.Policy, nil
+}
+
+func (s *HTTPServer) aclPolicyUpdate(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+	// Parse the policy
+	var policy structs.ACLPolicy
+	if err := decodeBody(req, &policy); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	// Ensure the policy name matches
+	if policy.Name != name {
+		return nil, CodedError(400, "ACL policy name does not match request path")
+	}
+
+	// Format the request
+	args := structs.ACLPolicyUpsertRequest{
+		Policies: []*structs.ACLPolicy{&policy},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ACL.UpsertPolicies", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) aclPolicyDelete(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+
+	args := structs.ACLPolicyDeleteRequest{
+		Names: []string{name},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ACL.DeletePolicies", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) ACLRolesRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.ACLRoleListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ACLRoleListResponse
+	if err := s.agent.RPC("ACL.ListRoles", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Roles == nil {
+		out.Roles = make([]*structs.ACLRoleListStub, 0)
+	}
+	return out.Roles, nil
+}
+
+func (s *HTTPServer) ACLRoleSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	name := strings.TrimPrefix(req.URL.Path, "/v1/acl/role/")
+	if len(name) == 0 {
+		return nil, CodedError(400, "Missing Role Name")
+	}
+	switch req.Method {
+	case "GET":
+		return s.aclRoleQuery(resp, req, name)
+	case "PUT", "POST":
+		return s.aclRoleUpdate(resp, req, name)
+	case "DELETE":
+		return s.aclRoleDelete(resp, req, name)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) aclRoleQuery(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+	args := structs.ACLRoleSpecificRequest{
+		Name: name,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.SingleACLRoleResponse
+	if err := s.agent.RPC("ACL.GetRole", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Role == nil {
+		return nil, CodedError(404, "ACL role not found")
+	}
+	return out.Role, nil
+}
+
+func (s *HTTPServer) aclRoleUpdate(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+	// Parse the role
+	var role structs.ACLRole
+	if err := decodeBody(req, &role); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	// Ensure the role name matches
+	if role.Name != name {
+		return nil, CodedError(400, "ACL role name does not match request path")
+	}
+
+	// Format the request
+	args := structs.ACLRoleUpsertRequest{
+		Roles: []*structs.ACLRole{&role},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ACL.UpsertRoles", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) aclRoleDelete(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+
+	args := structs.ACLRoleDeleteRequest{
+		Names: []string{name},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ACL.DeleteRoles", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) ACLAuthMethodsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.ACLAuthMethodListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ACLAuthMethodListResponse
+	if err := s.agent.RPC("ACL.ListAuthMethods", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.AuthMethods == nil {
+		out.AuthMethods = make([]*structs.ACLAuthMethodStub, 0)
+	}
+	return out.AuthMethods, nil
+}
+
+func (s *HTTPServer) ACLAuthMethodSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	name := strings.TrimPrefix(req.URL.Path, "/v1/acl/auth-method/")
+	if len(name) == 0 {
+		return nil, CodedError(400, "Missing Auth Method Name")
+	}
+	switch req.Method {
+	case "GET":
+		return s.aclAuthMethodQuery(resp, req, name)
+	case "PUT", "POST":
+		return s.aclAuthMethodUpdate(resp, req, name)
+	case "DELETE":
+		return s.aclAuthMethodDelete(resp, req, name)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) aclAuthMethodQuery(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+	args := structs.ACLAuthMethodSpecificRequest{
+		Name: name,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.SingleACLAuthMethodResponse
+	if err := s.agent.RPC("ACL.GetAuthMethod", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.AuthMethod == nil {
+		return nil, CodedError(404, "ACL auth method not found")
+	}
+	return out.AuthMethod, nil
+}
+
+func (s *HTTPServer) aclAuthMethodUpdate(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+	// Parse the auth method
+	var method structs.ACLAuthMethod
+	if err := decodeBody(req, &method); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	// Ensure the auth method name matches
+	if method.Name != name {
+		return nil, CodedError(400, "ACL auth method name does not match request path")
+	}
+
+	// Format the request
+	args := structs.ACLAuthMethodUpsertRequest{
+		AuthMethods: []*structs.ACLAuthMethod{&method},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ACL.UpsertAuthMethods", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) aclAuthMethodDelete(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+
+	args := structs.ACLAuthMethodDeleteRequest{
+		Names: []string{name},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ACL.DeleteAuthMethods", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+func (s *HTTPServer) ACLBindingRulesRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.ACLBindingRuleListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ACLBindingRuleListResponse
+	if err := s.agent.RPC("ACL.ListBindingRules", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.BindingRules == nil {
+		out.BindingRules = make([]*structs.ACLBindingRule, 0)
+	}
+	return out.BindingRules, nil
+}
+
+// ACLBindingRuleRequest creates a binding rule
+func (s *HTTPServer) ACLBindingRuleRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	return s.aclBindingRuleUpdate(resp, req, "")
+}
+
+func (s *HTTPServer) ACLBindingRuleSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	id := strings.TrimPrefix(req.URL.Path, "/v1/acl/binding-rule/")
+	if len(id) == 0 {
+		return nil, CodedError(400, "Missing Binding Rule ID")
+	}
+	switch req.Method {
+	case "GET":
+		return s.aclBindingRuleQuery(resp, req, id)
+	case "PUT", "POST":
+		return s.aclBindingRuleUpdate(resp, req, id)
+	case "DELETE":
+		return s.aclBindingRuleDelete(resp, req, id)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) aclBindingRuleQuery(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	args := structs.ACLBindingRuleSpecificRequest{
+		ID: id,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.SingleACLBindingRuleResponse
+	if err := s.agent.RPC("ACL.GetBindingRule", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.BindingRule == nil {
+		return nil, CodedError(404, "ACL binding rule not found")
+	}
+	return out.BindingRule, nil
+}
+
+func (s *HTTPServer) aclBindingRuleUpdate(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+	// Parse the binding rule
+	var rule structs.ACLBindingRule
+	if err := decodeBody(req, &rule); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	// Ensure the binding rule ID matches
+	if rule.ID != id {
+		return nil, CodedError(400, "ACL binding rule ID does not match request path")
+	}
+
+	// Format the request
+	args := structs.ACLBindingRuleUpsertRequest{
+		BindingRules: []*structs.ACLBindingRule{&rule},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.ACLBindingRuleUpsertResponse
+	if err := s.agent.RPC("ACL.UpsertBindingRules", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	if len(out.BindingRules) == 0 {
+		return nil, nil
+	}
+	return out.BindingRules[0], nil
+}
+
+func (s *HTTPServer) aclBindingRuleDelete(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+
+	args := structs.ACLBindingRuleDeleteRequest{
+		IDs: []string{id},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ACL.DeleteBindingRules", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
+
+// ACLOIDCAuthURLRequest starts logging in with an OIDC auth method
+func (s *HTTPServer) ACLOIDCAuthURLRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var args structs.ACLOIDCAuthURLRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	s.parseRegion(req, &args.Region)
+
+	var out structs.ACLOIDCAuthURLResponse
+	if err := s.agent.RPC("ACL.OIDCAuthURL", &args, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ACLOIDCCompleteAuthRequest completes logging in with an OIDC auth method
+// and returns the ACL token created
+func (s *HTTPServer) ACLOIDCCompleteAuthRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var args structs.ACLOIDCCompleteAuthRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	s.parseRegion(req, &args.Region)
+
+	var out structs.ACLLoginResponse
+	if err := s.agent.RPC("ACL.OIDCCompleteAuth", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out.Token, nil
+}
+
+// ACLLoginRequest logs in with a JWT auth method
+func (s *HTTPServer) ACLLoginRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var args structs.ACLLoginRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	s.parseRegion(req, &args.Region)
+
+	var out structs.ACLLoginResponse
+	if err := s.agent.RPC("ACL.Login", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out.Token, nil
+}
+
+func (s *HTTPServer) ACLTokensRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.ACLTokenListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+	if global := req.URL.Query().Get("global"); global != "" {
+		globalOnly, err := strconv.ParseBool(global)
+		if err != nil {
+			return nil, CodedError(400, "Invalid global value")
+		}
+		args.GlobalOnly = globalOnly
+	}
+
+	var out structs.ACLTokenListResponse
+	if err := s.agent.RPC("ACL.ListTokens", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Tokens == nil {
+		out.Tokens = make([]*structs.ACLTokenListStub, 0)
+	}
+	return out.Tokens, nil
+}
+
+// ACLTokenRequest creates a token
+func (s *HTTPServer) ACLTokenRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	return s.aclTokenUpdate(resp, req, "")
+}
+
+func (s *HTTPServer) ACLTokenSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	accessorID := strings.TrimPrefix(req.URL.Path, "/v1/acl/token/")
+	if len(accessorID) == 0 {
+		return nil, CodedError(400, "Missing Token Accessor ID")
+	}
+	if accessorID == "self" {
+		if req.Method != "GET" {
+			return nil, CodedError(405, ErrInvalidMethod)
+		}
+		return s.aclTokenSelf(resp, req)
+	}
+
+	switch req.Method {
+	case "GET":
+		return s.aclTokenQuery(resp, req, accessorID)
+	case "PUT", "POST":
+		return s.aclTokenUpdate(resp, req, accessorID)
+	case "DELETE":
+		return s.aclTokenDelete(resp, req, accessorID)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) aclTokenQuery(resp http.ResponseWriter, req *http.Request,
+	accessorID string) (interface{}, error) {
+	args := structs.ACLTokenSpecificRequest{
+		AccessorID: accessorID,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.SingleACLTokenResponse
+	if err := s.agent.RPC("ACL.GetToken", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Token == nil {
+		return nil, CodedError(404, "ACL token not found")
+	}
+	return out.Token, nil
+}
+
+func (s *HTTPServer) aclTokenSelf(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.ResolveACLTokenRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+	args.SecretID = args.AuthToken
+
+	var out structs.ResolveACLTokenResponse
+	if err := s.agent.RPC("ACL.ResolveToken", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	return out.Token, nil
+}
+
+func (s *HTTPServer) aclTokenUpdate(resp http.ResponseWriter, req *http.Request,
+	accessorID string) (interface{}, error) {
+	// Parse the token
+	var token structs.ACLToken
+	if err := decodeBody(req, &token); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	// Ensure the accessor ID matches when updating a token
+	if accessorID != "" && token.AccessorID != accessorID {
+		return nil, CodedError(400, "ACL token accessor ID does not match request path")
+	}
+	if accessorID == "" && token.AccessorID != "" {
+		return nil, CodedError(400, "ACL token accessor ID must not be set on creation")
+	}
+
+	// Format the request
+	args := structs.ACLTokenUpsertRequest{
+		Tokens: []*structs.ACLToken{&token},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.ACLTokenUpsertResponse
+	if err := s.agent.RPC("ACL.UpsertTokens", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	if len(out.Tokens) == 0 {
+		return nil, nil
+	}
+	return out.Tokens[0], nil
+}
+
+func (s *HTTPServer) aclTokenDelete(resp http.ResponseWriter, req *http.Request,
+	accessorID string) (interface{}, error) {
+
+	args := structs.ACLTokenDeleteRequest{
+		AccessorIDs: []string{accessorID},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ACL.DeleteTokens", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
@@ -0,0 +1,379 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// httpACLTest runs the test against an agent with ACLs enabled and a
+// management token
+func httpACLTest(t testing.TB, cb func(c *Config), f func(srv *TestAgent, root *structs.ACLToken)) {
+	httpTest(t, func(c *Config) {
+		c.ACL.Enabled = true
+		if cb != nil {
+			cb(c)
+		}
+	}, func(s *TestAgent) {
+		root := mock.ACLManagementToken()
+		if err := s.Agent.server.State().BootstrapACLTokens(1, root); err != nil {
+			t.Fatalf("failed to bootstrap: %v", err)
+		}
+		f(s, root)
+	})
+}
+
+// setToken sets the ACL token of the request
+func setToken(req *http.Request, token *structs.ACLToken) {
+	req.Header.Set("X-Nomad-Token", token.SecretID)
+}
+
+func TestHTTP_ACLBootstrap(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, func(c *Config) {
+		c.ACL.Enabled = true
+	}, func(s *TestAgent) {
+		req, err := http.NewRequest("PUT", "/v1/acl/bootstrap", nil)
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.ACLBootstrapRequest(respW, req)
+		assert.Nil(err, "Bootstrap Request")
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		token := obj.(*structs.ACLToken)
+		assert.Equal(structs.ACLManagementToken, token.Type, "Token Type")
+		assert.NotEmpty(token.SecretID, "Token SecretID")
+	})
+}
+
+func TestHTTP_ACLPolicyCRUD(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpACLTest(t, nil, func(s *TestAgent, root *structs.ACLToken) {
+		// Create the policy
+		policy := mock.ACLPolicy()
+		req, err := http.NewRequest("PUT", "/v1/acl/policy/"+policy.Name, encodeReq(policy))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		respW := httptest.NewRecorder()
+		_, err = s.Server.ACLPolicySpecificRequest(respW, req)
+		assert.Nil(err, "Upsert Request")
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		// The name must match the path
+		req, err = http.NewRequest("PUT", "/v1/acl/policy/other", encodeReq(policy))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		_, err = s.Server.ACLPolicySpecificRequest(httptest.NewRecorder(), req)
+		assert.NotNil(err, "Mismatched Name")
+
+		// Query the policy
+		req, err = http.NewRequest("GET", "/v1/acl/policy/"+policy.Name, nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err := s.Server.ACLPolicySpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Query Request")
+		assert.Equal(policy.Rules, obj.(*structs.ACLPolicy).Rules, "Policy Rules")
+
+		// List the policies
+		req, err = http.NewRequest("GET", "/v1/acl/policies", nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err = s.Server.ACLPoliciesRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "List Request")
+		assert.Len(obj.([]*structs.ACLPolicyListStub), 1, "Policies")
+
+		// Delete the policy
+		req, err = http.NewRequest("DELETE", "/v1/acl/policy/"+policy.Name, nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		_, err = s.Server.ACLPolicySpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Delete Request")
+
+		out, err := s.Agent.server.State().ACLPolicyByName(nil, policy.Name)
+		assert.Nil(err, "ACLPolicyByName")
+		assert.Nil(out, "Deleted Policy")
+	})
+}
+
+func TestHTTP_ACLRoleCRUD(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpACLTest(t, nil, func(s *TestAgent, root *structs.ACLToken) {
+		// Create the role and its policy
+		policy := mock.ACLPolicy()
+		err := s.Agent.server.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{policy})
+		assert.Nil(err, "UpsertACLPolicies")
+		role := mock.ACLRole()
+		role.Policies = []string{policy.Name}
+		req, err := http.NewRequest("PUT", "/v1/acl/role/"+role.Name, encodeReq(role))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		respW := httptest.NewRecorder()
+		_, err = s.Server.ACLRoleSpecificRequest(respW, req)
+		assert.Nil(err, "Upsert Request")
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		// The name must match the path
+		req, err = http.NewRequest("PUT", "/v1/acl/role/other", encodeReq(role))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		_, err = s.Server.ACLRoleSpecificRequest(httptest.NewRecorder(), req)
+		assert.NotNil(err, "Mismatched Name")
+
+		// Query the role
+		req, err = http.NewRequest("GET", "/v1/acl/role/"+role.Name, nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err := s.Server.ACLRoleSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Query Request")
+		assert.Equal(role.Policies, obj.(*structs.ACLRole).Policies, "Role Policies")
+
+		// List the roles
+		req, err = http.NewRequest("GET", "/v1/acl/roles", nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err = s.Server.ACLRolesRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "List Request")
+		assert.Len(obj.([]*structs.ACLRoleListStub), 1, "Roles")
+
+		// Delete the role
+		req, err = http.NewRequest("DELETE", "/v1/acl/role/"+role.Name, nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		_, err = s.Server.ACLRoleSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Delete Request")
+
+		out, err := s.Agent.server.State().ACLRoleByName(nil, role.Name)
+		assert.Nil(err, "ACLRoleByName")
+		assert.Nil(out, "Deleted Role")
+	})
+}
+
+func TestHTTP_ACLAuthMethodCRUD(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpACLTest(t, nil, func(s *TestAgent, root *structs.ACLToken) {
+		// Create the auth method
+		method := mock.ACLAuthMethod()
+		req, err := http.NewRequest("PUT", "/v1/acl/auth-method/"+method.Name, encodeReq(method))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		respW := httptest.NewRecorder()
+		_, err = s.Server.ACLAuthMethodSpecificRequest(respW, req)
+		assert.Nil(err, "Upsert Request")
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		// Query the auth method
+		req, err = http.NewRequest("GET", "/v1/acl/auth-method/"+method.Name, nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err := s.Server.ACLAuthMethodSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Query Request")
+		assert.Equal(method.Config.OIDCClientID, obj.(*structs.ACLAuthMethod).Config.OIDCClientID, "Auth Method Config")
+
+		// List the auth methods without a token
+		req, err = http.NewRequest("GET", "/v1/acl/auth-methods", nil)
+		assert.Nil(err, "HTTP Request")
+		obj, err = s.Server.ACLAuthMethodsRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "List Request")
+		assert.Len(obj.([]*structs.ACLAuthMethodStub), 1, "Auth Methods")
+
+		// Delete the auth method
+		req, err = http.NewRequest("DELETE", "/v1/acl/auth-method/"+method.Name, nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		_, err = s.Server.ACLAuthMethodSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Delete Request")
+
+		out, err := s.Agent.server.State().ACLAuthMethodByName(nil, method.Name)
+		assert.Nil(err, "ACLAuthMethodByName")
+		assert.Nil(out, "Deleted Auth Method")
+	})
+}
+
+func TestHTTP_ACLBindingRuleCRUD(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpACLTest(t, nil, func(s *TestAgent, root *structs.ACLToken) {
+		method := mock.ACLAuthMethod()
+		err := s.Agent.server.State().UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{method})
+		assert.Nil(err, "UpsertACLAuthMethods")
+
+		// Create the binding rule
+		rule := mock.ACLBindingRule()
+		rule.ID = ""
+		rule.AuthMethod = method.Name
+		req, err := http.NewRequest("PUT", "/v1/acl/binding-rule", encodeReq(rule))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err := s.Server.ACLBindingRuleRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Create Request")
+		created := obj.(*structs.ACLBindingRule)
+		assert.NotEmpty(created.ID, "Binding Rule ID")
+
+		// The ID must match the path
+		req, err = http.NewRequest("PUT", "/v1/acl/binding-rule/other", encodeReq(created))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		_, err = s.Server.ACLBindingRuleSpecificRequest(httptest.NewRecorder(), req)
+		assert.NotNil(err, "Mismatched ID")
+
+		// Update the binding rule
+		created.BindName = "engineering"
+		req, err = http.NewRequest("PUT", "/v1/acl/binding-rule/"+created.ID, encodeReq(created))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err = s.Server.ACLBindingRuleSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Update Request")
+		assert.Equal("engineering", obj.(*structs.ACLBindingRule).BindName, "Binding Rule BindName")
+
+		// List the binding rules
+		req, err = http.NewRequest("GET", "/v1/acl/binding-rules", nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err = s.Server.ACLBindingRulesRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "List Request")
+		assert.Len(obj.([]*structs.ACLBindingRule), 1, "Binding Rules")
+
+		// Delete the binding rule
+		req, err = http.NewRequest("DELETE", "/v1/acl/binding-rule/"+created.ID, nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		_, err = s.Server.ACLBindingRuleSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Delete Request")
+
+		out, err := s.Agent.server.State().ACLBindingRuleByID(nil, created.ID)
+		assert.Nil(err, "ACLBindingRuleByID")
+		assert.Nil(out, "Deleted Binding Rule")
+	})
+}
+
+func TestHTTP_ACLLogin(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpACLTest(t, nil, func(s *TestAgent, root *structs.ACLToken) {
+		provider := testutil.NewTestOIDCProvider(t)
+		defer provider.Stop()
+
+		state := s.Agent.server.State()
+		method := mock.ACLAuthMethod()
+		method.Type = structs.ACLAuthMethodTypeJWT
+		method.Config = &structs.ACLAuthMethodConfig{
+			JWTValidationPubKeys: []string{provider.PublicKeyPEM()},
+			ClaimMappings:        map[string]string{"team": "team"},
+		}
+		err := state.UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{method})
+		assert.Nil(err, "UpsertACLAuthMethods")
+		policy := mock.ACLPolicy()
+		policy.Name = "web"
+		err = state.UpsertACLPolicies(1001, []*structs.ACLPolicy{policy})
+		assert.Nil(err, "UpsertACLPolicies")
+		rule := mock.ACLBindingRule()
+		rule.AuthMethod = method.Name
+		rule.Selector = ""
+		err = state.UpsertACLBindingRules(1002, []*structs.ACLBindingRule{rule})
+		assert.Nil(err, "UpsertACLBindingRules")
+
+		jwt, err := provider.SignJWT(map[string]interface{}{
+			"team": "web",
+			"exp":  time.Now().Add(time.Minute).Unix(),
+		})
+		assert.Nil(err, "SignJWT")
+
+		// Log in without a token
+		args := structs.ACLLoginRequest{AuthMethodName: method.Name, LoginToken: jwt}
+		req, err := http.NewRequest("PUT", "/v1/acl/login", encodeReq(args))
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+		obj, err := s.Server.ACLLoginRequest(respW, req)
+		assert.Nil(err, "Login Request")
+		assert.NotEqual("", respW.HeaderMap.Get("X-Nomad-Index"), "Index")
+		token := obj.(*structs.ACLToken)
+		assert.Equal([]string{"web"}, token.Policies, "Token Policies")
+
+		out, err := state.ACLTokenBySecretID(nil, token.SecretID)
+		assert.Nil(err, "ACLTokenBySecretID")
+		assert.NotNil(out, "Created Token")
+	})
+}
+
+func TestHTTP_ACLTokenCRUD(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpACLTest(t, nil, func(s *TestAgent, root *structs.ACLToken) {
+		// Create an expiring token
+		token := &structs.ACLToken{
+			Name:          "expiring",
+			Type:          structs.ACLManagementToken,
+			ExpirationTTL: time.Hour,
+		}
+		req, err := http.NewRequest("PUT", "/v1/acl/token", encodeReq(token))
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err := s.Server.ACLTokenRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Create Request")
+		created := obj.(*structs.ACLToken)
+		assert.NotEmpty(created.AccessorID, "Token AccessorID")
+		assert.NotNil(created.ExpirationTime, "Token ExpirationTime")
+
+		// Look the token up by itself
+		req, err = http.NewRequest("GET", "/v1/acl/token/self", nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, created)
+		obj, err = s.Server.ACLTokenSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Self Request")
+		assert.Equal(created.AccessorID, obj.(*structs.ACLToken).AccessorID, "Self Token")
+
+		// List the tokens
+		req, err = http.NewRequest("GET", "/v1/acl/tokens", nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		obj, err = s.Server.ACLTokensRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "List Request")
+		assert.Len(obj.([]*structs.ACLTokenListStub), 2, "Tokens")
+
+		// Delete the token
+		req, err = http.NewRequest("DELETE", "/v1/acl/token/"+created.AccessorID, nil)
+		assert.Nil(err, "HTTP Request")
+		setToken(req, root)
+		_, err = s.Server.ACLTokenSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Delete Request")
+
+		out, err := s.Agent.server.State().ACLTokenByAccessorID(nil, created.AccessorID)
+		assert.Nil(err, "ACLTokenByAccessorID")
+		assert.Nil(out, "Deleted Token")
+	})
+}
+
+func TestHTTP_ACLPermissionDenied(t *testing.T) {
+	t.Parallel()
+	httpACLTest(t, nil, func(s *TestAgent, root *structs.ACLToken) {
+		// Requests without a token are denied
+		req, err := http.NewRequest("GET", "/v1/agent/self", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		s.Server.wrap(s.Server.AgentSelfRequest)(respW, req)
+		if respW.Code != 403 {
+			t.Fatalf("expected 403, got %d: %s", respW.Code, respW.Body.String())
+		}
+
+		// And allowed with a management token
+		setToken(req, root)
+		respW = httptest.NewRecorder()
+		s.Server.wrap(s.Server.AgentSelfRequest)(respW, req)
+		if respW.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", respW.Code, respW.Body.String())
+		}
+	})
+}
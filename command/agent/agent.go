@@ -1,8 +1,13 @@
 package agent
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
@@ -13,11 +18,16 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/armon/go-metrics"
+	"github.com/dustin/go-humanize"
 	"github.com/hashicorp/consul/api"
 	version "github.com/hashicorp/go-version"
 	"github.com/hashicorp/nomad/client"
 	clientconfig "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/helper/tracing"
+	"github.com/hashicorp/nomad/helper/audit"
 	"github.com/hashicorp/nomad/nomad"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/structs/config"
@@ -55,6 +65,14 @@ type Agent struct {
 	// consulCatalog is the subset of Consul's Catalog API Nomad uses.
 	consulCatalog consul.CatalogAPI
 
+	// consulACLs is the subset of Consul's ACL API Nomad servers use to
+	// manage Service Identity tokens.
+	consulACLs consul.ACLsAPI
+
+	// consulConfigEntries is the subset of Consul's configuration entries
+	// API Nomad servers use to configure Connect gateways.
+	consulConfigEntries consul.ConfigEntriesAPI
+
 	// consulSupportsTLSSkipVerify flags whether or not Nomad can register
 	// checks with TLSSkipVerify
 	consulSupportsTLSSkipVerify bool
@@ -63,6 +81,22 @@ type Agent struct {
 
 	server *nomad.Server
 
+	// logWriter buffers the agent's most recent logs, inmemSink holds its
+	// recent metrics and prometheusSink exposes its metrics to Prometheus.
+	// They are nil unless set by the command starting the agent, and
+	// prometheusSink is also nil unless enabled.
+	logWriter      *logWriter
+	inmemSink      *metrics.InmemSink
+	prometheusSink *prometheusSink
+
+	// tracer records the trace spans of the agent. It is nil unless tracing
+	// is enabled.
+	tracer *tracing.Tracer
+
+	// auditor records the requests made to the HTTP API. It is nil unless
+	// audit logging is enabled.
+	auditor *audit.Auditor
+
 	shutdown     bool
 	shutdownCh   chan struct{}
 	shutdownLock sync.Mutex
@@ -76,6 +110,13 @@ func NewAgent(config *Config, logOutput io.Writer) (*Agent, error) {
 		logOutput:  logOutput,
 		shutdownCh: make(chan struct{}),
 	}
+	a.tracer = tracing.New(config.Telemetry.tracingConfig(config.NodeName, config.Datacenter), a.logger)
+
+	auditor, err := audit.New(config.Audit.auditConfig(), a.logger)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize audit logging: %v", err)
+	}
+	a.auditor = auditor
 
 	if err := a.setupConsul(config.Consul); err != nil {
 		return nil, fmt.Errorf("Failed to initialize Consul client: %v", err)
@@ -201,6 +242,75 @@ func convertServerConfig(agentConfig *Config, logOutput io.Writer) (*nomad.Confi
 	if maxHPS := agentConfig.Server.MaxHeartbeatsPerSecond; maxHPS != 0 {
 		conf.MaxHeartbeatsPerSecond = maxHPS
 	}
+	if size := agentConfig.Server.MaxDispatchPayloadSize; size != "" {
+		limit, err := humanize.ParseBytes(size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse max_dispatch_payload_size %q: %v", size, err)
+		}
+		conf.DispatchPayloadSizeLimit = int(limit)
+	}
+	for _, policy := range agentConfig.Server.AdmissionPolicies {
+		conf.AdmissionPolicies = append(conf.AdmissionPolicies, policy.Copy())
+	}
+	for _, webhook := range agentConfig.Server.DeploymentWebhooks {
+		conf.DeploymentWebhooks = append(conf.DeploymentWebhooks, webhook.Copy())
+	}
+	conf.JobNotification = agentConfig.Server.JobNotification.Copy()
+	conf.ImageVerification = agentConfig.Server.ImageVerification.Copy()
+
+	// Set the variables encryption key, generating one in dev mode since
+	// its variables don't outlive the agent
+	if agentConfig.Server.VariablesEncryptionKey != "" {
+		key, err := agentConfig.Server.VariablesEncryptionKeyBytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse variables_encryption_key: %v", err)
+		}
+		conf.VariablesEncryptionKey = key
+	} else if agentConfig.DevMode {
+		key := make([]byte, nomad.VariablesKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate variables encryption key: %v", err)
+		}
+		conf.VariablesEncryptionKey = key
+	}
+
+	// Set the workload identity signing key, generating one in dev mode so
+	// that tasks can call the API with their workload identity
+	if file := agentConfig.Server.WorkloadIdentitySigningKeyFile; file != "" {
+		key, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workload_identity_signing_key_file: %v", err)
+		}
+		conf.WorkloadIdentitySigningKey = key
+	} else if agentConfig.DevMode {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate workload identity signing key: %v", err)
+		}
+		conf.WorkloadIdentitySigningKey = pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})
+	}
+
+	// Set the RPC rate limits
+	if agentConfig.Limits != nil {
+		conf.RPCRateLimit = agentConfig.Limits.RPCConfig()
+		conf.RPCTokenRateLimit = agentConfig.Limits.RPCTokenConfig()
+	}
+
+	// Set the ACL configuration
+	if agentConfig.ACL != nil {
+		conf.ACLEnabled = agentConfig.ACL.Enabled
+		conf.ReplicationToken = agentConfig.ACL.ReplicationToken
+		if agentConfig.ACL.TokenMinExpirationTTL != 0 {
+			conf.ACLTokenMinExpirationTTL = agentConfig.ACL.TokenMinExpirationTTL
+		}
+		if agentConfig.ACL.TokenMaxExpirationTTL != 0 {
+			conf.ACLTokenMaxExpirationTTL = agentConfig.ACL.TokenMaxExpirationTTL
+		}
+	}
+	conf.AuthoritativeRegion = agentConfig.Server.AuthoritativeRegion
 
 	if *agentConfig.Consul.AutoAdvertise && agentConfig.Consul.ServerServiceName == "" {
 		return nil, fmt.Errorf("server_service_name must be set when auto_advertise is enabled")
@@ -219,7 +329,12 @@ func convertServerConfig(agentConfig *Config, logOutput io.Writer) (*nomad.Confi
 // serverConfig is used to generate a new server configuration struct
 // for initializing a nomad server.
 func (a *Agent) serverConfig() (*nomad.Config, error) {
-	return convertServerConfig(a.config, a.logOutput)
+	conf, err := convertServerConfig(a.config, a.logOutput)
+	if err != nil {
+		return nil, err
+	}
+	conf.Tracer = a.tracer
+	return conf, nil
 }
 
 // clientConfig is used to generate a new client configuration struct
@@ -249,6 +364,21 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	if a.config.Client.AllocDir != "" {
 		conf.AllocDir = a.config.Client.AllocDir
 	}
+	if a.config.Client.CNIPath != "" {
+		conf.CNIPath = a.config.Client.CNIPath
+	}
+	if a.config.Client.CNIConfigDir != "" {
+		conf.CNIConfigDir = a.config.Client.CNIConfigDir
+	}
+	if a.config.Client.BridgeNetworkName != "" {
+		conf.BridgeNetworkName = a.config.Client.BridgeNetworkName
+	}
+	if a.config.Client.BridgeNetworkSubnet != "" {
+		conf.BridgeNetworkSubnet = a.config.Client.BridgeNetworkSubnet
+	}
+	if a.config.Client.BridgeNetworkSubnetIPv6 != "" {
+		conf.BridgeNetworkSubnetIPv6 = a.config.Client.BridgeNetworkSubnetIPv6
+	}
 	conf.Servers = a.config.Client.Servers
 	if a.config.Client.NetworkInterface != "" {
 		conf.NetworkInterface = a.config.Client.NetworkInterface
@@ -273,6 +403,10 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	if a.config.Client.NetworkSpeed != 0 {
 		conf.NetworkSpeed = a.config.Client.NetworkSpeed
 	}
+	for _, n := range a.config.Client.HostNetworks {
+		conf.HostNetworks = append(conf.HostNetworks, n.Copy())
+	}
+	conf.DNSConfig = a.config.Client.DNS.Copy()
 	if a.config.Client.CpuCompute != 0 {
 		conf.CpuCompute = a.config.Client.CpuCompute
 	}
@@ -293,6 +427,13 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	conf.Node.Meta = a.config.Client.Meta
 	conf.Node.NodeClass = a.config.Client.NodeClass
 
+	// Set the range of the dynamic ports of the node
+	if err := structs.ValidateDynamicPortRange(a.config.Client.MinDynamicPort, a.config.Client.MaxDynamicPort); err != nil {
+		return nil, err
+	}
+	conf.Node.MinDynamicPort = a.config.Client.MinDynamicPort
+	conf.Node.MaxDynamicPort = a.config.Client.MaxDynamicPort
+
 	// Set up the HTTP advertise address
 	conf.Node.HTTPAddr = a.config.AdvertiseAddrs.HTTP
 
@@ -320,6 +461,9 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 	conf.StatsCollectionInterval = a.config.Telemetry.collectionInterval
 	conf.PublishNodeMetrics = a.config.Telemetry.PublishNodeMetrics
 	conf.PublishAllocationMetrics = a.config.Telemetry.PublishAllocationMetrics
+	if labels := a.config.Telemetry.AllocationMetricLabels; labels != nil {
+		conf.AllocationMetricLabels = helper.CopySliceString(labels)
+	}
 
 	// Set the TLS related configs
 	conf.TLSConfig = a.config.TLSConfig
@@ -338,6 +482,14 @@ func (a *Agent) clientConfig() (*clientconfig.Config, error) {
 		conf.NoHostUUID = true
 	}
 
+	// Set the ACL configuration
+	if a.config.ACL != nil {
+		conf.ACLEnabled = a.config.ACL.Enabled
+		if a.config.ACL.TokenTTL != 0 {
+			conf.ACLTokenTTL = a.config.ACL.TokenTTL
+		}
+	}
+
 	return conf, nil
 }
 
@@ -359,7 +511,7 @@ func (a *Agent) setupServer() error {
 	}
 
 	// Create the server
-	server, err := nomad.NewServer(conf, a.consulCatalog, a.logger)
+	server, err := nomad.NewServer(conf, a.consulCatalog, a.consulACLs, a.consulConfigEntries, a.logger)
 	if err != nil {
 		return fmt.Errorf("server setup failed: %v", err)
 	}
@@ -649,6 +801,12 @@ func (a *Agent) Shutdown() error {
 		a.logger.Printf("[ERR] agent: shutting down Consul client failed: %v", err)
 	}
 
+	// Export the remaining trace spans
+	a.tracer.Shutdown()
+
+	// Write the remaining audit events
+	a.auditor.Close()
+
 	a.logger.Println("[INFO] agent: shutdown complete")
 	a.shutdown = true
 	close(a.shutdownCh)
@@ -711,8 +869,15 @@ func (a *Agent) setupConsul(consulConfig *config.ConsulConfig) error {
 	// Create Consul Catalog client for service discovery.
 	a.consulCatalog = client.Catalog()
 
+	// Create Consul ACLs client for managing Service Identity tokens.
+	a.consulACLs = consul.NewACLsClient(apiConf)
+
+	// Create Consul configuration entries client for Connect gateways.
+	a.consulConfigEntries = consul.NewConfigEntriesClient(apiConf)
+
 	// Create Consul Service client for service advertisement and checks.
-	a.consulService = consul.NewServiceClient(client.Agent(), a.consulSupportsTLSSkipVerify, a.logger)
+	a.consulService = consul.NewServiceClient(client.Agent(), consul.NewConnectClient(client),
+		consul.NewNamespacesClient(apiConf), a.consulSupportsTLSSkipVerify, a.logger)
 
 	// Run the Consul service client's sync'ing main loop
 	go a.consulService.Run()
@@ -1,10 +1,16 @@
 package agent
 
 import (
+	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"strings"
 
+	"github.com/armon/go-metrics"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/hashicorp/logutils"
+	"github.com/hashicorp/nomad/acl"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/serf/serf"
 	"github.com/mitchellh/copystructure"
@@ -44,6 +50,9 @@ func (s *HTTPServer) AgentSelfRequest(resp http.ResponseWriter, req *http.Reques
 	if req.Method != "GET" {
 		return nil, CodedError(405, ErrInvalidMethod)
 	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentRead); err != nil {
+		return nil, err
+	}
 
 	// Get the member as a server
 	var member serf.Member
@@ -65,6 +74,12 @@ func (s *HTTPServer) AgentSelfRequest(resp http.ResponseWriter, req *http.Reques
 	if self.Config != nil && self.Config.Vault != nil && self.Config.Vault.Token != "" {
 		self.Config.Vault.Token = "<redacted>"
 	}
+	if self.Config != nil && self.Config.Consul != nil && self.Config.Consul.Token != "" {
+		self.Config.Consul.Token = "<redacted>"
+	}
+	if self.Config != nil && self.Config.Telemetry != nil && self.Config.Telemetry.CirconusAPIToken != "" {
+		self.Config.Telemetry.CirconusAPIToken = "<redacted>"
+	}
 
 	return self, nil
 }
@@ -73,6 +88,9 @@ func (s *HTTPServer) AgentJoinRequest(resp http.ResponseWriter, req *http.Reques
 	if req.Method != "PUT" && req.Method != "POST" {
 		return nil, CodedError(405, ErrInvalidMethod)
 	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentWrite); err != nil {
+		return nil, err
+	}
 	srv := s.agent.Server()
 	if srv == nil {
 		return nil, CodedError(501, ErrInvalidMethod)
@@ -98,6 +116,9 @@ func (s *HTTPServer) AgentMembersRequest(resp http.ResponseWriter, req *http.Req
 	if req.Method != "GET" {
 		return nil, CodedError(405, ErrInvalidMethod)
 	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentRead); err != nil {
+		return nil, err
+	}
 	args := &structs.GenericRequest{}
 	var out structs.ServerMembersResponse
 	if err := s.agent.RPC("Status.Members", args, &out); err != nil {
@@ -111,6 +132,9 @@ func (s *HTTPServer) AgentForceLeaveRequest(resp http.ResponseWriter, req *http.
 	if req.Method != "PUT" && req.Method != "POST" {
 		return nil, CodedError(405, ErrInvalidMethod)
 	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentWrite); err != nil {
+		return nil, err
+	}
 	srv := s.agent.Server()
 	if srv == nil {
 		return nil, CodedError(501, ErrInvalidMethod)
@@ -146,6 +170,9 @@ func (s *HTTPServer) listServers(resp http.ResponseWriter, req *http.Request) (i
 	if client == nil {
 		return nil, CodedError(501, ErrInvalidMethod)
 	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentRead); err != nil {
+		return nil, err
+	}
 
 	peers := s.agent.client.GetServers()
 	return peers, nil
@@ -156,6 +183,9 @@ func (s *HTTPServer) updateServers(resp http.ResponseWriter, req *http.Request)
 	if client == nil {
 		return nil, CodedError(501, ErrInvalidMethod)
 	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentWrite); err != nil {
+		return nil, err
+	}
 
 	// Get the servers from the request
 	servers := req.URL.Query()["address"]
@@ -180,6 +210,10 @@ func (s *HTTPServer) KeyringOperationRequest(resp http.ResponseWriter, req *http
 		return nil, CodedError(501, ErrInvalidMethod)
 	}
 
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentWrite); err != nil {
+		return nil, err
+	}
+
 	kmgr := srv.KeyManager()
 	var sresp *serf.KeyResponse
 	var err error
@@ -223,6 +257,133 @@ func (s *HTTPServer) KeyringOperationRequest(resp http.ResponseWriter, req *http
 	return kresp, nil
 }
 
+// AgentMetricsRequest returns the agent's recent metrics, one entry per
+// aggregation interval.
+func (s *HTTPServer) AgentMetricsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentRead); err != nil {
+		return nil, err
+	}
+
+	out := []*metrics.IntervalMetrics{}
+	if s.agent.inmemSink == nil {
+		return out, nil
+	}
+
+	// Copy the intervals since the current one is still being written to
+	for _, intv := range s.agent.inmemSink.Data() {
+		intv.RLock()
+		c := metrics.NewIntervalMetrics(intv.Interval)
+		for k, v := range intv.Gauges {
+			c.Gauges[k] = v
+		}
+		for k, v := range intv.Points {
+			c.Points[k] = append([]float32(nil), v...)
+		}
+		for k, v := range intv.Counters {
+			sample := *v
+			c.Counters[k] = &sample
+		}
+		for k, v := range intv.Samples {
+			sample := *v
+			c.Samples[k] = &sample
+		}
+		intv.RUnlock()
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// AgentLogsRequest returns the agent's most recent log lines.
+func (s *HTTPServer) AgentLogsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentRead); err != nil {
+		return nil, err
+	}
+
+	if s.agent.logWriter == nil {
+		return []string{}, nil
+	}
+	return s.agent.logWriter.Logs(), nil
+}
+
+// AgentMonitorRequest streams the agent's logs at or above the requested log
+// level, starting with the most recent buffered logs.
+func (s *HTTPServer) AgentMonitorRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	if err := s.checkACL(req, (*acl.ACL).AllowAgentRead); err != nil {
+		return nil, err
+	}
+
+	logLevel := req.URL.Query().Get("log_level")
+	if logLevel == "" {
+		logLevel = "INFO"
+	}
+	filter := LevelFilter()
+	filter.MinLevel = logutils.LogLevel(strings.ToUpper(logLevel))
+	if !ValidateLevelFilter(filter.MinLevel, filter) {
+		return nil, CodedError(400, fmt.Sprintf("Unknown log level: %s", logLevel))
+	}
+
+	if s.agent.logWriter == nil {
+		return nil, CodedError(501, "Log streaming is not available")
+	}
+
+	handler := &monitorLogHandler{
+		filter: filter,
+		logCh:  make(chan string, monitorBufferSize),
+	}
+	s.agent.logWriter.RegisterHandler(handler)
+	defer s.agent.logWriter.DeregisterHandler(handler)
+
+	// Create an output that gets flushed on every write and send the headers
+	// right away so the client knows the stream started
+	resp.Header().Set("Content-Type", "text/plain")
+	output := ioutils.NewWriteFlusher(resp)
+	output.Flush()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return nil, nil
+		case <-s.agent.shutdownCh:
+			return nil, nil
+		case line := <-handler.logCh:
+			if _, err := io.WriteString(output, line+"\n"); err != nil {
+				return nil, nil
+			}
+		}
+	}
+}
+
+// monitorBufferSize is the number of log lines buffered for a monitor
+// before lines are dropped.
+const monitorBufferSize = 512
+
+// monitorLogHandler sends the logs passing its filter to a channel. Logs are
+// dropped if the channel is full so a slow reader doesn't block the agent's
+// logging.
+type monitorLogHandler struct {
+	filter *logutils.LevelFilter
+	logCh  chan string
+}
+
+func (h *monitorLogHandler) HandleLog(log string) {
+	if !h.filter.Check([]byte(log)) {
+		return
+	}
+	select {
+	case h.logCh <- log:
+	default:
+	}
+}
+
 type agentSelf struct {
 	Config *Config                      `json:"config"`
 	Member Member                       `json:"member,omitempty"`
@@ -2,13 +2,16 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -53,6 +56,105 @@ func TestHTTP_AgentSelf(t *testing.T) {
 		if self.Config.Vault.Token != "<redacted>" {
 			t.Fatalf("bad: %#v", self)
 		}
+
+		// Assign a Consul token and assert it is redacted.
+		s.Config.Consul.Token = "badc0deb-adc0-deba-dc0d-ebadc0debadc"
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.AgentSelfRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		self = obj.(agentSelf)
+		if self.Config.Consul.Token != "<redacted>" {
+			t.Fatalf("bad: %#v", self)
+		}
+	})
+}
+
+func TestHTTP_AgentMetrics(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		s.Agent.inmemSink = metrics.NewInmemSink(10*time.Second, time.Minute)
+		s.Agent.inmemSink.SetGauge([]string{"nomad", "test"}, 42)
+
+		req, err := http.NewRequest("GET", "/v1/agent/metrics", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.AgentMetricsRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		intvs := obj.([]*metrics.IntervalMetrics)
+		if len(intvs) != 1 || intvs[0].Gauges["nomad.test"] != 42 {
+			t.Fatalf("bad: %#v", intvs)
+		}
+	})
+}
+
+func TestHTTP_AgentLogs(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		s.Agent.logWriter = NewLogWriter(10)
+		s.Agent.logWriter.Write([]byte("[INFO] agent: foo\n"))
+
+		req, err := http.NewRequest("GET", "/v1/agent/logs", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.AgentLogsRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		logs := obj.([]string)
+		if len(logs) != 1 || logs[0] != "[INFO] agent: foo" {
+			t.Fatalf("bad: %#v", logs)
+		}
+	})
+}
+
+func TestHTTP_AgentMonitor(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		s.Agent.logWriter = NewLogWriter(10)
+		s.Agent.logWriter.Write([]byte("[DEBUG] agent: foo\n"))
+		s.Agent.logWriter.Write([]byte("[INFO] agent: bar\n"))
+
+		// Invalid log levels are rejected
+		req, err := http.NewRequest("GET", "/v1/agent/monitor?log_level=foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		_, err = s.Server.AgentMonitorRequest(httptest.NewRecorder(), req)
+		if err == nil || !strings.Contains(err.Error(), "Unknown log level") {
+			t.Fatalf("expected log level error, got: %v", err)
+		}
+
+		// Stream the logs until the request is done
+		req, err = http.NewRequest("GET", "/v1/agent/monitor?log_level=info", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.AgentMonitorRequest(respW, req.WithContext(ctx)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		out := respW.Body.String()
+		if !strings.Contains(out, "[INFO] agent: bar") {
+			t.Fatalf("missing info log: %q", out)
+		}
+		if strings.Contains(out, "[DEBUG] agent: foo") {
+			t.Fatalf("debug log should be filtered: %q", out)
+		}
 	})
 }
 
@@ -199,6 +199,21 @@ func TestAgent_ServerConfig(t *testing.T) {
 		t.Fatalf("expect 11, got: %v", max)
 	}
 
+	conf.Server.MaxDispatchPayloadSize = "1MB"
+	out, err = a.serverConfig()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if limit := out.DispatchPayloadSizeLimit; limit != 1000*1000 {
+		t.Fatalf("expect 1000000, got: %v", limit)
+	}
+
+	conf.Server.MaxDispatchPayloadSize = "lots"
+	if _, err = a.serverConfig(); err == nil {
+		t.Fatalf("expected error for invalid max_dispatch_payload_size")
+	}
+	conf.Server.MaxDispatchPayloadSize = ""
+
 	// Defaults to the global bind addr
 	conf.Addresses.RPC = ""
 	conf.Addresses.Serf = ""
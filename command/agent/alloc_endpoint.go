@@ -1,17 +1,27 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang/snappy"
+	"github.com/hashicorp/consul-template/signals"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/api"
+	cstructs "github.com/hashicorp/nomad/client/structs"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
 const (
 	allocNotFoundErr    = "allocation not found"
 	resourceNotFoundErr = "resource not found"
+
+	// maxAllocExecTimeout is the maximum, and default, time a command run in
+	// a task may take
+	maxAllocExecTimeout = 5 * time.Minute
 )
 
 func (s *HTTPServer) AllocsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
@@ -37,7 +47,70 @@ func (s *HTTPServer) AllocsRequest(resp http.ResponseWriter, req *http.Request)
 }
 
 func (s *HTTPServer) AllocSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
-	allocID := strings.TrimPrefix(req.URL.Path, "/v1/allocation/")
+	path := strings.TrimPrefix(req.URL.Path, "/v1/allocation/")
+	switch {
+	case strings.HasSuffix(path, "/stop"):
+		allocID := strings.TrimSuffix(path, "/stop")
+		return s.allocStop(resp, req, allocID)
+	case strings.HasSuffix(path, "/signal"):
+		allocID := strings.TrimSuffix(path, "/signal")
+		return s.allocSignalRequest(resp, req, allocID)
+	default:
+		return s.allocQuery(resp, req, path)
+	}
+}
+
+func (s *HTTPServer) allocStop(resp http.ResponseWriter, req *http.Request, allocID string) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.AllocStopRequest{
+		AllocID: allocID,
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.AllocStopResponse
+	if err := s.agent.RPC("Alloc.Stop", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
+func (s *HTTPServer) allocSignalRequest(resp http.ResponseWriter, req *http.Request, allocID string) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var body api.AllocSignalRequest
+	if err := decodeBody(req, &body); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if body.Signal == "" {
+		return nil, CodedError(400, "missing signal")
+	}
+	if _, err := signals.Parse(body.Signal); err != nil {
+		return nil, CodedError(400, fmt.Sprintf("invalid signal %q: %v", body.Signal, err))
+	}
+
+	args := structs.AllocSignalRequest{
+		AllocID: allocID,
+		Task:    body.Task,
+		Signal:  body.Signal,
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("Alloc.Signal", &args, &out); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *HTTPServer) allocQuery(resp http.ResponseWriter, req *http.Request, allocID string) (interface{}, error) {
 	if req.Method != "GET" {
 		return nil, CodedError(405, ErrInvalidMethod)
 	}
@@ -87,6 +160,21 @@ func (s *HTTPServer) ClientAllocRequest(resp http.ResponseWriter, req *http.Requ
 		return nil, CodedError(404, resourceNotFoundErr)
 	}
 	allocID := tokens[0]
+
+	// Reading the stats and snapshot of an allocation needs to read its
+	// namespace, running commands in its tasks needs the alloc-exec
+	// capability and the other actions need to write it
+	allowed := (*acl.ACL).AllowNamespaceWrite
+	switch tokens[1] {
+	case "stats", "snapshot":
+		allowed = (*acl.ACL).AllowNamespaceRead
+	case "exec":
+		allowed = allowNamespaceOperation(acl.NamespaceCapabilityAllocExec)
+	}
+	if err := s.checkClientAlloc(req, allocID, allowed); err != nil {
+		return nil, err
+	}
+
 	switch tokens[1] {
 	case "stats":
 		return s.allocStats(allocID, resp, req)
@@ -94,6 +182,12 @@ func (s *HTTPServer) ClientAllocRequest(resp http.ResponseWriter, req *http.Requ
 		return s.allocSnapshot(allocID, resp, req)
 	case "gc":
 		return s.allocGC(allocID, resp, req)
+	case "signal":
+		return s.allocSignal(allocID, resp, req)
+	case "restart":
+		return s.allocRestart(allocID, resp, req)
+	case "exec":
+		return s.allocExec(allocID, resp, req)
 	}
 
 	return nil, CodedError(404, resourceNotFoundErr)
@@ -103,13 +197,137 @@ func (s *HTTPServer) ClientGCRequest(resp http.ResponseWriter, req *http.Request
 	if s.agent.client == nil {
 		return nil, clientNotRunning
 	}
+	if err := s.checkACL(req, (*acl.ACL).AllowNodeWrite); err != nil {
+		return nil, err
+	}
 	return nil, s.agent.Client().CollectAllAllocs()
 }
 
+// checkClientAlloc returns ErrPermissionDenied unless the token of the
+// request passes the check on the namespace of the allocation running on the
+// client
+func (s *HTTPServer) checkClientAlloc(req *http.Request, allocID string, allowed func(*acl.ACL, string) bool) error {
+	aclObj, err := s.resolveToken(req)
+	if err != nil {
+		return err
+	}
+	return s.allowClientAlloc(aclObj, allocID, allowed)
+}
+
+// allowClientAlloc returns ErrPermissionDenied unless the ACL passes the
+// check on the namespace of the allocation running on the client
+func (s *HTTPServer) allowClientAlloc(aclObj *acl.ACL, allocID string, allowed func(*acl.ACL, string) bool) error {
+	if aclObj.IsManagement() {
+		return nil
+	}
+
+	alloc, err := s.agent.Client().GetClientAlloc(allocID)
+	if err != nil {
+		return err
+	}
+	ns := structs.DefaultNamespace
+	if alloc.Job != nil && alloc.Job.Namespace != "" {
+		ns = alloc.Job.Namespace
+	}
+
+	if !allowed(aclObj, ns) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
+// allowNamespaceOperation returns a check of the capability on a namespace
+func allowNamespaceOperation(capability string) func(*acl.ACL, string) bool {
+	return func(aclObj *acl.ACL, ns string) bool {
+		return aclObj.AllowNamespaceOperation(ns, capability)
+	}
+}
+
 func (s *HTTPServer) allocGC(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	return nil, s.agent.Client().CollectAllocation(allocID)
 }
 
+func (s *HTTPServer) allocSignal(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var args api.AllocSignalRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if args.Signal == "" {
+		return nil, CodedError(400, "missing signal")
+	}
+	sig, err := signals.Parse(args.Signal)
+	if err != nil {
+		return nil, CodedError(400, fmt.Sprintf("invalid signal %q: %v", args.Signal, err))
+	}
+
+	return nil, s.agent.Client().SignalAllocation(allocID, args.Task, sig)
+}
+
+func (s *HTTPServer) allocRestart(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var args api.AllocRestartRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	return nil, s.agent.Client().RestartAllocation(allocID, args.Task)
+}
+
+func (s *HTTPServer) allocExec(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var args api.AllocExecRequest
+	if err := decodeBody(req, &args); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if args.Task == "" {
+		return nil, CodedError(400, "missing task")
+	}
+	if args.Command == "" {
+		return nil, CodedError(400, "missing command")
+	}
+	timeout := args.Timeout
+	if timeout <= 0 || timeout > maxAllocExecTimeout {
+		timeout = maxAllocExecTimeout
+	}
+
+	// Tasks without filesystem isolation run directly on the node, so
+	// running commands in them also needs the alloc-node-exec capability
+	fsi, err := s.agent.Client().AllocTaskFSIsolation(allocID, args.Task)
+	if err != nil {
+		return nil, err
+	}
+	if fsi == cstructs.FSIsolationNone {
+		allowed := allowNamespaceOperation(acl.NamespaceCapabilityAllocNodeExec)
+		if err := s.checkClientAlloc(req, allocID, allowed); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+	output, code, err := s.agent.Client().ExecAllocation(ctx, allocID, args.Task, args.Command, args.Args)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &api.AllocExecResponse{
+		Output:   string(output),
+		ExitCode: code,
+	}, nil
+}
+
 func (s *HTTPServer) allocSnapshot(allocID string, resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	allocFS, err := s.agent.Client().GetAllocFS(allocID)
 	if err != nil {
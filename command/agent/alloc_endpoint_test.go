@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/golang/snappy"
+	"github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -223,6 +224,84 @@ func TestHTTP_AllocQuery_Payload(t *testing.T) {
 	})
 }
 
+func TestHTTP_AllocStop(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		alloc := mock.Alloc()
+		if err := state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID)); err != nil {
+			t.Fatal(err)
+		}
+		if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("PUT", "/v1/allocation/"+alloc.ID+"/stop", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.AllocSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// Check the response
+		resp := obj.(structs.AllocStopResponse)
+		if resp.EvalID == "" {
+			t.Fatalf("bad: %#v", resp)
+		}
+
+		// Check the allocation is stopped
+		out, err := state.AllocByID(nil, alloc.ID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out.DesiredStatus != structs.AllocDesiredStatusStop {
+			t.Fatalf("bad desired status: %v", out.DesiredStatus)
+		}
+	})
+}
+
+func TestHTTP_AllocSignalRequest(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// A valid signal is required
+		for _, body := range []*api.AllocSignalRequest{{}, {Signal: "SIGFOO"}} {
+			req, err := http.NewRequest("PUT", "/v1/allocation/123/signal", encodeReq(body))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			respW := httptest.NewRecorder()
+			_, err = s.Server.AllocSpecificRequest(respW, req)
+			if err == nil || !strings.Contains(err.Error(), "signal") {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		// The allocation must exist
+		path := "/v1/allocation/" + structs.GenerateUUID() + "/signal"
+		req, err := http.NewRequest("PUT", path, encodeReq(&api.AllocSignalRequest{Signal: "SIGHUP"}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		_, err = s.Server.AllocSpecificRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
 func TestHTTP_AllocStats(t *testing.T) {
 	t.Parallel()
 	httpTest(t, nil, func(s *TestAgent) {
@@ -277,6 +356,62 @@ func TestHTTP_AllocGC(t *testing.T) {
 	})
 }
 
+func TestHTTP_AllocSignal(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// A signal is required
+		req, err := http.NewRequest("PUT", "/v1/client/allocation/123/signal", encodeReq(&api.AllocSignalRequest{}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "missing signal") {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The signal must be valid
+		req, err = http.NewRequest("PUT", "/v1/client/allocation/123/signal", encodeReq(&api.AllocSignalRequest{Signal: "SIGFOO"}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "invalid signal") {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The allocation must exist
+		req, err = http.NewRequest("PUT", "/v1/client/allocation/123/signal", encodeReq(&api.AllocSignalRequest{Signal: "SIGHUP"}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "unknown allocation ID") {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
+func TestHTTP_AllocRestart(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// Make the HTTP request
+		req, err := http.NewRequest("PUT", "/v1/client/allocation/123/restart", encodeReq(&api.AllocRestartRequest{}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "unknown allocation ID") {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
 func TestHTTP_AllocAllGC(t *testing.T) {
 	t.Parallel()
 	httpTest(t, nil, func(s *TestAgent) {
@@ -295,3 +430,40 @@ func TestHTTP_AllocAllGC(t *testing.T) {
 	})
 
 }
+
+func TestHTTP_AllocExec(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// A task and command are required
+		req, err := http.NewRequest("PUT", "/v1/client/allocation/123/exec", encodeReq(&api.AllocExecRequest{Command: "ls"}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "missing task") {
+			t.Fatalf("err: %v", err)
+		}
+
+		req, err = http.NewRequest("PUT", "/v1/client/allocation/123/exec", encodeReq(&api.AllocExecRequest{Task: "web"}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "missing command") {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The allocation must exist
+		req, err = http.NewRequest("PUT", "/v1/client/allocation/123/exec", encodeReq(&api.AllocExecRequest{Task: "web", Command: "ls"}))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.ClientAllocRequest(respW, req)
+		if err == nil || !strings.Contains(err.Error(), "unknown allocation ID") {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
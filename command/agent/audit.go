@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/nomad/helper/audit"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// auditResponseWriter records the status code of the response to an audited
+// request
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *auditResponseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush flushes the response of streaming endpoints
+func (w *auditResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// auditReceived records the audit event of a received request. It returns
+// the event, which is completed once the request is handled, or an error if
+// the event couldn't be written to an enforced sink.
+func (s *HTTPServer) auditReceived(req *http.Request) (*audit.Event, error) {
+	var secretID string
+	s.parseToken(req, &secretID)
+
+	event := &audit.Event{
+		ID:        structs.GenerateUUID(),
+		Timestamp: time.Now().UTC(),
+		Stage:     audit.StageReceived,
+		Auth:      s.auditAuth(secretID),
+		Request: &audit.Request{
+			ID:         structs.GenerateUUID(),
+			Operation:  req.Method,
+			Endpoint:   req.URL.Path,
+			Namespace:  req.URL.Query().Get("namespace"),
+			RemoteAddr: req.RemoteAddr,
+			UserAgent:  req.UserAgent(),
+		},
+	}
+	return event, s.agent.auditor.Event(event)
+}
+
+// auditDetailsKey is the context key of the audit details of a request
+type auditDetailsKey struct{}
+
+// auditDetails are the details of an audited request that are only known
+// once its handler decoded it. They are recorded when it completes.
+type auditDetails struct {
+	policyOverrideJustification string
+}
+
+// withAuditDetails returns the request with empty audit details its handler
+// fills in
+func withAuditDetails(req *http.Request) (*http.Request, *auditDetails) {
+	details := &auditDetails{}
+	return req.WithContext(context.WithValue(req.Context(), auditDetailsKey{}, details)), details
+}
+
+// auditPolicyOverride records the justification of a policy override in the
+// audit event of the request, if it is audited
+func auditPolicyOverride(req *http.Request, justification string) {
+	if details, ok := req.Context().Value(auditDetailsKey{}).(*auditDetails); ok {
+		details.policyOverrideJustification = justification
+	}
+}
+
+// auditComplete records the audit event of a handled request
+func (s *HTTPServer) auditComplete(received *audit.Event, details *auditDetails, resp *auditResponseWriter, err error) {
+	event := *received
+	request := *received.Request
+	request.PolicyOverrideJustification = details.policyOverrideJustification
+	event.Request = &request
+	event.ID = structs.GenerateUUID()
+	event.Timestamp = time.Now().UTC()
+	event.Stage = audit.StageComplete
+	event.Response = &audit.Response{StatusCode: resp.status}
+	if err != nil {
+		event.Response.Error = err.Error()
+	}
+
+	// The response is already sent so failures can only be logged
+	if err := s.agent.auditor.Event(&event); err != nil {
+		s.logger.Printf("[ERR] http: failed to record audit event of request %s: %v", event.Request.ID, err)
+	}
+}
+
+// auditAuth returns the identity of the secret ID, nil if it isn't the one
+// of an ACL token or ACLs are disabled
+func (s *HTTPServer) auditAuth(secretID string) *audit.Auth {
+	token, err := s.resolveACLToken(secretID)
+	if err != nil || token == nil {
+		return nil
+	}
+	return &audit.Auth{
+		AccessorID: token.AccessorID,
+		Name:       token.Name,
+		Type:       token.Type,
+		Policies:   token.Policies,
+		Global:     token.Global,
+	}
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper/audit"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestHTTP_Audit(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	cb := func(c *Config) {
+		c.Audit = &AuditConfig{
+			Enabled: true,
+			Sinks:   []*AuditSink{{Name: "file", Type: "file", Path: path}},
+			Filters: []*AuditFilter{{Name: "self", Endpoints: []string{"/v1/agent/*"}}},
+		}
+	}
+	httpACLTest(t, cb, func(s *TestAgent, root *structs.ACLToken) {
+		// Filtered requests aren't recorded
+		req, err := http.NewRequest("GET", "/v1/agent/self", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		setToken(req, root)
+		respW := httptest.NewRecorder()
+		s.Server.wrap(s.Server.AgentSelfRequest)(respW, req)
+		if respW.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", respW.Code, respW.Body.String())
+		}
+
+		// The other requests are recorded when they are received and once
+		// they are handled
+		req, err = http.NewRequest("GET", "/v1/jobs?namespace=default", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		s.Server.wrap(s.Server.JobsRequest)(respW, req)
+		if respW.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", respW.Code, respW.Body.String())
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 events, got: %s", data)
+		}
+		var received, complete audit.Event
+		if err := json.Unmarshal([]byte(lines[0]), &received); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := json.Unmarshal([]byte(lines[1]), &complete); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if received.Stage != audit.StageReceived || received.Request.Endpoint != "/v1/jobs" ||
+			received.Request.Namespace != "default" || received.Auth == nil || received.Auth.AccessorID != "anonymous" {
+			t.Fatalf("bad received event: %s", lines[0])
+		}
+		if complete.Stage != audit.StageComplete || complete.Request.ID != received.Request.ID ||
+			complete.Response == nil || complete.Response.StatusCode != 200 || complete.Response.Error != "" {
+			t.Fatalf("bad complete event: %s", lines[1])
+		}
+	})
+}
+
+func TestHTTP_Audit_PolicyOverride(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	cb := func(c *Config) {
+		c.Audit = &AuditConfig{
+			Enabled: true,
+			Sinks:   []*AuditSink{{Name: "file", Type: "file", Path: path}},
+		}
+	}
+	httpTest(t, cb, func(s *TestAgent) {
+		// The justification of a policy override is recorded once the
+		// registration is handled
+		args := api.JobRegisterRequest{
+			Job:                         api.MockJob(),
+			PolicyOverride:              true,
+			PolicyOverrideJustification: "hotfix for incident 42",
+			WriteRequest:                api.WriteRequest{Region: "global"},
+		}
+		req, err := http.NewRequest("PUT", "/v1/jobs", encodeReq(args))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		s.Server.wrap(s.Server.JobsRequest)(respW, req)
+		if respW.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", respW.Code, respW.Body.String())
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 events, got: %s", data)
+		}
+		var received, complete audit.Event
+		if err := json.Unmarshal([]byte(lines[0]), &received); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := json.Unmarshal([]byte(lines[1]), &complete); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if received.Request.PolicyOverrideJustification != "" {
+			t.Fatalf("bad received event: %s", lines[0])
+		}
+		if complete.Request.PolicyOverrideJustification != "hotfix for incident 42" {
+			t.Fatalf("bad complete event: %s", lines[1])
+		}
+	})
+}
+
+func TestHTTP_Audit_Enforced(t *testing.T) {
+	t.Parallel()
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer sink.Close()
+
+	cb := func(c *Config) {
+		c.Audit = &AuditConfig{
+			Enabled: true,
+			Sinks:   []*AuditSink{{Name: "siem", Type: "http", Address: sink.URL}},
+		}
+	}
+	httpTest(t, cb, func(s *TestAgent) {
+		// Requests whose events can't be recorded are rejected
+		req, err := http.NewRequest("GET", "/v1/jobs", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		s.Server.wrap(s.Server.JobsRequest)(respW, req)
+		if respW.Code != 500 || !strings.Contains(respW.Body.String(), "audit") {
+			t.Fatalf("expected 500, got %d: %s", respW.Code, respW.Body.String())
+		}
+	})
+}
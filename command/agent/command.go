@@ -47,6 +47,7 @@ type Command struct {
 	args           []string
 	agent          *Agent
 	httpServer     *HTTPServer
+	grpcServer     *GRPCServer
 	logFilter      *logutils.LevelFilter
 	logOutput      io.Writer
 	retryJoinErrCh chan struct{}
@@ -240,49 +241,16 @@ func (c *Command) readConfig() *Config {
 	}
 	config.Server.retryInterval = dur
 
-	// Check that the server is running in at least one mode.
-	if !(config.Server.Enabled || config.Client.Enabled) {
-		c.Ui.Error("Must specify either server, client or dev mode for the agent.")
-		return nil
-	}
-
-	// Verify the paths are absolute.
-	dirs := map[string]string{
-		"data-dir":  config.DataDir,
-		"alloc-dir": config.Client.AllocDir,
-		"state-dir": config.Client.StateDir,
-	}
-	for k, dir := range dirs {
-		if dir == "" {
-			continue
-		}
-
-		if !filepath.IsAbs(dir) {
-			c.Ui.Error(fmt.Sprintf("%s must be given as an absolute path: got %v", k, dir))
-			return nil
-		}
+	// Check the semantics of the configuration.
+	warnings, err := config.Validate()
+	for _, warning := range warnings {
+		c.Ui.Warn(fmt.Sprintf("WARNING: %s", warning))
 	}
-
-	// Ensure that we have the directories we neet to run.
-	if config.Server.Enabled && config.DataDir == "" {
-		c.Ui.Error("Must specify data directory")
+	if err != nil {
+		c.Ui.Error(err.Error())
 		return nil
 	}
 
-	// The config is valid if the top-level data-dir is set or if both
-	// alloc-dir and state-dir are set.
-	if config.Client.Enabled && config.DataDir == "" {
-		if config.Client.AllocDir == "" || config.Client.StateDir == "" {
-			c.Ui.Error("Must specify both the state and alloc dir if data-dir is omitted.")
-			return nil
-		}
-	}
-
-	// Check the bootstrap flags
-	if config.Server.BootstrapExpect > 0 && !config.Server.Enabled {
-		c.Ui.Error("Bootstrap requires server mode to be enabled")
-		return nil
-	}
 	if config.Server.BootstrapExpect == 1 {
 		c.Ui.Error("WARNING: Bootstrap mode enabled! Potentially unsafe operation.")
 	}
@@ -334,13 +302,17 @@ func (c *Command) setupLoggers(config *Config) (*gatedwriter.Writer, *logWriter,
 }
 
 // setupAgent is used to start the agent and various interfaces
-func (c *Command) setupAgent(config *Config, logOutput io.Writer) error {
+func (c *Command) setupAgent(config *Config, logOutput io.Writer, logWriter *logWriter,
+	inmem *metrics.InmemSink, prometheus *prometheusSink) error {
 	c.Ui.Output("Starting Nomad agent...")
 	agent, err := NewAgent(config, logOutput)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error starting agent: %s", err))
 		return err
 	}
+	agent.logWriter = logWriter
+	agent.inmemSink = inmem
+	agent.prometheusSink = prometheus
 	c.agent = agent
 
 	// Enable the SCADA integration
@@ -359,6 +331,18 @@ func (c *Command) setupAgent(config *Config, logOutput io.Writer) error {
 	}
 	c.httpServer = http
 
+	// Setup the gRPC server if it is enabled
+	if config.Ports.GRPC != 0 {
+		grpc, err := NewGRPCServer(agent, http, config)
+		if err != nil {
+			agent.Shutdown()
+			http.Shutdown()
+			c.Ui.Error(fmt.Sprintf("Error starting grpc server: %s", err))
+			return err
+		}
+		c.grpcServer = grpc
+	}
+
 	// Setup update checking
 	if !config.DisableUpdateCheck {
 		version := config.Version
@@ -439,7 +423,7 @@ func (c *Command) Run(args []string) int {
 	}
 
 	// Setup the log outputs
-	logGate, _, logOutput := c.setupLoggers(config)
+	logGate, logWriter, logOutput := c.setupLoggers(config)
 	if logGate == nil {
 		return 1
 	}
@@ -452,13 +436,14 @@ func (c *Command) Run(args []string) int {
 	}
 
 	// Initialize the telemetry
-	if err := c.setupTelemetry(config); err != nil {
+	inmem, prometheus, err := c.setupTelemetry(config)
+	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error initializing telemetry: %s", err))
 		return 1
 	}
 
 	// Create the agent
-	if err := c.setupAgent(config, logOutput); err != nil {
+	if err := c.setupAgent(config, logOutput, logWriter, inmem, prometheus); err != nil {
 		logGate.Flush()
 		return 1
 	}
@@ -469,6 +454,9 @@ func (c *Command) Run(args []string) int {
 		if c.httpServer != nil {
 			c.httpServer.Shutdown()
 		}
+		if c.grpcServer != nil {
+			c.grpcServer.Shutdown()
+		}
 		if c.scadaHttp != nil {
 			c.scadaHttp.Shutdown()
 		}
@@ -626,8 +614,10 @@ func (c *Command) handleReload(config *Config) *Config {
 	return newConf
 }
 
-// setupTelemetry is used ot setup the telemetry sub-systems
-func (c *Command) setupTelemetry(config *Config) error {
+// setupTelemetry is used ot setup the telemetry sub-systems. It returns the
+// in-memory sink holding the recent metrics and, if enabled, the sink exposing
+// the metrics to Prometheus.
+func (c *Command) setupTelemetry(config *Config) (*metrics.InmemSink, *prometheusSink, error) {
 	/* Setup telemetry
 	Aggregate on 10 second intervals for 1 minute. Expose the
 	metrics over stderr when there is a SIGUSR1 received.
@@ -649,21 +639,41 @@ func (c *Command) setupTelemetry(config *Config) error {
 		metricsConf.EnableHostname = true
 	}
 
-	// Configure the statsite sink
+	// Configure the Prometheus sink
 	var fanout metrics.FanoutSink
+	var prometheus *prometheusSink
+	if telConfig.PrometheusMetrics {
+		hostname := ""
+		if metricsConf.EnableHostname {
+			hostname = metricsConf.HostName
+		}
+		prometheus = newPrometheusSink(hostname)
+		fanout = append(fanout, prometheus)
+	}
+
+	// Configure the statsite sink
 	if telConfig.StatsiteAddr != "" {
 		sink, err := metrics.NewStatsiteSink(telConfig.StatsiteAddr)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		fanout = append(fanout, sink)
 	}
 
-	// Configure the statsd sink
-	if telConfig.StatsdAddr != "" {
+	// Configure the statsd sink, sending tagged metrics in the DogStatsD
+	// format if enabled
+	tags := telConfig.metricTags(config.Datacenter)
+	if telConfig.StatsdAddr != "" && telConfig.StatsdTaggedMetrics {
+		sink, err := datadog.NewDogStatsdSink(telConfig.StatsdAddr, config.NodeName)
+		if err != nil {
+			return nil, nil, err
+		}
+		sink.SetTags(tags)
+		fanout = append(fanout, sink)
+	} else if telConfig.StatsdAddr != "" {
 		sink, err := metrics.NewStatsdSink(telConfig.StatsdAddr)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		fanout = append(fanout, sink)
 	}
@@ -672,8 +682,9 @@ func (c *Command) setupTelemetry(config *Config) error {
 	if telConfig.DataDogAddr != "" {
 		sink, err := datadog.NewDogStatsdSink(telConfig.DataDogAddr, config.NodeName)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
+		sink.SetTags(tags)
 		fanout = append(fanout, sink)
 	}
 
@@ -708,21 +719,26 @@ func (c *Command) setupTelemetry(config *Config) error {
 
 		sink, err := circonus.NewCirconusSink(cfg)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		sink.Start()
 		fanout = append(fanout, sink)
 	}
 
-	// Initialize the global sink
+	// Initialize the global sink, filtering the metrics as configured
+	var sink metrics.MetricSink = inm
 	if len(fanout) > 0 {
-		fanout = append(fanout, inm)
-		metrics.NewGlobal(metricsConf, fanout)
+		sink = append(fanout, inm)
 	} else {
 		metricsConf.EnableHostname = false
-		metrics.NewGlobal(metricsConf, inm)
 	}
-	return nil
+	hostname := ""
+	if metricsConf.EnableHostname {
+		hostname = metricsConf.HostName
+	}
+	logger := log.New(c.logOutput, "", log.LstdFlags|log.Lmicroseconds)
+	metrics.NewGlobal(metricsConf, newFilterSink(sink, telConfig, hostname, logger))
+	return inm, prometheus, nil
 }
 
 // setupSCADA is used to start a new SCADA provider and listener,
@@ -9,11 +9,13 @@ ports {
 	http = 1234
 	rpc = 2345
 	serf = 3456
+	grpc = 4567
 }
 addresses {
 	http = "127.0.0.1"
 	rpc = "127.0.0.2"
 	serf = "127.0.0.3"
+	grpc = "127.0.0.4"
 }
 advertise {
 	rpc = "127.0.0.3"
@@ -23,6 +25,11 @@ client {
 	enabled = true
 	state_dir = "/tmp/client-state"
 	alloc_dir = "/tmp/alloc"
+	cni_path = "/opt/cni/bin:/usr/libexec/cni"
+	cni_config_dir = "/etc/cni/net.d"
+	bridge_network_name = "nomad0"
+	bridge_network_subnet = "10.10.0.0/16"
+	bridge_network_subnet_ipv6 = "fd00:10::/64"
 	servers = ["a.b.c:80", "127.0.0.1:1234"]
 	node_class = "linux-medium-64bit"
 	meta {
@@ -39,6 +46,17 @@ client {
 	}
 	network_interface = "eth0"
 	network_speed = 100
+	host_network "public" {
+		interface = "eth1"
+		cidr = "203.0.113.0/24"
+	}
+	dns {
+		enabled = true
+		addr = "127.0.0.1:53"
+		domain = "nomad.example."
+		recursors = ["8.8.8.8:53"]
+		ttl = "10s"
+	}
 	cpu_total_compute = 4444
 	reserved {
 		cpu = 10
@@ -49,6 +67,8 @@ client {
 	}
 	client_min_port = 1000
 	client_max_port = 2000
+	min_dynamic_port = 25000
+	max_dynamic_port = 26000
     max_kill_timeout = "10s"
     stats {
         data_points = 35
@@ -75,12 +95,40 @@ server {
 	heartbeat_grace   = "30s"
 	min_heartbeat_ttl = "33s"
 	max_heartbeats_per_second = 11.0
+	max_dispatch_payload_size = "1MB"
 	retry_join = [ "1.1.1.1", "2.2.2.2" ]
 	start_join = [ "1.1.1.1", "2.2.2.2" ]
 	retry_max = 3
 	retry_interval = "15s"
 	rejoin_after_leave = true
     encrypt = "abc"
+	variables_encryption_key = "def"
+	admission_policy "no-raw-exec" {
+		type = "opa"
+		address = "http://127.0.0.1:8181/v1/data/nomad/admission"
+		timeout = "2s"
+		fail_open = true
+		enforcement_level = "soft-mandatory"
+	}
+	deployment_webhook "chat" {
+		address = "https://chat.example.com/hooks/deployments"
+		method = "PUT"
+		events = ["failed", "reverted"]
+		payload = "{\"text\": \"{{ .JobID }} {{ .Event }}\"}"
+		timeout = "5s"
+		headers {
+			Authorization = "Bearer secret"
+		}
+	}
+	job_notification {
+		allowed_webhooks = ["https://ci.example.com/hooks/"]
+	}
+	image_verification {
+		enabled = true
+		exempt_namespaces = ["dev"]
+	}
+    authoritative_region = "foobar"
+	workload_identity_signing_key_file = "/etc/nomad/workload-identity.pem"
 }
 telemetry {
 	statsite_address = "127.0.0.1:1234"
@@ -89,6 +137,15 @@ telemetry {
     collection_interval = "3s"
     publish_allocation_metrics = true
     publish_node_metrics = true
+    allocation_metric_labels = ["job", "group", "meta.team"]
+    tags = ["env:prod"]
+    statsd_tagged_metrics = true
+    prometheus_metrics = true
+    otlp_traces_endpoint = "http://127.0.0.1:4318"
+    trace_sample_rate = 0.5
+    prefix_filter = ["+nomad.raft", "-nomad.client.allocs"]
+    filter_default = false
+    max_metric_cardinality = 5000
 }
 leave_on_interrupt = true
 leave_on_terminate = true
@@ -109,6 +166,7 @@ consul {
     server_service_name = "nomad"
     client_service_name = "nomad-client"
     address = "127.0.0.1:9500"
+    grpc_address = "127.0.0.1:9502"
     token = "token1"
     auth = "username:pass"
     ssl = true
@@ -134,6 +192,13 @@ vault {
     tls_server_name = "foobar"
     tls_skip_verify = true
     create_from_role = "test_role"
+    identity_signing_key_file = "/path/to/signing/key"
+    identity_ttl = "5m"
+    identity_audience = "vault.example.com"
+    jwt_auth_backend_path = "nomad-jwt"
+    allowed_policies = ["web", "team-a-*"]
+    allowed_roles = ["sidecar"]
+    allowed_namespaces = ["team-a"]
 }
 tls {
     http = true
@@ -144,3 +209,45 @@ tls {
     key_file = "pipe"
     verify_https_client = true
 }
+limits {
+    http_rate = 100
+    http_burst = 200
+    http_rate_per_ip = 10
+    http_burst_per_ip = 20
+    rpc_rate = 500
+    rpc_burst = 1000
+    rpc_rate_per_ip = 50
+    rpc_burst_per_ip = 100
+    http_rate_per_token = 5
+    http_burst_per_token = 10
+    rpc_rate_per_token = 25
+    rpc_burst_per_token = 50
+}
+acl {
+    enabled = true
+    token_ttl = "60s"
+    replication_token = "foobar"
+    token_min_expiration_ttl = "10m"
+    token_max_expiration_ttl = "48h"
+}
+audit {
+    enabled = true
+    sink "file" {
+        type = "file"
+        path = "/var/log/nomad/audit.log"
+    }
+    sink "siem" {
+        type = "http"
+        delivery_guarantee = "best-effort"
+        address = "https://siem.example.com/events"
+        timeout = "3s"
+        headers {
+            Authorization = "Bearer foo"
+        }
+    }
+    filter "reads" {
+        endpoints = ["/v1/client/fs/*"]
+        operations = ["GET"]
+        identities = ["anonymous"]
+    }
+}
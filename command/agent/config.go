@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -14,10 +15,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-sockaddr/template"
+	"github.com/hashicorp/logutils"
 
 	client "github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/client/driver"
 	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/helper/audit"
+	"github.com/hashicorp/nomad/helper/ratelimit"
+	"github.com/hashicorp/nomad/helper/tracing"
 	"github.com/hashicorp/nomad/nomad"
 	"github.com/hashicorp/nomad/nomad/structs/config"
 )
@@ -128,6 +135,168 @@ type Config struct {
 	// HTTPAPIResponseHeaders allows users to configure the Nomad http agent to
 	// set arbritrary headers on API responses
 	HTTPAPIResponseHeaders map[string]string `mapstructure:"http_api_response_headers"`
+
+	// Limits configures the rate limits of the HTTP API and of the server's
+	// RPC endpoints.
+	Limits *Limits `mapstructure:"limits"`
+
+	// ACL configures the ACL system of the agent
+	ACL *ACLConfig `mapstructure:"acl"`
+
+	// Audit configures the audit log of the requests made to the HTTP API
+	Audit *AuditConfig `mapstructure:"audit"`
+}
+
+// AuditConfig configures the audit log of the requests made to the HTTP API.
+// Every request is recorded when it is received and once it is handled,
+// unless a filter drops it, and written to all the sinks.
+type AuditConfig struct {
+	// Enabled records the requests
+	Enabled bool `mapstructure:"enabled"`
+
+	// Sinks are the destinations the events are written to
+	Sinks []*AuditSink `mapstructure:"-"`
+
+	// Filters drop the events they match
+	Filters []*AuditFilter `mapstructure:"-"`
+}
+
+// AuditSink configures a destination of the audit events
+type AuditSink struct {
+	// Name is the name of the sink, shown in the logs
+	Name string `mapstructure:"-"`
+
+	// Type is the type of the sink: file, syslog or http
+	Type string `mapstructure:"type"`
+
+	// DeliveryGuarantee is enforced, to reject the requests whose events
+	// can't be written, or best-effort, to drop them. It defaults to
+	// enforced.
+	DeliveryGuarantee string `mapstructure:"delivery_guarantee"`
+
+	// Path is the file the events are appended to, for file sinks
+	Path string `mapstructure:"path"`
+
+	// Facility and Tag are the syslog facility and tag of the events, for
+	// syslog sinks
+	Facility string `mapstructure:"facility"`
+	Tag      string `mapstructure:"tag"`
+
+	// Address is the URL the events are posted to, with the headers, for
+	// http sinks
+	Address string            `mapstructure:"address"`
+	Headers map[string]string `mapstructure:"-" json:"-"`
+
+	// Timeout is the time an http sink has to accept an event
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// AuditFilter drops the audit events matching all of its non-empty criteria
+type AuditFilter struct {
+	// Name is the name of the filter
+	Name string `mapstructure:"-"`
+
+	// Endpoints are the paths of the requests, which may be glob patterns
+	Endpoints []string `mapstructure:"endpoints"`
+
+	// Operations are the HTTP methods of the requests
+	Operations []string `mapstructure:"operations"`
+
+	// Stages are the stages of the events, OperationReceived or
+	// OperationComplete
+	Stages []string `mapstructure:"stages"`
+
+	// Identities are the accessor IDs or names of the tokens of the requests
+	Identities []string `mapstructure:"identities"`
+}
+
+// ACLConfig configures the ACL system
+type ACLConfig struct {
+	// Enabled enforces the ACLs on the endpoints of the agent
+	Enabled bool `mapstructure:"enabled"`
+
+	// TokenTTL is how long clients cache the tokens they resolved through
+	// the servers
+	TokenTTL time.Duration `mapstructure:"token_ttl"`
+
+	// ReplicationToken is the management token the servers of other regions
+	// than the authoritative one replicate the ACLs with
+	ReplicationToken string `mapstructure:"replication_token" json:"-"`
+
+	// TokenMinExpirationTTL and TokenMaxExpirationTTL bound the time to live
+	// of the tokens created with an expiration
+	TokenMinExpirationTTL time.Duration `mapstructure:"token_min_expiration_ttl"`
+	TokenMaxExpirationTTL time.Duration `mapstructure:"token_max_expiration_ttl"`
+}
+
+// Limits configures request rate limits. Rates are in requests per second and
+// a zero rate disables the limit. Bursts default to the rate.
+type Limits struct {
+	// HTTPRate and HTTPBurst limit the rate of HTTP API requests to the agent.
+	HTTPRate  float64 `mapstructure:"http_rate"`
+	HTTPBurst int     `mapstructure:"http_burst"`
+
+	// HTTPRatePerIP and HTTPBurstPerIP limit the rate of HTTP API requests
+	// from a single IP address.
+	HTTPRatePerIP  float64 `mapstructure:"http_rate_per_ip"`
+	HTTPBurstPerIP int     `mapstructure:"http_burst_per_ip"`
+
+	// RPCRate and RPCBurst limit the rate of RPC requests to the server.
+	RPCRate  float64 `mapstructure:"rpc_rate"`
+	RPCBurst int     `mapstructure:"rpc_burst"`
+
+	// RPCRatePerIP and RPCBurstPerIP limit the rate of RPC requests from a
+	// single IP address. Requests from other servers are not limited.
+	RPCRatePerIP  float64 `mapstructure:"rpc_rate_per_ip"`
+	RPCBurstPerIP int     `mapstructure:"rpc_burst_per_ip"`
+
+	// HTTPRatePerToken and HTTPBurstPerToken limit the rate of HTTP API
+	// requests made with a single ACL token.
+	HTTPRatePerToken  float64 `mapstructure:"http_rate_per_token"`
+	HTTPBurstPerToken int     `mapstructure:"http_burst_per_token"`
+
+	// RPCRatePerToken and RPCBurstPerToken limit the rate of RPC requests
+	// made with a single ACL token.
+	RPCRatePerToken  float64 `mapstructure:"rpc_rate_per_token"`
+	RPCBurstPerToken int     `mapstructure:"rpc_burst_per_token"`
+}
+
+// HTTPConfig returns the rate limit configuration of the HTTP API.
+func (l *Limits) HTTPConfig() ratelimit.Config {
+	return ratelimit.Config{
+		Rate:        l.HTTPRate,
+		Burst:       l.HTTPBurst,
+		RatePerKey:  l.HTTPRatePerIP,
+		BurstPerKey: l.HTTPBurstPerIP,
+	}
+}
+
+// RPCConfig returns the rate limit configuration of the RPC endpoints.
+func (l *Limits) RPCConfig() ratelimit.Config {
+	return ratelimit.Config{
+		Rate:        l.RPCRate,
+		Burst:       l.RPCBurst,
+		RatePerKey:  l.RPCRatePerIP,
+		BurstPerKey: l.RPCBurstPerIP,
+	}
+}
+
+// HTTPTokenConfig returns the rate limit configuration of the HTTP API per
+// ACL token.
+func (l *Limits) HTTPTokenConfig() ratelimit.Config {
+	return ratelimit.Config{
+		RatePerKey:  l.HTTPRatePerToken,
+		BurstPerKey: l.HTTPBurstPerToken,
+	}
+}
+
+// RPCTokenConfig returns the rate limit configuration of the RPC endpoints
+// per ACL token.
+func (l *Limits) RPCTokenConfig() ratelimit.Config {
+	return ratelimit.Config{
+		RatePerKey:  l.RPCRatePerToken,
+		BurstPerKey: l.RPCBurstPerToken,
+	}
 }
 
 // AtlasConfig is used to enable an parameterize the Atlas integration
@@ -159,6 +328,26 @@ type ClientConfig struct {
 	// AllocDir is the directory for storing allocation data
 	AllocDir string `mapstructure:"alloc_dir"`
 
+	// CNIPath is the list of directories, separated by colons, the CNI
+	// plugins are searched in
+	CNIPath string `mapstructure:"cni_path"`
+
+	// CNIConfigDir is the directory of the CNI network configurations group
+	// networks can use
+	CNIConfigDir string `mapstructure:"cni_config_dir"`
+
+	// BridgeNetworkName is the name of the bridge the allocations in bridge
+	// network mode are joined to
+	BridgeNetworkName string `mapstructure:"bridge_network_name"`
+
+	// BridgeNetworkSubnet is the subnet the addresses of the allocations in
+	// bridge network mode are assigned from
+	BridgeNetworkSubnet string `mapstructure:"bridge_network_subnet"`
+
+	// BridgeNetworkSubnetIPv6 is the subnet the IPv6 addresses of the
+	// allocations in bridge network mode are assigned from, if any
+	BridgeNetworkSubnetIPv6 string `mapstructure:"bridge_network_subnet_ipv6"`
+
 	// Servers is a list of known server addresses. These are as "host:port"
 	Servers []string `mapstructure:"servers"`
 
@@ -185,6 +374,13 @@ type ClientConfig struct {
 	// speed.
 	NetworkSpeed int `mapstructure:"network_speed"`
 
+	// HostNetworks are the named networks of the host jobs can reserve ports
+	// in.
+	HostNetworks []*client.HostNetworkConfig `mapstructure:"-"`
+
+	// DNS configures the DNS interface serving the Nomad services.
+	DNS *client.DNSConfig `mapstructure:"-"`
+
 	// CpuCompute is used to override any detected or default total CPU compute.
 	CpuCompute int `mapstructure:"cpu_total_compute"`
 
@@ -199,6 +395,14 @@ type ClientConfig struct {
 	// communicating with plugin subsystems
 	ClientMinPort int `mapstructure:"client_min_port"`
 
+	// MinDynamicPort is the lower bound of the dynamic ports the scheduler
+	// assigns on the node
+	MinDynamicPort int `mapstructure:"min_dynamic_port"`
+
+	// MaxDynamicPort is the upper bound of the dynamic ports the scheduler
+	// assigns on the node
+	MaxDynamicPort int `mapstructure:"max_dynamic_port"`
+
 	// Reserved is used to reserve resources from being used by Nomad. This can
 	// be used to target a certain utilization or to prevent Nomad from using a
 	// particular set of ports.
@@ -288,6 +492,10 @@ type ServerConfig struct {
 	// to meet the target rate.
 	MaxHeartbeatsPerSecond float64 `mapstructure:"max_heartbeats_per_second"`
 
+	// MaxDispatchPayloadSize is the maximum size of the uncompressed payload
+	// of a dispatched job, for example "1MB".
+	MaxDispatchPayloadSize string `mapstructure:"max_dispatch_payload_size"`
+
 	// StartJoin is a list of addresses to attempt to join when the
 	// agent starts. If Serf is unable to communicate with any of these
 	// addresses, then the agent will error and exit.
@@ -315,6 +523,38 @@ type ServerConfig struct {
 
 	// Encryption key to use for the Serf communication
 	EncryptKey string `mapstructure:"encrypt" json:"-"`
+
+	// VariablesEncryptionKey is the base64 encoded 32 bytes key the items of
+	// the variables are encrypted with. It must be the same on all the
+	// servers of the region. Variables are disabled if it is not set.
+	VariablesEncryptionKey string `mapstructure:"variables_encryption_key" json:"-"`
+
+	// AdmissionPolicies are the external policy engines submitted jobs are
+	// evaluated against
+	AdmissionPolicies []*config.AdmissionPolicyConfig `mapstructure:"-"`
+
+	// DeploymentWebhooks are the webhooks called when deployments
+	// transition
+	DeploymentWebhooks []*config.DeploymentWebhookConfig `mapstructure:"-"`
+
+	// JobNotification restricts the webhooks the notifications of jobs
+	// may call
+	JobNotification *config.JobNotificationConfig `mapstructure:"-"`
+
+	// ImageVerification requires the images of the jobs to be verified by
+	// the clients running them
+	ImageVerification *config.ImageVerificationConfig `mapstructure:"-"`
+
+	// AuthoritativeRegion is the region the ACL policies and global ACL
+	// tokens are managed in and replicated from. It defaults to the region
+	// of the server.
+	AuthoritativeRegion string `mapstructure:"authoritative_region"`
+
+	// WorkloadIdentitySigningKeyFile is the path to the PEM-encoded RSA
+	// private key the workload identities of the allocations are signed
+	// with. It must be the same on all the servers of the region. Workload
+	// identities are disabled if it is not set.
+	WorkloadIdentitySigningKeyFile string `mapstructure:"workload_identity_signing_key_file"`
 }
 
 // EncryptBytes returns the encryption key configured.
@@ -322,6 +562,19 @@ func (s *ServerConfig) EncryptBytes() ([]byte, error) {
 	return base64.StdEncoding.DecodeString(s.EncryptKey)
 }
 
+// VariablesEncryptionKeyBytes returns the variables encryption key
+// configured.
+func (s *ServerConfig) VariablesEncryptionKeyBytes() ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s.VariablesEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != nomad.VariablesKeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", nomad.VariablesKeySize, len(key))
+	}
+	return key, nil
+}
+
 // Telemetry is the telemetry configuration for the server
 type Telemetry struct {
 	StatsiteAddr             string        `mapstructure:"statsite_address"`
@@ -334,6 +587,46 @@ type Telemetry struct {
 	PublishAllocationMetrics bool          `mapstructure:"publish_allocation_metrics"`
 	PublishNodeMetrics       bool          `mapstructure:"publish_node_metrics"`
 
+	// AllocationMetricLabels are the labels of the allocations included in
+	// the keys of allocation metrics, in order. The client's default labels
+	// are used if it is nil.
+	AllocationMetricLabels []string `mapstructure:"allocation_metric_labels"`
+
+	// Tags are key:value pairs added to the metrics of the sinks supporting
+	// tags, DogStatsD and Prometheus, along with the datacenter.
+	Tags []string `mapstructure:"tags"`
+
+	// StatsdTaggedMetrics sends the metrics to the statsd address in the
+	// DogStatsD format, with tags.
+	StatsdTaggedMetrics bool `mapstructure:"statsd_tagged_metrics"`
+
+	// PrometheusMetrics enables exposing the metrics of the agent in the
+	// Prometheus text format at /v1/metrics?format=prometheus.
+	PrometheusMetrics bool `mapstructure:"prometheus_metrics"`
+
+	// OTLPTracesEndpoint is the base URL of the OTLP/HTTP receiver trace
+	// spans are exported to. Tracing is disabled if it isn't set.
+	OTLPTracesEndpoint string `mapstructure:"otlp_traces_endpoint"`
+
+	// TraceSampleRate is the fraction of the traces started by the agent
+	// that are recorded. Defaults to 1.
+	TraceSampleRate *float64 `mapstructure:"trace_sample_rate"`
+
+	// PrefixFilter are the rules filtering the metrics of the agent by the
+	// prefix of their name, without the hostname. Rules starting with "+"
+	// allow the metrics under the prefix and rules starting with "-" block
+	// them. The rule with the longest matching prefix applies.
+	PrefixFilter []string `mapstructure:"prefix_filter"`
+
+	// FilterDefault is whether the metrics no prefix filter matches are
+	// allowed. Defaults to true.
+	FilterDefault *bool `mapstructure:"filter_default"`
+
+	// MaxMetricCardinality is the maximum number of series the agent emits.
+	// The series emitted beyond the limit are dropped until older series
+	// expire. Zero disables the limit.
+	MaxMetricCardinality int `mapstructure:"max_metric_cardinality"`
+
 	// Circonus: see https://github.com/circonus-labs/circonus-gometrics
 	// for more details on the various configuration options.
 	// Valid configuration combinations:
@@ -412,6 +705,10 @@ type Ports struct {
 	HTTP int `mapstructure:"http"`
 	RPC  int `mapstructure:"rpc"`
 	Serf int `mapstructure:"serf"`
+
+	// GRPC is the port of the gRPC API. The gRPC API is disabled if it is
+	// not set.
+	GRPC int `mapstructure:"grpc"`
 }
 
 // Addresses encapsulates all of the addresses we bind to for various
@@ -420,6 +717,7 @@ type Addresses struct {
 	HTTP string `mapstructure:"http"`
 	RPC  string `mapstructure:"rpc"`
 	Serf string `mapstructure:"serf"`
+	GRPC string `mapstructure:"grpc"`
 }
 
 // AdvertiseAddrs is used to control the addresses we advertise out for
@@ -544,6 +842,7 @@ func DefaultConfig() *Config {
 		Addresses:      &Addresses{},
 		AdvertiseAddrs: &AdvertiseAddrs{},
 		Atlas:          &AtlasConfig{},
+		Limits:         &Limits{},
 		Consul:         config.DefaultConsulConfig(),
 		Vault:          config.DefaultVaultConfig(),
 		Client: &ClientConfig{
@@ -558,6 +857,7 @@ func DefaultConfig() *Config {
 			GCInodeUsageThreshold: 70,
 			GCMaxAllocs:           50,
 			NoHostUUID:            helper.BoolToPtr(true),
+			DNS:                   client.DefaultDNSConfig(),
 		},
 		Server: &ServerConfig{
 			Enabled:          false,
@@ -572,6 +872,11 @@ func DefaultConfig() *Config {
 			collectionInterval: 1 * time.Second,
 		},
 		TLSConfig: &config.TLSConfig{},
+		ACL: &ACLConfig{
+			TokenTTL:              30 * time.Second,
+			TokenMinExpirationTTL: 1 * time.Minute,
+			TokenMaxExpirationTTL: 24 * time.Hour,
+		},
 	}
 }
 
@@ -708,6 +1013,30 @@ func (c *Config) Merge(b *Config) *Config {
 		result.Atlas = result.Atlas.Merge(b.Atlas)
 	}
 
+	// Apply the limits configuration
+	if result.Limits == nil && b.Limits != nil {
+		limits := *b.Limits
+		result.Limits = &limits
+	} else if b.Limits != nil {
+		result.Limits = result.Limits.Merge(b.Limits)
+	}
+
+	// Apply the ACL configuration
+	if result.ACL == nil && b.ACL != nil {
+		aclConfig := *b.ACL
+		result.ACL = &aclConfig
+	} else if b.ACL != nil {
+		result.ACL = result.ACL.Merge(b.ACL)
+	}
+
+	// Apply the audit configuration
+	if result.Audit == nil && b.Audit != nil {
+		auditConfig := *b.Audit
+		result.Audit = &auditConfig
+	} else if b.Audit != nil {
+		result.Audit = result.Audit.Merge(b.Audit)
+	}
+
 	// Apply the Consul Configuration
 	if result.Consul == nil && b.Consul != nil {
 		result.Consul = b.Consul.Copy()
@@ -745,7 +1074,7 @@ func (c *Config) normalizeAddrs() error {
 		if err != nil {
 			return fmt.Errorf("Bind address resolution failed: %v", err)
 		}
-		c.BindAddr = ipStr
+		c.BindAddr = trimIPv6Brackets(ipStr)
 	}
 
 	addr, err := normalizeBind(c.Addresses.HTTP, c.BindAddr)
@@ -766,10 +1095,17 @@ func (c *Config) normalizeAddrs() error {
 	}
 	c.Addresses.Serf = addr
 
+	addr, err = normalizeBind(c.Addresses.GRPC, c.BindAddr)
+	if err != nil {
+		return fmt.Errorf("Failed to parse gRPC address: %v", err)
+	}
+	c.Addresses.GRPC = addr
+
 	c.normalizedAddrs = &Addresses{
 		HTTP: net.JoinHostPort(c.Addresses.HTTP, strconv.Itoa(c.Ports.HTTP)),
 		RPC:  net.JoinHostPort(c.Addresses.RPC, strconv.Itoa(c.Ports.RPC)),
 		Serf: net.JoinHostPort(c.Addresses.Serf, strconv.Itoa(c.Ports.Serf)),
+		GRPC: net.JoinHostPort(c.Addresses.GRPC, strconv.Itoa(c.Ports.GRPC)),
 	}
 
 	addr, err = normalizeAdvertise(c.AdvertiseAddrs.HTTP, c.Addresses.HTTP, c.Ports.HTTP, c.DevMode)
@@ -796,6 +1132,240 @@ func (c *Config) normalizeAddrs() error {
 	return nil
 }
 
+// Validate checks the semantics of a complete agent configuration, merged over
+// the defaults. It returns the warnings about settings that are likely
+// mistakes and an error describing every invalid setting.
+func (c *Config) Validate() (warnings []string, err error) {
+	var mErr multierror.Error
+
+	// Check that the agent is running in at least one mode.
+	if !(c.Server.Enabled || c.Client.Enabled) {
+		multierror.Append(&mErr, fmt.Errorf("Must specify either server, client or dev mode for the agent."))
+	}
+
+	// Verify the paths are absolute.
+	for _, dir := range []struct{ name, path string }{
+		{"data-dir", c.DataDir},
+		{"alloc-dir", c.Client.AllocDir},
+		{"state-dir", c.Client.StateDir},
+	} {
+		if dir.path != "" && !filepath.IsAbs(dir.path) {
+			multierror.Append(&mErr, fmt.Errorf("%s must be given as an absolute path: got %v", dir.name, dir.path))
+		}
+	}
+
+	// Ensure that we have the directories we need to run.
+	if c.Server.Enabled && c.DataDir == "" {
+		multierror.Append(&mErr, fmt.Errorf("Must specify data directory"))
+	}
+
+	// The config is valid if the top-level data-dir is set or if both
+	// alloc-dir and state-dir are set.
+	if c.Client.Enabled && c.DataDir == "" {
+		if c.Client.AllocDir == "" || c.Client.StateDir == "" {
+			multierror.Append(&mErr, fmt.Errorf("Must specify both the state and alloc dir if data-dir is omitted."))
+		}
+	}
+
+	if c.Server.BootstrapExpect > 0 && !c.Server.Enabled {
+		multierror.Append(&mErr, fmt.Errorf("Bootstrap requires server mode to be enabled"))
+	}
+	if c.Server.EncryptKey != "" {
+		if _, err := c.Server.EncryptBytes(); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("Invalid encryption key: %s", err))
+		}
+	}
+	if c.Server.VariablesEncryptionKey != "" {
+		if _, err := c.Server.VariablesEncryptionKeyBytes(); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("Invalid variables encryption key: %s", err))
+		}
+	}
+	if c.Server.RetryInterval != "" {
+		if _, err := time.ParseDuration(c.Server.RetryInterval); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("Error parsing retry interval: %s", err))
+		}
+	}
+
+	if level := logutils.LogLevel(strings.ToUpper(c.LogLevel)); !ValidateLevelFilter(level, LevelFilter()) {
+		multierror.Append(&mErr, fmt.Errorf("Invalid log level: %s", c.LogLevel))
+	}
+
+	// Normalize the addresses on a copy since it modifies the config.
+	addrs := &Config{
+		BindAddr:       c.BindAddr,
+		DevMode:        c.DevMode,
+		Server:         c.Server,
+		Ports:          c.Ports,
+		Addresses:      c.Addresses.Merge(&Addresses{}),
+		AdvertiseAddrs: c.AdvertiseAddrs.Merge(&AdvertiseAddrs{}),
+	}
+	if err := addrs.normalizeAddrs(); err != nil {
+		multierror.Append(&mErr, err)
+	}
+
+	if err := c.validateTLS(); err != nil {
+		multierror.Append(&mErr, multierror.Prefix(err, "tls ->"))
+	}
+	if err := c.Telemetry.validate(); err != nil {
+		multierror.Append(&mErr, multierror.Prefix(err, "telemetry ->"))
+	}
+
+	// Warn about unknown drivers as they are silently ignored by the client.
+	for _, key := range []string{"driver.whitelist", "driver.blacklist"} {
+		for _, name := range strings.Split(c.Client.Options[key], ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := driver.BuiltinDrivers[name]; name != "" && !ok {
+				warnings = append(warnings, fmt.Sprintf("client -> options: %s contains unknown driver %q", key, name))
+			}
+		}
+	}
+
+	return warnings, mErr.ErrorOrNil()
+}
+
+// validateTLS checks that the certificates used to enable TLS are set and
+// readable.
+func (c *Config) validateTLS() error {
+	tls := c.TLSConfig
+	if tls == nil {
+		return nil
+	}
+
+	var mErr multierror.Error
+	if tls.EnableHTTP || tls.EnableRPC {
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			multierror.Append(&mErr, fmt.Errorf("cert_file and key_file must be set when TLS is enabled"))
+		}
+		if tls.VerifyHTTPSClient && tls.CAFile == "" {
+			multierror.Append(&mErr, fmt.Errorf("ca_file must be set to verify HTTPS clients"))
+		}
+	}
+
+	for _, file := range []struct{ name, path string }{
+		{"ca_file", tls.CAFile},
+		{"cert_file", tls.CertFile},
+		{"key_file", tls.KeyFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("%s: %v", file.name, err))
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// validate checks that the addresses and intervals of the metric sinks can be
+// parsed.
+func (t *Telemetry) validate() error {
+	if t == nil {
+		return nil
+	}
+
+	var mErr multierror.Error
+	for _, sink := range []struct{ name, addr string }{
+		{"statsite_address", t.StatsiteAddr},
+		{"statsd_address", t.StatsdAddr},
+		{"datadog_address", t.DataDogAddr},
+	} {
+		if sink.addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(sink.addr); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("%s: %v", sink.name, err))
+		}
+	}
+
+	if t.CollectionInterval != "" {
+		if _, err := time.ParseDuration(t.CollectionInterval); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("collection_interval: %v", err))
+		}
+	}
+	if t.CirconusSubmissionInterval != "" {
+		if _, err := time.ParseDuration(t.CirconusSubmissionInterval); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("circonus_submission_interval: %v", err))
+		}
+	}
+
+	for _, tag := range t.Tags {
+		if _, _, ok := splitMetricTag(tag); !ok {
+			multierror.Append(&mErr, fmt.Errorf("tags: invalid tag %q, expected key:value", tag))
+		}
+	}
+
+	if err := client.ValidateAllocationMetricLabels(t.AllocationMetricLabels); err != nil {
+		multierror.Append(&mErr, fmt.Errorf("allocation_metric_labels: %v", err))
+	}
+
+	if t.OTLPTracesEndpoint != "" {
+		if u, err := url.Parse(t.OTLPTracesEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			multierror.Append(&mErr, fmt.Errorf("otlp_traces_endpoint: invalid URL %q", t.OTLPTracesEndpoint))
+		}
+	}
+	if r := t.TraceSampleRate; r != nil && (*r < 0 || *r > 1) {
+		multierror.Append(&mErr, fmt.Errorf("trace_sample_rate: must be between 0 and 1"))
+	}
+
+	for _, rule := range t.PrefixFilter {
+		if _, _, ok := parsePrefixFilterRule(rule); !ok {
+			multierror.Append(&mErr, fmt.Errorf("prefix_filter: invalid rule %q, expected +prefix or -prefix", rule))
+		}
+	}
+	if t.MaxMetricCardinality < 0 {
+		multierror.Append(&mErr, fmt.Errorf("max_metric_cardinality: must not be negative"))
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// metricTags returns the tags added to the metrics of the agent: the
+// datacenter and the configured tags.
+func (t *Telemetry) metricTags(datacenter string) []string {
+	tags := []string{"datacenter:" + datacenter}
+	if t != nil {
+		tags = append(tags, t.Tags...)
+	}
+	return tags
+}
+
+// splitMetricTag splits a key:value metric tag. The key must be a valid
+// Prometheus label name.
+func splitMetricTag(tag string) (key, value string, ok bool) {
+	idx := strings.Index(tag, ":")
+	if idx <= 0 {
+		return "", "", false
+	}
+	key = tag[:idx]
+	for i, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || (c >= '0' && c <= '9' && i > 0)) {
+			return "", "", false
+		}
+	}
+	return key, tag[idx+1:], true
+}
+
+// tracingConfig returns the configuration of the tracer of the agent. Spans
+// are attributed to the node name and datacenter of the agent.
+func (t *Telemetry) tracingConfig(nodeName, datacenter string) tracing.Config {
+	if t == nil {
+		return tracing.Config{}
+	}
+	conf := tracing.Config{
+		Endpoint:   t.OTLPTracesEndpoint,
+		SampleRate: 1,
+		Attributes: map[string]string{
+			"host.name":  nodeName,
+			"datacenter": datacenter,
+		},
+	}
+	if t.TraceSampleRate != nil {
+		conf.SampleRate = *t.TraceSampleRate
+	}
+	return conf
+}
+
 // parseSingleIPTemplate is used as a helper function to parse out a single IP
 // address from a config parameter.
 func parseSingleIPTemplate(ipTmpl string) (string, error) {
@@ -822,7 +1392,11 @@ func normalizeBind(addr, bind string) (string, error) {
 	if addr == "" {
 		return bind, nil
 	}
-	return parseSingleIPTemplate(addr)
+	addr, err := parseSingleIPTemplate(addr)
+	if err != nil {
+		return "", err
+	}
+	return trimIPv6Brackets(addr), nil
 }
 
 // normalizeAdvertise returns a normalized advertise address.
@@ -852,7 +1426,7 @@ func normalizeAdvertise(addr string, bind string, defport int, dev bool) (string
 			}
 
 			// missing port, append the default
-			return net.JoinHostPort(addr, strconv.Itoa(defport)), nil
+			return net.JoinHostPort(trimIPv6Brackets(addr), strconv.Itoa(defport)), nil
 		}
 
 		return addr, nil
@@ -886,6 +1460,15 @@ func normalizeAdvertise(addr string, bind string, defport int, dev bool) (string
 	return net.JoinHostPort(addr, strconv.Itoa(defport)), nil
 }
 
+// trimIPv6Brackets returns an IPv6 address written in brackets without them,
+// so that a port can be joined to it.
+func trimIPv6Brackets(addr string) string {
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		return addr[1 : len(addr)-1]
+	}
+	return addr
+}
+
 // isMissingPort returns true if an error is a "missing port" error from
 // net.SplitHostPort.
 func isMissingPort(err error) bool {
@@ -942,6 +1525,9 @@ func (a *ServerConfig) Merge(b *ServerConfig) *ServerConfig {
 	if b.MaxHeartbeatsPerSecond != 0.0 {
 		result.MaxHeartbeatsPerSecond = b.MaxHeartbeatsPerSecond
 	}
+	if b.MaxDispatchPayloadSize != "" {
+		result.MaxDispatchPayloadSize = b.MaxDispatchPayloadSize
+	}
 	if b.RetryMaxAttempts != 0 {
 		result.RetryMaxAttempts = b.RetryMaxAttempts
 	}
@@ -955,6 +1541,15 @@ func (a *ServerConfig) Merge(b *ServerConfig) *ServerConfig {
 	if b.EncryptKey != "" {
 		result.EncryptKey = b.EncryptKey
 	}
+	if b.VariablesEncryptionKey != "" {
+		result.VariablesEncryptionKey = b.VariablesEncryptionKey
+	}
+	if b.AuthoritativeRegion != "" {
+		result.AuthoritativeRegion = b.AuthoritativeRegion
+	}
+	if b.WorkloadIdentitySigningKeyFile != "" {
+		result.WorkloadIdentitySigningKeyFile = b.WorkloadIdentitySigningKeyFile
+	}
 
 	// Add the schedulers
 	result.EnabledSchedulers = append(result.EnabledSchedulers, b.EnabledSchedulers...)
@@ -969,9 +1564,65 @@ func (a *ServerConfig) Merge(b *ServerConfig) *ServerConfig {
 	result.RetryJoin = append(result.RetryJoin, a.RetryJoin...)
 	result.RetryJoin = append(result.RetryJoin, b.RetryJoin...)
 
+	// Merge the admission policies by name
+	result.AdmissionPolicies = nil
+	for _, policy := range a.AdmissionPolicies {
+		result.AdmissionPolicies = mergeAdmissionPolicy(result.AdmissionPolicies, policy)
+	}
+	for _, policy := range b.AdmissionPolicies {
+		result.AdmissionPolicies = mergeAdmissionPolicy(result.AdmissionPolicies, policy)
+	}
+
+	// Merge the deployment webhooks by name
+	result.DeploymentWebhooks = nil
+	for _, webhook := range a.DeploymentWebhooks {
+		result.DeploymentWebhooks = mergeDeploymentWebhook(result.DeploymentWebhooks, webhook)
+	}
+	for _, webhook := range b.DeploymentWebhooks {
+		result.DeploymentWebhooks = mergeDeploymentWebhook(result.DeploymentWebhooks, webhook)
+	}
+
+	// Add the allowed job notification webhooks
+	if b.JobNotification != nil {
+		result.JobNotification = a.JobNotification.Merge(b.JobNotification)
+	} else {
+		result.JobNotification = a.JobNotification.Copy()
+	}
+
+	// Add the image verification policy
+	if b.ImageVerification != nil {
+		result.ImageVerification = a.ImageVerification.Merge(b.ImageVerification)
+	} else {
+		result.ImageVerification = a.ImageVerification.Copy()
+	}
+
 	return &result
 }
 
+// mergeAdmissionPolicy adds a copy of the policy to the policies, replacing
+// the policy of the same name.
+func mergeAdmissionPolicy(policies []*config.AdmissionPolicyConfig, policy *config.AdmissionPolicyConfig) []*config.AdmissionPolicyConfig {
+	for i, p := range policies {
+		if p.Name == policy.Name {
+			policies[i] = policy.Copy()
+			return policies
+		}
+	}
+	return append(policies, policy.Copy())
+}
+
+// mergeDeploymentWebhook adds a copy of the webhook to the webhooks,
+// replacing the webhook of the same name.
+func mergeDeploymentWebhook(webhooks []*config.DeploymentWebhookConfig, webhook *config.DeploymentWebhookConfig) []*config.DeploymentWebhookConfig {
+	for i, w := range webhooks {
+		if w.Name == webhook.Name {
+			webhooks[i] = webhook.Copy()
+			return webhooks
+		}
+	}
+	return append(webhooks, webhook.Copy())
+}
+
 // Merge is used to merge two client configs together
 func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	result := *a
@@ -985,6 +1636,21 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.AllocDir != "" {
 		result.AllocDir = b.AllocDir
 	}
+	if b.CNIPath != "" {
+		result.CNIPath = b.CNIPath
+	}
+	if b.CNIConfigDir != "" {
+		result.CNIConfigDir = b.CNIConfigDir
+	}
+	if b.BridgeNetworkName != "" {
+		result.BridgeNetworkName = b.BridgeNetworkName
+	}
+	if b.BridgeNetworkSubnet != "" {
+		result.BridgeNetworkSubnet = b.BridgeNetworkSubnet
+	}
+	if b.BridgeNetworkSubnetIPv6 != "" {
+		result.BridgeNetworkSubnetIPv6 = b.BridgeNetworkSubnetIPv6
+	}
 	if b.NodeClass != "" {
 		result.NodeClass = b.NodeClass
 	}
@@ -1006,6 +1672,12 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 	if b.ClientMinPort != 0 {
 		result.ClientMinPort = b.ClientMinPort
 	}
+	if b.MinDynamicPort != 0 {
+		result.MinDynamicPort = b.MinDynamicPort
+	}
+	if b.MaxDynamicPort != 0 {
+		result.MaxDynamicPort = b.MaxDynamicPort
+	}
 	if result.Reserved == nil && b.Reserved != nil {
 		reserved := *b.Reserved
 		result.Reserved = &reserved
@@ -1059,9 +1731,32 @@ func (a *ClientConfig) Merge(b *ClientConfig) *ClientConfig {
 		result.ChrootEnv[k] = v
 	}
 
+	// Merge the host networks by name
+	result.HostNetworks = nil
+	for _, n := range a.HostNetworks {
+		result.HostNetworks = mergeHostNetwork(result.HostNetworks, n)
+	}
+	for _, n := range b.HostNetworks {
+		result.HostNetworks = mergeHostNetwork(result.HostNetworks, n)
+	}
+
+	result.DNS = a.DNS.Merge(b.DNS)
+
 	return &result
 }
 
+// mergeHostNetwork adds a copy of the host network to the host networks,
+// replacing the host network of the same name.
+func mergeHostNetwork(networks []*client.HostNetworkConfig, network *client.HostNetworkConfig) []*client.HostNetworkConfig {
+	for i, n := range networks {
+		if n.Name == network.Name {
+			networks[i] = network.Copy()
+			return networks
+		}
+	}
+	return append(networks, network.Copy())
+}
+
 // Merge is used to merge two telemetry configs together
 func (a *Telemetry) Merge(b *Telemetry) *Telemetry {
 	result := *a
@@ -1094,6 +1789,33 @@ func (a *Telemetry) Merge(b *Telemetry) *Telemetry {
 	if b.PublishAllocationMetrics {
 		result.PublishAllocationMetrics = true
 	}
+	if b.Tags != nil {
+		result.Tags = helper.CopySliceString(b.Tags)
+	}
+	if b.StatsdTaggedMetrics {
+		result.StatsdTaggedMetrics = true
+	}
+	if b.AllocationMetricLabels != nil {
+		result.AllocationMetricLabels = helper.CopySliceString(b.AllocationMetricLabels)
+	}
+	if b.PrometheusMetrics {
+		result.PrometheusMetrics = true
+	}
+	if b.OTLPTracesEndpoint != "" {
+		result.OTLPTracesEndpoint = b.OTLPTracesEndpoint
+	}
+	if b.TraceSampleRate != nil {
+		result.TraceSampleRate = helper.Float64ToPtr(*b.TraceSampleRate)
+	}
+	if b.PrefixFilter != nil {
+		result.PrefixFilter = helper.CopySliceString(b.PrefixFilter)
+	}
+	if b.FilterDefault != nil {
+		result.FilterDefault = helper.BoolToPtr(*b.FilterDefault)
+	}
+	if b.MaxMetricCardinality != 0 {
+		result.MaxMetricCardinality = b.MaxMetricCardinality
+	}
 	if b.CirconusAPIToken != "" {
 		result.CirconusAPIToken = b.CirconusAPIToken
 	}
@@ -1149,6 +1871,9 @@ func (a *Ports) Merge(b *Ports) *Ports {
 	if b.Serf != 0 {
 		result.Serf = b.Serf
 	}
+	if b.GRPC != 0 {
+		result.GRPC = b.GRPC
+	}
 	return &result
 }
 
@@ -1165,6 +1890,9 @@ func (a *Addresses) Merge(b *Addresses) *Addresses {
 	if b.Serf != "" {
 		result.Serf = b.Serf
 	}
+	if b.GRPC != "" {
+		result.GRPC = b.GRPC
+	}
 	return &result
 }
 
@@ -1203,6 +1931,150 @@ func (a *AtlasConfig) Merge(b *AtlasConfig) *AtlasConfig {
 	return &result
 }
 
+// Merge merges two limits configurations together.
+func (l *Limits) Merge(b *Limits) *Limits {
+	result := *l
+
+	if b.HTTPRate != 0 {
+		result.HTTPRate = b.HTTPRate
+	}
+	if b.HTTPBurst != 0 {
+		result.HTTPBurst = b.HTTPBurst
+	}
+	if b.HTTPRatePerIP != 0 {
+		result.HTTPRatePerIP = b.HTTPRatePerIP
+	}
+	if b.HTTPBurstPerIP != 0 {
+		result.HTTPBurstPerIP = b.HTTPBurstPerIP
+	}
+	if b.RPCRate != 0 {
+		result.RPCRate = b.RPCRate
+	}
+	if b.RPCBurst != 0 {
+		result.RPCBurst = b.RPCBurst
+	}
+	if b.RPCRatePerIP != 0 {
+		result.RPCRatePerIP = b.RPCRatePerIP
+	}
+	if b.RPCBurstPerIP != 0 {
+		result.RPCBurstPerIP = b.RPCBurstPerIP
+	}
+	if b.HTTPRatePerToken != 0 {
+		result.HTTPRatePerToken = b.HTTPRatePerToken
+	}
+	if b.HTTPBurstPerToken != 0 {
+		result.HTTPBurstPerToken = b.HTTPBurstPerToken
+	}
+	if b.RPCRatePerToken != 0 {
+		result.RPCRatePerToken = b.RPCRatePerToken
+	}
+	if b.RPCBurstPerToken != 0 {
+		result.RPCBurstPerToken = b.RPCBurstPerToken
+	}
+	return &result
+}
+
+// Merge merges two audit configurations together. Sinks and filters are
+// merged by name.
+func (a *AuditConfig) Merge(b *AuditConfig) *AuditConfig {
+	result := *a
+
+	if b.Enabled {
+		result.Enabled = true
+	}
+
+	result.Sinks = nil
+	for _, sinks := range [][]*AuditSink{a.Sinks, b.Sinks} {
+		for _, sink := range sinks {
+			result.Sinks = mergeAuditSink(result.Sinks, sink)
+		}
+	}
+	result.Filters = nil
+	for _, filters := range [][]*AuditFilter{a.Filters, b.Filters} {
+		for _, filter := range filters {
+			result.Filters = mergeAuditFilter(result.Filters, filter)
+		}
+	}
+	return &result
+}
+
+// mergeAuditSink adds the sink to the sinks, replacing the sink of the same
+// name
+func mergeAuditSink(sinks []*AuditSink, sink *AuditSink) []*AuditSink {
+	for i, s := range sinks {
+		if s.Name == sink.Name {
+			sinks[i] = sink
+			return sinks
+		}
+	}
+	return append(sinks, sink)
+}
+
+// mergeAuditFilter adds the filter to the filters, replacing the filter of
+// the same name
+func mergeAuditFilter(filters []*AuditFilter, filter *AuditFilter) []*AuditFilter {
+	for i, f := range filters {
+		if f.Name == filter.Name {
+			filters[i] = filter
+			return filters
+		}
+	}
+	return append(filters, filter)
+}
+
+// auditConfig returns the configuration of the auditor of the agent.
+func (a *AuditConfig) auditConfig() *audit.Config {
+	if a == nil {
+		return nil
+	}
+	conf := &audit.Config{Enabled: a.Enabled}
+	for _, s := range a.Sinks {
+		conf.Sinks = append(conf.Sinks, &audit.SinkConfig{
+			Name:              s.Name,
+			Type:              s.Type,
+			DeliveryGuarantee: s.DeliveryGuarantee,
+			Path:              s.Path,
+			Facility:          s.Facility,
+			Tag:               s.Tag,
+			Address:           s.Address,
+			Headers:           s.Headers,
+			Timeout:           s.Timeout,
+		})
+	}
+	for _, f := range a.Filters {
+		conf.Filters = append(conf.Filters, &audit.Filter{
+			Name:       f.Name,
+			Endpoints:  f.Endpoints,
+			Operations: f.Operations,
+			Stages:     f.Stages,
+			Identities: f.Identities,
+		})
+	}
+	return conf
+}
+
+// Merge merges two ACL configurations together.
+func (a *ACLConfig) Merge(b *ACLConfig) *ACLConfig {
+	result := *a
+
+	if b.Enabled {
+		result.Enabled = true
+	}
+	if b.TokenTTL != 0 {
+		result.TokenTTL = b.TokenTTL
+	}
+	if b.ReplicationToken != "" {
+		result.ReplicationToken = b.ReplicationToken
+	}
+	if b.TokenMinExpirationTTL != 0 {
+		result.TokenMinExpirationTTL = b.TokenMinExpirationTTL
+	}
+	if b.TokenMaxExpirationTTL != 0 {
+		result.TokenMaxExpirationTTL = b.TokenMaxExpirationTTL
+	}
+	return &result
+}
+
 func (r *Resources) Merge(b *Resources) *Resources {
 	result := *r
 	if b.CPU != 0 {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	client "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/mitchellh/mapstructure"
 )
@@ -96,6 +98,9 @@ func parseConfig(result *Config, list *ast.ObjectList) error {
 		"vault",
 		"tls",
 		"http_api_response_headers",
+		"limits",
+		"acl",
+		"audit",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return multierror.Prefix(err, "config:")
@@ -118,6 +123,9 @@ func parseConfig(result *Config, list *ast.ObjectList) error {
 	delete(m, "vault")
 	delete(m, "tls")
 	delete(m, "http_api_response_headers")
+	delete(m, "limits")
+	delete(m, "acl")
+	delete(m, "audit")
 
 	// Decode the rest
 	if err := mapstructure.WeakDecode(m, result); err != nil {
@@ -194,6 +202,27 @@ func parseConfig(result *Config, list *ast.ObjectList) error {
 		}
 	}
 
+	// Parse the limits config
+	if o := list.Filter("limits"); len(o.Items) > 0 {
+		if err := parseLimits(&result.Limits, o); err != nil {
+			return multierror.Prefix(err, "limits ->")
+		}
+	}
+
+	// Parse the ACL config
+	if o := list.Filter("acl"); len(o.Items) > 0 {
+		if err := parseACL(&result.ACL, o); err != nil {
+			return multierror.Prefix(err, "acl ->")
+		}
+	}
+
+	// Parse the audit config
+	if o := list.Filter("audit"); len(o.Items) > 0 {
+		if err := parseAudit(&result.Audit, o); err != nil {
+			return multierror.Prefix(err, "audit ->")
+		}
+	}
+
 	// Parse out http_api_response_headers fields. These are in HCL as a list so
 	// we need to iterate over them and merge them.
 	if headersO := list.Filter("http_api_response_headers"); len(headersO.Items) > 0 {
@@ -225,6 +254,7 @@ func parsePorts(result **Ports, list *ast.ObjectList) error {
 		"http",
 		"rpc",
 		"serf",
+		"grpc",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return err
@@ -257,6 +287,7 @@ func parseAddresses(result **Addresses, list *ast.ObjectList) error {
 		"http",
 		"rpc",
 		"serf",
+		"grpc",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return err
@@ -329,6 +360,11 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		"enabled",
 		"state_dir",
 		"alloc_dir",
+		"cni_path",
+		"cni_config_dir",
+		"bridge_network_name",
+		"bridge_network_subnet",
+		"bridge_network_subnet_ipv6",
 		"servers",
 		"node_class",
 		"options",
@@ -336,10 +372,14 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		"chroot_env",
 		"network_interface",
 		"network_speed",
+		"host_network",
+		"dns",
 		"cpu_total_compute",
 		"max_kill_timeout",
 		"client_max_port",
 		"client_min_port",
+		"min_dynamic_port",
+		"max_dynamic_port",
 		"reserved",
 		"stats",
 		"gc_interval",
@@ -363,6 +403,8 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 	delete(m, "chroot_env")
 	delete(m, "reserved")
 	delete(m, "stats")
+	delete(m, "host_network")
+	delete(m, "dns")
 
 	var config ClientConfig
 	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
@@ -426,10 +468,111 @@ func parseClient(result **ClientConfig, list *ast.ObjectList) error {
 		}
 	}
 
+	// Parse the host networks
+	if o := listVal.Filter("host_network"); len(o.Items) > 0 {
+		if err := parseHostNetworks(&config.HostNetworks, o); err != nil {
+			return multierror.Prefix(err, "host_network ->")
+		}
+	}
+
+	// Parse the DNS interface
+	if o := listVal.Filter("dns"); len(o.Items) > 0 {
+		if err := parseDNS(&config.DNS, o); err != nil {
+			return multierror.Prefix(err, "dns ->")
+		}
+	}
+
 	*result = &config
 	return nil
 }
 
+func parseDNS(result **client.DNSConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'dns' block allowed")
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"enabled",
+		"addr",
+		"domain",
+		"recursors",
+		"ttl",
+	}
+	if err := checkHCLKeys(list.Items[0].Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, list.Items[0].Val); err != nil {
+		return err
+	}
+
+	var dns client.DNSConfig
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &dns,
+	})
+	if err != nil {
+		return err
+	}
+	if err := dec.Decode(m); err != nil {
+		return err
+	}
+
+	if dns.Addr != "" {
+		if _, _, err := net.SplitHostPort(dns.Addr); err != nil {
+			return fmt.Errorf("invalid addr %q: %v", dns.Addr, err)
+		}
+	}
+	for _, r := range dns.Recursors {
+		if _, _, err := net.SplitHostPort(r); err != nil {
+			return fmt.Errorf("invalid recursor %q: %v", r, err)
+		}
+	}
+
+	*result = &dns
+	return nil
+}
+
+func parseHostNetworks(result *[]*client.HostNetworkConfig, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("host_network must have a name")
+		}
+		name := item.Keys[0].Token.Value().(string)
+
+		// Check for invalid keys
+		valid := []string{
+			"interface",
+			"cidr",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s ->", name))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		network := &client.HostNetworkConfig{Name: name}
+		if err := mapstructure.WeakDecode(m, network); err != nil {
+			return err
+		}
+		if network.CIDR != "" {
+			if _, _, err := net.ParseCIDR(network.CIDR); err != nil {
+				return fmt.Errorf("%s -> invalid cidr %q: %v", name, network.CIDR, err)
+			}
+		}
+
+		*result = append(*result, network)
+	}
+	return nil
+}
+
 func parseReserved(result **Resources, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) > 1 {
@@ -508,12 +651,20 @@ func parseServer(result **ServerConfig, list *ast.ObjectList) error {
 		"heartbeat_grace",
 		"min_heartbeat_ttl",
 		"max_heartbeats_per_second",
+		"max_dispatch_payload_size",
 		"start_join",
 		"retry_join",
 		"retry_max",
 		"retry_interval",
 		"rejoin_after_leave",
 		"encrypt",
+		"variables_encryption_key",
+		"admission_policy",
+		"deployment_webhook",
+		"job_notification",
+		"image_verification",
+		"authoritative_region",
+		"workload_identity_signing_key_file",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return err
@@ -524,6 +675,11 @@ func parseServer(result **ServerConfig, list *ast.ObjectList) error {
 		return err
 	}
 
+	delete(m, "admission_policy")
+	delete(m, "deployment_webhook")
+	delete(m, "job_notification")
+	delete(m, "image_verification")
+
 	var config ServerConfig
 	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
@@ -537,10 +693,214 @@ func parseServer(result **ServerConfig, list *ast.ObjectList) error {
 		return err
 	}
 
+	// Parse the admission policies
+	if o := listVal.Filter("admission_policy"); len(o.Items) > 0 {
+		if err := parseAdmissionPolicies(&config.AdmissionPolicies, o); err != nil {
+			return multierror.Prefix(err, "admission_policy ->")
+		}
+	}
+
+	// Parse the deployment webhooks
+	if o := listVal.Filter("deployment_webhook"); len(o.Items) > 0 {
+		if err := parseDeploymentWebhooks(&config.DeploymentWebhooks, o); err != nil {
+			return multierror.Prefix(err, "deployment_webhook ->")
+		}
+	}
+
+	// Parse the job notification restrictions
+	if o := listVal.Filter("job_notification"); len(o.Items) > 0 {
+		if err := parseJobNotification(&config.JobNotification, o); err != nil {
+			return multierror.Prefix(err, "job_notification ->")
+		}
+	}
+
+	// Parse the image verification policy
+	if o := listVal.Filter("image_verification"); len(o.Items) > 0 {
+		if err := parseImageVerification(&config.ImageVerification, o); err != nil {
+			return multierror.Prefix(err, "image_verification ->")
+		}
+	}
+
 	*result = &config
 	return nil
 }
 
+func parseImageVerification(result **config.ImageVerificationConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'image_verification' block allowed")
+	}
+
+	// Check for invalid keys
+	listVal := list.Items[0].Val
+	valid := []string{
+		"enabled",
+		"exempt_namespaces",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+
+	var verification config.ImageVerificationConfig
+	if err := mapstructure.WeakDecode(m, &verification); err != nil {
+		return err
+	}
+
+	*result = &verification
+	return nil
+}
+
+func parseJobNotification(result **config.JobNotificationConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'job_notification' block allowed")
+	}
+
+	// Check for invalid keys
+	listVal := list.Items[0].Val
+	valid := []string{
+		"allowed_webhooks",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+
+	var notification config.JobNotificationConfig
+	if err := mapstructure.WeakDecode(m, &notification); err != nil {
+		return err
+	}
+	if err := notification.Validate(); err != nil {
+		return err
+	}
+
+	*result = &notification
+	return nil
+}
+
+func parseDeploymentWebhooks(result *[]*config.DeploymentWebhookConfig, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("deployment_webhook must have a name")
+		}
+		name := item.Keys[0].Token.Value().(string)
+
+		// Check for invalid keys
+		valid := []string{
+			"address",
+			"method",
+			"headers",
+			"events",
+			"payload",
+			"timeout",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s ->", name))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+		delete(m, "headers")
+
+		webhook := &config.DeploymentWebhookConfig{
+			Name:    name,
+			Timeout: config.DefaultDeploymentWebhookTimeout,
+		}
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           webhook,
+		})
+		if err != nil {
+			return err
+		}
+		if err := dec.Decode(m); err != nil {
+			return err
+		}
+
+		// Parse the headers
+		if ot, ok := item.Val.(*ast.ObjectType); ok {
+			if o := ot.List.Filter("headers"); len(o.Items) > 0 {
+				for _, o := range o.Elem().Items {
+					var m map[string]interface{}
+					if err := hcl.DecodeObject(&m, o.Val); err != nil {
+						return err
+					}
+					if err := mapstructure.WeakDecode(m, &webhook.Headers); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if err := webhook.Validate(); err != nil {
+			return err
+		}
+
+		*result = append(*result, webhook)
+	}
+	return nil
+}
+
+func parseAdmissionPolicies(result *[]*config.AdmissionPolicyConfig, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("admission_policy must have a name")
+		}
+		name := item.Keys[0].Token.Value().(string)
+
+		// Check for invalid keys
+		valid := []string{
+			"type",
+			"address",
+			"timeout",
+			"fail_open",
+			"enforcement_level",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s ->", name))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		policy := &config.AdmissionPolicyConfig{
+			Name:    name,
+			Timeout: config.DefaultAdmissionPolicyTimeout,
+		}
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           policy,
+		})
+		if err != nil {
+			return err
+		}
+		if err := dec.Decode(m); err != nil {
+			return err
+		}
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+
+		*result = append(*result, policy)
+	}
+	return nil
+}
+
 func parseTelemetry(result **Telemetry, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) > 1 {
@@ -559,6 +919,15 @@ func parseTelemetry(result **Telemetry, list *ast.ObjectList) error {
 		"collection_interval",
 		"publish_allocation_metrics",
 		"publish_node_metrics",
+		"allocation_metric_labels",
+		"tags",
+		"statsd_tagged_metrics",
+		"prometheus_metrics",
+		"otlp_traces_endpoint",
+		"trace_sample_rate",
+		"prefix_filter",
+		"filter_default",
+		"max_metric_cardinality",
 		"datadog_address",
 		"circonus_api_token",
 		"circonus_api_app",
@@ -631,6 +1000,236 @@ func parseAtlas(result **AtlasConfig, list *ast.ObjectList) error {
 	return nil
 }
 
+func parseLimits(result **Limits, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'limits' block allowed")
+	}
+
+	// Get our limits object
+	listVal := list.Items[0].Val
+
+	// Check for invalid keys
+	valid := []string{
+		"http_rate",
+		"http_burst",
+		"http_rate_per_ip",
+		"http_burst_per_ip",
+		"rpc_rate",
+		"rpc_burst",
+		"rpc_rate_per_ip",
+		"rpc_burst_per_ip",
+		"http_rate_per_token",
+		"http_burst_per_token",
+		"rpc_rate_per_token",
+		"rpc_burst_per_token",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+
+	var limits Limits
+	if err := mapstructure.WeakDecode(m, &limits); err != nil {
+		return err
+	}
+	*result = &limits
+	return nil
+}
+
+func parseACL(result **ACLConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'acl' block allowed")
+	}
+
+	// Get our ACL object
+	listVal := list.Items[0].Val
+
+	// Check for invalid keys
+	valid := []string{
+		"enabled",
+		"token_ttl",
+		"replication_token",
+		"token_min_expiration_ttl",
+		"token_max_expiration_ttl",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+
+	var aclConfig ACLConfig
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &aclConfig,
+	})
+	if err != nil {
+		return err
+	}
+	if err := dec.Decode(m); err != nil {
+		return err
+	}
+	*result = &aclConfig
+	return nil
+}
+
+func parseAudit(result **AuditConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'audit' block allowed")
+	}
+
+	// Get our audit object
+	listVal := list.Items[0].Val
+
+	// Check for invalid keys
+	valid := []string{
+		"enabled",
+		"sink",
+		"filter",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, listVal); err != nil {
+		return err
+	}
+	delete(m, "sink")
+	delete(m, "filter")
+
+	var auditConfig AuditConfig
+	if err := mapstructure.WeakDecode(m, &auditConfig); err != nil {
+		return err
+	}
+
+	ot, ok := listVal.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("audit value: should be an object")
+	}
+
+	// Parse the sinks
+	if o := ot.List.Filter("sink"); len(o.Items) > 0 {
+		if err := parseAuditSinks(&auditConfig.Sinks, o); err != nil {
+			return multierror.Prefix(err, "sink ->")
+		}
+	}
+
+	// Parse the filters
+	if o := ot.List.Filter("filter"); len(o.Items) > 0 {
+		if err := parseAuditFilters(&auditConfig.Filters, o); err != nil {
+			return multierror.Prefix(err, "filter ->")
+		}
+	}
+
+	*result = &auditConfig
+	return nil
+}
+
+func parseAuditSinks(result *[]*AuditSink, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("sink must have a name")
+		}
+		name := item.Keys[0].Token.Value().(string)
+
+		// Check for invalid keys
+		valid := []string{
+			"type",
+			"delivery_guarantee",
+			"path",
+			"facility",
+			"tag",
+			"address",
+			"headers",
+			"timeout",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s ->", name))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+		delete(m, "headers")
+
+		sink := &AuditSink{Name: name}
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           sink,
+		})
+		if err != nil {
+			return err
+		}
+		if err := dec.Decode(m); err != nil {
+			return err
+		}
+
+		// Parse the headers
+		if ot, ok := item.Val.(*ast.ObjectType); ok {
+			if o := ot.List.Filter("headers"); len(o.Items) > 0 {
+				for _, o := range o.Elem().Items {
+					var m map[string]interface{}
+					if err := hcl.DecodeObject(&m, o.Val); err != nil {
+						return err
+					}
+					if err := mapstructure.WeakDecode(m, &sink.Headers); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		*result = append(*result, sink)
+	}
+	return nil
+}
+
+func parseAuditFilters(result *[]*AuditFilter, list *ast.ObjectList) error {
+	for _, item := range list.Items {
+		if len(item.Keys) != 1 {
+			return fmt.Errorf("filter must have a name")
+		}
+		name := item.Keys[0].Token.Value().(string)
+
+		// Check for invalid keys
+		valid := []string{
+			"endpoints",
+			"operations",
+			"stages",
+			"identities",
+		}
+		if err := checkHCLKeys(item.Val, valid); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("%s ->", name))
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, item.Val); err != nil {
+			return err
+		}
+
+		filter := &AuditFilter{Name: name}
+		if err := mapstructure.WeakDecode(m, filter); err != nil {
+			return err
+		}
+		*result = append(*result, filter)
+	}
+	return nil
+}
+
 func parseConsulConfig(result **config.ConsulConfig, list *ast.ObjectList) error {
 	list = list.Elem()
 	if len(list.Items) > 1 {
@@ -650,6 +1249,7 @@ func parseConsulConfig(result **config.ConsulConfig, list *ast.ObjectList) error
 		"checks_use_advertise",
 		"client_auto_join",
 		"client_service_name",
+		"grpc_address",
 		"key_file",
 		"server_auto_join",
 		"server_service_name",
@@ -734,12 +1334,19 @@ func parseVaultConfig(result **config.VaultConfig, list *ast.ObjectList) error {
 	valid := []string{
 		"address",
 		"allow_unauthenticated",
+		"allowed_policies",
+		"allowed_roles",
+		"allowed_namespaces",
 		"enabled",
 		"task_token_ttl",
 		"ca_file",
 		"ca_path",
 		"cert_file",
 		"create_from_role",
+		"identity_audience",
+		"identity_signing_key_file",
+		"identity_ttl",
+		"jwt_auth_backend_path",
 		"key_file",
 		"tls_server_name",
 		"tls_skip_verify",
@@ -793,7 +1400,7 @@ func checkHCLKeys(node ast.Node, valid []string) error {
 		key := item.Keys[0].Token.Value().(string)
 		if _, ok := validMap[key]; !ok {
 			result = multierror.Append(result, fmt.Errorf(
-				"invalid key: %s", key))
+				"At %s: invalid key: %s", item.Pos(), key))
 		}
 	}
 
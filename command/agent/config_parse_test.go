@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	client "github.com/hashicorp/nomad/client/config"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/kr/pretty"
@@ -33,22 +34,29 @@ func TestConfig_Parse(t *testing.T) {
 					HTTP: 1234,
 					RPC:  2345,
 					Serf: 3456,
+					GRPC: 4567,
 				},
 				Addresses: &Addresses{
 					HTTP: "127.0.0.1",
 					RPC:  "127.0.0.2",
 					Serf: "127.0.0.3",
+					GRPC: "127.0.0.4",
 				},
 				AdvertiseAddrs: &AdvertiseAddrs{
 					RPC:  "127.0.0.3",
 					Serf: "127.0.0.4",
 				},
 				Client: &ClientConfig{
-					Enabled:   true,
-					StateDir:  "/tmp/client-state",
-					AllocDir:  "/tmp/alloc",
-					Servers:   []string{"a.b.c:80", "127.0.0.1:1234"},
-					NodeClass: "linux-medium-64bit",
+					Enabled:                 true,
+					StateDir:                "/tmp/client-state",
+					AllocDir:                "/tmp/alloc",
+					CNIPath:                 "/opt/cni/bin:/usr/libexec/cni",
+					CNIConfigDir:            "/etc/cni/net.d",
+					BridgeNetworkName:       "nomad0",
+					BridgeNetworkSubnet:     "10.10.0.0/16",
+					BridgeNetworkSubnetIPv6: "fd00:10::/64",
+					Servers:                 []string{"a.b.c:80", "127.0.0.1:1234"},
+					NodeClass:               "linux-medium-64bit",
 					Meta: map[string]string{
 						"foo": "bar",
 						"baz": "zip",
@@ -63,10 +71,26 @@ func TestConfig_Parse(t *testing.T) {
 					},
 					NetworkInterface: "eth0",
 					NetworkSpeed:     100,
-					CpuCompute:       4444,
-					MaxKillTimeout:   "10s",
-					ClientMinPort:    1000,
-					ClientMaxPort:    2000,
+					HostNetworks: []*client.HostNetworkConfig{
+						{
+							Name:      "public",
+							Interface: "eth1",
+							CIDR:      "203.0.113.0/24",
+						},
+					},
+					DNS: &client.DNSConfig{
+						Enabled:   true,
+						Addr:      "127.0.0.1:53",
+						Domain:    "nomad.example.",
+						Recursors: []string{"8.8.8.8:53"},
+						TTL:       10 * time.Second,
+					},
+					CpuCompute:     4444,
+					MaxKillTimeout: "10s",
+					ClientMinPort:  1000,
+					ClientMaxPort:  2000,
+					MinDynamicPort: 25000,
+					MaxDynamicPort: 26000,
 					Reserved: &Resources{
 						CPU:                 10,
 						MemoryMB:            10,
@@ -83,25 +107,57 @@ func TestConfig_Parse(t *testing.T) {
 					NoHostUUID:            helper.BoolToPtr(false),
 				},
 				Server: &ServerConfig{
-					Enabled:                true,
-					BootstrapExpect:        5,
-					DataDir:                "/tmp/data",
-					ProtocolVersion:        3,
-					NumSchedulers:          2,
-					EnabledSchedulers:      []string{"test"},
-					NodeGCThreshold:        "12h",
-					EvalGCThreshold:        "12h",
-					JobGCThreshold:         "12h",
-					DeploymentGCThreshold:  "12h",
-					HeartbeatGrace:         30 * time.Second,
-					MinHeartbeatTTL:        33 * time.Second,
-					MaxHeartbeatsPerSecond: 11.0,
-					RetryJoin:              []string{"1.1.1.1", "2.2.2.2"},
-					StartJoin:              []string{"1.1.1.1", "2.2.2.2"},
-					RetryInterval:          "15s",
-					RejoinAfterLeave:       true,
-					RetryMaxAttempts:       3,
-					EncryptKey:             "abc",
+					Enabled:                        true,
+					BootstrapExpect:                5,
+					DataDir:                        "/tmp/data",
+					ProtocolVersion:                3,
+					NumSchedulers:                  2,
+					EnabledSchedulers:              []string{"test"},
+					NodeGCThreshold:                "12h",
+					EvalGCThreshold:                "12h",
+					JobGCThreshold:                 "12h",
+					DeploymentGCThreshold:          "12h",
+					HeartbeatGrace:                 30 * time.Second,
+					MinHeartbeatTTL:                33 * time.Second,
+					MaxHeartbeatsPerSecond:         11.0,
+					MaxDispatchPayloadSize:         "1MB",
+					RetryJoin:                      []string{"1.1.1.1", "2.2.2.2"},
+					StartJoin:                      []string{"1.1.1.1", "2.2.2.2"},
+					RetryInterval:                  "15s",
+					RejoinAfterLeave:               true,
+					RetryMaxAttempts:               3,
+					EncryptKey:                     "abc",
+					AuthoritativeRegion:            "foobar",
+					WorkloadIdentitySigningKeyFile: "/etc/nomad/workload-identity.pem",
+					VariablesEncryptionKey: "def",
+					AdmissionPolicies: []*config.AdmissionPolicyConfig{
+						{
+							Name:             "no-raw-exec",
+							Type:             "opa",
+							Address:          "http://127.0.0.1:8181/v1/data/nomad/admission",
+							Timeout:          2 * time.Second,
+							FailOpen:         true,
+							EnforcementLevel: "soft-mandatory",
+						},
+					},
+					DeploymentWebhooks: []*config.DeploymentWebhookConfig{
+						{
+							Name:    "chat",
+							Address: "https://chat.example.com/hooks/deployments",
+							Method:  "PUT",
+							Headers: map[string]string{"Authorization": "Bearer secret"},
+							Events:  []string{"failed", "reverted"},
+							Payload: `{"text": "{{ .JobID }} {{ .Event }}"}`,
+							Timeout: 5 * time.Second,
+						},
+					},
+					JobNotification: &config.JobNotificationConfig{
+						AllowedWebhooks: []string{"https://ci.example.com/hooks/"},
+					},
+					ImageVerification: &config.ImageVerificationConfig{
+						Enabled:          true,
+						ExemptNamespaces: []string{"dev"},
+					},
 				},
 				Telemetry: &Telemetry{
 					StatsiteAddr:             "127.0.0.1:1234",
@@ -112,6 +168,15 @@ func TestConfig_Parse(t *testing.T) {
 					collectionInterval:       3 * time.Second,
 					PublishAllocationMetrics: true,
 					PublishNodeMetrics:       true,
+					AllocationMetricLabels:   []string{"job", "group", "meta.team"},
+					Tags:                     []string{"env:prod"},
+					StatsdTaggedMetrics:      true,
+					PrometheusMetrics:        true,
+					OTLPTracesEndpoint:       "http://127.0.0.1:4318",
+					TraceSampleRate:          helper.Float64ToPtr(0.5),
+					PrefixFilter:             []string{"+nomad.raft", "-nomad.client.allocs"},
+					FilterDefault:            helper.BoolToPtr(false),
+					MaxMetricCardinality:     5000,
 				},
 				LeaveOnInt:                true,
 				LeaveOnTerm:               true,
@@ -129,6 +194,7 @@ func TestConfig_Parse(t *testing.T) {
 					ServerServiceName:  "nomad",
 					ClientServiceName:  "nomad-client",
 					Addr:               "127.0.0.1:9500",
+					GRPCAddr:           "127.0.0.1:9502",
 					Token:              "token1",
 					Auth:               "username:pass",
 					EnableSSL:          &trueValue,
@@ -142,18 +208,25 @@ func TestConfig_Parse(t *testing.T) {
 					ChecksUseAdvertise: &trueValue,
 				},
 				Vault: &config.VaultConfig{
-					Addr:                 "127.0.0.1:9500",
-					AllowUnauthenticated: &trueValue,
-					Enabled:              &falseValue,
-					Role:                 "test_role",
-					TLSCaFile:            "/path/to/ca/file",
-					TLSCaPath:            "/path/to/ca",
-					TLSCertFile:          "/path/to/cert/file",
-					TLSKeyFile:           "/path/to/key/file",
-					TLSServerName:        "foobar",
-					TLSSkipVerify:        &trueValue,
-					TaskTokenTTL:         "1s",
-					Token:                "12345",
+					Addr:                   "127.0.0.1:9500",
+					AllowUnauthenticated:   &trueValue,
+					Enabled:                &falseValue,
+					Role:                   "test_role",
+					TLSCaFile:              "/path/to/ca/file",
+					TLSCaPath:              "/path/to/ca",
+					TLSCertFile:            "/path/to/cert/file",
+					TLSKeyFile:             "/path/to/key/file",
+					TLSServerName:          "foobar",
+					TLSSkipVerify:          &trueValue,
+					TaskTokenTTL:           "1s",
+					Token:                  "12345",
+					IdentitySigningKeyFile: "/path/to/signing/key",
+					IdentityTTL:            "5m",
+					IdentityAudience:       "vault.example.com",
+					JWTAuthBackendPath:     "nomad-jwt",
+					AllowedPolicies:        []string{"web", "team-a-*"},
+					AllowedRoles:           []string{"sidecar"},
+					AllowedNamespaces:      []string{"team-a"},
 				},
 				TLSConfig: &config.TLSConfig{
 					EnableHTTP:           true,
@@ -164,6 +237,53 @@ func TestConfig_Parse(t *testing.T) {
 					KeyFile:              "pipe",
 					VerifyHTTPSClient:    true,
 				},
+				Limits: &Limits{
+					HTTPRate:          100,
+					HTTPBurst:         200,
+					HTTPRatePerIP:     10,
+					HTTPBurstPerIP:    20,
+					RPCRate:           500,
+					RPCBurst:          1000,
+					RPCRatePerIP:      50,
+					RPCBurstPerIP:     100,
+					HTTPRatePerToken:  5,
+					HTTPBurstPerToken: 10,
+					RPCRatePerToken:   25,
+					RPCBurstPerToken:  50,
+				},
+				ACL: &ACLConfig{
+					Enabled:               true,
+					TokenTTL:              60 * time.Second,
+					ReplicationToken:      "foobar",
+					TokenMinExpirationTTL: 10 * time.Minute,
+					TokenMaxExpirationTTL: 48 * time.Hour,
+				},
+				Audit: &AuditConfig{
+					Enabled: true,
+					Sinks: []*AuditSink{
+						{
+							Name: "file",
+							Type: "file",
+							Path: "/var/log/nomad/audit.log",
+						},
+						{
+							Name:              "siem",
+							Type:              "http",
+							DeliveryGuarantee: "best-effort",
+							Address:           "https://siem.example.com/events",
+							Headers:           map[string]string{"Authorization": "Bearer foo"},
+							Timeout:           3 * time.Second,
+						},
+					},
+					Filters: []*AuditFilter{
+						{
+							Name:       "reads",
+							Endpoints:  []string{"/v1/client/fs/*"},
+							Operations: []string{"GET"},
+							Identities: []string{"anonymous"},
+						},
+					},
+				},
 				HTTPAPIResponseHeaders: map[string]string{
 					"Access-Control-Allow-Origin": "*",
 				},
@@ -190,3 +310,22 @@ func TestConfig_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_Parse_InvalidKeyPosition(t *testing.T) {
+	t.Parallel()
+	input := `
+region = "global"
+
+client {
+  enabled = true
+  bad_key = 1
+}
+`
+	_, err := ParseConfig(strings.NewReader(input))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "At 6:3: invalid key: bad_key") {
+		t.Fatalf("expected the position of the invalid key; got %v", err)
+	}
+}
@@ -7,9 +7,12 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	client "github.com/hashicorp/nomad/client/config"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/structs/config"
 )
@@ -31,6 +34,8 @@ func TestConfig_Merge(t *testing.T) {
 		Addresses:      &Addresses{},
 		AdvertiseAddrs: &AdvertiseAddrs{},
 		Atlas:          &AtlasConfig{},
+		Limits:         &Limits{},
+		ACL:            &ACLConfig{},
 		Vault:          &config.VaultConfig{},
 		Consul:         &config.ConsulConfig{},
 	}
@@ -76,6 +81,10 @@ func TestConfig_Merge(t *testing.T) {
 			Options: map[string]string{
 				"foo": "bar",
 			},
+			DNS: &client.DNSConfig{
+				Addr:      "127.0.0.1:8600",
+				Recursors: []string{"1.1.1.1:53"},
+			},
 			NetworkSpeed:   100,
 			CpuCompute:     100,
 			MaxKillTimeout: "20s",
@@ -120,6 +129,17 @@ func TestConfig_Merge(t *testing.T) {
 			Join:           false,
 			Endpoint:       "foo",
 		},
+		Limits: &Limits{
+			HTTPRate:     100,
+			RPCRatePerIP: 10,
+		},
+		ACL: &ACLConfig{
+			TokenTTL:         30 * time.Second,
+			ReplicationToken: "foo",
+		},
+		Audit: &AuditConfig{
+			Sinks: []*AuditSink{{Name: "file", Type: "file", Path: "/tmp/a.log"}},
+		},
 		HTTPAPIResponseHeaders: map[string]string{
 			"Access-Control-Allow-Origin": "*",
 		},
@@ -140,6 +160,7 @@ func TestConfig_Merge(t *testing.T) {
 			ClientServiceName:  "1",
 			AutoAdvertise:      &falseValue,
 			Addr:               "1",
+			GRPCAddr:           "1",
 			Timeout:            1 * time.Second,
 			Token:              "1",
 			Auth:               "1",
@@ -175,6 +196,15 @@ func TestConfig_Merge(t *testing.T) {
 			DisableHostname:                    true,
 			PublishNodeMetrics:                 true,
 			PublishAllocationMetrics:           true,
+			AllocationMetricLabels:             []string{"job", "group"},
+			Tags:                               []string{"env:prod", "team:infra"},
+			StatsdTaggedMetrics:                true,
+			PrometheusMetrics:                  true,
+			OTLPTracesEndpoint:                 "http://127.0.0.1:4318",
+			TraceSampleRate:                    helper.Float64ToPtr(0.5),
+			PrefixFilter:                       []string{"-nomad.client.allocs"},
+			FilterDefault:                      helper.BoolToPtr(true),
+			MaxMetricCardinality:               1000,
 			CirconusAPIToken:                   "1",
 			CirconusAPIApp:                     "nomad",
 			CirconusAPIURL:                     "https://api.circonus.com/v2",
@@ -190,11 +220,13 @@ func TestConfig_Merge(t *testing.T) {
 			CirconusBrokerSelectTag:            "dc:dc2",
 		},
 		Client: &ClientConfig{
-			Enabled:   true,
-			StateDir:  "/tmp/state2",
-			AllocDir:  "/tmp/alloc2",
-			NodeClass: "class2",
-			Servers:   []string{"server2"},
+			Enabled:             true,
+			StateDir:            "/tmp/state2",
+			AllocDir:            "/tmp/alloc2",
+			CNIConfigDir:        "/tmp/cni2",
+			BridgeNetworkSubnet: "10.10.0.0/16",
+			NodeClass:           "class2",
+			Servers:             []string{"server2"},
 			Meta: map[string]string{
 				"baz": "zip",
 			},
@@ -202,6 +234,13 @@ func TestConfig_Merge(t *testing.T) {
 				"foo": "bar",
 				"baz": "zip",
 			},
+			DNS: &client.DNSConfig{
+				Enabled:   true,
+				Addr:      "127.0.0.1:53",
+				Domain:    "nomad.",
+				Recursors: []string{"8.8.8.8:53"},
+				TTL:       5 * time.Second,
+			},
 			ChrootEnv:      map[string]string{},
 			ClientMaxPort:  20000,
 			ClientMinPort:  22000,
@@ -228,10 +267,12 @@ func TestConfig_Merge(t *testing.T) {
 			ProtocolVersion:        2,
 			NumSchedulers:          2,
 			EnabledSchedulers:      []string{structs.JobTypeBatch},
+			AuthoritativeRegion:    "global",
 			NodeGCThreshold:        "12h",
 			HeartbeatGrace:         2 * time.Minute,
 			MinHeartbeatTTL:        2 * time.Minute,
 			MaxHeartbeatsPerSecond: 200.0,
+			MaxDispatchPayloadSize: "1MB",
 			RejoinAfterLeave:       true,
 			StartJoin:              []string{"1.1.1.1"},
 			RetryJoin:              []string{"1.1.1.1"},
@@ -242,11 +283,13 @@ func TestConfig_Merge(t *testing.T) {
 			HTTP: 20000,
 			RPC:  21000,
 			Serf: 22000,
+			GRPC: 23000,
 		},
 		Addresses: &Addresses{
 			HTTP: "127.0.0.2",
 			RPC:  "127.0.0.2",
 			Serf: "127.0.0.2",
+			GRPC: "127.0.0.2",
 		},
 		AdvertiseAddrs: &AdvertiseAddrs{
 			RPC:  "127.0.0.2",
@@ -258,6 +301,32 @@ func TestConfig_Merge(t *testing.T) {
 			Join:           true,
 			Endpoint:       "bar",
 		},
+		Limits: &Limits{
+			HTTPRate:          200,
+			HTTPBurst:         400,
+			HTTPRatePerIP:     20,
+			HTTPBurstPerIP:    40,
+			RPCRate:           500,
+			RPCBurst:          1000,
+			RPCRatePerIP:      50,
+			RPCBurstPerIP:     100,
+			HTTPRatePerToken:  5,
+			HTTPBurstPerToken: 10,
+			RPCRatePerToken:   25,
+			RPCBurstPerToken:  50,
+		},
+		ACL: &ACLConfig{
+			Enabled:               true,
+			TokenTTL:              60 * time.Second,
+			ReplicationToken:      "bar",
+			TokenMinExpirationTTL: 10 * time.Minute,
+			TokenMaxExpirationTTL: 48 * time.Hour,
+		},
+		Audit: &AuditConfig{
+			Enabled: true,
+			Sinks:   []*AuditSink{{Name: "file", Type: "file", Path: "/tmp/b.log"}},
+			Filters: []*AuditFilter{{Name: "reads", Operations: []string{"GET"}}},
+		},
 		HTTPAPIResponseHeaders: map[string]string{
 			"Access-Control-Allow-Origin":  "*",
 			"Access-Control-Allow-Methods": "GET, POST, OPTIONS",
@@ -279,6 +348,7 @@ func TestConfig_Merge(t *testing.T) {
 			ClientServiceName:  "2",
 			AutoAdvertise:      &trueValue,
 			Addr:               "2",
+			GRPCAddr:           "2",
 			Timeout:            2 * time.Second,
 			Token:              "2",
 			Auth:               "2",
@@ -841,6 +911,42 @@ func TestResources_ParseReserved(t *testing.T) {
 	}
 }
 
+func TestConfig_normalizeAddrs_IPv6Brackets(t *testing.T) {
+	c := &Config{
+		BindAddr: "[::1]",
+		Ports: &Ports{
+			HTTP: 4646,
+			RPC:  4647,
+			Serf: 4648,
+		},
+		Addresses: &Addresses{
+			HTTP: "[2001:db8::1]",
+		},
+		AdvertiseAddrs: &AdvertiseAddrs{
+			HTTP: "[2001:db8::1]",
+			RPC:  "2001:db8::1",
+			Serf: "[2001:db8::1]:5648",
+		},
+	}
+
+	if err := c.normalizeAddrs(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.Addresses.HTTP != "2001:db8::1" {
+		t.Errorf("expected 2001:db8::1 HTTP address, got %s", c.Addresses.HTTP)
+	}
+	if c.AdvertiseAddrs.HTTP != "[2001:db8::1]:4646" {
+		t.Errorf("expected [2001:db8::1]:4646 HTTP advertise address, got %s", c.AdvertiseAddrs.HTTP)
+	}
+	if c.AdvertiseAddrs.RPC != "[2001:db8::1]:4647" {
+		t.Errorf("expected [2001:db8::1]:4647 RPC advertise address, got %s", c.AdvertiseAddrs.RPC)
+	}
+	if c.AdvertiseAddrs.Serf != "[2001:db8::1]:5648" {
+		t.Errorf("expected [2001:db8::1]:5648 Serf advertise address, got %s", c.AdvertiseAddrs.Serf)
+	}
+}
+
 func TestIsMissingPort(t *testing.T) {
 	_, _, err := net.SplitHostPort("localhost")
 	if missing := isMissingPort(err); !missing {
@@ -851,3 +957,61 @@ func TestIsMissingPort(t *testing.T) {
 		t.Errorf("expected no error, but got %v", err)
 	}
 }
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A valid configuration
+	c := DefaultConfig()
+	c.BindAddr = "169.254.0.1"
+	c.DataDir = dir
+	c.Server.Enabled = true
+	warnings, err := c.Validate()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	if c.normalizedAddrs != nil {
+		t.Fatalf("validation should not modify the config")
+	}
+
+	// An invalid configuration
+	c = DefaultConfig()
+	c.DataDir = "relative/dir"
+	c.Client.Enabled = true
+	c.Client.Options = map[string]string{"driver.whitelist": "exec, bogus"}
+	c.LogLevel = "LOUD"
+	c.TLSConfig = &config.TLSConfig{
+		EnableHTTP: true,
+		CertFile:   filepath.Join(dir, "missing.pem"),
+	}
+	c.Telemetry = &Telemetry{StatsdAddr: "no-port", Tags: []string{"env"}, PrefixFilter: []string{"nomad.raft"}}
+
+	warnings, err = c.Validate()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	for _, expected := range []string{
+		"data-dir must be given as an absolute path",
+		"Invalid log level: LOUD",
+		"cert_file and key_file must be set",
+		"missing.pem",
+		"statsd_address",
+		`invalid tag "env"`,
+		`invalid rule "nomad.raft"`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in error; got %v", expected, err)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"bogus"`) {
+		t.Fatalf("expected an unknown driver warning; got %v", warnings)
+	}
+}
@@ -0,0 +1,81 @@
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ACLsAPI is the Consul ACL API used by Nomad servers to manage the Service
+// Identity tokens of the Connect sidecar proxies.
+type ACLsAPI interface {
+	// CreateServiceIdentityToken creates a local token with the service
+	// identity of the given service in the Consul namespace and partition
+	// and returns its accessor and secret IDs.
+	CreateServiceIdentityToken(consul *structs.Consul, service, description string) (accessorID, secretID string, err error)
+
+	// DeleteToken deletes the token with the given accessor ID from the
+	// Consul namespace and partition.
+	DeleteToken(consul *structs.Consul, accessorID string) error
+}
+
+// aclsClient implements ACLsAPI against the Consul HTTP API. The vendored
+// Consul API client predates Service Identities so the token endpoints are
+// called directly using the client's configuration.
+type aclsClient struct {
+	httpClient
+}
+
+// NewACLsClient returns an ACLsAPI using the given Consul client
+// configuration. The configuration must have been used to create an
+// api.Client so that its HTTP client and address are set.
+func NewACLsClient(config *api.Config) ACLsAPI {
+	return &aclsClient{httpClient{config: config}}
+}
+
+// serviceIdentityToken is the subset of a Consul ACL token Nomad creates and
+// reads back.
+type serviceIdentityToken struct {
+	AccessorID        string `json:",omitempty"`
+	SecretID          string `json:",omitempty"`
+	Description       string `json:",omitempty"`
+	Local             bool   `json:",omitempty"`
+	ServiceIdentities []*serviceIdentity
+	Namespace         string `json:",omitempty"`
+	Partition         string `json:",omitempty"`
+}
+
+type serviceIdentity struct {
+	ServiceName string
+}
+
+func (c *aclsClient) CreateServiceIdentityToken(consul *structs.Consul, service, description string) (string, string, error) {
+	in := &serviceIdentityToken{
+		Description:       description,
+		Local:             true,
+		ServiceIdentities: []*serviceIdentity{{ServiceName: service}},
+		Namespace:         consul.GetNamespace(),
+		Partition:         consul.GetPartition(),
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return "", "", err
+	}
+
+	var out serviceIdentityToken
+	if err := c.do("PUT", "/v1/acl/token", nil, body, &out); err != nil {
+		return "", "", fmt.Errorf("failed to create service identity token for %q: %v", service, err)
+	}
+	return out.AccessorID, out.SecretID, nil
+}
+
+func (c *aclsClient) DeleteToken(consul *structs.Consul, accessorID string) error {
+	query := scopeQuery(consul.GetNamespace(), consul.GetPartition())
+	if err := c.do("DELETE", "/v1/acl/token/"+url.PathEscape(accessorID), query, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete token %q: %v", accessorID, err)
+	}
+	return nil
+}
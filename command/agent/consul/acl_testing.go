@@ -0,0 +1,86 @@
+package consul
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// MockACLs can be used for testing where the ACLsAPI is needed. Tokens are
+// kept in memory and errors can be injected with SetError.
+type MockACLs struct {
+	logger *log.Logger
+
+	// tokens maps the accessor IDs of the tokens to their service and
+	// namespaces to their Consul namespace
+	tokens     map[string]string
+	namespaces map[string]string
+	err        error
+	l          sync.Mutex
+}
+
+func NewMockACLs(l *log.Logger) *MockACLs {
+	return &MockACLs{
+		logger:     l,
+		tokens:     make(map[string]string),
+		namespaces: make(map[string]string),
+	}
+}
+
+// SetError makes all subsequent calls fail with the given error. A nil error
+// clears it.
+func (m *MockACLs) SetError(err error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	m.err = err
+}
+
+// Tokens returns a copy of the tokens that exist, keyed by accessor ID.
+func (m *MockACLs) Tokens() map[string]string {
+	m.l.Lock()
+	defer m.l.Unlock()
+	tokens := make(map[string]string, len(m.tokens))
+	for k, v := range m.tokens {
+		tokens[k] = v
+	}
+	return tokens
+}
+
+// TokenNamespace returns the Consul namespace of the token with the given
+// accessor ID.
+func (m *MockACLs) TokenNamespace(accessorID string) string {
+	m.l.Lock()
+	defer m.l.Unlock()
+	return m.namespaces[accessorID]
+}
+
+func (m *MockACLs) CreateServiceIdentityToken(consul *structs.Consul, service, description string) (string, string, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if m.err != nil {
+		return "", "", m.err
+	}
+	accessor, secret := structs.GenerateUUID(), structs.GenerateUUID()
+	m.tokens[accessor] = service
+	m.namespaces[accessor] = consul.GetNamespace()
+	m.logger.Printf("[DEBUG] mock_consul: CreateServiceIdentityToken(%q, %q, %q) -> (%q, nil)",
+		consul.GetNamespace(), service, description, accessor)
+	return accessor, secret, nil
+}
+
+func (m *MockACLs) DeleteToken(consul *structs.Consul, accessorID string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.tokens[accessorID]; !ok {
+		return fmt.Errorf("token %q not found", accessorID)
+	}
+	delete(m.tokens, accessorID)
+	delete(m.namespaces, accessorID)
+	m.logger.Printf("[DEBUG] mock_consul: DeleteToken(%q) -> nil", accessorID)
+	return nil
+}
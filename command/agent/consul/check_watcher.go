@@ -0,0 +1,308 @@
+package consul
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// defaultPollFreq is the default rate to poll the Consul Checks API
+	defaultPollFreq = 900 * time.Millisecond
+)
+
+// TaskRestarter allows the checkWatcher to restart tasks.
+type TaskRestarter interface {
+	Restart(source, reason string, failure bool)
+}
+
+// CheckRestart handles restarting a task if a check is unhealthy for longer
+// than its check_restart stanza allows.
+type CheckRestart struct {
+	allocID   string
+	taskName  string
+	checkName string
+
+	task           TaskRestarter
+	timeLimit      time.Duration
+	ignoreWarnings bool
+
+	// unhealthyState is the time a check first went unhealthy. Set to the
+	// zero value if the check passes before timeLimit.
+	unhealthyState time.Time
+
+	// graceUntil is when the check's grace period expires and unhealthy
+	// checks should be counted.
+	graceUntil time.Time
+
+	logger *log.Logger
+}
+
+// NewCheckRestart returns a CheckRestart restarting the task once the check
+// has been unhealthy for Limit intervals. The grace period starts now. The
+// check must trigger restarts.
+func NewCheckRestart(allocID, taskName string, check *structs.ServiceCheck, task TaskRestarter,
+	logger *log.Logger) *CheckRestart {
+
+	return &CheckRestart{
+		allocID:        allocID,
+		taskName:       taskName,
+		checkName:      check.Name,
+		task:           task,
+		timeLimit:      check.Interval * time.Duration(check.CheckRestart.Limit-1),
+		ignoreWarnings: check.CheckRestart.IgnoreWarnings,
+		graceUntil:     time.Now().Add(check.CheckRestart.Grace),
+		logger:         logger,
+	}
+}
+
+// Apply restarts the task if the check has been unhealthy for too long given
+// its status at now and returns true if a restart was triggered. The restart
+// is asynchronous so callers may hold locks the task needs to restart.
+//
+// Not thread safe.
+func (c *CheckRestart) Apply(now time.Time, status string) bool {
+	healthy := func() {
+		if !c.unhealthyState.IsZero() {
+			c.logger.Printf("[DEBUG] consul.health: alloc %q task %q check %q became healthy; canceling restart",
+				c.allocID, c.taskName, c.checkName)
+			c.unhealthyState = time.Time{}
+		}
+	}
+
+	// Don't restart until grace period has elapsed
+	if now.Before(c.graceUntil) {
+		return false
+	}
+
+	switch status {
+	case api.HealthCritical:
+	case api.HealthWarning:
+		if c.ignoreWarnings {
+			// Warnings are ignored, reset state and exit
+			healthy()
+			return false
+		}
+	default:
+		// All other statuses are ok, reset state and exit
+		healthy()
+		return false
+	}
+
+	if c.unhealthyState.IsZero() {
+		// First failure, set restart deadline
+		c.logger.Printf("[DEBUG] consul.health: alloc %q task %q check %q became unhealthy. Restarting in %s if not healthy",
+			c.allocID, c.taskName, c.checkName, c.timeLimit)
+		c.unhealthyState = now
+	}
+
+	// restartAt is when the check can be restarted
+	restartAt := c.unhealthyState.Add(c.timeLimit)
+
+	// Must test >= because if limit=1, restartAt == first failure
+	if now.Equal(restartAt) || now.After(restartAt) {
+		// hasn't become healthy by deadline, restart!
+		c.logger.Printf("[DEBUG] consul.health: restarting alloc %q task %q due to unhealthy check %q",
+			c.allocID, c.taskName, c.checkName)
+
+		// Tell TaskRunner to restart due to failure
+		const failure = true
+		go c.task.Restart("healthcheck", fmt.Sprintf("check %q unhealthy", c.checkName), failure)
+		return true
+	}
+
+	return false
+}
+
+// watchedCheck is a check whose status is polled by the checkWatcher.
+type watchedCheck struct {
+	*CheckRestart
+
+	// scope is the Consul namespace and partition the check is registered
+	// in
+	scope consulScope
+}
+
+// taskKey returns a key identifying the task of the check.
+func (w *watchedCheck) taskKey() string {
+	return w.allocID + w.taskName
+}
+
+// checkWatchUpdate adds or removes checks from the watcher.
+type checkWatchUpdate struct {
+	checkID string
+	remove  bool
+	check   *watchedCheck
+}
+
+// checkWatcher watches Consul checks and restarts tasks when they're
+// unhealthy.
+type checkWatcher struct {
+	// agent returns the agent API of a scope
+	agent func(consulScope) AgentAPI
+
+	// pollFreq is how often to poll the checks API and defaults to
+	// defaultPollFreq
+	pollFreq time.Duration
+
+	// checkUpdateCh is how watches (and removals) are sent to the main
+	// watching loop
+	checkUpdateCh chan checkWatchUpdate
+
+	// shutdownCh stops the watching loop when closed
+	shutdownCh <-chan struct{}
+
+	logger *log.Logger
+}
+
+// newCheckWatcher creates a new checkWatcher but does not call its Run method.
+func newCheckWatcher(logger *log.Logger, agent func(consulScope) AgentAPI, shutdownCh <-chan struct{}) *checkWatcher {
+	return &checkWatcher{
+		agent:         agent,
+		pollFreq:      defaultPollFreq,
+		checkUpdateCh: make(chan checkWatchUpdate, 8),
+		shutdownCh:    shutdownCh,
+		logger:        logger,
+	}
+}
+
+// Run the main Consul checks watching loop until the watcher is shutdown.
+func (w *checkWatcher) Run() {
+	// Map of check IDs to their metadata
+	checks := map[string]*watchedCheck{}
+
+	ticker := time.NewTicker(w.pollFreq)
+	defer ticker.Stop()
+
+	// Only log the first of consecutive errors polling Consul
+	lastErr := false
+
+	for {
+		// Don't poll Consul until there are checks that trigger restarts
+		var pollCh <-chan time.Time
+		if len(checks) > 0 {
+			pollCh = ticker.C
+		}
+
+		select {
+		case update := <-w.checkUpdateCh:
+			if update.remove {
+				delete(checks, update.checkID)
+			} else {
+				checks[update.checkID] = update.check
+			}
+
+		case <-w.shutdownCh:
+			return
+
+		case <-pollCh:
+			// Set "now" as the point in time the following check results
+			// represent
+			now := time.Now()
+
+			results, err := w.poll(checks)
+			if err != nil {
+				if !lastErr {
+					w.logger.Printf("[WARN] consul.health: error retrieving health checks: %v", err)
+				}
+				lastErr = true
+				continue
+			}
+			lastErr = false
+
+			// Keep track of tasks restarted this period so they only
+			// restart once and all of their checks are removed.
+			restartedTasks := map[string]struct{}{}
+
+			// Loop over watched checks and update their status from
+			// results
+			for cid, check := range checks {
+				if _, ok := restartedTasks[check.taskKey()]; ok {
+					// Check for this task already restarted; remove and
+					// skip check
+					delete(checks, cid)
+					continue
+				}
+
+				result, ok := results[check.scope][cid]
+				if !ok {
+					// Only warn if outside grace period to avoid races
+					// with check registration
+					if now.After(check.graceUntil) {
+						w.logger.Printf("[WARN] consul.health: watched check %q (%s) not found in Consul", check.checkName, cid)
+					}
+					continue
+				}
+
+				if check.Apply(now, result.Status) {
+					// Checks are registered again when the task
+					// restarts so remove them now
+					delete(checks, cid)
+					restartedTasks[check.taskKey()] = struct{}{}
+				}
+			}
+
+			// Ensure even passing checks of restarted tasks are removed
+			if len(restartedTasks) > 0 {
+				for cid, check := range checks {
+					if _, ok := restartedTasks[check.taskKey()]; ok {
+						delete(checks, cid)
+					}
+				}
+			}
+		}
+	}
+}
+
+// poll returns the checks of the scopes of the watched checks.
+func (w *checkWatcher) poll(checks map[string]*watchedCheck) (map[consulScope]map[string]*api.AgentCheck, error) {
+	results := make(map[consulScope]map[string]*api.AgentCheck)
+	for _, check := range checks {
+		if _, ok := results[check.scope]; ok {
+			continue
+		}
+		scopeChecks, err := w.agent(check.scope).Checks()
+		if err != nil {
+			return nil, err
+		}
+		results[check.scope] = scopeChecks
+	}
+	return results, nil
+}
+
+// Watch a check and restart its task if unhealthy.
+func (w *checkWatcher) Watch(allocID, taskName, checkID string, scope consulScope, check *structs.ServiceCheck,
+	restarter TaskRestarter) {
+	if !check.TriggersRestarts() {
+		// Not watched, noop
+		return
+	}
+
+	c := &watchedCheck{
+		CheckRestart: NewCheckRestart(allocID, taskName, check, restarter, w.logger),
+		scope:        scope,
+	}
+	w.update(checkWatchUpdate{
+		checkID: checkID,
+		check:   c,
+	})
+}
+
+// Unwatch a check.
+func (w *checkWatcher) Unwatch(cid string) {
+	w.update(checkWatchUpdate{
+		checkID: cid,
+		remove:  true,
+	})
+}
+
+// update sends an update to the watching loop unless shutdown.
+func (w *checkWatcher) update(u checkWatchUpdate) {
+	select {
+	case w.checkUpdateCh <- u:
+	case <-w.shutdownCh:
+	}
+}
@@ -0,0 +1,208 @@
+package consul
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// fakeCheckRestarter is a test implementation of TaskRestarter.
+type fakeCheckRestarter struct {
+	mu       sync.Mutex
+	restarts int
+	failures int
+}
+
+func (c *fakeCheckRestarter) Restart(source, reason string, failure bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.restarts++
+	if failure {
+		c.failures++
+	}
+}
+
+func (c *fakeCheckRestarter) counts() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.restarts, c.failures
+}
+
+// fakeChecksAPI implements the Checks method of AgentAPI returning the
+// statuses set by the test.
+type fakeChecksAPI struct {
+	AgentAPI
+
+	mu       sync.Mutex
+	statuses map[string]string
+}
+
+func (c *fakeChecksAPI) set(checkID, status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statuses[checkID] = status
+}
+
+func (c *fakeChecksAPI) Checks() (map[string]*api.AgentCheck, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	checks := make(map[string]*api.AgentCheck, len(c.statuses))
+	for id, status := range c.statuses {
+		checks[id] = &api.AgentCheck{CheckID: id, Status: status}
+	}
+	return checks, nil
+}
+
+// testWatcherSetup returns a running checkWatcher polling the fake checks
+// API. The watcher is stopped when the returned channel is closed.
+func testWatcherSetup() (*checkWatcher, *fakeChecksAPI, chan struct{}) {
+	fakeAPI := &fakeChecksAPI{statuses: make(map[string]string)}
+	shutdownCh := make(chan struct{})
+	cw := newCheckWatcher(testLogger(), func(consulScope) AgentAPI { return fakeAPI }, shutdownCh)
+	cw.pollFreq = 10 * time.Millisecond
+	go cw.Run()
+	return cw, fakeAPI, shutdownCh
+}
+
+func testCheck(limit int) *structs.ServiceCheck {
+	return &structs.ServiceCheck{
+		Name:     "testcheck",
+		Interval: 20 * time.Millisecond,
+		Timeout:  100 * time.Millisecond,
+		CheckRestart: &structs.CheckRestart{
+			Limit: limit,
+		},
+	}
+}
+
+func TestCheckRestart_Apply(t *testing.T) {
+	t.Parallel()
+	restarter := &fakeCheckRestarter{}
+	check := testCheck(2)
+	check.CheckRestart.Grace = time.Minute
+	cr := NewCheckRestart("alloc", "task", check, restarter, testLogger())
+
+	// Unhealthy checks are ignored during the grace period
+	now := time.Now()
+	if cr.Apply(now, api.HealthCritical) {
+		t.Fatalf("unexpected restart during grace period")
+	}
+
+	// The task is restarted once unhealthy for limit intervals
+	now = now.Add(time.Minute)
+	if cr.Apply(now, api.HealthCritical) {
+		t.Fatalf("unexpected restart on first failure")
+	}
+	if cr.Apply(now.Add(check.Interval/2), api.HealthPassing) {
+		t.Fatalf("unexpected restart when healthy")
+	}
+	if cr.Apply(now.Add(check.Interval), api.HealthCritical) {
+		t.Fatalf("unexpected restart after becoming healthy")
+	}
+	if !cr.Apply(now.Add(2*check.Interval), api.HealthCritical) {
+		t.Fatalf("expected restart")
+	}
+
+	testutil.WaitForResult(func() (bool, error) {
+		restarts, failures := restarter.counts()
+		if restarts != 1 || failures != 1 {
+			return false, fmt.Errorf("expected 1 failure restart; got %d restarts and %d failures", restarts, failures)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
+
+func TestCheckRestart_Apply_IgnoreWarnings(t *testing.T) {
+	t.Parallel()
+	check := testCheck(1)
+
+	cr := NewCheckRestart("alloc", "task", check, &fakeCheckRestarter{}, testLogger())
+	if !cr.Apply(time.Now(), api.HealthWarning) {
+		t.Fatalf("expected restart on warning")
+	}
+
+	check.CheckRestart.IgnoreWarnings = true
+	cr = NewCheckRestart("alloc", "task", check, &fakeCheckRestarter{}, testLogger())
+	if cr.Apply(time.Now(), api.HealthWarning) {
+		t.Fatalf("unexpected restart on ignored warning")
+	}
+}
+
+// TestCheckWatcher_Skip asserts unwatched checks are ignored.
+func TestCheckWatcher_Skip(t *testing.T) {
+	t.Parallel()
+	cw, fakeAPI, shutdownCh := testWatcherSetup()
+	defer close(shutdownCh)
+
+	restarter := &fakeCheckRestarter{}
+	fakeAPI.set("testcheck", api.HealthCritical)
+	cw.Watch("alloc", "task", "testcheck", consulScope{}, testCheck(0), restarter)
+
+	time.Sleep(100 * time.Millisecond)
+	if restarts, _ := restarter.counts(); restarts != 0 {
+		t.Fatalf("expected no restarts; got %d", restarts)
+	}
+}
+
+// TestCheckWatcher_Unhealthy asserts unhealthy checks restart their task once
+// and are unwatched.
+func TestCheckWatcher_Unhealthy(t *testing.T) {
+	t.Parallel()
+	cw, fakeAPI, shutdownCh := testWatcherSetup()
+	defer close(shutdownCh)
+
+	restarter := &fakeCheckRestarter{}
+	fakeAPI.set("check1", api.HealthCritical)
+	fakeAPI.set("check2", api.HealthCritical)
+	cw.Watch("alloc", "task", "check1", consulScope{}, testCheck(1), restarter)
+	cw.Watch("alloc", "task", "check2", consulScope{}, testCheck(1), restarter)
+
+	healthy := &fakeCheckRestarter{}
+	fakeAPI.set("check3", api.HealthPassing)
+	cw.Watch("alloc", "other", "check3", consulScope{}, testCheck(1), healthy)
+
+	testutil.WaitForResult(func() (bool, error) {
+		restarts, failures := restarter.counts()
+		if restarts != 1 || failures != 1 {
+			return false, fmt.Errorf("expected 1 failure restart; got %d restarts and %d failures", restarts, failures)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// The checks of the restarted task aren't watched anymore
+	time.Sleep(100 * time.Millisecond)
+	if restarts, _ := restarter.counts(); restarts != 1 {
+		t.Fatalf("expected 1 restart; got %d", restarts)
+	}
+	if restarts, _ := healthy.counts(); restarts != 0 {
+		t.Fatalf("expected healthy task not to restart; got %d", restarts)
+	}
+}
+
+// TestCheckWatcher_Unwatch asserts unwatched checks don't restart their task.
+func TestCheckWatcher_Unwatch(t *testing.T) {
+	t.Parallel()
+	cw, fakeAPI, shutdownCh := testWatcherSetup()
+	defer close(shutdownCh)
+
+	restarter := &fakeCheckRestarter{}
+	check := testCheck(1)
+	check.CheckRestart.Grace = 100 * time.Millisecond
+	fakeAPI.set("testcheck", api.HealthCritical)
+	cw.Watch("alloc", "task", "testcheck", consulScope{}, check, restarter)
+	cw.Unwatch("testcheck")
+
+	time.Sleep(200 * time.Millisecond)
+	if restarts, _ := restarter.counts(); restarts != 0 {
+		t.Fatalf("expected no restarts; got %d", restarts)
+	}
+}
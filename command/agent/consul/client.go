@@ -5,6 +5,7 @@ import (
 	"log"
 	"net"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,6 +16,7 @@ import (
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/nomad/client/driver"
 	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -50,6 +52,11 @@ const (
 
 	// ServiceTagSerf is the tag assigned to Serf services
 	ServiceTagSerf = "serf"
+
+	// taggedAddressLANIPv4 and taggedAddressLANIPv6 are the tagged addresses
+	// of the services of dual stack hosts in each family
+	taggedAddressLANIPv4 = "lan_ipv4"
+	taggedAddressLANIPv6 = "lan_ipv6"
 )
 
 // CatalogAPI is the consul/api.Catalog API used by Nomad.
@@ -76,17 +83,38 @@ type addrParser func(portLabel string) (string, int)
 // operations are submitted to the main loop via commit() for synchronizing
 // with Consul.
 type operations struct {
+	// scope is the Consul namespace and partition of the registrations
+	scope consulScope
+
 	regServices []*api.AgentServiceRegistration
+	regProxies  []*ProxyRegistration
 	regChecks   []*api.AgentCheckRegistration
 	scripts     []*scriptCheck
 
 	deregServices []string
 	deregChecks   []string
+
+	// tokens are the Service Identity tokens of the registered services and
+	// proxies by ID
+	tokens map[string]string
+}
+
+// setToken records the Service Identity token a service is registered with.
+func (o *operations) setToken(serviceID, token string) {
+	if token == "" {
+		return
+	}
+	if o.tokens == nil {
+		o.tokens = make(map[string]string)
+	}
+	o.tokens[serviceID] = token
 }
 
 // ServiceClient handles task and agent service registration with Consul.
 type ServiceClient struct {
 	client           AgentAPI
+	connect          ConnectAPI
+	namespaces       NamespacesAPI
 	logger           *log.Logger
 	retryInterval    time.Duration
 	maxRetryInterval time.Duration
@@ -107,10 +135,32 @@ type ServiceClient struct {
 	opCh chan *operations
 
 	services       map[string]*api.AgentServiceRegistration
+	proxies        map[string]*ProxyRegistration
 	checks         map[string]*api.AgentCheckRegistration
 	scripts        map[string]*scriptCheck
 	runningScripts map[string]*scriptHandle
 
+	// serviceScopes are the scopes of the services registered outside of
+	// the default scope by ID, and scopes all the scopes services were
+	// registered in so that they are removed from them once unknown
+	serviceScopes map[string]consulScope
+	scopes        map[consulScope]struct{}
+
+	// serviceTokens are the Service Identity tokens services are registered
+	// and deregistered with by ID. Services without one use the token of
+	// the agent.
+	serviceTokens map[string]string
+
+	// taskTokens are the Service Identity tokens of the services of the
+	// tasks by service name, set by the tasks before registering them
+	taskTokens     map[string]map[string]string
+	taskTokensLock sync.RWMutex
+
+	// staleProxies are the IDs of the proxies whose configuration changed
+	// since they were registered. Consul doesn't return the proxy
+	// configuration of services so they can't be diffed like ports.
+	staleProxies map[string]struct{}
+
 	// agent services and checks record entries for the agent itself which
 	// should be removed on shutdown
 	agentServices map[string]struct{}
@@ -120,13 +170,24 @@ type ServiceClient struct {
 	// seen is 1 if Consul has ever been seen; otherise 0. Accessed with
 	// atomics.
 	seen int32
+
+	// syncErr is the error of the last sync with Consul, or nil if it
+	// succeeded.
+	syncErr     error
+	syncErrLock sync.Mutex
+
+	// checkWatcher restarts checks that are unhealthy.
+	checkWatcher *checkWatcher
 }
 
 // NewServiceClient creates a new Consul ServiceClient from an existing Consul API
 // Client and logger.
-func NewServiceClient(consulClient AgentAPI, skipVerifySupport bool, logger *log.Logger) *ServiceClient {
-	return &ServiceClient{
+func NewServiceClient(consulClient AgentAPI, connectClient ConnectAPI, namespaces NamespacesAPI,
+	skipVerifySupport bool, logger *log.Logger) *ServiceClient {
+	c := &ServiceClient{
 		client:            consulClient,
+		connect:           connectClient,
+		namespaces:        namespaces,
 		skipVerifySupport: skipVerifySupport,
 		logger:            logger,
 		retryInterval:     defaultRetryInterval,
@@ -136,12 +197,20 @@ func NewServiceClient(consulClient AgentAPI, skipVerifySupport bool, logger *log
 		shutdownWait:      defaultShutdownWait,
 		opCh:              make(chan *operations, 8),
 		services:          make(map[string]*api.AgentServiceRegistration),
+		proxies:           make(map[string]*ProxyRegistration),
 		checks:            make(map[string]*api.AgentCheckRegistration),
 		scripts:           make(map[string]*scriptCheck),
 		runningScripts:    make(map[string]*scriptHandle),
+		serviceScopes:     make(map[string]consulScope),
+		scopes:            make(map[consulScope]struct{}),
+		serviceTokens:     make(map[string]string),
+		taskTokens:        make(map[string]map[string]string),
+		staleProxies:      make(map[string]struct{}),
 		agentServices:     make(map[string]struct{}),
 		agentChecks:       make(map[string]struct{}),
 	}
+	c.checkWatcher = newCheckWatcher(logger, c.agent, c.shutdownCh)
+	return c
 }
 
 // seen is used by markSeen and hasSeen
@@ -159,10 +228,22 @@ func (c *ServiceClient) hasSeen() bool {
 	return atomic.LoadInt32(&c.seen) == seen
 }
 
+// SyncError returns the error of the last sync of the services and checks
+// with Consul, or nil if it succeeded.
+func (c *ServiceClient) SyncError() error {
+	c.syncErrLock.Lock()
+	defer c.syncErrLock.Unlock()
+	return c.syncErr
+}
+
 // Run the Consul main loop which retries operations against Consul. It should
 // be called exactly once.
 func (c *ServiceClient) Run() {
 	defer close(c.exitCh)
+
+	// Watch checks that restart their task when unhealthy
+	go c.checkWatcher.Run()
+
 	retryTimer := time.NewTimer(0)
 	<-retryTimer.C // disabled by default
 	failures := 0
@@ -174,7 +255,11 @@ func (c *ServiceClient) Run() {
 			c.merge(ops)
 		}
 
-		if err := c.sync(); err != nil {
+		err := c.sync()
+		c.syncErrLock.Lock()
+		c.syncErr = err
+		c.syncErrLock.Unlock()
+		if err != nil {
 			if failures == 0 {
 				c.logger.Printf("[WARN] consul.sync: failed to update services in Consul: %v", err)
 			}
@@ -227,8 +312,26 @@ func (c *ServiceClient) commit(ops *operations) {
 // merge registrations into state map prior to sync'ing with Consul
 func (c *ServiceClient) merge(ops *operations) {
 	for _, s := range ops.regServices {
+		c.setScope(s.ID, ops.scope)
+		c.setServiceToken(s.ID, ops.tokens[s.ID])
+		if _, ok := c.proxies[s.ID]; ok {
+			// A gateway turned back into a plain service
+			delete(c.proxies, s.ID)
+			c.staleProxies[s.ID] = struct{}{}
+		}
 		c.services[s.ID] = s
 	}
+	for _, p := range ops.regProxies {
+		c.setScope(p.ID, ops.scope)
+		c.setServiceToken(p.ID, ops.tokens[p.ID])
+		old, ok := c.proxies[p.ID]
+		_, exists := c.services[p.ID]
+		if (ok && !reflect.DeepEqual(old, p)) || (!ok && exists) {
+			c.staleProxies[p.ID] = struct{}{}
+		}
+		c.services[p.ID] = p.agentServiceReg()
+		c.proxies[p.ID] = p
+	}
 	for _, check := range ops.regChecks {
 		c.checks[check.ID] = check
 	}
@@ -237,6 +340,9 @@ func (c *ServiceClient) merge(ops *operations) {
 	}
 	for _, sid := range ops.deregServices {
 		delete(c.services, sid)
+		delete(c.proxies, sid)
+		delete(c.staleProxies, sid)
+		delete(c.serviceScopes, sid)
 	}
 	for _, cid := range ops.deregChecks {
 		if script, ok := c.runningScripts[cid]; ok {
@@ -251,17 +357,149 @@ func (c *ServiceClient) merge(ops *operations) {
 	metrics.SetGauge([]string{"client", "consul", "script_checks"}, float32(len(c.runningScripts)))
 }
 
-// sync enqueued operations.
+// setScope records the scope of a registered service.
+func (c *ServiceClient) setScope(serviceID string, scope consulScope) {
+	if scope.isDefault() {
+		delete(c.serviceScopes, serviceID)
+		return
+	}
+	c.serviceScopes[serviceID] = scope
+	c.scopes[scope] = struct{}{}
+}
+
+// setServiceToken records the Service Identity token of a registered
+// service. Tokens are kept after the service is removed until it has been
+// deregistered from Consul.
+func (c *ServiceClient) setServiceToken(serviceID, token string) {
+	if token == "" {
+		delete(c.serviceTokens, serviceID)
+		return
+	}
+	c.serviceTokens[serviceID] = token
+}
+
+// agent returns the agent API of the scope.
+func (c *ServiceClient) agent(scope consulScope) AgentAPI {
+	if scope.isDefault() {
+		return c.client
+	}
+	return c.namespaces.Agent(scope.namespace, scope.partition)
+}
+
+// tokenAgent returns the agent API of the scope authenticating with the
+// given token, or with the token of the agent if it is empty.
+func (c *ServiceClient) tokenAgent(scope consulScope, token string) AgentAPI {
+	if token == "" {
+		return c.agent(scope)
+	}
+	return c.namespaces.TokenAgent(scope.namespace, scope.partition, token)
+}
+
+// serviceAgent returns the APIs a service, its proxy and its checks are
+// registered with: the ones authenticating with the Service Identity token
+// of the service if it has one, or else the ones of the scope.
+func (c *ServiceClient) serviceAgent(scope consulScope, serviceID string) (AgentAPI, ConnectAPI) {
+	if token := c.serviceTokens[serviceID]; token != "" {
+		agent := c.namespaces.TokenAgent(scope.namespace, scope.partition, token)
+		return agent, agent
+	}
+	return c.agent(scope), c.connect
+}
+
+// deregisterService deregisters a service with its Service Identity token,
+// falling back to the token of the agent as the token of the service may
+// have been revoked along with its allocation.
+func (c *ServiceClient) deregisterService(scope consulScope, serviceID string) error {
+	agent, _ := c.serviceAgent(scope, serviceID)
+	err := agent.ServiceDeregister(serviceID)
+	if err != nil && c.serviceTokens[serviceID] != "" {
+		err = c.agent(scope).ServiceDeregister(serviceID)
+	}
+	return err
+}
+
+// deregisterCheck deregisters a check with the Service Identity token of its
+// service like deregisterService.
+func (c *ServiceClient) deregisterCheck(scope consulScope, serviceID, checkID string) error {
+	agent, _ := c.serviceAgent(scope, serviceID)
+	err := agent.CheckDeregister(checkID)
+	if err != nil && c.serviceTokens[serviceID] != "" {
+		err = c.agent(scope).CheckDeregister(checkID)
+	}
+	return err
+}
+
+// SetServiceTokens sets the Service Identity tokens, by service name, the
+// services of a task are registered with by the next calls of RegisterTask
+// and UpdateTask. Services without a token are registered with the token of
+// the agent.
+func (c *ServiceClient) SetServiceTokens(allocID, taskName string, tokens map[string]string) {
+	c.taskTokensLock.Lock()
+	defer c.taskTokensLock.Unlock()
+	if len(tokens) == 0 {
+		delete(c.taskTokens, makeTaskTokensKey(allocID, taskName))
+		return
+	}
+	c.taskTokens[makeTaskTokensKey(allocID, taskName)] = helper.CopyMapStringString(tokens)
+}
+
+// serviceToken returns the Service Identity token of the service of a task or
+// an empty string if it has none.
+func (c *ServiceClient) serviceToken(allocID, taskName, service string) string {
+	c.taskTokensLock.RLock()
+	defer c.taskTokensLock.RUnlock()
+	return c.taskTokens[makeTaskTokensKey(allocID, taskName)][service]
+}
+
+// makeTaskTokensKey returns the key of the Service Identity tokens of a task.
+func makeTaskTokensKey(allocID, taskName string) string {
+	return allocID + "/" + taskName
+}
+
+// syncStats counts the operations performed by a sync.
+type syncStats struct {
+	sreg, creg, sdereg, cdereg int
+}
+
+// sync enqueued operations in the default scope and all the scopes services
+// were registered in.
 func (c *ServiceClient) sync() error {
-	sreg, creg, sdereg, cdereg := 0, 0, 0, 0
+	stats := &syncStats{}
+	if err := c.syncScope(consulScope{}, stats); err != nil {
+		return err
+	}
+	for scope := range c.scopes {
+		if err := c.syncScope(scope, stats); err != nil {
+			return fmt.Errorf("namespace %q partition %q: %v", scope.namespace, scope.partition, err)
+		}
+	}
+
+	// Every removed service has been deregistered so their tokens can be
+	// forgotten
+	for id := range c.serviceTokens {
+		if _, ok := c.services[id]; !ok {
+			delete(c.serviceTokens, id)
+		}
+	}
+
+	// A Consul operation has succeeded, mark Consul as having been seen
+	c.markSeen()
+
+	c.logger.Printf("[DEBUG] consul.sync: registered %d services, %d checks; deregistered %d services, %d checks",
+		stats.sreg, stats.creg, stats.sdereg, stats.cdereg)
+	return nil
+}
 
-	consulServices, err := c.client.Services()
+// syncScope syncs the services and checks of a scope.
+func (c *ServiceClient) syncScope(scope consulScope, stats *syncStats) error {
+	agent := c.agent(scope)
+	consulServices, err := agent.Services()
 	if err != nil {
 		metrics.IncrCounter([]string{"client", "consul", "sync_failure"}, 1)
 		return fmt.Errorf("error querying Consul services: %v", err)
 	}
 
-	consulChecks, err := c.client.Checks()
+	consulChecks, err := agent.Checks()
 	if err != nil {
 		metrics.IncrCounter([]string{"client", "consul", "sync_failure"}, 1)
 		return fmt.Errorf("error querying Consul checks: %v", err)
@@ -269,7 +507,7 @@ func (c *ServiceClient) sync() error {
 
 	// Remove Nomad services in Consul but unknown locally
 	for id := range consulServices {
-		if _, ok := c.services[id]; ok {
+		if _, ok := c.services[id]; ok && c.serviceScopes[id] == scope {
 			// Known service, skip
 			continue
 		}
@@ -278,11 +516,11 @@ func (c *ServiceClient) sync() error {
 			continue
 		}
 		// Unknown Nomad managed service; kill
-		if err := c.client.ServiceDeregister(id); err != nil {
+		if err := c.deregisterService(scope, id); err != nil {
 			metrics.IncrCounter([]string{"client", "consul", "sync_failure"}, 1)
 			return err
 		}
-		sdereg++
+		stats.sdereg++
 		metrics.IncrCounter([]string{"client", "consul", "service_deregisrations"}, 1)
 	}
 
@@ -292,28 +530,39 @@ func (c *ServiceClient) sync() error {
 
 	// Add Nomad services missing from Consul
 	for id, locals := range c.services {
+		if c.serviceScopes[id] != scope {
+			continue
+		}
 		if remotes, ok := consulServices[id]; ok {
 			// Make sure Port and Address are stable since
 			// PortLabel and AddressMode aren't included in the
 			// service ID.
-			if locals.Port == remotes.Port && locals.Address == remotes.Address {
+			_, stale := c.staleProxies[id]
+			if locals.Port == remotes.Port && locals.Address == remotes.Address && !stale {
 				// Already exists in Consul; skip
 				continue
 			}
 			// Port changed, reregister it and its checks
 			portsChanged[id] = struct{}{}
 		}
-		if err = c.client.ServiceRegister(locals); err != nil {
+		serviceAgent, connect := c.serviceAgent(scope, id)
+		if proxy, ok := c.proxies[id]; ok {
+			err = connect.ProxyRegister(proxy)
+		} else {
+			err = serviceAgent.ServiceRegister(locals)
+		}
+		if err != nil {
 			metrics.IncrCounter([]string{"client", "consul", "sync_failure"}, 1)
 			return err
 		}
-		sreg++
+		delete(c.staleProxies, id)
+		stats.sreg++
 		metrics.IncrCounter([]string{"client", "consul", "service_regisrations"}, 1)
 	}
 
 	// Remove Nomad checks in Consul but unknown locally
 	for id, check := range consulChecks {
-		if _, ok := c.checks[id]; ok {
+		if local, ok := c.checks[id]; ok && c.serviceScopes[local.ServiceID] == scope {
 			// Known check, leave it
 			continue
 		}
@@ -322,27 +571,31 @@ func (c *ServiceClient) sync() error {
 			continue
 		}
 		// Unknown Nomad managed check; kill
-		if err := c.client.CheckDeregister(id); err != nil {
+		if err := c.deregisterCheck(scope, check.ServiceID, id); err != nil {
 			metrics.IncrCounter([]string{"client", "consul", "sync_failure"}, 1)
 			return err
 		}
-		cdereg++
+		stats.cdereg++
 		metrics.IncrCounter([]string{"client", "consul", "check_deregisrations"}, 1)
 	}
 
 	// Add Nomad checks missing from Consul
 	for id, check := range c.checks {
+		if c.serviceScopes[check.ServiceID] != scope {
+			continue
+		}
 		if check, ok := consulChecks[id]; ok {
 			if _, changed := portsChanged[check.ServiceID]; !changed {
 				// Already in Consul and ports didn't change; skipping
 				continue
 			}
 		}
-		if err := c.client.CheckRegister(check); err != nil {
+		serviceAgent, _ := c.serviceAgent(scope, check.ServiceID)
+		if err := serviceAgent.CheckRegister(check); err != nil {
 			metrics.IncrCounter([]string{"client", "consul", "sync_failure"}, 1)
 			return err
 		}
-		creg++
+		stats.creg++
 		metrics.IncrCounter([]string{"client", "consul", "check_regisrations"}, 1)
 
 		// Handle starting scripts
@@ -355,12 +608,6 @@ func (c *ServiceClient) sync() error {
 			c.runningScripts[id] = script.run()
 		}
 	}
-
-	// A Consul operation has succeeded, mark Consul as having been seen
-	c.markSeen()
-
-	c.logger.Printf("[DEBUG] consul.sync: registered %d services, %d checks; deregistered %d services, %d checks",
-		sreg, creg, sdereg, cdereg)
 	return nil
 }
 
@@ -477,10 +724,104 @@ func (c *ServiceClient) serviceRegs(ops *operations, allocID string, service *st
 	// copy isn't strictly necessary but can avoid bugs especially
 	// with tests that may reuse Tasks
 	copy(serviceReg.Tags, service.Tags)
-	ops.regServices = append(ops.regServices, serviceReg)
+
+	// The token of the service also allows registering its sidecar proxy
+	token := c.serviceToken(allocID, task.Name, service.Name)
+	ops.setToken(id, token)
+
+	taggedAddresses, err := parseTaggedAddresses(service.TaggedAddresses, port)
+	if err != nil {
+		return fmt.Errorf("service %s has invalid tagged addresses: %v", service.Name, err)
+	}
+	if addrMode != structs.AddressModeDriver {
+		if ipv6 := task.Resources.Networks.PortIPv6(service.PortLabel); ipv6 != "" && ipv6 != ip {
+			taggedAddresses = dualStackAddresses(taggedAddresses, ip, ipv6, port)
+		}
+	}
+
+	// Gateways are registered with their Consul service kind
+	if service.Connect.IsGateway() {
+		proxyReg := connectGatewayReg(service, serviceReg)
+		proxyReg.Meta = helper.CopyMapStringString(service.Meta)
+		proxyReg.TaggedAddresses = taggedAddresses
+		proxyReg.setScope(ops.scope)
+		ops.regProxies = append(ops.regProxies, proxyReg)
+		return c.checkRegs(ops, allocID, id, service, task, exec, net)
+	}
+
+	// The vendored Consul API lacks the metadata and tagged addresses of
+	// services so services setting them are registered raw like proxies
+	if len(service.Meta) != 0 || len(taggedAddresses) != 0 {
+		reg := &ProxyRegistration{
+			ID:              serviceReg.ID,
+			Name:            serviceReg.Name,
+			Tags:            serviceReg.Tags,
+			Address:         serviceReg.Address,
+			Port:            serviceReg.Port,
+			Meta:            helper.CopyMapStringString(service.Meta),
+			TaggedAddresses: taggedAddresses,
+		}
+		reg.setScope(ops.scope)
+		ops.regProxies = append(ops.regProxies, reg)
+	} else {
+		ops.regServices = append(ops.regServices, serviceReg)
+	}
+
+	// Register the sidecar proxy of Connect services along with them
+	if service.Connect != nil {
+		proxyReg, err := connectProxyReg(allocID, task, service, serviceReg)
+		if err != nil {
+			return err
+		}
+		proxyReg.setScope(ops.scope)
+		ops.setToken(proxyReg.ID, token)
+		ops.regProxies = append(ops.regProxies, proxyReg)
+	}
 	return c.checkRegs(ops, allocID, id, service, task, exec, net)
 }
 
+// parseTaggedAddresses parses the tagged addresses of a service, of the form
+// "address" or "address:port". Addresses without a port use the port of the
+// service.
+func parseTaggedAddresses(addrs map[string]string, port int) (map[string]ServiceAddress, error) {
+	if len(addrs) == 0 {
+		return nil, nil
+	}
+
+	parsed := make(map[string]ServiceAddress, len(addrs))
+	for tag, addr := range addrs {
+		host, portStr, err := net.SplitHostPort(addr)
+		if err != nil {
+			// No port, use the service's
+			host, portStr = addr, strconv.Itoa(port)
+		}
+		p, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port of %q address %q: %v", tag, addr, err)
+		}
+		if host == "" {
+			return nil, fmt.Errorf("%q address is empty", tag)
+		}
+		parsed[tag] = ServiceAddress{Address: host, Port: p}
+	}
+	return parsed, nil
+}
+
+// dualStackAddresses adds the addresses of both families of a service of a
+// dual stack host to its tagged addresses, unless they are set.
+func dualStackAddresses(addrs map[string]ServiceAddress, ipv4, ipv6 string, port int) map[string]ServiceAddress {
+	if addrs == nil {
+		addrs = make(map[string]ServiceAddress, 2)
+	}
+	if _, ok := addrs[taggedAddressLANIPv4]; !ok {
+		addrs[taggedAddressLANIPv4] = ServiceAddress{Address: ipv4, Port: port}
+	}
+	if _, ok := addrs[taggedAddressLANIPv6]; !ok {
+		addrs[taggedAddressLANIPv6] = ServiceAddress{Address: ipv6, Port: port}
+	}
+	return addrs
+}
+
 func (c *ServiceClient) checkRegs(ops *operations, allocID, serviceID string, service *structs.Service,
 	task *structs.Task, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
 
@@ -496,7 +837,7 @@ func (c *ServiceClient) checkRegs(ops *operations, allocID, serviceID string, se
 				return fmt.Errorf("driver doesn't support script checks")
 			}
 			ops.scripts = append(ops.scripts, newScriptCheck(
-				allocID, task.Name, checkID, check, exec, c.client, c.logger, c.shutdownCh))
+				allocID, task.Name, checkID, check, exec, c.tokenAgent(ops.scope, ops.tokens[serviceID]), c.logger, c.shutdownCh))
 
 		}
 
@@ -522,24 +863,45 @@ func (c *ServiceClient) checkRegs(ops *operations, allocID, serviceID string, se
 // If the service IP is set it used as the address in the service registration.
 // Checks will always use the IP from the Task struct (host's IP).
 //
+// The services are registered in the Consul namespace and partition of the
+// task group.
+//
+// The restarter restarts the task when a check with a check_restart stanza
+// stays unhealthy.
+//
 // Actual communication with Consul is done asynchrously (see Run).
-func (c *ServiceClient) RegisterTask(allocID string, task *structs.Task, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
-	ops := &operations{}
+func (c *ServiceClient) RegisterTask(allocID string, consul *structs.Consul, task *structs.Task,
+	restarter TaskRestarter, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
+	ops := &operations{scope: scopeOf(consul)}
 	for _, service := range task.Services {
 		if err := c.serviceRegs(ops, allocID, service, task, exec, net); err != nil {
 			return err
 		}
 	}
 	c.commit(ops)
+
+	// Start watching checks. Done after service registrations are built
+	// since an error building them could leak watches.
+	for _, service := range task.Services {
+		serviceID := makeTaskServiceID(allocID, task.Name, service)
+		for _, check := range service.Checks {
+			if check.TriggersRestarts() {
+				checkID := makeCheckID(serviceID, check)
+				c.checkWatcher.Watch(allocID, task.Name, checkID, ops.scope, check, restarter)
+			}
+		}
+	}
 	return nil
 }
 
 // UpdateTask in Consul. Does not alter the service if only checks have
 // changed.
 //
-// DriverNetwork must not change between invocations for the same allocation.
-func (c *ServiceClient) UpdateTask(allocID string, existing, newTask *structs.Task, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
-	ops := &operations{}
+// DriverNetwork and the Consul namespace and partition must not change
+// between invocations for the same allocation.
+func (c *ServiceClient) UpdateTask(allocID string, consul *structs.Consul, existing, newTask *structs.Task,
+	restarter TaskRestarter, exec driver.ScriptExecutor, net *cstructs.DriverNetwork) error {
+	ops := &operations{scope: scopeOf(consul)}
 
 	existingIDs := make(map[string]*structs.Service, len(existing.Services))
 	for _, s := range existing.Services {
@@ -557,15 +919,29 @@ func (c *ServiceClient) UpdateTask(allocID string, existing, newTask *structs.Ta
 		if !ok {
 			// Existing service entry removed
 			ops.deregServices = append(ops.deregServices, existingID)
+			if existingSvc.Connect != nil && !existingSvc.Connect.IsGateway() {
+				ops.deregServices = append(ops.deregServices,
+					MakeConnectProxyID(allocID, existing.Name, existingSvc.Name))
+			}
 			for _, check := range existingSvc.Checks {
 				ops.deregChecks = append(ops.deregChecks, makeCheckID(existingID, check))
 			}
 			continue
 		}
 
-		// PortLabel and AddressMode aren't included in the ID, so we
-		// have to compare manually.
-		serviceUnchanged := newSvc.PortLabel == existingSvc.PortLabel && newSvc.AddressMode == existingSvc.AddressMode
+		// PortLabel, AddressMode, Connect, Meta and TaggedAddresses aren't
+		// included in the ID, so we have to compare manually.
+		serviceUnchanged := newSvc.PortLabel == existingSvc.PortLabel &&
+			newSvc.AddressMode == existingSvc.AddressMode &&
+			reflect.DeepEqual(newSvc.Connect, existingSvc.Connect) &&
+			reflect.DeepEqual(newSvc.Meta, existingSvc.Meta) &&
+			reflect.DeepEqual(newSvc.TaggedAddresses, existingSvc.TaggedAddresses)
+		if existingSvc.Connect != nil && !existingSvc.Connect.IsGateway() &&
+			(newSvc.Connect == nil || newSvc.Connect.IsGateway()) {
+			// The sidecar proxy isn't used anymore so remove it
+			ops.deregServices = append(ops.deregServices,
+				MakeConnectProxyID(allocID, existing.Name, existingSvc.Name))
+		}
 		if serviceUnchanged {
 			// Service exists and hasn't changed, don't add it later
 			delete(newIDs, existingID)
@@ -607,10 +983,36 @@ func (c *ServiceClient) UpdateTask(allocID string, existing, newTask *structs.Ta
 	}
 
 	c.commit(ops)
+
+	// Stop watching the checks that were removed or don't restart their
+	// task anymore and watch the checks of the new task. CheckRestart
+	// fields aren't part of the check ID so unchanged checks are watched
+	// again to pick up their new configuration.
+	watched := make(map[string]struct{})
+	for _, service := range newTask.Services {
+		serviceID := makeTaskServiceID(allocID, newTask.Name, service)
+		for _, check := range service.Checks {
+			if check.TriggersRestarts() {
+				checkID := makeCheckID(serviceID, check)
+				watched[checkID] = struct{}{}
+				c.checkWatcher.Watch(allocID, newTask.Name, checkID, ops.scope, check, restarter)
+			}
+		}
+	}
+	for _, service := range existing.Services {
+		serviceID := makeTaskServiceID(allocID, existing.Name, service)
+		for _, check := range service.Checks {
+			checkID := makeCheckID(serviceID, check)
+			if _, ok := watched[checkID]; !ok && check.TriggersRestarts() {
+				c.checkWatcher.Unwatch(checkID)
+			}
+		}
+	}
 	return nil
 }
 
-// RemoveTask from Consul. Removes all service entries and checks.
+// RemoveTask from Consul. Removes all service entries and checks, from
+// whichever namespace and partition they were registered in.
 //
 // Actual communication with Consul is done asynchrously (see Run).
 func (c *ServiceClient) RemoveTask(allocID string, task *structs.Task) {
@@ -619,14 +1021,26 @@ func (c *ServiceClient) RemoveTask(allocID string, task *structs.Task) {
 	for _, service := range task.Services {
 		id := makeTaskServiceID(allocID, task.Name, service)
 		ops.deregServices = append(ops.deregServices, id)
+		if service.Connect != nil && !service.Connect.IsGateway() {
+			ops.deregServices = append(ops.deregServices, MakeConnectProxyID(allocID, task.Name, service.Name))
+		}
 
 		for _, check := range service.Checks {
-			ops.deregChecks = append(ops.deregChecks, makeCheckID(id, check))
+			cid := makeCheckID(id, check)
+			ops.deregChecks = append(ops.deregChecks, cid)
+
+			if check.TriggersRestarts() {
+				c.checkWatcher.Unwatch(cid)
+			}
 		}
 	}
 
 	// Now add them to the deregistration fields; main Run loop will update
 	c.commit(&ops)
+
+	// The services are deregistered with the tokens recorded when they were
+	// registered
+	c.SetServiceTokens(allocID, task.Name, nil)
 }
 
 // Checks returns the checks registered against the agent for the given
@@ -648,8 +1062,8 @@ func (c *ServiceClient) Checks(a *structs.Allocation) ([]*api.AgentCheck, error)
 		}
 	}
 
-	// Query all the checks
-	checks, err := c.client.Checks()
+	// Query all the checks of the namespace and partition of the group
+	checks, err := c.agent(scopeOf(tg.Consul)).Checks()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,136 @@
+package consul
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// ingressGatewayKind, terminatingGatewayKind and meshGatewayKind are the
+	// Consul service and configuration entry kinds of the Connect gateways
+	ingressGatewayKind     = "ingress-gateway"
+	terminatingGatewayKind = "terminating-gateway"
+	meshGatewayKind        = "mesh-gateway"
+)
+
+// ConfigEntriesAPI is the Consul configuration entries API used by Nomad
+// servers to write the configuration of the Connect gateways of the jobs.
+type ConfigEntriesAPI interface {
+	// SetIngressGatewayConfigEntry writes the configuration entry of the
+	// ingress gateway service in the Consul namespace and partition.
+	SetIngressGatewayConfigEntry(consul *structs.Consul, service string, entry *structs.ConsulIngressConfigEntry) error
+
+	// SetTerminatingGatewayConfigEntry writes the configuration entry of the
+	// terminating gateway service in the Consul namespace and partition.
+	SetTerminatingGatewayConfigEntry(consul *structs.Consul, service string, entry *structs.ConsulTerminatingConfigEntry) error
+}
+
+// configEntriesClient implements ConfigEntriesAPI against the Consul HTTP
+// API. The vendored Consul API client predates configuration entries so the
+// endpoint is called directly.
+type configEntriesClient struct {
+	httpClient
+}
+
+// NewConfigEntriesClient returns a ConfigEntriesAPI using the given Consul
+// client configuration. The configuration must have been used to create an
+// api.Client so that its HTTP client and address are set.
+func NewConfigEntriesClient(config *api.Config) ConfigEntriesAPI {
+	return &configEntriesClient{httpClient{config: config}}
+}
+
+// ingressGatewayConfigEntry is the Consul ingress gateway configuration
+// entry.
+type ingressGatewayConfigEntry struct {
+	Kind      string
+	Name      string
+	Namespace string `json:",omitempty"`
+	Partition string `json:",omitempty"`
+	Listeners []*ingressListener
+}
+
+type ingressListener struct {
+	Port     int
+	Protocol string
+	Services []*ingressService
+}
+
+type ingressService struct {
+	Name  string
+	Hosts []string `json:",omitempty"`
+}
+
+// terminatingGatewayConfigEntry is the Consul terminating gateway
+// configuration entry.
+type terminatingGatewayConfigEntry struct {
+	Kind      string
+	Name      string
+	Namespace string `json:",omitempty"`
+	Partition string `json:",omitempty"`
+	Services  []*linkedService
+}
+
+type linkedService struct {
+	Name     string
+	CAFile   string `json:",omitempty"`
+	CertFile string `json:",omitempty"`
+	KeyFile  string `json:",omitempty"`
+	SNI      string `json:",omitempty"`
+}
+
+func (c *configEntriesClient) SetIngressGatewayConfigEntry(consul *structs.Consul, service string, entry *structs.ConsulIngressConfigEntry) error {
+	in := &ingressGatewayConfigEntry{
+		Kind:      ingressGatewayKind,
+		Name:      service,
+		Namespace: consul.GetNamespace(),
+		Partition: consul.GetPartition(),
+	}
+	for _, l := range entry.Listeners {
+		listener := &ingressListener{
+			Port:     l.Port,
+			Protocol: l.Protocol,
+		}
+		for _, s := range l.Services {
+			listener.Services = append(listener.Services, &ingressService{
+				Name:  s.Name,
+				Hosts: s.Hosts,
+			})
+		}
+		in.Listeners = append(in.Listeners, listener)
+	}
+	return c.set(in.Kind, service, in)
+}
+
+func (c *configEntriesClient) SetTerminatingGatewayConfigEntry(consul *structs.Consul, service string, entry *structs.ConsulTerminatingConfigEntry) error {
+	in := &terminatingGatewayConfigEntry{
+		Kind:      terminatingGatewayKind,
+		Name:      service,
+		Namespace: consul.GetNamespace(),
+		Partition: consul.GetPartition(),
+	}
+	for _, s := range entry.Services {
+		in.Services = append(in.Services, &linkedService{
+			Name:     s.Name,
+			CAFile:   s.CAFile,
+			CertFile: s.CertFile,
+			KeyFile:  s.KeyFile,
+			SNI:      s.SNI,
+		})
+	}
+	return c.set(in.Kind, service, in)
+}
+
+// set writes the configuration entry.
+func (c *configEntriesClient) set(kind, service string, entry interface{}) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := c.do("PUT", "/v1/config", nil, body, nil); err != nil {
+		return fmt.Errorf("failed to set %s configuration entry for %q: %v", kind, service, err)
+	}
+	return nil
+}
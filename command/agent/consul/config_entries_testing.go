@@ -0,0 +1,72 @@
+package consul
+
+import (
+	"log"
+	"sync"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// MockConfigEntries can be used for testing where the ConfigEntriesAPI is
+// needed. The entries are kept in memory keyed by service name.
+type MockConfigEntries struct {
+	logger *log.Logger
+
+	ingress     map[string]*structs.ConsulIngressConfigEntry
+	terminating map[string]*structs.ConsulTerminatingConfigEntry
+	err         error
+	l           sync.Mutex
+}
+
+func NewMockConfigEntries(l *log.Logger) *MockConfigEntries {
+	return &MockConfigEntries{
+		logger:      l,
+		ingress:     make(map[string]*structs.ConsulIngressConfigEntry),
+		terminating: make(map[string]*structs.ConsulTerminatingConfigEntry),
+	}
+}
+
+// SetError makes all subsequent calls fail with the given error. A nil error
+// clears it.
+func (m *MockConfigEntries) SetError(err error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	m.err = err
+}
+
+// Ingress returns the ingress gateway configuration entry of the service.
+func (m *MockConfigEntries) Ingress(service string) *structs.ConsulIngressConfigEntry {
+	m.l.Lock()
+	defer m.l.Unlock()
+	return m.ingress[service]
+}
+
+// Terminating returns the terminating gateway configuration entry of the
+// service.
+func (m *MockConfigEntries) Terminating(service string) *structs.ConsulTerminatingConfigEntry {
+	m.l.Lock()
+	defer m.l.Unlock()
+	return m.terminating[service]
+}
+
+func (m *MockConfigEntries) SetIngressGatewayConfigEntry(consul *structs.Consul, service string, entry *structs.ConsulIngressConfigEntry) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.ingress[service] = entry.Copy()
+	m.logger.Printf("[DEBUG] mock_consul: SetIngressGatewayConfigEntry(%q, %q) -> nil", consul.GetNamespace(), service)
+	return nil
+}
+
+func (m *MockConfigEntries) SetTerminatingGatewayConfigEntry(consul *structs.Consul, service string, entry *structs.ConsulTerminatingConfigEntry) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.terminating[service] = entry.Copy()
+	m.logger.Printf("[DEBUG] mock_consul: SetTerminatingGatewayConfigEntry(%q, %q) -> nil", consul.GetNamespace(), service)
+	return nil
+}
@@ -0,0 +1,204 @@
+package consul
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// connectProxyKind is the Consul service kind of Connect sidecar proxies
+	connectProxyKind = "connect-proxy"
+
+	// connectProxySuffix is appended to the name and ID of a service to form
+	// the name and ID of its sidecar proxy
+	connectProxySuffix = "sidecar-proxy"
+)
+
+// ConnectAPI registers Connect sidecar proxies and gateways with the Consul
+// agent. The
+// vendored Consul API predates Connect so proxies are registered with the raw
+// agent endpoint. Proxies are deregistered like any other service. Services
+// with metadata or tagged addresses, which the vendored API lacks too, are
+// registered the same way.
+type ConnectAPI interface {
+	ProxyRegister(reg *ProxyRegistration) error
+}
+
+// ProxyRegistration is the registration of a Connect sidecar proxy or gateway
+// service, or of a service with metadata or tagged addresses.
+type ProxyRegistration struct {
+	ID              string
+	Name            string
+	Kind            string   `json:",omitempty"`
+	Tags            []string `json:",omitempty"`
+	Address         string
+	Port            int
+	Meta            map[string]string         `json:",omitempty"`
+	TaggedAddresses map[string]ServiceAddress `json:",omitempty"`
+	Proxy           *ProxyConfig              `json:",omitempty"`
+	Namespace       string                    `json:",omitempty"`
+	Partition       string                    `json:",omitempty"`
+}
+
+// ServiceAddress is a tagged address of a service.
+type ServiceAddress struct {
+	Address string
+	Port    int
+}
+
+// setScope sets the Consul namespace and partition of the registration.
+func (p *ProxyRegistration) setScope(scope consulScope) {
+	p.Namespace = scope.namespace
+	p.Partition = scope.partition
+}
+
+// ProxyConfig configures the service a sidecar proxy fronts and the
+// upstreams it exposes, or the Envoy options of a gateway.
+type ProxyConfig struct {
+	DestinationServiceName string                 `json:",omitempty"`
+	DestinationServiceID   string                 `json:",omitempty"`
+	LocalServiceAddress    string                 `json:",omitempty"`
+	LocalServicePort       int                    `json:",omitempty"`
+	Upstreams              []*ProxyUpstream       `json:",omitempty"`
+	MeshGateway            *MeshGateway           `json:",omitempty"`
+	Config                 map[string]interface{} `json:",omitempty"`
+}
+
+// ProxyUpstream is a service the sidecar proxy exposes locally.
+type ProxyUpstream struct {
+	DestinationName      string
+	DestinationNamespace string `json:",omitempty"`
+	Datacenter           string `json:",omitempty"`
+	LocalBindAddress     string `json:",omitempty"`
+	LocalBindPort        int
+	MeshGateway          *MeshGateway `json:",omitempty"`
+}
+
+// MeshGateway is the mesh gateway mode of a proxy or upstream.
+type MeshGateway struct {
+	Mode string `json:",omitempty"`
+}
+
+// connectClient implements ConnectAPI using the raw Consul API.
+type connectClient struct {
+	raw *api.Raw
+}
+
+// NewConnectClient returns a ConnectAPI backed by the given Consul client.
+func NewConnectClient(client *api.Client) ConnectAPI {
+	return &connectClient{raw: client.Raw()}
+}
+
+func (c *connectClient) ProxyRegister(reg *ProxyRegistration) error {
+	_, err := c.raw.Write("/v1/agent/service/register", reg, nil, nil)
+	return err
+}
+
+// MakeConnectProxyID returns the Consul service ID of the sidecar proxy of a
+// Connect service.
+//
+//	Example Proxy ID: _nomad-executor-1234-web-echo-sidecar-proxy
+func MakeConnectProxyID(allocID, taskName, serviceName string) string {
+	return fmt.Sprintf("%s-executor-%s-%s-%s-%s",
+		nomadServicePrefix, allocID, taskName, serviceName, connectProxySuffix)
+}
+
+// MakeConnectGatewayID returns the Consul service ID of a Connect gateway.
+// Gateways are registered under the ID of the service defining them.
+func MakeConnectGatewayID(allocID, taskName string, service *structs.Service) string {
+	return makeTaskServiceID(allocID, taskName, service)
+}
+
+// connectProxyReg returns the registration of the sidecar proxy of the
+// service registered with serviceReg. The proxy listens on the host address
+// of the port reserved for it on the task.
+func connectProxyReg(allocID string, task *structs.Task, service *structs.Service,
+	serviceReg *api.AgentServiceRegistration) (*ProxyRegistration, error) {
+
+	label := structs.ConnectProxyPortLabel(service.Name)
+	ip, port := task.Resources.Networks.Port(label)
+	if port == 0 {
+		return nil, fmt.Errorf("no port %q for the sidecar proxy of service %q", label, service.Name)
+	}
+
+	config := &ProxyConfig{
+		DestinationServiceName: service.Name,
+		DestinationServiceID:   serviceReg.ID,
+		LocalServiceAddress:    serviceReg.Address,
+		LocalServicePort:       serviceReg.Port,
+	}
+	if p := service.Connect.SidecarService.Proxy; p != nil {
+		for _, u := range p.Upstreams {
+			config.Upstreams = append(config.Upstreams, &ProxyUpstream{
+				DestinationName:      u.DestinationName,
+				DestinationNamespace: u.DestinationNamespace,
+				Datacenter:           u.Datacenter,
+				LocalBindAddress:     u.LocalBindAddress,
+				LocalBindPort:        u.LocalBindPort,
+				MeshGateway:          meshGateway(u.MeshGateway),
+			})
+		}
+		config.MeshGateway = meshGateway(p.MeshGateway)
+	}
+
+	return &ProxyRegistration{
+		ID:      MakeConnectProxyID(allocID, task.Name, service.Name),
+		Name:    fmt.Sprintf("%s-%s", service.Name, connectProxySuffix),
+		Kind:    connectProxyKind,
+		Address: ip,
+		Port:    port,
+		Proxy:   config,
+	}, nil
+}
+
+// meshGateway converts the mesh gateway mode of a proxy or upstream.
+func meshGateway(g *structs.ConsulMeshGateway) *MeshGateway {
+	if g == nil {
+		return nil
+	}
+	return &MeshGateway{Mode: g.Mode}
+}
+
+// connectGatewayReg returns the registration of the gateway service whose
+// plain registration is serviceReg.
+func connectGatewayReg(service *structs.Service, serviceReg *api.AgentServiceRegistration) *ProxyRegistration {
+	gateway := service.Connect.Gateway
+	reg := &ProxyRegistration{
+		ID:      serviceReg.ID,
+		Name:    serviceReg.Name,
+		Tags:    serviceReg.Tags,
+		Address: serviceReg.Address,
+		Port:    serviceReg.Port,
+	}
+	switch {
+	case gateway.Ingress != nil:
+		reg.Kind = ingressGatewayKind
+	case gateway.Terminating != nil:
+		reg.Kind = terminatingGatewayKind
+	case gateway.Mesh != nil:
+		reg.Kind = meshGatewayKind
+	}
+	if gateway.Proxy != nil && gateway.Proxy.ConnectTimeout != nil {
+		reg.Proxy = &ProxyConfig{
+			Config: map[string]interface{}{
+				"connect_timeout_ms": int(*gateway.Proxy.ConnectTimeout / time.Millisecond),
+			},
+		}
+	}
+	return reg
+}
+
+// agentServiceReg returns the plain registration of the proxy used to diff
+// the local state against the services registered in Consul.
+func (p *ProxyRegistration) agentServiceReg() *api.AgentServiceRegistration {
+	return &api.AgentServiceRegistration{
+		ID:      p.ID,
+		Name:    p.Name,
+		Tags:    p.Tags,
+		Address: p.Address,
+		Port:    p.Port,
+	}
+}
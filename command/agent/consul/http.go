@@ -0,0 +1,76 @@
+package consul
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// httpClient calls the Consul HTTP API endpoints the vendored Consul API
+// client doesn't support, using the configuration of an api.Client.
+type httpClient struct {
+	config *api.Config
+}
+
+// do sends a request with the optional query parameters to the Consul agent
+// and decodes the JSON response into out if it isn't nil.
+func (c *httpClient) do(method, path string, query url.Values, body []byte, out interface{}) error {
+	u := &url.URL{
+		Scheme: c.config.Scheme,
+		Host:   c.config.Address,
+		Path:   path,
+	}
+	if query == nil {
+		query = make(url.Values)
+	}
+	if c.config.Datacenter != "" {
+		query.Set("dc", c.config.Datacenter)
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if c.config.Token != "" {
+		req.Header.Set("X-Consul-Token", c.config.Token)
+	}
+
+	client := c.config.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response code: %d (%s)", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// scopeQuery returns the query parameters selecting a Consul Enterprise
+// namespace and admin partition. Empty values are omitted so that Consul
+// uses the ones of the token.
+func scopeQuery(namespace, partition string) url.Values {
+	query := make(url.Values)
+	if namespace != "" {
+		query.Set("ns", namespace)
+	}
+	if partition != "" {
+		query.Set("partition", partition)
+	}
+	return query
+}
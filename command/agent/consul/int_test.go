@@ -131,14 +131,15 @@ func TestConsul_Integration(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error creating consul client: %v", err)
 	}
-	serviceClient := consul.NewServiceClient(consulClient.Agent(), true, logger)
+	serviceClient := consul.NewServiceClient(consulClient.Agent(), consul.NewConnectClient(consulClient),
+		consul.NewNamespacesClient(consulConfig), true, logger)
 	defer serviceClient.Shutdown() // just-in-case cleanup
 	consulRan := make(chan struct{})
 	go func() {
 		serviceClient.Run()
 		close(consulRan)
 	}()
-	tr := client.NewTaskRunner(logger, conf, db, logUpdate, taskDir, alloc, task, vclient, serviceClient)
+	tr := client.NewTaskRunner(logger, conf, db, logUpdate, taskDir, alloc, task, vclient, serviceClient, nil)
 	tr.MarkReceived()
 	go tr.Run()
 	defer func() {
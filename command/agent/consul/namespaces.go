@@ -0,0 +1,149 @@
+package consul
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// NamespacesAPI returns the Consul agent API of the services and checks of a
+// Consul Enterprise namespace and admin partition.
+type NamespacesAPI interface {
+	Agent(namespace, partition string) AgentAPI
+
+	// TokenAgent returns the agent API of the namespace and partition
+	// authenticating with the given ACL token instead of the token of the
+	// Nomad agent.
+	TokenAgent(namespace, partition, token string) ServiceAgentAPI
+}
+
+// ServiceAgentAPI registers the services, sidecar proxies and checks of a
+// service with the Service Identity token of the service.
+type ServiceAgentAPI interface {
+	AgentAPI
+	ConnectAPI
+}
+
+// consulScope is a Consul namespace and admin partition. The zero value is
+// the namespace and partition of the token of the Nomad agent.
+type consulScope struct {
+	namespace string
+	partition string
+}
+
+// scopeOf returns the scope of the Consul configuration of a task group.
+func scopeOf(consul *structs.Consul) consulScope {
+	return consulScope{
+		namespace: consul.GetNamespace(),
+		partition: consul.GetPartition(),
+	}
+}
+
+// isDefault returns whether the scope is the one of the agent's token.
+func (s consulScope) isDefault() bool {
+	return s == consulScope{}
+}
+
+// namespacesClient implements NamespacesAPI against the Consul HTTP API. The
+// vendored Consul API client predates namespaces so the agent endpoints are
+// called directly with the namespace and partition query parameters.
+type namespacesClient struct {
+	httpClient
+}
+
+// NewNamespacesClient returns a NamespacesAPI using the given Consul client
+// configuration. The configuration must have been used to create an
+// api.Client so that its HTTP client and address are set.
+func NewNamespacesClient(config *api.Config) NamespacesAPI {
+	return &namespacesClient{httpClient{config: config}}
+}
+
+func (c *namespacesClient) Agent(namespace, partition string) AgentAPI {
+	return &namespacedAgent{
+		httpClient: c.httpClient,
+		namespace:  namespace,
+		partition:  partition,
+	}
+}
+
+func (c *namespacesClient) TokenAgent(namespace, partition, token string) ServiceAgentAPI {
+	config := *c.config
+	config.Token = token
+	return &namespacedAgent{
+		httpClient: httpClient{config: &config},
+		namespace:  namespace,
+		partition:  partition,
+	}
+}
+
+// namespacedAgent implements AgentAPI within a namespace and partition.
+type namespacedAgent struct {
+	httpClient
+	namespace string
+	partition string
+}
+
+func (a *namespacedAgent) query() url.Values {
+	return scopeQuery(a.namespace, a.partition)
+}
+
+// put sends a PUT request with the JSON encoding of in as the body.
+func (a *namespacedAgent) put(path string, in interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	return a.do("PUT", path, a.query(), body, nil)
+}
+
+func (a *namespacedAgent) Services() (map[string]*api.AgentService, error) {
+	var out map[string]*api.AgentService
+	if err := a.do("GET", "/v1/agent/services", a.query(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (a *namespacedAgent) Checks() (map[string]*api.AgentCheck, error) {
+	var out map[string]*api.AgentCheck
+	if err := a.do("GET", "/v1/agent/checks", a.query(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (a *namespacedAgent) CheckRegister(check *api.AgentCheckRegistration) error {
+	return a.put("/v1/agent/check/register", check)
+}
+
+func (a *namespacedAgent) CheckDeregister(checkID string) error {
+	return a.put("/v1/agent/check/deregister/"+url.PathEscape(checkID), nil)
+}
+
+func (a *namespacedAgent) ServiceRegister(service *api.AgentServiceRegistration) error {
+	return a.put("/v1/agent/service/register", service)
+}
+
+func (a *namespacedAgent) ProxyRegister(reg *ProxyRegistration) error {
+	return a.put("/v1/agent/service/register", reg)
+}
+
+func (a *namespacedAgent) ServiceDeregister(serviceID string) error {
+	return a.put("/v1/agent/service/deregister/"+url.PathEscape(serviceID), nil)
+}
+
+func (a *namespacedAgent) UpdateTTL(id, output, status string) error {
+	update := struct {
+		Status string
+		Output string
+	}{
+		Status: status,
+		Output: output,
+	}
+	return a.put("/v1/agent/check/update/"+url.PathEscape(id), &update)
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/hashicorp/consul/api"
 	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -99,7 +100,7 @@ func (t *testFakeCtx) syncOnce() error {
 func setupFake() *testFakeCtx {
 	fc := newFakeConsul()
 	return &testFakeCtx{
-		ServiceClient: NewServiceClient(fc, true, testLogger()),
+		ServiceClient: NewServiceClient(fc, fc, fc, true, testLogger()),
 		FakeConsul:    fc,
 		Task:          testTask(),
 		execs:         make(chan int, 100),
@@ -110,6 +111,7 @@ func setupFake() *testFakeCtx {
 type fakeConsul struct {
 	// maps of what services and checks have been registered
 	services map[string]*api.AgentServiceRegistration
+	proxies  map[string]*ProxyRegistration
 	checks   map[string]*api.AgentCheckRegistration
 	mu       sync.Mutex
 
@@ -118,17 +120,84 @@ type fakeConsul struct {
 
 	// What check status to return from Checks()
 	checkStatus string
+
+	// namespaces are the fake backends of the Consul namespaces
+	namespaces map[string]*fakeConsul
+
+	// tokens are the ACL tokens services and checks were registered with
+	// other than the token of the agent, by ID
+	tokens map[string]string
 }
 
 func newFakeConsul() *fakeConsul {
 	return &fakeConsul{
 		services:    make(map[string]*api.AgentServiceRegistration),
+		proxies:     make(map[string]*ProxyRegistration),
 		checks:      make(map[string]*api.AgentCheckRegistration),
 		checkTTLs:   make(map[string]int),
 		checkStatus: api.HealthPassing,
+		namespaces:  make(map[string]*fakeConsul),
+		tokens:      make(map[string]string),
+	}
+}
+
+// Agent returns the fake backend of the namespace, ignoring partitions.
+func (c *fakeConsul) Agent(namespace, partition string) AgentAPI {
+	return c.namespace(namespace)
+}
+
+// TokenAgent returns the fake backend of the namespace recording the token
+// services and checks are registered with.
+func (c *fakeConsul) TokenAgent(namespace, partition, token string) ServiceAgentAPI {
+	return &fakeTokenAgent{
+		fakeConsul: c.namespace(namespace),
+		root:       c,
+		token:      token,
 	}
 }
 
+// fakeTokenAgent is a fakeConsul used with an ACL token.
+type fakeTokenAgent struct {
+	*fakeConsul
+	root  *fakeConsul
+	token string
+}
+
+func (a *fakeTokenAgent) setToken(id string) {
+	a.root.mu.Lock()
+	defer a.root.mu.Unlock()
+	a.root.tokens[id] = a.token
+}
+
+func (a *fakeTokenAgent) ServiceRegister(service *api.AgentServiceRegistration) error {
+	a.setToken(service.ID)
+	return a.fakeConsul.ServiceRegister(service)
+}
+
+func (a *fakeTokenAgent) ProxyRegister(proxy *ProxyRegistration) error {
+	a.setToken(proxy.ID)
+	return a.root.ProxyRegister(proxy)
+}
+
+func (a *fakeTokenAgent) CheckRegister(check *api.AgentCheckRegistration) error {
+	a.setToken(check.ID)
+	return a.fakeConsul.CheckRegister(check)
+}
+
+func (c *fakeConsul) namespace(namespace string) *fakeConsul {
+	if namespace == "" {
+		return c
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ns, ok := c.namespaces[namespace]
+	if !ok {
+		ns = newFakeConsul()
+		c.namespaces[namespace] = ns
+	}
+	return ns
+}
+
 func (c *fakeConsul) Services() (map[string]*api.AgentService, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -189,6 +258,7 @@ func (c *fakeConsul) ServiceRegister(service *api.AgentServiceRegistration) erro
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.services[service.ID] = service
+	delete(c.proxies, service.ID)
 	return nil
 }
 
@@ -196,6 +266,16 @@ func (c *fakeConsul) ServiceDeregister(serviceID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	delete(c.services, serviceID)
+	delete(c.proxies, serviceID)
+	return nil
+}
+
+func (c *fakeConsul) ProxyRegister(proxy *ProxyRegistration) error {
+	c = c.namespace(proxy.Namespace)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.services[proxy.ID] = proxy.agentServiceReg()
+	c.proxies[proxy.ID] = proxy
 	return nil
 }
 
@@ -215,7 +295,7 @@ func (c *fakeConsul) UpdateTTL(id string, output string, status string) error {
 func TestConsul_ChangeTags(t *testing.T) {
 	ctx := setupFake()
 
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, nil, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -241,7 +321,7 @@ func TestConsul_ChangeTags(t *testing.T) {
 	origTask := ctx.Task
 	ctx.Task = testTask()
 	ctx.Task.Services[0].Tags[0] = "newtag"
-	if err := ctx.ServiceClient.UpdateTask("allocid", origTask, ctx.Task, nil, nil); err != nil {
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, origTask, ctx.Task, nil, nil, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 	if err := ctx.syncOnce(); err != nil {
@@ -295,7 +375,7 @@ func TestConsul_ChangePorts(t *testing.T) {
 		},
 	}
 
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -383,7 +463,7 @@ func TestConsul_ChangePorts(t *testing.T) {
 			// Removed PortLabel; should default to service's (y)
 		},
 	}
-	if err := ctx.ServiceClient.UpdateTask("allocid", origTask, ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, origTask, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 	if err := ctx.syncOnce(); err != nil {
@@ -461,7 +541,7 @@ func TestConsul_ChangeChecks(t *testing.T) {
 		},
 	}
 
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -512,7 +592,7 @@ func TestConsul_ChangeChecks(t *testing.T) {
 			PortLabel: "x",
 		},
 	}
-	if err := ctx.ServiceClient.UpdateTask("allocid", origTask, ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, origTask, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 	if err := ctx.syncOnce(); err != nil {
@@ -551,7 +631,7 @@ func TestConsul_ChangeChecks(t *testing.T) {
 func TestConsul_RegServices(t *testing.T) {
 	ctx := setupFake()
 
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, nil, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -577,7 +657,7 @@ func TestConsul_RegServices(t *testing.T) {
 	// Make a change which will register a new service
 	ctx.Task.Services[0].Name = "taskname-service2"
 	ctx.Task.Services[0].Tags[0] = "tag3"
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, nil, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
 		t.Fatalf("unpexpected error registering task: %v", err)
 	}
 
@@ -630,6 +710,410 @@ func TestConsul_RegServices(t *testing.T) {
 	}
 }
 
+// TestConsul_ConnectProxy asserts the sidecar proxy of a Connect service is
+// registered and deregistered along with the service.
+func TestConsul_ConnectProxy(t *testing.T) {
+	ctx := setupFake()
+	const proxyPort = 1236
+	ctx.Task.Resources.Networks[0].DynamicPorts = append(ctx.Task.Resources.Networks[0].DynamicPorts,
+		structs.Port{Label: structs.ConnectProxyPortLabel("taskname-service"), Value: proxyPort})
+	ctx.Task.Services[0].Connect = &structs.ConsulConnect{
+		SidecarService: &structs.ConsulSidecarService{
+			Proxy: &structs.ConsulProxy{
+				Upstreams: []*structs.ConsulUpstream{
+					{DestinationName: "db", LocalBindPort: 5432},
+					{
+						DestinationName:  "cache",
+						Datacenter:       "dc2",
+						LocalBindAddress: "127.0.0.2",
+						LocalBindPort:    6379,
+						MeshGateway:      &structs.ConsulMeshGateway{Mode: structs.ConsulMeshGatewayModeRemote},
+					},
+				},
+				MeshGateway: &structs.ConsulMeshGateway{Mode: structs.ConsulMeshGatewayModeLocal},
+			},
+		},
+	}
+
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error registering task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+
+	if n := len(ctx.FakeConsul.services); n != 2 {
+		t.Fatalf("expected 2 services but found %d:\n%#v", n, ctx.FakeConsul.services)
+	}
+	proxyID := MakeConnectProxyID("allocid", ctx.Task.Name, "taskname-service")
+	proxy, ok := ctx.FakeConsul.proxies[proxyID]
+	if !ok {
+		t.Fatalf("proxy %q not registered: %#v", proxyID, ctx.FakeConsul.proxies)
+	}
+	if proxy.Kind != "connect-proxy" || proxy.Name != "taskname-service-sidecar-proxy" || proxy.Port != proxyPort {
+		t.Fatalf("bad proxy: %#v", proxy)
+	}
+	serviceID := makeTaskServiceID("allocid", ctx.Task.Name, ctx.Task.Services[0])
+	if proxy.Proxy.DestinationServiceID != serviceID || proxy.Proxy.LocalServicePort != xPort {
+		t.Fatalf("bad proxy config: %#v", proxy.Proxy)
+	}
+	if len(proxy.Proxy.Upstreams) != 2 || proxy.Proxy.Upstreams[0].LocalBindPort != 5432 {
+		t.Fatalf("bad upstreams: %#v", proxy.Proxy.Upstreams)
+	}
+	if u := proxy.Proxy.Upstreams[1]; u.Datacenter != "dc2" || u.LocalBindAddress != "127.0.0.2" ||
+		u.MeshGateway == nil || u.MeshGateway.Mode != "remote" {
+		t.Fatalf("bad cross datacenter upstream: %#v", u)
+	}
+	if proxy.Proxy.MeshGateway == nil || proxy.Proxy.MeshGateway.Mode != "local" {
+		t.Fatalf("bad mesh gateway: %#v", proxy.Proxy.MeshGateway)
+	}
+
+	// Disabling Connect removes the proxy but keeps the service
+	origTask := ctx.Task.Copy()
+	ctx.Task.Services[0].Connect = nil
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, origTask, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error updating task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+	if n := len(ctx.FakeConsul.services); n != 1 {
+		t.Fatalf("expected 1 service but found %d:\n%#v", n, ctx.FakeConsul.services)
+	}
+	if _, ok := ctx.FakeConsul.services[serviceID]; !ok {
+		t.Fatalf("service %q not registered: %#v", serviceID, ctx.FakeConsul.services)
+	}
+
+	// Removing a task with a Connect service removes its proxy
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, origTask, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error registering task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+	if n := len(ctx.FakeConsul.proxies); n != 1 {
+		t.Fatalf("expected 1 proxy but found %d", n)
+	}
+	ctx.ServiceClient.RemoveTask("allocid", origTask)
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+	if n := len(ctx.FakeConsul.services); n != 0 {
+		t.Fatalf("expected 0 services but found %d:\n%#v", n, ctx.FakeConsul.services)
+	}
+}
+
+func TestConsul_ConnectGateway(t *testing.T) {
+	ctx := setupFake()
+	ctx.Task.Services[0].Connect = &structs.ConsulConnect{
+		Gateway: &structs.ConsulGateway{
+			Proxy: &structs.ConsulGatewayProxy{
+				ConnectTimeout: helper.TimeToPtr(3 * time.Second),
+			},
+			Mesh: &structs.ConsulMeshConfigEntry{},
+		},
+	}
+
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error registering task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+
+	// The gateway is registered in place of the service
+	if n := len(ctx.FakeConsul.services); n != 1 {
+		t.Fatalf("expected 1 service but found %d:\n%#v", n, ctx.FakeConsul.services)
+	}
+	gatewayID := MakeConnectGatewayID("allocid", ctx.Task.Name, ctx.Task.Services[0])
+	gateway, ok := ctx.FakeConsul.proxies[gatewayID]
+	if !ok {
+		t.Fatalf("gateway %q not registered: %#v", gatewayID, ctx.FakeConsul.proxies)
+	}
+	if gateway.Kind != "mesh-gateway" || gateway.Name != "taskname-service" || gateway.Port != xPort {
+		t.Fatalf("bad gateway: %#v", gateway)
+	}
+	if gateway.Proxy == nil || gateway.Proxy.Config["connect_timeout_ms"] != 3000 {
+		t.Fatalf("bad gateway config: %#v", gateway.Proxy)
+	}
+
+	// Turning the gateway into a plain service reregisters it
+	origTask := ctx.Task.Copy()
+	ctx.Task.Services[0].Connect = nil
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, origTask, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error updating task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+	if n := len(ctx.FakeConsul.proxies); n != 0 {
+		t.Fatalf("expected 0 proxies but found %d:\n%#v", n, ctx.FakeConsul.proxies)
+	}
+	if _, ok := ctx.FakeConsul.services[gatewayID]; !ok {
+		t.Fatalf("service %q not registered: %#v", gatewayID, ctx.FakeConsul.services)
+	}
+}
+
+// TestConsul_ServiceMeta asserts services with metadata or tagged addresses
+// are registered raw and reregistered when they change.
+func TestConsul_ServiceMeta(t *testing.T) {
+	ctx := setupFake()
+	ctx.Task.Services[0].Meta = map[string]string{"version": "1"}
+	ctx.Task.Services[0].TaggedAddresses = map[string]string{
+		"lan": "10.0.0.1",
+		"wan": "203.0.113.10:8080",
+	}
+
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error registering task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+
+	id := makeTaskServiceID("allocid", ctx.Task.Name, ctx.Task.Services[0])
+	reg, ok := ctx.FakeConsul.proxies[id]
+	if !ok {
+		t.Fatalf("service %q not registered raw: %#v", id, ctx.FakeConsul.proxies)
+	}
+	if reg.Kind != "" || reg.Meta["version"] != "1" {
+		t.Fatalf("bad registration: %#v", reg)
+	}
+	expected := map[string]ServiceAddress{
+		"lan": {Address: "10.0.0.1", Port: xPort},
+		"wan": {Address: "203.0.113.10", Port: 8080},
+	}
+	if !reflect.DeepEqual(reg.TaggedAddresses, expected) {
+		t.Fatalf("expected tagged addresses %#v; got %#v", expected, reg.TaggedAddresses)
+	}
+
+	// Changing the metadata reregisters the service
+	origTask := ctx.Task.Copy()
+	ctx.Task.Services[0].Meta["version"] = "2"
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, origTask, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error updating task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+	if v := ctx.FakeConsul.proxies[id].Meta["version"]; v != "2" {
+		t.Fatalf("expected version 2; got %q", v)
+	}
+
+	// Removing them registers a plain service
+	origTask = ctx.Task.Copy()
+	ctx.Task.Services[0].Meta = nil
+	ctx.Task.Services[0].TaggedAddresses = nil
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, origTask, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error updating task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+	if n := len(ctx.FakeConsul.proxies); n != 0 {
+		t.Fatalf("expected 0 proxies but found %d:\n%#v", n, ctx.FakeConsul.proxies)
+	}
+	if _, ok := ctx.FakeConsul.services[id]; !ok {
+		t.Fatalf("service %q not registered: %#v", id, ctx.FakeConsul.services)
+	}
+
+	// Invalid tagged addresses fail the registration
+	ctx.Task.Services[0].TaggedAddresses = map[string]string{"wan": "203.0.113.10:http"}
+	if err := ctx.ServiceClient.RegisterTask("allocid2", nil, ctx.Task, nil, nil, nil); err == nil {
+		t.Fatalf("expected an error registering invalid tagged addresses")
+	}
+}
+
+// TestConsul_ServiceDualStack asserts the services of dual stack hosts are
+// tagged with the addresses of both families.
+func TestConsul_ServiceDualStack(t *testing.T) {
+	ctx := setupFake()
+	ctx.Task.Resources.Networks[0].IP = "10.0.0.1"
+	ctx.Task.Resources.Networks[0].IPv6 = "2001:db8::1"
+	ctx.Task.Services[0].TaggedAddresses = map[string]string{"lan_ipv6": "2001:db8::2"}
+
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error registering task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+
+	id := makeTaskServiceID("allocid", ctx.Task.Name, ctx.Task.Services[0])
+	reg, ok := ctx.FakeConsul.proxies[id]
+	if !ok {
+		t.Fatalf("service %q not registered raw: %#v", id, ctx.FakeConsul.proxies)
+	}
+	if reg.Address != "10.0.0.1" {
+		t.Fatalf("bad address: %q", reg.Address)
+	}
+	expected := map[string]ServiceAddress{
+		"lan_ipv4": {Address: "10.0.0.1", Port: xPort},
+		"lan_ipv6": {Address: "2001:db8::2", Port: xPort},
+	}
+	if !reflect.DeepEqual(reg.TaggedAddresses, expected) {
+		t.Fatalf("expected tagged addresses %#v; got %#v", expected, reg.TaggedAddresses)
+	}
+}
+
+// TestConsul_ServiceTokens asserts services and their checks are registered
+// with the Service Identity tokens of the services and that services without
+// a token use the token of the agent.
+func TestConsul_ServiceTokens(t *testing.T) {
+	ctx := setupFake()
+	ctx.Task.Services[0].Checks = []*structs.ServiceCheck{
+		{
+			Name:      "c1",
+			Type:      "tcp",
+			Interval:  time.Second,
+			Timeout:   time.Second,
+			PortLabel: "x",
+		},
+	}
+	ctx.Task.Services = append(ctx.Task.Services, &structs.Service{
+		Name:      "other",
+		PortLabel: "y",
+	})
+
+	ctx.ServiceClient.SetServiceTokens("allocid", ctx.Task.Name, map[string]string{"taskname-service": "secret"})
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error registering task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+
+	id := makeTaskServiceID("allocid", ctx.Task.Name, ctx.Task.Services[0])
+	checkID := makeCheckID(id, ctx.Task.Services[0].Checks[0])
+	if n := len(ctx.FakeConsul.services); n != 2 {
+		t.Fatalf("expected 2 services but found %d:\n%#v", n, ctx.FakeConsul.services)
+	}
+
+	// The other service has no token and used the token of the agent
+	expected := map[string]string{id: "secret", checkID: "secret"}
+	if !reflect.DeepEqual(ctx.FakeConsul.tokens, expected) {
+		t.Fatalf("expected tokens %#v; got %#v", expected, ctx.FakeConsul.tokens)
+	}
+
+	// Removing the task forgets the tokens once deregistered
+	ctx.ServiceClient.RemoveTask("allocid", ctx.Task)
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+	if n := len(ctx.FakeConsul.services); n != 0 {
+		t.Fatalf("expected 0 services but found %d:\n%#v", n, ctx.FakeConsul.services)
+	}
+	if n := len(ctx.ServiceClient.serviceTokens); n != 0 {
+		t.Fatalf("expected 0 service tokens but found %d", n)
+	}
+	if token := ctx.ServiceClient.serviceToken("allocid", ctx.Task.Name, "taskname-service"); token != "" {
+		t.Fatalf("expected task tokens to be removed")
+	}
+}
+
+// TestConsul_Namespace asserts services and checks are registered in the
+// Consul namespace of their task group and removed from it.
+func TestConsul_Namespace(t *testing.T) {
+	ctx := setupFake()
+	ctx.Task.Services[0].Checks = []*structs.ServiceCheck{
+		{
+			Name:     "c1",
+			Type:     "tcp",
+			Interval: time.Second,
+			Timeout:  time.Second,
+		},
+	}
+	consul := &structs.Consul{Namespace: "team"}
+
+	if err := ctx.ServiceClient.RegisterTask("allocid", consul, ctx.Task, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error registering task: %v", err)
+	}
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+
+	ns := ctx.FakeConsul.namespace("team")
+	if n := len(ctx.FakeConsul.services); n != 0 {
+		t.Fatalf("expected 0 services in the default namespace but found %d:\n%#v", n, ctx.FakeConsul.services)
+	}
+	if n := len(ns.services); n != 1 {
+		t.Fatalf("expected 1 service but found %d:\n%#v", n, ns.services)
+	}
+	if n := len(ns.checks); n != 1 {
+		t.Fatalf("expected 1 check but found %d:\n%#v", n, ns.checks)
+	}
+
+	// Syncing again doesn't remove the services of other namespaces
+	if err := ctx.ServiceClient.sync(); err != nil {
+		t.Fatalf("unexpected error syncing: %v", err)
+	}
+	if n := len(ns.services); n != 1 {
+		t.Fatalf("expected 1 service but found %d:\n%#v", n, ns.services)
+	}
+
+	ctx.ServiceClient.RemoveTask("allocid", ctx.Task)
+	if err := ctx.syncOnce(); err != nil {
+		t.Fatalf("unexpected error syncing task: %v", err)
+	}
+	if n := len(ns.services); n != 0 {
+		t.Fatalf("expected 0 services but found %d:\n%#v", n, ns.services)
+	}
+	if n := len(ns.checks); n != 0 {
+		t.Fatalf("expected 0 checks but found %d:\n%#v", n, ns.checks)
+	}
+}
+
+// TestConsul_CheckRestart asserts checks with a check_restart stanza are
+// watched when registered and unwatched when removed.
+func TestConsul_CheckRestart(t *testing.T) {
+	ctx := setupFake()
+	ctx.Task.Services[0].Checks = []*structs.ServiceCheck{
+		{
+			Name:     "c1",
+			Type:     "tcp",
+			Interval: time.Second,
+			Timeout:  time.Second,
+			CheckRestart: &structs.CheckRestart{
+				Limit: 3,
+			},
+		},
+		{
+			Name:     "c2",
+			Type:     "tcp",
+			Interval: time.Second,
+			Timeout:  time.Second,
+		},
+	}
+
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, &fakeCheckRestarter{}, nil, nil); err != nil {
+		t.Fatalf("unexpected error registering task: %v", err)
+	}
+
+	serviceID := makeTaskServiceID("allocid", ctx.Task.Name, ctx.Task.Services[0])
+	checkID := makeCheckID(serviceID, ctx.Task.Services[0].Checks[0])
+	select {
+	case update := <-ctx.ServiceClient.checkWatcher.checkUpdateCh:
+		if update.remove || update.checkID != checkID {
+			t.Fatalf("expected watch of %q but found %#v", checkID, update)
+		}
+	default:
+		t.Fatalf("expected check to be watched")
+	}
+	if n := len(ctx.ServiceClient.checkWatcher.checkUpdateCh); n != 0 {
+		t.Fatalf("expected only 1 check to be watched but found %d", n+1)
+	}
+
+	ctx.ServiceClient.RemoveTask("allocid", ctx.Task)
+	select {
+	case update := <-ctx.ServiceClient.checkWatcher.checkUpdateCh:
+		if !update.remove || update.checkID != checkID {
+			t.Fatalf("expected unwatch of %q but found %#v", checkID, update)
+		}
+	default:
+		t.Fatalf("expected check to be unwatched")
+	}
+}
+
 // TestConsul_ShutdownOK tests the ok path for the shutdown logic in
 // ServiceClient.
 func TestConsul_ShutdownOK(t *testing.T) {
@@ -651,7 +1135,7 @@ func TestConsul_ShutdownOK(t *testing.T) {
 	go ctx.ServiceClient.Run()
 
 	// Register a task and agent
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -724,7 +1208,7 @@ func TestConsul_ShutdownSlow(t *testing.T) {
 	go ctx.ServiceClient.Run()
 
 	// Register a task and agent
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -795,7 +1279,7 @@ func TestConsul_ShutdownBlocked(t *testing.T) {
 	go ctx.ServiceClient.Run()
 
 	// Register a task and agent
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -832,7 +1316,7 @@ func TestConsul_ShutdownBlocked(t *testing.T) {
 // TLSSkipVerify=true are skipped when Consul doesn't support TLSSkipVerify.
 func TestConsul_NoTLSSkipVerifySupport(t *testing.T) {
 	ctx := setupFake()
-	ctx.ServiceClient = NewServiceClient(ctx.FakeConsul, false, testLogger())
+	ctx.ServiceClient = NewServiceClient(ctx.FakeConsul, ctx.FakeConsul, ctx.FakeConsul, false, testLogger())
 	ctx.Task.Services[0].Checks = []*structs.ServiceCheck{
 		// This check sets TLSSkipVerify so it should get dropped
 		{
@@ -852,7 +1336,7 @@ func TestConsul_NoTLSSkipVerifySupport(t *testing.T) {
 		},
 	}
 
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, nil, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, nil, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -892,7 +1376,7 @@ func TestConsul_CancelScript(t *testing.T) {
 		},
 	}
 
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -929,7 +1413,7 @@ func TestConsul_CancelScript(t *testing.T) {
 		},
 	}
 
-	if err := ctx.ServiceClient.UpdateTask("allocid", origTask, ctx.Task, ctx, nil); err != nil {
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, origTask, ctx.Task, nil, ctx, nil); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -1016,7 +1500,7 @@ func TestConsul_DriverNetwork_AutoUse(t *testing.T) {
 		AutoAdvertise: true,
 	}
 
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, net); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, net); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -1119,7 +1603,7 @@ func TestConsul_DriverNetwork_NoAutoUse(t *testing.T) {
 		AutoAdvertise: false,
 	}
 
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, net); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, net); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -1205,7 +1689,7 @@ func TestConsul_DriverNetwork_Change(t *testing.T) {
 	}
 
 	// Initial service should advertise host port x
-	if err := ctx.ServiceClient.RegisterTask("allocid", ctx.Task, ctx, net); err != nil {
+	if err := ctx.ServiceClient.RegisterTask("allocid", nil, ctx.Task, nil, ctx, net); err != nil {
 		t.Fatalf("unexpected error registering task: %v", err)
 	}
 
@@ -1215,7 +1699,7 @@ func TestConsul_DriverNetwork_Change(t *testing.T) {
 	orig := ctx.Task.Copy()
 	ctx.Task.Services[0].AddressMode = structs.AddressModeHost
 
-	if err := ctx.ServiceClient.UpdateTask("allocid", orig, ctx.Task, ctx, net); err != nil {
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, orig, ctx.Task, nil, ctx, net); err != nil {
 		t.Fatalf("unexpected error updating task: %v", err)
 	}
 
@@ -1225,7 +1709,7 @@ func TestConsul_DriverNetwork_Change(t *testing.T) {
 	orig = ctx.Task.Copy()
 	ctx.Task.Services[0].AddressMode = structs.AddressModeDriver
 
-	if err := ctx.ServiceClient.UpdateTask("allocid", orig, ctx.Task, ctx, net); err != nil {
+	if err := ctx.ServiceClient.UpdateTask("allocid", nil, orig, ctx.Task, nil, ctx, net); err != nil {
 		t.Fatalf("unexpected error updating task: %v", err)
 	}
 
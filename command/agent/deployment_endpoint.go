@@ -61,6 +61,7 @@ func (s *HTTPServer) deploymentFail(resp http.ResponseWriter, req *http.Request,
 		DeploymentID: deploymentID,
 	}
 	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.DeploymentUpdateResponse
 	if err := s.agent.RPC("Deployment.Fail", &args, &out); err != nil {
@@ -86,6 +87,7 @@ func (s *HTTPServer) deploymentPause(resp http.ResponseWriter, req *http.Request
 		return nil, CodedError(400, "Deployment ID does not match")
 	}
 	s.parseRegion(req, &pauseRequest.Region)
+	s.parseToken(req, &pauseRequest.AuthToken)
 
 	var out structs.DeploymentUpdateResponse
 	if err := s.agent.RPC("Deployment.Pause", &pauseRequest, &out); err != nil {
@@ -111,6 +113,7 @@ func (s *HTTPServer) deploymentPromote(resp http.ResponseWriter, req *http.Reque
 		return nil, CodedError(400, "Deployment ID does not match")
 	}
 	s.parseRegion(req, &promoteRequest.Region)
+	s.parseToken(req, &promoteRequest.AuthToken)
 
 	var out structs.DeploymentUpdateResponse
 	if err := s.agent.RPC("Deployment.Promote", &promoteRequest, &out); err != nil {
@@ -136,6 +139,7 @@ func (s *HTTPServer) deploymentSetAllocHealth(resp http.ResponseWriter, req *htt
 		return nil, CodedError(400, "Deployment ID does not match")
 	}
 	s.parseRegion(req, &healthRequest.Region)
+	s.parseToken(req, &healthRequest.AuthToken)
 
 	var out structs.DeploymentUpdateResponse
 	if err := s.agent.RPC("Deployment.SetAllocHealth", &healthRequest, &out); err != nil {
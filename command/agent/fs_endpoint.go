@@ -20,6 +20,7 @@ import (
 	"gopkg.in/tomb.v1"
 
 	"github.com/docker/docker/pkg/ioutils"
+	"github.com/hashicorp/nomad/acl"
 	"github.com/hashicorp/nomad/client/allocdir"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hpcloud/tail/watch"
@@ -71,6 +72,20 @@ func (s *HTTPServer) FsRequest(resp http.ResponseWriter, req *http.Request) (int
 	}
 
 	path := strings.TrimPrefix(req.URL.Path, "/v1/client/fs/")
+
+	// The logs of an allocation may be read by the tokens with the read-logs
+	// capability on its namespace and its other files by the tokens with the
+	// read-fs capability
+	if i := strings.Index(path, "/"); i != -1 && path[i+1:] != "" {
+		capability := acl.NamespaceCapabilityReadFS
+		if strings.HasPrefix(path, "logs/") {
+			capability = acl.NamespaceCapabilityReadLogs
+		}
+		if err := s.checkClientAlloc(req, path[i+1:], allowNamespaceOperation(capability)); err != nil {
+			return nil, err
+		}
+	}
+
 	switch {
 	case strings.HasPrefix(path, "ls/"):
 		return s.DirectoryListRequest(resp, req)
@@ -89,6 +104,26 @@ func (s *HTTPServer) FsRequest(resp http.ResponseWriter, req *http.Request) (int
 	}
 }
 
+// allocFS returns the AllocDirFS of the allocation. The files of the secret
+// directories of its tasks may only be read if the request explicitly asks
+// for them with the secrets parameter, the token has the read-secrets
+// capability on the namespace of the allocation and the client allows it.
+func (s *HTTPServer) allocFS(allocID string, req *http.Request) (allocdir.AllocDirFS, error) {
+	if secrets := req.URL.Query().Get("secrets"); secrets != "" {
+		read, err := strconv.ParseBool(secrets)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing secrets: %v", err)
+		}
+		if read {
+			if err := s.checkClientAlloc(req, allocID, allowNamespaceOperation(acl.NamespaceCapabilityReadSecrets)); err != nil {
+				return nil, err
+			}
+			return s.agent.client.GetAllocSecretsFS(allocID)
+		}
+	}
+	return s.agent.client.GetAllocFS(allocID)
+}
+
 func (s *HTTPServer) DirectoryListRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	var allocID, path string
 
@@ -98,7 +133,7 @@ func (s *HTTPServer) DirectoryListRequest(resp http.ResponseWriter, req *http.Re
 	if path = req.URL.Query().Get("path"); path == "" {
 		path = "/"
 	}
-	fs, err := s.agent.client.GetAllocFS(allocID)
+	fs, err := s.allocFS(allocID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -113,7 +148,7 @@ func (s *HTTPServer) FileStatRequest(resp http.ResponseWriter, req *http.Request
 	if path = req.URL.Query().Get("path"); path == "" {
 		return nil, fileNameNotPresentErr
 	}
-	fs, err := s.agent.client.GetAllocFS(allocID)
+	fs, err := s.allocFS(allocID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -145,7 +180,7 @@ func (s *HTTPServer) FileReadAtRequest(resp http.ResponseWriter, req *http.Reque
 		}
 	}
 
-	fs, err := s.agent.client.GetAllocFS(allocID)
+	fs, err := s.allocFS(allocID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +220,7 @@ func (s *HTTPServer) FileCatRequest(resp http.ResponseWriter, req *http.Request)
 	if path = q.Get("path"); path == "" {
 		return nil, fileNameNotPresentErr
 	}
-	fs, err := s.agent.client.GetAllocFS(allocID)
+	fs, err := s.allocFS(allocID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -538,7 +573,7 @@ func (s *HTTPServer) Stream(resp http.ResponseWriter, req *http.Request) (interf
 		return nil, invalidOrigin
 	}
 
-	fs, err := s.agent.client.GetAllocFS(allocID)
+	fs, err := s.allocFS(allocID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -757,6 +792,20 @@ func (s *HTTPServer) Logs(resp http.ResponseWriter, req *http.Request) (interfac
 		return nil, invalidOrigin
 	}
 
+	fs, err := s.logsFS(allocID, task)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create an output that gets flushed on every write
+	output := ioutils.NewWriteFlusher(resp)
+
+	return nil, s.logs(follow, plain, offset, origin, task, logType, fs, output)
+}
+
+// logsFS returns the filesystem of the allocation, checking that the task
+// exists and has started so it may have logs.
+func (s *HTTPServer) logsFS(allocID, task string) (allocdir.AllocDirFS, error) {
 	fs, err := s.agent.client.GetAllocFS(allocID)
 	if err != nil {
 		return nil, err
@@ -780,10 +829,7 @@ func (s *HTTPServer) Logs(resp http.ResponseWriter, req *http.Request) (interfac
 		return nil, CodedError(404, fmt.Sprintf("task %q not started yet. No logs available", task))
 	}
 
-	// Create an output that gets flushed on every write
-	output := ioutils.NewWriteFlusher(resp)
-
-	return nil, s.logs(follow, plain, offset, origin, task, logType, fs, output)
+	return fs, nil
 }
 
 func (s *HTTPServer) logs(follow, plain bool, offset int64,
@@ -0,0 +1,660 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"syscall"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/api/nomadpb"
+	"github.com/hashicorp/nomad/helper/filter"
+	"github.com/hashicorp/nomad/helper/tlsutil"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// Topics of the event stream
+	eventTopicJobs        = "jobs"
+	eventTopicAllocations = "allocations"
+	eventTopicEvaluations = "evaluations"
+	eventTopicDeployments = "deployments"
+	eventTopicNodes       = "nodes"
+)
+
+// eventTopics are the topics streamed if none are requested.
+var eventTopics = []string{
+	eventTopicJobs,
+	eventTopicAllocations,
+	eventTopicEvaluations,
+	eventTopicDeployments,
+	eventTopicNodes,
+}
+
+// GRPCServer serves the gRPC API of the agent. The services defined in the
+// nomadpb package are implemented on top of the same RPCs as the HTTP API.
+type GRPCServer struct {
+	agent    *Agent
+	http     *HTTPServer
+	server   *grpc.Server
+	listener net.Listener
+	logger   *log.Logger
+	Addr     string
+}
+
+// NewGRPCServer starts a new gRPC server over the agent. The HTTP server is
+// used to stream logs.
+func NewGRPCServer(agent *Agent, http *HTTPServer, config *Config) (*GRPCServer, error) {
+	lnAddr, err := net.ResolveTCPAddr("tcp", config.normalizedAddrs.GRPC)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := config.Listener("tcp", lnAddr.IP.String(), lnAddr.Port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start gRPC listener: %v", err)
+	}
+
+	// The gRPC API is protected by the same TLS configuration as the HTTP API
+	var opts []grpc.ServerOption
+	if config.TLSConfig.EnableHTTP {
+		tlsConf := &tlsutil.Config{
+			VerifyIncoming:       config.TLSConfig.VerifyHTTPSClient,
+			VerifyOutgoing:       true,
+			VerifyServerHostname: config.TLSConfig.VerifyServerHostname,
+			CAFile:               config.TLSConfig.CAFile,
+			CertFile:             config.TLSConfig.CertFile,
+			KeyFile:              config.TLSConfig.KeyFile,
+		}
+		tlsConfig, err := tlsConf.IncomingTLSConfig()
+		if err != nil {
+			ln.Close()
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	srv := &GRPCServer{
+		agent:    agent,
+		http:     http,
+		listener: ln,
+		logger:   agent.logger,
+		Addr:     ln.Addr().String(),
+	}
+	opts = append(opts,
+		grpc.UnaryInterceptor(srv.unarySourceInterceptor),
+		grpc.StreamInterceptor(srv.streamSourceInterceptor))
+	srv.server = grpc.NewServer(opts...)
+	nomadpb.RegisterJobsServer(srv.server, &grpcJobs{srv})
+	nomadpb.RegisterAllocationsServer(srv.server, &grpcAllocations{srv})
+	nomadpb.RegisterEventsServer(srv.server, &grpcEvents{srv})
+
+	go srv.server.Serve(ln)
+	return srv, nil
+}
+
+// Shutdown is used to shutdown the gRPC server
+func (s *GRPCServer) Shutdown() {
+	if s != nil {
+		s.logger.Printf("[DEBUG] grpc: Shutting down grpc server")
+		s.server.Stop()
+	}
+}
+
+// rpcError converts the error of an RPC or of the HTTP helpers to a gRPC
+// error.
+func rpcError(err error) error {
+	if coded, ok := err.(HTTPCodedError); ok {
+		switch coded.Code() {
+		case 400:
+			return grpc.Errorf(codes.InvalidArgument, "%s", err)
+		case 404:
+			return grpc.Errorf(codes.NotFound, "%s", err)
+		}
+	}
+	if isPermissionError(err) {
+		return grpc.Errorf(codes.PermissionDenied, "%s", err)
+	}
+	return grpc.Errorf(codes.Unknown, "%s", err)
+}
+
+// checkTokenSource returns an error if the token of the request may not be
+// used from the address of its peer.
+func (s *GRPCServer) checkTokenSource(ctx context.Context) error {
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	if _, err := s.http.checkTokenSource(token(ctx), ip); err != nil {
+		return rpcError(err)
+	}
+	return nil
+}
+
+// unarySourceInterceptor rejects the unary requests made with tokens that may
+// not be used from the address of their peer.
+func (s *GRPCServer) unarySourceInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.checkTokenSource(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamSourceInterceptor rejects the streams opened with tokens that may not
+// be used from the address of their peer.
+func (s *GRPCServer) streamSourceInterceptor(srv interface{}, stream grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.checkTokenSource(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// region returns the region of a request, defaulting to the agent's.
+func (s *GRPCServer) region(r string) string {
+	if r == "" {
+		return s.agent.config.Region
+	}
+	return r
+}
+
+// writeRegion returns the region of a write request.
+func (s *GRPCServer) writeRegion(opts *nomadpb.WriteOptions) string {
+	if opts == nil {
+		return s.region("")
+	}
+	return s.region(opts.Region)
+}
+
+// token returns the secret ID of the ACL token sent in the x-nomad-token
+// metadata of a request.
+func token(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md["x-nomad-token"]; len(values) != 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// queryOptions converts the query options of a request.
+func (s *GRPCServer) queryOptions(ctx context.Context, opts *nomadpb.QueryOptions) (structs.QueryOptions, error) {
+	if opts == nil {
+		opts = &nomadpb.QueryOptions{}
+	}
+	if opts.PerPage < 0 {
+		return structs.QueryOptions{}, grpc.Errorf(codes.InvalidArgument, "Invalid per_page")
+	}
+	if opts.Filter != "" {
+		if _, err := filter.Parse(opts.Filter); err != nil {
+			return structs.QueryOptions{}, grpc.Errorf(codes.InvalidArgument, "Invalid filter: %v", err)
+		}
+	}
+	return structs.QueryOptions{
+		Region:     s.region(opts.Region),
+		AuthToken:  token(ctx),
+		AllowStale: opts.AllowStale,
+		Prefix:     opts.Prefix,
+		Filter:     opts.Filter,
+		PerPage:    opts.PerPage,
+		NextToken:  opts.NextToken,
+	}, nil
+}
+
+func queryMeta(m *structs.QueryMeta) *nomadpb.QueryMeta {
+	return &nomadpb.QueryMeta{
+		Index:       m.Index,
+		KnownLeader: m.KnownLeader,
+		NextToken:   m.NextToken,
+	}
+}
+
+// grpcJobs implements the Jobs service.
+type grpcJobs struct {
+	srv *GRPCServer
+}
+
+func (j *grpcJobs) Register(ctx context.Context, req *nomadpb.JobRegisterRequest) (*nomadpb.JobRegisterResponse, error) {
+	var job api.Job
+	if err := json.Unmarshal(req.Job, &job); err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Failed to decode job: %v", err)
+	}
+	if job.ID == nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Job ID hasn't been provided")
+	}
+
+	args := structs.JobRegisterRequest{
+		Job:            ApiJobToStructJob(&job),
+		EnforceIndex:   req.EnforceIndex,
+		JobModifyIndex: req.JobModifyIndex,
+		WriteRequest: structs.WriteRequest{
+			Region:    j.srv.writeRegion(req.Options),
+			AuthToken: token(ctx),
+		},
+	}
+	var out structs.JobRegisterResponse
+	if err := j.srv.agent.RPC("Job.Register", &args, &out); err != nil {
+		return nil, rpcError(err)
+	}
+	return &nomadpb.JobRegisterResponse{
+		EvalId:          out.EvalID,
+		EvalCreateIndex: out.EvalCreateIndex,
+		JobModifyIndex:  out.JobModifyIndex,
+		Warnings:        out.Warnings,
+		Index:           out.Index,
+	}, nil
+}
+
+func (j *grpcJobs) Deregister(ctx context.Context, req *nomadpb.JobDeregisterRequest) (*nomadpb.JobDeregisterResponse, error) {
+	if req.JobId == "" {
+		return nil, grpc.Errorf(codes.InvalidArgument, "Missing job ID")
+	}
+
+	args := structs.JobDeregisterRequest{
+		JobID: req.JobId,
+		Purge: req.Purge,
+		WriteRequest: structs.WriteRequest{
+			Region:    j.srv.writeRegion(req.Options),
+			AuthToken: token(ctx),
+		},
+	}
+	var out structs.JobDeregisterResponse
+	if err := j.srv.agent.RPC("Job.Deregister", &args, &out); err != nil {
+		return nil, rpcError(err)
+	}
+	return &nomadpb.JobDeregisterResponse{
+		EvalId:          out.EvalID,
+		EvalCreateIndex: out.EvalCreateIndex,
+		JobModifyIndex:  out.JobModifyIndex,
+		Index:           out.Index,
+	}, nil
+}
+
+func (j *grpcJobs) List(ctx context.Context, req *nomadpb.JobListRequest) (*nomadpb.JobListResponse, error) {
+	opts, err := j.srv.queryOptions(ctx, req.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	args := structs.JobListRequest{QueryOptions: opts}
+	var out structs.JobListResponse
+	if err := j.srv.agent.RPC("Job.List", &args, &out); err != nil {
+		return nil, rpcError(err)
+	}
+
+	resp := &nomadpb.JobListResponse{
+		Jobs: make([]*nomadpb.JobStub, len(out.Jobs)),
+		Meta: queryMeta(&out.QueryMeta),
+	}
+	for i, job := range out.Jobs {
+		resp.Jobs[i] = jobStubToProto(job)
+	}
+	return resp, nil
+}
+
+// grpcAllocations implements the Allocations service.
+type grpcAllocations struct {
+	srv *GRPCServer
+}
+
+func (a *grpcAllocations) List(ctx context.Context, req *nomadpb.AllocationListRequest) (*nomadpb.AllocationListResponse, error) {
+	opts, err := a.srv.queryOptions(ctx, req.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	args := structs.AllocListRequest{QueryOptions: opts}
+	var out structs.AllocListResponse
+	if err := a.srv.agent.RPC("Alloc.List", &args, &out); err != nil {
+		return nil, rpcError(err)
+	}
+
+	resp := &nomadpb.AllocationListResponse{
+		Allocations: make([]*nomadpb.Allocation, len(out.Allocations)),
+		Meta:        queryMeta(&out.QueryMeta),
+	}
+	for i, alloc := range out.Allocations {
+		resp.Allocations[i] = allocStubToProto(alloc)
+	}
+	return resp, nil
+}
+
+func (a *grpcAllocations) Get(ctx context.Context, req *nomadpb.AllocationGetRequest) (*nomadpb.AllocationGetResponse, error) {
+	opts, err := a.srv.queryOptions(ctx, req.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	args := structs.AllocSpecificRequest{
+		AllocID:      req.AllocId,
+		QueryOptions: opts,
+	}
+	var out structs.SingleAllocResponse
+	if err := a.srv.agent.RPC("Alloc.GetAlloc", &args, &out); err != nil {
+		return nil, rpcError(err)
+	}
+	if out.Alloc == nil {
+		return nil, grpc.Errorf(codes.NotFound, "alloc not found")
+	}
+
+	return &nomadpb.AllocationGetResponse{
+		Allocation: allocStubToProto(out.Alloc.Stub()),
+		Meta:       queryMeta(&out.QueryMeta),
+	}, nil
+}
+
+func (a *grpcAllocations) Logs(req *nomadpb.LogsRequest, stream nomadpb.Allocations_LogsServer) error {
+	if a.srv.agent.client == nil {
+		return grpc.Errorf(codes.FailedPrecondition, "%s", clientNotRunning)
+	}
+	if req.AllocId == "" {
+		return grpc.Errorf(codes.InvalidArgument, "%s", allocIDNotPresentErr)
+	}
+	if req.Task == "" {
+		return grpc.Errorf(codes.InvalidArgument, "%s", taskNotPresentErr)
+	}
+	switch req.Type {
+	case "stdout", "stderr":
+	default:
+		return grpc.Errorf(codes.InvalidArgument, "%s", logTypeNotPresentErr)
+	}
+
+	origin := req.Origin
+	switch origin {
+	case OriginStart, OriginEnd:
+	case "":
+		origin = OriginStart
+	default:
+		return grpc.Errorf(codes.InvalidArgument, "%s", invalidOrigin)
+	}
+
+	aclObj, err := a.srv.http.resolveSecretID(token(stream.Context()))
+	if err != nil {
+		return rpcError(err)
+	}
+	if err := a.srv.http.allowClientAlloc(aclObj, req.AllocId, allowNamespaceOperation(acl.NamespaceCapabilityReadLogs)); err != nil {
+		return rpcError(err)
+	}
+
+	fs, err := a.srv.http.logsFS(req.AllocId, req.Task)
+	if err != nil {
+		return rpcError(err)
+	}
+
+	output := &logFrameWriter{stream: stream}
+	if err := a.srv.http.logs(req.Follow, true, req.Offset, origin, req.Task, req.Type, fs, output); err != nil {
+		return rpcError(err)
+	}
+	return nil
+}
+
+// logFrameWriter sends the data written to it as log frames.
+type logFrameWriter struct {
+	stream nomadpb.Allocations_LogsServer
+}
+
+func (w *logFrameWriter) Write(p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+	if err := w.stream.Send(&nomadpb.LogFrame{Data: data}); err != nil {
+		// Report the client going away as a closed pipe so streaming stops
+		// without an error
+		return 0, syscall.EPIPE
+	}
+	return len(p), nil
+}
+
+func (w *logFrameWriter) Close() error {
+	return nil
+}
+
+// grpcEvents implements the Events service.
+type grpcEvents struct {
+	srv *GRPCServer
+}
+
+func (e *grpcEvents) Stream(req *nomadpb.EventStreamRequest, stream nomadpb.Events_StreamServer) error {
+	topics := req.Topics
+	if len(topics) == 0 {
+		topics = eventTopics
+	}
+
+	ctx := stream.Context()
+	region := e.srv.region(req.Region)
+	secretID := token(ctx)
+	eventsCh := make(chan *nomadpb.Event)
+	errCh := make(chan error, len(topics))
+	for _, topic := range topics {
+		watch, ok := e.watchFn(topic)
+		if !ok {
+			return grpc.Errorf(codes.InvalidArgument, "Invalid topic %q", topic)
+		}
+		go e.watch(ctx, topic, region, secretID, req.Index, watch, eventsCh, errCh)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			return rpcError(err)
+		case event := <-eventsCh:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// eventWatchFn runs a blocking query for the objects of a topic and returns
+// an event for each object. The events' Index is the object's modify index.
+type eventWatchFn func(opts structs.QueryOptions) ([]*nomadpb.Event, uint64, error)
+
+// watch runs blocking queries for a topic and sends an event for each
+// object modified after the index.
+func (e *grpcEvents) watch(ctx context.Context, topic, region, secretID string, index uint64,
+	watch eventWatchFn, eventsCh chan<- *nomadpb.Event, errCh chan<- error) {
+
+	for {
+		opts := structs.QueryOptions{
+			Region:        region,
+			AuthToken:     secretID,
+			MinQueryIndex: index,
+		}
+		events, queryIndex, err := watch(opts)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		for _, event := range events {
+			if event.Index <= index {
+				continue
+			}
+			event.Topic = topic
+			select {
+			case eventsCh <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if queryIndex > index {
+			index = queryIndex
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+// watchFn returns the watch function of a topic.
+func (e *grpcEvents) watchFn(topic string) (eventWatchFn, bool) {
+	agent := e.srv.agent
+	switch topic {
+	case eventTopicJobs:
+		return func(opts structs.QueryOptions) ([]*nomadpb.Event, uint64, error) {
+			args := structs.JobListRequest{QueryOptions: opts}
+			var out structs.JobListResponse
+			if err := agent.RPC("Job.List", &args, &out); err != nil {
+				return nil, 0, err
+			}
+			events := make([]*nomadpb.Event, len(out.Jobs))
+			for i, job := range out.Jobs {
+				events[i] = &nomadpb.Event{Index: job.ModifyIndex, Job: jobStubToProto(job)}
+			}
+			return events, out.Index, nil
+		}, true
+	case eventTopicAllocations:
+		return func(opts structs.QueryOptions) ([]*nomadpb.Event, uint64, error) {
+			args := structs.AllocListRequest{QueryOptions: opts}
+			var out structs.AllocListResponse
+			if err := agent.RPC("Alloc.List", &args, &out); err != nil {
+				return nil, 0, err
+			}
+			events := make([]*nomadpb.Event, len(out.Allocations))
+			for i, alloc := range out.Allocations {
+				events[i] = &nomadpb.Event{Index: alloc.ModifyIndex, Allocation: allocStubToProto(alloc)}
+			}
+			return events, out.Index, nil
+		}, true
+	case eventTopicEvaluations:
+		return func(opts structs.QueryOptions) ([]*nomadpb.Event, uint64, error) {
+			args := structs.EvalListRequest{QueryOptions: opts}
+			var out structs.EvalListResponse
+			if err := agent.RPC("Eval.List", &args, &out); err != nil {
+				return nil, 0, err
+			}
+			events := make([]*nomadpb.Event, len(out.Evaluations))
+			for i, eval := range out.Evaluations {
+				events[i] = &nomadpb.Event{Index: eval.ModifyIndex, Evaluation: evalToProto(eval)}
+			}
+			return events, out.Index, nil
+		}, true
+	case eventTopicDeployments:
+		return func(opts structs.QueryOptions) ([]*nomadpb.Event, uint64, error) {
+			args := structs.DeploymentListRequest{QueryOptions: opts}
+			var out structs.DeploymentListResponse
+			if err := agent.RPC("Deployment.List", &args, &out); err != nil {
+				return nil, 0, err
+			}
+			events := make([]*nomadpb.Event, len(out.Deployments))
+			for i, d := range out.Deployments {
+				events[i] = &nomadpb.Event{Index: d.ModifyIndex, Deployment: deploymentToProto(d)}
+			}
+			return events, out.Index, nil
+		}, true
+	case eventTopicNodes:
+		return func(opts structs.QueryOptions) ([]*nomadpb.Event, uint64, error) {
+			args := structs.NodeListRequest{QueryOptions: opts}
+			var out structs.NodeListResponse
+			if err := agent.RPC("Node.List", &args, &out); err != nil {
+				return nil, 0, err
+			}
+			events := make([]*nomadpb.Event, len(out.Nodes))
+			for i, node := range out.Nodes {
+				events[i] = &nomadpb.Event{Index: node.ModifyIndex, Node: nodeStubToProto(node)}
+			}
+			return events, out.Index, nil
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+func jobStubToProto(j *structs.JobListStub) *nomadpb.JobStub {
+	return &nomadpb.JobStub{
+		Id:                j.ID,
+		ParentId:          j.ParentID,
+		Name:              j.Name,
+		Type:              j.Type,
+		Priority:          int32(j.Priority),
+		Periodic:          j.Periodic,
+		Parameterized:     j.ParameterizedJob,
+		Stop:              j.Stop,
+		Status:            j.Status,
+		StatusDescription: j.StatusDescription,
+		SubmitTime:        j.SubmitTime,
+		CreateIndex:       j.CreateIndex,
+		ModifyIndex:       j.ModifyIndex,
+		JobModifyIndex:    j.JobModifyIndex,
+	}
+}
+
+func allocStubToProto(a *structs.AllocListStub) *nomadpb.Allocation {
+	return &nomadpb.Allocation{
+		Id:                 a.ID,
+		EvalId:             a.EvalID,
+		Name:               a.Name,
+		NodeId:             a.NodeID,
+		JobId:              a.JobID,
+		JobVersion:         a.JobVersion,
+		TaskGroup:          a.TaskGroup,
+		DesiredStatus:      a.DesiredStatus,
+		DesiredDescription: a.DesiredDescription,
+		ClientStatus:       a.ClientStatus,
+		ClientDescription:  a.ClientDescription,
+		CreateTime:         a.CreateTime,
+		CreateIndex:        a.CreateIndex,
+		ModifyIndex:        a.ModifyIndex,
+	}
+}
+
+func evalToProto(e *structs.Evaluation) *nomadpb.Evaluation {
+	return &nomadpb.Evaluation{
+		Id:                e.ID,
+		Priority:          int32(e.Priority),
+		Type:              e.Type,
+		TriggeredBy:       e.TriggeredBy,
+		JobId:             e.JobID,
+		NodeId:            e.NodeID,
+		DeploymentId:      e.DeploymentID,
+		Status:            e.Status,
+		StatusDescription: e.StatusDescription,
+		CreateIndex:       e.CreateIndex,
+		ModifyIndex:       e.ModifyIndex,
+	}
+}
+
+func deploymentToProto(d *structs.Deployment) *nomadpb.Deployment {
+	return &nomadpb.Deployment{
+		Id:                d.ID,
+		JobId:             d.JobID,
+		JobVersion:        d.JobVersion,
+		Status:            d.Status,
+		StatusDescription: d.StatusDescription,
+		CreateIndex:       d.CreateIndex,
+		ModifyIndex:       d.ModifyIndex,
+	}
+}
+
+func nodeStubToProto(n *structs.NodeListStub) *nomadpb.Node {
+	return &nomadpb.Node{
+		Id:                n.ID,
+		Datacenter:        n.Datacenter,
+		Name:              n.Name,
+		NodeClass:         n.NodeClass,
+		Drain:             n.Drain,
+		Status:            n.Status,
+		StatusDescription: n.StatusDescription,
+		CreateIndex:       n.CreateIndex,
+		ModifyIndex:       n.ModifyIndex,
+	}
+}
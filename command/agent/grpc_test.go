@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/api/nomadpb"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// grpcTest runs f with a client connection to the gRPC server of a test
+// agent.
+func grpcTest(t *testing.T, f func(s *TestAgent, conn *grpc.ClientConn)) {
+	httpTest(t, func(c *Config) {
+		c.Client.Enabled = false
+	}, func(s *TestAgent) {
+		s.Config.Ports.GRPC = getPort()
+		if err := s.Config.normalizeAddrs(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		srv, err := NewGRPCServer(s.Agent, s.Server, s.Config)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer srv.Shutdown()
+
+		conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer conn.Close()
+
+		f(s, conn)
+	})
+}
+
+func TestGRPC_Jobs(t *testing.T) {
+	t.Parallel()
+	grpcTest(t, func(s *TestAgent, conn *grpc.ClientConn) {
+		client := nomadpb.NewJobsClient(conn)
+		job := api.MockJob()
+		buf, err := json.Marshal(job)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Register the job
+		reg, err := client.Register(context.Background(), &nomadpb.JobRegisterRequest{Job: buf})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if reg.EvalId == "" || reg.Index == 0 {
+			t.Fatalf("bad: %#v", reg)
+		}
+
+		// List the jobs
+		list, err := client.List(context.Background(), &nomadpb.JobListRequest{})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(list.Jobs) != 1 || list.Jobs[0].Id != *job.ID {
+			t.Fatalf("bad: %#v", list.Jobs)
+		}
+		if list.Meta.Index == 0 {
+			t.Fatalf("missing index")
+		}
+
+		// Deregister the job
+		dereg, err := client.Deregister(context.Background(), &nomadpb.JobDeregisterRequest{JobId: *job.ID})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if dereg.EvalId == "" {
+			t.Fatalf("bad: %#v", dereg)
+		}
+	})
+}
+
+func TestGRPC_Jobs_InvalidFilter(t *testing.T) {
+	t.Parallel()
+	grpcTest(t, func(s *TestAgent, conn *grpc.ClientConn) {
+		client := nomadpb.NewJobsClient(conn)
+		req := &nomadpb.JobListRequest{
+			Options: &nomadpb.QueryOptions{Filter: "Status ="},
+		}
+		_, err := client.List(context.Background(), req)
+		if grpc.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected invalid argument error, got %v", err)
+		}
+	})
+}
+
+func TestGRPC_Allocations_NotFound(t *testing.T) {
+	t.Parallel()
+	grpcTest(t, func(s *TestAgent, conn *grpc.ClientConn) {
+		client := nomadpb.NewAllocationsClient(conn)
+		_, err := client.Get(context.Background(), &nomadpb.AllocationGetRequest{AllocId: structs.GenerateUUID()})
+		if grpc.Code(err) != codes.NotFound {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+	})
+}
+
+func TestGRPC_TokenSource(t *testing.T) {
+	t.Parallel()
+	httpACLTest(t, func(c *Config) {
+		c.Client.Enabled = false
+	}, func(s *TestAgent, root *structs.ACLToken) {
+		s.Config.Ports.GRPC = getPort()
+		if err := s.Config.normalizeAddrs(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		srv, err := NewGRPCServer(s.Agent, s.Server, s.Config)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer srv.Shutdown()
+		conn, err := grpc.Dial(srv.Addr, grpc.WithInsecure())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		defer conn.Close()
+
+		// The test connects from the loopback address
+		token := mock.ACLManagementToken()
+		token.AllowedCIDRs = []string{"10.0.0.0/8"}
+		if err := s.Agent.server.State().UpsertACLTokens(1000, []*structs.ACLToken{token}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		client := nomadpb.NewJobsClient(conn)
+		ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("x-nomad-token", token.SecretID))
+		_, err = client.List(ctx, &nomadpb.JobListRequest{})
+		if grpc.Code(err) != codes.PermissionDenied {
+			t.Fatalf("expected permission denied error, got %v", err)
+		}
+
+		ctx = metadata.NewOutgoingContext(context.Background(), metadata.Pairs("x-nomad-token", root.SecretID))
+		if _, err := client.List(ctx, &nomadpb.JobListRequest{}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
+func TestGRPC_Events(t *testing.T) {
+	t.Parallel()
+	grpcTest(t, func(s *TestAgent, conn *grpc.ClientConn) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events := nomadpb.NewEventsClient(conn)
+		stream, err := events.Stream(ctx, &nomadpb.EventStreamRequest{Topics: []string{"jobs"}})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Register a job, which should be streamed
+		job := api.MockJob()
+		buf, err := json.Marshal(job)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		jobs := nomadpb.NewJobsClient(conn)
+		if _, err := jobs.Register(ctx, &nomadpb.JobRegisterRequest{Job: buf}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if event.Topic != "jobs" || event.Job == nil || event.Job.Id != *job.ID {
+			t.Fatalf("bad: %#v", event)
+		}
+	})
+}
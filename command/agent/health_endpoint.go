@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	// The components of the health of an agent. Drivers are also reported
+	// individually as driver.<name>.
+	healthRaft    = "raft"
+	healthLeader  = "leader"
+	healthVault   = "vault"
+	healthConsul  = "consul"
+	healthServers = "servers"
+	healthDrivers = "drivers"
+
+	// healthLeaderContactThreshold is the time after which a follower that
+	// didn't hear from the leader considers it lost.
+	healthLeaderContactThreshold = 10 * time.Second
+
+	// healthModeReadiness fails the health check when a required component
+	// is unhealthy. healthModeLiveness only fails it if the agent can't
+	// answer, so orchestrators don't restart agents waiting for the cluster.
+	healthModeReadiness = "readiness"
+	healthModeLiveness  = "liveness"
+)
+
+// AgentHealthResponse is the health of an agent and of its components.
+type AgentHealthResponse struct {
+	// Healthy is whether all the required components are healthy.
+	Healthy bool
+
+	// Mode is the semantics the health was evaluated with: readiness or
+	// liveness.
+	Mode string
+
+	Components map[string]*AgentHealthComponent
+}
+
+// AgentHealthComponent is the health of a component of an agent.
+type AgentHealthComponent struct {
+	Healthy bool
+
+	// Required is whether the component must be healthy for the agent to be
+	// healthy.
+	Required bool
+
+	// Message describes the state of the component.
+	Message string
+}
+
+// AgentHealthRequest reports the health of the agent and of its components.
+// In readiness mode, the default, the agent is unhealthy if a required
+// component is: by default the Raft state and the leader on servers and the
+// connection to the servers on clients. The require parameter overrides the
+// required components. In liveness mode no component is required. The
+// response code is 503 if the agent is unhealthy.
+func (s *HTTPServer) AgentHealthRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	query := req.URL.Query()
+	mode := query.Get("mode")
+	switch mode {
+	case "":
+		mode = healthModeReadiness
+	case healthModeReadiness, healthModeLiveness:
+	default:
+		return nil, CodedError(400, fmt.Sprintf("Invalid mode %q, expected readiness or liveness", mode))
+	}
+
+	out := &AgentHealthResponse{
+		Healthy:    true,
+		Mode:       mode,
+		Components: s.agent.healthComponents(),
+	}
+
+	var required []string
+	if r := query.Get("require"); r != "" {
+		for _, name := range strings.Split(r, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := out.Components[name]; !ok && !validHealthComponent(name) {
+				return nil, CodedError(400, fmt.Sprintf("Unknown health component %q", name))
+			}
+			required = append(required, name)
+		}
+	} else {
+		if s.agent.server != nil {
+			required = append(required, healthRaft, healthLeader)
+		}
+		if s.agent.client != nil {
+			required = append(required, healthServers)
+		}
+	}
+
+	if mode == healthModeReadiness {
+		for _, name := range required {
+			c, ok := out.Components[name]
+			if !ok {
+				// Components of a role the agent doesn't have, or of drivers
+				// it doesn't know, are unhealthy if required
+				c = &AgentHealthComponent{Message: "not reported by this agent"}
+				out.Components[name] = c
+			}
+			c.Required = true
+			if !c.Healthy {
+				out.Healthy = false
+			}
+		}
+	}
+
+	if out.Healthy {
+		return out, nil
+	}
+
+	// Write the response with the unhealthy status code, since only errors
+	// change the status code of responses
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, structs.JsonHandle).Encode(out); err != nil {
+		return nil, err
+	}
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(http.StatusServiceUnavailable)
+	resp.Write(buf.Bytes())
+	return nil, nil
+}
+
+// validHealthComponent returns whether the name is the name of a component
+// of the health of agents.
+func validHealthComponent(name string) bool {
+	switch name {
+	case healthRaft, healthLeader, healthVault, healthConsul, healthServers, healthDrivers:
+		return true
+	}
+	return strings.HasPrefix(name, "driver.")
+}
+
+// healthComponents returns the health of the components of the agent.
+func (a *Agent) healthComponents() map[string]*AgentHealthComponent {
+	components := make(map[string]*AgentHealthComponent)
+
+	if err := a.consulService.SyncError(); err != nil {
+		components[healthConsul] = &AgentHealthComponent{Message: fmt.Sprintf("failed to sync with Consul: %v", err)}
+	} else {
+		components[healthConsul] = &AgentHealthComponent{Healthy: true, Message: "ok"}
+	}
+
+	if a.server != nil {
+		health := a.server.Health()
+
+		switch health.RaftState {
+		case "Leader", "Follower":
+			components[healthRaft] = &AgentHealthComponent{Healthy: true, Message: health.RaftState}
+		default:
+			components[healthRaft] = &AgentHealthComponent{Message: health.RaftState}
+		}
+
+		leader := &AgentHealthComponent{}
+		switch {
+		case health.Leader == "":
+			leader.Message = "no known leader"
+		case health.RaftState == "Leader":
+			leader.Healthy = true
+			leader.Message = "this server is the leader"
+		case health.LastLeaderContact.IsZero():
+			leader.Message = "never heard from the leader"
+		case time.Since(health.LastLeaderContact) > healthLeaderContactThreshold:
+			leader.Message = fmt.Sprintf("last heard from the leader %v ago", time.Since(health.LastLeaderContact))
+		default:
+			leader.Healthy = true
+			leader.Message = fmt.Sprintf("leader is %s", health.Leader)
+		}
+		components[healthLeader] = leader
+
+		vault := &AgentHealthComponent{}
+		switch {
+		case !health.VaultEnabled:
+			vault.Message = "Vault integration is disabled"
+		case health.VaultEstablished:
+			vault.Healthy = true
+			vault.Message = "ok"
+		case health.VaultError != nil:
+			vault.Message = fmt.Sprintf("failed to establish a connection to Vault: %v", health.VaultError)
+		default:
+			vault.Message = "establishing a connection to Vault"
+		}
+		components[healthVault] = vault
+	}
+
+	if a.client != nil {
+		health := a.client.Health()
+
+		servers := &AgentHealthComponent{}
+		switch {
+		case health.KnownServers == 0:
+			servers.Message = "no known servers"
+		case health.LastHeartbeat.IsZero():
+			servers.Message = "node is not registered"
+		case time.Since(health.LastHeartbeat) > health.HeartbeatTTL:
+			servers.Message = fmt.Sprintf("last heartbeat %v ago", time.Since(health.LastHeartbeat))
+		default:
+			servers.Healthy = true
+			servers.Message = fmt.Sprintf("%d known servers", health.KnownServers)
+		}
+		components[healthServers] = servers
+
+		// The drivers are healthy if at least one is detected and none of the
+		// whitelisted or previously detected ones is missing
+		names := make([]string, 0, len(health.Drivers))
+		for name := range health.Drivers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var detected, undetected []string
+		for _, name := range names {
+			if health.Drivers[name] {
+				detected = append(detected, name)
+				components["driver."+name] = &AgentHealthComponent{Healthy: true, Message: "detected"}
+			} else {
+				undetected = append(undetected, name)
+				components["driver."+name] = &AgentHealthComponent{Message: "not detected"}
+			}
+		}
+		drivers := &AgentHealthComponent{}
+		switch {
+		case len(undetected) > 0:
+			drivers.Message = fmt.Sprintf("drivers not detected: %s", strings.Join(undetected, ", "))
+		case len(detected) == 0:
+			drivers.Message = "no driver detected"
+		default:
+			drivers.Healthy = true
+			drivers.Message = fmt.Sprintf("drivers detected: %s", strings.Join(detected, ", "))
+		}
+		components[healthDrivers] = drivers
+	}
+
+	return components
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTP_AgentHealth(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// The leader is healthy
+		req, err := http.NewRequest("GET", "/v1/agent/health?require=raft,leader", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		obj, err := s.Server.AgentHealthRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		health := obj.(*AgentHealthResponse)
+		if !health.Healthy || health.Mode != "readiness" {
+			t.Fatalf("bad: %#v", health)
+		}
+		for _, name := range []string{"raft", "leader"} {
+			if c := health.Components[name]; c == nil || !c.Healthy || !c.Required {
+				t.Fatalf("bad %s: %#v", name, c)
+			}
+		}
+		for _, name := range []string{"consul", "vault", "servers", "drivers"} {
+			if c := health.Components[name]; c == nil || c.Required {
+				t.Fatalf("bad %s: %#v", name, c)
+			}
+		}
+
+		// Requiring a missing driver makes the agent unhealthy
+		req, err = http.NewRequest("GET", "/v1/agent/health?require=driver.bogus", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.AgentHealthRequest(respW, req)
+		if err != nil || obj != nil {
+			t.Fatalf("bad: %#v %v", obj, err)
+		}
+		if respW.Code != http.StatusServiceUnavailable {
+			t.Fatalf("bad code: %d", respW.Code)
+		}
+		var out AgentHealthResponse
+		if err := json.Unmarshal(respW.Body.Bytes(), &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out.Healthy || !out.Components["driver.bogus"].Required {
+			t.Fatalf("bad: %#v", out)
+		}
+
+		// Unless only liveness is checked
+		req, err = http.NewRequest("GET", "/v1/agent/health?mode=liveness&require=driver.bogus", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.AgentHealthRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if health := obj.(*AgentHealthResponse); !health.Healthy {
+			t.Fatalf("bad: %#v", health)
+		}
+
+		// Unknown components are rejected
+		req, err = http.NewRequest("GET", "/v1/agent/health?require=bogus", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.AgentHealthRequest(respW, req); err == nil {
+			t.Fatalf("expected an error")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -10,10 +11,16 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/helper/filter"
+	"github.com/hashicorp/nomad/helper/ratelimit"
 	"github.com/hashicorp/nomad/helper/tlsutil"
+	"github.com/hashicorp/nomad/helper/tracing"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/ugorji/go/codec"
 )
@@ -36,6 +43,14 @@ type HTTPServer struct {
 	listener net.Listener
 	logger   *log.Logger
 	Addr     string
+
+	// limiter enforces the HTTP rate limits, keyed by client IP. It is nil
+	// if no limit is configured.
+	limiter *ratelimit.Limiter
+
+	// tokenLimiter enforces the HTTP rate limit per ACL token, keyed by the
+	// accessor ID of the token. It is nil if no limit is configured.
+	tokenLimiter *ratelimit.Limiter
 }
 
 // NewHTTPServer starts new HTTP server over the agent
@@ -72,11 +87,13 @@ func NewHTTPServer(agent *Agent, config *Config) (*HTTPServer, error) {
 
 	// Create the server
 	srv := &HTTPServer{
-		agent:    agent,
-		mux:      mux,
-		listener: ln,
-		logger:   agent.logger,
-		Addr:     ln.Addr().String(),
+		agent:        agent,
+		mux:          mux,
+		listener:     ln,
+		logger:       agent.logger,
+		Addr:         ln.Addr().String(),
+		limiter:      newHTTPLimiter(config),
+		tokenLimiter: newHTTPTokenLimiter(config),
 	}
 	srv.registerHandlers(config.EnableDebug)
 
@@ -93,11 +110,13 @@ func newScadaHttp(agent *Agent, list net.Listener) *HTTPServer {
 
 	// Create the server
 	srv := &HTTPServer{
-		agent:    agent,
-		mux:      mux,
-		listener: list,
-		logger:   agent.logger,
-		Addr:     scadaHTTPAddr,
+		agent:        agent,
+		mux:          mux,
+		listener:     list,
+		logger:       agent.logger,
+		Addr:         scadaHTTPAddr,
+		limiter:      newHTTPLimiter(agent.config),
+		tokenLimiter: newHTTPTokenLimiter(agent.config),
 	}
 	srv.registerHandlers(false) // Never allow debug for SCADA
 
@@ -106,6 +125,40 @@ func newScadaHttp(agent *Agent, list net.Listener) *HTTPServer {
 	return srv
 }
 
+// newHTTPLimiter returns the limiter of the HTTP rate limits.
+func newHTTPLimiter(config *Config) *ratelimit.Limiter {
+	if config.Limits == nil {
+		return nil
+	}
+	return ratelimit.New(config.Limits.HTTPConfig())
+}
+
+// newHTTPTokenLimiter returns the limiter of the HTTP rate limit per ACL
+// token.
+func newHTTPTokenLimiter(config *Config) *ratelimit.Limiter {
+	if config.Limits == nil {
+		return nil
+	}
+	return ratelimit.New(config.Limits.HTTPTokenConfig())
+}
+
+// writeRateLimited answers a request over a rate limit.
+func writeRateLimited(resp http.ResponseWriter) {
+	metrics.IncrCounter([]string{"nomad", "http", "rate_limited"}, 1)
+	resp.Header().Set("Retry-After", "1")
+	resp.WriteHeader(429)
+	resp.Write([]byte("Too many requests"))
+}
+
+// clientIP returns the IP address of the client of a request.
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
 // tcpKeepAliveListener sets TCP keep-alive timeouts on accepted
 // connections. It's used by NewHttpServer so
 // dead TCP connections eventually go away.
@@ -146,21 +199,55 @@ func (s *HTTPServer) registerHandlers(enableDebug bool) {
 	s.mux.HandleFunc("/v1/evaluation/", s.wrap(s.EvalSpecificRequest))
 
 	s.mux.HandleFunc("/v1/resources/", s.wrap(s.ResourceListRequest))
+	s.mux.HandleFunc("/v1/search/fuzzy", s.wrap(s.FuzzySearchRequest))
 
 	s.mux.HandleFunc("/v1/deployments", s.wrap(s.DeploymentsRequest))
 	s.mux.HandleFunc("/v1/deployment/", s.wrap(s.DeploymentSpecificRequest))
 
+	s.mux.HandleFunc("/v1/namespaces", s.wrap(s.NamespacesRequest))
+	s.mux.HandleFunc("/v1/namespace/", s.wrap(s.NamespaceSpecificRequest))
+
+	s.mux.HandleFunc("/v1/vars", s.wrap(s.VariablesRequest))
+	s.mux.HandleFunc("/v1/var/", s.wrap(s.VariableSpecificRequest))
+
+	s.mux.HandleFunc("/v1/acl/bootstrap", s.wrap(s.ACLBootstrapRequest))
+	s.mux.HandleFunc("/v1/acl/policies", s.wrap(s.ACLPoliciesRequest))
+	s.mux.HandleFunc("/v1/acl/policy/", s.wrap(s.ACLPolicySpecificRequest))
+	s.mux.HandleFunc("/v1/acl/roles", s.wrap(s.ACLRolesRequest))
+	s.mux.HandleFunc("/v1/acl/role/", s.wrap(s.ACLRoleSpecificRequest))
+	s.mux.HandleFunc("/v1/acl/auth-methods", s.wrap(s.ACLAuthMethodsRequest))
+	s.mux.HandleFunc("/v1/acl/auth-method/", s.wrap(s.ACLAuthMethodSpecificRequest))
+	s.mux.HandleFunc("/v1/acl/binding-rules", s.wrap(s.ACLBindingRulesRequest))
+	s.mux.HandleFunc("/v1/acl/binding-rule", s.wrap(s.ACLBindingRuleRequest))
+	s.mux.HandleFunc("/v1/acl/binding-rule/", s.wrap(s.ACLBindingRuleSpecificRequest))
+	s.mux.HandleFunc("/v1/acl/oidc/auth-url", s.wrap(s.ACLOIDCAuthURLRequest))
+	s.mux.HandleFunc("/v1/acl/oidc/complete-auth", s.wrap(s.ACLOIDCCompleteAuthRequest))
+	s.mux.HandleFunc("/v1/acl/login", s.wrap(s.ACLLoginRequest))
+	s.mux.HandleFunc("/v1/acl/tokens", s.wrap(s.ACLTokensRequest))
+	s.mux.HandleFunc("/v1/acl/token", s.wrap(s.ACLTokenRequest))
+	s.mux.HandleFunc("/v1/acl/token/", s.wrap(s.ACLTokenSpecificRequest))
+
+	s.mux.HandleFunc("/v1/services", s.wrap(s.ServiceRegistrationListRequest))
+	s.mux.HandleFunc("/v1/service/", s.wrap(s.ServiceRegistrationRequest))
+
 	s.mux.HandleFunc("/v1/client/fs/", s.wrap(s.FsRequest))
 	s.mux.HandleFunc("/v1/client/stats", s.wrap(s.ClientStatsRequest))
 	s.mux.HandleFunc("/v1/client/allocation/", s.wrap(s.ClientAllocRequest))
 	s.mux.HandleFunc("/v1/client/gc", s.wrap(s.ClientGCRequest))
+	s.mux.HandleFunc("/v1/client/metadata", s.wrap(s.ClientMetaRequest))
 
 	s.mux.HandleFunc("/v1/agent/self", s.wrap(s.AgentSelfRequest))
 	s.mux.HandleFunc("/v1/agent/join", s.wrap(s.AgentJoinRequest))
 	s.mux.HandleFunc("/v1/agent/members", s.wrap(s.AgentMembersRequest))
 	s.mux.HandleFunc("/v1/agent/force-leave", s.wrap(s.AgentForceLeaveRequest))
 	s.mux.HandleFunc("/v1/agent/servers", s.wrap(s.AgentServersRequest))
+	s.mux.HandleFunc("/v1/agent/health", s.wrap(s.AgentHealthRequest))
 	s.mux.HandleFunc("/v1/agent/keyring/", s.wrap(s.KeyringOperationRequest))
+	s.mux.HandleFunc("/v1/agent/metrics", s.wrap(s.AgentMetricsRequest))
+	s.mux.HandleFunc("/v1/agent/logs", s.wrap(s.AgentLogsRequest))
+	s.mux.HandleFunc("/v1/agent/monitor", s.wrap(s.AgentMonitorRequest))
+
+	s.mux.HandleFunc("/v1/metrics", s.wrap(s.MetricsRequest))
 
 	s.mux.HandleFunc("/v1/validate/job", s.wrap(s.ValidateJobRequest))
 
@@ -170,6 +257,8 @@ func (s *HTTPServer) registerHandlers(enableDebug bool) {
 	s.mux.HandleFunc("/v1/status/peers", s.wrap(s.StatusPeersRequest))
 
 	s.mux.HandleFunc("/v1/operator/", s.wrap(s.OperatorRequest))
+	s.mux.HandleFunc("/v1/operator/scheduler/configuration", s.wrap(s.OperatorSchedulerConfiguration))
+	s.mux.HandleFunc("/v1/operator/capacity", s.wrap(s.OperatorCapacityRequest))
 
 	s.mux.HandleFunc("/v1/system/gc", s.wrap(s.GarbageCollectRequest))
 	s.mux.HandleFunc("/v1/system/reconcile/summaries", s.wrap(s.ReconcileJobSummaries))
@@ -210,6 +299,60 @@ func (e *codedError) Code() int {
 func (s *HTTPServer) wrap(handler func(resp http.ResponseWriter, req *http.Request) (interface{}, error)) func(resp http.ResponseWriter, req *http.Request) {
 	f := func(resp http.ResponseWriter, req *http.Request) {
 		setHeaders(resp, s.agent.config.HTTPAPIResponseHeaders)
+
+		// Enforce the rate limits
+		if !s.limiter.Allow(clientIP(req)) {
+			writeRateLimited(resp)
+			return
+		}
+
+		// Trace the request, continuing the trace of the caller if any
+		span := s.agent.tracer.Start("HTTP "+req.Method+" "+req.URL.Path, req.Header.Get("traceparent"))
+		if span != nil {
+			span.SetKind(tracing.SpanKindServer)
+			span.SetAttribute("http.method", req.Method)
+			span.SetAttribute("http.target", req.URL.Path)
+			defer span.End()
+			req = req.WithContext(tracing.ContextWithSpan(req.Context(), span))
+		}
+
+		// Audit the request, rejecting it if its event can't be recorded
+		var err error
+		if s.agent.auditor != nil {
+			event, auditErr := s.auditReceived(req)
+			if auditErr != nil {
+				resp.WriteHeader(500)
+				resp.Write([]byte("failed to record the audit event of the request"))
+				return
+			}
+			auditResp := &auditResponseWriter{ResponseWriter: resp, status: 200}
+			resp = auditResp
+			var details *auditDetails
+			req, details = withAuditDetails(req)
+			defer func() {
+				s.auditComplete(event, details, auditResp, err)
+			}()
+		}
+
+		// Reject the tokens used from outside of their allowed CIDRs or over
+		// their rate limit. Anonymous requests are only limited per client IP.
+		var secretID string
+		var token *structs.ACLToken
+		s.parseToken(req, &secretID)
+		if token, err = s.checkTokenSource(secretID, clientIP(req)); err != nil {
+			code := 500
+			if isPermissionError(err) {
+				code = 403
+			}
+			resp.WriteHeader(code)
+			resp.Write([]byte(err.Error()))
+			return
+		}
+		if token != nil && !s.tokenLimiter.Allow(token.AccessorID) {
+			writeRateLimited(resp)
+			return
+		}
+
 		// Invoke the handler
 		reqURL := req.URL.String()
 		start := time.Now()
@@ -217,6 +360,7 @@ func (s *HTTPServer) wrap(handler func(resp http.ResponseWriter, req *http.Reque
 			s.logger.Printf("[DEBUG] http: Request %v (%v)", reqURL, time.Now().Sub(start))
 		}()
 		obj, err := handler(resp, req)
+		span.SetError(err)
 
 		// Check for an error
 	HAS_ERR:
@@ -225,6 +369,8 @@ func (s *HTTPServer) wrap(handler func(resp http.ResponseWriter, req *http.Reque
 			code := 500
 			if http, ok := err.(HTTPCodedError); ok {
 				code = http.Code()
+			} else if isPermissionError(err) {
+				code = 403
 			}
 			resp.WriteHeader(code)
 			resp.Write([]byte(err.Error()))
@@ -255,12 +401,47 @@ func (s *HTTPServer) wrap(handler func(resp http.ResponseWriter, req *http.Reque
 				goto HAS_ERR
 			}
 			resp.Header().Set("Content-Type", "application/json")
+
+			// Let clients skip responses identical to the one they have
+			if req.Method == "GET" {
+				etag := responseETag(buf.Bytes())
+				resp.Header().Set("ETag", etag)
+				if etagMatches(req.Header.Get("If-None-Match"), etag) {
+					resp.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+
 			resp.Write(buf.Bytes())
 		}
 	}
 	return f
 }
 
+// responseETag returns the entity tag of the given response body. The tag is
+// weak since the body may be compressed.
+func responseETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`W/"%x"`, sum[:16])
+}
+
+// etagMatches returns whether the If-None-Match header matches the given
+// entity tag, using the weak comparison.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // decodeBody is used to decode a JSON request body
 func decodeBody(req *http.Request, out interface{}) error {
 	dec := json.NewDecoder(req.Body)
@@ -287,11 +468,19 @@ func setLastContact(resp http.ResponseWriter, last time.Duration) {
 	resp.Header().Set("X-Nomad-LastContact", strconv.FormatUint(lastMsec, 10))
 }
 
+// setNextToken is used to set the next token header for paginated queries
+func setNextToken(resp http.ResponseWriter, token string) {
+	if token != "" {
+		resp.Header().Set("X-Nomad-NextToken", token)
+	}
+}
+
 // setMeta is used to set the query response meta data
 func setMeta(resp http.ResponseWriter, m *structs.QueryMeta) {
 	setIndex(resp, m.Index)
 	setLastContact(resp, m.LastContact)
 	setKnownLeader(resp, m.KnownLeader)
+	setNextToken(resp, m.NextToken)
 }
 
 // setHeaders is used to set canonical response header fields
@@ -342,6 +531,48 @@ func parsePrefix(req *http.Request, b *structs.QueryOptions) {
 	}
 }
 
+// parseNamespace is used to parse the ?namespace query param
+func parseNamespace(req *http.Request, b *structs.QueryOptions) {
+	if namespace := req.URL.Query().Get("namespace"); namespace != "" {
+		b.Namespace = namespace
+	}
+}
+
+// parsePagination is used to parse the ?per_page and ?next_token query params
+// Returns true on error
+func parsePagination(resp http.ResponseWriter, req *http.Request, b *structs.QueryOptions) bool {
+	query := req.URL.Query()
+	if perPage := query.Get("per_page"); perPage != "" {
+		n, err := strconv.ParseInt(perPage, 10, 32)
+		if err != nil || n < 0 {
+			resp.WriteHeader(400)
+			resp.Write([]byte("Invalid per_page"))
+			return true
+		}
+		b.PerPage = int32(n)
+	}
+	if token := query.Get("next_token"); token != "" {
+		b.NextToken = token
+	}
+	return false
+}
+
+// parseFilter is used to parse the ?filter query param
+// Returns true on error
+func parseFilter(resp http.ResponseWriter, req *http.Request, b *structs.QueryOptions) bool {
+	expr := req.URL.Query().Get("filter")
+	if expr == "" {
+		return false
+	}
+	if _, err := filter.Parse(expr); err != nil {
+		resp.WriteHeader(400)
+		resp.Write([]byte(fmt.Sprintf("Invalid filter: %v", err)))
+		return true
+	}
+	b.Filter = expr
+	return false
+}
+
 // parseRegion is used to parse the ?region query param
 func (s *HTTPServer) parseRegion(req *http.Request, r *string) {
 	if other := req.URL.Query().Get("region"); other != "" {
@@ -351,10 +582,112 @@ func (s *HTTPServer) parseRegion(req *http.Request, r *string) {
 	}
 }
 
+// parseTrace sets the traceparent of a write to the span of the request, so
+// the servers handling the write continue the trace of the request.
+func parseTrace(req *http.Request, tp *string) {
+	if span := tracing.SpanFromContext(req.Context()); span != nil {
+		*tp = span.TraceParent()
+	}
+}
+
+// parseToken is used to parse the X-Nomad-Token header or the ?token query
+// param
+func (s *HTTPServer) parseToken(req *http.Request, token *string) {
+	if other := req.Header.Get("X-Nomad-Token"); other != "" {
+		*token = other
+		return
+	}
+	if other := req.URL.Query().Get("token"); other != "" {
+		*token = other
+	}
+}
+
+// resolveToken resolves the token of the request to its ACL, which is nil if
+// ACLs are disabled. Servers resolve the tokens from their state while
+// clients resolve them through the servers.
+func (s *HTTPServer) resolveToken(req *http.Request) (*acl.ACL, error) {
+	var secretID string
+	s.parseToken(req, &secretID)
+	return s.resolveSecretID(secretID)
+}
+
+// resolveSecretID resolves the secret ID of a token to its ACL
+func (s *HTTPServer) resolveSecretID(secretID string) (*acl.ACL, error) {
+	if srv := s.agent.Server(); srv != nil {
+		return srv.ResolveToken(secretID)
+	}
+	if client := s.agent.Client(); client != nil {
+		return client.ResolveToken(secretID)
+	}
+	return nil, nil
+}
+
+// checkTokenSource returns the token of the secret ID, or ErrPermissionDenied
+// if it may not be used from the IP address. The token is nil if the secret ID
+// is empty or ACLs are disabled. The requests the agent forwards to the
+// servers are trusted by the servers as long as they are made over mutual TLS
+// or the agent is a server.
+func (s *HTTPServer) checkTokenSource(secretID, ip string) (*structs.ACLToken, error) {
+	if secretID == "" {
+		return nil, nil
+	}
+	token, err := s.resolveACLToken(secretID)
+	if err != nil {
+		return nil, err
+	}
+	if token != nil && !token.AllowsSource(ip) {
+		s.logger.Printf("[WARN] http: ACL token %s used from disallowed address %q", token.AccessorID, ip)
+		return nil, structs.ErrPermissionDenied
+	}
+	return token, nil
+}
+
+// resolveACLToken returns the token of the secret ID, which is nil if ACLs
+// are disabled or the secret ID isn't the one of an ACL token
+func (s *HTTPServer) resolveACLToken(secretID string) (*structs.ACLToken, error) {
+	if srv := s.agent.Server(); srv != nil {
+		return srv.ResolveACLToken(secretID)
+	}
+	if client := s.agent.Client(); client != nil {
+		return client.ResolveACLToken(secretID)
+	}
+	return nil, nil
+}
+
+// checkACL returns ErrPermissionDenied unless the ACL of the token of the
+// request passes the check
+func (s *HTTPServer) checkACL(req *http.Request, allowed func(*acl.ACL) bool) error {
+	aclObj, err := s.resolveToken(req)
+	if err != nil {
+		return err
+	}
+	if !allowed(aclObj) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
+// isPermissionError returns whether the error, which may have been returned
+// by an RPC, denies the request its permissions
+func isPermissionError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, structs.ErrPermissionDenied.Error()) ||
+		strings.Contains(msg, structs.ErrTokenNotFound.Error()) ||
+		strings.Contains(msg, structs.ErrTokenExpired.Error())
+}
+
 // parse is a convenience method for endpoints that need to parse multiple flags
 func (s *HTTPServer) parse(resp http.ResponseWriter, req *http.Request, r *string, b *structs.QueryOptions) bool {
 	s.parseRegion(req, r)
+	s.parseToken(req, &b.AuthToken)
 	parseConsistency(req, b)
 	parsePrefix(req, b)
+	parseNamespace(req, b)
+	if parsePagination(resp, req, b) {
+		return true
+	}
+	if parseFilter(resp, req, b) {
+		return true
+	}
 	return parseWait(resp, req, b)
 }
@@ -13,6 +13,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -164,6 +165,216 @@ func TestContentTypeIsJSON(t *testing.T) {
 	}
 }
 
+func TestHTTP_RateLimit(t *testing.T) {
+	t.Parallel()
+	s := makeHTTPServer(t, func(c *Config) {
+		c.Limits.HTTPRatePerIP = 0.001
+	})
+	defer s.Shutdown()
+
+	handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+		return &structs.Job{Name: "foo"}, nil
+	}
+
+	// The first request is within the burst
+	req, _ := http.NewRequest("GET", "/v1/kv/key", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	resp := httptest.NewRecorder()
+	s.Server.wrap(handler)(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+
+	// The second request from the same IP is limited
+	resp = httptest.NewRecorder()
+	s.Server.wrap(handler)(resp, req)
+	if resp.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", resp.Code)
+	}
+	if resp.Header().Get("Retry-After") == "" {
+		t.Fatalf("missing Retry-After header")
+	}
+
+	// Requests from other IPs are not limited
+	req.RemoteAddr = "10.0.0.2:1234"
+	resp = httptest.NewRecorder()
+	s.Server.wrap(handler)(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+}
+
+func TestHTTP_RateLimitPerToken(t *testing.T) {
+	t.Parallel()
+	httpACLTest(t, func(c *Config) {
+		c.Limits.HTTPRatePerToken = 0.001
+	}, func(s *TestAgent, root *structs.ACLToken) {
+		token := mock.ACLManagementToken()
+		if err := s.Agent.server.State().UpsertACLTokens(1000, []*structs.ACLToken{token}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+			return &structs.Job{Name: "foo"}, nil
+		}
+		cases := []struct {
+			addr  string
+			token *structs.ACLToken
+			code  int
+		}{
+			// The limit of a token applies across client IPs
+			{"10.0.0.1:1234", token, http.StatusOK},
+			{"10.0.0.2:1234", token, http.StatusTooManyRequests},
+
+			// Other tokens and anonymous requests are not limited by it
+			{"10.0.0.1:1234", root, http.StatusOK},
+			{"10.0.0.1:1234", nil, http.StatusOK},
+			{"10.0.0.1:1234", nil, http.StatusOK},
+		}
+		for i, c := range cases {
+			req, _ := http.NewRequest("GET", "/v1/kv/key", nil)
+			req.RemoteAddr = c.addr
+			if c.token != nil {
+				setToken(req, c.token)
+			}
+			resp := httptest.NewRecorder()
+			s.Server.wrap(handler)(resp, req)
+			if resp.Code != c.code {
+				t.Fatalf("case %d: expected %d, got %d", i, c.code, resp.Code)
+			}
+		}
+	})
+}
+
+func TestHTTP_TokenSource(t *testing.T) {
+	t.Parallel()
+	httpACLTest(t, nil, func(s *TestAgent, root *structs.ACLToken) {
+		token := mock.ACLManagementToken()
+		token.AllowedCIDRs = []string{"10.0.0.0/8"}
+		if err := s.Agent.server.State().UpsertACLTokens(1000, []*structs.ACLToken{token}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+			return &structs.Job{Name: "foo"}, nil
+		}
+		cases := []struct {
+			addr  string
+			token *structs.ACLToken
+			code  int
+		}{
+			{"10.0.0.1:1234", token, http.StatusOK},
+			{"192.168.1.1:1234", token, http.StatusForbidden},
+			{"192.168.1.1:1234", root, http.StatusOK},
+		}
+		for _, c := range cases {
+			req, _ := http.NewRequest("GET", "/v1/kv/key", nil)
+			req.RemoteAddr = c.addr
+			setToken(req, c.token)
+			resp := httptest.NewRecorder()
+			s.Server.wrap(handler)(resp, req)
+			if resp.Code != c.code {
+				t.Fatalf("%s: expected %d, got %d", c.addr, c.code, resp.Code)
+			}
+		}
+	})
+}
+
+func TestHTTP_ETag(t *testing.T) {
+	t.Parallel()
+	s := makeHTTPServer(t, nil)
+	defer s.Shutdown()
+
+	job := &structs.Job{Name: "foo"}
+	handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+		return job, nil
+	}
+
+	// The response is tagged
+	req, _ := http.NewRequest("GET", "/v1/jobs", nil)
+	resp := httptest.NewRecorder()
+	s.Server.wrap(handler)(resp, req)
+	etag := resp.Header().Get("ETag")
+	if resp.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected tagged response, got %d %q", resp.Code, etag)
+	}
+
+	// A matching request isn't sent the body again
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	resp = httptest.NewRecorder()
+	s.Server.wrap(handler)(resp, req)
+	if resp.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", resp.Code)
+	}
+	if resp.Body.Len() != 0 {
+		t.Fatalf("unexpected body: %s", resp.Body.String())
+	}
+
+	// A changed response is sent with a new tag
+	job = &structs.Job{Name: "bar"}
+	resp = httptest.NewRecorder()
+	s.Server.wrap(handler)(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.Code)
+	}
+	if newTag := resp.Header().Get("ETag"); newTag == etag {
+		t.Fatalf("expected a new tag, got %q", newTag)
+	}
+}
+
+func TestHTTP_Trace(t *testing.T) {
+	t.Parallel()
+	s := makeHTTPServer(t, func(c *Config) {
+		c.Telemetry.OTLPTracesEndpoint = "http://127.0.0.1:1"
+	})
+	defer s.Shutdown()
+
+	var traceParent string
+	handler := func(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+		parseTrace(req, &traceParent)
+		return nil, nil
+	}
+
+	// The request continues the trace of the caller
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req, _ := http.NewRequest("PUT", "/v1/jobs", nil)
+	req.Header.Set("traceparent", parent)
+	s.Server.wrap(handler)(httptest.NewRecorder(), req)
+	if !strings.HasPrefix(traceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-") ||
+		traceParent == parent || !strings.HasSuffix(traceParent, "-01") {
+		t.Fatalf("bad traceparent: %q", traceParent)
+	}
+
+	// Without a traceparent header a new trace is started
+	traceParent = ""
+	req, _ = http.NewRequest("PUT", "/v1/jobs", nil)
+	s.Server.wrap(handler)(httptest.NewRecorder(), req)
+	if traceParent == "" || strings.Contains(traceParent, "4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Fatalf("bad traceparent: %q", traceParent)
+	}
+}
+
+func TestHTTP_ETagMatches(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		header string
+		match  bool
+	}{
+		{"", false},
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`"def", W/"abc"`, true},
+		{`"def"`, false},
+		{"*", true},
+	}
+
+	for _, c := range cases {
+		if got := etagMatches(c.header, `W/"abc"`); got != c.match {
+			t.Fatalf("If-None-Match %q: expected %v, got %v", c.header, c.match, got)
+		}
+	}
+}
+
 func TestPrettyPrint(t *testing.T) {
 	t.Parallel()
 	testPrettyPrint("pretty=1", true, t)
@@ -274,6 +485,50 @@ func TestParseWait_InvalidIndex(t *testing.T) {
 	}
 }
 
+func TestParsePagination(t *testing.T) {
+	t.Parallel()
+	resp := httptest.NewRecorder()
+	var b structs.QueryOptions
+
+	req, err := http.NewRequest("GET",
+		"/v1/jobs?per_page=10&next_token=example%2Fjob", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if d := parsePagination(resp, req, &b); d {
+		t.Fatalf("unexpected done")
+	}
+
+	if b.PerPage != 10 {
+		t.Fatalf("Bad: %v", b)
+	}
+	if b.NextToken != "example/job" {
+		t.Fatalf("Bad: %v", b)
+	}
+}
+
+func TestParsePagination_InvalidPerPage(t *testing.T) {
+	t.Parallel()
+	for _, perPage := range []string{"foo", "-1", "4294967296"} {
+		resp := httptest.NewRecorder()
+		var b structs.QueryOptions
+
+		req, err := http.NewRequest("GET", "/v1/jobs?per_page="+perPage, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if d := parsePagination(resp, req, &b); !d {
+			t.Fatalf("expected done for %q", perPage)
+		}
+
+		if resp.Code != 400 {
+			t.Fatalf("bad code for %q: %v", perPage, resp.Code)
+		}
+	}
+}
+
 func TestParseConsistency(t *testing.T) {
 	t.Parallel()
 	var b structs.QueryOptions
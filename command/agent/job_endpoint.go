@@ -8,6 +8,7 @@ import (
 
 	"github.com/golang/snappy"
 	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -52,6 +53,9 @@ func (s *HTTPServer) JobSpecificRequest(resp http.ResponseWriter, req *http.Requ
 	case strings.HasSuffix(path, "/evaluations"):
 		jobName := strings.TrimSuffix(path, "/evaluations")
 		return s.jobEvaluations(resp, req, jobName)
+	case strings.HasSuffix(path, "/dependencies"):
+		jobName := strings.TrimSuffix(path, "/dependencies")
+		return s.jobDependencies(resp, req, jobName)
 	case strings.HasSuffix(path, "/periodic/force"):
 		jobName := strings.TrimSuffix(path, "/periodic/force")
 		return s.periodicForceRequest(resp, req, jobName)
@@ -79,6 +83,12 @@ func (s *HTTPServer) JobSpecificRequest(resp http.ResponseWriter, req *http.Requ
 	case strings.HasSuffix(path, "/stable"):
 		jobName := strings.TrimSuffix(path, "/stable")
 		return s.jobStable(resp, req, jobName)
+	case strings.HasSuffix(path, "/priority"):
+		jobName := strings.TrimSuffix(path, "/priority")
+		return s.jobUpdatePriority(resp, req, jobName)
+	case strings.HasSuffix(path, "/scale"):
+		jobName := strings.TrimSuffix(path, "/scale")
+		return s.jobScale(resp, req, jobName)
 	default:
 		return s.jobCRUD(resp, req, path)
 	}
@@ -93,6 +103,8 @@ func (s *HTTPServer) jobForceEvaluate(resp http.ResponseWriter, req *http.Reques
 		JobID: jobName,
 	}
 	s.parseRegion(req, &args.Region)
+	parseTrace(req, &args.TraceParent)
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.JobRegisterResponse
 	if err := s.agent.RPC("Job.Evaluate", &args, &out); err != nil {
@@ -122,15 +134,18 @@ func (s *HTTPServer) jobPlan(resp http.ResponseWriter, req *http.Request,
 		return nil, CodedError(400, "Job ID does not match")
 	}
 	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.SecretID)
 
 	sJob := ApiJobToStructJob(args.Job)
 	planReq := structs.JobPlanRequest{
 		Job:  sJob,
 		Diff: args.Diff,
 		WriteRequest: structs.WriteRequest{
-			Region: args.WriteRequest.Region,
+			Region:    args.WriteRequest.Region,
+			AuthToken: args.WriteRequest.SecretID,
 		},
 	}
+	parseTrace(req, &planReq.TraceParent)
 	var out structs.JobPlanResponse
 	if err := s.agent.RPC("Job.Plan", &planReq, &out); err != nil {
 		return nil, err
@@ -157,10 +172,12 @@ func (s *HTTPServer) ValidateJobRequest(resp http.ResponseWriter, req *http.Requ
 	args := structs.JobValidateRequest{
 		Job: job,
 		WriteRequest: structs.WriteRequest{
-			Region: validateRequest.Region,
+			Region:    validateRequest.Region,
+			AuthToken: validateRequest.SecretID,
 		},
 	}
 	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.JobValidateResponse
 	if err := s.agent.RPC("Job.Validate", &args, &out); err != nil {
@@ -180,6 +197,7 @@ func (s *HTTPServer) periodicForceRequest(resp http.ResponseWriter, req *http.Re
 		JobID: jobName,
 	}
 	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.PeriodicForceResponse
 	if err := s.agent.RPC("Periodic.Force", &args, &out); err != nil {
@@ -240,6 +258,30 @@ func (s *HTTPServer) jobEvaluations(resp http.ResponseWriter, req *http.Request,
 	return out.Evaluations, nil
 }
 
+func (s *HTTPServer) jobDependencies(resp http.ResponseWriter, req *http.Request,
+	jobName string) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+	args := structs.JobSpecificRequest{
+		JobID: jobName,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.JobDependenciesResponse
+	if err := s.agent.RPC("Job.Dependencies", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Nodes == nil {
+		return nil, CodedError(404, "job not found")
+	}
+	return out.Nodes, nil
+}
+
 func (s *HTTPServer) jobDeployments(resp http.ResponseWriter, req *http.Request,
 	jobName string) (interface{}, error) {
 	if req.Method != "GET" {
@@ -349,17 +391,25 @@ func (s *HTTPServer) jobUpdate(resp http.ResponseWriter, req *http.Request,
 		return nil, CodedError(400, "Job ID does not match name")
 	}
 	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.SecretID)
+	if args.PolicyOverride {
+		auditPolicyOverride(req, args.PolicyOverrideJustification)
+	}
 
 	sJob := ApiJobToStructJob(args.Job)
 
 	regReq := structs.JobRegisterRequest{
-		Job:            sJob,
-		EnforceIndex:   args.EnforceIndex,
-		JobModifyIndex: args.JobModifyIndex,
+		Job:                         sJob,
+		EnforceIndex:                args.EnforceIndex,
+		JobModifyIndex:              args.JobModifyIndex,
+		PolicyOverride:              args.PolicyOverride,
+		PolicyOverrideJustification: args.PolicyOverrideJustification,
 		WriteRequest: structs.WriteRequest{
-			Region: args.WriteRequest.Region,
+			Region:    args.WriteRequest.Region,
+			AuthToken: args.WriteRequest.SecretID,
 		},
 	}
+	parseTrace(req, &regReq.TraceParent)
 	var out structs.JobRegisterResponse
 	if err := s.agent.RPC("Job.Register", &regReq, &out); err != nil {
 		return nil, err
@@ -386,6 +436,8 @@ func (s *HTTPServer) jobDelete(resp http.ResponseWriter, req *http.Request,
 		Purge: purgeBool,
 	}
 	s.parseRegion(req, &args.Region)
+	parseTrace(req, &args.TraceParent)
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.JobDeregisterResponse
 	if err := s.agent.RPC("Job.Deregister", &args, &out); err != nil {
@@ -412,6 +464,14 @@ func (s *HTTPServer) jobVersions(resp http.ResponseWriter, req *http.Request,
 		JobID: jobName,
 		Diffs: diffsBool,
 	}
+
+	if diffVersionStr := req.URL.Query().Get("diff_version"); diffVersionStr != "" {
+		diffVersion, err := strconv.ParseUint(diffVersionStr, 10, 64)
+		if err != nil {
+			return nil, CodedError(400, fmt.Sprintf("Failed to parse value of %q (%v) as a uint64: %v", "diff_version", diffVersionStr, err))
+		}
+		args.DiffVersion = &diffVersion
+	}
 	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
 		return nil, nil
 	}
@@ -449,6 +509,8 @@ func (s *HTTPServer) jobRevert(resp http.ResponseWriter, req *http.Request,
 
 	s.parseRegion(req, &revertRequest.Region)
 
+	s.parseToken(req, &revertRequest.AuthToken)
+
 	var out structs.JobRegisterResponse
 	if err := s.agent.RPC("Job.Revert", &revertRequest, &out); err != nil {
 		return nil, err
@@ -478,6 +540,8 @@ func (s *HTTPServer) jobStable(resp http.ResponseWriter, req *http.Request,
 
 	s.parseRegion(req, &stableRequest.Region)
 
+	s.parseToken(req, &stableRequest.AuthToken)
+
 	var out structs.JobStabilityResponse
 	if err := s.agent.RPC("Job.Stable", &stableRequest, &out); err != nil {
 		return nil, err
@@ -487,6 +551,67 @@ func (s *HTTPServer) jobStable(resp http.ResponseWriter, req *http.Request,
 	return out, nil
 }
 
+func (s *HTTPServer) jobUpdatePriority(resp http.ResponseWriter, req *http.Request,
+	jobName string) (interface{}, error) {
+
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var priorityRequest structs.JobUpdatePriorityRequest
+	if err := decodeBody(req, &priorityRequest); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if priorityRequest.JobID == "" {
+		return nil, CodedError(400, "JobID must be specified")
+	}
+	if priorityRequest.JobID != jobName {
+		return nil, CodedError(400, "Job ID does not match")
+	}
+
+	s.parseRegion(req, &priorityRequest.Region)
+	s.parseToken(req, &priorityRequest.AuthToken)
+
+	var out structs.JobUpdatePriorityResponse
+	if err := s.agent.RPC("Job.UpdatePriority", &priorityRequest, &out); err != nil {
+		return nil, err
+	}
+
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
+func (s *HTTPServer) jobScale(resp http.ResponseWriter, req *http.Request,
+	jobName string) (interface{}, error) {
+
+	if req.Method != "PUT" && req.Method != "POST" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var scaleRequest structs.JobScaleRequest
+	if err := decodeBody(req, &scaleRequest); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+	if scaleRequest.JobID == "" {
+		return nil, CodedError(400, "JobID must be specified")
+	}
+	if scaleRequest.JobID != jobName {
+		return nil, CodedError(400, "Job ID does not match")
+	}
+
+	s.parseRegion(req, &scaleRequest.Region)
+
+	s.parseToken(req, &scaleRequest.AuthToken)
+
+	var out structs.JobRegisterResponse
+	if err := s.agent.RPC("Job.Scale", &scaleRequest, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	return out, nil
+}
+
 func (s *HTTPServer) jobSummaryRequest(resp http.ResponseWriter, req *http.Request, name string) (interface{}, error) {
 	args := structs.JobSummaryRequest{
 		JobID: name,
@@ -524,6 +649,9 @@ func (s *HTTPServer) jobDispatchRequest(resp http.ResponseWriter, req *http.Requ
 	}
 
 	s.parseRegion(req, &args.Region)
+	parseTrace(req, &args.TraceParent)
+
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.JobDispatchResponse
 	if err := s.agent.RPC("Job.Dispatch", &args, &out); err != nil {
@@ -541,11 +669,13 @@ func ApiJobToStructJob(job *api.Job) *structs.Job {
 		Region:      *job.Region,
 		ID:          *job.ID,
 		ParentID:    *job.ParentID,
+		Namespace:   *job.Namespace,
 		Name:        *job.Name,
 		Type:        *job.Type,
 		Priority:    *job.Priority,
 		AllAtOnce:   *job.AllAtOnce,
 		Datacenters: job.Datacenters,
+		DependsOn:   job.DependsOn,
 		Payload:     job.Payload,
 		Meta:        job.Meta,
 		VaultToken:  *job.VaultToken,
@@ -578,11 +708,15 @@ func ApiJobToStructJob(job *api.Job) *structs.Job {
 			SpecType:        *job.Periodic.SpecType,
 			ProhibitOverlap: *job.Periodic.ProhibitOverlap,
 			TimeZone:        *job.Periodic.TimeZone,
+			Specs:           job.Periodic.Specs,
 		}
 
 		if job.Periodic.Spec != nil {
 			j.Periodic.Spec = *job.Periodic.Spec
 		}
+		if job.Periodic.DSTMode != nil {
+			j.Periodic.DSTMode = *job.Periodic.DSTMode
+		}
 	}
 
 	if job.ParameterizedJob != nil {
@@ -591,6 +725,48 @@ func ApiJobToStructJob(job *api.Job) *structs.Job {
 			MetaRequired: job.ParameterizedJob.MetaRequired,
 			MetaOptional: job.ParameterizedJob.MetaOptional,
 		}
+
+		if l := len(job.ParameterizedJob.MetaSchema); l != 0 {
+			j.ParameterizedJob.MetaSchema = make(map[string]*structs.DispatchMetaSchema, l)
+			for k, v := range job.ParameterizedJob.MetaSchema {
+				j.ParameterizedJob.MetaSchema[k] = &structs.DispatchMetaSchema{
+					Type:    v.Type,
+					Pattern: v.Pattern,
+					Allowed: v.Allowed,
+				}
+			}
+		}
+
+		if p := job.ParameterizedJob.PayloadSchema; p != nil {
+			j.ParameterizedJob.PayloadSchema = &structs.DispatchPayloadSchema{
+				MaxSize:        p.MaxSize,
+				ContentType:    p.ContentType,
+				RequiredFields: p.RequiredFields,
+			}
+		}
+	}
+
+	if g := job.GC; g != nil {
+		j.GC = &structs.JobGCConfig{}
+		if g.AllocThreshold != nil {
+			j.GC.AllocThreshold = *g.AllocThreshold
+		}
+		if g.EvalThreshold != nil {
+			j.GC.EvalThreshold = *g.EvalThreshold
+		}
+		if g.DeploymentThreshold != nil {
+			j.GC.DeploymentThreshold = *g.DeploymentThreshold
+		}
+	}
+
+	if n := job.Notification; n != nil {
+		j.Notification = &structs.JobNotification{
+			Webhook: *n.Webhook,
+			Method:  *n.Method,
+			Headers: n.Headers,
+			Payload: *n.Payload,
+			Timeout: *n.Timeout,
+		}
 	}
 
 	if l := len(job.TaskGroups); l != 0 {
@@ -632,15 +808,39 @@ func ApiTgToStructsTG(taskGroup *api.TaskGroup, tg *structs.TaskGroup) {
 		Migrate: *taskGroup.EphemeralDisk.Migrate,
 	}
 
+	if taskGroup.Consul != nil {
+		tg.Consul = &structs.Consul{
+			Namespace: taskGroup.Consul.Namespace,
+			Partition: taskGroup.Consul.Partition,
+		}
+	}
+
+	if taskGroup.StopAfterClientDisconnect != nil {
+		tg.StopAfterClientDisconnect = helper.TimeToPtr(*taskGroup.StopAfterClientDisconnect)
+	}
+
+	if taskGroup.MaxClientDisconnect != nil {
+		tg.MaxClientDisconnect = helper.TimeToPtr(*taskGroup.MaxClientDisconnect)
+	}
+
+	if l := len(taskGroup.Networks); l != 0 {
+		tg.Networks = make([]*structs.NetworkResource, l)
+		for i, nw := range taskGroup.Networks {
+			tg.Networks[i] = ApiNetworkResourceToStructs(nw)
+		}
+	}
+
 	if taskGroup.Update != nil {
 		tg.Update = &structs.UpdateStrategy{
-			Stagger:         *taskGroup.Update.Stagger,
-			MaxParallel:     *taskGroup.Update.MaxParallel,
-			HealthCheck:     *taskGroup.Update.HealthCheck,
-			MinHealthyTime:  *taskGroup.Update.MinHealthyTime,
-			HealthyDeadline: *taskGroup.Update.HealthyDeadline,
-			AutoRevert:      *taskGroup.Update.AutoRevert,
-			Canary:          *taskGroup.Update.Canary,
+			Stagger:          *taskGroup.Update.Stagger,
+			MaxParallel:      *taskGroup.Update.MaxParallel,
+			HealthCheck:      *taskGroup.Update.HealthCheck,
+			MinHealthyTime:   *taskGroup.Update.MinHealthyTime,
+			HealthyDeadline:  *taskGroup.Update.HealthyDeadline,
+			AutoRevert:       *taskGroup.Update.AutoRevert,
+			Canary:           *taskGroup.Update.Canary,
+			AutoPromote:      *taskGroup.Update.AutoPromote,
+			ProgressDeadline: *taskGroup.Update.ProgressDeadline,
 		}
 	}
 
@@ -654,11 +854,117 @@ func ApiTgToStructsTG(taskGroup *api.TaskGroup, tg *structs.TaskGroup) {
 	}
 }
 
+// ApiConsulConnectToStructs converts the Connect block of a service.
+func ApiConsulConnectToStructs(in *api.ConsulConnect) *structs.ConsulConnect {
+	out := &structs.ConsulConnect{}
+	if in.SidecarService != nil {
+		out.SidecarService = &structs.ConsulSidecarService{}
+		if proxy := in.SidecarService.Proxy; proxy != nil {
+			out.SidecarService.Proxy = &structs.ConsulProxy{
+				MeshGateway: apiMeshGatewayToStructs(proxy.MeshGateway),
+			}
+			for _, u := range proxy.Upstreams {
+				out.SidecarService.Proxy.Upstreams = append(out.SidecarService.Proxy.Upstreams, &structs.ConsulUpstream{
+					DestinationName:      u.DestinationName,
+					DestinationNamespace: u.DestinationNamespace,
+					Datacenter:           u.Datacenter,
+					LocalBindAddress:     u.LocalBindAddress,
+					LocalBindPort:        u.LocalBindPort,
+					MeshGateway:          apiMeshGatewayToStructs(u.MeshGateway),
+				})
+			}
+		}
+	}
+
+	if t := in.SidecarTask; t != nil {
+		out.SidecarTask = &structs.SidecarTask{
+			Driver:      t.Driver,
+			User:        t.User,
+			Config:      t.Config,
+			Env:         t.Env,
+			Meta:        t.Meta,
+			KillTimeout: t.KillTimeout,
+		}
+		if r := t.Resources; r != nil {
+			out.SidecarTask.Resources = &structs.Resources{}
+			if r.CPU != nil {
+				out.SidecarTask.Resources.CPU = *r.CPU
+			}
+			if r.MemoryMB != nil {
+				out.SidecarTask.Resources.MemoryMB = *r.MemoryMB
+			}
+			if r.IOPS != nil {
+				out.SidecarTask.Resources.IOPS = *r.IOPS
+			}
+		}
+		if l := t.LogConfig; l != nil {
+			out.SidecarTask.LogConfig = &structs.LogConfig{}
+			if l.MaxFiles != nil {
+				out.SidecarTask.LogConfig.MaxFiles = *l.MaxFiles
+			}
+			if l.MaxFileSizeMB != nil {
+				out.SidecarTask.LogConfig.MaxFileSizeMB = *l.MaxFileSizeMB
+			}
+		}
+	}
+
+	if g := in.Gateway; g != nil {
+		out.Gateway = &structs.ConsulGateway{}
+		if g.Proxy != nil {
+			out.Gateway.Proxy = &structs.ConsulGatewayProxy{
+				ConnectTimeout: g.Proxy.ConnectTimeout,
+			}
+		}
+		if g.Ingress != nil {
+			out.Gateway.Ingress = &structs.ConsulIngressConfigEntry{}
+			for _, l := range g.Ingress.Listeners {
+				listener := &structs.ConsulIngressListener{
+					Port:     l.Port,
+					Protocol: l.Protocol,
+				}
+				for _, s := range l.Services {
+					listener.Services = append(listener.Services, &structs.ConsulIngressService{
+						Name:  s.Name,
+						Hosts: s.Hosts,
+					})
+				}
+				out.Gateway.Ingress.Listeners = append(out.Gateway.Ingress.Listeners, listener)
+			}
+		}
+		if g.Terminating != nil {
+			out.Gateway.Terminating = &structs.ConsulTerminatingConfigEntry{}
+			for _, s := range g.Terminating.Services {
+				out.Gateway.Terminating.Services = append(out.Gateway.Terminating.Services, &structs.ConsulLinkedService{
+					Name:     s.Name,
+					CAFile:   s.CAFile,
+					CertFile: s.CertFile,
+					KeyFile:  s.KeyFile,
+					SNI:      s.SNI,
+				})
+			}
+		}
+		if g.Mesh != nil {
+			out.Gateway.Mesh = &structs.ConsulMeshConfigEntry{}
+		}
+	}
+	return out
+}
+
+func apiMeshGatewayToStructs(in *api.ConsulMeshGateway) *structs.ConsulMeshGateway {
+	if in == nil {
+		return nil
+	}
+	return &structs.ConsulMeshGateway{
+		Mode: in.Mode,
+	}
+}
+
 func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 	structsTask.Name = apiTask.Name
 	structsTask.Driver = apiTask.Driver
 	structsTask.User = apiTask.User
 	structsTask.Leader = apiTask.Leader
+	structsTask.Kind = structs.TaskKind(apiTask.Kind)
 	structsTask.Config = apiTask.Config
 	structsTask.Env = apiTask.Env
 	structsTask.Meta = apiTask.Meta
@@ -677,10 +983,14 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 		structsTask.Services = make([]*structs.Service, l)
 		for i, service := range apiTask.Services {
 			structsTask.Services[i] = &structs.Service{
-				Name:        service.Name,
-				PortLabel:   service.PortLabel,
-				Tags:        service.Tags,
-				AddressMode: service.AddressMode,
+				Name:            service.Name,
+				PortLabel:       service.PortLabel,
+				Tags:            service.Tags,
+				CanaryTags:      service.CanaryTags,
+				AddressMode:     service.AddressMode,
+				Provider:        service.Provider,
+				Meta:            service.Meta,
+				TaggedAddresses: service.TaggedAddresses,
 			}
 
 			if l := len(service.Checks); l != 0 {
@@ -699,8 +1009,19 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 						InitialStatus: check.InitialStatus,
 						TLSSkipVerify: check.TLSSkipVerify,
 					}
+					if check.CheckRestart != nil {
+						structsTask.Services[i].Checks[j].CheckRestart = &structs.CheckRestart{
+							Limit:          check.CheckRestart.Limit,
+							Grace:          *check.CheckRestart.Grace,
+							IgnoreWarnings: check.CheckRestart.IgnoreWarnings,
+						}
+					}
 				}
 			}
+
+			if service.Connect != nil {
+				structsTask.Services[i].Connect = ApiConsulConnectToStructs(service.Connect)
+			}
 		}
 	}
 
@@ -713,31 +1034,7 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 	if l := len(apiTask.Resources.Networks); l != 0 {
 		structsTask.Resources.Networks = make([]*structs.NetworkResource, l)
 		for i, nw := range apiTask.Resources.Networks {
-			structsTask.Resources.Networks[i] = &structs.NetworkResource{
-				CIDR:  nw.CIDR,
-				IP:    nw.IP,
-				MBits: *nw.MBits,
-			}
-
-			if l := len(nw.DynamicPorts); l != 0 {
-				structsTask.Resources.Networks[i].DynamicPorts = make([]structs.Port, l)
-				for j, dp := range nw.DynamicPorts {
-					structsTask.Resources.Networks[i].DynamicPorts[j] = structs.Port{
-						Label: dp.Label,
-						Value: dp.Value,
-					}
-				}
-			}
-
-			if l := len(nw.ReservedPorts); l != 0 {
-				structsTask.Resources.Networks[i].ReservedPorts = make([]structs.Port, l)
-				for j, rp := range nw.ReservedPorts {
-					structsTask.Resources.Networks[i].ReservedPorts[j] = structs.Port{
-						Label: rp.Label,
-						Value: rp.Value,
-					}
-				}
-			}
+			structsTask.Resources.Networks[i] = ApiNetworkResourceToStructs(nw)
 		}
 	}
 
@@ -758,12 +1055,16 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 		}
 	}
 
-	if apiTask.Vault != nil {
+	// Tasks can disable the Vault block inherited from their group or job
+	if apiTask.Vault != nil && *apiTask.Vault.Enabled {
 		structsTask.Vault = &structs.Vault{
 			Policies:     apiTask.Vault.Policies,
+			Role:         *apiTask.Vault.Role,
+			Namespace:    *apiTask.Vault.Namespace,
 			Env:          *apiTask.Vault.Env,
 			ChangeMode:   *apiTask.Vault.ChangeMode,
 			ChangeSignal: *apiTask.Vault.ChangeSignal,
+			ChangeScript: ApiChangeScriptToStructs(apiTask.Vault.ChangeScript),
 		}
 	}
 
@@ -771,17 +1072,26 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 		structsTask.Templates = make([]*structs.Template, l)
 		for i, template := range apiTask.Templates {
 			structsTask.Templates[i] = &structs.Template{
-				SourcePath:   *template.SourcePath,
-				DestPath:     *template.DestPath,
-				EmbeddedTmpl: *template.EmbeddedTmpl,
-				ChangeMode:   *template.ChangeMode,
-				ChangeSignal: *template.ChangeSignal,
-				Splay:        *template.Splay,
-				Perms:        *template.Perms,
-				LeftDelim:    *template.LeftDelim,
-				RightDelim:   *template.RightDelim,
-				Envvars:      *template.Envvars,
-				VaultGrace:   *template.VaultGrace,
+				SourcePath:    *template.SourcePath,
+				DestPath:      *template.DestPath,
+				EmbeddedTmpl:  *template.EmbeddedTmpl,
+				ChangeMode:    *template.ChangeMode,
+				ChangeSignal:  *template.ChangeSignal,
+				ChangeScript:  ApiChangeScriptToStructs(template.ChangeScript),
+				Splay:         *template.Splay,
+				Perms:         *template.Perms,
+				LeftDelim:     *template.LeftDelim,
+				RightDelim:    *template.RightDelim,
+				Envvars:       *template.Envvars,
+				EnvKeys:       template.EnvKeys,
+				ErrMissingKey: *template.ErrMissingKey,
+				VaultGrace:    *template.VaultGrace,
+			}
+			if template.Wait != nil {
+				structsTask.Templates[i].Wait = &structs.WaitConfig{
+					Min: *template.Wait.Min,
+					Max: *template.Wait.Max,
+				}
 			}
 		}
 	}
@@ -791,6 +1101,28 @@ func ApiTaskToStructsTask(apiTask *api.Task, structsTask *structs.Task) {
 			File: apiTask.DispatchPayload.File,
 		}
 	}
+
+	if apiTask.Lifecycle != nil {
+		structsTask.Lifecycle = &structs.TaskLifecycleConfig{
+			Hook:    apiTask.Lifecycle.Hook,
+			Sidecar: apiTask.Lifecycle.Sidecar,
+		}
+	}
+}
+
+// ApiChangeScriptToStructs converts the change script of a template or Vault
+// block. Returns nil if the change script is unset.
+func ApiChangeScriptToStructs(in *api.ChangeScript) *structs.ChangeScript {
+	if in == nil {
+		return nil
+	}
+
+	return &structs.ChangeScript{
+		Command:     *in.Command,
+		Args:        in.Args,
+		Timeout:     *in.Timeout,
+		FailOnError: *in.FailOnError,
+	}
 }
 
 func ApiConstraintToStructs(c1 *api.Constraint, c2 *structs.Constraint) {
@@ -798,3 +1130,39 @@ func ApiConstraintToStructs(c1 *api.Constraint, c2 *structs.Constraint) {
 	c2.RTarget = c1.RTarget
 	c2.Operand = c1.Operand
 }
+
+// ApiNetworkResourceToStructs converts a task or group network.
+func ApiNetworkResourceToStructs(nw *api.NetworkResource) *structs.NetworkResource {
+	n := &structs.NetworkResource{
+		Mode:        nw.Mode,
+		HostNetwork: nw.HostNetwork,
+		CIDR:        nw.CIDR,
+		IP:          nw.IP,
+	}
+	if nw.MBits != nil {
+		n.MBits = *nw.MBits
+	}
+
+	if l := len(nw.DynamicPorts); l != 0 {
+		n.DynamicPorts = make([]structs.Port, l)
+		for j, dp := range nw.DynamicPorts {
+			n.DynamicPorts[j] = structs.Port{
+				Label: dp.Label,
+				Value: dp.Value,
+				To:    dp.To,
+			}
+		}
+	}
+
+	if l := len(nw.ReservedPorts); l != 0 {
+		n.ReservedPorts = make([]structs.Port, l)
+		for j, rp := range nw.ReservedPorts {
+			n.ReservedPorts[j] = structs.Port{
+				Label: rp.Label,
+				Value: rp.Value,
+				To:    rp.To,
+			}
+		}
+	}
+	return n
+}
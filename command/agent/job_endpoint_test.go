@@ -561,6 +561,62 @@ func TestHTTP_JobEvaluations(t *testing.T) {
 	})
 }
 
+func TestHTTP_JobDependencies(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// Create the job and the job it depends on
+		dep := mock.Job()
+		dep.Type = structs.JobTypeBatch
+		job := mock.Job()
+		job.Type = structs.JobTypeBatch
+		job.DependsOn = []string{dep.ID}
+		for _, j := range []*structs.Job{dep, job} {
+			args := structs.JobRegisterRequest{
+				Job:          j,
+				WriteRequest: structs.WriteRequest{Region: "global"},
+			}
+			var resp structs.JobRegisterResponse
+			if err := s.Agent.RPC("Job.Register", &args, &resp); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/job/"+job.ID+"/dependencies", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		nodes := obj.([]*structs.JobDAGNode)
+		if len(nodes) != 2 || nodes[0].JobID != dep.ID || nodes[1].JobID != job.ID {
+			t.Fatalf("bad: %#v", nodes)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+
+		// A job that doesn't exist isn't found
+		req, err = http.NewRequest("GET", "/v1/job/missing/dependencies", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		_, err = s.Server.JobSpecificRequest(httptest.NewRecorder(), req)
+		if err == nil || !strings.Contains(err.Error(), "job not found") {
+			t.Fatalf("expected not found: %v", err)
+		}
+	})
+}
+
 func TestHTTP_JobAllocations(t *testing.T) {
 	t.Parallel()
 	httpTest(t, nil, func(s *TestAgent) {
@@ -751,6 +807,28 @@ func TestHTTP_JobVersions(t *testing.T) {
 			t.Fatalf("bad %v", vResp)
 		}
 
+		// Diff both versions against the first one
+		req, err = http.NewRequest("GET", "/v1/job/"+job.ID+"/versions?diffs=true&diff_version=0", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		obj, err = s.Server.JobSpecificRequest(httptest.NewRecorder(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if diffs := obj.(structs.JobVersionsResponse).Diffs; len(diffs) != 2 {
+			t.Fatalf("got %d diffs; want 2", len(diffs))
+		}
+
+		// An invalid diff version is rejected
+		req, err = http.NewRequest("GET", "/v1/job/"+job.ID+"/versions?diffs=true&diff_version=foo", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if _, err := s.Server.JobSpecificRequest(httptest.NewRecorder(), req); err == nil {
+			t.Fatalf("expected error for invalid diff version")
+		}
+
 		// Check for the index
 		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
 			t.Fatalf("missing index")
@@ -798,7 +876,7 @@ func TestHTTP_PeriodicForce(t *testing.T) {
 
 		// Check the response
 		r := obj.(structs.PeriodicForceResponse)
-		if r.EvalID == "" {
+		if r.EvalID == "" || r.JobID == "" {
 			t.Fatalf("bad: %#v", r)
 		}
 	})
@@ -995,12 +1073,108 @@ func TestHTTP_JobStable(t *testing.T) {
 	})
 }
 
+func TestHTTP_JobUpdatePriority(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// Create the job
+		job := mock.Job()
+		regReq := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var regResp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &regReq, &regResp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		args := structs.JobUpdatePriorityRequest{
+			JobID:        job.ID,
+			Priority:     80,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		buf := encodeReq(args)
+
+		// Make the HTTP request
+		req, err := http.NewRequest("PUT", "/v1/job/"+job.ID+"/priority", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		priorityResp := obj.(structs.JobUpdatePriorityResponse)
+		if priorityResp.Index == 0 || priorityResp.JobVersion != 1 {
+			t.Fatalf("bad: %v", priorityResp)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+	})
+}
+
+func TestHTTP_JobScale(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// Create the job
+		job := mock.Job()
+		regReq := structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var regResp structs.JobRegisterResponse
+		if err := s.Agent.RPC("Job.Register", &regReq, &regResp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		args := structs.JobScaleRequest{
+			JobID:        job.ID,
+			TaskGroup:    job.TaskGroups[0].Name,
+			Count:        3,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		buf := encodeReq(args)
+
+		// Make the HTTP request
+		req, err := http.NewRequest("PUT", "/v1/job/"+job.ID+"/scale", buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.JobSpecificRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Check the response
+		scaleResp := obj.(structs.JobRegisterResponse)
+		if scaleResp.EvalID == "" || scaleResp.JobModifyIndex == 0 {
+			t.Fatalf("bad: %v", scaleResp)
+		}
+
+		// Check for the index
+		if respW.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+	})
+}
+
 func TestJobs_ApiJobToStructsJob(t *testing.T) {
 	apiJob := &api.Job{
 		Stop:        helper.BoolToPtr(true),
 		Region:      helper.StringToPtr("global"),
 		ID:          helper.StringToPtr("foo"),
 		ParentID:    helper.StringToPtr("lol"),
+		Namespace:   helper.StringToPtr("default"),
 		Name:        helper.StringToPtr("name"),
 		Type:        helper.StringToPtr("service"),
 		Priority:    helper.IntToPtr(50),
@@ -1060,6 +1234,17 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 					Sticky:  helper.BoolToPtr(true),
 					Migrate: helper.BoolToPtr(true),
 				},
+				Consul: &api.Consul{
+					Namespace: "team",
+				},
+				StopAfterClientDisconnect: helper.TimeToPtr(5 * time.Minute),
+				Networks: []*api.NetworkResource{
+					{
+						Mode:          "bridge",
+						HostNetwork:   "public",
+						ReservedPorts: []api.Port{{Label: "http", Value: 80, To: 8080}},
+					},
+				},
 				Update: &api.UpdateStrategy{
 					HealthCheck:     helper.StringToPtr(structs.UpdateStrategyHealthCheck_Checks),
 					MinHealthyTime:  helper.TimeToPtr(2 * time.Minute),
@@ -1096,6 +1281,12 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 								Name:      "serviceA",
 								Tags:      []string{"1", "2"},
 								PortLabel: "foo",
+								Meta: map[string]string{
+									"version": "1",
+								},
+								TaggedAddresses: map[string]string{
+									"wan": "1.2.3.4:80",
+								},
 								Checks: []api.ServiceCheck{
 									{
 										Id:            "hello",
@@ -1155,23 +1346,37 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 						},
 						Vault: &api.Vault{
 							Policies:     []string{"a", "b", "c"},
+							Role:         helper.StringToPtr("role"),
+							Namespace:    helper.StringToPtr("team-a"),
 							Env:          helper.BoolToPtr(true),
 							ChangeMode:   helper.StringToPtr("c"),
 							ChangeSignal: helper.StringToPtr("sighup"),
+							ChangeScript: &api.ChangeScript{
+								Command:     helper.StringToPtr("/bin/reload"),
+								Args:        []string{"-v"},
+								Timeout:     helper.TimeToPtr(3 * time.Second),
+								FailOnError: helper.BoolToPtr(true),
+							},
 						},
 						Templates: []*api.Template{
 							{
-								SourcePath:   helper.StringToPtr("source"),
-								DestPath:     helper.StringToPtr("dest"),
-								EmbeddedTmpl: helper.StringToPtr("embedded"),
-								ChangeMode:   helper.StringToPtr("change"),
-								ChangeSignal: helper.StringToPtr("signal"),
-								Splay:        helper.TimeToPtr(1 * time.Minute),
-								Perms:        helper.StringToPtr("666"),
-								LeftDelim:    helper.StringToPtr("abc"),
-								RightDelim:   helper.StringToPtr("def"),
-								Envvars:      helper.BoolToPtr(true),
-								VaultGrace:   helper.TimeToPtr(3 * time.Second),
+								SourcePath:    helper.StringToPtr("source"),
+								DestPath:      helper.StringToPtr("dest"),
+								EmbeddedTmpl:  helper.StringToPtr("embedded"),
+								ChangeMode:    helper.StringToPtr("change"),
+								ChangeSignal:  helper.StringToPtr("signal"),
+								Splay:         helper.TimeToPtr(1 * time.Minute),
+								Perms:         helper.StringToPtr("666"),
+								LeftDelim:     helper.StringToPtr("abc"),
+								RightDelim:    helper.StringToPtr("def"),
+								Envvars:       helper.BoolToPtr(true),
+								EnvKeys:       map[string]string{"KEY": "NAME"},
+								ErrMissingKey: helper.BoolToPtr(true),
+								VaultGrace:    helper.TimeToPtr(3 * time.Second),
+								Wait: &api.WaitConfig{
+									Min: helper.TimeToPtr(5 * time.Second),
+									Max: helper.TimeToPtr(10 * time.Second),
+								},
 							},
 						},
 						DispatchPayload: &api.DispatchPayloadConfig{
@@ -1195,6 +1400,7 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 		Region:      "global",
 		ID:          "foo",
 		ParentID:    "lol",
+		Namespace:   "default",
 		Name:        "name",
 		Type:        "service",
 		Priority:    50,
@@ -1249,14 +1455,26 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 					Sticky:  true,
 					Migrate: true,
 				},
+				Consul: &structs.Consul{
+					Namespace: "team",
+				},
+				StopAfterClientDisconnect: helper.TimeToPtr(5 * time.Minute),
+				Networks: []*structs.NetworkResource{
+					{
+						Mode:          "bridge",
+						HostNetwork:   "public",
+						ReservedPorts: []structs.Port{{Label: "http", Value: 80, To: 8080}},
+					},
+				},
 				Update: &structs.UpdateStrategy{
-					Stagger:         1 * time.Second,
-					MaxParallel:     5,
-					HealthCheck:     structs.UpdateStrategyHealthCheck_Checks,
-					MinHealthyTime:  2 * time.Minute,
-					HealthyDeadline: 5 * time.Minute,
-					AutoRevert:      true,
-					Canary:          1,
+					Stagger:          1 * time.Second,
+					MaxParallel:      5,
+					HealthCheck:      structs.UpdateStrategyHealthCheck_Checks,
+					MinHealthyTime:   2 * time.Minute,
+					HealthyDeadline:  5 * time.Minute,
+					ProgressDeadline: 10 * time.Minute,
+					AutoRevert:       true,
+					Canary:           1,
 				},
 				Meta: map[string]string{
 					"key": "value",
@@ -1286,6 +1504,12 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 								Tags:        []string{"1", "2"},
 								PortLabel:   "foo",
 								AddressMode: "auto",
+								Meta: map[string]string{
+									"version": "1",
+								},
+								TaggedAddresses: map[string]string{
+									"wan": "1.2.3.4:80",
+								},
 								Checks: []*structs.ServiceCheck{
 									&structs.ServiceCheck{
 										Name:          "bar",
@@ -1344,23 +1568,37 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 						},
 						Vault: &structs.Vault{
 							Policies:     []string{"a", "b", "c"},
+							Role:         "role",
+							Namespace:    "team-a",
 							Env:          true,
 							ChangeMode:   "c",
 							ChangeSignal: "sighup",
+							ChangeScript: &structs.ChangeScript{
+								Command:     "/bin/reload",
+								Args:        []string{"-v"},
+								Timeout:     3 * time.Second,
+								FailOnError: true,
+							},
 						},
 						Templates: []*structs.Template{
 							{
-								SourcePath:   "source",
-								DestPath:     "dest",
-								EmbeddedTmpl: "embedded",
-								ChangeMode:   "change",
-								ChangeSignal: "SIGNAL",
-								Splay:        1 * time.Minute,
-								Perms:        "666",
-								LeftDelim:    "abc",
-								RightDelim:   "def",
-								Envvars:      true,
-								VaultGrace:   3 * time.Second,
+								SourcePath:    "source",
+								DestPath:      "dest",
+								EmbeddedTmpl:  "embedded",
+								ChangeMode:    "change",
+								ChangeSignal:  "SIGNAL",
+								Splay:         1 * time.Minute,
+								Perms:         "666",
+								LeftDelim:     "abc",
+								RightDelim:    "def",
+								Envvars:       true,
+								EnvKeys:       map[string]string{"KEY": "NAME"},
+								ErrMissingKey: true,
+								VaultGrace:    3 * time.Second,
+								Wait: &structs.WaitConfig{
+									Min: 5 * time.Second,
+									Max: 10 * time.Second,
+								},
 							},
 						},
 						DispatchPayload: &structs.DispatchPayloadConfig{
@@ -1380,3 +1618,20 @@ func TestJobs_ApiJobToStructsJob(t *testing.T) {
 		t.Fatalf("bad:\n%s", strings.Join(diff, "\n"))
 	}
 }
+
+func TestJobs_ApiTaskToStructsTask_VaultDisabled(t *testing.T) {
+	apiTask := &api.Task{
+		Name: "logs",
+		Vault: &api.Vault{
+			Enabled:  helper.BoolToPtr(false),
+			Policies: []string{"app"},
+		},
+	}
+	apiTask.Canonicalize(&api.TaskGroup{Name: helper.StringToPtr("group")}, &api.Job{ID: helper.StringToPtr("job")})
+
+	structsTask := &structs.Task{}
+	ApiTaskToStructsTask(apiTask, structsTask)
+	if structsTask.Vault != nil {
+		t.Fatalf("expected disabled Vault block to be dropped: %#v", structsTask.Vault)
+	}
+}
@@ -81,3 +81,17 @@ func (l *logWriter) Write(p []byte) (n int, err error) {
 	}
 	return
 }
+
+// Logs returns the buffered logs, oldest first.
+func (l *logWriter) Logs() []string {
+	l.Lock()
+	defer l.Unlock()
+
+	logs := make([]string, 0, len(l.logs))
+	for i := 0; i < len(l.logs); i++ {
+		if line := l.logs[(l.index+i)%len(l.logs)]; line != "" {
+			logs = append(logs, line)
+		}
+	}
+	return logs
+}
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -50,3 +51,23 @@ func TestLogWriter(t *testing.T) {
 		}
 	}
 }
+
+func TestLogWriter_Logs(t *testing.T) {
+	t.Parallel()
+	w := NewLogWriter(3)
+	if logs := w.Logs(); len(logs) != 0 {
+		t.Fatalf("expected no logs: %v", logs)
+	}
+
+	w.Write([]byte("one\n"))
+	w.Write([]byte("two\n"))
+	if logs := w.Logs(); !reflect.DeepEqual(logs, []string{"one", "two"}) {
+		t.Fatalf("bad: %v", logs)
+	}
+
+	w.Write([]byte("three\n"))
+	w.Write([]byte("four\n"))
+	if logs := w.Logs(); !reflect.DeepEqual(logs, []string{"two", "three", "four"}) {
+		t.Fatalf("bad: %v", logs)
+	}
+}
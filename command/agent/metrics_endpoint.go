@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"net/http"
+)
+
+// MetricsRequest returns the agent's metrics. By default the recent metrics of
+// the in-memory sink are returned as JSON, like /v1/agent/metrics. With
+// ?format=prometheus, the metrics are returned in the Prometheus text format,
+// labeled with the configured tags, the datacenter and, on clients, the node
+// ID and class.
+func (s *HTTPServer) MetricsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	switch format := req.URL.Query().Get("format"); format {
+	case "":
+		return s.AgentMetricsRequest(resp, req)
+	case "prometheus":
+		return s.prometheusMetrics(resp)
+	default:
+		return nil, CodedError(400, "Unsupported metrics format: "+format)
+	}
+}
+
+func (s *HTTPServer) prometheusMetrics(resp http.ResponseWriter) (interface{}, error) {
+	if s.agent.prometheusSink == nil {
+		return nil, CodedError(400, "Prometheus metrics are disabled; enable telemetry.prometheus_metrics")
+	}
+
+	labels := make(map[string]string)
+	for _, tag := range s.agent.config.Telemetry.metricTags(s.agent.config.Datacenter) {
+		if key, value, ok := splitMetricTag(tag); ok {
+			labels[key] = value
+		}
+	}
+	if s.agent.client != nil {
+		if node := s.agent.client.Node(); node != nil {
+			labels["node_id"] = node.ID
+			labels["node_class"] = node.NodeClass
+		}
+	}
+
+	resp.Header().Set("Content-Type", prometheusContentType)
+	return nil, s.agent.prometheusSink.Write(resp, labels)
+}
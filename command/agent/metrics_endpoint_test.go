@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTP_MetricsPrometheus(t *testing.T) {
+	t.Parallel()
+	cb := func(c *Config) {
+		c.Telemetry.Tags = []string{"env:prod"}
+	}
+	httpTest(t, cb, func(s *TestAgent) {
+		req, err := http.NewRequest("GET", "/v1/metrics?format=prometheus", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Prometheus metrics are disabled by default
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.MetricsRequest(respW, req); err == nil || !strings.Contains(err.Error(), "disabled") {
+			t.Fatalf("expected an error; got %v", err)
+		}
+
+		s.Agent.prometheusSink = newPrometheusSink("")
+		s.Agent.prometheusSink.SetGauge([]string{"nomad", "test"}, 42)
+
+		respW = httptest.NewRecorder()
+		obj, err := s.Server.MetricsRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if obj != nil {
+			t.Fatalf("unexpected object: %#v", obj)
+		}
+		if ct := respW.HeaderMap.Get("Content-Type"); ct != prometheusContentType {
+			t.Fatalf("bad content type: %q", ct)
+		}
+		out := respW.Body.String()
+		if !strings.Contains(out, `nomad_test{datacenter="dc1",env="prod",node_id="`+s.client.Node().ID+`"`) {
+			t.Fatalf("bad output:\n%s", out)
+		}
+	})
+}
+
+func TestHTTP_MetricsFormat(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		req, err := http.NewRequest("GET", "/v1/metrics?format=xml", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.MetricsRequest(respW, req); err == nil || !strings.Contains(err.Error(), "Unsupported") {
+			t.Fatalf("expected an error; got %v", err)
+		}
+	})
+}
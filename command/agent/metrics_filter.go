@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+const (
+	// metricSeriesExpiration is the time after which a series that wasn't
+	// emitted no longer counts towards the maximum cardinality, so the series
+	// of stopped allocations make room for the ones of new allocations.
+	metricSeriesExpiration = 10 * time.Minute
+
+	// metricSeriesPruneInterval is the minimum interval between two scans for
+	// expired series once the maximum cardinality is reached.
+	metricSeriesPruneInterval = time.Minute
+)
+
+// prefixFilterRule allows or blocks the metrics under a prefix.
+type prefixFilterRule struct {
+	prefix string
+	allow  bool
+}
+
+// parsePrefixFilterRule parses a "+prefix" or "-prefix" filter rule.
+func parsePrefixFilterRule(rule string) (prefix string, allow, ok bool) {
+	if len(rule) < 2 {
+		return "", false, false
+	}
+	switch rule[0] {
+	case '+':
+		return rule[1:], true, true
+	case '-':
+		return rule[1:], false, true
+	default:
+		return "", false, false
+	}
+}
+
+// filterSink is a metrics sink dropping the metrics blocked by the prefix
+// filters and the new series emitted beyond the maximum cardinality, before
+// forwarding the others to the sink it wraps.
+type filterSink struct {
+	sink   metrics.MetricSink
+	logger *log.Logger
+
+	// hostname is removed from the keys of gauges before matching the
+	// prefixes, so the filters don't depend on the node.
+	hostname string
+
+	rules         []prefixFilterRule
+	filterDefault bool
+
+	// maxSeries is the maximum number of series, or zero if unlimited.
+	// series holds the time each series was last emitted.
+	maxSeries int
+	series    map[string]time.Time
+	lastPrune time.Time
+	warned    bool
+	l         sync.Mutex
+}
+
+// newFilterSink returns a sink filtering the metrics forwarded to the sink as
+// configured by the telemetry configuration. The sink itself is returned if
+// nothing is filtered.
+func newFilterSink(sink metrics.MetricSink, t *Telemetry, hostname string, logger *log.Logger) metrics.MetricSink {
+	filterDefault := t.FilterDefault == nil || *t.FilterDefault
+	if len(t.PrefixFilter) == 0 && filterDefault && t.MaxMetricCardinality == 0 {
+		return sink
+	}
+
+	f := &filterSink{
+		sink:          sink,
+		logger:        logger,
+		hostname:      hostname,
+		filterDefault: filterDefault,
+		maxSeries:     t.MaxMetricCardinality,
+		series:        make(map[string]time.Time),
+	}
+	for _, rule := range t.PrefixFilter {
+		if prefix, allow, ok := parsePrefixFilterRule(rule); ok {
+			f.rules = append(f.rules, prefixFilterRule{prefix: prefix, allow: allow})
+		}
+	}
+	return f
+}
+
+func (f *filterSink) SetGauge(key []string, val float32) {
+	if f.allowed("gauge", f.trimHostname(key)) {
+		f.sink.SetGauge(key, val)
+	}
+}
+
+func (f *filterSink) EmitKey(key []string, val float32) {
+	if f.allowed("kv", key) {
+		f.sink.EmitKey(key, val)
+	}
+}
+
+func (f *filterSink) IncrCounter(key []string, val float32) {
+	if f.allowed("counter", key) {
+		f.sink.IncrCounter(key, val)
+	}
+}
+
+func (f *filterSink) AddSample(key []string, val float32) {
+	if f.allowed("sample", key) {
+		f.sink.AddSample(key, val)
+	}
+}
+
+// trimHostname removes the hostname the metrics library inserts after the
+// service name in the keys of gauges.
+func (f *filterSink) trimHostname(key []string) []string {
+	if f.hostname != "" && len(key) > 1 && key[1] == f.hostname {
+		return append([]string{key[0]}, key[2:]...)
+	}
+	return key
+}
+
+// allowed returns whether a metric of the given type is forwarded.
+func (f *filterSink) allowed(kind string, key []string) bool {
+	name := strings.Join(key, ".")
+	if !f.matches(name) {
+		return false
+	}
+	if f.maxSeries == 0 {
+		return true
+	}
+
+	series := kind + ":" + name
+	now := time.Now()
+
+	f.l.Lock()
+	defer f.l.Unlock()
+	if _, ok := f.series[series]; ok || len(f.series) < f.maxSeries || f.prune(now) {
+		f.series[series] = now
+		return true
+	}
+
+	if !f.warned {
+		f.warned = true
+		f.logger.Printf("[WARN] agent: reached the maximum metric cardinality of %d series, dropping new series", f.maxSeries)
+	}
+	f.sink.IncrCounter([]string{key[0], "agent", "metrics", "dropped_series"}, 1)
+	return false
+}
+
+// matches returns whether the prefix filters allow the metric. The rule with
+// the longest prefix matching the name on a segment boundary applies.
+func (f *filterSink) matches(name string) bool {
+	allow, longest := f.filterDefault, -1
+	for _, rule := range f.rules {
+		if len(rule.prefix) < longest {
+			continue
+		}
+		if name == rule.prefix || strings.HasPrefix(name, rule.prefix+".") {
+			allow, longest = rule.allow, len(rule.prefix)
+		}
+	}
+	return allow
+}
+
+// prune forgets the expired series and returns whether there is room for a
+// new series. It must be called with the lock held.
+func (f *filterSink) prune(now time.Time) bool {
+	if now.Sub(f.lastPrune) < metricSeriesPruneInterval {
+		return false
+	}
+	f.lastPrune = now
+	for series, updated := range f.series {
+		if now.Sub(updated) > metricSeriesExpiration {
+			delete(f.series, series)
+		}
+	}
+	return len(f.series) < f.maxSeries
+}
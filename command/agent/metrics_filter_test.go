@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestFilterSink_PrefixFilter(t *testing.T) {
+	t.Parallel()
+	prometheus := newPrometheusSink("host1")
+	telemetry := &Telemetry{
+		PrefixFilter:  []string{"+nomad.client", "-nomad.client.allocs", "+nomad.client.allocs.web"},
+		FilterDefault: helper.BoolToPtr(false),
+	}
+	sink := newFilterSink(prometheus, telemetry, "host1", log.New(ioutil.Discard, "", 0))
+
+	sink.SetGauge([]string{"nomad", "host1", "client", "host", "cpu", "total"}, 1)
+	sink.SetGauge([]string{"nomad", "host1", "client", "allocs", "api", "memory", "rss"}, 1)
+	sink.SetGauge([]string{"nomad", "host1", "client", "allocs", "web", "memory", "rss"}, 1)
+	sink.SetGauge([]string{"nomad", "host1", "client", "allocsx"}, 1)
+	sink.IncrCounter([]string{"nomad", "rpc", "query"}, 1)
+
+	expected := map[string]bool{
+		"nomad_client_host_cpu_total":        true,
+		"nomad_client_allocs_api_memory_rss": false,
+		"nomad_client_allocs_web_memory_rss": true,
+		"nomad_client_allocsx":               true,
+		"nomad_rpc_query":                    false,
+	}
+	for name, allowed := range expected {
+		_, gauge := prometheus.gauges[name]
+		_, counter := prometheus.counters[name]
+		if (gauge || counter) != allowed {
+			t.Fatalf("metric %q allowed %v, expected %v", name, gauge || counter, allowed)
+		}
+	}
+}
+
+func TestFilterSink_MaxCardinality(t *testing.T) {
+	t.Parallel()
+	prometheus := newPrometheusSink("")
+	telemetry := &Telemetry{MaxMetricCardinality: 2}
+	sink := newFilterSink(prometheus, telemetry, "", log.New(ioutil.Discard, "", 0))
+
+	sink.SetGauge([]string{"nomad", "a"}, 1)
+	sink.SetGauge([]string{"nomad", "b"}, 1)
+	sink.SetGauge([]string{"nomad", "c"}, 1)
+
+	// Known series are still emitted
+	sink.SetGauge([]string{"nomad", "a"}, 2)
+
+	if len(prometheus.gauges) != 2 || prometheus.gauges["nomad_a"].value != 2 {
+		t.Fatalf("bad gauges: %#v", prometheus.gauges)
+	}
+	if _, ok := prometheus.gauges["nomad_c"]; ok {
+		t.Fatalf("expected nomad.c to be dropped")
+	}
+	if prometheus.counters["nomad_agent_metrics_dropped_series"] != 1 {
+		t.Fatalf("bad counters: %#v", prometheus.counters)
+	}
+}
+
+func TestFilterSink_Disabled(t *testing.T) {
+	t.Parallel()
+	prometheus := newPrometheusSink("")
+	if sink := newFilterSink(prometheus, &Telemetry{}, "", nil); sink != prometheus {
+		t.Fatalf("expected the sink to be returned unwrapped")
+	}
+}
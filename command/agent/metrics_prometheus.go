@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// prometheusGaugeExpiration is the time after which gauges that weren't
+	// updated are dropped, so the gauges of stopped allocations and departed
+	// peers aren't exposed forever.
+	prometheusGaugeExpiration = time.Minute
+
+	// prometheusContentType is the content type of the text exposition
+	// format.
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// prometheusBuckets are the upper bounds of the histogram buckets samples are
+// counted in. Most samples are timers measured in milliseconds, the longest
+// being the scheduling latencies.
+var prometheusBuckets = []float64{0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// prometheusSink is a metrics sink keeping the metrics in the shape Prometheus
+// expects them: gauges hold their last value, counters are cumulative since the
+// agent started and samples are counted in histogram buckets. Unlike the
+// in-memory sink, nothing is reset at the end of an aggregation interval.
+type prometheusSink struct {
+	// hostname is removed from the keys of gauges, which the metrics library
+	// prefixes with the hostname when it is enabled.
+	hostname string
+
+	gauges     map[string]*prometheusGauge
+	counters   map[string]float64
+	histograms map[string]*prometheusHistogram
+	l          sync.Mutex
+}
+
+type prometheusGauge struct {
+	value   float64
+	updated time.Time
+}
+
+type prometheusHistogram struct {
+	// buckets are the non-cumulative counts of the samples of each bucket of
+	// prometheusBuckets.
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// newPrometheusSink returns a sink removing the hostname, if not empty, from
+// the keys of gauges.
+func newPrometheusSink(hostname string) *prometheusSink {
+	return &prometheusSink{
+		hostname:   hostname,
+		gauges:     make(map[string]*prometheusGauge),
+		counters:   make(map[string]float64),
+		histograms: make(map[string]*prometheusHistogram),
+	}
+}
+
+func (p *prometheusSink) SetGauge(key []string, val float32) {
+	if p.hostname != "" && len(key) > 1 && key[1] == p.hostname {
+		key = append([]string{key[0]}, key[2:]...)
+	}
+	name := prometheusName(key)
+
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.gauges[name] = &prometheusGauge{value: float64(val), updated: time.Now()}
+}
+
+func (p *prometheusSink) EmitKey(key []string, val float32) {
+	// Key/value pairs have no Prometheus equivalent, and are exposed as gauges
+	p.SetGauge(key, val)
+}
+
+func (p *prometheusSink) IncrCounter(key []string, val float32) {
+	name := prometheusName(key)
+
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.counters[name] += float64(val)
+}
+
+func (p *prometheusSink) AddSample(key []string, val float32) {
+	name := prometheusName(key)
+
+	p.l.Lock()
+	defer p.l.Unlock()
+	h, ok := p.histograms[name]
+	if !ok {
+		h = &prometheusHistogram{buckets: make([]uint64, len(prometheusBuckets))}
+		p.histograms[name] = h
+	}
+	v := float64(val)
+	if i := sort.SearchFloat64s(prometheusBuckets, v); i < len(prometheusBuckets) {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// Write writes the metrics in the Prometheus text exposition format. The
+// labels are added to every series.
+func (p *prometheusSink) Write(w io.Writer, labels map[string]string) error {
+	var buf bytes.Buffer
+	base := formatPrometheusLabels(labels)
+
+	p.l.Lock()
+	now := time.Now()
+	gauges := make([]string, 0, len(p.gauges))
+	for name := range p.gauges {
+		gauges = append(gauges, name)
+	}
+	sort.Strings(gauges)
+	for _, name := range gauges {
+		g := p.gauges[name]
+		if now.Sub(g.updated) > prometheusGaugeExpiration {
+			delete(p.gauges, name)
+			continue
+		}
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&buf, "%s%s %s\n", name, wrapPrometheusLabels(base), formatPrometheusValue(g.value))
+	}
+
+	counters := make([]string, 0, len(p.counters))
+	for name := range p.counters {
+		counters = append(counters, name)
+	}
+	sort.Strings(counters)
+	for _, name := range counters {
+		fmt.Fprintf(&buf, "# TYPE %s counter\n", name)
+		fmt.Fprintf(&buf, "%s%s %s\n", name, wrapPrometheusLabels(base), formatPrometheusValue(p.counters[name]))
+	}
+
+	histograms := make([]string, 0, len(p.histograms))
+	for name := range p.histograms {
+		histograms = append(histograms, name)
+	}
+	sort.Strings(histograms)
+	for _, name := range histograms {
+		h := p.histograms[name]
+		fmt.Fprintf(&buf, "# TYPE %s histogram\n", name)
+		var cumulative uint64
+		for i, le := range prometheusBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", name,
+				wrapPrometheusLabels(appendPrometheusLabel(base, "le", formatPrometheusValue(le))), cumulative)
+		}
+		fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, wrapPrometheusLabels(appendPrometheusLabel(base, "le", "+Inf")), h.count)
+		fmt.Fprintf(&buf, "%s_sum%s %s\n", name, wrapPrometheusLabels(base), formatPrometheusValue(h.sum))
+		fmt.Fprintf(&buf, "%s_count%s %d\n", name, wrapPrometheusLabels(base), h.count)
+	}
+	p.l.Unlock()
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// prometheusName returns the metric name of a key, with the characters not
+// allowed in Prometheus metric names replaced with underscores.
+func prometheusName(key []string) string {
+	name := []byte(strings.Join(key, "_"))
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':' || (c >= '0' && c <= '9' && i > 0)) {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// formatPrometheusLabels returns the labels formatted as the comma separated
+// pairs of a series, sorted by name. Empty labels are omitted.
+func formatPrometheusLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name, value := range labels {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var pairs string
+	for _, name := range names {
+		pairs = appendPrometheusLabel(pairs, name, labels[name])
+	}
+	return pairs
+}
+
+// appendPrometheusLabel appends a label to formatted labels.
+func appendPrometheusLabel(pairs, name, value string) string {
+	pair := name + "=" + strconv.Quote(value)
+	if pairs == "" {
+		return pair
+	}
+	return pairs + "," + pair
+}
+
+// wrapPrometheusLabels wraps formatted labels in braces, if any.
+func wrapPrometheusLabels(pairs string) string {
+	if pairs == "" {
+		return ""
+	}
+	return "{" + pairs + "}"
+}
+
+// formatPrometheusValue formats a sample value.
+func formatPrometheusValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
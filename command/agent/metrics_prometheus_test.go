@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusSink(t *testing.T) {
+	t.Parallel()
+	sink := newPrometheusSink("host1")
+	sink.SetGauge([]string{"nomad", "host1", "runtime", "num_goroutines"}, 42)
+	sink.IncrCounter([]string{"nomad", "http", "rate_limited"}, 1)
+	sink.IncrCounter([]string{"nomad", "http", "rate_limited"}, 2)
+	sink.AddSample([]string{"nomad", "job", "register"}, 3)
+	sink.AddSample([]string{"nomad", "job", "register"}, 20000)
+
+	var buf bytes.Buffer
+	if err := sink.Write(&buf, map[string]string{"datacenter": "dc1", "node_id": ""}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"# TYPE nomad_runtime_num_goroutines gauge\n",
+		`nomad_runtime_num_goroutines{datacenter="dc1"} 42` + "\n",
+		"# TYPE nomad_http_rate_limited counter\n",
+		`nomad_http_rate_limited{datacenter="dc1"} 3` + "\n",
+		"# TYPE nomad_job_register histogram\n",
+		`nomad_job_register_bucket{datacenter="dc1",le="2.5"} 0` + "\n",
+		`nomad_job_register_bucket{datacenter="dc1",le="5"} 1` + "\n",
+		`nomad_job_register_bucket{datacenter="dc1",le="10000"} 1` + "\n",
+		`nomad_job_register_bucket{datacenter="dc1",le="+Inf"} 2` + "\n",
+		`nomad_job_register_sum{datacenter="dc1"} 20003` + "\n",
+		`nomad_job_register_count{datacenter="dc1"} 2` + "\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Fatalf("expected %q in output:\n%s", e, out)
+		}
+	}
+}
+
+func TestPrometheusName(t *testing.T) {
+	t.Parallel()
+	cases := map[string][]string{
+		"nomad_client_allocs_web_1_cpu": {"nomad", "client", "allocs", "web-1", "cpu"},
+		"__node":                        {"1", "node"},
+		"nomad_raft_fsm_apply":          {"nomad", "raft.fsm", "apply"},
+	}
+	for expected, key := range cases {
+		if name := prometheusName(key); name != expected {
+			t.Fatalf("key %v: expected %q; got %q", key, expected, name)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP_NamespaceList(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, nil, func(s *TestAgent) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		ns1 := mock.Namespace()
+		ns2 := mock.Namespace()
+		assert.Nil(state.UpsertNamespaces(1000, []*structs.Namespace{ns1, ns2}), "UpsertNamespaces")
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/namespaces", nil)
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.NamespacesRequest(respW, req)
+		assert.Nil(err, "Namespace Request")
+
+		// Check for the index
+		assert.Equal("1000", respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+		assert.Equal("true", respW.HeaderMap.Get("X-Nomad-KnownLeader"), "missing known leader")
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-LastContact"), "missing last contact")
+
+		// Check the namespaces
+		namespaces := obj.([]*structs.Namespace)
+		assert.Len(namespaces, 2, "Namespaces")
+	})
+}
+
+func TestHTTP_NamespaceQuery(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, nil, func(s *TestAgent) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		ns := mock.Namespace()
+		assert.Nil(state.UpsertNamespaces(1000, []*structs.Namespace{ns}), "UpsertNamespaces")
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/namespace/"+ns.Name, nil)
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.NamespaceSpecificRequest(respW, req)
+		assert.Nil(err, "Namespace Request")
+
+		// Check for the index
+		assert.Equal("1000", respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		// Check the namespace
+		out := obj.(*structs.Namespace)
+		assert.Equal(ns.Name, out.Name, "Namespace name")
+
+		// Query an unknown namespace
+		req, err = http.NewRequest("GET", "/v1/namespace/unknown", nil)
+		assert.Nil(err, "HTTP Request")
+		_, err = s.Server.NamespaceSpecificRequest(httptest.NewRecorder(), req)
+		assert.NotNil(err, "Namespace Request")
+		assert.Equal(404, err.(HTTPCodedError).Code())
+	})
+}
+
+func TestHTTP_NamespaceUpdate(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, nil, func(s *TestAgent) {
+		// Make the HTTP request
+		ns := mock.Namespace()
+		buf := encodeReq(ns)
+		req, err := http.NewRequest("PUT", "/v1/namespace/"+ns.Name, buf)
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.NamespaceSpecificRequest(respW, req)
+		assert.Nil(err, "Namespace Request")
+		assert.Nil(obj)
+
+		// Check for the index
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		// Check the namespace was created
+		out, err := s.Agent.server.State().NamespaceByName(nil, ns.Name)
+		assert.Nil(err, "NamespaceByName")
+		assert.NotNil(out, "Namespace")
+		assert.Equal(ns.Description, out.Description, "Namespace description")
+
+		// A mismatching name is rejected
+		req, err = http.NewRequest("PUT", "/v1/namespace/other", encodeReq(ns))
+		assert.Nil(err, "HTTP Request")
+		_, err = s.Server.NamespaceSpecificRequest(httptest.NewRecorder(), req)
+		assert.NotNil(err, "Namespace Request")
+		assert.Equal(400, err.(HTTPCodedError).Code())
+	})
+}
+
+func TestHTTP_NamespaceDelete(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, nil, func(s *TestAgent) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		ns := mock.Namespace()
+		assert.Nil(state.UpsertNamespaces(1000, []*structs.Namespace{ns}), "UpsertNamespaces")
+
+		// Make the HTTP request
+		req, err := http.NewRequest("DELETE", "/v1/namespace/"+ns.Name, nil)
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		_, err = s.Server.NamespaceSpecificRequest(respW, req)
+		assert.Nil(err, "Namespace Request")
+
+		// Check for the index
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		// Check the namespace was deleted
+		out, err := state.NamespaceByName(nil, ns.Name)
+		assert.Nil(err, "NamespaceByName")
+		assert.Nil(out, "Namespace")
+	})
+}
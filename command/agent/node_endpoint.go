@@ -56,6 +56,7 @@ func (s *HTTPServer) nodeForceEvaluate(resp http.ResponseWriter, req *http.Reque
 		NodeID: nodeID,
 	}
 	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.NodeUpdateResponse
 	if err := s.agent.RPC("Node.Evaluate", &args, &out); err != nil {
@@ -110,6 +111,7 @@ func (s *HTTPServer) nodeToggleDrain(resp http.ResponseWriter, req *http.Request
 		Drain:  enable,
 	}
 	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.NodeDrainUpdateResponse
 	if err := s.agent.RPC("Node.UpdateDrain", &args, &out); err != nil {
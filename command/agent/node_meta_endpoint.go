@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/api"
+)
+
+// ClientMetaRequest returns the meta of the local client node, or applies
+// changes to its dynamic meta.
+func (s *HTTPServer) ClientMetaRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if s.agent.client == nil {
+		return nil, clientNotRunning
+	}
+
+	switch req.Method {
+	case "GET":
+		if err := s.checkACL(req, (*acl.ACL).AllowNodeRead); err != nil {
+			return nil, err
+		}
+		return s.agent.Client().NodeMeta(), nil
+	case "PUT", "POST":
+		if err := s.checkACL(req, (*acl.ACL).AllowNodeWrite); err != nil {
+			return nil, err
+		}
+		var args api.NodeMetaApplyRequest
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		if len(args.Meta) == 0 {
+			return nil, CodedError(400, "missing node meta")
+		}
+
+		out, err := s.agent.Client().ApplyNodeMeta(args.Meta)
+		if err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+		return out, nil
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	cstructs "github.com/hashicorp/nomad/client/structs"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestHTTP_ClientMetaRequest(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// Missing meta is rejected
+		args := api.NodeMetaApplyRequest{}
+		req, err := http.NewRequest("PUT", "/v1/client/metadata", encodeReq(args))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.ClientMetaRequest(respW, req); err == nil {
+			t.Fatalf("expected error for missing meta")
+		}
+
+		// Apply a key
+		args.Meta = map[string]*string{"rack": helper.StringToPtr("r1")}
+		req, err = http.NewRequest("PUT", "/v1/client/metadata", encodeReq(args))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err := s.Server.ClientMetaRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if meta := obj.(*cstructs.NodeMetaResponse); meta.Meta["rack"] != "r1" {
+			t.Fatalf("bad: %#v", meta.Meta)
+		}
+
+		// Read it back
+		req, err = http.NewRequest("GET", "/v1/client/metadata", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		obj, err = s.Server.ClientMetaRequest(respW, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		meta := obj.(*cstructs.NodeMetaResponse)
+		if meta.Meta["rack"] != "r1" || *meta.Dynamic["rack"] != "r1" {
+			t.Fatalf("bad: %#v", meta)
+		}
+
+		// Invalid methods are rejected
+		req, err = http.NewRequest("DELETE", "/v1/client/metadata", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		if _, err := s.Server.ClientMetaRequest(respW, req); err == nil {
+			t.Fatalf("expected error for invalid method")
+		}
+	})
+}
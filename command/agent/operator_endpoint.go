@@ -1,7 +1,9 @@
 package agent
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -51,6 +53,7 @@ func (s *HTTPServer) OperatorRaftPeer(resp http.ResponseWriter, req *http.Reques
 
 	var args structs.RaftPeerByAddressRequest
 	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
 
 	params := req.URL.Query()
 	if _, ok := params["address"]; ok {
@@ -67,3 +70,75 @@ func (s *HTTPServer) OperatorRaftPeer(resp http.ResponseWriter, req *http.Reques
 	}
 	return nil, nil
 }
+
+// OperatorSchedulerConfiguration is used to read or update the scheduler
+// configuration. Updates can use check-and-set semantics by passing the
+// ModifyIndex of the configuration they are based on with ?cas.
+func (s *HTTPServer) OperatorSchedulerConfiguration(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	switch req.Method {
+	case "GET":
+		var args structs.GenericRequest
+		if done := s.parse(resp, req, &args.Region, &args.QueryOptions); done {
+			return nil, nil
+		}
+
+		var reply structs.SchedulerConfigurationResponse
+		if err := s.agent.RPC("Operator.SchedulerGetConfiguration", &args, &reply); err != nil {
+			return nil, err
+		}
+
+		setMeta(resp, &reply.QueryMeta)
+		return reply, nil
+
+	case "PUT", "POST":
+		var args structs.SchedulerSetConfigRequest
+		s.parseRegion(req, &args.Region)
+		s.parseToken(req, &args.AuthToken)
+
+		if err := decodeBody(req, &args.Config); err != nil {
+			return nil, CodedError(400, fmt.Sprintf("Error parsing scheduler config: %v", err))
+		}
+
+		// Check for cas value
+		if casStr := req.URL.Query().Get("cas"); casStr != "" {
+			casVal, err := strconv.ParseUint(casStr, 10, 64)
+			if err != nil {
+				return nil, CodedError(400, fmt.Sprintf("Error parsing cas value: %v", err))
+			}
+			args.Config.ModifyIndex = casVal
+			args.CAS = true
+		}
+
+		var reply structs.SchedulerSetConfigurationResponse
+		if err := s.agent.RPC("Operator.SchedulerSetConfiguration", &args, &reply); err != nil {
+			return nil, err
+		}
+		setIndex(resp, reply.Index)
+		return reply, nil
+
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+// OperatorCapacityRequest returns the capacity of the cluster and the
+// resources reserved and used per node class, namespace and job, along with
+// their history.
+func (s *HTTPServer) OperatorCapacityRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	var args structs.GenericRequest
+	if done := s.parse(resp, req, &args.Region, &args.QueryOptions); done {
+		return nil, nil
+	}
+
+	var reply structs.CapacityResponse
+	if err := s.agent.RPC("Operator.CapacityReport", &args, &reply); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &reply.QueryMeta)
+	return reply, nil
+}
@@ -58,3 +58,105 @@ func TestHTTP_OperatorRaftPeer(t *testing.T) {
 		}
 	})
 }
+
+func TestHTTP_OperatorSchedulerConfiguration(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		// Set the configuration
+		body := bytes.NewBufferString(`{"SchedulerAlgorithm": "spread"}`)
+		req, err := http.NewRequest("PUT", "/v1/operator/scheduler/configuration", body)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		resp := httptest.NewRecorder()
+		obj, err := s.Server.OperatorSchedulerConfiguration(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.Code != 200 {
+			t.Fatalf("bad code: %d", resp.Code)
+		}
+		setReply, ok := obj.(structs.SchedulerSetConfigurationResponse)
+		if !ok {
+			t.Fatalf("unexpected: %T", obj)
+		}
+		if !setReply.Updated {
+			t.Fatalf("bad: %#v", setReply)
+		}
+
+		// Read it back
+		req, err = http.NewRequest("GET", "/v1/operator/scheduler/configuration", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		resp = httptest.NewRecorder()
+		obj, err = s.Server.OperatorSchedulerConfiguration(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		out, ok := obj.(structs.SchedulerConfigurationResponse)
+		if !ok {
+			t.Fatalf("unexpected: %T", obj)
+		}
+		if out.SchedulerConfig.SchedulerAlgorithm != structs.SchedulerAlgorithmSpread {
+			t.Fatalf("bad: %#v", out.SchedulerConfig)
+		}
+
+		// A check-and-set with a stale index is not applied
+		body = bytes.NewBufferString(`{"SchedulerAlgorithm": "binpack"}`)
+		req, err = http.NewRequest("PUT", "/v1/operator/scheduler/configuration?cas=1", body)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		resp = httptest.NewRecorder()
+		obj, err = s.Server.OperatorSchedulerConfiguration(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if obj.(structs.SchedulerSetConfigurationResponse).Updated {
+			t.Fatalf("bad: %#v", obj)
+		}
+
+		// An invalid cas value is rejected
+		body = bytes.NewBufferString(`{}`)
+		req, err = http.NewRequest("PUT", "/v1/operator/scheduler/configuration?cas=foo", body)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		resp = httptest.NewRecorder()
+		_, err = s.Server.OperatorSchedulerConfiguration(resp, req)
+		if err == nil || err.(HTTPCodedError).Code() != 400 {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
+func TestHTTP_OperatorCapacity(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		req, err := http.NewRequest("GET", "/v1/operator/capacity", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		resp := httptest.NewRecorder()
+		obj, err := s.Server.OperatorCapacityRequest(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.HeaderMap.Get("X-Nomad-Index") == "" {
+			t.Fatalf("missing index")
+		}
+		out, ok := obj.(structs.CapacityResponse)
+		if !ok {
+			t.Fatalf("unexpected: %T", obj)
+		}
+		if out.Total == nil || out.Namespaces == nil || out.Jobs == nil {
+			t.Fatalf("bad: %#v", out)
+		}
+	})
+}
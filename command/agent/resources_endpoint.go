@@ -20,6 +20,7 @@ func (s *HTTPServer) resourcesRequest(resp http.ResponseWriter, req *http.Reques
 	if err := decodeBody(req, &args); err != nil {
 		return nil, CodedError(400, err.Error())
 	}
+	s.parseToken(req, &args.AuthToken)
 
 	var out structs.ResourceListResponse
 	if err := s.agent.RPC("Resources.List", &args, &out); err != nil {
@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// FuzzySearchRequest accepts a search text and an optional context and returns
+// the objects whose name or ID contains the text.
+func (s *HTTPServer) FuzzySearchRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.FuzzySearchRequest{}
+
+	switch req.Method {
+	case "GET":
+		query := req.URL.Query()
+		args.Text = query.Get("text")
+		args.Context = query.Get("context")
+	case "PUT", "POST":
+		if err := decodeBody(req, &args); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	if args.Text == "" {
+		return nil, CodedError(400, "missing search text")
+	}
+
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.FuzzySearchResponse
+	if err := s.agent.RPC("Search.FuzzySearch", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	return out, nil
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	a "github.com/stretchr/testify/assert"
+)
+
+func TestHTTP_FuzzySearchWithIllegalMethod(t *testing.T) {
+	assert := a.New(t)
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		req, err := http.NewRequest("DELETE", "/v1/search/fuzzy?text=foo", nil)
+		assert.Nil(err)
+		respW := httptest.NewRecorder()
+
+		_, err = s.Server.FuzzySearchRequest(respW, req)
+		assert.NotNil(err, "HTTP DELETE should not be accepted for this endpoint")
+	})
+}
+
+func TestHTTP_FuzzySearch_GET(t *testing.T) {
+	assert := a.New(t)
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		job := mock.Job()
+		job.Name = "frontend-web"
+		state := s.Agent.server.State()
+		assert.Nil(state.UpsertJob(1000, job))
+
+		req, err := http.NewRequest("GET", "/v1/search/fuzzy?text=web&context=jobs", nil)
+		assert.Nil(err)
+		respW := httptest.NewRecorder()
+
+		resp, err := s.Server.FuzzySearchRequest(respW, req)
+		assert.Nil(err)
+
+		res := resp.(structs.FuzzySearchResponse)
+		j := res.Matches["jobs"]
+		if assert.Len(j, 1) {
+			assert.Equal(job.ID, j[0].ID)
+			assert.Equal("frontend-web", j[0].Name)
+		}
+		assert.False(res.Truncations["jobs"])
+		assert.Equal("1000", respW.HeaderMap.Get("X-Nomad-Index"))
+	})
+}
+
+func TestHTTP_FuzzySearch_MissingText(t *testing.T) {
+	assert := a.New(t)
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		req, err := http.NewRequest("GET", "/v1/search/fuzzy", nil)
+		assert.Nil(err)
+		respW := httptest.NewRecorder()
+
+		_, err = s.Server.FuzzySearchRequest(respW, req)
+		assert.NotNil(err)
+	})
+}
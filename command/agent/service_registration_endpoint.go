@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func (s *HTTPServer) ServiceRegistrationListRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.ServiceRegistrationListRequest{}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ServiceRegistrationListResponse
+	if err := s.agent.RPC("ServiceRegistration.List", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Services == nil {
+		out.Services = make([]*structs.ServiceRegistrationListStub, 0)
+	}
+	return out.Services, nil
+}
+
+func (s *HTTPServer) ServiceRegistrationRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/v1/service/")
+	if len(path) == 0 {
+		return nil, CodedError(400, "Missing Service Name")
+	}
+
+	// The path is either the name of the service or the name of the service
+	// followed by the ID of a registration
+	parts := strings.SplitN(path, "/", 2)
+	switch {
+	case len(parts) == 1 && req.Method == "GET":
+		return s.serviceRegistrationQuery(resp, req, parts[0])
+	case len(parts) == 2 && req.Method == "DELETE":
+		return s.serviceRegistrationDelete(resp, req, parts[1])
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) serviceRegistrationQuery(resp http.ResponseWriter, req *http.Request,
+	name string) (interface{}, error) {
+	args := structs.ServiceRegistrationByNameRequest{
+		ServiceName: name,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.ServiceRegistrationByNameResponse
+	if err := s.agent.RPC("ServiceRegistration.GetService", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Services == nil {
+		out.Services = make([]*structs.ServiceRegistration, 0)
+	}
+	return out.Services, nil
+}
+
+func (s *HTTPServer) serviceRegistrationDelete(resp http.ResponseWriter, req *http.Request,
+	id string) (interface{}, error) {
+
+	args := structs.ServiceRegistrationDeleteByIDRequest{
+		IDs: []string{id},
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	var out structs.GenericResponse
+	if err := s.agent.RPC("ServiceRegistration.DeleteByID", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return nil, nil
+}
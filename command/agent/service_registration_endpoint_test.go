@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP_ServiceRegistrationList(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, nil, func(s *TestAgent) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		s1 := mock.ServiceRegistration()
+		s2 := mock.ServiceRegistration()
+		s2.ServiceName = "api"
+		assert.Nil(state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2}), "UpsertServiceRegistrations")
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/services", nil)
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ServiceRegistrationListRequest(respW, req)
+		assert.Nil(err, "Service Registration Request")
+
+		// Check for the index
+		assert.Equal("1000", respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		// Check the services
+		services := obj.([]*structs.ServiceRegistrationListStub)
+		assert.Len(services, 2, "Services")
+	})
+}
+
+func TestHTTP_ServiceRegistrationQuery(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, nil, func(s *TestAgent) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		service := mock.ServiceRegistration()
+		assert.Nil(state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}), "UpsertServiceRegistrations")
+
+		// Make the HTTP request
+		req, err := http.NewRequest("GET", "/v1/service/"+service.ServiceName, nil)
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		obj, err := s.Server.ServiceRegistrationRequest(respW, req)
+		assert.Nil(err, "Service Registration Request")
+		assert.Equal("1000", respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		services := obj.([]*structs.ServiceRegistration)
+		assert.Len(services, 1, "Services")
+		assert.Equal(service.ID, services[0].ID, "Registration")
+
+		// An unknown service has no registrations
+		req, err = http.NewRequest("GET", "/v1/service/unknown", nil)
+		assert.Nil(err, "HTTP Request")
+		obj, err = s.Server.ServiceRegistrationRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Service Registration Request")
+		assert.Len(obj.([]*structs.ServiceRegistration), 0, "Services")
+	})
+}
+
+func TestHTTP_ServiceRegistrationDelete(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, nil, func(s *TestAgent) {
+		// Directly manipulate the state
+		state := s.Agent.server.State()
+		service := mock.ServiceRegistration()
+		assert.Nil(state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}), "UpsertServiceRegistrations")
+
+		// Make the HTTP request
+		req, err := http.NewRequest("DELETE", "/v1/service/"+service.ServiceName+"/"+service.ID, nil)
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		// Make the request
+		_, err = s.Server.ServiceRegistrationRequest(respW, req)
+		assert.Nil(err, "Service Registration Request")
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		out, err := state.ServiceRegistrationByID(nil, service.ID)
+		assert.Nil(err, "ServiceRegistrationByID")
+		assert.Nil(out, "Registration")
+	})
+}
@@ -1,11 +1,18 @@
 package agent
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/hashicorp/nomad/acl"
+)
 
 func (s *HTTPServer) ClientStatsRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	if s.agent.client == nil {
 		return nil, clientNotRunning
 	}
+	if err := s.checkACL(req, (*acl.ACL).AllowNodeRead); err != nil {
+		return nil, err
+	}
 
 	clientStats := s.agent.client.StatsReporter()
 	return clientStats.LatestHostStats(), nil
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -11,11 +12,18 @@ func (s *HTTPServer) GarbageCollectRequest(resp http.ResponseWriter, req *http.R
 		return nil, CodedError(405, ErrInvalidMethod)
 	}
 
-	var args structs.GenericRequest
+	var args structs.GarbageCollectRequest
 	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
 		return nil, nil
 	}
 
+	if scope := req.URL.Query().Get("scope"); scope != "" {
+		args.Scopes = strings.Split(scope, ",")
+		if _, err := structs.ForceGCJobID(args.Scopes); err != nil {
+			return nil, CodedError(400, err.Error())
+		}
+	}
+
 	var gResp structs.GenericResponse
 	if err := s.agent.RPC("System.GarbageCollect", &args, &gResp); err != nil {
 		return nil, err
@@ -43,3 +43,31 @@ func TestHTTP_ReconcileJobSummaries(t *testing.T) {
 		}
 	})
 }
+
+func TestHTTP_SystemGarbageCollect_Scope(t *testing.T) {
+	t.Parallel()
+	httpTest(t, nil, func(s *TestAgent) {
+		req, err := http.NewRequest("PUT", "/v1/system/gc?scope=jobs,evals", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW := httptest.NewRecorder()
+		if _, err := s.Server.GarbageCollectRequest(respW, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// An unknown scope is rejected
+		req, err = http.NewRequest("PUT", "/v1/system/gc?scope=allocs", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		respW = httptest.NewRecorder()
+		_, err = s.Server.GarbageCollectRequest(respW, req)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if code := err.(HTTPCodedError).Code(); code != 400 {
+			t.Fatalf("expected 400; got %d", code)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// VariablesRequest is used to list the variables of a namespace. Only the
+// metadata of the variables is returned.
+func (s *HTTPServer) VariablesRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "GET" {
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+
+	args := structs.VariablesListRequest{
+		Namespace: req.URL.Query().Get("namespace"),
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.VariablesListResponse
+	if err := s.agent.RPC("Variables.List", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Variables == nil {
+		out.Variables = make([]*structs.VariableMetadata, 0)
+	}
+	return out.Variables, nil
+}
+
+// VariableSpecificRequest is used to read, write or delete a variable.
+// Writes and deletes can use check-and-set semantics by passing the
+// ModifyIndex of the variable they are based on with ?cas, 0 meaning that
+// the variable must not exist.
+func (s *HTTPServer) VariableSpecificRequest(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/v1/var/")
+	if len(path) == 0 {
+		return nil, CodedError(400, "Missing variable path")
+	}
+	switch req.Method {
+	case "GET":
+		return s.variableQuery(resp, req, path)
+	case "PUT", "POST":
+		return s.variableUpdate(resp, req, path)
+	case "DELETE":
+		return s.variableDelete(resp, req, path)
+	default:
+		return nil, CodedError(405, ErrInvalidMethod)
+	}
+}
+
+func (s *HTTPServer) variableQuery(resp http.ResponseWriter, req *http.Request,
+	path string) (interface{}, error) {
+	args := structs.VariablesReadRequest{
+		Namespace: req.URL.Query().Get("namespace"),
+		Path:      path,
+	}
+	if s.parse(resp, req, &args.Region, &args.QueryOptions) {
+		return nil, nil
+	}
+
+	var out structs.VariablesReadResponse
+	if err := s.agent.RPC("Variables.Read", &args, &out); err != nil {
+		return nil, err
+	}
+
+	setMeta(resp, &out.QueryMeta)
+	if out.Variable == nil {
+		return nil, CodedError(404, "variable not found")
+	}
+	return out.Variable, nil
+}
+
+func (s *HTTPServer) variableUpdate(resp http.ResponseWriter, req *http.Request,
+	path string) (interface{}, error) {
+	// Parse the variable
+	var variable structs.Variable
+	if err := decodeBody(req, &variable); err != nil {
+		return nil, CodedError(400, err.Error())
+	}
+
+	// Ensure the path and namespace match
+	if variable.Path == "" {
+		variable.Path = path
+	} else if variable.Path != path {
+		return nil, CodedError(400, "Variable path does not match request path")
+	}
+	if ns := req.URL.Query().Get("namespace"); ns != "" {
+		if variable.Namespace != "" && variable.Namespace != ns {
+			return nil, CodedError(400, "Variable namespace does not match request namespace")
+		}
+		variable.Namespace = ns
+	}
+
+	// Format the request
+	args := structs.VariablesUpsertRequest{
+		Variable: &variable,
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	// Check for cas value
+	cas, casVal, err := parseCAS(req)
+	if err != nil {
+		return nil, err
+	}
+	if cas {
+		args.Variable.ModifyIndex = casVal
+		args.CAS = true
+	}
+
+	var out structs.VariablesWriteResponse
+	if err := s.agent.RPC("Variables.Upsert", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
+func (s *HTTPServer) variableDelete(resp http.ResponseWriter, req *http.Request,
+	path string) (interface{}, error) {
+
+	args := structs.VariablesDeleteRequest{
+		Namespace: req.URL.Query().Get("namespace"),
+		Path:      path,
+	}
+	s.parseRegion(req, &args.Region)
+	s.parseToken(req, &args.AuthToken)
+
+	// Check for cas value
+	cas, casVal, err := parseCAS(req)
+	if err != nil {
+		return nil, err
+	}
+	args.CAS = cas
+	args.CheckIndex = casVal
+
+	var out structs.VariablesWriteResponse
+	if err := s.agent.RPC("Variables.Delete", &args, &out); err != nil {
+		return nil, err
+	}
+	setIndex(resp, out.Index)
+	return out, nil
+}
+
+// parseCAS returns whether the request uses check-and-set semantics and the
+// index to check against.
+func parseCAS(req *http.Request) (bool, uint64, error) {
+	casStr := req.URL.Query().Get("cas")
+	if casStr == "" {
+		return false, 0, nil
+	}
+	casVal, err := strconv.ParseUint(casStr, 10, 64)
+	if err != nil {
+		return false, 0, CodedError(400, fmt.Sprintf("Error parsing cas value: %v", err))
+	}
+	return true, casVal, nil
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP_VariablePutQueryList(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	httpTest(t, nil, func(s *TestAgent) {
+		// Write a variable
+		v := &structs.Variable{Items: map[string]string{"password": "secret"}}
+		req, err := http.NewRequest("PUT", "/v1/var/app/db?cas=0", encodeReq(v))
+		assert.Nil(err, "HTTP Request")
+		respW := httptest.NewRecorder()
+
+		obj, err := s.Server.VariableSpecificRequest(respW, req)
+		assert.Nil(err, "Variable Request")
+		assert.True(obj.(structs.VariablesWriteResponse).Updated, "Updated")
+		assert.NotZero(respW.HeaderMap.Get("X-Nomad-Index"), "missing index")
+
+		// Writing it again with the same check-and-set index fails
+		req, err = http.NewRequest("PUT", "/v1/var/app/db?cas=0", encodeReq(v))
+		assert.Nil(err, "HTTP Request")
+		obj, err = s.Server.VariableSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Variable Request")
+		assert.False(obj.(structs.VariablesWriteResponse).Updated, "Updated")
+
+		// Read it back
+		req, err = http.NewRequest("GET", "/v1/var/app/db", nil)
+		assert.Nil(err, "HTTP Request")
+		obj, err = s.Server.VariableSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Variable Request")
+		out := obj.(*structs.Variable)
+		assert.Equal(structs.DefaultNamespace, out.Namespace)
+		assert.Equal("app/db", out.Path)
+		assert.Equal("secret", out.Items["password"])
+
+		// List the variables
+		req, err = http.NewRequest("GET", "/v1/vars?prefix=app/", nil)
+		assert.Nil(err, "HTTP Request")
+		obj, err = s.Server.VariablesRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Variables Request")
+		assert.Len(obj.([]*structs.VariableMetadata), 1, "Variables")
+
+		// Delete the variable
+		req, err = http.NewRequest("DELETE", "/v1/var/app/db", nil)
+		assert.Nil(err, "HTTP Request")
+		_, err = s.Server.VariableSpecificRequest(httptest.NewRecorder(), req)
+		assert.Nil(err, "Variable Request")
+
+		req, err = http.NewRequest("GET", "/v1/var/app/db", nil)
+		assert.Nil(err, "HTTP Request")
+		_, err = s.Server.VariableSpecificRequest(httptest.NewRecorder(), req)
+		assert.NotNil(err, "expected variable not found")
+	})
+}
@@ -18,7 +18,16 @@ Usage: nomad agent-info [options]
 
 General Options:
 
-  ` + generalOptionsUsage()
+  ` + generalOptionsUsage() + `
+
+Agent Info Options:
+
+  -json
+    Output the agent info in a JSON format.
+
+  -t
+    Format and display the agent info using a Go template.
+`
 	return strings.TrimSpace(helpText)
 }
 
@@ -27,8 +36,13 @@ func (c *AgentInfoCommand) Synopsis() string {
 }
 
 func (c *AgentInfoCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
 	flags := c.Meta.FlagSet("agent-info", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
@@ -54,6 +68,17 @@ func (c *AgentInfoCommand) Run(args []string) int {
 		return 1
 	}
 
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, info)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
 	// Sort and output agent info
 	statsKeys := make([]string, 0, len(info.Stats))
 	for key := range info.Stats {
@@ -24,6 +24,15 @@ func TestAgentInfoCommand_Run(t *testing.T) {
 	if code != 0 {
 		t.Fatalf("expected exit 0, got: %d", code)
 	}
+
+	// Format the agent info with a template
+	code = cmd.Run([]string{"-address=" + url, "-t", `{{index .Config "Region"}}`})
+	if code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); !strings.HasSuffix(out, "global") {
+		t.Fatalf("expected region, got: %s", out)
+	}
 }
 
 func TestAgentInfoCommand_Fails(t *testing.T) {
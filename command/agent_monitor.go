@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/posener/complete"
+)
+
+type AgentMonitorCommand struct {
+	Meta
+}
+
+func (c *AgentMonitorCommand) Help() string {
+	helpText := `
+Usage: nomad monitor [options]
+
+  Stream the logs of a running Nomad agent. The logs are streamed at the
+  requested log level regardless of the level the agent logs at, so debug
+  logs can be inspected without restarting the agent.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Monitor Options:
+
+  -log-level=<level>
+    The log level to stream logs at. Valid levels are TRACE, DEBUG, INFO,
+    WARN and ERR. Defaults to INFO.
+
+  -node-id=<id>
+    The ID of a client node to stream the logs of. Defaults to the agent
+    the command is run against.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AgentMonitorCommand) Synopsis() string {
+	return "Stream the logs of a Nomad agent"
+}
+
+func (c *AgentMonitorCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-log-level": complete.PredictSet("TRACE", "DEBUG", "INFO", "WARN", "ERR"),
+			"-node-id":   c.PredictNodes(),
+		})
+}
+
+func (c *AgentMonitorCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *AgentMonitorCommand) Run(args []string) int {
+	var logLevel, nodeID string
+
+	flags := c.Meta.FlagSet("monitor", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&logLevel, "log-level", "INFO", "")
+	flags.StringVar(&nodeID, "node-id", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Connect to the node's agent if a node was given
+	if nodeID != "" {
+		if client, err = nodeClient(client, nodeID); err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+	}
+
+	stopCh := make(chan struct{})
+	logCh, err := client.Agent().Monitor(logLevel, stopCh, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error starting monitor: %s", err))
+		return 1
+	}
+
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalCh)
+
+	for {
+		select {
+		case line, ok := <-logCh:
+			if !ok {
+				c.Ui.Error("Remote side ended the monitor! This usually means that the\n" +
+					"remote side has exited or crashed.")
+				return 1
+			}
+			c.Ui.Output(line)
+		case <-signalCh:
+			close(stopCh)
+			return 0
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestAgentMonitorCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &AgentMonitorCommand{}
+}
+
+func TestAgentMonitorCommand_Fails(t *testing.T) {
+	t.Parallel()
+	srv, _, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &AgentMonitorCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on an invalid log level
+	if code := cmd.Run([]string{"-address=" + url, "-log-level=foo"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Unknown log level") {
+		t.Fatalf("expected log level error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on a nonexistent node
+	if code := cmd.Run([]string{"-address=" + url, "-node-id=12345678"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "No node(s) with prefix") {
+		t.Fatalf("expected node error, got: %s", out)
+	}
+}
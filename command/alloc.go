@@ -0,0 +1,71 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+type AllocCommand struct {
+	Meta
+}
+
+func (f *AllocCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *AllocCommand) Synopsis() string {
+	return "Interact with allocations"
+}
+
+func (f *AllocCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// lookupAllocation returns the allocation matching the ID or prefix. An error
+// listing the matches is returned if the prefix is ambiguous.
+func lookupAllocation(client *api.Client, allocID string, verbose bool, length int) (*api.Allocation, error) {
+	if len(allocID) == 1 {
+		return nil, fmt.Errorf("Alloc ID must contain at least two characters.")
+	}
+	if len(allocID)%2 == 1 {
+		// Identifiers must be of even length, so we strip off the last byte
+		// to provide a consistent user experience.
+		allocID = allocID[:len(allocID)-1]
+	}
+
+	allocs, _, err := client.Allocations().PrefixList(allocID)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying allocation: %v", err)
+	}
+	if len(allocs) == 0 {
+		return nil, fmt.Errorf("No allocation(s) with prefix or id %q found", allocID)
+	}
+	if len(allocs) > 1 {
+		out := formatAllocListStubs(allocs, verbose, length)
+		return nil, fmt.Errorf("Prefix matched multiple allocations\n\n%s", out)
+	}
+
+	// Prefix lookup matched a single allocation
+	alloc, _, err := client.Allocations().Info(allocs[0].ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying allocation: %s", err)
+	}
+	return alloc, nil
+}
+
+// allocHasTask returns whether the allocation's task group has the task.
+func allocHasTask(alloc *api.Allocation, task string) bool {
+	for _, tg := range alloc.Job.TaskGroups {
+		if tg.Name == nil || *tg.Name != alloc.TaskGroup {
+			continue
+		}
+		for _, t := range tg.Tasks {
+			if t.Name == task {
+				return true
+			}
+		}
+	}
+	return false
+}
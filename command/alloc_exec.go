@@ -0,0 +1,112 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/posener/complete"
+)
+
+type AllocExecCommand struct {
+	Meta
+}
+
+func (c *AllocExecCommand) Help() string {
+	helpText := `
+Usage: nomad alloc exec [options] <allocation> <task> <command> [<args>...]
+
+  Exec runs a command inside a running task of an allocation and prints its
+  output. The exit code of the command is the exit code of nomad alloc exec.
+  The command needs the alloc-exec capability on the namespace of the
+  allocation, and the alloc-node-exec capability too if the driver of the
+  task doesn't isolate it from the node.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Exec Options:
+
+  -timeout
+    Maximum time the command may run, for example "30s". The client agent
+    applies its own maximum of five minutes.
+
+  -verbose
+    Display full allocation IDs.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AllocExecCommand) Synopsis() string {
+	return "Run a command inside a task of an allocation"
+}
+
+func (c *AllocExecCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-timeout": complete.PredictAnything,
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *AllocExecCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictAllocations()
+}
+
+func (c *AllocExecCommand) Run(args []string) int {
+	var verbose bool
+	var timeout time.Duration
+
+	flags := c.Meta.FlagSet("alloc exec", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.DurationVar(&timeout, "timeout", 0, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got the allocation, task and command
+	args = flags.Args()
+	if len(args) < 3 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	allocID, task := args[0], args[1]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	alloc, err := lookupAllocation(client, allocID, verbose, length)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Validate the task
+	if !allocHasTask(alloc, task) {
+		c.Ui.Error(fmt.Sprintf("Task %q not found in allocation %q", task, limit(alloc.ID, length)))
+		return 1
+	}
+
+	resp, err := client.Allocations().Exec(alloc, task, args[2], args[3:], timeout, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error running command in allocation: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(strings.TrimSuffix(resp.Output, "\n"))
+	return resp.ExitCode
+}
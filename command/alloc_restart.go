@@ -0,0 +1,111 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type AllocRestartCommand struct {
+	Meta
+}
+
+func (c *AllocRestartCommand) Help() string {
+	helpText := `
+Usage: nomad alloc restart [options] <allocation> [<task>]
+
+  Restart restarts the tasks of an allocation in place, on the same node and
+  using the same allocation directory. If a task is given only that task is
+  restarted, otherwise all of the allocation's running tasks are. Tasks are
+  stopped respecting their kill_timeout, and the restart does not count
+  against the task group's restart policy.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Restart Options:
+
+  -verbose
+    Display full allocation IDs.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AllocRestartCommand) Synopsis() string {
+	return "Restart the tasks of an allocation in place"
+}
+
+func (c *AllocRestartCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *AllocRestartCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictAllocations()
+}
+
+func (c *AllocRestartCommand) Run(args []string) int {
+	var verbose bool
+
+	flags := c.Meta.FlagSet("alloc restart", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&verbose, "verbose", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got one or two args
+	args = flags.Args()
+	if l := len(args); l < 1 || l > 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	allocID := args[0]
+	var task string
+	if len(args) == 2 {
+		task = args[1]
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	alloc, err := lookupAllocation(client, allocID, verbose, length)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Validate the task
+	if task != "" && !allocHasTask(alloc, task) {
+		c.Ui.Error(fmt.Sprintf("Task %q not found in allocation %q", task, limit(alloc.ID, length)))
+		return 1
+	}
+
+	if err := client.Allocations().Restart(alloc, task, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error restarting allocation: %s", err))
+		return 1
+	}
+
+	if task != "" {
+		c.Ui.Output(fmt.Sprintf("Restarting task %q of allocation %q", task, limit(alloc.ID, length)))
+	} else {
+		c.Ui.Output(fmt.Sprintf("Restarting allocation %q", limit(alloc.ID, length)))
+	}
+	return 0
+}
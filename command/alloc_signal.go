@@ -0,0 +1,111 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type AllocSignalCommand struct {
+	Meta
+}
+
+func (c *AllocSignalCommand) Help() string {
+	helpText := `
+Usage: nomad alloc signal [options] <allocation> [<task>]
+
+  Signal sends a signal to the tasks of an allocation. If a task is given only
+  that task is signalled, otherwise all of the allocation's running tasks are.
+  This can be used to have a task reload its configuration without restarting
+  it.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Signal Options:
+
+  -s
+    The signal to send. Defaults to SIGKILL.
+
+  -verbose
+    Display full allocation IDs.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AllocSignalCommand) Synopsis() string {
+	return "Signal the tasks of an allocation"
+}
+
+func (c *AllocSignalCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-verbose": complete.PredictNothing,
+			"-s":       complete.PredictAnything,
+		})
+}
+
+func (c *AllocSignalCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictAllocations()
+}
+
+func (c *AllocSignalCommand) Run(args []string) int {
+	var verbose bool
+	var signal string
+
+	flags := c.Meta.FlagSet("alloc signal", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.StringVar(&signal, "s", "SIGKILL", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got one or two args
+	args = flags.Args()
+	if l := len(args); l < 1 || l > 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	allocID := args[0]
+	var task string
+	if len(args) == 2 {
+		task = args[1]
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	alloc, err := lookupAllocation(client, allocID, verbose, length)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Validate the task
+	if task != "" && !allocHasTask(alloc, task) {
+		c.Ui.Error(fmt.Sprintf("Task %q not found in allocation %q", task, limit(alloc.ID, length)))
+		return 1
+	}
+
+	if err := client.Allocations().Signal(alloc, nil, task, signal); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error signalling allocation: %s", err))
+		return 1
+	}
+
+	return 0
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/client"
+	"github.com/posener/complete"
 )
 
 type AllocStatusCommand struct {
@@ -58,6 +59,21 @@ func (c *AllocStatusCommand) Synopsis() string {
 	return "Display allocation status information and metadata"
 }
 
+func (c *AllocStatusCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-short":   complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+			"-stats":   complete.PredictNothing,
+			"-json":    complete.PredictNothing,
+			"-t":       complete.PredictAnything,
+		})
+}
+
+func (c *AllocStatusCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictAllocations()
+}
+
 func (c *AllocStatusCommand) Run(args []string) int {
 	var short, displayStats, verbose, json bool
 	var tmpl string
@@ -398,6 +414,10 @@ func (c *AllocStatusCommand) outputTaskStatus(state *api.TaskState) {
 			desc = event.DriverMessage
 		case api.TaskLeaderDead:
 			desc = "Leader Task in Group dead"
+		case api.TaskMainDead:
+			desc = "Main Tasks in Group dead"
+		case api.TaskChangeScript:
+			desc = event.Message
 		}
 
 		// Reverse order so we are sorted by time
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type AllocStopCommand struct {
+	Meta
+}
+
+func (c *AllocStopCommand) Help() string {
+	helpText := `
+Usage: nomad alloc stop [options] <allocation>
+
+  Stop an existing allocation. The allocation's tasks are gracefully shut
+  down and an evaluation is created so that the scheduler replaces the
+  allocation, possibly on another node. This can be used to evict a single
+  misbehaving allocation without draining its node. Upon successful stop, an
+  interactive monitor session will start to display log lines as the
+  evaluation is processed. It is safe to exit the monitor early using ctrl+c.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Stop Options:
+
+  -detach
+    Return immediately instead of entering monitor mode. After the stop
+    command is submitted, a new evaluation ID is printed to the screen, which
+    can be used to examine the evaluation using the eval-status command.
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AllocStopCommand) Synopsis() string {
+	return "Stop and reschedule an allocation"
+}
+
+func (c *AllocStopCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *AllocStopCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictAllocations()
+}
+
+func (c *AllocStopCommand) Run(args []string) int {
+	var detach, verbose bool
+
+	flags := c.Meta.FlagSet("alloc stop", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&detach, "detach", false, "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one arg
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	alloc, err := lookupAllocation(client, args[0], verbose, length)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	// Invoke the stop
+	resp, err := client.Allocations().Stop(alloc, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error stopping allocation: %s", err))
+		return 1
+	}
+
+	if detach {
+		c.Ui.Output(resp.EvalID)
+		return 0
+	}
+
+	// Start monitoring the replacement eval
+	mon := newMonitor(c.Ui, client, length)
+	return mon.monitor(resp.EvalID, false)
+}
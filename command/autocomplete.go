@@ -0,0 +1,236 @@
+package command
+
+import (
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+// autocompleteTimeout bounds the API requests made while completing a command
+// line so that an unreachable agent doesn't hang the shell.
+const autocompleteTimeout = 2 * time.Second
+
+// autocompleteClient returns an API client to use for dynamic completions.
+// Only the environment variables are taken into account since the command
+// line flags are not parsed while completing.
+func (m *Meta) autocompleteClient() (*api.Client, error) {
+	config := m.clientConfig()
+	config.HttpClient.Timeout = autocompleteTimeout
+	return api.NewClient(config)
+}
+
+// uuidPrefix returns the longest prefix of the given identifier that can be
+// used to query by ID, since identifier prefixes must be of even length.
+func uuidPrefix(prefix string) string {
+	if len(prefix)%2 == 1 {
+		return prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// predictIDs returns a predictor that completes identifiers using the given
+// lookup function. Any error is ignored and results in no completions.
+func (m *Meta) predictIDs(lookup func(client *api.Client, prefix string) ([]string, error)) complete.Predictor {
+	return complete.PredictFunc(func(a complete.Args) []string {
+		client, err := m.autocompleteClient()
+		if err != nil {
+			return nil
+		}
+
+		ids, err := lookup(client, a.Last)
+		if err != nil {
+			return nil
+		}
+		return ids
+	})
+}
+
+// PredictJobs returns a predictor that completes job IDs.
+func (m *Meta) PredictJobs() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		jobs, _, err := client.Jobs().PrefixList(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			ids = append(ids, job.ID)
+		}
+		return ids, nil
+	})
+}
+
+// PredictAllocations returns a predictor that completes allocation IDs.
+func (m *Meta) PredictAllocations() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		allocs, _, err := client.Allocations().PrefixList(uuidPrefix(prefix))
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(allocs))
+		for _, alloc := range allocs {
+			ids = append(ids, alloc.ID)
+		}
+		return ids, nil
+	})
+}
+
+// PredictNodes returns a predictor that completes node IDs.
+func (m *Meta) PredictNodes() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		nodes, _, err := client.Nodes().PrefixList(uuidPrefix(prefix))
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			ids = append(ids, node.ID)
+		}
+		return ids, nil
+	})
+}
+
+// PredictEvals returns a predictor that completes evaluation IDs.
+func (m *Meta) PredictEvals() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		evals, _, err := client.Evaluations().PrefixList(uuidPrefix(prefix))
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(evals))
+		for _, eval := range evals {
+			ids = append(ids, eval.ID)
+		}
+		return ids, nil
+	})
+}
+
+// PredictDeployments returns a predictor that completes deployment IDs.
+func (m *Meta) PredictDeployments() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		deployments, _, err := client.Deployments().PrefixList(uuidPrefix(prefix))
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(deployments))
+		for _, d := range deployments {
+			ids = append(ids, d.ID)
+		}
+		return ids, nil
+	})
+}
+
+// PredictNamespaces returns a predictor that completes namespace names.
+func (m *Meta) PredictNamespaces() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		namespaces, _, err := client.Namespaces().PrefixList(prefix, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(namespaces))
+		for _, ns := range namespaces {
+			names = append(names, ns.Name)
+		}
+		return names, nil
+	})
+}
+
+// PredictACLPolicies returns a predictor that completes ACL policy names.
+func (m *Meta) PredictACLPolicies() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		policies, _, err := client.ACLPolicies().List(&api.QueryOptions{Prefix: prefix})
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(policies))
+		for _, policy := range policies {
+			names = append(names, policy.Name)
+		}
+		return names, nil
+	})
+}
+
+// PredictACLRoles returns a predictor that completes ACL role names.
+func (m *Meta) PredictACLRoles() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		roles, _, err := client.ACLRoles().List(&api.QueryOptions{Prefix: prefix})
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(roles))
+		for _, role := range roles {
+			names = append(names, role.Name)
+		}
+		return names, nil
+	})
+}
+
+// PredictACLAuthMethods returns a predictor that completes ACL auth method
+// names.
+func (m *Meta) PredictACLAuthMethods() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		methods, _, err := client.ACLAuthMethods().List(&api.QueryOptions{Prefix: prefix})
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(methods))
+		for _, method := range methods {
+			names = append(names, method.Name)
+		}
+		return names, nil
+	})
+}
+
+// PredictACLBindingRules returns a predictor that completes ACL binding rule
+// IDs.
+func (m *Meta) PredictACLBindingRules() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		rules, _, err := client.ACLBindingRules().List(&api.QueryOptions{Prefix: prefix})
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			ids = append(ids, rule.ID)
+		}
+		return ids, nil
+	})
+}
+
+// PredictACLTokens returns a predictor that completes ACL token accessor IDs.
+func (m *Meta) PredictACLTokens() complete.Predictor {
+	return m.predictIDs(func(client *api.Client, prefix string) ([]string, error) {
+		tokens, _, err := client.ACLTokens().List(&api.QueryOptions{Prefix: prefix})
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(tokens))
+		for _, token := range tokens {
+			ids = append(ids, token.AccessorID)
+		}
+		return ids, nil
+	})
+}
+
+// mergeAutocompleteFlags merges the given sets of flag predictors.
+func mergeAutocompleteFlags(flags ...complete.Flags) complete.Flags {
+	merged := make(complete.Flags)
+	for _, f := range flags {
+		for k, v := range f {
+			merged[k] = v
+		}
+	}
+	return merged
+}
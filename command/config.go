@@ -0,0 +1,35 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+type ConfigCommand struct {
+	Meta
+}
+
+func (f *ConfigCommand) Help() string {
+	helpText := `
+Usage: nomad config <subcommand> [options] [args]
+
+  This command groups subcommands for interacting with the configuration of
+  the Nomad agents.
+
+  Validate the configuration files of an agent:
+
+      $ nomad config validate /etc/nomad.d
+
+  Please see the individual subcommand help for detailed usage information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (f *ConfigCommand) Synopsis() string {
+	return "Interact with agent configurations"
+}
+
+func (f *ConfigCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/command/agent"
+	"github.com/posener/complete"
+)
+
+type ConfigValidateCommand struct {
+	Meta
+}
+
+func (c *ConfigValidateCommand) Help() string {
+	helpText := `
+Usage: nomad config validate <path> [<path>...]
+
+  Performs a thorough check of the given agent configuration files or
+  directories, without starting an agent. The files are merged in the order
+  given, the same way as the -config flags of the agent command, and the
+  resulting configuration is checked for syntax errors, unknown keys and
+  invalid settings such as missing TLS certificates or malformed telemetry
+  addresses. Errors are reported with the file and line they occur at when
+  possible.
+
+  This can be used to check a configuration before restarting an agent with it.
+  Settings given as agent command line flags are not taken into account.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ConfigValidateCommand) Synopsis() string {
+	return "Validate agent configuration files"
+}
+
+func (c *ConfigValidateCommand) AutocompleteFlags() complete.Flags {
+	return nil
+}
+
+func (c *ConfigValidateCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictOr(complete.PredictFiles("*.hcl"), complete.PredictFiles("*.json"), complete.PredictDirs("*"))
+}
+
+func (c *ConfigValidateCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("config validate", FlagSetNone)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got at least one path
+	args = flags.Args()
+	if len(args) == 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Load every path so that all the syntax errors are reported at once
+	config := agent.DefaultConfig()
+	failed := false
+	for _, path := range args {
+		current, err := agent.LoadConfig(path)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error loading configuration: %s", err))
+			failed = true
+			continue
+		}
+		config = config.Merge(current)
+	}
+	if failed {
+		return 1
+	}
+
+	warnings, err := config.Validate()
+	if len(warnings) != 0 {
+		c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+			"[bold][yellow]Configuration warnings:\n%s[reset]\n", strings.Join(warnings, "\n"))))
+	}
+	if err != nil {
+		c.Ui.Error(c.Colorize().Color("[bold][red]Configuration validation errors:[reset]"))
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui.Output(c.Colorize().Color("[bold][green]Configuration validation successful[reset]"))
+	return 0
+}
@@ -0,0 +1,119 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestConfigValidateCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &ConfigValidateCommand{}
+}
+
+func TestConfigValidateCommand(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return path
+	}
+
+	// Bind to a link local address so that the advertise addresses can be
+	// determined on hosts whose hostname resolves to a loopback address.
+	base := writeConfig("base.hcl", `
+data_dir = "`+dir+`"
+bind_addr = "169.254.0.1"
+`)
+	server := writeConfig("server.hcl", `
+server {
+  enabled = true
+}
+`)
+	syntax := writeConfig("syntax.hcl", `
+server {
+  enabled = true
+  bootstrap = 1
+}
+`)
+	telemetry := writeConfig("telemetry.hcl", `
+telemetry {
+  statsd_address = "localhost"
+}
+`)
+
+	cases := []struct {
+		Paths  []string
+		Code   int
+		Output string
+	}{
+		{
+			Paths:  []string{base, server},
+			Code:   0,
+			Output: "Configuration validation successful",
+		},
+		{
+			Paths:  []string{base},
+			Code:   1,
+			Output: "Must specify either server, client or dev mode",
+		},
+		{
+			Paths:  []string{base, syntax},
+			Code:   1,
+			Output: "At 4:3: invalid key: bootstrap",
+		},
+		{
+			Paths:  []string{base, server, telemetry},
+			Code:   1,
+			Output: "statsd_address",
+		},
+	}
+
+	for _, tc := range cases {
+		ui := new(cli.MockUi)
+		cmd := &ConfigValidateCommand{Meta: Meta{Ui: ui}}
+		if code := cmd.Run(tc.Paths); code != tc.Code {
+			t.Fatalf("%v: expected exit code %d; got %d: %s", tc.Paths, tc.Code, code, ui.ErrorWriter.String())
+		}
+
+		out := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(out, tc.Output) {
+			t.Fatalf("%v: expected %q in output; got %s", tc.Paths, tc.Output, out)
+		}
+	}
+}
+
+func TestConfigValidateCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &ConfigValidateCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on a missing file
+	if code := cmd.Run([]string{"/unicorns/leprechauns.hcl"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "/unicorns/leprechauns.hcl") {
+		t.Fatalf("expected loading error, got: %s", out)
+	}
+}
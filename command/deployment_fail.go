@@ -3,6 +3,8 @@ package command
 import (
 	"fmt"
 	"strings"
+
+	"github.com/posener/complete"
 )
 
 type DeploymentFailCommand struct {
@@ -39,6 +41,18 @@ func (c *DeploymentFailCommand) Synopsis() string {
 	return "Manually fail a deployment"
 }
 
+func (c *DeploymentFailCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *DeploymentFailCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictDeployments()
+}
+
 func (c *DeploymentFailCommand) Run(args []string) int {
 	var detach, verbose bool
 
@@ -31,6 +31,18 @@ List Options:
 
   -verbose
     Display full information.
+
+  -per-page
+    How many deployments to list at a time. Defaults to listing all
+    deployments.
+
+  -page-token
+    Where to start listing deployments from when -per-page is set. This is the
+    token printed with the previous page.
+
+  -filter
+    Only list the deployments matching the filter expression, e.g.
+    'Status == "running"'.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -41,13 +53,17 @@ func (c *DeploymentListCommand) Synopsis() string {
 
 func (c *DeploymentListCommand) Run(args []string) int {
 	var json, verbose bool
-	var tmpl string
+	var tmpl, pageToken, filter string
+	var perPage int
 
 	flags := c.Meta.FlagSet("deployment list", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&verbose, "verbose", false, "")
 	flags.BoolVar(&json, "json", false, "")
 	flags.StringVar(&tmpl, "t", "", "")
+	flags.IntVar(&perPage, "per-page", 0, "")
+	flags.StringVar(&pageToken, "page-token", "", "")
+	flags.StringVar(&filter, "filter", "", "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -73,7 +89,12 @@ func (c *DeploymentListCommand) Run(args []string) int {
 		return 1
 	}
 
-	deploys, _, err := client.Deployments().List(nil)
+	q := &api.QueryOptions{
+		PerPage:   int32(perPage),
+		NextToken: pageToken,
+		Filter:    filter,
+	}
+	deploys, qm, err := client.Deployments().List(q)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error retrieving deployments: %s", err))
 		return 1
@@ -91,6 +112,9 @@ func (c *DeploymentListCommand) Run(args []string) int {
 	}
 
 	c.Ui.Output(formatDeployments(deploys, length))
+	if next := formatNextPage("deployment list", perPage, qm.NextToken); next != "" {
+		c.Ui.Output(next)
+	}
 	return 0
 }
 
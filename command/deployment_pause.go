@@ -3,6 +3,8 @@ package command
 import (
 	"fmt"
 	"strings"
+
+	"github.com/posener/complete"
 )
 
 type DeploymentPauseCommand struct {
@@ -32,6 +34,17 @@ func (c *DeploymentPauseCommand) Synopsis() string {
 	return "Pause a deployment"
 }
 
+func (c *DeploymentPauseCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *DeploymentPauseCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictDeployments()
+}
+
 func (c *DeploymentPauseCommand) Run(args []string) int {
 	var verbose bool
 
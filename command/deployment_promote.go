@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/nomad/api"
 	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/posener/complete"
 )
 
 type DeploymentPromoteCommand struct {
@@ -49,6 +50,19 @@ func (c *DeploymentPromoteCommand) Synopsis() string {
 	return "Promote canaries in a deployment"
 }
 
+func (c *DeploymentPromoteCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+			"-group":   complete.PredictAnything,
+		})
+}
+
+func (c *DeploymentPromoteCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictDeployments()
+}
+
 func (c *DeploymentPromoteCommand) Run(args []string) int {
 	var detach, verbose bool
 	var groups []string
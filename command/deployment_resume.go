@@ -3,6 +3,8 @@ package command
 import (
 	"fmt"
 	"strings"
+
+	"github.com/posener/complete"
 )
 
 type DeploymentResumeCommand struct {
@@ -37,6 +39,18 @@ func (c *DeploymentResumeCommand) Synopsis() string {
 	return "Resume a paused deployment"
 }
 
+func (c *DeploymentResumeCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *DeploymentResumeCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictDeployments()
+}
+
 func (c *DeploymentResumeCommand) Run(args []string) int {
 	var detach, verbose bool
 
@@ -2,9 +2,11 @@ package command
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
 )
 
 type DeploymentStatusCommand struct {
@@ -18,6 +20,10 @@ Usage: nomad deployment status [options] <deployment id>
 Status is used to display the status of a deployment. The status will display
 the number of desired changes as well as the currently applied changes.
 
+While canaries await promotion, such as during a blue/green deployment, the
+allocations of the job are also summarized per version so the new set can be
+compared with the old one.
+
 General Options:
 
   ` + generalOptionsUsage() + `
@@ -40,6 +46,19 @@ func (c *DeploymentStatusCommand) Synopsis() string {
 	return "Display the status of a deployment"
 }
 
+func (c *DeploymentStatusCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-verbose": complete.PredictNothing,
+			"-json":    complete.PredictNothing,
+			"-t":       complete.PredictAnything,
+		})
+}
+
+func (c *DeploymentStatusCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictDeployments()
+}
+
 func (c *DeploymentStatusCommand) Run(args []string) int {
 	var json, verbose bool
 	var tmpl string
@@ -100,9 +119,96 @@ func (c *DeploymentStatusCommand) Run(args []string) int {
 	}
 
 	c.Ui.Output(c.Colorize().Color(formatDeployment(deploy, length)))
+
+	// Show the old and new sets of allocations side by side until the
+	// canaries are promoted
+	if deploy.Status == "running" && deploymentAwaitsPromotion(deploy) {
+		allocs, _, err := client.Jobs().Allocations(deploy.JobID, false, nil)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error retrieving allocations of job %q: %s", deploy.JobID, err))
+			return 1
+		}
+		c.Ui.Output(c.Colorize().Color("\n[bold]Versions[reset]\n" + formatDeploymentVersions(allocs)))
+	}
 	return 0
 }
 
+// deploymentAwaitsPromotion returns whether a task group of the deployment has
+// canaries that haven't been promoted.
+func deploymentAwaitsPromotion(d *api.Deployment) bool {
+	for _, state := range d.TaskGroups {
+		if state.DesiredCanaries > 0 && !state.Promoted {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDeploymentVersions summarizes the running and pending allocations per
+// task group and job version, newest version first.
+func formatDeploymentVersions(allocs []*api.AllocationListStub) string {
+	type versionKey struct {
+		group   string
+		version uint64
+	}
+	type versionCounts struct {
+		canaries, running, pending, healthy, unhealthy int
+	}
+
+	counts := make(map[versionKey]*versionCounts)
+	var keys []versionKey
+	for _, alloc := range allocs {
+		if alloc.DesiredStatus != "run" {
+			continue
+		}
+		if alloc.ClientStatus != "running" && alloc.ClientStatus != "pending" {
+			continue
+		}
+
+		key := versionKey{alloc.TaskGroup, alloc.JobVersion}
+		c, ok := counts[key]
+		if !ok {
+			c = &versionCounts{}
+			counts[key] = c
+			keys = append(keys, key)
+		}
+
+		if alloc.ClientStatus == "running" {
+			c.running++
+		} else {
+			c.pending++
+		}
+		if ds := alloc.DeploymentStatus; ds != nil {
+			if ds.Canary {
+				c.canaries++
+			}
+			if ds.Healthy != nil {
+				if *ds.Healthy {
+					c.healthy++
+				} else {
+					c.unhealthy++
+				}
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].group != keys[j].group {
+			return keys[i].group < keys[j].group
+		}
+		return keys[i].version > keys[j].version
+	})
+
+	rows := make([]string, len(keys)+1)
+	rows[0] = "Task Group|Version|Canaries|Running|Pending|Healthy|Unhealthy"
+	for i, key := range keys {
+		c := counts[key]
+		rows[i+1] = fmt.Sprintf("%s|%d|%d|%d|%d|%d|%d",
+			key.group, key.version, c.canaries, c.running, c.pending, c.healthy, c.unhealthy)
+	}
+	return formatList(rows)
+}
+
 func getDeployment(client *api.Deployments, dID string) (match *api.Deployment, possible []*api.Deployment, err error) {
 	// First attempt an immediate lookup if we have a proper length
 	if len(dID) == 36 {
@@ -162,14 +268,20 @@ func formatDeployment(d *api.Deployment, uuidLength int) string {
 
 func formatDeploymentGroups(d *api.Deployment, uuidLength int) string {
 	// Detect if we need to add these columns
-	canaries, autorevert := false, false
+	canaries, autorevert, autopromote, progressDeadline := false, false, false, false
 	for _, state := range d.TaskGroups {
 		if state.AutoRevert {
 			autorevert = true
 		}
+		if state.AutoPromote {
+			autopromote = true
+		}
 		if state.DesiredCanaries > 0 {
 			canaries = true
 		}
+		if state.ProgressDeadline != 0 {
+			progressDeadline = true
+		}
 	}
 
 	// Build the row string
@@ -177,6 +289,9 @@ func formatDeploymentGroups(d *api.Deployment, uuidLength int) string {
 	if autorevert {
 		rowString += "Auto Revert|"
 	}
+	if autopromote {
+		rowString += "Auto Promote|"
+	}
 	if canaries {
 		rowString += "Promoted|"
 	}
@@ -185,6 +300,9 @@ func formatDeploymentGroups(d *api.Deployment, uuidLength int) string {
 		rowString += "Canaries|"
 	}
 	rowString += "Placed|Healthy|Unhealthy"
+	if progressDeadline {
+		rowString += "|Progress Deadline"
+	}
 
 	rows := make([]string, len(d.TaskGroups)+1)
 	rows[0] = rowString
@@ -194,6 +312,9 @@ func formatDeploymentGroups(d *api.Deployment, uuidLength int) string {
 		if autorevert {
 			row += fmt.Sprintf("%v|", state.AutoRevert)
 		}
+		if autopromote {
+			row += fmt.Sprintf("%v|", state.AutoPromote)
+		}
 		if canaries {
 			if state.DesiredCanaries > 0 {
 				row += fmt.Sprintf("%v|", state.Promoted)
@@ -206,6 +327,13 @@ func formatDeploymentGroups(d *api.Deployment, uuidLength int) string {
 			row += fmt.Sprintf("%d|", state.DesiredCanaries)
 		}
 		row += fmt.Sprintf("%d|%d|%d", state.PlacedAllocs, state.HealthyAllocs, state.UnhealthyAllocs)
+		if progressDeadline {
+			if state.ProgressDeadline != 0 {
+				row += fmt.Sprintf("|%v", state.ProgressDeadline)
+			} else {
+				row += "|N/A"
+			}
+		}
 		rows[i] = row
 		i++
 	}
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/mitchellh/cli"
 )
 
@@ -34,3 +36,34 @@ func TestDeploymentStatusCommand_Fails(t *testing.T) {
 	}
 	ui.ErrorWriter.Reset()
 }
+
+func TestDeploymentStatusCommand_FormatVersions(t *testing.T) {
+	t.Parallel()
+	allocs := []*api.AllocationListStub{
+		// The old set
+		{TaskGroup: "web", JobVersion: 1, DesiredStatus: "run", ClientStatus: "running"},
+		{TaskGroup: "web", JobVersion: 1, DesiredStatus: "run", ClientStatus: "running"},
+		{TaskGroup: "web", JobVersion: 0, DesiredStatus: "stop", ClientStatus: "complete"},
+
+		// The new set
+		{
+			TaskGroup: "web", JobVersion: 2, DesiredStatus: "run", ClientStatus: "running",
+			DeploymentStatus: &api.AllocDeploymentStatus{Canary: true, Healthy: helper.BoolToPtr(true)},
+		},
+		{
+			TaskGroup: "web", JobVersion: 2, DesiredStatus: "run", ClientStatus: "pending",
+			DeploymentStatus: &api.AllocDeploymentStatus{Canary: true},
+		},
+	}
+
+	lines := strings.Split(formatDeploymentVersions(allocs), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got: %q", lines)
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "web 2 2 1 1 1 0" {
+		t.Fatalf("bad new set: %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "web 1 0 2 0 0 0" {
+		t.Fatalf("bad old set: %q", lines[2])
+	}
+}
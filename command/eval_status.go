@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
 )
 
 type EvalStatusCommand struct {
@@ -46,6 +47,20 @@ func (c *EvalStatusCommand) Synopsis() string {
 	return "Display evaluation status and placement failure reasons"
 }
 
+func (c *EvalStatusCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-monitor": complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+			"-json":    complete.PredictNothing,
+			"-t":       complete.PredictAnything,
+		})
+}
+
+func (c *EvalStatusCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictEvals()
+}
+
 func (c *EvalStatusCommand) Run(args []string) int {
 	var monitor, verbose, json bool
 	var tmpl string
@@ -12,6 +12,7 @@ import (
 
 	humanize "github.com/dustin/go-humanize"
 	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
 )
 
 const (
@@ -69,6 +70,10 @@ FS Specific Options:
 
   -c
     Sets the tail location in number of bytes relative to the end of the file.
+
+  -secrets
+    Allow listing and reading the files of the secrets directories of tasks.
+    The client must grant it with the "fs.read_secrets" option.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -77,8 +82,27 @@ func (f *FSCommand) Synopsis() string {
 	return "Inspect the contents of an allocation directory"
 }
 
+func (f *FSCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(f.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-verbose": complete.PredictNothing,
+			"-H":       complete.PredictNothing,
+			"-job":     complete.PredictNothing,
+			"-stat":    complete.PredictNothing,
+			"-f":       complete.PredictNothing,
+			"-tail":    complete.PredictNothing,
+			"-n":       complete.PredictAnything,
+			"-c":       complete.PredictAnything,
+			"-secrets": complete.PredictNothing,
+		})
+}
+
+func (f *FSCommand) AutocompleteArgs() complete.Predictor {
+	return f.PredictAllocations()
+}
+
 func (f *FSCommand) Run(args []string) int {
-	var verbose, machine, job, stat, tail, follow bool
+	var verbose, machine, job, stat, tail, follow, secrets bool
 	var numLines, numBytes int64
 
 	flags := f.Meta.FlagSet("fs", FlagSetClient)
@@ -89,6 +113,7 @@ func (f *FSCommand) Run(args []string) int {
 	flags.BoolVar(&stat, "stat", false, "")
 	flags.BoolVar(&follow, "f", false, "")
 	flags.BoolVar(&tail, "tail", false, "")
+	flags.BoolVar(&secrets, "secrets", false, "")
 	flags.Int64Var(&numLines, "n", -1, "")
 	flags.Int64Var(&numBytes, "c", -1, "")
 
@@ -171,7 +196,7 @@ func (f *FSCommand) Run(args []string) int {
 	}
 
 	// Get file stat info
-	file, _, err := client.AllocFS().Stat(alloc, path, nil)
+	file, _, err := client.AllocFS().Stat(alloc, path, fsQueryOptions(secrets))
 	if err != nil {
 		f.Ui.Error(err.Error())
 		return 1
@@ -203,7 +228,7 @@ func (f *FSCommand) Run(args []string) int {
 	// Determine if the path is a file or a directory.
 	if file.IsDir {
 		// We have a directory, list it.
-		files, _, err := client.AllocFS().List(alloc, path, nil)
+		files, _, err := client.AllocFS().List(alloc, path, fsQueryOptions(secrets))
 		if err != nil {
 			f.Ui.Error(fmt.Sprintf("Error listing alloc dir: %s", err))
 			return 1
@@ -238,9 +263,9 @@ func (f *FSCommand) Run(args []string) int {
 	var readErr error
 	if !tail {
 		if follow {
-			r, readErr = f.followFile(client, alloc, path, api.OriginStart, 0, -1)
+			r, readErr = f.followFile(client, alloc, path, api.OriginStart, 0, -1, secrets)
 		} else {
-			r, readErr = client.AllocFS().Cat(alloc, path, nil)
+			r, readErr = client.AllocFS().Cat(alloc, path, fsQueryOptions(secrets))
 		}
 
 		if readErr != nil {
@@ -269,12 +294,12 @@ func (f *FSCommand) Run(args []string) int {
 		}
 
 		if follow {
-			r, readErr = f.followFile(client, alloc, path, api.OriginEnd, offset, numLines)
+			r, readErr = f.followFile(client, alloc, path, api.OriginEnd, offset, numLines, secrets)
 		} else {
 			// This offset needs to be relative from the front versus the follow
 			// is relative to the end
 			offset = file.Size - offset
-			r, readErr = client.AllocFS().ReadAt(alloc, path, offset, -1, nil)
+			r, readErr = client.AllocFS().ReadAt(alloc, path, offset, -1, fsQueryOptions(secrets))
 
 			// If numLines is set, wrap the reader
 			if numLines != -1 {
@@ -299,13 +324,22 @@ func (f *FSCommand) Run(args []string) int {
 	return 0
 }
 
+// fsQueryOptions returns the options of the requests to the file system API,
+// asking to read the secrets directories if set.
+func fsQueryOptions(secrets bool) *api.QueryOptions {
+	if !secrets {
+		return nil
+	}
+	return &api.QueryOptions{Params: map[string]string{"secrets": "true"}}
+}
+
 // followFile outputs the contents of the file to stdout relative to the end of
 // the file. If numLines does not equal -1, then tail -n behavior is used.
 func (f *FSCommand) followFile(client *api.Client, alloc *api.Allocation,
-	path, origin string, offset, numLines int64) (io.ReadCloser, error) {
+	path, origin string, offset, numLines int64, secrets bool) (io.ReadCloser, error) {
 
 	cancel := make(chan struct{})
-	frames, err := client.AllocFS().Stream(alloc, path, origin, offset, cancel, nil)
+	frames, err := client.AllocFS().Stream(alloc, path, origin, offset, cancel, fsQueryOptions(secrets))
 	if err != nil {
 		return nil, err
 	}
@@ -73,6 +73,16 @@ func formatTimeDifference(first, second time.Time, d time.Duration) string {
 	return second.Truncate(d).Sub(first.Truncate(d)).String()
 }
 
+// formatNextPage returns a hint on how to list the next page of a paginated
+// list command. An empty string is returned if there are no more pages.
+func formatNextPage(command string, perPage int, nextToken string) string {
+	if nextToken == "" {
+		return ""
+	}
+	return fmt.Sprintf("\nResults have been paginated. To get the next page run:\n\n"+
+		"  nomad %s -per-page %d -page-token %s", command, perPage, nextToken)
+}
+
 // getLocalNodeID returns the node ID of the local Nomad Client and an error if
 // it couldn't be determined or the Agent is not running in Client mode.
 func getLocalNodeID(client *api.Client) (string, error) {
@@ -318,3 +328,34 @@ func getVersion(job *api.Job) uint64 {
 
 	return 0
 }
+
+// nodeClient returns a client for the agent of the node matching the ID
+// prefix.
+func nodeClient(client *api.Client, nodeID string) (*api.Client, error) {
+	if len(nodeID) == 1 {
+		return nil, fmt.Errorf("Identifier must contain at least two characters.")
+	}
+	if len(nodeID)%2 == 1 {
+		// Identifiers must be of even length, so we strip off the last byte
+		// to provide a consistent user experience.
+		nodeID = nodeID[:len(nodeID)-1]
+	}
+
+	nodes, _, err := client.Nodes().PrefixList(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying node info: %s", err)
+	}
+	switch len(nodes) {
+	case 0:
+		return nil, fmt.Errorf("No node(s) with prefix %q found", nodeID)
+	case 1:
+	default:
+		return nil, fmt.Errorf("Prefix %q matched multiple nodes", nodeID)
+	}
+
+	agentClient, err := client.GetNodeClient(nodes[0].ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating client for node %q: %s", nodes[0].ID, err)
+	}
+	return agentClient, nil
+}
@@ -294,7 +294,6 @@ job "example" {
         cpu    = 500 # 500 MHz
         memory = 256 # 256MB
         network {
-          mbits = 10
           port "db" {}
         }
       }
@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/jobspec"
+	"github.com/posener/complete"
 )
 
 type InspectCommand struct {
@@ -26,6 +28,10 @@ Inspect Options:
   -version <job version>
     Display the job at the given job version.
 
+  -hcl
+    Display the job as a job specification in HCL that can be submitted with
+    "nomad run", for example to roll back to the given job version.
+
   -json
     Output the job in its JSON format.
 
@@ -39,12 +45,27 @@ func (c *InspectCommand) Synopsis() string {
 	return "Inspect a submitted job"
 }
 
+func (c *InspectCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-hcl":     complete.PredictNothing,
+			"-json":    complete.PredictNothing,
+			"-t":       complete.PredictAnything,
+			"-version": complete.PredictAnything,
+		})
+}
+
+func (c *InspectCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
 func (c *InspectCommand) Run(args []string) int {
-	var json bool
+	var json, hcl bool
 	var tmpl, versionStr string
 
 	flags := c.Meta.FlagSet("inspect", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&hcl, "hcl", false, "")
 	flags.BoolVar(&json, "json", false, "")
 	flags.StringVar(&tmpl, "t", "", "")
 	flags.StringVar(&versionStr, "version", "", "")
@@ -54,6 +75,11 @@ func (c *InspectCommand) Run(args []string) int {
 	}
 	args = flags.Args()
 
+	if hcl && (json || len(tmpl) > 0) {
+		c.Ui.Error("-hcl is exclusive with -json and -t")
+		return 1
+	}
+
 	// Get the HTTP client
 	client, err := c.Meta.Client()
 	if err != nil {
@@ -131,6 +157,18 @@ func (c *InspectCommand) Run(args []string) int {
 		return 0
 	}
 
+	// Print the job specification
+	if hcl {
+		out, err := jobspec.Format(job)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error formatting the job specification: %s", err))
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
 	// Print the contents of the job
 	req := api.RegisterJobRequest{Job: job}
 	f, err := DataFormat("json", "")
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/jobspec"
 	"github.com/mitchellh/cli"
 )
 
@@ -54,4 +56,48 @@ func TestInspectCommand_Fails(t *testing.T) {
 	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Both json and template formatting are not allowed") {
 		t.Fatalf("expected getting formatter error, got: %s", out)
 	}
+	ui.ErrorWriter.Reset()
+
+	// Fails when -hcl is combined with -json
+	if code := cmd.Run([]string{"-address=" + url, "-hcl", "-json", "nope"}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "exclusive") {
+		t.Fatalf("expected exclusive flags error, got: %s", out)
+	}
+}
+
+func TestInspectCommand_HCL(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	// Register two versions of the job
+	job := testJob("job1_sfx")
+	if _, _, err := client.Jobs().Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	job.Priority = helper.IntToPtr(80)
+	if _, _, err := client.Jobs().Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &InspectCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-hcl", "-version=0", "job1_sfx"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+
+	// The output is a job specification of the requested version
+	out := ui.OutputWriter.String()
+	parsed, err := jobspec.Parse(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("error parsing output: %v\n%s", err, out)
+	}
+	if *parsed.ID != "job1_sfx" || *parsed.Priority != 1 {
+		t.Fatalf("bad job: %s", out)
+	}
+	if task := parsed.TaskGroups[0].Tasks[0]; task.Name != "task1" || task.Config["run_for"] != "5s" {
+		t.Fatalf("bad task: %s", out)
+	}
 }
@@ -3,6 +3,8 @@ package command
 import (
 	"fmt"
 	"strings"
+
+	"github.com/posener/complete"
 )
 
 type JobDeploymentsCommand struct {
@@ -40,6 +42,20 @@ func (c *JobDeploymentsCommand) Synopsis() string {
 	return "List deployments for a job"
 }
 
+func (c *JobDeploymentsCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-latest":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+			"-json":    complete.PredictNothing,
+			"-t":       complete.PredictAnything,
+		})
+}
+
+func (c *JobDeploymentsCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
 func (c *JobDeploymentsCommand) Run(args []string) int {
 	var json, latest, verbose bool
 	var tmpl string
@@ -6,7 +6,10 @@ import (
 	"os"
 	"strings"
 
+	"github.com/golang/snappy"
+	"github.com/hashicorp/nomad/api"
 	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/posener/complete"
 )
 
 type JobDispatchCommand struct {
@@ -39,6 +42,16 @@ Dispatch Options:
     once to inject multiple metadata key/value pairs. Arbitrary keys are not
     allowed. The parameterized job must allow the key to be merged.
     
+  -compress
+    Compress the payload before sending it. The payload size limit applies to
+    the uncompressed payload, which defaults to 16KiB and can be raised with
+    the server's max_dispatch_payload_size option.
+
+  -idempotency-token
+    Token used to deduplicate dispatches, for example when a dispatch is
+    retried. If a job was already dispatched with the token, its ID is
+    returned and no new job is dispatched.
+
   -detach
     Return immediately instead of entering monitor mode. After job dispatch,
     the evaluation ID will be printed to the screen, which can be used to
@@ -54,14 +67,32 @@ func (c *JobDispatchCommand) Synopsis() string {
 	return "Dispatch an instance of a parameterized job"
 }
 
+func (c *JobDispatchCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":            complete.PredictNothing,
+			"-verbose":           complete.PredictNothing,
+			"-compress":          complete.PredictNothing,
+			"-idempotency-token": complete.PredictAnything,
+			"-meta":              complete.PredictAnything,
+		})
+}
+
+func (c *JobDispatchCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
 func (c *JobDispatchCommand) Run(args []string) int {
-	var detach, verbose bool
+	var detach, verbose, compress bool
+	var idempotencyToken string
 	var meta []string
 
 	flags := c.Meta.FlagSet("job dispatch", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&detach, "detach", false, "")
 	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.BoolVar(&compress, "compress", false, "")
+	flags.StringVar(&idempotencyToken, "idempotency-token", "", "")
 	flags.Var((*flaghelper.StringFlag)(&meta), "meta", "")
 
 	if err := flags.Parse(args); err != nil {
@@ -119,14 +150,24 @@ func (c *JobDispatchCommand) Run(args []string) int {
 	}
 
 	// Dispatch the job
-	resp, _, err := client.Jobs().Dispatch(job, metaMap, payload, nil)
+	req := &api.JobDispatchRequest{
+		JobID:            job,
+		Meta:             metaMap,
+		Payload:          payload,
+		IdempotencyToken: idempotencyToken,
+	}
+	if compress && len(payload) != 0 {
+		req.Payload = snappy.Encode(nil, payload)
+		req.PayloadCompressed = true
+	}
+	resp, _, err := client.Jobs().DispatchOpts(req, nil)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to dispatch job: %s", err))
 		return 1
 	}
 
-	// See if an evaluation was created. If the job is periodic there will be no
-	// eval.
+	// See if an evaluation was created. If the job is periodic or was already
+	// dispatched with the idempotency token there will be no eval.
 	evalCreated := resp.EvalID != ""
 
 	basic := []string{
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
 	"github.com/ryanuber/columnize"
 )
 
@@ -32,7 +33,11 @@ History Options:
 
   -p
     Display the difference between each job and its predecessor.
-    
+
+  -diff-version <job version>
+    Display the difference between each job and the given job version instead
+    of its predecessor. Implies -p.
+
   -full
     Display the full job definition for each version.
 
@@ -52,9 +57,25 @@ func (c *JobHistoryCommand) Synopsis() string {
 	return "Display all tracked versions of a job"
 }
 
+func (c *JobHistoryCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-p":            complete.PredictNothing,
+			"-diff-version": complete.PredictAnything,
+			"-full":         complete.PredictNothing,
+			"-json":         complete.PredictNothing,
+			"-version":      complete.PredictAnything,
+			"-t":            complete.PredictAnything,
+		})
+}
+
+func (c *JobHistoryCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
 func (c *JobHistoryCommand) Run(args []string) int {
 	var json, diff, full bool
-	var tmpl, versionStr string
+	var tmpl, versionStr, diffVersionStr string
 
 	flags := c.Meta.FlagSet("job history", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
@@ -62,6 +83,7 @@ func (c *JobHistoryCommand) Run(args []string) int {
 	flags.BoolVar(&full, "full", false, "")
 	flags.BoolVar(&json, "json", false, "")
 	flags.StringVar(&versionStr, "version", "", "")
+	flags.StringVar(&diffVersionStr, "diff-version", "", "")
 	flags.StringVar(&tmpl, "t", "", "")
 
 	if err := flags.Parse(args); err != nil {
@@ -75,8 +97,17 @@ func (c *JobHistoryCommand) Run(args []string) int {
 		return 1
 	}
 
+	diffVersion, diffVersionSet, err := parseVersion(diffVersionStr)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error parsing diff version value %q: %v", diffVersionStr, err))
+		return 1
+	}
+	if diffVersionSet {
+		diff = true
+	}
+
 	if (json || len(tmpl) != 0) && (diff || full) {
-		c.Ui.Error("-json and -t are exclusive with -p and -full")
+		c.Ui.Error("-json and -t are exclusive with -p, -diff-version and -full")
 		return 1
 	}
 
@@ -105,7 +136,13 @@ func (c *JobHistoryCommand) Run(args []string) int {
 	}
 
 	// Prefix lookup matched a single job
-	versions, diffs, _, err := client.Jobs().Versions(jobs[0].ID, diff, nil)
+	var versions []*api.Job
+	var diffs []*api.JobDiff
+	if diffVersionSet {
+		versions, diffs, _, err = client.Jobs().VersionsDiff(jobs[0].ID, diffVersion, nil)
+	} else {
+		versions, diffs, _, err = client.Jobs().Versions(jobs[0].ID, diff, nil)
+	}
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error retrieving job versions: %s", err))
 		return 1
@@ -134,12 +171,20 @@ func (c *JobHistoryCommand) Run(args []string) int {
 			}
 
 			job = v
-			if i+1 <= len(diffs) {
+			if diffVersionSet {
+				diff = diffs[i]
+				nextVersion = diffVersion
+			} else if i+1 <= len(diffs) {
 				diff = diffs[i]
 				nextVersion = *versions[i+1].Version
 			}
 		}
 
+		if job == nil {
+			c.Ui.Error(fmt.Sprintf("Job version %d not found", version))
+			return 1
+		}
+
 		if json || len(tmpl) > 0 {
 			out, err := Format(json, tmpl, job)
 			if err != nil {
@@ -168,7 +213,7 @@ func (c *JobHistoryCommand) Run(args []string) int {
 			return 0
 		}
 
-		if err := c.formatJobVersions(versions, diffs, full); err != nil {
+		if err := c.formatJobVersions(versions, diffs, diffVersionSet, diffVersion, full); err != nil {
 			c.Ui.Error(err.Error())
 			return 1
 		}
@@ -188,17 +233,25 @@ func parseVersion(input string) (uint64, bool, error) {
 	return u, true, err
 }
 
-func (c *JobHistoryCommand) formatJobVersions(versions []*api.Job, diffs []*api.JobDiff, full bool) error {
+// formatJobVersions displays the given job versions and their diffs. If
+// against is set, each diff is against the given diff version rather than the
+// next older version.
+func (c *JobHistoryCommand) formatJobVersions(versions []*api.Job, diffs []*api.JobDiff, against bool, diffVersion uint64, full bool) error {
 	vLen := len(versions)
 	dLen := len(diffs)
-	if dLen != 0 && vLen != dLen+1 {
+	if against && vLen != dLen {
+		return fmt.Errorf("Number of job versions %d doesn't match number of diffs %d", vLen, dLen)
+	} else if !against && dLen != 0 && vLen != dLen+1 {
 		return fmt.Errorf("Number of job versions %d doesn't match number of diffs %d", vLen, dLen)
 	}
 
 	for i, version := range versions {
 		var diff *api.JobDiff
 		var nextVersion uint64
-		if i+1 <= dLen {
+		if against {
+			diff = diffs[i]
+			nextVersion = diffVersion
+		} else if i+1 <= dLen {
 			diff = diffs[i]
 			nextVersion = *versions[i+1].Version
 		}
@@ -223,6 +276,19 @@ func (c *JobHistoryCommand) formatJobVersion(job *api.Job, diff *api.JobDiff, ne
 		fmt.Sprintf("Submit Date|%v", formatTime(time.Unix(0, *job.SubmitTime))),
 	}
 
+	if job.RevertVersion != nil {
+		basic = append(basic, fmt.Sprintf("Reverted To|%d", *job.RevertVersion))
+	}
+	if job.RevertReason != nil && *job.RevertReason != "" {
+		basic = append(basic, fmt.Sprintf("Revert Reason|%s", *job.RevertReason))
+	}
+	if job.PolicyOverrideJustification != nil && *job.PolicyOverrideJustification != "" {
+		basic = append(basic, fmt.Sprintf("Policy Override|%s", *job.PolicyOverrideJustification))
+	}
+	if job.NoPlacementChange != nil && *job.NoPlacementChange {
+		basic = append(basic, "Placement Change|false")
+	}
+
 	if diff != nil {
 		//diffStr := fmt.Sprintf("Difference between version %d and %d:", *job.Version, nextVersion)
 		basic = append(basic, fmt.Sprintf("Diff|\n%s", strings.TrimSpace(formatJobDiff(diff, false))))
@@ -33,4 +33,20 @@ func TestJobHistoryCommand_Fails(t *testing.T) {
 		t.Fatalf("expected failed query error, got: %s", out)
 	}
 	ui.ErrorWriter.Reset()
+
+	if code := cmd.Run([]string{"-diff-version=foo", "foo"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error parsing diff version") {
+		t.Fatalf("expected diff version error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	if code := cmd.Run([]string{"-json", "-diff-version=1", "foo"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "exclusive") {
+		t.Fatalf("expected exclusive flags error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
 }
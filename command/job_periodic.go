@@ -0,0 +1,19 @@
+package command
+
+import "github.com/mitchellh/cli"
+
+type JobPeriodicCommand struct {
+	Meta
+}
+
+func (f *JobPeriodicCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *JobPeriodicCommand) Synopsis() string {
+	return "Interact with periodic jobs"
+}
+
+func (f *JobPeriodicCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type JobPeriodicForceCommand struct {
+	Meta
+}
+
+func (c *JobPeriodicForceCommand) Help() string {
+	helpText := `
+Usage: nomad job periodic force [options] <job>
+
+Periodic force launches an instance of a periodic job immediately, outside of
+its schedule. The periodic configuration of the job is not changed.
+
+Upon successful launch, the ID of the launched child job will be printed and
+the triggered evaluation will be monitored. This can be disabled by supplying
+the detach flag.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Periodic Force Options:
+
+  -detach
+    Return immediately instead of entering monitor mode. After the force
+    launch, the evaluation ID will be printed to the screen, which can be used
+    to examine the evaluation using the eval-status command.
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *JobPeriodicForceCommand) Synopsis() string {
+	return "Force the launch of a periodic job"
+}
+
+func (c *JobPeriodicForceCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *JobPeriodicForceCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
+func (c *JobPeriodicForceCommand) Run(args []string) int {
+	var detach, verbose bool
+
+	flags := c.Meta.FlagSet("job periodic force", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&detach, "detach", false, "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Check that we got exactly one job
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	jobID := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Check if the job exists and is periodic
+	jobs, _, err := client.Jobs().PrefixList(jobID)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying job: %s", err))
+		return 1
+	}
+	if len(jobs) == 0 {
+		c.Ui.Error(fmt.Sprintf("No job(s) with prefix or id %q found", jobID))
+		return 1
+	}
+	if len(jobs) > 1 && strings.TrimSpace(jobID) != jobs[0].ID {
+		c.Ui.Error(fmt.Sprintf("Prefix matched multiple jobs\n\n%s", createStatusListOutput(jobs)))
+		return 1
+	}
+	job, _, err := client.Jobs().Info(jobs[0].ID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying job: %s", err))
+		return 1
+	}
+	if !job.IsPeriodic() {
+		c.Ui.Error(fmt.Sprintf("Job %q is not periodic", *job.ID))
+		return 1
+	}
+
+	// Force the launch
+	resp, _, err := client.Jobs().PeriodicForceLaunch(*job.ID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error forcing periodic job launch: %s", err))
+		return 1
+	}
+
+	basic := []string{
+		fmt.Sprintf("Launched Job ID|%s", resp.JobID),
+		fmt.Sprintf("Evaluation ID|%s", limit(resp.EvalID, length)),
+	}
+	c.Ui.Output(formatKV(basic))
+
+	if detach {
+		return 0
+	}
+
+	c.Ui.Output("")
+	mon := newMonitor(c.Ui, client, length)
+	return mon.monitor(resp.EvalID, false)
+}
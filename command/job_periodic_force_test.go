@@ -0,0 +1,80 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/mitchellh/cli"
+)
+
+func TestJobPeriodicForceCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &JobPeriodicForceCommand{}
+}
+
+func TestJobPeriodicForceCommand_Fails(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &JobPeriodicForceCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	if code := cmd.Run([]string{"-address=nope", "foo"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error querying job") {
+		t.Fatalf("expected failed query error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on a job that is not periodic
+	if _, _, err := client.Jobs().Register(testJob("job1"), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if code := cmd.Run([]string{"-address=" + url, "job1"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "is not periodic") {
+		t.Fatalf("expected not periodic error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+}
+
+func TestJobPeriodicForceCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &JobPeriodicForceCommand{Meta: Meta{Ui: ui}}
+
+	// Register a periodic job
+	job := testJob("job1_periodic")
+	job.Periodic = &api.PeriodicConfig{
+		SpecType: helper.StringToPtr(api.PeriodicSpecCron),
+		Spec:     helper.StringToPtr("0 0 1 1 *"),
+	}
+	if _, _, err := client.Jobs().Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if code := cmd.Run([]string{"-address=" + url, "-detach", "job1_periodic"}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d; %s", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "Launched Job ID") || !strings.Contains(out, "job1_periodic/periodic-") {
+		t.Fatalf("expected launched job, got: %s", out)
+	}
+}
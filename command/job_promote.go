@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/nomad/api"
 	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/posener/complete"
 )
 
 type JobPromoteCommand struct {
@@ -50,6 +51,19 @@ func (c *JobPromoteCommand) Synopsis() string {
 	return "Promote a job's canaries"
 }
 
+func (c *JobPromoteCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+			"-group":   complete.PredictAnything,
+		})
+}
+
+func (c *JobPromoteCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
 func (c *JobPromoteCommand) Run(args []string) int {
 	var detach, verbose bool
 	var groups []string
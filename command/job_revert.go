@@ -3,6 +3,9 @@ package command
 import (
 	"fmt"
 	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
 )
 
 type JobRevertCommand struct {
@@ -14,7 +17,9 @@ func (c *JobRevertCommand) Help() string {
 Usage: nomad job revert [options] <job> <version>
 
 Revert is used to revert a job to a prior version of the job. The available
-versions to revert to can be found using "nomad job history" command.
+versions to revert to can be found using "nomad job history" command. The
+revert is recorded as a new version of the job that references the version it
+was reverted to.
 
 General Options:
 
@@ -27,6 +32,9 @@ Revert Options:
     the evaluation ID will be printed to the screen, which can be used to
     examine the evaluation using the eval-status command.
 
+  -require-stable
+    Only revert if the version being reverted to is marked as stable.
+
   -verbose
     Display full information.
 `
@@ -37,13 +45,27 @@ func (c *JobRevertCommand) Synopsis() string {
 	return "Revert to a prior version of the job"
 }
 
+func (c *JobRevertCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":         complete.PredictNothing,
+			"-verbose":        complete.PredictNothing,
+			"-require-stable": complete.PredictNothing,
+		})
+}
+
+func (c *JobRevertCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
 func (c *JobRevertCommand) Run(args []string) int {
-	var detach, verbose bool
+	var detach, verbose, requireStable bool
 
 	flags := c.Meta.FlagSet("job revert", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&detach, "detach", false, "")
 	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.BoolVar(&requireStable, "require-stable", false, "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -96,7 +118,12 @@ func (c *JobRevertCommand) Run(args []string) int {
 	}
 
 	// Prefix lookup matched a single job
-	resp, _, err := client.Jobs().Revert(jobs[0].ID, revertVersion, nil, nil)
+	req := &api.JobRevertRequest{
+		JobID:         jobs[0].ID,
+		JobVersion:    revertVersion,
+		RequireStable: requireStable,
+	}
+	resp, _, err := client.Jobs().RevertOpts(req, nil)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error retrieving job versions: %s", err))
 		return 1
@@ -0,0 +1,125 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type JobScaleCommand struct {
+	Meta
+}
+
+func (c *JobScaleCommand) Help() string {
+	helpText := `
+Usage: nomad job scale [options] <job> <group> <count>
+
+Scale is used to change the count of a task group of a job. The change is
+recorded as a new version of the job. Scaling needs the scale-job capability
+on the namespace of the job, which doesn't allow changing anything else.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Scale Options:
+
+  -detach
+    Return immediately instead of entering monitor mode. After job scale,
+    the evaluation ID will be printed to the screen, which can be used to
+    examine the evaluation using the eval-status command.
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *JobScaleCommand) Synopsis() string {
+	return "Change the count of a task group of a job"
+}
+
+func (c *JobScaleCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *JobScaleCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
+func (c *JobScaleCommand) Run(args []string) int {
+	var detach, verbose bool
+
+	flags := c.Meta.FlagSet("job scale", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&detach, "detach", false, "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Check that we got three args
+	args = flags.Args()
+	if l := len(args); l != 3 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	jobID, group := args[0], args[1]
+	count, err := strconv.Atoi(args[2])
+	if err != nil || count < 0 {
+		c.Ui.Error(fmt.Sprintf("Invalid count %q: must be a non-negative integer", args[2]))
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Check if the job exists
+	jobs, _, err := client.Jobs().PrefixList(jobID)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error listing jobs: %s", err))
+		return 1
+	}
+	if len(jobs) == 0 {
+		c.Ui.Error(fmt.Sprintf("No job(s) with prefix or id %q found", jobID))
+		return 1
+	}
+	if len(jobs) > 1 && strings.TrimSpace(jobID) != jobs[0].ID {
+		c.Ui.Error(fmt.Sprintf("Prefix matched multiple jobs\n\n%s", createStatusListOutput(jobs)))
+		return 1
+	}
+
+	// Prefix lookup matched a single job
+	resp, _, err := client.Jobs().Scale(jobs[0].ID, group, count, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error scaling job: %s", err))
+		return 1
+	}
+
+	// Nothing to do
+	evalCreated := resp.EvalID != ""
+	if detach || !evalCreated {
+		return 0
+	}
+
+	mon := newMonitor(c.Ui, client, length)
+	return mon.monitor(resp.EvalID, false)
+}
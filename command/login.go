@@ -0,0 +1,254 @@
+package command
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+const (
+	// defaultOIDCCallbackAddr is the address the OIDC provider redirects the
+	// browser to once the user logged in
+	defaultOIDCCallbackAddr = "localhost:4649"
+
+	// oidcCallbackPath is the path the OIDC provider redirects the browser to
+	oidcCallbackPath = "/oidc/callback"
+)
+
+// openURL opens the URL in the browser of the user. It is replaced by tests.
+var openURL = func(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	return cmd.Start()
+}
+
+type LoginCommand struct {
+	Meta
+}
+
+func (c *LoginCommand) Help() string {
+	helpText := `
+Usage: nomad login [options]
+
+Login is used to log in with an ACL auth method, such as an OIDC provider, and
+be granted an ACL token. The token is granted the roles and policies of the
+binding rules of the auth method matching the identity of the user.
+
+For OIDC auth methods, the login page of the provider is opened in the
+browser, which is redirected back to a local callback server once the user
+logged in. The redirect URI of the callback server must be allowed by the auth
+method.
+
+For JWT auth methods, the JWT given with -login-token is exchanged for the ACL
+token. This lets machines such as CI pipelines log in with the identity their
+platform issues them instead of a static token.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Login Options:
+
+  -method
+    The name of the auth method to log in with. The default auth method is used
+    if omitted.
+
+  -login-token
+    The JWT to log in with a JWT auth method. If "-", the JWT is read from
+    stdin. The OIDC login flow is used if omitted.
+
+  -oidc-callback-addr
+    The address the local callback server listens on. Defaults to
+    "localhost:4649", whose redirect URI is
+    "http://localhost:4649/oidc/callback".
+
+  -json
+    Output the ACL token in a JSON format.
+
+  -t
+    Format and display the ACL token using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *LoginCommand) Synopsis() string {
+	return "Log in with an ACL auth method"
+}
+
+func (c *LoginCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-method":             c.PredictACLAuthMethods(),
+			"-login-token":        complete.PredictAnything,
+			"-oidc-callback-addr": complete.PredictAnything,
+			"-json":               complete.PredictNothing,
+			"-t":                  complete.PredictAnything,
+		})
+}
+
+func (c *LoginCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *LoginCommand) Run(args []string) int {
+	var method, loginToken, callbackAddr, tmpl string
+	var json bool
+
+	flags := c.Meta.FlagSet("login", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&method, "method", "", "")
+	flags.StringVar(&loginToken, "login-token", "", "")
+	flags.StringVar(&callbackAddr, "oidc-callback-addr", defaultOIDCCallbackAddr, "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if loginToken == "-" {
+		raw, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading login token from stdin: %s", err))
+			return 1
+		}
+		loginToken = strings.TrimSpace(string(raw))
+	}
+
+	var token *api.ACLToken
+	if loginToken != "" {
+		token, _, err = client.ACLAuthMethods().Login(&api.ACLLoginRequest{
+			AuthMethodName: method,
+			LoginToken:     loginToken,
+		}, nil)
+	} else {
+		token, err = c.oidcLogin(client, method, callbackAddr)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error logging in: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, token)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output("Successfully logged in!\n")
+	c.Ui.Output(formatACLToken(token))
+	return 0
+}
+
+// oidcCallback is the authorization code the OIDC provider redirected the
+// browser to the callback server with
+type oidcCallback struct {
+	state string
+	code  string
+	err   error
+}
+
+// oidcLogin logs in with the OIDC auth method. It opens the login page of
+// the provider and waits for the browser to be redirected back to the
+// callback server, then exchanges the authorization code for an ACL token.
+func (c *LoginCommand) oidcLogin(client *api.Client, method, callbackAddr string) (*api.ACLToken, error) {
+	ln, err := net.Listen("tcp", callbackAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the callback server: %v", err)
+	}
+	defer ln.Close()
+	redirectURI := "http://" + callbackAddr + oidcCallbackPath
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	clientNonce := base64.RawURLEncoding.EncodeToString(buf)
+
+	// Serve the redirect of the browser
+	callbacks := make(chan *oidcCallback, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidcCallbackPath, func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		cb := &oidcCallback{state: q.Get("state"), code: q.Get("code")}
+		if e := q.Get("error"); e != "" {
+			cb.err = fmt.Errorf("OIDC provider returned an error: %s %s", e, q.Get("error_description"))
+		} else if cb.state == "" || cb.code == "" {
+			cb.err = fmt.Errorf("OIDC provider redirected without a state or code")
+		}
+		if cb.err != nil {
+			fmt.Fprintf(w, "Login failed: %v. You can close this window.", cb.err)
+		} else {
+			fmt.Fprint(w, "Login successful. You can close this window and return to the CLI.")
+		}
+		select {
+		case callbacks <- cb:
+		default:
+		}
+	})
+	go http.Serve(ln, mux)
+
+	authURL, _, err := client.ACLOIDC().GetAuthURL(&api.ACLOIDCAuthURLRequest{
+		AuthMethodName: method,
+		RedirectURI:    redirectURI,
+		ClientNonce:    clientNonce,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Ui.Output(fmt.Sprintf("Complete the login via your OIDC provider at:\n\n    %s\n", authURL.AuthURL))
+	if err := openURL(authURL.AuthURL); err != nil {
+		c.Ui.Output("Failed to open the browser, please open the URL manually.")
+	}
+	c.Ui.Output("Waiting for the OIDC provider to redirect back...\n")
+
+	cb := <-callbacks
+	if cb.err != nil {
+		return nil, cb.err
+	}
+
+	token, _, err := client.ACLOIDC().CompleteAuth(&api.ACLOIDCCompleteAuthRequest{
+		AuthMethodName: method,
+		ClientNonce:    clientNonce,
+		State:          cb.state,
+		Code:           cb.code,
+		RedirectURI:    redirectURI,
+	}, nil)
+	return token, err
+}
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/mitchellh/cli"
+)
+
+func TestLoginCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &LoginCommand{}
+}
+
+func TestLoginCommand_Run(t *testing.T) {
+	// Not parallel as the browser is replaced by following the auth URL
+	srv, client, url, _ := testACLServer(t)
+	defer srv.Shutdown()
+
+	provider := testutil.NewTestOIDCProvider(t)
+	defer provider.Stop()
+	provider.SetClaims(map[string]interface{}{"groups": []string{"engineering"}})
+
+	method := testACLAuthMethod(t, client, "okta", provider.URL())
+	method.Config.OIDCClientID = provider.ClientID
+	method.Config.OIDCClientSecret = provider.ClientSecret
+	method.Default = true
+	if _, err := client.ACLAuthMethods().Upsert(method, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testACLBindingRule(t, client, "okta", "engineering")
+
+	defer func(f func(string) error) { openURL = f }(openURL)
+	openURL = func(u string) error {
+		go func() {
+			if resp, err := http.Get(u); err == nil {
+				resp.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &LoginCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=", "-json"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	i := strings.Index(out, "{")
+	if i < 0 {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// The token is granted the policy of the binding rule
+	var token api.ACLToken
+	if err := json.Unmarshal([]byte(out[i:]), &token); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(token.Policies) != 1 || token.Policies[0] != "engineering" || token.ExpirationTime == nil {
+		t.Fatalf("bad: %#v", token)
+	}
+
+	client.SetSecretID(token.SecretID)
+	self, _, err := client.ACLTokens().Self(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if self.AccessorID != token.AccessorID {
+		t.Fatalf("bad: %#v", self)
+	}
+}
+
+func TestLoginCommand_Run_JWT(t *testing.T) {
+	t.Parallel()
+	srv, client, url, _ := testACLServer(t)
+	defer srv.Shutdown()
+
+	provider := testutil.NewTestOIDCProvider(t)
+	defer provider.Stop()
+
+	method := &api.ACLAuthMethod{
+		Name:          "ci",
+		Type:          "JWT",
+		TokenLocality: "local",
+		MaxTokenTTL:   time.Hour,
+		Config: &api.ACLAuthMethodConfig{
+			JWTValidationPubKeys: []string{provider.PublicKeyPEM()},
+			ListClaimMappings:    map[string]string{"groups": "groups"},
+		},
+	}
+	if _, err := client.ACLAuthMethods().Upsert(method, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	testACLBindingRule(t, client, "ci", "engineering")
+
+	jwt, err := provider.SignJWT(map[string]interface{}{
+		"sub":    "pipeline",
+		"groups": []string{"engineering"},
+		"exp":    time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &LoginCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "-token=", "-method=ci", "-login-token=" + jwt}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "Successfully logged in") || !strings.Contains(out, "engineering") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// JWTs signed by other keys are denied
+	ui = new(cli.MockUi)
+	cmd = &LoginCommand{Meta: Meta{Ui: ui}}
+	other := testutil.NewTestOIDCProvider(t)
+	defer other.Stop()
+	jwt, err = other.SignJWT(map[string]interface{}{"exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if code := cmd.Run([]string{"-address=" + url, "-token=", "-method=ci", "-login-token=" + jwt}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Permission denied") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
 )
 
 type LogsCommand struct {
@@ -59,6 +60,23 @@ func (l *LogsCommand) Synopsis() string {
 	return "Streams the logs of a task."
 }
 
+func (l *LogsCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(l.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-verbose": complete.PredictNothing,
+			"-job":     complete.PredictNothing,
+			"-tail":    complete.PredictNothing,
+			"-f":       complete.PredictNothing,
+			"-stderr":  complete.PredictNothing,
+			"-n":       complete.PredictAnything,
+			"-c":       complete.PredictAnything,
+		})
+}
+
+func (l *LogsCommand) AutocompleteArgs() complete.Predictor {
+	return l.PredictAllocations()
+}
+
 func (l *LogsCommand) Run(args []string) int {
 	var verbose, job, tail, stderr, follow bool
 	var numLines, numBytes int64
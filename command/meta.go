@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/nomad/api"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/colorstring"
+	"github.com/posener/complete"
 )
 
 const (
@@ -17,6 +18,7 @@ const (
 	// config options to the Nomad CLI.
 	EnvNomadAddress = "NOMAD_ADDR"
 	EnvNomadRegion  = "NOMAD_REGION"
+	EnvNomadToken   = "NOMAD_TOKEN"
 
 	// Constants for CLI identifier length
 	shortId = 8
@@ -47,6 +49,9 @@ type Meta struct {
 	// The region to send API requests
 	region string
 
+	// token is the secret ID of the ACL token of the API requests
+	token string
+
 	caCert     string
 	caPath     string
 	clientCert string
@@ -66,6 +71,7 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 	if fs&FlagSetClient != 0 {
 		f.StringVar(&m.flagAddress, "address", "", "")
 		f.StringVar(&m.region, "region", "", "")
+		f.StringVar(&m.token, "token", "", "")
 		f.BoolVar(&m.noColor, "no-color", false, "")
 		f.StringVar(&m.caCert, "ca-cert", "", "")
 		f.StringVar(&m.caPath, "ca-path", "", "")
@@ -92,9 +98,36 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 	return f
 }
 
+// AutocompleteFlags returns the autocomplete predictors for the flags
+// returned by FlagSet.
+func (m *Meta) AutocompleteFlags(fs FlagSetFlags) complete.Flags {
+	if fs&FlagSetClient == 0 {
+		return nil
+	}
+
+	return complete.Flags{
+		"-address":         complete.PredictAnything,
+		"-region":          complete.PredictAnything,
+		"-token":           complete.PredictAnything,
+		"-no-color":        complete.PredictNothing,
+		"-ca-cert":         complete.PredictFiles("*"),
+		"-ca-path":         complete.PredictDirs("*"),
+		"-client-cert":     complete.PredictFiles("*"),
+		"-client-key":      complete.PredictFiles("*"),
+		"-insecure":        complete.PredictNothing,
+		"-tls-skip-verify": complete.PredictNothing,
+	}
+}
+
 // Client is used to initialize and return a new API client using
 // the default command line arguments and env vars.
 func (m *Meta) Client() (*api.Client, error) {
+	return api.NewClient(m.clientConfig())
+}
+
+// clientConfig returns the API client configuration built from the default
+// command line arguments and env vars.
+func (m *Meta) clientConfig() *api.Config {
 	config := api.DefaultConfig()
 	if v := os.Getenv(EnvNomadAddress); v != "" {
 		config.Address = v
@@ -108,6 +141,12 @@ func (m *Meta) Client() (*api.Client, error) {
 	if m.region != "" {
 		config.Region = m.region
 	}
+	if v := os.Getenv(EnvNomadToken); v != "" {
+		config.SecretID = v
+	}
+	if m.token != "" {
+		config.SecretID = m.token
+	}
 	// If we need custom TLS configuration, then set it
 	if m.caCert != "" || m.caPath != "" || m.clientCert != "" || m.clientKey != "" || m.insecure {
 		t := &api.TLSConfig{
@@ -120,7 +159,7 @@ func (m *Meta) Client() (*api.Client, error) {
 		config.TLSConfig = t
 	}
 
-	return api.NewClient(config)
+	return config
 }
 
 func (m *Meta) Colorize() *colorstring.Colorize {
@@ -143,6 +182,10 @@ func generalOptionsUsage() string {
     The region of the Nomad servers to forward commands to.
     Overrides the NOMAD_REGION environment variable if set.
     Defaults to the Agent's local region.
+
+  -token=<secret-id>
+    The secret ID of the ACL token to make the requests with.
+    Overrides the NOMAD_TOKEN environment variable if set.
   
   -no-color
     Disables colored command output.
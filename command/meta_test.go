@@ -23,6 +23,7 @@ func TestMeta_FlagSet(t *testing.T) {
 				"address",
 				"no-color",
 				"region",
+				"token",
 				"ca-cert",
 				"ca-path",
 				"client-cert",
@@ -50,3 +51,25 @@ func TestMeta_FlagSet(t *testing.T) {
 		}
 	}
 }
+
+func TestMeta_AutocompleteFlags(t *testing.T) {
+	t.Parallel()
+	for _, fs := range []FlagSetFlags{FlagSetNone, FlagSetClient} {
+		var m Meta
+		expected := make([]string, 0, 0)
+		m.FlagSet("foo", fs).VisitAll(func(f *flag.Flag) {
+			expected = append(expected, "-"+f.Name)
+		})
+
+		actual := make([]string, 0, 0)
+		for name := range m.AutocompleteFlags(fs) {
+			actual = append(actual, name)
+		}
+		sort.Strings(actual)
+		sort.Strings(expected)
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("flags: %#v\n\nExpected: %#v\nGot: %#v", fs, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package command
+
+import (
+	"fmt"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/mapstructure"
+)
+
+type NamespaceCommand struct {
+	Meta
+}
+
+func (f *NamespaceCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *NamespaceCommand) Synopsis() string {
+	return "Interact with namespaces"
+}
+
+func (f *NamespaceCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// parseNamespaceSpec parses a namespace specification written in HCL or JSON.
+func parseNamespaceSpec(input []byte) (*api.Namespace, error) {
+	root, err := hcl.ParseBytes(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Top-level item should be a list
+	list, ok := root.Node.(*ast.ObjectList)
+	if !ok {
+		return nil, fmt.Errorf("root should be an object")
+	}
+
+	valid := []string{
+		"name",
+		"description",
+		"quota",
+		"capabilities",
+		"gc",
+		"meta",
+	}
+	if err := checkHCLKeys(list, valid); err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, list); err != nil {
+		return nil, err
+	}
+	delete(m, "capabilities")
+	delete(m, "gc")
+	delete(m, "meta")
+
+	var ns api.Namespace
+	if err := mapstructure.WeakDecode(m, &ns); err != nil {
+		return nil, err
+	}
+
+	// Parse the capabilities
+	if o := list.Filter("capabilities"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return nil, fmt.Errorf("only one 'capabilities' block allowed")
+		}
+
+		valid := []string{
+			"enabled_task_drivers",
+			"disabled_task_drivers",
+		}
+		if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
+			return nil, fmt.Errorf("capabilities -> %v", err)
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
+			return nil, err
+		}
+
+		var capabilities api.NamespaceCapabilities
+		if err := mapstructure.WeakDecode(m, &capabilities); err != nil {
+			return nil, err
+		}
+		ns.Capabilities = &capabilities
+	}
+
+	// Parse the garbage collection overrides
+	if o := list.Filter("gc"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return nil, fmt.Errorf("only one 'gc' block allowed")
+		}
+
+		valid := []string{
+			"alloc_threshold",
+			"eval_threshold",
+			"deployment_threshold",
+		}
+		if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
+			return nil, fmt.Errorf("gc -> %v", err)
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
+			return nil, err
+		}
+
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           &ns.GC,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := dec.Decode(m); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse the meta
+	if o := list.Filter("meta"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return nil, fmt.Errorf("only one 'meta' block allowed")
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
+			return nil, err
+		}
+		if err := mapstructure.WeakDecode(m, &ns.Meta); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ns, nil
+}
+
+// checkHCLKeys returns an error listing the keys of the node that aren't in
+// the set of valid keys.
+func checkHCLKeys(node ast.Node, valid []string) error {
+	var list *ast.ObjectList
+	switch n := node.(type) {
+	case *ast.ObjectList:
+		list = n
+	case *ast.ObjectType:
+		list = n.List
+	default:
+		return fmt.Errorf("cannot check HCL keys of type %T", n)
+	}
+
+	validMap := make(map[string]struct{}, len(valid))
+	for _, v := range valid {
+		validMap[v] = struct{}{}
+	}
+
+	var result error
+	for _, item := range list.Items {
+		key := item.Keys[0].Token.Value().(string)
+		if _, ok := validMap[key]; !ok {
+			result = multierror.Append(result, fmt.Errorf(
+				"invalid key: %s", key))
+		}
+	}
+
+	return result
+}
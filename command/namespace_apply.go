@@ -0,0 +1,165 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/posener/complete"
+)
+
+type NamespaceApplyCommand struct {
+	Meta
+}
+
+func (c *NamespaceApplyCommand) Help() string {
+	helpText := `
+Usage: nomad namespace apply [options] <namespace>
+       nomad namespace apply [options] -file <path>
+
+Apply is used to create or update a namespace. The namespace is either given
+by name, in which case only the fields set with the options below are
+updated, or read from a specification file, in which case the namespace is
+replaced by the file's definition. Specification files can be kept in version
+control and applied again whenever they change.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Apply Options:
+
+  -file
+    Path to a namespace specification written in HCL or JSON, for example:
+
+      name        = "web"
+      description = "Namespace of the web team"
+      quota       = "web-quota"
+
+      capabilities {
+        enabled_task_drivers  = ["docker"]
+        disabled_task_drivers = ["raw_exec"]
+      }
+
+      gc {
+        eval_threshold = "24h"
+      }
+
+      meta {
+        owner = "web-team"
+      }
+
+  -description
+    An optional human readable description for the namespace.
+
+  -quota
+    The name of the quota to attach to the namespace.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *NamespaceApplyCommand) Synopsis() string {
+	return "Create or update a namespace"
+}
+
+func (c *NamespaceApplyCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-file": complete.PredictOr(
+				complete.PredictFiles("*.json"),
+				complete.PredictFiles("*.hcl")),
+			"-description": complete.PredictAnything,
+			"-quota":       complete.PredictAnything,
+		})
+}
+
+func (c *NamespaceApplyCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictNamespaces()
+}
+
+func (c *NamespaceApplyCommand) Run(args []string) int {
+	var file string
+	var description, quota *string
+
+	flags := c.Meta.FlagSet("namespace apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&file, "file", "", "")
+	flags.Var((flaghelper.FuncVar)(func(s string) error {
+		description = &s
+		return nil
+	}), "description", "")
+	flags.Var((flaghelper.FuncVar)(func(s string) error {
+		quota = &s
+		return nil
+	}), "quota", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we were given either a namespace name or a file
+	args = flags.Args()
+	if (file == "") == (len(args) == 0) || len(args) > 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	if file != "" && (description != nil || quota != nil) {
+		c.Ui.Error("-description and -quota can't be used with -file")
+		return 1
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	var ns *api.Namespace
+	if file != "" {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading %q: %s", file, err))
+			return 1
+		}
+
+		ns, err = parseNamespaceSpec(contents)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error parsing %q: %s", file, err))
+			return 1
+		}
+		if ns.Name == "" {
+			c.Ui.Error(fmt.Sprintf("Namespace specification %q is missing a name", file))
+			return 1
+		}
+	} else {
+		// Update the existing namespace if there is one
+		name := args[0]
+		ns, _, err = client.Namespaces().Info(name, nil)
+		if err != nil {
+			if !api.IsNotFound(err) {
+				c.Ui.Error(fmt.Sprintf("Error looking up namespace: %s", err))
+				return 1
+			}
+			ns = &api.Namespace{Name: name}
+		}
+
+		if description != nil {
+			ns.Description = *description
+		}
+		if quota != nil {
+			ns.Quota = *quota
+		}
+	}
+
+	if _, err := client.Namespaces().Register(ns, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error applying namespace: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully applied namespace %q!", ns.Name))
+	return 0
+}
@@ -0,0 +1,140 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestNamespaceApplyCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &NamespaceApplyCommand{}
+}
+
+func TestNamespaceApplyCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &NamespaceApplyCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run(nil); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails when given both a name and a file
+	if code := cmd.Run([]string{"-file=web.hcl", "web"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails when mixing a file with the field options
+	if code := cmd.Run([]string{"-file=web.hcl", "-description=web"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "can't be used with -file") {
+		t.Fatalf("expected exclusive options error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on a missing file
+	if code := cmd.Run([]string{"-file=/unicorns/leprechauns"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error reading") {
+		t.Fatalf("expected read error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "web"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error looking up namespace") {
+		t.Fatalf("expected failed lookup error, got: %s", out)
+	}
+}
+
+func TestNamespaceApplyCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &NamespaceApplyCommand{Meta: Meta{Ui: ui}}
+
+	// Create a namespace by name
+	if code := cmd.Run([]string{"-address=" + url, "-description=web team", "web"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `Successfully applied namespace "web"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Updating the quota keeps the description
+	if code := cmd.Run([]string{"-address=" + url, "-quota=small", "web"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	ns, _, err := client.Namespaces().Info("web", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ns.Description != "web team" || ns.Quota != "small" {
+		t.Fatalf("bad: %#v", ns)
+	}
+}
+
+func TestNamespaceApplyCommand_File(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	fh, err := ioutil.TempFile("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(fh.Name())
+	spec := `
+name        = "batch"
+description = "Batch jobs"
+
+capabilities {
+  disabled_task_drivers = ["raw_exec"]
+}
+
+meta {
+  owner = "data"
+}
+`
+	if _, err := fh.WriteString(spec); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	fh.Close()
+
+	ui := new(cli.MockUi)
+	cmd := &NamespaceApplyCommand{Meta: Meta{Ui: ui}}
+
+	if code := cmd.Run([]string{"-address=" + url, "-file=" + fh.Name()}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+
+	ns, _, err := client.Namespaces().Info("batch", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ns.Description != "Batch jobs" || ns.Meta["owner"] != "data" {
+		t.Fatalf("bad: %#v", ns)
+	}
+	if ns.Capabilities == nil || len(ns.Capabilities.DisabledTaskDrivers) != 1 {
+		t.Fatalf("bad capabilities: %#v", ns.Capabilities)
+	}
+}
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestNamespaceDeleteCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &NamespaceDeleteCommand{}
+}
+
+func TestNamespaceDeleteCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &NamespaceDeleteCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run(nil); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "web"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error deleting namespace") {
+		t.Fatalf("expected failed delete error, got: %s", out)
+	}
+}
+
+func TestNamespaceDeleteCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	// Register a namespace
+	ns := &api.Namespace{Name: "web"}
+	if _, err := client.Namespaces().Register(ns, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &NamespaceDeleteCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-address=" + url, "web"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `Successfully deleted namespace "web"`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	namespaces, _, err := client.Namespaces().List(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(namespaces) != 0 {
+		t.Fatalf("bad: %#v", namespaces)
+	}
+}
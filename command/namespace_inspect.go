@@ -0,0 +1,99 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type NamespaceInspectCommand struct {
+	Meta
+}
+
+func (c *NamespaceInspectCommand) Help() string {
+	helpText := `
+Usage: nomad namespace inspect [options] <namespace>
+
+Inspect is used to view raw information about a particular namespace.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Inspect Options:
+
+  -json
+    Output the namespace in a JSON format, the default.
+
+  -t
+    Format and display the namespace using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *NamespaceInspectCommand) Synopsis() string {
+	return "Inspect a namespace"
+}
+
+func (c *NamespaceInspectCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *NamespaceInspectCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictNamespaces()
+}
+
+func (c *NamespaceInspectCommand) Run(args []string) int {
+	var json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("namespace inspect", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one namespace
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	name := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	ns, _, err := client.Namespaces().Info(name, nil)
+	if err != nil {
+		if api.IsNotFound(err) {
+			c.Ui.Error(fmt.Sprintf("Namespace %q not found", name))
+			return 1
+		}
+		c.Ui.Error(fmt.Sprintf("Error retrieving namespace: %s", err))
+		return 1
+	}
+
+	// The namespace is output in a JSON format unless a template is given
+	out, err := Format(json || len(tmpl) == 0, tmpl, ns)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	c.Ui.Output(out)
+	return 0
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestNamespaceInspectCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &NamespaceInspectCommand{}
+}
+
+func TestNamespaceInspectCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &NamespaceInspectCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "web"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error retrieving namespace") {
+		t.Fatalf("expected failed query error, got: %s", out)
+	}
+}
+
+func TestNamespaceInspectCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &NamespaceInspectCommand{Meta: Meta{Ui: ui}}
+
+	// Unknown namespace
+	if code := cmd.Run([]string{"-address=" + url, "web"}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, `Namespace "web" not found`) {
+		t.Fatalf("bad: %s", out)
+	}
+
+	// Register a namespace
+	ns := &api.Namespace{Name: "web", Description: "web team"}
+	if _, err := client.Namespaces().Register(ns, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if code := cmd.Run([]string{"-address=" + url, "web"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"Description": "web team"`) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Output the namespace in a JSON format
+	if code := cmd.Run([]string{"-address=" + url, "-json", "web"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"Description": "web team"`) {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format with a template
+	if code := cmd.Run([]string{"-address=" + url, "-t", "{{.Description}}", "web"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "web team" {
+		t.Fatalf("bad: %q", out)
+	}
+}
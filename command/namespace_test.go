@@ -0,0 +1,120 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestParseNamespaceSpec(t *testing.T) {
+	t.Parallel()
+	expected := &api.Namespace{
+		Name:        "web",
+		Description: "Namespace of the web team",
+		Quota:       "web-quota",
+		Capabilities: &api.NamespaceCapabilities{
+			EnabledTaskDrivers:  []string{"docker", "exec"},
+			DisabledTaskDrivers: []string{"raw_exec"},
+		},
+		GC: &api.JobGCConfig{
+			EvalThreshold:       helper.TimeToPtr(24 * time.Hour),
+			DeploymentThreshold: helper.TimeToPtr(time.Hour),
+		},
+		Meta: map[string]string{
+			"owner": "web-team",
+			"tier":  "1",
+		},
+	}
+
+	hclSpec := `
+name        = "web"
+description = "Namespace of the web team"
+quota       = "web-quota"
+
+capabilities {
+  enabled_task_drivers  = ["docker", "exec"]
+  disabled_task_drivers = ["raw_exec"]
+}
+
+gc {
+  eval_threshold       = "24h"
+  deployment_threshold = "1h"
+}
+
+meta {
+  owner = "web-team"
+  tier  = 1
+}
+`
+	jsonSpec := `
+{
+  "name": "web",
+  "description": "Namespace of the web team",
+  "quota": "web-quota",
+  "capabilities": {
+    "enabled_task_drivers": ["docker", "exec"],
+    "disabled_task_drivers": ["raw_exec"]
+  },
+  "gc": {
+    "eval_threshold": "24h",
+    "deployment_threshold": "1h"
+  },
+  "meta": {
+    "owner": "web-team",
+    "tier": "1"
+  }
+}
+`
+	for _, spec := range []string{hclSpec, jsonSpec} {
+		ns, err := parseNamespaceSpec([]byte(spec))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !reflect.DeepEqual(ns, expected) {
+			t.Fatalf("bad: %#v", ns)
+		}
+	}
+}
+
+func TestParseNamespaceSpec_Invalid(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		spec string
+		err  string
+	}{
+		{
+			spec: `name = "web"` + "\n" + `owner = "ops"`,
+			err:  "invalid key: owner",
+		},
+		{
+			spec: `capabilities { drivers = ["docker"] }`,
+			err:  "capabilities -> 1 error(s) occurred:\n\n* invalid key: drivers",
+		},
+		{
+			spec: "capabilities {}\ncapabilities {}",
+			err:  "only one 'capabilities' block allowed",
+		},
+		{
+			spec: `gc { job_threshold = "1h" }`,
+			err:  "gc -> 1 error(s) occurred:\n\n* invalid key: job_threshold",
+		},
+		{
+			spec: `capabilities {`,
+			err:  "",
+		},
+	}
+
+	for _, c := range cases {
+		_, err := parseNamespaceSpec([]byte(c.spec))
+		if err == nil {
+			t.Fatalf("expected error parsing %q", c.spec)
+		}
+		if !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("expected error %q parsing %q, got: %v", c.err, c.spec, err)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package command
 import (
 	"fmt"
 	"strings"
+
+	"github.com/posener/complete"
 )
 
 type NodeDrainCommand struct {
@@ -42,6 +44,20 @@ func (c *NodeDrainCommand) Synopsis() string {
 	return "Toggle drain mode on a given node"
 }
 
+func (c *NodeDrainCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-enable":  complete.PredictNothing,
+			"-disable": complete.PredictNothing,
+			"-self":    complete.PredictNothing,
+			"-yes":     complete.PredictNothing,
+		})
+}
+
+func (c *NodeDrainCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictNodes()
+}
+
 func (c *NodeDrainCommand) Run(args []string) int {
 	var enable, disable, self, autoYes bool
 
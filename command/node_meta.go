@@ -0,0 +1,63 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+type NodeMetaCommand struct {
+	Meta
+}
+
+func (f *NodeMetaCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *NodeMetaCommand) Synopsis() string {
+	return "Interact with node metadata"
+}
+
+func (f *NodeMetaCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// nodeMetaClient returns a client for the agent of the node matching the ID
+// prefix, or for the agent the command is configured with if it is empty.
+func (m *Meta) nodeMetaClient(nodeID string) (*api.Client, error) {
+	client, err := m.Client()
+	if err != nil {
+		return nil, fmt.Errorf("Error initializing client: %s", err)
+	}
+	if nodeID == "" {
+		return client, nil
+	}
+	return nodeClient(client, nodeID)
+}
+
+// formatNodeMeta formats the node meta along with whether each key is set in
+// the agent's configuration or was applied at runtime.
+func formatNodeMeta(resp *api.NodeMetaResponse) string {
+	if len(resp.Meta) == 0 {
+		return "No node meta"
+	}
+
+	keys := make([]string, 0, len(resp.Meta))
+	for k := range resp.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]string, 0, len(keys)+1)
+	out = append(out, "Key|Value|Source")
+	for _, k := range keys {
+		source := "static"
+		if _, ok := resp.Dynamic[k]; ok {
+			source = "dynamic"
+		}
+		out = append(out, fmt.Sprintf("%s|%s|%s", k, resp.Meta[k], source))
+	}
+	return formatList(out)
+}
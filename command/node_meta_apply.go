@@ -0,0 +1,153 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/posener/complete"
+)
+
+type NodeMetaApplyCommand struct {
+	Meta
+}
+
+func (c *NodeMetaApplyCommand) Help() string {
+	helpText := `
+Usage: nomad node meta apply [options] [<key>=<value>...]
+
+Apply sets keys of the dynamic metadata of a client node. Dynamic metadata
+overrides the static meta set in the client configuration, is persisted by the
+client and is registered with the servers shortly after being applied. By
+default the node of the agent the command is run against is updated.
+
+The keys to set can be given as arguments, read from a file, or both, in
+which case the arguments take precedence.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Meta Apply Options:
+
+  -node-id
+    Apply the metadata to the node matching the ID prefix.
+
+  -file
+    Path to a JSON or HCL file mapping the keys to set to their value, for
+    example:
+
+      rack = "r1"
+      zone = "us-east-1a"
+
+  -json
+    Output the resulting metadata in a JSON format.
+
+  -t
+    Format and display the resulting metadata using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *NodeMetaApplyCommand) Synopsis() string {
+	return "Apply changes to the metadata of a node"
+}
+
+func (c *NodeMetaApplyCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-node-id": c.PredictNodes(),
+			"-file": complete.PredictOr(
+				complete.PredictFiles("*.json"),
+				complete.PredictFiles("*.hcl")),
+			"-json": complete.PredictNothing,
+			"-t":    complete.PredictAnything,
+		})
+}
+
+func (c *NodeMetaApplyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *NodeMetaApplyCommand) Run(args []string) int {
+	var json bool
+	var nodeID, file, tmpl string
+
+	flags := c.Meta.FlagSet("node meta apply", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&nodeID, "node-id", "", "")
+	flags.StringVar(&file, "file", "", "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we were given something to apply
+	args = flags.Args()
+	if len(args) == 0 && file == "" {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	meta := make(map[string]*string)
+	if file != "" {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error reading %q: %s", file, err))
+			return 1
+		}
+
+		var fileMeta map[string]string
+		if err := hcl.Decode(&fileMeta, string(contents)); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error parsing %q: %s", file, err))
+			return 1
+		}
+		for k, v := range fileMeta {
+			meta[k] = helper.StringToPtr(v)
+		}
+	}
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			c.Ui.Error(fmt.Sprintf("Invalid meta %q, must be of the form key=value", arg))
+			return 1
+		}
+		meta[parts[0]] = helper.StringToPtr(parts[1])
+	}
+
+	if len(meta) == 0 {
+		c.Ui.Error(fmt.Sprintf("No node meta to apply in %q", file))
+		return 1
+	}
+
+	client, err := c.Meta.nodeMetaClient(nodeID)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	resp, err := client.Agent().ApplyNodeMeta(meta)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error applying node meta: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, resp)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatNodeMeta(resp))
+	return 0
+}
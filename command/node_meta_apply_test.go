@@ -0,0 +1,92 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestNodeMetaApplyCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &NodeMetaApplyCommand{}
+}
+
+func TestNodeMetaApplyCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &NodeMetaApplyCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run(nil); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on invalid meta
+	if code := cmd.Run([]string{"rack"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "must be of the form key=value") {
+		t.Fatalf("expected invalid meta error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on a missing file
+	if code := cmd.Run([]string{"-file=/unicorns/leprechauns"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error reading") {
+		t.Fatalf("expected read error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "rack=r1"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error applying node meta") {
+		t.Fatalf("expected failed apply error, got: %s", out)
+	}
+}
+
+func TestNodeMetaApplyCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, true, nil)
+	defer srv.Shutdown()
+
+	fh, err := ioutil.TempFile("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(fh.Name())
+	if _, err := fh.WriteString("rack = \"r1\"\nzone = \"z1\"\n"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	fh.Close()
+
+	ui := new(cli.MockUi)
+	cmd := &NodeMetaApplyCommand{Meta: Meta{Ui: ui}}
+
+	// Arguments take precedence over the file
+	if code := cmd.Run([]string{"-address=" + url, "-file=" + fh.Name(), "zone=z2"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "r1") || !strings.Contains(out, "z2") || !strings.Contains(out, "dynamic") {
+		t.Fatalf("expected applied meta, got: %s", out)
+	}
+
+	meta, err := client.Agent().NodeMeta()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if meta.Meta["rack"] != "r1" || meta.Meta["zone"] != "z2" {
+		t.Fatalf("bad: %#v", meta.Meta)
+	}
+}
@@ -0,0 +1,103 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type NodeMetaReadCommand struct {
+	Meta
+}
+
+func (c *NodeMetaReadCommand) Help() string {
+	helpText := `
+Usage: nomad node meta read [options]
+
+Read displays the metadata of a client node. The metadata is the static meta
+set in the client configuration overlaid with the dynamic meta applied at
+runtime with the apply and unset commands. By default the node of the agent
+the command is run against is read.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Meta Read Options:
+
+  -node-id
+    Read the metadata of the node matching the ID prefix.
+
+  -json
+    Output the static, dynamic and merged metadata in a JSON format.
+
+  -t
+    Format and display the metadata using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *NodeMetaReadCommand) Synopsis() string {
+	return "Read the metadata of a node"
+}
+
+func (c *NodeMetaReadCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-node-id": c.PredictNodes(),
+			"-json":    complete.PredictNothing,
+			"-t":       complete.PredictAnything,
+		})
+}
+
+func (c *NodeMetaReadCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *NodeMetaReadCommand) Run(args []string) int {
+	var json bool
+	var nodeID, tmpl string
+
+	flags := c.Meta.FlagSet("node meta read", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&nodeID, "node-id", "", "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	client, err := c.Meta.nodeMetaClient(nodeID)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	meta, err := client.Agent().NodeMeta()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading node meta: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, meta)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatNodeMeta(meta))
+	return 0
+}
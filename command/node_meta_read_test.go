@@ -0,0 +1,65 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/command/agent"
+	"github.com/mitchellh/cli"
+)
+
+func TestNodeMetaReadCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &NodeMetaReadCommand{}
+}
+
+func TestNodeMetaReadCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &NodeMetaReadCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error reading node meta") {
+		t.Fatalf("expected failed read error, got: %s", out)
+	}
+}
+
+func TestNodeMetaReadCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, _, url := testServer(t, true, func(c *agent.Config) {
+		c.Client.Meta = map[string]string{"rack": "r1"}
+	})
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &NodeMetaReadCommand{Meta: Meta{Ui: ui}}
+
+	if code := cmd.Run([]string{"-address=" + url}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "rack") || !strings.Contains(out, "static") {
+		t.Fatalf("expected static rack meta, got: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format the meta with a template
+	if code := cmd.Run([]string{"-address=" + url, "-t", `{{index .Static "rack"}}`}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "r1" {
+		t.Fatalf("expected r1, got: %s", out)
+	}
+}
@@ -0,0 +1,109 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type NodeMetaUnsetCommand struct {
+	Meta
+}
+
+func (c *NodeMetaUnsetCommand) Help() string {
+	helpText := `
+Usage: nomad node meta unset [options] <key>...
+
+Unset removes keys from the metadata of a client node, including keys set in
+the client configuration. The change is persisted by the client and is
+registered with the servers shortly after. By default the node of the agent
+the command is run against is updated.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Meta Unset Options:
+
+  -node-id
+    Unset the metadata of the node matching the ID prefix.
+
+  -json
+    Output the resulting metadata in a JSON format.
+
+  -t
+    Format and display the resulting metadata using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *NodeMetaUnsetCommand) Synopsis() string {
+	return "Unset metadata keys of a node"
+}
+
+func (c *NodeMetaUnsetCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-node-id": c.PredictNodes(),
+			"-json":    complete.PredictNothing,
+			"-t":       complete.PredictAnything,
+		})
+}
+
+func (c *NodeMetaUnsetCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *NodeMetaUnsetCommand) Run(args []string) int {
+	var json bool
+	var nodeID, tmpl string
+
+	flags := c.Meta.FlagSet("node meta unset", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&nodeID, "node-id", "", "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got at least one key
+	args = flags.Args()
+	if len(args) == 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	meta := make(map[string]*string, len(args))
+	for _, key := range args {
+		meta[key] = nil
+	}
+
+	client, err := c.Meta.nodeMetaClient(nodeID)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	resp, err := client.Agent().ApplyNodeMeta(meta)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error unsetting node meta: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, resp)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatNodeMeta(resp))
+	return 0
+}
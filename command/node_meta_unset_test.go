@@ -0,0 +1,64 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/command/agent"
+	"github.com/mitchellh/cli"
+)
+
+func TestNodeMetaUnsetCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &NodeMetaUnsetCommand{}
+}
+
+func TestNodeMetaUnsetCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &NodeMetaUnsetCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run(nil); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "rack"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error unsetting node meta") {
+		t.Fatalf("expected failed unset error, got: %s", out)
+	}
+}
+
+func TestNodeMetaUnsetCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, true, func(c *agent.Config) {
+		c.Client.Meta = map[string]string{"rack": "r1", "zone": "z1"}
+	})
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &NodeMetaUnsetCommand{Meta: Meta{Ui: ui}}
+
+	// Static keys can be unset
+	if code := cmd.Run([]string{"-address=" + url, "rack"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+
+	meta, err := client.Agent().NodeMeta()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, ok := meta.Meta["rack"]; ok {
+		t.Fatalf("expected rack to be unset: %#v", meta.Meta)
+	}
+	if meta.Meta["zone"] != "z1" {
+		t.Fatalf("bad: %#v", meta.Meta)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/helper"
+	"github.com/posener/complete"
 )
 
 const (
@@ -33,6 +34,9 @@ type NodeStatusCommand struct {
 	stats       bool
 	json        bool
 	tmpl        string
+	perPage     int
+	pageToken   string
+	filter      string
 }
 
 func (c *NodeStatusCommand) Help() string {
@@ -75,6 +79,18 @@ Node Status Options:
 
   -t
     Format and display node using a Go template.
+
+  -per-page
+    How many nodes to list at a time when no node is given. Defaults to
+    listing all nodes.
+
+  -page-token
+    Where to start listing nodes from when -per-page is set. This is the token
+    printed with the previous page.
+
+  -filter
+    Only list the nodes matching the filter expression when no node is given,
+    e.g. 'Datacenter == "dc1"'.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -83,6 +99,26 @@ func (c *NodeStatusCommand) Synopsis() string {
 	return "Display status information about nodes"
 }
 
+func (c *NodeStatusCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-short":      complete.PredictNothing,
+			"-verbose":    complete.PredictNothing,
+			"-allocs":     complete.PredictNothing,
+			"-self":       complete.PredictNothing,
+			"-stats":      complete.PredictNothing,
+			"-json":       complete.PredictNothing,
+			"-t":          complete.PredictAnything,
+			"-per-page":   complete.PredictAnything,
+			"-page-token": complete.PredictAnything,
+			"-filter":     complete.PredictAnything,
+		})
+}
+
+func (c *NodeStatusCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictNodes()
+}
+
 func (c *NodeStatusCommand) Run(args []string) int {
 
 	flags := c.Meta.FlagSet("node-status", FlagSetClient)
@@ -94,6 +130,9 @@ func (c *NodeStatusCommand) Run(args []string) int {
 	flags.BoolVar(&c.stats, "stats", false, "")
 	flags.BoolVar(&c.json, "json", false, "")
 	flags.StringVar(&c.tmpl, "t", "", "")
+	flags.IntVar(&c.perPage, "per-page", 0, "")
+	flags.StringVar(&c.pageToken, "page-token", "", "")
+	flags.StringVar(&c.filter, "filter", "", "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -123,7 +162,12 @@ func (c *NodeStatusCommand) Run(args []string) int {
 	if len(args) == 0 && !c.self {
 
 		// Query the node info
-		nodes, _, err := client.Nodes().List(nil)
+		q := &api.QueryOptions{
+			PerPage:   int32(c.perPage),
+			NextToken: c.pageToken,
+			Filter:    c.filter,
+		}
+		nodes, qm, err := client.Nodes().List(q)
 		if err != nil {
 			c.Ui.Error(fmt.Sprintf("Error querying node status: %s", err))
 			return 1
@@ -182,6 +226,9 @@ func (c *NodeStatusCommand) Run(args []string) int {
 
 		// Dump the output
 		c.Ui.Output(formatList(out))
+		if next := formatNextPage("node-status", c.perPage, qm.NextToken); next != "" {
+			c.Ui.Output(next)
+		}
 		return 0
 	}
 
@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/nomad/command/agent"
 	"github.com/hashicorp/nomad/testutil"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func TestNodeStatusCommand_Implements(t *testing.T) {
@@ -213,3 +214,35 @@ func TestNodeStatusCommand_Fails(t *testing.T) {
 		t.Fatalf("expected getting formatter error, got: %s", out)
 	}
 }
+
+func TestNodeStatusCommand_AutocompleteArgs(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, true, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &NodeStatusCommand{Meta: Meta{Ui: ui, flagAddress: url}}
+
+	// Wait for a node to appear
+	var nodeID string
+	testutil.WaitForResult(func() (bool, error) {
+		nodes, _, err := client.Nodes().List(nil)
+		if err != nil {
+			return false, err
+		}
+		if len(nodes) == 0 {
+			return false, fmt.Errorf("missing node")
+		}
+		nodeID = nodes[0].ID
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %s", err)
+	})
+
+	// Odd length prefixes are supported
+	predictor := cmd.AutocompleteArgs()
+	res := predictor.Predict(complete.Args{Last: nodeID[:5]})
+	if len(res) != 1 || res[0] != nodeID {
+		t.Fatalf("expected %q; got %v", nodeID, res)
+	}
+}
@@ -15,8 +15,9 @@ func (f *OperatorCommand) Help() string {
 Usage: nomad operator <subcommand> [options]
 
   Provides cluster-level tools for Nomad operators, such as interacting with
-  the Raft subsystem. NOTE: Use this command with extreme caution, as improper
-  use could lead to a Nomad outage and even loss of data.
+  the Raft subsystem or the scheduler configuration. NOTE: Use this command
+  with extreme caution, as improper use could lead to a Nomad outage and even
+  loss of data.
 
   Run nomad operator <subcommand> with no arguments for help on that subcommand.
 `
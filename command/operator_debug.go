@@ -0,0 +1,381 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// debugMaxProfileDuration is the maximum duration of the CPU profile
+	// captured from each agent.
+	debugMaxProfileDuration = 30 * time.Second
+)
+
+type OperatorDebugCommand struct {
+	Meta
+}
+
+func (c *OperatorDebugCommand) Help() string {
+	helpText := `
+Usage: nomad operator debug [options]
+
+  Captures debugging information from the agent and optionally from client
+  nodes over a period of time and writes it into a single archive. The archive
+  contains the cluster members, nodes and Raft configuration, and for each
+  agent its redacted configuration, periodic metrics snapshots, goroutine
+  dumps and heap profiles, a CPU profile and its recent logs.
+
+  Profiles and goroutine dumps require the agents to run with enable_debug.
+  To capture other servers, run the command against each of them.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Debug Options:
+
+  -duration=<duration>
+    The duration of the capture. Defaults to 2m.
+
+  -interval=<duration>
+    The interval between the snapshots of metrics and profiles taken during
+    the capture. Defaults to 30s.
+
+  -node-id=<ids>
+    Comma separated list of the IDs of client nodes to also capture, or "all"
+    to capture all ready client nodes. Node IDs may be prefixes.
+
+  -output=<path>
+    The directory in which to write the archive. Defaults to the current
+    directory.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorDebugCommand) Synopsis() string {
+	return "Capture a debugging bundle from Nomad agents"
+}
+
+// debugTarget is an agent debugging information is captured from.
+type debugTarget struct {
+	name   string
+	dir    string
+	client *api.Client
+}
+
+func (c *OperatorDebugCommand) Run(args []string) int {
+	var duration, interval time.Duration
+	var nodeIDs, output string
+
+	flags := c.Meta.FlagSet("debug", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.DurationVar(&duration, "duration", 2*time.Minute, "")
+	flags.DurationVar(&interval, "interval", 30*time.Second, "")
+	flags.StringVar(&nodeIDs, "node-id", "", "")
+	flags.StringVar(&output, "output", "", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	if duration <= 0 || interval <= 0 {
+		c.Ui.Error("Duration and interval must be positive")
+		return 1
+	}
+	if interval > duration {
+		interval = duration
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Create the directory the capture is written to before it is archived
+	name := fmt.Sprintf("nomad-debug-%s", time.Now().UTC().Format("2006-01-02-150405Z"))
+	tmp, err := ioutil.TempDir("", "nomad-debug")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating temporary directory: %s", err))
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating temporary directory: %s", err))
+		return 1
+	}
+
+	// Collect the agents to capture
+	self, err := client.Agent().Self()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error querying agent info: %s", err))
+		return 1
+	}
+	targets := []*debugTarget{{
+		name:   self.Member.Name,
+		dir:    filepath.Join(dir, "agent", sanitizeDebugName(self.Member.Name)),
+		client: client,
+	}}
+
+	nodes, err := c.debugNodes(client, nodeIDs)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	for _, node := range nodes {
+		nodeClient, err := client.GetNodeClient(node.ID, nil)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error creating client for node %q: %s", node.Name, err))
+			return 1
+		}
+		targets = append(targets, &debugTarget{
+			name:   node.Name,
+			dir:    filepath.Join(dir, "client", node.ID),
+			client: nodeClient,
+		})
+	}
+
+	c.Ui.Output(fmt.Sprintf("Capturing from %d agent(s) for %s", len(targets), duration))
+
+	// Capture the cluster state
+	c.writeJSON(filepath.Join(dir, "cluster", "members.json"), func() (interface{}, error) {
+		return client.Agent().Members()
+	})
+	c.writeJSON(filepath.Join(dir, "cluster", "nodes.json"), func() (interface{}, error) {
+		nodes, _, err := client.Nodes().List(nil)
+		return nodes, err
+	})
+	c.writeJSON(filepath.Join(dir, "cluster", "raft.json"), func() (interface{}, error) {
+		return client.Operator().RaftGetConfiguration(nil)
+	})
+
+	// Capture the agents' configurations and a CPU profile covering the
+	// start of the capture
+	seconds := int(duration.Seconds())
+	if duration > debugMaxProfileDuration {
+		seconds = int(debugMaxProfileDuration.Seconds())
+	}
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		c.writeJSON(filepath.Join(t.dir, "self.json"), func() (interface{}, error) {
+			return t.client.Agent().Self()
+		})
+
+		wg.Add(1)
+		go func(t *debugTarget) {
+			defer wg.Done()
+			c.writeProfile(t, "profile.prof", fmt.Sprintf("/debug/pprof/profile?seconds=%d", seconds))
+		}(t)
+	}
+
+	// Take the periodic snapshots
+	deadline := time.Now().Add(duration)
+	for i := 0; ; i++ {
+		for _, t := range targets {
+			c.writeJSON(filepath.Join(t.dir, fmt.Sprintf("metrics-%04d.json", i)), func() (interface{}, error) {
+				return t.client.Agent().Metrics()
+			})
+			c.writeProfile(t, fmt.Sprintf("goroutine-%04d.txt", i), "/debug/pprof/goroutine?debug=2")
+			c.writeProfile(t, fmt.Sprintf("heap-%04d.prof", i), "/debug/pprof/heap")
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			break
+		}
+		time.Sleep(interval)
+	}
+	wg.Wait()
+
+	// Capture the logs covering the end of the capture
+	for _, t := range targets {
+		c.writeJSON(filepath.Join(t.dir, "logs.json"), func() (interface{}, error) {
+			return t.client.Agent().Logs()
+		})
+	}
+
+	// Write the archive
+	if output == "" {
+		output = "."
+	}
+	archive := filepath.Join(output, name+".tar.gz")
+	if err := writeDebugArchive(archive, tmp, name); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing archive: %s", err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Created debug archive: %s", archive))
+	return 0
+}
+
+// debugNodes returns the client nodes to capture given the value of the
+// -node-id flag.
+func (c *OperatorDebugCommand) debugNodes(client *api.Client, ids string) ([]*api.NodeListStub, error) {
+	if ids == "" {
+		return nil, nil
+	}
+
+	var nodes []*api.NodeListStub
+	if ids == "all" {
+		all, _, err := client.Nodes().List(nil)
+		if err != nil {
+			return nil, fmt.Errorf("Error querying nodes: %s", err)
+		}
+		for _, node := range all {
+			if node.Status == "ready" {
+				nodes = append(nodes, node)
+			}
+		}
+		return nodes, nil
+	}
+
+	for _, id := range strings.Split(ids, ",") {
+		id = strings.TrimSpace(id)
+		if len(id) < 2 {
+			return nil, fmt.Errorf("Identifier must contain at least two characters.")
+		}
+		if len(id)%2 == 1 {
+			// Identifiers must be of even length, so we strip off the last
+			// byte to provide a consistent user experience.
+			id = id[:len(id)-1]
+		}
+		matches, _, err := client.Nodes().PrefixList(id)
+		if err != nil {
+			return nil, fmt.Errorf("Error querying node %q: %s", id, err)
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("No node(s) with prefix %q found", id)
+		case 1:
+			nodes = append(nodes, matches[0])
+		default:
+			return nil, fmt.Errorf("Prefix %q matched multiple nodes", id)
+		}
+	}
+	return nodes, nil
+}
+
+// writeJSON writes the result of the query as JSON to the path. A failed
+// query is reported as a warning so the rest of the capture continues.
+func (c *OperatorDebugCommand) writeJSON(path string, query func() (interface{}, error)) {
+	out, err := query()
+	if err != nil {
+		c.Ui.Warn(fmt.Sprintf("Failed to capture %s: %s", filepath.Base(path), err))
+		return
+	}
+	buf, err := json.MarshalIndent(out, "", "    ")
+	if err != nil {
+		c.Ui.Warn(fmt.Sprintf("Failed to encode %s: %s", filepath.Base(path), err))
+		return
+	}
+	if err := writeDebugFile(path, buf); err != nil {
+		c.Ui.Warn(fmt.Sprintf("Failed to write %s: %s", path, err))
+	}
+}
+
+// writeProfile writes the profile served by the agent at the endpoint.
+func (c *OperatorDebugCommand) writeProfile(t *debugTarget, file, endpoint string) {
+	body, err := t.client.Raw().Response(endpoint, nil)
+	if err != nil {
+		c.Ui.Warn(fmt.Sprintf("Failed to capture %s from %s: %s", file, t.name, err))
+		return
+	}
+	defer body.Close()
+
+	buf, err := ioutil.ReadAll(body)
+	if err != nil {
+		c.Ui.Warn(fmt.Sprintf("Failed to capture %s from %s: %s", file, t.name, err))
+		return
+	}
+	if err := writeDebugFile(filepath.Join(t.dir, file), buf); err != nil {
+		c.Ui.Warn(fmt.Sprintf("Failed to write %s: %s", file, err))
+	}
+}
+
+// writeDebugFile writes the file, creating its directory if needed.
+func writeDebugFile(path string, buf []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf, 0644)
+}
+
+// sanitizeDebugName makes an agent name safe to use as a directory name.
+func sanitizeDebugName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// writeDebugArchive writes the directory name within root into a gzipped tar
+// archive at path.
+func writeDebugArchive(path, root, name string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(filepath.Join(root, name), func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		src, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
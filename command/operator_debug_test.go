@@ -0,0 +1,94 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestOperator_Debug_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &OperatorDebugCommand{}
+}
+
+func TestOperator_Debug_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	c := &OperatorDebugCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := c.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, c.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on invalid durations
+	if code := c.Run([]string{"-duration=0s"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "must be positive") {
+		t.Fatalf("expected duration error, got: %s", out)
+	}
+}
+
+func TestOperator_Debug(t *testing.T) {
+	t.Parallel()
+	srv, _, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	dir, err := ioutil.TempDir("", "nomad-debug-test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ui := new(cli.MockUi)
+	c := &OperatorDebugCommand{Meta: Meta{Ui: ui}}
+	args := []string{"-address=" + url, "-duration=1s", "-interval=1s", "-output=" + dir}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	archives, err := filepath.Glob(filepath.Join(dir, "nomad-debug-*.tar.gz"))
+	if err != nil || len(archives) != 1 {
+		t.Fatalf("expected one archive, got: %v %v", archives, err)
+	}
+
+	// Check the archive contains the agent's configuration and metrics
+	f, err := os.Open(archives[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	files := make(map[string]bool)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		files[filepath.Base(hdr.Name)] = true
+	}
+	for _, file := range []string{"members.json", "self.json", "metrics-0000.json", "logs.json"} {
+		if !files[file] {
+			t.Fatalf("archive is missing %s: %v", file, files)
+		}
+	}
+}
@@ -28,6 +28,12 @@ List Peers Options:
     The -stale argument defaults to "false" which means the leader provides the
     result. If the cluster is in an outage state without a leader, you may need
     to set -stale to "true" to get the configuration from a non-leader server.
+
+  -json
+    Output the raft configuration in a JSON format.
+
+  -t
+    Format and display the raft configuration using a Go template.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -37,12 +43,15 @@ func (c *OperatorRaftListCommand) Synopsis() string {
 }
 
 func (c *OperatorRaftListCommand) Run(args []string) int {
-	var stale bool
+	var stale, json bool
+	var tmpl string
 
 	flags := c.Meta.FlagSet("raft", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 
 	flags.BoolVar(&stale, "stale", false, "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
 	if err := flags.Parse(args); err != nil {
 		c.Ui.Error(fmt.Sprintf("Failed to parse args: %v", err))
 		return 1
@@ -66,6 +75,17 @@ func (c *OperatorRaftListCommand) Run(args []string) int {
 		return 1
 	}
 
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, reply)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
 	// Format it as a nice table.
 	result := []string{"Node|ID|Address|State|Voter"}
 	for _, s := range reply.Servers {
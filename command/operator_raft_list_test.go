@@ -29,4 +29,14 @@ func TestOperator_Raft_ListPeers(t *testing.T) {
 	if !strings.Contains(output, "leader") {
 		t.Fatalf("bad: %s", output)
 	}
+	ui.OutputWriter.Reset()
+
+	// Output the configuration in JSON
+	if code := c.Run([]string{"-address=" + addr, "-json"}); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	output = ui.OutputWriter.String()
+	if !strings.Contains(output, `"Leader": true`) {
+		t.Fatalf("bad: %s", output)
+	}
 }
@@ -0,0 +1,31 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+type OperatorSchedulerCommand struct {
+	Meta
+}
+
+func (c *OperatorSchedulerCommand) Help() string {
+	helpText := `
+Usage: nomad operator scheduler <subcommand> [options]
+
+The scheduler operator command is used to interact with the configuration of
+Nomad's schedulers. The configuration is stored in Raft and changes take effect
+for the evaluations processed after they are applied, without restarting the
+servers.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSchedulerCommand) Synopsis() string {
+	return "Provides access to the scheduler configuration"
+}
+
+func (c *OperatorSchedulerCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
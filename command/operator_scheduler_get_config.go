@@ -0,0 +1,109 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+type OperatorSchedulerGetConfigCommand struct {
+	Meta
+}
+
+func (c *OperatorSchedulerGetConfigCommand) Help() string {
+	helpText := `
+Usage: nomad operator scheduler get-config [options]
+
+Displays the current scheduler configuration.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Get Config Options:
+
+  -stale=[true|false]
+    The -stale argument defaults to "false" which means the leader provides the
+    result. If the cluster is in an outage state without a leader, you may need
+    to set -stale to "true" to get the configuration from a non-leader server.
+
+  -json
+    Output the scheduler configuration in a JSON format.
+
+  -t
+    Format and display the scheduler configuration using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSchedulerGetConfigCommand) Synopsis() string {
+	return "Display the current scheduler configuration"
+}
+
+func (c *OperatorSchedulerGetConfigCommand) Run(args []string) int {
+	var stale, json bool
+	var tmpl string
+
+	flags := c.Meta.FlagSet("scheduler get-config", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+
+	flags.BoolVar(&stale, "stale", false, "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+	if err := flags.Parse(args); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse args: %v", err))
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Set up a client.
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	// Fetch the current configuration.
+	q := &api.QueryOptions{
+		AllowStale: stale,
+	}
+	resp, _, err := client.Operator().SchedulerGetConfiguration(q)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to retrieve scheduler configuration: %v", err))
+		return 1
+	}
+	config := resp.SchedulerConfig
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, config)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatSchedulerConfig(config))
+	return 0
+}
+
+// formatSchedulerConfig formats the scheduler configuration for display.
+func formatSchedulerConfig(config *api.SchedulerConfiguration) string {
+	algorithm := config.SchedulerAlgorithm
+	if algorithm == "" {
+		algorithm = api.SchedulerAlgorithmBinpack
+	}
+
+	return formatKV([]string{
+		fmt.Sprintf("Scheduler Algorithm|%s", algorithm),
+		fmt.Sprintf("Modify Index|%d", config.ModifyIndex),
+	})
+}
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestOperator_Scheduler_GetConfig_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &OperatorSchedulerGetConfigCommand{}
+}
+
+func TestOperator_Scheduler_GetConfig_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &OperatorSchedulerGetConfigCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Failed to retrieve scheduler configuration") {
+		t.Fatalf("expected failed query error, got: %s", out)
+	}
+}
+
+func TestOperator_Scheduler_GetConfig(t *testing.T) {
+	t.Parallel()
+	s, _, addr := testServer(t, false, nil)
+	defer s.Shutdown()
+
+	ui := new(cli.MockUi)
+	c := &OperatorSchedulerGetConfigCommand{Meta: Meta{Ui: ui}}
+
+	if code := c.Run([]string{"-address=" + addr}); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	if !strings.Contains(output, "Scheduler Algorithm") || !strings.Contains(output, "binpack") {
+		t.Fatalf("bad: %s", output)
+	}
+	ui.OutputWriter.Reset()
+
+	// Output the configuration in JSON
+	if code := c.Run([]string{"-address=" + addr, "-json"}); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	output = ui.OutputWriter.String()
+	if !strings.Contains(output, `"SchedulerAlgorithm": "binpack"`) {
+		t.Fatalf("bad: %s", output)
+	}
+}
@@ -0,0 +1,121 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+)
+
+type OperatorSchedulerSetConfigCommand struct {
+	Meta
+}
+
+func (c *OperatorSchedulerSetConfigCommand) Help() string {
+	helpText := `
+Usage: nomad operator scheduler set-config [options]
+
+Modifies the current scheduler configuration. Only the settings given as
+options are changed, the others keep their current value. The new
+configuration is used by the schedulers without restarting the servers.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Set Config Options:
+
+  -scheduler-algorithm=["binpack"|"spread"]
+    Specifies whether the schedulers place allocations on the most utilized
+    nodes that fit them ("binpack") or on the least utilized nodes ("spread").
+
+  -check-index
+    If set, the configuration is only updated if its modify index matches the
+    given value. Use the index displayed by get-config to avoid overwriting a
+    concurrent change.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSchedulerSetConfigCommand) Synopsis() string {
+	return "Modify the current scheduler configuration"
+}
+
+func (c *OperatorSchedulerSetConfigCommand) Run(args []string) int {
+	var algorithm string
+	var checkIndex *uint64
+
+	flags := c.Meta.FlagSet("scheduler set-config", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&algorithm, "scheduler-algorithm", "", "")
+	flags.Var((flaghelper.FuncVar)(func(s string) error {
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		checkIndex = &v
+		return nil
+	}), "check-index", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	switch algorithm {
+	case "", api.SchedulerAlgorithmBinpack, api.SchedulerAlgorithmSpread:
+	default:
+		c.Ui.Error(fmt.Sprintf("Invalid scheduler algorithm %q, must be %q or %q",
+			algorithm, api.SchedulerAlgorithmBinpack, api.SchedulerAlgorithmSpread))
+		return 1
+	}
+
+	// Set up a client.
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+	operator := client.Operator()
+
+	// Fetch the current configuration so that unset options are kept.
+	resp, _, err := operator.SchedulerGetConfiguration(nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to retrieve scheduler configuration: %v", err))
+		return 1
+	}
+	config := resp.SchedulerConfig
+
+	// Apply the options
+	if algorithm != "" {
+		config.SchedulerAlgorithm = algorithm
+	}
+
+	// Submit the update, checking the index if requested
+	var setResp *api.SchedulerSetConfigurationResponse
+	if checkIndex != nil {
+		config.ModifyIndex = *checkIndex
+		setResp, _, err = operator.SchedulerCASConfiguration(config, nil)
+	} else {
+		setResp, _, err = operator.SchedulerSetConfiguration(config, nil)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error setting scheduler configuration: %v", err))
+		return 1
+	}
+
+	if !setResp.Updated {
+		c.Ui.Error(fmt.Sprintf("Scheduler configuration was not updated: the modify index is not %d", *checkIndex))
+		return 1
+	}
+
+	c.Ui.Output("Scheduler configuration updated!")
+	return 0
+}
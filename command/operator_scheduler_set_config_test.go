@@ -0,0 +1,80 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestOperator_Scheduler_SetConfig_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &OperatorSchedulerSetConfigCommand{}
+}
+
+func TestOperator_Scheduler_SetConfig_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &OperatorSchedulerSetConfigCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on an invalid algorithm
+	if code := cmd.Run([]string{"-scheduler-algorithm=random"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Invalid scheduler algorithm") {
+		t.Fatalf("expected invalid algorithm error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "-scheduler-algorithm=spread"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Failed to retrieve scheduler configuration") {
+		t.Fatalf("expected failed query error, got: %s", out)
+	}
+}
+
+func TestOperator_Scheduler_SetConfig(t *testing.T) {
+	t.Parallel()
+	s, client, addr := testServer(t, false, nil)
+	defer s.Shutdown()
+
+	ui := new(cli.MockUi)
+	c := &OperatorSchedulerSetConfigCommand{Meta: Meta{Ui: ui}}
+
+	args := []string{"-address=" + addr, "-scheduler-algorithm=spread"}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Scheduler configuration updated") {
+		t.Fatalf("bad: %s", out)
+	}
+
+	resp, _, err := client.Operator().SchedulerGetConfiguration(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config := resp.SchedulerConfig
+	if config.SchedulerAlgorithm != "spread" {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	// A stale check index is rejected
+	args = []string{"-address=" + addr, "-scheduler-algorithm=binpack", "-check-index=1"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "was not updated") {
+		t.Fatalf("bad: %s", out)
+	}
+}
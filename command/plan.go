@@ -70,6 +70,24 @@ Plan Options:
 
   -verbose
     Increase diff verbosity.
+
+  -json
+    Output the plan, including the full diff, the scheduler's annotations and
+    the dry-run results, in its JSON format.
+
+  -t
+    Format and display the plan using a Go template.
+
+  -render-templates
+    Render the embedded templates of the tasks against Consul and Vault and
+    show how their rendered contents change compared to the current version of
+    the job. Templates are rendered with the Consul and Vault addresses and
+    tokens of the CONSUL_HTTP_ADDR, CONSUL_HTTP_TOKEN, VAULT_ADDR and
+    VAULT_TOKEN environment variables, and the Nomad services and variables
+    they use are read from the Nomad agent of the command. Nothing is
+    written: the leases of the Vault secrets read are revoked and templates
+    writing to Vault, reading files or running plugins aren't rendered. The
+    contents of templates rendered to the secrets directory are hidden.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -79,7 +97,14 @@ func (c *PlanCommand) Synopsis() string {
 }
 
 func (c *PlanCommand) AutocompleteFlags() complete.Flags {
-	return nil
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-diff":             complete.PredictNothing,
+			"-verbose":          complete.PredictNothing,
+			"-json":             complete.PredictNothing,
+			"-t":                complete.PredictAnything,
+			"-render-templates": complete.PredictNothing,
+		})
 }
 
 func (c *PlanCommand) AutocompleteArgs() complete.Predictor {
@@ -87,12 +112,16 @@ func (c *PlanCommand) AutocompleteArgs() complete.Predictor {
 }
 
 func (c *PlanCommand) Run(args []string) int {
-	var diff, verbose bool
+	var diff, verbose, json, renderTemplates bool
+	var tmpl string
 
 	flags := c.Meta.FlagSet("plan", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&diff, "diff", true, "")
 	flags.BoolVar(&verbose, "verbose", false, "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+	flags.BoolVar(&renderTemplates, "render-templates", false, "")
 
 	if err := flags.Parse(args); err != nil {
 		return 255
@@ -105,6 +134,16 @@ func (c *PlanCommand) Run(args []string) int {
 		return 255
 	}
 
+	if json && len(tmpl) > 0 {
+		c.Ui.Error("Both json and template formatting are not allowed")
+		return 255
+	}
+
+	if renderTemplates && (json || len(tmpl) > 0) {
+		c.Ui.Error("Templates can't be rendered with json or template formatting")
+		return 255
+	}
+
 	path := args[0]
 	// Get Job struct from Jobfile
 	job, err := c.JobGetter.ApiJob(args[0])
@@ -126,12 +165,23 @@ func (c *PlanCommand) Run(args []string) int {
 	}
 
 	// Submit the job
-	resp, _, err := client.Jobs().Plan(job, diff, nil)
+	resp, _, err := client.Jobs().Plan(job, diff || json || len(tmpl) > 0, nil)
 	if err != nil {
 		c.Ui.Error(fmt.Sprintf("Error during plan: %s", err))
 		return 255
 	}
 
+	// Output the formatted plan, which always includes the diff
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, resp)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 255
+		}
+		c.Ui.Output(out)
+		return getExitCode(resp)
+	}
+
 	// Print the diff if not disabled
 	if diff {
 		c.Ui.Output(fmt.Sprintf("%s\n",
@@ -149,11 +199,46 @@ func (c *PlanCommand) Run(args []string) int {
 			c.Colorize().Color(fmt.Sprintf("[bold][yellow]Job Warnings:\n%s[reset]\n", resp.Warnings)))
 	}
 
+	// Print the rendered templates if requested
+	if renderTemplates {
+		if err := c.outputTemplatePreviews(client, job); err != nil {
+			c.Ui.Error(err.Error())
+			return 255
+		}
+	}
+
 	// Print the job index info
 	c.Ui.Output(c.Colorize().Color(formatJobModifyIndex(resp.JobModifyIndex, path)))
 	return getExitCode(resp)
 }
 
+// outputTemplatePreviews renders the templates of the planned job and of its
+// current version and outputs the differences.
+func (c *PlanCommand) outputTemplatePreviews(client *api.Client, job *api.Job) error {
+	var current *api.Job
+	if job.ID != nil {
+		var err error
+		current, _, err = client.Jobs().Info(*job.ID, nil)
+		if err != nil && !strings.Contains(err.Error(), "404") {
+			return fmt.Errorf("Error retrieving the current version of the job: %s", err)
+		}
+	}
+
+	renderer, err := newTemplateRenderer(client)
+	if err != nil {
+		return fmt.Errorf("Error initializing template rendering: %s", err)
+	}
+
+	c.Ui.Output(c.Colorize().Color("[bold]Rendered templates:[reset]"))
+	c.Ui.Output(c.Colorize().Color(formatTemplatePreviews(renderer, job, current)))
+	c.Ui.Output("")
+
+	if err := renderer.close(); err != nil {
+		return fmt.Errorf("Error cleaning up template rendering: %s", err)
+	}
+	return nil
+}
+
 // getExitCode returns 0:
 // * 0: No allocations created or destroyed.
 // * 1: Allocations created or destroyed.
@@ -0,0 +1,364 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	ctdep "github.com/hashicorp/consul-template/dependency"
+	cttemplate "github.com/hashicorp/consul-template/template"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/client"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	// templateRenderPasses is the maximum number of times a template is
+	// executed while fetching its dependencies. Templates whose dependencies
+	// depend on other dependencies need a pass per level.
+	templateRenderPasses = 8
+
+	// templateSecretsDir is the prefix of the destinations of templates
+	// whose rendered contents aren't shown
+	templateSecretsDir = "secrets/"
+)
+
+// templatePreviewDenied are the template functions that run commands or
+// read files on the machine rendering the template, which would be the one of
+// the user rather than the client when previewing.
+var templatePreviewDenied = []string{"file", "plugin"}
+
+// templateRenderer renders templates once against Consul and Vault with the
+// credentials of the user running the command, set with the usual Consul and
+// Vault environment variables. The template functions implemented by Nomad
+// are resolved with the Nomad client of the command. Dependencies are shared
+// between templates.
+type templateRenderer struct {
+	clients *ctdep.ClientSet
+	brain   *cttemplate.Brain
+	nomad   *api.Client
+
+	// leases are the IDs of the leases of the Vault secrets read while
+	// rendering, revoked once done so that previews don't leak credentials
+	leases []string
+}
+
+// newTemplateRenderer returns a templateRenderer configured from the
+// environment.
+func newTemplateRenderer(nomad *api.Client) (*templateRenderer, error) {
+	clients := ctdep.NewClientSet()
+	consulTLS := os.Getenv("CONSUL_CACERT") != "" || os.Getenv("CONSUL_CLIENT_CERT") != ""
+	err := clients.CreateConsulClient(&ctdep.CreateConsulClientInput{
+		Address:    os.Getenv("CONSUL_HTTP_ADDR"),
+		Token:      os.Getenv("CONSUL_HTTP_TOKEN"),
+		SSLEnabled: consulTLS,
+		SSLVerify:  envBoolDefault("CONSUL_HTTP_SSL_VERIFY", true),
+		SSLCert:    os.Getenv("CONSUL_CLIENT_CERT"),
+		SSLKey:     os.Getenv("CONSUL_CLIENT_KEY"),
+		SSLCACert:  os.Getenv("CONSUL_CACERT"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vaultTLS := os.Getenv("VAULT_CACERT") != "" || os.Getenv("VAULT_CLIENT_CERT") != ""
+	err = clients.CreateVaultClient(&ctdep.CreateVaultClientInput{
+		Address:    os.Getenv("VAULT_ADDR"),
+		Token:      os.Getenv("VAULT_TOKEN"),
+		SSLEnabled: vaultTLS,
+		SSLVerify:  !envBoolDefault("VAULT_SKIP_VERIFY", false),
+		SSLCert:    os.Getenv("VAULT_CLIENT_CERT"),
+		SSLKey:     os.Getenv("VAULT_CLIENT_KEY"),
+		SSLCACert:  os.Getenv("VAULT_CACERT"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &templateRenderer{
+		clients: clients,
+		brain:   cttemplate.NewBrain(),
+		nomad:   nomad,
+	}, nil
+}
+
+// envBoolDefault returns the boolean value of the environment variable or the
+// default if it is unset or invalid.
+func envBoolDefault(key string, def bool) bool {
+	b, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// render renders the embedded template. Templates can't write to Vault while
+// being previewed.
+func (r *templateRenderer) render(tmpl *api.Template) (string, error) {
+	contents := stringValue(tmpl.EmbeddedTmpl)
+	left := stringValueDefault(tmpl.LeftDelim, "{{")
+	right := stringValueDefault(tmpl.RightDelim, "}}")
+	denied, err := client.TemplateFunctionsUsed(contents, left, right, templatePreviewDenied...)
+	if err != nil {
+		return "", err
+	}
+	if len(denied) != 0 {
+		return "", fmt.Errorf("template functions can't be previewed: %s", strings.Join(denied, ", "))
+	}
+	contents, err = client.RewriteTemplateDataCalls(contents, left, right, r.resolve)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := cttemplate.NewTemplate(&cttemplate.NewTemplateInput{
+		Contents:      contents,
+		ErrMissingKey: tmpl.ErrMissingKey != nil && *tmpl.ErrMissingKey,
+		LeftDelim:     left,
+		RightDelim:    right,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for i := 0; i < templateRenderPasses; i++ {
+		result, err := t.Execute(&cttemplate.ExecuteInput{Brain: r.brain})
+		if err != nil {
+			return "", err
+		}
+		missing := result.Missing.List()
+		if len(missing) == 0 {
+			return string(result.Output), nil
+		}
+
+		for _, d := range missing {
+			if _, ok := d.(*ctdep.VaultWriteQuery); ok {
+				return "", fmt.Errorf("%s writes to Vault and can't be previewed", d)
+			}
+			data, _, err := d.Fetch(r.clients, &ctdep.QueryOptions{})
+			if err != nil {
+				return "", err
+			}
+			if secret, ok := data.(*ctdep.Secret); ok && secret.LeaseID != "" {
+				r.leases = append(r.leases, secret.LeaseID)
+			}
+			r.brain.Remember(d, data)
+		}
+	}
+	return "", fmt.Errorf("dependencies not resolved after %d passes", templateRenderPasses)
+}
+
+// resolve returns the expression evaluating to the data of the call of a
+// template function implemented by Nomad, parsed like the data the clients
+// write for the templates of the tasks.
+func (r *templateRenderer) resolve(call *client.TemplateDataCall) (string, error) {
+	var data interface{}
+	switch call.Function {
+	case "nomadService":
+		if r.nomad == nil {
+			return "", fmt.Errorf("%s: Nomad services are not available", call)
+		}
+		tag, name := "", call.Args[0]
+		if i := strings.LastIndex(name, "."); i != -1 {
+			tag, name = name[:i], name[i+1:]
+		}
+		regs, _, err := r.nomad.ServiceRegistrations().Get(name, nil)
+		if err != nil && !strings.Contains(err.Error(), "404") {
+			return "", fmt.Errorf("%s: %v", call, err)
+		}
+		services := make([]map[string]interface{}, 0, len(regs))
+		for _, reg := range regs {
+			if reg.Status == "critical" || (tag != "" && !helper.SliceStringContains(reg.Tags, tag)) {
+				continue
+			}
+			services = append(services, map[string]interface{}{
+				"ID":         reg.ID,
+				"Name":       reg.ServiceName,
+				"Tags":       reg.Tags,
+				"Address":    reg.Address,
+				"Port":       reg.Port,
+				"Datacenter": reg.Datacenter,
+				"NodeID":     reg.NodeID,
+				"JobID":      reg.JobID,
+				"AllocID":    reg.AllocID,
+			})
+		}
+		data = services
+	case "nomadVar":
+		if r.nomad == nil {
+			return "", fmt.Errorf("%s: Nomad variables are not available", call)
+		}
+		items := make(map[string]string)
+		variable, _, err := r.nomad.Variables().Read("", call.Args[0], nil)
+		switch {
+		case err != nil && !strings.Contains(err.Error(), "404"):
+			return "", fmt.Errorf("%s: %v", call, err)
+		case err == nil:
+			items = variable.Items
+		}
+		data = items
+	default:
+		var err error
+		if data, err = client.TemplateKVData(r.clients.Vault(), call); err != nil {
+			return "", fmt.Errorf("%s: %v", call, err)
+		}
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return "parseJSON " + strconv.Quote(string(raw)), nil
+}
+
+// close revokes the leases of the secrets read and closes the clients.
+func (r *templateRenderer) close() error {
+	defer r.clients.Stop()
+
+	var failed []string
+	for _, lease := range r.leases {
+		if err := r.clients.Vault().Sys().Revoke(lease); err != nil {
+			failed = append(failed, lease)
+		}
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("failed to revoke the leases of the secrets read: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// formatTemplatePreviews renders the templates of the tasks of the planned and
+// current versions of a job and returns the diffs of their rendered contents,
+// matching the templates by task and destination. The current job is nil if
+// the job isn't registered yet.
+func formatTemplatePreviews(r *templateRenderer, planned, current *api.Job) string {
+	var out []string
+	for _, tg := range planned.TaskGroups {
+		for _, task := range tg.Tasks {
+			if len(task.Templates) == 0 {
+				continue
+			}
+			out = append(out, fmt.Sprintf("[bold]Task %q (group %q):[reset]",
+				task.Name, stringValue(tg.Name)))
+
+			currentTemplates := make(map[string]*api.Template)
+			if currentTask := lookupApiTask(current, stringValue(tg.Name), task.Name); currentTask != nil {
+				for _, tmpl := range currentTask.Templates {
+					currentTemplates[stringValue(tmpl.DestPath)] = tmpl
+				}
+			}
+
+			for _, tmpl := range task.Templates {
+				dest := stringValue(tmpl.DestPath)
+				out = append(out, indentString(formatTemplatePreview(r, dest, tmpl, currentTemplates[dest]), 2))
+			}
+		}
+	}
+
+	if len(out) == 0 {
+		return "No templates to render."
+	}
+	return strings.Join(out, "\n")
+}
+
+// formatTemplatePreview returns the diff of the rendered contents of a
+// template. The contents of templates rendered to the secrets directory are
+// hidden.
+func formatTemplatePreview(r *templateRenderer, dest string, planned, current *api.Template) string {
+	header := fmt.Sprintf("Template %q: ", dest)
+	if stringValue(planned.SourcePath) != "" {
+		return header + "[yellow]source is read by the client and can't be previewed[reset]"
+	}
+
+	rendered, err := r.render(planned)
+	if err != nil {
+		return header + fmt.Sprintf("[red]failed to render: %v[reset]", err)
+	}
+
+	var previous string
+	if current != nil && stringValue(current.SourcePath) == "" {
+		if previous, err = r.render(current); err != nil {
+			return header + fmt.Sprintf("[red]failed to render current version: %v[reset]", err)
+		}
+	}
+
+	switch {
+	case current != nil && rendered == previous:
+		return header + "unchanged"
+	case strings.HasPrefix(dest, templateSecretsDir) || strings.Contains(dest, "/"+templateSecretsDir):
+		if current == nil {
+			return header + "[green]added[reset] (contents hidden)"
+		}
+		return header + "[yellow]changed[reset] (contents hidden)"
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(previous),
+		B:        difflib.SplitLines(rendered),
+		FromFile: dest + " (current)",
+		ToFile:   dest + " (planned)",
+		Context:  3,
+	})
+	if err != nil {
+		return header + fmt.Sprintf("[red]failed to diff: %v[reset]", err)
+	}
+	return header + "\n" + indentString(colorUnifiedDiff(strings.TrimSuffix(diff, "\n")), 2)
+}
+
+// colorUnifiedDiff colors the added and removed lines of a unified diff.
+func colorUnifiedDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[i] = "[bold]" + line + "[reset]"
+		case strings.HasPrefix(line, "+"):
+			lines[i] = "[green]" + line + "[reset]"
+		case strings.HasPrefix(line, "-"):
+			lines[i] = "[red]" + line + "[reset]"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lookupApiTask returns the task of the task group of the job or nil if the job
+// is nil or doesn't have it.
+func lookupApiTask(job *api.Job, group, name string) *api.Task {
+	if job == nil {
+		return nil
+	}
+	for _, tg := range job.TaskGroups {
+		if stringValue(tg.Name) != group {
+			continue
+		}
+		for _, task := range tg.Tasks {
+			if task.Name == name {
+				return task
+			}
+		}
+	}
+	return nil
+}
+
+// stringValue returns the value of the string pointer or the empty string if
+// it is nil.
+func stringValue(s *string) string {
+	return stringValueDefault(s, "")
+}
+
+// stringValueDefault returns the value of the string pointer or the default if
+// it is nil or empty.
+func stringValueDefault(s *string, def string) string {
+	if s == nil || *s == "" {
+		return def
+	}
+	return *s
+}
+
+// indentString indents every line of the string by the number of spaces.
+func indentString(s string, spaces int) string {
+	prefix := strings.Repeat(" ", spaces)
+	return prefix + strings.Replace(s, "\n", "\n"+prefix, -1)
+}
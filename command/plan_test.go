@@ -1,12 +1,15 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/testutil"
 	"github.com/mitchellh/cli"
 )
@@ -108,6 +111,67 @@ job "job1" {
 	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error during plan") {
 		t.Fatalf("expected failed query error, got: %s", out)
 	}
+	ui.ErrorWriter.Reset()
+
+	// Fails when both -json and -t are specified
+	if code := cmd.Run([]string{"-json", "-t", "{{.Diff}}", fh3.Name()}); code != 255 {
+		t.Fatalf("expected exit code 255, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Both json and template formatting are not allowed") {
+		t.Fatalf("expected formatting error, got: %s", out)
+	}
+}
+
+func TestPlanCommand_JSON(t *testing.T) {
+	t.Parallel()
+	srv, _, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	fh, err := ioutil.TempFile("", "nomad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(fh.Name())
+	_, err = fh.WriteString(`
+job "job1" {
+	type = "service"
+	datacenters = [ "dc1" ]
+	group "group1" {
+		count = 1
+		task "task1" {
+			driver = "exec"
+			config {
+				command = "/bin/sleep"
+			}
+			resources = {
+				cpu = 1000
+				memory = 512
+			}
+		}
+	}
+}`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &PlanCommand{Meta: Meta{Ui: ui}}
+
+	// Placing the new job creates allocations
+	if code := cmd.Run([]string{"-address=" + url, "-json", fh.Name()}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d: %s", code, ui.ErrorWriter.String())
+	}
+
+	var resp api.JobPlanResponse
+	if err := json.Unmarshal([]byte(ui.OutputWriter.String()), &resp); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.Diff == nil || resp.Diff.Type != "Added" {
+		t.Fatalf("bad diff: %#v", resp.Diff)
+	}
+	if resp.Annotations == nil || resp.Annotations.DesiredTGUpdates["group1"].Place != 1 {
+		t.Fatalf("bad annotations: %#v", resp.Annotations)
+	}
 }
 
 func TestPlanCommand_From_STDIN(t *testing.T) {
@@ -169,3 +233,54 @@ func TestPlanCommand_From_URL(t *testing.T) {
 		t.Fatalf("expected error getting jobfile, got: %s", out)
 	}
 }
+
+func TestPlanCommand_TemplatePreviews(t *testing.T) {
+	t.Parallel()
+	renderer, err := newTemplateRenderer(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer renderer.close()
+
+	newJob := func(templates ...*api.Template) *api.Job {
+		job := api.NewServiceJob("example", "example", "global", 1)
+		task := api.NewTask("web", "exec")
+		task.Templates = templates
+		job.AddTaskGroup(api.NewTaskGroup("group", 1).AddTask(task))
+		return job
+	}
+	tmpl := func(dest, data string) *api.Template {
+		return &api.Template{
+			DestPath:     helper.StringToPtr(dest),
+			EmbeddedTmpl: helper.StringToPtr(data),
+		}
+	}
+
+	current := newJob(
+		tmpl("local/app.conf", "port = 80\nlevel = {{ \"info\" | toUpper }}\n"),
+		tmpl("local/same.conf", "same"),
+		tmpl("secrets/app.env", "PASSWORD=a"),
+	)
+	planned := newJob(
+		tmpl("local/app.conf", "port = 80\nlevel = {{ \"debug\" | toUpper }}\n"),
+		tmpl("local/same.conf", "{{ \"same\" }}"),
+		tmpl("secrets/app.env", "PASSWORD=b"),
+		tmpl("local/plugin.conf", "{{ plugin \"/bin/true\" }}"),
+	)
+
+	out := formatTemplatePreviews(renderer, planned, current)
+	for _, expected := range []string{
+		"-level = INFO",
+		"+level = DEBUG",
+		`Template "local/same.conf": unchanged`,
+		`Template "secrets/app.env": [yellow]changed[reset] (contents hidden)`,
+		"can't be previewed: plugin",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected output to contain %q:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "PASSWORD") {
+		t.Fatalf("expected secrets to be hidden:\n%s", out)
+	}
+}
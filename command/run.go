@@ -51,6 +51,10 @@ Usage: nomad run [options] <path>
   exit code will be 2. Any other errors, including client connection
   issues or internal errors, are indicated by exit code 1.
 
+  Batch jobs can be run with -follow to stream the logs of their tasks until
+  they finish. The exit code then reflects the outcome of the tasks: 0 if they
+  all succeeded, or the exit code of the failed task otherwise.
+
   If the job has specified the region, the -region flag and NOMAD_REGION
   environment variable are overridden and the job's region is used.
 
@@ -77,6 +81,15 @@ Run Options:
     the evaluation ID will be printed to the screen, which can be used to
     examine the evaluation using the eval-status command.
 
+  -namespace
+    The namespace the job is registered in if the job file doesn't specify
+    one. Defaults to the "default" namespace.
+
+  -follow
+    Once the batch job is placed, stream the stdout and stderr logs of its tasks
+    and wait for them to finish. The logs of multiple tasks are interleaved.
+    The exit code reflects whether the tasks succeeded.
+
   -verbose
     Display full information.
 
@@ -89,6 +102,14 @@ Run Options:
   -output
     Output the JSON that would be submitted to the HTTP API without submitting
     the job.
+
+  -policy-override
+    Register the job despite the denials of soft-mandatory admission policies.
+    The denials are returned as warnings and logged by the servers along with
+    the justification, which is required.
+
+  -policy-override-justification
+    The reason the admission policies are overridden.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -98,7 +119,18 @@ func (c *RunCommand) Synopsis() string {
 }
 
 func (c *RunCommand) AutocompleteFlags() complete.Flags {
-	return nil
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":                        complete.PredictNothing,
+			"-follow":                        complete.PredictNothing,
+			"-namespace":                     c.PredictNamespaces(),
+			"-verbose":                       complete.PredictNothing,
+			"-output":                        complete.PredictNothing,
+			"-check-index":                   complete.PredictAnything,
+			"-vault-token":                   complete.PredictAnything,
+			"-policy-override":               complete.PredictNothing,
+			"-policy-override-justification": complete.PredictAnything,
+		})
 }
 
 func (c *RunCommand) AutocompleteArgs() complete.Predictor {
@@ -106,16 +138,20 @@ func (c *RunCommand) AutocompleteArgs() complete.Predictor {
 }
 
 func (c *RunCommand) Run(args []string) int {
-	var detach, verbose, output bool
-	var checkIndexStr, vaultToken string
+	var detach, follow, verbose, output, policyOverride bool
+	var checkIndexStr, vaultToken, overrideJustification, namespace string
 
 	flags := c.Meta.FlagSet("run", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&detach, "detach", false, "")
+	flags.BoolVar(&follow, "follow", false, "")
+	flags.StringVar(&namespace, "namespace", "", "")
 	flags.BoolVar(&verbose, "verbose", false, "")
 	flags.BoolVar(&output, "output", false, "")
 	flags.StringVar(&checkIndexStr, "check-index", "", "")
 	flags.StringVar(&vaultToken, "vault-token", "", "")
+	flags.BoolVar(&policyOverride, "policy-override", false, "")
+	flags.StringVar(&overrideJustification, "policy-override-justification", "", "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -164,6 +200,18 @@ func (c *RunCommand) Run(args []string) int {
 	periodic := job.IsPeriodic()
 	paramjob := job.IsParameterized()
 
+	// Following is only possible if the job runs to completion right away
+	if follow {
+		if detach {
+			c.Ui.Error("-follow and -detach are mutually exclusive")
+			return 1
+		}
+		if job.Type == nil || *job.Type != structs.JobTypeBatch || periodic || paramjob {
+			c.Ui.Error("-follow can only be used with batch jobs that are neither periodic nor parameterized")
+			return 1
+		}
+	}
+
 	// Parse the Vault token
 	if vaultToken == "" {
 		// Check the environment variable
@@ -174,6 +222,11 @@ func (c *RunCommand) Run(args []string) int {
 		job.VaultToken = helper.StringToPtr(vaultToken)
 	}
 
+	// The namespace of the job file takes precedence over the flag
+	if namespace != "" && job.Namespace == nil {
+		job.Namespace = helper.StringToPtr(namespace)
+	}
+
 	if output {
 		req := api.RegisterJobRequest{Job: job}
 		buf, err := json.MarshalIndent(req, "", "    ")
@@ -193,13 +246,20 @@ func (c *RunCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Check the policy override
+	if policyOverride && strings.TrimSpace(overrideJustification) == "" {
+		c.Ui.Error("-policy-override requires a -policy-override-justification")
+		return 1
+	}
+
 	// Submit the job
-	var resp *api.JobRegisterResponse
-	if enforce {
-		resp, _, err = client.Jobs().EnforceRegister(job, checkIndex, nil)
-	} else {
-		resp, _, err = client.Jobs().Register(job, nil)
+	opts := &api.RegisterOptions{
+		EnforceIndex:                enforce,
+		ModifyIndex:                 checkIndex,
+		PolicyOverride:              policyOverride,
+		PolicyOverrideJustification: overrideJustification,
 	}
+	resp, _, err := client.Jobs().RegisterOpts(job, opts, nil)
 	if err != nil {
 		if strings.Contains(err.Error(), api.RegisterEnforceIndexErrPrefix) {
 			// Format the error specially if the error is due to index
@@ -244,8 +304,12 @@ func (c *RunCommand) Run(args []string) int {
 
 	// Detach was not specified, so start monitoring
 	mon := newMonitor(c.Ui, client, length)
-	return mon.monitor(evalID, false)
+	code := mon.monitor(evalID, false)
+	if !follow || code != 0 {
+		return code
+	}
 
+	return c.follow(client, evalID, length)
 }
 
 // parseCheckIndex parses the check-index flag and returns the index, whether it
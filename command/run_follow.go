@@ -0,0 +1,135 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// followDrainPeriod is how long the logs of a finished allocation keep
+	// being streamed so that the last lines written by its tasks are output.
+	followDrainPeriod = 2 * time.Second
+)
+
+// follow streams the logs of the tasks of the allocations placed by the
+// evaluation until they finish. The returned exit code is zero if every
+// allocation completed successfully, or the exit code of a failed task.
+func (c *RunCommand) follow(client *api.Client, evalID string, length int) int {
+	stubs, _, err := client.Evaluations().Allocations(evalID, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading the allocations of evaluation %q: %s", limit(evalID, length), err))
+		return 1
+	}
+	if len(stubs) == 0 {
+		c.Ui.Error(fmt.Sprintf("No allocations were placed by evaluation %q", limit(evalID, length)))
+		return 1
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, len(stubs))
+	for i, stub := range stubs {
+		alloc, _, err := client.Allocations().Info(stub.ID, nil)
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error querying allocation %q: %s", limit(stub.ID, length), err))
+			return 1
+		}
+
+		wg.Add(1)
+		go func(i int, alloc *api.Allocation) {
+			defer wg.Done()
+			codes[i] = c.followAlloc(client, alloc, length)
+		}(i, alloc)
+	}
+	wg.Wait()
+
+	for _, code := range codes {
+		if code != 0 {
+			return code
+		}
+	}
+	return 0
+}
+
+// followAlloc streams the logs of the tasks of the allocation until it
+// finishes and returns the exit code reflecting its outcome.
+func (c *RunCommand) followAlloc(client *api.Client, alloc *api.Allocation, length int) int {
+	cancel := make(chan struct{})
+	var streams sync.WaitGroup
+	for _, task := range allocTasks(alloc) {
+		for logType, w := range map[string]io.Writer{"stdout": os.Stdout, "stderr": os.Stderr} {
+			frames, err := client.AllocFS().Logs(alloc, true, task, logType, api.OriginStart, 0, cancel, nil)
+			if err != nil {
+				c.Ui.Error(fmt.Sprintf("Error streaming the %s logs of task %q: %s", logType, task, err))
+				continue
+			}
+
+			r := api.NewFrameReader(frames, cancel)
+			r.SetUnblockTime(500 * time.Millisecond)
+			streams.Add(1)
+			go func(w io.Writer, r io.Reader) {
+				defer streams.Done()
+				io.Copy(w, r)
+			}(w, r)
+		}
+	}
+
+	final, err := client.Allocations().WaitForCompletion(alloc.ID, nil)
+
+	// Give the streams time to output the last logs before stopping them
+	time.Sleep(followDrainPeriod)
+	close(cancel)
+	streams.Wait()
+
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error waiting for allocation %q: %s", limit(alloc.ID, length), err))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Allocation %q finished with status %q", limit(final.ID, length), final.ClientStatus))
+	return allocExitCode(final)
+}
+
+// allocTasks returns the sorted names of the tasks of the allocation.
+func allocTasks(alloc *api.Allocation) []string {
+	var tasks []string
+	for _, tg := range alloc.Job.TaskGroups {
+		if *tg.Name != alloc.TaskGroup {
+			continue
+		}
+		for _, task := range tg.Tasks {
+			tasks = append(tasks, task.Name)
+		}
+	}
+	sort.Strings(tasks)
+	return tasks
+}
+
+// allocExitCode returns the exit code reflecting the outcome of a finished
+// allocation: zero if it completed, the non-zero exit code of its first failed
+// task, or one otherwise.
+func allocExitCode(alloc *api.Allocation) int {
+	if alloc.ClientStatus == api.AllocClientStatusComplete {
+		return 0
+	}
+
+	tasks := make([]string, 0, len(alloc.TaskStates))
+	for task := range alloc.TaskStates {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+
+	for _, task := range tasks {
+		if state := alloc.TaskStates[task]; state.Failed {
+			if code := state.ExitCode(); code != 0 {
+				return code
+			}
+		}
+	}
+	return 1
+}
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestRunCommand_AllocExitCode(t *testing.T) {
+	t.Parallel()
+	terminated := func(code int) []*api.TaskEvent {
+		return []*api.TaskEvent{
+			{Type: api.TaskStarted},
+			{Type: api.TaskTerminated, ExitCode: code},
+			{Type: api.TaskNotRestarting},
+		}
+	}
+
+	cases := []struct {
+		Name     string
+		Alloc    *api.Allocation
+		Expected int
+	}{
+		{
+			Name:     "complete",
+			Alloc:    &api.Allocation{ClientStatus: api.AllocClientStatusComplete},
+			Expected: 0,
+		},
+		{
+			Name: "failed task",
+			Alloc: &api.Allocation{
+				ClientStatus: api.AllocClientStatusFailed,
+				TaskStates: map[string]*api.TaskState{
+					"a": {State: "dead", Events: terminated(0)},
+					"b": {State: "dead", Failed: true, Events: terminated(3)},
+				},
+			},
+			Expected: 3,
+		},
+		{
+			Name: "failed without exit code",
+			Alloc: &api.Allocation{
+				ClientStatus: api.AllocClientStatusFailed,
+				TaskStates: map[string]*api.TaskState{
+					"a": {State: "dead", Failed: true, Events: []*api.TaskEvent{{Type: api.TaskDriverFailure}}},
+				},
+			},
+			Expected: 1,
+		},
+		{
+			Name:     "lost",
+			Alloc:    &api.Allocation{ClientStatus: api.AllocClientStatusLost},
+			Expected: 1,
+		},
+	}
+
+	for _, c := range cases {
+		if code := allocExitCode(c.Alloc); code != c.Expected {
+			t.Fatalf("%s: expected exit code %d; got %d", c.Name, c.Expected, code)
+		}
+	}
+}
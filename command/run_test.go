@@ -154,6 +154,23 @@ job "job1" {
 	}
 	ui.ErrorWriter.Reset()
 
+	// Fails when following a service job (requires a valid job)
+	if code := cmd.Run([]string{"-follow", fh3.Name()}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "-follow can only be used with batch jobs") {
+		t.Fatalf("expected follow error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on a policy override without justification (requires a valid job)
+	if code := cmd.Run([]string{"-policy-override", fh3.Name()}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "requires a -policy-override-justification") {
+		t.Fatalf("expected justification error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
 }
 
 func TestRunCommand_From_STDIN(t *testing.T) {
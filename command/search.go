@@ -0,0 +1,148 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type SearchCommand struct {
+	Meta
+}
+
+func (c *SearchCommand) Help() string {
+	helpText := `
+Usage: nomad search [options] <text>
+
+  Search finds the allocations, deployments, jobs and nodes whose name or ID
+  contains the given text. Each match is displayed with its context, the type
+  of the object, so that it can be queried with the matching command.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Search Options:
+
+  -context
+    Only search objects of the given context. Must be one of allocs,
+    deployments, jobs or nodes.
+
+  -json
+    Output the matches in a JSON format.
+
+  -t
+    Format and display the matches using a Go template.
+
+  -verbose
+    Display full information.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SearchCommand) Synopsis() string {
+	return "Search for objects by name or ID"
+}
+
+func (c *SearchCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-context": complete.PredictSet("allocs", "deployments", "jobs", "nodes"),
+			"-json":    complete.PredictNothing,
+			"-t":       complete.PredictAnything,
+			"-verbose": complete.PredictNothing,
+		})
+}
+
+func (c *SearchCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *SearchCommand) Run(args []string) int {
+	var json, verbose bool
+	var context, tmpl string
+
+	flags := c.Meta.FlagSet("search", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&context, "context", "", "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+	flags.BoolVar(&verbose, "verbose", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one argument
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	text := args[0]
+
+	// Truncate the id unless full length is requested
+	length := shortId
+	if verbose {
+		length = fullId
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	resp, _, err := client.Search().FuzzySearch(text, context, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error searching: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, resp)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	contexts := make([]string, 0, len(resp.Matches))
+	for ctx, matches := range resp.Matches {
+		if len(matches) != 0 {
+			contexts = append(contexts, ctx)
+		}
+	}
+	if len(contexts) == 0 {
+		c.Ui.Output(fmt.Sprintf("No matches found for %q", text))
+		return 0
+	}
+	sort.Strings(contexts)
+
+	out := []string{"Context|ID|Name"}
+	for _, ctx := range contexts {
+		for _, match := range resp.Matches[ctx] {
+			// Job IDs are user given and never truncated
+			id := match.ID
+			if ctx != "jobs" {
+				id = limit(id, length)
+			}
+			out = append(out, fmt.Sprintf("%s|%s|%s", ctx, id, match.Name))
+		}
+	}
+	c.Ui.Output(formatList(out))
+
+	for _, ctx := range contexts {
+		if resp.Truncations[ctx] {
+			c.Ui.Output(c.Colorize().Color(fmt.Sprintf(
+				"\n[bold][yellow]Matches for context %q were truncated, refine the search text to see all of them[reset]", ctx)))
+		}
+	}
+	return 0
+}
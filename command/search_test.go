@@ -0,0 +1,95 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestSearchCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &SearchCommand{}
+}
+
+func TestSearchCommand_Fails(t *testing.T) {
+	t.Parallel()
+	srv, _, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &SearchCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "web"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error searching") {
+		t.Fatalf("expected failed search error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on an unknown context
+	if code := cmd.Run([]string{"-address=" + url, "-context=foo", "web"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "context must be one of") {
+		t.Fatalf("expected context error, got: %s", out)
+	}
+}
+
+func TestSearchCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &SearchCommand{Meta: Meta{Ui: ui}}
+
+	// No matches
+	if code := cmd.Run([]string{"-address=" + url, "web-"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "No matches found") {
+		t.Fatalf("expected no matches, got: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	for _, id := range []string{"web-frontend", "api"} {
+		if _, _, err := client.Jobs().Register(testJob(id), nil); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// Jobs are found as soon as they are registered, and matches are
+	// displayed with their context
+	if code := cmd.Run([]string{"-address=" + url, "web-"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "jobs") || !strings.Contains(out, "web-frontend") {
+		t.Fatalf("expected web-frontend job, got: %s", out)
+	}
+	if strings.Contains(out, "api") {
+		t.Fatalf("expected only web-frontend, got: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format the matches with a template
+	tmpl := `{{range .Matches.jobs}}{{.ID}}{{end}}`
+	if code := cmd.Run([]string{"-address=" + url, "-context=jobs", "-t", tmpl, "web-"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "web-frontend" {
+		t.Fatalf("expected web-frontend, got: %s", out)
+	}
+}
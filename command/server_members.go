@@ -31,6 +31,12 @@ Server Members Options:
     Show detailed information about each member. This dumps
     a raw set of tags which shows more information than the
     default output format.
+
+  -json
+    Output the server members in a JSON format.
+
+  -t
+    Format and display the server members using a Go template.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -40,11 +46,14 @@ func (c *ServerMembersCommand) Synopsis() string {
 }
 
 func (c *ServerMembersCommand) Run(args []string) int {
-	var detailed bool
+	var detailed, json bool
+	var tmpl string
 
 	flags := c.Meta.FlagSet("server-members", FlagSetClient)
 	flags.Usage = func() { c.Ui.Output(c.Help()) }
 	flags.BoolVar(&detailed, "detailed", false, "Show detailed output")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -79,6 +88,17 @@ func (c *ServerMembersCommand) Run(args []string) int {
 	// Sort the members
 	sort.Sort(api.AgentMembersNameSort(srvMembers.Members))
 
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, srvMembers.Members)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
 	// Determine the leaders per region.
 	leaders, err := regionLeaders(client, srvMembers.Members)
 	if err != nil {
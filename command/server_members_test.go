@@ -42,6 +42,15 @@ func TestServerMembersCommand_Run(t *testing.T) {
 	if out := ui.OutputWriter.String(); !strings.Contains(out, "Tags") {
 		t.Fatalf("expected tags in output, got: %s", out)
 	}
+	ui.OutputWriter.Reset()
+
+	// Format the members with a template
+	if code := cmd.Run([]string{"-address=" + url, "-t", "{{range .}}{{.Name}}{{end}}"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != name {
+		t.Fatalf("expected %q, got: %s", name, out)
+	}
 }
 
 func TestMembersCommand_Fails(t *testing.T) {
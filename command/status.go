@@ -8,6 +8,7 @@ import (
 
 	"github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/posener/complete"
 )
 
 const (
@@ -22,6 +23,22 @@ type StatusCommand struct {
 	evals     bool
 	allAllocs bool
 	verbose   bool
+	perPage   int
+	pageToken string
+	filter    string
+	namespace string
+	json      bool
+	tmpl      string
+}
+
+// jobStatus is the data formatted when the status of a single job is output
+// with the -json or -t flags.
+type jobStatus struct {
+	Job              *api.Job
+	Summary          *api.JobSummary
+	Allocations      []*api.AllocationListStub
+	Evaluations      []*api.Evaluation
+	LatestDeployment *api.Deployment
 }
 
 func (c *StatusCommand) Help() string {
@@ -50,6 +67,30 @@ Status Options:
 
   -verbose
     Display full information.
+
+  -per-page
+    How many jobs to list at a time when no job is given. Defaults to listing
+    all jobs.
+
+  -page-token
+    Where to start listing jobs from when -per-page is set. This is the token
+    printed with the previous page.
+
+  -filter
+    Only list the jobs matching the filter expression when no job is given,
+    e.g. 'Type == "batch"'.
+
+  -namespace
+    Only list the jobs of the namespace when no job is given. Defaults to
+    listing the jobs of all namespaces.
+
+  -json
+    Output the jobs in a JSON format. When a job is given, its summary,
+    allocations, evaluations and latest deployment are included.
+
+  -t
+    Format and display the jobs using a Go template. When a job is given, the
+    template is executed with the same data as the -json output.
 `
 	return strings.TrimSpace(helpText)
 }
@@ -58,6 +99,26 @@ func (c *StatusCommand) Synopsis() string {
 	return "Display status information about jobs"
 }
 
+func (c *StatusCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-short":      complete.PredictNothing,
+			"-evals":      complete.PredictNothing,
+			"-all-allocs": complete.PredictNothing,
+			"-verbose":    complete.PredictNothing,
+			"-per-page":   complete.PredictAnything,
+			"-page-token": complete.PredictAnything,
+			"-filter":     complete.PredictAnything,
+			"-namespace":  c.PredictNamespaces(),
+			"-json":       complete.PredictNothing,
+			"-t":          complete.PredictAnything,
+		})
+}
+
+func (c *StatusCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
 func (c *StatusCommand) Run(args []string) int {
 	var short bool
 
@@ -67,6 +128,12 @@ func (c *StatusCommand) Run(args []string) int {
 	flags.BoolVar(&c.evals, "evals", false, "")
 	flags.BoolVar(&c.allAllocs, "all-allocs", false, "")
 	flags.BoolVar(&c.verbose, "verbose", false, "")
+	flags.IntVar(&c.perPage, "per-page", 0, "")
+	flags.StringVar(&c.pageToken, "page-token", "", "")
+	flags.StringVar(&c.filter, "filter", "", "")
+	flags.StringVar(&c.namespace, "namespace", "", "")
+	flags.BoolVar(&c.json, "json", false, "")
+	flags.StringVar(&c.tmpl, "t", "", "")
 
 	if err := flags.Parse(args); err != nil {
 		return 1
@@ -94,18 +161,38 @@ func (c *StatusCommand) Run(args []string) int {
 
 	// Invoke list mode if no job ID.
 	if len(args) == 0 {
-		jobs, _, err := client.Jobs().List(nil)
+		q := &api.QueryOptions{
+			PerPage:   int32(c.perPage),
+			NextToken: c.pageToken,
+			Filter:    c.filter,
+			Namespace: c.namespace,
+		}
+		jobs, qm, err := client.Jobs().List(q)
 		if err != nil {
 			c.Ui.Error(fmt.Sprintf("Error querying jobs: %s", err))
 			return 1
 		}
 
+		if c.json || len(c.tmpl) > 0 {
+			out, err := Format(c.json, c.tmpl, jobs)
+			if err != nil {
+				c.Ui.Error(err.Error())
+				return 1
+			}
+
+			c.Ui.Output(out)
+			return 0
+		}
+
 		if len(jobs) == 0 {
 			// No output if we have no jobs
 			c.Ui.Output("No running jobs")
 		} else {
 			c.Ui.Output(createStatusListOutput(jobs))
 		}
+		if next := formatNextPage("status", c.perPage, qm.NextToken); next != "" {
+			c.Ui.Output(next)
+		}
 		return 0
 	}
 
@@ -131,6 +218,23 @@ func (c *StatusCommand) Run(args []string) int {
 		return 1
 	}
 
+	if c.json || len(c.tmpl) > 0 {
+		status, err := c.jobStatus(client, job)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		out, err := Format(c.json, c.tmpl, status)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
 	periodic := job.IsPeriodic()
 	parameterized := job.IsParameterized()
 
@@ -138,6 +242,7 @@ func (c *StatusCommand) Run(args []string) int {
 	basic := []string{
 		fmt.Sprintf("ID|%s", *job.ID),
 		fmt.Sprintf("Name|%s", *job.Name),
+		fmt.Sprintf("Namespace|%s", *job.Namespace),
 		fmt.Sprintf("Submit Date|%s", formatTime(getSubmitTime(job))),
 		fmt.Sprintf("Type|%s", *job.Type),
 		fmt.Sprintf("Priority|%d", *job.Priority),
@@ -190,6 +295,38 @@ func (c *StatusCommand) Run(args []string) int {
 	return 0
 }
 
+// jobStatus queries the data formatted with the -json or -t flags for the
+// passed job. If a request fails, an error is returned.
+func (c *StatusCommand) jobStatus(client *api.Client, job *api.Job) (*jobStatus, error) {
+	summary, _, err := client.Jobs().Summary(*job.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying job summary: %s", err)
+	}
+
+	allocs, _, err := client.Jobs().Allocations(*job.ID, c.allAllocs, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying job allocations: %s", err)
+	}
+
+	evals, _, err := client.Jobs().Evaluations(*job.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying job evaluations: %s", err)
+	}
+
+	latestDeployment, _, err := client.Jobs().LatestDeployment(*job.ID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying latest job deployment: %s", err)
+	}
+
+	return &jobStatus{
+		Job:              job,
+		Summary:          summary,
+		Allocations:      allocs,
+		Evaluations:      evals,
+		LatestDeployment: latestDeployment,
+	}, nil
+}
+
 // outputPeriodicInfo prints information about the passed periodic job. If a
 // request fails, an error is returned.
 func (c *StatusCommand) outputPeriodicInfo(client *api.Client, job *api.Job) error {
@@ -302,6 +439,11 @@ func (c *StatusCommand) outputJobInfo(client *api.Client, job *api.Job) error {
 		return err
 	}
 
+	// Output the status of the jobs the job depends on
+	if err := c.outputJobDependencies(client, job); err != nil {
+		return err
+	}
+
 	// Determine latest evaluation with failures whose follow up hasn't
 	// completed, this is done while formatting
 	var latestFailedPlacement *api.Evaluation
@@ -354,6 +496,33 @@ func (c *StatusCommand) outputJobInfo(client *api.Client, job *api.Job) error {
 	return nil
 }
 
+// outputJobDependencies prints the status of the jobs the passed job depends
+// on. If a request fails, an error is returned.
+func (c *StatusCommand) outputJobDependencies(client *api.Client, job *api.Job) error {
+	if len(job.DependsOn) == 0 {
+		return nil
+	}
+
+	nodes, _, err := client.Jobs().Dependencies(*job.ID, nil)
+	if err != nil {
+		return fmt.Errorf("Error querying job dependencies: %s", err)
+	}
+	statuses := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		statuses[node.JobID] = node.Status
+	}
+
+	out := make([]string, len(job.DependsOn)+1)
+	out[0] = "ID|Status"
+	for i, id := range job.DependsOn {
+		out[i+1] = fmt.Sprintf("%s|%s", id, statuses[id])
+	}
+
+	c.Ui.Output(c.Colorize().Color("\n[bold]Dependencies[reset]"))
+	c.Ui.Output(formatList(out))
+	return nil
+}
+
 func (c *StatusCommand) formatDeployment(d *api.Deployment) string {
 	// Format the high-level elements
 	high := []string{
@@ -463,7 +632,7 @@ func (c *StatusCommand) outputJobSummary(client *api.Client, job *api.Job) error
 	if !periodic && !parameterizedJob {
 		c.Ui.Output(c.Colorize().Color("\n[bold]Summary[reset]"))
 		summaries := make([]string, len(summary.Summary)+1)
-		summaries[0] = "Task Group|Queued|Starting|Running|Failed|Complete|Lost"
+		summaries[0] = "Task Group|Queued|Starting|Running|Failed|Complete|Lost|Unknown"
 		taskGroups := make([]string, 0, len(summary.Summary))
 		for taskGroup := range summary.Summary {
 			taskGroups = append(taskGroups, taskGroup)
@@ -471,10 +640,10 @@ func (c *StatusCommand) outputJobSummary(client *api.Client, job *api.Job) error
 		sort.Strings(taskGroups)
 		for idx, taskGroup := range taskGroups {
 			tgs := summary.Summary[taskGroup]
-			summaries[idx+1] = fmt.Sprintf("%s|%d|%d|%d|%d|%d|%d",
+			summaries[idx+1] = fmt.Sprintf("%s|%d|%d|%d|%d|%d|%d|%d",
 				taskGroup, tgs.Queued, tgs.Starting,
 				tgs.Running, tgs.Failed,
-				tgs.Complete, tgs.Lost,
+				tgs.Complete, tgs.Lost, tgs.Unknown,
 			)
 		}
 		c.Ui.Output(formatList(summaries))
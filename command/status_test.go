@@ -1,11 +1,14 @@
 package command
 
 import (
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/nomad/api"
 	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
 )
 
 func TestStatusCommand_Implements(t *testing.T) {
@@ -193,3 +196,72 @@ func waitForSuccess(ui cli.Ui, client *api.Client, length int, t *testing.T, eva
 	monErr := mon.monitor(evalId, false)
 	return monErr
 }
+
+func TestStatusCommand_AutocompleteArgs(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &StatusCommand{Meta: Meta{Ui: ui, flagAddress: url}}
+
+	for _, id := range []string{"job1_sfx", "job2_sfx", "other"} {
+		if _, _, err := client.Jobs().Register(testJob(id), nil); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	predictor := cmd.AutocompleteArgs()
+	res := predictor.Predict(complete.Args{Last: "job"})
+	sort.Strings(res)
+	if expected := []string{"job1_sfx", "job2_sfx"}; !reflect.DeepEqual(res, expected) {
+		t.Fatalf("expected %v; got %v", expected, res)
+	}
+
+	// An unreachable agent results in no predictions
+	cmd.Meta.flagAddress = "http://127.0.0.1:1"
+	if res := predictor.Predict(complete.Args{Last: "job"}); len(res) != 0 {
+		t.Fatalf("expected no predictions; got %v", res)
+	}
+}
+
+func TestStatusCommand_Format(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &StatusCommand{Meta: Meta{Ui: ui}}
+
+	job := testJob("job1_sfx")
+	if _, _, err := client.Jobs().Register(job, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// List the jobs in JSON
+	if code := cmd.Run([]string{"-address=" + url, "-json"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, `"ID": "job1_sfx"`) {
+		t.Fatalf("expected JSON job list, got: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Format a single job with a template
+	tmpl := "{{.Job.ID}} {{.Summary.JobID}}"
+	if code := cmd.Run([]string{"-address=" + url, "-t", tmpl, "job1"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d", code)
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "job1_sfx job1_sfx" {
+		t.Fatalf("expected templated job status, got: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Fails if both formats are given
+	if code := cmd.Run([]string{"-address=" + url, "-json", "-t", tmpl}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Both json and template") {
+		t.Fatalf("expected format error, got: %s", out)
+	}
+}
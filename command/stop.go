@@ -3,6 +3,8 @@ package command
 import (
 	"fmt"
 	"strings"
+
+	"github.com/posener/complete"
 )
 
 type StopCommand struct {
@@ -48,6 +50,20 @@ func (c *StopCommand) Synopsis() string {
 	return "Stop a running job"
 }
 
+func (c *StopCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-detach":  complete.PredictNothing,
+			"-verbose": complete.PredictNothing,
+			"-yes":     complete.PredictNothing,
+			"-purge":   complete.PredictNothing,
+		})
+}
+
+func (c *StopCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictJobs()
+}
+
 func (c *StopCommand) Run(args []string) int {
 	var detach, purge, verbose, autoYes bool
 
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+type SystemCommand struct {
+	Meta
+}
+
+func (c *SystemCommand) Help() string {
+	helpText := `
+Usage: nomad system <subcommand> [options]
+
+  Provides access to the maintenance operations of the Nomad servers, such as
+  forcing a garbage collection or reconciling the summaries of the jobs. These
+  operations can be used to recover from drifted state.
+
+  Run nomad system <subcommand> with no arguments for help on that subcommand.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SystemCommand) Synopsis() string {
+	return "Interact with the system maintenance API"
+}
+
+func (c *SystemCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// confirm asks the user to confirm the operation described by the question.
+// It returns whether to proceed and, if not, the exit code to return.
+func (c *SystemCommand) confirm(question, cancel string) (bool, int) {
+	answer, err := c.Ui.Ask(question + " [y/N]")
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse answer: %v", err))
+		return false, 1
+	}
+
+	if answer == "" || strings.ToLower(answer)[0] == 'n' {
+		// No case
+		c.Ui.Output(cancel)
+		return false, 0
+	} else if strings.ToLower(answer)[0] == 'y' && len(answer) > 1 {
+		// Non exact match yes
+		c.Ui.Output("For confirmation, an exact ‘y’ is required.")
+		return false, 0
+	} else if answer != "y" {
+		c.Ui.Output("No confirmation detected. For confirmation, an exact 'y' is required.")
+		return false, 1
+	}
+	return true, 0
+}
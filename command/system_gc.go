@@ -0,0 +1,128 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type SystemGCCommand struct {
+	SystemCommand
+}
+
+func (c *SystemGCCommand) Help() string {
+	helpText := `
+Usage: nomad system gc [options]
+
+  Forces a garbage collection of the terminal objects of the cluster, ignoring
+  the configured garbage collection thresholds. By default jobs, evaluations,
+  deployments and nodes are all collected. The scope flags limit the collection
+  to the given object types.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+GC Options:
+
+  -jobs
+    Garbage collect the stopped and dead jobs.
+
+  -evals
+    Garbage collect the terminal evaluations and allocations.
+
+  -deployments
+    Garbage collect the terminal deployments.
+
+  -nodes
+    Garbage collect the down nodes without allocations.
+
+  -yes
+    Automatic yes to prompts.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SystemGCCommand) Synopsis() string {
+	return "Force a garbage collection"
+}
+
+func (c *SystemGCCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-jobs":        complete.PredictNothing,
+			"-evals":       complete.PredictNothing,
+			"-deployments": complete.PredictNothing,
+			"-nodes":       complete.PredictNothing,
+			"-yes":         complete.PredictNothing,
+		})
+}
+
+func (c *SystemGCCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *SystemGCCommand) Run(args []string) int {
+	var jobs, evals, deployments, nodes, autoYes bool
+
+	flags := c.Meta.FlagSet("system gc", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&jobs, "jobs", false, "")
+	flags.BoolVar(&evals, "evals", false, "")
+	flags.BoolVar(&deployments, "deployments", false, "")
+	flags.BoolVar(&nodes, "nodes", false, "")
+	flags.BoolVar(&autoYes, "yes", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	// Build the scopes in the order they are collected
+	var scopes []string
+	for _, scope := range []struct {
+		name string
+		set  bool
+	}{
+		{"jobs", jobs},
+		{"evals", evals},
+		{"deployments", deployments},
+		{"nodes", nodes},
+	} {
+		if scope.set {
+			scopes = append(scopes, scope.name)
+		}
+	}
+
+	if !autoYes {
+		target := "all terminal objects"
+		if len(scopes) != 0 {
+			target = "the terminal " + strings.Join(scopes, ", ")
+		}
+		question := fmt.Sprintf("Are you sure you want to garbage collect %s?", target)
+		if ok, code := c.confirm(question, "Cancelling garbage collection"); !ok {
+			return code
+		}
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if err := client.System().GarbageCollectScopes(scopes, nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error running garbage collection: %s", err))
+		return 1
+	}
+
+	c.Ui.Output("Garbage collection started")
+	return 0
+}
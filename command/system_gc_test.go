@@ -0,0 +1,71 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestSystemGCCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &SystemGCCommand{}
+}
+
+func TestSystemGCCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &SystemGCCommand{SystemCommand: SystemCommand{Meta: Meta{Ui: ui}}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"some", "bad", "args"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "-yes"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error running garbage collection") {
+		t.Fatalf("expected failed garbage collection error, got: %s", out)
+	}
+}
+
+func TestSystemGCCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, _, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &SystemGCCommand{SystemCommand: SystemCommand{Meta: Meta{Ui: ui}}}
+
+	if code := cmd.Run([]string{"-address=" + url, "-yes", "-jobs", "-nodes"}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Garbage collection started") {
+		t.Fatalf("bad output: %s", out)
+	}
+}
+
+func TestSystemGCCommand_Confirm(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	ui.InputReader = strings.NewReader("n\n")
+	cmd := &SystemGCCommand{SystemCommand: SystemCommand{Meta: Meta{Ui: ui}}}
+
+	// Declining the prompt doesn't contact the servers
+	if code := cmd.Run([]string{"-address=nope", "-evals"}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "the terminal evals?") {
+		t.Fatalf("expected the scopes in the prompt, got: %s", out)
+	}
+	if !strings.Contains(out, "Cancelling garbage collection") {
+		t.Fatalf("expected cancellation, got: %s", out)
+	}
+}
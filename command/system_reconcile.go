@@ -0,0 +1,31 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+type SystemReconcileCommand struct {
+	Meta
+}
+
+func (c *SystemReconcileCommand) Help() string {
+	helpText := `
+Usage: nomad system reconcile <subcommand> [options]
+
+  Reconciles the state of the Nomad servers when it has drifted.
+
+  Run nomad system reconcile <subcommand> with no arguments for help on that
+  subcommand.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SystemReconcileCommand) Synopsis() string {
+	return "Reconcile drifted system state"
+}
+
+func (c *SystemReconcileCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/posener/complete"
+)
+
+type SystemReconcileSummariesCommand struct {
+	SystemCommand
+}
+
+func (c *SystemReconcileSummariesCommand) Help() string {
+	helpText := `
+Usage: nomad system reconcile summaries [options]
+
+  Recomputes the summaries of every job from their allocations. The summaries
+  count the allocations of each task group by status and can drift from the
+  actual allocations, for example after a bug or an unclean upgrade.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Reconcile Summaries Options:
+
+  -yes
+    Automatic yes to prompts.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SystemReconcileSummariesCommand) Synopsis() string {
+	return "Reconcile the summaries of all jobs"
+}
+
+func (c *SystemReconcileSummariesCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-yes": complete.PredictNothing,
+		})
+}
+
+func (c *SystemReconcileSummariesCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *SystemReconcileSummariesCommand) Run(args []string) int {
+	var autoYes bool
+
+	flags := c.Meta.FlagSet("system reconcile summaries", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&autoYes, "yes", false, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	if !autoYes {
+		question := "Are you sure you want to reconcile the summaries of all jobs?"
+		if ok, code := c.confirm(question, "Cancelling reconciliation"); !ok {
+			return code
+		}
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	if err := client.System().ReconcileSummaries(nil); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reconciling job summaries: %s", err))
+		return 1
+	}
+
+	c.Ui.Output("Successfully reconciled job summaries")
+	return 0
+}
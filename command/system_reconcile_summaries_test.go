@@ -0,0 +1,44 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestSystemReconcileSummariesCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &SystemReconcileSummariesCommand{}
+}
+
+func TestSystemReconcileSummariesCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, _, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &SystemReconcileSummariesCommand{SystemCommand: SystemCommand{Meta: Meta{Ui: ui}}}
+
+	if code := cmd.Run([]string{"-address=" + url, "-yes"}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "Successfully reconciled") {
+		t.Fatalf("bad output: %s", out)
+	}
+}
+
+func TestSystemReconcileSummariesCommand_Confirm(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	ui.InputReader = strings.NewReader("yes\n")
+	cmd := &SystemReconcileSummariesCommand{SystemCommand: SystemCommand{Meta: Meta{Ui: ui}}}
+
+	// A non exact yes cancels the reconciliation
+	if code := cmd.Run([]string{"-address=nope"}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "exact ‘y’ is required") {
+		t.Fatalf("expected confirmation output, got: %s", out)
+	}
+}
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+const (
+	// tlsCAFile and tlsCAKeyFile are the files the CA created by the tls
+	// commands is written to and read from by default
+	tlsCAFile    = "nomad-agent-ca.pem"
+	tlsCAKeyFile = "nomad-agent-ca-key.pem"
+)
+
+type TLSCommand struct {
+	Meta
+}
+
+func (c *TLSCommand) Help() string {
+	helpText := `
+Usage: nomad tls <subcommand> [options]
+
+  Provides tools to create a certificate authority and the certificates of
+  the agents and operators of a cluster, to secure its RPC and HTTP traffic
+  with mutual TLS.
+
+  Agents reload their certificate, key and CA files when they are modified,
+  so certificates can be renewed by overwriting the configured files without
+  restarting the agents.
+
+  Create a CA:
+
+      $ nomad tls ca create
+
+  Create a server certificate for the global region:
+
+      $ nomad tls cert create -server
+
+  Run nomad tls <subcommand> with no arguments for help on that subcommand.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *TLSCommand) Synopsis() string {
+	return "Generate certificates for mutual TLS"
+}
+
+func (c *TLSCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// writeTLSFiles writes the PEM encoded certificate and key to their files,
+// refusing to overwrite existing files. Keys are only readable by their
+// owner.
+func writeTLSFiles(ui cli.Ui, certFile, cert, keyFile, key string) bool {
+	for _, path := range []string{certFile, keyFile} {
+		if _, err := os.Stat(path); err == nil {
+			ui.Error(fmt.Sprintf("File %q already exists", path))
+			return false
+		} else if !os.IsNotExist(err) {
+			ui.Error(fmt.Sprintf("Error checking %q: %s", path, err))
+			return false
+		}
+	}
+
+	if err := ioutil.WriteFile(certFile, []byte(cert), 0644); err != nil {
+		ui.Error(fmt.Sprintf("Error writing %q: %s", certFile, err))
+		return false
+	}
+	ui.Output(fmt.Sprintf("==> Certificate saved to %s", certFile))
+	if err := ioutil.WriteFile(keyFile, []byte(key), 0600); err != nil {
+		ui.Error(fmt.Sprintf("Error writing %q: %s", keyFile, err))
+		return false
+	}
+	ui.Output(fmt.Sprintf("==> Key saved to %s", keyFile))
+	return true
+}
@@ -0,0 +1,31 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+type TLSCACommand struct {
+	Meta
+}
+
+func (c *TLSCACommand) Help() string {
+	helpText := `
+Usage: nomad tls ca <subcommand> [options]
+
+  Manages the certificate authority signing the certificates of the agents
+  and operators of a cluster.
+
+  Run nomad tls ca <subcommand> with no arguments for help on that subcommand.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *TLSCACommand) Synopsis() string {
+	return "Manage the certificate authority"
+}
+
+func (c *TLSCACommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
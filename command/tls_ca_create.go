@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/helper/tlsutil"
+)
+
+type TLSCACreateCommand struct {
+	Meta
+}
+
+func (c *TLSCACreateCommand) Help() string {
+	helpText := `
+Usage: nomad tls ca create [options]
+
+  Creates a certificate authority to sign the certificates of the agents and
+  operators of a cluster. The CA certificate is written to
+  nomad-agent-ca.pem and its key to nomad-agent-ca-key.pem in the current
+  directory. Existing files are not overwritten.
+
+  The key of the CA must be kept secret: anyone holding it can create
+  certificates trusted by the cluster.
+
+CA Create Options:
+
+  -common-name=<name>
+    The common name of the CA certificate. Defaults to "Nomad Agent CA".
+
+  -days=<days>
+    The number of days the CA certificate is valid for. Defaults to 1825.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *TLSCACreateCommand) Synopsis() string {
+	return "Create a certificate authority"
+}
+
+func (c *TLSCACreateCommand) Run(args []string) int {
+	var commonName string
+	var days int
+
+	flags := c.Meta.FlagSet("tls ca create", FlagSetNone)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&commonName, "common-name", "Nomad Agent CA", "")
+	flags.IntVar(&days, "days", 1825, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	if days <= 0 {
+		c.Ui.Error("Days must be positive")
+		return 1
+	}
+
+	ca, key, err := tlsutil.GenerateCA(tlsutil.CAOpts{
+		CommonName: commonName,
+		Validity:   time.Duration(days) * 24 * time.Hour,
+	})
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating CA: %s", err))
+		return 1
+	}
+
+	if !writeTLSFiles(c.Ui, tlsCAFile, ca, tlsCAKeyFile, key) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+type TLSCertCommand struct {
+	Meta
+}
+
+func (c *TLSCertCommand) Help() string {
+	helpText := `
+Usage: nomad tls cert <subcommand> [options]
+
+  Manages the certificates of the agents and operators of a cluster.
+
+  Run nomad tls cert <subcommand> with no arguments for help on that
+  subcommand.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *TLSCertCommand) Synopsis() string {
+	return "Manage agent and operator certificates"
+}
+
+func (c *TLSCertCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
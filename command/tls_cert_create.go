@@ -0,0 +1,170 @@
+package command
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	flaghelper "github.com/hashicorp/nomad/helper/flag-helpers"
+	"github.com/hashicorp/nomad/helper/tlsutil"
+)
+
+type TLSCertCreateCommand struct {
+	Meta
+}
+
+func (c *TLSCertCreateCommand) Help() string {
+	helpText := `
+Usage: nomad tls cert create [options]
+
+  Creates a certificate signed by the CA created with "nomad tls ca create",
+  for a server or client agent or for an operator using the CLI. The
+  certificate is written to <region>-<type>-nomad.pem and its key to
+  <region>-<type>-nomad-key.pem in the current directory. Existing files are
+  not overwritten.
+
+  Server certificates are valid for server.<region>.nomad and client
+  certificates for client.<region>.nomad, the names verified by agents when
+  verify_server_hostname is enabled. Both are also valid for localhost and
+  127.0.0.1.
+
+  To renew a certificate, create a new one and move it and its key over the
+  files configured in the tls block of the agent. Agents load the renewed
+  files without being restarted.
+
+Cert Create Options:
+
+  -server
+    Create a certificate for a server agent.
+
+  -client
+    Create a certificate for a client agent.
+
+  -cli
+    Create a certificate for an operator using the CLI or HTTP API.
+
+  -region=<region>
+    The region of the agent. Defaults to "global".
+
+  -days=<days>
+    The number of days the certificate is valid for. Defaults to 365.
+
+  -ca=<path>
+    The CA certificate. Defaults to nomad-agent-ca.pem.
+
+  -key=<path>
+    The key of the CA. Defaults to nomad-agent-ca-key.pem.
+
+  -additional-dnsname=<name>
+    An additional DNS name the certificate is valid for, for example the
+    address of a load balancer. May be specified multiple times.
+
+  -additional-ipaddress=<ip>
+    An additional IP address the certificate is valid for. May be specified
+    multiple times.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *TLSCertCreateCommand) Synopsis() string {
+	return "Create a certificate signed by the CA"
+}
+
+func (c *TLSCertCreateCommand) Run(args []string) int {
+	var server, client, cli bool
+	var region, caFile, keyFile string
+	var days int
+	var dnsNames, ipAddresses flaghelper.StringFlag
+
+	flags := c.Meta.FlagSet("tls cert create", FlagSetNone)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.BoolVar(&server, "server", false, "")
+	flags.BoolVar(&client, "client", false, "")
+	flags.BoolVar(&cli, "cli", false, "")
+	flags.StringVar(&region, "region", "global", "")
+	flags.IntVar(&days, "days", 365, "")
+	flags.StringVar(&caFile, "ca", tlsCAFile, "")
+	flags.StringVar(&keyFile, "key", tlsCAKeyFile, "")
+	flags.Var(&dnsNames, "additional-dnsname", "")
+	flags.Var(&ipAddresses, "additional-ipaddress", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got no arguments
+	if len(flags.Args()) != 0 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	var typ string
+	var usage []x509.ExtKeyUsage
+	switch {
+	case server && !client && !cli:
+		typ = "server"
+		usage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	case client && !server && !cli:
+		// Clients serve the HTTP API and connect to the servers
+		typ = "client"
+		usage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	case cli && !server && !client:
+		typ = "cli"
+		usage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	default:
+		c.Ui.Error("Exactly one of -server, -client or -cli must be set")
+		return 1
+	}
+	if region == "" {
+		c.Ui.Error("Region must be set")
+		return 1
+	}
+	if days <= 0 {
+		c.Ui.Error("Days must be positive")
+		return 1
+	}
+
+	name := fmt.Sprintf("%s.%s.nomad", typ, region)
+	opts := tlsutil.CertOpts{
+		CommonName:  name,
+		DNSNames:    append([]string{name, "localhost"}, dnsNames...),
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: usage,
+		Validity:    time.Duration(days) * 24 * time.Hour,
+	}
+	for _, addr := range ipAddresses {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			c.Ui.Error(fmt.Sprintf("Invalid IP address %q", addr))
+			return 1
+		}
+		opts.IPAddresses = append(opts.IPAddresses, ip)
+	}
+
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading CA certificate: %s", err))
+		return 1
+	}
+	caKey, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading CA key: %s", err))
+		return 1
+	}
+	opts.CA = string(ca)
+	opts.CAKey = string(caKey)
+
+	cert, key, err := tlsutil.GenerateCert(opts)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error creating certificate: %s", err))
+		return 1
+	}
+
+	prefix := fmt.Sprintf("%s-%s-nomad", region, typ)
+	if !writeTLSFiles(c.Ui, prefix+".pem", cert, prefix+"-key.pem", key) {
+		return 1
+	}
+	return 0
+}
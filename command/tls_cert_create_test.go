@@ -0,0 +1,63 @@
+package command
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestTLSCertCreateCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &TLSCACreateCommand{}
+	var _ cli.Command = &TLSCertCreateCommand{}
+}
+
+func TestTLSCertCreateCommand(t *testing.T) {
+	// The files are written to the working directory
+	dir, err := ioutil.TempDir("", "nomad-tls")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	ui := new(cli.MockUi)
+	if code := (&TLSCACreateCommand{Meta: Meta{Ui: ui}}).Run(nil); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+
+	cmd := &TLSCertCreateCommand{Meta: Meta{Ui: ui}}
+	if code := cmd.Run([]string{"-server", "-client"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if code := cmd.Run([]string{"-server", "-region=eu", "-additional-dnsname=nomad.example.com"}); code != 0 {
+		t.Fatalf("expected exit code 0, got: %d %s", code, ui.ErrorWriter.String())
+	}
+	pair, err := tls.LoadX509KeyPair("eu-server-nomad.pem", "eu-server-nomad-key.pem")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pair.Certificate == nil {
+		t.Fatalf("expected certificate")
+	}
+
+	// Existing certificates aren't overwritten
+	ui.ErrorWriter.Reset()
+	if code := cmd.Run([]string{"-server", "-region=eu"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "already exists") {
+		t.Fatalf("expected overwrite error, got: %s", out)
+	}
+}
@@ -129,6 +129,6 @@ func (c *ValidateCommand) validateLocal(aj *api.Job) (*api.JobValidateResponse,
 	}
 
 	warnings := job.Warnings()
-	out.Warnings = structs.MergeMultierrorWarnings(warnings, canonicalizeWarnings)
+	out.Warnings = structs.MergeMultierrorWarnings(canonicalizeWarnings, warnings)
 	return &out, nil
 }
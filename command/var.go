@@ -0,0 +1,21 @@
+package command
+
+import (
+	"github.com/mitchellh/cli"
+)
+
+type VarCommand struct {
+	Meta
+}
+
+func (f *VarCommand) Help() string {
+	return "This command is accessed by using one of the subcommands below."
+}
+
+func (f *VarCommand) Synopsis() string {
+	return "Interact with variables"
+}
+
+func (f *VarCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
@@ -0,0 +1,97 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type VarDeleteCommand struct {
+	Meta
+}
+
+func (c *VarDeleteCommand) Help() string {
+	helpText := `
+Usage: nomad var delete [options] <path>
+
+Delete is used to remove a variable.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Delete Options:
+
+  -namespace
+    The namespace of the variable. Defaults to the default namespace.
+
+  -cas
+    Only delete the variable if its modify index is the given index.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VarDeleteCommand) Synopsis() string {
+	return "Delete a variable"
+}
+
+func (c *VarDeleteCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-namespace": c.PredictNamespaces(),
+			"-cas":       complete.PredictAnything,
+		})
+}
+
+func (c *VarDeleteCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *VarDeleteCommand) Run(args []string) int {
+	var namespace string
+	var cas int64
+
+	flags := c.Meta.FlagSet("var delete", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&namespace, "namespace", "", "")
+	flags.Int64Var(&cas, "cas", -1, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one path
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	path := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	var resp *api.VariableWriteResponse
+	if cas >= 0 {
+		resp, _, err = client.Variables().CheckedDelete(namespace, path, uint64(cas), nil)
+	} else {
+		resp, _, err = client.Variables().Delete(namespace, path, nil)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error deleting variable: %s", err))
+		return 1
+	}
+	if !resp.Updated {
+		c.Ui.Error(formatVariableConflict(path, resp.Conflict))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully deleted variable %q!", path))
+	return 0
+}
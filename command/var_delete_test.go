@@ -0,0 +1,43 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestVarDeleteCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &VarDeleteCommand{}
+}
+
+func TestVarDeleteCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	v := &api.Variable{Path: "app/db", Items: map[string]string{"user": "app"}}
+	if _, _, err := client.Variables().Put(v, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := new(cli.MockUi)
+	cmd := &VarDeleteCommand{Meta: Meta{Ui: ui}}
+
+	// A check-and-set delete fails with a stale index
+	if code := cmd.Run([]string{"-address=" + url, "-cas=1", "app/db"}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Check-and-set failed") {
+		t.Fatalf("expected check-and-set error, got: %s", out)
+	}
+
+	if code := cmd.Run([]string{"-address=" + url, "app/db"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if _, _, err := client.Variables().Read("", "app/db", nil); err == nil {
+		t.Fatalf("expected variable to be deleted")
+	}
+}
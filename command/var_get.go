@@ -0,0 +1,142 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type VarGetCommand struct {
+	Meta
+}
+
+func (c *VarGetCommand) Help() string {
+	helpText := `
+Usage: nomad var get [options] <path>
+
+Get is used to read a variable and its items.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Get Options:
+
+  -namespace
+    The namespace of the variable. Defaults to the default namespace.
+
+  -item
+    Only output the value of the given item.
+
+  -json
+    Output the variable in a JSON format.
+
+  -t
+    Format and display the variable using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VarGetCommand) Synopsis() string {
+	return "Read a variable"
+}
+
+func (c *VarGetCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-namespace": c.PredictNamespaces(),
+			"-item":      complete.PredictAnything,
+			"-json":      complete.PredictNothing,
+			"-t":         complete.PredictAnything,
+		})
+}
+
+func (c *VarGetCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *VarGetCommand) Run(args []string) int {
+	var namespace, item, tmpl string
+	var json bool
+
+	flags := c.Meta.FlagSet("var get", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&namespace, "namespace", "", "")
+	flags.StringVar(&item, "item", "", "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got exactly one path
+	args = flags.Args()
+	if len(args) != 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	path := args[0]
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	v, _, err := client.Variables().Read(namespace, path, nil)
+	if err != nil {
+		if api.IsNotFound(err) {
+			c.Ui.Error(fmt.Sprintf("Variable %q not found", path))
+			return 1
+		}
+		c.Ui.Error(fmt.Sprintf("Error retrieving variable: %s", err))
+		return 1
+	}
+
+	if item != "" {
+		value, ok := v.Items[item]
+		if !ok {
+			c.Ui.Error(fmt.Sprintf("Variable %q has no item %q", path, item))
+			return 1
+		}
+		c.Ui.Output(value)
+		return 0
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, v)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	basic := []string{
+		fmt.Sprintf("Namespace|%s", v.Namespace),
+		fmt.Sprintf("Path|%s", v.Path),
+		fmt.Sprintf("Create Index|%d", v.CreateIndex),
+		fmt.Sprintf("Modify Index|%d", v.ModifyIndex),
+	}
+	c.Ui.Output(formatKV(basic))
+
+	keys := make([]string, 0, len(v.Items))
+	for k := range v.Items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, fmt.Sprintf("%s|%s", k, v.Items[k]))
+	}
+	c.Ui.Output(c.Colorize().Color("\n[bold]Items[reset]"))
+	c.Ui.Output(formatKV(items))
+	return 0
+}
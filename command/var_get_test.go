@@ -0,0 +1,52 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestVarGetCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &VarGetCommand{}
+}
+
+func TestVarGetCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &VarGetCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on missing variables
+	if code := cmd.Run([]string{"-address=" + url, "app/db"}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "not found") {
+		t.Fatalf("expected not found error, got: %s", out)
+	}
+
+	v := &api.Variable{Path: "app/db", Items: map[string]string{"user": "app"}}
+	if _, _, err := client.Variables().Put(v, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if code := cmd.Run([]string{"-address=" + url, "app/db"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "app/db") || !strings.Contains(out, "user") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	// Output a single item
+	if code := cmd.Run([]string{"-address=" + url, "-item=user", "app/db"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); out != "app\n" {
+		t.Fatalf("bad: %q", out)
+	}
+}
@@ -0,0 +1,124 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type VarListCommand struct {
+	Meta
+}
+
+func (c *VarListCommand) Help() string {
+	helpText := `
+Usage: nomad var list [options] [<prefix>]
+
+List is used to list the variables of a namespace, optionally filtered by
+path prefix. The items of the variables are not displayed.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+List Options:
+
+  -namespace
+    The namespace of the variables. Defaults to the default namespace.
+
+  -json
+    Output the variables in a JSON format.
+
+  -t
+    Format and display the variables using a Go template.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VarListCommand) Synopsis() string {
+	return "List variables"
+}
+
+func (c *VarListCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-namespace": c.PredictNamespaces(),
+			"-json":      complete.PredictNothing,
+			"-t":         complete.PredictAnything,
+		})
+}
+
+func (c *VarListCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *VarListCommand) Run(args []string) int {
+	var namespace, tmpl string
+	var json bool
+
+	flags := c.Meta.FlagSet("var list", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&namespace, "namespace", "", "")
+	flags.BoolVar(&json, "json", false, "")
+	flags.StringVar(&tmpl, "t", "", "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got at most a prefix
+	args = flags.Args()
+	if len(args) > 1 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	variables, _, err := client.Variables().PrefixList(namespace, prefix, nil)
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error retrieving variables: %s", err))
+		return 1
+	}
+
+	if json || len(tmpl) > 0 {
+		out, err := Format(json, tmpl, variables)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+
+		c.Ui.Output(out)
+		return 0
+	}
+
+	c.Ui.Output(formatVariables(variables))
+	return 0
+}
+
+func formatVariables(variables []*api.VariableMetadata) string {
+	if len(variables) == 0 {
+		return "No variables found"
+	}
+
+	rows := make([]string, len(variables)+1)
+	rows[0] = "Namespace|Path|Modify Index"
+	for i, v := range variables {
+		rows[i+1] = fmt.Sprintf("%s|%s|%d",
+			v.Namespace,
+			v.Path,
+			v.ModifyIndex)
+	}
+	return formatList(rows)
+}
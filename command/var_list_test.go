@@ -0,0 +1,48 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestVarListCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &VarListCommand{}
+}
+
+func TestVarListCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &VarListCommand{Meta: Meta{Ui: ui}}
+
+	// No variables
+	if code := cmd.Run([]string{"-address=" + url}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.OutputWriter.String(); !strings.Contains(out, "No variables found") {
+		t.Fatalf("bad: %s", out)
+	}
+	ui.OutputWriter.Reset()
+
+	for _, path := range []string{"app/db", "web/tls"} {
+		v := &api.Variable{Path: path, Items: map[string]string{"k": "v"}}
+		if _, _, err := client.Variables().Put(v, nil); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// List by prefix
+	if code := cmd.Run([]string{"-address=" + url, "app/"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "app/db") || strings.Contains(out, "web/tls") {
+		t.Fatalf("bad: %s", out)
+	}
+}
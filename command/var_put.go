@@ -0,0 +1,121 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/posener/complete"
+)
+
+type VarPutCommand struct {
+	Meta
+}
+
+func (c *VarPutCommand) Help() string {
+	helpText := `
+Usage: nomad var put [options] <path> <key>=<value> [<key>=<value>...]
+
+Put is used to create or update a variable. The items of the variable are
+replaced by the given items.
+
+General Options:
+
+  ` + generalOptionsUsage() + `
+
+Put Options:
+
+  -namespace
+    The namespace of the variable. Defaults to the default namespace.
+
+  -cas
+    Only write the variable if its modify index is the given index, 0
+    meaning that the variable must not exist yet.
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *VarPutCommand) Synopsis() string {
+	return "Create or update a variable"
+}
+
+func (c *VarPutCommand) AutocompleteFlags() complete.Flags {
+	return mergeAutocompleteFlags(c.Meta.AutocompleteFlags(FlagSetClient),
+		complete.Flags{
+			"-namespace": c.PredictNamespaces(),
+			"-cas":       complete.PredictAnything,
+		})
+}
+
+func (c *VarPutCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *VarPutCommand) Run(args []string) int {
+	var namespace string
+	var cas int64
+
+	flags := c.Meta.FlagSet("var put", FlagSetClient)
+	flags.Usage = func() { c.Ui.Output(c.Help()) }
+	flags.StringVar(&namespace, "namespace", "", "")
+	flags.Int64Var(&cas, "cas", -1, "")
+
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check that we got a path and items
+	args = flags.Args()
+	if len(args) < 2 {
+		c.Ui.Error(c.Help())
+		return 1
+	}
+
+	v := &api.Variable{
+		Namespace: namespace,
+		Path:      args[0],
+		Items:     make(map[string]string, len(args)-1),
+	}
+	for _, arg := range args[1:] {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			c.Ui.Error(fmt.Sprintf("Invalid item %q: must be of the form <key>=<value>", arg))
+			return 1
+		}
+		v.Items[parts[0]] = parts[1]
+	}
+
+	// Get the HTTP client
+	client, err := c.Meta.Client()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error initializing client: %s", err))
+		return 1
+	}
+
+	var resp *api.VariableWriteResponse
+	if cas >= 0 {
+		v.ModifyIndex = uint64(cas)
+		resp, _, err = client.Variables().CheckedPut(v, nil)
+	} else {
+		resp, _, err = client.Variables().Put(v, nil)
+	}
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing variable: %s", err))
+		return 1
+	}
+	if !resp.Updated {
+		c.Ui.Error(formatVariableConflict(v.Path, resp.Conflict))
+		return 1
+	}
+
+	c.Ui.Output(fmt.Sprintf("Successfully wrote variable %q!", v.Path))
+	return 0
+}
+
+// formatVariableConflict returns the error of a failed check-and-set write.
+func formatVariableConflict(path string, conflict *api.VariableMetadata) string {
+	if conflict == nil {
+		return fmt.Sprintf("Check-and-set failed: variable %q doesn't exist", path)
+	}
+	return fmt.Sprintf("Check-and-set failed: variable %q has modify index %d", path, conflict.ModifyIndex)
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func TestVarPutCommand_Implements(t *testing.T) {
+	t.Parallel()
+	var _ cli.Command = &VarPutCommand{}
+}
+
+func TestVarPutCommand_Fails(t *testing.T) {
+	t.Parallel()
+	ui := new(cli.MockUi)
+	cmd := &VarPutCommand{Meta: Meta{Ui: ui}}
+
+	// Fails on misuse
+	if code := cmd.Run([]string{"app/db"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, cmd.Help()) {
+		t.Fatalf("expected help output, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on invalid items
+	if code := cmd.Run([]string{"app/db", "user"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Invalid item") {
+		t.Fatalf("expected invalid item error, got: %s", out)
+	}
+	ui.ErrorWriter.Reset()
+
+	// Fails on connection failure
+	if code := cmd.Run([]string{"-address=nope", "app/db", "user=app"}); code != 1 {
+		t.Fatalf("expected exit code 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Error writing variable") {
+		t.Fatalf("expected failed write error, got: %s", out)
+	}
+}
+
+func TestVarPutCommand_Run(t *testing.T) {
+	t.Parallel()
+	srv, client, url := testServer(t, false, nil)
+	defer srv.Shutdown()
+
+	ui := new(cli.MockUi)
+	cmd := &VarPutCommand{Meta: Meta{Ui: ui}}
+
+	if code := cmd.Run([]string{"-address=" + url, "app/db", "user=app", "password=a=b"}); code != 0 {
+		t.Fatalf("expected exit 0, got: %d; %v", code, ui.ErrorWriter.String())
+	}
+	v, _, err := client.Variables().Read("", "app/db", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if v.Items["user"] != "app" || v.Items["password"] != "a=b" {
+		t.Fatalf("bad items: %#v", v.Items)
+	}
+
+	// A check-and-set write fails if the variable exists
+	if code := cmd.Run([]string{"-address=" + url, "-cas=0", "app/db", "user=other"}); code != 1 {
+		t.Fatalf("expected exit 1, got: %d", code)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Check-and-set failed") {
+		t.Fatalf("expected check-and-set error, got: %s", out)
+	}
+}
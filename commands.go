@@ -25,6 +25,176 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 	}
 
 	return map[string]cli.CommandFactory{
+		"acl": func() (cli.Command, error) {
+			return &command.ACLCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl auth-method": func() (cli.Command, error) {
+			return &command.ACLAuthMethodCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl auth-method apply": func() (cli.Command, error) {
+			return &command.ACLAuthMethodApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl auth-method delete": func() (cli.Command, error) {
+			return &command.ACLAuthMethodDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl auth-method info": func() (cli.Command, error) {
+			return &command.ACLAuthMethodInfoCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl auth-method list": func() (cli.Command, error) {
+			return &command.ACLAuthMethodListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl binding-rule": func() (cli.Command, error) {
+			return &command.ACLBindingRuleCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl binding-rule apply": func() (cli.Command, error) {
+			return &command.ACLBindingRuleApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl binding-rule delete": func() (cli.Command, error) {
+			return &command.ACLBindingRuleDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl binding-rule info": func() (cli.Command, error) {
+			return &command.ACLBindingRuleInfoCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl binding-rule list": func() (cli.Command, error) {
+			return &command.ACLBindingRuleListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl bootstrap": func() (cli.Command, error) {
+			return &command.ACLBootstrapCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl policy": func() (cli.Command, error) {
+			return &command.ACLPolicyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl policy apply": func() (cli.Command, error) {
+			return &command.ACLPolicyApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl policy delete": func() (cli.Command, error) {
+			return &command.ACLPolicyDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl policy info": func() (cli.Command, error) {
+			return &command.ACLPolicyInfoCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl policy list": func() (cli.Command, error) {
+			return &command.ACLPolicyListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl role": func() (cli.Command, error) {
+			return &command.ACLRoleCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl role apply": func() (cli.Command, error) {
+			return &command.ACLRoleApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl role delete": func() (cli.Command, error) {
+			return &command.ACLRoleDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl role info": func() (cli.Command, error) {
+			return &command.ACLRoleInfoCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl role list": func() (cli.Command, error) {
+			return &command.ACLRoleListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl token": func() (cli.Command, error) {
+			return &command.ACLTokenCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl token create": func() (cli.Command, error) {
+			return &command.ACLTokenCreateCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl token delete": func() (cli.Command, error) {
+			return &command.ACLTokenDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl token info": func() (cli.Command, error) {
+			return &command.ACLTokenInfoCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl token list": func() (cli.Command, error) {
+			return &command.ACLTokenListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl token self": func() (cli.Command, error) {
+			return &command.ACLTokenSelfCommand{
+				Meta: meta,
+			}, nil
+		},
+		"acl token update": func() (cli.Command, error) {
+			return &command.ACLTokenUpdateCommand{
+				Meta: meta,
+			}, nil
+		},
+		"alloc": func() (cli.Command, error) {
+			return &command.AllocCommand{
+				Meta: meta,
+			}, nil
+		},
+		"alloc exec": func() (cli.Command, error) {
+			return &command.AllocExecCommand{
+				Meta: meta,
+			}, nil
+		},
+		"alloc restart": func() (cli.Command, error) {
+			return &command.AllocRestartCommand{
+				Meta: meta,
+			}, nil
+		},
+		"alloc signal": func() (cli.Command, error) {
+			return &command.AllocSignalCommand{
+				Meta: meta,
+			}, nil
+		},
+		"alloc stop": func() (cli.Command, error) {
+			return &command.AllocStopCommand{
+				Meta: meta,
+			}, nil
+		},
 		"alloc-status": func() (cli.Command, error) {
 			return &command.AllocStatusCommand{
 				Meta: meta,
@@ -54,6 +224,16 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"config": func() (cli.Command, error) {
+			return &command.ConfigCommand{
+				Meta: meta,
+			}, nil
+		},
+		"config validate": func() (cli.Command, error) {
+			return &command.ConfigValidateCommand{
+				Meta: meta,
+			}, nil
+		},
 		"deployment": func() (cli.Command, error) {
 			return &command.DeploymentCommand{
 				Meta: meta,
@@ -144,6 +324,16 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"job periodic": func() (cli.Command, error) {
+			return &command.JobPeriodicCommand{
+				Meta: meta,
+			}, nil
+		},
+		"job periodic force": func() (cli.Command, error) {
+			return &command.JobPeriodicForceCommand{
+				Meta: meta,
+			}, nil
+		},
 		"job promote": func() (cli.Command, error) {
 			return &command.JobPromoteCommand{
 				Meta: meta,
@@ -154,11 +344,76 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"job scale": func() (cli.Command, error) {
+			return &command.JobScaleCommand{
+				Meta: meta,
+			}, nil
+		},
+		"login": func() (cli.Command, error) {
+			return &command.LoginCommand{
+				Meta: meta,
+			}, nil
+		},
 		"logs": func() (cli.Command, error) {
 			return &command.LogsCommand{
 				Meta: meta,
 			}, nil
 		},
+		"monitor": func() (cli.Command, error) {
+			return &command.AgentMonitorCommand{
+				Meta: meta,
+			}, nil
+		},
+		"namespace": func() (cli.Command, error) {
+			return &command.NamespaceCommand{
+				Meta: meta,
+			}, nil
+		},
+		"namespace apply": func() (cli.Command, error) {
+			return &command.NamespaceApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"namespace delete": func() (cli.Command, error) {
+			return &command.NamespaceDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"namespace inspect": func() (cli.Command, error) {
+			return &command.NamespaceInspectCommand{
+				Meta: meta,
+			}, nil
+		},
+		"namespace list": func() (cli.Command, error) {
+			return &command.NamespaceListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"node": func() (cli.Command, error) {
+			return &command.NodeCommand{
+				Meta: meta,
+			}, nil
+		},
+		"node meta": func() (cli.Command, error) {
+			return &command.NodeMetaCommand{
+				Meta: meta,
+			}, nil
+		},
+		"node meta apply": func() (cli.Command, error) {
+			return &command.NodeMetaApplyCommand{
+				Meta: meta,
+			}, nil
+		},
+		"node meta read": func() (cli.Command, error) {
+			return &command.NodeMetaReadCommand{
+				Meta: meta,
+			}, nil
+		},
+		"node meta unset": func() (cli.Command, error) {
+			return &command.NodeMetaUnsetCommand{
+				Meta: meta,
+			}, nil
+		},
 		"node-drain": func() (cli.Command, error) {
 			return &command.NodeDrainCommand{
 				Meta: meta,
@@ -176,6 +431,12 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 			}, nil
 		},
 
+		"operator debug": func() (cli.Command, error) {
+			return &command.OperatorDebugCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"operator raft": func() (cli.Command, error) {
 			return &command.OperatorRaftCommand{
 				Meta: meta,
@@ -194,6 +455,24 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 			}, nil
 		},
 
+		"operator scheduler": func() (cli.Command, error) {
+			return &command.OperatorSchedulerCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"operator scheduler get-config": func() (cli.Command, error) {
+			return &command.OperatorSchedulerGetConfigCommand{
+				Meta: meta,
+			}, nil
+		},
+
+		"operator scheduler set-config": func() (cli.Command, error) {
+			return &command.OperatorSchedulerSetConfigCommand{
+				Meta: meta,
+			}, nil
+		},
+
 		"plan": func() (cli.Command, error) {
 			return &command.PlanCommand{
 				Meta: meta,
@@ -205,6 +484,11 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"search": func() (cli.Command, error) {
+			return &command.SearchCommand{
+				Meta: meta,
+			}, nil
+		},
 		"server-force-leave": func() (cli.Command, error) {
 			return &command.ServerForceLeaveCommand{
 				Meta: meta,
@@ -230,11 +514,81 @@ func Commands(metaPtr *command.Meta) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"system": func() (cli.Command, error) {
+			return &command.SystemCommand{
+				Meta: meta,
+			}, nil
+		},
+		"system gc": func() (cli.Command, error) {
+			return &command.SystemGCCommand{
+				SystemCommand: command.SystemCommand{Meta: meta},
+			}, nil
+		},
+		"system reconcile": func() (cli.Command, error) {
+			return &command.SystemReconcileCommand{
+				Meta: meta,
+			}, nil
+		},
+		"system reconcile summaries": func() (cli.Command, error) {
+			return &command.SystemReconcileSummariesCommand{
+				SystemCommand: command.SystemCommand{Meta: meta},
+			}, nil
+		},
+		"tls": func() (cli.Command, error) {
+			return &command.TLSCommand{
+				Meta: meta,
+			}, nil
+		},
+		"tls ca": func() (cli.Command, error) {
+			return &command.TLSCACommand{
+				Meta: meta,
+			}, nil
+		},
+		"tls ca create": func() (cli.Command, error) {
+			return &command.TLSCACreateCommand{
+				Meta: meta,
+			}, nil
+		},
+		"tls cert": func() (cli.Command, error) {
+			return &command.TLSCertCommand{
+				Meta: meta,
+			}, nil
+		},
+		"tls cert create": func() (cli.Command, error) {
+			return &command.TLSCertCreateCommand{
+				Meta: meta,
+			}, nil
+		},
 		"validate": func() (cli.Command, error) {
 			return &command.ValidateCommand{
 				Meta: meta,
 			}, nil
 		},
+		"var": func() (cli.Command, error) {
+			return &command.VarCommand{
+				Meta: meta,
+			}, nil
+		},
+		"var delete": func() (cli.Command, error) {
+			return &command.VarDeleteCommand{
+				Meta: meta,
+			}, nil
+		},
+		"var get": func() (cli.Command, error) {
+			return &command.VarGetCommand{
+				Meta: meta,
+			}, nil
+		},
+		"var list": func() (cli.Command, error) {
+			return &command.VarListCommand{
+				Meta: meta,
+			}, nil
+		},
+		"var put": func() (cli.Command, error) {
+			return &command.VarPutCommand{
+				Meta: meta,
+			}, nil
+		},
 		"version": func() (cli.Command, error) {
 			return &command.VersionCommand{
 				Version: PrettyVersion(GetVersionParts()),
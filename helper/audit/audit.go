@@ -0,0 +1,396 @@
+// Package audit records the requests made to the HTTP API of an agent as
+// audit events and writes them to file, syslog and HTTP sinks. Filters drop
+// the events of chosen endpoints, operations and identities, so that the
+// high-volume reads don't drown the writes in the audit log.
+package audit
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+const (
+	// StageReceived is the stage of the events recorded when a request is
+	// received, before it is handled.
+	StageReceived = "OperationReceived"
+
+	// StageComplete is the stage of the events recorded once a request is
+	// handled.
+	StageComplete = "OperationComplete"
+
+	// SinkTypeFile, SinkTypeSyslog and SinkTypeHTTP are the types of sinks.
+	SinkTypeFile   = "file"
+	SinkTypeSyslog = "syslog"
+	SinkTypeHTTP   = "http"
+
+	// DeliveryEnforced rejects the requests whose events can't be written
+	// to the sink.
+	DeliveryEnforced = "enforced"
+
+	// DeliveryBestEffort writes the events to the sink in the background,
+	// dropping them if the sink fails or falls behind.
+	DeliveryBestEffort = "best-effort"
+
+	// eventVersion is the version of the format of the events
+	eventVersion = 1
+
+	// bestEffortQueueSize is the number of events a best-effort sink can
+	// buffer. Events recorded while the queue is full are dropped.
+	bestEffortQueueSize = 1024
+)
+
+// Event is the audit event of a stage of a request.
+type Event struct {
+	ID        string
+	Type      string
+	Timestamp time.Time
+	Version   int
+	Stage     string
+	Auth      *Auth
+	Request   *Request
+	Response  *Response `json:",omitempty"`
+}
+
+// Auth is the identity a request was made with.
+type Auth struct {
+	AccessorID string
+	Name       string
+	Type       string
+	Policies   []string
+	Global     bool
+}
+
+// Request describes the request of an event.
+type Request struct {
+	ID         string
+	Operation  string
+	Endpoint   string
+	Namespace  string
+	RemoteAddr string
+	UserAgent  string
+
+	// PolicyOverrideJustification is the justification of a job
+	// registration overriding soft-mandatory admission policies.
+	PolicyOverrideJustification string `json:",omitempty"`
+}
+
+// Response describes the outcome of a request.
+type Response struct {
+	StatusCode int
+	Error      string `json:",omitempty"`
+}
+
+// identity returns the accessor ID and name of the identity of the event,
+// empty if the request wasn't authenticated.
+func (e *Event) identity() (string, string) {
+	if e.Auth == nil {
+		return "", ""
+	}
+	return e.Auth.AccessorID, e.Auth.Name
+}
+
+// Filter drops the events matching all of its non-empty criteria.
+type Filter struct {
+	// Name is the name of the filter, shown in the logs.
+	Name string
+
+	// Endpoints are the paths of the requests, such as /v1/jobs. They may
+	// be glob patterns, such as /v1/client/fs/*.
+	Endpoints []string
+
+	// Operations are the HTTP methods of the requests, such as GET.
+	Operations []string
+
+	// Stages are the stages of the events, OperationReceived or
+	// OperationComplete.
+	Stages []string
+
+	// Identities are the accessor IDs or names of the tokens of the
+	// requests. The anonymous token has the "anonymous" accessor ID.
+	Identities []string
+}
+
+// Validate returns an error if a pattern of the filter is invalid.
+func (f *Filter) Validate() error {
+	for _, endpoint := range f.Endpoints {
+		if _, err := path.Match(endpoint, ""); err != nil {
+			return fmt.Errorf("audit filter %q: invalid endpoint %q: %v", f.Name, endpoint, err)
+		}
+	}
+	for _, stage := range f.Stages {
+		if stage != StageReceived && stage != StageComplete && stage != "*" {
+			return fmt.Errorf("audit filter %q: invalid stage %q", f.Name, stage)
+		}
+	}
+	return nil
+}
+
+// Match returns whether the filter drops the event.
+func (f *Filter) Match(e *Event) bool {
+	if len(f.Endpoints) > 0 && !matchAny(f.Endpoints, e.Request.Endpoint, true) {
+		return false
+	}
+	if len(f.Operations) > 0 && !matchAny(f.Operations, e.Request.Operation, false) {
+		return false
+	}
+	if len(f.Stages) > 0 && !matchAny(f.Stages, e.Stage, false) {
+		return false
+	}
+	if len(f.Identities) > 0 {
+		accessorID, name := e.identity()
+		if !matchAny(f.Identities, accessorID, false) && !matchAny(f.Identities, name, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny returns whether the value matches one of the patterns, "*"
+// matching everything. Glob patterns are only allowed if glob is set.
+func matchAny(patterns []string, value string, glob bool) bool {
+	for _, p := range patterns {
+		if p == "*" || strings.EqualFold(p, value) {
+			return true
+		}
+		if glob {
+			if ok, _ := path.Match(p, value); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SinkConfig configures a sink the events are written to.
+type SinkConfig struct {
+	// Name is the name of the sink, shown in the logs.
+	Name string
+
+	// Type is the type of the sink: file, syslog or http.
+	Type string
+
+	// DeliveryGuarantee is what happens when an event can't be written:
+	// enforced rejects the request, best-effort drops the event. It
+	// defaults to enforced.
+	DeliveryGuarantee string
+
+	// Path is the file events are appended to, for file sinks.
+	Path string
+
+	// Facility and Tag are the syslog facility and tag of the events, for
+	// syslog sinks. They default to LOCAL0 and nomad-audit.
+	Facility string
+	Tag      string
+
+	// Address is the URL events are posted to, for http sinks. Headers are
+	// added to the requests.
+	Address string
+	Headers map[string]string
+
+	// Timeout is the time an http sink has to accept an event.
+	Timeout time.Duration
+}
+
+// Validate returns an error if the sink is misconfigured.
+func (c *SinkConfig) Validate() error {
+	switch c.DeliveryGuarantee {
+	case "", DeliveryEnforced, DeliveryBestEffort:
+	default:
+		return fmt.Errorf("audit sink %q: invalid delivery_guarantee %q", c.Name, c.DeliveryGuarantee)
+	}
+	switch c.Type {
+	case SinkTypeFile:
+		if c.Path == "" {
+			return fmt.Errorf("audit sink %q: file sinks need a path", c.Name)
+		}
+	case SinkTypeSyslog:
+	case SinkTypeHTTP:
+		if c.Address == "" {
+			return fmt.Errorf("audit sink %q: http sinks need an address", c.Name)
+		}
+	default:
+		return fmt.Errorf("audit sink %q: invalid type %q", c.Name, c.Type)
+	}
+	return nil
+}
+
+// Config configures an Auditor. Auditing is disabled unless enabled.
+type Config struct {
+	Enabled bool
+	Sinks   []*SinkConfig
+	Filters []*Filter
+}
+
+// Sink writes the events to a destination.
+type Sink interface {
+	Write(e *Event) error
+	Close() error
+}
+
+// sink is a configured sink and how its failures are handled
+type sink struct {
+	name     string
+	sink     Sink
+	enforced bool
+
+	// queue buffers the events of best-effort sinks
+	queue chan *Event
+	done  chan struct{}
+}
+
+// Auditor writes the audit events that aren't filtered to its sinks. It is
+// safe for concurrent use. A nil Auditor records nothing.
+type Auditor struct {
+	sinks   []*sink
+	filters []*Filter
+	logger  *log.Logger
+
+	// closed is set once the sinks are closed, after which events are
+	// dropped
+	closed bool
+	l      sync.RWMutex
+}
+
+// New returns an Auditor for the configuration or nil if auditing is
+// disabled.
+func New(config *Config, logger *log.Logger) (*Auditor, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+	if len(config.Sinks) == 0 {
+		return nil, fmt.Errorf("audit logging needs at least one sink")
+	}
+
+	a := &Auditor{
+		filters: config.Filters,
+		logger:  logger,
+	}
+	for _, f := range config.Filters {
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range config.Sinks {
+		if err := c.Validate(); err != nil {
+			a.Close()
+			return nil, err
+		}
+		s, err := newSink(c)
+		if err != nil {
+			a.Close()
+			return nil, fmt.Errorf("audit sink %q: %v", c.Name, err)
+		}
+		a.addSink(c, s)
+	}
+	return a, nil
+}
+
+// addSink adds the sink, starting the writer of best-effort sinks
+func (a *Auditor) addSink(c *SinkConfig, s Sink) {
+	as := &sink{
+		name:     c.Name,
+		sink:     s,
+		enforced: c.DeliveryGuarantee != DeliveryBestEffort,
+	}
+	if !as.enforced {
+		as.queue = make(chan *Event, bestEffortQueueSize)
+		as.done = make(chan struct{})
+		go a.runBestEffort(as)
+	}
+	a.sinks = append(a.sinks, as)
+}
+
+// newSink returns the sink of the configuration
+func newSink(c *SinkConfig) (Sink, error) {
+	switch c.Type {
+	case SinkTypeFile:
+		return newFileSink(c.Path)
+	case SinkTypeSyslog:
+		return newSyslogSink(c.Facility, c.Tag)
+	default:
+		return newHTTPSink(c.Address, c.Headers, c.Timeout), nil
+	}
+}
+
+// Event writes the event to the sinks unless a filter drops it. It returns an
+// error if the event couldn't be written to an enforced sink, in which case
+// the request must be rejected.
+func (a *Auditor) Event(e *Event) error {
+	if a == nil {
+		return nil
+	}
+	for _, f := range a.filters {
+		if f.Match(e) {
+			metrics.IncrCounter([]string{"nomad", "audit", "filtered"}, 1)
+			return nil
+		}
+	}
+	e.Type = "audit"
+	e.Version = eventVersion
+
+	a.l.RLock()
+	defer a.l.RUnlock()
+	if a.closed {
+		return nil
+	}
+
+	var mErr multierror.Error
+	for _, s := range a.sinks {
+		if !s.enforced {
+			select {
+			case s.queue <- e:
+			default:
+				metrics.IncrCounter([]string{"nomad", "audit", "dropped"}, 1)
+				a.logger.Printf("[WARN] audit: sink %q is falling behind, dropping event %s", s.name, e.ID)
+			}
+			continue
+		}
+		if err := s.sink.Write(e); err != nil {
+			metrics.IncrCounter([]string{"nomad", "audit", "failed"}, 1)
+			a.logger.Printf("[ERR] audit: failed to write event %s to sink %q: %v", e.ID, s.name, err)
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("audit sink %q: %v", s.name, err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// runBestEffort writes the queued events of the best-effort sink, dropping
+// the ones that fail.
+func (a *Auditor) runBestEffort(s *sink) {
+	defer close(s.done)
+	for e := range s.queue {
+		if err := s.sink.Write(e); err != nil {
+			metrics.IncrCounter([]string{"nomad", "audit", "dropped"}, 1)
+			a.logger.Printf("[WARN] audit: failed to write event %s to sink %q, dropping it: %v", e.ID, s.name, err)
+		}
+	}
+}
+
+// Close writes the events still queued and closes the sinks.
+func (a *Auditor) Close() {
+	if a == nil {
+		return
+	}
+	a.l.Lock()
+	defer a.l.Unlock()
+	if a.closed {
+		return
+	}
+	a.closed = true
+	for _, s := range a.sinks {
+		if s.queue != nil {
+			close(s.queue)
+			<-s.done
+		}
+		if err := s.sink.Close(); err != nil {
+			a.logger.Printf("[WARN] audit: failed to close sink %q: %v", s.name, err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSink records the events written to it, failing if err is set
+type testSink struct {
+	events []*Event
+	err    error
+	l      sync.Mutex
+}
+
+func (s *testSink) Write(e *Event) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	return nil
+}
+
+func (s *testSink) count() int {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return len(s.events)
+}
+
+func testLogger() *log.Logger {
+	return log.New(os.Stderr, "", log.LstdFlags)
+}
+
+func testEvent(method, endpoint, accessorID string) *Event {
+	return &Event{
+		ID:        "e",
+		Timestamp: time.Now(),
+		Stage:     StageReceived,
+		Auth:      &Auth{AccessorID: accessorID, Name: "token " + accessorID},
+		Request:   &Request{Operation: method, Endpoint: endpoint},
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		filter *Filter
+		event  *Event
+		match  bool
+	}{
+		{&Filter{}, testEvent("GET", "/v1/jobs", "a"), true},
+		{&Filter{Endpoints: []string{"/v1/jobs"}}, testEvent("GET", "/v1/jobs", "a"), true},
+		{&Filter{Endpoints: []string{"/v1/jobs"}}, testEvent("GET", "/v1/job/foo", "a"), false},
+		{&Filter{Endpoints: []string{"/v1/client/fs/*"}}, testEvent("GET", "/v1/client/fs/logs", "a"), true},
+		{&Filter{Operations: []string{"get"}}, testEvent("GET", "/v1/jobs", "a"), true},
+		{&Filter{Operations: []string{"GET"}}, testEvent("PUT", "/v1/jobs", "a"), false},
+		{&Filter{Stages: []string{StageComplete}}, testEvent("GET", "/v1/jobs", "a"), false},
+		{&Filter{Identities: []string{"a"}}, testEvent("GET", "/v1/jobs", "a"), true},
+		{&Filter{Identities: []string{"token b"}}, testEvent("GET", "/v1/jobs", "b"), true},
+		{&Filter{Identities: []string{"a"}}, testEvent("GET", "/v1/jobs", "b"), false},
+		{&Filter{Operations: []string{"GET"}, Identities: []string{"a"}}, testEvent("GET", "/v1/jobs", "b"), false},
+	}
+	for i, c := range cases {
+		if act := c.filter.Match(c.event); act != c.match {
+			t.Fatalf("case %d: expected match %v, got %v", i, c.match, act)
+		}
+	}
+
+	bad := &Filter{Name: "bad", Endpoints: []string{"/v1/["}}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected invalid endpoint error")
+	}
+	bad = &Filter{Name: "bad", Stages: []string{"Done"}}
+	if err := bad.Validate(); err == nil {
+		t.Fatalf("expected invalid stage error")
+	}
+}
+
+func TestAuditor_Event(t *testing.T) {
+	t.Parallel()
+	writes, reads := &testSink{}, &testSink{}
+	a := &Auditor{
+		filters: []*Filter{{Name: "reads", Operations: []string{"GET"}}},
+		logger:  testLogger(),
+	}
+	a.addSink(&SinkConfig{Name: "writes"}, writes)
+	a.addSink(&SinkConfig{Name: "best", DeliveryGuarantee: DeliveryBestEffort}, reads)
+
+	// Filtered events aren't written
+	if err := a.Event(testEvent("GET", "/v1/jobs", "a")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := a.Event(testEvent("PUT", "/v1/jobs", "a")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	a.Close()
+	if writes.count() != 1 || reads.count() != 1 {
+		t.Fatalf("expected one event per sink, got %d and %d", writes.count(), reads.count())
+	}
+	if e := writes.events[0]; e.Type != "audit" || e.Version != eventVersion || e.Request.Operation != "PUT" {
+		t.Fatalf("bad event: %#v", e)
+	}
+
+	// Events are dropped once the auditor is closed
+	if err := a.Event(testEvent("PUT", "/v1/jobs", "a")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if writes.count() != 1 {
+		t.Fatalf("unexpected event after close")
+	}
+}
+
+func TestAuditor_DeliveryGuarantee(t *testing.T) {
+	t.Parallel()
+	enforced := &testSink{err: fmt.Errorf("disk full")}
+	a := &Auditor{logger: testLogger()}
+	a.addSink(&SinkConfig{Name: "enforced", DeliveryGuarantee: DeliveryEnforced}, enforced)
+	err := a.Event(testEvent("PUT", "/v1/jobs", "a"))
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected sink error: %v", err)
+	}
+
+	// Best-effort failures are dropped
+	bestEffort := &testSink{err: fmt.Errorf("unreachable")}
+	a = &Auditor{logger: testLogger()}
+	a.addSink(&SinkConfig{Name: "best", DeliveryGuarantee: DeliveryBestEffort}, bestEffort)
+	if err := a.Event(testEvent("PUT", "/v1/jobs", "a")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	a.Close()
+}
+
+func TestAuditor_New(t *testing.T) {
+	t.Parallel()
+	a, err := New(&Config{}, testLogger())
+	if err != nil || a != nil {
+		t.Fatalf("expected disabled auditor: %v %v", a, err)
+	}
+	if _, err := New(&Config{Enabled: true}, testLogger()); err == nil {
+		t.Fatalf("expected missing sink error")
+	}
+	_, err = New(&Config{Enabled: true, Sinks: []*SinkConfig{{Name: "s", Type: "kafka"}}}, testLogger())
+	if err == nil || !strings.Contains(err.Error(), "invalid type") {
+		t.Fatalf("expected invalid type error: %v", err)
+	}
+	_, err = New(&Config{Enabled: true, Sinks: []*SinkConfig{{Name: "s", Type: SinkTypeFile}}}, testLogger())
+	if err == nil || !strings.Contains(err.Error(), "need a path") {
+		t.Fatalf("expected missing path error: %v", err)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit", "audit.log")
+
+	a, err := New(&Config{
+		Enabled: true,
+		Sinks:   []*SinkConfig{{Name: "file", Type: SinkTypeFile, Path: path}},
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := a.Event(testEvent("PUT", "/v1/jobs", "a")); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	a.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 events, got: %s", data)
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if e.Request.Endpoint != "/v1/jobs" || e.Auth.AccessorID != "a" {
+		t.Fatalf("bad event: %#v", e)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	t.Parallel()
+	var l sync.Mutex
+	var received []Event
+	fail := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		defer l.Unlock()
+		if fail {
+			w.WriteHeader(500)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(401)
+			return
+		}
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(400)
+			return
+		}
+		received = append(received, e)
+	}))
+	defer ts.Close()
+
+	s := newHTTPSink(ts.URL, map[string]string{"Authorization": "Bearer secret"}, 0)
+	if err := s.Write(testEvent("PUT", "/v1/jobs", "a")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(received) != 1 || received[0].Request.Endpoint != "/v1/jobs" {
+		t.Fatalf("bad events: %#v", received)
+	}
+
+	l.Lock()
+	fail = true
+	l.Unlock()
+	if err := s.Write(testEvent("PUT", "/v1/jobs", "a")); err == nil {
+		t.Fatalf("expected error on a 500 response")
+	}
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-syslog"
+)
+
+const (
+	// defaultSyslogFacility and defaultSyslogTag are the syslog facility and
+	// tag of the events of syslog sinks
+	defaultSyslogFacility = "LOCAL0"
+	defaultSyslogTag      = "nomad-audit"
+
+	// defaultHTTPTimeout is the time an http sink has to accept an event
+	defaultHTTPTimeout = 5 * time.Second
+)
+
+// fileSink appends the events to a file, one JSON object per line.
+type fileSink struct {
+	f *os.File
+	l sync.Mutex
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{f: f}, nil
+}
+
+func (s *fileSink) Write(e *Event) error {
+	buf, err := encodeEvent(e)
+	if err != nil {
+		return err
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	_, err = s.f.Write(buf)
+	return err
+}
+
+func (s *fileSink) Close() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	return s.f.Close()
+}
+
+// syslogSink sends the events to the local syslog daemon.
+type syslogSink struct {
+	l gsyslog.Syslogger
+}
+
+func newSyslogSink(facility, tag string) (*syslogSink, error) {
+	if facility == "" {
+		facility = defaultSyslogFacility
+	}
+	if tag == "" {
+		tag = defaultSyslogTag
+	}
+	l, err := gsyslog.NewLogger(gsyslog.LOG_NOTICE, facility, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{l: l}, nil
+}
+
+func (s *syslogSink) Write(e *Event) error {
+	buf, err := encodeEvent(e)
+	if err != nil {
+		return err
+	}
+	return s.l.WriteLevel(gsyslog.LOG_NOTICE, buf)
+}
+
+func (s *syslogSink) Close() error {
+	return nil
+}
+
+// httpSink posts the events to an HTTP endpoint, one JSON object per
+// request. Any response but a 2xx is a failure.
+type httpSink struct {
+	address string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPSink(address string, headers map[string]string, timeout time.Duration) *httpSink {
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	client := cleanhttp.DefaultClient()
+	client.Timeout = timeout
+	return &httpSink{
+		address: address,
+		headers: headers,
+		client:  client,
+	}
+}
+
+func (s *httpSink) Write(e *Event) error {
+	buf, err := encodeEvent(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.address, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error {
+	return nil
+}
+
+// encodeEvent encodes the event as a line of JSON
+func encodeEvent(e *Event) ([]byte, error) {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
@@ -0,0 +1,469 @@
+// Package filter implements the boolean expression language used to filter
+// the results of list queries. An expression compares the fields of an object
+// against literal values, for example:
+//
+//	Status == "running" and (NodeID == "abc" or not Meta.team is empty)
+//
+// Selectors are dotted paths of struct field names and map keys. The supported
+// comparisons are "==", "!=", "contains", "not contains", "in", "not in",
+// "matches", "not matches", "is empty" and "is not empty". Comparisons can be
+// combined with "and", "or", "not" and parentheses.
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expression is a parsed filter expression.
+type Expression struct {
+	raw  string
+	root node
+}
+
+// Parse parses the filter expression.
+func Parse(expr string) (*Expression, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+
+	return &Expression{raw: expr, root: root}, nil
+}
+
+// String returns the expression as it was given.
+func (e *Expression) String() string {
+	return e.raw
+}
+
+// Match returns whether the object matches the expression. An error is
+// returned if the expression selects a field the object does not have.
+func (e *Expression) Match(obj interface{}) (bool, error) {
+	return e.root.eval(reflect.ValueOf(obj))
+}
+
+// node is a node of the parsed expression tree.
+type node interface {
+	eval(v reflect.Value) (bool, error)
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n *andNode) eval(v reflect.Value) (bool, error) {
+	l, err := n.left.eval(v)
+	if err != nil || !l {
+		return false, err
+	}
+	return n.right.eval(v)
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n *orNode) eval(v reflect.Value) (bool, error) {
+	l, err := n.left.eval(v)
+	if err != nil || l {
+		return l, err
+	}
+	return n.right.eval(v)
+}
+
+type notNode struct {
+	inner node
+}
+
+func (n *notNode) eval(v reflect.Value) (bool, error) {
+	m, err := n.inner.eval(v)
+	return !m, err
+}
+
+const (
+	opEqual    = "=="
+	opNotEqual = "!="
+	opContains = "contains"
+	opIn       = "in"
+	opMatches  = "matches"
+	opEmpty    = "empty"
+)
+
+// matchNode compares the value of a selector against a literal value.
+type matchNode struct {
+	selector []string
+	op       string
+	negate   bool
+	value    string
+	re       *regexp.Regexp
+}
+
+func (n *matchNode) eval(v reflect.Value) (bool, error) {
+	field, found, err := resolve(v, n.selector)
+	if err != nil {
+		return false, err
+	}
+
+	var m bool
+	switch n.op {
+	case opEqual:
+		m = found && isScalar(field) && formatScalar(field) == n.value
+	case opNotEqual:
+		m = !(found && isScalar(field) && formatScalar(field) == n.value)
+	case opEmpty:
+		m = !found || isEmpty(field)
+	case opContains, opIn:
+		m = found && contains(field, n.value)
+	case opMatches:
+		m = found && isScalar(field) && n.re.MatchString(formatScalar(field))
+	}
+
+	if n.negate {
+		return !m, nil
+	}
+	return m, nil
+}
+
+// resolve walks the selector path starting at the value. It returns whether
+// the path exists, which is false for missing map keys and nil pointers. An
+// error is returned if a struct doesn't have a selected field.
+func resolve(v reflect.Value, selector []string) (reflect.Value, bool, error) {
+	for i, part := range selector {
+		v = indirect(v)
+		if !v.IsValid() {
+			return v, false, nil
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f := v.FieldByName(part)
+			if !f.IsValid() || !isExported(part) {
+				return v, false, fmt.Errorf("unknown selector %q", strings.Join(selector[:i+1], "."))
+			}
+			v = f
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return v, false, fmt.Errorf("selector %q indexes a map without string keys", strings.Join(selector[:i+1], "."))
+			}
+			v = v.MapIndex(reflect.ValueOf(part).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return v, false, nil
+			}
+		default:
+			return v, false, fmt.Errorf("selector %q indexes a %s", strings.Join(selector[:i+1], "."), v.Kind())
+		}
+	}
+
+	v = indirect(v)
+	return v, v.IsValid(), nil
+}
+
+// indirect dereferences pointers and interfaces.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isExported(name string) bool {
+	return name != "" && strings.ToUpper(name[:1]) == name[:1]
+}
+
+func isScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// formatScalar returns the string form of a scalar value that literal values
+// are compared against.
+func formatScalar(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.String:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// contains returns whether a string contains the value as a substring, a
+// slice contains it as an element or a map contains it as a key.
+func contains(v reflect.Value, value string) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.Contains(v.String(), value)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			e := indirect(v.Index(i))
+			if e.IsValid() && isScalar(e) && formatScalar(e) == value {
+				return true
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			if isScalar(k) && formatScalar(k) == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parser is a recursive descent parser over the lexed tokens.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is the given keyword.
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tokenIdent && t.text == kw {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.keyword("not") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{inner: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("expected \")\" at position %d", closing.pos)
+		}
+		return inner, nil
+	case tokenString, tokenNumber:
+		// Only the "in" operator takes the value on the left
+		negate := p.keyword("not")
+		if !p.keyword("in") {
+			return nil, fmt.Errorf("expected \"in\" at position %d", p.peek().pos)
+		}
+		sel := p.next()
+		if sel.kind != tokenIdent {
+			return nil, fmt.Errorf("expected selector at position %d", sel.pos)
+		}
+		return &matchNode{selector: strings.Split(sel.text, "."), op: opIn, negate: negate, value: t.text}, nil
+	case tokenIdent:
+		return p.parseComparison(t)
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+}
+
+// parseComparison parses the comparison following a selector.
+func (p *parser) parseComparison(sel token) (node, error) {
+	n := &matchNode{selector: strings.Split(sel.text, ".")}
+	for _, part := range n.selector {
+		if part == "" {
+			return nil, fmt.Errorf("invalid selector %q at position %d", sel.text, sel.pos)
+		}
+	}
+
+	op := p.next()
+	switch {
+	case op.kind == tokenOp:
+		n.op = op.text
+	case op.kind == tokenIdent && op.text == "is":
+		n.negate = p.keyword("not")
+		if !p.keyword("empty") {
+			return nil, fmt.Errorf("expected \"empty\" at position %d", p.peek().pos)
+		}
+		n.op = opEmpty
+		return n, nil
+	case op.kind == tokenIdent && op.text == "not":
+		n.negate = true
+		op = p.next()
+		if op.kind != tokenIdent || (op.text != opContains && op.text != opMatches) {
+			return nil, fmt.Errorf("expected \"contains\" or \"matches\" at position %d", op.pos)
+		}
+		n.op = op.text
+	case op.kind == tokenIdent && (op.text == opContains || op.text == opMatches):
+		n.op = op.text
+	default:
+		return nil, fmt.Errorf("expected operator after %q at position %d", sel.text, op.pos)
+	}
+
+	val := p.next()
+	if val.kind != tokenString && val.kind != tokenNumber && val.kind != tokenIdent {
+		return nil, fmt.Errorf("expected value at position %d", val.pos)
+	}
+	n.value = val.text
+
+	if n.op == opMatches {
+		re, err := regexp.Compile(n.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", n.value, err)
+		}
+		n.re = re
+	}
+	return n, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits the expression into tokens.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(expr) {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case c == '=' || c == '!':
+			if i+1 >= len(expr) || expr[i+1] != '=' {
+				return nil, fmt.Errorf("invalid operator at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: expr[i : i+2], pos: i})
+			i += 2
+		case c == '"' || c == '`':
+			end := i + 1
+			for end < len(expr) && expr[end] != c {
+				if c == '"' && expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s, pos: i})
+			i = end + 1
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(expr) && strings.IndexByte("0123456789.eE+-", expr[end]) >= 0 {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[i:end], pos: i})
+			i = end
+		case isIdentChar(c):
+			end := i + 1
+			for end < len(expr) && (isIdentChar(expr[end]) || (expr[end] >= '0' && expr[end] <= '9') || expr[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[i:end], pos: i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(expr)})
+	return tokens, nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
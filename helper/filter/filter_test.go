@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"testing"
+)
+
+type testNested struct {
+	Name string
+}
+
+type testObject struct {
+	ID       string
+	Status   string
+	Priority int
+	Stop     bool
+	Tags     []string
+	Meta     map[string]string
+	Nested   *testNested
+	private  string
+}
+
+func TestExpression_Match(t *testing.T) {
+	obj := &testObject{
+		ID:       "abc-123",
+		Status:   "running",
+		Priority: 50,
+		Tags:     []string{"web", "prod"},
+		Meta:     map[string]string{"team": "infra"},
+		Nested:   &testNested{Name: "foo"},
+	}
+
+	cases := []struct {
+		expr  string
+		match bool
+	}{
+		{`Status == "running"`, true},
+		{`Status != "running"`, false},
+		{`Status == "running" and Priority == 50`, true},
+		{`Status == "pending" or Priority == 50`, true},
+		{`not Status == "running"`, false},
+		{`Stop == false`, true},
+		{`Status == "pending" or (Stop == false and Priority == 50)`, true},
+		{`Tags contains "web"`, true},
+		{`Tags not contains "web"`, false},
+		{`"prod" in Tags`, true},
+		{`"dev" not in Tags`, true},
+		{`ID contains "123"`, true},
+		{`ID matches "^abc-[0-9]+$"`, true},
+		{`ID not matches "^xyz"`, true},
+		{`Meta.team == "infra"`, true},
+		{`Meta.missing == "infra"`, false},
+		{`Meta.missing is empty`, true},
+		{`Meta is not empty`, true},
+		{`Nested.Name == "foo"`, true},
+		{`Tags == "web"`, false},
+	}
+
+	for _, c := range cases {
+		e, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", c.expr, err)
+		}
+		m, err := e.Match(obj)
+		if err != nil {
+			t.Fatalf("Match(%q) failed: %v", c.expr, err)
+		}
+		if m != c.match {
+			t.Fatalf("Match(%q) returned %v; want %v", c.expr, m, c.match)
+		}
+	}
+}
+
+func TestExpression_Match_UnknownSelector(t *testing.T) {
+	for _, expr := range []string{`Missing == "foo"`, `private == ""`, `Status.Foo == "bar"`} {
+		e, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", expr, err)
+		}
+		if _, err := e.Match(&testObject{}); err == nil {
+			t.Fatalf("Match(%q) should have failed", expr)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := []string{
+		``,
+		`Status`,
+		`Status = "running"`,
+		`Status == `,
+		`Status == "running" and`,
+		`(Status == "running"`,
+		`Status == "running")`,
+		`Status == "running`,
+		`"foo" == Status`,
+		`ID matches "["`,
+		`Status is full`,
+	}
+
+	for _, expr := range cases {
+		if _, err := Parse(expr); err == nil {
+			t.Fatalf("Parse(%q) should have failed", expr)
+		}
+	}
+}
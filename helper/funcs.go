@@ -59,6 +59,11 @@ func Uint64ToPtr(u uint64) *uint64 {
 	return &u
 }
 
+// Float64ToPtr returns the pointer to a float64
+func Float64ToPtr(f float64) *float64 {
+	return &f
+}
+
 // StringToPtr returns the pointer to a string
 func StringToPtr(str string) *string {
 	return &str
@@ -114,6 +119,16 @@ func SliceStringToSet(s []string) map[string]struct{} {
 	return m
 }
 
+// SliceStringContains returns whether the slice contains the given string.
+func SliceStringContains(list []string, item string) bool {
+	for _, s := range list {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
+
 // SliceStringIsSubset returns whether the smaller set of strings is a subset of
 // the larger. If the smaller slice is not a subset, the offending elements are
 // returned.
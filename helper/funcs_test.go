@@ -21,6 +21,16 @@ func TestSliceStringIsSubset(t *testing.T) {
 	}
 }
 
+func TestSliceStringContains(t *testing.T) {
+	l := []string{"a", "b", "c"}
+	if !SliceStringContains(l, "b") {
+		t.Fatalf("expected %v to contain b", l)
+	}
+	if SliceStringContains(l, "d") {
+		t.Fatalf("expected %v to not contain d", l)
+	}
+}
+
 func TestMapStringStringSliceValueSet(t *testing.T) {
 	m := map[string][]string{
 		"foo": []string{"1", "2"},
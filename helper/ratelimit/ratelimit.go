@@ -0,0 +1,138 @@
+// Package ratelimit implements request rate limiting with a global limit and
+// a limit per key, such as the client's IP address.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// gcInterval is the interval at which idle keys are removed.
+	gcInterval = 1 * time.Minute
+
+	// idleTimeout is how long a key must be unused to be removed. A key that
+	// has been unused for this long has refilled its bucket so removing it
+	// does not change the limit.
+	idleTimeout = 5 * time.Minute
+)
+
+// Config configures a Limiter. Rates are in requests per second and a zero
+// rate disables the respective limit. If a burst is not set it defaults to
+// the rate.
+type Config struct {
+	Rate        float64
+	Burst       int
+	RatePerKey  float64
+	BurstPerKey int
+}
+
+// Enabled returns whether any limit is configured.
+func (c *Config) Enabled() bool {
+	return c.Rate > 0 || c.RatePerKey > 0
+}
+
+// Limiter limits the rate of requests globally and per key. It is safe for
+// concurrent use.
+type Limiter struct {
+	config Config
+	global *rate.Limiter
+
+	keys   map[string]*keyLimiter
+	lastGC time.Time
+	l      sync.Mutex
+}
+
+// keyLimiter is the limiter of a single key.
+type keyLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New returns a Limiter for the configuration or nil if no limit is
+// configured. A nil Limiter allows all requests.
+func New(config Config) *Limiter {
+	if !config.Enabled() {
+		return nil
+	}
+
+	l := &Limiter{
+		config: config,
+		keys:   make(map[string]*keyLimiter),
+		lastGC: time.Now(),
+	}
+	if config.Rate > 0 {
+		l.global = rate.NewLimiter(rate.Limit(config.Rate), burst(config.Rate, config.Burst))
+	}
+	return l
+}
+
+// burst returns the burst of a limit, defaulting to the rate.
+func burst(r float64, b int) int {
+	if b > 0 {
+		return b
+	}
+	return int(math.Max(1, math.Ceil(r)))
+}
+
+// Allow returns whether a request for the key may happen now. A request that
+// is denied doesn't consume any token.
+func (l *Limiter) Allow(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	// The global limit is checked first so that the requests it denies don't
+	// use up the limit of their key. Its token is returned if the key is
+	// over its own limit.
+	now := time.Now()
+	var global *rate.Reservation
+	if l.global != nil {
+		global = l.global.ReserveN(now, 1)
+		if !global.OK() || global.DelayFrom(now) > 0 {
+			global.CancelAt(now)
+			return false
+		}
+	}
+	if kl := l.keyLimiter(key, now); kl != nil && !kl.AllowN(now, 1) {
+		if global != nil {
+			global.CancelAt(now)
+		}
+		return false
+	}
+	return true
+}
+
+// keyLimiter returns the limiter of the key, creating it if needed. It
+// returns nil if there is no limit per key.
+func (l *Limiter) keyLimiter(key string, now time.Time) *rate.Limiter {
+	if l.config.RatePerKey <= 0 {
+		return nil
+	}
+
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if now.Sub(l.lastGC) > gcInterval {
+		for k, kl := range l.keys {
+			if now.Sub(kl.lastSeen) > idleTimeout {
+				delete(l.keys, k)
+			}
+		}
+		l.lastGC = now
+	}
+
+	kl, ok := l.keys[key]
+	if !ok {
+		kl = &keyLimiter{
+			limiter: rate.NewLimiter(rate.Limit(l.config.RatePerKey),
+				burst(l.config.RatePerKey, l.config.BurstPerKey)),
+		}
+		l.keys[key] = kl
+	}
+	kl.lastSeen = now
+	return kl.limiter
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestLimiter_Disabled(t *testing.T) {
+	l := New(Config{})
+	if l != nil {
+		t.Fatalf("expected nil limiter")
+	}
+	for i := 0; i < 100; i++ {
+		if !l.Allow("foo") {
+			t.Fatalf("nil limiter should allow all requests")
+		}
+	}
+}
+
+func TestLimiter_Global(t *testing.T) {
+	l := New(Config{Rate: 1, Burst: 3})
+	for i := 0; i < 3; i++ {
+		if !l.Allow("foo") {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if l.Allow("bar") {
+		t.Fatalf("global limit should apply to all keys")
+	}
+}
+
+func TestLimiter_PerKey(t *testing.T) {
+	l := New(Config{RatePerKey: 2})
+	for i := 0; i < 2; i++ {
+		if !l.Allow("foo") {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if l.Allow("foo") {
+		t.Fatalf("request over the key's burst should not be allowed")
+	}
+	if !l.Allow("bar") {
+		t.Fatalf("other keys should not be limited")
+	}
+}
+
+func TestLimiter_Denied(t *testing.T) {
+	// Requests denied by the global limit don't use up the limit of the key
+	l := New(Config{Rate: 1, Burst: 1, RatePerKey: 1, BurstPerKey: 1})
+	if !l.Allow("foo") {
+		t.Fatalf("first request should be allowed")
+	}
+	if l.Allow("bar") {
+		t.Fatalf("global limit should apply to all keys")
+	}
+	l.global.SetLimit(rate.Inf)
+	if !l.Allow("bar") {
+		t.Fatalf("denied request should not use the key's token")
+	}
+
+	// Requests denied by the limit of their key don't use up the global limit
+	l = New(Config{Rate: 1, Burst: 2, RatePerKey: 1, BurstPerKey: 1})
+	if !l.Allow("foo") {
+		t.Fatalf("first request should be allowed")
+	}
+	if l.Allow("foo") {
+		t.Fatalf("request over the key's burst should not be allowed")
+	}
+	if !l.Allow("bar") {
+		t.Fatalf("denied request should not use the global token")
+	}
+}
@@ -60,6 +60,24 @@ type Config struct {
 	// KeyFile is used to provide a TLS key that is used for serving TLS connections.
 	// Must be provided to serve TLS connections.
 	KeyFile string
+
+	// loader loads the files and reloads them when they are renewed. It is
+	// shared by the TLS configurations generated from the Config.
+	loader *KeyLoader
+}
+
+// KeyLoader returns the KeyLoader of the Config, loading its files the first
+// time it is called.
+func (c *Config) KeyLoader() (*KeyLoader, error) {
+	if c.loader != nil {
+		return c.loader, nil
+	}
+	loader, err := NewKeyLoader(c)
+	if err != nil {
+		return nil, err
+	}
+	c.loader = loader
+	return loader, nil
 }
 
 // AppendCA opens and parses the CA file and adds the certificates to
@@ -105,32 +123,29 @@ func (c *Config) OutgoingTLSConfig() (*tls.Config, error) {
 	if !c.VerifyOutgoing {
 		return nil, nil
 	}
-	// Create the tlsConfig
-	tlsConfig := &tls.Config{
-		RootCAs:            x509.NewCertPool(),
-		InsecureSkipVerify: true,
-	}
-	if c.VerifyServerHostname {
-		tlsConfig.InsecureSkipVerify = false
-	}
-
 	// Ensure we have a CA if VerifyOutgoing is set
 	if c.VerifyOutgoing && c.CAFile == "" {
 		return nil, fmt.Errorf("VerifyOutgoing set, and no CA certificate provided!")
 	}
 
-	// Parse the CA cert if any
-	err := c.AppendCA(tlsConfig.RootCAs)
+	// Parse the CA cert and cert/key
+	loader, err := c.KeyLoader()
 	if err != nil {
 		return nil, err
 	}
 
-	// Add cert/key
-	cert, err := c.KeyPair()
-	if err != nil {
-		return nil, err
-	} else if cert != nil {
-		tlsConfig.Certificates = []tls.Certificate{*cert}
+	// Create the tlsConfig. The CA pool is refreshed by the wrapper for each
+	// connection and the certificate is fetched during the handshake, so
+	// that renewed files are used without restarting.
+	tlsConfig := &tls.Config{
+		RootCAs:            loader.CAPool(),
+		InsecureSkipVerify: true,
+	}
+	if c.VerifyServerHostname {
+		tlsConfig.InsecureSkipVerify = false
+	}
+	if loader.Certificate() != nil {
+		tlsConfig.GetClientCertificate = loader.GetClientCertificate
 	}
 
 	return tlsConfig, nil
@@ -150,18 +165,22 @@ func (c *Config) OutgoingTLSWrapper() (RegionWrapper, error) {
 	if tlsConfig == nil {
 		return nil, nil
 	}
+	loader := c.loader
 
 	// Generate the wrapper based on hostname verification
 	if c.VerifyServerHostname {
 		wrapper := func(region string, conn net.Conn) (net.Conn, error) {
 			conf := tlsConfig.Clone()
+			conf.RootCAs = loader.CAPool()
 			conf.ServerName = "server." + region + ".nomad"
 			return WrapTLSClient(conn, conf)
 		}
 		return wrapper, nil
 	} else {
 		wrapper := func(dc string, c net.Conn) (net.Conn, error) {
-			return WrapTLSClient(c, tlsConfig)
+			conf := tlsConfig.Clone()
+			conf.RootCAs = loader.CAPool()
+			return WrapTLSClient(c, conf)
 		}
 		return wrapper, nil
 	}
@@ -223,24 +242,27 @@ func WrapTLSClient(conn net.Conn, tlsConfig *tls.Config) (net.Conn, error) {
 
 // IncomingTLSConfig generates a TLS configuration for incoming requests
 func (c *Config) IncomingTLSConfig() (*tls.Config, error) {
-	// Create the tlsConfig
-	tlsConfig := &tls.Config{
-		ClientCAs:  x509.NewCertPool(),
-		ClientAuth: tls.NoClientCert,
-	}
-
-	// Parse the CA cert if any
-	err := c.AppendCA(tlsConfig.ClientCAs)
+	// Parse the CA cert and cert/key if any
+	loader, err := c.KeyLoader()
 	if err != nil {
 		return nil, err
 	}
 
-	// Add cert/key
-	cert, err := c.KeyPair()
-	if err != nil {
-		return nil, err
-	} else if cert != nil {
-		tlsConfig.Certificates = []tls.Certificate{*cert}
+	// Create the tlsConfig. The certificate and client CAs are fetched for
+	// each handshake, so that renewed files are used without restarting.
+	tlsConfig := &tls.Config{
+		ClientCAs:  loader.CAPool(),
+		ClientAuth: tls.NoClientCert,
+	}
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		conf := tlsConfig.Clone()
+		conf.ClientCAs = loader.CAPool()
+		conf.GetConfigForClient = nil
+		return conf, nil
+	}
+	cert := loader.Certificate()
+	if cert != nil {
+		tlsConfig.GetCertificate = loader.GetCertificate
 	}
 
 	// Check if we require verification
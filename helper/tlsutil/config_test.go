@@ -157,7 +157,7 @@ func TestConfig_OutgoingTLS_WithKeyPair(t *testing.T) {
 	if !tls.InsecureSkipVerify {
 		t.Fatalf("should skip verification")
 	}
-	if len(tls.Certificates) != 1 {
+	if tls.GetClientCertificate == nil {
 		t.Fatalf("expected client cert")
 	}
 }
@@ -354,7 +354,7 @@ func TestConfig_IncomingTLS(t *testing.T) {
 	if tlsC.ClientAuth != tls.RequireAndVerifyClientCert {
 		t.Fatalf("should not skip verification")
 	}
-	if len(tlsC.Certificates) != 1 {
+	if tlsC.GetCertificate == nil {
 		t.Fatalf("expected client cert")
 	}
 }
@@ -397,7 +397,7 @@ func TestConfig_IncomingTLS_NoVerify(t *testing.T) {
 	if tlsC.ClientAuth != tls.NoClientCert {
 		t.Fatalf("should skip verification")
 	}
-	if len(tlsC.Certificates) != 0 {
+	if tlsC.GetCertificate != nil {
 		t.Fatalf("unexpected client cert")
 	}
 }
@@ -0,0 +1,214 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// CAOpts are the options of the CA generated by GenerateCA.
+type CAOpts struct {
+	// CommonName is the common name of the CA certificate
+	CommonName string
+
+	// Validity is the duration the CA certificate is valid for
+	Validity time.Duration
+}
+
+// CertOpts are the options of the certificates generated by GenerateCert.
+type CertOpts struct {
+	// CommonName is the common name of the certificate
+	CommonName string
+
+	// DNSNames and IPAddresses are the subject alternative names of the
+	// certificate
+	DNSNames    []string
+	IPAddresses []net.IP
+
+	// ExtKeyUsage are the usages of the certificate, server and/or client
+	// authentication.
+	ExtKeyUsage []x509.ExtKeyUsage
+
+	// Validity is the duration the certificate is valid for
+	Validity time.Duration
+
+	// CA and CAKey are the PEM encoded certificate and key of the CA signing
+	// the certificate
+	CA    string
+	CAKey string
+}
+
+// GenerateCA generates a self-signed CA certificate and its key, PEM encoded,
+// to sign the certificates of the agents and operators of a cluster.
+func GenerateCA(opts CAOpts) (string, string, error) {
+	key, keyPEM, err := generateKey()
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return "", "", err
+	}
+	id, err := keyID(key.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: opts.CommonName},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(opts.Validity),
+		SubjectKeyId:          id,
+		AuthorityKeyId:        id,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create CA certificate: %v", err)
+	}
+	return encodePEM("CERTIFICATE", der), keyPEM, nil
+}
+
+// GenerateCert generates a certificate and its key, PEM encoded, signed by
+// the given CA.
+func GenerateCert(opts CertOpts) (string, string, error) {
+	ca, err := ParseCertificate(opts.CA)
+	if err != nil {
+		return "", "", err
+	}
+	signer, err := ParseSigner(opts.CAKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	key, keyPEM, err := generateKey()
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := serialNumber()
+	if err != nil {
+		return "", "", err
+	}
+	id, err := keyID(key.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: opts.CommonName},
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           opts.ExtKeyUsage,
+		DNSNames:              opts.DNSNames,
+		IPAddresses:           opts.IPAddresses,
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(opts.Validity),
+		SubjectKeyId:          id,
+		AuthorityKeyId:        ca.SubjectKeyId,
+	}
+	if template.NotAfter.After(ca.NotAfter) {
+		return "", "", fmt.Errorf("certificate would expire after its CA, on %s", ca.NotAfter.Format(time.RFC3339))
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), signer)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create certificate: %v", err)
+	}
+	return encodePEM("CERTIFICATE", der), keyPEM, nil
+}
+
+// ParseCertificate parses a PEM encoded certificate.
+func ParseCertificate(data string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	return cert, nil
+}
+
+// ParseSigner parses a PEM encoded EC, RSA or PKCS #8 private key.
+func ParseSigner(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("private key can't be used for signing")
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %q", block.Type)
+	}
+}
+
+// generateKey generates an ECDSA P-256 key and returns it PEM encoded.
+func generateKey() (crypto.Signer, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate private key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode private key: %v", err)
+	}
+	return key, encodePEM("EC PRIVATE KEY", der), nil
+}
+
+// serialNumber returns a random 128 bit serial number.
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+	return serial, nil
+}
+
+// keyID returns the identifier of the public key, used as the subject key
+// identifier of the certificates.
+func keyID(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return sum[:], nil
+}
+
+func encodePEM(typ string, der []byte) string {
+	var buf bytes.Buffer
+	pem.Encode(&buf, &pem.Block{Type: typ, Bytes: der})
+	return buf.String()
+}
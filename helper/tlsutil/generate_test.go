@@ -0,0 +1,50 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGenerateCert(t *testing.T) {
+	ca, caKey, err := GenerateCA(CAOpts{CommonName: "Nomad Agent CA", Validity: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cert, key, err := GenerateCert(CertOpts{
+		CommonName:  "server.global.nomad",
+		DNSNames:    []string{"server.global.nomad", "localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		Validity:    time.Hour,
+		CA:          ca,
+		CAKey:       caKey,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := tls.X509KeyPair([]byte(cert), []byte(key)); err != nil {
+		t.Fatalf("invalid key pair: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(ca)) {
+		t.Fatalf("invalid CA")
+	}
+	parsed, err := ParseCertificate(cert)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := parsed.Verify(x509.VerifyOptions{Roots: pool, DNSName: "server.global.nomad"}); err != nil {
+		t.Fatalf("certificate not signed by the CA: %v", err)
+	}
+
+	// Certificates can't outlive their CA
+	_, _, err = GenerateCert(CertOpts{Validity: 48 * time.Hour, CA: ca, CAKey: caKey})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,167 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// reloadCheckInterval is the minimum interval between the checks for
+	// renewed certificate, key and CA files. Files are checked when
+	// connections are established.
+	reloadCheckInterval = 5 * time.Second
+)
+
+// KeyLoader loads the certificate, key and CA files of a Config and reloads
+// them when they are modified, so that certificates renewed on disk are used
+// by new connections without restarting the agent. Existing connections keep
+// the certificates they were established with.
+type KeyLoader struct {
+	config *Config
+
+	cert   *tls.Certificate
+	caPool *x509.CertPool
+
+	// modTimes are the modification times of the files when last loaded
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+	lock      sync.RWMutex
+}
+
+// NewKeyLoader returns a KeyLoader that has loaded the files of the Config.
+func NewKeyLoader(c *Config) (*KeyLoader, error) {
+	l := &KeyLoader{config: c}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// load reads the files and replaces the certificate and CA pool.
+func (l *KeyLoader) load() error {
+	modTimes, err := l.statFiles()
+	if err != nil {
+		return err
+	}
+
+	caPool := x509.NewCertPool()
+	if err := l.config.AppendCA(caPool); err != nil {
+		return err
+	}
+	cert, err := l.config.KeyPair()
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.cert = cert
+	l.caPool = caPool
+	l.modTimes = modTimes
+	l.lastCheck = time.Now()
+	return nil
+}
+
+// statFiles returns the modification times of the configured files.
+func (l *KeyLoader) statFiles() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time, 3)
+	for _, path := range []string{l.config.CAFile, l.config.CertFile, l.config.KeyFile} {
+		if path == "" {
+			continue
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to stat %q: %v", path, err)
+		}
+		modTimes[path] = fi.ModTime()
+	}
+	return modTimes, nil
+}
+
+// Reload reloads the files if any was modified since they were loaded. An
+// error is returned if the files can't be loaded, in which case the
+// previously loaded certificates are kept.
+func (l *KeyLoader) Reload() error {
+	modTimes, err := l.statFiles()
+	if err != nil {
+		return err
+	}
+
+	l.lock.RLock()
+	changed := len(modTimes) != len(l.modTimes)
+	for path, t := range modTimes {
+		if !l.modTimes[path].Equal(t) {
+			changed = true
+		}
+	}
+	l.lock.RUnlock()
+
+	if !changed {
+		l.lock.Lock()
+		l.lastCheck = time.Now()
+		l.lock.Unlock()
+		return nil
+	}
+	return l.load()
+}
+
+// maybeReload reloads the files if they haven't been checked recently. A
+// renewal is usually written as several files, so failures are retried on
+// the next check rather than surfaced to the connection.
+func (l *KeyLoader) maybeReload() {
+	l.lock.RLock()
+	due := time.Since(l.lastCheck) >= reloadCheckInterval
+	l.lock.RUnlock()
+	if !due {
+		return
+	}
+
+	if err := l.Reload(); err != nil {
+		l.lock.Lock()
+		l.lastCheck = time.Now()
+		l.lock.Unlock()
+	}
+}
+
+// Certificate returns the current certificate, which may be nil if no
+// certificate is configured.
+func (l *KeyLoader) Certificate() *tls.Certificate {
+	l.maybeReload()
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.cert
+}
+
+// CAPool returns the pool of the current CA certificates.
+func (l *KeyLoader) CAPool() *x509.CertPool {
+	l.maybeReload()
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.caPool
+}
+
+// GetCertificate returns the current certificate to present to clients. It
+// is meant to be used as the GetCertificate function of a tls.Config.
+func (l *KeyLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := l.Certificate()
+	if cert == nil {
+		return nil, fmt.Errorf("No certificate configured")
+	}
+	return cert, nil
+}
+
+// GetClientCertificate returns the current certificate to present to servers.
+// It is meant to be used as the GetClientCertificate function of a
+// tls.Config.
+func (l *KeyLoader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert := l.Certificate()
+	if cert == nil {
+		// Continue the handshake without a certificate
+		return &tls.Certificate{}, nil
+	}
+	return cert, nil
+}
@@ -0,0 +1,81 @@
+package tlsutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyLoader_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsutil")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := &Config{
+		CAFile:   filepath.Join(dir, "ca.pem"),
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+	}
+	ca, caKey, err := GenerateCA(CAOpts{CommonName: "Nomad Agent CA", Validity: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	writeCert := func(modTime time.Time) {
+		cert, key, err := GenerateCert(CertOpts{Validity: time.Hour, CA: ca, CAKey: caKey})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		for path, data := range map[string]string{conf.CAFile: ca, conf.CertFile: cert, conf.KeyFile: key} {
+			if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+	}
+	writeCert(time.Now().Add(-time.Hour))
+
+	loader, err := conf.KeyLoader()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	first := loader.Certificate()
+	if first == nil {
+		t.Fatalf("expected certificate")
+	}
+
+	// Unmodified files aren't reloaded
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if loader.Certificate() != first {
+		t.Fatalf("certificate shouldn't have been reloaded")
+	}
+
+	// Renewed certificates are loaded
+	writeCert(time.Now())
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	renewed := loader.Certificate()
+	if bytes.Equal(renewed.Certificate[0], first.Certificate[0]) {
+		t.Fatalf("certificate should have been reloaded")
+	}
+
+	// A partially written renewal keeps the current certificate
+	if err := ioutil.WriteFile(conf.KeyFile, []byte("invalid"), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := loader.Reload(); err == nil {
+		t.Fatalf("expected error")
+	}
+	if loader.Certificate() != renewed {
+		t.Fatalf("certificate should have been kept")
+	}
+}
@@ -0,0 +1,277 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+const (
+	// exportInterval is the interval at which buffered spans are exported
+	exportInterval = 5 * time.Second
+
+	// exportBatchSize is the number of buffered spans triggering an export
+	// before the interval elapses
+	exportBatchSize = 512
+
+	// exportQueueSize is the number of spans that can be buffered. Spans
+	// ended while the queue is full are dropped.
+	exportQueueSize = 4096
+
+	// exportTimeout is the time the collector has to accept a batch
+	exportTimeout = 10 * time.Second
+
+	// otlpStatusError is the OTLP status code of failed spans
+	otlpStatusError = 2
+)
+
+// exporter batches the ended spans and posts them to an OTLP/HTTP receiver
+// using the JSON encoding.
+type exporter struct {
+	url      string
+	resource otlpResource
+	client   *http.Client
+	logger   *log.Logger
+
+	spanCh     chan *Span
+	shutdownCh chan struct{}
+	doneCh     chan struct{}
+}
+
+func newExporter(config *Config, logger *log.Logger) *exporter {
+	resource := otlpResource{
+		Attributes: []otlpKeyValue{newKeyValue("service.name", config.ServiceName)},
+	}
+	keys := make([]string, 0, len(config.Attributes))
+	for k := range config.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resource.Attributes = append(resource.Attributes, newKeyValue(k, config.Attributes[k]))
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = exportTimeout
+	e := &exporter{
+		url:        strings.TrimSuffix(config.Endpoint, "/") + "/v1/traces",
+		resource:   resource,
+		client:     client,
+		logger:     logger,
+		spanCh:     make(chan *Span, exportQueueSize),
+		shutdownCh: make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// export queues an ended span, dropping it if the queue is full.
+func (e *exporter) export(s *Span) {
+	select {
+	case e.spanCh <- s:
+	default:
+		metrics.IncrCounter([]string{"nomad", "tracing", "dropped_spans"}, 1)
+	}
+}
+
+// shutdown exports the queued spans and stops the exporter.
+func (e *exporter) shutdown() {
+	select {
+	case <-e.shutdownCh:
+	default:
+		close(e.shutdownCh)
+	}
+	<-e.doneCh
+}
+
+func (e *exporter) run() {
+	defer close(e.doneCh)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case s := <-e.spanCh:
+			batch = append(batch, s)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-e.shutdownCh:
+			// Drain the queue before the final export
+			for len(e.spanCh) > 0 {
+				batch = append(batch, <-e.spanCh)
+			}
+			e.post(batch)
+			return
+		}
+
+		e.post(batch)
+		batch = nil
+	}
+}
+
+// post exports a batch of spans. Failed exports are logged and the spans are
+// dropped.
+func (e *exporter) post(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	spans := make([]*otlpSpan, len(batch))
+	for i, s := range batch {
+		spans[i] = s.otlp()
+	}
+	req := &otlpTracesRequest{
+		ResourceSpans: []*otlpResourceSpans{{
+			Resource: e.resource,
+			ScopeSpans: []*otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/hashicorp/nomad"},
+				Spans: spans,
+			}},
+		}},
+	}
+	buf, err := json.Marshal(req)
+	if err != nil {
+		e.logger.Printf("[ERR] tracing: failed to encode spans: %v", err)
+		return
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		e.logger.Printf("[WARN] tracing: failed to export %d spans: %v", len(batch), err)
+		metrics.IncrCounter([]string{"nomad", "tracing", "dropped_spans"}, float32(len(batch)))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		e.logger.Printf("[WARN] tracing: failed to export %d spans: unexpected response code %d: %s",
+			len(batch), resp.StatusCode, bytes.TrimSpace(body))
+		metrics.IncrCounter([]string{"nomad", "tracing", "dropped_spans"}, float32(len(batch)))
+		return
+	}
+	metrics.IncrCounter([]string{"nomad", "tracing", "exported_spans"}, float32(len(batch)))
+}
+
+// otlp returns the OTLP representation of an ended span.
+func (s *Span) otlp() *otlpSpan {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	out := &otlpSpan{
+		TraceID:           s.traceID,
+		SpanID:            s.spanID,
+		ParentSpanID:      s.parentID,
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	keys := make([]string, 0, len(s.attributes))
+	for k := range s.attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		out.Attributes = append(out.Attributes, newKeyValue(k, s.attributes[k]))
+	}
+	if s.err != "" {
+		out.Status = &otlpStatus{Code: otlpStatusError, Message: s.err}
+	}
+	return out
+}
+
+// The types below are the subset of the OTLP/JSON trace export request used
+// by the exporter. IDs are hex encoded and times are decimal strings, as the
+// OTLP JSON mapping requires.
+
+type otlpTracesRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// newKeyValue returns the OTLP attribute of a value. Values of other types
+// than strings, integers, floats and booleans are formatted as strings.
+func newKeyValue(key string, value interface{}) otlpKeyValue {
+	var v otlpValue
+	switch value := value.(type) {
+	case string:
+		v.StringValue = &value
+	case int:
+		i := strconv.FormatInt(int64(value), 10)
+		v.IntValue = &i
+	case int64:
+		i := strconv.FormatInt(value, 10)
+		v.IntValue = &i
+	case uint64:
+		i := strconv.FormatUint(value, 10)
+		v.IntValue = &i
+	case float64:
+		v.DoubleValue = &value
+	case bool:
+		v.BoolValue = &value
+	default:
+		s := fmt.Sprintf("%v", value)
+		v.StringValue = &s
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
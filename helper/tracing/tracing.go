@@ -0,0 +1,265 @@
+// Package tracing records trace spans and exports them to an OpenTelemetry
+// collector with OTLP. Trace contexts are propagated between agents, and
+// through evaluations and plans, as W3C traceparent strings.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	mrand "math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SpanKindInternal, SpanKindServer and SpanKindClient are the OTLP kinds
+	// of spans.
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+
+	// traceParentVersion is the supported version of the traceparent format
+	traceParentVersion = "00"
+)
+
+// Config configures a Tracer. Tracing is disabled unless an endpoint is set.
+type Config struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver of the collector,
+	// for example http://127.0.0.1:4318. Spans are posted to /v1/traces.
+	Endpoint string
+
+	// ServiceName is the service.name attribute of the exported spans.
+	ServiceName string
+
+	// SampleRate is the fraction of the traces started by this agent that
+	// are recorded, from 0 to 1. Traces continued from a parent follow the
+	// sampling decision of the parent.
+	SampleRate float64
+
+	// Attributes are added to the resource of the exported spans, for
+	// example the name of the node.
+	Attributes map[string]string
+}
+
+// Enabled returns whether spans are exported.
+func (c *Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Tracer starts spans and exports the recorded ones. It is safe for
+// concurrent use. A nil Tracer starts nil spans, which record nothing but are
+// safe to use.
+type Tracer struct {
+	config   Config
+	exporter *exporter
+
+	rand *mrand.Rand
+	l    sync.Mutex
+}
+
+// New returns a Tracer for the configuration or nil if tracing is disabled.
+func New(config Config, logger *log.Logger) *Tracer {
+	if !config.Enabled() {
+		return nil
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "nomad"
+	}
+	return &Tracer{
+		config:   config,
+		exporter: newExporter(&config, logger),
+		rand:     mrand.New(mrand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Start starts a span. The span continues the trace of the parent, a W3C
+// traceparent, or starts a new trace if the parent is empty or invalid.
+func (t *Tracer) Start(name, parent string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	s := &Span{
+		tracer: t,
+		name:   name,
+		kind:   SpanKindInternal,
+		start:  time.Now(),
+		spanID: newID(8),
+	}
+	if traceID, parentID, sampled, ok := parseTraceParent(parent); ok {
+		s.traceID = traceID
+		s.parentID = parentID
+		s.sampled = sampled
+	} else {
+		s.traceID = newID(16)
+		s.sampled = t.sample()
+	}
+	return s
+}
+
+// Shutdown exports the spans that are still buffered and stops the exporter.
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+	t.exporter.shutdown()
+}
+
+// sample returns whether a new trace is recorded.
+func (t *Tracer) sample() bool {
+	if t.config.SampleRate >= 1 {
+		return true
+	} else if t.config.SampleRate <= 0 {
+		return false
+	}
+	t.l.Lock()
+	defer t.l.Unlock()
+	return t.rand.Float64() < t.config.SampleRate
+}
+
+// Span is an operation of a trace. A nil Span records nothing.
+type Span struct {
+	tracer *Tracer
+
+	traceID  string
+	spanID   string
+	parentID string
+	sampled  bool
+
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	err        string
+	l          sync.Mutex
+}
+
+// TraceParent returns the W3C traceparent of the span, to be passed to the
+// operations it causes so their spans are its children. It is empty for a nil
+// span.
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return strings.Join([]string{traceParentVersion, s.traceID, s.spanID, flags}, "-")
+}
+
+// SetKind sets the OTLP kind of the span. Spans are internal by default.
+func (s *Span) SetKind(kind int) {
+	if s == nil {
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.kind = kind
+}
+
+// SetAttribute sets an attribute of the span. Values are exported as strings,
+// integers, floats or booleans.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]interface{})
+	}
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed with the error, if not nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.err = err.Error()
+}
+
+// End ends the span and queues it for export if its trace is sampled. Ending a
+// span more than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.l.Lock()
+	if !s.end.IsZero() {
+		s.l.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.l.Unlock()
+
+	if s.sampled {
+		s.tracer.exporter.export(s)
+	}
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a copy of the context holding the span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, s)
+}
+
+// SpanFromContext returns the span held by the context, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanContextKey{}).(*Span)
+	return s
+}
+
+// parseTraceParent parses a W3C traceparent into its trace ID, parent span ID
+// and sampled flag.
+func parseTraceParent(tp string) (traceID, parentID string, sampled, ok bool) {
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || parts[0] != traceParentVersion {
+		return "", "", false, false
+	}
+	if !validID(parts[1], 16) || !validID(parts[2], 8) || len(parts[3]) != 2 {
+		return "", "", false, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return "", "", false, false
+	}
+	return parts[1], parts[2], flags[0]&0x01 == 1, true
+}
+
+// validID returns whether the ID is the lowercase hex encoding of size bytes,
+// not all zeros.
+func validID(id string, size int) bool {
+	if len(id) != 2*size || strings.ToLower(id) != id {
+		return false
+	}
+	b, err := hex.DecodeString(id)
+	if err != nil {
+		return false
+	}
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newID returns a random ID of size bytes, hex encoded.
+func newID(size int) string {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(fmt.Errorf("failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
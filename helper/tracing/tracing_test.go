@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTracer_Disabled(t *testing.T) {
+	t.Parallel()
+	tracer := New(Config{}, log.New(os.Stderr, "", log.LstdFlags))
+	if tracer != nil {
+		t.Fatalf("expected a nil tracer")
+	}
+
+	// Nil tracers and spans are safe to use
+	span := tracer.Start("test", "")
+	span.SetAttribute("key", "value")
+	span.SetError(fmt.Errorf("failed"))
+	span.End()
+	if tp := span.TraceParent(); tp != "" {
+		t.Fatalf("expected no trace parent; got %q", tp)
+	}
+	tracer.Shutdown()
+}
+
+func TestTracer_Export(t *testing.T) {
+	t.Parallel()
+	requests := make(chan *otlpTracesRequest, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		var req otlpTracesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		requests <- &req
+	}))
+	defer ts.Close()
+
+	tracer := New(Config{
+		Endpoint:   ts.URL,
+		SampleRate: 1,
+		Attributes: map[string]string{"node.name": "node1"},
+	}, log.New(os.Stderr, "", log.LstdFlags))
+
+	parent := tracer.Start("parent", "")
+	parent.SetKind(SpanKindServer)
+	child := tracer.Start("child", parent.TraceParent())
+	child.SetAttribute("eval_id", "123")
+	child.SetError(fmt.Errorf("failed"))
+	child.End()
+	parent.End()
+
+	// Shutting down exports the buffered spans
+	tracer.Shutdown()
+	req := <-requests
+
+	if len(req.ResourceSpans) != 1 {
+		t.Fatalf("bad request: %#v", req)
+	}
+	rs := req.ResourceSpans[0]
+	if attrs := rs.Resource.Attributes; len(attrs) != 2 || attrs[0].Key != "service.name" ||
+		*attrs[0].Value.StringValue != "nomad" || *attrs[1].Value.StringValue != "node1" {
+		t.Fatalf("bad resource: %#v", rs.Resource)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans; got %d", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if c.Name != "child" || p.Name != "parent" {
+		t.Fatalf("bad spans: %#v, %#v", c, p)
+	}
+	if c.TraceID != p.TraceID || c.ParentSpanID != p.SpanID || p.ParentSpanID != "" {
+		t.Fatalf("child isn't a child of the parent: %#v, %#v", c, p)
+	}
+	if p.Kind != SpanKindServer || c.Kind != SpanKindInternal {
+		t.Fatalf("bad kinds: %d, %d", p.Kind, c.Kind)
+	}
+	if c.Status == nil || c.Status.Code != otlpStatusError || c.Status.Message != "failed" {
+		t.Fatalf("bad status: %#v", c.Status)
+	}
+	if len(c.Attributes) != 1 || c.Attributes[0].Key != "eval_id" {
+		t.Fatalf("bad attributes: %#v", c.Attributes)
+	}
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	t.Parallel()
+	tracer := &Tracer{config: Config{SampleRate: 0}}
+
+	// New traces aren't sampled
+	span := tracer.Start("test", "")
+	if span.sampled || !strings.HasSuffix(span.TraceParent(), "-00") {
+		t.Fatalf("expected span not to be sampled: %q", span.TraceParent())
+	}
+
+	// Traces continued from a parent follow its decision
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	span = tracer.Start("test", parent)
+	if !span.sampled || span.traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || span.parentID != "00f067aa0ba902b7" {
+		t.Fatalf("bad span: %#v", span)
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		tp      string
+		ok      bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"", false, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", false, false},
+	}
+	for _, c := range cases {
+		_, _, sampled, ok := parseTraceParent(c.tp)
+		if ok != c.ok || sampled != c.sampled {
+			t.Fatalf("%q: expected ok=%v sampled=%v; got ok=%v sampled=%v", c.tp, c.ok, c.sampled, ok, sampled)
+		}
+	}
+}
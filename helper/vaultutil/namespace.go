@@ -0,0 +1,52 @@
+package vaultutil
+
+import (
+	"net/http"
+
+	vapi "github.com/hashicorp/vault/api"
+)
+
+// NamespaceHeader is the header selecting the Vault Enterprise namespace of a
+// request. The vendored Vault API client predates namespaces so requests
+// within a namespace are built with the raw request API.
+const NamespaceHeader = "X-Vault-Namespace"
+
+// Write writes the body to the path like the Logical API of the client, but
+// within the Vault namespace. The namespace of the client is used if the
+// namespace is empty.
+func Write(client *vapi.Client, namespace, path string, body interface{}) (*vapi.Secret, error) {
+	return write(client, namespace, client.Token(), path, body)
+}
+
+// Unwrap unwraps the response wrapping token like the Logical API of the
+// client, but within the Vault namespace the token was created in.
+func Unwrap(client *vapi.Client, namespace, wrappingToken string) (*vapi.Secret, error) {
+	if namespace == "" {
+		return client.Logical().Unwrap(wrappingToken)
+	}
+	return write(client, namespace, wrappingToken, "sys/wrapping/unwrap", nil)
+}
+
+func write(client *vapi.Client, namespace, token, path string, body interface{}) (*vapi.Secret, error) {
+	r := client.NewRequest("PUT", "/v1/"+path)
+	r.ClientToken = token
+	if namespace != "" {
+		r.Headers = http.Header{NamespaceHeader: []string{namespace}}
+	}
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+
+	resp, err := client.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		return vapi.ParseSecret(resp.Body)
+	}
+	return nil, nil
+}
@@ -0,0 +1,74 @@
+package vaultutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vapi "github.com/hashicorp/vault/api"
+)
+
+func testClient(t *testing.T, handler http.HandlerFunc) (*vapi.Client, *httptest.Server) {
+	ts := httptest.NewServer(handler)
+
+	conf := vapi.DefaultConfig()
+	conf.Address = ts.URL
+	client, err := vapi.NewClient(conf)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client.SetToken("root")
+	return client, ts
+}
+
+func TestWrite_Namespace(t *testing.T) {
+	var namespace, token, path string
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		namespace = r.Header.Get(NamespaceHeader)
+		token = r.Header.Get("X-Vault-Token")
+		path = r.URL.Path
+		fmt.Fprint(w, `{"auth": {"client_token": "child"}}`)
+	})
+	defer ts.Close()
+
+	secret, err := Write(client, "team-a", "auth/jwt/login", map[string]interface{}{"role": "web"})
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken != "child" {
+		t.Fatalf("bad secret: %#v", secret)
+	}
+	if namespace != "team-a" || token != "root" || path != "/v1/auth/jwt/login" {
+		t.Fatalf("bad request: namespace %q, token %q, path %q", namespace, token, path)
+	}
+
+	// The namespace header is omitted for the namespace of the client
+	if _, err := Write(client, "", "auth/jwt/login", nil); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if namespace != "" {
+		t.Fatalf("expected no namespace; got %q", namespace)
+	}
+}
+
+func TestUnwrap_Namespace(t *testing.T) {
+	var namespace, token string
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		namespace = r.Header.Get(NamespaceHeader)
+		token = r.Header.Get("X-Vault-Token")
+		fmt.Fprint(w, `{"auth": {"client_token": "unwrapped"}}`)
+	})
+	defer ts.Close()
+
+	secret, err := Unwrap(client, "team-a", "wrapping")
+	if err != nil {
+		t.Fatalf("failed to unwrap: %v", err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken != "unwrapped" {
+		t.Fatalf("bad secret: %#v", secret)
+	}
+	if namespace != "team-a" || token != "wrapping" {
+		t.Fatalf("bad request: namespace %q, token %q", namespace, token)
+	}
+}
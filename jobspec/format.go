@@ -0,0 +1,823 @@
+package jobspec
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// reIdentifier matches the keys that can be written without quotes.
+var reIdentifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_\-\.]*$`)
+
+// Format returns the job specification of the given job in HCL. The result can
+// be parsed back with Parse and submitted. Fields that are set by the servers,
+// such as the job status or indexes, are omitted.
+func Format(job *api.Job) (string, error) {
+	if job == nil || job.ID == nil {
+		return "", fmt.Errorf("job must have an ID")
+	}
+
+	w := &hclWriter{}
+	if err := w.job(job); err != nil {
+		return "", err
+	}
+	return w.buf.String(), nil
+}
+
+// hclWriter writes an indented HCL document.
+type hclWriter struct {
+	buf    bytes.Buffer
+	indent int
+
+	// empty is set when nothing was written in the current block yet.
+	empty bool
+
+	// blank is set when a blank line should separate the next item from the
+	// previous one.
+	blank bool
+}
+
+// line writes a line at the current indentation, separating it from the
+// previous item if needed.
+func (w *hclWriter) line(s string) {
+	if w.blank && !w.empty {
+		w.buf.WriteString("\n")
+	}
+	w.blank = false
+	w.empty = false
+	w.buf.WriteString(strings.Repeat("  ", w.indent))
+	w.buf.WriteString(s)
+	w.buf.WriteString("\n")
+}
+
+// open starts a block with the given name and labels.
+func (w *hclWriter) open(name string, labels ...string) {
+	parts := []string{name}
+	for _, l := range labels {
+		parts = append(parts, quoteString(l))
+	}
+	w.blank = true
+	w.line(strings.Join(parts, " ") + " {")
+	w.indent++
+	w.empty = true
+}
+
+// close ends the current block and separates it from the next item.
+func (w *hclWriter) close() {
+	w.indent--
+	w.blank = false
+	w.line("}")
+	w.blank = true
+}
+
+// attr writes an attribute with a value of any supported type.
+func (w *hclWriter) attr(key string, value interface{}) error {
+	s, err := w.value(value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	w.line(fmt.Sprintf("%s = %s", formatKey(key), s))
+	return nil
+}
+
+func (w *hclWriter) str(key string, value *string) {
+	if value != nil {
+		w.attr(key, *value)
+	}
+}
+
+func (w *hclWriter) integer(key string, value *int) {
+	if value != nil {
+		w.attr(key, *value)
+	}
+}
+
+func (w *hclWriter) boolean(key string, value *bool) {
+	if value != nil {
+		w.attr(key, *value)
+	}
+}
+
+func (w *hclWriter) duration(key string, value *time.Duration) {
+	if value != nil {
+		w.attr(key, value.String())
+	}
+}
+
+func (w *hclWriter) strings(key string, values []string) {
+	if len(values) != 0 {
+		w.attr(key, values)
+	}
+}
+
+// stringMap writes a block holding the given map, if it isn't empty.
+func (w *hclWriter) stringMap(name string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+
+	w.open(name)
+	for _, k := range sortedKeys(m) {
+		w.attr(k, m[k])
+	}
+	w.close()
+}
+
+// object writes the entries of a free form map, such as a task config. Maps
+// and lists of maps are written as blocks, as they are decoded when parsed.
+func (w *hclWriter) object(m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := reflect.ValueOf(m[k])
+		if !v.IsValid() {
+			continue
+		}
+
+		switch {
+		case v.Kind() == reflect.Map:
+			if err := w.objectBlock(k, v); err != nil {
+				return err
+			}
+			continue
+		case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Len() != 0 && allMaps(v):
+			for i := 0; i < v.Len(); i++ {
+				if err := w.objectBlock(k, elem(v.Index(i))); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := w.attr(k, v.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *hclWriter) objectBlock(key string, v reflect.Value) error {
+	m, err := toObject(v)
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+
+	w.open(formatKey(key))
+	if err := w.object(m); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	w.close()
+	return nil
+}
+
+// value formats a literal value.
+func (w *hclWriter) value(i interface{}) (string, error) {
+	v := elem(reflect.ValueOf(i))
+	switch v.Kind() {
+	case reflect.String:
+		return quoteString(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s, err := w.value(v.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case reflect.Map:
+		m, err := toObject(v)
+		if err != nil {
+			return "", err
+		}
+
+		inner := &hclWriter{indent: w.indent + 1}
+		if err := inner.object(m); err != nil {
+			return "", err
+		}
+		return "{\n" + inner.buf.String() + strings.Repeat("  ", w.indent) + "}", nil
+	default:
+		return "", fmt.Errorf("unsupported value of type %s", v.Kind())
+	}
+}
+
+func (w *hclWriter) job(job *api.Job) error {
+	w.open("job", *job.ID)
+	if job.Name != nil && *job.Name != *job.ID {
+		w.str("name", job.Name)
+	}
+	w.str("region", job.Region)
+	w.str("namespace", job.Namespace)
+	w.str("type", job.Type)
+	w.integer("priority", job.Priority)
+	if job.AllAtOnce != nil && *job.AllAtOnce {
+		w.boolean("all_at_once", job.AllAtOnce)
+	}
+	w.strings("datacenters", job.Datacenters)
+	w.strings("depends_on", job.DependsOn)
+	w.str("vault_token", job.VaultToken)
+	w.blank = true
+
+	w.stringMap("meta", job.Meta)
+	w.constraints(job.Constraints)
+	w.update(jobUpdate(job.Update))
+
+	if p := job.Periodic; p != nil {
+		w.open("periodic")
+		if p.SpecType == nil || *p.SpecType == structs.PeriodicSpecCron {
+			if len(p.Specs) != 0 {
+				w.strings("crons", p.Specs)
+			} else {
+				w.str("cron", p.Spec)
+			}
+		}
+		w.boolean("prohibit_overlap", p.ProhibitOverlap)
+		w.str("time_zone", p.TimeZone)
+		w.str("dst_mode", p.DSTMode)
+		w.boolean("enabled", p.Enabled)
+		w.close()
+	}
+
+	if p := job.ParameterizedJob; p != nil {
+		w.open("parameterized")
+		if p.Payload != "" {
+			w.attr("payload", p.Payload)
+		}
+		w.strings("meta_required", p.MetaRequired)
+		w.strings("meta_optional", p.MetaOptional)
+
+		keys := make([]string, 0, len(p.MetaSchema))
+		for k := range p.MetaSchema {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			schema := p.MetaSchema[k]
+			w.open("meta_schema", k)
+			if schema.Type != "" {
+				w.attr("type", schema.Type)
+			}
+			if schema.Pattern != "" {
+				w.attr("pattern", schema.Pattern)
+			}
+			w.strings("allowed", schema.Allowed)
+			w.close()
+		}
+
+		if schema := p.PayloadSchema; schema != nil {
+			w.open("payload_schema")
+			if schema.MaxSize != 0 {
+				w.attr("max_size", schema.MaxSize)
+			}
+			if schema.ContentType != "" {
+				w.attr("content_type", schema.ContentType)
+			}
+			w.strings("required_fields", schema.RequiredFields)
+			w.close()
+		}
+		w.close()
+	}
+
+	if gc := job.GC; gc != nil && !isZeroGC(gc) {
+		w.open("gc")
+		w.nonZeroDuration("alloc_threshold", gc.AllocThreshold)
+		w.nonZeroDuration("eval_threshold", gc.EvalThreshold)
+		w.nonZeroDuration("deployment_threshold", gc.DeploymentThreshold)
+		w.close()
+	}
+
+	if n := job.Notification; n != nil {
+		w.open("notification")
+		w.str("webhook", n.Webhook)
+		w.str("method", n.Method)
+		w.stringMap("headers", n.Headers)
+		if n.Payload != nil && *n.Payload != "" {
+			w.heredoc("payload", *n.Payload)
+		}
+		w.duration("timeout", n.Timeout)
+		w.close()
+	}
+
+	for _, tg := range job.TaskGroups {
+		if err := w.group(tg); err != nil {
+			return err
+		}
+	}
+
+	w.close()
+	return nil
+}
+
+func (w *hclWriter) nonZeroDuration(key string, value *time.Duration) {
+	if value != nil && *value != 0 {
+		w.duration(key, value)
+	}
+}
+
+func isZeroGC(gc *api.JobGCConfig) bool {
+	for _, d := range []*time.Duration{gc.AllocThreshold, gc.EvalThreshold, gc.DeploymentThreshold} {
+		if d != nil && *d != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *hclWriter) constraints(constraints []*api.Constraint) {
+	for _, c := range constraints {
+		w.open("constraint")
+		switch c.Operand {
+		case structs.ConstraintDistinctHosts:
+			w.attr(structs.ConstraintDistinctHosts, true)
+		case structs.ConstraintDistinctProperty:
+			w.attr(structs.ConstraintDistinctProperty, c.LTarget)
+			if c.RTarget != "" {
+				w.attr("value", c.RTarget)
+			}
+		case structs.ConstraintVersion, structs.ConstraintRegex, structs.ConstraintSetContains:
+			if c.LTarget != "" {
+				w.attr("attribute", c.LTarget)
+			}
+			w.attr(c.Operand, c.RTarget)
+		default:
+			if c.LTarget != "" {
+				w.attr("attribute", c.LTarget)
+			}
+			if c.Operand != "" && c.Operand != "=" {
+				w.attr("operator", c.Operand)
+			}
+			if c.RTarget != "" {
+				w.attr("value", c.RTarget)
+			}
+		}
+		w.close()
+	}
+}
+
+func (w *hclWriter) update(u *api.UpdateStrategy) {
+	if u == nil {
+		return
+	}
+
+	w.open("update")
+	w.duration("stagger", u.Stagger)
+	w.integer("max_parallel", u.MaxParallel)
+	w.str("health_check", u.HealthCheck)
+	w.duration("min_healthy_time", u.MinHealthyTime)
+	w.duration("healthy_deadline", u.HealthyDeadline)
+	w.boolean("auto_revert", u.AutoRevert)
+	w.integer("canary", u.Canary)
+	w.boolean("auto_promote", u.AutoPromote)
+	w.duration("progress_deadline", u.ProgressDeadline)
+	w.close()
+}
+
+// jobUpdate returns the set fields of the job level update strategy, or nil
+// if none are. Jobs returned by the servers always have one, with zero values
+// for the fields that weren't set when the job was submitted.
+func jobUpdate(u *api.UpdateStrategy) *api.UpdateStrategy {
+	if u == nil {
+		return nil
+	}
+
+	var out api.UpdateStrategy
+	set := false
+	if u.Stagger != nil && *u.Stagger != 0 {
+		out.Stagger, set = u.Stagger, true
+	}
+	if u.MaxParallel != nil && *u.MaxParallel != 0 {
+		out.MaxParallel, set = u.MaxParallel, true
+	}
+	if u.HealthCheck != nil && *u.HealthCheck != "" {
+		out.HealthCheck, set = u.HealthCheck, true
+	}
+	if u.MinHealthyTime != nil && *u.MinHealthyTime != 0 {
+		out.MinHealthyTime, set = u.MinHealthyTime, true
+	}
+	if u.HealthyDeadline != nil && *u.HealthyDeadline != 0 {
+		out.HealthyDeadline, set = u.HealthyDeadline, true
+	}
+	if u.AutoRevert != nil && *u.AutoRevert {
+		out.AutoRevert, set = u.AutoRevert, true
+	}
+	if u.Canary != nil && *u.Canary != 0 {
+		out.Canary, set = u.Canary, true
+	}
+	if u.AutoPromote != nil && *u.AutoPromote {
+		out.AutoPromote, set = u.AutoPromote, true
+	}
+	if u.ProgressDeadline != nil && *u.ProgressDeadline != 0 {
+		out.ProgressDeadline, set = u.ProgressDeadline, true
+	}
+
+	if !set {
+		return nil
+	}
+	return &out
+}
+
+func (w *hclWriter) group(tg *api.TaskGroup) error {
+	if tg.Name == nil {
+		return fmt.Errorf("task group must have a name")
+	}
+
+	w.open("group", *tg.Name)
+	w.integer("count", tg.Count)
+	w.duration("stop_after_client_disconnect", tg.StopAfterClientDisconnect)
+	w.duration("max_client_disconnect", tg.MaxClientDisconnect)
+	w.blank = true
+
+	w.constraints(tg.Constraints)
+
+	if r := tg.RestartPolicy; r != nil {
+		w.open("restart")
+		w.integer("attempts", r.Attempts)
+		w.duration("interval", r.Interval)
+		w.duration("delay", r.Delay)
+		w.str("mode", r.Mode)
+		w.close()
+	}
+
+	if d := tg.EphemeralDisk; d != nil {
+		w.open("ephemeral_disk")
+		w.boolean("sticky", d.Sticky)
+		w.boolean("migrate", d.Migrate)
+		w.integer("size", d.SizeMB)
+		w.close()
+	}
+
+	for _, n := range tg.Networks {
+		w.open("network")
+		if n.Mode != "" {
+			w.attr("mode", n.Mode)
+		}
+		w.integer("mbits", n.MBits)
+		if n.HostNetwork != "" {
+			w.attr("host_network", n.HostNetwork)
+		}
+		w.ports(n)
+		w.close()
+	}
+
+	w.update(tg.Update)
+	w.stringMap("meta", tg.Meta)
+
+	for _, task := range tg.Tasks {
+		if err := w.task(task); err != nil {
+			return fmt.Errorf("group %q: %v", *tg.Name, err)
+		}
+	}
+
+	w.close()
+	return nil
+}
+
+func (w *hclWriter) task(t *api.Task) error {
+	w.open("task", t.Name)
+	if t.Driver != "" {
+		w.attr("driver", t.Driver)
+	}
+	if t.User != "" {
+		w.attr("user", t.User)
+	}
+	if t.Leader {
+		w.attr("leader", true)
+	}
+	w.duration("kill_timeout", t.KillTimeout)
+	w.blank = true
+
+	if l := t.Lifecycle; l != nil {
+		w.open("lifecycle")
+		w.attr("hook", l.Hook)
+		if l.Sidecar {
+			w.attr("sidecar", true)
+		}
+		w.close()
+	}
+
+	if len(t.Config) != 0 {
+		w.open("config")
+		if err := w.object(t.Config); err != nil {
+			return fmt.Errorf("task %q: config: %v", t.Name, err)
+		}
+		w.close()
+	}
+
+	w.stringMap("env", t.Env)
+	w.constraints(t.Constraints)
+
+	for _, s := range t.Services {
+		w.service(s)
+	}
+
+	if r := t.Resources; r != nil {
+		w.resources(r)
+	}
+
+	w.stringMap("meta", t.Meta)
+
+	if l := t.LogConfig; l != nil {
+		w.open("logs")
+		w.integer("max_files", l.MaxFiles)
+		w.integer("max_file_size", l.MaxFileSizeMB)
+		w.close()
+	}
+
+	for _, a := range t.Artifacts {
+		w.open("artifact")
+		w.str("source", a.GetterSource)
+		w.str("destination", a.RelativeDest)
+		w.str("mode", a.GetterMode)
+		w.stringMap("options", a.GetterOptions)
+		w.close()
+	}
+
+	for _, tmpl := range t.Templates {
+		w.open("template")
+		w.str("source", tmpl.SourcePath)
+		w.str("destination", tmpl.DestPath)
+		if tmpl.EmbeddedTmpl != nil && *tmpl.EmbeddedTmpl != "" {
+			w.heredoc("data", *tmpl.EmbeddedTmpl)
+		}
+		w.str("change_mode", tmpl.ChangeMode)
+		w.str("change_signal", tmpl.ChangeSignal)
+		w.duration("splay", tmpl.Splay)
+		w.str("perms", tmpl.Perms)
+		w.str("left_delimiter", tmpl.LeftDelim)
+		w.str("right_delimiter", tmpl.RightDelim)
+		w.boolean("env", tmpl.Envvars)
+		w.stringMap("env_keys", tmpl.EnvKeys)
+		w.boolean("error_on_missing_key", tmpl.ErrMissingKey)
+		w.duration("vault_grace", tmpl.VaultGrace)
+		if wait := tmpl.Wait; wait != nil {
+			w.open("wait")
+			w.duration("min", wait.Min)
+			w.duration("max", wait.Max)
+			w.close()
+		}
+		w.changeScript(tmpl.ChangeScript)
+		w.close()
+	}
+
+	if v := t.Vault; v != nil {
+		w.open("vault")
+		w.boolean("enabled", v.Enabled)
+		w.strings("policies", v.Policies)
+		w.str("role", v.Role)
+		w.str("namespace", v.Namespace)
+		w.boolean("env", v.Env)
+		w.str("change_mode", v.ChangeMode)
+		w.str("change_signal", v.ChangeSignal)
+		w.changeScript(v.ChangeScript)
+		w.close()
+	}
+
+	if p := t.DispatchPayload; p != nil {
+		w.open("dispatch_payload")
+		if p.File != "" {
+			w.attr("file", p.File)
+		}
+		w.close()
+	}
+
+	w.close()
+	return nil
+}
+
+func (w *hclWriter) changeScript(cs *api.ChangeScript) {
+	if cs == nil {
+		return
+	}
+	w.open("change_script")
+	w.str("command", cs.Command)
+	w.strings("args", cs.Args)
+	w.duration("timeout", cs.Timeout)
+	w.boolean("fail_on_error", cs.FailOnError)
+	w.close()
+}
+
+func (w *hclWriter) service(s *api.Service) {
+	w.open("service")
+	if s.Name != "" {
+		w.attr("name", s.Name)
+	}
+	w.strings("tags", s.Tags)
+	w.strings("canary_tags", s.CanaryTags)
+	if s.PortLabel != "" {
+		w.attr("port", s.PortLabel)
+	}
+	if s.AddressMode != "" {
+		w.attr("address_mode", s.AddressMode)
+	}
+	w.stringMap("meta", s.Meta)
+	w.stringMap("tagged_addresses", s.TaggedAddresses)
+
+	for _, c := range s.Checks {
+		w.blank = true
+		w.open("check")
+		for _, a := range []struct{ key, value string }{
+			{"name", c.Name},
+			{"type", c.Type},
+			{"command", c.Command},
+			{"path", c.Path},
+			{"protocol", c.Protocol},
+			{"port", c.PortLabel},
+			{"initial_status", c.InitialStatus},
+		} {
+			if a.value != "" {
+				w.attr(a.key, a.value)
+			}
+		}
+		w.strings("args", c.Args)
+		if c.Interval != 0 {
+			w.attr("interval", c.Interval.String())
+		}
+		if c.Timeout != 0 {
+			w.attr("timeout", c.Timeout.String())
+		}
+		if c.TLSSkipVerify {
+			w.attr("tls_skip_verify", true)
+		}
+		w.close()
+	}
+	w.close()
+}
+
+func (w *hclWriter) resources(r *api.Resources) {
+	w.open("resources")
+	w.integer("cpu", r.CPU)
+	w.integer("memory", r.MemoryMB)
+	if r.DiskMB != nil && *r.DiskMB != 0 {
+		w.integer("disk", r.DiskMB)
+	}
+	if r.IOPS != nil && *r.IOPS != 0 {
+		w.integer("iops", r.IOPS)
+	}
+
+	for _, n := range r.Networks {
+		w.blank = true
+		w.open("network")
+		w.integer("mbits", n.MBits)
+		if n.HostNetwork != "" {
+			w.attr("host_network", n.HostNetwork)
+		}
+		w.ports(n)
+		w.close()
+	}
+	w.close()
+}
+
+// ports writes the port blocks of a network.
+func (w *hclWriter) ports(n *api.NetworkResource) {
+	for _, p := range n.ReservedPorts {
+		w.blank = true
+		w.open("port", p.Label)
+		w.attr("static", p.Value)
+		if p.To != 0 {
+			w.attr("to", p.To)
+		}
+		w.close()
+	}
+	for _, p := range n.DynamicPorts {
+		w.blank = true
+		w.open("port", p.Label)
+		if p.To != 0 {
+			w.attr("to", p.To)
+		}
+		w.close()
+	}
+}
+
+// heredoc writes a string attribute, using a heredoc if it spans several
+// lines.
+func (w *hclWriter) heredoc(key, value string) {
+	if !strings.Contains(value, "\n") || !strings.HasSuffix(value, "\n") {
+		w.attr(key, value)
+		return
+	}
+
+	marker := "EOH"
+	for i := 0; strings.Contains("\n"+value, "\n"+marker+"\n"); i++ {
+		marker = fmt.Sprintf("EOH%d", i)
+	}
+
+	w.line(fmt.Sprintf("%s = <<%s", formatKey(key), marker))
+	w.buf.WriteString(value)
+	w.buf.WriteString(marker + "\n")
+}
+
+// quoteString quotes a string so that HCL parses it back to the same value.
+// The content of interpolations is left as is since HCL doesn't unescape it.
+func quoteString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	braces := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if braces > 0 {
+			switch c {
+			case '{':
+				braces++
+			case '}':
+				braces--
+			case '\\':
+				buf.WriteByte('\\')
+			}
+			buf.WriteByte(c)
+			continue
+		}
+
+		switch {
+		case c == '$' && strings.HasPrefix(s[i:], "${"):
+			braces = 1
+			buf.WriteString("${")
+			i++
+		case c == '"':
+			buf.WriteString(`\"`)
+		case c == '\\':
+			buf.WriteString(`\\`)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\r':
+			buf.WriteString(`\r`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c < ' ':
+			fmt.Fprintf(&buf, `\x%02x`, c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// formatKey quotes the key if it isn't a valid identifier.
+func formatKey(key string) string {
+	if reIdentifier.MatchString(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+// elem dereferences pointers and interfaces.
+func elem(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		v = v.Elem()
+	}
+	return v
+}
+
+// allMaps returns whether all the elements of the list are maps.
+func allMaps(v reflect.Value) bool {
+	for i := 0; i < v.Len(); i++ {
+		if elem(v.Index(i)).Kind() != reflect.Map {
+			return false
+		}
+	}
+	return true
+}
+
+// toObject converts a map value with string keys to a generic map.
+func toObject(v reflect.Value) (map[string]interface{}, error) {
+	v = elem(v)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("unsupported value of type %s", v.Type())
+	}
+
+	m := make(map[string]interface{}, v.Len())
+	for _, k := range v.MapKeys() {
+		m[k.String()] = v.MapIndex(k).Interface()
+	}
+	return m, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,130 @@
+package jobspec
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/kr/pretty"
+)
+
+func TestFormat_RoundTrip(t *testing.T) {
+	files := []string{
+		"artifacts.hcl",
+		"basic.hcl",
+		"change-script.hcl",
+		"default-job.hcl",
+		"distinctHosts-constraint.hcl",
+		"distinctProperty-constraint.hcl",
+		"group-network.hcl",
+		"job-depends-on.hcl",
+		"job-gc.hcl",
+		"job-notification.hcl",
+		"max-client-disconnect.hcl",
+		"job-namespace.hcl",
+		"parameterized_job.hcl",
+		"periodic-cron.hcl",
+		"periodic-crons.hcl",
+		"regexp-constraint.hcl",
+		"service-check-initial-status.hcl",
+		"service-meta.hcl",
+		"set-contains-constraint.hcl",
+		"specify-job.hcl",
+		"stop-after-client-disconnect.hcl",
+		"task-nested-config.hcl",
+		"template-wait.hcl",
+		"template-env-keys.hcl",
+		"template-missing-key.hcl",
+		"vault-role.hcl",
+		"vault-disabled.hcl",
+		"vault-namespace.hcl",
+		"vault_inheritance.hcl",
+		"version-constraint.hcl",
+	}
+
+	for _, file := range files {
+		expected, err := ParseFile(filepath.Join("./test-fixtures", file))
+		if err != nil {
+			t.Fatalf("%s: error parsing: %v", file, err)
+		}
+
+		out, err := Format(expected)
+		if err != nil {
+			t.Fatalf("%s: error formatting: %v", file, err)
+		}
+
+		actual, err := Parse(strings.NewReader(out))
+		if err != nil {
+			t.Fatalf("%s: error parsing formatted job: %v\n%s", file, err, out)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%s: formatted job differs:\n%s\n%s", file, out, pretty.Diff(actual, expected))
+		}
+	}
+}
+
+func TestFormat_Strings(t *testing.T) {
+	job := &api.Job{
+		ID: helper.StringToPtr("example"),
+		Meta: map[string]string{
+			"quotes":        `say "hi"`,
+			"interpolation": `${meta.foo} \ ${env["BAR"]}`,
+			"lines":         "a\nb\tc",
+			"key with/sep":  "ok",
+		},
+		TaskGroups: []*api.TaskGroup{
+			{
+				Name: helper.StringToPtr("cache"),
+				Tasks: []*api.Task{
+					{
+						Name:   "redis",
+						Driver: "docker",
+						Config: map[string]interface{}{
+							"image":    "redis:3.2",
+							"args":     []interface{}{"-p", float64(6379)},
+							"port_map": []interface{}{map[string]interface{}{"db": float64(6379)}},
+						},
+						Templates: []*api.Template{
+							{
+								EmbeddedTmpl: helper.StringToPtr("{{ key \"foo\" }}\nEOH\n"),
+								DestPath:     helper.StringToPtr("local/foo"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	out, err := Format(job)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	actual, err := Parse(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("error parsing formatted job: %v\n%s", err, out)
+	}
+
+	if !reflect.DeepEqual(actual.Meta, job.Meta) {
+		t.Fatalf("bad meta:\n%s\n%s", out, pretty.Diff(actual.Meta, job.Meta))
+	}
+
+	task := actual.TaskGroups[0].Tasks[0]
+	if tmpl := *task.Templates[0].EmbeddedTmpl; tmpl != *job.TaskGroups[0].Tasks[0].Templates[0].EmbeddedTmpl {
+		t.Fatalf("bad template data %q:\n%s", tmpl, out)
+	}
+
+	expectedConfig := map[string]interface{}{
+		"image":    "redis:3.2",
+		"args":     []interface{}{"-p", 6379},
+		"port_map": []map[string]interface{}{{"db": 6379}},
+	}
+	if !reflect.DeepEqual(task.Config, expectedConfig) {
+		t.Fatalf("bad config:\n%s\n%s", out, pretty.Diff(task.Config, expectedConfig))
+	}
+}
@@ -108,6 +108,8 @@ func parseJob(result *api.Job, list *ast.ObjectList) error {
 	delete(m, "periodic")
 	delete(m, "vault")
 	delete(m, "parameterized")
+	delete(m, "gc")
+	delete(m, "notification")
 
 	// Set the ID and name to the object key
 	result.ID = helper.StringToPtr(obj.Keys[0].Token.Value().(string))
@@ -131,11 +133,15 @@ func parseJob(result *api.Job, list *ast.ObjectList) error {
 		"all_at_once",
 		"constraint",
 		"datacenters",
+		"depends_on",
+		"gc",
 		"parameterized",
 		"group",
 		"id",
 		"meta",
 		"name",
+		"notification",
+		"namespace",
 		"periodic",
 		"priority",
 		"region",
@@ -177,6 +183,20 @@ func parseJob(result *api.Job, list *ast.ObjectList) error {
 		}
 	}
 
+	// If we have a GC definition, then parse that
+	if o := listVal.Filter("gc"); len(o.Items) > 0 {
+		if err := parseJobGC(&result.GC, o); err != nil {
+			return multierror.Prefix(err, "gc ->")
+		}
+	}
+
+	// If we have a notification, then parse that
+	if o := listVal.Filter("notification"); len(o.Items) > 0 {
+		if err := parseJobNotification(&result.Notification, o); err != nil {
+			return multierror.Prefix(err, "notification ->")
+		}
+	}
+
 	// Parse out meta fields. These are in HCL as a list so we need
 	// to iterate over them and merge them.
 	if metaO := listVal.Filter("meta"); len(metaO.Items) > 0 {
@@ -274,6 +294,10 @@ func parseGroups(result *api.Job, list *ast.ObjectList) error {
 			"ephemeral_disk",
 			"update",
 			"vault",
+			"consul",
+			"stop_after_client_disconnect",
+			"max_client_disconnect",
+			"network",
 		}
 		if err := checkHCLKeys(listVal, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("'%s' ->", n))
@@ -290,11 +314,21 @@ func parseGroups(result *api.Job, list *ast.ObjectList) error {
 		delete(m, "ephemeral_disk")
 		delete(m, "update")
 		delete(m, "vault")
+		delete(m, "consul")
+		delete(m, "network")
 
 		// Build the group with the basic decode
 		var g api.TaskGroup
 		g.Name = helper.StringToPtr(n)
-		if err := mapstructure.WeakDecode(m, &g); err != nil {
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           &g,
+		})
+		if err != nil {
+			return err
+		}
+		if err := dec.Decode(m); err != nil {
 			return err
 		}
 
@@ -327,6 +361,20 @@ func parseGroups(result *api.Job, list *ast.ObjectList) error {
 			}
 		}
 
+		// Parse the Consul namespace and partition
+		if o := listVal.Filter("consul"); len(o.Items) > 0 {
+			if err := parseConsul(&g.Consul, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', consul ->", n))
+			}
+		}
+
+		// Parse the group network
+		if o := listVal.Filter("network"); len(o.Items) > 0 {
+			if err := parseGroupNetwork(&g.Networks, o); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', network ->", n))
+			}
+		}
+
 		// Parse out meta fields. These are in HCL as a list so we need
 		// to iterate over them and merge them.
 		if metaO := listVal.Filter("meta"); len(metaO.Items) > 0 {
@@ -530,6 +578,38 @@ func parseEphemeralDisk(result **api.EphemeralDisk, list *ast.ObjectList) error
 	return nil
 }
 
+func parseConsul(result **api.Consul, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'consul' block allowed")
+	}
+
+	// Get our consul object
+	obj := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"namespace",
+		"partition",
+	}
+	if err := checkHCLKeys(obj.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, obj.Val); err != nil {
+		return err
+	}
+
+	var consul api.Consul
+	if err := mapstructure.WeakDecode(m, &consul); err != nil {
+		return err
+	}
+	*result = &consul
+
+	return nil
+}
+
 // parseBool takes an interface value and tries to convert it to a boolean and
 // returns an error if the type can't be converted.
 func parseBool(value interface{}) (bool, error) {
@@ -582,6 +662,7 @@ func parseTasks(jobName string, taskGroupName string, result *[]*api.Task, list
 			"env",
 			"kill_timeout",
 			"leader",
+			"lifecycle",
 			"logs",
 			"meta",
 			"resources",
@@ -603,6 +684,7 @@ func parseTasks(jobName string, taskGroupName string, result *[]*api.Task, list
 		delete(m, "constraint")
 		delete(m, "dispatch_payload")
 		delete(m, "env")
+		delete(m, "lifecycle")
 		delete(m, "logs")
 		delete(m, "meta")
 		delete(m, "resources")
@@ -776,6 +858,33 @@ func parseTasks(jobName string, taskGroupName string, result *[]*api.Task, list
 			}
 		}
 
+		// If we have a lifecycle block parse that
+		if o := listVal.Filter("lifecycle"); len(o.Items) > 0 {
+			if len(o.Items) > 1 {
+				return fmt.Errorf("only one lifecycle block is allowed in a task. Number of lifecycle blocks found: %d", len(o.Items))
+			}
+			var m map[string]interface{}
+			lifecycleBlock := o.Items[0]
+
+			// Check for invalid keys
+			valid := []string{
+				"hook",
+				"sidecar",
+			}
+			if err := checkHCLKeys(lifecycleBlock.Val, valid); err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("'%s', lifecycle ->", n))
+			}
+
+			if err := hcl.DecodeObject(&m, lifecycleBlock.Val); err != nil {
+				return err
+			}
+
+			t.Lifecycle = &api.TaskLifecycle{}
+			if err := mapstructure.WeakDecode(m, t.Lifecycle); err != nil {
+				return err
+			}
+		}
+
 		*result = append(*result, &t)
 	}
 
@@ -855,6 +964,7 @@ func parseTemplates(result *[]*api.Template, list *ast.ObjectList) error {
 		valid := []string{
 			"change_mode",
 			"change_signal",
+			"change_script",
 			"data",
 			"destination",
 			"left_delimiter",
@@ -863,7 +973,10 @@ func parseTemplates(result *[]*api.Template, list *ast.ObjectList) error {
 			"source",
 			"splay",
 			"env",
+			"env_keys",
+			"error_on_missing_key",
 			"vault_grace",
+			"wait",
 		}
 		if err := checkHCLKeys(o.Val, valid); err != nil {
 			return err
@@ -874,6 +987,11 @@ func parseTemplates(result *[]*api.Template, list *ast.ObjectList) error {
 			return err
 		}
 
+		// The change_script, env_keys and wait blocks are parsed below
+		delete(m, "change_script")
+		delete(m, "env_keys")
+		delete(m, "wait")
+
 		templ := &api.Template{
 			ChangeMode: helper.StringToPtr("restart"),
 			Splay:      helper.TimeToPtr(5 * time.Second),
@@ -892,6 +1010,39 @@ func parseTemplates(result *[]*api.Template, list *ast.ObjectList) error {
 			return err
 		}
 
+		// Parse the change_script block
+		if ot, ok := o.Val.(*ast.ObjectType); ok {
+			if cso := ot.List.Filter("change_script"); len(cso.Items) > 0 {
+				cs, err := parseChangeScript(cso)
+				if err != nil {
+					return multierror.Prefix(err, "template ->")
+				}
+				templ.ChangeScript = cs
+			}
+
+			if wo := ot.List.Filter("wait"); len(wo.Items) > 0 {
+				wait, err := parseWait(wo)
+				if err != nil {
+					return multierror.Prefix(err, "template ->")
+				}
+				templ.Wait = wait
+			}
+
+			// Parse out the env_keys fields. These are in HCL as a list so
+			// we need to iterate over them and merge them.
+			if eko := ot.List.Filter("env_keys"); len(eko.Items) > 0 {
+				for _, o := range eko.Elem().Items {
+					var m map[string]interface{}
+					if err := hcl.DecodeObject(&m, o.Val); err != nil {
+						return err
+					}
+					if err := mapstructure.WeakDecode(m, &templ.EnvKeys); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
 		*result = append(*result, templ)
 	}
 
@@ -905,9 +1056,15 @@ func parseServices(jobName string, taskGroupName string, task *api.Task, service
 		valid := []string{
 			"name",
 			"tags",
+			"canary_tags",
 			"port",
 			"check",
 			"address_mode",
+			"connect",
+			"provider",
+			"check_restart",
+			"meta",
+			"tagged_addresses",
 		}
 		if err := checkHCLKeys(o.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("service (%d) ->", idx))
@@ -920,6 +1077,10 @@ func parseServices(jobName string, taskGroupName string, task *api.Task, service
 		}
 
 		delete(m, "check")
+		delete(m, "connect")
+		delete(m, "check_restart")
+		delete(m, "meta")
+		delete(m, "tagged_addresses")
 
 		if err := mapstructure.WeakDecode(m, &service); err != nil {
 			return err
@@ -939,160 +1100,717 @@ func parseServices(jobName string, taskGroupName string, task *api.Task, service
 			}
 		}
 
+		// Parse the Connect block
+		if co := checkList.Filter("connect"); len(co.Items) > 0 {
+			if len(co.Items) > 1 {
+				return fmt.Errorf("service '%s': only one connect block is allowed", service.Name)
+			}
+			connect, err := parseConnect(co.Items[0])
+			if err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("service: '%s', connect ->", service.Name))
+			}
+			service.Connect = connect
+		}
+
+		// Parse the check_restart block
+		if cro := checkList.Filter("check_restart"); len(cro.Items) > 0 {
+			cr, err := parseCheckRestart(cro)
+			if err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("service: '%s',", service.Name))
+			}
+			service.CheckRestart = cr
+		}
+
+		// Parse the meta and tagged_addresses blocks. These are in HCL as a
+		// list so we need to iterate over them and merge them.
+		for _, block := range []struct {
+			name string
+			dest *map[string]string
+		}{
+			{"meta", &service.Meta},
+			{"tagged_addresses", &service.TaggedAddresses},
+		} {
+			if mo := checkList.Filter(block.name); len(mo.Items) > 0 {
+				for _, item := range mo.Elem().Items {
+					var m map[string]interface{}
+					if err := hcl.DecodeObject(&m, item.Val); err != nil {
+						return err
+					}
+					if err := mapstructure.WeakDecode(m, block.dest); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
 		task.Services[idx] = &service
 	}
 
 	return nil
 }
 
-func parseChecks(service *api.Service, checkObjs *ast.ObjectList) error {
-	service.Checks = make([]api.ServiceCheck, len(checkObjs.Items))
-	for idx, co := range checkObjs.Items {
-		// Check for invalid keys
-		valid := []string{
-			"name",
-			"type",
-			"interval",
-			"timeout",
-			"path",
-			"protocol",
-			"port",
-			"command",
-			"args",
-			"initial_status",
-			"tls_skip_verify",
+func parseConnect(co *ast.ObjectItem) (*api.ConsulConnect, error) {
+	valid := []string{
+		"sidecar_service",
+		"sidecar_task",
+		"gateway",
+	}
+	if err := checkHCLKeys(co.Val, valid); err != nil {
+		return nil, err
+	}
+
+	var connect api.ConsulConnect
+	listVal, ok := co.Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("should be an object")
+	}
+
+	if o := listVal.List.Filter("sidecar_service"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return nil, fmt.Errorf("only one sidecar_service block is allowed")
 		}
-		if err := checkHCLKeys(co.Val, valid); err != nil {
-			return multierror.Prefix(err, "check ->")
+		sidecarService, err := parseSidecarService(o.Items[0])
+		if err != nil {
+			return nil, multierror.Prefix(err, "sidecar_service ->")
 		}
+		connect.SidecarService = sidecarService
+	}
 
-		var check api.ServiceCheck
-		var cm map[string]interface{}
-		if err := hcl.DecodeObject(&cm, co.Val); err != nil {
-			return err
+	if o := listVal.List.Filter("sidecar_task"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return nil, fmt.Errorf("only one sidecar_task block is allowed")
 		}
-		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
-			WeaklyTypedInput: true,
-			Result:           &check,
-		})
+		sidecarTask, err := parseSidecarTask(o.Items[0])
 		if err != nil {
-			return err
-		}
-		if err := dec.Decode(cm); err != nil {
-			return err
+			return nil, multierror.Prefix(err, "sidecar_task ->")
 		}
+		connect.SidecarTask = sidecarTask
+	}
 
-		service.Checks[idx] = check
+	if o := listVal.List.Filter("gateway"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return nil, fmt.Errorf("only one gateway block is allowed")
+		}
+		gateway, err := parseGateway(o.Items[0])
+		if err != nil {
+			return nil, multierror.Prefix(err, "gateway ->")
+		}
+		connect.Gateway = gateway
 	}
 
-	return nil
+	return &connect, nil
 }
 
-func parseResources(result *api.Resources, list *ast.ObjectList) error {
-	list = list.Elem()
-	if len(list.Items) == 0 {
-		return nil
+func parseGateway(o *ast.ObjectItem) (*api.ConsulGateway, error) {
+	valid := []string{
+		"proxy",
+		"ingress",
+		"terminating",
+		"mesh",
 	}
-	if len(list.Items) > 1 {
-		return fmt.Errorf("only one 'resource' block allowed per task")
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return nil, err
 	}
 
-	// Get our resource object
-	o := list.Items[0]
-
-	// We need this later
-	var listVal *ast.ObjectList
-	if ot, ok := o.Val.(*ast.ObjectType); ok {
-		listVal = ot.List
-	} else {
-		return fmt.Errorf("resource: should be an object")
+	var gateway api.ConsulGateway
+	listVal, ok := o.Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("should be an object")
 	}
 
-	// Check for invalid keys
-	valid := []string{
-		"cpu",
-		"iops",
-		"disk",
-		"memory",
-		"network",
+	for _, key := range valid {
+		if items := listVal.List.Filter(key).Items; len(items) > 1 {
+			return nil, fmt.Errorf("only one %s block is allowed", key)
+		}
 	}
-	if err := checkHCLKeys(listVal, valid); err != nil {
-		return multierror.Prefix(err, "resources ->")
+
+	if o := listVal.List.Filter("proxy"); len(o.Items) > 0 {
+		valid := []string{
+			"connect_timeout",
+		}
+		if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "proxy ->")
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
+			return nil, err
+		}
+		var proxy api.ConsulGatewayProxy
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           &proxy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := dec.Decode(m); err != nil {
+			return nil, err
+		}
+		gateway.Proxy = &proxy
 	}
 
-	var m map[string]interface{}
-	if err := hcl.DecodeObject(&m, o.Val); err != nil {
-		return err
+	if o := listVal.List.Filter("ingress"); len(o.Items) > 0 {
+		ingress, err := parseIngressConfigEntry(o.Items[0])
+		if err != nil {
+			return nil, multierror.Prefix(err, "ingress ->")
+		}
+		gateway.Ingress = ingress
 	}
-	delete(m, "network")
 
-	if err := mapstructure.WeakDecode(m, result); err != nil {
-		return err
+	if o := listVal.List.Filter("terminating"); len(o.Items) > 0 {
+		terminating, err := parseTerminatingConfigEntry(o.Items[0])
+		if err != nil {
+			return nil, multierror.Prefix(err, "terminating ->")
+		}
+		gateway.Terminating = terminating
 	}
 
-	// Parse the network resources
-	if o := listVal.Filter("network"); len(o.Items) > 0 {
-		if len(o.Items) > 1 {
-			return fmt.Errorf("only one 'network' resource allowed")
+	if o := listVal.List.Filter("mesh"); len(o.Items) > 0 {
+		if err := checkHCLKeys(o.Items[0].Val, nil); err != nil {
+			return nil, multierror.Prefix(err, "mesh ->")
 		}
+		gateway.Mesh = &api.ConsulMeshConfigEntry{}
+	}
 
-		// Check for invalid keys
+	return &gateway, nil
+}
+
+func parseIngressConfigEntry(o *ast.ObjectItem) (*api.ConsulIngressConfigEntry, error) {
+	if err := checkHCLKeys(o.Val, []string{"listener"}); err != nil {
+		return nil, err
+	}
+
+	listVal, ok := o.Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("should be an object")
+	}
+
+	var ingress api.ConsulIngressConfigEntry
+	for _, lo := range listVal.List.Filter("listener").Items {
 		valid := []string{
-			"mbits",
 			"port",
+			"protocol",
+			"service",
 		}
-		if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
-			return multierror.Prefix(err, "resources, network ->")
+		if err := checkHCLKeys(lo.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "listener ->")
 		}
 
-		var r api.NetworkResource
 		var m map[string]interface{}
-		if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
-			return err
-		}
-		if err := mapstructure.WeakDecode(m, &r); err != nil {
-			return err
+		if err := hcl.DecodeObject(&m, lo.Val); err != nil {
+			return nil, err
 		}
+		delete(m, "service")
 
-		var networkObj *ast.ObjectList
-		if ot, ok := o.Items[0].Val.(*ast.ObjectType); ok {
-			networkObj = ot.List
-		} else {
-			return fmt.Errorf("resource: should be an object")
+		var listener api.ConsulIngressListener
+		if err := mapstructure.WeakDecode(m, &listener); err != nil {
+			return nil, err
 		}
-		if err := parsePorts(networkObj, &r); err != nil {
-			return multierror.Prefix(err, "resources, network, ports ->")
+
+		lVal, ok := lo.Val.(*ast.ObjectType)
+		if !ok {
+			return nil, fmt.Errorf("listener: should be an object")
 		}
+		for _, so := range lVal.List.Filter("service").Items {
+			valid := []string{
+				"name",
+				"hosts",
+			}
+			if err := checkHCLKeys(so.Val, valid); err != nil {
+				return nil, multierror.Prefix(err, "listener -> service ->")
+			}
 
-		result.Networks = []*api.NetworkResource{&r}
+			var m map[string]interface{}
+			if err := hcl.DecodeObject(&m, so.Val); err != nil {
+				return nil, err
+			}
+			var service api.ConsulIngressService
+			if err := mapstructure.WeakDecode(m, &service); err != nil {
+				return nil, err
+			}
+			listener.Services = append(listener.Services, &service)
+		}
+		ingress.Listeners = append(ingress.Listeners, &listener)
 	}
 
-	return nil
+	return &ingress, nil
 }
 
-func parsePorts(networkObj *ast.ObjectList, nw *api.NetworkResource) error {
-	// Check for invalid keys
-	valid := []string{
-		"mbits",
-		"port",
+func parseTerminatingConfigEntry(o *ast.ObjectItem) (*api.ConsulTerminatingConfigEntry, error) {
+	if err := checkHCLKeys(o.Val, []string{"service"}); err != nil {
+		return nil, err
 	}
-	if err := checkHCLKeys(networkObj, valid); err != nil {
-		return err
+
+	listVal, ok := o.Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("should be an object")
 	}
 
-	portsObjList := networkObj.Filter("port")
-	knownPortLabels := make(map[string]bool)
-	for _, port := range portsObjList.Items {
-		if len(port.Keys) == 0 {
-			return fmt.Errorf("ports must be named")
+	var terminating api.ConsulTerminatingConfigEntry
+	for _, so := range listVal.List.Filter("service").Items {
+		valid := []string{
+			"name",
+			"ca_file",
+			"cert_file",
+			"key_file",
+			"sni",
 		}
-		label := port.Keys[0].Token.Value().(string)
-		if !reDynamicPorts.MatchString(label) {
-			return errPortLabel
+		if err := checkHCLKeys(so.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "service ->")
 		}
-		l := strings.ToLower(label)
-		if knownPortLabels[l] {
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, so.Val); err != nil {
+			return nil, err
+		}
+		var service api.ConsulLinkedService
+		if err := mapstructure.WeakDecode(m, &service); err != nil {
+			return nil, err
+		}
+		terminating.Services = append(terminating.Services, &service)
+	}
+
+	return &terminating, nil
+}
+
+func parseSidecarService(o *ast.ObjectItem) (*api.ConsulSidecarService, error) {
+	valid := []string{
+		"proxy",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return nil, err
+	}
+
+	var sidecarService api.ConsulSidecarService
+	listVal, ok := o.Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("should be an object")
+	}
+
+	po := listVal.List.Filter("proxy")
+	if len(po.Items) == 0 {
+		return &sidecarService, nil
+	}
+	if len(po.Items) > 1 {
+		return nil, fmt.Errorf("only one proxy block is allowed")
+	}
+
+	valid = []string{
+		"upstreams",
+		"mesh_gateway",
+	}
+	if err := checkHCLKeys(po.Items[0].Val, valid); err != nil {
+		return nil, multierror.Prefix(err, "proxy ->")
+	}
+
+	var proxy api.ConsulProxy
+	proxyVal, ok := po.Items[0].Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("proxy: should be an object")
+	}
+	meshGateway, err := parseMeshGateway(proxyVal.List.Filter("mesh_gateway"))
+	if err != nil {
+		return nil, multierror.Prefix(err, "proxy ->")
+	}
+	proxy.MeshGateway = meshGateway
+
+	for _, uo := range proxyVal.List.Filter("upstreams").Items {
+		valid := []string{
+			"destination_name",
+			"destination_namespace",
+			"datacenter",
+			"local_bind_address",
+			"local_bind_port",
+			"mesh_gateway",
+		}
+		if err := checkHCLKeys(uo.Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "proxy -> upstreams ->")
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, uo.Val); err != nil {
+			return nil, err
+		}
+		delete(m, "mesh_gateway")
+
+		var upstream api.ConsulUpstream
+		if err := mapstructure.WeakDecode(m, &upstream); err != nil {
+			return nil, err
+		}
+
+		if upstreamVal, ok := uo.Val.(*ast.ObjectType); ok {
+			meshGateway, err := parseMeshGateway(upstreamVal.List.Filter("mesh_gateway"))
+			if err != nil {
+				return nil, multierror.Prefix(err, "proxy -> upstreams ->")
+			}
+			upstream.MeshGateway = meshGateway
+		}
+		proxy.Upstreams = append(proxy.Upstreams, &upstream)
+	}
+	sidecarService.Proxy = &proxy
+
+	return &sidecarService, nil
+}
+
+func parseMeshGateway(list *ast.ObjectList) (*api.ConsulMeshGateway, error) {
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	if len(list.Items) > 1 {
+		return nil, fmt.Errorf("only one mesh_gateway block is allowed")
+	}
+
+	valid := []string{
+		"mode",
+	}
+	if err := checkHCLKeys(list.Items[0].Val, valid); err != nil {
+		return nil, multierror.Prefix(err, "mesh_gateway ->")
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, list.Items[0].Val); err != nil {
+		return nil, err
+	}
+	var meshGateway api.ConsulMeshGateway
+	if err := mapstructure.WeakDecode(m, &meshGateway); err != nil {
+		return nil, err
+	}
+	return &meshGateway, nil
+}
+
+func parseSidecarTask(item *ast.ObjectItem) (*api.SidecarTask, error) {
+	valid := []string{
+		"driver",
+		"user",
+		"config",
+		"env",
+		"resources",
+		"meta",
+		"kill_timeout",
+		"logs",
+	}
+	if err := checkHCLKeys(item.Val, valid); err != nil {
+		return nil, err
+	}
+
+	listVal, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return nil, fmt.Errorf("should be an object")
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, item.Val); err != nil {
+		return nil, err
+	}
+	delete(m, "config")
+	delete(m, "env")
+	delete(m, "resources")
+	delete(m, "meta")
+	delete(m, "logs")
+
+	var t api.SidecarTask
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &t,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+
+	// Parse the maps, which are lists in HCL
+	for key, out := range map[string]interface{}{
+		"config": &t.Config,
+		"env":    &t.Env,
+		"meta":   &t.Meta,
+	} {
+		for _, o := range listVal.List.Filter(key).Elem().Items {
+			var m map[string]interface{}
+			if err := hcl.DecodeObject(&m, o.Val); err != nil {
+				return nil, err
+			}
+			if err := mapstructure.WeakDecode(m, out); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if o := listVal.List.Filter("resources"); len(o.Items) > 0 {
+		var r api.Resources
+		if err := parseResources(&r, o); err != nil {
+			return nil, err
+		}
+		t.Resources = &r
+	}
+
+	if o := listVal.List.Filter("logs"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return nil, fmt.Errorf("only one logs block is allowed")
+		}
+		valid := []string{
+			"max_files",
+			"max_file_size",
+		}
+		if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
+			return nil, multierror.Prefix(err, "logs ->")
+		}
+
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
+			return nil, err
+		}
+		var log api.LogConfig
+		if err := mapstructure.WeakDecode(m, &log); err != nil {
+			return nil, err
+		}
+		t.LogConfig = &log
+	}
+
+	return &t, nil
+}
+
+func parseChecks(service *api.Service, checkObjs *ast.ObjectList) error {
+	service.Checks = make([]api.ServiceCheck, len(checkObjs.Items))
+	for idx, co := range checkObjs.Items {
+		// Check for invalid keys
+		valid := []string{
+			"name",
+			"type",
+			"interval",
+			"timeout",
+			"path",
+			"protocol",
+			"port",
+			"command",
+			"args",
+			"initial_status",
+			"tls_skip_verify",
+			"check_restart",
+		}
+		if err := checkHCLKeys(co.Val, valid); err != nil {
+			return multierror.Prefix(err, "check ->")
+		}
+
+		var check api.ServiceCheck
+		var cm map[string]interface{}
+		if err := hcl.DecodeObject(&cm, co.Val); err != nil {
+			return err
+		}
+
+		// The check_restart block is parsed below
+		delete(cm, "check_restart")
+		dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+			WeaklyTypedInput: true,
+			Result:           &check,
+		})
+		if err != nil {
+			return err
+		}
+		if err := dec.Decode(cm); err != nil {
+			return err
+		}
+
+		// Parse the check_restart block
+		if ot, ok := co.Val.(*ast.ObjectType); ok {
+			if cro := ot.List.Filter("check_restart"); len(cro.Items) > 0 {
+				cr, err := parseCheckRestart(cro)
+				if err != nil {
+					return multierror.Prefix(err, fmt.Sprintf("check: '%s',", check.Name))
+				}
+				check.CheckRestart = cr
+			}
+		}
+
+		service.Checks[idx] = check
+	}
+
+	return nil
+}
+
+func parseCheckRestart(cro *ast.ObjectList) (*api.CheckRestart, error) {
+	if len(cro.Items) > 1 {
+		return nil, fmt.Errorf("only one 'check_restart' block allowed")
+	}
+
+	cr := cro.Items[0]
+	valid := []string{
+		"limit",
+		"grace",
+		"ignore_warnings",
+	}
+	if err := checkHCLKeys(cr.Val, valid); err != nil {
+		return nil, multierror.Prefix(err, "check_restart ->")
+	}
+
+	var checkRestart api.CheckRestart
+	var crm map[string]interface{}
+	if err := hcl.DecodeObject(&crm, cr.Val); err != nil {
+		return nil, err
+	}
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &checkRestart,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(crm); err != nil {
+		return nil, err
+	}
+
+	return &checkRestart, nil
+}
+
+func parseResources(result *api.Resources, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) == 0 {
+		return nil
+	}
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'resource' block allowed per task")
+	}
+
+	// Get our resource object
+	o := list.Items[0]
+
+	// We need this later
+	var listVal *ast.ObjectList
+	if ot, ok := o.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("resource: should be an object")
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"cpu",
+		"iops",
+		"disk",
+		"memory",
+		"network",
+	}
+	if err := checkHCLKeys(listVal, valid); err != nil {
+		return multierror.Prefix(err, "resources ->")
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+	delete(m, "network")
+
+	if err := mapstructure.WeakDecode(m, result); err != nil {
+		return err
+	}
+
+	// Parse the network resources
+	if o := listVal.Filter("network"); len(o.Items) > 0 {
+		if len(o.Items) > 1 {
+			return fmt.Errorf("only one 'network' resource allowed")
+		}
+
+		// Check for invalid keys
+		valid := []string{
+			"mbits",
+			"host_network",
+			"port",
+		}
+		if err := checkHCLKeys(o.Items[0].Val, valid); err != nil {
+			return multierror.Prefix(err, "resources, network ->")
+		}
+
+		var r api.NetworkResource
+		var m map[string]interface{}
+		if err := hcl.DecodeObject(&m, o.Items[0].Val); err != nil {
+			return err
+		}
+		if err := mapstructure.WeakDecode(m, &r); err != nil {
+			return err
+		}
+
+		var networkObj *ast.ObjectList
+		if ot, ok := o.Items[0].Val.(*ast.ObjectType); ok {
+			networkObj = ot.List
+		} else {
+			return fmt.Errorf("resource: should be an object")
+		}
+		if err := parsePorts(networkObj, &r); err != nil {
+			return multierror.Prefix(err, "resources, network, ports ->")
+		}
+
+		result.Networks = []*api.NetworkResource{&r}
+	}
+
+	return nil
+}
+
+func parseGroupNetwork(result *[]*api.NetworkResource, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'network' block allowed per group")
+	}
+
+	// Get our network object
+	o := list.Items[0]
+
+	// Check for invalid keys
+	valid := []string{
+		"mode",
+		"host_network",
+		"mbits",
+		"port",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+	delete(m, "port")
+
+	var r api.NetworkResource
+	if err := mapstructure.WeakDecode(m, &r); err != nil {
+		return err
+	}
+
+	var networkObj *ast.ObjectList
+	if ot, ok := o.Val.(*ast.ObjectType); ok {
+		networkObj = ot.List
+	} else {
+		return fmt.Errorf("network: should be an object")
+	}
+	if err := parsePorts(networkObj, &r); err != nil {
+		return multierror.Prefix(err, "network, ports ->")
+	}
+
+	*result = []*api.NetworkResource{&r}
+	return nil
+}
+
+// parsePorts parses the ports of a network whose keys were checked.
+func parsePorts(networkObj *ast.ObjectList, nw *api.NetworkResource) error {
+	portsObjList := networkObj.Filter("port")
+	knownPortLabels := make(map[string]bool)
+	for _, port := range portsObjList.Items {
+		if len(port.Keys) == 0 {
+			return fmt.Errorf("ports must be named")
+		}
+		label := port.Keys[0].Token.Value().(string)
+		if !reDynamicPorts.MatchString(label) {
+			return errPortLabel
+		}
+		l := strings.ToLower(label)
+		if knownPortLabels[l] {
 			return fmt.Errorf("found a port label collision: %s", label)
 		}
 		var p map[string]interface{}
@@ -1138,6 +1856,8 @@ func parseUpdate(result **api.UpdateStrategy, list *ast.ObjectList) error {
 		"healthy_deadline",
 		"auto_revert",
 		"canary",
+		"auto_promote",
+		"progress_deadline",
 	}
 	if err := checkHCLKeys(o.Val, valid); err != nil {
 		return err
@@ -1172,8 +1892,10 @@ func parsePeriodic(result **api.PeriodicConfig, list *ast.ObjectList) error {
 	valid := []string{
 		"enabled",
 		"cron",
+		"crons",
 		"prohibit_overlap",
 		"time_zone",
+		"dst_mode",
 	}
 	if err := checkHCLKeys(o.Val, valid); err != nil {
 		return err
@@ -1193,6 +1915,11 @@ func parsePeriodic(result **api.PeriodicConfig, list *ast.ObjectList) error {
 		m["Spec"] = cron
 	}
 
+	// A list of cron specs may be provided instead
+	if _, ok := m["crons"]; ok {
+		m["SpecType"] = structs.PeriodicSpecCron
+	}
+
 	// Build the constraint
 	var p api.PeriodicConfig
 	if err := mapstructure.WeakDecode(m, &p); err != nil {
@@ -1224,10 +1951,14 @@ func parseVault(result *api.Vault, list *ast.ObjectList) error {
 
 	// Check for invalid keys
 	valid := []string{
+		"enabled",
 		"policies",
+		"role",
+		"namespace",
 		"env",
 		"change_mode",
 		"change_signal",
+		"change_script",
 	}
 	if err := checkHCLKeys(listVal, valid); err != nil {
 		return multierror.Prefix(err, "vault ->")
@@ -1238,10 +1969,191 @@ func parseVault(result *api.Vault, list *ast.ObjectList) error {
 		return err
 	}
 
+	delete(m, "change_script")
+
 	if err := mapstructure.WeakDecode(m, result); err != nil {
 		return err
 	}
 
+	// Parse the change_script block
+	if cso := listVal.Filter("change_script"); len(cso.Items) > 0 {
+		cs, err := parseChangeScript(cso)
+		if err != nil {
+			return multierror.Prefix(err, "vault ->")
+		}
+		result.ChangeScript = cs
+	}
+
+	return nil
+}
+
+func parseWait(wo *ast.ObjectList) (*api.WaitConfig, error) {
+	if len(wo.Items) > 1 {
+		return nil, fmt.Errorf("only one 'wait' block allowed")
+	}
+
+	w := wo.Items[0]
+	valid := []string{
+		"min",
+		"max",
+	}
+	if err := checkHCLKeys(w.Val, valid); err != nil {
+		return nil, multierror.Prefix(err, "wait ->")
+	}
+
+	var wait api.WaitConfig
+	var wm map[string]interface{}
+	if err := hcl.DecodeObject(&wm, w.Val); err != nil {
+		return nil, err
+	}
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &wait,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(wm); err != nil {
+		return nil, err
+	}
+
+	return &wait, nil
+}
+
+func parseChangeScript(cso *ast.ObjectList) (*api.ChangeScript, error) {
+	if len(cso.Items) > 1 {
+		return nil, fmt.Errorf("only one 'change_script' block allowed")
+	}
+
+	cs := cso.Items[0]
+	valid := []string{
+		"command",
+		"args",
+		"timeout",
+		"fail_on_error",
+	}
+	if err := checkHCLKeys(cs.Val, valid); err != nil {
+		return nil, multierror.Prefix(err, "change_script ->")
+	}
+
+	var changeScript api.ChangeScript
+	var csm map[string]interface{}
+	if err := hcl.DecodeObject(&csm, cs.Val); err != nil {
+		return nil, err
+	}
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &changeScript,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.Decode(csm); err != nil {
+		return nil, err
+	}
+
+	return &changeScript, nil
+}
+
+func parseJobGC(result **api.JobGCConfig, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'gc' block allowed per job")
+	}
+
+	// Get our resource object
+	o := list.Items[0]
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+
+	// Check for invalid keys
+	valid := []string{
+		"alloc_threshold",
+		"eval_threshold",
+		"deployment_threshold",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           result,
+	})
+	if err != nil {
+		return err
+	}
+	return dec.Decode(m)
+}
+
+func parseJobNotification(result **api.JobNotification, list *ast.ObjectList) error {
+	list = list.Elem()
+	if len(list.Items) > 1 {
+		return fmt.Errorf("only one 'notification' block allowed per job")
+	}
+
+	// Get our resource object
+	o := list.Items[0]
+
+	var m map[string]interface{}
+	if err := hcl.DecodeObject(&m, o.Val); err != nil {
+		return err
+	}
+	delete(m, "headers")
+
+	// Check for invalid keys
+	valid := []string{
+		"webhook",
+		"method",
+		"headers",
+		"payload",
+		"timeout",
+	}
+	if err := checkHCLKeys(o.Val, valid); err != nil {
+		return err
+	}
+
+	var notification api.JobNotification
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           &notification,
+	})
+	if err != nil {
+		return err
+	}
+	if err := dec.Decode(m); err != nil {
+		return err
+	}
+
+	// Parse the headers
+	var listVal *ast.ObjectList
+	if ot, ok := o.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("notification: should be an object")
+	}
+	if o := listVal.Filter("headers"); len(o.Items) > 0 {
+		for _, o := range o.Elem().Items {
+			var m map[string]interface{}
+			if err := hcl.DecodeObject(&m, o.Val); err != nil {
+				return err
+			}
+			if err := mapstructure.WeakDecode(m, &notification.Headers); err != nil {
+				return err
+			}
+		}
+	}
+
+	*result = &notification
 	return nil
 }
 
@@ -1264,17 +2176,78 @@ func parseParameterizedJob(result **api.ParameterizedJobConfig, list *ast.Object
 		"payload",
 		"meta_required",
 		"meta_optional",
+		"meta_schema",
+		"payload_schema",
 	}
 	if err := checkHCLKeys(o.Val, valid); err != nil {
 		return err
 	}
 
+	delete(m, "meta_schema")
+	delete(m, "payload_schema")
+
 	// Build the parameterized job block
 	var d api.ParameterizedJobConfig
 	if err := mapstructure.WeakDecode(m, &d); err != nil {
 		return err
 	}
 
+	var listVal *ast.ObjectList
+	if ot, ok := o.Val.(*ast.ObjectType); ok {
+		listVal = ot.List
+	} else {
+		return fmt.Errorf("parameterized should be an object")
+	}
+
+	// Parse the meta schemas
+	for _, item := range listVal.Filter("meta_schema").Items {
+		if len(item.Keys) == 0 {
+			return fmt.Errorf("meta_schema must be named after the meta key")
+		}
+		key := item.Keys[0].Token.Value().(string)
+		if err := checkHCLKeys(item.Val, []string{"type", "pattern", "allowed"}); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("meta_schema %q ->", key))
+		}
+
+		var sm map[string]interface{}
+		if err := hcl.DecodeObject(&sm, item.Val); err != nil {
+			return err
+		}
+		var schema api.DispatchMetaSchema
+		if err := mapstructure.WeakDecode(sm, &schema); err != nil {
+			return err
+		}
+
+		if d.MetaSchema == nil {
+			d.MetaSchema = make(map[string]*api.DispatchMetaSchema)
+		}
+		if _, ok := d.MetaSchema[key]; ok {
+			return fmt.Errorf("only one 'meta_schema' block allowed per meta key, found several for %q", key)
+		}
+		d.MetaSchema[key] = &schema
+	}
+
+	// Parse the payload schema
+	if po := listVal.Filter("payload_schema"); len(po.Items) > 0 {
+		if len(po.Items) > 1 {
+			return fmt.Errorf("only one 'payload_schema' block allowed")
+		}
+		item := po.Items[0]
+		if err := checkHCLKeys(item.Val, []string{"max_size", "content_type", "required_fields"}); err != nil {
+			return multierror.Prefix(err, "payload_schema ->")
+		}
+
+		var sm map[string]interface{}
+		if err := hcl.DecodeObject(&sm, item.Val); err != nil {
+			return err
+		}
+		var schema api.DispatchPayloadSchema
+		if err := mapstructure.WeakDecode(sm, &schema); err != nil {
+			return err
+		}
+		d.PayloadSchema = &schema
+	}
+
 	*result = &d
 	return nil
 }
@@ -98,12 +98,14 @@ func TestParse(t *testing.T) {
 							SizeMB: helper.IntToPtr(150),
 						},
 						Update: &api.UpdateStrategy{
-							MaxParallel:     helper.IntToPtr(3),
-							HealthCheck:     helper.StringToPtr("checks"),
-							MinHealthyTime:  helper.TimeToPtr(1 * time.Second),
-							HealthyDeadline: helper.TimeToPtr(1 * time.Minute),
-							AutoRevert:      helper.BoolToPtr(false),
-							Canary:          helper.IntToPtr(2),
+							MaxParallel:      helper.IntToPtr(3),
+							HealthCheck:      helper.StringToPtr("checks"),
+							MinHealthyTime:   helper.TimeToPtr(1 * time.Second),
+							HealthyDeadline:  helper.TimeToPtr(1 * time.Minute),
+							AutoRevert:       helper.BoolToPtr(false),
+							Canary:           helper.IntToPtr(2),
+							AutoPromote:      helper.BoolToPtr(true),
+							ProgressDeadline: helper.TimeToPtr(5 * time.Minute),
 						},
 						Tasks: []*api.Task{
 							&api.Task{
@@ -120,8 +122,9 @@ func TestParse(t *testing.T) {
 								},
 								Services: []*api.Service{
 									{
-										Tags:      []string{"foo", "bar"},
-										PortLabel: "http",
+										Tags:       []string{"foo", "bar"},
+										CanaryTags: []string{"canary", "bar"},
+										PortLabel:  "http",
 										Checks: []api.ServiceCheck{
 											{
 												Name:      "check-name",
@@ -349,6 +352,136 @@ func TestParse(t *testing.T) {
 			false,
 		},
 
+		{
+			"periodic-crons.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("foo"),
+				Name: helper.StringToPtr("foo"),
+				Periodic: &api.PeriodicConfig{
+					SpecType: helper.StringToPtr(api.PeriodicSpecCron),
+					Specs:    []string{"0 9 * * 1-5", "0 13 * * 1-5"},
+					TimeZone: helper.StringToPtr("America/New_York"),
+					DSTMode:  helper.StringToPtr("skip"),
+				},
+			},
+			false,
+		},
+
+		{
+			"job-gc.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("foo"),
+				Name: helper.StringToPtr("foo"),
+				GC: &api.JobGCConfig{
+					AllocThreshold:      helper.TimeToPtr(30 * time.Minute),
+					EvalThreshold:       helper.TimeToPtr(72 * time.Hour),
+					DeploymentThreshold: helper.TimeToPtr(168 * time.Hour),
+				},
+			},
+			false,
+		},
+
+		{
+			"job-notification.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("foo"),
+				Name: helper.StringToPtr("foo"),
+				Type: helper.StringToPtr("batch"),
+				Notification: &api.JobNotification{
+					Webhook: helper.StringToPtr("https://ci.example.com/hooks/nomad"),
+					Method:  helper.StringToPtr("PUT"),
+					Headers: map[string]string{
+						"Authorization": "Bearer secret",
+					},
+					Payload: helper.StringToPtr("{\"job\": {{ json .JobID }}, \"failed\": {{ .Failed }}}\n"),
+					Timeout: helper.TimeToPtr(30 * time.Second),
+				},
+			},
+			false,
+		},
+
+		{
+			"job-depends-on.hcl",
+			&api.Job{
+				ID:        helper.StringToPtr("report"),
+				Name:      helper.StringToPtr("report"),
+				Type:      helper.StringToPtr("batch"),
+				DependsOn: []string{"extract", "transform"},
+			},
+			false,
+		},
+
+		{
+			"stop-after-client-disconnect.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("foo"),
+				Name: helper.StringToPtr("foo"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name:                      helper.StringToPtr("bar"),
+						StopAfterClientDisconnect: helper.TimeToPtr(5 * time.Minute),
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"max-client-disconnect.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("foo"),
+				Name: helper.StringToPtr("foo"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name:                helper.StringToPtr("bar"),
+						MaxClientDisconnect: helper.TimeToPtr(time.Hour),
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"group-network.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("foo"),
+				Name: helper.StringToPtr("foo"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("bar"),
+						Networks: []*api.NetworkResource{
+							{
+								Mode: "cni/mynet",
+							},
+						},
+					},
+					&api.TaskGroup{
+						Name: helper.StringToPtr("baz"),
+						Networks: []*api.NetworkResource{
+							{
+								Mode:          "bridge",
+								HostNetwork:   "public",
+								MBits:         helper.IntToPtr(50),
+								ReservedPorts: []api.Port{{Label: "http", Value: 80, To: 8080}},
+								DynamicPorts:  []api.Port{{Label: "admin", To: 9000}},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"job-namespace.hcl",
+			&api.Job{
+				ID:        helper.StringToPtr("foo"),
+				Name:      helper.StringToPtr("foo"),
+				Namespace: helper.StringToPtr("web"),
+			},
+			false,
+		},
+
 		{
 			"specify-job.hcl",
 			&api.Job{
@@ -463,6 +596,423 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"service-connect.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("connect"),
+				Name: helper.StringToPtr("connect"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Services: []*api.Service{
+									{
+										Name:      "web",
+										PortLabel: "http",
+										Connect: &api.ConsulConnect{
+											SidecarService: &api.ConsulSidecarService{
+												Proxy: &api.ConsulProxy{
+													Upstreams: []*api.ConsulUpstream{
+														{
+															DestinationName: "db",
+															LocalBindPort:   5432,
+														},
+														{
+															DestinationName:      "cache",
+															DestinationNamespace: "infra",
+															Datacenter:           "dc2",
+															LocalBindAddress:     "127.0.0.2",
+															LocalBindPort:        6379,
+															MeshGateway: &api.ConsulMeshGateway{
+																Mode: "remote",
+															},
+														},
+													},
+													MeshGateway: &api.ConsulMeshGateway{
+														Mode: "local",
+													},
+												},
+											},
+											SidecarTask: &api.SidecarTask{
+												Driver:      "docker",
+												KillTimeout: helper.TimeToPtr(10 * time.Second),
+												Config: map[string]interface{}{
+													"image": "envoyproxy/envoy:v1.12.0",
+												},
+												Resources: &api.Resources{
+													CPU:      helper.IntToPtr(500),
+													MemoryMB: helper.IntToPtr(256),
+												},
+												LogConfig: &api.LogConfig{
+													MaxFiles: helper.IntToPtr(3),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"service-provider.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("service_provider"),
+				Name: helper.StringToPtr("service_provider"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Services: []*api.Service{
+									{
+										Name:      "web",
+										PortLabel: "http",
+										Provider:  "nomad",
+										Checks: []api.ServiceCheck{
+											{
+												Name:     "alive",
+												Type:     "tcp",
+												Interval: 10 * time.Second,
+												Timeout:  2 * time.Second,
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"service-meta.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("service_meta"),
+				Name: helper.StringToPtr("service_meta"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Services: []*api.Service{
+									{
+										Name:      "web",
+										PortLabel: "http",
+										Meta: map[string]string{
+											"version": "${NOMAD_META_version}",
+											"weight":  "10",
+										},
+										TaggedAddresses: map[string]string{
+											"lan": "${NOMAD_IP_http}",
+											"wan": "203.0.113.10:8080",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"consul-namespace.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("consul_namespace"),
+				Name: helper.StringToPtr("consul_namespace"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Consul: &api.Consul{
+							Namespace: "web-team",
+							Partition: "billing",
+						},
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Services: []*api.Service{
+									{
+										Name:      "web",
+										PortLabel: "http",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"service-check-restart.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("service_check_restart"),
+				Name: helper.StringToPtr("service_check_restart"),
+				Type: helper.StringToPtr("service"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Services: []*api.Service{
+									{
+										Name: "http-service",
+										CheckRestart: &api.CheckRestart{
+											Limit:          3,
+											Grace:          helper.TimeToPtr(10 * time.Second),
+											IgnoreWarnings: true,
+										},
+										Checks: []api.ServiceCheck{
+											{
+												Name:      "random-check",
+												Type:      "tcp",
+												PortLabel: "9001",
+												Interval:  10 * time.Second,
+												Timeout:   2 * time.Second,
+												CheckRestart: &api.CheckRestart{
+													Limit: 5,
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"change-script.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("change_script"),
+				Name: helper.StringToPtr("change_script"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Vault: &api.Vault{
+									Policies:   []string{"foo"},
+									Env:        helper.BoolToPtr(true),
+									ChangeMode: helper.StringToPtr("script"),
+									ChangeScript: &api.ChangeScript{
+										Command: helper.StringToPtr("/bin/reload"),
+									},
+								},
+								Templates: []*api.Template{
+									{
+										SourcePath: helper.StringToPtr("foo"),
+										DestPath:   helper.StringToPtr("bar"),
+										ChangeMode: helper.StringToPtr("script"),
+										ChangeScript: &api.ChangeScript{
+											Command:     helper.StringToPtr("/bin/reload"),
+											Args:        []string{"-config", "local/bar"},
+											Timeout:     helper.TimeToPtr(10 * time.Second),
+											FailOnError: helper.BoolToPtr(true),
+										},
+										Splay: helper.TimeToPtr(5 * time.Second),
+										Perms: helper.StringToPtr("0644"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template-wait.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("template_wait"),
+				Name: helper.StringToPtr("template_wait"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Templates: []*api.Template{
+									{
+										SourcePath: helper.StringToPtr("foo"),
+										DestPath:   helper.StringToPtr("bar"),
+										ChangeMode: helper.StringToPtr("restart"),
+										Splay:      helper.TimeToPtr(10 * time.Second),
+										Perms:      helper.StringToPtr("0644"),
+										Wait: &api.WaitConfig{
+											Min: helper.TimeToPtr(5 * time.Second),
+											Max: helper.TimeToPtr(30 * time.Second),
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template-env-keys.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("template_env_keys"),
+				Name: helper.StringToPtr("template_env_keys"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Templates: []*api.Template{
+									{
+										SourcePath: helper.StringToPtr("foo"),
+										DestPath:   helper.StringToPtr("bar.env"),
+										ChangeMode: helper.StringToPtr("restart"),
+										Splay:      helper.TimeToPtr(5 * time.Second),
+										Perms:      helper.StringToPtr("0644"),
+										Envvars:    helper.BoolToPtr(true),
+										EnvKeys: map[string]string{
+											"USER":     "PGUSER",
+											"PASSWORD": "PGPASSWORD",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template-missing-key.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("template_missing_key"),
+				Name: helper.StringToPtr("template_missing_key"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Templates: []*api.Template{
+									{
+										SourcePath:    helper.StringToPtr("foo"),
+										DestPath:      helper.StringToPtr("bar"),
+										ChangeMode:    helper.StringToPtr("restart"),
+										Splay:         helper.TimeToPtr(5 * time.Second),
+										Perms:         helper.StringToPtr("0644"),
+										ErrMissingKey: helper.BoolToPtr(true),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"service-connect-gateway.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("gateways"),
+				Name: helper.StringToPtr("gateways"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "ingress",
+								Services: []*api.Service{
+									{
+										Name: "ingress",
+										Connect: &api.ConsulConnect{
+											Gateway: &api.ConsulGateway{
+												Proxy: &api.ConsulGatewayProxy{
+													ConnectTimeout: helper.TimeToPtr(3 * time.Second),
+												},
+												Ingress: &api.ConsulIngressConfigEntry{
+													Listeners: []*api.ConsulIngressListener{
+														{
+															Port:     8080,
+															Protocol: "http",
+															Services: []*api.ConsulIngressService{
+																{
+																	Name:  "web",
+																	Hosts: []string{"web.example.com"},
+																},
+															},
+														},
+														{
+															Port: 5432,
+															Services: []*api.ConsulIngressService{
+																{
+																	Name: "db",
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+							&api.Task{
+								Name: "terminating",
+								Services: []*api.Service{
+									{
+										Name: "terminating",
+										Connect: &api.ConsulConnect{
+											Gateway: &api.ConsulGateway{
+												Terminating: &api.ConsulTerminatingConfigEntry{
+													Services: []*api.ConsulLinkedService{
+														{
+															Name:     "billing",
+															CAFile:   "/etc/certs/ca.pem",
+															CertFile: "/etc/certs/cert.pem",
+															KeyFile:  "/etc/certs/key.pem",
+															SNI:      "billing.example.com",
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+							&api.Task{
+								Name: "mesh",
+								Services: []*api.Service{
+									{
+										Name: "mesh",
+										Connect: &api.ConsulConnect{
+											Gateway: &api.ConsulGateway{
+												Mesh: &api.ConsulMeshConfigEntry{},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
 		{
 			// TODO This should be pushed into the API
 			"vault_inheritance.hcl",
@@ -508,6 +1058,93 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"vault-role.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("vault_role"),
+				Name: helper.StringToPtr("vault_role"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "task",
+								Vault: &api.Vault{
+									Role:       helper.StringToPtr("web"),
+									Env:        helper.BoolToPtr(true),
+									ChangeMode: helper.StringToPtr(structs.VaultChangeModeRestart),
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"vault-disabled.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("vault_disabled"),
+				Name: helper.StringToPtr("vault_disabled"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "app",
+								Vault: &api.Vault{
+									Policies:   []string{"app"},
+									Env:        helper.BoolToPtr(true),
+									ChangeMode: helper.StringToPtr(structs.VaultChangeModeRestart),
+								},
+							},
+							&api.Task{
+								Name: "logs",
+								Vault: &api.Vault{
+									Enabled:    helper.BoolToPtr(false),
+									Env:        helper.BoolToPtr(true),
+									ChangeMode: helper.StringToPtr(structs.VaultChangeModeRestart),
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"vault-namespace.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("vault_namespace"),
+				Name: helper.StringToPtr("vault_namespace"),
+				TaskGroups: []*api.TaskGroup{
+					&api.TaskGroup{
+						Name: helper.StringToPtr("group"),
+						Tasks: []*api.Task{
+							&api.Task{
+								Name: "app",
+								Vault: &api.Vault{
+									Policies:   []string{"app"},
+									Namespace:  helper.StringToPtr("team-a"),
+									Env:        helper.BoolToPtr(true),
+									ChangeMode: helper.StringToPtr(structs.VaultChangeModeRestart),
+								},
+							},
+							&api.Task{
+								Name: "db",
+								Vault: &api.Vault{
+									Policies:   []string{"db"},
+									Namespace:  helper.StringToPtr("team-a/db"),
+									Env:        helper.BoolToPtr(true),
+									ChangeMode: helper.StringToPtr(structs.VaultChangeModeRestart),
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
 		{
 			"parameterized_job.hcl",
 			&api.Job{
@@ -518,6 +1155,20 @@ func TestParse(t *testing.T) {
 					Payload:      "required",
 					MetaRequired: []string{"foo", "bar"},
 					MetaOptional: []string{"baz", "bam"},
+					MetaSchema: map[string]*api.DispatchMetaSchema{
+						"foo": {
+							Type: "int",
+						},
+						"bar": {
+							Pattern: "^[a-z]+$",
+							Allowed: []string{"dev", "prod"},
+						},
+					},
+					PayloadSchema: &api.DispatchPayloadSchema{
+						MaxSize:        1024,
+						ContentType:    "application/json",
+						RequiredFields: []string{"id"},
+					},
 				},
 
 				TaskGroups: []*api.TaskGroup{
@@ -537,6 +1188,48 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"lifecycle.hcl",
+			&api.Job{
+				ID:   helper.StringToPtr("lifecycle"),
+				Name: helper.StringToPtr("lifecycle"),
+
+				TaskGroups: []*api.TaskGroup{
+					{
+						Name: helper.StringToPtr("cache"),
+						Tasks: []*api.Task{
+							{
+								Name:   "init",
+								Driver: "exec",
+								Lifecycle: &api.TaskLifecycle{
+									Hook: "prestart",
+								},
+							},
+							{
+								Name:   "proxy",
+								Driver: "docker",
+								Lifecycle: &api.TaskLifecycle{
+									Hook:    "prestart",
+									Sidecar: true,
+								},
+							},
+							{
+								Name:   "redis",
+								Driver: "docker",
+							},
+							{
+								Name:   "cleanup",
+								Driver: "exec",
+								Lifecycle: &api.TaskLifecycle{
+									Hook: "poststop",
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
 	}
 
 	for _, tc := range cases {
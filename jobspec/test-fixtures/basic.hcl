@@ -59,6 +59,8 @@ job "binstore-storagelocker" {
         healthy_deadline = "1m"
         auto_revert = false
         canary = 2
+        auto_promote = true
+        progress_deadline = "5m"
     }
 
     task "binstore" {
@@ -86,6 +88,7 @@ job "binstore-storagelocker" {
 
       service {
         tags = ["foo", "bar"]
+        canary_tags = ["canary", "bar"]
         port = "http"
 
         check {
@@ -0,0 +1,27 @@
+job "change_script" {
+  group "group" {
+    task "task" {
+      vault {
+        policies    = ["foo"]
+        change_mode = "script"
+
+        change_script {
+          command = "/bin/reload"
+        }
+      }
+
+      template {
+        source      = "foo"
+        destination = "bar"
+        change_mode = "script"
+
+        change_script {
+          command       = "/bin/reload"
+          args          = ["-config", "local/bar"]
+          timeout       = "10s"
+          fail_on_error = true
+        }
+      }
+    }
+  }
+}
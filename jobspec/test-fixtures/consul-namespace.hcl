@@ -0,0 +1,15 @@
+job "consul_namespace" {
+  group "group" {
+    consul {
+      namespace = "web-team"
+      partition = "billing"
+    }
+
+    task "task" {
+      service {
+        name = "web"
+        port = "http"
+      }
+    }
+  }
+}
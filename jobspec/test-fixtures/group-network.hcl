@@ -0,0 +1,24 @@
+job "foo" {
+    group "bar" {
+        network {
+            mode = "cni/mynet"
+        }
+    }
+
+    group "baz" {
+        network {
+            mode = "bridge"
+            host_network = "public"
+            mbits = 50
+
+            port "http" {
+                static = 80
+                to = 8080
+            }
+
+            port "admin" {
+                to = 9000
+            }
+        }
+    }
+}
@@ -0,0 +1,4 @@
+job "report" {
+    type = "batch"
+    depends_on = ["extract", "transform"]
+}
@@ -0,0 +1,7 @@
+job "foo" {
+    gc {
+        alloc_threshold = "30m"
+        eval_threshold = "72h"
+        deployment_threshold = "168h"
+    }
+}
@@ -0,0 +1,3 @@
+job "foo" {
+    namespace = "web"
+}
@@ -0,0 +1,17 @@
+job "foo" {
+    type = "batch"
+
+    notification {
+        webhook = "https://ci.example.com/hooks/nomad"
+        method = "PUT"
+        timeout = "30s"
+
+        headers {
+            Authorization = "Bearer secret"
+        }
+
+        payload = <<EOF
+{"job": {{ json .JobID }}, "failed": {{ .Failed }}}
+EOF
+    }
+}
@@ -0,0 +1,26 @@
+job "lifecycle" {
+    group "cache" {
+        task "init" {
+            driver = "exec"
+            lifecycle {
+                hook = "prestart"
+            }
+        }
+        task "proxy" {
+            driver = "docker"
+            lifecycle {
+                hook = "prestart"
+                sidecar = true
+            }
+        }
+        task "redis" {
+            driver = "docker"
+        }
+        task "cleanup" {
+            driver = "exec"
+            lifecycle {
+                hook = "poststop"
+            }
+        }
+    }
+}
@@ -0,0 +1,5 @@
+job "foo" {
+    group "bar" {
+        max_client_disconnect = "1h"
+    }
+}
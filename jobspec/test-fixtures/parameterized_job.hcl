@@ -3,6 +3,18 @@ job "parameterized_job" {
         payload = "required"
         meta_required = ["foo", "bar"]
         meta_optional = ["baz", "bam"]
+        meta_schema "foo" {
+            type = "int"
+        }
+        meta_schema "bar" {
+            pattern = "^[a-z]+$"
+            allowed = ["dev", "prod"]
+        }
+        payload_schema {
+            max_size = 1024
+            content_type = "application/json"
+            required_fields = ["id"]
+        }
     }
     group "foo" {
         task "bar" {
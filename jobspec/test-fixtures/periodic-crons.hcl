@@ -0,0 +1,7 @@
+job "foo" {
+    periodic {
+        crons = ["0 9 * * 1-5", "0 13 * * 1-5"]
+        time_zone = "America/New_York"
+        dst_mode = "skip"
+    }
+}
@@ -0,0 +1,69 @@
+job "gateways" {
+  group "group" {
+    task "ingress" {
+      service {
+        name = "ingress"
+
+        connect {
+          gateway {
+            proxy {
+              connect_timeout = "3s"
+            }
+
+            ingress {
+              listener {
+                port     = 8080
+                protocol = "http"
+
+                service {
+                  name  = "web"
+                  hosts = ["web.example.com"]
+                }
+              }
+
+              listener {
+                port = 5432
+
+                service {
+                  name = "db"
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+
+    task "terminating" {
+      service {
+        name = "terminating"
+
+        connect {
+          gateway {
+            terminating {
+              service {
+                name      = "billing"
+                ca_file   = "/etc/certs/ca.pem"
+                cert_file = "/etc/certs/cert.pem"
+                key_file  = "/etc/certs/key.pem"
+                sni       = "billing.example.com"
+              }
+            }
+          }
+        }
+      }
+    }
+
+    task "mesh" {
+      service {
+        name = "mesh"
+
+        connect {
+          gateway {
+            mesh {}
+          }
+        }
+      }
+    }
+  }
+}
@@ -0,0 +1,55 @@
+job "connect" {
+  group "group" {
+    task "task" {
+      service {
+        name = "web"
+        port = "http"
+
+        connect {
+          sidecar_service {
+            proxy {
+              upstreams {
+                destination_name = "db"
+                local_bind_port  = 5432
+              }
+
+              upstreams {
+                destination_name      = "cache"
+                destination_namespace = "infra"
+                datacenter            = "dc2"
+                local_bind_address    = "127.0.0.2"
+                local_bind_port       = 6379
+
+                mesh_gateway {
+                  mode = "remote"
+                }
+              }
+
+              mesh_gateway {
+                mode = "local"
+              }
+            }
+          }
+
+          sidecar_task {
+            driver       = "docker"
+            kill_timeout = "10s"
+
+            config {
+              image = "envoyproxy/envoy:v1.12.0"
+            }
+
+            resources {
+              cpu    = 500
+              memory = 256
+            }
+
+            logs {
+              max_files = 3
+            }
+          }
+        }
+      }
+    }
+  }
+}
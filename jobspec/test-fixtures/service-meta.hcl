@@ -0,0 +1,20 @@
+job "service_meta" {
+  group "group" {
+    task "task" {
+      service {
+        name = "web"
+        port = "http"
+
+        meta {
+          version = "${NOMAD_META_version}"
+          weight  = "10"
+        }
+
+        tagged_addresses {
+          lan = "${NOMAD_IP_http}"
+          wan = "203.0.113.10:8080"
+        }
+      }
+    }
+  }
+}
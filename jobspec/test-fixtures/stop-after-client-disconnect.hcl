@@ -0,0 +1,5 @@
+job "foo" {
+    group "bar" {
+        stop_after_client_disconnect = "5m"
+    }
+}
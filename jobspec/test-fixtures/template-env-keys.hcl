@@ -0,0 +1,16 @@
+job "template_env_keys" {
+  group "group" {
+    task "task" {
+      template {
+        source      = "foo"
+        destination = "bar.env"
+        env         = true
+
+        env_keys {
+          USER     = "PGUSER"
+          PASSWORD = "PGPASSWORD"
+        }
+      }
+    }
+  }
+}
@@ -0,0 +1,11 @@
+job "template_missing_key" {
+  group "group" {
+    task "task" {
+      template {
+        source               = "foo"
+        destination          = "bar"
+        error_on_missing_key = true
+      }
+    }
+  }
+}
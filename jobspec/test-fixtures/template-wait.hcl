@@ -0,0 +1,16 @@
+job "template_wait" {
+  group "group" {
+    task "task" {
+      template {
+        source      = "foo"
+        destination = "bar"
+        splay       = "10s"
+
+        wait {
+          min = "5s"
+          max = "30s"
+        }
+      }
+    }
+  }
+}
@@ -0,0 +1,15 @@
+job "vault_disabled" {
+  group "group" {
+    vault {
+      policies = ["app"]
+    }
+
+    task "app" {}
+
+    task "logs" {
+      vault {
+        enabled = false
+      }
+    }
+  }
+}
@@ -0,0 +1,17 @@
+job "vault_namespace" {
+  vault {
+    policies  = ["app"]
+    namespace = "team-a"
+  }
+
+  group "group" {
+    task "app" {}
+
+    task "db" {
+      vault {
+        policies  = ["db"]
+        namespace = "team-a/db"
+      }
+    }
+  }
+}
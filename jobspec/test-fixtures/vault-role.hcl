@@ -0,0 +1,9 @@
+job "vault_role" {
+  group "group" {
+    task "task" {
+      vault {
+        role = "web"
+      }
+    }
+  }
+}
@@ -28,12 +28,15 @@ func RunCustom(args []string, commands map[string]cli.CommandFactory) int {
 	commandsInclude := make([]string, 0, len(commands))
 	for k, _ := range commands {
 		switch k {
+		case "alloc exec", "alloc signal", "alloc restart", "alloc stop":
 		case "check":
 		case "deployment list", "deployment status", "deployment pause",
 			"deployment resume", "deployment fail", "deployment promote":
 		case "executor":
 		case "fs ls", "fs cat", "fs stat":
-		case "job deployments", "job dispatch", "job history", "job promote", "job revert":
+		case "job deployments", "job dispatch", "job history", "job periodic",
+			"job periodic force", "job promote", "job revert", "job scale":
+		case "node meta", "node meta apply", "node meta read", "node meta unset":
 		case "operator raft", "operator raft list-peers", "operator raft remove-peer":
 		case "syslog":
 		default:
@@ -0,0 +1,356 @@
+package nomad
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	memdb "github.com/hashicorp/go-memdb"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// aclCacheSize is the number of ACLs compiled from sets of policies that
+	// are cached
+	aclCacheSize = 512
+)
+
+// ResolveToken resolves the secret ID of a request to the ACL of its token.
+// It returns a nil ACL if ACLs are disabled, in which case everything is
+// allowed. Requests without a secret ID are granted the permissions of the
+// anonymous policy.
+func (s *Server) ResolveToken(secretID string) (*acl.ACL, error) {
+	if !s.config.ACLEnabled {
+		return nil, nil
+	}
+
+	// The leader's token is used for the work the leader issues itself
+	if leaderAcl := s.getLeaderAcl(); leaderAcl != "" && secretID == leaderAcl {
+		return acl.ManagementACL, nil
+	}
+
+	snap, err := s.fsm.State().Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	if isWorkloadIdentity(secretID) {
+		return s.resolveWorkloadIdentity(snap, secretID)
+	}
+	return resolveTokenFromSnapshot(snap, s.aclCache, secretID)
+}
+
+// ResolveACLToken returns the token of the secret ID, or the anonymous token
+// if the secret ID is empty. It returns a nil token if ACLs are disabled or
+// the secret ID isn't the one of an ACL token, such as a workload identity.
+func (s *Server) ResolveACLToken(secretID string) (*structs.ACLToken, error) {
+	if !s.config.ACLEnabled || isWorkloadIdentity(secretID) {
+		return nil, nil
+	}
+	if secretID == "" {
+		return structs.AnonymousACLToken, nil
+	}
+	return s.fsm.State().ACLTokenBySecretID(nil, secretID)
+}
+
+// allowToken returns whether a request made with the token of the secret ID is
+// within the RPC rate limit of the token. Anonymous requests and the requests
+// of unknown tokens are only limited per client IP address; the latter are
+// rejected by the endpoints.
+func (s *Server) allowToken(secretID string) bool {
+	if secretID == "" {
+		return true
+	}
+	token, err := s.ResolveACLToken(secretID)
+	if err != nil || token == nil {
+		return true
+	}
+	if !s.rpcTokenLimiter.Allow(token.AccessorID) {
+		metrics.IncrCounter([]string{"nomad", "rpc", "rate_limited"}, 1)
+		return false
+	}
+	return true
+}
+
+// checkTokenSource returns ErrPermissionDenied if the token of the secret ID
+// may not be used from the IP address
+func (s *Server) checkTokenSource(secretID, ip string) error {
+	if !s.config.ACLEnabled || secretID == "" || isWorkloadIdentity(secretID) {
+		return nil
+	}
+	token, err := s.fsm.State().ACLTokenBySecretID(nil, secretID)
+	if err != nil {
+		return err
+	}
+	if token != nil && !token.AllowsSource(ip) {
+		s.logger.Printf("[WARN] nomad: ACL token %s used from disallowed address %q", token.AccessorID, ip)
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
+// resolveTokenFromSnapshot resolves the secret ID to the ACL of its token from
+// the state snapshot, caching the ACLs compiled from sets of policies.
+func resolveTokenFromSnapshot(snap *state.StateSnapshot, cache *lru.TwoQueueCache, secretID string) (*acl.ACL, error) {
+	token := structs.AnonymousACLToken
+	if secretID != "" {
+		var err error
+		token, err = snap.ACLTokenBySecretID(nil, secretID)
+		if err != nil {
+			return nil, err
+		}
+		if token == nil {
+			return nil, structs.ErrTokenNotFound
+		}
+		if token.IsExpired(time.Now()) {
+			return nil, structs.ErrTokenExpired
+		}
+	}
+
+	if token.Type == structs.ACLManagementToken {
+		return acl.ManagementACL, nil
+	}
+
+	policies, err := tokenPolicies(nil, &snap.StateStore, token)
+	if err != nil {
+		return nil, err
+	}
+	return structs.CompileACLObject(cache, policies)
+}
+
+// tokenPolicyNames returns the names of the policies of the token, including
+// the policies of its roles. Missing roles grant nothing.
+func tokenPolicyNames(ws memdb.WatchSet, state *state.StateStore, token *structs.ACLToken) ([]string, error) {
+	if len(token.Roles) == 0 {
+		return token.Policies, nil
+	}
+
+	seen := make(map[string]struct{}, len(token.Policies))
+	names := make([]string, 0, len(token.Policies))
+	add := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	for _, name := range token.Policies {
+		add(name)
+	}
+	for _, roleName := range token.Roles {
+		role, err := state.ACLRoleByName(ws, roleName)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			continue
+		}
+		for _, name := range role.Policies {
+			add(name)
+		}
+	}
+	return names, nil
+}
+
+// tokenPolicies returns the policies of the token, including the policies of
+// its roles. Missing policies grant nothing.
+func tokenPolicies(ws memdb.WatchSet, state *state.StateStore, token *structs.ACLToken) ([]*structs.ACLPolicy, error) {
+	names, err := tokenPolicyNames(ws, state, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []*structs.ACLPolicy
+	for _, name := range names {
+		policy, err := state.ACLPolicyByName(ws, name)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+// resolveClientOrToken resolves the secret ID of a request made either by a
+// client, with the secret ID of its node, or with an ACL token. It returns
+// the node of the client or else the ACL of the token, which is nil if ACLs
+// are disabled.
+func (s *Server) resolveClientOrToken(secretID string) (*structs.Node, *acl.ACL, error) {
+	if secretID != "" {
+		node, err := s.fsm.State().NodeBySecretID(nil, secretID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if node != nil {
+			return node, nil, nil
+		}
+	}
+
+	aclObj, err := s.ResolveToken(secretID)
+	return nil, aclObj, err
+}
+
+// setLeaderAcl sets the leader's token
+func (s *Server) setLeaderAcl(secretID string) {
+	s.leaderAclLock.Lock()
+	s.leaderAcl = secretID
+	s.leaderAclLock.Unlock()
+}
+
+// getLeaderAcl returns the leader's token, which is a management token as
+// long as this server is the leader. It is empty otherwise.
+func (s *Server) getLeaderAcl() string {
+	s.leaderAclLock.Lock()
+	defer s.leaderAclLock.Unlock()
+	return s.leaderAcl
+}
+
+// checkNamespaceRead returns ErrPermissionDenied unless the token of the
+// secret ID is allowed to read the namespace
+func (s *Server) checkNamespaceRead(secretID, ns string) error {
+	aclObj, err := s.ResolveToken(secretID)
+	if err != nil {
+		return err
+	}
+	if !aclObj.AllowNamespaceRead(ns) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
+// checkNamespaceWrite returns ErrPermissionDenied unless the token of the
+// secret ID is allowed to write the namespace
+func (s *Server) checkNamespaceWrite(secretID, ns string) error {
+	aclObj, err := s.ResolveToken(secretID)
+	if err != nil {
+		return err
+	}
+	if !aclObj.AllowNamespaceWrite(ns) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
+// checkNamespaceOperation returns ErrPermissionDenied unless the token of the
+// secret ID is granted the capability on the namespace
+func (s *Server) checkNamespaceOperation(secretID, ns, capability string) error {
+	aclObj, err := s.ResolveToken(secretID)
+	if err != nil {
+		return err
+	}
+	if !aclObj.AllowNamespaceOperation(ns, capability) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
+
+// checkHostVolumes returns ErrPermissionDenied unless the token of the secret
+// ID may mount the host volumes of the tasks of the job. The sources of host
+// volumes can't be interpolated, as their paths aren't known until the tasks
+// run.
+func (s *Server) checkHostVolumes(secretID string, job *structs.Job) error {
+	aclObj, err := s.ResolveToken(secretID)
+	if err != nil {
+		return err
+	}
+	if aclObj.IsManagement() {
+		return nil
+	}
+
+	ns := namespaceOfJob(job)
+	for _, tg := range job.TaskGroups {
+		for _, task := range tg.Tasks {
+			for _, v := range taskHostVolumes(task) {
+				if strings.Contains(v.path, "${") || !aclObj.AllowHostVolume(ns, v.path, v.readOnly) {
+					return fmt.Errorf("%v: task %q can't mount host volume %q", structs.ErrPermissionDenied, task.Name, v.path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// hostVolume is a host path mounted by a task
+type hostVolume struct {
+	path     string
+	readOnly bool
+}
+
+// taskHostVolumes returns the host paths the task mounts with the volumes of
+// its driver. Docker mounts the relative paths inside the task directory,
+// unless they escape it, and the named volumes of volume drivers, which
+// aren't host paths. Interpolated paths are returned as is.
+func taskHostVolumes(task *structs.Task) []*hostVolume {
+	var volumes []string
+	switch raw := task.Config["volumes"].(type) {
+	case []string:
+		volumes = raw
+	case []interface{}:
+		for _, v := range raw {
+			if str, ok := v.(string); ok {
+				volumes = append(volumes, str)
+			}
+		}
+	}
+
+	var hostVolumes []*hostVolume
+	for _, v := range volumes {
+		parts := strings.Split(v, ":")
+		hostPath := parts[0]
+		interpolated := strings.Contains(hostPath, "${")
+		if !interpolated {
+			hostPath = filepath.Clean(hostPath)
+		}
+
+		switch task.Driver {
+		case "docker":
+			// /host/path:/container/path[:ro,...]
+			if !interpolated && !filepath.IsAbs(hostPath) && !strings.HasPrefix(hostPath, "..") {
+				continue
+			}
+			readOnly := false
+			if len(parts) > 2 {
+				for _, opt := range strings.Split(parts[2], ",") {
+					readOnly = readOnly || opt == "ro"
+				}
+			}
+			hostVolumes = append(hostVolumes, &hostVolume{path: hostPath, readOnly: readOnly})
+		case "rkt":
+			// /host/path:/container/path[:readOnly]
+			readOnly := len(parts) > 2 && parts[2] == "readOnly"
+			hostVolumes = append(hostVolumes, &hostVolume{path: hostPath, readOnly: readOnly})
+		}
+	}
+	return hostVolumes
+}
+
+// jobNamespace returns the namespace of the job. Evaluations and deployments
+// are in the namespace of their job, which is the default namespace once the
+// job is purged.
+func jobNamespace(ws memdb.WatchSet, state *state.StateStore, jobID string) (string, error) {
+	job, err := state.JobByID(ws, jobID)
+	if err != nil {
+		return "", err
+	}
+	return namespaceOfJob(job), nil
+}
+
+// namespaceOfJob returns the namespace of the job, which may be nil once
+// purged
+func namespaceOfJob(job *structs.Job) string {
+	if job == nil || job.Namespace == "" {
+		return structs.DefaultNamespace
+	}
+	return job.Namespace
+}
+
+// allocNamespace returns the namespace of the allocation, which is the one of
+// its job
+func allocNamespace(alloc *structs.Allocation) string {
+	return namespaceOfJob(alloc.Job)
+}
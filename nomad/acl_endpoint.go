@@ -0,0 +1,1715 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+var (
+	// aclDisabled is returned when an ACL endpoint is used but ACLs are
+	// disabled
+	aclDisabled = fmt.Errorf("ACL support disabled")
+)
+
+// ACL endpoint is used for manipulating ACL tokens, policies, roles, auth
+// methods and binding rules, and for logging in with the auth methods
+type ACL struct {
+	srv *Server
+}
+
+// Bootstrap is used to create the initial management token. It can only be
+// done once.
+func (a *ACL) Bootstrap(args *structs.ACLTokenBootstrapRequest, reply *structs.ACLTokenUpsertResponse) error {
+	// The initial token is global so it is created in the authoritative
+	// region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.Bootstrap", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "bootstrap"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	ok, err := a.srv.fsm.State().CanBootstrapACLToken()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("ACL bootstrap already done")
+	}
+
+	args.Token = &structs.ACLToken{
+		AccessorID: structs.GenerateUUID(),
+		SecretID:   structs.GenerateUUID(),
+		Name:       "Bootstrap Token",
+		Type:       structs.ACLManagementToken,
+		Global:     true,
+		CreateTime: time.Now().UTC(),
+	}
+	args.Token.SetHash()
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLTokenBootstrapRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Return the created token
+	out, err := a.srv.fsm.State().ACLTokenByAccessorID(nil, args.Token.AccessorID)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		reply.Tokens = append(reply.Tokens, out)
+	}
+	reply.Index = index
+	return nil
+}
+
+// UpsertPolicies is used to create or update a set of policies
+func (a *ACL) UpsertPolicies(args *structs.ACLPolicyUpsertRequest, reply *structs.GenericResponse) error {
+	// Policies are managed in the authoritative region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.UpsertPolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "upsert_policies"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage policies
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Policies) == 0 {
+		return fmt.Errorf("must specify at least one policy")
+	}
+	for _, policy := range args.Policies {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("Invalid policy %q: %v", policy.Name, err)
+		}
+		if _, err := acl.Parse(policy.Rules); err != nil {
+			return fmt.Errorf("Invalid policy %q: %v", policy.Name, err)
+		}
+		policy.SetHash()
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLPolicyUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// DeletePolicies is used to delete a set of policies
+func (a *ACL) DeletePolicies(args *structs.ACLPolicyDeleteRequest, reply *structs.GenericResponse) error {
+	// Policies are managed in the authoritative region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.DeletePolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "delete_policies"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage policies
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Names) == 0 {
+		return fmt.Errorf("must specify at least one policy to delete")
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLPolicyDeleteRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// ListPolicies is used to list the policies. Management tokens list all the
+// policies while other tokens only list their own and the policies of their
+// roles.
+func (a *ACL) ListPolicies(args *structs.ACLPolicyListRequest, reply *structs.ACLPolicyListResponse) error {
+	if done, err := a.srv.forward("ACL.ListPolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "list_policies"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Resolve the token to the policies it may list
+	token, err := a.requestToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	management := token.Type == structs.ACLManagementToken
+	names, err := tokenPolicyNames(nil, a.srv.fsm.State(), token)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Capture all the policies
+			iter, err := state.PrefixFrom(ws, "acl_policy", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
+			if err != nil {
+				return err
+			}
+
+			iter = newFilterIterator(iter, func(raw interface{}) bool {
+				return management || helper.SliceStringContains(names, raw.(*structs.ACLPolicy).Name)
+			})
+
+			var policies []*structs.ACLPolicyListStub
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.ACLPolicy).Name
+			}, func(raw interface{}) (interface{}, error) {
+				return raw.(*structs.ACLPolicy).Stub(), nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				policies = append(policies, obj.(*structs.ACLPolicyListStub))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			reply.Policies = policies
+			reply.NextToken = nextToken
+
+			// Use the last index that affected the policy table
+			index, err := state.Index("acl_policy")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetPolicy is used to get a specific policy. Tokens other than management
+// tokens can only get their own policies and the policies of their roles.
+func (a *ACL) GetPolicy(args *structs.ACLPolicySpecificRequest, reply *structs.SingleACLPolicyResponse) error {
+	if done, err := a.srv.forward("ACL.GetPolicy", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_policy"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	token, err := a.requestToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	if token.Type != structs.ACLManagementToken {
+		names, err := tokenPolicyNames(nil, a.srv.fsm.State(), token)
+		if err != nil {
+			return err
+		}
+		if !helper.SliceStringContains(names, args.Name) {
+			return structs.ErrPermissionDenied
+		}
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the policy
+			out, err := state.ACLPolicyByName(ws, args.Name)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Policy = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the policy table
+				index, err := state.Index("acl_policy")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetPolicies is used to get a set of policies. It is used by the servers to
+// replicate the policies.
+func (a *ACL) GetPolicies(args *structs.ACLPolicySetRequest, reply *structs.ACLPolicySetResponse) error {
+	if done, err := a.srv.forward("ACL.GetPolicies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_policies"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the policies
+			reply.Policies = make(map[string]*structs.ACLPolicy, len(args.Names))
+			for _, name := range args.Names {
+				out, err := state.ACLPolicyByName(ws, name)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					reply.Policies[name] = out
+				}
+			}
+
+			// Use the last index that affected the policy table
+			index, err := state.Index("acl_policy")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// UpsertRoles is used to create or update a set of roles
+func (a *ACL) UpsertRoles(args *structs.ACLRoleUpsertRequest, reply *structs.GenericResponse) error {
+	// Roles are managed in the authoritative region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.UpsertRoles", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "upsert_roles"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage roles
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Roles) == 0 {
+		return fmt.Errorf("must specify at least one role")
+	}
+	state := a.srv.fsm.State()
+	for _, role := range args.Roles {
+		if err := role.Validate(); err != nil {
+			return fmt.Errorf("Invalid role %q: %v", role.Name, err)
+		}
+
+		// The policies of the role must exist
+		for _, name := range role.Policies {
+			policy, err := state.ACLPolicyByName(nil, name)
+			if err != nil {
+				return err
+			}
+			if policy == nil {
+				return fmt.Errorf("policy %q not found", name)
+			}
+		}
+		role.SetHash()
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLRoleUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// DeleteRoles is used to delete a set of roles. The tokens of a deleted role
+// keep it, but are no longer granted its policies.
+func (a *ACL) DeleteRoles(args *structs.ACLRoleDeleteRequest, reply *structs.GenericResponse) error {
+	// Roles are managed in the authoritative region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.DeleteRoles", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "delete_roles"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage roles
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Names) == 0 {
+		return fmt.Errorf("must specify at least one role to delete")
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLRoleDeleteRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// ListRoles is used to list the roles. Management tokens list all the roles
+// while other tokens only list their own.
+func (a *ACL) ListRoles(args *structs.ACLRoleListRequest, reply *structs.ACLRoleListResponse) error {
+	if done, err := a.srv.forward("ACL.ListRoles", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "list_roles"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Resolve the token to the roles it may list
+	token, err := a.requestToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	management := token.Type == structs.ACLManagementToken
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Capture all the roles
+			iter, err := state.PrefixFrom(ws, "acl_role", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
+			if err != nil {
+				return err
+			}
+
+			iter = newFilterIterator(iter, func(raw interface{}) bool {
+				return management || helper.SliceStringContains(token.Roles, raw.(*structs.ACLRole).Name)
+			})
+
+			var roles []*structs.ACLRoleListStub
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.ACLRole).Name
+			}, func(raw interface{}) (interface{}, error) {
+				return raw.(*structs.ACLRole).Stub(), nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				roles = append(roles, obj.(*structs.ACLRoleListStub))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			reply.Roles = roles
+			reply.NextToken = nextToken
+
+			// Use the last index that affected the role table
+			index, err := state.Index("acl_role")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetRole is used to get a specific role. Tokens other than management tokens
+// can only get their own roles.
+func (a *ACL) GetRole(args *structs.ACLRoleSpecificRequest, reply *structs.SingleACLRoleResponse) error {
+	if done, err := a.srv.forward("ACL.GetRole", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_role"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	token, err := a.requestToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	if token.Type != structs.ACLManagementToken && !helper.SliceStringContains(token.Roles, args.Name) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the role
+			out, err := state.ACLRoleByName(ws, args.Name)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Role = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the role table
+				index, err := state.Index("acl_role")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetRoles is used to get a set of roles. It is used by the servers to
+// replicate the roles.
+func (a *ACL) GetRoles(args *structs.ACLRoleSetRequest, reply *structs.ACLRoleSetResponse) error {
+	if done, err := a.srv.forward("ACL.GetRoles", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_roles"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the roles
+			reply.Roles = make(map[string]*structs.ACLRole, len(args.Names))
+			for _, name := range args.Names {
+				out, err := state.ACLRoleByName(ws, name)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					reply.Roles[name] = out
+				}
+			}
+
+			// Use the last index that affected the role table
+			index, err := state.Index("acl_role")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// UpsertAuthMethods is used to create or update a set of auth methods
+func (a *ACL) UpsertAuthMethods(args *structs.ACLAuthMethodUpsertRequest, reply *structs.GenericResponse) error {
+	// Auth methods are managed in the authoritative region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.UpsertAuthMethods", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "upsert_auth_methods"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage auth methods
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.AuthMethods) == 0 {
+		return fmt.Errorf("must specify at least one auth method")
+	}
+	defaults := 0
+	for _, method := range args.AuthMethods {
+		if err := method.Validate(a.srv.config.ACLTokenMinExpirationTTL, a.srv.config.ACLTokenMaxExpirationTTL); err != nil {
+			return fmt.Errorf("Invalid auth method %q: %v", method.Name, err)
+		}
+		if method.Default {
+			defaults++
+		}
+		method.SetHash()
+	}
+	if defaults > 1 {
+		return fmt.Errorf("only one auth method can be the default")
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLAuthMethodUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// DeleteAuthMethods is used to delete a set of auth methods along with their
+// binding rules. The tokens created by the auth methods are kept.
+func (a *ACL) DeleteAuthMethods(args *structs.ACLAuthMethodDeleteRequest, reply *structs.GenericResponse) error {
+	// Auth methods are managed in the authoritative region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.DeleteAuthMethods", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "delete_auth_methods"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage auth methods
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Names) == 0 {
+		return fmt.Errorf("must specify at least one auth method to delete")
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLAuthMethodDeleteRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// ListAuthMethods is used to list the auth methods. The list doesn't require
+// a token since users pick the auth method to log in with from it.
+func (a *ACL) ListAuthMethods(args *structs.ACLAuthMethodListRequest, reply *structs.ACLAuthMethodListResponse) error {
+	if done, err := a.srv.forward("ACL.ListAuthMethods", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "list_auth_methods"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Capture all the auth methods
+			iter, err := state.PrefixFrom(ws, "acl_auth_method", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
+			if err != nil {
+				return err
+			}
+
+			var methods []*structs.ACLAuthMethodStub
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.ACLAuthMethod).Name
+			}, func(raw interface{}) (interface{}, error) {
+				return raw.(*structs.ACLAuthMethod).Stub(), nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				methods = append(methods, obj.(*structs.ACLAuthMethodStub))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			reply.AuthMethods = methods
+			reply.NextToken = nextToken
+
+			// Use the last index that affected the auth method table
+			index, err := state.Index("acl_auth_method")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetAuthMethod is used to get a specific auth method, which requires a
+// management token since the config holds the secrets of the auth method
+func (a *ACL) GetAuthMethod(args *structs.ACLAuthMethodSpecificRequest, reply *structs.SingleACLAuthMethodResponse) error {
+	if done, err := a.srv.forward("ACL.GetAuthMethod", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_auth_method"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the auth method
+			out, err := state.ACLAuthMethodByName(ws, args.Name)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.AuthMethod = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the auth method table
+				index, err := state.Index("acl_auth_method")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetAuthMethods is used to get a set of auth methods. It is used by the
+// servers to replicate the auth methods.
+func (a *ACL) GetAuthMethods(args *structs.ACLAuthMethodSetRequest, reply *structs.ACLAuthMethodSetResponse) error {
+	if done, err := a.srv.forward("ACL.GetAuthMethods", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_auth_methods"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the auth methods
+			reply.AuthMethods = make(map[string]*structs.ACLAuthMethod, len(args.Names))
+			for _, name := range args.Names {
+				out, err := state.ACLAuthMethodByName(ws, name)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					reply.AuthMethods[name] = out
+				}
+			}
+
+			// Use the last index that affected the auth method table
+			index, err := state.Index("acl_auth_method")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// UpsertBindingRules is used to create or update a set of binding rules.
+// Binding rules without an ID are created.
+func (a *ACL) UpsertBindingRules(args *structs.ACLBindingRuleUpsertRequest, reply *structs.ACLBindingRuleUpsertResponse) error {
+	// Binding rules are managed in the authoritative region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.UpsertBindingRules", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "upsert_binding_rules"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage binding rules
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.BindingRules) == 0 {
+		return fmt.Errorf("must specify at least one binding rule")
+	}
+	state := a.srv.fsm.State()
+	for _, rule := range args.BindingRules {
+		if rule.ID == "" {
+			rule.ID = structs.GenerateUUID()
+		} else {
+			existing, err := state.ACLBindingRuleByID(nil, rule.ID)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				return fmt.Errorf("binding rule %q not found", rule.ID)
+			}
+		}
+
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("Invalid binding rule: %v", err)
+		}
+
+		// The auth method of the binding rule must exist
+		method, err := state.ACLAuthMethodByName(nil, rule.AuthMethod)
+		if err != nil {
+			return err
+		}
+		if method == nil {
+			return fmt.Errorf("auth method %q not found", rule.AuthMethod)
+		}
+		rule.SetHash()
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLBindingRuleUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Return the created or updated binding rules
+	state = a.srv.fsm.State()
+	for _, rule := range args.BindingRules {
+		out, err := state.ACLBindingRuleByID(nil, rule.ID)
+		if err != nil {
+			return err
+		}
+		if out != nil {
+			reply.BindingRules = append(reply.BindingRules, out)
+		}
+	}
+	reply.Index = index
+	return nil
+}
+
+// DeleteBindingRules is used to delete a set of binding rules
+func (a *ACL) DeleteBindingRules(args *structs.ACLBindingRuleDeleteRequest, reply *structs.GenericResponse) error {
+	// Binding rules are managed in the authoritative region
+	args.Region = a.srv.config.AuthoritativeRegion
+	if done, err := a.srv.forward("ACL.DeleteBindingRules", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "delete_binding_rules"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage binding rules
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.IDs) == 0 {
+		return fmt.Errorf("must specify at least one binding rule to delete")
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLBindingRuleDeleteRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// ListBindingRules is used to list the binding rules
+func (a *ACL) ListBindingRules(args *structs.ACLBindingRuleListRequest, reply *structs.ACLBindingRuleListResponse) error {
+	if done, err := a.srv.forward("ACL.ListBindingRules", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "list_binding_rules"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can list binding rules
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Capture all the binding rules
+			iter, err := state.PrefixFrom(ws, "acl_binding_rule", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
+			if err != nil {
+				return err
+			}
+
+			var rules []*structs.ACLBindingRule
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.ACLBindingRule).ID
+			}, func(raw interface{}) (interface{}, error) {
+				return raw, nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				rules = append(rules, obj.(*structs.ACLBindingRule))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			reply.BindingRules = rules
+			reply.NextToken = nextToken
+
+			// Use the last index that affected the binding rule table
+			index, err := state.Index("acl_binding_rule")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetBindingRule is used to get a specific binding rule
+func (a *ACL) GetBindingRule(args *structs.ACLBindingRuleSpecificRequest, reply *structs.SingleACLBindingRuleResponse) error {
+	if done, err := a.srv.forward("ACL.GetBindingRule", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_binding_rule"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the binding rule
+			out, err := state.ACLBindingRuleByID(ws, args.ID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.BindingRule = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the binding rule table
+				index, err := state.Index("acl_binding_rule")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetBindingRules is used to get a set of binding rules. It is used by the
+// servers to replicate the binding rules.
+func (a *ACL) GetBindingRules(args *structs.ACLBindingRuleSetRequest, reply *structs.ACLBindingRuleSetResponse) error {
+	if done, err := a.srv.forward("ACL.GetBindingRules", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_binding_rules"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the binding rules
+			reply.BindingRules = make(map[string]*structs.ACLBindingRule, len(args.IDs))
+			for _, id := range args.IDs {
+				out, err := state.ACLBindingRuleByID(ws, id)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					reply.BindingRules[id] = out
+				}
+			}
+
+			// Use the last index that affected the binding rule table
+			index, err := state.Index("acl_binding_rule")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// OIDCAuthURL is used to start logging in with an OIDC auth method. It
+// returns the URL of the OIDC provider the user logs in at. Logins of auth
+// methods creating global tokens are handled by the authoritative region.
+func (a *ACL) OIDCAuthURL(args *structs.ACLOIDCAuthURLRequest, reply *structs.ACLOIDCAuthURLResponse) error {
+	method, err := a.loginAuthMethod(args.AuthMethodName, structs.ACLAuthMethodTypeOIDC)
+	if err != nil {
+		return err
+	}
+	if method.TokenGlobal() {
+		args.Region = a.srv.config.AuthoritativeRegion
+	}
+
+	// The login completes on the leader which started it
+	if done, err := a.srv.forward("ACL.OIDCAuthURL", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "oidc_auth_url"}, time.Now())
+
+	if args.RedirectURI == "" || args.ClientNonce == "" {
+		return fmt.Errorf("missing redirect URI or client nonce")
+	}
+
+	authURL, err := a.srv.oidcAuthURL(method, args.RedirectURI, args.ClientNonce)
+	if err != nil {
+		return err
+	}
+	reply.AuthURL = authURL
+	return nil
+}
+
+// OIDCCompleteAuth is used to complete logging in with an OIDC auth method.
+// It exchanges the authorization code for an ACL token granted the roles and
+// policies of the binding rules matching the identity of the user.
+func (a *ACL) OIDCCompleteAuth(args *structs.ACLOIDCCompleteAuthRequest, reply *structs.ACLLoginResponse) error {
+	method, err := a.loginAuthMethod(args.AuthMethodName, structs.ACLAuthMethodTypeOIDC)
+	if err != nil {
+		return err
+	}
+	if method.TokenGlobal() {
+		args.Region = a.srv.config.AuthoritativeRegion
+	}
+
+	if done, err := a.srv.forward("ACL.OIDCCompleteAuth", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "oidc_complete_auth"}, time.Now())
+
+	if args.State == "" || args.Code == "" {
+		return fmt.Errorf("missing state or authorization code")
+	}
+
+	identity, err := a.srv.oidcCompleteAuth(method, args)
+	if err != nil {
+		return fmt.Errorf("%v: %v", structs.ErrPermissionDenied, err)
+	}
+	return a.createLoginToken(method, identity, reply)
+}
+
+// Login is used to log in with a JWT auth method. It exchanges the JWT for an
+// ACL token granted the roles and policies of the binding rules matching the
+// identity asserted by the JWT.
+func (a *ACL) Login(args *structs.ACLLoginRequest, reply *structs.ACLLoginResponse) error {
+	method, err := a.loginAuthMethod(args.AuthMethodName, structs.ACLAuthMethodTypeJWT)
+	if err != nil {
+		return err
+	}
+	if method.TokenGlobal() {
+		args.Region = a.srv.config.AuthoritativeRegion
+	}
+
+	if done, err := a.srv.forward("ACL.Login", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "login"}, time.Now())
+
+	if args.LoginToken == "" {
+		return fmt.Errorf("missing login token")
+	}
+
+	identity, err := a.srv.jwtLogin(method, args.LoginToken)
+	if err != nil {
+		return fmt.Errorf("%v: %v", structs.ErrPermissionDenied, err)
+	}
+	return a.createLoginToken(method, identity, reply)
+}
+
+// loginAuthMethod returns the named auth method, or the default one, which
+// must be of the type
+func (a *ACL) loginAuthMethod(name, methodType string) (*structs.ACLAuthMethod, error) {
+	if !a.srv.config.ACLEnabled {
+		return nil, aclDisabled
+	}
+
+	state := a.srv.fsm.State()
+	var method *structs.ACLAuthMethod
+	var err error
+	if name == "" {
+		method, err = state.DefaultACLAuthMethod(nil)
+	} else {
+		method, err = state.ACLAuthMethodByName(nil, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if method == nil {
+		if name == "" {
+			return nil, fmt.Errorf("no default auth method")
+		}
+		return nil, fmt.Errorf("auth method %q not found", name)
+	}
+	if method.Type != methodType {
+		return nil, fmt.Errorf("auth method %q is not of type %s", method.Name, methodType)
+	}
+	return method, nil
+}
+
+// createLoginToken creates the token granted to the identity by the binding
+// rules of the auth method. The token expires after the max token TTL of the
+// auth method. Roles and policies that don't exist are left out.
+func (a *ACL) createLoginToken(method *structs.ACLAuthMethod, identity *structs.ACLIdentity, reply *structs.ACLLoginResponse) error {
+	state := a.srv.fsm.State()
+	iter, err := state.ACLBindingRulesByAuthMethod(nil, method.Name)
+	if err != nil {
+		return err
+	}
+	var rules []*structs.ACLBindingRule
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		rules = append(rules, raw.(*structs.ACLBindingRule))
+	}
+
+	token, err := identity.Bind(rules)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return fmt.Errorf("%v: no binding rule of auth method %q matched", structs.ErrPermissionDenied, method.Name)
+	}
+
+	if token.Type == structs.ACLClientToken {
+		var roles, policies []string
+		for _, name := range token.Roles {
+			if role, err := state.ACLRoleByName(nil, name); err != nil {
+				return err
+			} else if role != nil {
+				roles = append(roles, name)
+			}
+		}
+		for _, name := range token.Policies {
+			if policy, err := state.ACLPolicyByName(nil, name); err != nil {
+				return err
+			} else if policy != nil {
+				policies = append(policies, name)
+			}
+		}
+		if len(roles) == 0 && len(policies) == 0 {
+			return fmt.Errorf("%v: the binding rules of auth method %q granted no existing role or policy",
+				structs.ErrPermissionDenied, method.Name)
+		}
+		token.Roles = roles
+		token.Policies = policies
+	}
+
+	now := time.Now().UTC()
+	expiration := now.Add(method.MaxTokenTTL)
+	token.AccessorID = structs.GenerateUUID()
+	token.SecretID = structs.GenerateUUID()
+	token.Name = fmt.Sprintf("%s-%s", method.Type, method.Name)
+	token.Global = method.TokenGlobal()
+	token.CreateTime = now
+	token.ExpirationTime = &expiration
+	token.SetHash()
+
+	// Update via Raft
+	req := structs.ACLTokenUpsertRequest{Tokens: []*structs.ACLToken{token}}
+	_, index, err := a.srv.raftApply(structs.ACLTokenUpsertRequestType, &req)
+	if err != nil {
+		return err
+	}
+
+	out, err := a.srv.fsm.State().ACLTokenByAccessorID(nil, token.AccessorID)
+	if err != nil {
+		return err
+	}
+	reply.Token = out
+	reply.Index = index
+	return nil
+}
+
+// UpsertTokens is used to create or update a set of tokens. Global tokens are
+// created in the authoritative region.
+func (a *ACL) UpsertTokens(args *structs.ACLTokenUpsertRequest, reply *structs.ACLTokenUpsertResponse) error {
+	// Validate the arguments
+	if len(args.Tokens) == 0 {
+		return fmt.Errorf("must specify at least one token")
+	}
+	for _, token := range args.Tokens {
+		if token.Global {
+			args.Region = a.srv.config.AuthoritativeRegion
+		}
+	}
+
+	if done, err := a.srv.forward("ACL.UpsertTokens", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "upsert_tokens"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage tokens
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	snap, err := a.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, token := range args.Tokens {
+		if token.AccessorID == "" {
+			// Setup the new token
+			token.AccessorID = structs.GenerateUUID()
+			token.SecretID = structs.GenerateUUID()
+			token.CreateTime = now
+			if token.ExpirationTTL != 0 {
+				expiration := now.Add(token.ExpirationTTL)
+				token.ExpirationTime = &expiration
+			}
+		} else {
+			// Only the name, type, policies, roles and allowed CIDRs of a
+			// token can be updated
+			existing, err := snap.ACLTokenByAccessorID(nil, token.AccessorID)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				return fmt.Errorf("token %q not found", token.AccessorID)
+			}
+			if token.Global != existing.Global {
+				return fmt.Errorf("cannot change the global flag of token %q", token.AccessorID)
+			}
+			// Tokens read back from the API keep their TTL and expiration
+			// time, which is fine as long as they are unchanged
+			if (token.ExpirationTTL != 0 && token.ExpirationTTL != existing.ExpirationTTL) ||
+				(token.ExpirationTime != nil &&
+					(existing.ExpirationTime == nil || !token.ExpirationTime.Equal(*existing.ExpirationTime))) {
+				return fmt.Errorf("cannot change the expiration of token %q", token.AccessorID)
+			}
+			token.SecretID = existing.SecretID
+			token.CreateTime = existing.CreateTime
+			token.ExpirationTTL = existing.ExpirationTTL
+			token.ExpirationTime = existing.ExpirationTime
+		}
+
+		if err := token.Validate(a.srv.config.ACLTokenMinExpirationTTL, a.srv.config.ACLTokenMaxExpirationTTL); err != nil {
+			return fmt.Errorf("Invalid token: %v", err)
+		}
+
+		// The policies of the token must exist
+		for _, name := range token.Policies {
+			policy, err := snap.ACLPolicyByName(nil, name)
+			if err != nil {
+				return err
+			}
+			if policy == nil {
+				return fmt.Errorf("policy %q not found", name)
+			}
+		}
+
+		// And so must its roles
+		for _, name := range token.Roles {
+			role, err := snap.ACLRoleByName(nil, name)
+			if err != nil {
+				return err
+			}
+			if role == nil {
+				return fmt.Errorf("role %q not found", name)
+			}
+		}
+		token.SetHash()
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLTokenUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Return the created or updated tokens
+	state := a.srv.fsm.State()
+	for _, token := range args.Tokens {
+		out, err := state.ACLTokenByAccessorID(nil, token.AccessorID)
+		if err != nil {
+			return err
+		}
+		if out != nil {
+			reply.Tokens = append(reply.Tokens, out)
+		}
+	}
+	reply.Index = index
+	return nil
+}
+
+// DeleteTokens is used to delete a set of tokens. Global tokens are deleted
+// in the authoritative region.
+func (a *ACL) DeleteTokens(args *structs.ACLTokenDeleteRequest, reply *structs.GenericResponse) error {
+	// Validate the arguments
+	if len(args.AccessorIDs) == 0 {
+		return fmt.Errorf("must specify at least one token to delete")
+	}
+
+	// Deletions of global tokens are forwarded to the authoritative region,
+	// which replicates them
+	if args.Region == a.srv.config.Region && a.srv.config.Region != a.srv.config.AuthoritativeRegion {
+		state := a.srv.fsm.State()
+		for _, id := range args.AccessorIDs {
+			token, err := state.ACLTokenByAccessorID(nil, id)
+			if err != nil {
+				return err
+			}
+			if token != nil && token.Global {
+				args.Region = a.srv.config.AuthoritativeRegion
+				break
+			}
+		}
+	}
+
+	if done, err := a.srv.forward("ACL.DeleteTokens", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "delete_tokens"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can manage tokens
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Update via Raft
+	_, index, err := a.srv.raftApply(structs.ACLTokenDeleteRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// ListTokens is used to list the tokens
+func (a *ACL) ListTokens(args *structs.ACLTokenListRequest, reply *structs.ACLTokenListResponse) error {
+	if done, err := a.srv.forward("ACL.ListTokens", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "list_tokens"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Only management tokens can list tokens
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Capture all the tokens
+			iter, err := state.PrefixFrom(ws, "acl_token", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
+			if err != nil {
+				return err
+			}
+
+			if args.GlobalOnly {
+				iter = newFilterIterator(iter, func(raw interface{}) bool {
+					return raw.(*structs.ACLToken).Global
+				})
+			}
+
+			var tokens []*structs.ACLTokenListStub
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.ACLToken).AccessorID
+			}, func(raw interface{}) (interface{}, error) {
+				return raw.(*structs.ACLToken).Stub(), nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				tokens = append(tokens, obj.(*structs.ACLTokenListStub))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			reply.Tokens = tokens
+			reply.NextToken = nextToken
+
+			// Use the last index that affected the token table
+			index, err := state.Index("acl_token")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetToken is used to get a specific token. Tokens other than management
+// tokens can only get themselves.
+func (a *ACL) GetToken(args *structs.ACLTokenSpecificRequest, reply *structs.SingleACLTokenResponse) error {
+	if done, err := a.srv.forward("ACL.GetToken", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_token"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	token, err := a.requestToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	if token.Type != structs.ACLManagementToken && token.AccessorID != args.AccessorID {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the token
+			out, err := state.ACLTokenByAccessorID(ws, args.AccessorID)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Token = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the token table
+				index, err := state.Index("acl_token")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// GetTokens is used to get a set of tokens. It is used by the servers to
+// replicate the global tokens.
+func (a *ACL) GetTokens(args *structs.ACLTokenSetRequest, reply *structs.ACLTokenSetResponse) error {
+	if done, err := a.srv.forward("ACL.GetTokens", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "get_tokens"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Look for the tokens
+			reply.Tokens = make(map[string]*structs.ACLToken, len(args.AccessorIDs))
+			for _, id := range args.AccessorIDs {
+				out, err := state.ACLTokenByAccessorID(ws, id)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					reply.Tokens[id] = out
+				}
+			}
+
+			// Use the last index that affected the token table
+			index, err := state.Index("acl_token")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// ResolveToken is used to resolve a secret ID to its token and the token's
+// policies, including the policies of its roles. Clients use it to enforce
+// the ACLs of the requests they serve, and it returns the token of the caller
+// for the "acl token self" command.
+func (a *ACL) ResolveToken(args *structs.ResolveACLTokenRequest, reply *structs.ResolveACLTokenResponse) error {
+	if done, err := a.srv.forward("ACL.ResolveToken", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "acl", "resolve_token"}, time.Now())
+
+	if !a.srv.config.ACLEnabled {
+		return aclDisabled
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			token := structs.AnonymousACLToken
+			if args.SecretID != "" {
+				out, err := state.ACLTokenBySecretID(ws, args.SecretID)
+				if err != nil {
+					return err
+				}
+				if out == nil {
+					return structs.ErrTokenNotFound
+				}
+				if out.IsExpired(time.Now()) {
+					return structs.ErrTokenExpired
+				}
+				token = out
+			}
+
+			policies, err := tokenPolicies(ws, state, token)
+			if err != nil {
+				return err
+			}
+			reply.Token = token
+			reply.Policies = policies
+
+			// Use the last index that affected the token, policy or role
+			// tables
+			var index uint64
+			for _, table := range []string{"acl_token", "acl_policy", "acl_role"} {
+				tableIndex, err := state.Index(table)
+				if err != nil {
+					return err
+				}
+				if tableIndex > index {
+					index = tableIndex
+				}
+			}
+			reply.Index = index
+
+			// Set the query response
+			a.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return a.srv.blockingRPC(&opts)
+}
+
+// requestToken returns the token of the secret ID of a request, which is the
+// anonymous token for requests without one.
+func (a *ACL) requestToken(secretID string) (*structs.ACLToken, error) {
+	if secretID == "" {
+		return structs.AnonymousACLToken, nil
+	}
+	if leaderAcl := a.srv.getLeaderAcl(); leaderAcl != "" && secretID == leaderAcl {
+		return &structs.ACLToken{Type: structs.ACLManagementToken}, nil
+	}
+
+	token, err := a.srv.fsm.State().ACLTokenBySecretID(nil, secretID)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, structs.ErrTokenNotFound
+	}
+	if token.IsExpired(time.Now()) {
+		return nil, structs.ErrTokenExpired
+	}
+	return token, nil
+}
@@ -0,0 +1,1243 @@
+package nomad
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/rpc"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	msgpackrpc "github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// testACLServer returns a server with ACLs enabled and a management token
+func testACLServer(t *testing.T, cb func(*Config)) (*Server, *structs.ACLToken) {
+	s := testServer(t, func(c *Config) {
+		c.ACLEnabled = true
+		if cb != nil {
+			cb(c)
+		}
+	})
+	token := mock.ACLManagementToken()
+	if err := s.fsm.State().BootstrapACLTokens(1, token); err != nil {
+		t.Fatalf("failed to bootstrap: %v", err)
+	}
+	return s, token
+}
+
+func TestACLEndpoint_Bootstrap(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.ACLEnabled = true
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	req := &structs.ACLTokenBootstrapRequest{
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.ACLTokenUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.Bootstrap", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Tokens) != 1 {
+		t.Fatalf("bad: %#v", resp)
+	}
+	token := resp.Tokens[0]
+	if token.Type != structs.ACLManagementToken || !token.Global || token.SecretID == "" {
+		t.Fatalf("bad: %#v", token)
+	}
+
+	// Bootstrapping can only be done once
+	var resp2 structs.ACLTokenUpsertResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.Bootstrap", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), "already done") {
+		t.Fatalf("expected bootstrap error, got %v", err)
+	}
+}
+
+func TestACLEndpoint_UpsertPolicies(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	policy := mock.ACLPolicy()
+	req := &structs.ACLPolicyUpsertRequest{
+		Policies:     []*structs.ACLPolicy{policy},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Fails without a management token
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertPolicies", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertPolicies", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	out, err := s1.fsm.State().ACLPolicyByName(nil, policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.Rules != policy.Rules || out.Hash == nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Invalid rules are rejected
+	bad := mock.ACLPolicy()
+	bad.Rules = `namespace "default" { policy = "nope" }`
+	req.Policies = []*structs.ACLPolicy{bad}
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertPolicies", req, &resp); err == nil {
+		t.Fatalf("expected invalid policy error")
+	}
+}
+
+func TestACLEndpoint_DeletePolicies(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	policy := mock.ACLPolicy()
+	if err := s1.fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ACLPolicyDeleteRequest{
+		Names: []string{policy.Name},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.DeletePolicies", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := s1.fsm.State().ACLPolicyByName(nil, policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestACLEndpoint_ListPolicies(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	p1 := mock.ACLPolicy()
+	p2 := mock.ACLPolicy()
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{p1, p2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := mock.ACLToken()
+	token.Policies = []string{p1.Name}
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Management tokens list all the policies
+	req := &structs.ACLPolicyListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListPolicies", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Policies) != 2 || resp.Index != 1000 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Client tokens only list their own
+	req.AuthToken = token.SecretID
+	var resp2 structs.ACLPolicyListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListPolicies", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Policies) != 1 || resp2.Policies[0].Name != p1.Name {
+		t.Fatalf("bad: %#v", resp2.Policies)
+	}
+}
+
+func TestACLEndpoint_GetPolicy(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	policy := mock.ACLPolicy()
+	if err := s1.fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ACLPolicySpecificRequest{
+		Name: policy.Name,
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.SingleACLPolicyResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicy", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Policy == nil || resp.Policy.Name != policy.Name || resp.Index != 1000 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The anonymous token can't read other policies
+	req.AuthToken = ""
+	var resp2 structs.SingleACLPolicyResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicy", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestACLEndpoint_UpsertRoles(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	policy := mock.ACLPolicy()
+	if err := s1.fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	role := mock.ACLRole()
+	role.Policies = []string{policy.Name}
+	req := &structs.ACLRoleUpsertRequest{
+		Roles:        []*structs.ACLRole{role},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Fails without a management token
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertRoles", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertRoles", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	out, err := s1.fsm.State().ACLRoleByName(nil, role.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || len(out.Policies) != 1 || out.Hash == nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// The policies of a role must exist
+	bad := mock.ACLRole()
+	req.Roles = []*structs.ACLRole{bad}
+	err = msgpackrpc.CallWithCodec(codec, "ACL.UpsertRoles", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing policy error, got %v", err)
+	}
+}
+
+func TestACLEndpoint_DeleteRoles(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	role := mock.ACLRole()
+	if err := s1.fsm.State().UpsertACLRoles(1000, []*structs.ACLRole{role}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ACLRoleDeleteRequest{
+		Names: []string{role.Name},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.DeleteRoles", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := s1.fsm.State().ACLRoleByName(nil, role.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestACLEndpoint_ListRoles(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	r1 := mock.ACLRole()
+	r2 := mock.ACLRole()
+	if err := state.UpsertACLRoles(1000, []*structs.ACLRole{r1, r2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := mock.ACLToken()
+	token.Roles = []string{r1.Name}
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Management tokens list all the roles
+	req := &structs.ACLRoleListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLRoleListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListRoles", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Roles) != 2 || resp.Index != 1000 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Client tokens only list their own
+	req.AuthToken = token.SecretID
+	var resp2 structs.ACLRoleListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListRoles", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Roles) != 1 || resp2.Roles[0].Name != r1.Name {
+		t.Fatalf("bad: %#v", resp2.Roles)
+	}
+}
+
+func TestACLEndpoint_GetRole(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	role := mock.ACLRole()
+	if err := s1.fsm.State().UpsertACLRoles(1000, []*structs.ACLRole{role}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ACLRoleSpecificRequest{
+		Name: role.Name,
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.SingleACLRoleResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetRole", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Role == nil || resp.Role.Name != role.Name || resp.Index != 1000 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The anonymous token can't read other roles
+	req.AuthToken = ""
+	var resp2 structs.SingleACLRoleResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.GetRole", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestACLEndpoint_UpsertAuthMethods(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	method := mock.ACLAuthMethod()
+	method.Default = true
+	req := &structs.ACLAuthMethodUpsertRequest{
+		AuthMethods:  []*structs.ACLAuthMethod{method},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Fails without a management token
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertAuthMethods", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertAuthMethods", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 {
+		t.Fatalf("bad index: %d", resp.Index)
+	}
+
+	out, err := s1.fsm.State().ACLAuthMethodByName(nil, method.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || !out.Default || out.Hash == nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Only one auth method can be the default
+	m1, m2 := mock.ACLAuthMethod(), mock.ACLAuthMethod()
+	m1.Default, m2.Default = true, true
+	req.AuthMethods = []*structs.ACLAuthMethod{m1, m2}
+	err = msgpackrpc.CallWithCodec(codec, "ACL.UpsertAuthMethods", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "only one auth method") {
+		t.Fatalf("expected default error, got %v", err)
+	}
+
+	// The max token TTL is bounded by the token expiration TTLs
+	bad := mock.ACLAuthMethod()
+	bad.MaxTokenTTL = 365 * 24 * time.Hour
+	req.AuthMethods = []*structs.ACLAuthMethod{bad}
+	err = msgpackrpc.CallWithCodec(codec, "ACL.UpsertAuthMethods", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "max token TTL") {
+		t.Fatalf("expected TTL error, got %v", err)
+	}
+}
+
+func TestACLEndpoint_DeleteAuthMethods(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	method := mock.ACLAuthMethod()
+	if err := s1.fsm.State().UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{method}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ACLAuthMethodDeleteRequest{
+		Names: []string{method.Name},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.DeleteAuthMethods", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := s1.fsm.State().ACLAuthMethodByName(nil, method.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestACLEndpoint_ListAuthMethods(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	m1 := mock.ACLAuthMethod()
+	m2 := mock.ACLAuthMethod()
+	if err := s1.fsm.State().UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{m1, m2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Listing the auth methods doesn't need a token, so users can find the
+	// ones to log in with
+	req := &structs.ACLAuthMethodListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ACLAuthMethodListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListAuthMethods", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.AuthMethods) != 2 || resp.Index != 1000 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Reading the config of an auth method needs a management token
+	getReq := &structs.ACLAuthMethodSpecificRequest{
+		Name:         m1.Name,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var getResp structs.SingleACLAuthMethodResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.GetAuthMethod", getReq, &getResp)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	getReq.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetAuthMethod", getReq, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.AuthMethod == nil || getResp.AuthMethod.Config.OIDCClientSecret != m1.Config.OIDCClientSecret {
+		t.Fatalf("bad: %#v", getResp.AuthMethod)
+	}
+}
+
+func TestACLEndpoint_UpsertBindingRules(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	method := mock.ACLAuthMethod()
+	if err := s1.fsm.State().UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{method}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	rule := mock.ACLBindingRule()
+	rule.ID = ""
+	rule.AuthMethod = method.Name
+	req := &structs.ACLBindingRuleUpsertRequest{
+		BindingRules: []*structs.ACLBindingRule{rule},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+
+	// New binding rules are given an ID
+	var resp structs.ACLBindingRuleUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertBindingRules", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.BindingRules) != 1 || resp.BindingRules[0].ID == "" || resp.Index == 0 {
+		t.Fatalf("bad: %#v", resp)
+	}
+	created := resp.BindingRules[0]
+
+	// And are updated by their ID
+	update := *created
+	update.BindType = structs.ACLBindingRuleBindTypeRole
+	req.BindingRules = []*structs.ACLBindingRule{&update}
+	var resp2 structs.ACLBindingRuleUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertBindingRules", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := s1.fsm.State().ACLBindingRuleByID(nil, created.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.BindType != structs.ACLBindingRuleBindTypeRole || out.CreateIndex != created.CreateIndex {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// The auth method must exist
+	bad := mock.ACLBindingRule()
+	bad.ID = ""
+	req.BindingRules = []*structs.ACLBindingRule{bad}
+	err = msgpackrpc.CallWithCodec(codec, "ACL.UpsertBindingRules", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing auth method error, got %v", err)
+	}
+
+	// The selector must parse
+	bad = mock.ACLBindingRule()
+	bad.ID = ""
+	bad.AuthMethod = method.Name
+	bad.Selector = "list.groups contains"
+	req.BindingRules = []*structs.ACLBindingRule{bad}
+	err = msgpackrpc.CallWithCodec(codec, "ACL.UpsertBindingRules", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "selector") {
+		t.Fatalf("expected selector error, got %v", err)
+	}
+}
+
+func TestACLEndpoint_DeleteBindingRules(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	rule := mock.ACLBindingRule()
+	if err := s1.fsm.State().UpsertACLBindingRules(1000, []*structs.ACLBindingRule{rule}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ACLBindingRuleDeleteRequest{
+		IDs: []string{rule.ID},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.DeleteBindingRules", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := s1.fsm.State().ACLBindingRuleByID(nil, rule.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+// testOIDCAuthorize starts logging in with the OIDC auth method and follows
+// the auth URL to the provider, returning the state and authorization code it
+// redirects back with
+func testOIDCAuthorize(t *testing.T, codec rpc.ClientCodec, method, redirectURI, clientNonce string) (string, string) {
+	req := &structs.ACLOIDCAuthURLRequest{
+		AuthMethodName: method,
+		RedirectURI:    redirectURI,
+		ClientNonce:    clientNonce,
+		WriteRequest:   structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.ACLOIDCAuthURLResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.OIDCAuthURL", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	httpResp, err := client.Get(resp.AuthURL)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusFound {
+		t.Fatalf("bad status: %d", httpResp.StatusCode)
+	}
+	redirect, err := url.Parse(httpResp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return redirect.Query().Get("state"), redirect.Query().Get("code")
+}
+
+func TestACLEndpoint_OIDCLogin(t *testing.T) {
+	t.Parallel()
+	s1, _ := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	provider := testutil.NewTestOIDCProvider(t)
+	defer provider.Stop()
+	provider.SetClaims(map[string]interface{}{
+		"team":   "web",
+		"groups": []string{"engineering", "oncall"},
+	})
+
+	redirectURI := "http://localhost:4649/oidc/callback"
+	method := mock.ACLAuthMethod()
+	method.Default = true
+	method.Config.OIDCDiscoveryURL = provider.URL()
+	method.Config.OIDCClientID = provider.ClientID
+	method.Config.OIDCClientSecret = provider.ClientSecret
+	method.Config.AllowedRedirectURIs = []string{redirectURI}
+	if err := state.UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{method}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	policy := mock.ACLPolicy()
+	policy.Name = "web"
+	role := mock.ACLRole()
+	role.Policies = []string{policy.Name}
+	if err := state.UpsertACLPolicies(1001, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLRoles(1002, []*structs.ACLRole{role}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Engineers are granted the policy of their team, and the on call
+	// engineers a role
+	byTeam := mock.ACLBindingRule()
+	byTeam.AuthMethod = method.Name
+	oncall := mock.ACLBindingRule()
+	oncall.AuthMethod = method.Name
+	oncall.Selector = `"oncall" in list.groups`
+	oncall.BindType = structs.ACLBindingRuleBindTypeRole
+	oncall.BindName = role.Name
+	if err := state.UpsertACLBindingRules(1003, []*structs.ACLBindingRule{byTeam, oncall}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The redirect URI must be allowed by the auth method
+	urlReq := &structs.ACLOIDCAuthURLRequest{
+		RedirectURI:  "http://attacker.example.com/callback",
+		ClientNonce:  "nonce",
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var urlResp structs.ACLOIDCAuthURLResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.OIDCAuthURL", urlReq, &urlResp)
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected redirect URI error, got %v", err)
+	}
+
+	// Log in with the default auth method
+	stateParam, code := testOIDCAuthorize(t, codec, "", redirectURI, "client-nonce")
+	req := &structs.ACLOIDCCompleteAuthRequest{
+		ClientNonce:  "client-nonce",
+		State:        stateParam,
+		Code:         code,
+		RedirectURI:  redirectURI,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.ACLLoginResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.OIDCCompleteAuth", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := resp.Token
+	if token == nil || token.Type != structs.ACLClientToken || token.Global {
+		t.Fatalf("bad: %#v", token)
+	}
+	if len(token.Policies) != 1 || token.Policies[0] != policy.Name {
+		t.Fatalf("bad policies: %v", token.Policies)
+	}
+	if len(token.Roles) != 1 || token.Roles[0] != role.Name {
+		t.Fatalf("bad roles: %v", token.Roles)
+	}
+	if token.ExpirationTime == nil || token.ExpirationTime.After(time.Now().Add(method.MaxTokenTTL)) {
+		t.Fatalf("bad expiration: %v", token.ExpirationTime)
+	}
+	out, err := state.ACLTokenBySecretID(nil, token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("token not created")
+	}
+
+	// Each login completes once
+	var resp2 structs.ACLLoginResponse
+	err = msgpackrpc.CallWithCodec(codec, "ACL.OIDCCompleteAuth", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// The client nonce must match the one the login started with
+	stateParam, code = testOIDCAuthorize(t, codec, method.Name, redirectURI, "client-nonce")
+	req.AuthMethodName = method.Name
+	req.State, req.Code, req.ClientNonce = stateParam, code, "other-nonce"
+	var resp3 structs.ACLLoginResponse
+	err = msgpackrpc.CallWithCodec(codec, "ACL.OIDCCompleteAuth", req, &resp3)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// Users no binding rule matches are denied
+	provider.SetClaims(map[string]interface{}{"groups": []string{"sales"}})
+	stateParam, code = testOIDCAuthorize(t, codec, method.Name, redirectURI, "client-nonce")
+	req.State, req.Code, req.ClientNonce = stateParam, code, "client-nonce"
+	var resp4 structs.ACLLoginResponse
+	err = msgpackrpc.CallWithCodec(codec, "ACL.OIDCCompleteAuth", req, &resp4)
+	if err == nil || !strings.Contains(err.Error(), "no binding rule") {
+		t.Fatalf("expected no binding rule error, got %v", err)
+	}
+}
+
+func TestACLEndpoint_Login(t *testing.T) {
+	t.Parallel()
+	s1, _ := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	provider := testutil.NewTestOIDCProvider(t)
+	defer provider.Stop()
+
+	// One auth method fetches the keys of the CI system, the other one has
+	// its public key
+	jwks := mock.ACLAuthMethod()
+	jwks.Name = "ci"
+	jwks.Type = structs.ACLAuthMethodTypeJWT
+	jwks.Config = &structs.ACLAuthMethodConfig{
+		JWKSURL:        provider.KeysURL(),
+		BoundIssuer:    "https://ci.example.com",
+		BoundAudiences: []string{"nomad"},
+		BoundClaims:    map[string][]string{"ref": {"refs/heads/main"}},
+		ClaimMappings:  map[string]string{"/repository/name": "repo"},
+	}
+	static := jwks.Copy()
+	static.Name = "ci-static"
+	static.Config.JWKSURL = ""
+	static.Config.JWTValidationPubKeys = []string{provider.PublicKeyPEM()}
+	oidc := mock.ACLAuthMethod()
+	if err := state.UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{jwks, static, oidc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	policy := mock.ACLPolicy()
+	policy.Name = "deploy-web"
+	if err := state.UpsertACLPolicies(1001, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var rules []*structs.ACLBindingRule
+	for _, method := range []string{jwks.Name, static.Name} {
+		rule := mock.ACLBindingRule()
+		rule.AuthMethod = method
+		rule.Selector = ""
+		rule.BindName = "deploy-${value.repo}"
+		rules = append(rules, rule)
+	}
+	if err := state.UpsertACLBindingRules(1002, rules); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":        "https://ci.example.com",
+			"aud":        []string{"nomad", "other"},
+			"sub":        "pipeline-1",
+			"ref":        "refs/heads/main",
+			"repository": map[string]interface{}{"name": "web"},
+			"exp":        time.Now().Add(5 * time.Minute).Unix(),
+		}
+	}
+	login := func(method string, claims map[string]interface{}) (*structs.ACLToken, error) {
+		jwt, err := provider.SignJWT(claims)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		req := &structs.ACLLoginRequest{
+			AuthMethodName: method,
+			LoginToken:     jwt,
+			WriteRequest:   structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.ACLLoginResponse
+		err = msgpackrpc.CallWithCodec(codec, "ACL.Login", req, &resp)
+		return resp.Token, err
+	}
+
+	// Both auth methods exchange the JWT for a token with the policy of the
+	// repository
+	for _, method := range []string{jwks.Name, static.Name} {
+		token, err := login(method, claims())
+		if err != nil {
+			t.Fatalf("%s: err: %v", method, err)
+		}
+		if token.Name != "JWT-"+method || len(token.Policies) != 1 || token.Policies[0] != policy.Name {
+			t.Fatalf("%s: bad token: %#v", method, token)
+		}
+		if token.ExpirationTime == nil {
+			t.Fatalf("%s: token doesn't expire", method)
+		}
+	}
+
+	// JWTs must expire, and have the bound issuer, audience and claims
+	noExp := claims()
+	delete(noExp, "exp")
+	expired := claims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	badIssuer := claims()
+	badIssuer["iss"] = "https://attacker.example.com"
+	badAudience := claims()
+	badAudience["aud"] = "other"
+	badRef := claims()
+	badRef["ref"] = "refs/heads/feature"
+	for name, c := range map[string]map[string]interface{}{
+		"no exp":       noExp,
+		"expired":      expired,
+		"bad issuer":   badIssuer,
+		"bad audience": badAudience,
+		"bad ref":      badRef,
+	} {
+		_, err := login(jwks.Name, c)
+		if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+			t.Fatalf("%s: expected permission denied, got %v", name, err)
+		}
+	}
+
+	// Tampered JWTs are denied
+	jwt, err := provider.SignJWT(claims())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	parts := strings.Split(jwt, ".")
+	forged := claims()
+	forged["ref"] = "refs/heads/feature"
+	payload, _ := json.Marshal(forged)
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	req := &structs.ACLLoginRequest{
+		AuthMethodName: jwks.Name,
+		LoginToken:     strings.Join(parts, "."),
+		WriteRequest:   structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.ACLLoginResponse
+	err = msgpackrpc.CallWithCodec(codec, "ACL.Login", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "invalid JWT signature") {
+		t.Fatalf("expected signature error, got %v", err)
+	}
+
+	// OIDC auth methods can't be logged in with a JWT
+	if _, err := login(oidc.Name, claims()); err == nil || !strings.Contains(err.Error(), "not of type JWT") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+}
+
+func TestACLEndpoint_UpsertTokens(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	policy := mock.ACLPolicy()
+	if err := s1.fsm.State().UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an expiring token
+	req := &structs.ACLTokenUpsertRequest{
+		Tokens: []*structs.ACLToken{{
+			Name:          "expiring",
+			Type:          structs.ACLClientToken,
+			Policies:      []string{policy.Name},
+			ExpirationTTL: time.Hour,
+		}},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLTokenUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Tokens) != 1 {
+		t.Fatalf("bad: %#v", resp)
+	}
+	token := resp.Tokens[0]
+	if token.AccessorID == "" || token.SecretID == "" || token.ExpirationTime == nil {
+		t.Fatalf("bad: %#v", token)
+	}
+	if ttl := token.ExpirationTime.Sub(token.CreateTime); ttl != time.Hour {
+		t.Fatalf("bad ttl: %v", ttl)
+	}
+
+	// Update the name but keep the expiration
+	update := token.Copy()
+	update.Name = "renamed"
+	req.Tokens = []*structs.ACLToken{update}
+	var resp2 structs.ACLTokenUpsertResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out := resp2.Tokens[0]; out.Name != "renamed" || out.SecretID != token.SecretID {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// The expiration of a token can't be changed
+	update = token.Copy()
+	update.ExpirationTTL = 2 * time.Hour
+	req.Tokens = []*structs.ACLToken{update}
+	err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), "cannot change the expiration") {
+		t.Fatalf("expected expiration error, got %v", err)
+	}
+
+	// TTLs outside of the configured bounds are rejected
+	for _, ttl := range []time.Duration{time.Second, 48 * time.Hour} {
+		req.Tokens = []*structs.ACLToken{{
+			Type:          structs.ACLManagementToken,
+			ExpirationTTL: ttl,
+		}}
+		if err := msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp2); err == nil {
+			t.Fatalf("expected invalid TTL error for %v", ttl)
+		}
+	}
+
+	// Policies must exist
+	req.Tokens = []*structs.ACLToken{{
+		Type:     structs.ACLClientToken,
+		Policies: []string{"unknown"},
+	}}
+	err = msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing policy error, got %v", err)
+	}
+
+	// And so must roles
+	req.Tokens = []*structs.ACLToken{{
+		Type:  structs.ACLClientToken,
+		Roles: []string{"unknown"},
+	}}
+	err = msgpackrpc.CallWithCodec(codec, "ACL.UpsertTokens", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), "role \"unknown\" not found") {
+		t.Fatalf("expected missing role error, got %v", err)
+	}
+}
+
+func TestACLEndpoint_DeleteTokens(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	token := mock.ACLToken()
+	if err := s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Client tokens can't delete tokens, not even themselves
+	req := &structs.ACLTokenDeleteRequest{
+		AccessorIDs: []string{token.AccessorID},
+		WriteRequest: structs.WriteRequest{
+			Region:    "global",
+			AuthToken: token.SecretID,
+		},
+	}
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.DeleteTokens", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	req.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.DeleteTokens", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := s1.fsm.State().ACLTokenByAccessorID(nil, token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestACLEndpoint_ListTokens(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	local := mock.ACLToken()
+	global := mock.ACLToken()
+	global.Global = true
+	if err := s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{local, global}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ACLTokenListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: root.SecretID,
+		},
+	}
+	var resp structs.ACLTokenListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListTokens", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Tokens) != 3 || resp.Index != 1000 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Only list the global tokens
+	req.GlobalOnly = true
+	var resp2 structs.ACLTokenListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ListTokens", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Tokens) != 1 || resp2.Tokens[0].AccessorID != global.AccessorID {
+		t.Fatalf("bad: %#v", resp2.Tokens)
+	}
+}
+
+func TestACLEndpoint_GetToken(t *testing.T) {
+	t.Parallel()
+	s1, _ := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	token := mock.ACLToken()
+	other := mock.ACLToken()
+	if err := s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token, other}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Client tokens can get themselves
+	req := &structs.ACLTokenSpecificRequest{
+		AccessorID: token.AccessorID,
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: token.SecretID,
+		},
+	}
+	var resp structs.SingleACLTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetToken", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Token == nil || resp.Token.AccessorID != token.AccessorID {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// But not the other tokens
+	req.AccessorID = other.AccessorID
+	var resp2 structs.SingleACLTokenResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.GetToken", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestACLEndpoint_ResolveToken(t *testing.T) {
+	t.Parallel()
+	s1, _ := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	policy := mock.ACLPolicy()
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	expired := mock.ACLToken()
+	expiration := time.Now().Add(-time.Minute)
+	expired.ExpirationTime = &expiration
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{token, expired}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.ResolveACLTokenRequest{
+		SecretID:     token.SecretID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ResolveACLTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ResolveToken", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Token == nil || resp.Token.AccessorID != token.AccessorID {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if len(resp.Policies) != 1 || resp.Policies[0].Name != policy.Name || resp.Index != 1001 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Expired tokens are rejected
+	req.SecretID = expired.SecretID
+	var resp2 structs.ResolveACLTokenResponse
+	err := msgpackrpc.CallWithCodec(codec, "ACL.ResolveToken", req, &resp2)
+	if err == nil || !strings.Contains(err.Error(), structs.ErrTokenExpired.Error()) {
+		t.Fatalf("expected expired token error, got %v", err)
+	}
+}
+
+func TestACLEndpoint_ResolveToken_Roles(t *testing.T) {
+	t.Parallel()
+	s1, _ := testACLServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	p1 := mock.ACLPolicy()
+	p2 := mock.ACLPolicy()
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{p1, p2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	role := mock.ACLRole()
+	role.Policies = []string{p1.Name, p2.Name}
+	if err := state.UpsertACLRoles(1001, []*structs.ACLRole{role}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := mock.ACLToken()
+	token.Policies = []string{p1.Name}
+	token.Roles = []string{role.Name}
+	if err := state.UpsertACLTokens(1002, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The token is granted the policies of its role once
+	req := &structs.ResolveACLTokenRequest{
+		SecretID:     token.SecretID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ResolveACLTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ResolveToken", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Policies) != 2 || resp.Index != 1002 {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// And can read them
+	getReq := &structs.ACLPolicySpecificRequest{
+		Name: p2.Name,
+		QueryOptions: structs.QueryOptions{
+			Region:    "global",
+			AuthToken: token.SecretID,
+		},
+	}
+	var getResp structs.SingleACLPolicyResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.GetPolicy", getReq, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Deleting the role revokes its policies
+	if err := state.DeleteACLRoles(1003, []string{role.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var resp2 structs.ResolveACLTokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "ACL.ResolveToken", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Policies) != 1 || resp2.Policies[0].Name != p1.Name || resp2.Index != 1003 {
+		t.Fatalf("bad: %#v", resp2)
+	}
+}
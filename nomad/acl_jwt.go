@@ -0,0 +1,666 @@
+package nomad
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// jwtClockSkew is the clock skew tolerated when checking the times of
+	// the JWTs
+	jwtClockSkew = 60 * time.Second
+
+	// jwksCacheTTL is how long the key sets of the identity providers are
+	// cached for
+	jwksCacheTTL = 5 * time.Minute
+
+	// jwksMinRefreshInterval limits how often a key set is fetched again to
+	// find a key it didn't have
+	jwksMinRefreshInterval = 10 * time.Second
+
+	// oidcDiscoveryCacheTTL is how long the discovery documents of the OIDC
+	// providers are cached for
+	oidcDiscoveryCacheTTL = 5 * time.Minute
+
+	// authHTTPTimeout is the timeout of the requests to the identity
+	// providers
+	authHTTPTimeout = 10 * time.Second
+
+	// defaultJWTSigningAlg is the algorithm JWTs are expected to be signed
+	// with unless the auth method says otherwise
+	defaultJWTSigningAlg = "RS256"
+)
+
+// jwtHeader is the JOSE header of a JWT
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// parsedJWT is a JWT whose signature is yet to be verified
+type parsedJWT struct {
+	header    jwtHeader
+	claims    map[string]interface{}
+	signed    []byte
+	signature []byte
+}
+
+// parseJWT parses a JWT in its compact serialization
+func parseJWT(raw string) (*parsedJWT, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWT")
+	}
+
+	var jwt parsedJWT
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT header: %v", err)
+	}
+	if err := json.Unmarshal(header, &jwt.header); err != nil {
+		return nil, fmt.Errorf("malformed JWT header: %v", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT claims: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&jwt.claims); err != nil {
+		return nil, fmt.Errorf("malformed JWT claims: %v", err)
+	}
+
+	jwt.signature, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT signature: %v", err)
+	}
+	jwt.signed = []byte(parts[0] + "." + parts[1])
+	return &jwt, nil
+}
+
+// verifySignature verifies the signature of the JWT with the key set. The
+// JWT must be signed with one of the algorithms.
+func (j *parsedJWT) verifySignature(keys *jwtKeySet, algs []string) error {
+	if len(algs) == 0 {
+		algs = []string{defaultJWTSigningAlg}
+	}
+	if !helper.SliceStringContains(algs, j.header.Algorithm) {
+		return fmt.Errorf("JWT signed with unsupported algorithm %q", j.header.Algorithm)
+	}
+
+	candidates := keys.candidates(j.header.KeyID)
+	if len(candidates) == 0 {
+		return fmt.Errorf("no key %q to verify the JWT with", j.header.KeyID)
+	}
+	for _, key := range candidates {
+		if verifyJWTSignature(j.header.Algorithm, key, j.signed, j.signature) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid JWT signature")
+}
+
+// verifyJWTSignature verifies the signature of the signed bytes with the key
+func verifyJWTSignature(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an RSA key", alg)
+		}
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(rsaKey, hash, digest, sig, nil)
+		}
+		return rsa.VerifyPKCS1v15(rsaKey, hash, digest, sig)
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an ECDSA key", alg)
+		}
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return fmt.Errorf("invalid ECDSA signature length")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid ECDSA signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// jwtExpectations are the claims a JWT is checked against. Empty expectations
+// aren't checked.
+type jwtExpectations struct {
+	Issuer    string
+	Audiences []string
+	Nonce     string
+}
+
+// validateClaims checks the times of the JWT and that its claims meet the
+// expectations
+func (j *parsedJWT) validateClaims(exp jwtExpectations, now time.Time) error {
+	if t, ok := j.timeClaim("exp"); ok && !now.Before(t.Add(jwtClockSkew)) {
+		return fmt.Errorf("JWT expired")
+	}
+	if t, ok := j.timeClaim("nbf"); ok && now.Add(jwtClockSkew).Before(t) {
+		return fmt.Errorf("JWT not valid yet")
+	}
+	if t, ok := j.timeClaim("iat"); ok && now.Add(jwtClockSkew).Before(t) {
+		return fmt.Errorf("JWT issued in the future")
+	}
+
+	if exp.Issuer != "" {
+		if iss, _ := j.claims["iss"].(string); iss != exp.Issuer {
+			return fmt.Errorf("JWT issued by %q, expected %q", iss, exp.Issuer)
+		}
+	}
+
+	if len(exp.Audiences) != 0 {
+		var audiences []string
+		switch aud := j.claims["aud"].(type) {
+		case string:
+			audiences = []string{aud}
+		case []interface{}:
+			for _, a := range aud {
+				if s, ok := a.(string); ok {
+					audiences = append(audiences, s)
+				}
+			}
+		}
+		found := false
+		for _, aud := range audiences {
+			if helper.SliceStringContains(exp.Audiences, aud) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("JWT audience %v not bound", audiences)
+		}
+	}
+
+	if exp.Nonce != "" {
+		if nonce, _ := j.claims["nonce"].(string); nonce != exp.Nonce {
+			return fmt.Errorf("JWT nonce mismatch")
+		}
+	}
+	return nil
+}
+
+// timeClaim returns a NumericDate claim of the JWT
+func (j *parsedJWT) timeClaim(name string) (time.Time, bool) {
+	n, ok := j.claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// mapClaims returns the identity made of the claims mapped by the auth method.
+// Claims are named by their key or, to select nested claims, by a JSON
+// pointer such as /address/country.
+func mapClaims(claims map[string]interface{}, config *structs.ACLAuthMethodConfig) *structs.ACLIdentity {
+	identity := &structs.ACLIdentity{
+		Values: make(map[string]string, len(config.ClaimMappings)),
+		Lists:  make(map[string][]string, len(config.ListClaimMappings)),
+	}
+	for claim, name := range config.ClaimMappings {
+		if value, ok := claimScalar(lookupClaim(claims, claim)); ok {
+			identity.Values[name] = value
+		}
+	}
+	for claim, name := range config.ListClaimMappings {
+		if list, ok := claimList(lookupClaim(claims, claim)); ok {
+			identity.Lists[name] = list
+		}
+	}
+	return identity
+}
+
+// checkBoundClaims returns an error unless each bound claim has one of its
+// values, or contains one of them if it is a list
+func checkBoundClaims(claims map[string]interface{}, bound map[string][]string) error {
+	for claim, values := range bound {
+		list, _ := claimList(lookupClaim(claims, claim))
+		found := false
+		for _, value := range list {
+			if helper.SliceStringContains(values, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("claim %q doesn't match the bound claims", claim)
+		}
+	}
+	return nil
+}
+
+// lookupClaim returns the claim named by its key or by a JSON pointer
+func lookupClaim(claims map[string]interface{}, name string) interface{} {
+	if !strings.HasPrefix(name, "/") {
+		return claims[name]
+	}
+
+	var cur interface{} = claims
+	for _, part := range strings.Split(name[1:], "/") {
+		part = strings.Replace(strings.Replace(part, "~1", "/", -1), "~0", "~", -1)
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// claimList returns the string forms of the scalars of a list claim. A scalar
+// claim is a list of one.
+func claimList(raw interface{}) ([]string, bool) {
+	if items, ok := raw.([]interface{}); ok {
+		list := make([]string, 0, len(items))
+		for _, item := range items {
+			if value, ok := claimScalar(item); ok {
+				list = append(list, value)
+			}
+		}
+		return list, true
+	}
+	if value, ok := claimScalar(raw); ok {
+		return []string{value}, true
+	}
+	return nil, false
+}
+
+// claimScalar returns the string form of a scalar claim
+func claimScalar(raw interface{}) (string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// jwtKeySet is a set of public keys verifying the signatures of JWTs
+type jwtKeySet struct {
+	// byID are the keys with a key ID
+	byID map[string]crypto.PublicKey
+
+	// all are all the keys, including those without a key ID
+	all []crypto.PublicKey
+}
+
+// candidates returns the keys that may have signed a JWT with the key ID.
+// JWTs without a key ID, or verified with keys without IDs, may have been
+// signed by any of the keys.
+func (k *jwtKeySet) candidates(keyID string) []crypto.PublicKey {
+	if keyID == "" || len(k.byID) == 0 {
+		return k.all
+	}
+	if key, ok := k.byID[keyID]; ok {
+		return []crypto.PublicKey{key}
+	}
+	return nil
+}
+
+// jsonWebKey is a key of a JSON Web Key Set
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// parseJWKS parses the RSA and EC signing keys of a JSON Web Key Set. Other
+// keys are ignored.
+func parseJWKS(raw []byte) (*jwtKeySet, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode key set: %v", err)
+	}
+
+	keys := &jwtKeySet{byID: make(map[string]crypto.PublicKey)}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", jwk.KeyID, err)
+		}
+		if key == nil {
+			continue
+		}
+		if jwk.KeyID != "" {
+			keys.byID[jwk.KeyID] = key
+		}
+		keys.all = append(keys.all, key)
+	}
+	return keys, nil
+}
+
+// publicKey returns the public key of the JWK, or nil if its type isn't
+// supported
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// decodeJWKInt decodes a base64url encoded big-endian integer of a JWK
+func decodeJWKInt(s string) (*big.Int, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(buf), nil
+}
+
+// authHTTPClient returns the client used to reach an identity provider,
+// trusting the PEM-encoded CA certificates if any are given
+func authHTTPClient(caPems []string) (*http.Client, error) {
+	client := &http.Client{Timeout: authHTTPTimeout}
+	if len(caPems) == 0 {
+		return client, nil
+	}
+
+	pool := x509.NewCertPool()
+	for _, pem := range caPems {
+		if !pool.AppendCertsFromPEM([]byte(pem)) {
+			return nil, fmt.Errorf("invalid CA certificate")
+		}
+	}
+	client.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+	return client, nil
+}
+
+// getJSON fetches a JSON document from an identity provider
+func getJSON(client *http.Client, url string, out interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response code %d from %s", resp.StatusCode, url)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// jwksCache fetches and caches the key sets and the OIDC discovery documents
+// of the identity providers. The documents are fetched without holding the
+// lock of the cache, and concurrent fetches of a URL share one request.
+type jwksCache struct {
+	sets     map[string]*cachedKeySet
+	docs     map[string]*cachedDiscovery
+	inflight map[string]*cacheFetch
+	l        sync.Mutex
+}
+
+// cachedKeySet is a cached key set and when it was fetched
+type cachedKeySet struct {
+	keys    *jwtKeySet
+	fetched time.Time
+}
+
+// cachedDiscovery is a cached discovery document and when it was fetched
+type cachedDiscovery struct {
+	doc     *oidcDiscovery
+	fetched time.Time
+}
+
+// cacheFetch is a fetch in progress. done is closed once its result is set.
+type cacheFetch struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// newJWKSCache returns an empty key set cache
+func newJWKSCache() *jwksCache {
+	return &jwksCache{
+		sets:     make(map[string]*cachedKeySet),
+		docs:     make(map[string]*cachedDiscovery),
+		inflight: make(map[string]*cacheFetch),
+	}
+}
+
+// get returns the key set at the URL. The key set is fetched again if it is
+// stale or, unless it was just fetched, if it is missing the key ID.
+func (c *jwksCache) get(client *http.Client, url, keyID string) (*jwtKeySet, error) {
+	c.l.Lock()
+	cached, ok := c.sets[url]
+	c.l.Unlock()
+	if ok {
+		age := time.Since(cached.fetched)
+		if age < jwksCacheTTL && (len(cached.keys.candidates(keyID)) != 0 || age < jwksMinRefreshInterval) {
+			return cached.keys, nil
+		}
+	}
+
+	keys, err := c.fetch("jwks "+url, func() (interface{}, error) {
+		var raw json.RawMessage
+		if err := getJSON(client, url, &raw); err != nil {
+			return nil, fmt.Errorf("failed to fetch key set: %v", err)
+		}
+		keys, err := parseJWKS(raw)
+		if err != nil {
+			return nil, err
+		}
+		c.l.Lock()
+		c.sets[url] = &cachedKeySet{keys: keys, fetched: time.Now()}
+		c.l.Unlock()
+		return keys, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys.(*jwtKeySet), nil
+}
+
+// discovery returns the discovery document of the OIDC provider, fetching it
+// again once it is stale.
+func (c *jwksCache) discovery(client *http.Client, discoveryURL string) (*oidcDiscovery, error) {
+	c.l.Lock()
+	cached, ok := c.docs[discoveryURL]
+	c.l.Unlock()
+	if ok && time.Since(cached.fetched) < oidcDiscoveryCacheTTL {
+		return cached.doc, nil
+	}
+
+	doc, err := c.fetch("oidc "+discoveryURL, func() (interface{}, error) {
+		doc, err := fetchOIDCDiscovery(client, discoveryURL)
+		if err != nil {
+			return nil, err
+		}
+		c.l.Lock()
+		c.docs[discoveryURL] = &cachedDiscovery{doc: doc, fetched: time.Now()}
+		c.l.Unlock()
+		return doc, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc.(*oidcDiscovery), nil
+}
+
+// fetch calls fn unless a fetch with the same key is in progress, in which
+// case it waits for its result instead.
+func (c *jwksCache) fetch(key string, fn func() (interface{}, error)) (interface{}, error) {
+	c.l.Lock()
+	if f, ok := c.inflight[key]; ok {
+		c.l.Unlock()
+		<-f.done
+		return f.value, f.err
+	}
+	f := &cacheFetch{done: make(chan struct{})}
+	c.inflight[key] = f
+	c.l.Unlock()
+
+	f.value, f.err = fn()
+
+	c.l.Lock()
+	delete(c.inflight, key)
+	c.l.Unlock()
+	close(f.done)
+	return f.value, f.err
+}
+
+// jwtLogin verifies the JWT with the keys of the JWT auth method and returns
+// the identity it asserts. The JWT must expire, have the bound issuer and
+// audiences if the auth method has some, and match its bound claims.
+func (s *Server) jwtLogin(method *structs.ACLAuthMethod, raw string) (*structs.ACLIdentity, error) {
+	config := method.Config
+	jwt, err := parseJWT(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := config.BoundIssuer
+	var keys *jwtKeySet
+	if len(config.JWTValidationPubKeys) != 0 {
+		keys = &jwtKeySet{byID: make(map[string]crypto.PublicKey)}
+		for _, pem := range config.JWTValidationPubKeys {
+			key, err := structs.ParseJWTValidationPubKey(pem)
+			if err != nil {
+				return nil, err
+			}
+			keys.all = append(keys.all, key)
+		}
+	} else {
+		client, err := authHTTPClient(config.DiscoveryCaPem)
+		if err != nil {
+			return nil, err
+		}
+		jwksURL := config.JWKSURL
+		if config.OIDCDiscoveryURL != "" {
+			doc, err := s.authKeySets.discovery(client, config.OIDCDiscoveryURL)
+			if err != nil {
+				return nil, err
+			}
+			jwksURL = doc.JWKSURI
+			if issuer == "" {
+				issuer = doc.Issuer
+			}
+		}
+		if keys, err = s.authKeySets.get(client, jwksURL, jwt.header.KeyID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := jwt.verifySignature(keys, config.SigningAlgs); err != nil {
+		return nil, err
+	}
+	if _, ok := jwt.timeClaim("exp"); !ok {
+		return nil, fmt.Errorf("JWT has no expiration time")
+	}
+	exp := jwtExpectations{
+		Issuer:    issuer,
+		Audiences: config.BoundAudiences,
+	}
+	if err := jwt.validateClaims(exp, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := checkBoundClaims(jwt.claims, config.BoundClaims); err != nil {
+		return nil, err
+	}
+	return mapClaims(jwt.claims, config), nil
+}
@@ -0,0 +1,90 @@
+package nomad
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+func TestJWKSCache(t *testing.T) {
+	t.Parallel()
+
+	var keyRequests, docRequests int32
+	release := make(chan struct{})
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/keys":
+			atomic.AddInt32(&keyRequests, 1)
+			<-release
+			fmt.Fprint(w, `{"keys": []}`)
+		case oidcDiscoveryPath:
+			atomic.AddInt32(&docRequests, 1)
+			fmt.Fprintf(w, `{"issuer": %q, "authorization_endpoint": "%[1]s/auth", "token_endpoint": "%[1]s/token", "jwks_uri": "%[1]s/keys"}`, ts.URL)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	c := newJWKSCache()
+	client := cleanhttp.DefaultClient()
+
+	// Concurrent fetches of a key set share one request
+	var wg sync.WaitGroup
+	errCh := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.get(client, ts.URL+"/keys", "")
+			errCh <- err
+		}()
+	}
+
+	// The cache isn't locked while the key set is fetched
+	for start := time.Now(); atomic.LoadInt32(&keyRequests) == 0; {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("key set not fetched")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 2; i++ {
+		doc, err := c.discovery(client, ts.URL)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if doc.JWKSURI != ts.URL+"/keys" {
+			t.Fatalf("bad: %#v", doc)
+		}
+	}
+	if n := atomic.LoadInt32(&docRequests); n != 1 {
+		t.Fatalf("discovery document fetched %d times", n)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&keyRequests); n != 1 {
+		t.Fatalf("key set fetched %d times", n)
+	}
+
+	// The key set is cached
+	if _, err := c.get(client, ts.URL+"/keys", ""); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n := atomic.LoadInt32(&keyRequests); n != 1 {
+		t.Fatalf("key set fetched %d times", n)
+	}
+}
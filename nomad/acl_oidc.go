@@ -0,0 +1,266 @@
+package nomad
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// oidcLoginTTL is how long a user has to log in with the OIDC provider
+	// once the login started
+	oidcLoginTTL = 10 * time.Minute
+
+	// oidcDiscoveryPath is the path of the discovery document below the
+	// issuer URL of an OIDC provider
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+)
+
+// oidcDiscovery is the part of the discovery document of an OIDC provider
+// needed to log users in
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// fetchOIDCDiscovery fetches the discovery document of the OIDC provider. The
+// issuer of the document must be the discovery URL.
+func fetchOIDCDiscovery(client *http.Client, discoveryURL string) (*oidcDiscovery, error) {
+	issuer := strings.TrimSuffix(discoveryURL, "/")
+	var doc oidcDiscovery
+	if err := getJSON(client, issuer+oidcDiscoveryPath, &doc); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC discovery document: %v", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC issuer %q doesn't match the discovery URL %q", doc.Issuer, discoveryURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("incomplete OIDC discovery document")
+	}
+	return &doc, nil
+}
+
+// oidcLogin is a login started with an OIDC provider, waiting for the user to
+// be redirected back with an authorization code
+type oidcLogin struct {
+	authMethod  string
+	redirectURI string
+	clientNonce string
+	nonce       string
+	expires     time.Time
+}
+
+// oidcLogins tracks the logins started with the OIDC providers by their state
+// parameter
+type oidcLogins struct {
+	pending map[string]*oidcLogin
+	l       sync.Mutex
+}
+
+// newOIDCLogins returns an empty set of logins
+func newOIDCLogins() *oidcLogins {
+	return &oidcLogins{pending: make(map[string]*oidcLogin)}
+}
+
+// add tracks the login by its state, dropping the logins that expired
+func (o *oidcLogins) add(state string, login *oidcLogin) {
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	now := time.Now()
+	for s, l := range o.pending {
+		if now.After(l.expires) {
+			delete(o.pending, s)
+		}
+	}
+	o.pending[state] = login
+}
+
+// take returns the unexpired login of the state and stops tracking it, so
+// each login completes at most once
+func (o *oidcLogins) take(state string) *oidcLogin {
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	login, ok := o.pending[state]
+	if !ok {
+		return nil
+	}
+	delete(o.pending, state)
+	if time.Now().After(login.expires) {
+		return nil
+	}
+	return login
+}
+
+// randomOIDCParam returns a random state or nonce parameter
+func randomOIDCParam() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// oidcAuthURL starts a login with the OIDC provider of the auth method and
+// returns the URL the user logs in at
+func (s *Server) oidcAuthURL(method *structs.ACLAuthMethod, redirectURI, clientNonce string) (string, error) {
+	config := method.Config
+	if !helper.SliceStringContains(config.AllowedRedirectURIs, redirectURI) {
+		return "", fmt.Errorf("redirect URI %q not allowed", redirectURI)
+	}
+
+	client, err := authHTTPClient(config.DiscoveryCaPem)
+	if err != nil {
+		return "", err
+	}
+	doc, err := s.authKeySets.discovery(client, config.OIDCDiscoveryURL)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomOIDCParam()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomOIDCParam()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(doc.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid OIDC authorization endpoint: %v", err)
+	}
+	scopes := append([]string{"openid"}, config.OIDCScopes...)
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", config.OIDCClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	authURL.RawQuery = q.Encode()
+
+	s.oidcLogins.add(state, &oidcLogin{
+		authMethod:  method.Name,
+		redirectURI: redirectURI,
+		clientNonce: clientNonce,
+		nonce:       nonce,
+		expires:     time.Now().Add(oidcLoginTTL),
+	})
+	return authURL.String(), nil
+}
+
+// oidcCompleteAuth completes the login of the state by exchanging the
+// authorization code for an ID token, and returns the identity asserted by
+// the ID token
+func (s *Server) oidcCompleteAuth(method *structs.ACLAuthMethod, args *structs.ACLOIDCCompleteAuthRequest) (*structs.ACLIdentity, error) {
+	login := s.oidcLogins.take(args.State)
+	if login == nil {
+		return nil, fmt.Errorf("OIDC login not found or expired")
+	}
+	if login.authMethod != method.Name || login.redirectURI != args.RedirectURI || login.clientNonce != args.ClientNonce {
+		return nil, fmt.Errorf("OIDC login doesn't match the request")
+	}
+
+	config := method.Config
+	client, err := authHTTPClient(config.DiscoveryCaPem)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.authKeySets.discovery(client, config.OIDCDiscoveryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := exchangeOIDCCode(client, doc, config, args.Code, args.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := parseJWT(rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := s.authKeySets.get(client, doc.JWKSURI, idToken.header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := idToken.verifySignature(keys, config.SigningAlgs); err != nil {
+		return nil, err
+	}
+
+	audiences := config.BoundAudiences
+	if len(audiences) == 0 {
+		audiences = []string{config.OIDCClientID}
+	}
+	exp := jwtExpectations{
+		Issuer:    doc.Issuer,
+		Audiences: audiences,
+		Nonce:     login.nonce,
+	}
+	if err := idToken.validateClaims(exp, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := checkBoundClaims(idToken.claims, config.BoundClaims); err != nil {
+		return nil, err
+	}
+	return mapClaims(idToken.claims, config), nil
+}
+
+// exchangeOIDCCode exchanges the authorization code for an ID token with the
+// token endpoint of the OIDC provider
+func exchangeOIDCCode(client *http.Client, doc *oidcDiscovery, config *structs.ACLAuthMethodConfig,
+	code, redirectURI string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+
+	req, err := http.NewRequest("POST", doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(config.OIDCClientID), url.QueryEscape(config.OIDCClientSecret))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange OIDC authorization code: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", fmt.Errorf("failed to decode OIDC token response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || out.Error != "" {
+		return "", fmt.Errorf("OIDC provider rejected the authorization code: %s %s", out.Error, out.ErrorDescription)
+	}
+	if out.IDToken == "" {
+		return "", fmt.Errorf("OIDC token response missing ID token")
+	}
+	return out.IDToken, nil
+}
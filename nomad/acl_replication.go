@@ -0,0 +1,586 @@
+package nomad
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// aclReplicationRateLimit limits the rate of the queries replicating the
+	// ACLs from the authoritative region
+	aclReplicationRateLimit rate.Limit = 10.0
+)
+
+// replicateACLPolicies is a long running routine of the leader of a region
+// other than the authoritative one, that replicates the ACL policies of the
+// authoritative region.
+func (s *Server) replicateACLPolicies(stopCh chan struct{}) {
+	req := structs.ACLPolicyListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AuthToken:  s.config.ReplicationToken,
+			AllowStale: true,
+		},
+	}
+	limiter := rate.NewLimiter(aclReplicationRateLimit, int(aclReplicationRateLimit))
+	ctx := stopContext(stopCh)
+	s.logger.Printf("[DEBUG] nomad: starting ACL policy replication from authoritative region %q", req.Region)
+
+	for {
+		if err := limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		// Block on the remote policies changing
+		var resp structs.ACLPolicyListResponse
+		err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.ListPolicies", &req, &resp)
+		if err == nil {
+			err = s.applyACLPolicyReplication(&resp)
+		}
+		if err != nil {
+			s.logger.Printf("[ERR] nomad: failed to replicate ACL policies: %v", err)
+			select {
+			case <-time.After(s.config.ReplicationBackoff):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		// Wait for the next change of the remote policies
+		req.MinQueryIndex = resp.Index
+	}
+}
+
+// applyACLPolicyReplication applies the changes between the remote policies
+// listed and the local ones
+func (s *Server) applyACLPolicyReplication(remote *structs.ACLPolicyListResponse) error {
+	local, err := s.fsm.State().ACLPolicies(nil)
+	if err != nil {
+		return err
+	}
+	deletes, updates := diffACLPolicies(local, remote.Policies)
+
+	if len(deletes) != 0 {
+		req := structs.ACLPolicyDeleteRequest{Names: deletes}
+		if _, _, err := s.raftApply(structs.ACLPolicyDeleteRequestType, &req); err != nil {
+			return err
+		}
+	}
+
+	if len(updates) != 0 {
+		// Fetch the changed policies
+		getReq := structs.ACLPolicySetRequest{
+			Names: updates,
+			QueryOptions: structs.QueryOptions{
+				Region:        s.config.AuthoritativeRegion,
+				AuthToken:     s.config.ReplicationToken,
+				AllowStale:    true,
+				MinQueryIndex: remote.Index - 1,
+			},
+		}
+		var getResp structs.ACLPolicySetResponse
+		if err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.GetPolicies", &getReq, &getResp); err != nil {
+			return err
+		}
+
+		req := structs.ACLPolicyUpsertRequest{}
+		for _, policy := range getResp.Policies {
+			req.Policies = append(req.Policies, policy)
+		}
+		if len(req.Policies) != 0 {
+			if _, _, err := s.raftApply(structs.ACLPolicyUpsertRequestType, &req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffACLPolicies returns the names of the local policies missing from the
+// remote ones and of the remote policies that are missing or changed locally
+func diffACLPolicies(local memdb.ResultIterator, remote []*structs.ACLPolicyListStub) (deletes, updates []string) {
+	remoteHashes := make(map[string][]byte, len(remote))
+	for _, stub := range remote {
+		remoteHashes[stub.Name] = stub.Hash
+	}
+
+	localHashes := make(map[string][]byte)
+	for raw := local.Next(); raw != nil; raw = local.Next() {
+		policy := raw.(*structs.ACLPolicy)
+		localHashes[policy.Name] = policy.Hash
+		if _, ok := remoteHashes[policy.Name]; !ok {
+			deletes = append(deletes, policy.Name)
+		}
+	}
+
+	for _, stub := range remote {
+		if hash, ok := localHashes[stub.Name]; !ok || !bytes.Equal(hash, stub.Hash) {
+			updates = append(updates, stub.Name)
+		}
+	}
+	return deletes, updates
+}
+
+// replicateACLRoles is a long running routine of the leader of a region other
+// than the authoritative one, that replicates the ACL roles of the
+// authoritative region.
+func (s *Server) replicateACLRoles(stopCh chan struct{}) {
+	req := structs.ACLRoleListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AuthToken:  s.config.ReplicationToken,
+			AllowStale: true,
+		},
+	}
+	limiter := rate.NewLimiter(aclReplicationRateLimit, int(aclReplicationRateLimit))
+	ctx := stopContext(stopCh)
+	s.logger.Printf("[DEBUG] nomad: starting ACL role replication from authoritative region %q", req.Region)
+
+	for {
+		if err := limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		// Block on the remote roles changing
+		var resp structs.ACLRoleListResponse
+		err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.ListRoles", &req, &resp)
+		if err == nil {
+			err = s.applyACLRoleReplication(&resp)
+		}
+		if err != nil {
+			s.logger.Printf("[ERR] nomad: failed to replicate ACL roles: %v", err)
+			select {
+			case <-time.After(s.config.ReplicationBackoff):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		// Wait for the next change of the remote roles
+		req.MinQueryIndex = resp.Index
+	}
+}
+
+// applyACLRoleReplication applies the changes between the remote roles listed
+// and the local ones
+func (s *Server) applyACLRoleReplication(remote *structs.ACLRoleListResponse) error {
+	local, err := s.fsm.State().ACLRoles(nil)
+	if err != nil {
+		return err
+	}
+	deletes, updates := diffACLRoles(local, remote.Roles)
+
+	if len(deletes) != 0 {
+		req := structs.ACLRoleDeleteRequest{Names: deletes}
+		if _, _, err := s.raftApply(structs.ACLRoleDeleteRequestType, &req); err != nil {
+			return err
+		}
+	}
+
+	if len(updates) != 0 {
+		// Fetch the changed roles
+		getReq := structs.ACLRoleSetRequest{
+			Names: updates,
+			QueryOptions: structs.QueryOptions{
+				Region:        s.config.AuthoritativeRegion,
+				AuthToken:     s.config.ReplicationToken,
+				AllowStale:    true,
+				MinQueryIndex: remote.Index - 1,
+			},
+		}
+		var getResp structs.ACLRoleSetResponse
+		if err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.GetRoles", &getReq, &getResp); err != nil {
+			return err
+		}
+
+		req := structs.ACLRoleUpsertRequest{}
+		for _, role := range getResp.Roles {
+			req.Roles = append(req.Roles, role)
+		}
+		if len(req.Roles) != 0 {
+			if _, _, err := s.raftApply(structs.ACLRoleUpsertRequestType, &req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffACLRoles returns the names of the local roles missing from the remote
+// ones and of the remote roles that are missing or changed locally
+func diffACLRoles(local memdb.ResultIterator, remote []*structs.ACLRoleListStub) (deletes, updates []string) {
+	remoteHashes := make(map[string][]byte, len(remote))
+	for _, stub := range remote {
+		remoteHashes[stub.Name] = stub.Hash
+	}
+
+	localHashes := make(map[string][]byte)
+	for raw := local.Next(); raw != nil; raw = local.Next() {
+		role := raw.(*structs.ACLRole)
+		localHashes[role.Name] = role.Hash
+		if _, ok := remoteHashes[role.Name]; !ok {
+			deletes = append(deletes, role.Name)
+		}
+	}
+
+	for _, stub := range remote {
+		if hash, ok := localHashes[stub.Name]; !ok || !bytes.Equal(hash, stub.Hash) {
+			updates = append(updates, stub.Name)
+		}
+	}
+	return deletes, updates
+}
+
+// replicateACLAuthMethods is a long running routine of the leader of a region
+// other than the authoritative one, that replicates the ACL auth methods of
+// the authoritative region.
+func (s *Server) replicateACLAuthMethods(stopCh chan struct{}) {
+	req := structs.ACLAuthMethodListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AuthToken:  s.config.ReplicationToken,
+			AllowStale: true,
+		},
+	}
+	limiter := rate.NewLimiter(aclReplicationRateLimit, int(aclReplicationRateLimit))
+	ctx := stopContext(stopCh)
+	s.logger.Printf("[DEBUG] nomad: starting ACL auth method replication from authoritative region %q", req.Region)
+
+	for {
+		if err := limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		// Block on the remote auth methods changing
+		var resp structs.ACLAuthMethodListResponse
+		err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.ListAuthMethods", &req, &resp)
+		if err == nil {
+			err = s.applyACLAuthMethodReplication(&resp)
+		}
+		if err != nil {
+			s.logger.Printf("[ERR] nomad: failed to replicate ACL auth methods: %v", err)
+			select {
+			case <-time.After(s.config.ReplicationBackoff):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		// Wait for the next change of the remote auth methods
+		req.MinQueryIndex = resp.Index
+	}
+}
+
+// applyACLAuthMethodReplication applies the changes between the remote auth
+// methods listed and the local ones
+func (s *Server) applyACLAuthMethodReplication(remote *structs.ACLAuthMethodListResponse) error {
+	local, err := s.fsm.State().ACLAuthMethods(nil)
+	if err != nil {
+		return err
+	}
+	deletes, updates := diffACLAuthMethods(local, remote.AuthMethods)
+
+	if len(deletes) != 0 {
+		req := structs.ACLAuthMethodDeleteRequest{Names: deletes}
+		if _, _, err := s.raftApply(structs.ACLAuthMethodDeleteRequestType, &req); err != nil {
+			return err
+		}
+	}
+
+	if len(updates) != 0 {
+		// Fetch the changed auth methods
+		getReq := structs.ACLAuthMethodSetRequest{
+			Names: updates,
+			QueryOptions: structs.QueryOptions{
+				Region:        s.config.AuthoritativeRegion,
+				AuthToken:     s.config.ReplicationToken,
+				AllowStale:    true,
+				MinQueryIndex: remote.Index - 1,
+			},
+		}
+		var getResp structs.ACLAuthMethodSetResponse
+		if err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.GetAuthMethods", &getReq, &getResp); err != nil {
+			return err
+		}
+
+		req := structs.ACLAuthMethodUpsertRequest{}
+		for _, method := range getResp.AuthMethods {
+			req.AuthMethods = append(req.AuthMethods, method)
+		}
+		if len(req.AuthMethods) != 0 {
+			if _, _, err := s.raftApply(structs.ACLAuthMethodUpsertRequestType, &req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffACLAuthMethods returns the names of the local auth methods missing from
+// the remote ones and of the remote auth methods that are missing or changed
+// locally
+func diffACLAuthMethods(local memdb.ResultIterator, remote []*structs.ACLAuthMethodStub) (deletes, updates []string) {
+	remoteHashes := make(map[string][]byte, len(remote))
+	for _, stub := range remote {
+		remoteHashes[stub.Name] = stub.Hash
+	}
+
+	localHashes := make(map[string][]byte)
+	for raw := local.Next(); raw != nil; raw = local.Next() {
+		method := raw.(*structs.ACLAuthMethod)
+		localHashes[method.Name] = method.Hash
+		if _, ok := remoteHashes[method.Name]; !ok {
+			deletes = append(deletes, method.Name)
+		}
+	}
+
+	for _, stub := range remote {
+		if hash, ok := localHashes[stub.Name]; !ok || !bytes.Equal(hash, stub.Hash) {
+			updates = append(updates, stub.Name)
+		}
+	}
+	return deletes, updates
+}
+
+// replicateACLBindingRules is a long running routine of the leader of a region
+// other than the authoritative one, that replicates the ACL binding rules of
+// the authoritative region.
+func (s *Server) replicateACLBindingRules(stopCh chan struct{}) {
+	req := structs.ACLBindingRuleListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AuthToken:  s.config.ReplicationToken,
+			AllowStale: true,
+		},
+	}
+	limiter := rate.NewLimiter(aclReplicationRateLimit, int(aclReplicationRateLimit))
+	ctx := stopContext(stopCh)
+	s.logger.Printf("[DEBUG] nomad: starting ACL binding rule replication from authoritative region %q", req.Region)
+
+	for {
+		if err := limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		// Block on the remote binding rules changing
+		var resp structs.ACLBindingRuleListResponse
+		err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.ListBindingRules", &req, &resp)
+		if err == nil {
+			err = s.applyACLBindingRuleReplication(&resp)
+		}
+		if err != nil {
+			s.logger.Printf("[ERR] nomad: failed to replicate ACL binding rules: %v", err)
+			select {
+			case <-time.After(s.config.ReplicationBackoff):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		// Wait for the next change of the remote binding rules
+		req.MinQueryIndex = resp.Index
+	}
+}
+
+// applyACLBindingRuleReplication applies the changes between the remote
+// binding rules listed and the local ones
+func (s *Server) applyACLBindingRuleReplication(remote *structs.ACLBindingRuleListResponse) error {
+	local, err := s.fsm.State().ACLBindingRules(nil)
+	if err != nil {
+		return err
+	}
+	deletes, updates := diffACLBindingRules(local, remote.BindingRules)
+
+	if len(deletes) != 0 {
+		req := structs.ACLBindingRuleDeleteRequest{IDs: deletes}
+		if _, _, err := s.raftApply(structs.ACLBindingRuleDeleteRequestType, &req); err != nil {
+			return err
+		}
+	}
+
+	if len(updates) != 0 {
+		// Fetch the changed binding rules
+		getReq := structs.ACLBindingRuleSetRequest{
+			IDs: updates,
+			QueryOptions: structs.QueryOptions{
+				Region:        s.config.AuthoritativeRegion,
+				AuthToken:     s.config.ReplicationToken,
+				AllowStale:    true,
+				MinQueryIndex: remote.Index - 1,
+			},
+		}
+		var getResp structs.ACLBindingRuleSetResponse
+		if err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.GetBindingRules", &getReq, &getResp); err != nil {
+			return err
+		}
+
+		req := structs.ACLBindingRuleUpsertRequest{}
+		for _, rule := range getResp.BindingRules {
+			req.BindingRules = append(req.BindingRules, rule)
+		}
+		if len(req.BindingRules) != 0 {
+			if _, _, err := s.raftApply(structs.ACLBindingRuleUpsertRequestType, &req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffACLBindingRules returns the IDs of the local binding rules missing from
+// the remote ones and of the remote binding rules that are missing or changed
+// locally
+func diffACLBindingRules(local memdb.ResultIterator, remote []*structs.ACLBindingRule) (deletes, updates []string) {
+	remoteHashes := make(map[string][]byte, len(remote))
+	for _, rule := range remote {
+		remoteHashes[rule.ID] = rule.Hash
+	}
+
+	localHashes := make(map[string][]byte)
+	for raw := local.Next(); raw != nil; raw = local.Next() {
+		rule := raw.(*structs.ACLBindingRule)
+		localHashes[rule.ID] = rule.Hash
+		if _, ok := remoteHashes[rule.ID]; !ok {
+			deletes = append(deletes, rule.ID)
+		}
+	}
+
+	for _, rule := range remote {
+		if hash, ok := localHashes[rule.ID]; !ok || !bytes.Equal(hash, rule.Hash) {
+			updates = append(updates, rule.ID)
+		}
+	}
+	return deletes, updates
+}
+
+// replicateACLTokens is a long running routine of the leader of a region
+// other than the authoritative one, that replicates the global ACL tokens of
+// the authoritative region.
+func (s *Server) replicateACLTokens(stopCh chan struct{}) {
+	req := structs.ACLTokenListRequest{
+		GlobalOnly: true,
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.AuthoritativeRegion,
+			AuthToken:  s.config.ReplicationToken,
+			AllowStale: true,
+		},
+	}
+	limiter := rate.NewLimiter(aclReplicationRateLimit, int(aclReplicationRateLimit))
+	ctx := stopContext(stopCh)
+	s.logger.Printf("[DEBUG] nomad: starting ACL token replication from authoritative region %q", req.Region)
+
+	for {
+		if err := limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		// Block on the remote tokens changing
+		var resp structs.ACLTokenListResponse
+		err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.ListTokens", &req, &resp)
+		if err == nil {
+			err = s.applyACLTokenReplication(&resp)
+		}
+		if err != nil {
+			s.logger.Printf("[ERR] nomad: failed to replicate ACL tokens: %v", err)
+			select {
+			case <-time.After(s.config.ReplicationBackoff):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		// Wait for the next change of the remote tokens
+		req.MinQueryIndex = resp.Index
+	}
+}
+
+// applyACLTokenReplication applies the changes between the remote global
+// tokens listed and the local ones
+func (s *Server) applyACLTokenReplication(remote *structs.ACLTokenListResponse) error {
+	local, err := s.fsm.State().ACLTokensByGlobal(nil, true)
+	if err != nil {
+		return err
+	}
+	deletes, updates := diffACLTokens(local, remote.Tokens)
+
+	if len(deletes) != 0 {
+		req := structs.ACLTokenDeleteRequest{AccessorIDs: deletes}
+		if _, _, err := s.raftApply(structs.ACLTokenDeleteRequestType, &req); err != nil {
+			return err
+		}
+	}
+
+	if len(updates) != 0 {
+		// Fetch the changed tokens
+		getReq := structs.ACLTokenSetRequest{
+			AccessorIDs: updates,
+			QueryOptions: structs.QueryOptions{
+				Region:        s.config.AuthoritativeRegion,
+				AuthToken:     s.config.ReplicationToken,
+				AllowStale:    true,
+				MinQueryIndex: remote.Index - 1,
+			},
+		}
+		var getResp structs.ACLTokenSetResponse
+		if err := s.forwardRegion(s.config.AuthoritativeRegion, "ACL.GetTokens", &getReq, &getResp); err != nil {
+			return err
+		}
+
+		req := structs.ACLTokenUpsertRequest{}
+		for _, token := range getResp.Tokens {
+			req.Tokens = append(req.Tokens, token)
+		}
+		if len(req.Tokens) != 0 {
+			if _, _, err := s.raftApply(structs.ACLTokenUpsertRequestType, &req); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// diffACLTokens returns the accessor IDs of the local global tokens missing
+// from the remote ones and of the remote tokens that are missing or changed
+// locally
+func diffACLTokens(local memdb.ResultIterator, remote []*structs.ACLTokenListStub) (deletes, updates []string) {
+	remoteHashes := make(map[string][]byte, len(remote))
+	for _, stub := range remote {
+		remoteHashes[stub.AccessorID] = stub.Hash
+	}
+
+	localHashes := make(map[string][]byte)
+	for raw := local.Next(); raw != nil; raw = local.Next() {
+		token := raw.(*structs.ACLToken)
+		localHashes[token.AccessorID] = token.Hash
+		if _, ok := remoteHashes[token.AccessorID]; !ok {
+			deletes = append(deletes, token.AccessorID)
+		}
+	}
+
+	for _, stub := range remote {
+		if hash, ok := localHashes[stub.AccessorID]; !ok || !bytes.Equal(hash, stub.Hash) {
+			updates = append(updates, stub.AccessorID)
+		}
+	}
+	return deletes, updates
+}
+
+// stopContext returns a context canceled once the stop channel is closed
+func stopContext(stopCh chan struct{}) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	return ctx
+}
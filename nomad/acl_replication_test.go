@@ -0,0 +1,264 @@
+package nomad
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestDiffACLPolicies(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	// Keep p1, delete p2 and update p3
+	p1, p2, p3 := mock.ACLPolicy(), mock.ACLPolicy(), mock.ACLPolicy()
+	if err := state.UpsertACLPolicies(100, []*structs.ACLPolicy{p1, p2, p3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	p3Updated := p3.Stub()
+	p3Updated.Hash = []byte{1, 2, 3}
+	p4 := mock.ACLPolicy()
+	remote := []*structs.ACLPolicyListStub{p1.Stub(), p3Updated, p4.Stub()}
+
+	local, err := state.ACLPolicies(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	deletes, updates := diffACLPolicies(local, remote)
+	sort.Strings(updates)
+	expected := []string{p3.Name, p4.Name}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(deletes, []string{p2.Name}) || !reflect.DeepEqual(updates, expected) {
+		t.Fatalf("bad: %v %v", deletes, updates)
+	}
+}
+
+func TestDiffACLRoles(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	// Keep r1, delete r2 and update r3
+	r1, r2, r3 := mock.ACLRole(), mock.ACLRole(), mock.ACLRole()
+	if err := state.UpsertACLRoles(100, []*structs.ACLRole{r1, r2, r3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r3Updated := r3.Stub()
+	r3Updated.Hash = []byte{1, 2, 3}
+	r4 := mock.ACLRole()
+	remote := []*structs.ACLRoleListStub{r1.Stub(), r3Updated, r4.Stub()}
+
+	local, err := state.ACLRoles(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	deletes, updates := diffACLRoles(local, remote)
+	sort.Strings(updates)
+	expected := []string{r3.Name, r4.Name}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(deletes, []string{r2.Name}) || !reflect.DeepEqual(updates, expected) {
+		t.Fatalf("bad: %v %v", deletes, updates)
+	}
+}
+
+func TestDiffACLAuthMethods(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	// Keep m1, delete m2 and update m3
+	m1, m2, m3 := mock.ACLAuthMethod(), mock.ACLAuthMethod(), mock.ACLAuthMethod()
+	if err := state.UpsertACLAuthMethods(100, []*structs.ACLAuthMethod{m1, m2, m3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	m3Updated := m3.Stub()
+	m3Updated.Hash = []byte{1, 2, 3}
+	m4 := mock.ACLAuthMethod()
+	remote := []*structs.ACLAuthMethodStub{m1.Stub(), m3Updated, m4.Stub()}
+
+	local, err := state.ACLAuthMethods(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	deletes, updates := diffACLAuthMethods(local, remote)
+	sort.Strings(updates)
+	expected := []string{m3.Name, m4.Name}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(deletes, []string{m2.Name}) || !reflect.DeepEqual(updates, expected) {
+		t.Fatalf("bad: %v %v", deletes, updates)
+	}
+}
+
+func TestDiffACLBindingRules(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	// Keep r1, delete r2 and update r3
+	r1, r2, r3 := mock.ACLBindingRule(), mock.ACLBindingRule(), mock.ACLBindingRule()
+	if err := state.UpsertACLBindingRules(100, []*structs.ACLBindingRule{r1, r2, r3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	r3Updated := *r3
+	r3Updated.Hash = []byte{1, 2, 3}
+	r4 := mock.ACLBindingRule()
+	remote := []*structs.ACLBindingRule{r1, &r3Updated, r4}
+
+	local, err := state.ACLBindingRules(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	deletes, updates := diffACLBindingRules(local, remote)
+	sort.Strings(updates)
+	expected := []string{r3.ID, r4.ID}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(deletes, []string{r2.ID}) || !reflect.DeepEqual(updates, expected) {
+		t.Fatalf("bad: %v %v", deletes, updates)
+	}
+}
+
+func TestDiffACLTokens(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	// Keep t1, delete t2 and update t3
+	t1, t2, t3 := mock.ACLToken(), mock.ACLToken(), mock.ACLToken()
+	for _, token := range []*structs.ACLToken{t1, t2, t3} {
+		token.Global = true
+		token.SetHash()
+	}
+	if err := state.UpsertACLTokens(100, []*structs.ACLToken{t1, t2, t3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	t3Updated := t3.Stub()
+	t3Updated.Hash = []byte{1, 2, 3}
+	t4 := mock.ACLToken()
+	remote := []*structs.ACLTokenListStub{t1.Stub(), t3Updated, t4.Stub()}
+
+	local, err := state.ACLTokensByGlobal(nil, true)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	deletes, updates := diffACLTokens(local, remote)
+	sort.Strings(updates)
+	expected := []string{t3.AccessorID, t4.AccessorID}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(deletes, []string{t2.AccessorID}) || !reflect.DeepEqual(updates, expected) {
+		t.Fatalf("bad: %v %v", deletes, updates)
+	}
+}
+
+func TestACLReplication(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, func(c *Config) {
+		c.Region = "region1"
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	s2, _ := testACLServer(t, func(c *Config) {
+		c.Region = "region2"
+		c.AuthoritativeRegion = "region1"
+		c.ReplicationToken = root.SecretID
+		c.ReplicationBackoff = 20 * time.Millisecond
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+	testutil.WaitForLeader(t, s2.RPC)
+
+	// Create a policy, a role, an auth method with a binding rule and a global
+	// and a local token in the authoritative region
+	policy := mock.ACLPolicy()
+	role := mock.ACLRole()
+	role.Policies = []string{policy.Name}
+	role.SetHash()
+	global := mock.ACLToken()
+	global.Policies = []string{policy.Name}
+	global.Roles = []string{role.Name}
+	global.Global = true
+	global.SetHash()
+	local := mock.ACLToken()
+	local.Policies = []string{policy.Name}
+	method := mock.ACLAuthMethod()
+	rule := mock.ACLBindingRule()
+	rule.AuthMethod = method.Name
+	state1 := s1.fsm.State()
+	if err := state1.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state1.UpsertACLRoles(1001, []*structs.ACLRole{role}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state1.UpsertACLTokens(1002, []*structs.ACLToken{global, local}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state1.UpsertACLAuthMethods(1003, []*structs.ACLAuthMethod{method}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state1.UpsertACLBindingRules(1004, []*structs.ACLBindingRule{rule}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The policy, the role, the auth method, the binding rule and the global
+	// token are replicated
+	state2 := s2.fsm.State()
+	testutil.WaitForResult(func() (bool, error) {
+		out, err := state2.ACLPolicyByName(nil, policy.Name)
+		if err != nil || out == nil {
+			return false, fmt.Errorf("policy not replicated: %v", err)
+		}
+		replicated, err := state2.ACLRoleByName(nil, role.Name)
+		if err != nil || replicated == nil {
+			return false, fmt.Errorf("role not replicated: %v", err)
+		}
+		token, err := state2.ACLTokenByAccessorID(nil, global.AccessorID)
+		if err != nil || token == nil {
+			return false, fmt.Errorf("token not replicated: %v", err)
+		}
+		outMethod, err := state2.ACLAuthMethodByName(nil, method.Name)
+		if err != nil || outMethod == nil {
+			return false, fmt.Errorf("auth method not replicated: %v", err)
+		}
+		outRule, err := state2.ACLBindingRuleByID(nil, rule.ID)
+		if err != nil || outRule == nil {
+			return false, fmt.Errorf("binding rule not replicated: %v", err)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+	if out, _ := state2.ACLTokenByAccessorID(nil, local.AccessorID); out != nil {
+		t.Fatalf("local token replicated: %#v", out)
+	}
+
+	// Deleting the policy, the role and the auth method is replicated as well
+	if err := state1.DeleteACLPolicies(1005, []string{policy.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state1.DeleteACLRoles(1006, []string{role.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state1.DeleteACLAuthMethods(1007, []string{method.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testutil.WaitForResult(func() (bool, error) {
+		out, err := state2.ACLPolicyByName(nil, policy.Name)
+		if err != nil || out != nil {
+			return false, fmt.Errorf("policy deletion not replicated: %v", err)
+		}
+		replicated, err := state2.ACLRoleByName(nil, role.Name)
+		if err != nil || replicated != nil {
+			return false, fmt.Errorf("role deletion not replicated: %v", err)
+		}
+		outRule, err := state2.ACLBindingRuleByID(nil, rule.ID)
+		if err != nil || outRule != nil {
+			return false, fmt.Errorf("binding rule deletion not replicated: %v", err)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
@@ -0,0 +1,89 @@
+package nomad
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestResolveToken(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+
+	policy := mock.ACLPolicy()
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	expired := mock.ACLToken()
+	expiration := time.Now().Add(-time.Minute)
+	expired.ExpirationTime = &expiration
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{token, expired}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Management tokens are allowed everything
+	aclObj, err := s1.ResolveToken(root.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj != acl.ManagementACL {
+		t.Fatalf("expected the management ACL, got %#v", aclObj)
+	}
+
+	// Client tokens are granted their policies
+	aclObj, err = s1.ResolveToken(token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !aclObj.AllowNamespaceRead(structs.DefaultNamespace) || aclObj.AllowNamespaceWrite(structs.DefaultNamespace) {
+		t.Fatalf("bad: %#v", aclObj)
+	}
+
+	// The compiled ACL is cached
+	aclObj2, err := s1.ResolveToken(token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj != aclObj2 {
+		t.Fatalf("expected the cached ACL")
+	}
+
+	// Requests without a token have no permissions without an anonymous
+	// policy
+	aclObj, err = s1.ResolveToken("")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj.AllowNamespaceRead(structs.DefaultNamespace) {
+		t.Fatalf("bad: %#v", aclObj)
+	}
+
+	// Unknown and expired tokens are rejected
+	if _, err := s1.ResolveToken(structs.GenerateUUID()); err != structs.ErrTokenNotFound {
+		t.Fatalf("expected token not found, got %v", err)
+	}
+	if _, err := s1.ResolveToken(expired.SecretID); err != structs.ErrTokenExpired {
+		t.Fatalf("expected token expired, got %v", err)
+	}
+}
+
+func TestResolveToken_Disabled(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+
+	aclObj, err := s1.ResolveToken(structs.GenerateUUID())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if aclObj != nil {
+		t.Fatalf("expected a nil ACL, got %#v", aclObj)
+	}
+}
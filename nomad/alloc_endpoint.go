@@ -1,6 +1,7 @@
 package nomad
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -9,6 +10,12 @@ import (
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
+const (
+	// allocStoppedDesc is the desired description of allocations stopped
+	// through the Alloc.Stop endpoint.
+	allocStoppedDesc = "alloc was stopped by the user"
+)
+
 // Alloc endpoint is used for manipulating allocations
 type Alloc struct {
 	srv *Server
@@ -21,33 +28,43 @@ func (a *Alloc) List(args *structs.AllocListRequest, reply *structs.AllocListRes
 	}
 	defer metrics.MeasureSince([]string{"nomad", "alloc", "list"}, time.Now())
 
+	aclObj, err := a.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
 			// Capture all the allocations
-			var err error
-			var iter memdb.ResultIterator
-			if prefix := args.QueryOptions.Prefix; prefix != "" {
-				iter, err = state.AllocsByIDPrefix(ws, prefix)
-			} else {
-				iter, err = state.Allocs(ws)
-			}
+			iter, err := state.PrefixFrom(ws, "allocs", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
 			if err != nil {
 				return err
 			}
+			iter = newFilterIterator(iter, func(raw interface{}) bool {
+				return aclObj.AllowNamespaceRead(allocNamespace(raw.(*structs.Allocation)))
+			})
 
 			var allocs []*structs.AllocListStub
-			for {
-				raw := iter.Next()
-				if raw == nil {
-					break
-				}
-				alloc := raw.(*structs.Allocation)
-				allocs = append(allocs, alloc.Stub())
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.Allocation).ID
+			}, func(raw interface{}) (interface{}, error) {
+				return raw.(*structs.Allocation).Stub(), nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				allocs = append(allocs, obj.(*structs.AllocListStub))
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 			reply.Allocations = allocs
+			reply.NextToken = nextToken
 
 			// Use the last index that affected the jobs table
 			index, err := state.Index("allocs")
@@ -71,6 +88,13 @@ func (a *Alloc) GetAlloc(args *structs.AllocSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "alloc", "get_alloc"}, time.Now())
 
+	// Clients read the allocations they migrate data from, which may be on
+	// other nodes
+	_, aclObj, err := a.srv.resolveClientOrToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
@@ -81,6 +105,9 @@ func (a *Alloc) GetAlloc(args *structs.AllocSpecificRequest,
 			if err != nil {
 				return err
 			}
+			if out != nil && !aclObj.AllowNamespaceRead(allocNamespace(out)) {
+				return structs.ErrPermissionDenied
+			}
 
 			// Setup the output
 			reply.Alloc = out
@@ -110,6 +137,11 @@ func (a *Alloc) GetAllocs(args *structs.AllocsGetRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "alloc", "get_allocs"}, time.Now())
 
+	_, aclObj, err := a.srv.resolveClientOrToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	allocs := make([]*structs.Allocation, len(args.AllocIDs))
 
 	// Setup the blocking query. We wait for at least one of the requested
@@ -132,6 +164,9 @@ func (a *Alloc) GetAllocs(args *structs.AllocsGetRequest,
 					thresholdMet = false
 					break
 				}
+				if !aclObj.AllowNamespaceRead(allocNamespace(out)) {
+					return structs.ErrPermissionDenied
+				}
 
 				// Store the pointer
 				allocs[i] = out
@@ -166,3 +201,129 @@ func (a *Alloc) GetAllocs(args *structs.AllocsGetRequest,
 	}
 	return a.srv.blockingRPC(&opts)
 }
+
+// Stop is used to stop an allocation and create an evaluation so that the
+// scheduler replaces it.
+func (a *Alloc) Stop(args *structs.AllocStopRequest,
+	reply *structs.AllocStopResponse) error {
+	if done, err := a.srv.forward("Alloc.Stop", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "alloc", "stop"}, time.Now())
+
+	// Validate the arguments
+	if args.AllocID == "" {
+		return fmt.Errorf("missing allocation ID")
+	}
+
+	// Lookup the allocation
+	snap, err := a.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	alloc, err := snap.AllocByID(nil, args.AllocID)
+	if err != nil {
+		return err
+	}
+	if alloc == nil {
+		return fmt.Errorf("allocation not found")
+	}
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowNamespaceWrite(allocNamespace(alloc)) {
+		return structs.ErrPermissionDenied
+	}
+	if alloc.TerminalStatus() {
+		return fmt.Errorf("can't stop terminal allocation")
+	}
+	job := alloc.Job
+	if job == nil {
+		return fmt.Errorf("allocation %q has no job", alloc.ID)
+	}
+
+	// Mark the allocation as stopped so that its client stops it and the
+	// scheduler places a replacement
+	stopped := alloc.Copy()
+	stopped.Job = nil
+	stopped.DesiredStatus = structs.AllocDesiredStatusStop
+	stopped.DesiredDescription = allocStoppedDesc
+
+	eval := &structs.Evaluation{
+		ID:             structs.GenerateUUID(),
+		Priority:       job.Priority,
+		Type:           job.Type,
+		TriggeredBy:    structs.EvalTriggerAllocStop,
+		JobID:          job.ID,
+		JobModifyIndex: job.ModifyIndex,
+		Status:         structs.EvalStatusPending,
+	}
+	update := &structs.AllocUpdateRequest{
+		Alloc:        []*structs.Allocation{stopped},
+		Job:          job,
+		Evals:        []*structs.Evaluation{eval},
+		WriteRequest: structs.WriteRequest{Region: args.Region},
+	}
+
+	// Commit the update via Raft
+	_, index, err := a.srv.raftApply(structs.AllocUpdateRequestType, update)
+	if err != nil {
+		a.srv.logger.Printf("[ERR] nomad.alloc: Stop failed: %v", err)
+		return err
+	}
+
+	// Populate the reply with eval information
+	reply.EvalID = eval.ID
+	reply.EvalCreateIndex = index
+	reply.Index = index
+	return nil
+}
+
+// Signal sends a signal to the tasks of an allocation. The signal is sent by
+// the client running the allocation, over its node connection to this or
+// another server of the region.
+func (a *Alloc) Signal(args *structs.AllocSignalRequest, reply *structs.GenericResponse) error {
+	// Signals are handled by the server the node is connected to rather than
+	// the leader
+	if args.Region == "" {
+		args.Region = a.srv.config.Region
+	}
+	if args.Region != a.srv.config.Region {
+		return a.srv.forwardRegion(args.Region, "Alloc.Signal", args, reply)
+	}
+	defer metrics.MeasureSince([]string{"nomad", "alloc", "signal"}, time.Now())
+
+	// Validate the arguments
+	if args.AllocID == "" {
+		return fmt.Errorf("missing allocation ID")
+	}
+	if args.Signal == "" {
+		return fmt.Errorf("missing signal")
+	}
+
+	// Lookup the allocation
+	alloc, err := a.srv.fsm.State().AllocByID(nil, args.AllocID)
+	if err != nil {
+		return err
+	}
+	if alloc == nil {
+		return fmt.Errorf("allocation not found")
+	}
+	if aclObj, err := a.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowNamespaceWrite(allocNamespace(alloc)) {
+		return structs.ErrPermissionDenied
+	}
+	if alloc.TerminalStatus() {
+		return fmt.Errorf("can't signal terminal allocation")
+	}
+
+	if session, ok := a.srv.nodeConn(alloc.NodeID); ok {
+		return a.srv.nodeRPC(session, "ClientAllocations.Signal", args, reply)
+	}
+
+	server, err := a.srv.findNodeConnServer(alloc.NodeID)
+	if err != nil {
+		return err
+	}
+	return a.srv.connPool.RPC(a.srv.config.Region, server.Addr, server.MajorVersion, "Alloc.Signal", args, reply)
+}
@@ -1,7 +1,9 @@
 package nomad
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -334,3 +336,200 @@ func TestAllocEndpoint_GetAllocs_Blocking(t *testing.T) {
 		t.Fatalf("bad: %#v", resp.Allocs)
 	}
 }
+
+func TestAllocEndpoint_Stop(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the allocation
+	alloc := mock.Alloc()
+	state := s1.fsm.State()
+	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Stop the allocation
+	req := &structs.AllocStopRequest{
+		AllocID:      alloc.ID,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.AllocStopResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Alloc.Stop", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Index == 0 || resp.EvalID == "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Check the allocation is stopped
+	out, err := state.AllocByID(nil, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.DesiredStatus != structs.AllocDesiredStatusStop {
+		t.Fatalf("bad desired status: %v", out.DesiredStatus)
+	}
+	if out.Job == nil {
+		t.Fatalf("expected job to be denormalized")
+	}
+
+	// Check the evaluation was created
+	eval, err := state.EvalByID(nil, resp.EvalID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if eval == nil {
+		t.Fatalf("expected eval")
+	}
+	if eval.CreateIndex != resp.EvalCreateIndex {
+		t.Fatalf("index mis-match")
+	}
+	if eval.JobID != alloc.JobID || eval.TriggeredBy != structs.EvalTriggerAllocStop ||
+		eval.Type != alloc.Job.Type || eval.Status != structs.EvalStatusPending {
+		t.Fatalf("bad: %#v", eval)
+	}
+
+	// Stopping the allocation again fails
+	err = msgpackrpc.CallWithCodec(codec, "Alloc.Stop", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "terminal") {
+		t.Fatalf("expected terminal allocation error, got: %v", err)
+	}
+
+	// Stopping an unknown allocation fails
+	req.AllocID = structs.GenerateUUID()
+	err = msgpackrpc.CallWithCodec(codec, "Alloc.Stop", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+}
+
+func TestAllocEndpoint_Signal(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the node and its allocation
+	node := mock.Node()
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	state := s1.fsm.State()
+	if err := state.UpsertNode(998, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID))
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.AllocSignalRequest{
+		AllocID:      alloc.ID,
+		Task:         "web",
+		Signal:       "SIGHUP",
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+
+	// Signalling fails while the node isn't connected
+	err := msgpackrpc.CallWithCodec(codec, "Alloc.Signal", req, &resp)
+	if err == nil || err.Error() != structs.ErrNoNodeConn.Error() {
+		t.Fatalf("expected no node connection error, got: %v", err)
+	}
+
+	// The signal is sent to the client over its node connection
+	client, session := testNodeConn(t, s1, node)
+	defer session.Close()
+	if err := msgpackrpc.CallWithCodec(codec, "Alloc.Signal", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	select {
+	case out := <-client.signals:
+		if out.AllocID != alloc.ID || out.Task != "web" || out.Signal != "SIGHUP" {
+			t.Fatalf("bad: %#v", out)
+		}
+	default:
+		t.Fatalf("signal not sent to the client")
+	}
+
+	// Errors of the client are returned
+	req.Signal = "SIGBAD"
+	err = msgpackrpc.CallWithCodec(codec, "Alloc.Signal", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "invalid signal") {
+		t.Fatalf("expected invalid signal error, got: %v", err)
+	}
+
+	// Unknown allocations can't be signalled
+	req.AllocID = structs.GenerateUUID()
+	err = msgpackrpc.CallWithCodec(codec, "Alloc.Signal", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got: %v", err)
+	}
+}
+
+func TestAllocEndpoint_Signal_Forward(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	s2 := testServer(t, func(c *Config) {
+		c.DevDisableBootstrap = true
+	})
+	defer s2.Shutdown()
+	testJoin(t, s1, s2)
+	testutil.WaitForLeader(t, s1.RPC)
+	testutil.WaitForLeader(t, s2.RPC)
+
+	// Register the node and its allocation through Raft for both servers to
+	// have them
+	leader, follower := s1, s2
+	if !s1.IsLeader() {
+		leader, follower = s2, s1
+	}
+	node := mock.Node()
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	if _, _, err := leader.raftApply(structs.NodeRegisterRequestType, &structs.NodeRegisterRequest{Node: node}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	update := &structs.AllocUpdateRequest{
+		Alloc: []*structs.Allocation{alloc},
+		Job:   alloc.Job,
+	}
+	if _, _, err := leader.raftApply(structs.AllocUpdateRequestType, update); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testutil.WaitForResult(func() (bool, error) {
+		out, err := follower.fsm.State().AllocByID(nil, alloc.ID)
+		return out != nil, fmt.Errorf("allocation not replicated: %v", err)
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// The server without the node connection forwards the signal to the one
+	// with it
+	client, session := testNodeConn(t, follower, node)
+	defer session.Close()
+	req := &structs.AllocSignalRequest{
+		AllocID:      alloc.ID,
+		Signal:       "SIGHUP",
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(rpcClient(t, leader), "Alloc.Signal", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	select {
+	case out := <-client.signals:
+		if out.AllocID != alloc.ID || out.Signal != "SIGHUP" {
+			t.Fatalf("bad: %#v", out)
+		}
+	default:
+		t.Fatalf("signal not sent to the client")
+	}
+}
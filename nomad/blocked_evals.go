@@ -1,6 +1,8 @@
 package nomad
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +16,12 @@ const (
 	// should be large to ensure that the FSM doesn't block when calling Unblock
 	// as this would apply back-pressure on Raft.
 	unblockBuffer = 8096
+
+	// maxBlockedMetricValues is the maximum number of node classes and of
+	// datacenters the blocked evaluations are broken down by. The blocked
+	// evaluations of the others are counted as "other", bounding the
+	// cardinality of the metrics.
+	maxBlockedMetricValues = 25
 )
 
 // BlockedEvals is used to track evaluations that shouldn't be queued until a
@@ -465,14 +473,211 @@ func (b *BlockedEvals) Stats() *BlockedStats {
 	return stats
 }
 
+// BlockedResourceStats breaks down the blocked evaluations by the reasons
+// their placements failed. An evaluation is counted once per key, however
+// many of its allocations failed.
+type BlockedResourceStats struct {
+	// ByReason is the number of blocked evaluations per exhausted resource:
+	// cpu, memory, disk, iops, ports, bandwidth or other.
+	ByReason map[string]int
+
+	// ByClass is the number of blocked evaluations per node class that was
+	// exhausted. Nodes without a class are counted as "none".
+	ByClass map[string]int
+
+	// ByDatacenter is the number of blocked evaluations per datacenter with
+	// feasible nodes and per exhausted resource.
+	ByDatacenter map[string]map[string]int
+}
+
+// ResourceStats returns the breakdown of the blocked evaluations by the
+// reasons their placements failed.
+func (b *BlockedEvals) ResourceStats() *BlockedResourceStats {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	var evals []*structs.Evaluation
+	for _, wrapped := range b.captured {
+		evals = append(evals, wrapped.eval)
+	}
+	for _, wrapped := range b.escaped {
+		evals = append(evals, wrapped.eval)
+	}
+	return blockedResourceStats(evals)
+}
+
+// blockedResourceStats breaks down the evaluations by the reasons their
+// placements failed, keeping at most maxBlockedMetricValues node classes and
+// datacenters.
+func blockedResourceStats(evals []*structs.Evaluation) *BlockedResourceStats {
+	reasons := make([]map[string]struct{}, len(evals))
+	classes := make([]map[string]struct{}, len(evals))
+	dcs := make([]map[string]struct{}, len(evals))
+	classCounts := make(map[string]int)
+	dcCounts := make(map[string]int)
+	for i, eval := range evals {
+		reasons[i] = make(map[string]struct{})
+		classes[i] = make(map[string]struct{})
+		dcs[i] = make(map[string]struct{})
+		for _, metric := range eval.FailedTGAllocs {
+			for dim := range metric.DimensionExhausted {
+				reasons[i][exhaustedReason(dim)] = struct{}{}
+			}
+			classified := 0
+			for class, n := range metric.ClassExhausted {
+				classes[i][class] = struct{}{}
+				classified += n
+			}
+			if metric.NodesExhausted > classified {
+				classes[i]["none"] = struct{}{}
+			}
+			if metric.NodesExhausted == 0 {
+				continue
+			}
+			for dc, available := range metric.NodesAvailable {
+				if available > 0 {
+					dcs[i][dc] = struct{}{}
+				}
+			}
+		}
+		for class := range classes[i] {
+			classCounts[class]++
+		}
+		for dc := range dcs[i] {
+			dcCounts[dc]++
+		}
+	}
+
+	topClasses := topBlockedMetricValues(classCounts)
+	topDCs := topBlockedMetricValues(dcCounts)
+	stats := &BlockedResourceStats{
+		ByReason:     make(map[string]int),
+		ByClass:      make(map[string]int),
+		ByDatacenter: make(map[string]map[string]int),
+	}
+	for i := range evals {
+		for reason := range reasons[i] {
+			stats.ByReason[reason]++
+		}
+
+		// An evaluation exhausting several classes or datacenters beyond the
+		// limit is only counted once as other
+		seen := make(map[string]struct{})
+		for class := range classes[i] {
+			if _, ok := topClasses[class]; !ok {
+				class = "other"
+			}
+			if _, ok := seen[class]; !ok {
+				seen[class] = struct{}{}
+				stats.ByClass[class]++
+			}
+		}
+
+		seen = make(map[string]struct{})
+		for dc := range dcs[i] {
+			if _, ok := topDCs[dc]; !ok {
+				dc = "other"
+			}
+			if _, ok := seen[dc]; ok {
+				continue
+			}
+			seen[dc] = struct{}{}
+			byReason, ok := stats.ByDatacenter[dc]
+			if !ok {
+				byReason = make(map[string]int)
+				stats.ByDatacenter[dc] = byReason
+			}
+			for reason := range reasons[i] {
+				byReason[reason]++
+			}
+		}
+	}
+	return stats
+}
+
+// topBlockedMetricValues returns the maxBlockedMetricValues values with the
+// highest counts. Ties are broken by name so the set is stable.
+func topBlockedMetricValues(counts map[string]int) map[string]struct{} {
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	if len(values) > maxBlockedMetricValues {
+		values = values[:maxBlockedMetricValues]
+	}
+
+	top := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		top[value] = struct{}{}
+	}
+	return top
+}
+
+// exhaustedReason maps an exhausted dimension, as recorded by the scheduler,
+// to the resource it exhausted.
+func exhaustedReason(dimension string) string {
+	dimension = strings.TrimPrefix(dimension, "network: ")
+	switch {
+	case strings.HasPrefix(dimension, "cpu"):
+		return "cpu"
+	case strings.HasPrefix(dimension, "memory"):
+		return "memory"
+	case strings.HasPrefix(dimension, "disk"):
+		return "disk"
+	case strings.HasPrefix(dimension, "iops"):
+		return "iops"
+	case strings.Contains(dimension, "port"):
+		return "ports"
+	case strings.Contains(dimension, "bandwidth"):
+		return "bandwidth"
+	default:
+		return "other"
+	}
+}
+
 // EmitStats is used to export metrics about the blocked eval tracker while enabled
 func (b *BlockedEvals) EmitStats(period time.Duration, stopCh chan struct{}) {
+	// emitted holds the keys of the breakdown gauges set in the previous
+	// period, so the ones no longer blocking any evaluation are set to zero.
+	emitted := make(map[string][]string)
 	for {
 		select {
 		case <-time.After(period):
 			stats := b.Stats()
 			metrics.SetGauge([]string{"nomad", "blocked_evals", "total_blocked"}, float32(stats.TotalBlocked))
 			metrics.SetGauge([]string{"nomad", "blocked_evals", "total_escaped"}, float32(stats.TotalEscaped))
+
+			gauges := make(map[string][]string)
+			resources := b.ResourceStats()
+			for reason, n := range resources.ByReason {
+				key := []string{"nomad", "blocked_evals", "reason", reason}
+				gauges[strings.Join(key, ".")] = key
+				metrics.SetGauge(key, float32(n))
+			}
+			for class, n := range resources.ByClass {
+				key := []string{"nomad", "blocked_evals", "class", class}
+				gauges[strings.Join(key, ".")] = key
+				metrics.SetGauge(key, float32(n))
+			}
+			for dc, byReason := range resources.ByDatacenter {
+				for reason, n := range byReason {
+					key := []string{"nomad", "blocked_evals", "datacenter", dc, reason}
+					gauges[strings.Join(key, ".")] = key
+					metrics.SetGauge(key, float32(n))
+				}
+			}
+			for name, key := range emitted {
+				if _, ok := gauges[name]; !ok {
+					metrics.SetGauge(key, 0)
+				}
+			}
+			emitted = gauges
 		case <-stopCh:
 			return
 		}
@@ -523,3 +523,81 @@ func TestBlockedEvals_Untrack(t *testing.T) {
 		t.Fatalf("bad: %#v", bStats)
 	}
 }
+
+func TestBlockedEvals_ResourceStats(t *testing.T) {
+	t.Parallel()
+	blocked, _ := testBlockedEvals(t)
+
+	// An evaluation out of memory on a class of nodes of dc1
+	e1 := mock.Eval()
+	e1.Status = structs.EvalStatusBlocked
+	e1.ClassEligibility = map[string]bool{"v1:123": true}
+	e1.FailedTGAllocs = map[string]*structs.AllocMetric{
+		"web": {
+			NodesAvailable:     map[string]int{"dc1": 2, "dc2": 0},
+			NodesExhausted:     2,
+			ClassExhausted:     map[string]int{"large": 2},
+			DimensionExhausted: map[string]int{"memory exhausted": 2},
+		},
+	}
+	blocked.Block(e1)
+
+	// An evaluation out of ports and memory on unclassed nodes of dc1 and dc2
+	e2 := mock.Eval()
+	e2.Status = structs.EvalStatusBlocked
+	e2.EscapedComputedClass = true
+	e2.FailedTGAllocs = map[string]*structs.AllocMetric{
+		"api": {
+			NodesAvailable:     map[string]int{"dc1": 1, "dc2": 1},
+			NodesExhausted:     2,
+			DimensionExhausted: map[string]int{"network: reserved port collision": 1},
+		},
+		"cache": {
+			NodesAvailable:     map[string]int{"dc1": 1, "dc2": 1},
+			NodesExhausted:     2,
+			DimensionExhausted: map[string]int{"memory exhausted": 2},
+		},
+	}
+	blocked.Block(e2)
+
+	stats := blocked.ResourceStats()
+	if expected := map[string]int{"memory": 2, "ports": 1}; !reflect.DeepEqual(stats.ByReason, expected) {
+		t.Fatalf("bad reasons: %v", stats.ByReason)
+	}
+	if expected := map[string]int{"large": 1, "none": 1}; !reflect.DeepEqual(stats.ByClass, expected) {
+		t.Fatalf("bad classes: %v", stats.ByClass)
+	}
+	expected := map[string]map[string]int{
+		"dc1": {"memory": 2, "ports": 1},
+		"dc2": {"memory": 1, "ports": 1},
+	}
+	if !reflect.DeepEqual(stats.ByDatacenter, expected) {
+		t.Fatalf("bad datacenters: %v", stats.ByDatacenter)
+	}
+}
+
+func TestBlockedEvals_ResourceStats_Bounded(t *testing.T) {
+	t.Parallel()
+
+	var evals []*structs.Evaluation
+	for i := 0; i < maxBlockedMetricValues+5; i++ {
+		e := mock.Eval()
+		e.FailedTGAllocs = map[string]*structs.AllocMetric{
+			"web": {
+				NodesAvailable:     map[string]int{fmt.Sprintf("dc%d", i): 1},
+				NodesExhausted:     1,
+				ClassExhausted:     map[string]int{fmt.Sprintf("class%d", i): 1},
+				DimensionExhausted: map[string]int{"cpu exhausted": 1},
+			},
+		}
+		evals = append(evals, e)
+	}
+
+	stats := blockedResourceStats(evals)
+	if len(stats.ByClass) != maxBlockedMetricValues+1 || stats.ByClass["other"] != 5 {
+		t.Fatalf("bad classes: %v", stats.ByClass)
+	}
+	if len(stats.ByDatacenter) != maxBlockedMetricValues+1 || stats.ByDatacenter["other"]["cpu"] != 5 {
+		t.Fatalf("bad datacenters: %v", stats.ByDatacenter)
+	}
+}
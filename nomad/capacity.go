@@ -0,0 +1,272 @@
+package nomad
+
+import (
+	"sync"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// capacityRollupInterval is the interval at which the capacity of the
+	// cluster is rolled up.
+	capacityRollupInterval = time.Minute
+
+	// capacityMinuteRollups and capacityHourlyRollups are the number of
+	// minute and hourly rollups kept: an hour and a week.
+	capacityMinuteRollups = 60
+	capacityHourlyRollups = 7 * 24
+
+	// capacityUsageExpiration is the time after which the usage reported by a
+	// node that stopped heartbeating is forgotten.
+	capacityUsageExpiration = 5 * time.Minute
+
+	// capacityNoClass is the node class nodes without a class are reported
+	// as.
+	capacityNoClass = "none"
+)
+
+// CapacityTracker keeps the resources used by the allocations, as reported
+// with the heartbeats of the nodes, and rolls up the capacity of the cluster
+// over time. It is enabled on the leader, which receives the heartbeats.
+type CapacityTracker struct {
+	enabled bool
+
+	// usage is the latest usage reported per node ID.
+	usage map[string]*nodeUsage
+
+	// minutes are the minute rollups, oldest first, of which hour holds the
+	// ones not yet averaged into an hourly rollup.
+	minutes []*structs.CapacityRollup
+	hours   []*structs.CapacityRollup
+	hour    []*structs.CapacityRollup
+
+	l sync.Mutex
+}
+
+// nodeUsage is the usage of the allocations of a node.
+type nodeUsage struct {
+	allocs  map[string]*structs.AllocResourceUsed
+	updated time.Time
+}
+
+// NewCapacityTracker returns a disabled capacity tracker.
+func NewCapacityTracker() *CapacityTracker {
+	return &CapacityTracker{
+		usage: make(map[string]*nodeUsage),
+	}
+}
+
+// SetEnabled enables or disables the tracker. Disabling it forgets the usage
+// and the rollups.
+func (c *CapacityTracker) SetEnabled(enabled bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.enabled = enabled
+	if !enabled {
+		c.usage = make(map[string]*nodeUsage)
+		c.minutes = nil
+		c.hours = nil
+		c.hour = nil
+	}
+}
+
+// Update records the usage of the allocations of a node.
+func (c *CapacityTracker) Update(nodeID string, allocs map[string]*structs.AllocResourceUsed) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if !c.enabled {
+		return
+	}
+	c.usage[nodeID] = &nodeUsage{allocs: allocs, updated: time.Now()}
+}
+
+// Report returns the capacity of the cluster and the resources reserved and
+// used per node class, namespace and job.
+func (c *CapacityTracker) Report(snap *state.StateSnapshot) (*structs.CapacityResponse, error) {
+	ws := memdb.NewWatchSet()
+	out := &structs.CapacityResponse{
+		Total:       new(structs.CapacityStats),
+		NodeClasses: make(map[string]*structs.CapacityStats),
+		Namespaces:  make(map[string]*structs.CapacityStats),
+		Jobs:        make(map[string]*structs.CapacityStats),
+	}
+
+	// Index the class of the nodes and add the capacity of the ready ones
+	classes := make(map[string]string)
+	iter, err := snap.Nodes(ws)
+	if err != nil {
+		return nil, err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		node := raw.(*structs.Node)
+		class := node.NodeClass
+		if class == "" {
+			class = capacityNoClass
+		}
+		classes[node.ID] = class
+		if !node.Ready() || node.Resources == nil {
+			continue
+		}
+
+		cpu, mem, disk := node.Resources.CPU, node.Resources.MemoryMB, node.Resources.DiskMB
+		if r := node.Reserved; r != nil {
+			cpu, mem, disk = cpu-r.CPU, mem-r.MemoryMB, disk-r.DiskMB
+		}
+		out.Total.Capacity.Add(cpu, mem, disk)
+		capacityStats(out.NodeClasses, class).Capacity.Add(cpu, mem, disk)
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+	now := time.Now()
+	for id, usage := range c.usage {
+		if now.Sub(usage.updated) > capacityUsageExpiration {
+			delete(c.usage, id)
+		}
+	}
+
+	iter, err = snap.Allocs(ws)
+	if err != nil {
+		return nil, err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		alloc := raw.(*structs.Allocation)
+		if alloc.TerminalStatus() {
+			continue
+		}
+
+		groups := []*structs.CapacityStats{
+			out.Total,
+			capacityStats(out.Namespaces, allocNamespace(alloc)),
+			capacityStats(out.Jobs, alloc.JobID),
+		}
+		if class, ok := classes[alloc.NodeID]; ok {
+			groups = append(groups, capacityStats(out.NodeClasses, class))
+		}
+
+		var used *structs.AllocResourceUsed
+		if usage, ok := c.usage[alloc.NodeID]; ok {
+			used = usage.allocs[alloc.ID]
+		}
+		for _, stats := range groups {
+			stats.Allocations++
+			if r := alloc.Resources; r != nil {
+				stats.Reserved.Add(r.CPU, r.MemoryMB, r.DiskMB)
+			}
+			if used != nil {
+				stats.Used.Add(used.CPU, used.MemoryMB, used.DiskMB)
+			}
+		}
+	}
+
+	out.MinuteRollups = append(out.MinuteRollups, c.minutes...)
+	out.HourlyRollups = append(out.HourlyRollups, c.hours...)
+	return out, nil
+}
+
+// capacityStats returns the stats of the key, creating them if needed.
+func capacityStats(m map[string]*structs.CapacityStats, key string) *structs.CapacityStats {
+	stats, ok := m[key]
+	if !ok {
+		stats = new(structs.CapacityStats)
+		m[key] = stats
+	}
+	return stats
+}
+
+// rollupCapacity rolls up the capacity of the cluster every minute until the
+// stop channel is closed. It runs on the leader.
+func (s *Server) rollupCapacity(stopCh chan struct{}) {
+	ticker := time.NewTicker(capacityRollupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		}
+
+		snap, err := s.fsm.State().Snapshot()
+		if err != nil {
+			s.logger.Printf("[ERR] nomad.capacity: failed to snapshot the state: %v", err)
+			continue
+		}
+		report, err := s.capacity.Report(snap)
+		if err != nil {
+			s.logger.Printf("[ERR] nomad.capacity: failed to compute the capacity: %v", err)
+			continue
+		}
+		s.capacity.rollup(&structs.CapacityRollup{
+			Time:        time.Now().Add(-capacityRollupInterval).Truncate(time.Second),
+			Total:       report.Total,
+			NodeClasses: report.NodeClasses,
+		})
+	}
+}
+
+// rollup adds a minute rollup, averaging the last hour of them into an
+// hourly rollup once complete.
+func (c *CapacityTracker) rollup(r *structs.CapacityRollup) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if !c.enabled {
+		return
+	}
+
+	c.minutes = append(c.minutes, r)
+	if len(c.minutes) > capacityMinuteRollups {
+		c.minutes = c.minutes[len(c.minutes)-capacityMinuteRollups:]
+	}
+
+	c.hour = append(c.hour, r)
+	if len(c.hour) < capacityMinuteRollups {
+		return
+	}
+	c.hours = append(c.hours, averageCapacityRollups(c.hour))
+	if len(c.hours) > capacityHourlyRollups {
+		c.hours = c.hours[len(c.hours)-capacityHourlyRollups:]
+	}
+	c.hour = nil
+}
+
+// averageCapacityRollups returns the average of the rollups, starting at the
+// time of the first one. Node classes missing from a rollup count as zero.
+func averageCapacityRollups(rollups []*structs.CapacityRollup) *structs.CapacityRollup {
+	out := &structs.CapacityRollup{
+		Time:        rollups[0].Time,
+		Total:       new(structs.CapacityStats),
+		NodeClasses: make(map[string]*structs.CapacityStats),
+	}
+	for _, r := range rollups {
+		addCapacityStats(out.Total, r.Total)
+		for class, stats := range r.NodeClasses {
+			addCapacityStats(capacityStats(out.NodeClasses, class), stats)
+		}
+	}
+
+	n := len(rollups)
+	divideCapacityStats(out.Total, n)
+	for _, stats := range out.NodeClasses {
+		divideCapacityStats(stats, n)
+	}
+	return out
+}
+
+func addCapacityStats(dst, src *structs.CapacityStats) {
+	dst.Capacity.Add(src.Capacity.CPU, src.Capacity.MemoryMB, src.Capacity.DiskMB)
+	dst.Reserved.Add(src.Reserved.CPU, src.Reserved.MemoryMB, src.Reserved.DiskMB)
+	dst.Used.Add(src.Used.CPU, src.Used.MemoryMB, src.Used.DiskMB)
+	dst.Allocations += src.Allocations
+}
+
+func divideCapacityStats(stats *structs.CapacityStats, n int) {
+	for _, r := range []*structs.CapacityResources{&stats.Capacity, &stats.Reserved, &stats.Used} {
+		r.CPU /= n
+		r.MemoryMB /= n
+		r.DiskMB /= n
+	}
+	stats.Allocations /= n
+}
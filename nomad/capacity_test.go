@@ -0,0 +1,125 @@
+package nomad
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func TestCapacityTracker_Report(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	node := mock.Node()
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	other := mock.Alloc()
+	other.NodeID = node.ID
+	other.Job.Namespace = "billing"
+	stopped := mock.Alloc()
+	stopped.NodeID = node.ID
+	stopped.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc, other, stopped}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tracker := NewCapacityTracker()
+	tracker.SetEnabled(true)
+	tracker.Update(node.ID, map[string]*structs.AllocResourceUsed{
+		alloc.ID: {CPU: 250, MemoryMB: 128, DiskMB: 10},
+	})
+
+	snap, err := state.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	report, err := tracker.Report(snap)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expected := &structs.CapacityStats{
+		Capacity:    structs.CapacityResources{CPU: 3900, MemoryMB: 7936, DiskMB: 96 * 1024},
+		Reserved:    structs.CapacityResources{CPU: 1000, MemoryMB: 512, DiskMB: 300},
+		Used:        structs.CapacityResources{CPU: 250, MemoryMB: 128, DiskMB: 10},
+		Allocations: 2,
+	}
+	if !reflect.DeepEqual(report.Total, expected) {
+		t.Fatalf("bad total: %#v", report.Total)
+	}
+	if !reflect.DeepEqual(report.NodeClasses[node.NodeClass], expected) {
+		t.Fatalf("bad node classes: %#v", report.NodeClasses)
+	}
+
+	// Namespaces and jobs have no capacity, and allocations are accounted
+	// to the namespace of their job
+	expected = &structs.CapacityStats{
+		Reserved:    structs.CapacityResources{CPU: 500, MemoryMB: 256, DiskMB: 150},
+		Used:        structs.CapacityResources{CPU: 250, MemoryMB: 128, DiskMB: 10},
+		Allocations: 1,
+	}
+	if !reflect.DeepEqual(report.Namespaces[structs.DefaultNamespace], expected) {
+		t.Fatalf("bad namespaces: %#v", report.Namespaces)
+	}
+	if !reflect.DeepEqual(report.Jobs[alloc.JobID], expected) {
+		t.Fatalf("bad jobs: %#v", report.Jobs)
+	}
+	expected.Used = structs.CapacityResources{}
+	if !reflect.DeepEqual(report.Namespaces["billing"], expected) {
+		t.Fatalf("bad namespaces: %#v", report.Namespaces)
+	}
+	if !reflect.DeepEqual(report.Jobs[other.JobID], expected) {
+		t.Fatalf("bad jobs: %#v", report.Jobs)
+	}
+
+	// The usage is forgotten when the tracker is disabled
+	tracker.SetEnabled(false)
+	tracker.SetEnabled(true)
+	report, err = tracker.Report(snap)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if report.Total.Used != (structs.CapacityResources{}) {
+		t.Fatalf("bad used: %#v", report.Total.Used)
+	}
+}
+
+func TestCapacityTracker_Rollup(t *testing.T) {
+	t.Parallel()
+	tracker := NewCapacityTracker()
+	tracker.SetEnabled(true)
+
+	start := time.Now()
+	for i := 0; i < capacityMinuteRollups+1; i++ {
+		used := 100
+		if i%2 == 1 {
+			used = 300
+		}
+		tracker.rollup(&structs.CapacityRollup{
+			Time: start.Add(time.Duration(i) * time.Minute),
+			Total: &structs.CapacityStats{
+				Used: structs.CapacityResources{CPU: used},
+			},
+			NodeClasses: map[string]*structs.CapacityStats{
+				"large": {Used: structs.CapacityResources{MemoryMB: used}},
+			},
+		})
+	}
+
+	if len(tracker.minutes) != capacityMinuteRollups || !tracker.minutes[0].Time.Equal(start.Add(time.Minute)) {
+		t.Fatalf("bad minute rollups: %d", len(tracker.minutes))
+	}
+	if len(tracker.hours) != 1 || len(tracker.hour) != 1 {
+		t.Fatalf("bad hourly rollups: %d", len(tracker.hours))
+	}
+	hour := tracker.hours[0]
+	if !hour.Time.Equal(start) || hour.Total.Used.CPU != 200 || hour.NodeClasses["large"].Used.MemoryMB != 200 {
+		t.Fatalf("bad hourly rollup: %#v", hour)
+	}
+}
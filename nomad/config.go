@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"github.com/hashicorp/memberlist"
+	"github.com/hashicorp/nomad/helper/ratelimit"
 	"github.com/hashicorp/nomad/helper/tlsutil"
+	"github.com/hashicorp/nomad/helper/tracing"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/scheduler"
@@ -204,6 +206,10 @@ type Config struct {
 	// as well as clock skew.
 	HeartbeatGrace time.Duration
 
+	// DispatchPayloadSizeLimit is the maximum size of the uncompressed
+	// payload of a dispatched job.
+	DispatchPayloadSizeLimit int
+
 	// FailoverHeartbeatTTL is the TTL applied to heartbeats after
 	// a new leader is elected, since we no longer know the status
 	// of all the heartbeats.
@@ -215,6 +221,37 @@ type Config struct {
 	// VaultConfig is this Agent's Vault configuration
 	VaultConfig *config.VaultConfig
 
+	// AdmissionPolicies are the external policy engines submitted jobs are
+	// evaluated against before being admitted
+	AdmissionPolicies []*config.AdmissionPolicyConfig
+
+	// DeploymentWebhooks are the webhooks the leader calls when deployments
+	// transition
+	DeploymentWebhooks []*config.DeploymentWebhookConfig
+
+	// JobNotification restricts the webhooks the notifications of jobs may
+	// call. No notification is sent if it is nil.
+	JobNotification *config.JobNotificationConfig
+
+	// ImageVerification requires the images of the jobs to be verified by
+	// the clients running them
+	ImageVerification *config.ImageVerificationConfig
+
+	// Tracer records the trace spans of the server. Tracing is disabled if
+	// it is nil.
+	Tracer *tracing.Tracer
+
+	// VariablesEncryptionKey is the key the items of the variables are
+	// encrypted with. Variables are disabled if it is not set. It must be
+	// the same on all the servers of the region.
+	VariablesEncryptionKey []byte
+
+	// WorkloadIdentitySigningKey is the PEM-encoded RSA private key the
+	// workload identities of the allocations are signed with. Workload
+	// identities are disabled if it is not set. It must be the same on all
+	// the servers of the region.
+	WorkloadIdentitySigningKey []byte
+
 	// RPCHoldTimeout is how long an RPC can be "held" before it is errored.
 	// This is used to paper over a loss of leadership by instead holding RPCs,
 	// so that the caller experiences a slow response rather than an error.
@@ -222,8 +259,43 @@ type Config struct {
 	// place, and a small jitter is applied to avoid a thundering herd.
 	RPCHoldTimeout time.Duration
 
+	// RPCRateLimit limits the rate of RPC requests, globally and per client
+	// IP address. Requests over the limit are rejected. Requests from other
+	// servers are never limited.
+	RPCRateLimit ratelimit.Config
+
+	// RPCTokenRateLimit limits the rate of RPC requests per ACL token.
+	// Requests from other servers are never limited.
+	RPCTokenRateLimit ratelimit.Config
+
 	// TLSConfig holds various TLS related configurations
 	TLSConfig *config.TLSConfig
+
+	// ACLEnabled controls if ACL enforcement and management is enabled.
+	ACLEnabled bool
+
+	// AuthoritativeRegion is the region the ACL policies and global ACL
+	// tokens are created in and replicated from. It defaults to the region
+	// of the server.
+	AuthoritativeRegion string
+
+	// ReplicationToken is the ACL token used to replicate the policies and
+	// global tokens from the authoritative region. It must be a management
+	// token.
+	ReplicationToken string
+
+	// ReplicationBackoff is how long to wait before replicating the ACLs
+	// again after an error
+	ReplicationBackoff time.Duration
+
+	// ACLTokenMinExpirationTTL and ACLTokenMaxExpirationTTL bound the time
+	// to live of the ACL tokens created with an expiration.
+	ACLTokenMinExpirationTTL time.Duration
+	ACLTokenMaxExpirationTTL time.Duration
+
+	// ACLTokenExpirationGCInterval is how often the expired ACL tokens are
+	// garbage collected.
+	ACLTokenExpirationGCInterval time.Duration
 }
 
 // CheckVersion is used to check if the ProtocolVersion is valid
@@ -275,10 +347,15 @@ func DefaultConfig() *Config {
 		MaxHeartbeatsPerSecond:           50.0,
 		HeartbeatGrace:                   10 * time.Second,
 		FailoverHeartbeatTTL:             300 * time.Second,
+		DispatchPayloadSizeLimit:         DispatchPayloadSizeLimit,
 		ConsulConfig:                     config.DefaultConsulConfig(),
 		VaultConfig:                      config.DefaultVaultConfig(),
 		RPCHoldTimeout:                   5 * time.Second,
 		TLSConfig:                        &config.TLSConfig{},
+		ACLTokenMinExpirationTTL:         1 * time.Minute,
+		ACLTokenMaxExpirationTTL:         24 * time.Hour,
+		ACLTokenExpirationGCInterval:     5 * time.Minute,
+		ReplicationBackoff:               30 * time.Second,
 	}
 
 	// Enable all known schedulers by default
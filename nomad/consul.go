@@ -0,0 +1,67 @@
+package nomad
+
+import (
+	"fmt"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// revokeSITokenAccessors deletes the Consul Service Identity tokens of the
+// given accessors. If committed is true the accessors are stored in the state
+// store and the ones whose token was deleted are deregistered through Raft;
+// the others are kept so the revocation is retried when a leader is
+// established.
+func (s *Server) revokeSITokenAccessors(accessors []*structs.SITokenAccessor, committed bool) error {
+	var mErr multierror.Error
+	revoked := make([]*structs.SITokenAccessor, 0, len(accessors))
+	for _, accessor := range accessors {
+		consul := &structs.Consul{
+			Namespace: accessor.ConsulNamespace,
+			Partition: accessor.ConsulPartition,
+		}
+		if err := s.consulACLs.DeleteToken(consul, accessor.AccessorID); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("failed to revoke token of task %q on alloc %q: %v",
+				accessor.TaskName, accessor.AllocID, err))
+			continue
+		}
+		revoked = append(revoked, accessor)
+	}
+
+	if committed && len(revoked) != 0 {
+		req := structs.SITokenAccessorsRequest{Accessors: revoked}
+		if _, _, err := s.raftApply(structs.ServiceIdentityAccessorDeregisterRequestType, &req); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("failed to deregister SI accessors: %v", err))
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// setConsulGatewayConfigEntries writes the Consul configuration entries of
+// the ingress and terminating gateways of the job. Mesh gateways have no
+// configuration entry.
+func (s *Server) setConsulGatewayConfigEntries(job *structs.Job) error {
+	for _, tg := range job.TaskGroups {
+		for _, task := range tg.Tasks {
+			for _, service := range task.Services {
+				if !service.Connect.IsGateway() {
+					continue
+				}
+
+				gateway := service.Connect.Gateway
+				switch {
+				case gateway.Ingress != nil:
+					if err := s.consulConfigEntries.SetIngressGatewayConfigEntry(tg.Consul, service.Name, gateway.Ingress); err != nil {
+						return err
+					}
+				case gateway.Terminating != nil:
+					if err := s.consulConfigEntries.SetTerminatingGatewayConfigEntry(tg.Consul, service.Name, gateway.Terminating); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
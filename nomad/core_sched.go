@@ -24,6 +24,10 @@ var (
 type CoreScheduler struct {
 	srv  *Server
 	snap *state.StateSnapshot
+
+	// leaderACL is the token of the evaluation being processed, which its
+	// RPCs to the leader are issued with
+	leaderACL string
 }
 
 // NewCoreScheduler is used to return a new system scheduler instance
@@ -37,6 +41,7 @@ func NewCoreScheduler(srv *Server, snap *state.StateSnapshot) scheduler.Schedule
 
 // Process is used to implement the scheduler.Scheduler interface
 func (c *CoreScheduler) Process(eval *structs.Evaluation) error {
+	c.leaderACL = eval.LeaderACL
 	switch eval.JobID {
 	case structs.CoreJobEvalGC:
 		return c.evalGC(eval)
@@ -46,28 +51,52 @@ func (c *CoreScheduler) Process(eval *structs.Evaluation) error {
 		return c.jobGC(eval)
 	case structs.CoreJobDeploymentGC:
 		return c.deploymentGC(eval)
-	case structs.CoreJobForceGC:
-		return c.forceGC(eval)
+	case structs.CoreJobExpiredACLTokenGC:
+		return c.expiredACLTokenGC(eval)
 	default:
+		if forced, _ := structs.ParseForceGCJobID(eval.JobID); forced {
+			return c.forceGC(eval)
+		}
 		return fmt.Errorf("core scheduler cannot handle job '%s'", eval.JobID)
 	}
 }
 
-// forceGC is used to garbage collect all eligible objects.
+// forceGC is used to garbage collect all eligible objects, or only those of
+// the scopes the GC was limited to.
 func (c *CoreScheduler) forceGC(eval *structs.Evaluation) error {
-	if err := c.jobGC(eval); err != nil {
-		return err
-	}
-	if err := c.evalGC(eval); err != nil {
-		return err
-	}
-	if err := c.deploymentGC(eval); err != nil {
-		return err
+	_, scopes := structs.ParseForceGCJobID(eval.JobID)
+	if scopes == nil {
+		scopes = structs.GCScopes
+	}
+
+	// The scopes are ordered so that node GC occurs after the others to
+	// ensure the allocations are cleared.
+	for _, scope := range scopes {
+		var err error
+		switch scope {
+		case structs.GCScopeJobs:
+			err = c.jobGC(eval)
+		case structs.GCScopeEvals:
+			err = c.evalGC(eval)
+		case structs.GCScopeDeployments:
+			err = c.deploymentGC(eval)
+		case structs.GCScopeNodes:
+			err = c.nodeGC(eval)
+		default:
+			err = fmt.Errorf("unknown garbage collection scope %q", scope)
+		}
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// Node GC must occur after the others to ensure the allocations are
-	// cleared.
-	return c.nodeGC(eval)
+// isForcedGC returns whether the core job evaluation is a forced garbage
+// collection.
+func isForcedGC(eval *structs.Evaluation) bool {
+	forced, _ := structs.ParseForceGCJobID(eval.JobID)
+	return forced
 }
 
 // jobGC is used to garbage collect eligible jobs.
@@ -80,7 +109,7 @@ func (c *CoreScheduler) jobGC(eval *structs.Evaluation) error {
 	}
 
 	var oldThreshold uint64
-	if eval.JobID == structs.CoreJobForceGC {
+	if isForcedGC(eval) {
 		// The GC was forced, so set the threshold to its maximum so everything
 		// will GC.
 		oldThreshold = math.MaxUint64
@@ -95,6 +124,8 @@ func (c *CoreScheduler) jobGC(eval *structs.Evaluation) error {
 	}
 
 	// Collect the allocations, evaluations and jobs to GC
+	forced := isForcedGC(eval)
+	jobGC := newGCConfigCache()
 	var gcAlloc, gcEval, gcJob []string
 
 OUTER:
@@ -113,10 +144,21 @@ OUTER:
 			continue
 		}
 
+		// Apply any thresholds overridden by the job or its namespace
+		evalThreshold, allocThreshold := oldThreshold, oldThreshold
+		cfg, err := c.jobGCConfig(job.ID, jobGC)
+		if err != nil {
+			return err
+		}
+		if cfg != nil {
+			evalThreshold = c.overrideThreshold(forced, cfg.EvalThreshold, oldThreshold)
+			allocThreshold = c.overrideThreshold(forced, cfg.AllocThreshold, evalThreshold)
+		}
+
 		allEvalsGC := true
 		var jobAlloc, jobEval []string
 		for _, eval := range evals {
-			gc, allocs, err := c.gcEval(eval, oldThreshold, true)
+			gc, allocs, err := c.gcEval(eval, evalThreshold, allocThreshold, true)
 			if err != nil {
 				continue OUTER
 			}
@@ -156,7 +198,8 @@ OUTER:
 			JobID: job,
 			Purge: true,
 			WriteRequest: structs.WriteRequest{
-				Region: c.srv.config.Region,
+				Region:    c.srv.config.Region,
+				AuthToken: c.leaderACL,
 			},
 		}
 		var resp structs.JobDeregisterResponse
@@ -179,7 +222,7 @@ func (c *CoreScheduler) evalGC(eval *structs.Evaluation) error {
 	}
 
 	var oldThreshold uint64
-	if eval.JobID == structs.CoreJobForceGC {
+	if isForcedGC(eval) {
 		// The GC was forced, so set the threshold to its maximum so everything
 		// will GC.
 		oldThreshold = math.MaxUint64
@@ -196,13 +239,26 @@ func (c *CoreScheduler) evalGC(eval *structs.Evaluation) error {
 	}
 
 	// Collect the allocations and evaluations to GC
+	forced := isForcedGC(eval)
+	jobGC := newGCConfigCache()
 	var gcAlloc, gcEval []string
 	for raw := iter.Next(); raw != nil; raw = iter.Next() {
 		eval := raw.(*structs.Evaluation)
 
+		// Apply any thresholds overridden by the job or its namespace
+		evalThreshold, allocThreshold := oldThreshold, oldThreshold
+		cfg, err := c.jobGCConfig(eval.JobID, jobGC)
+		if err != nil {
+			return err
+		}
+		if cfg != nil {
+			evalThreshold = c.overrideThreshold(forced, cfg.EvalThreshold, oldThreshold)
+			allocThreshold = c.overrideThreshold(forced, cfg.AllocThreshold, evalThreshold)
+		}
+
 		// The Evaluation GC should not handle batch jobs since those need to be
 		// garbage collected in one shot
-		gc, allocs, err := c.gcEval(eval, oldThreshold, false)
+		gc, allocs, err := c.gcEval(eval, evalThreshold, allocThreshold, false)
 		if err != nil {
 			return err
 		}
@@ -223,12 +279,12 @@ func (c *CoreScheduler) evalGC(eval *structs.Evaluation) error {
 	return c.evalReap(gcEval, gcAlloc)
 }
 
-// gcEval returns whether the eval should be garbage collected given a raft
-// threshold index. The eval disqualifies for garbage collection if it or its
-// allocs are not older than the threshold. If the eval should be garbage
-// collected, the associated alloc ids that should also be removed are also
-// returned
-func (c *CoreScheduler) gcEval(eval *structs.Evaluation, thresholdIndex uint64, allowBatch bool) (
+// gcEval returns whether the eval should be garbage collected given raft
+// threshold indexes for the eval and its allocations. The eval disqualifies for
+// garbage collection if it is not older than its threshold or its allocs are
+// not older than theirs. The ids of allocs that are eligible for garbage
+// collection are also returned.
+func (c *CoreScheduler) gcEval(eval *structs.Evaluation, thresholdIndex, allocThresholdIndex uint64, allowBatch bool) (
 	bool, []string, error) {
 	// Ignore non-terminal and new evaluations
 	if !eval.TerminalStatus() || eval.ModifyIndex > thresholdIndex {
@@ -283,7 +339,7 @@ func (c *CoreScheduler) gcEval(eval *structs.Evaluation, thresholdIndex uint64,
 	gcEval := true
 	var gcAllocIDs []string
 	for _, alloc := range allocs {
-		if !alloc.TerminalStatus() || alloc.ModifyIndex > thresholdIndex {
+		if !alloc.TerminalStatus() || alloc.ModifyIndex > allocThresholdIndex {
 			// Can't GC the evaluation since not all of the allocations are
 			// terminal
 			gcEval = false
@@ -296,6 +352,69 @@ func (c *CoreScheduler) gcEval(eval *structs.Evaluation, thresholdIndex uint64,
 	return gcEval, gcAllocIDs, nil
 }
 
+// gcConfigCache caches the garbage collection overrides of jobs and
+// namespaces looked up during a garbage collection.
+type gcConfigCache struct {
+	jobs       map[string]*structs.JobGCConfig
+	namespaces map[string]*structs.JobGCConfig
+}
+
+func newGCConfigCache() *gcConfigCache {
+	return &gcConfigCache{
+		jobs:       make(map[string]*structs.JobGCConfig),
+		namespaces: make(map[string]*structs.JobGCConfig),
+	}
+}
+
+// jobGCConfig returns the garbage collection overrides of the given job,
+// caching the lookup. The thresholds the job doesn't override are those of its
+// namespace. Nil is returned if the job doesn't exist or neither it nor its
+// namespace overrides the server's thresholds.
+func (c *CoreScheduler) jobGCConfig(jobID string, cache *gcConfigCache) (*structs.JobGCConfig, error) {
+	if cfg, ok := cache.jobs[jobID]; ok {
+		return cfg, nil
+	}
+
+	ws := memdb.NewWatchSet()
+	job, err := c.snap.JobByID(ws, jobID)
+	if err != nil {
+		c.srv.logger.Printf("[ERR] sched.core: failed to get job %s: %v", jobID, err)
+		return nil, err
+	}
+
+	var cfg *structs.JobGCConfig
+	if job != nil {
+		nsCfg, ok := cache.namespaces[job.Namespace]
+		if !ok {
+			ns, err := c.snap.NamespaceByName(ws, job.Namespace)
+			if err != nil {
+				c.srv.logger.Printf("[ERR] sched.core: failed to get namespace %s: %v", job.Namespace, err)
+				return nil, err
+			}
+			if ns != nil {
+				nsCfg = ns.GC
+			}
+			cache.namespaces[job.Namespace] = nsCfg
+		}
+		cfg = job.GC.Merge(nsCfg)
+	}
+	cache.jobs[jobID] = cfg
+	return cfg, nil
+}
+
+// overrideThreshold returns the raft threshold index to use for an object
+// whose job overrides the GC threshold. If the GC was forced or the override
+// is unset, the default index is returned.
+func (c *CoreScheduler) overrideThreshold(forced bool, override time.Duration, defaultIndex uint64) uint64 {
+	if forced || override <= 0 {
+		return defaultIndex
+	}
+
+	tt := c.srv.fsm.TimeTable()
+	cutoff := time.Now().UTC().Add(-1 * override)
+	return tt.NearestIndex(cutoff)
+}
+
 // evalReap contacts the leader and issues a reap on the passed evals and
 // allocs.
 func (c *CoreScheduler) evalReap(evals, allocs []string) error {
@@ -320,7 +439,8 @@ func (c *CoreScheduler) partitionEvalReap(evals, allocs []string) []*structs.Eva
 	for submittedEvals != len(evals) || submittedAllocs != len(allocs) {
 		req := &structs.EvalDeleteRequest{
 			WriteRequest: structs.WriteRequest{
-				Region: c.srv.config.Region,
+				Region:    c.srv.config.Region,
+				AuthToken: c.leaderACL,
 			},
 		}
 		requests = append(requests, req)
@@ -366,7 +486,7 @@ func (c *CoreScheduler) nodeGC(eval *structs.Evaluation) error {
 	}
 
 	var oldThreshold uint64
-	if eval.JobID == structs.CoreJobForceGC {
+	if isForcedGC(eval) {
 		// The GC was forced, so set the threshold to its maximum so everything
 		// will GC.
 		oldThreshold = math.MaxUint64
@@ -431,7 +551,8 @@ OUTER:
 		req := structs.NodeDeregisterRequest{
 			NodeID: nodeID,
 			WriteRequest: structs.WriteRequest{
-				Region: c.srv.config.Region,
+				Region:    c.srv.config.Region,
+				AuthToken: c.leaderACL,
 			},
 		}
 		var resp structs.NodeUpdateResponse
@@ -453,7 +574,7 @@ func (c *CoreScheduler) deploymentGC(eval *structs.Evaluation) error {
 	}
 
 	var oldThreshold uint64
-	if eval.JobID == structs.CoreJobForceGC {
+	if isForcedGC(eval) {
 		// The GC was forced, so set the threshold to its maximum so everything
 		// will GC.
 		oldThreshold = math.MaxUint64
@@ -470,6 +591,8 @@ func (c *CoreScheduler) deploymentGC(eval *structs.Evaluation) error {
 	}
 
 	// Collect the deployments to GC
+	forced := isForcedGC(eval)
+	jobGC := newGCConfigCache()
 	var gcDeployment []string
 
 OUTER:
@@ -480,8 +603,18 @@ OUTER:
 		}
 		deploy := raw.(*structs.Deployment)
 
+		// Apply any threshold overridden by the job or its namespace
+		threshold := oldThreshold
+		cfg, err := c.jobGCConfig(deploy.JobID, jobGC)
+		if err != nil {
+			return err
+		}
+		if cfg != nil {
+			threshold = c.overrideThreshold(forced, cfg.DeploymentThreshold, oldThreshold)
+		}
+
 		// Ignore non-terminal and new deployments
-		if deploy.Active() || deploy.ModifyIndex > oldThreshold {
+		if deploy.Active() || deploy.ModifyIndex > threshold {
 			continue
 		}
 
@@ -536,7 +669,8 @@ func (c *CoreScheduler) partitionDeploymentReap(deployments []string) []*structs
 	for submittedDeployments != len(deployments) {
 		req := &structs.DeploymentDeleteRequest{
 			WriteRequest: structs.WriteRequest{
-				Region: c.srv.config.Region,
+				Region:    c.srv.config.Region,
+				AuthToken: c.leaderACL,
 			},
 		}
 		requests = append(requests, req)
@@ -555,3 +689,54 @@ func (c *CoreScheduler) partitionDeploymentReap(deployments []string) []*structs
 
 	return requests
 }
+
+// expiredACLTokenGC is used to garbage collect the expired ACL tokens. The
+// global tokens are only collected in the authoritative region, the other
+// regions replicate their deletion.
+func (c *CoreScheduler) expiredACLTokenGC(eval *structs.Evaluation) error {
+	ws := memdb.NewWatchSet()
+	iter, err := c.snap.ACLTokensByExpiring(ws)
+	if err != nil {
+		return err
+	}
+
+	authoritative := c.srv.config.Region == c.srv.config.AuthoritativeRegion
+	now := time.Now()
+	var expired []string
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		token := raw.(*structs.ACLToken)
+		if !token.IsExpired(now) || (token.Global && !authoritative) {
+			continue
+		}
+		expired = append(expired, token.AccessorID)
+	}
+
+	// Fast-path the nothing case
+	if len(expired) == 0 {
+		return nil
+	}
+	c.srv.logger.Printf("[DEBUG] sched.core: expired ACL token GC: %d tokens eligible", len(expired))
+
+	// Call to the leader to delete the tokens, in batches to bound the size
+	// of the Raft transactions
+	for start := 0; start < len(expired); start += maxIdsPerReap {
+		end := start + maxIdsPerReap
+		if end > len(expired) {
+			end = len(expired)
+		}
+		req := structs.ACLTokenDeleteRequest{
+			AccessorIDs: expired[start:end],
+			WriteRequest: structs.WriteRequest{
+				Region:    c.srv.config.Region,
+				AuthToken: c.leaderACL,
+			},
+		}
+		var resp structs.GenericResponse
+		if err := c.srv.RPC("ACL.DeleteTokens", &req, &resp); err != nil {
+			c.srv.logger.Printf("[ERR] sched.core: expired ACL token GC failed: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -646,6 +646,65 @@ func TestCoreScheduler_NodeGC_Force(t *testing.T) {
 	}
 }
 
+func TestCoreScheduler_ForceGC_Scoped(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// COMPAT Remove in 0.6: Reset the FSM time table since we reconcile which sets index 0
+	s1.fsm.timetable.table = make([]TimeTableEntry, 1, 10)
+
+	// Insert a "dead" node and a stopped job that can both be GC'd
+	state := s1.fsm.State()
+	node := mock.Node()
+	node.Status = structs.NodeStatusDown
+	if err := state.UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.Stop = true
+	if err := state.UpsertJob(1001, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a core scheduler
+	snap, err := state.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	core := NewCoreScheduler(s1, snap)
+
+	// Attempt a GC limited to nodes
+	jobID, err := structs.ForceGCJobID([]string{structs.GCScopeNodes})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := core.Process(s1.coreJobEval(jobID, 1001)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The node should be gone but not the job
+	ws := memdb.NewWatchSet()
+	outNode, err := state.NodeByID(ws, node.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if outNode != nil {
+		t.Fatalf("node should have been GC'd: %v", outNode)
+	}
+
+	outJob, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if outJob == nil {
+		t.Fatalf("job should not have been GC'd")
+	}
+}
+
 func TestCoreScheduler_JobGC_OutstandingEvals(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
@@ -1117,6 +1176,130 @@ func TestCoreScheduler_JobGC_Stopped(t *testing.T) {
 	}
 }
 
+func TestCoreScheduler_JobGC_JobThreshold(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// COMPAT Remove in 0.6: Reset the FSM time table since we reconcile which sets index 0
+	s1.fsm.timetable.table = make([]TimeTableEntry, 1, 10)
+
+	// Insert a batch job that retains its evaluations for longer than the
+	// default and one that doesn't
+	state := s1.fsm.State()
+	job1, job2 := mock.Job(), mock.Job()
+	job1.Type = structs.JobTypeBatch
+	job1.Stop = true
+	job1.GC = &structs.JobGCConfig{
+		EvalThreshold: 48 * time.Hour,
+	}
+	job2.Type = structs.JobTypeBatch
+	job2.Stop = true
+	assert.Nil(state.UpsertJob(1000, job1), "UpsertJob")
+	assert.Nil(state.UpsertJob(1001, job2), "UpsertJob")
+
+	// Insert a complete eval for each job
+	eval1, eval2 := mock.Eval(), mock.Eval()
+	eval1.JobID = job1.ID
+	eval1.Type = structs.JobTypeBatch
+	eval1.Status = structs.EvalStatusComplete
+	eval2.JobID = job2.ID
+	eval2.Type = structs.JobTypeBatch
+	eval2.Status = structs.EvalStatusComplete
+	assert.Nil(state.UpsertEvals(1002, []*structs.Evaluation{eval1, eval2}), "UpsertEvals")
+
+	// Update the time tables to make this work
+	tt := s1.fsm.TimeTable()
+	tt.Witness(2000, time.Now().UTC().Add(-1*s1.config.JobGCThreshold))
+
+	// Create a core scheduler
+	snap, err := state.Snapshot()
+	assert.Nil(err, "Snapshot")
+	core := NewCoreScheduler(s1, snap)
+
+	// Attempt the GC
+	gc := s1.coreJobEval(structs.CoreJobJobGC, 2000)
+	assert.Nil(core.Process(gc), "Process GC")
+
+	// Only the job using the default threshold should be gone
+	ws := memdb.NewWatchSet()
+	out, err := state.JobByID(ws, job1.ID)
+	assert.Nil(err, "JobByID")
+	assert.NotNil(out, "Job With Job Threshold")
+	outE, err := state.EvalByID(ws, eval1.ID)
+	assert.Nil(err, "EvalByID")
+	assert.NotNil(outE, "Eval With Job Threshold")
+	out2, err := state.JobByID(ws, job2.ID)
+	assert.Nil(err, "JobByID")
+	assert.Nil(out2, "Job With Default Threshold")
+	outE2, err := state.EvalByID(ws, eval2.ID)
+	assert.Nil(err, "EvalByID")
+	assert.Nil(outE2, "Eval With Default Threshold")
+}
+
+func TestCoreScheduler_JobGC_NamespaceThreshold(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// COMPAT Remove in 0.6: Reset the FSM time table since we reconcile which sets index 0
+	s1.fsm.timetable.table = make([]TimeTableEntry, 1, 10)
+
+	// Insert a namespace that retains evaluations for longer than the default
+	state := s1.fsm.State()
+	ns := mock.Namespace()
+	ns.GC = &structs.JobGCConfig{
+		EvalThreshold: 48 * time.Hour,
+	}
+	assert.Nil(state.UpsertNamespaces(999, []*structs.Namespace{ns}), "UpsertNamespaces")
+
+	// Insert a job using the namespace's threshold and one overriding it
+	job1, job2 := mock.Job(), mock.Job()
+	job1.Namespace = ns.Name
+	job1.Stop = true
+	job2.Namespace = ns.Name
+	job2.Stop = true
+	job2.GC = &structs.JobGCConfig{
+		EvalThreshold: time.Hour,
+	}
+	assert.Nil(state.UpsertJob(1000, job1), "UpsertJob")
+	assert.Nil(state.UpsertJob(1001, job2), "UpsertJob")
+
+	// Insert a complete eval for each job
+	eval1, eval2 := mock.Eval(), mock.Eval()
+	eval1.JobID = job1.ID
+	eval1.Status = structs.EvalStatusComplete
+	eval2.JobID = job2.ID
+	eval2.Status = structs.EvalStatusComplete
+	assert.Nil(state.UpsertEvals(1002, []*structs.Evaluation{eval1, eval2}), "UpsertEvals")
+
+	// Update the time tables to make this work
+	tt := s1.fsm.TimeTable()
+	tt.Witness(2000, time.Now().UTC().Add(-1*s1.config.JobGCThreshold))
+
+	// Create a core scheduler
+	snap, err := state.Snapshot()
+	assert.Nil(err, "Snapshot")
+	core := NewCoreScheduler(s1, snap)
+
+	// Attempt the GC
+	gc := s1.coreJobEval(structs.CoreJobJobGC, 2000)
+	assert.Nil(core.Process(gc), "Process GC")
+
+	// Only the job overriding the namespace's threshold should be gone
+	ws := memdb.NewWatchSet()
+	out, err := state.JobByID(ws, job1.ID)
+	assert.Nil(err, "JobByID")
+	assert.NotNil(out, "Job With Namespace Threshold")
+	out2, err := state.JobByID(ws, job2.ID)
+	assert.Nil(err, "JobByID")
+	assert.Nil(out2, "Job With Job Threshold")
+}
+
 func TestCoreScheduler_JobGC_Force(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
@@ -1382,6 +1565,55 @@ func TestCoreScheduler_DeploymentGC(t *testing.T) {
 	assert.NotNil(out3, "Terminal Deployment With Allocs")
 }
 
+func TestCoreScheduler_DeploymentGC_JobThreshold(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// COMPAT Remove in 0.6: Reset the FSM time table since we reconcile which sets index 0
+	s1.fsm.timetable.table = make([]TimeTableEntry, 1, 10)
+
+	// Insert a job that retains its deployments for longer than the default
+	state := s1.fsm.State()
+	job := mock.Job()
+	job.GC = &structs.JobGCConfig{
+		DeploymentThreshold: 48 * time.Hour,
+	}
+	assert.Nil(state.UpsertJob(999, job), "UpsertJob")
+
+	// Insert a terminal deployment for the job and one for an unknown job
+	d1, d2 := mock.Deployment(), mock.Deployment()
+	d1.JobID = job.ID
+	d1.Status = structs.DeploymentStatusFailed
+	d2.Status = structs.DeploymentStatusFailed
+	assert.Nil(state.UpsertDeployment(1000, d1), "UpsertDeployment")
+	assert.Nil(state.UpsertDeployment(1001, d2), "UpsertDeployment")
+
+	// Update the time tables to make this work
+	tt := s1.fsm.TimeTable()
+	tt.Witness(2000, time.Now().UTC().Add(-1*s1.config.DeploymentGCThreshold))
+
+	// Create a core scheduler
+	snap, err := state.Snapshot()
+	assert.Nil(err, "Snapshot")
+	core := NewCoreScheduler(s1, snap)
+
+	// Attempt the GC
+	gc := s1.coreJobEval(structs.CoreJobDeploymentGC, 2000)
+	assert.Nil(core.Process(gc), "Process GC")
+
+	// Only the deployment using the default threshold should be gone
+	ws := memdb.NewWatchSet()
+	out, err := state.DeploymentByID(ws, d1.ID)
+	assert.Nil(err, "DeploymentByID")
+	assert.NotNil(out, "Deployment With Job Threshold")
+	out2, err := state.DeploymentByID(ws, d2.ID)
+	assert.Nil(err, "DeploymentByID")
+	assert.Nil(out2, "Deployment With Default Threshold")
+}
+
 func TestCoreScheduler_DeploymentGC_Force(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
@@ -1418,6 +1650,50 @@ func TestCoreScheduler_DeploymentGC_Force(t *testing.T) {
 	assert.NotNil(out2, "Active Deployment")
 }
 
+func TestCoreScheduler_ExpiredACLTokenGC(t *testing.T) {
+	t.Parallel()
+	s1, _ := testACLServer(t, func(c *Config) {
+		c.Region = "region1"
+		c.AuthoritativeRegion = "region2"
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// Insert an expired, an expired global and an unexpired token
+	state := s1.fsm.State()
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	expired, expiredGlobal, unexpired := mock.ACLToken(), mock.ACLToken(), mock.ACLToken()
+	expired.ExpirationTime = &past
+	expiredGlobal.ExpirationTime = &past
+	expiredGlobal.Global = true
+	unexpired.ExpirationTime = &future
+	tokens := []*structs.ACLToken{expired, expiredGlobal, unexpired}
+	assert.Nil(state.UpsertACLTokens(1000, tokens), "UpsertACLTokens")
+
+	// Create a core scheduler
+	snap, err := state.Snapshot()
+	assert.Nil(err, "Snapshot")
+	core := NewCoreScheduler(s1, snap)
+
+	// Attempt the GC
+	gc := s1.coreJobEval(structs.CoreJobExpiredACLTokenGC, 2000)
+	assert.Nil(core.Process(gc), "Process GC")
+
+	// Only the expired local token is gone, the global ones are collected by
+	// the authoritative region
+	out, err := state.ACLTokenByAccessorID(nil, expired.AccessorID)
+	assert.Nil(err, "ACLTokenByAccessorID")
+	assert.Nil(out, "Expired Token")
+	out, err = state.ACLTokenByAccessorID(nil, expiredGlobal.AccessorID)
+	assert.Nil(err, "ACLTokenByAccessorID")
+	assert.NotNil(out, "Expired Global Token")
+	out, err = state.ACLTokenByAccessorID(nil, unexpired.AccessorID)
+	assert.Nil(err, "ACLTokenByAccessorID")
+	assert.NotNil(out, "Unexpired Token")
+}
+
 func TestCoreScheduler_PartitionEvalReap(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
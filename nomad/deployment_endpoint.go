@@ -23,6 +23,11 @@ func (d *Deployment) GetDeployment(args *structs.DeploymentSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "deployment", "get_deployment"}, time.Now())
 
+	aclObj, err := d.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
@@ -38,6 +43,15 @@ func (d *Deployment) GetDeployment(args *structs.DeploymentSpecificRequest,
 			if err != nil {
 				return err
 			}
+			if out != nil {
+				ns, err := jobNamespace(ws, state, out.JobID)
+				if err != nil {
+					return err
+				}
+				if !aclObj.AllowNamespaceRead(ns) {
+					return structs.ErrPermissionDenied
+				}
+			}
 
 			// Setup the output
 			reply.Deployment = out
@@ -85,6 +99,9 @@ func (d *Deployment) Fail(args *structs.DeploymentFailRequest, reply *structs.De
 	if deploy == nil {
 		return fmt.Errorf("deployment not found")
 	}
+	if err := d.checkDeploymentWrite(args.AuthToken, &snap.StateStore, deploy); err != nil {
+		return err
+	}
 
 	if !deploy.Active() {
 		return fmt.Errorf("can't fail terminal deployment")
@@ -120,6 +137,9 @@ func (d *Deployment) Pause(args *structs.DeploymentPauseRequest, reply *structs.
 	if deploy == nil {
 		return fmt.Errorf("deployment not found")
 	}
+	if err := d.checkDeploymentWrite(args.AuthToken, &snap.StateStore, deploy); err != nil {
+		return err
+	}
 
 	if !deploy.Active() {
 		if args.Pause {
@@ -159,6 +179,9 @@ func (d *Deployment) Promote(args *structs.DeploymentPromoteRequest, reply *stru
 	if deploy == nil {
 		return fmt.Errorf("deployment not found")
 	}
+	if err := d.checkDeploymentWrite(args.AuthToken, &snap.StateStore, deploy); err != nil {
+		return err
+	}
 
 	if !deploy.Active() {
 		return fmt.Errorf("can't promote terminal deployment")
@@ -199,6 +222,9 @@ func (d *Deployment) SetAllocHealth(args *structs.DeploymentAllocHealthRequest,
 	if deploy == nil {
 		return fmt.Errorf("deployment not found")
 	}
+	if err := d.checkDeploymentWrite(args.AuthToken, &snap.StateStore, deploy); err != nil {
+		return err
+	}
 
 	if !deploy.Active() {
 		return fmt.Errorf("can't set health of allocations for a terminal deployment")
@@ -215,33 +241,42 @@ func (d *Deployment) List(args *structs.DeploymentListRequest, reply *structs.De
 	}
 	defer metrics.MeasureSince([]string{"nomad", "deployment", "list"}, time.Now())
 
+	aclObj, err := d.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
 			// Capture all the deployments
-			var err error
-			var iter memdb.ResultIterator
-			if prefix := args.QueryOptions.Prefix; prefix != "" {
-				iter, err = state.DeploymentsByIDPrefix(ws, prefix)
-			} else {
-				iter, err = state.Deployments(ws)
-			}
+			iter, err := state.PrefixFrom(ws, "deployment", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
 			if err != nil {
 				return err
 			}
+			iter = newFilterIterator(iter, func(raw interface{}) bool {
+				ns, err := jobNamespace(nil, state, raw.(*structs.Deployment).JobID)
+				return err == nil && aclObj.AllowNamespaceRead(ns)
+			})
 
 			var deploys []*structs.Deployment
-			for {
-				raw := iter.Next()
-				if raw == nil {
-					break
-				}
-				deploy := raw.(*structs.Deployment)
-				deploys = append(deploys, deploy)
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.Deployment).ID
+			}, nil)
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				deploys = append(deploys, obj.(*structs.Deployment))
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 			reply.Deployments = deploys
+			reply.NextToken = nextToken
 
 			// Use the last index that affected the jobs table
 			index, err := state.Index("deployment")
@@ -264,6 +299,11 @@ func (d *Deployment) Allocations(args *structs.DeploymentSpecificRequest, reply
 	}
 	defer metrics.MeasureSince([]string{"nomad", "deployment", "allocations"}, time.Now())
 
+	aclObj, err := d.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
@@ -277,6 +317,9 @@ func (d *Deployment) Allocations(args *structs.DeploymentSpecificRequest, reply
 
 			stubs := make([]*structs.AllocListStub, 0, len(allocs))
 			for _, alloc := range allocs {
+				if !aclObj.AllowNamespaceRead(allocNamespace(alloc)) {
+					return structs.ErrPermissionDenied
+				}
 				stubs = append(stubs, alloc.Stub())
 			}
 			reply.Allocations = stubs
@@ -303,6 +346,13 @@ func (d *Deployment) Reap(args *structs.DeploymentDeleteRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "deployment", "reap"}, time.Now())
 
+	// Reaping is done by the core jobs with the leader's token
+	if aclObj, err := d.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Update via Raft
 	_, index, err := d.srv.raftApply(structs.DeploymentDeleteRequestType, args)
 	if err != nil {
@@ -313,3 +363,13 @@ func (d *Deployment) Reap(args *structs.DeploymentDeleteRequest,
 	reply.Index = index
 	return nil
 }
+
+// checkDeploymentWrite returns an error unless the token is allowed to write
+// the namespace of the deployment
+func (d *Deployment) checkDeploymentWrite(secretID string, state *state.StateStore, deploy *structs.Deployment) error {
+	ns, err := jobNamespace(nil, state, deploy.JobID)
+	if err != nil {
+		return err
+	}
+	return d.srv.checkNamespaceWrite(secretID, ns)
+}
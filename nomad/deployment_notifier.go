@@ -0,0 +1,303 @@
+package nomad
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+const (
+	// deploymentNotifyAttempts is the number of times a webhook is called
+	// before the event is dropped, waiting deploymentNotifyRetryInterval
+	// times the number of failed attempts in between.
+	deploymentNotifyAttempts      = 3
+	deploymentNotifyRetryInterval = 5 * time.Second
+)
+
+// deploymentNotifier calls the configured webhooks when deployments
+// transition. It runs on the leader.
+type deploymentNotifier struct {
+	region   string
+	logger   *log.Logger
+	client   *http.Client
+	webhooks []*config.DeploymentWebhookConfig
+
+	// notified records through Raft that the webhooks were notified of the
+	// events of the deployment.
+	notified func(deploymentID string, events []string) error
+
+	// inflight is the events of the deployments being notified, so that they
+	// aren't notified twice before being recorded.
+	inflight map[string][]string
+	l        sync.Mutex
+
+	stopCh chan struct{}
+}
+
+// notifyDeployments calls the deployment webhooks until the stop channel is
+// closed. The events notified are recorded in the deployments so that a new
+// leader only sends the events that weren't sent yet.
+func (s *Server) notifyDeployments(stopCh chan struct{}) {
+	n := &deploymentNotifier{
+		region:   s.config.Region,
+		logger:   s.logger,
+		client:   cleanhttp.DefaultClient(),
+		webhooks: s.config.DeploymentWebhooks,
+		inflight: make(map[string][]string),
+		stopCh:   stopCh,
+		notified: func(deploymentID string, events []string) error {
+			req := structs.DeploymentNotifiedRequest{
+				DeploymentID: deploymentID,
+				Events:       events,
+				WriteRequest: structs.WriteRequest{Region: s.config.Region},
+			}
+			_, _, err := s.raftApply(structs.DeploymentNotifiedRequestType, &req)
+			return err
+		},
+	}
+
+	for {
+		state := s.fsm.State()
+		ws := memdb.NewWatchSet()
+		ws.Add(state.AbandonCh())
+		if err := n.scan(ws, state); err != nil {
+			s.logger.Printf("[ERR] nomad.deployment_notifier: failed to look for deployment transitions: %v", err)
+			select {
+			case <-time.After(deploymentNotifyRetryInterval):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		// The stop channel is watched along with the deployments
+		ws.Add(stopCh)
+		ws.Watch(nil)
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+	}
+}
+
+// scan notifies the events of the deployments that weren't notified and
+// aren't already being notified.
+func (n *deploymentNotifier) scan(ws memdb.WatchSet, state *state.StateStore) error {
+	iter, err := state.Deployments(ws)
+	if err != nil {
+		return err
+	}
+
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		d := raw.(*structs.Deployment)
+		pending := n.pending(d)
+		if len(pending) == 0 {
+			continue
+		}
+
+		events := make([]*structs.DeploymentEvent, 0, len(pending))
+		for _, event := range pending {
+			events = append(events, n.event(event, d))
+		}
+		go n.notify(d.ID, events)
+	}
+	return nil
+}
+
+// pending returns the events of the deployment that weren't notified and
+// marks them as being notified.
+func (n *deploymentNotifier) pending(d *structs.Deployment) []string {
+	n.l.Lock()
+	defer n.l.Unlock()
+
+	var pending []string
+	for _, event := range deploymentEvents(d) {
+		if helper.SliceStringContains(d.NotifiedEvents, event) ||
+			helper.SliceStringContains(n.inflight[d.ID], event) {
+			continue
+		}
+		pending = append(pending, event)
+	}
+	if len(pending) != 0 {
+		n.inflight[d.ID] = append(n.inflight[d.ID], pending...)
+	}
+	return pending
+}
+
+// done removes the events of the deployment from the events being notified.
+func (n *deploymentNotifier) done(deploymentID string, events []string) {
+	n.l.Lock()
+	defer n.l.Unlock()
+
+	var inflight []string
+	for _, event := range n.inflight[deploymentID] {
+		if !helper.SliceStringContains(events, event) {
+			inflight = append(inflight, event)
+		}
+	}
+	if len(inflight) == 0 {
+		delete(n.inflight, deploymentID)
+	} else {
+		n.inflight[deploymentID] = inflight
+	}
+}
+
+// deploymentEvents returns the events the deployment went through given its
+// current state, in order. Once reached, the events are recorded as notified
+// so a deployment that goes back to an earlier state isn't notified again.
+func deploymentEvents(d *structs.Deployment) []string {
+	events := []string{config.DeploymentEventStarted}
+
+	canaries, healthy, promoted := false, true, true
+	for _, tg := range d.TaskGroups {
+		if tg.DesiredCanaries == 0 {
+			continue
+		}
+		canaries = true
+		if !tg.Promoted {
+			promoted = false
+			if tg.HealthyAllocs < tg.DesiredCanaries {
+				healthy = false
+			}
+		}
+	}
+	if canaries && healthy {
+		events = append(events, config.DeploymentEventCanariesHealthy)
+	}
+	if canaries && promoted {
+		events = append(events, config.DeploymentEventPromoted)
+	}
+
+	if d.Status == structs.DeploymentStatusFailed {
+		events = append(events, config.DeploymentEventFailed)
+		if d.RevertedJobVersion != nil {
+			events = append(events, config.DeploymentEventReverted)
+		}
+	}
+	return events
+}
+
+// event returns the event of the deployment transition.
+func (n *deploymentNotifier) event(event string, d *structs.Deployment) *structs.DeploymentEvent {
+	e := &structs.DeploymentEvent{
+		Event:             event,
+		Region:            n.region,
+		DeploymentID:      d.ID,
+		JobID:             d.JobID,
+		JobVersion:        d.JobVersion,
+		Status:            d.Status,
+		StatusDescription: d.StatusDescription,
+		TaskGroups:        d.TaskGroups,
+		Time:              time.Now().UTC(),
+	}
+	if d.RevertedJobVersion != nil {
+		e.RevertedJobVersion = helper.Uint64ToPtr(*d.RevertedJobVersion)
+	}
+	return e
+}
+
+// notify calls each webhook with the events of the deployment it subscribed
+// to, in order. Unless the server stopped being the leader, the events are
+// then recorded as notified so that they aren't sent again.
+func (n *deploymentNotifier) notify(deploymentID string, events []*structs.DeploymentEvent) {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, event.Event)
+	}
+	defer n.done(deploymentID, names)
+
+	var wg sync.WaitGroup
+	for _, webhook := range n.webhooks {
+		wg.Add(1)
+		go func(webhook *config.DeploymentWebhookConfig) {
+			defer wg.Done()
+			for _, event := range events {
+				if webhook.Subscribed(event.Event) && !n.send(webhook, event) {
+					return
+				}
+			}
+		}(webhook)
+	}
+	wg.Wait()
+
+	select {
+	case <-n.stopCh:
+		return
+	default:
+	}
+	if err := n.notified(deploymentID, names); err != nil {
+		n.logger.Printf("[ERR] nomad.deployment_notifier: failed to record the events of deployment %q: %v", deploymentID, err)
+	}
+}
+
+// send calls the webhook with the event, retrying until it succeeds or the
+// attempts are exhausted. It returns false if the server stopped being the
+// leader before the event was sent or dropped.
+func (n *deploymentNotifier) send(webhook *config.DeploymentWebhookConfig, event *structs.DeploymentEvent) bool {
+	body, err := renderDeploymentEvent(webhook, event)
+	if err != nil {
+		n.logger.Printf("[ERR] nomad.deployment_notifier: failed to render event %q of deployment %q for webhook %q: %v",
+			event.Event, event.DeploymentID, webhook.Name, err)
+		return true
+	}
+
+	method := webhook.Method
+	if method == "" {
+		method = structs.DefaultJobNotificationMethod
+	}
+	timeout := webhook.Timeout
+	if timeout == 0 {
+		timeout = config.DefaultDeploymentWebhookTimeout
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := callWebhook(n.client, method, webhook.Address, webhook.Headers, timeout, body)
+		if err == nil {
+			n.logger.Printf("[DEBUG] nomad.deployment_notifier: notified webhook %q of event %q of deployment %q",
+				webhook.Name, event.Event, event.DeploymentID)
+			return true
+		}
+		if attempt == deploymentNotifyAttempts {
+			n.logger.Printf("[ERR] nomad.deployment_notifier: failed to notify webhook %q of event %q of deployment %q, giving up: %v",
+				webhook.Name, event.Event, event.DeploymentID, err)
+			return true
+		}
+		n.logger.Printf("[WARN] nomad.deployment_notifier: failed to notify webhook %q of event %q of deployment %q, retrying: %v",
+			webhook.Name, event.Event, event.DeploymentID, err)
+
+		select {
+		case <-time.After(time.Duration(attempt) * deploymentNotifyRetryInterval):
+		case <-n.stopCh:
+			return false
+		}
+	}
+}
+
+// renderDeploymentEvent returns the body of the request notifying the webhook
+// of the event.
+func renderDeploymentEvent(webhook *config.DeploymentWebhookConfig, event *structs.DeploymentEvent) ([]byte, error) {
+	if webhook.Payload == "" {
+		return json.Marshal(event)
+	}
+
+	tmpl, err := webhook.Template()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,171 @@
+package nomad
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestDeploymentNotifier_Scan(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	requests := make(chan string, 10)
+	reverts := make(chan *structs.DeploymentEvent, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/reverted" {
+			var event structs.DeploymentEvent
+			if err := json.Unmarshal(body, &event); err != nil {
+				t.Errorf("err: %v", err)
+			}
+			reverts <- &event
+			return
+		}
+		requests <- r.Header.Get("X-Token") + " " + string(body)
+	}))
+	defer ts.Close()
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	var l sync.Mutex
+	index := uint64(2000)
+	notified := func(deploymentID string, events []string) error {
+		l.Lock()
+		defer l.Unlock()
+		index++
+		return state.UpdateDeploymentNotified(index, deploymentID, events)
+	}
+	webhooks := []*config.DeploymentWebhookConfig{
+		{
+			Name:    "all",
+			Address: ts.URL,
+			Headers: map[string]string{"X-Token": "secret"},
+			Payload: `{{ .Event }} {{ .DeploymentID }}`,
+		},
+		{
+			Name:    "reverted",
+			Address: ts.URL + "/reverted",
+			Events:  []string{config.DeploymentEventReverted},
+		},
+	}
+	n := &deploymentNotifier{
+		region:   "global",
+		logger:   testLogger(),
+		client:   cleanhttp.DefaultClient(),
+		webhooks: webhooks,
+		notified: notified,
+		inflight: make(map[string][]string),
+		stopCh:   stopCh,
+	}
+	scan := func(n *deploymentNotifier) {
+		if err := n.scan(memdb.NewWatchSet(), state); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// A deployment starts and its canaries become healthy
+	d := mock.Deployment()
+	d.TaskGroups["web"].DesiredCanaries = 2
+	if err := state.UpsertDeployment(1000, d); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	scan(n)
+	d = d.Copy()
+	d.TaskGroups["web"].HealthyAllocs = 2
+	if err := state.UpsertDeployment(1001, d); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	scan(n)
+
+	// The deployment fails and the job is reverted
+	if err := state.UpdateDeploymentStatus(1002, &structs.DeploymentStatusUpdateRequest{
+		DeploymentUpdate: &structs.DeploymentStatusUpdate{
+			DeploymentID:       d.ID,
+			Status:             structs.DeploymentStatusFailed,
+			StatusDescription:  structs.DeploymentStatusDescriptionRollback(structs.DeploymentStatusDescriptionFailedAllocations, 1),
+			RevertedJobVersion: helper.Uint64ToPtr(1),
+		},
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		scan(n)
+	}
+
+	// The events of a deployment are sent concurrently
+	expected := map[string]bool{
+		"secret started " + d.ID:          true,
+		"secret canaries_healthy " + d.ID: true,
+		"secret failed " + d.ID:           true,
+		"secret reverted " + d.ID:         true,
+	}
+	for len(expected) != 0 {
+		select {
+		case req := <-requests:
+			if !expected[req] {
+				t.Fatalf("unexpected notification: %q", req)
+			}
+			delete(expected, req)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("webhook not called: %v", expected)
+		}
+	}
+
+	select {
+	case event := <-reverts:
+		if event.DeploymentID != d.ID || event.RevertedJobVersion == nil || *event.RevertedJobVersion != 1 {
+			t.Fatalf("bad: %#v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reverted webhook not called")
+	}
+
+	// The events sent are recorded in the deployment
+	testutil.WaitForResult(func() (bool, error) {
+		out, err := state.DeploymentByID(nil, d.ID)
+		if err != nil {
+			return false, err
+		}
+		if len(out.NotifiedEvents) != 4 {
+			return false, fmt.Errorf("bad: %v", out.NotifiedEvents)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+
+	// A new leader doesn't send the events again
+	n2 := &deploymentNotifier{
+		region:   "global",
+		logger:   testLogger(),
+		client:   cleanhttp.DefaultClient(),
+		webhooks: webhooks,
+		notified: notified,
+		inflight: make(map[string][]string),
+		stopCh:   stopCh,
+	}
+	scan(n)
+	scan(n2)
+
+	select {
+	case req := <-requests:
+		t.Fatalf("unexpected notification: %q", req)
+	case event := <-reverts:
+		t.Fatalf("unexpected notification: %#v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
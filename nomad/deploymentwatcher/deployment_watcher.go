@@ -2,6 +2,7 @@ package deploymentwatcher
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -16,6 +17,10 @@ const (
 	// perJobEvalBatchPeriod is the batching length before creating an evaluation to
 	// trigger the scheduler when allocations are marked as healthy.
 	perJobEvalBatchPeriod = 1 * time.Second
+
+	// progressDeadlineRetryInterval is the interval after which the progress
+	// deadline is checked again when checking it failed.
+	progressDeadlineRetryInterval = 10 * time.Second
 )
 
 // deploymentTriggers are the set of functions required to trigger changes on
@@ -68,6 +73,13 @@ type deploymentWatcher struct {
 	// by holding the lock or using the setter and getter methods.
 	latestEval uint64
 
+	// progressAt is the last time the deployment made progress: when the
+	// watcher started, an allocation became healthy, or the deployment was
+	// promoted or resumed. The progress deadlines of the task groups count
+	// from it. The field should be accessed by holding the lock or using the
+	// setter and getter methods.
+	progressAt time.Time
+
 	logger *log.Logger
 	ctx    context.Context
 	exitFn context.CancelFunc
@@ -87,6 +99,7 @@ func newDeploymentWatcher(parent context.Context, queryLimiter *rate.Limiter,
 		j:                  j,
 		watchers:           watchers,
 		deploymentTriggers: triggers,
+		progressAt:         time.Now(),
 		logger:             logger,
 		ctx:                ctx,
 		exitFn:             exitFn,
@@ -136,19 +149,15 @@ func (w *deploymentWatcher) SetAllocHealth(
 				continue
 			}
 
-			var err error
-			j, err = w.latestStableJob()
+			stable, err := w.latestStableJob()
 			if err != nil {
 				return err
 			}
-
-			if j != nil {
-				desc = structs.DeploymentStatusDescriptionRollback(desc, j.Version)
-			}
+			j, desc = w.rollbackJob(stable, desc)
 			break
 		}
 
-		u = w.getDeploymentStatusUpdate(structs.DeploymentStatusFailed, desc)
+		u = w.getFailedStatusUpdate(desc, j)
 	}
 
 	// Create the request
@@ -190,6 +199,7 @@ func (w *deploymentWatcher) PromoteDeployment(
 	if err != nil {
 		return err
 	}
+	w.setProgress()
 
 	// Build the response
 	resp.EvalID = areq.Eval.ID
@@ -220,6 +230,9 @@ func (w *deploymentWatcher) PauseDeployment(
 	if err != nil {
 		return err
 	}
+	if !req.Pause {
+		w.setProgress()
+	}
 
 	// Build the response
 	if evalID != "" {
@@ -236,7 +249,7 @@ func (w *deploymentWatcher) FailDeployment(
 	req *structs.DeploymentFailRequest,
 	resp *structs.DeploymentUpdateResponse) error {
 
-	status, desc := structs.DeploymentStatusFailed, structs.DeploymentStatusDescriptionFailedByUser
+	desc := structs.DeploymentStatusDescriptionFailedByUser
 
 	// Determine if we should rollback
 	rollback := false
@@ -249,19 +262,15 @@ func (w *deploymentWatcher) FailDeployment(
 
 	var rollbackJob *structs.Job
 	if rollback {
-		var err error
-		rollbackJob, err = w.latestStableJob()
+		stable, err := w.latestStableJob()
 		if err != nil {
 			return err
 		}
-
-		if rollbackJob != nil {
-			desc = structs.DeploymentStatusDescriptionRollback(desc, rollbackJob.Version)
-		}
+		rollbackJob, desc = w.rollbackJob(stable, desc)
 	}
 
 	// Commit the change
-	update := w.getDeploymentStatusUpdate(status, desc)
+	update := w.getFailedStatusUpdate(desc, rollbackJob)
 	eval := w.getEval()
 	i, err := w.upsertDeploymentStatusUpdate(update, eval, rollbackJob)
 	if err != nil {
@@ -288,13 +297,43 @@ func (w *deploymentWatcher) StopWatch() {
 
 // watch is the long running watcher that takes actions upon allocation changes
 func (w *deploymentWatcher) watch() {
+	// The deadline timer fires when the task groups must have made progress.
+	// It is only armed if a task group has a progress deadline.
+	var deadlineTimer *time.Timer
+	var deadlineCh <-chan time.Time
+	if deadline := w.progressDeadline(); deadline != 0 {
+		deadlineTimer = time.NewTimer(deadline)
+		defer deadlineTimer.Stop()
+		deadlineCh = deadlineTimer.C
+	}
+
 	allocIndex := uint64(1)
+	allocsCh := w.getAllocsCh(allocIndex)
+	healthy := 0
 	for {
 		// Block getting all allocations that are part of the deployment using
 		// the last evaluation index. This will have us block waiting for
 		// something to change past what the scheduler has evaluated.
-		allocResp, err := w.getAllocs(allocIndex)
-		if err != nil {
+		var updates *allocUpdates
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-deadlineCh:
+			failed, next, err := w.checkProgressDeadline()
+			switch {
+			case err != nil:
+				w.logger.Printf("[ERR] nomad.deployment_watcher: failed to check the progress deadline of deployment %q: %v", w.d.ID, err)
+				deadlineTimer.Reset(progressDeadlineRetryInterval)
+			case failed:
+				return
+			case next != 0:
+				deadlineTimer.Reset(next)
+			}
+			continue
+		case updates = <-allocsCh:
+		}
+
+		if err := updates.err; err != nil {
 			if err == context.Canceled || w.ctx.Err() == context.Canceled {
 				return
 			}
@@ -302,6 +341,7 @@ func (w *deploymentWatcher) watch() {
 			w.logger.Printf("[ERR] nomad.deployment_watcher: failed to retrieve allocations for deployment %q: %v", w.d.ID, err)
 			return
 		}
+		allocResp := updates.allocs
 		allocIndex = allocResp.Index
 
 		// Get the latest evaluation index
@@ -318,7 +358,12 @@ func (w *deploymentWatcher) watch() {
 		// Create an evaluation trigger if there is any allocation whose
 		// deployment status has been updated past the latest eval index.
 		createEval, failDeployment, rollback := false, false, false
+		allocsHealthy := 0
 		for _, alloc := range allocResp.Allocations {
+			if alloc.DeploymentStatus.IsHealthy() {
+				allocsHealthy++
+			}
+
 			if alloc.DeploymentStatus == nil || alloc.DeploymentStatus.ModifyIndex <= latestEval {
 				continue
 			}
@@ -336,12 +381,13 @@ func (w *deploymentWatcher) watch() {
 				// Since we have an unhealthy allocation, fail the deployment
 				failDeployment = true
 			}
+		}
 
-			// All conditions have been hit so we can break
-			if createEval && failDeployment && rollback {
-				break
-			}
+		// The deployment made progress if more allocations are healthy
+		if allocsHealthy > healthy {
+			w.setProgress()
 		}
+		healthy = allocsHealthy
 
 		// Change the deployments status to failed
 		if failDeployment {
@@ -351,35 +397,207 @@ func (w *deploymentWatcher) watch() {
 			// Rollback to the old job if necessary
 			var j *structs.Job
 			if rollback {
-				var err error
-				j, err = w.latestStableJob()
+				stable, err := w.latestStableJob()
 				if err != nil {
 					w.logger.Printf("[ERR] nomad.deployment_watcher: failed to lookup latest stable job for %q: %v", w.d.JobID, err)
-				}
-
-				// Description should include that the job is being rolled back to
-				// version N
-				if j != nil {
-					desc = structs.DeploymentStatusDescriptionRollback(desc, j.Version)
+				} else {
+					// Description should include that the job is being rolled
+					// back to version N
+					j, desc = w.rollbackJob(stable, desc)
 				}
 			}
 
 			// Update the status of the deployment to failed and create an
 			// evaluation.
 			e := w.getEval()
-			u := w.getDeploymentStatusUpdate(structs.DeploymentStatusFailed, desc)
+			u := w.getFailedStatusUpdate(desc, j)
 			if index, err := w.upsertDeploymentStatusUpdate(u, e, j); err != nil {
 				w.logger.Printf("[ERR] nomad.deployment_watcher: failed to update deployment %q status: %v", w.d.ID, err)
 			} else {
 				w.setLatestEval(index)
 			}
-		} else if createEval {
-			// Create an eval to push the deployment along
-			w.createEvalBatched(allocResp.Index)
+		} else {
+			// Promote the deployment if its canaries became healthy, which
+			// also creates an eval
+			promoted, err := w.autoPromoteDeployment(allocResp.Allocations)
+			if err != nil {
+				w.logger.Printf("[ERR] nomad.deployment_watcher: failed to auto promote deployment %q: %v", w.d.ID, err)
+			} else if promoted {
+				w.logger.Printf("[DEBUG] nomad.deployment_watcher: auto promoted deployment %q", w.d.ID)
+			}
+
+			if !promoted && createEval {
+				// Create an eval to push the deployment along
+				w.createEvalBatched(allocResp.Index)
+			}
 		}
+
+		allocsCh = w.getAllocsCh(allocIndex)
 	}
 }
 
+// progressDeadline returns the shortest progress deadline of the task groups
+// of the deployment, or zero if none has one.
+func (w *deploymentWatcher) progressDeadline() time.Duration {
+	var deadline time.Duration
+	for _, state := range w.d.TaskGroups {
+		if state.ProgressDeadline != 0 && (deadline == 0 || state.ProgressDeadline < deadline) {
+			deadline = state.ProgressDeadline
+		}
+	}
+	return deadline
+}
+
+// checkProgressDeadline fails the deployment if a task group didn't make
+// progress within its progress deadline, rolling back the job if the task
+// group has autorevert set. It returns whether the deployment failed or
+// otherwise the time until the deadline should be checked again, zero if it
+// shouldn't.
+func (w *deploymentWatcher) checkProgressDeadline() (bool, time.Duration, error) {
+	// Lookup the latest state of the deployment
+	args := &structs.DeploymentSpecificRequest{DeploymentID: w.d.ID}
+	var resp structs.SingleDeploymentResponse
+	if err := w.watchers.GetDeployment(args, &resp); err != nil {
+		return false, 0, err
+	}
+	d := resp.Deployment
+	if d == nil || !d.Active() {
+		return false, 0, nil
+	}
+
+	progressAt := w.getProgress()
+	now := time.Now()
+	var next time.Duration
+	failed, rollback := false, false
+	for _, state := range d.TaskGroups {
+		if state.ProgressDeadline == 0 {
+			continue
+		}
+
+		// A paused deployment and task groups that are complete or whose
+		// canaries await a promotion aren't expected to make progress, so
+		// check them again later
+		wait := progressAt.Add(state.ProgressDeadline).Sub(now)
+		if d.Status == structs.DeploymentStatusPaused ||
+			state.HealthyAllocs >= state.DesiredTotal ||
+			state.DesiredCanaries > 0 && !state.Promoted && state.HealthyAllocs >= state.DesiredCanaries {
+			wait = state.ProgressDeadline
+		}
+
+		if wait <= 0 {
+			failed = true
+			rollback = rollback || state.AutoRevert
+		} else if next == 0 || wait < next {
+			next = wait
+		}
+	}
+	if !failed {
+		return false, next, nil
+	}
+
+	w.logger.Printf("[INFO] nomad.deployment_watcher: deployment %q made no progress within its progress deadline", w.d.ID)
+
+	desc := structs.DeploymentStatusDescriptionProgressDeadline
+	var j *structs.Job
+	if rollback {
+		stable, err := w.latestStableJob()
+		if err != nil {
+			return false, 0, err
+		}
+		j, desc = w.rollbackJob(stable, desc)
+	}
+
+	u := w.getFailedStatusUpdate(desc, j)
+	index, err := w.upsertDeploymentStatusUpdate(u, w.getEval(), j)
+	if err != nil {
+		return false, 0, err
+	}
+	w.setLatestEval(index)
+	return true, 0, nil
+}
+
+// autoPromoteDeployment promotes the deployment once all the canaries are
+// placed and healthy, if all the task groups awaiting a promotion have
+// auto_promote set. The task groups are promoted together since they are part
+// of the same job version. It returns whether the deployment was promoted.
+func (w *deploymentWatcher) autoPromoteDeployment(allocs []*structs.AllocListStub) (bool, error) {
+	// The task groups set whether to auto promote when the deployment is
+	// created, so avoid the lookup for deployments not using it
+	autoPromote := false
+	for _, state := range w.d.TaskGroups {
+		if state.AutoPromote {
+			autoPromote = true
+			break
+		}
+	}
+	if !autoPromote {
+		return false, nil
+	}
+
+	// Lookup the latest state of the deployment
+	args := &structs.DeploymentSpecificRequest{DeploymentID: w.d.ID}
+	var resp structs.SingleDeploymentResponse
+	if err := w.watchers.GetDeployment(args, &resp); err != nil {
+		return false, err
+	}
+	d := resp.Deployment
+	if !d.HasPlacedCanaries() || !d.RequiresPromotion() {
+		return false, nil
+	}
+
+	healthy := make(map[string]bool, len(allocs))
+	for _, alloc := range allocs {
+		healthy[alloc.ID] = alloc.DeploymentStatus.IsHealthy()
+	}
+	for _, state := range d.TaskGroups {
+		if state.DesiredCanaries == 0 || state.Promoted {
+			continue
+		}
+		if !state.AutoPromote || len(state.PlacedCanaries) < state.DesiredCanaries {
+			return false, nil
+		}
+		for _, id := range state.PlacedCanaries {
+			if !healthy[id] {
+				return false, nil
+			}
+		}
+	}
+
+	areq := &structs.ApplyDeploymentPromoteRequest{
+		DeploymentPromoteRequest: structs.DeploymentPromoteRequest{
+			DeploymentID: d.ID,
+			All:          true,
+		},
+		Eval: w.getEval(),
+	}
+	index, err := w.upsertDeploymentPromotion(areq)
+	if err != nil {
+		return false, err
+	}
+	w.setLatestEval(index)
+	w.setProgress()
+	return true, nil
+}
+
+// rollbackJob returns the job to upsert to roll back to the stable job, which
+// records the version it reverts to and why, along with the description of
+// the failed deployment. The job is nil if there is no stable job.
+func (w *deploymentWatcher) rollbackJob(stable *structs.Job, desc string) (*structs.Job, string) {
+	if stable == nil {
+		w.logger.Printf("[WARN] nomad.deployment_watcher: deployment %q failed but job %q has no stable version to auto revert to", w.d.ID, w.d.JobID)
+		return nil, structs.DeploymentStatusDescriptionNoRollbackTarget(desc)
+	}
+
+	desc = structs.DeploymentStatusDescriptionRollback(desc, stable.Version)
+	w.logger.Printf("[INFO] nomad.deployment_watcher: deployment %q failed, auto reverting job %q to version %d", w.d.ID, w.d.JobID, stable.Version)
+
+	j := stable.Copy()
+	j.RevertVersion = helper.Uint64ToPtr(stable.Version)
+	j.RevertReason = fmt.Sprintf("Deployment %q: %s", w.d.ID, desc)
+	j.NoPlacementChange = false
+	return j, desc
+}
+
 // latestStableJob returns the latest stable job. It may be nil if none exist
 func (w *deploymentWatcher) latestStableJob() (*structs.Job, error) {
 	args := &structs.JobVersionsRequest{JobID: w.d.JobID}
@@ -448,6 +666,34 @@ func (w *deploymentWatcher) getDeploymentStatusUpdate(status, desc string) *stru
 	}
 }
 
+// getFailedStatusUpdate returns a deployment status update failing the
+// deployment, which records the version of the job it rolls back to if any.
+func (w *deploymentWatcher) getFailedStatusUpdate(desc string, rollback *structs.Job) *structs.DeploymentStatusUpdate {
+	u := w.getDeploymentStatusUpdate(structs.DeploymentStatusFailed, desc)
+	if rollback != nil {
+		u.RevertedJobVersion = helper.Uint64ToPtr(rollback.Version)
+	}
+	return u
+}
+
+// allocUpdates is the result of a blocking query for the allocations of the
+// deployment.
+type allocUpdates struct {
+	allocs *structs.AllocListResponse
+	err    error
+}
+
+// getAllocsCh retrieves the allocations that are part of the deployment
+// blocking at the given index, returning the result on the channel.
+func (w *deploymentWatcher) getAllocsCh(index uint64) <-chan *allocUpdates {
+	out := make(chan *allocUpdates, 1)
+	go func() {
+		allocs, err := w.getAllocs(index)
+		out <- &allocUpdates{allocs: allocs, err: err}
+	}()
+	return out
+}
+
 // getAllocs retrieves the allocations that are part of the deployment blocking
 // at the given index.
 func (w *deploymentWatcher) getAllocs(index uint64) (*structs.AllocListResponse, error) {
@@ -522,3 +768,17 @@ func (w *deploymentWatcher) getLatestEval() uint64 {
 	defer w.l.Unlock()
 	return w.latestEval
 }
+
+// setProgress records that the deployment made progress.
+func (w *deploymentWatcher) setProgress() {
+	w.l.Lock()
+	defer w.l.Unlock()
+	w.progressAt = time.Now()
+}
+
+// getProgress returns the last time the deployment made progress.
+func (w *deploymentWatcher) getProgress() time.Time {
+	w.l.Lock()
+	defer w.l.Unlock()
+	return w.progressAt
+}
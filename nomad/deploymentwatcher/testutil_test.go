@@ -136,6 +136,12 @@ func matchDeploymentStatusUpdateRequest(c *matchDeploymentStatusUpdateConfig) fu
 			return false
 		}
 
+		// A rollback is recorded in the deployment
+		if (args.Job != nil) != (args.DeploymentUpdate.RevertedJobVersion != nil) {
+			testLogger().Printf("reverted job versions dont match")
+			return false
+		}
+
 		return true
 	}
 }
@@ -318,6 +324,14 @@ func (m *mockBackend) GetDeployment(args *structs.DeploymentSpecificRequest, rep
 	return rargs.Error(0)
 }
 
+func (m *mockBackend) getDeploymentFromState(in mocker.Arguments) {
+	args, reply := in.Get(0).(*structs.DeploymentSpecificRequest), in.Get(1).(*structs.SingleDeploymentResponse)
+	ws := memdb.NewWatchSet()
+	d, _ := m.state.DeploymentByID(ws, args.DeploymentID)
+	reply.Deployment = d
+	reply.Index, _ = m.state.Index("deployment")
+}
+
 func (m *mockBackend) GetJobVersions(args *structs.JobVersionsRequest, reply *structs.JobVersionsResponse) error {
 	rargs := m.Called(args, reply)
 	return rargs.Error(0)
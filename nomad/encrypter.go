@@ -0,0 +1,101 @@
+package nomad
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// VariablesKeySize is the size of the key the variables are encrypted
+	// with, for AES-256.
+	VariablesKeySize = 32
+)
+
+// encrypter encrypts the items of the variables with AES-GCM before they are
+// written to Raft, so that neither the Raft log nor the snapshots hold them in
+// clear text. The namespace and path of a variable are authenticated with its
+// items, so encrypted items can't be moved to another variable.
+type encrypter struct {
+	keyID string
+	aead  cipher.AEAD
+}
+
+// newEncrypter returns an encrypter using the given key, which must be
+// VariablesKeySize bytes long.
+func newEncrypter(key []byte) (*encrypter, error) {
+	if len(key) != VariablesKeySize {
+		return nil, fmt.Errorf("variables encryption key must be %d bytes, got %d", VariablesKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(key)
+	return &encrypter{
+		keyID: hex.EncodeToString(sum[:8]),
+		aead:  aead,
+	}, nil
+}
+
+// Encrypt returns the variable with its items encrypted.
+func (e *encrypter) Encrypt(v *structs.Variable) (*structs.VariableEncrypted, error) {
+	plaintext, err := json.Marshal(v.Items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode items: %v", err)
+	}
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	return &structs.VariableEncrypted{
+		Namespace:   v.Namespace,
+		Path:        v.Path,
+		KeyID:       e.keyID,
+		Nonce:       nonce,
+		Data:        e.aead.Seal(nil, nonce, plaintext, variableAdditionalData(v.Namespace, v.Path)),
+		CreateIndex: v.CreateIndex,
+		ModifyIndex: v.ModifyIndex,
+	}, nil
+}
+
+// Decrypt returns the variable with its items decrypted.
+func (e *encrypter) Decrypt(ev *structs.VariableEncrypted) (*structs.Variable, error) {
+	if ev.KeyID != e.keyID {
+		return nil, fmt.Errorf("variable %q was encrypted with key %q, servers have key %q", ev.Path, ev.KeyID, e.keyID)
+	}
+	plaintext, err := e.aead.Open(nil, ev.Nonce, ev.Data, variableAdditionalData(ev.Namespace, ev.Path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt variable %q: %v", ev.Path, err)
+	}
+
+	v := &structs.Variable{
+		Namespace:   ev.Namespace,
+		Path:        ev.Path,
+		CreateIndex: ev.CreateIndex,
+		ModifyIndex: ev.ModifyIndex,
+	}
+	if err := json.Unmarshal(plaintext, &v.Items); err != nil {
+		return nil, fmt.Errorf("failed to decode items of variable %q: %v", ev.Path, err)
+	}
+	return v, nil
+}
+
+// variableAdditionalData returns the data authenticated with the items of a
+// variable.
+func variableAdditionalData(namespace, path string) []byte {
+	return []byte(namespace + "\x00" + path)
+}
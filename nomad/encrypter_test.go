@@ -0,0 +1,64 @@
+package nomad
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+func testEncrypter(t *testing.T, b byte) *encrypter {
+	key := make([]byte, VariablesKeySize)
+	for i := range key {
+		key[i] = b
+	}
+	e, err := newEncrypter(key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return e
+}
+
+func TestEncrypter(t *testing.T) {
+	t.Parallel()
+	e := testEncrypter(t, 1)
+
+	v := &structs.Variable{
+		Namespace: structs.DefaultNamespace,
+		Path:      "app/db",
+		Items:     map[string]string{"password": "secret"},
+	}
+	ev, err := e.Encrypt(v)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if strings.Contains(string(ev.Data), "secret") {
+		t.Fatalf("items not encrypted: %q", ev.Data)
+	}
+
+	out, err := e.Decrypt(ev)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(v, out) {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Encrypted items can't be moved to another variable
+	moved := *ev
+	moved.Path = "app/other"
+	if _, err := e.Decrypt(&moved); err == nil {
+		t.Fatalf("expected decryption of moved items to fail")
+	}
+
+	// Variables encrypted with another key are rejected
+	if _, err := testEncrypter(t, 2).Decrypt(ev); err == nil || !strings.Contains(err.Error(), "encrypted with key") {
+		t.Fatalf("expected key mismatch error; got %v", err)
+	}
+
+	// Keys must be 32 bytes
+	if _, err := newEncrypter([]byte("short")); err == nil {
+		t.Fatalf("expected invalid key error")
+	}
+}
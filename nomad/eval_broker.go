@@ -76,6 +76,11 @@ type EvalBroker struct {
 	// timeWait has evaluations that are waiting for time to elapse
 	timeWait map[string]*time.Timer
 
+	// enqueueTime tracks when queued evaluations were enqueued, to measure
+	// the time they wait to be dequeued. Evaluations blocked behind another
+	// evaluation of their job keep the time they were first enqueued.
+	enqueueTime map[string]time.Time
+
 	// initialNackDelay is the delay applied before reenqueuing a
 	// Nacked evaluation for the first time.
 	initialNackDelay time.Duration
@@ -124,6 +129,7 @@ func NewEvalBroker(timeout, initialNackDelay, subsequentNackDelay time.Duration,
 		waiting:             make(map[string]chan struct{}),
 		requeue:             make(map[string]*structs.Evaluation),
 		timeWait:            make(map[string]*time.Timer),
+		enqueueTime:         make(map[string]time.Time),
 		initialNackDelay:    initialNackDelay,
 		subsequentNackDelay: subsequentNackDelay,
 	}
@@ -234,6 +240,10 @@ func (b *EvalBroker) enqueueLocked(eval *structs.Evaluation, queue string) {
 		return
 	}
 
+	if _, ok := b.enqueueTime[eval.ID]; !ok {
+		b.enqueueTime[eval.ID] = time.Now()
+	}
+
 	// Check if there is an evaluation for this JobID pending
 	pendingEval := b.jobEvals[eval.JobID]
 	if pendingEval == "" {
@@ -378,6 +388,12 @@ func (b *EvalBroker) dequeueForSched(sched string) (*structs.Evaluation, string,
 	b.ready[sched] = pending
 	eval := raw.(*structs.Evaluation)
 
+	// Measure the time the evaluation waited to be dequeued
+	if enqueued, ok := b.enqueueTime[eval.ID]; ok {
+		metrics.MeasureSince([]string{"nomad", "broker", "wait_time", sched}, enqueued)
+		delete(b.enqueueTime, eval.ID)
+	}
+
 	// Generate a UUID for the token
 	token := structs.GenerateUUID()
 
@@ -665,6 +681,7 @@ func (b *EvalBroker) Flush() {
 	b.ready = make(map[string]PendingEvaluations)
 	b.unack = make(map[string]*unackEval)
 	b.timeWait = make(map[string]*time.Timer)
+	b.enqueueTime = make(map[string]time.Time)
 }
 
 // Stats is used to query the state of the broker
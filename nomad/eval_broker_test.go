@@ -1200,3 +1200,48 @@ func TestEvalBroker_EnqueueAll_Requeue_Nack(t *testing.T) {
 		t.Fatal(e)
 	})
 }
+
+func TestEvalBroker_EnqueueTime(t *testing.T) {
+	t.Parallel()
+	b := testBroker(t, 0)
+	b.SetEnabled(true)
+
+	// An evaluation blocked behind another of its job keeps the time it was
+	// first enqueued
+	eval := mock.Eval()
+	eval2 := mock.Eval()
+	eval2.JobID = eval.JobID
+	b.Enqueue(eval)
+	b.Enqueue(eval2)
+
+	b.l.RLock()
+	enqueued, ok := b.enqueueTime[eval2.ID]
+	tracked := len(b.enqueueTime)
+	b.l.RUnlock()
+	if !ok || tracked != 2 {
+		t.Fatalf("expected both evaluations to be tracked")
+	}
+
+	out, token, err := b.Dequeue(defaultSched, time.Second)
+	if err != nil || out != eval {
+		t.Fatalf("bad: %#v %v", out, err)
+	}
+	if err := b.Ack(out.ID, token); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	b.l.RLock()
+	if _, ok := b.enqueueTime[eval.ID]; ok {
+		t.Fatalf("dequeued evaluation should not be tracked")
+	}
+	if b.enqueueTime[eval2.ID] != enqueued {
+		t.Fatalf("unblocked evaluation should keep its enqueue time")
+	}
+	b.l.RUnlock()
+
+	// Flushing forgets the queued evaluations
+	b.SetEnabled(false)
+	if len(b.enqueueTime) != 0 {
+		t.Fatalf("bad: %#v", b.enqueueTime)
+	}
+}
@@ -29,6 +29,11 @@ func (e *Eval) GetEval(args *structs.EvalSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "eval", "get_eval"}, time.Now())
 
+	aclObj, err := e.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
@@ -39,6 +44,15 @@ func (e *Eval) GetEval(args *structs.EvalSpecificRequest,
 			if err != nil {
 				return err
 			}
+			if out != nil {
+				ns, err := jobNamespace(ws, state, out.JobID)
+				if err != nil {
+					return err
+				}
+				if !aclObj.AllowNamespaceRead(ns) {
+					return structs.ErrPermissionDenied
+				}
+			}
 
 			// Setup the output
 			reply.Eval = out
@@ -256,6 +270,13 @@ func (e *Eval) Reap(args *structs.EvalDeleteRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "eval", "reap"}, time.Now())
 
+	// Reaping is done by the core jobs with the leader's token
+	if aclObj, err := e.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Update via Raft
 	_, index, err := e.srv.raftApply(structs.EvalDeleteRequestType, args)
 	if err != nil {
@@ -275,33 +296,42 @@ func (e *Eval) List(args *structs.EvalListRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "eval", "list"}, time.Now())
 
+	aclObj, err := e.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
 			// Scan all the evaluations
-			var err error
-			var iter memdb.ResultIterator
-			if prefix := args.QueryOptions.Prefix; prefix != "" {
-				iter, err = state.EvalsByIDPrefix(ws, prefix)
-			} else {
-				iter, err = state.Evals(ws)
-			}
+			iter, err := state.PrefixFrom(ws, "evals", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
 			if err != nil {
 				return err
 			}
+			iter = newFilterIterator(iter, func(raw interface{}) bool {
+				ns, err := jobNamespace(nil, state, raw.(*structs.Evaluation).JobID)
+				return err == nil && aclObj.AllowNamespaceRead(ns)
+			})
 
 			var evals []*structs.Evaluation
-			for {
-				raw := iter.Next()
-				if raw == nil {
-					break
-				}
-				eval := raw.(*structs.Evaluation)
-				evals = append(evals, eval)
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.Evaluation).ID
+			}, nil)
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				evals = append(evals, obj.(*structs.Evaluation))
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 			reply.Evaluations = evals
+			reply.NextToken = nextToken
 
 			// Use the last index that affected the jobs table
 			index, err := state.Index("evals")
@@ -325,6 +355,11 @@ func (e *Eval) Allocations(args *structs.EvalSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "eval", "allocations"}, time.Now())
 
+	aclObj, err := e.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
@@ -340,6 +375,9 @@ func (e *Eval) Allocations(args *structs.EvalSpecificRequest,
 			if len(allocs) > 0 {
 				reply.Allocations = make([]*structs.AllocListStub, 0, len(allocs))
 				for _, alloc := range allocs {
+					if !aclObj.AllowNamespaceRead(allocNamespace(alloc)) {
+						return structs.ErrPermissionDenied
+					}
 					reply.Allocations = append(reply.Allocations, alloc.Stub())
 				}
 			}
@@ -474,6 +474,92 @@ func TestEvalEndpoint_List(t *testing.T) {
 
 }
 
+func TestEvalEndpoint_List_Paginated(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the evals
+	eval1 := mock.Eval()
+	eval1.ID = "aaaaaaaa-3350-4b4b-d185-0e1992ed43e9"
+	eval2 := mock.Eval()
+	eval2.ID = "aaaabbbb-3350-4b4b-d185-0e1992ed43e9"
+	eval3 := mock.Eval()
+	eval3.ID = "aaaacccc-3350-4b4b-d185-0e1992ed43e9"
+	s1.fsm.State().UpsertEvals(1000, []*structs.Evaluation{eval1, eval2, eval3})
+
+	// Lookup the first page
+	get := &structs.EvalListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", PerPage: 2},
+	}
+	var resp structs.EvalListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Eval.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Evaluations) != 2 {
+		t.Fatalf("bad: %#v", resp.Evaluations)
+	}
+	if resp.Evaluations[0].ID != eval1.ID || resp.Evaluations[1].ID != eval2.ID {
+		t.Fatalf("bad: %#v", resp.Evaluations)
+	}
+	if resp.NextToken != eval3.ID {
+		t.Fatalf("bad next token: %q", resp.NextToken)
+	}
+
+	// Lookup the second page
+	get = &structs.EvalListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", PerPage: 2, NextToken: resp.NextToken},
+	}
+	var resp2 structs.EvalListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Eval.List", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Evaluations) != 1 || resp2.Evaluations[0].ID != eval3.ID {
+		t.Fatalf("bad: %#v", resp2.Evaluations)
+	}
+	if resp2.NextToken != "" {
+		t.Fatalf("bad next token: %q", resp2.NextToken)
+	}
+}
+
+func TestEvalEndpoint_List_Filter(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the evals
+	eval1 := mock.Eval()
+	eval2 := mock.Eval()
+	eval2.Status = structs.EvalStatusComplete
+	s1.fsm.State().UpsertEvals(1000, []*structs.Evaluation{eval1, eval2})
+
+	// Lookup the complete evals
+	get := &structs.EvalListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+			Filter: `Status == "complete"`,
+		},
+	}
+	var resp structs.EvalListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Eval.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Evaluations) != 1 || resp.Evaluations[0].ID != eval2.ID {
+		t.Fatalf("bad: %#v", resp.Evaluations)
+	}
+
+	// An invalid filter should fail
+	get.Filter = `Status = "complete"`
+	var resp2 structs.EvalListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Eval.List", get, &resp2); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
 func TestEvalEndpoint_List_Blocking(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
@@ -41,6 +41,16 @@ const (
 	VaultAccessorSnapshot
 	JobVersionSnapshot
 	DeploymentSnapshot
+	NamespaceSnapshot
+	SchedulerConfigSnapshot
+	SITokenAccessorSnapshot
+	ServiceRegistrationSnapshot
+	VariableSnapshot
+	ACLPolicySnapshot
+	ACLTokenSnapshot
+	ACLRoleSnapshot
+	ACLAuthMethodSnapshot
+	ACLBindingRuleSnapshot
 )
 
 // nomadFSM implements a finite state machine that is used
@@ -167,6 +177,52 @@ func (n *nomadFSM) Apply(log *raft.Log) interface{} {
 		return n.applyDeploymentDelete(buf[1:], log.Index)
 	case structs.JobStabilityRequestType:
 		return n.applyJobStability(buf[1:], log.Index)
+	case structs.NamespaceUpsertRequestType:
+		return n.applyNamespaceUpsert(buf[1:], log.Index)
+	case structs.NamespaceDeleteRequestType:
+		return n.applyNamespaceDelete(buf[1:], log.Index)
+	case structs.SchedulerConfigRequestType:
+		return n.applySchedulerConfigUpdate(buf[1:], log.Index)
+	case structs.ServiceIdentityAccessorRegisterRequestType:
+		return n.applyUpsertSIAccessor(buf[1:], log.Index)
+	case structs.ServiceIdentityAccessorDeregisterRequestType:
+		return n.applyDeregisterSIAccessor(buf[1:], log.Index)
+	case structs.ServiceRegistrationUpsertRequestType:
+		return n.applyServiceRegistrationUpsert(buf[1:], log.Index)
+	case structs.ServiceRegistrationDeleteByIDRequestType:
+		return n.applyServiceRegistrationDeleteByID(buf[1:], log.Index)
+	case structs.VariablesUpsertRequestType:
+		return n.applyVariableUpsert(buf[1:], log.Index)
+	case structs.VariablesDeleteRequestType:
+		return n.applyVariableDelete(buf[1:], log.Index)
+	case structs.JobUpdatePriorityRequestType:
+		return n.applyJobUpdatePriority(buf[1:], log.Index)
+	case structs.JobNotifiedRequestType:
+		return n.applyJobNotified(buf[1:], log.Index)
+	case structs.DeploymentNotifiedRequestType:
+		return n.applyDeploymentNotified(buf[1:], log.Index)
+	case structs.ACLPolicyUpsertRequestType:
+		return n.applyACLPolicyUpsert(buf[1:], log.Index)
+	case structs.ACLPolicyDeleteRequestType:
+		return n.applyACLPolicyDelete(buf[1:], log.Index)
+	case structs.ACLTokenUpsertRequestType:
+		return n.applyACLTokenUpsert(buf[1:], log.Index)
+	case structs.ACLTokenDeleteRequestType:
+		return n.applyACLTokenDelete(buf[1:], log.Index)
+	case structs.ACLTokenBootstrapRequestType:
+		return n.applyACLTokenBootstrap(buf[1:], log.Index)
+	case structs.ACLRoleUpsertRequestType:
+		return n.applyACLRoleUpsert(buf[1:], log.Index)
+	case structs.ACLRoleDeleteRequestType:
+		return n.applyACLRoleDelete(buf[1:], log.Index)
+	case structs.ACLAuthMethodUpsertRequestType:
+		return n.applyACLAuthMethodUpsert(buf[1:], log.Index)
+	case structs.ACLAuthMethodDeleteRequestType:
+		return n.applyACLAuthMethodDelete(buf[1:], log.Index)
+	case structs.ACLBindingRuleUpsertRequestType:
+		return n.applyACLBindingRuleUpsert(buf[1:], log.Index)
+	case structs.ACLBindingRuleDeleteRequestType:
+		return n.applyACLBindingRuleDelete(buf[1:], log.Index)
 	default:
 		if ignoreUnknown {
 			n.logger.Printf("[WARN] nomad.fsm: ignoring unknown message type (%d), upgrade to newer version", msgType)
@@ -219,7 +275,7 @@ func (n *nomadFSM) applyStatusUpdate(buf []byte, index uint64) interface{} {
 		panic(fmt.Errorf("failed to decode request: %v", err))
 	}
 
-	if err := n.state.UpdateNodeStatus(index, req.NodeID, req.Status); err != nil {
+	if err := n.state.UpdateNodeStatus(index, req.NodeID, req.Status, req.UpdatedAt); err != nil {
 		n.logger.Printf("[ERR] nomad.fsm: UpdateNodeStatus failed: %v", err)
 		return err
 	}
@@ -394,12 +450,21 @@ func (n *nomadFSM) applyUpdateEval(buf []byte, index uint64) interface{} {
 		panic(fmt.Errorf("failed to decode request: %v", err))
 	}
 
-	if err := n.state.UpsertEvals(index, req.Evals); err != nil {
+	if err := n.upsertEvals(index, req.Evals); err != nil {
+		return err
+	}
+	return nil
+}
+
+// upsertEvals upserts the evaluations and hands them to the eval broker or
+// the blocked evals tracker as needed.
+func (n *nomadFSM) upsertEvals(index uint64, evals []*structs.Evaluation) error {
+	if err := n.state.UpsertEvals(index, evals); err != nil {
 		n.logger.Printf("[ERR] nomad.fsm: UpsertEvals failed: %v", err)
 		return err
 	}
 
-	for _, eval := range req.Evals {
+	for _, eval := range evals {
 		if eval.ShouldEnqueue() {
 			n.evalBroker.Enqueue(eval)
 		} else if eval.ShouldBlock() {
@@ -469,6 +534,13 @@ func (n *nomadFSM) applyAllocUpdate(buf []byte, index uint64) interface{} {
 		n.logger.Printf("[ERR] nomad.fsm: UpsertAllocs failed: %v", err)
 		return err
 	}
+
+	// Create the evaluations that accompany the allocation changes
+	if len(req.Evals) != 0 {
+		if err := n.upsertEvals(index, req.Evals); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -669,6 +741,388 @@ func (n *nomadFSM) applyJobStability(buf []byte, index uint64) interface{} {
 	return nil
 }
 
+// applyJobUpdatePriority is used to change the priority of a job
+func (n *nomadFSM) applyJobUpdatePriority(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_job_update_priority"}, time.Now())
+	var req structs.JobUpdatePriorityRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpdateJobPriority(index, &req); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpdateJobPriority failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyJobNotified is used to record that the notification of a job was sent
+func (n *nomadFSM) applyJobNotified(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_job_notified"}, time.Now())
+	var req structs.JobNotifiedRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpdateJobNotified(index, req.JobID, req.JobModifyIndex); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpdateJobNotified failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyDeploymentNotified is used to record that the webhooks were notified
+// of events of a deployment
+func (n *nomadFSM) applyDeploymentNotified(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_deployment_notified"}, time.Now())
+	var req structs.DeploymentNotifiedRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpdateDeploymentNotified(index, req.DeploymentID, req.Events); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpdateDeploymentNotified failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyNamespaceUpsert is used to upsert a set of namespaces
+func (n *nomadFSM) applyNamespaceUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_namespace_upsert"}, time.Now())
+	var req structs.NamespaceUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertNamespaces(index, req.Namespaces); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertNamespaces failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyNamespaceDelete is used to delete a set of namespaces
+func (n *nomadFSM) applyNamespaceDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_namespace_delete"}, time.Now())
+	var req structs.NamespaceDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteNamespaces(index, req.Namespaces); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteNamespaces failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applySchedulerConfigUpdate is used to update the scheduler configuration.
+// Check-and-set updates return whether the configuration was updated.
+func (n *nomadFSM) applySchedulerConfigUpdate(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_scheduler_config"}, time.Now())
+	var req structs.SchedulerSetConfigRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if req.CAS {
+		updated, err := n.state.SchedulerCASConfig(index, req.Config.ModifyIndex, &req.Config)
+		if err != nil {
+			n.logger.Printf("[ERR] nomad.fsm: SchedulerCASConfig failed: %v", err)
+			return err
+		}
+		return updated
+	}
+
+	if err := n.state.SchedulerSetConfig(index, &req.Config); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: SchedulerSetConfig failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyUpsertSIAccessor stores the Service Identity token accessors of an
+// allocation.
+func (n *nomadFSM) applyUpsertSIAccessor(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "upsert_si_accessor_token"}, time.Now())
+	var req structs.SITokenAccessorsRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertSITokenAccessors(index, req.Accessors); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertSITokenAccessors failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyDeregisterSIAccessor deregisters a set of Service Identity token
+// accessors.
+func (n *nomadFSM) applyDeregisterSIAccessor(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "deregister_si_accessor_token"}, time.Now())
+	var req structs.SITokenAccessorsRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteSITokenAccessors(index, req.Accessors); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeregisterSITokenAccessor failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyServiceRegistrationUpsert is used to upsert a set of service
+// registrations
+func (n *nomadFSM) applyServiceRegistrationUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_service_registration_upsert"}, time.Now())
+	var req structs.ServiceRegistrationUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertServiceRegistrations(index, req.Services); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertServiceRegistrations failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyServiceRegistrationDeleteByID is used to delete a set of service
+// registrations
+func (n *nomadFSM) applyServiceRegistrationDeleteByID(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_service_registration_delete_by_id"}, time.Now())
+	var req structs.ServiceRegistrationDeleteByIDRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteServiceRegistrationsByID(index, req.IDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteServiceRegistrationsByID failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// applyVariableUpsert is used to write an encrypted variable. It returns the
+// response of the write, which is not updated if a check-and-set failed.
+func (n *nomadFSM) applyVariableUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_variable_upsert"}, time.Now())
+	var req structs.VariablesEncryptedUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	updated, conflict, err := n.state.UpsertVariable(index, req.Variable, req.CAS)
+	if err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertVariable failed: %v", err)
+		return err
+	}
+	return variableWriteResponse(updated, conflict)
+}
+
+// applyVariableDelete is used to delete a variable. It returns the response
+// of the delete, which is not updated if a check-and-set failed.
+func (n *nomadFSM) applyVariableDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_variable_delete"}, time.Now())
+	var req structs.VariablesDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	updated, conflict, err := n.state.DeleteVariable(index, req.Namespace, req.Path, req.CAS, req.CheckIndex)
+	if err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteVariable failed: %v", err)
+		return err
+	}
+	return variableWriteResponse(updated, conflict)
+}
+
+func variableWriteResponse(updated bool, conflict *structs.VariableEncrypted) *structs.VariablesWriteResponse {
+	resp := &structs.VariablesWriteResponse{Updated: updated}
+	if conflict != nil {
+		resp.Conflict = conflict.Metadata()
+	}
+	return resp
+}
+
+// applyACLPolicyUpsert is used to upsert a set of ACL policies
+func (n *nomadFSM) applyACLPolicyUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_policy_upsert"}, time.Now())
+	var req structs.ACLPolicyUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertACLPolicies(index, req.Policies); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertACLPolicies failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLPolicyDelete is used to delete a set of ACL policies
+func (n *nomadFSM) applyACLPolicyDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_policy_delete"}, time.Now())
+	var req structs.ACLPolicyDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteACLPolicies(index, req.Names); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteACLPolicies failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLRoleUpsert is used to upsert a set of ACL roles
+func (n *nomadFSM) applyACLRoleUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_role_upsert"}, time.Now())
+	var req structs.ACLRoleUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertACLRoles(index, req.Roles); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertACLRoles failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLRoleDelete is used to delete a set of ACL roles
+func (n *nomadFSM) applyACLRoleDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_role_delete"}, time.Now())
+	var req structs.ACLRoleDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteACLRoles(index, req.Names); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteACLRoles failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLAuthMethodUpsert is used to upsert a set of ACL auth methods
+func (n *nomadFSM) applyACLAuthMethodUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_auth_method_upsert"}, time.Now())
+	var req structs.ACLAuthMethodUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertACLAuthMethods(index, req.AuthMethods); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertACLAuthMethods failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLAuthMethodDelete is used to delete a set of ACL auth methods
+func (n *nomadFSM) applyACLAuthMethodDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_auth_method_delete"}, time.Now())
+	var req structs.ACLAuthMethodDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteACLAuthMethods(index, req.Names); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteACLAuthMethods failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLBindingRuleUpsert is used to upsert a set of ACL binding rules
+func (n *nomadFSM) applyACLBindingRuleUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_binding_rule_upsert"}, time.Now())
+	var req structs.ACLBindingRuleUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertACLBindingRules(index, req.BindingRules); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertACLBindingRules failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLBindingRuleDelete is used to delete a set of ACL binding rules
+func (n *nomadFSM) applyACLBindingRuleDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_binding_rule_delete"}, time.Now())
+	var req structs.ACLBindingRuleDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteACLBindingRules(index, req.IDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteACLBindingRules failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLTokenUpsert is used to upsert a set of ACL tokens
+func (n *nomadFSM) applyACLTokenUpsert(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_token_upsert"}, time.Now())
+	var req structs.ACLTokenUpsertRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.UpsertACLTokens(index, req.Tokens); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: UpsertACLTokens failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLTokenDelete is used to delete a set of ACL tokens
+func (n *nomadFSM) applyACLTokenDelete(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_token_delete"}, time.Now())
+	var req structs.ACLTokenDeleteRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.DeleteACLTokens(index, req.AccessorIDs); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: DeleteACLTokens failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// applyACLTokenBootstrap is used to create the initial management token
+func (n *nomadFSM) applyACLTokenBootstrap(buf []byte, index uint64) interface{} {
+	defer metrics.MeasureSince([]string{"nomad", "fsm", "apply_acl_token_bootstrap"}, time.Now())
+	var req structs.ACLTokenBootstrapRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+
+	if err := n.state.BootstrapACLTokens(index, req.Token); err != nil {
+		n.logger.Printf("[ERR] nomad.fsm: BootstrapACLTokens failed: %v", err)
+		return err
+	}
+	return nil
+}
+
 func (n *nomadFSM) Snapshot() (raft.FSMSnapshot, error) {
 	// Create a new snapshot
 	snap, err := n.state.Snapshot()
@@ -826,6 +1280,96 @@ func (n *nomadFSM) Restore(old io.ReadCloser) error {
 				return err
 			}
 
+		case NamespaceSnapshot:
+			ns := new(structs.Namespace)
+			if err := dec.Decode(ns); err != nil {
+				return err
+			}
+			if err := restore.NamespaceRestore(ns); err != nil {
+				return err
+			}
+
+		case SchedulerConfigSnapshot:
+			config := new(structs.SchedulerConfiguration)
+			if err := dec.Decode(config); err != nil {
+				return err
+			}
+			if err := restore.SchedulerConfigRestore(config); err != nil {
+				return err
+			}
+
+		case SITokenAccessorSnapshot:
+			accessor := new(structs.SITokenAccessor)
+			if err := dec.Decode(accessor); err != nil {
+				return err
+			}
+			if err := restore.SITokenAccessorRestore(accessor); err != nil {
+				return err
+			}
+
+		case ServiceRegistrationSnapshot:
+			service := new(structs.ServiceRegistration)
+			if err := dec.Decode(service); err != nil {
+				return err
+			}
+			if err := restore.ServiceRegistrationRestore(service); err != nil {
+				return err
+			}
+
+		case VariableSnapshot:
+			v := new(structs.VariableEncrypted)
+			if err := dec.Decode(v); err != nil {
+				return err
+			}
+			if err := restore.VariableRestore(v); err != nil {
+				return err
+			}
+
+		case ACLPolicySnapshot:
+			policy := new(structs.ACLPolicy)
+			if err := dec.Decode(policy); err != nil {
+				return err
+			}
+			if err := restore.ACLPolicyRestore(policy); err != nil {
+				return err
+			}
+
+		case ACLTokenSnapshot:
+			token := new(structs.ACLToken)
+			if err := dec.Decode(token); err != nil {
+				return err
+			}
+			if err := restore.ACLTokenRestore(token); err != nil {
+				return err
+			}
+
+		case ACLRoleSnapshot:
+			role := new(structs.ACLRole)
+			if err := dec.Decode(role); err != nil {
+				return err
+			}
+			if err := restore.ACLRoleRestore(role); err != nil {
+				return err
+			}
+
+		case ACLAuthMethodSnapshot:
+			method := new(structs.ACLAuthMethod)
+			if err := dec.Decode(method); err != nil {
+				return err
+			}
+			if err := restore.ACLAuthMethodRestore(method); err != nil {
+				return err
+			}
+
+		case ACLBindingRuleSnapshot:
+			rule := new(structs.ACLBindingRule)
+			if err := dec.Decode(rule); err != nil {
+				return err
+			}
+			if err := restore.ACLBindingRuleRestore(rule); err != nil {
+				return err
+			}
+
 		default:
 			return fmt.Errorf("Unrecognized snapshot type: %v", msgType)
 		}
@@ -1032,6 +1576,46 @@ func (s *nomadSnapshot) Persist(sink raft.SnapshotSink) error {
 		sink.Cancel()
 		return err
 	}
+	if err := s.persistNamespaces(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistSchedulerConfig(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistSITokenAccessors(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistServiceRegistrations(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistVariables(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistACLPolicies(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistACLTokens(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistACLRoles(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistACLAuthMethods(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+	if err := s.persistACLBindingRules(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
 	return nil
 }
 
@@ -1308,6 +1892,258 @@ func (s *nomadSnapshot) persistDeployments(sink raft.SnapshotSink,
 	return nil
 }
 
+func (s *nomadSnapshot) persistNamespaces(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the namespaces
+	ws := memdb.NewWatchSet()
+	namespaces, err := s.snap.Namespaces(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := namespaces.Next()
+		if raw == nil {
+			break
+		}
+
+		// Write out the namespace
+		ns := raw.(*structs.Namespace)
+		sink.Write([]byte{byte(NamespaceSnapshot)})
+		if err := encoder.Encode(ns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistSchedulerConfig(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get the scheduler config
+	_, config, err := s.snap.SchedulerConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return nil
+	}
+
+	// Write out the scheduler config
+	sink.Write([]byte{byte(SchedulerConfigSnapshot)})
+	if err := encoder.Encode(config); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistSITokenAccessors(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+
+	ws := memdb.NewWatchSet()
+	accessors, err := s.snap.SITokenAccessors(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		raw := accessors.Next()
+		if raw == nil {
+			break
+		}
+
+		accessor := raw.(*structs.SITokenAccessor)
+
+		sink.Write([]byte{byte(SITokenAccessorSnapshot)})
+		if err := encoder.Encode(accessor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistServiceRegistrations(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the service registrations
+	ws := memdb.NewWatchSet()
+	services, err := s.snap.ServiceRegistrations(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := services.Next()
+		if raw == nil {
+			break
+		}
+
+		// Write out the service registration
+		service := raw.(*structs.ServiceRegistration)
+		sink.Write([]byte{byte(ServiceRegistrationSnapshot)})
+		if err := encoder.Encode(service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistVariables(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the variables, which are persisted encrypted
+	ws := memdb.NewWatchSet()
+	variables, err := s.snap.Variables(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := variables.Next()
+		if raw == nil {
+			break
+		}
+
+		// Write out the variable
+		v := raw.(*structs.VariableEncrypted)
+		sink.Write([]byte{byte(VariableSnapshot)})
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistACLPolicies(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the policies
+	ws := memdb.NewWatchSet()
+	policies, err := s.snap.ACLPolicies(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := policies.Next()
+		if raw == nil {
+			break
+		}
+
+		// Write out the policy
+		policy := raw.(*structs.ACLPolicy)
+		sink.Write([]byte{byte(ACLPolicySnapshot)})
+		if err := encoder.Encode(policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistACLRoles(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the roles
+	ws := memdb.NewWatchSet()
+	roles, err := s.snap.ACLRoles(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := roles.Next()
+		if raw == nil {
+			break
+		}
+
+		// Write out the role
+		role := raw.(*structs.ACLRole)
+		sink.Write([]byte{byte(ACLRoleSnapshot)})
+		if err := encoder.Encode(role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistACLAuthMethods(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the auth methods
+	ws := memdb.NewWatchSet()
+	methods, err := s.snap.ACLAuthMethods(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := methods.Next()
+		if raw == nil {
+			break
+		}
+
+		// Write out the auth method
+		method := raw.(*structs.ACLAuthMethod)
+		sink.Write([]byte{byte(ACLAuthMethodSnapshot)})
+		if err := encoder.Encode(method); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistACLBindingRules(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the binding rules
+	ws := memdb.NewWatchSet()
+	rules, err := s.snap.ACLBindingRules(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := rules.Next()
+		if raw == nil {
+			break
+		}
+
+		// Write out the binding rule
+		rule := raw.(*structs.ACLBindingRule)
+		sink.Write([]byte{byte(ACLBindingRuleSnapshot)})
+		if err := encoder.Encode(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *nomadSnapshot) persistACLTokens(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	// Get all the tokens
+	ws := memdb.NewWatchSet()
+	tokens, err := s.snap.ACLTokens(ws)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the next item
+		raw := tokens.Next()
+		if raw == nil {
+			break
+		}
+
+		// Write out the token
+		token := raw.(*structs.ACLToken)
+		sink.Write([]byte{byte(ACLTokenSnapshot)})
+		if err := encoder.Encode(token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Release is a no-op, as we just need to GC the pointer
 // to the state store snapshot. There is nothing to explicitly
 // cleanup.
@@ -737,6 +737,55 @@ func TestFSM_UpsertAllocs(t *testing.T) {
 	}
 }
 
+func TestFSM_UpsertAllocs_Evals(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	fsm.evalBroker.SetEnabled(true)
+
+	alloc := mock.Alloc()
+	fsm.State().UpsertJobSummary(1, mock.JobSummary(alloc.JobID))
+	eval := mock.Eval()
+	eval.JobID = alloc.JobID
+	req := structs.AllocUpdateRequest{
+		Alloc: []*structs.Allocation{alloc},
+		Evals: []*structs.Evaluation{eval},
+	}
+	buf, err := structs.Encode(structs.AllocUpdateRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the allocation and the evaluation were created
+	ws := memdb.NewWatchSet()
+	out, err := fsm.State().AllocByID(ws, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("alloc not found!")
+	}
+	evalOut, err := fsm.State().EvalByID(ws, eval.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if evalOut == nil {
+		t.Fatalf("eval not found!")
+	}
+	if evalOut.CreateIndex != out.ModifyIndex {
+		t.Fatalf("bad index: %d %d", evalOut.CreateIndex, out.ModifyIndex)
+	}
+
+	// Verify the evaluation was enqueued
+	if stats := fsm.evalBroker.Stats(); stats.TotalReady != 1 {
+		t.Fatalf("bad: %#v", stats)
+	}
+}
+
 func TestFSM_UpsertAllocs_SharedJob(t *testing.T) {
 	t.Parallel()
 	fsm := testFSM(t)
@@ -1068,6 +1117,82 @@ func TestFSM_DeregisterVaultAccessor(t *testing.T) {
 	}
 }
 
+func TestFSM_UpsertSITokenAccessors(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	a1 := mock.SITokenAccessor()
+	a2 := mock.SITokenAccessor()
+	req := structs.SITokenAccessorsRequest{
+		Accessors: []*structs.SITokenAccessor{a1, a2},
+	}
+	buf, err := structs.Encode(structs.ServiceIdentityAccessorRegisterRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	ws := memdb.NewWatchSet()
+	out1, err := fsm.State().SITokenAccessor(ws, a1.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out1 == nil {
+		t.Fatalf("not found!")
+	}
+	if out1.CreateIndex != 1 {
+		t.Fatalf("bad index: %d", out1.CreateIndex)
+	}
+	out2, err := fsm.State().SITokenAccessor(ws, a2.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out2 == nil {
+		t.Fatalf("not found!")
+	}
+}
+
+func TestFSM_DeregisterSITokenAccessors(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	a1 := mock.SITokenAccessor()
+	a2 := mock.SITokenAccessor()
+	accessors := []*structs.SITokenAccessor{a1, a2}
+
+	// Insert the accessors
+	if err := fsm.State().UpsertSITokenAccessors(1000, accessors); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.SITokenAccessorsRequest{
+		Accessors: accessors,
+	}
+	buf, err := structs.Encode(structs.ServiceIdentityAccessorDeregisterRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	ws := memdb.NewWatchSet()
+	out1, err := fsm.State().SITokenAccessor(ws, a1.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out1 != nil {
+		t.Fatalf("not deleted!")
+	}
+}
+
 func TestFSM_ApplyPlanResults(t *testing.T) {
 	t.Parallel()
 	fsm := testFSM(t)
@@ -1269,6 +1394,125 @@ func TestFSM_JobStabilityUpdate(t *testing.T) {
 	}
 }
 
+func TestFSM_JobNotified(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.Stop = true
+	job.Notification = &structs.JobNotification{Webhook: "https://ci.example.com/hooks"}
+	if err := state.UpsertJob(1, job); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := &structs.JobNotifiedRequest{
+		JobID:          job.ID,
+		JobModifyIndex: job.JobModifyIndex,
+	}
+	buf, err := structs.Encode(structs.JobNotifiedRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// The job is no longer pending its notification and its version is kept
+	ws := memdb.NewWatchSet()
+	jout, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if jout.NotifiedIndex != job.JobModifyIndex || jout.Version != job.Version {
+		t.Fatalf("bad: %#v", jout)
+	}
+	iter, err := state.JobsPendingNotification(ws)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if raw := iter.Next(); raw != nil {
+		t.Fatalf("unexpected pending job: %#v", raw)
+	}
+}
+
+func TestFSM_DeploymentNotified(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	d := mock.Deployment()
+	if err := state.UpsertDeployment(1, d); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	for _, events := range [][]string{{"started"}, {"started", "failed"}} {
+		req := &structs.DeploymentNotifiedRequest{
+			DeploymentID: d.ID,
+			Events:       events,
+		}
+		buf, err := structs.Encode(structs.DeploymentNotifiedRequestType, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		resp := fsm.Apply(makeLog(buf))
+		if resp != nil {
+			t.Fatalf("resp: %v", resp)
+		}
+	}
+
+	// The events are recorded once and kept when the deployment is updated
+	d = d.Copy()
+	d.Status = structs.DeploymentStatusFailed
+	if err := state.UpsertDeployment(2, d); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	out, err := state.DeploymentByID(nil, d.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out.NotifiedEvents, []string{"started", "failed"}) {
+		t.Fatalf("bad: %v", out.NotifiedEvents)
+	}
+}
+
+func TestFSM_JobUpdatePriority(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	job := mock.Job()
+	if err := state.UpsertJob(1, job); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Create a request to update the priority of the job
+	req := &structs.JobUpdatePriorityRequest{
+		JobID:    job.ID,
+		Priority: 80,
+	}
+	buf, err := structs.Encode(structs.JobUpdatePriorityRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Check that the priority was updated properly
+	ws := memdb.NewWatchSet()
+	jout, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if jout == nil || jout.Priority != 80 || !jout.NoPlacementChange || jout.JobModifyIndex != job.JobModifyIndex {
+		t.Fatalf("bad: %#v", jout)
+	}
+}
+
 func TestFSM_DeploymentPromotion(t *testing.T) {
 	t.Parallel()
 	fsm := testFSM(t)
@@ -1516,66 +1760,598 @@ func TestFSM_DeleteDeployment(t *testing.T) {
 	}
 }
 
-func testSnapshotRestore(t *testing.T, fsm *nomadFSM) *nomadFSM {
-	// Snapshot
-	snap, err := fsm.Snapshot()
+func TestFSM_UpsertServiceRegistrations(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+	req := structs.ServiceRegistrationUpsertRequest{
+		Services: []*structs.ServiceRegistration{s1, s2},
+	}
+	buf, err := structs.Encode(structs.ServiceRegistrationUpsertRequestType, req)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	defer snap.Release()
 
-	// Persist
-	buf := bytes.NewBuffer(nil)
-	sink := &MockSink{buf, false}
-	if err := snap.Persist(sink); err != nil {
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	ws := memdb.NewWatchSet()
+	for _, s := range []*structs.ServiceRegistration{s1, s2} {
+		out, err := fsm.State().ServiceRegistrationByID(ws, s.ID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out == nil {
+			t.Fatalf("service registration %q not found", s.ID)
+		}
+		if out.CreateIndex != 1 {
+			t.Fatalf("bad index: %d", out.CreateIndex)
+		}
+	}
+}
+
+func TestFSM_DeleteServiceRegistrationsByID(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	s1 := mock.ServiceRegistration()
+	if err := fsm.State().UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1}); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
-	// Try to restore on a new FSM
-	fsm2 := testFSM(t)
-	snap, err = fsm2.Snapshot()
+	req := structs.ServiceRegistrationDeleteByIDRequest{
+		IDs: []string{s1.ID},
+	}
+	buf, err := structs.Encode(structs.ServiceRegistrationDeleteByIDRequestType, req)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-	defer snap.Release()
 
-	abandonCh := fsm2.State().AbandonCh()
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
 
-	// Do a restore
-	if err := fsm2.Restore(sink); err != nil {
+	// Verify we are deleted
+	out, err := fsm.State().ServiceRegistrationByID(nil, s1.ID)
+	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
-
-	select {
-	case <-abandonCh:
-	default:
-		t.Fatalf("bad")
+	if out != nil {
+		t.Fatalf("service registration not deleted: %#v", out)
 	}
-
-	return fsm2
 }
 
-func TestFSM_SnapshotRestore_Nodes(t *testing.T) {
+func TestFSM_UpsertNamespaces(t *testing.T) {
 	t.Parallel()
-	// Add some state
 	fsm := testFSM(t)
-	state := fsm.State()
-	node1 := mock.Node()
-	state.UpsertNode(1000, node1)
-	node2 := mock.Node()
-	state.UpsertNode(1001, node2)
 
-	// Verify the contents
-	fsm2 := testSnapshotRestore(t, fsm)
-	state2 := fsm2.State()
-	ws := memdb.NewWatchSet()
-	out1, _ := state2.NodeByID(ws, node1.ID)
-	out2, _ := state2.NodeByID(ws, node2.ID)
-	if !reflect.DeepEqual(node1, out1) {
-		t.Fatalf("bad: \n%#v\n%#v", out1, node1)
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
+	req := structs.NamespaceUpsertRequest{
+		Namespaces: []*structs.Namespace{ns1, ns2},
 	}
-	if !reflect.DeepEqual(node2, out2) {
-		t.Fatalf("bad: \n%#v\n%#v", out2, node2)
+	buf, err := structs.Encode(structs.NamespaceUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	ws := memdb.NewWatchSet()
+	for _, ns := range []*structs.Namespace{ns1, ns2} {
+		out, err := fsm.State().NamespaceByName(ws, ns.Name)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out == nil {
+			t.Fatalf("namespace %q not found", ns.Name)
+		}
+		if out.CreateIndex != 1 {
+			t.Fatalf("bad index: %d", out.CreateIndex)
+		}
+	}
+}
+
+func TestFSM_DeleteNamespaces(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	// Upsert a namespace
+	ns := mock.Namespace()
+	if err := state.UpsertNamespaces(1, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.NamespaceDeleteRequest{
+		Namespaces: []string{ns.Name},
+	}
+	buf, err := structs.Encode(structs.NamespaceDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are NOT registered
+	ws := memdb.NewWatchSet()
+	out, err := state.NamespaceByName(ws, ns.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("namespace found!")
+	}
+}
+
+func TestFSM_UpsertACLPolicies(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	policy := mock.ACLPolicy()
+	req := structs.ACLPolicyUpsertRequest{
+		Policies: []*structs.ACLPolicy{policy},
+	}
+	buf, err := structs.Encode(structs.ACLPolicyUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ACLPolicyByName(nil, policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("policy %q not found", policy.Name)
+	}
+}
+
+func TestFSM_DeleteACLPolicies(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	policy := mock.ACLPolicy()
+	if err := state.UpsertACLPolicies(1, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.ACLPolicyDeleteRequest{
+		Names: []string{policy.Name},
+	}
+	buf, err := structs.Encode(structs.ACLPolicyDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are NOT registered
+	out, err := state.ACLPolicyByName(nil, policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("policy found!")
+	}
+}
+
+func TestFSM_UpsertACLRoles(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	role := mock.ACLRole()
+	req := structs.ACLRoleUpsertRequest{
+		Roles: []*structs.ACLRole{role},
+	}
+	buf, err := structs.Encode(structs.ACLRoleUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ACLRoleByName(nil, role.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("role %q not found", role.Name)
+	}
+}
+
+func TestFSM_DeleteACLRoles(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	role := mock.ACLRole()
+	if err := state.UpsertACLRoles(1, []*structs.ACLRole{role}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.ACLRoleDeleteRequest{
+		Names: []string{role.Name},
+	}
+	buf, err := structs.Encode(structs.ACLRoleDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are NOT registered
+	out, err := state.ACLRoleByName(nil, role.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("role found!")
+	}
+}
+
+func TestFSM_UpsertACLAuthMethods(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	method := mock.ACLAuthMethod()
+	req := structs.ACLAuthMethodUpsertRequest{
+		AuthMethods: []*structs.ACLAuthMethod{method},
+	}
+	buf, err := structs.Encode(structs.ACLAuthMethodUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ACLAuthMethodByName(nil, method.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("auth method %q not found", method.Name)
+	}
+}
+
+func TestFSM_DeleteACLAuthMethods(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	method := mock.ACLAuthMethod()
+	if err := state.UpsertACLAuthMethods(1, []*structs.ACLAuthMethod{method}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.ACLAuthMethodDeleteRequest{
+		Names: []string{method.Name},
+	}
+	buf, err := structs.Encode(structs.ACLAuthMethodDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are NOT registered
+	out, err := state.ACLAuthMethodByName(nil, method.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("auth method found!")
+	}
+}
+
+func TestFSM_UpsertACLBindingRules(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	rule := mock.ACLBindingRule()
+	req := structs.ACLBindingRuleUpsertRequest{
+		BindingRules: []*structs.ACLBindingRule{rule},
+	}
+	buf, err := structs.Encode(structs.ACLBindingRuleUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ACLBindingRuleByID(nil, rule.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("binding rule %q not found", rule.ID)
+	}
+}
+
+func TestFSM_DeleteACLBindingRules(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	rule := mock.ACLBindingRule()
+	if err := state.UpsertACLBindingRules(1, []*structs.ACLBindingRule{rule}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.ACLBindingRuleDeleteRequest{
+		IDs: []string{rule.ID},
+	}
+	buf, err := structs.Encode(structs.ACLBindingRuleDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are NOT registered
+	out, err := state.ACLBindingRuleByID(nil, rule.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("binding rule found!")
+	}
+}
+
+func TestFSM_UpsertACLTokens(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	token := mock.ACLToken()
+	req := structs.ACLTokenUpsertRequest{
+		Tokens: []*structs.ACLToken{token},
+	}
+	buf, err := structs.Encode(structs.ACLTokenUpsertRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are registered
+	out, err := fsm.State().ACLTokenBySecretID(nil, token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("token %q not found", token.AccessorID)
+	}
+}
+
+func TestFSM_DeleteACLTokens(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+	state := fsm.State()
+
+	token := mock.ACLToken()
+	if err := state.UpsertACLTokens(1, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := structs.ACLTokenDeleteRequest{
+		AccessorIDs: []string{token.AccessorID},
+	}
+	buf, err := structs.Encode(structs.ACLTokenDeleteRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify we are NOT registered
+	out, err := state.ACLTokenByAccessorID(nil, token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("token found!")
+	}
+}
+
+func TestFSM_BootstrapACLTokens(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	token := mock.ACLManagementToken()
+	req := structs.ACLTokenBootstrapRequest{
+		Token: token,
+	}
+	buf, err := structs.Encode(structs.ACLTokenBootstrapRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	out, err := fsm.State().ACLTokenByAccessorID(nil, token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("token %q not found", token.AccessorID)
+	}
+
+	// A second bootstrap fails
+	req.Token = mock.ACLManagementToken()
+	buf, err = structs.Encode(structs.ACLTokenBootstrapRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp := fsm.Apply(makeLog(buf)); resp == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFSM_SchedulerConfig(t *testing.T) {
+	t.Parallel()
+	fsm := testFSM(t)
+
+	req := structs.SchedulerSetConfigRequest{
+		Config: structs.SchedulerConfiguration{
+			SchedulerAlgorithm: structs.SchedulerAlgorithmSpread,
+		},
+	}
+	buf, err := structs.Encode(structs.SchedulerConfigRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify the config was set
+	_, config, err := fsm.State().SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if config.SchedulerAlgorithm != structs.SchedulerAlgorithmSpread {
+		t.Fatalf("bad: %#v", config)
+	}
+	if config.CreateIndex != 1 || config.ModifyIndex != 1 {
+		t.Fatalf("bad index: %#v", config)
+	}
+
+	// A CAS update with a stale index is not applied
+	req.CAS = true
+	req.Config.SchedulerAlgorithm = structs.SchedulerAlgorithmBinpack
+	req.Config.ModifyIndex = 0
+	buf, err = structs.Encode(structs.SchedulerConfigRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp = fsm.Apply(makeLog(buf))
+	if updated, ok := resp.(bool); !ok || updated {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	_, config, err = fsm.State().SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if config.SchedulerAlgorithm != structs.SchedulerAlgorithmSpread {
+		t.Fatalf("bad: %#v", config)
+	}
+}
+
+func testSnapshotRestore(t *testing.T, fsm *nomadFSM) *nomadFSM {
+	// Snapshot
+	snap, err := fsm.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer snap.Release()
+
+	// Persist
+	buf := bytes.NewBuffer(nil)
+	sink := &MockSink{buf, false}
+	if err := snap.Persist(sink); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Try to restore on a new FSM
+	fsm2 := testFSM(t)
+	snap, err = fsm2.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer snap.Release()
+
+	abandonCh := fsm2.State().AbandonCh()
+
+	// Do a restore
+	if err := fsm2.Restore(sink); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	select {
+	case <-abandonCh:
+	default:
+		t.Fatalf("bad")
+	}
+
+	return fsm2
+}
+
+func TestFSM_SnapshotRestore_Nodes(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	node1 := mock.Node()
+	state.UpsertNode(1000, node1)
+	node2 := mock.Node()
+	state.UpsertNode(1001, node2)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	out1, _ := state2.NodeByID(ws, node1.ID)
+	out2, _ := state2.NodeByID(ws, node2.ID)
+	if !reflect.DeepEqual(node1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, node1)
+	}
+	if !reflect.DeepEqual(node2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, node2)
 	}
 }
 
@@ -1804,6 +2580,52 @@ func TestFSM_SnapshotRestore_VaultAccessors(t *testing.T) {
 	}
 }
 
+func TestFSM_SnapshotRestore_SITokenAccessors(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	a1 := mock.SITokenAccessor()
+	a2 := mock.SITokenAccessor()
+	state.UpsertSITokenAccessors(1000, []*structs.SITokenAccessor{a1, a2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	out1, _ := state2.SITokenAccessor(ws, a1.AccessorID)
+	out2, _ := state2.SITokenAccessor(ws, a2.AccessorID)
+	if !reflect.DeepEqual(a1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, a1)
+	}
+	if !reflect.DeepEqual(a2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, a2)
+	}
+}
+
+func TestFSM_SnapshotRestore_ServiceRegistrations(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+	state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	out1, _ := state2.ServiceRegistrationByID(ws, s1.ID)
+	out2, _ := state2.ServiceRegistrationByID(ws, s2.ID)
+	if !reflect.DeepEqual(s1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, s1)
+	}
+	if !reflect.DeepEqual(s2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, s2)
+	}
+}
+
 func TestFSM_SnapshotRestore_JobVersions(t *testing.T) {
 	t.Parallel()
 	// Add some state
@@ -1856,6 +2678,139 @@ func TestFSM_SnapshotRestore_Deployments(t *testing.T) {
 	}
 }
 
+func TestFSM_SnapshotRestore_Namespaces(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
+	state.UpsertNamespaces(1000, []*structs.Namespace{ns1, ns2})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	ws := memdb.NewWatchSet()
+	out1, _ := state2.NamespaceByName(ws, ns1.Name)
+	out2, _ := state2.NamespaceByName(ws, ns2.Name)
+	if !reflect.DeepEqual(ns1, out1) {
+		t.Fatalf("bad: \n%#v\n%#v", out1, ns1)
+	}
+	if !reflect.DeepEqual(ns2, out2) {
+		t.Fatalf("bad: \n%#v\n%#v", out2, ns2)
+	}
+}
+
+func TestFSM_SnapshotRestore_ACLPolicy(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	policy := mock.ACLPolicy()
+	state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out, _ := state2.ACLPolicyByName(nil, policy.Name)
+	if !reflect.DeepEqual(policy, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, policy)
+	}
+}
+
+func TestFSM_SnapshotRestore_ACLRole(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	role := mock.ACLRole()
+	state.UpsertACLRoles(1000, []*structs.ACLRole{role})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out, _ := state2.ACLRoleByName(nil, role.Name)
+	if !reflect.DeepEqual(role, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, role)
+	}
+}
+
+func TestFSM_SnapshotRestore_ACLAuthMethod(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	method := mock.ACLAuthMethod()
+	state.UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{method})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out, _ := state2.ACLAuthMethodByName(nil, method.Name)
+	if !reflect.DeepEqual(method, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, method)
+	}
+}
+
+func TestFSM_SnapshotRestore_ACLBindingRule(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	rule := mock.ACLBindingRule()
+	state.UpsertACLBindingRules(1000, []*structs.ACLBindingRule{rule})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out, _ := state2.ACLBindingRuleByID(nil, rule.ID)
+	if !reflect.DeepEqual(rule, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, rule)
+	}
+}
+
+func TestFSM_SnapshotRestore_ACLTokens(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	token := mock.ACLToken()
+	state.UpsertACLTokens(1000, []*structs.ACLToken{token})
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	out, _ := state2.ACLTokenByAccessorID(nil, token.AccessorID)
+	if out == nil || out.SecretID != token.SecretID || out.Hash == nil {
+		t.Fatalf("bad: \n%#v\n%#v", out, token)
+	}
+}
+
+func TestFSM_SnapshotRestore_SchedulerConfig(t *testing.T) {
+	t.Parallel()
+	// Add some state
+	fsm := testFSM(t)
+	state := fsm.State()
+	config := &structs.SchedulerConfiguration{
+		SchedulerAlgorithm: structs.SchedulerAlgorithmSpread,
+	}
+	state.SchedulerSetConfig(1000, config)
+
+	// Verify the contents
+	fsm2 := testSnapshotRestore(t, fsm)
+	state2 := fsm2.State()
+	index, out, err := state2.SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+	if !reflect.DeepEqual(config, out) {
+		t.Fatalf("bad: \n%#v\n%#v", out, config)
+	}
+}
+
 func TestFSM_SnapshotRestore_AddMissingSummary(t *testing.T) {
 	t.Parallel()
 	// Add some state
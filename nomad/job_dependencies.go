@@ -0,0 +1,328 @@
+package nomad
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// jobDependenciesRetryInterval is the interval at which the leader
+	// retries looking for held evaluations to release after a failure.
+	jobDependenciesRetryInterval = 5 * time.Second
+)
+
+// releaseHeldEvals releases the evaluations held until the jobs their job
+// depends on complete, until the stop channel is closed. The held evaluations
+// are looked at again whenever one of them, their job or a dependency
+// changes.
+func (s *Server) releaseHeldEvals(stopCh chan struct{}) {
+	for {
+		state := s.fsm.State()
+		ws := memdb.NewWatchSet()
+		ws.Add(state.AbandonCh())
+		if err := s.applyHeldEvalUpdates(ws, state); err != nil {
+			s.logger.Printf("[ERR] nomad.job_dependencies: failed to release held evaluations: %v", err)
+			select {
+			case <-time.After(jobDependenciesRetryInterval):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		// The stop channel is watched along with the evaluations and jobs
+		ws.Add(stopCh)
+		ws.Watch(nil)
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+	}
+}
+
+// applyHeldEvalUpdates applies the updates of the held evaluations. Once
+// updated, the evaluations aren't held anymore so the watch set fires right
+// away to look at the remaining ones again.
+func (s *Server) applyHeldEvalUpdates(ws memdb.WatchSet, state *state.StateStore) error {
+	updates, err := heldEvalUpdates(ws, state)
+	if err != nil || len(updates) == 0 {
+		return err
+	}
+	req := structs.EvalUpdateRequest{
+		Evals: updates,
+	}
+	_, _, err = s.raftApply(structs.EvalUpdateRequestType, &req)
+	return err
+}
+
+// jobDependenciesIndex returns the index of the tables whose changes may
+// release a held evaluation.
+func jobDependenciesIndex(state *state.StateStore) (uint64, error) {
+	var max uint64
+	for _, table := range []string{"jobs", "evals"} {
+		index, err := state.Index(table)
+		if err != nil {
+			return 0, err
+		}
+		if index > max {
+			max = index
+		}
+	}
+	return max, nil
+}
+
+// heldEvalUpdates returns the evaluation updates releasing the held
+// evaluations whose dependencies completed. The held evaluation is completed
+// and followed by a new one for the scheduler. Held evaluations of jobs that
+// were stopped, purged or registered again are cancelled, and those of jobs
+// with a dependency that failed or doesn't exist are failed. The held
+// evaluations, their jobs and the dependencies are added to the watch set.
+func heldEvalUpdates(ws memdb.WatchSet, state *state.StateStore) ([]*structs.Evaluation, error) {
+	iter, err := state.EvalsHeld(ws)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []*structs.Evaluation
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		eval := raw.(*structs.Evaluation)
+
+		job, err := state.JobByID(ws, eval.JobID)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil || job.Stopped() || eval.JobModifyIndex < job.JobModifyIndex {
+			cancel := eval.Copy()
+			cancel.Status = structs.EvalStatusCancelled
+			cancel.StatusDescription = "job was stopped or updated while held"
+			updates = append(updates, cancel)
+			continue
+		}
+
+		pending, failure, err := pendingJobDependencies(ws, state, job)
+		if err != nil {
+			return nil, err
+		}
+		if failure != "" {
+			failed := eval.Copy()
+			failed.Status = structs.EvalStatusFailed
+			failed.StatusDescription = failure
+			updates = append(updates, failed)
+			continue
+		}
+		if len(pending) != 0 {
+			continue
+		}
+
+		next := &structs.Evaluation{
+			ID:             structs.GenerateUUID(),
+			Priority:       job.Priority,
+			Type:           job.Type,
+			TriggeredBy:    structs.EvalTriggerJobDependencies,
+			JobID:          job.ID,
+			JobModifyIndex: eval.JobModifyIndex,
+			Status:         structs.EvalStatusPending,
+			PreviousEval:   eval.ID,
+		}
+		release := eval.Copy()
+		release.Status = structs.EvalStatusComplete
+		release.StatusDescription = "dependencies completed"
+		release.NextEval = next.ID
+		updates = append(updates, release, next)
+	}
+	return updates, nil
+}
+
+// pendingJobDependencies returns the jobs the job depends on that haven't
+// completed, and the reason the job can't run if one of them failed or
+// doesn't exist.
+func pendingJobDependencies(ws memdb.WatchSet, state *state.StateStore, job *structs.Job) ([]string, string, error) {
+	var pending []string
+	for _, id := range job.DependsOn {
+		status, err := jobDependencyStatus(ws, state, id)
+		if err != nil {
+			return nil, "", err
+		}
+		switch status {
+		case structs.JobDependencyStatusComplete:
+		case structs.JobDependencyStatusFailed:
+			return nil, fmt.Sprintf("dependency %q failed", id), nil
+		case structs.JobDependencyStatusMissing:
+			return nil, fmt.Sprintf("dependency %q doesn't exist", id), nil
+		default:
+			pending = append(pending, id)
+		}
+	}
+	return pending, "", nil
+}
+
+// jobDependencyStatus returns the status of the job as a dependency.
+func jobDependencyStatus(ws memdb.WatchSet, state *state.StateStore, id string) (string, error) {
+	job, err := state.JobByID(ws, id)
+	if err != nil {
+		return "", err
+	}
+	var allocs []*structs.Allocation
+	if job != nil {
+		allocs, err = state.AllocsByJob(ws, id, false)
+		if err != nil {
+			return "", err
+		}
+	}
+	return structs.JobDependencyStatus(job, allocs), nil
+}
+
+// validateJobDependencies returns an error if registering the job would
+// create a cycle of job dependencies.
+func validateJobDependencies(snap *state.StateSnapshot, job *structs.Job) error {
+	visited := make(map[string]struct{})
+
+	// walk returns the path of dependencies from the job back to itself
+	// through the given job, if there is one
+	var walk func(id string, path []string) ([]string, error)
+	walk = func(id string, path []string) ([]string, error) {
+		path = append(path, id)
+		if id == job.ID {
+			return path, nil
+		}
+		if _, ok := visited[id]; ok {
+			return nil, nil
+		}
+		visited[id] = struct{}{}
+
+		dep, err := snap.JobByID(nil, id)
+		if err != nil || dep == nil {
+			return nil, err
+		}
+		for _, next := range dep.DependsOn {
+			cycle, err := walk(next, path)
+			if err != nil || cycle != nil {
+				return cycle, err
+			}
+		}
+		return nil, nil
+	}
+
+	for _, id := range job.DependsOn {
+		cycle, err := walk(id, []string{job.ID})
+		if err != nil {
+			return err
+		}
+		if cycle != nil {
+			return fmt.Errorf("Job dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
+
+// jobDependencyGraph returns the jobs connected to the given job by
+// dependencies, ordered so that jobs come after their dependencies. It
+// returns nil if the job doesn't exist.
+func jobDependencyGraph(ws memdb.WatchSet, state *state.StateStore, jobID string) ([]*structs.JobDAGNode, error) {
+	iter, err := state.Jobs(ws)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make(map[string]*structs.Job)
+	dependents := make(map[string][]string)
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		job := raw.(*structs.Job)
+		jobs[job.ID] = job
+		for _, id := range job.DependsOn {
+			dependents[id] = append(dependents[id], job.ID)
+		}
+	}
+	if _, ok := jobs[jobID]; !ok {
+		return nil, nil
+	}
+
+	// Collect the jobs connected to the job in either direction
+	connected := map[string]struct{}{jobID: {}}
+	queue := []string{jobID}
+	for len(queue) != 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		var edges []string
+		if job, ok := jobs[id]; ok {
+			edges = append(edges, job.DependsOn...)
+		}
+		edges = append(edges, dependents[id]...)
+		for _, next := range edges {
+			if _, ok := connected[next]; !ok {
+				connected[next] = struct{}{}
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	// Order the jobs topologically, breaking ties by ID
+	remaining := make(map[string]int, len(connected))
+	for id := range connected {
+		if job, ok := jobs[id]; ok {
+			remaining[id] = len(job.DependsOn)
+		}
+	}
+	var ready []string
+	for id := range connected {
+		if remaining[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	nodes := make([]*structs.JobDAGNode, 0, len(connected))
+	for len(ready) != 0 {
+		sort.Strings(ready)
+		id := ready[0]
+		ready = ready[1:]
+
+		node, err := jobDAGNode(ws, state, id, jobs[id])
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+
+		for _, next := range dependents[id] {
+			remaining[next]--
+			if remaining[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// jobDAGNode returns the node of the job in the graph of job dependencies.
+func jobDAGNode(ws memdb.WatchSet, state *state.StateStore, id string, job *structs.Job) (*structs.JobDAGNode, error) {
+	status, err := jobDependencyStatus(ws, state, id)
+	if err != nil {
+		return nil, err
+	}
+	node := &structs.JobDAGNode{
+		JobID:  id,
+		Status: status,
+	}
+	if job == nil {
+		return node, nil
+	}
+	node.DependsOn = job.DependsOn
+
+	evals, err := state.EvalsByJob(ws, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, eval := range evals {
+		if eval.Status == structs.EvalStatusHeld {
+			node.Held = true
+			break
+		}
+	}
+	return node, nil
+}
@@ -0,0 +1,318 @@
+package nomad
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestHeldEvalUpdates(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	dep := mock.Job()
+	dep.Type = structs.JobTypeBatch
+	if err := state.UpsertJob(1000, dep); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.DependsOn = []string{dep.ID}
+	if err := state.UpsertJob(1001, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A held eval of the current job and one of a purged job
+	held := mock.Eval()
+	held.JobID = job.ID
+	held.JobModifyIndex = job.JobModifyIndex
+	held.Status = structs.EvalStatusHeld
+	purged := mock.Eval()
+	purged.Status = structs.EvalStatusHeld
+	if err := state.UpsertEvals(1002, []*structs.Evaluation{held, purged}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Only the eval of the purged job is cancelled while the dependency runs
+	updates, err := heldEvalUpdates(nil, state)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(updates) != 1 || updates[0].ID != purged.ID || updates[0].Status != structs.EvalStatusCancelled {
+		t.Fatalf("bad: %#v", updates)
+	}
+	if err := state.UpsertEvals(1003, updates); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Complete the dependency
+	alloc := mock.Alloc()
+	alloc.Job = dep
+	alloc.JobID = dep.ID
+	alloc.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpsertAllocs(1004, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The held eval is completed and followed by a new one
+	updates, err = heldEvalUpdates(nil, state)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("bad: %#v", updates)
+	}
+	release, next := updates[0], updates[1]
+	if release.ID != held.ID || release.Status != structs.EvalStatusComplete || release.NextEval != next.ID {
+		t.Fatalf("bad: %#v", release)
+	}
+	if next.Status != structs.EvalStatusPending || next.TriggeredBy != structs.EvalTriggerJobDependencies ||
+		next.JobID != job.ID || next.PreviousEval != held.ID {
+		t.Fatalf("bad: %#v", next)
+	}
+}
+
+func TestHeldEvalUpdates_FailedDependencies(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	// A dependency whose allocation failed and one that was deregistered
+	failedDep := mock.Job()
+	failedDep.Type = structs.JobTypeBatch
+	deregistered := mock.Job()
+	deregistered.Type = structs.JobTypeBatch
+	for i, dep := range []*structs.Job{failedDep, deregistered} {
+		if err := state.UpsertJob(uint64(1000+i), dep); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	alloc := mock.Alloc()
+	alloc.Job = failedDep
+	alloc.JobID = failedDep.ID
+	alloc.ClientStatus = structs.AllocClientStatusFailed
+	if err := state.UpsertAllocs(1002, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.DeleteJob(1003, deregistered.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Held evals of jobs depending on them and on a job that never existed
+	expected := map[string]string{}
+	var evals []*structs.Evaluation
+	for i, dep := range []string{failedDep.ID, deregistered.ID, "never-registered"} {
+		job := mock.Job()
+		job.Type = structs.JobTypeBatch
+		job.DependsOn = []string{dep}
+		if err := state.UpsertJob(uint64(1004+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		eval := mock.Eval()
+		eval.JobID = job.ID
+		eval.JobModifyIndex = job.JobModifyIndex
+		eval.Status = structs.EvalStatusHeld
+		evals = append(evals, eval)
+		expected[eval.ID] = dep
+	}
+	if err := state.UpsertEvals(1010, evals); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// All of them are failed
+	updates, err := heldEvalUpdates(nil, state)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(updates) != 3 {
+		t.Fatalf("bad: %#v", updates)
+	}
+	for _, update := range updates {
+		dep, ok := expected[update.ID]
+		if !ok || update.Status != structs.EvalStatusFailed || !strings.Contains(update.StatusDescription, dep) {
+			t.Fatalf("bad: %#v", update)
+		}
+	}
+}
+
+func TestHeldEvalUpdates_Watch(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	dep := mock.Job()
+	dep.Type = structs.JobTypeBatch
+	if err := state.UpsertJob(1000, dep); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.DependsOn = []string{dep.ID}
+	if err := state.UpsertJob(1001, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	held := mock.Eval()
+	held.JobID = job.ID
+	held.JobModifyIndex = job.JobModifyIndex
+	held.Status = structs.EvalStatusHeld
+	if err := state.UpsertEvals(1002, []*structs.Evaluation{held}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ws := memdb.NewWatchSet()
+	updates, err := heldEvalUpdates(ws, state)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(updates) != 0 {
+		t.Fatalf("bad: %#v", updates)
+	}
+
+	// Completing the dependency fires the watch set
+	alloc := mock.Alloc()
+	alloc.Job = dep
+	alloc.JobID = dep.ID
+	alloc.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpsertAllocs(1003, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if timedOut := ws.Watch(time.After(time.Second)); timedOut {
+		t.Fatalf("watch set didn't fire")
+	}
+}
+
+func TestLeader_ReleaseHeldEvals(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.DependsOn = []string{"never-registered"}
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	held := mock.Eval()
+	held.JobID = job.ID
+	held.JobModifyIndex = job.JobModifyIndex
+	held.Status = structs.EvalStatusHeld
+	if err := state.UpsertEvals(1001, []*structs.Evaluation{held}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The leader fails the held eval as soon as it is written
+	testutil.WaitForResult(func() (bool, error) {
+		eval, err := state.EvalByID(nil, held.ID)
+		if err != nil {
+			return false, err
+		}
+		if eval.Status != structs.EvalStatusFailed {
+			return false, fmt.Errorf("eval status is %q", eval.Status)
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("held eval not failed: %v", err)
+	})
+}
+
+func TestValidateJobDependencies(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	a := mock.Job()
+	a.ID = "a"
+	a.Type = structs.JobTypeBatch
+	b := a.Copy()
+	b.ID = "b"
+	b.DependsOn = []string{"a", "missing"}
+	c := a.Copy()
+	c.ID = "c"
+	c.DependsOn = []string{"b"}
+	for i, job := range []*structs.Job{a, b, c} {
+		if err := state.UpsertJob(uint64(1000+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	snap, err := state.Snapshot()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Depending on jobs that don't depend on the job is fine
+	d := a.Copy()
+	d.ID = "d"
+	d.DependsOn = []string{"b", "c"}
+	if err := validateJobDependencies(snap, d); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Making a depend on c creates a cycle
+	update := a.Copy()
+	update.DependsOn = []string{"c"}
+	err = validateJobDependencies(snap, update)
+	if err == nil || !strings.Contains(err.Error(), "a -> c -> b -> a") {
+		t.Fatalf("expected cycle error: %v", err)
+	}
+}
+
+func TestJobDependencyGraph(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	a := mock.Job()
+	a.ID = "a"
+	a.Type = structs.JobTypeBatch
+	b := a.Copy()
+	b.ID = "b"
+	b.DependsOn = []string{"missing"}
+	c := a.Copy()
+	c.ID = "c"
+	c.DependsOn = []string{"b", "a"}
+	unrelated := a.Copy()
+	unrelated.ID = "unrelated"
+	for i, job := range []*structs.Job{c, b, a, unrelated} {
+		if err := state.UpsertJob(uint64(1000+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	held := mock.Eval()
+	held.JobID = c.ID
+	held.Status = structs.EvalStatusHeld
+	if err := state.UpsertEvals(1004, []*structs.Evaluation{held}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	nodes, err := jobDependencyGraph(nil, state, "b")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var order []string
+	for _, node := range nodes {
+		order = append(order, node.JobID)
+	}
+	if strings.Join(order, ",") != "a,missing,b,c" {
+		t.Fatalf("bad order: %v", order)
+	}
+	if nodes[1].Status != structs.JobDependencyStatusMissing {
+		t.Fatalf("bad: %#v", nodes[1])
+	}
+	if !nodes[3].Held || nodes[2].Held {
+		t.Fatalf("bad: %#v %#v", nodes[2], nodes[3])
+	}
+
+	// A job that doesn't exist has no graph
+	nodes, err = jobDependencyGraph(nil, state, "missing")
+	if err != nil || nodes != nil {
+		t.Fatalf("bad: %v %#v", err, nodes)
+	}
+}
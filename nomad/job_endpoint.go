@@ -12,10 +12,12 @@ import (
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/acl"
 	"github.com/hashicorp/nomad/client/driver"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/scheduler"
 )
 
@@ -24,8 +26,8 @@ const (
 	// enforcing the job modify index during registers.
 	RegisterEnforceIndexErrPrefix = "Enforcing job modify index"
 
-	// DispatchPayloadSizeLimit is the maximum size of the uncompressed input
-	// data payload.
+	// DispatchPayloadSizeLimit is the default maximum size of the
+	// uncompressed input data payload.
 	DispatchPayloadSizeLimit = 16 * 1024
 )
 
@@ -42,6 +44,30 @@ var (
 // Job endpoint is used for job interactions
 type Job struct {
 	srv *Server
+
+	// mutators and validators form the admission chain jobs go through when
+	// they are registered, planned or validated.
+	mutators   []jobMutator
+	validators []jobValidator
+}
+
+// NewJobEndpoints creates a Job endpoint with the built-in admission
+// controllers followed by the configured admission policies.
+func NewJobEndpoints(s *Server) *Job {
+	imageVerification := jobImageVerification{config: s.config.ImageVerification}
+	return &Job{
+		srv: s,
+		mutators: []jobMutator{
+			jobCanonicalizer{},
+			jobConnectHook{},
+			jobImpliedConstraints{},
+			imageVerification,
+		},
+		validators: append([]jobValidator{
+			jobValidate{},
+			imageVerification,
+		}, newJobAdmissionPolicies(s.config.AdmissionPolicies)...),
+	}
 }
 
 // Register is used to upsert a job for scheduling
@@ -50,26 +76,20 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 		return err
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "register"}, time.Now())
+	return j.register(args, reply, acl.NamespaceCapabilitySubmitJob)
+}
 
+// register upserts the job if the token of the request is granted the
+// capability on the namespaces of the job and of the job it updates.
+func (j *Job) register(args *structs.JobRegisterRequest, reply *structs.JobRegisterResponse, capability string) error {
 	// Validate the arguments
 	if args.Job == nil {
 		return fmt.Errorf("missing job for registration")
 	}
 
-	// Initialize the job fields (sets defaults and any necessary init work).
-	canonicalizeWarnings := args.Job.Canonicalize()
-
-	// Add implicit constraints
-	setImplicitConstraints(args.Job)
-
-	// Validate the job and capture any warnings
-	err, warnings := validateJob(args.Job)
-	if err != nil {
-		return err
-	}
-
-	// Set the warning message
-	reply.Warnings = structs.MergeMultierrorWarnings(warnings, canonicalizeWarnings)
+	span := j.srv.startRPCSpan("Job.Register", args.TraceParent)
+	span.SetAttribute("job_id", args.Job.ID)
+	defer span.End()
 
 	// Lookup the job
 	snap, err := j.srv.fsm.State().Snapshot()
@@ -82,6 +102,61 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 		return err
 	}
 
+	// The token must be granted the capability on the namespace of the job,
+	// and on the one of the job it updates, before the job is passed to the
+	// admission controllers
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(args.Job), capability); err != nil {
+		return err
+	}
+	if existingJob != nil {
+		if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(existingJob), capability); err != nil {
+			return err
+		}
+	}
+
+	// Overriding the denials of soft-mandatory admission policies requires
+	// its own capability and a justification
+	if args.PolicyOverride {
+		if strings.TrimSpace(args.PolicyOverrideJustification) == "" {
+			return fmt.Errorf("policy override requires a justification")
+		}
+		if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(args.Job), acl.NamespaceCapabilityPolicyOverride); err != nil {
+			return err
+		}
+	}
+
+	// Run the job through the admission controllers and capture any warnings.
+	// The justification of an override is recorded in the version of the job
+	// it admits.
+	warnings, err := j.admissionControllers(args.Job)
+	args.Job.PolicyOverrideJustification = ""
+	if err != nil && args.PolicyOverride {
+		var overridden []error
+		overridden, err = overrideSoftMandatoryDenials(err)
+		for _, denial := range overridden {
+			j.srv.logger.Printf("[WARN] nomad.job: overriding denial of job %q with justification %q: %v",
+				args.Job.ID, args.PolicyOverrideJustification, denial)
+			warnings = append(warnings, fmt.Errorf("overridden: %v", denial))
+		}
+		if len(overridden) != 0 {
+			args.Job.PolicyOverrideJustification = args.PolicyOverrideJustification
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Set the warning message
+	reply.Warnings = structs.MergeMultierrorWarnings(warnings...)
+
+	// Scaling only changes the counts of the task groups, so only the jobs
+	// submitted must be allowed to mount their host volumes
+	if capability != acl.NamespaceCapabilityScaleJob {
+		if err := j.srv.checkHostVolumes(args.AuthToken, args.Job); err != nil {
+			return err
+		}
+	}
+
 	// If EnforceIndex set, check it before trying to apply
 	if args.EnforceIndex {
 		jmi := args.JobModifyIndex
@@ -104,9 +179,38 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 		}
 	}
 
-	// Ensure that the job has permissions for the requested Vault tokens
+	// Ensure that the job's dependencies don't form a cycle
+	if err := validateJobDependencies(snap, args.Job); err != nil {
+		return err
+	}
+
+	// Ensure that the job's namespace exists
+	if err := validateJobNamespace(snap, args.Job); err != nil {
+		return err
+	}
+
+	// Ensure that the servers allow the requested Vault policies and roles
+	if err := validateJobVault(j.srv.config.VaultConfig, args.Job); err != nil {
+		return err
+	}
+
+	// Ensure that the servers allow the webhook of the notification
+	if n := args.Job.Notification; n != nil && !j.srv.config.JobNotification.Allows(n.Webhook) {
+		return fmt.Errorf("notification webhook %q not allowed by the servers' job_notification configuration", n.Webhook)
+	}
+
+	// Ensure that the servers can sign the identities of the tasks with a
+	// Vault role
 	policies := args.Job.VaultPolicies()
-	if len(policies) != 0 {
+	if roles := structs.VaultRolesSet(policies); len(roles) != 0 && j.srv.vaultIdentities == nil {
+		sort.Strings(roles)
+		return fmt.Errorf("Vault workload identities not enabled and Vault roles requested: %s",
+			strings.Join(roles, ", "))
+	}
+
+	// Ensure that the job has permissions for the requested Vault tokens.
+	// Tasks with a Vault role don't request policies.
+	if flatPolicies := structs.VaultPoliciesSet(policies); len(flatPolicies) != 0 {
 		vconf := j.srv.config.VaultConfig
 		if !vconf.IsEnabled() {
 			return fmt.Errorf("Vault not enabled and Vault policies requested")
@@ -131,7 +235,6 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 
 			// If we are given a root token it can access all policies
 			if !lib.StrContains(allowedPolicies, "root") {
-				flatPolicies := structs.VaultPoliciesSet(policies)
 				subset, offending := helper.SliceStringIsSubset(allowedPolicies, flatPolicies)
 				if !subset {
 					return fmt.Errorf("Passed Vault Token doesn't allow access to the following policies: %s",
@@ -144,13 +247,21 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 	// Clear the Vault token
 	args.Job.VaultToken = ""
 
+	// A registration may change the placements of the job
+	args.Job.NoPlacementChange = false
+
+	// Write the Consul configuration entries of the Connect gateways
+	if err := j.srv.setConsulGatewayConfigEntries(args.Job); err != nil {
+		return err
+	}
+
 	// Check if the job has changed at all
 	if existingJob == nil || existingJob.SpecChanged(args.Job) {
 		// Set the submit time
 		args.Job.SetSubmitTime()
 
 		// Commit this update via Raft
-		_, index, err := j.srv.raftApply(structs.JobRegisterRequestType, args)
+		_, index, err := j.srv.tracedRaftApply(span, structs.JobRegisterRequestType, args)
 		if err != nil {
 			j.srv.logger.Printf("[ERR] nomad.job: Register failed: %v", err)
 			return err
@@ -176,6 +287,7 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 		JobID:          args.Job.ID,
 		JobModifyIndex: reply.JobModifyIndex,
 		Status:         structs.EvalStatusPending,
+		TraceParent:    span.TraceParent(),
 	}
 	update := &structs.EvalUpdateRequest{
 		Evals:        []*structs.Evaluation{eval},
@@ -185,7 +297,7 @@ func (j *Job) Register(args *structs.JobRegisterRequest, reply *structs.JobRegis
 	// Commit this evaluation via Raft
 	// XXX: There is a risk of partial failure where the JobRegister succeeds
 	// but that the EvalUpdate does not.
-	_, evalIndex, err := j.srv.raftApply(structs.EvalUpdateRequestType, update)
+	_, evalIndex, err := j.srv.tracedRaftApply(span, structs.EvalUpdateRequestType, update)
 	if err != nil {
 		j.srv.logger.Printf("[ERR] nomad.job: Eval create failed: %v", err)
 		return err
@@ -276,11 +388,25 @@ func (j *Job) Summary(args *structs.JobSummaryRequest,
 		return err
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job_summary", "get_job_summary"}, time.Now())
+
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			ns, err := jobNamespace(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			if !aclObj.AllowJobRead(ns, args.JobID) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Look for job summary
 			out, err := state.JobSummaryByID(ws, args.JobID)
 			if err != nil {
@@ -311,14 +437,14 @@ func (j *Job) Summary(args *structs.JobSummaryRequest,
 func (j *Job) Validate(args *structs.JobValidateRequest, reply *structs.JobValidateResponse) error {
 	defer metrics.MeasureSince([]string{"nomad", "job", "validate"}, time.Now())
 
-	// Initialize the job fields (sets defaults and any necessary init work).
-	canonicalizeWarnings := args.Job.Canonicalize()
-
-	// Add implicit constraints
-	setImplicitConstraints(args.Job)
+	if args.Job != nil {
+		if err := j.srv.checkNamespaceRead(args.AuthToken, namespaceOfJob(args.Job)); err != nil {
+			return err
+		}
+	}
 
-	// Validate the job and capture any warnings
-	err, warnings := validateJob(args.Job)
+	// Run the job through the admission controllers and capture any warnings
+	warnings, err := j.admissionControllers(args.Job)
 	if err != nil {
 		if merr, ok := err.(*multierror.Error); ok {
 			for _, err := range merr.Errors {
@@ -332,7 +458,7 @@ func (j *Job) Validate(args *structs.JobValidateRequest, reply *structs.JobValid
 	}
 
 	// Set the warning message
-	reply.Warnings = structs.MergeMultierrorWarnings(warnings, canonicalizeWarnings)
+	reply.Warnings = structs.MergeMultierrorWarnings(warnings...)
 	reply.DriverConfigValidated = true
 	return nil
 }
@@ -363,6 +489,9 @@ func (j *Job) Revert(args *structs.JobRevertRequest, reply *structs.JobRegisterR
 	if cur == nil {
 		return fmt.Errorf("job %q not found", args.JobID)
 	}
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(cur), acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
 	if args.JobVersion == cur.Version {
 		return fmt.Errorf("can't revert to current version")
 	}
@@ -374,12 +503,18 @@ func (j *Job) Revert(args *structs.JobRevertRequest, reply *structs.JobRegisterR
 	if jobV == nil {
 		return fmt.Errorf("job %q at version %d not found", args.JobID, args.JobVersion)
 	}
+	if args.RequireStable && !jobV.Stable {
+		return fmt.Errorf("job %q at version %d is not stable", args.JobID, args.JobVersion)
+	}
 
-	// Build the register request
+	// Build the register request, recording the version the new version of
+	// the job is reverted to
 	reg := &structs.JobRegisterRequest{
 		Job:          jobV.Copy(),
 		WriteRequest: args.WriteRequest,
 	}
+	reg.Job.RevertVersion = helper.Uint64ToPtr(args.JobVersion)
+	reg.Job.RevertReason = ""
 
 	// If the request is enforcing the existing version do a check.
 	if args.EnforcePriorVersion != nil {
@@ -421,6 +556,9 @@ func (j *Job) Stable(args *structs.JobStabilityRequest, reply *structs.JobStabil
 	if jobV == nil {
 		return fmt.Errorf("job %q at version %d not found", args.JobID, args.JobVersion)
 	}
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(jobV), acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
 
 	// Commit this stability request via Raft
 	_, modifyIndex, err := j.srv.raftApply(structs.JobStabilityRequestType, args)
@@ -434,6 +572,127 @@ func (j *Job) Stable(args *structs.JobStabilityRequest, reply *structs.JobStabil
 	return nil
 }
 
+// UpdatePriority is used to change the priority of a job without changing
+// its placements. The new version of the job leaves its allocations and
+// deployment untouched and no evaluation is created.
+func (j *Job) UpdatePriority(args *structs.JobUpdatePriorityRequest, reply *structs.JobUpdatePriorityResponse) error {
+	if done, err := j.srv.forward("Job.UpdatePriority", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "update_priority"}, time.Now())
+
+	// Validate the arguments
+	if args.JobID == "" {
+		return fmt.Errorf("missing job ID for updating the priority")
+	}
+	if args.Priority < structs.JobMinPriority || args.Priority > structs.JobMaxPriority {
+		return fmt.Errorf("Job priority must be between [%d, %d]", structs.JobMinPriority, structs.JobMaxPriority)
+	}
+
+	// Lookup the job
+	snap, err := j.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	ws := memdb.NewWatchSet()
+	job, err := snap.JobByID(ws, args.JobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("job %q not found", args.JobID)
+	}
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(job), acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
+
+	// Nothing to do if the job already has the priority
+	if job.Priority == args.Priority {
+		reply.JobVersion = job.Version
+		reply.Index = job.ModifyIndex
+		return nil
+	}
+
+	// Commit this update via Raft
+	args.SubmitTime = time.Now().UTC().UnixNano()
+	resp, index, err := j.srv.raftApply(structs.JobUpdatePriorityRequestType, args)
+	if err != nil {
+		j.srv.logger.Printf("[ERR] nomad.job: Job priority update failed: %v", err)
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	// Lookup the new version of the job
+	job, err = j.srv.fsm.State().JobByID(ws, args.JobID)
+	if err != nil {
+		return err
+	}
+	if job != nil {
+		reply.JobVersion = job.Version
+	}
+	reply.Index = index
+	return nil
+}
+
+// Scale is used to change the count of a task group of a job. Scaling only
+// needs the scale-job capability, so the new version of the job is registered
+// without the token having to be allowed to submit jobs.
+func (j *Job) Scale(args *structs.JobScaleRequest, reply *structs.JobRegisterResponse) error {
+	if done, err := j.srv.forward("Job.Scale", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "scale"}, time.Now())
+
+	// Validate the arguments
+	if args.JobID == "" {
+		return fmt.Errorf("missing job ID for scaling")
+	}
+	if args.TaskGroup == "" {
+		return fmt.Errorf("missing task group for scaling")
+	}
+	if args.Count < 0 {
+		return fmt.Errorf("task group count must not be negative")
+	}
+
+	// Lookup the job
+	snap, err := j.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	ws := memdb.NewWatchSet()
+	job, err := snap.JobByID(ws, args.JobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("job %q not found", args.JobID)
+	}
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(job), acl.NamespaceCapabilityScaleJob); err != nil {
+		return err
+	}
+	if job.IsPeriodic() || job.IsParameterized() {
+		return fmt.Errorf("can't scale periodic or parameterized job %q", args.JobID)
+	}
+
+	// Build the register request of the scaled job, failing if the job
+	// changed since it was looked up
+	reg := &structs.JobRegisterRequest{
+		Job:            job.Copy(),
+		EnforceIndex:   true,
+		JobModifyIndex: job.JobModifyIndex,
+		WriteRequest:   args.WriteRequest,
+	}
+	tg := reg.Job.LookupTaskGroup(args.TaskGroup)
+	if tg == nil {
+		return fmt.Errorf("task group %q not found in job %q", args.TaskGroup, args.JobID)
+	}
+	tg.Count = args.Count
+
+	return j.register(reg, reply, acl.NamespaceCapabilityScaleJob)
+}
+
 // Evaluate is used to force a job for re-evaluation
 func (j *Job) Evaluate(args *structs.JobEvaluateRequest, reply *structs.JobRegisterResponse) error {
 	if done, err := j.srv.forward("Job.Evaluate", args, args, reply); done {
@@ -446,6 +705,10 @@ func (j *Job) Evaluate(args *structs.JobEvaluateRequest, reply *structs.JobRegis
 		return fmt.Errorf("missing job ID for evaluation")
 	}
 
+	span := j.srv.startRPCSpan("Job.Evaluate", args.TraceParent)
+	span.SetAttribute("job_id", args.JobID)
+	defer span.End()
+
 	// Lookup the job
 	snap, err := j.srv.fsm.State().Snapshot()
 	if err != nil {
@@ -459,6 +722,9 @@ func (j *Job) Evaluate(args *structs.JobEvaluateRequest, reply *structs.JobRegis
 	if job == nil {
 		return fmt.Errorf("job not found")
 	}
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(job), acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
 
 	if job.IsPeriodic() {
 		return fmt.Errorf("can't evaluate periodic job")
@@ -475,6 +741,7 @@ func (j *Job) Evaluate(args *structs.JobEvaluateRequest, reply *structs.JobRegis
 		JobID:          job.ID,
 		JobModifyIndex: job.ModifyIndex,
 		Status:         structs.EvalStatusPending,
+		TraceParent:    span.TraceParent(),
 	}
 	update := &structs.EvalUpdateRequest{
 		Evals:        []*structs.Evaluation{eval},
@@ -482,7 +749,7 @@ func (j *Job) Evaluate(args *structs.JobEvaluateRequest, reply *structs.JobRegis
 	}
 
 	// Commit this evaluation via Raft
-	_, evalIndex, err := j.srv.raftApply(structs.EvalUpdateRequestType, update)
+	_, evalIndex, err := j.srv.tracedRaftApply(span, structs.EvalUpdateRequestType, update)
 	if err != nil {
 		j.srv.logger.Printf("[ERR] nomad.job: Eval create failed: %v", err)
 		return err
@@ -508,6 +775,10 @@ func (j *Job) Deregister(args *structs.JobDeregisterRequest, reply *structs.JobD
 		return fmt.Errorf("missing job ID for deregistering")
 	}
 
+	span := j.srv.startRPCSpan("Job.Deregister", args.TraceParent)
+	span.SetAttribute("job_id", args.JobID)
+	defer span.End()
+
 	// Lookup the job
 	snap, err := j.srv.fsm.State().Snapshot()
 	if err != nil {
@@ -518,9 +789,12 @@ func (j *Job) Deregister(args *structs.JobDeregisterRequest, reply *structs.JobD
 	if err != nil {
 		return err
 	}
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(job), acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
 
 	// Commit this update via Raft
-	_, index, err := j.srv.raftApply(structs.JobDeregisterRequestType, args)
+	_, index, err := j.srv.tracedRaftApply(span, structs.JobDeregisterRequestType, args)
 	if err != nil {
 		j.srv.logger.Printf("[ERR] nomad.job: Deregister failed: %v", err)
 		return err
@@ -546,6 +820,7 @@ func (j *Job) Deregister(args *structs.JobDeregisterRequest, reply *structs.JobD
 		JobID:          args.JobID,
 		JobModifyIndex: index,
 		Status:         structs.EvalStatusPending,
+		TraceParent:    span.TraceParent(),
 	}
 	update := &structs.EvalUpdateRequest{
 		Evals:        []*structs.Evaluation{eval},
@@ -553,7 +828,7 @@ func (j *Job) Deregister(args *structs.JobDeregisterRequest, reply *structs.JobD
 	}
 
 	// Commit this evaluation via Raft
-	_, evalIndex, err := j.srv.raftApply(structs.EvalUpdateRequestType, update)
+	_, evalIndex, err := j.srv.tracedRaftApply(span, structs.EvalUpdateRequestType, update)
 	if err != nil {
 		j.srv.logger.Printf("[ERR] nomad.job: Eval create failed: %v", err)
 		return err
@@ -574,11 +849,24 @@ func (j *Job) GetJob(args *structs.JobSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "get_job"}, time.Now())
 
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			ns, err := jobNamespace(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			if !aclObj.AllowJobRead(ns, args.JobID) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Look for the job
 			out, err := state.JobByID(ws, args.JobID)
 			if err != nil {
@@ -613,11 +901,24 @@ func (j *Job) GetJobVersions(args *structs.JobVersionsRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "get_job_versions"}, time.Now())
 
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			ns, err := jobNamespace(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			if !aclObj.AllowNamespaceRead(ns) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Look for the job
 			out, err := state.JobVersionsByID(ws, args.JobID)
 			if err != nil {
@@ -630,7 +931,13 @@ func (j *Job) GetJobVersions(args *structs.JobVersionsRequest,
 				reply.Index = out[0].ModifyIndex
 
 				// Compute the diffs
-				if args.Diffs {
+				if args.Diffs && args.DiffVersion != nil {
+					diffs, err := jobVersionDiffs(out, *args.DiffVersion)
+					if err != nil {
+						return err
+					}
+					reply.Diffs = diffs
+				} else if args.Diffs {
 					for i := 0; i < len(out)-1; i++ {
 						old, new := out[i+1], out[i]
 						d, err := old.Diff(new, true)
@@ -656,6 +963,31 @@ func (j *Job) GetJobVersions(args *structs.JobVersionsRequest,
 	return j.srv.blockingRPC(&opts)
 }
 
+// jobVersionDiffs returns the diff of each of the given job versions against
+// the given version of the job.
+func jobVersionDiffs(versions []*structs.Job, version uint64) ([]*structs.JobDiff, error) {
+	var base *structs.Job
+	for _, v := range versions {
+		if v.Version == version {
+			base = v
+			break
+		}
+	}
+	if base == nil {
+		return nil, fmt.Errorf("job version %d not found", version)
+	}
+
+	diffs := make([]*structs.JobDiff, 0, len(versions))
+	for _, v := range versions {
+		d, err := base.Diff(v, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create job diff: %v", err)
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
 // List is used to list the jobs registered in the system
 func (j *Job) List(args *structs.JobListRequest,
 	reply *structs.JobListResponse) error {
@@ -664,6 +996,11 @@ func (j *Job) List(args *structs.JobListRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "list"}, time.Now())
 
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
@@ -672,29 +1009,42 @@ func (j *Job) List(args *structs.JobListRequest,
 			// Capture all the jobs
 			var err error
 			var iter memdb.ResultIterator
-			if prefix := args.QueryOptions.Prefix; prefix != "" {
-				iter, err = state.JobsByIDPrefix(ws, prefix)
+			if ns := args.QueryOptions.Namespace; ns != "" {
+				iter, err = state.PrefixFrom(ws, "jobs", "namespace", []interface{}{ns}, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
 			} else {
-				iter, err = state.Jobs(ws)
+				iter, err = state.PrefixFrom(ws, "jobs", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
 			}
 			if err != nil {
 				return err
 			}
+			iter = newFilterIterator(iter, func(raw interface{}) bool {
+				job := raw.(*structs.Job)
+				return aclObj.AllowJobRead(namespaceOfJob(job), job.ID)
+			})
 
 			var jobs []*structs.JobListStub
-			for {
-				raw := iter.Next()
-				if raw == nil {
-					break
-				}
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.Job).ID
+			}, func(raw interface{}) (interface{}, error) {
 				job := raw.(*structs.Job)
 				summary, err := state.JobSummaryByID(ws, job.ID)
 				if err != nil {
-					return fmt.Errorf("unable to look up summary for job: %v", job.ID)
+					return nil, fmt.Errorf("unable to look up summary for job: %v", job.ID)
 				}
-				jobs = append(jobs, job.Stub(summary))
+				return job.Stub(summary), nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				jobs = append(jobs, obj.(*structs.JobListStub))
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 			reply.Jobs = jobs
+			reply.NextToken = nextToken
 
 			// Use the last index that affected the jobs table
 			index, err := state.Index("jobs")
@@ -718,11 +1068,24 @@ func (j *Job) Allocations(args *structs.JobSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "allocations"}, time.Now())
 
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			ns, err := jobNamespace(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			if !aclObj.AllowJobRead(ns, args.JobID) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Capture the allocations
 			allocs, err := state.AllocsByJob(ws, args.JobID, args.AllAllocs)
 			if err != nil {
@@ -752,6 +1115,59 @@ func (j *Job) Allocations(args *structs.JobSpecificRequest,
 	return j.srv.blockingRPC(&opts)
 }
 
+// Dependencies is used to get the graph of dependencies a job belongs to
+func (j *Job) Dependencies(args *structs.JobSpecificRequest,
+	reply *structs.JobDependenciesResponse) error {
+	if done, err := j.srv.forward("Job.Dependencies", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "job", "dependencies"}, time.Now())
+
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			ns, err := jobNamespace(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			if !aclObj.AllowNamespaceRead(ns) {
+				return structs.ErrPermissionDenied
+			}
+
+			nodes, err := jobDependencyGraph(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			reply.Nodes = nodes
+
+			// Use the last index that affected the graph
+			index, err := jobDependenciesIndex(state)
+			if err != nil {
+				return err
+			}
+			allocsIndex, err := state.Index("allocs")
+			if err != nil {
+				return err
+			}
+			if allocsIndex > index {
+				index = allocsIndex
+			}
+			reply.Index = index
+
+			// Set the query response
+			j.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return j.srv.blockingRPC(&opts)
+}
+
 // Evaluations is used to list the evaluations for a job
 func (j *Job) Evaluations(args *structs.JobSpecificRequest,
 	reply *structs.JobEvaluationsResponse) error {
@@ -760,13 +1176,25 @@ func (j *Job) Evaluations(args *structs.JobSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "evaluations"}, time.Now())
 
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			ns, err := jobNamespace(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			if !aclObj.AllowNamespaceRead(ns) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Capture the evals
-			var err error
 			reply.Evaluations, err = state.EvalsByJob(ws, args.JobID)
 			if err != nil {
 				return err
@@ -795,11 +1223,24 @@ func (j *Job) Deployments(args *structs.JobSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "deployments"}, time.Now())
 
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			ns, err := jobNamespace(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			if !aclObj.AllowNamespaceRead(ns) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Capture the deployments
 			deploys, err := state.DeploymentsByJobID(ws, args.JobID)
 			if err != nil {
@@ -830,11 +1271,24 @@ func (j *Job) LatestDeployment(args *structs.JobSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "job", "latest_deployment"}, time.Now())
 
+	aclObj, err := j.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			ns, err := jobNamespace(ws, state, args.JobID)
+			if err != nil {
+				return err
+			}
+			if !aclObj.AllowNamespaceRead(ns) {
+				return structs.ErrPermissionDenied
+			}
+
 			// Capture the deployments
 			deploys, err := state.DeploymentsByJobID(ws, args.JobID)
 			if err != nil {
@@ -875,21 +1329,6 @@ func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse)
 		return fmt.Errorf("Job required for plan")
 	}
 
-	// Initialize the job fields (sets defaults and any necessary init work).
-	canonicalizeWarnings := args.Job.Canonicalize()
-
-	// Add implicit constraints
-	setImplicitConstraints(args.Job)
-
-	// Validate the job and capture any warnings
-	err, warnings := validateJob(args.Job)
-	if err != nil {
-		return err
-	}
-
-	// Set the warning message
-	reply.Warnings = structs.MergeMultierrorWarnings(warnings, canonicalizeWarnings)
-
 	// Acquire a snapshot of the state
 	snap, err := j.srv.fsm.State().Snapshot()
 	if err != nil {
@@ -903,6 +1342,26 @@ func (j *Job) Plan(args *structs.JobPlanRequest, reply *structs.JobPlanResponse)
 		return err
 	}
 
+	// Planning requires the same permissions as registering the job, which
+	// are checked before the job is passed to the admission controllers
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(args.Job), acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
+	if oldJob != nil {
+		if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(oldJob), acl.NamespaceCapabilitySubmitJob); err != nil {
+			return err
+		}
+	}
+
+	// Run the job through the admission controllers and capture any warnings
+	warnings, err := j.admissionControllers(args.Job)
+	if err != nil {
+		return err
+	}
+
+	// Set the warning message
+	reply.Warnings = structs.MergeMultierrorWarnings(warnings...)
+
 	var index uint64
 	var updatedIndex uint64
 
@@ -1021,6 +1480,14 @@ func validateJob(job *structs.Job) (invalid, warnings error) {
 				multierror.Append(validationErrors, formatted)
 			}
 
+			// Change scripts are run inside the task. Ensure the driver is
+			// capable
+			if task.UsesChangeScripts() && !d.Abilities().Exec {
+				formatted := fmt.Errorf("group %q -> task %q: driver %q doesn't support running change scripts",
+					tg.Name, task.Name, task.Driver)
+				multierror.Append(validationErrors, formatted)
+			}
+
 			// The task group didn't have any task that required signals
 			if !tgOk {
 				continue
@@ -1049,6 +1516,41 @@ func validateJob(job *structs.Job) (invalid, warnings error) {
 	return validationErrors.ErrorOrNil(), warnings
 }
 
+// validateJobVault ensures the tasks of a job only request the Vault policies,
+// roles and namespaces allowed by the servers.
+func validateJobVault(vconf *config.VaultConfig, job *structs.Job) error {
+	var mErr multierror.Error
+	for _, tg := range job.TaskGroups {
+		for _, task := range tg.Tasks {
+			if task.Vault == nil {
+				continue
+			}
+
+			var denied []string
+			for _, p := range task.Vault.Policies {
+				if !vconf.AllowsPolicy(p) {
+					denied = append(denied, p)
+				}
+			}
+			if len(denied) != 0 {
+				multierror.Append(&mErr, fmt.Errorf("group %q -> task %q: Vault policies not allowed: %s",
+					tg.Name, task.Name, strings.Join(denied, ", ")))
+			}
+
+			if role := task.Vault.Role; role != "" && !vconf.AllowsRole(role) {
+				multierror.Append(&mErr, fmt.Errorf("group %q -> task %q: Vault role %q not allowed",
+					tg.Name, task.Name, role))
+			}
+
+			if ns := task.Vault.Namespace; !vconf.AllowsNamespace(ns) {
+				multierror.Append(&mErr, fmt.Errorf("group %q -> task %q: Vault namespace %q not allowed",
+					tg.Name, task.Name, ns))
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
 // validateJobUpdate ensures updates to a job are valid.
 func validateJobUpdate(old, new *structs.Job) error {
 	// Type transitions are disallowed
@@ -1072,6 +1574,28 @@ func validateJobUpdate(old, new *structs.Job) error {
 		return fmt.Errorf("cannot update parameterized job to being non-parameterized")
 	}
 
+	// Moving to another namespace is disallowed, job IDs are unique across
+	// namespaces
+	if old.Namespace != new.Namespace {
+		return fmt.Errorf("cannot move job from namespace %q to %q", old.Namespace, new.Namespace)
+	}
+
+	return nil
+}
+
+// validateJobNamespace returns an error if the namespace of the job doesn't
+// exist. The default namespace always exists.
+func validateJobNamespace(snap *state.StateSnapshot, job *structs.Job) error {
+	if job.Namespace == structs.DefaultNamespace {
+		return nil
+	}
+	ns, err := snap.NamespaceByName(nil, job.Namespace)
+	if err != nil {
+		return err
+	}
+	if ns == nil {
+		return fmt.Errorf("namespace %q not found", job.Namespace)
+	}
 	return nil
 }
 
@@ -1087,6 +1611,10 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 		return fmt.Errorf("missing parameterized job ID")
 	}
 
+	span := j.srv.startRPCSpan("Job.Dispatch", args.TraceParent)
+	span.SetAttribute("job_id", args.JobID)
+	defer span.End()
+
 	snap, err := j.srv.fsm.State().Snapshot()
 	if err != nil {
 		return err
@@ -1099,6 +1627,9 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 	if parameterizedJob == nil {
 		return fmt.Errorf("parameterized job not found")
 	}
+	if err := j.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(parameterizedJob), acl.NamespaceCapabilityDispatchJob); err != nil {
+		return err
+	}
 
 	if !parameterizedJob.IsParameterized() {
 		return fmt.Errorf("Specified job %q is not a parameterized job", args.JobID)
@@ -1109,10 +1640,25 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 	}
 
 	// Validate the arguments
-	if err := validateDispatchRequest(args, parameterizedJob); err != nil {
+	if err := validateDispatchRequest(args, parameterizedJob, j.srv.config.DispatchPayloadSizeLimit); err != nil {
 		return err
 	}
 
+	// If the request carries an idempotency token, return the job that was
+	// already dispatched with it instead of dispatching another one.
+	if args.IdempotencyToken != "" {
+		existing, err := dispatchedJobByToken(snap, parameterizedJob.ID, args.IdempotencyToken)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			reply.DispatchedJobID = existing.ID
+			reply.JobCreateIndex = existing.CreateIndex
+			reply.Index = existing.ModifyIndex
+			return nil
+		}
+	}
+
 	// Derive the child job and commit it via Raft
 	dispatchJob := parameterizedJob.Copy()
 	dispatchJob.ParameterizedJob = nil
@@ -1129,8 +1675,13 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 		dispatchJob.Meta[k] = v
 	}
 
-	// Compress the payload
-	dispatchJob.Payload = snappy.Encode(nil, args.Payload)
+	// Compress the payload unless it was sent compressed
+	if args.PayloadCompressed {
+		dispatchJob.Payload = args.Payload
+	} else {
+		dispatchJob.Payload = snappy.Encode(nil, args.Payload)
+	}
+	dispatchJob.DispatchIdempotencyToken = args.IdempotencyToken
 
 	regReq := &structs.JobRegisterRequest{
 		Job:          dispatchJob,
@@ -1138,7 +1689,7 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 	}
 
 	// Commit this update via Raft
-	_, jobCreateIndex, err := j.srv.raftApply(structs.JobRegisterRequestType, regReq)
+	_, jobCreateIndex, err := j.srv.tracedRaftApply(span, structs.JobRegisterRequestType, regReq)
 	if err != nil {
 		j.srv.logger.Printf("[ERR] nomad.job: Dispatched job register failed: %v", err)
 		return err
@@ -1159,6 +1710,7 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 			JobID:          dispatchJob.ID,
 			JobModifyIndex: jobCreateIndex,
 			Status:         structs.EvalStatusPending,
+			TraceParent:    span.TraceParent(),
 		}
 		update := &structs.EvalUpdateRequest{
 			Evals:        []*structs.Evaluation{eval},
@@ -1166,7 +1718,7 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 		}
 
 		// Commit this evaluation via Raft
-		_, evalIndex, err := j.srv.raftApply(structs.EvalUpdateRequestType, update)
+		_, evalIndex, err := j.srv.tracedRaftApply(span, structs.EvalUpdateRequestType, update)
 		if err != nil {
 			j.srv.logger.Printf("[ERR] nomad.job: Eval create failed: %v", err)
 			return err
@@ -1181,11 +1733,40 @@ func (j *Job) Dispatch(args *structs.JobDispatchRequest, reply *structs.JobDispa
 	return nil
 }
 
+// dispatchedJobByToken returns the job dispatched from the parameterized job
+// with the given idempotency token, or nil if there is none.
+func dispatchedJobByToken(snap *state.StateSnapshot, parentID, token string) (*structs.Job, error) {
+	iter, err := snap.JobsByIDPrefix(nil, parentID+structs.DispatchLaunchSuffix)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			return nil, nil
+		}
+		job := raw.(*structs.Job)
+		if job.ParentID == parentID && job.DispatchIdempotencyToken == token {
+			return job, nil
+		}
+	}
+}
+
 // validateDispatchRequest returns whether the request is valid given the
-// parameterized job.
-func validateDispatchRequest(req *structs.JobDispatchRequest, job *structs.Job) error {
+// parameterized job and the payload size limit.
+func validateDispatchRequest(req *structs.JobDispatchRequest, job *structs.Job, sizeLimit int) error {
+	// Determine the size of the uncompressed payload
+	size := len(req.Payload)
+	if req.PayloadCompressed && size != 0 {
+		decodedLen, err := snappy.DecodedLen(req.Payload)
+		if err != nil {
+			return fmt.Errorf("Failed to decode compressed payload: %v", err)
+		}
+		size = decodedLen
+	}
+
 	// Check the payload constraint is met
-	hasInputData := len(req.Payload) != 0
+	hasInputData := size != 0
 	if job.ParameterizedJob.Payload == structs.DispatchPayloadRequired && !hasInputData {
 		return fmt.Errorf("Payload is not provided but required by parameterized job")
 	} else if job.ParameterizedJob.Payload == structs.DispatchPayloadForbidden && hasInputData {
@@ -1193,8 +1774,25 @@ func validateDispatchRequest(req *structs.JobDispatchRequest, job *structs.Job)
 	}
 
 	// Check the payload doesn't exceed the size limit
-	if l := len(req.Payload); l > DispatchPayloadSizeLimit {
-		return fmt.Errorf("Payload exceeds maximum size; %d > %d", l, DispatchPayloadSizeLimit)
+	if size > sizeLimit {
+		return fmt.Errorf("Payload exceeds maximum size; %d > %d", size, sizeLimit)
+	}
+
+	// Check a compressed payload can be decoded
+	payload := req.Payload
+	if req.PayloadCompressed && size != 0 {
+		decoded, err := snappy.Decode(nil, req.Payload)
+		if err != nil {
+			return fmt.Errorf("Failed to decode compressed payload: %v", err)
+		}
+		payload = decoded
+	}
+
+	// Check the payload conforms to its schema
+	if schema := job.ParameterizedJob.PayloadSchema; schema != nil && hasInputData {
+		if err := schema.ValidatePayload(payload); err != nil {
+			return err
+		}
 	}
 
 	// Check if the metadata is a set
@@ -1244,5 +1842,28 @@ func validateDispatchRequest(req *structs.JobDispatchRequest, job *structs.Job)
 		return fmt.Errorf("Dispatch did not provide required meta keys: %v", flat)
 	}
 
+	// Check the metadata values conform to their schema
+	var mErr multierror.Error
+	for _, k := range sortedMetaKeys(req.Meta) {
+		if schema, ok := job.ParameterizedJob.MetaSchema[k]; ok {
+			if err := schema.ValidateValue(req.Meta[k]); err != nil {
+				multierror.Append(&mErr, fmt.Errorf("Meta key %q: %v", k, err))
+			}
+		}
+	}
+	if err := mErr.ErrorOrNil(); err != nil {
+		return fmt.Errorf("Dispatch provided invalid meta values: %v", err)
+	}
+
 	return nil
 }
+
+// sortedMetaKeys returns the keys of the metadata in sorted order.
+func sortedMetaKeys(meta map[string]string) []string {
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
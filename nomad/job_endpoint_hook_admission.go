@@ -0,0 +1,199 @@
+package nomad
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+const (
+	// admissionResponseLimit is the maximum size of the responses of the
+	// admission policies
+	admissionResponseLimit = 1024 * 1024
+)
+
+// admissionInput is the document admission policies evaluate. It is the
+// input document of OPA policies and the body of webhook requests.
+type admissionInput struct {
+	Job *structs.Job `json:"job"`
+}
+
+// admissionDecision is the decision of an admission policy about a job.
+type admissionDecision struct {
+	// Allow rejects the job when set to false. Policies only returning deny
+	// and warn messages may leave it unset.
+	Allow *bool `json:"allow"`
+
+	// Deny are the reasons the job is rejected for. The job is rejected if
+	// any is returned.
+	Deny []string `json:"deny"`
+
+	// Warn are warnings surfaced to the submitter of an admitted job.
+	Warn []string `json:"warn"`
+
+	// Metadata describes the policy, for example its owner or the URL of
+	// its documentation, and is included in the rejection message.
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// denied returns whether the decision rejects the job.
+func (d *admissionDecision) denied() bool {
+	return len(d.Deny) != 0 || (d.Allow != nil && !*d.Allow)
+}
+
+// opaResponse is the response of the Data API of OPA. Result is unset if the
+// decision document is undefined.
+type opaResponse struct {
+	Result *admissionDecision `json:"result"`
+}
+
+// jobAdmissionPolicy validates submitted jobs against an external policy
+// engine, such as Open Policy Agent or a webhook, enabling organization
+// wide rules like forbidding a driver in a namespace.
+type jobAdmissionPolicy struct {
+	config *config.AdmissionPolicyConfig
+	client *http.Client
+}
+
+// newJobAdmissionPolicies returns the validators of the configured admission
+// policies.
+func newJobAdmissionPolicies(policies []*config.AdmissionPolicyConfig) []jobValidator {
+	validators := make([]jobValidator, 0, len(policies))
+	for _, policy := range policies {
+		client := cleanhttp.DefaultClient()
+		client.Timeout = policy.Timeout
+		validators = append(validators, &jobAdmissionPolicy{
+			config: policy,
+			client: client,
+		})
+	}
+	return validators
+}
+
+func (p *jobAdmissionPolicy) Name() string {
+	return "admission_policy_" + p.config.Name
+}
+
+func (p *jobAdmissionPolicy) Validate(job *structs.Job) ([]error, error) {
+	decision, err := p.evaluate(job)
+	if err != nil {
+		if p.config.FailOpen {
+			return []error{fmt.Errorf("admission policy %q failed, admitting the job: %v", p.config.Name, err)}, nil
+		}
+		return nil, fmt.Errorf("admission policy %q failed: %v", p.config.Name, err)
+	}
+
+	var warnings []error
+	for _, w := range decision.Warn {
+		warnings = append(warnings, fmt.Errorf("admission policy %q: %s", p.config.Name, w))
+	}
+
+	if !decision.denied() {
+		return warnings, nil
+	}
+	reason := "job not allowed"
+	if len(decision.Deny) != 0 {
+		reason = strings.Join(decision.Deny, "; ")
+	}
+	err = fmt.Errorf("admission policy %q denied the job: %s%s",
+		p.config.Name, reason, formatAdmissionMetadata(decision.Metadata))
+	if p.config.SoftMandatory() {
+		return warnings, &softMandatoryDenial{err: err}
+	}
+	return warnings, err
+}
+
+// softMandatoryDenial is the error of a soft-mandatory admission policy
+// denying a job. Submitters can override it with a justification.
+type softMandatoryDenial struct {
+	err error
+}
+
+func (d *softMandatoryDenial) Error() string {
+	return d.err.Error() + "; the policy is soft-mandatory and can be overridden"
+}
+
+// overrideSoftMandatoryDenials splits the errors of the admission controllers
+// into the denials of soft-mandatory policies, which are overridden, and the
+// remaining errors, which still reject the job.
+func overrideSoftMandatoryDenials(err error) (overridden []error, remaining error) {
+	var mErr multierror.Error
+	for _, e := range flattenErrors(err) {
+		if denial, ok := e.(*softMandatoryDenial); ok {
+			overridden = append(overridden, denial.err)
+			continue
+		}
+		multierror.Append(&mErr, e)
+	}
+	return overridden, mErr.ErrorOrNil()
+}
+
+// evaluate posts the job to the policy engine and returns its decision.
+func (p *jobAdmissionPolicy) evaluate(job *structs.Job) (*admissionDecision, error) {
+	var body interface{} = &admissionInput{Job: job}
+	if p.config.Type == config.AdmissionPolicyTypeOPA {
+		body = map[string]interface{}{"input": body}
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job: %v", err)
+	}
+
+	resp, err := p.client.Post(p.config.Address, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(io.LimitReader(resp.Body, admissionResponseLimit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response code %d: %s", resp.StatusCode, bytes.TrimSpace(raw))
+	}
+
+	var decision *admissionDecision
+	if p.config.Type == config.AdmissionPolicyTypeOPA {
+		var opa opaResponse
+		if err := json.Unmarshal(raw, &opa); err != nil {
+			return nil, fmt.Errorf("failed to decode decision: %v", err)
+		}
+		decision = opa.Result
+	} else if err := json.Unmarshal(raw, &decision); err != nil {
+		return nil, fmt.Errorf("failed to decode decision: %v", err)
+	}
+	if decision == nil {
+		return nil, fmt.Errorf("decision is undefined")
+	}
+	return decision, nil
+}
+
+// formatAdmissionMetadata formats the metadata of a policy to be appended to
+// its rejection message.
+func formatAdmissionMetadata(metadata map[string]interface{}) string {
+	if len(metadata) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, metadata[k])
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(pairs, ", "))
+}
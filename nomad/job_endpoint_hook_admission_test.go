@@ -0,0 +1,324 @@
+package nomad
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// testAdmissionServer returns a server denying jobs using the raw_exec driver
+// and warning about jobs without meta. The decision is wrapped in a result
+// for OPA.
+func testAdmissionServer(t *testing.T, opa bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		raw := body["job"]
+		if opa {
+			var input map[string]json.RawMessage
+			if err := json.Unmarshal(body["input"], &input); err != nil {
+				t.Fatalf("failed to decode input: %v", err)
+			}
+			raw = input["job"]
+		}
+		var input admissionInput
+		if err := json.Unmarshal(raw, &input.Job); err != nil || input.Job == nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+
+		decision := &admissionDecision{
+			Metadata: map[string]interface{}{"owner": "platform"},
+		}
+		for _, tg := range input.Job.TaskGroups {
+			for _, task := range tg.Tasks {
+				if task.Driver == "raw_exec" {
+					decision.Deny = append(decision.Deny, "task "+task.Name+" uses raw_exec")
+				}
+			}
+		}
+		if len(input.Job.Meta) == 0 {
+			decision.Warn = append(decision.Warn, "job has no meta")
+		}
+
+		var resp interface{} = decision
+		if opa {
+			resp = &opaResponse{Result: decision}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestJobAdmissionPolicy(t *testing.T) {
+	t.Parallel()
+	for _, typ := range []string{config.AdmissionPolicyTypeOPA, config.AdmissionPolicyTypeWebhook} {
+		ts := testAdmissionServer(t, typ == config.AdmissionPolicyTypeOPA)
+		defer ts.Close()
+
+		validators := newJobAdmissionPolicies([]*config.AdmissionPolicyConfig{{
+			Name:    "no-raw-exec",
+			Type:    typ,
+			Address: ts.URL,
+			Timeout: time.Second,
+		}})
+
+		job := mock.Job()
+		job.Meta = nil
+		warnings, err := validators[0].Validate(job)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", typ, err)
+		}
+		if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), `"no-raw-exec": job has no meta`) {
+			t.Fatalf("%s: expected a warning; got %v", typ, warnings)
+		}
+
+		job.TaskGroups[0].Tasks[0].Driver = "raw_exec"
+		_, err = validators[0].Validate(job)
+		expected := `admission policy "no-raw-exec" denied the job: task web uses raw_exec (owner=platform)`
+		if err == nil || err.Error() != expected {
+			t.Fatalf("%s: expected %q; got %v", typ, expected, err)
+		}
+	}
+}
+
+func TestJobAdmissionPolicy_Failure(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// An undefined OPA decision has no result
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	policy := &config.AdmissionPolicyConfig{
+		Name:    "undefined",
+		Type:    config.AdmissionPolicyTypeOPA,
+		Address: ts.URL,
+		Timeout: time.Second,
+	}
+	validators := newJobAdmissionPolicies([]*config.AdmissionPolicyConfig{policy})
+	if _, err := validators[0].Validate(mock.Job()); err == nil || !strings.Contains(err.Error(), "decision is undefined") {
+		t.Fatalf("expected job to be rejected; got %v", err)
+	}
+
+	// Failing open admits the job with a warning
+	policy.FailOpen = true
+	warnings, err := validators[0].Validate(mock.Job())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Error(), "admitting the job") {
+		t.Fatalf("expected a warning; got %v", warnings)
+	}
+}
+
+func TestJobEndpoint_Register_PolicyOverride(t *testing.T) {
+	t.Parallel()
+	ts := testAdmissionServer(t, false)
+	defer ts.Close()
+
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		c.AdmissionPolicies = []*config.AdmissionPolicyConfig{{
+			Name:             "no-raw-exec",
+			Type:             config.AdmissionPolicyTypeWebhook,
+			Address:          ts.URL,
+			Timeout:          time.Second,
+			EnforcementLevel: config.AdmissionEnforcementSoftMandatory,
+		}}
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Driver = "raw_exec"
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// The soft-mandatory denial rejects the job
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "can be overridden") {
+		t.Fatalf("expected job to be rejected; got %v", err)
+	}
+
+	// Overriding requires a justification
+	req.PolicyOverride = true
+	err = msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "requires a justification") {
+		t.Fatalf("expected a justification to be required; got %v", err)
+	}
+
+	// The overridden denial is returned as a warning
+	req.PolicyOverrideJustification = "hotfix for incident 42"
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.Contains(resp.Warnings, "overridden: admission policy \"no-raw-exec\" denied the job") {
+		t.Fatalf("expected the overridden denial in the warnings; got %q", resp.Warnings)
+	}
+
+	// The justification is recorded in the version of the job it admitted
+	state := s1.fsm.State()
+	out, err := state.JobByID(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.PolicyOverrideJustification != "hotfix for incident 42" {
+		t.Fatalf("expected the justification to be recorded; got %#v", out)
+	}
+
+	// Versions admitted without an override don't carry it over
+	job = job.Copy()
+	job.TaskGroups[0].Tasks[0].Driver = "exec"
+	req = &structs.JobRegisterRequest{
+		Job:                         job,
+		PolicyOverride:              true,
+		PolicyOverrideJustification: "hotfix for incident 42",
+		WriteRequest:                structs.WriteRequest{Region: "global"},
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.JobByID(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.Version != 1 || out.PolicyOverrideJustification != "" {
+		t.Fatalf("expected no justification to be recorded; got %#v", out)
+	}
+}
+
+func TestJobEndpoint_Register_PolicyOverride_ACL(t *testing.T) {
+	t.Parallel()
+	ts := testAdmissionServer(t, false)
+	defer ts.Close()
+
+	s1, _ := testACLServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		c.AdmissionPolicies = []*config.AdmissionPolicyConfig{{
+			Name:             "no-raw-exec",
+			Type:             config.AdmissionPolicyTypeWebhook,
+			Address:          ts.URL,
+			Timeout:          time.Second,
+			EnforcementLevel: config.AdmissionEnforcementSoftMandatory,
+		}}
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	// Overriding policies needs the policy-override capability on top of
+	// the ones to submit the job
+	deploy := mock.ACLPolicy()
+	deploy.Rules = `namespace "default" { policy = "write" }`
+	override := mock.ACLPolicy()
+	override.Rules = `namespace "default" { capabilities = ["submit-job", "policy-override"] }`
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{deploy, override}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	deployToken := mock.ACLToken()
+	deployToken.Policies = []string{deploy.Name}
+	overrideToken := mock.ACLToken()
+	overrideToken.Policies = []string{override.Name}
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{deployToken, overrideToken}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Driver = "raw_exec"
+	req := &structs.JobRegisterRequest{
+		Job:                         job,
+		PolicyOverride:              true,
+		PolicyOverrideJustification: "hotfix for incident 42",
+		WriteRequest:                structs.WriteRequest{Region: "global", AuthToken: deployToken.SecretID},
+	}
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied; got %v", err)
+	}
+	req.AuthToken = overrideToken.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestJobEndpoint_Admission_ACL(t *testing.T) {
+	t.Parallel()
+	called := make(chan struct{}, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+		json.NewEncoder(w).Encode(&admissionDecision{})
+	}))
+	defer ts.Close()
+
+	s1, _ := testACLServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		c.AdmissionPolicies = []*config.AdmissionPolicyConfig{{
+			Name:    "audit",
+			Type:    config.AdmissionPolicyTypeWebhook,
+			Address: ts.URL,
+			Timeout: time.Second,
+		}}
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Callers without the permissions to submit the job don't get to pass
+	// it to the admission controllers
+	regReq := &structs.JobRegisterRequest{
+		Job:          mock.Job(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var regResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", regReq, &regResp); err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied; got %v", err)
+	}
+	planReq := &structs.JobPlanRequest{
+		Job:          mock.Job(),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var planResp structs.JobPlanResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Plan", planReq, &planResp); err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied; got %v", err)
+	}
+
+	select {
+	case <-called:
+		t.Fatalf("admission controller called")
+	default:
+	}
+}
+
+func TestOverrideSoftMandatoryDenials(t *testing.T) {
+	t.Parallel()
+	hard := fmt.Errorf("hard denial")
+	soft := fmt.Errorf("soft denial")
+
+	var mErr multierror.Error
+	multierror.Append(&mErr, hard, &softMandatoryDenial{err: soft})
+	overridden, remaining := overrideSoftMandatoryDenials(mErr.ErrorOrNil())
+	if len(overridden) != 1 || overridden[0] != soft {
+		t.Fatalf("bad overridden denials: %v", overridden)
+	}
+	if flat := flattenErrors(remaining); len(flat) != 1 || flat[0] != hard {
+		t.Fatalf("bad remaining errors: %v", remaining)
+	}
+}
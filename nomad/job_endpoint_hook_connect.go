@@ -0,0 +1,185 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// connectSidecarImage is the Envoy image run by the injected sidecar
+	// proxy tasks unless the sidecar_task overrides it.
+	connectSidecarImage = "envoyproxy/envoy:v1.11.2"
+
+	// connectSidecarBootstrap is the path of the Envoy bootstrap
+	// configuration the client writes in the secrets directory of the
+	// sidecar proxy tasks.
+	connectSidecarBootstrap = "${NOMAD_SECRETS_DIR}/envoy_bootstrap.json"
+)
+
+// jobConnectHook injects a sidecar proxy task in the task groups for each
+// service using Consul Connect, and reserves the port the proxy listens on.
+// Tasks defining a Connect gateway service are turned into gateway tasks.
+type jobConnectHook struct{}
+
+func (jobConnectHook) Name() string {
+	return "connect"
+}
+
+func (jobConnectHook) Mutate(job *structs.Job) ([]error, error) {
+	for _, tg := range job.TaskGroups {
+		if err := groupConnectHook(job, tg); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// groupConnectHook injects the sidecar proxy tasks of the Connect services of
+// the task group. It is idempotent so that a job which already went through
+// the hook is left unchanged.
+func groupConnectHook(job *structs.Job, tg *structs.TaskGroup) error {
+	// Only iterate over the tasks defined by the user as the proxy tasks are
+	// appended to the group
+	tasks := tg.Tasks
+	seen := make(map[string]string)
+	for _, task := range tasks {
+		if task.Kind != "" {
+			continue
+		}
+
+		for _, service := range task.Services {
+			if service.Connect == nil {
+				continue
+			}
+			if service.Connect.IsGateway() {
+				if err := connectGatewayTask(task, service); err != nil {
+					return fmt.Errorf("task %q in group %q: %v", task.Name, tg.Name, err)
+				}
+				continue
+			}
+			if other, ok := seen[service.Name]; ok {
+				return fmt.Errorf("Connect service %q is defined by tasks %q and %q in group %q",
+					service.Name, other, task.Name, tg.Name)
+			}
+			seen[service.Name] = task.Name
+
+			// Reserve the port of the proxy on the task owning the service
+			// so that the client can register the proxy along with it
+			addConnectPort(task, structs.ConnectProxyPortLabel(service.Name), 0)
+
+			kind := structs.NewTaskKind(structs.ConnectProxyPrefix, service.Name)
+			if findTaskByKind(tg, kind) != nil {
+				continue
+			}
+
+			proxy := newConnectSidecarTask(service.Name)
+			if service.Connect.SidecarTask != nil {
+				service.Connect.SidecarTask.MergeIntoTask(proxy)
+			}
+			if tg.LookupTask(proxy.Name) != nil {
+				return fmt.Errorf("task %q in group %q conflicts with the Connect sidecar proxy of service %q",
+					proxy.Name, tg.Name, service.Name)
+			}
+			proxy.Canonicalize(job, tg)
+			tg.Tasks = append(tg.Tasks, proxy)
+		}
+	}
+	return nil
+}
+
+// findTaskByKind returns the task of the group with the given kind or nil if
+// there is none.
+func findTaskByKind(tg *structs.TaskGroup, kind structs.TaskKind) *structs.Task {
+	for _, task := range tg.Tasks {
+		if task.Kind == kind {
+			return task
+		}
+	}
+	return nil
+}
+
+// connectGatewayTask turns the task defining the gateway service into the
+// gateway. The task runs the Envoy image unless it sets its own driver, and
+// reserves the port of the gateway service and the ports of the ingress
+// listeners.
+func connectGatewayTask(task *structs.Task, service *structs.Service) error {
+	if len(task.Services) != 1 {
+		return fmt.Errorf("Connect gateway service %q must be the only service of its task", service.Name)
+	}
+
+	gateway := service.Connect.Gateway
+	task.Kind = structs.NewTaskKind(gateway.Prefix(), service.Name)
+	if task.Driver == "" {
+		task.Driver = "docker"
+		task.Config = connectEnvoyConfig(connectSidecarImage)
+	}
+
+	if service.PortLabel == "" {
+		service.PortLabel = fmt.Sprintf("%s-%s", gateway.Prefix(), service.Name)
+		addConnectPort(task, service.PortLabel, 0)
+	}
+	if gateway.Ingress != nil {
+		for _, l := range gateway.Ingress.Listeners {
+			addConnectPort(task, fmt.Sprintf("%s-%d", structs.ConnectIngressPrefix, l.Port), l.Port)
+		}
+	}
+	return nil
+}
+
+// addConnectPort adds the port with the given label to the task if it doesn't
+// have it yet. The port is dynamic unless a value is given.
+func addConnectPort(task *structs.Task, label string, value int) {
+	if len(task.Resources.Networks) == 0 {
+		task.Resources.Networks = []*structs.NetworkResource{{}}
+	}
+	for _, n := range task.Resources.Networks {
+		for _, p := range n.ReservedPorts {
+			if p.Label == label {
+				return
+			}
+		}
+		for _, p := range n.DynamicPorts {
+			if p.Label == label {
+				return
+			}
+		}
+	}
+	net := task.Resources.Networks[0]
+	if value != 0 {
+		net.ReservedPorts = append(net.ReservedPorts, structs.Port{Label: label, Value: value})
+		return
+	}
+	net.DynamicPorts = append(net.DynamicPorts, structs.Port{Label: label})
+}
+
+// newConnectSidecarTask returns the default Envoy sidecar proxy task of the
+// service.
+func newConnectSidecarTask(service string) *structs.Task {
+	return &structs.Task{
+		Name:   fmt.Sprintf("%s-%s", structs.ConnectProxyPrefix, service),
+		Kind:   structs.NewTaskKind(structs.ConnectProxyPrefix, service),
+		Driver: "docker",
+		Config: connectEnvoyConfig(connectSidecarImage),
+		Resources: &structs.Resources{
+			CPU:      250,
+			MemoryMB: 128,
+		},
+		LogConfig:   structs.DefaultLogConfig(),
+		KillTimeout: 5 * time.Second,
+	}
+}
+
+// connectEnvoyConfig returns the docker driver configuration running Envoy
+// with the bootstrap configuration written by the client.
+func connectEnvoyConfig(image string) map[string]interface{} {
+	return map[string]interface{}{
+		"image":        image,
+		"network_mode": "host",
+		"args": []interface{}{
+			"-c", connectSidecarBootstrap,
+			"--disable-hot-restart",
+		},
+	}
+}
@@ -0,0 +1,209 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// connectTestJob returns a job whose first task defines a Connect service
+// without its sidecar proxy.
+func connectTestJob() *structs.Job {
+	job := mock.Job()
+	task := job.TaskGroups[0].Tasks[0]
+	task.Services = append(task.Services, &structs.Service{
+		Name:      "api",
+		PortLabel: "http",
+		Connect: &structs.ConsulConnect{
+			SidecarService: &structs.ConsulSidecarService{},
+		},
+	})
+	return job
+}
+
+func TestJobEndpoint_ConnectHook(t *testing.T) {
+	t.Parallel()
+	job := connectTestJob()
+
+	if _, err := (jobConnectHook{}).Mutate(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tg := job.TaskGroups[0]
+	if len(tg.Tasks) != 2 {
+		t.Fatalf("expected the sidecar proxy task to be injected; got %d tasks", len(tg.Tasks))
+	}
+	proxy := tg.Tasks[1]
+	if proxy.Name != "connect-proxy-api" || proxy.Kind != "connect-proxy:api" {
+		t.Fatalf("bad proxy task: %q %q", proxy.Name, proxy.Kind)
+	}
+	if !proxy.Kind.IsConnectProxy() || proxy.Kind.Value() != "api" {
+		t.Fatalf("bad proxy kind: %q", proxy.Kind)
+	}
+	if proxy.Driver != "docker" || proxy.Config["image"] != connectSidecarImage {
+		t.Fatalf("bad proxy task: %#v", proxy)
+	}
+
+	found := false
+	for _, p := range tg.Tasks[0].Resources.Networks[0].DynamicPorts {
+		if p.Label == "connect-proxy-api" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("proxy port not added: %#v", tg.Tasks[0].Resources.Networks[0])
+	}
+
+	// Running the hook again doesn't change the job
+	before := job.Copy()
+	if _, err := (jobConnectHook{}).Mutate(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(job.TaskGroups[0].Tasks) != len(before.TaskGroups[0].Tasks) {
+		t.Fatalf("hook is not idempotent: %d tasks", len(job.TaskGroups[0].Tasks))
+	}
+	if n := len(job.TaskGroups[0].Tasks[0].Resources.Networks[0].DynamicPorts); n != len(before.TaskGroups[0].Tasks[0].Resources.Networks[0].DynamicPorts) {
+		t.Fatalf("hook is not idempotent: %d ports", n)
+	}
+}
+
+func TestJobEndpoint_ConnectHook_SidecarTask(t *testing.T) {
+	t.Parallel()
+	job := connectTestJob()
+	service := job.TaskGroups[0].Tasks[0].Services[2]
+	service.Connect.SidecarTask = &structs.SidecarTask{
+		Config: map[string]interface{}{
+			"image": "custom/envoy:latest",
+		},
+		Env: map[string]string{
+			"FOO": "bar",
+		},
+		Resources: &structs.Resources{
+			MemoryMB: 512,
+		},
+		KillTimeout: helper.TimeToPtr(30 * time.Second),
+	}
+
+	if _, err := (jobConnectHook{}).Mutate(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	proxy := job.TaskGroups[0].Tasks[1]
+	if proxy.Config["image"] != "custom/envoy:latest" || proxy.Config["network_mode"] != "host" {
+		t.Fatalf("bad config: %#v", proxy.Config)
+	}
+	if proxy.Env["FOO"] != "bar" {
+		t.Fatalf("bad env: %#v", proxy.Env)
+	}
+	if proxy.Resources.MemoryMB != 512 || proxy.Resources.CPU != 250 {
+		t.Fatalf("bad resources: %#v", proxy.Resources)
+	}
+	if proxy.KillTimeout != 30*time.Second {
+		t.Fatalf("bad kill timeout: %v", proxy.KillTimeout)
+	}
+}
+
+func TestJobEndpoint_ConnectHook_Conflicts(t *testing.T) {
+	t.Parallel()
+
+	// The same Connect service defined twice in a group
+	job := connectTestJob()
+	tg := job.TaskGroups[0]
+	other := tg.Tasks[0].Copy()
+	other.Name = "other"
+	tg.Tasks = append(tg.Tasks, other)
+	if _, err := (jobConnectHook{}).Mutate(job); err == nil || !strings.Contains(err.Error(), "is defined by tasks") {
+		t.Fatalf("expected duplicate service error; got %v", err)
+	}
+
+	// A user task using the name of the sidecar proxy
+	job = connectTestJob()
+	tg = job.TaskGroups[0]
+	other = mock.Job().TaskGroups[0].Tasks[0]
+	other.Name = "connect-proxy-api"
+	other.Services = nil
+	tg.Tasks = append(tg.Tasks, other)
+	if _, err := (jobConnectHook{}).Mutate(job); err == nil || !strings.Contains(err.Error(), "conflicts") {
+		t.Fatalf("expected name conflict error; got %v", err)
+	}
+}
+
+func TestJobEndpoint_ConnectHook_Gateway(t *testing.T) {
+	t.Parallel()
+	job := mock.Job()
+	tg := job.TaskGroups[0]
+	task := tg.Tasks[0]
+	task.Driver = ""
+	task.Config = nil
+	task.Services = []*structs.Service{
+		{
+			Name: "ingress",
+			Connect: &structs.ConsulConnect{
+				Gateway: &structs.ConsulGateway{
+					Ingress: &structs.ConsulIngressConfigEntry{
+						Listeners: []*structs.ConsulIngressListener{
+							{
+								Port:     8080,
+								Protocol: "tcp",
+								Services: []*structs.ConsulIngressService{{Name: "api"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if _, err := (jobConnectHook{}).Mutate(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(tg.Tasks) != 1 {
+		t.Fatalf("expected no task to be injected; got %d tasks", len(tg.Tasks))
+	}
+	if task.Kind != "connect-ingress:ingress" || !task.Kind.IsConnectGateway() {
+		t.Fatalf("bad gateway kind: %q", task.Kind)
+	}
+	if task.Driver != "docker" || task.Config["image"] != connectSidecarImage {
+		t.Fatalf("bad gateway task: %#v", task)
+	}
+	if task.Services[0].PortLabel != "connect-ingress-ingress" {
+		t.Fatalf("bad port label: %q", task.Services[0].PortLabel)
+	}
+
+	net := task.Resources.Networks[0]
+	found := false
+	for _, p := range net.ReservedPorts {
+		if p.Label == "connect-ingress-8080" && p.Value == 8080 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("listener port not reserved: %#v", net)
+	}
+
+	// Running the hook again doesn't change the job
+	reserved, dynamic := len(net.ReservedPorts), len(net.DynamicPorts)
+	if _, err := (jobConnectHook{}).Mutate(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(net.ReservedPorts) != reserved || len(net.DynamicPorts) != dynamic {
+		t.Fatalf("hook is not idempotent: %#v", net)
+	}
+
+	// A gateway must be the only service of its task
+	job = mock.Job()
+	task = job.TaskGroups[0].Tasks[0]
+	task.Services[0].Connect = &structs.ConsulConnect{
+		Gateway: &structs.ConsulGateway{
+			Mesh: &structs.ConsulMeshConfigEntry{},
+		},
+	}
+	if _, err := (jobConnectHook{}).Mutate(job); err == nil || !strings.Contains(err.Error(), "only service") {
+		t.Fatalf("expected only service error; got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package nomad
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+var (
+	// imageVerificationConstraint is the implicit constraint added to the
+	// task groups running docker tasks when their images must be verified
+	imageVerificationConstraint = &structs.Constraint{
+		LTarget: "${attr.driver.docker.verify}",
+		RTarget: "1",
+		Operand: "=",
+	}
+)
+
+// jobImageVerification enforces the image verification policy of the
+// servers. The task groups of the jobs whose images must be verified are
+// constrained to the clients verifying the images of every namespace, and the
+// images that can't be verified are rejected.
+type jobImageVerification struct {
+	config *config.ImageVerificationConfig
+}
+
+func (jobImageVerification) Name() string {
+	return "image_verification"
+}
+
+func (h jobImageVerification) Mutate(job *structs.Job) ([]error, error) {
+	if !h.config.Required(job.Namespace) {
+		return nil, nil
+	}
+
+	for _, tg := range job.TaskGroups {
+		if !groupRunsDocker(tg) {
+			continue
+		}
+
+		found := false
+		for _, c := range tg.Constraints {
+			if c.Equal(imageVerificationConstraint) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			tg.Constraints = append(tg.Constraints, imageVerificationConstraint)
+		}
+	}
+	return nil, nil
+}
+
+func (h jobImageVerification) Validate(job *structs.Job) ([]error, error) {
+	if !h.config.Required(job.Namespace) {
+		return nil, nil
+	}
+
+	// Images loaded from archives aren't in a registry to read their
+	// signatures from
+	var mErr multierror.Error
+	for _, tg := range job.TaskGroups {
+		for _, task := range tg.Tasks {
+			if task.Driver != "docker" {
+				continue
+			}
+			if load, ok := task.Config["load"]; ok && load != "" {
+				multierror.Append(&mErr, fmt.Errorf(
+					"task %q in group %q loads its image from an archive, which can't be verified in namespace %q",
+					task.Name, tg.Name, job.Namespace))
+			}
+		}
+	}
+	return nil, mErr.ErrorOrNil()
+}
+
+// groupRunsDocker returns whether a task of the group uses the docker driver.
+func groupRunsDocker(tg *structs.TaskGroup) bool {
+	for _, task := range tg.Tasks {
+		if task.Driver == "docker" {
+			return true
+		}
+	}
+	return false
+}
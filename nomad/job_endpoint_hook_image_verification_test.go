@@ -0,0 +1,76 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+func TestJobEndpoint_ImageVerification(t *testing.T) {
+	t.Parallel()
+	h := jobImageVerification{config: &config.ImageVerificationConfig{
+		Enabled:          true,
+		ExemptNamespaces: []string{"dev"},
+	}}
+
+	job := mock.Job()
+	job.TaskGroups = append(job.TaskGroups, job.TaskGroups[0].Copy())
+	job.TaskGroups[1].Name = "docker"
+	task := job.TaskGroups[1].Tasks[0]
+	task.Driver = "docker"
+	task.Config = map[string]interface{}{"image": "redis:3.2"}
+
+	// Only the groups running docker tasks are constrained, once
+	for i := 0; i < 2; i++ {
+		if _, err := h.Mutate(job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	for _, c := range job.TaskGroups[0].Constraints {
+		if c.Equal(imageVerificationConstraint) {
+			t.Fatalf("unexpected constraint on group without docker tasks")
+		}
+	}
+	found := 0
+	for _, c := range job.TaskGroups[1].Constraints {
+		if c.Equal(imageVerificationConstraint) {
+			found++
+		}
+	}
+	if found != 1 {
+		t.Fatalf("expected one image verification constraint; got %d", found)
+	}
+
+	// Images loaded from archives can't be verified
+	if _, err := h.Validate(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	task.Config["load"] = "redis.tar"
+	if _, err := h.Validate(job); err == nil || !strings.Contains(err.Error(), "can't be verified") {
+		t.Fatalf("expected loaded image to be rejected; got %v", err)
+	}
+
+	// The jobs of exempt namespaces are left alone
+	exempt := mock.Job()
+	exempt.Namespace = "dev"
+	exempt.TaskGroups[0].Tasks[0].Driver = "docker"
+	exempt.TaskGroups[0].Tasks[0].Config = map[string]interface{}{"image": "redis:3.2", "load": "redis.tar"}
+	constraints := len(exempt.TaskGroups[0].Constraints)
+	if _, err := h.Mutate(exempt); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := h.Validate(exempt); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(exempt.TaskGroups[0].Constraints) != constraints {
+		t.Fatalf("unexpected constraint on exempt job: %v", exempt.TaskGroups[0].Constraints)
+	}
+
+	// Nothing is required when the policy is disabled
+	h.config.Enabled = false
+	if _, err := h.Validate(job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
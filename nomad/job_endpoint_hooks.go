@@ -0,0 +1,109 @@
+package nomad
+
+import (
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// jobAdmissionController is the base interface of the hooks a job goes
+// through when it is submitted to the servers.
+type jobAdmissionController interface {
+	// Name returns the name of the admission controller.
+	Name() string
+}
+
+// jobMutator is an admission controller that may modify a submitted job, for
+// example to set defaults or add implicit constraints. Mutators are run before
+// any validator so that validators see the job that will be stored.
+type jobMutator interface {
+	jobAdmissionController
+
+	// Mutate modifies the job in place and returns any warnings to surface to
+	// the submitter. A returned error rejects the job.
+	Mutate(job *structs.Job) (warnings []error, err error)
+}
+
+// jobValidator is an admission controller that checks a submitted job without
+// modifying it. Validators can be used to enforce quotas, site policies or to
+// warn about deprecated fields.
+type jobValidator interface {
+	jobAdmissionController
+
+	// Validate returns any warnings to surface to the submitter and an error
+	// if the job must be rejected.
+	Validate(job *structs.Job) (warnings []error, err error)
+}
+
+// admissionControllers runs the job through the endpoint's mutators and then
+// its validators. The warnings of every controller are returned. Mutator
+// errors abort the chain, while the errors of all validators are combined so
+// the submitter sees every problem with the job at once.
+func (j *Job) admissionControllers(job *structs.Job) (warnings []error, err error) {
+	for _, mutator := range j.mutators {
+		w, err := mutator.Mutate(job)
+		warnings = append(warnings, w...)
+		if err != nil {
+			return warnings, multierror.Prefix(err, mutator.Name()+":")
+		}
+	}
+
+	var mErr multierror.Error
+	for _, validator := range j.validators {
+		w, err := validator.Validate(job)
+		warnings = append(warnings, w...)
+		if err != nil {
+			multierror.Append(&mErr, err)
+		}
+	}
+
+	return warnings, mErr.ErrorOrNil()
+}
+
+// jobCanonicalizer sets the defaults of the job fields and converts
+// deprecated fields to their replacement.
+type jobCanonicalizer struct{}
+
+func (jobCanonicalizer) Name() string {
+	return "canonicalize"
+}
+
+func (jobCanonicalizer) Mutate(job *structs.Job) ([]error, error) {
+	return flattenErrors(job.Canonicalize()), nil
+}
+
+// jobImpliedConstraints adds the constraints required by the features the job
+// is requesting.
+type jobImpliedConstraints struct{}
+
+func (jobImpliedConstraints) Name() string {
+	return "implied_constraints"
+}
+
+func (jobImpliedConstraints) Mutate(job *structs.Job) ([]error, error) {
+	setImplicitConstraints(job)
+	return nil, nil
+}
+
+// jobValidate validates the job and its task driver configurations.
+type jobValidate struct{}
+
+func (jobValidate) Name() string {
+	return "validate"
+}
+
+func (jobValidate) Validate(job *structs.Job) ([]error, error) {
+	err, warnings := validateJob(job)
+	return flattenErrors(warnings), err
+}
+
+// flattenErrors returns the errors wrapped by a multierror, or the error itself
+// if it isn't one.
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if mErr, ok := err.(*multierror.Error); ok {
+		return mErr.Errors
+	}
+	return []error{err}
+}
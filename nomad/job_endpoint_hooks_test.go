@@ -0,0 +1,119 @@
+package nomad
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// testMutator sets the job meta key to the given value
+type testMutator struct {
+	key, value string
+	err        error
+}
+
+func (m testMutator) Name() string { return "test_mutator" }
+
+func (m testMutator) Mutate(job *structs.Job) ([]error, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if job.Meta == nil {
+		job.Meta = make(map[string]string)
+	}
+	job.Meta[m.key] = m.value
+	return []error{fmt.Errorf("set meta %q", m.key)}, nil
+}
+
+// testValidator rejects jobs whose meta key doesn't have the given value
+type testValidator struct {
+	key, value string
+}
+
+func (v testValidator) Name() string { return "test_validator" }
+
+func (v testValidator) Validate(job *structs.Job) ([]error, error) {
+	if job.Meta[v.key] != v.value {
+		return nil, fmt.Errorf("meta %q must be %q", v.key, v.value)
+	}
+	return []error{fmt.Errorf("checked meta %q", v.key)}, nil
+}
+
+func TestJobEndpoint_AdmissionControllers(t *testing.T) {
+	t.Parallel()
+	j := &Job{
+		mutators: []jobMutator{
+			testMutator{key: "foo", value: "bar"},
+		},
+		validators: []jobValidator{
+			testValidator{key: "foo", value: "bar"},
+		},
+	}
+
+	job := mock.Job()
+	warnings, err := j.admissionControllers(job)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if job.Meta["foo"] != "bar" {
+		t.Fatalf("mutator not applied: %v", job.Meta)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("expected a warning per controller; got %v", warnings)
+	}
+}
+
+func TestJobEndpoint_AdmissionControllers_ValidatorErrors(t *testing.T) {
+	t.Parallel()
+	j := &Job{
+		validators: []jobValidator{
+			testValidator{key: "foo", value: "bar"},
+			testValidator{key: "baz", value: "qux"},
+			jobValidate{},
+		},
+	}
+
+	job := mock.Job()
+	job.Type = structs.JobTypeCore
+	_, err := j.admissionControllers(job)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	mErr, ok := err.(*multierror.Error)
+	if !ok {
+		t.Fatalf("expected a multierror; got %#v", err)
+	}
+	if len(mErr.Errors) < 3 {
+		t.Fatalf("expected the errors of every validator; got %v", mErr.Errors)
+	}
+	for _, expected := range []string{`"foo"`, `"baz"`, "cannot be core"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %s in error; got %v", expected, err)
+		}
+	}
+}
+
+func TestJobEndpoint_AdmissionControllers_MutatorError(t *testing.T) {
+	t.Parallel()
+	j := &Job{
+		mutators: []jobMutator{
+			testMutator{err: fmt.Errorf("failed")},
+		},
+		validators: []jobValidator{
+			testValidator{key: "foo", value: "bar"},
+		},
+	}
+
+	_, err := j.admissionControllers(mock.Job())
+	if err == nil || !strings.Contains(err.Error(), "test_mutator: failed") {
+		t.Fatalf("expected mutator error; got %v", err)
+	}
+	if strings.Contains(err.Error(), "must be") {
+		t.Fatalf("validators should not run after a mutator error: %v", err)
+	}
+}
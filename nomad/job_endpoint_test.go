@@ -7,11 +7,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
 	memdb "github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/helper"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/testutil"
 	"github.com/kr/pretty"
 	"github.com/stretchr/testify/assert"
@@ -93,6 +96,73 @@ func TestJobEndpoint_Register(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_ConnectGateway(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a job with an ingress gateway
+	job := mock.Job()
+	task := job.TaskGroups[0].Tasks[0]
+	task.Services = []*structs.Service{
+		{
+			Name: "ingress",
+			Connect: &structs.ConsulConnect{
+				Gateway: &structs.ConsulGateway{
+					Ingress: &structs.ConsulIngressConfigEntry{
+						Listeners: []*structs.ConsulIngressListener{
+							{
+								Port:     8080,
+								Protocol: "tcp",
+								Services: []*structs.ConsulIngressService{{Name: "api"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Check the configuration entry was written
+	entries := s1.consulConfigEntries.(*consul.MockConfigEntries)
+	entry := entries.Ingress("ingress")
+	if entry == nil || len(entry.Listeners) != 1 || entry.Listeners[0].Port != 8080 {
+		t.Fatalf("bad config entry: %#v", entry)
+	}
+
+	// Check the task was turned into the gateway
+	ws := memdb.NewWatchSet()
+	out, err := s1.fsm.State().JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if kind := out.TaskGroups[0].Tasks[0].Kind; !kind.IsConnectGateway() {
+		t.Fatalf("bad task kind: %q", kind)
+	}
+
+	// Errors writing the configuration entry fail the registration
+	entries.SetError(fmt.Errorf("consul is down"))
+	job = job.Copy()
+	job.ID = "other"
+	req.Job = job
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err == nil || !strings.Contains(err.Error(), "consul is down") {
+		t.Fatalf("expected Consul error; got %v", err)
+	}
+}
+
 func TestJobEndpoint_Register_InvalidDriverConfig(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, func(c *Config) {
@@ -360,6 +430,55 @@ func TestJobEndpoint_Register_ParameterizedJob(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_Namespace(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Registering a job in an unknown namespace fails
+	job := mock.Job()
+	job.Namespace = "web"
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), `namespace "web" not found`) {
+		t.Fatalf("expected namespace error; got %v", err)
+	}
+
+	// Create the namespace and register the job
+	ns := mock.Namespace()
+	ns.Name = "web"
+	if err := s1.fsm.State().UpsertNamespaces(1000, []*structs.Namespace{ns}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := s1.fsm.State().JobByID(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.Namespace != "web" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Moving the job to another namespace fails
+	job2 := job.Copy()
+	job2.Namespace = ""
+	req.Job = job2
+	err = msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "cannot move job") {
+		t.Fatalf("expected move error; got %v", err)
+	}
+}
+
 func TestJobEndpoint_Register_EnforceIndex(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, func(c *Config) {
@@ -504,6 +623,79 @@ func TestJobEndpoint_Register_Vault_Disabled(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_ImageVerification(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		c.ImageVerification = &config.ImageVerificationConfig{Enabled: true}
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Driver = "docker"
+	job.TaskGroups[0].Tasks[0].Config = map[string]interface{}{"image": "redis:3.2"}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The job is constrained to the clients verifying its images
+	out, err := s1.fsm.State().JobByID(nil, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	found := false
+	for _, c := range out.TaskGroups[0].Constraints {
+		if c.Equal(imageVerificationConstraint) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected image verification constraint: %v", out.TaskGroups[0].Constraints)
+	}
+}
+
+func TestJobEndpoint_Register_Notification(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		c.JobNotification = &config.JobNotificationConfig{
+			AllowedWebhooks: []string{"https://ci.example.com/hooks"},
+		}
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.Notification = &structs.JobNotification{
+		Webhook: "http://169.254.169.254/latest/meta-data",
+	}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Webhooks outside of the allowed ones are rejected
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected webhook not allowed error: %v", err)
+	}
+
+	job.Notification.Webhook = "https://ci.example.com/hooks/build"
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestJobEndpoint_Register_Vault_AllowUnauthenticated(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, func(c *Config) {
@@ -554,6 +746,105 @@ func TestJobEndpoint_Register_Vault_AllowUnauthenticated(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Register_Vault_Role(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		f := false
+		c.VaultConfig.Enabled = &f
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request with a job using a Vault role
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Vault = &structs.Vault{
+		Role:       "web",
+		ChangeMode: structs.VaultChangeModeRestart,
+	}
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Roles require workload identities
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "workload identities not enabled") {
+		t.Fatalf("expected identities not enabled error: %v", err)
+	}
+
+	// The servers don't need a Vault token to sign identities
+	s1.vaultIdentities, _ = testVaultIdentitySigner(t)
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestJobEndpoint_Register_Vault_Allowlist(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Enable vault, allow unauthenticated and restrict the policies and roles
+	tr := true
+	s1.config.VaultConfig.Enabled = &tr
+	s1.config.VaultConfig.AllowUnauthenticated = &tr
+	s1.config.VaultConfig.AllowedPolicies = []string{"web-*"}
+	s1.config.VaultConfig.AllowedRoles = []string{"sidecar"}
+	s1.config.VaultConfig.AllowedNamespaces = []string{"team-a"}
+	s1.vault = &TestVaultClient{}
+	s1.vaultIdentities, _ = testVaultIdentitySigner(t)
+
+	// Create the register request with tasks requesting denied policies and
+	// roles
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Vault = &structs.Vault{
+		Policies:   []string{"web-read", "admin"},
+		Namespace:  "team-b",
+		ChangeMode: structs.VaultChangeModeRestart,
+	}
+	sidecar := job.TaskGroups[0].Tasks[0].Copy()
+	sidecar.Name = "sidecar"
+	sidecar.Vault = &structs.Vault{
+		Role:       "web",
+		ChangeMode: structs.VaultChangeModeRestart,
+	}
+	job.TaskGroups[0].Tasks = append(job.TaskGroups[0].Tasks, sidecar)
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil {
+		t.Fatalf("expected allowlist errors")
+	}
+	if !strings.Contains(err.Error(), "Vault policies not allowed: admin") {
+		t.Fatalf("expected policy error: %v", err)
+	}
+	if !strings.Contains(err.Error(), `Vault role "web" not allowed`) {
+		t.Fatalf("expected role error: %v", err)
+	}
+	if !strings.Contains(err.Error(), `Vault namespace "team-b" not allowed`) {
+		t.Fatalf("expected namespace error: %v", err)
+	}
+
+	// Only request allowed policies, roles and namespaces
+	job.TaskGroups[0].Tasks[0].Vault.Policies = []string{"web-read"}
+	job.TaskGroups[0].Tasks[0].Vault.Namespace = "team-a"
+	sidecar.Vault.Role = "sidecar"
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
 func TestJobEndpoint_Register_Vault_NoToken(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, func(c *Config) {
@@ -795,6 +1086,20 @@ func TestJobEndpoint_Revert(t *testing.T) {
 		t.Fatalf("expected current version err: %v", err)
 	}
 
+	// Create revert request requiring the version to be stable
+	revertReq = &structs.JobRevertRequest{
+		JobID:         job.ID,
+		JobVersion:    0,
+		RequireStable: true,
+		WriteRequest:  structs.WriteRequest{Region: "global"},
+	}
+
+	// Fetch the response
+	err = msgpackrpc.CallWithCodec(codec, "Job.Revert", revertReq, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not stable") {
+		t.Fatalf("expected stability err: %v", err)
+	}
+
 	// Create revert request and enforcing it be at version 1
 	revertReq = &structs.JobRevertRequest{
 		JobID:               job.ID,
@@ -856,6 +1161,9 @@ func TestJobEndpoint_Revert(t *testing.T) {
 	if out.Version != 2 {
 		t.Fatalf("got version %d; want %d", out.Version, 2)
 	}
+	if out.RevertVersion == nil || *out.RevertVersion != 0 {
+		t.Fatalf("got revert version %v; want 0", out.RevertVersion)
+	}
 
 	eout, err := state.EvalByID(ws, resp.EvalID)
 	if err != nil {
@@ -934,6 +1242,317 @@ func TestJobEndpoint_Stable(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_UpdatePriority(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the initial register request
+	job := mock.Job()
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Fetch the response
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// An invalid priority is rejected
+	priorityReq := &structs.JobUpdatePriorityRequest{
+		JobID:        job.ID,
+		Priority:     structs.JobMaxPriority + 1,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var priorityResp structs.JobUpdatePriorityResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.UpdatePriority", priorityReq, &priorityResp)
+	if err == nil || !strings.Contains(err.Error(), "priority must be between") {
+		t.Fatalf("expected priority error: %v", err)
+	}
+
+	// Update the priority
+	priorityReq.Priority = 80
+	if err := msgpackrpc.CallWithCodec(codec, "Job.UpdatePriority", priorityReq, &priorityResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if priorityResp.Index == 0 {
+		t.Fatalf("bad index: %d", priorityResp.Index)
+	}
+	if priorityResp.JobVersion != 1 {
+		t.Fatalf("bad version: %d", priorityResp.JobVersion)
+	}
+
+	// Check that the job has the priority but kept its job modify index
+	state := s1.fsm.State()
+	ws := memdb.NewWatchSet()
+	out, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("expected job")
+	}
+	if out.Priority != 80 || !out.NoPlacementChange || out.JobModifyIndex != resp.JobModifyIndex {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Check that no evaluation was created
+	evals, err := state.EvalsByJob(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(evals) != 1 {
+		t.Fatalf("expected only the registration eval: %#v", evals)
+	}
+
+	// Registering the job again records a placement change
+	job.Priority = 80
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Version != 1 || !out.NoPlacementChange {
+		t.Fatalf("unchanged job shouldn't be registered: %#v", out)
+	}
+	job.Meta = map[string]string{"foo": "bar"}
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Version != 2 || out.NoPlacementChange {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestJobEndpoint_Scale(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the initial register request
+	job := mock.Job()
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Unknown task groups and negative counts are rejected
+	scaleReq := &structs.JobScaleRequest{
+		JobID:        job.ID,
+		TaskGroup:    "foo",
+		Count:        3,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var scaleResp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Scale", scaleReq, &scaleResp)
+	if err == nil || !strings.Contains(err.Error(), "task group \"foo\" not found") {
+		t.Fatalf("expected task group error: %v", err)
+	}
+	scaleReq.TaskGroup = job.TaskGroups[0].Name
+	scaleReq.Count = -1
+	err = msgpackrpc.CallWithCodec(codec, "Job.Scale", scaleReq, &scaleResp)
+	if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Fatalf("expected count error: %v", err)
+	}
+
+	// Scale the group
+	scaleReq.Count = 3
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", scaleReq, &scaleResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if scaleResp.EvalID == "" {
+		t.Fatalf("expected eval")
+	}
+
+	state := s1.fsm.State()
+	ws := memdb.NewWatchSet()
+	out, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.Version != 1 || out.TaskGroups[0].Count != 3 {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestJobEndpoint_Capabilities_ACL(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	// A deploy token can submit jobs but not scale them, and a scaler token
+	// can only scale them
+	deploy := mock.ACLPolicy()
+	deploy.Rules = `namespace "default" { capabilities = ["submit-job"] }`
+	scaler := mock.ACLPolicy()
+	scaler.Rules = `namespace "default" { capabilities = ["scale-job"] }`
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{deploy, scaler}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	deployToken := mock.ACLToken()
+	deployToken.Policies = []string{deploy.Name}
+	scalerToken := mock.ACLToken()
+	scalerToken.Policies = []string{scaler.Name}
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{deployToken, scalerToken}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	job := mock.Job()
+	req := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global", AuthToken: scalerToken.SecretID},
+	}
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+	req.AuthToken = deployToken.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	scaleReq := &structs.JobScaleRequest{
+		JobID:        job.ID,
+		TaskGroup:    job.TaskGroups[0].Name,
+		Count:        3,
+		WriteRequest: structs.WriteRequest{Region: "global", AuthToken: deployToken.SecretID},
+	}
+	var scaleResp structs.JobRegisterResponse
+	err = msgpackrpc.CallWithCodec(codec, "Job.Scale", scaleReq, &scaleResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+	scaleReq.AuthToken = scalerToken.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Scale", scaleReq, &scaleResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Dispatching needs the dispatch-job capability
+	dispatchReq := &structs.JobDispatchRequest{
+		JobID:        job.ID,
+		WriteRequest: structs.WriteRequest{Region: "global", AuthToken: deployToken.SecretID},
+	}
+	var dispatchResp structs.JobDispatchResponse
+	err = msgpackrpc.CallWithCodec(codec, "Job.Dispatch", dispatchReq, &dispatchResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Neither token can read the job back
+	get := &structs.JobSpecificRequest{
+		JobID:        job.ID,
+		QueryOptions: structs.QueryOptions{Region: "global", AuthToken: deployToken.SecretID},
+	}
+	var getResp structs.SingleJobResponse
+	err = msgpackrpc.CallWithCodec(codec, "Job.GetJob", get, &getResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+	get.AuthToken = root.SecretID
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJob", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Job == nil || getResp.Job.TaskGroups[0].Count != 3 {
+		t.Fatalf("bad job: %#v", getResp.Job)
+	}
+}
+
+func TestJobEndpoint_Register_HostVolumes_ACL(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	policy := mock.ACLPolicy()
+	policy.Rules = `
+namespace "default" {
+  policy = "write"
+  host_volume "/var/log/*" {
+    policy = "read"
+  }
+}`
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	token := mock.ACLToken()
+	token.Policies = []string{policy.Name}
+	if err := state.UpsertACLTokens(1001, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cases := []struct {
+		driver  string
+		volume  string
+		allowed bool
+	}{
+		{"docker", "/var/log/nginx:/logs:ro", true},
+		{"docker", "/var/log/nginx:/logs", false},
+		{"docker", "/etc:/etc:ro", false},
+		{"docker", "local/data:/data", true},
+		{"docker", "../../../etc:/etc:ro", false},
+		{"docker", "${NOMAD_META_path}:/data:ro", false},
+		{"rkt", "/var/log/nginx:/logs:readOnly", true},
+		{"rkt", "/var/log/nginx:/logs", false},
+	}
+	for _, c := range cases {
+		job := mock.Job()
+		task := job.TaskGroups[0].Tasks[0]
+		task.Driver = c.driver
+		task.Config = map[string]interface{}{
+			"image":   "redis",
+			"volumes": []interface{}{c.volume},
+		}
+		req := &structs.JobRegisterRequest{
+			Job:          job,
+			WriteRequest: structs.WriteRequest{Region: "global", AuthToken: token.SecretID},
+		}
+		var resp structs.JobRegisterResponse
+		err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+		if c.allowed && err != nil {
+			t.Fatalf("%s volume %q: err: %v", c.driver, c.volume, err)
+		}
+		if !c.allowed && (err == nil || !strings.Contains(err.Error(), structs.ErrPermissionDenied.Error())) {
+			t.Fatalf("%s volume %q: expected permission denied: %v", c.driver, c.volume, err)
+		}
+
+		// Management tokens mount any host volume
+		req.AuthToken = root.SecretID
+		if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+			t.Fatalf("%s volume %q: err: %v", c.driver, c.volume, err)
+		}
+	}
+}
+
 func TestJobEndpoint_Evaluate(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, func(c *Config) {
@@ -1684,6 +2303,61 @@ func TestJobEndpoint_GetJobVersions_Diff(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_GetJobVersions_DiffVersion(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register three versions of the job
+	job := mock.Job()
+	reg := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	for _, p := range []int{88, 90, 100} {
+		job.Priority = p
+		if err := msgpackrpc.CallWithCodec(codec, "Job.Register", reg, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Diff every version against the first one
+	get := &structs.JobVersionsRequest{
+		JobID:        job.ID,
+		Diffs:        true,
+		DiffVersion:  helper.Uint64ToPtr(0),
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var versionsResp structs.JobVersionsResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJobVersions", get, &versionsResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	diffs := versionsResp.Diffs
+	if l := len(diffs); l != 3 {
+		t.Fatalf("Got %d diffs; want 3", l)
+	}
+	if d := diffs[0]; len(d.Fields) != 1 || d.Fields[0].Old != "88" || d.Fields[0].New != "100" {
+		t.Fatalf("bad diff: %#v", d)
+	}
+	if d := diffs[1]; len(d.Fields) != 1 || d.Fields[0].Old != "88" || d.Fields[0].New != "90" {
+		t.Fatalf("bad diff: %#v", d)
+	}
+	if d := diffs[2]; d.Type != structs.DiffTypeNone {
+		t.Fatalf("expected no changes against itself: %#v", d)
+	}
+
+	// Diffing against an unknown version fails
+	get.DiffVersion = helper.Uint64ToPtr(10)
+	err := msgpackrpc.CallWithCodec(codec, "Job.GetJobVersions", get, &versionsResp)
+	if err == nil || !strings.Contains(err.Error(), "version 10 not found") {
+		t.Fatalf("expected version not found error; got %v", err)
+	}
+}
+
 func TestJobEndpoint_GetJobVersions_Blocking(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
@@ -1967,6 +2641,102 @@ func TestJobEndpoint_ListJobs(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_ListJobs_Namespace(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a job in the default namespace and one in another
+	state := s1.fsm.State()
+	job1 := mock.Job()
+	job1.ID = "redis"
+	if err := state.UpsertJob(1000, job1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	job2 := mock.Job()
+	job2.ID = "riak"
+	job2.Namespace = "web"
+	if err := state.UpsertJob(1001, job2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Jobs of all namespaces are listed by default
+	get := &structs.JobListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Jobs) != 2 {
+		t.Fatalf("bad: %#v", resp.Jobs)
+	}
+
+	// Lookup the jobs of the namespace
+	get.Namespace = "web"
+	var resp2 structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp2.Jobs) != 1 || resp2.Jobs[0].ID != job2.ID || resp2.Jobs[0].Namespace != "web" {
+		t.Fatalf("bad: %#v", resp2.Jobs)
+	}
+
+	// The prefix only matches the jobs of the namespace
+	get.Prefix = "re"
+	var resp3 structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp3.Jobs) != 0 {
+		t.Fatalf("bad: %#v", resp3.Jobs)
+	}
+}
+
+func TestJobEndpoint_ListJobs_Filter(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create a periodic job and one that isn't
+	state := s1.fsm.State()
+	job1 := mock.Job()
+	job2 := mock.PeriodicJob()
+	if err := state.UpsertJob(1000, job1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(1001, job2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The filter is evaluated against the stubs, whose Periodic field is a
+	// boolean rather than the periodic config of the job
+	get := &structs.JobListRequest{
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+			Filter: `Periodic == "true" and JobSummary.JobID is not empty`,
+		},
+	}
+	var resp structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(resp.Jobs) != 1 || resp.Jobs[0].ID != job2.ID {
+		t.Fatalf("bad: %#v", resp.Jobs)
+	}
+
+	// Fields only the jobs have can't be selected
+	get.Filter = `TaskGroups is empty`
+	var resp2 structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", get, &resp2); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
 func TestJobEndpoint_ListJobs_Blocking(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
@@ -2216,6 +2986,60 @@ func TestJobEndpoint_Evaluations_Blocking(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_Dependencies(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Register a job and a job depending on it
+	dep := mock.Job()
+	dep.Type = structs.JobTypeBatch
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.DependsOn = []string{dep.ID}
+	for _, j := range []*structs.Job{dep, job} {
+		req := &structs.JobRegisterRequest{
+			Job:          j,
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.JobRegisterResponse
+		if err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Making the dependency depend on the job is rejected
+	cycle := dep.Copy()
+	cycle.DependsOn = []string{job.ID}
+	req := &structs.JobRegisterRequest{
+		Job:          cycle,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobRegisterResponse
+	err := msgpackrpc.CallWithCodec(codec, "Job.Register", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "Job dependency cycle") {
+		t.Fatalf("expected cycle error: %v", err)
+	}
+
+	// Lookup the graph
+	get := &structs.JobSpecificRequest{
+		JobID:        dep.ID,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp2 structs.JobDependenciesResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Dependencies", get, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.Index == 0 {
+		t.Fatalf("Bad index: %d", resp2.Index)
+	}
+	if len(resp2.Nodes) != 2 || resp2.Nodes[0].JobID != dep.ID || resp2.Nodes[1].JobID != job.ID {
+		t.Fatalf("bad: %#v", resp2.Nodes)
+	}
+}
+
 func TestJobEndpoint_Deployments(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
@@ -2639,6 +3463,34 @@ func TestJobEndpoint_ValidateJob_InvalidSignals(t *testing.T) {
 	}
 }
 
+func TestJobEndpoint_ValidateJob_InvalidChangeScript(t *testing.T) {
+	t.Parallel()
+	// Create a mock job that wants to run a change script in a driver that
+	// can't
+	job := mock.Job()
+	job.TaskGroups[0].Tasks[0].Driver = "qemu"
+	job.TaskGroups[0].Tasks[0].Templates = []*structs.Template{
+		{
+			EmbeddedTmpl: "foo",
+			DestPath:     "local/foo",
+			ChangeMode:   structs.TemplateChangeModeScript,
+			ChangeScript: &structs.ChangeScript{
+				Command: "/bin/reload",
+				Timeout: time.Second,
+			},
+		},
+	}
+
+	err, warnings := validateJob(job)
+	if err == nil || !strings.Contains(err.Error(), "support running change scripts") {
+		t.Fatalf("Expected change script feasibility error; got %v", err)
+	}
+
+	if warnings != nil {
+		t.Fatalf("got unexpected warnings: %v", warnings)
+	}
+}
+
 func TestJobEndpoint_ValidateJobUpdate(t *testing.T) {
 	t.Parallel()
 	old := mock.Job()
@@ -2717,6 +3569,21 @@ func TestJobEndpoint_Dispatch(t *testing.T) {
 	d7.ParameterizedJob = &structs.ParameterizedJobConfig{}
 	d7.Stop = true
 
+	// Meta and payload schemas
+	d8 := mock.Job()
+	d8.Type = structs.JobTypeBatch
+	d8.ParameterizedJob = &structs.ParameterizedJobConfig{
+		MetaRequired: []string{"foo"},
+		MetaOptional: []string{"bar"},
+		MetaSchema: map[string]*structs.DispatchMetaSchema{
+			"foo": {Type: structs.DispatchMetaTypeInt},
+			"bar": {Allowed: []string{"dev", "prod"}},
+		},
+		PayloadSchema: &structs.DispatchPayloadSchema{
+			ContentType: structs.DispatchPayloadContentTypeJSON,
+		},
+	}
+
 	reqNoInputNoMeta := &structs.JobDispatchRequest{}
 	reqInputDataNoMeta := &structs.JobDispatchRequest{
 		Payload: []byte("hello world"),
@@ -2745,6 +3612,38 @@ func TestJobEndpoint_Dispatch(t *testing.T) {
 	reqInputDataTooLarge := &structs.JobDispatchRequest{
 		Payload: make([]byte, DispatchPayloadSizeLimit+100),
 	}
+	reqCompressedInputData := &structs.JobDispatchRequest{
+		Payload:           snappy.Encode(nil, []byte("hello world")),
+		PayloadCompressed: true,
+	}
+	reqCompressedInputDataTooLarge := &structs.JobDispatchRequest{
+		Payload:           snappy.Encode(nil, make([]byte, DispatchPayloadSizeLimit+100)),
+		PayloadCompressed: true,
+	}
+	reqCompressedInputDataCorrupt := &structs.JobDispatchRequest{
+		Payload:           []byte("hello world"),
+		PayloadCompressed: true,
+	}
+	reqSchemaValid := &structs.JobDispatchRequest{
+		Payload: []byte(`{"hello": "world"}`),
+		Meta: map[string]string{
+			"foo": "1",
+			"bar": "dev",
+		},
+	}
+	reqSchemaBadMeta := &structs.JobDispatchRequest{
+		Meta: map[string]string{
+			"foo": "one",
+			"bar": "staging",
+		},
+	}
+	reqSchemaBadPayload := &structs.JobDispatchRequest{
+		Payload: snappy.Encode(nil, []byte("hello world")),
+		Meta: map[string]string{
+			"foo": "1",
+		},
+		PayloadCompressed: true,
+	}
 
 	type testCase struct {
 		name             string
@@ -2832,6 +3731,46 @@ func TestJobEndpoint_Dispatch(t *testing.T) {
 			err:              true,
 			errStr:           "Payload exceeds maximum size",
 		},
+		{
+			name:             "optional input w/ compressed input",
+			parameterizedJob: d1,
+			dispatchReq:      reqCompressedInputData,
+			err:              false,
+		},
+		{
+			name:             "optional input w/ too big of compressed input",
+			parameterizedJob: d1,
+			dispatchReq:      reqCompressedInputDataTooLarge,
+			err:              true,
+			errStr:           "Payload exceeds maximum size",
+		},
+		{
+			name:             "optional input w/ corrupt compressed input",
+			parameterizedJob: d1,
+			dispatchReq:      reqCompressedInputDataCorrupt,
+			err:              true,
+			errStr:           "Failed to decode compressed payload",
+		},
+		{
+			name:             "schema w/ valid meta and payload",
+			parameterizedJob: d8,
+			dispatchReq:      reqSchemaValid,
+			err:              false,
+		},
+		{
+			name:             "schema w/ invalid meta",
+			parameterizedJob: d8,
+			dispatchReq:      reqSchemaBadMeta,
+			err:              true,
+			errStr:           `Meta key "foo": value "one" is not of type int`,
+		},
+		{
+			name:             "schema w/ invalid compressed payload",
+			parameterizedJob: d8,
+			dispatchReq:      reqSchemaBadPayload,
+			err:              true,
+			errStr:           "Payload is not valid JSON",
+		},
 		{
 			name:             "periodic job dispatched, ensure no eval",
 			parameterizedJob: d6,
@@ -2938,3 +3877,113 @@ func TestJobEndpoint_Dispatch(t *testing.T) {
 		})
 	}
 }
+
+func TestJobEndpoint_Dispatch_PayloadSizeLimit(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+		c.DispatchPayloadSizeLimit = 1024 * 1024
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the parameterized job
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.ParameterizedJob = &structs.ParameterizedJobConfig{}
+	regReq := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var regResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", regReq, &regResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A payload over the default limit is accepted
+	req := &structs.JobDispatchRequest{
+		JobID:        job.ID,
+		Payload:      make([]byte, DispatchPayloadSizeLimit+100),
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.JobDispatchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A payload over the configured limit is rejected
+	req.Payload = make([]byte, 1024*1024+1)
+	err := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "Payload exceeds maximum size") {
+		t.Fatalf("expected size error, got: %v", err)
+	}
+}
+
+func TestJobEndpoint_Dispatch_IdempotencyToken(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0 // Prevent automatic dequeue
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the parameterized job
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.ParameterizedJob = &structs.ParameterizedJobConfig{}
+	regReq := &structs.JobRegisterRequest{
+		Job:          job,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var regResp structs.JobRegisterResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Register", regReq, &regResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Dispatch with a token
+	req := &structs.JobDispatchRequest{
+		JobID:            job.ID,
+		IdempotencyToken: "foo",
+		WriteRequest:     structs.WriteRequest{Region: "global"},
+	}
+	var resp1 structs.JobDispatchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", req, &resp1); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp1.EvalID == "" {
+		t.Fatalf("expected eval")
+	}
+
+	state := s1.fsm.State()
+	out, err := state.JobByID(nil, resp1.DispatchedJobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.DispatchIdempotencyToken != "foo" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Dispatching again with the same token returns the same job
+	var resp2 structs.JobDispatchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp2.DispatchedJobID != resp1.DispatchedJobID {
+		t.Fatalf("got job %q; want %q", resp2.DispatchedJobID, resp1.DispatchedJobID)
+	}
+	if resp2.EvalID != "" {
+		t.Fatalf("unexpected eval %q", resp2.EvalID)
+	}
+
+	// Dispatching with another token dispatches a new job
+	req.IdempotencyToken = "bar"
+	var resp3 structs.JobDispatchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.Dispatch", req, &resp3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp3.DispatchedJobID == resp1.DispatchedJobID {
+		t.Fatalf("expected a new job")
+	}
+}
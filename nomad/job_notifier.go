@@ -0,0 +1,273 @@
+package nomad
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+const (
+	// jobNotifyAttempts is the number of times a webhook is called before
+	// the notification is dropped, waiting jobNotifyRetryInterval times the
+	// number of failed attempts in between.
+	jobNotifyAttempts      = 3
+	jobNotifyRetryInterval = 5 * time.Second
+
+	// webhookResponseLimit is the size of the response of a webhook that is
+	// read, so that the connection may be reused.
+	webhookResponseLimit = 64 * 1024
+)
+
+// jobNotifier calls the webhooks of the batch jobs that reach a terminal
+// state. It runs on the leader.
+type jobNotifier struct {
+	region  string
+	logger  *log.Logger
+	client  *http.Client
+	allowed *config.JobNotificationConfig
+
+	// notified records through Raft that the notification of the run of the
+	// job with the job modify index was sent.
+	notified func(jobID string, jobModifyIndex uint64) error
+
+	// inflight is the job modify index of the jobs whose notification is
+	// being sent, so that they aren't sent twice before being recorded.
+	inflight map[string]uint64
+	l        sync.Mutex
+
+	stopCh chan struct{}
+}
+
+// notifyJobCompletions calls the webhooks of the jobs that reach a terminal
+// state until the stop channel is closed. The notified runs are recorded in
+// the jobs so that a new leader only sends the notifications that weren't
+// sent yet.
+func (s *Server) notifyJobCompletions(stopCh chan struct{}) {
+	// Redirects aren't followed as they could lead out of the allowed
+	// webhooks
+	client := cleanhttp.DefaultClient()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	n := &jobNotifier{
+		region:   s.config.Region,
+		logger:   s.logger,
+		client:   client,
+		allowed:  s.config.JobNotification,
+		inflight: make(map[string]uint64),
+		stopCh:   stopCh,
+		notified: func(jobID string, jobModifyIndex uint64) error {
+			req := structs.JobNotifiedRequest{
+				JobID:          jobID,
+				JobModifyIndex: jobModifyIndex,
+				WriteRequest:   structs.WriteRequest{Region: s.config.Region},
+			}
+			_, _, err := s.raftApply(structs.JobNotifiedRequestType, &req)
+			return err
+		},
+	}
+
+	for {
+		state := s.fsm.State()
+		ws := memdb.NewWatchSet()
+		ws.Add(state.AbandonCh())
+		if err := n.scan(ws, state); err != nil {
+			s.logger.Printf("[ERR] nomad.job_notifier: failed to look for terminal jobs: %v", err)
+			select {
+			case <-time.After(jobNotifyRetryInterval):
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		// The stop channel is watched along with the jobs
+		ws.Add(stopCh)
+		ws.Watch(nil)
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+	}
+}
+
+// scan sends the notifications of the jobs pending one that aren't already
+// being sent.
+func (n *jobNotifier) scan(ws memdb.WatchSet, state *state.StateStore) error {
+	iter, err := state.JobsPendingNotification(ws)
+	if err != nil {
+		return err
+	}
+
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		job := raw.(*structs.Job)
+
+		n.l.Lock()
+		modifyIndex, ok := n.inflight[job.ID]
+		if !ok || modifyIndex != job.JobModifyIndex {
+			n.inflight[job.ID] = job.JobModifyIndex
+		}
+		n.l.Unlock()
+		if ok && modifyIndex == job.JobModifyIndex {
+			continue
+		}
+
+		event, err := n.event(state, job)
+		if err != nil {
+			n.done(job)
+			return err
+		}
+		go n.notify(job, event)
+	}
+	return nil
+}
+
+// done removes the job from the notifications being sent.
+func (n *jobNotifier) done(job *structs.Job) {
+	n.l.Lock()
+	defer n.l.Unlock()
+	if n.inflight[job.ID] == job.JobModifyIndex {
+		delete(n.inflight, job.ID)
+	}
+}
+
+// event returns the event notifying that the job reached a terminal state.
+func (n *jobNotifier) event(state *state.StateStore, job *structs.Job) (*structs.JobNotificationEvent, error) {
+	event := &structs.JobNotificationEvent{
+		Region:   n.region,
+		JobID:    job.ID,
+		ParentID: job.ParentID,
+		Name:     job.Name,
+		Type:     job.Type,
+		Version:  job.Version,
+		Status:   job.Status,
+		Meta:     job.Meta,
+		Stopped:  job.Stop,
+		Time:     time.Now().UTC(),
+	}
+
+	summary, err := state.JobSummaryByID(nil, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if summary != nil {
+		event.Summary = summary.Summary
+	}
+
+	// Only the latest allocation of each instance counts, since the failed
+	// ones may have been rescheduled
+	allocs, err := state.AllocsByJob(nil, job.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, alloc := range structs.LatestAllocations(allocs) {
+		switch alloc.ClientStatus {
+		case structs.AllocClientStatusFailed, structs.AllocClientStatusLost:
+			event.Failed = true
+		}
+	}
+	return event, nil
+}
+
+// notify calls the webhook of the job, retrying until it succeeds, the
+// attempts are exhausted or the server stops being the leader. Unless the
+// server stopped being the leader, the notification is then recorded as sent
+// so that it isn't sent again.
+func (n *jobNotifier) notify(job *structs.Job, event *structs.JobNotificationEvent) {
+	defer n.done(job)
+	if n.send(job.Notification, event) {
+		if err := n.notified(job.ID, job.JobModifyIndex); err != nil {
+			n.logger.Printf("[ERR] nomad.job_notifier: failed to record the notification of job %q: %v", job.ID, err)
+		}
+	}
+}
+
+// send calls the webhook of the notification. It returns false if the server
+// stopped being the leader before the notification was sent or dropped.
+func (n *jobNotifier) send(notification *structs.JobNotification, event *structs.JobNotificationEvent) bool {
+	if !n.allowed.Allows(notification.Webhook) {
+		n.logger.Printf("[WARN] nomad.job_notifier: webhook of job %q is not allowed by the job_notification configuration, dropping the notification", event.JobID)
+		return true
+	}
+
+	body, err := notification.Render(event)
+	if err != nil {
+		n.logger.Printf("[ERR] nomad.job_notifier: failed to render the notification of job %q: %v", event.JobID, err)
+		return true
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := n.call(notification, body)
+		if err == nil {
+			n.logger.Printf("[DEBUG] nomad.job_notifier: notified that job %q is %s", event.JobID, event.Status)
+			return true
+		}
+		if attempt == jobNotifyAttempts {
+			n.logger.Printf("[ERR] nomad.job_notifier: failed to notify that job %q is %s, giving up: %v", event.JobID, event.Status, err)
+			return true
+		}
+		n.logger.Printf("[WARN] nomad.job_notifier: failed to notify that job %q is %s, retrying: %v", event.JobID, event.Status, err)
+
+		select {
+		case <-time.After(time.Duration(attempt) * jobNotifyRetryInterval):
+		case <-n.stopCh:
+			return false
+		}
+	}
+}
+
+// call sends the body to the webhook of the notification.
+func (n *jobNotifier) call(notification *structs.JobNotification, body []byte) error {
+	method := notification.Method
+	if method == "" {
+		method = structs.DefaultJobNotificationMethod
+	}
+	timeout := notification.Timeout
+	if timeout == 0 {
+		timeout = structs.DefaultJobNotificationTimeout
+	}
+	return callWebhook(n.client, method, notification.Webhook, notification.Headers, timeout, body)
+}
+
+// callWebhook sends the body to a webhook, returning an error unless it
+// responds with a success status code.
+func callWebhook(c *http.Client, method, address string, headers map[string]string, timeout time.Duration, body []byte) error {
+	req, err := http.NewRequest(method, address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := *c
+	client.Timeout = timeout
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The response is discarded rather than logged as the webhook may
+	// not be trusted
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package nomad
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+func TestJobNotifier_Scan(t *testing.T) {
+	t.Parallel()
+	state := testStateStore(t)
+
+	requests := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- r.Method + " " + r.Header.Get("X-Token") + " " + string(body)
+	}))
+	defer ts.Close()
+
+	notification := &structs.JobNotification{
+		Webhook: ts.URL + "/hooks/jobs",
+		Method:  "PUT",
+		Headers: map[string]string{"X-Token": "secret"},
+		Payload: `{{ .JobID }} {{ .Status }} {{ .Failed }}`,
+	}
+
+	// A job whose allocation failed
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.Notification = notification
+	if err := state.UpsertJob(1000, job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	alloc.Job = job
+	alloc.JobID = job.ID
+	alloc.ClientStatus = structs.AllocClientStatusFailed
+	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A job whose webhook isn't allowed
+	denied := mock.Job()
+	denied.Type = structs.JobTypeBatch
+	denied.Stop = true
+	denied.Notification = notification.Copy()
+	denied.Notification.Webhook = ts.URL + "/admin"
+	if err := state.UpsertJob(1002, denied); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	index := uint64(1003)
+	newNotifier := func(stopCh chan struct{}) *jobNotifier {
+		return &jobNotifier{
+			region: "global",
+			logger: testLogger(),
+			client: cleanhttp.DefaultClient(),
+			allowed: &config.JobNotificationConfig{
+				AllowedWebhooks: []string{ts.URL + "/hooks/"},
+			},
+			inflight: make(map[string]uint64),
+			stopCh:   stopCh,
+			notified: func(jobID string, jobModifyIndex uint64) error {
+				index++
+				return state.UpdateJobNotified(index, jobID, jobModifyIndex)
+			},
+		}
+	}
+
+	// The job is notified once although it is pending until the
+	// notification is recorded
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	n := newNotifier(stopCh)
+	for i := 0; i < 2; i++ {
+		if err := n.scan(memdb.NewWatchSet(), state); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	select {
+	case req := <-requests:
+		expected := "PUT secret " + job.ID + " dead true"
+		if req != expected {
+			t.Fatalf("got %q; want %q", req, expected)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("webhook not called")
+	}
+
+	// Both notifications are recorded, the denied one without being sent
+	testutil.WaitForResult(func() (bool, error) {
+		iter, err := state.JobsPendingNotification(nil)
+		if err != nil {
+			return false, err
+		}
+		if raw := iter.Next(); raw != nil {
+			return false, nil
+		}
+		return true, nil
+	}, func(err error) {
+		t.Fatalf("notifications not recorded: %v", err)
+	})
+
+	// A new leader doesn't send them again
+	n = newNotifier(stopCh)
+	if err := n.scan(memdb.NewWatchSet(), state); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	select {
+	case req := <-requests:
+		t.Fatalf("unexpected notification: %q", req)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
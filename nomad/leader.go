@@ -118,6 +118,9 @@ func (s *Server) establishLeadership(stopCh chan struct{}) error {
 		}
 	}
 
+	// Generate the management token the leader issues its own work with
+	s.setLeaderAcl(structs.GenerateUUID())
+
 	// Enable the plan queue, since we are now the leader
 	s.planQueue.SetEnabled(true)
 
@@ -145,6 +148,9 @@ func (s *Server) establishLeadership(stopCh chan struct{}) error {
 	if err := s.restoreRevokingAccessors(); err != nil {
 		return err
 	}
+	if err := s.restoreRevokingSIAccessors(); err != nil {
+		return err
+	}
 
 	// Enable the periodic dispatcher, since we are now the leader.
 	s.periodicDispatcher.SetEnabled(true)
@@ -166,6 +172,31 @@ func (s *Server) establishLeadership(stopCh chan struct{}) error {
 	// Periodically unblock failed allocations
 	go s.periodicUnblockFailedEvals(stopCh)
 
+	// Track the usage reported by the nodes and roll up the capacity
+	s.capacity.SetEnabled(true)
+	go s.rollupCapacity(stopCh)
+
+	// Call the webhooks of the jobs reaching a terminal state
+	go s.notifyJobCompletions(stopCh)
+
+	// Call the webhooks of the deployment transitions
+	if len(s.config.DeploymentWebhooks) != 0 {
+		go s.notifyDeployments(stopCh)
+	}
+
+	// Release the evaluations held until their job's dependencies complete
+	go s.releaseHeldEvals(stopCh)
+
+	// Replicate the ACL policies, roles, auth methods, binding rules and
+	// global tokens from the authoritative region
+	if s.config.ACLEnabled && s.config.Region != s.config.AuthoritativeRegion {
+		go s.replicateACLPolicies(stopCh)
+		go s.replicateACLRoles(stopCh)
+		go s.replicateACLAuthMethods(stopCh)
+		go s.replicateACLBindingRules(stopCh)
+		go s.replicateACLTokens(stopCh)
+	}
+
 	// Setup the heartbeat timers. This is done both when starting up or when
 	// a leader fail over happens. Since the timers are maintained by the leader
 	// node, effectively this means all the timers are renewed at the time of failover.
@@ -271,6 +302,57 @@ func (s *Server) restoreRevokingAccessors() error {
 	return nil
 }
 
+// restoreRevokingSIAccessors revokes the Consul Service Identity tokens whose
+// allocation or node is terminal.
+func (s *Server) restoreRevokingSIAccessors() error {
+	ws := memdb.NewWatchSet()
+	state := s.fsm.State()
+	iter, err := state.SITokenAccessors(ws)
+	if err != nil {
+		return fmt.Errorf("failed to get SI accessors: %v", err)
+	}
+
+	var revoke []*structs.SITokenAccessor
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		accessor := raw.(*structs.SITokenAccessor)
+
+		// Check the allocation
+		alloc, err := state.AllocByID(ws, accessor.AllocID)
+		if err != nil {
+			return fmt.Errorf("failed to lookup allocation %q: %v", accessor.AllocID, err)
+		}
+		if alloc == nil || alloc.Terminated() {
+			revoke = append(revoke, accessor)
+			continue
+		}
+
+		// Check the node
+		node, err := state.NodeByID(ws, accessor.NodeID)
+		if err != nil {
+			return fmt.Errorf("failed to lookup node %q: %v", accessor.NodeID, err)
+		}
+		if node == nil || node.TerminalStatus() {
+			revoke = append(revoke, accessor)
+			continue
+		}
+	}
+
+	if len(revoke) != 0 {
+		if err := s.revokeSITokenAccessors(revoke, true); err != nil {
+			// Tokens that couldn't be revoked are retried on the next
+			// leadership transition so don't block establishing leadership
+			s.logger.Printf("[ERR] nomad: failed to revoke SI tokens: %v", err)
+		}
+	}
+
+	return nil
+}
+
 // restorePeriodicDispatcher is used to restore all periodic jobs into the
 // periodic dispatcher. It also determines if a periodic job should have been
 // created during the leadership transition and force runs them. The periodic
@@ -333,6 +415,8 @@ func (s *Server) schedulePeriodic(stopCh chan struct{}) {
 	defer nodeGC.Stop()
 	jobGC := time.NewTicker(s.config.JobGCInterval)
 	defer jobGC.Stop()
+	aclTokenGC := time.NewTicker(s.config.ACLTokenExpirationGCInterval)
+	defer aclTokenGC.Stop()
 
 	// getLatest grabs the latest index from the state store. It returns true if
 	// the index was retrieved successfully.
@@ -361,6 +445,13 @@ func (s *Server) schedulePeriodic(stopCh chan struct{}) {
 			if index, ok := getLatest(); ok {
 				s.evalBroker.Enqueue(s.coreJobEval(structs.CoreJobJobGC, index))
 			}
+		case <-aclTokenGC.C:
+			if !s.config.ACLEnabled {
+				continue
+			}
+			if index, ok := getLatest(); ok {
+				s.evalBroker.Enqueue(s.coreJobEval(structs.CoreJobExpiredACLTokenGC, index))
+			}
 		case <-stopCh:
 			return
 		}
@@ -377,6 +468,7 @@ func (s *Server) coreJobEval(job string, modifyIndex uint64) *structs.Evaluation
 		JobID:       job,
 		Status:      structs.EvalStatusPending,
 		ModifyIndex: modifyIndex,
+		LeaderACL:   s.getLeaderAcl(),
 	}
 }
 
@@ -478,6 +570,9 @@ func (s *Server) periodicUnblockFailedEvals(stopCh chan struct{}) {
 // revokeLeadership is invoked once we step down as leader.
 // This is used to cleanup any state that may be specific to a leader.
 func (s *Server) revokeLeadership() error {
+	// Clear the leader's token, since we are no longer leader
+	s.setLeaderAcl("")
+
 	// Disable the plan queue, since we are no longer leader
 	s.planQueue.SetEnabled(false)
 
@@ -487,6 +582,9 @@ func (s *Server) revokeLeadership() error {
 	// Disable the blocked eval tracker, since it is only useful as a leader
 	s.blockedEvals.SetEnabled(false)
 
+	// Disable the capacity tracker, forgetting the usage and rollups
+	s.capacity.SetEnabled(false)
+
 	// Disable the periodic dispatcher, since it is only useful as a leader
 	s.periodicDispatcher.SetEnabled(false)
 
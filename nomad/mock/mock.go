@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/hashicorp/nomad/nomad/structs"
@@ -125,7 +126,6 @@ func Job() *structs.Job {
 							MemoryMB: 256,
 							Networks: []*structs.NetworkResource{
 								&structs.NetworkResource{
-									MBits:        50,
 									DynamicPorts: []structs.Port{{Label: "http"}, {Label: "admin"}},
 								},
 							},
@@ -195,7 +195,6 @@ func SystemJob() *structs.Job {
 							MemoryMB: 256,
 							Networks: []*structs.NetworkResource{
 								&structs.NetworkResource{
-									MBits:        50,
 									DynamicPorts: []structs.Port{{Label: "http"}},
 								},
 							},
@@ -228,6 +227,38 @@ func PeriodicJob() *structs.Job {
 	return job
 }
 
+func ConnectJob() *structs.Job {
+	job := Job()
+	tg := job.TaskGroups[0]
+	task := tg.Tasks[0]
+	task.Services = append(task.Services, &structs.Service{
+		Name:      "testconnect",
+		PortLabel: "http",
+		Connect: &structs.ConsulConnect{
+			SidecarService: &structs.ConsulSidecarService{},
+		},
+	})
+	task.Resources.Networks[0].DynamicPorts = append(task.Resources.Networks[0].DynamicPorts,
+		structs.Port{Label: structs.ConnectProxyPortLabel("testconnect")})
+
+	proxy := &structs.Task{
+		Name:   "connect-proxy-testconnect",
+		Kind:   structs.NewTaskKind(structs.ConnectProxyPrefix, "testconnect"),
+		Driver: "docker",
+		Config: map[string]interface{}{
+			"image": "envoyproxy/envoy:v1.11.2",
+		},
+		LogConfig: structs.DefaultLogConfig(),
+		Resources: &structs.Resources{
+			CPU:      250,
+			MemoryMB: 128,
+		},
+	}
+	proxy.Canonicalize(job, tg)
+	tg.Tasks = append(tg.Tasks, proxy)
+	return job
+}
+
 func Eval() *structs.Evaluation {
 	eval := &structs.Evaluation{
 		ID:       structs.GenerateUUID(),
@@ -298,6 +329,17 @@ func Alloc() *structs.Allocation {
 	return alloc
 }
 
+func ConnectAlloc() *structs.Allocation {
+	alloc := Alloc()
+	alloc.Job = ConnectJob()
+	alloc.JobID = alloc.Job.ID
+	alloc.TaskResources["connect-proxy-testconnect"] = &structs.Resources{
+		CPU:      250,
+		MemoryMB: 128,
+	}
+	return alloc
+}
+
 func VaultAccessor() *structs.VaultAccessor {
 	return &structs.VaultAccessor{
 		Accessor:    structs.GenerateUUID(),
@@ -308,6 +350,30 @@ func VaultAccessor() *structs.VaultAccessor {
 	}
 }
 
+func SITokenAccessor() *structs.SITokenAccessor {
+	return &structs.SITokenAccessor{
+		NodeID:     structs.GenerateUUID(),
+		AllocID:    structs.GenerateUUID(),
+		AccessorID: structs.GenerateUUID(),
+		TaskName:   "foo",
+	}
+}
+
+func ServiceRegistration() *structs.ServiceRegistration {
+	return &structs.ServiceRegistration{
+		ID:          structs.GenerateUUID(),
+		ServiceName: "web",
+		JobID:       "example",
+		AllocID:     structs.GenerateUUID(),
+		TaskName:    "web",
+		NodeID:      structs.GenerateUUID(),
+		Datacenter:  "dc1",
+		Tags:        []string{"pci"},
+		Address:     "192.168.0.100",
+		Port:        5000,
+	}
+}
+
 func Deployment() *structs.Deployment {
 	return &structs.Deployment{
 		ID:             structs.GenerateUUID(),
@@ -327,6 +393,104 @@ func Deployment() *structs.Deployment {
 	}
 }
 
+func Namespace() *structs.Namespace {
+	return &structs.Namespace{
+		Name:        fmt.Sprintf("team-%s", structs.GenerateUUID()[:8]),
+		Description: "Namespace of a team",
+		Meta: map[string]string{
+			"owner": "ops",
+		},
+		Capabilities: &structs.NamespaceCapabilities{
+			DisabledTaskDrivers: []string{"raw_exec"},
+		},
+		CreateIndex: 100,
+		ModifyIndex: 101,
+	}
+}
+
+func ACLPolicy() *structs.ACLPolicy {
+	policy := &structs.ACLPolicy{
+		Name:        fmt.Sprintf("policy-%s", structs.GenerateUUID()[:8]),
+		Description: "Read the default namespace",
+		Rules:       `namespace "default" { policy = "read" }`,
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+	policy.SetHash()
+	return policy
+}
+
+func ACLRole() *structs.ACLRole {
+	role := &structs.ACLRole{
+		Name:        fmt.Sprintf("role-%s", structs.GenerateUUID()[:8]),
+		Description: "Operate the default namespace",
+		Policies:    []string{"foo", "bar"},
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+	role.SetHash()
+	return role
+}
+
+func ACLAuthMethod() *structs.ACLAuthMethod {
+	method := &structs.ACLAuthMethod{
+		Name:          fmt.Sprintf("auth-%s", structs.GenerateUUID()[:8]),
+		Type:          structs.ACLAuthMethodTypeOIDC,
+		TokenLocality: structs.ACLAuthMethodTokenLocalityLocal,
+		MaxTokenTTL:   time.Hour,
+		Config: &structs.ACLAuthMethodConfig{
+			OIDCDiscoveryURL:    "https://oidc.example.com",
+			OIDCClientID:        "nomad",
+			OIDCClientSecret:    "secret",
+			AllowedRedirectURIs: []string{"http://localhost:4649/oidc/callback"},
+			ClaimMappings:       map[string]string{"team": "team"},
+			ListClaimMappings:   map[string]string{"groups": "groups"},
+		},
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+	method.SetHash()
+	return method
+}
+
+func ACLBindingRule() *structs.ACLBindingRule {
+	rule := &structs.ACLBindingRule{
+		ID:          structs.GenerateUUID(),
+		Description: "Grant the engineers the policy of their team",
+		AuthMethod:  "auth-method",
+		Selector:    `"engineering" in list.groups`,
+		BindType:    structs.ACLBindingRuleBindTypePolicy,
+		BindName:    "${value.team}",
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+	rule.SetHash()
+	return rule
+}
+
+func ACLToken() *structs.ACLToken {
+	token := &structs.ACLToken{
+		AccessorID:  structs.GenerateUUID(),
+		SecretID:    structs.GenerateUUID(),
+		Name:        "my cool token " + structs.GenerateUUID(),
+		Type:        structs.ACLClientToken,
+		Policies:    []string{"foo", "bar"},
+		CreateTime:  time.Now().UTC(),
+		CreateIndex: 10,
+		ModifyIndex: 20,
+	}
+	token.SetHash()
+	return token
+}
+
+func ACLManagementToken() *structs.ACLToken {
+	token := ACLToken()
+	token.Type = structs.ACLManagementToken
+	token.Policies = nil
+	token.SetHash()
+	return token
+}
+
 func Plan() *structs.Plan {
 	return &structs.Plan{
 		Priority: 50,
@@ -0,0 +1,231 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	memdb "github.com/hashicorp/go-memdb"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Namespace endpoint is used for manipulating namespaces
+type Namespace struct {
+	srv *Server
+}
+
+// UpsertNamespaces is used to create or update a set of namespaces
+func (n *Namespace) UpsertNamespaces(args *structs.NamespaceUpsertRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := n.srv.forward("Namespace.UpsertNamespaces", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "upsert_namespaces"}, time.Now())
+
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Namespaces) == 0 {
+		return fmt.Errorf("must specify at least one namespace")
+	}
+	for _, ns := range args.Namespaces {
+		if err := ns.Validate(); err != nil {
+			return fmt.Errorf("Invalid namespace %q: %v", ns.Name, err)
+		}
+	}
+
+	// Update via Raft
+	_, index, err := n.srv.raftApply(structs.NamespaceUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// DeleteNamespaces is used to delete a set of namespaces
+func (n *Namespace) DeleteNamespaces(args *structs.NamespaceDeleteRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := n.srv.forward("Namespace.DeleteNamespaces", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "delete_namespaces"}, time.Now())
+
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the arguments
+	if len(args.Namespaces) == 0 {
+		return fmt.Errorf("must specify at least one namespace to delete")
+	}
+
+	// Check that every namespace exists
+	snap, err := n.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+
+	var mErr multierror.Error
+	for _, name := range args.Namespaces {
+		ns, err := snap.NamespaceByName(nil, name)
+		if err != nil {
+			return err
+		}
+		if ns == nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("namespace %q not found", name))
+			continue
+		}
+
+		// Variables would be orphaned by the deletion of their namespace
+		iter, err := snap.VariablesByPathPrefix(nil, name, "")
+		if err != nil {
+			return err
+		}
+		if iter.Next() != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("namespace %q has variables", name))
+		}
+
+		// Jobs too
+		iter, err = snap.JobsByNamespace(nil, name, "")
+		if err != nil {
+			return err
+		}
+		if iter.Next() != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("namespace %q has jobs", name))
+		}
+	}
+	if err := mErr.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	// Update via Raft
+	_, index, err := n.srv.raftApply(structs.NamespaceDeleteRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// ListNamespaces is used to list the namespaces
+func (n *Namespace) ListNamespaces(args *structs.NamespaceListRequest,
+	reply *structs.NamespaceListResponse) error {
+	if done, err := n.srv.forward("Namespace.ListNamespaces", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "list_namespace"}, time.Now())
+
+	aclObj, err := n.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Capture all the namespaces
+			iter, err := state.PrefixFrom(ws, "namespaces", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
+			if err != nil {
+				return err
+			}
+			iter = newFilterIterator(iter, func(raw interface{}) bool {
+				return aclObj.AllowNamespaceRead(raw.(*structs.Namespace).Name)
+			})
+
+			var namespaces []*structs.Namespace
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.Namespace).Name
+			}, nil)
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				namespaces = append(namespaces, obj.(*structs.Namespace))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			reply.Namespaces = namespaces
+			reply.NextToken = nextToken
+
+			// Use the last index that affected the namespaces table
+			index, err := state.Index("namespaces")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			n.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return n.srv.blockingRPC(&opts)
+}
+
+// GetNamespace is used to request information about a specific namespace
+func (n *Namespace) GetNamespace(args *structs.NamespaceSpecificRequest,
+	reply *structs.SingleNamespaceResponse) error {
+	if done, err := n.srv.forward("Namespace.GetNamespace", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "namespace", "get_namespace"}, time.Now())
+
+	aclObj, err := n.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Verify the arguments
+			if args.Name == "" {
+				return fmt.Errorf("missing namespace name")
+			}
+			if !aclObj.AllowNamespaceRead(args.Name) {
+				return structs.ErrPermissionDenied
+			}
+
+			// Look for the namespace
+			out, err := state.NamespaceByName(ws, args.Name)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Namespace = out
+			if out != nil {
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the namespaces table
+				index, err := state.Index("namespaces")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			n.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return n.srv.blockingRPC(&opts)
+}
@@ -0,0 +1,204 @@
+package nomad
+
+import (
+	"testing"
+	"time"
+
+	memdb "github.com/hashicorp/go-memdb"
+	msgpackrpc "github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceEndpoint_GetNamespace(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// Create the namespace
+	ns := mock.Namespace()
+	assert.Nil(s1.fsm.State().UpsertNamespaces(1000, []*structs.Namespace{ns}), "UpsertNamespaces")
+
+	// Lookup the namespace
+	get := &structs.NamespaceSpecificRequest{
+		Name:         ns.Name,
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.SingleNamespaceResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Namespace.GetNamespace", get, &resp), "RPC")
+	assert.EqualValues(1000, resp.Index, "resp.Index")
+	assert.Equal(ns, resp.Namespace, "Returned namespace not equal")
+
+	// Lookup a namespace that doesn't exist
+	get.Name = "unknown"
+	var resp2 structs.SingleNamespaceResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Namespace.GetNamespace", get, &resp2), "RPC")
+	assert.EqualValues(1000, resp2.Index, "resp2.Index")
+	assert.Nil(resp2.Namespace, "Namespace")
+}
+
+func TestNamespaceEndpoint_GetNamespace_Blocking(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+	assert := assert.New(t)
+
+	// Upsert the namespace we are watching later
+	ns := mock.Namespace()
+	time.AfterFunc(100*time.Millisecond, func() {
+		assert.Nil(state.UpsertNamespaces(200, []*structs.Namespace{ns}), "UpsertNamespaces")
+	})
+
+	// Lookup the namespace
+	req := &structs.NamespaceSpecificRequest{
+		Name: ns.Name,
+		QueryOptions: structs.QueryOptions{
+			Region:        "global",
+			MinQueryIndex: 150,
+		},
+	}
+	var resp structs.SingleNamespaceResponse
+	start := time.Now()
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Namespace.GetNamespace", req, &resp), "RPC")
+	assert.False(time.Since(start) < 100*time.Millisecond, "should block")
+	assert.EqualValues(200, resp.Index, "resp.Index")
+	assert.NotNil(resp.Namespace, "Namespace")
+	assert.Equal(ns.Name, resp.Namespace.Name, "Namespace name")
+}
+
+func TestNamespaceEndpoint_ListNamespaces(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// Create the namespaces
+	ns1 := mock.Namespace()
+	ns1.Name = "web"
+	ns2 := mock.Namespace()
+	ns2.Name = "batch"
+	assert.Nil(s1.fsm.State().UpsertNamespaces(1000, []*structs.Namespace{ns1, ns2}), "UpsertNamespaces")
+
+	// Lookup the namespaces
+	get := &structs.NamespaceListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.NamespaceListResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Namespace.ListNamespaces", get, &resp), "RPC")
+	assert.EqualValues(1000, resp.Index, "Wrong Index")
+	assert.Len(resp.Namespaces, 2, "Namespaces")
+	assert.Equal("batch", resp.Namespaces[0].Name, "Namespace name")
+
+	// Lookup the namespaces by prefix
+	get = &structs.NamespaceListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", Prefix: "we"},
+	}
+	var resp2 structs.NamespaceListResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Namespace.ListNamespaces", get, &resp2), "RPC")
+	assert.EqualValues(1000, resp2.Index, "Wrong Index")
+	assert.Len(resp2.Namespaces, 1, "Namespaces")
+	assert.Equal("web", resp2.Namespaces[0].Name, "Namespace name")
+}
+
+func TestNamespaceEndpoint_UpsertNamespaces(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// Create the register request
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
+	req := &structs.NamespaceUpsertRequest{
+		Namespaces:   []*structs.Namespace{ns1, ns2},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Namespace.UpsertNamespaces", req, &resp), "RPC")
+	assert.NotZero(resp.Index, "bad response index")
+
+	// Ensure created
+	ws := memdb.NewWatchSet()
+	for _, ns := range []*structs.Namespace{ns1, ns2} {
+		out, err := s1.fsm.State().NamespaceByName(ws, ns.Name)
+		assert.Nil(err, "NamespaceByName")
+		assert.NotNil(out, "Namespace")
+		assert.Equal(ns.Description, out.Description, "Namespace description")
+	}
+}
+
+func TestNamespaceEndpoint_UpsertNamespaces_Invalid(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// Create an invalid namespace
+	ns := mock.Namespace()
+	ns.Name = "invalid name"
+	req := &structs.NamespaceUpsertRequest{
+		Namespaces:   []*structs.Namespace{ns},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "Namespace.UpsertNamespaces", req, &resp)
+	assert.NotNil(err, "RPC")
+	assert.Contains(err.Error(), "invalid name")
+}
+
+func TestNamespaceEndpoint_DeleteNamespaces(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// Create the namespace
+	ns := mock.Namespace()
+	assert.Nil(s1.fsm.State().UpsertNamespaces(1000, []*structs.Namespace{ns}), "UpsertNamespaces")
+
+	// Deleting an unknown namespace fails
+	req := &structs.NamespaceDeleteRequest{
+		Namespaces:   []string{ns.Name, "unknown"},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "Namespace.DeleteNamespaces", req, &resp)
+	assert.NotNil(err, "RPC")
+	assert.Contains(err.Error(), `namespace "unknown" not found`)
+
+	// Deleting a namespace with jobs fails
+	job := mock.Job()
+	job.Namespace = ns.Name
+	assert.Nil(s1.fsm.State().UpsertJob(1001, job), "UpsertJob")
+	req.Namespaces = []string{ns.Name}
+	err = msgpackrpc.CallWithCodec(codec, "Namespace.DeleteNamespaces", req, &resp)
+	assert.NotNil(err, "RPC")
+	assert.Contains(err.Error(), "has jobs")
+	assert.Nil(s1.fsm.State().DeleteJob(1002, job.ID), "DeleteJob")
+
+	// Delete the namespace
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Namespace.DeleteNamespaces", req, &resp), "RPC")
+	assert.NotZero(resp.Index, "bad response index")
+
+	// Ensure deleted
+	ws := memdb.NewWatchSet()
+	out, err := s1.fsm.State().NamespaceByName(ws, ns.Name)
+	assert.Nil(err, "NamespaceByName")
+	assert.Nil(out, "Deleted namespace")
+}
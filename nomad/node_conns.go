@@ -0,0 +1,167 @@
+package nomad
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/yamux"
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	// nodeConnHandshakeTimeout bounds how long a client may take to identify
+	// its node on a node connection.
+	nodeConnHandshakeTimeout = 10 * time.Second
+
+	// nodeConnAccepted is written back to a client once its node connection
+	// is accepted. Rejected connections are closed instead.
+	nodeConnAccepted byte = 0x01
+)
+
+// ServeNodeConn handles a connection a client opened for the servers to make
+// RPCs to its node. The client identifies the node with its ID and secret ID,
+// after which the server is the client of a multiplexed session on the
+// connection, on which it opens a stream per RPC. Clients running in process
+// with the server hand it one end of a pipe.
+func (s *Server) ServeNodeConn(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(nodeConnHandshakeTimeout))
+	var req structs.NodeConnRequest
+	if err := codec.NewDecoder(conn, structs.MsgpackHandle).Decode(&req); err != nil {
+		s.logger.Printf("[ERR] nomad.rpc: failed to decode node connection request: %v", err)
+		conn.Close()
+		return
+	}
+
+	node, err := s.fsm.State().NodeByID(nil, req.NodeID)
+	if err != nil {
+		s.logger.Printf("[ERR] nomad.rpc: failed to look up node %q of node connection: %v", req.NodeID, err)
+		conn.Close()
+		return
+	}
+	if node == nil || node.SecretID != req.SecretID {
+		s.logger.Printf("[WARN] nomad.rpc: rejected node connection for unknown node %q", req.NodeID)
+		conn.Close()
+		return
+	}
+
+	if _, err := conn.Write([]byte{nodeConnAccepted}); err != nil {
+		s.logger.Printf("[ERR] nomad.rpc: failed to accept node connection of node %q: %v", req.NodeID, err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	conf := yamux.DefaultConfig()
+	conf.LogOutput = s.config.LogOutput
+	session, err := yamux.Client(conn, conf)
+	if err != nil {
+		s.logger.Printf("[ERR] nomad.rpc: failed to start session of node connection: %v", err)
+		conn.Close()
+		return
+	}
+	s.addNodeConn(req.NodeID, session)
+
+	// The client doesn't open streams, so accepting only waits for the
+	// session to be closed
+	for {
+		stream, err := session.Accept()
+		if err != nil {
+			break
+		}
+		stream.Close()
+	}
+	s.removeNodeConn(req.NodeID, session)
+}
+
+// addNodeConn tracks the session of the node connection of a node, closing
+// the session of a previous connection of the node.
+func (s *Server) addNodeConn(nodeID string, session *yamux.Session) {
+	s.nodeConnsLock.Lock()
+	defer s.nodeConnsLock.Unlock()
+	if old, ok := s.nodeConns[nodeID]; ok {
+		old.Close()
+	}
+	s.nodeConns[nodeID] = session
+}
+
+// removeNodeConn stops tracking the session of a node connection unless the
+// node has connected again since.
+func (s *Server) removeNodeConn(nodeID string, session *yamux.Session) {
+	s.nodeConnsLock.Lock()
+	defer s.nodeConnsLock.Unlock()
+	if s.nodeConns[nodeID] == session {
+		delete(s.nodeConns, nodeID)
+	}
+}
+
+// nodeConn returns the session of the node connection of a node to this
+// server if there is one.
+func (s *Server) nodeConn(nodeID string) (*yamux.Session, bool) {
+	s.nodeConnsLock.Lock()
+	defer s.nodeConnsLock.Unlock()
+	session, ok := s.nodeConns[nodeID]
+	if ok && session.IsClosed() {
+		return nil, false
+	}
+	return session, ok
+}
+
+// closeNodeConns closes the sessions of all the node connections.
+func (s *Server) closeNodeConns() {
+	s.nodeConnsLock.Lock()
+	defer s.nodeConnsLock.Unlock()
+	for nodeID, session := range s.nodeConns {
+		session.Close()
+		delete(s.nodeConns, nodeID)
+	}
+}
+
+// nodeRPC makes an RPC to a node over its node connection to this server.
+func (s *Server) nodeRPC(session *yamux.Session, method string, args, reply interface{}) error {
+	stream, err := session.Open()
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	return msgpackrpc.CallWithCodec(NewClientCodec(stream), method, args, reply)
+}
+
+// findNodeConnServer returns the server of the region the node has its node
+// connection to, asking each of the other servers whether it is theirs.
+func (s *Server) findNodeConnServer(nodeID string) (*serverParts, error) {
+	s.peerLock.RLock()
+	peers := make([]*serverParts, 0, len(s.localPeers))
+	for _, parts := range s.localPeers {
+		peers = append(peers, parts)
+	}
+	s.peerLock.RUnlock()
+
+	args := &structs.NodeSpecificRequest{
+		NodeID: nodeID,
+		QueryOptions: structs.QueryOptions{
+			Region:     s.config.Region,
+			AllowStale: true,
+		},
+	}
+	var lastErr error
+	for _, parts := range peers {
+		if parts.Addr.String() == s.rpcAdvertise.String() {
+			continue
+		}
+		var reply structs.NodeConnQueryResponse
+		if err := s.connPool.RPC(s.config.Region, parts.Addr, parts.MajorVersion, "Status.HasNodeConn", args, &reply); err != nil {
+			lastErr = err
+			continue
+		}
+		if reply.Connected {
+			return parts, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("node %q is not connected to a reachable server: %v", nodeID, lastErr)
+	}
+	return nil, structs.ErrNoNodeConn
+}
@@ -0,0 +1,97 @@
+package nomad
+
+import (
+	"fmt"
+	"net/rpc"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/hashicorp/yamux"
+)
+
+// testClientAllocations serves the client endpoints of a node connection,
+// recording the signals it receives.
+type testClientAllocations struct {
+	signals chan *structs.AllocSignalRequest
+}
+
+func (a *testClientAllocations) Signal(args *structs.AllocSignalRequest, reply *structs.GenericResponse) error {
+	if args.Signal == "SIGBAD" {
+		return fmt.Errorf("invalid signal %q", args.Signal)
+	}
+	a.signals <- args
+	return nil
+}
+
+// testNodeConn opens a node connection of the node to the server, serving the
+// returned endpoint on it, and waits for the server to track it.
+func testNodeConn(t *testing.T, s *Server, node *structs.Node) (*testClientAllocations, *yamux.Session) {
+	pool := NewPool(os.Stderr, time.Minute, 1, nil)
+	session, err := pool.DialNodeConn(s.config.Region, s.config.RPCAddr, &structs.NodeConnRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	endpoint := &testClientAllocations{signals: make(chan *structs.AllocSignalRequest, 1)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("ClientAllocations", endpoint); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	go func() {
+		for {
+			stream, err := session.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeCodec(NewServerCodec(stream))
+		}
+	}()
+
+	testutil.WaitForResult(func() (bool, error) {
+		_, ok := s.nodeConn(node.ID)
+		return ok, fmt.Errorf("node connection not tracked")
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+	return endpoint, session
+}
+
+func TestServer_NodeConn(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	node := mock.Node()
+	if err := s1.fsm.State().UpsertNode(1000, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Connections of unknown nodes or with the wrong secret are rejected
+	pool := NewPool(os.Stderr, time.Minute, 1, nil)
+	for _, req := range []*structs.NodeConnRequest{
+		{NodeID: structs.GenerateUUID(), SecretID: node.SecretID},
+		{NodeID: node.ID, SecretID: structs.GenerateUUID()},
+	} {
+		if _, err := pool.DialNodeConn("global", s1.config.RPCAddr, req); err == nil {
+			t.Fatalf("expected node connection %#v to be rejected", req)
+		}
+	}
+
+	// The connection is tracked until it is closed
+	_, session := testNodeConn(t, s1, node)
+	session.Close()
+	testutil.WaitForResult(func() (bool, error) {
+		_, ok := s1.nodeConn(node.ID)
+		return !ok, fmt.Errorf("closed node connection still tracked")
+	}, func(err error) {
+		t.Fatalf("err: %v", err)
+	})
+}
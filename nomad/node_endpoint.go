@@ -70,6 +70,9 @@ func (n *Node) Register(args *structs.NodeRegisterRequest, reply *structs.NodeUp
 	if len(args.Node.Attributes) == 0 {
 		return fmt.Errorf("missing attributes for client registration")
 	}
+	if err := structs.ValidateDynamicPortRange(args.Node.MinDynamicPort, args.Node.MaxDynamicPort); err != nil {
+		return fmt.Errorf("invalid client registration: %v", err)
+	}
 
 	// COMPAT: Remove after 0.6
 	// Need to check if this node is <0.4.x since SecretID is new in 0.5
@@ -227,6 +230,12 @@ func (n *Node) Deregister(args *structs.NodeDeregisterRequest, reply *structs.No
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "deregister"}, time.Now())
 
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Verify the arguments
 	if args.NodeID == "" {
 		return fmt.Errorf("missing node ID for client deregistration")
@@ -265,6 +274,21 @@ func (n *Node) Deregister(args *structs.NodeDeregisterRequest, reply *structs.No
 		}
 	}
 
+	// Determine if there are any Service Identity token accessors on the node
+	siAccessors, err := n.srv.State().SITokenAccessorsByNode(ws, args.NodeID)
+	if err != nil {
+		n.srv.logger.Printf("[ERR] nomad.client: looking up SI accessors for node %q failed: %v", args.NodeID, err)
+		return err
+	}
+
+	if l := len(siAccessors); l != 0 {
+		n.srv.logger.Printf("[DEBUG] nomad.client: revoking %d SI accessors on node %q due to deregister", l, args.NodeID)
+		if err := n.srv.revokeSITokenAccessors(siAccessors, true); err != nil {
+			n.srv.logger.Printf("[ERR] nomad.client: revoking SI accessors for node %q failed: %v", args.NodeID, err)
+			return err
+		}
+	}
+
 	// Setup the reply
 	reply.EvalIDs = evalIDs
 	reply.EvalCreateIndex = evalIndex
@@ -308,6 +332,13 @@ func (n *Node) UpdateStatus(args *structs.NodeUpdateStatusRequest, reply *struct
 
 	// Update the timestamp of when the node status was updated
 	node.StatusUpdatedAt = time.Now().Unix()
+	args.UpdatedAt = node.StatusUpdatedAt
+
+	// Track the usage of the allocations, which isn't persisted
+	if args.AllocUsage != nil {
+		n.srv.capacity.Update(args.NodeID, args.AllocUsage)
+		args.AllocUsage = nil
+	}
 
 	// Commit this update via Raft
 	var index uint64
@@ -349,6 +380,22 @@ func (n *Node) UpdateStatus(args *structs.NodeUpdateStatusRequest, reply *struct
 				return err
 			}
 		}
+
+		// Determine if there are any Service Identity token accessors on the
+		// node
+		siAccessors, err := n.srv.State().SITokenAccessorsByNode(ws, args.NodeID)
+		if err != nil {
+			n.srv.logger.Printf("[ERR] nomad.client: looking up SI accessors for node %q failed: %v", args.NodeID, err)
+			return err
+		}
+
+		if l := len(siAccessors); l != 0 {
+			n.srv.logger.Printf("[DEBUG] nomad.client: revoking %d SI accessors on node %q due to down state", l, args.NodeID)
+			if err := n.srv.revokeSITokenAccessors(siAccessors, true); err != nil {
+				n.srv.logger.Printf("[ERR] nomad.client: revoking SI accessors for node %q failed: %v", args.NodeID, err)
+				return err
+			}
+		}
 	default:
 		ttl, err := n.srv.resetHeartbeatTimer(args.NodeID)
 		if err != nil {
@@ -386,6 +433,12 @@ func (n *Node) UpdateDrain(args *structs.NodeUpdateDrainRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "update_drain"}, time.Now())
 
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Verify the arguments
 	if args.NodeID == "" {
 		return fmt.Errorf("missing node ID for drain update")
@@ -441,6 +494,12 @@ func (n *Node) Evaluate(args *structs.NodeEvaluateRequest, reply *structs.NodeUp
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "evaluate"}, time.Now())
 
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowNodeWrite() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Verify the arguments
 	if args.NodeID == "" {
 		return fmt.Errorf("missing node ID for evaluation")
@@ -489,6 +548,15 @@ func (n *Node) GetNode(args *structs.NodeSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "get_node"}, time.Now())
 
+	// Clients can read their own node
+	if node, aclObj, err := n.srv.resolveClientOrToken(args.AuthToken); err != nil {
+		return err
+	} else if node != nil && node.ID != args.NodeID {
+		return structs.ErrPermissionDenied
+	} else if !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
@@ -536,6 +604,12 @@ func (n *Node) GetAllocs(args *structs.NodeSpecificRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "get_allocs"}, time.Now())
 
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Verify the arguments
 	if args.NodeID == "" {
 		return fmt.Errorf("missing node ID")
@@ -708,6 +782,13 @@ func (n *Node) batchUpdate(future *batchFuture, updates []*structs.Allocation) {
 		WriteRequest: structs.WriteRequest{Region: n.srv.config.Region},
 	}
 
+	// Find the allocations whose client reconnected before their status is
+	// updated
+	reconnected, err := n.reconnectedAllocs(updates)
+	if err != nil {
+		n.srv.logger.Printf("[ERR] nomad.client: looking up reconnected allocations failed: %v", err)
+	}
+
 	// Commit this update via Raft
 	var mErr multierror.Error
 	_, index, err := n.srv.raftApply(structs.AllocClientUpdateRequestType, batch)
@@ -716,9 +797,19 @@ func (n *Node) batchUpdate(future *batchFuture, updates []*structs.Allocation) {
 		mErr.Errors = append(mErr.Errors, err)
 	}
 
+	// Evaluate the jobs of the allocations whose client reconnected to
+	// reconcile them with their replacements
+	if err == nil && len(reconnected) != 0 {
+		if err := n.createReconnectEvals(reconnected); err != nil {
+			n.srv.logger.Printf("[ERR] nomad.client: creating reconnect evaluations failed: %v", err)
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+
 	// For each allocation we are updating check if we should revoke any
 	// Vault Accessors
 	var revoke []*structs.VaultAccessor
+	var revokeSI []*structs.SITokenAccessor
 	for _, alloc := range updates {
 		// Skip any allocation that isn't dead on the client
 		if !alloc.Terminated() {
@@ -734,6 +825,16 @@ func (n *Node) batchUpdate(future *batchFuture, updates []*structs.Allocation) {
 		}
 
 		revoke = append(revoke, accessors...)
+
+		// Determine if there are any Service Identity token accessors for
+		// the allocation
+		siAccessors, err := n.srv.State().SITokenAccessorsByAlloc(ws, alloc.ID)
+		if err != nil {
+			n.srv.logger.Printf("[ERR] nomad.client: looking up SI accessors for alloc %q failed: %v", alloc.ID, err)
+			mErr.Errors = append(mErr.Errors, err)
+		}
+
+		revokeSI = append(revokeSI, siAccessors...)
 	}
 
 	if l := len(revoke); l != 0 {
@@ -744,10 +845,68 @@ func (n *Node) batchUpdate(future *batchFuture, updates []*structs.Allocation) {
 		}
 	}
 
+	if l := len(revokeSI); l != 0 {
+		n.srv.logger.Printf("[DEBUG] nomad.client: revoking %d SI accessors due to terminal allocations", l)
+		if err := n.srv.revokeSITokenAccessors(revokeSI, true); err != nil {
+			n.srv.logger.Printf("[ERR] nomad.client: batched SI accessor revocation failed: %v", err)
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+
 	// Respond to the future
 	future.Respond(index, mErr.ErrorOrNil())
 }
 
+// reconnectedAllocs returns the allocations of the updates whose status was
+// unknown until their client reconnected.
+func (n *Node) reconnectedAllocs(updates []*structs.Allocation) ([]*structs.Allocation, error) {
+	ws := memdb.NewWatchSet()
+	var reconnected []*structs.Allocation
+	for _, update := range updates {
+		if update.ClientStatus == structs.AllocClientStatusUnknown {
+			continue
+		}
+		alloc, err := n.srv.State().AllocByID(ws, update.ID)
+		if err != nil {
+			return nil, err
+		}
+		if alloc != nil && alloc.ClientStatus == structs.AllocClientStatusUnknown && alloc.Job != nil {
+			reconnected = append(reconnected, alloc)
+		}
+	}
+	return reconnected, nil
+}
+
+// createReconnectEvals creates an evaluation for each job of the allocations
+// whose client reconnected.
+func (n *Node) createReconnectEvals(allocs []*structs.Allocation) error {
+	var evals []*structs.Evaluation
+	jobIDs := make(map[string]struct{})
+	for _, alloc := range allocs {
+		if _, ok := jobIDs[alloc.JobID]; ok {
+			continue
+		}
+		jobIDs[alloc.JobID] = struct{}{}
+
+		evals = append(evals, &structs.Evaluation{
+			ID:          structs.GenerateUUID(),
+			Priority:    alloc.Job.Priority,
+			Type:        alloc.Job.Type,
+			TriggeredBy: structs.EvalTriggerReconnect,
+			JobID:       alloc.JobID,
+			NodeID:      alloc.NodeID,
+			Status:      structs.EvalStatusPending,
+		})
+	}
+
+	update := &structs.EvalUpdateRequest{
+		Evals:        evals,
+		WriteRequest: structs.WriteRequest{Region: n.srv.config.Region},
+	}
+	_, _, err := n.srv.raftApply(structs.EvalUpdateRequestType, update)
+	return err
+}
+
 // List is used to list the available nodes
 func (n *Node) List(args *structs.NodeListRequest,
 	reply *structs.NodeListResponse) error {
@@ -756,33 +915,41 @@ func (n *Node) List(args *structs.NodeListRequest,
 	}
 	defer metrics.MeasureSince([]string{"nomad", "client", "list"}, time.Now())
 
+	if aclObj, err := n.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowNodeRead() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Setup the blocking query
 	opts := blockingOptions{
 		queryOpts: &args.QueryOptions,
 		queryMeta: &reply.QueryMeta,
 		run: func(ws memdb.WatchSet, state *state.StateStore) error {
 			// Capture all the nodes
-			var err error
-			var iter memdb.ResultIterator
-			if prefix := args.QueryOptions.Prefix; prefix != "" {
-				iter, err = state.NodesByIDPrefix(ws, prefix)
-			} else {
-				iter, err = state.Nodes(ws)
-			}
+			iter, err := state.PrefixFrom(ws, "nodes", "id", nil, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
 			if err != nil {
 				return err
 			}
 
 			var nodes []*structs.NodeListStub
-			for {
-				raw := iter.Next()
-				if raw == nil {
-					break
-				}
-				node := raw.(*structs.Node)
-				nodes = append(nodes, node.Stub())
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.Node).ID
+			}, func(raw interface{}) (interface{}, error) {
+				return raw.(*structs.Node).Stub(), nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				nodes = append(nodes, obj.(*structs.NodeListStub))
+				return nil
+			})
+			if err != nil {
+				return err
 			}
 			reply.Nodes = nodes
+			reply.NextToken = nextToken
 
 			// Use the last index that affected the jobs table
 			index, err := state.Index("nodes")
@@ -1108,6 +1275,7 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 			NodeID:      alloc.NodeID,
 			AllocID:     alloc.ID,
 			CreationTTL: w.TTL,
+			Namespace:   tg[task].Namespace,
 		}
 
 		accessors = append(accessors, accessor)
@@ -1152,3 +1320,439 @@ func (n *Node) DeriveVaultToken(args *structs.DeriveVaultTokenRequest,
 	n.srv.setQueryMeta(&reply.QueryMeta)
 	return nil
 }
+
+// SignVaultIdentities is used by the clients to request the workload
+// identities the tasks of an allocation log in to Vault with
+func (n *Node) SignVaultIdentities(args *structs.SignVaultIdentitiesRequest,
+	reply *structs.SignVaultIdentitiesResponse) error {
+
+	// setErr is a helper for setting the recoverable error on the reply and
+	// logging it
+	setErr := func(e error, recoverable bool) {
+		if e == nil {
+			return
+		}
+		reply.Error = structs.NewRecoverableError(e, recoverable).(*structs.RecoverableError)
+		n.srv.logger.Printf("[ERR] nomad.client: SignVaultIdentities failed (recoverable %v): %v", recoverable, e)
+	}
+
+	if done, err := n.srv.forward("Node.SignVaultIdentities", args, args, reply); done {
+		setErr(err, structs.IsRecoverable(err) || err == structs.ErrNoLeader)
+		return nil
+	}
+	defer metrics.MeasureSince([]string{"nomad", "client", "sign_vault_identities"}, time.Now())
+
+	if n.srv.vaultIdentities == nil {
+		setErr(fmt.Errorf("Vault workload identities not enabled on the servers"), false)
+		return nil
+	}
+
+	// Verify the arguments
+	if args.NodeID == "" {
+		setErr(fmt.Errorf("missing node ID"), false)
+		return nil
+	}
+	if args.SecretID == "" {
+		setErr(fmt.Errorf("missing node SecretID"), false)
+		return nil
+	}
+	if args.AllocID == "" {
+		setErr(fmt.Errorf("missing allocation ID"), false)
+		return nil
+	}
+	if len(args.Tasks) == 0 {
+		setErr(fmt.Errorf("no tasks specified"), false)
+		return nil
+	}
+
+	// Verify the following:
+	// * The Node exists and has the correct SecretID
+	// * The Allocation exists on the specified node
+	// * The allocation contains the given tasks and they each have a Vault
+	//   role
+	snap, err := n.srv.fsm.State().Snapshot()
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	ws := memdb.NewWatchSet()
+	node, err := snap.NodeByID(ws, args.NodeID)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if node == nil {
+		setErr(fmt.Errorf("Node %q does not exist", args.NodeID), false)
+		return nil
+	}
+	if node.SecretID != args.SecretID {
+		setErr(fmt.Errorf("SecretID mismatch"), false)
+		return nil
+	}
+
+	alloc, err := snap.AllocByID(ws, args.AllocID)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if alloc == nil {
+		setErr(fmt.Errorf("Allocation %q does not exist", args.AllocID), false)
+		return nil
+	}
+	if alloc.NodeID != args.NodeID {
+		setErr(fmt.Errorf("Allocation %q not running on Node %q", args.AllocID, args.NodeID), false)
+		return nil
+	}
+	if alloc.TerminalStatus() {
+		setErr(fmt.Errorf("Can't request Vault identity for terminal allocation"), false)
+		return nil
+	}
+
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		setErr(fmt.Errorf("Allocation %q has no task group %q", alloc.ID, alloc.TaskGroup), false)
+		return nil
+	}
+
+	var unneeded []string
+	tasks := make([]*structs.Task, 0, len(args.Tasks))
+	for _, name := range args.Tasks {
+		task := tg.LookupTask(name)
+		if task == nil || task.Vault == nil || task.Vault.Role == "" {
+			unneeded = append(unneeded, name)
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	if len(unneeded) != 0 {
+		e := fmt.Errorf("Requested Vault identities for tasks without a Vault role: %s",
+			strings.Join(unneeded, ", "))
+		setErr(e, false)
+		return nil
+	}
+
+	// Sign the identities. Unlike derived tokens there is nothing to track
+	// since the Vault tokens are created by the JWT auth backend.
+	now := time.Now()
+	identities := make(map[string]string, len(tasks))
+	for _, task := range tasks {
+		jwt, err := n.srv.vaultIdentities.Sign(alloc, task, now)
+		if err != nil {
+			setErr(fmt.Errorf("failed to sign identity for task %q on alloc %q: %v", task.Name, alloc.ID, err), false)
+			return nil
+		}
+		identities[task.Name] = jwt
+	}
+
+	reply.Identities = identities
+	n.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
+// SignWorkloadIdentity is used by the clients to request the workload identity
+// the tasks of an allocation call the Nomad API with
+func (n *Node) SignWorkloadIdentity(args *structs.SignWorkloadIdentityRequest,
+	reply *structs.SignWorkloadIdentityResponse) error {
+
+	// setErr is a helper for setting the recoverable error on the reply and
+	// logging it
+	setErr := func(e error, recoverable bool) {
+		if e == nil {
+			return
+		}
+		reply.Error = structs.NewRecoverableError(e, recoverable).(*structs.RecoverableError)
+		n.srv.logger.Printf("[ERR] nomad.client: SignWorkloadIdentity failed (recoverable %v): %v", recoverable, e)
+	}
+
+	if done, err := n.srv.forward("Node.SignWorkloadIdentity", args, args, reply); done {
+		setErr(err, structs.IsRecoverable(err) || err == structs.ErrNoLeader)
+		return nil
+	}
+	defer metrics.MeasureSince([]string{"nomad", "client", "sign_workload_identity"}, time.Now())
+
+	// Tasks don't get an identity if workload identities aren't enabled
+	if n.srv.workloadIdentities == nil {
+		n.srv.setQueryMeta(&reply.QueryMeta)
+		return nil
+	}
+
+	// Verify the arguments
+	if args.NodeID == "" {
+		setErr(fmt.Errorf("missing node ID"), false)
+		return nil
+	}
+	if args.SecretID == "" {
+		setErr(fmt.Errorf("missing node SecretID"), false)
+		return nil
+	}
+	if args.AllocID == "" {
+		setErr(fmt.Errorf("missing allocation ID"), false)
+		return nil
+	}
+
+	// Verify the following:
+	// * The Node exists and has the correct SecretID
+	// * The Allocation exists on the specified node and isn't terminal
+	snap, err := n.srv.fsm.State().Snapshot()
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	ws := memdb.NewWatchSet()
+	node, err := snap.NodeByID(ws, args.NodeID)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if node == nil {
+		setErr(fmt.Errorf("Node %q does not exist", args.NodeID), false)
+		return nil
+	}
+	if node.SecretID != args.SecretID {
+		setErr(fmt.Errorf("SecretID mismatch"), false)
+		return nil
+	}
+
+	alloc, err := snap.AllocByID(ws, args.AllocID)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if alloc == nil {
+		setErr(fmt.Errorf("Allocation %q does not exist", args.AllocID), false)
+		return nil
+	}
+	if alloc.NodeID != args.NodeID {
+		setErr(fmt.Errorf("Allocation %q not running on Node %q", args.AllocID, args.NodeID), false)
+		return nil
+	}
+	if alloc.TerminalStatus() {
+		setErr(fmt.Errorf("Can't request workload identity for terminal allocation"), false)
+		return nil
+	}
+
+	identity, err := n.srv.workloadIdentities.Sign(alloc)
+	if err != nil {
+		setErr(fmt.Errorf("failed to sign workload identity of alloc %q: %v", alloc.ID, err), false)
+		return nil
+	}
+
+	reply.Identity = identity
+	n.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
+// DeriveSIToken is used by the clients to request Consul Service Identity
+// tokens for the Connect sidecar proxy tasks of an allocation
+func (n *Node) DeriveSIToken(args *structs.DeriveSITokenRequest,
+	reply *structs.DeriveSITokenResponse) error {
+
+	// setErr is a helper for setting the recoverable error on the reply and
+	// logging it
+	setErr := func(e error, recoverable bool) {
+		if e == nil {
+			return
+		}
+		reply.Error = structs.NewRecoverableError(e, recoverable).(*structs.RecoverableError)
+		n.srv.logger.Printf("[ERR] nomad.client: DeriveSIToken failed (recoverable %v): %v", recoverable, e)
+	}
+
+	if done, err := n.srv.forward("Node.DeriveSIToken", args, args, reply); done {
+		setErr(err, structs.IsRecoverable(err) || err == structs.ErrNoLeader)
+		return nil
+	}
+	defer metrics.MeasureSince([]string{"nomad", "client", "derive_si_token"}, time.Now())
+
+	// Verify the arguments
+	if args.NodeID == "" {
+		setErr(fmt.Errorf("missing node ID"), false)
+		return nil
+	}
+	if args.SecretID == "" {
+		setErr(fmt.Errorf("missing node SecretID"), false)
+		return nil
+	}
+	if args.AllocID == "" {
+		setErr(fmt.Errorf("missing allocation ID"), false)
+		return nil
+	}
+	if len(args.Tasks) == 0 {
+		setErr(fmt.Errorf("no tasks specified"), false)
+		return nil
+	}
+
+	// Verify the following:
+	// * The Node exists and has the correct SecretID
+	// * The Allocation exists on the specified node
+	// * The allocation contains the given tasks and they are each Connect
+	//   sidecar proxies
+	snap, err := n.srv.fsm.State().Snapshot()
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	ws := memdb.NewWatchSet()
+	node, err := snap.NodeByID(ws, args.NodeID)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if node == nil {
+		setErr(fmt.Errorf("Node %q does not exist", args.NodeID), false)
+		return nil
+	}
+	if node.SecretID != args.SecretID {
+		setErr(fmt.Errorf("SecretID mismatch"), false)
+		return nil
+	}
+
+	alloc, err := snap.AllocByID(ws, args.AllocID)
+	if err != nil {
+		setErr(err, false)
+		return nil
+	}
+	if alloc == nil {
+		setErr(fmt.Errorf("Allocation %q does not exist", args.AllocID), false)
+		return nil
+	}
+	if alloc.NodeID != args.NodeID {
+		setErr(fmt.Errorf("Allocation %q not running on Node %q", args.AllocID, args.NodeID), false)
+		return nil
+	}
+	if alloc.TerminalStatus() {
+		setErr(fmt.Errorf("Can't request Service Identity token for terminal allocation"), false)
+		return nil
+	}
+
+	tg := alloc.Job.LookupTaskGroup(alloc.TaskGroup)
+	if tg == nil {
+		setErr(fmt.Errorf("Allocation %q has no task group %q", alloc.ID, alloc.TaskGroup), false)
+		return nil
+	}
+
+	var targets []siTokenTarget
+	var unneeded []string
+	for _, name := range args.Tasks {
+		task := tg.LookupTask(name)
+		switch {
+		case task == nil:
+			unneeded = append(unneeded, name)
+		case args.Services:
+			targets = append(targets, serviceSITokenTargets(task)...)
+		case task.Kind.IsConnectProxy() || task.Kind.IsConnectGateway():
+			targets = append(targets, siTokenTarget{task: name, service: task.Kind.Value()})
+		default:
+			unneeded = append(unneeded, name)
+		}
+	}
+
+	if len(unneeded) != 0 {
+		e := fmt.Errorf("Requested Service Identity tokens for tasks that are not Connect sidecar proxies or gateways: %s",
+			strings.Join(unneeded, ", "))
+		if args.Services {
+			e = fmt.Errorf("Requested Service Identity tokens for tasks not in the allocation: %s",
+				strings.Join(unneeded, ", "))
+		}
+		setErr(e, false)
+		return nil
+	}
+
+	// At this point the request is valid and we should contact Consul for
+	// tokens. There is one sidecar proxy per Connect service of the group
+	// and few services per task so the tokens are created sequentially.
+	accessors := make([]*structs.SITokenAccessor, 0, len(targets))
+	tokens := make(map[string]string, len(targets))
+	serviceTokens := make(map[string]map[string]string)
+	for _, target := range targets {
+		description := fmt.Sprintf("_nomad_si [%s] [%s]", alloc.ID, target.task)
+		if args.Services {
+			description = fmt.Sprintf("_nomad_si [%s] [%s] [%s]", alloc.ID, target.task, target.service)
+		}
+		accessorID, secretID, err := n.srv.consulACLs.CreateServiceIdentityToken(tg.Consul, target.service, description)
+		if err != nil {
+			n.srv.logger.Printf("[ERR] nomad.node: Service Identity token creation for alloc %q failed: %v", alloc.ID, err)
+
+			// Revoke the tokens created so far
+			if revokeErr := n.srv.revokeSITokenAccessors(accessors, false); revokeErr != nil {
+				n.srv.logger.Printf("[ERR] nomad.node: Service Identity token revocation for alloc %q failed: %v", alloc.ID, revokeErr)
+			}
+
+			wrapped := fmt.Sprintf("failed to create token for task %q on alloc %q: %v", target.task, alloc.ID, err)
+			setErr(structs.WrapRecoverable(wrapped, err), true)
+			return nil
+		}
+
+		accessor := &structs.SITokenAccessor{
+			NodeID:          alloc.NodeID,
+			AllocID:         alloc.ID,
+			TaskName:        target.task,
+			AccessorID:      accessorID,
+			ConsulNamespace: tg.Consul.GetNamespace(),
+			ConsulPartition: tg.Consul.GetPartition(),
+		}
+		if args.Services {
+			accessor.ServiceName = target.service
+			if serviceTokens[target.task] == nil {
+				serviceTokens[target.task] = make(map[string]string)
+			}
+			serviceTokens[target.task][target.service] = secretID
+		} else {
+			tokens[target.task] = secretID
+		}
+		accessors = append(accessors, accessor)
+	}
+
+	// Commit to Raft before returning any of the tokens
+	req := structs.SITokenAccessorsRequest{Accessors: accessors}
+	_, index, err := n.srv.raftApply(structs.ServiceIdentityAccessorRegisterRequestType, &req)
+	if err != nil {
+		n.srv.logger.Printf("[ERR] nomad.client: Register Service Identity accessors for alloc %q failed: %v", alloc.ID, err)
+
+		// Determine if we can recover from the error
+		retry := false
+		switch err {
+		case raft.ErrNotLeader, raft.ErrLeadershipLost, raft.ErrRaftShutdown, raft.ErrEnqueueTimeout:
+			retry = true
+		}
+
+		setErr(err, retry)
+		return nil
+	}
+
+	reply.Index = index
+	if args.Services {
+		reply.ServiceTokens = serviceTokens
+	} else {
+		reply.Tokens = tokens
+	}
+	n.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
+// siTokenTarget is a service a Service Identity token is created for on
+// behalf of a task.
+type siTokenTarget struct {
+	task    string
+	service string
+}
+
+// serviceSITokenTargets returns the Consul services of a task that can have
+// a Service Identity token. Services whose name is interpolated by the
+// client are skipped as their name isn't known yet and are registered with
+// the token of the client.
+func serviceSITokenTargets(task *structs.Task) []siTokenTarget {
+	var targets []siTokenTarget
+	seen := make(map[string]struct{}, len(task.Services))
+	for _, service := range task.Services {
+		if service.Provider == structs.ServiceProviderNomad || strings.Contains(service.Name, "${") {
+			continue
+		}
+		if _, ok := seen[service.Name]; ok {
+			continue
+		}
+		seen[service.Name] = struct{}{}
+		targets = append(targets, siTokenTarget{task: task.Name, service: service.Name})
+	}
+	return targets
+}
@@ -9,6 +9,7 @@ import (
 
 	memdb "github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/command/agent/consul"
 	"github.com/hashicorp/nomad/nomad/mock"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/testutil"
@@ -1329,6 +1330,64 @@ func TestClientEndpoint_UpdateAlloc(t *testing.T) {
 	}
 }
 
+func TestClientEndpoint_UpdateAlloc_Reconnect(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the register request
+	node := mock.Node()
+	reg := &structs.NodeRegisterRequest{
+		Node:         node,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+
+	// Fetch the response
+	var resp structs.GenericResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.Register", reg, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Inject an allocation marked unknown while its client was disconnected
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	alloc.ClientStatus = structs.AllocClientStatusUnknown
+	state := s1.fsm.State()
+	state.UpsertJobSummary(99, mock.JobSummary(alloc.JobID))
+	err := state.UpsertAllocs(100, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Update the alloc after the client reconnected
+	clientAlloc := new(structs.Allocation)
+	*clientAlloc = *alloc
+	clientAlloc.ClientStatus = structs.AllocClientStatusRunning
+	update := &structs.AllocUpdateRequest{
+		Alloc:        []*structs.Allocation{clientAlloc},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp2 structs.NodeAllocsResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateAlloc", update, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Lookup the evaluation of the job
+	ws := memdb.NewWatchSet()
+	evals, err := state.EvalsByJob(ws, alloc.JobID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(evals) != 1 {
+		t.Fatalf("expected one eval; got %d", len(evals))
+	}
+	if evals[0].TriggeredBy != structs.EvalTriggerReconnect || evals[0].NodeID != node.ID {
+		t.Fatalf("Bad: %#v", evals[0])
+	}
+}
+
 func TestClientEndpoint_BatchUpdate(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
@@ -1759,7 +1818,7 @@ func TestClientEndpoint_ListNodes_Blocking(t *testing.T) {
 
 	// Node status update triggers watches
 	time.AfterFunc(100*time.Millisecond, func() {
-		if err := state.UpdateNodeStatus(4, node.ID, structs.NodeStatusDown); err != nil {
+		if err := state.UpdateNodeStatus(4, node.ID, structs.NodeStatusDown, 0); err != nil {
 			t.Fatalf("err: %v", err)
 		}
 	})
@@ -2063,3 +2122,422 @@ func TestClientEndpoint_DeriveVaultToken_VaultError(t *testing.T) {
 		t.Fatalf("bad: %+v", resp.Error)
 	}
 }
+
+func TestClientEndpoint_SignVaultIdentities(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an alloc with a task using a Vault role
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Vault = &structs.Vault{Role: "web-role"}
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.SignVaultIdentitiesRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    []string{task.Name},
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	// Identities can't be signed without a signing key
+	var resp structs.SignVaultIdentitiesResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.SignVaultIdentities", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "not enabled") {
+		t.Fatalf("expected not enabled error: %v", resp.Error)
+	}
+
+	signer, key := testVaultIdentitySigner(t)
+	s1.vaultIdentities = signer
+
+	resp = structs.SignVaultIdentitiesResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.SignVaultIdentities", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("bad: %v", resp.Error)
+	}
+	claims := testVerifyVaultIdentity(t, key, resp.Identities[task.Name])
+	if claims.AllocID != alloc.ID || claims.Task != task.Name || claims.Role != "web-role" {
+		t.Fatalf("bad claims: %#v", claims)
+	}
+
+	// Tasks without a role can't request identities
+	task.Vault = &structs.Vault{Policies: []string{"a"}}
+	if err := state.UpsertAllocs(4, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp = structs.SignVaultIdentitiesResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.SignVaultIdentities", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "without a Vault role") {
+		t.Fatalf("expected role error: %v", resp.Error)
+	}
+}
+
+func TestClientEndpoint_SignWorkloadIdentity(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the node and an alloc running on it
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alloc := mock.Alloc()
+	alloc.NodeID = node.ID
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.SignWorkloadIdentityRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	// No identity is signed without a signing key
+	var resp structs.SignWorkloadIdentityResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.SignWorkloadIdentity", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error != nil || resp.Identity != "" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	signer, err := newWorkloadIdentitySigner(testWorkloadIdentityKey(t), "global")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s1.workloadIdentities = signer
+
+	resp = structs.SignWorkloadIdentityResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.SignWorkloadIdentity", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("bad: %v", resp.Error)
+	}
+	claims, err := signer.Verify(resp.Identity)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if claims.AllocID != alloc.ID || claims.JobID != alloc.JobID {
+		t.Fatalf("bad claims: %#v", claims)
+	}
+
+	// Nodes can only request the identities of their allocations
+	req.SecretID = structs.GenerateUUID()
+	resp = structs.SignWorkloadIdentityResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.SignWorkloadIdentity", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "SecretID mismatch") {
+		t.Fatalf("expected SecretID error: %v", resp.Error)
+	}
+}
+
+func TestClientEndpoint_DeriveSIToken_Bad(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an alloc with a Connect sidecar proxy
+	alloc := mock.ConnectAlloc()
+	alloc.NodeID = node.ID
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Request a token for a task that isn't a sidecar proxy
+	req := &structs.DeriveSITokenRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    []string{"web"},
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.DeriveSITokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveSIToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "not Connect sidecar proxies") {
+		t.Fatalf("Expected not a sidecar proxy error: %v", resp.Error)
+	}
+
+	// Update to be terminal
+	alloc.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(4, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req.Tasks = []string{"connect-proxy-testconnect"}
+	resp = structs.DeriveSITokenResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveSIToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "terminal") {
+		t.Fatalf("Expected terminal allocation error: %v", resp.Error)
+	}
+}
+
+func TestClientEndpoint_DeriveSIToken(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Replace the Consul ACLs client on the server
+	acls := consul.NewMockACLs(s1.logger)
+	s1.consulACLs = acls
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an alloc with a Connect sidecar proxy in a Consul namespace
+	alloc := mock.ConnectAlloc()
+	alloc.NodeID = node.ID
+	alloc.Job.TaskGroups[0].Consul = &structs.Consul{Namespace: "team"}
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	task := "connect-proxy-testconnect"
+	req := &structs.DeriveSITokenRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    []string{task},
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.DeriveSITokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveSIToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("bad: %v", resp.Error)
+	}
+	if resp.Tokens[task] == "" {
+		t.Fatalf("bad: %#v", resp.Tokens)
+	}
+
+	// Check the state store and that the token was created for the service
+	ws := memdb.NewWatchSet()
+	accessors, err := state.SITokenAccessorsByAlloc(ws, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(accessors) != 1 {
+		t.Fatalf("bad: %#v", accessors)
+	}
+	a := accessors[0]
+	if a.TaskName != task || a.NodeID != node.ID || a.ConsulNamespace != "team" {
+		t.Fatalf("bad: %#v", a)
+	}
+	if service := acls.Tokens()[a.AccessorID]; service != "testconnect" {
+		t.Fatalf("bad: %q", service)
+	}
+	if ns := acls.TokenNamespace(a.AccessorID); ns != "team" {
+		t.Fatalf("bad namespace: %q", ns)
+	}
+
+	// Mark the allocation as complete and check the token is revoked
+	update := alloc.Copy()
+	update.ClientStatus = structs.AllocClientStatusComplete
+	updateReq := &structs.AllocUpdateRequest{
+		Alloc:        []*structs.Allocation{update},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var updateResp structs.NodeAllocsResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.UpdateAlloc", updateReq, &updateResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if tokens := acls.Tokens(); len(tokens) != 0 {
+		t.Fatalf("tokens not revoked: %#v", tokens)
+	}
+	accessors, err = state.SITokenAccessorsByAlloc(ws, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(accessors) != 0 {
+		t.Fatalf("accessors not deregistered: %#v", accessors)
+	}
+}
+
+func TestClientEndpoint_DeriveSIToken_Services(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Replace the Consul ACLs client on the server
+	acls := consul.NewMockACLs(s1.logger)
+	s1.consulACLs = acls
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an alloc whose web task has two services named by the client and
+	// a Connect service
+	alloc := mock.ConnectAlloc()
+	alloc.NodeID = node.ID
+	for _, service := range alloc.Job.TaskGroups[0].Tasks[0].Services {
+		if service.Name != "testconnect" {
+			service.Name = "${NOMAD_META_prefix}-" + service.Name
+		}
+	}
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.DeriveSITokenRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    []string{"web"},
+		Services: true,
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.DeriveSITokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveSIToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error != nil {
+		t.Fatalf("bad: %v", resp.Error)
+	}
+	if len(resp.Tokens) != 0 {
+		t.Fatalf("unexpected task tokens: %#v", resp.Tokens)
+	}
+
+	// Only the service whose name isn't interpolated gets a token
+	tokens := resp.ServiceTokens["web"]
+	if len(tokens) != 1 || tokens["testconnect"] == "" {
+		t.Fatalf("bad: %#v", resp.ServiceTokens)
+	}
+
+	ws := memdb.NewWatchSet()
+	accessors, err := state.SITokenAccessorsByAlloc(ws, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(accessors) != 1 {
+		t.Fatalf("bad: %#v", accessors)
+	}
+	a := accessors[0]
+	if a.TaskName != "web" || a.ServiceName != "testconnect" {
+		t.Fatalf("bad: %#v", a)
+	}
+	if service := acls.Tokens()[a.AccessorID]; service != "testconnect" {
+		t.Fatalf("bad: %q", service)
+	}
+
+	// Tasks must be in the allocation
+	req.Tasks = []string{"unknown"}
+	resp = structs.DeriveSITokenResponse{}
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveSIToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "not in the allocation") {
+		t.Fatalf("Expected unknown task error: %v", resp.Error)
+	}
+}
+
+func TestClientEndpoint_DeriveSIToken_ConsulError(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	state := s1.fsm.State()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// Replace the Consul ACLs client on the server and make it fail
+	acls := consul.NewMockACLs(s1.logger)
+	acls.SetError(fmt.Errorf("consul unavailable"))
+	s1.consulACLs = acls
+
+	// Create the node
+	node := mock.Node()
+	if err := state.UpsertNode(2, node); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create an alloc with a Connect sidecar proxy
+	alloc := mock.ConnectAlloc()
+	alloc.NodeID = node.ID
+	if err := state.UpsertAllocs(3, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := &structs.DeriveSITokenRequest{
+		NodeID:   node.ID,
+		SecretID: node.SecretID,
+		AllocID:  alloc.ID,
+		Tasks:    []string{"connect-proxy-testconnect"},
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.DeriveSITokenResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Node.DeriveSIToken", req, &resp); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if resp.Error == nil || !resp.Error.IsRecoverable() {
+		t.Fatalf("bad: %+v", resp.Error)
+	}
+}
@@ -20,6 +20,12 @@ func (op *Operator) RaftGetConfiguration(args *structs.GenericRequest, reply *st
 		return err
 	}
 
+	if aclObj, err := op.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowOperatorRead() {
+		return structs.ErrPermissionDenied
+	}
+
 	// We can't fetch the leader and the configuration atomically with
 	// the current Raft API.
 	future := op.srv.raft.GetConfiguration()
@@ -69,6 +75,12 @@ func (op *Operator) RaftRemovePeerByAddress(args *structs.RaftPeerByAddressReque
 		return err
 	}
 
+	if aclObj, err := op.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowOperatorWrite() {
+		return structs.ErrPermissionDenied
+	}
+
 	// Since this is an operation designed for humans to use, we will return
 	// an error if the supplied address isn't among the peers since it's
 	// likely they screwed up.
@@ -105,3 +117,104 @@ REMOVE:
 	op.srv.logger.Printf("[WARN] nomad.operator: Removed Raft peer %q", args.Address)
 	return nil
 }
+
+// SchedulerGetConfiguration is used to retrieve the scheduler configuration.
+// The default configuration is returned if none was set.
+func (op *Operator) SchedulerGetConfiguration(args *structs.GenericRequest, reply *structs.SchedulerConfigurationResponse) error {
+	if done, err := op.srv.forward("Operator.SchedulerGetConfiguration", args, args, reply); done {
+		return err
+	}
+
+	if aclObj, err := op.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowOperatorRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	index, config, err := op.srv.fsm.State().SchedulerConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		config = structs.DefaultSchedulerConfiguration()
+	}
+
+	reply.SchedulerConfig = config
+	reply.Index = index
+	op.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
+
+// SchedulerSetConfiguration is used to set the scheduler configuration. The
+// schedulers use the new configuration for the evaluations processed after
+// it is applied.
+func (op *Operator) SchedulerSetConfiguration(args *structs.SchedulerSetConfigRequest, reply *structs.SchedulerSetConfigurationResponse) error {
+	if done, err := op.srv.forward("Operator.SchedulerSetConfiguration", args, args, reply); done {
+		return err
+	}
+
+	if aclObj, err := op.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowOperatorWrite() {
+		return structs.ErrPermissionDenied
+	}
+
+	// Validate the configuration
+	if err := args.Config.Validate(); err != nil {
+		return err
+	}
+
+	// Apply the update
+	resp, index, err := op.srv.raftApply(structs.SchedulerConfigRequestType, args)
+	if err != nil {
+		op.srv.logger.Printf("[ERR] nomad.operator: Apply failed: %v", err)
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+
+	// Check if the return type is a bool, which is the case for
+	// check-and-set updates
+	reply.Updated = true
+	if respBool, ok := resp.(bool); ok {
+		reply.Updated = respBool
+	}
+	reply.Index = index
+	return nil
+}
+
+// CapacityReport returns the capacity of the cluster and the resources
+// reserved and used per node class, namespace and job. The usage is tracked
+// by the leader, so the request is always forwarded to it.
+func (op *Operator) CapacityReport(args *structs.GenericRequest, reply *structs.CapacityResponse) error {
+	args.AllowStale = false
+	if done, err := op.srv.forward("Operator.CapacityReport", args, args, reply); done {
+		return err
+	}
+
+	if aclObj, err := op.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.AllowOperatorRead() {
+		return structs.ErrPermissionDenied
+	}
+
+	snap, err := op.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	report, err := op.srv.capacity.Report(snap)
+	if err != nil {
+		return err
+	}
+
+	index, err := snap.LatestIndex()
+	if err != nil {
+		return err
+	}
+
+	*reply = *report
+	reply.Index = index
+	op.srv.setQueryMeta(&reply.QueryMeta)
+	return nil
+}
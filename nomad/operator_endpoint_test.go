@@ -109,3 +109,68 @@ func TestOperator_RaftRemovePeerByAddress(t *testing.T) {
 		}
 	}
 }
+
+func TestOperator_SchedulerConfiguration(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// The default configuration is returned until one is set
+	get := structs.GenericRequest{
+		QueryOptions: structs.QueryOptions{
+			Region: s1.config.Region,
+		},
+	}
+	var reply structs.SchedulerConfigurationResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerGetConfiguration", &get, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(reply.SchedulerConfig, structs.DefaultSchedulerConfiguration()) {
+		t.Fatalf("bad: %#v", reply.SchedulerConfig)
+	}
+
+	// Set the configuration
+	set := structs.SchedulerSetConfigRequest{
+		Config: structs.SchedulerConfiguration{
+			SchedulerAlgorithm: structs.SchedulerAlgorithmSpread,
+		},
+		WriteRequest: structs.WriteRequest{Region: s1.config.Region},
+	}
+	var setReply structs.SchedulerSetConfigurationResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerSetConfiguration", &set, &setReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !setReply.Updated || setReply.Index == 0 {
+		t.Fatalf("bad: %#v", setReply)
+	}
+
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerGetConfiguration", &get, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reply.SchedulerConfig.SchedulerAlgorithm != structs.SchedulerAlgorithmSpread {
+		t.Fatalf("bad: %#v", reply.SchedulerConfig)
+	}
+	if reply.Index != setReply.Index {
+		t.Fatalf("bad index: %d %d", reply.Index, setReply.Index)
+	}
+
+	// A check-and-set with a stale index is not applied
+	set.CAS = true
+	set.Config.ModifyIndex = setReply.Index - 1
+	if err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerSetConfiguration", &set, &setReply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if setReply.Updated {
+		t.Fatalf("bad: %#v", setReply)
+	}
+
+	// An invalid algorithm is rejected
+	set.CAS = false
+	set.Config.SchedulerAlgorithm = "random"
+	err := msgpackrpc.CallWithCodec(codec, "Operator.SchedulerSetConfiguration", &set, &setReply)
+	if err == nil || !strings.Contains(err.Error(), "invalid scheduler algorithm") {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package nomad
+
+import (
+	"fmt"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/helper/filter"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// paginator wraps a memdb iterator to return a single page of a list query,
+// skipping the objects that don't match the query's filter. The token of a
+// page is the ID returned by idFn of its first object, and the iterator must
+// start at the object of the query's token, as returned by
+// StateStore.PrefixFrom.
+type paginator struct {
+	iter    memdb.ResultIterator
+	perPage int32
+	filter  *filter.Expression
+	idFn    func(raw interface{}) string
+	stubFn  func(raw interface{}) (interface{}, error)
+}
+
+// newPaginator returns a paginator over the iterator using the pagination and
+// filter parameters of the query options. The stubFn converts the objects of
+// the iterator to the ones returned by the query, which the filter is
+// evaluated against. If it is nil, the objects are returned as they are.
+func newPaginator(iter memdb.ResultIterator, opts structs.QueryOptions, idFn func(raw interface{}) string,
+	stubFn func(raw interface{}) (interface{}, error)) (*paginator, error) {
+	p := &paginator{
+		iter:    iter,
+		perPage: opts.PerPage,
+		idFn:    idFn,
+		stubFn:  stubFn,
+	}
+
+	if opts.Filter != "" {
+		expr, err := filter.Parse(opts.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse filter: %v", err)
+		}
+		p.filter = expr
+	}
+
+	return p, nil
+}
+
+// Page calls visit for each object of the requested page, as returned by the
+// stubFn, and returns the token of the next page. The returned token is empty
+// if there are no more results.
+func (p *paginator) Page(visit func(obj interface{}) error) (string, error) {
+	var count int32
+	for raw := p.iter.Next(); raw != nil; raw = p.iter.Next() {
+		obj := raw
+		if p.stubFn != nil {
+			var err error
+			if obj, err = p.stubFn(raw); err != nil {
+				return "", err
+			}
+		}
+
+		// Skip the objects not matching the filter
+		if p.filter != nil {
+			match, err := p.filter.Match(obj)
+			if err != nil {
+				return "", fmt.Errorf("failed to evaluate filter: %v", err)
+			}
+			if !match {
+				continue
+			}
+		}
+
+		// The page is full so the current object starts the next page
+		if p.perPage > 0 && count == p.perPage {
+			return p.idFn(raw), nil
+		}
+
+		if err := visit(obj); err != nil {
+			return "", err
+		}
+		count++
+	}
+
+	return "", nil
+}
+
+// filterIterator wraps a memdb iterator to only return the objects for which
+// keep returns true, which list queries use to hide the objects the ACL of
+// the request doesn't allow reading.
+type filterIterator struct {
+	iter memdb.ResultIterator
+	keep func(raw interface{}) bool
+}
+
+// newFilterIterator returns an iterator over the objects of iter that keep
+// returns true for
+func newFilterIterator(iter memdb.ResultIterator, keep func(raw interface{}) bool) memdb.ResultIterator {
+	return &filterIterator{iter: iter, keep: keep}
+}
+
+func (f *filterIterator) WatchCh() <-chan struct{} {
+	return f.iter.WatchCh()
+}
+
+func (f *filterIterator) Next() interface{} {
+	for raw := f.iter.Next(); raw != nil; raw = f.iter.Next() {
+		if f.keep(raw) {
+			return raw
+		}
+	}
+	return nil
+}
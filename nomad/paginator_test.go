@@ -0,0 +1,94 @@
+package nomad
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// paginateEvals returns the IDs of the evaluations of the page of the query
+// and the token of the next page.
+func paginateEvals(t *testing.T, s *state.StateStore, opts structs.QueryOptions) ([]string, string) {
+	iter, err := s.PrefixFrom(nil, "evals", "id", nil, opts.Prefix, opts.NextToken)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	paginator, err := newPaginator(iter, opts, func(raw interface{}) string {
+		return raw.(*structs.Evaluation).ID
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var ids []string
+	token, err := paginator.Page(func(obj interface{}) error {
+		ids = append(ids, obj.(*structs.Evaluation).ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return ids, token
+}
+
+func TestPaginator(t *testing.T) {
+	t.Parallel()
+	s, err := state.NewStateStore(os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// An empty table has a single empty page
+	ids, token := paginateEvals(t, s, structs.QueryOptions{PerPage: 2})
+	if len(ids) != 0 || token != "" {
+		t.Fatalf("bad page %v with token %q", ids, token)
+	}
+
+	var evals []*structs.Evaluation
+	var expected []string
+	for i := 0; i < 5; i++ {
+		eval := mock.Eval()
+		evals = append(evals, eval)
+		expected = append(expected, eval.ID)
+	}
+	if err := s.UpsertEvals(1000, evals); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sort.Strings(expected)
+
+	// Each page starts at the token of the previous one and the last page
+	// has no token
+	var all []string
+	opts := structs.QueryOptions{PerPage: 2}
+	for pages := 1; ; pages++ {
+		ids, token := paginateEvals(t, s, opts)
+		all = append(all, ids...)
+		if token == "" {
+			if pages != 3 || len(ids) != 1 {
+				t.Fatalf("bad last page %v after %d pages", ids, pages)
+			}
+			break
+		}
+		if len(ids) != 2 {
+			t.Fatalf("bad page %v", ids)
+		}
+		opts.NextToken = token
+	}
+	if !reflect.DeepEqual(all, expected) {
+		t.Fatalf("got %v; want %v", all, expected)
+	}
+
+	// A token missing from the table starts at the next ID
+	if err := s.DeleteEval(1001, []string{expected[2]}, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ids, token = paginateEvals(t, s, structs.QueryOptions{PerPage: 2, NextToken: expected[2]})
+	if !reflect.DeepEqual(ids, expected[3:]) || token != "" {
+		t.Fatalf("bad page %v with token %q", ids, token)
+	}
+}
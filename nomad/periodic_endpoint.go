@@ -6,6 +6,7 @@ import (
 
 	"github.com/armon/go-metrics"
 	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
 
@@ -40,6 +41,9 @@ func (p *Periodic) Force(args *structs.PeriodicForceRequest, reply *structs.Peri
 	if job == nil {
 		return fmt.Errorf("job not found")
 	}
+	if err := p.srv.checkNamespaceOperation(args.AuthToken, namespaceOfJob(job), acl.NamespaceCapabilitySubmitJob); err != nil {
+		return err
+	}
 
 	if !job.IsPeriodic() {
 		return fmt.Errorf("can't force launch non-periodic job")
@@ -53,6 +57,7 @@ func (p *Periodic) Force(args *structs.PeriodicForceRequest, reply *structs.Peri
 
 	reply.EvalID = eval.ID
 	reply.EvalCreateIndex = eval.CreateIndex
+	reply.JobID = eval.JobID
 	reply.Index = eval.CreateIndex
 	return nil
 }
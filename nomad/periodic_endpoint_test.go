@@ -1,6 +1,7 @@
 package nomad
 
 import (
+	"strings"
 	"testing"
 
 	memdb "github.com/hashicorp/go-memdb"
@@ -55,6 +56,9 @@ func TestPeriodicEndpoint_Force(t *testing.T) {
 	if eval.CreateIndex != resp.EvalCreateIndex {
 		t.Fatalf("index mis-match")
 	}
+	if eval.JobID != resp.JobID || !strings.HasPrefix(resp.JobID, job.ID+structs.PeriodicLaunchSuffix) {
+		t.Fatalf("bad launched job ID: %q", resp.JobID)
+	}
 }
 
 func TestPeriodicEndpoint_Force_NonPeriodic(t *testing.T) {
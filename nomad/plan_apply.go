@@ -9,6 +9,7 @@ import (
 	"github.com/armon/go-metrics"
 	memdb "github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/helper/tracing"
 	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/raft"
@@ -81,7 +82,10 @@ func (s *Server) planApply() {
 		}
 
 		// Evaluate the plan
+		evalSpan := s.config.Tracer.Start("plan.evaluate", pending.plan.TraceParent)
 		result, err := evaluatePlan(pool, snap, pending.plan, s.logger)
+		evalSpan.SetError(err)
+		evalSpan.End()
 		if err != nil {
 			s.logger.Printf("[ERR] nomad: failed to evaluate plan: %v", err)
 			pending.respond(nil, err)
@@ -106,17 +110,21 @@ func (s *Server) planApply() {
 			}
 		}
 
-		// Dispatch the Raft transaction for the plan
+		// Dispatch the Raft transaction for the plan. The span ends once the
+		// plan is applied.
+		applySpan := s.config.Tracer.Start("plan.apply", pending.plan.TraceParent)
 		future, err := s.applyPlan(pending.plan, result, snap)
 		if err != nil {
 			s.logger.Printf("[ERR] nomad: failed to submit plan: %v", err)
+			applySpan.SetError(err)
+			applySpan.End()
 			pending.respond(nil, err)
 			continue
 		}
 
 		// Respond to the plan in async
 		waitCh = make(chan struct{})
-		go s.asyncPlanWait(waitCh, future, result, pending)
+		go s.asyncPlanWait(waitCh, future, result, pending, applySpan)
 	}
 }
 
@@ -170,12 +178,14 @@ func (s *Server) applyPlan(plan *structs.Plan, result *structs.PlanResult, snap
 
 // asyncPlanWait is used to apply and respond to a plan async
 func (s *Server) asyncPlanWait(waitCh chan struct{}, future raft.ApplyFuture,
-	result *structs.PlanResult, pending *pendingPlan) {
+	result *structs.PlanResult, pending *pendingPlan, span *tracing.Span) {
 	defer metrics.MeasureSince([]string{"nomad", "plan", "apply"}, time.Now())
 	defer close(waitCh)
+	defer span.End()
 
 	// Wait for the plan to apply
 	if err := future.Error(); err != nil {
+		span.SetError(err)
 		s.logger.Printf("[ERR] nomad: failed to apply plan: %v", err)
 		pending.respond(nil, err)
 		return
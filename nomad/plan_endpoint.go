@@ -19,10 +19,17 @@ func (p *Plan) Submit(args *structs.PlanRequest, reply *structs.PlanResponse) er
 	}
 	defer metrics.MeasureSince([]string{"nomad", "plan", "submit"}, time.Now())
 
+	// Trace the time spent queued and applied. The plan applier continues
+	// the trace from the plan.
+	plan := args.Plan
+	span := p.srv.startRPCSpan("Plan.Submit", args.TraceParent)
+	span.SetAttribute("eval_id", plan.EvalID)
+	defer span.End()
+	plan.TraceParent = span.TraceParent()
+
 	// Pause the Nack timer for the eval as it is making progress as long as it
 	// is in the plan queue. We resume immediately after we get a result to
 	// handle the case that the receiving worker dies.
-	plan := args.Plan
 	id := plan.EvalID
 	token := plan.EvalToken
 	if err := p.srv.evalBroker.PauseNackTimeout(id, token); err != nil {
@@ -39,6 +46,7 @@ func (p *Plan) Submit(args *structs.PlanRequest, reply *structs.PlanResponse) er
 	// Wait for the results
 	result, err := future.Wait()
 	if err != nil {
+		span.SetError(err)
 		return err
 	}
 
@@ -139,6 +139,7 @@ SCAN:
 		pending := raw.(*pendingPlan)
 		q.stats.Depth -= 1
 		q.l.Unlock()
+		metrics.MeasureSince([]string{"nomad", "plan", "wait_time"}, pending.enqueueTime)
 		return pending, nil
 	}
 	q.l.Unlock()
@@ -12,7 +12,9 @@ import (
 
 	"github.com/hashicorp/net-rpc-msgpackrpc"
 	"github.com/hashicorp/nomad/helper/tlsutil"
+	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/yamux"
+	"github.com/ugorji/go/codec"
 )
 
 // streamClient is used to wrap a stream with an RPC client
@@ -240,6 +242,44 @@ func (p *ConnPool) acquire(region string, addr net.Addr, version int) (*Conn, er
 
 // getNewConn is used to return a new connection
 func (p *ConnPool) getNewConn(region string, addr net.Addr, version int) (*Conn, error) {
+	conn, err := p.dial(region, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the multiplex byte to set the mode
+	if _, err := conn.Write([]byte{byte(rpcMultiplex)}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Setup the logger
+	conf := yamux.DefaultConfig()
+	conf.LogOutput = p.logOutput
+
+	// Create a multiplexed session
+	session, err := yamux.Client(conn, conf)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Wrap the connection
+	c := &Conn{
+		refCount: 1,
+		addr:     addr,
+		session:  session,
+		clients:  list.New(),
+		lastUsed: time.Now(),
+		version:  version,
+		pool:     p,
+	}
+	return c, nil
+}
+
+// dial opens a connection to the server at the address, switching it into
+// TLS mode if TLS is enabled.
+func (p *ConnPool) dial(region string, addr net.Addr) (net.Conn, error) {
 	// Try to dial the conn
 	conn, err := net.DialTimeout("tcp", addr.String(), 10*time.Second)
 	if err != nil {
@@ -268,35 +308,48 @@ func (p *ConnPool) getNewConn(region string, addr net.Addr, version int) (*Conn,
 		}
 		conn = tlsConn
 	}
+	return conn, nil
+}
 
-	// Write the multiplex byte to set the mode
-	if _, err := conn.Write([]byte{byte(rpcMultiplex)}); err != nil {
+// DialNodeConn opens the node connection of a node to the server at the
+// address. The returned session accepts a stream for each RPC the server
+// makes to the node.
+func (p *ConnPool) DialNodeConn(region string, addr net.Addr, req *structs.NodeConnRequest) (*yamux.Session, error) {
+	conn, err := p.dial(region, addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{byte(rpcNodeConn)}); err != nil {
 		conn.Close()
 		return nil, err
 	}
+	return OpenNodeConn(conn, req, p.logOutput)
+}
 
-	// Setup the logger
-	conf := yamux.DefaultConfig()
-	conf.LogOutput = p.logOutput
-
-	// Create a multiplexed session
-	session, err := yamux.Client(conn, conf)
-	if err != nil {
+// OpenNodeConn identifies the node on a connection in node connection mode
+// and returns the session the server makes RPCs to the node over. The
+// connection is closed if the server rejects it.
+func OpenNodeConn(conn net.Conn, req *structs.NodeConnRequest, logOutput io.Writer) (*yamux.Session, error) {
+	conn.SetDeadline(time.Now().Add(nodeConnHandshakeTimeout))
+	if err := codec.NewEncoder(conn, structs.MsgpackHandle).Encode(req); err != nil {
 		conn.Close()
 		return nil, err
 	}
+	ack := make([]byte, 1)
+	if _, err := io.ReadFull(conn, ack); err != nil || ack[0] != nodeConnAccepted {
+		conn.Close()
+		return nil, fmt.Errorf("node connection rejected by server %s", conn.RemoteAddr())
+	}
+	conn.SetDeadline(time.Time{})
 
-	// Wrap the connection
-	c := &Conn{
-		refCount: 1,
-		addr:     addr,
-		session:  session,
-		clients:  list.New(),
-		lastUsed: time.Now(),
-		version:  version,
-		pool:     p,
+	conf := yamux.DefaultConfig()
+	conf.LogOutput = logOutput
+	session, err := yamux.Server(conn, conf)
+	if err != nil {
+		conn.Close()
+		return nil, err
 	}
-	return c, nil
+	return session, nil
 }
 
 // clearConn is used to clear any cached connection, potentially in response to an erro
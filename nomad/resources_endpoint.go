@@ -3,6 +3,7 @@ package nomad
 import (
 	"fmt"
 	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/acl"
 	"github.com/hashicorp/nomad/nomad/state"
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -73,10 +74,34 @@ func getResourceIter(context, prefix string, ws memdb.WatchSet, state *state.Sta
 	}
 }
 
+// allowResource returns whether the ACL allows reading the resource. Nodes
+// require node read permissions and the other resources read permissions on
+// the namespace of their job.
+func allowResource(aclObj *acl.ACL, state *state.StateStore, raw interface{}) bool {
+	switch t := raw.(type) {
+	case *structs.Job:
+		return aclObj.AllowNamespaceRead(namespaceOfJob(t))
+	case *structs.Evaluation:
+		ns, err := jobNamespace(nil, state, t.JobID)
+		return err == nil && aclObj.AllowNamespaceRead(ns)
+	case *structs.Allocation:
+		return aclObj.AllowNamespaceRead(allocNamespace(t))
+	case *structs.Node:
+		return aclObj.AllowNodeRead()
+	default:
+		return false
+	}
+}
+
 // List is used to list the resouces registered in the system that matches the
 // given prefix. Resources are jobs, evaluations, allocations, and/or nodes.
 func (r *Resources) List(args *structs.ResourceListRequest,
 	reply *structs.ResourceListResponse) error {
+	aclObj, err := r.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
 	reply.Matches = make(map[string][]string)
 	reply.Truncations = make(map[string]bool)
 
@@ -98,7 +123,9 @@ func (r *Resources) List(args *structs.ResourceListRequest,
 				if err != nil {
 					return err
 				}
-				iters[e] = iter
+				iters[e] = newFilterIterator(iter, func(raw interface{}) bool {
+					return allowResource(aclObj, state, raw)
+				})
 			}
 
 			// Return matches for the given prefix
@@ -27,6 +27,7 @@ const (
 	rpcRaft              = 0x02
 	rpcMultiplex         = 0x03
 	rpcTLS               = 0x04
+	rpcNodeConn          = 0x05
 )
 
 const (
@@ -107,14 +108,17 @@ func (s *Server) handleConn(conn net.Conn, isTLS bool) {
 	// Switch on the byte
 	switch RPCType(buf[0]) {
 	case rpcNomad:
-		s.handleNomadConn(conn)
+		s.handleNomadConn(conn, s.isTrustedConn(conn))
 
 	case rpcRaft:
 		metrics.IncrCounter([]string{"nomad", "rpc", "raft_handoff"}, 1)
 		s.raftLayer.Handoff(conn)
 
 	case rpcMultiplex:
-		s.handleMultiplex(conn)
+		s.handleMultiplex(conn, s.isTrustedConn(conn))
+
+	case rpcNodeConn:
+		s.ServeNodeConn(conn)
 
 	case rpcTLS:
 		if s.rpcTLS == nil {
@@ -134,7 +138,7 @@ func (s *Server) handleConn(conn net.Conn, isTLS bool) {
 
 // handleMultiplex is used to multiplex a single incoming connection
 // using the Yamux multiplexer
-func (s *Server) handleMultiplex(conn net.Conn) {
+func (s *Server) handleMultiplex(conn net.Conn, trusted bool) {
 	defer conn.Close()
 	conf := yamux.DefaultConfig()
 	conf.LogOutput = s.config.LogOutput
@@ -147,14 +151,19 @@ func (s *Server) handleMultiplex(conn net.Conn) {
 			}
 			return
 		}
-		go s.handleNomadConn(sub)
+		go s.handleNomadConn(sub, trusted)
 	}
 }
 
-// handleNomadConn is used to service a single Nomad RPC connection
-func (s *Server) handleNomadConn(conn net.Conn) {
+// handleNomadConn is used to service a single Nomad RPC connection. The
+// sources of the tokens of the requests are checked unless the connection is
+// trusted.
+func (s *Server) handleNomadConn(conn net.Conn, trusted bool) {
 	defer conn.Close()
-	rpcCodec := NewServerCodec(conn)
+	rpcCodec := s.limitCodec(conn, NewServerCodec(conn))
+	if !trusted {
+		rpcCodec = s.sourceCodec(conn, rpcCodec)
+	}
 	for {
 		select {
 		case <-s.shutdownCh:
@@ -163,6 +172,11 @@ func (s *Server) handleNomadConn(conn net.Conn) {
 		}
 
 		if err := s.rpcServer.ServeRequest(rpcCodec); err != nil {
+			// The caller of a rejected request has been answered and may
+			// keep using the connection
+			if err == structs.ErrRateLimited || err == structs.ErrPermissionDenied {
+				continue
+			}
 			if err != io.EOF && !strings.Contains(err.Error(), "closed") {
 				s.logger.Printf("[ERR] nomad.rpc: RPC error: %v (%v)", err, conn)
 				metrics.IncrCounter([]string{"nomad", "rpc", "request_error"}, 1)
@@ -173,6 +187,164 @@ func (s *Server) handleNomadConn(conn net.Conn) {
 	}
 }
 
+// limitCodec wraps the codec of the connection to reject its requests over
+// the rate limits if an RPC rate limit is configured. Connections from other
+// servers are not limited.
+func (s *Server) limitCodec(conn net.Conn, codec rpc.ServerCodec) rpc.ServerCodec {
+	if s.rpcLimiter == nil && s.rpcTokenLimiter == nil {
+		return codec
+	}
+
+	ip := remoteIP(conn.RemoteAddr())
+	if s.isServerIP(ip) {
+		return codec
+	}
+	return &limitedCodec{
+		ServerCodec: codec,
+		srv:         s,
+		key:         ip,
+	}
+}
+
+// isTrustedConn returns whether the connection comes from another agent of the
+// cluster, which checks the sources of the tokens of the requests it forwards
+// against the addresses of its own callers. The agents authenticated by a
+// certificate verified with mutual TLS are trusted. Without TLS, agents can't
+// be authenticated and the connections from the addresses of the known
+// servers are trusted, so that the requests forwarded between servers aren't
+// checked against the address of the forwarding server.
+func (s *Server) isTrustedConn(conn net.Conn) bool {
+	if tlsConn, ok := conn.(*tls.Conn); ok && len(tlsConn.ConnectionState().VerifiedChains) != 0 {
+		return true
+	}
+	if s.config.TLSConfig.EnableRPC {
+		return false
+	}
+	return s.isServerIP(remoteIP(conn.RemoteAddr()))
+}
+
+// isServerIP returns whether the IP address belongs to a known server.
+func (s *Server) isServerIP(ip string) bool {
+	s.peerLock.RLock()
+	defer s.peerLock.RUnlock()
+	for _, servers := range s.peers {
+		for _, server := range servers {
+			if remoteIP(server.Addr) == ip {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// remoteIP returns the IP address of a network address, or the address
+// itself if it has no IP.
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// limitedCodec is a server codec that rejects the requests exceeding the
+// rate limits. The limits of the connection are checked once the header of a
+// request is read, and the limit of its ACL token once its body is. The caller
+// of a rejected request receives structs.ErrRateLimited.
+type limitedCodec struct {
+	rpc.ServerCodec
+	srv *Server
+	key string
+
+	// exempt is whether the request whose header was read last is never
+	// limited, and limited whether it is over the limits of the connection
+	exempt  bool
+	limited bool
+}
+
+func (c *limitedCodec) ReadRequestHeader(r *rpc.Request) error {
+	c.exempt, c.limited = false, false
+	if err := c.ServerCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+
+	// Heartbeats are never limited, as rejecting them would mark the node
+	// down and reschedule its allocations
+	if r.ServiceMethod == "Node.UpdateStatus" {
+		c.exempt = true
+		return nil
+	}
+
+	if !c.srv.rpcLimiter.Allow(c.key) {
+		metrics.IncrCounter([]string{"nomad", "rpc", "rate_limited"}, 1)
+		c.limited = true
+	}
+	return nil
+}
+
+func (c *limitedCodec) ReadRequestBody(body interface{}) error {
+	// The body of a rejected request is discarded
+	if c.limited {
+		if err := c.ServerCodec.ReadRequestBody(nil); err != nil {
+			return err
+		}
+		return structs.ErrRateLimited
+	}
+
+	if err := c.ServerCodec.ReadRequestBody(body); err != nil {
+		return err
+	}
+	if c.exempt || c.srv.rpcTokenLimiter == nil {
+		return nil
+	}
+	if req, ok := body.(structs.AuthenticatedRequest); ok && !c.srv.allowToken(req.RequestAuthToken()) {
+		return structs.ErrRateLimited
+	}
+	return nil
+}
+
+// sourceCodec wraps the codec of an untrusted connection to reject the
+// requests made with tokens that may not be used from its address if ACLs are
+// enabled. The address checked is the one of the direct caller: the requests
+// an untrusted agent forwards are checked against the address of the agent,
+// not of the agent's caller.
+func (s *Server) sourceCodec(conn net.Conn, codec rpc.ServerCodec) rpc.ServerCodec {
+	if !s.config.ACLEnabled {
+		return codec
+	}
+	return &sourceCodec{
+		ServerCodec: codec,
+		srv:         s,
+		ip:          remoteIP(conn.RemoteAddr()),
+	}
+}
+
+// sourceCodec is a server codec that checks the token of each request against
+// the IP address of the connection. The caller of a rejected request receives
+// structs.ErrPermissionDenied.
+type sourceCodec struct {
+	rpc.ServerCodec
+	srv *Server
+	ip  string
+}
+
+func (c *sourceCodec) ReadRequestBody(body interface{}) error {
+	if err := c.ServerCodec.ReadRequestBody(body); err != nil {
+		return err
+	}
+	if req, ok := body.(structs.AuthenticatedRequest); ok {
+		return c.srv.checkTokenSource(req.RequestAuthToken(), c.ip)
+	}
+	return nil
+}
+
 // forward is used to forward to a remote region or to forward to the local leader
 // Returns a bool of if forwarding was performed, as well as any error
 func (s *Server) forward(method string, info structs.RPCInfo, args interface{}, reply interface{}) (bool, error) {
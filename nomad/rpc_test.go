@@ -6,6 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/nomad/helper/ratelimit"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
 	"github.com/hashicorp/nomad/testutil"
 )
 
@@ -84,3 +88,167 @@ func TestRPC_forwardRegion(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+func TestRPC_RateLimit(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, func(c *Config) {
+		c.RPCRateLimit = ratelimit.Config{Rate: 0.001, Burst: 1}
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	// A pipe has no IP address so it isn't mistaken for another server
+	p1, p2 := net.Pipe()
+	go s1.handleNomadConn(p1, s1.isTrustedConn(p1))
+	client := rpc.NewClientWithCodec(NewClientCodec(p2))
+	defer client.Close()
+
+	if err := client.Call("Status.Ping", struct{}{}, &struct{}{}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Requests over the limit are rejected without closing the connection
+	for i := 0; i < 2; i++ {
+		err := client.Call("Status.Ping", struct{}{}, &struct{}{})
+		if err == nil || err.Error() != structs.ErrRateLimited.Error() {
+			t.Fatalf("expected rate limit error; got %v", err)
+		}
+	}
+
+	// Heartbeats are not limited
+	req := &structs.NodeUpdateStatusRequest{
+		NodeID:       "12345678-abcd-efab-cdef-123456789abc",
+		Status:       structs.NodeStatusReady,
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.NodeUpdateResponse
+	err := client.Call("Node.UpdateStatus", req, &resp)
+	if err == nil || err.Error() == structs.ErrRateLimited.Error() {
+		t.Fatalf("expected the heartbeat of an unknown node to be served; got %v", err)
+	}
+}
+
+func TestRPC_RateLimitPerToken(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, func(c *Config) {
+		c.RPCTokenRateLimit = ratelimit.Config{RatePerKey: 0.001}
+	})
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	token := mock.ACLManagementToken()
+	if err := s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The limit of a token applies across connections
+	list := func(secretID string) error {
+		p1, p2 := net.Pipe()
+		go s1.handleNomadConn(p1, false)
+		client := rpc.NewClientWithCodec(NewClientCodec(p2))
+		defer client.Close()
+		req := &structs.JobListRequest{
+			QueryOptions: structs.QueryOptions{Region: "global", AuthToken: secretID},
+		}
+		var resp structs.JobListResponse
+		return client.Call("Job.List", req, &resp)
+	}
+	if err := list(token.SecretID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := list(token.SecretID); err == nil || err.Error() != structs.ErrRateLimited.Error() {
+		t.Fatalf("expected rate limit error; got %v", err)
+	}
+
+	// Other tokens are not limited by it
+	if err := list(root.SecretID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+// addrConn is a connection with the given remote address
+type addrConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func TestRPC_TokenSource(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, nil)
+	defer s1.Shutdown()
+	testutil.WaitForLeader(t, s1.RPC)
+
+	token := mock.ACLManagementToken()
+	token.AllowedCIDRs = []string{"10.0.0.0/8"}
+	if err := s1.fsm.State().UpsertACLTokens(1000, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cases := []struct {
+		ip      string
+		secret  string
+		allowed bool
+	}{
+		{"10.1.2.3", token.SecretID, true},
+
+		// The requests forwarded by an untrusted agent are checked against
+		// the address of the agent, whatever the address of its caller
+		{"192.168.1.1", token.SecretID, false},
+		{"192.168.1.1", root.SecretID, true},
+	}
+	for _, c := range cases {
+		p1, p2 := net.Pipe()
+		conn := &addrConn{Conn: p1, addr: &net.TCPAddr{IP: net.ParseIP(c.ip), Port: 4647}}
+		if s1.isTrustedConn(conn) {
+			t.Fatalf("%s: connection unexpectedly trusted", c.ip)
+		}
+		go s1.handleNomadConn(conn, false)
+		client := rpc.NewClientWithCodec(NewClientCodec(p2))
+
+		req := &structs.JobListRequest{
+			QueryOptions: structs.QueryOptions{Region: "global", AuthToken: c.secret},
+		}
+		var resp structs.JobListResponse
+		err := client.Call("Job.List", req, &resp)
+		if c.allowed && err != nil {
+			t.Fatalf("%s: err: %v", c.ip, err)
+		}
+		if !c.allowed {
+			if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+				t.Fatalf("%s: expected permission denied: %v", c.ip, err)
+			}
+
+			// The connection remains usable
+			req.AuthToken = root.SecretID
+			if err := client.Call("Job.List", req, &resp); err != nil {
+				t.Fatalf("%s: err: %v", c.ip, err)
+			}
+		}
+		client.Close()
+	}
+
+	// The requests forwarded by other servers are trusted
+	conn := &addrConn{addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 4647}}
+	if !s1.isTrustedConn(conn) {
+		t.Fatalf("connection from a server not trusted")
+	}
+
+	// With TLS, only the agents presenting a verified certificate are
+	// trusted
+	s2 := testServer(t, func(c *Config) {
+		c.TLSConfig = &config.TLSConfig{
+			EnableRPC: true,
+			CAFile:         "../helper/tlsutil/testdata/ca.pem",
+			CertFile:       "../helper/tlsutil/testdata/nomad-foo.pem",
+			KeyFile:        "../helper/tlsutil/testdata/nomad-foo-key.pem",
+		}
+	})
+	defer s2.Shutdown()
+	if s2.isTrustedConn(conn) {
+		t.Fatalf("connection from a server without a certificate trusted")
+	}
+}
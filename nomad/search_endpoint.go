@@ -0,0 +1,320 @@
+package nomad
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// fuzzyIndexRebuildInterval is the minimum time between two builds of the
+// fuzzy search index of a context for blocking queries that are still waiting
+// for changes. The allocations table changes on almost every write, so without
+// it the index of allocations would be rebuilt from the whole table for
+// blocking queries whose results it can't change.
+const fuzzyIndexRebuildInterval = time.Second
+
+// fuzzyContexts are the available contexts which are searched to find
+// matches for a given fuzzy search text. Each context maps to the state store
+// table it is indexed from.
+var (
+	fuzzyContexts = map[string]string{
+		"allocs":      "allocs",
+		"deployments": "deployment",
+		"jobs":        "jobs",
+		"nodes":       "nodes",
+	}
+)
+
+// fuzzyEntry is a single searchable object in the fuzzy search index.
+type fuzzyEntry struct {
+	ID   string
+	Name string
+
+	// Namespace is the namespace of the job of the entry, which is empty for
+	// nodes
+	Namespace string
+
+	// key is the lower cased text the entry is matched against
+	key string
+}
+
+// fuzzyContextIndex is the fuzzy search index of a single context. It is
+// rebuilt from the whole backing table when the table has been modified since
+// it was last built. Entries are found by the trigrams of their keys, so a
+// search only looks at the entries sharing the rarest trigram of its text.
+type fuzzyContextIndex struct {
+	index   uint64
+	entries []fuzzyEntry
+
+	// rebuildCh is closed once the index may be rebuilt
+	rebuildCh chan struct{}
+
+	// trigrams maps each trigram of the keys to the ascending positions of
+	// the entries whose key contains it
+	trigrams map[string][]int
+}
+
+// newFuzzyContextIndex returns an empty index of the table at the index.
+func newFuzzyContextIndex(index uint64) *fuzzyContextIndex {
+	return &fuzzyContextIndex{
+		index:     index,
+		rebuildCh: make(chan struct{}),
+		trigrams:  make(map[string][]int),
+	}
+}
+
+// rebuildable returns whether the rebuild interval of the index has elapsed.
+func (idx *fuzzyContextIndex) rebuildable() bool {
+	select {
+	case <-idx.rebuildCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// add adds the entry to the index.
+func (idx *fuzzyContextIndex) add(e fuzzyEntry) {
+	pos := len(idx.entries)
+	idx.entries = append(idx.entries, e)
+	for i := 0; i+3 <= len(e.key); i++ {
+		trigram := e.key[i : i+3]
+		positions := idx.trigrams[trigram]
+		if n := len(positions); n > 0 && positions[n-1] == pos {
+			continue
+		}
+		idx.trigrams[trigram] = append(positions, pos)
+	}
+}
+
+// candidates returns the positions of the entries whose key may contain the
+// lower cased text. Texts shorter than a trigram may match any entry.
+func (idx *fuzzyContextIndex) candidates(text string) []int {
+	if len(text) < 3 {
+		all := make([]int, len(idx.entries))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+
+	var rarest []int
+	for i := 0; i+3 <= len(text); i++ {
+		positions, ok := idx.trigrams[text[i:i+3]]
+		if !ok {
+			return nil
+		}
+		if i == 0 || len(positions) < len(rarest) {
+			rarest = positions
+		}
+	}
+	return rarest
+}
+
+// Search endpoint is used to look up objects by fuzzy matching their names
+// and IDs.
+type Search struct {
+	srv *Server
+
+	l       sync.Mutex
+	indexes map[string]*fuzzyContextIndex
+}
+
+// fuzzyIter returns a memdb iterator over the table backing the context.
+// Creating the iterator also registers the table with the watch set.
+func fuzzyIter(context string, ws memdb.WatchSet, state *state.StateStore) (memdb.ResultIterator, error) {
+	switch context {
+	case "allocs":
+		return state.Allocs(ws)
+	case "deployments":
+		return state.Deployments(ws)
+	case "jobs":
+		return state.Jobs(ws)
+	case "nodes":
+		return state.Nodes(ws)
+	default:
+		return nil, fmt.Errorf("context must be one of %v; got %q", fuzzyContextNames(), context)
+	}
+}
+
+// fuzzyContextNames returns the sorted names of the fuzzy search contexts.
+func fuzzyContextNames() []string {
+	names := make([]string, 0, len(fuzzyContexts))
+	for name := range fuzzyContexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newFuzzyEntry returns the fuzzy search index entry for the object.
+func newFuzzyEntry(state *state.StateStore, raw interface{}) (fuzzyEntry, bool, error) {
+	var e fuzzyEntry
+	switch t := raw.(type) {
+	case *structs.Allocation:
+		e.ID, e.Name, e.Namespace = t.ID, t.Name, allocNamespace(t)
+	case *structs.Deployment:
+		ns, err := jobNamespace(nil, state, t.JobID)
+		if err != nil {
+			return e, false, err
+		}
+		e.ID, e.Name, e.Namespace = t.ID, t.JobID, ns
+	case *structs.Job:
+		e.ID, e.Name, e.Namespace = t.ID, t.Name, namespaceOfJob(t)
+	case *structs.Node:
+		e.ID, e.Name = t.ID, t.Name
+	default:
+		return e, false, nil
+	}
+
+	e.key = strings.ToLower(e.ID + "\x00" + e.Name)
+	return e, true, nil
+}
+
+// contextIndex returns the fuzzy search index for the context, rebuilding it
+// if the backing table has changed since it was last built. Only blocking
+// queries waiting for changes past the table index are given an index built
+// less than fuzzyIndexRebuildInterval ago as is, and the watch set fires once
+// it may be rebuilt. Every other query sees the current table.
+func (s *Search) contextIndex(context string, minIndex uint64, ws memdb.WatchSet, state *state.StateStore) (*fuzzyContextIndex, error) {
+	iter, err := fuzzyIter(context, ws, state)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := state.Index(fuzzyContexts[context])
+	if err != nil {
+		return nil, err
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.indexes == nil {
+		s.indexes = make(map[string]*fuzzyContextIndex)
+	}
+	existing, ok := s.indexes[context]
+	if ok && existing.index == index {
+		return existing, nil
+	}
+	if ok && existing.index < index && minIndex != 0 && minIndex >= index && !existing.rebuildable() {
+		ws.Add(existing.rebuildCh)
+		return existing, nil
+	}
+
+	idx := newFuzzyContextIndex(index)
+	time.AfterFunc(fuzzyIndexRebuildInterval, func() { close(idx.rebuildCh) })
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		e, ok, err := newFuzzyEntry(state, raw)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			s.srv.logger.Printf("[ERR] nomad.search: unexpected type for fuzzy search context: %T", raw)
+			continue
+		}
+		idx.add(e)
+	}
+	s.indexes[context] = idx
+	return idx, nil
+}
+
+// fuzzyMatches returns the entries of the index matching the text that the
+// allow function returns true for, along with whether the matches were
+// truncated. Prefix matches are returned before substring matches.
+func (idx *fuzzyContextIndex) fuzzyMatches(text string, allow func(e *fuzzyEntry) bool) ([]structs.FuzzyMatch, bool) {
+	text = strings.ToLower(text)
+
+	var prefix, substring []structs.FuzzyMatch
+	for _, pos := range idx.candidates(text) {
+		e := idx.entries[pos]
+		if !allow(&e) {
+			continue
+		}
+		id, name := strings.ToLower(e.ID), strings.ToLower(e.Name)
+		switch {
+		case strings.HasPrefix(id, text) || strings.HasPrefix(name, text):
+			prefix = append(prefix, structs.FuzzyMatch{ID: e.ID, Name: e.Name})
+		case strings.Contains(e.key, text):
+			substring = append(substring, structs.FuzzyMatch{ID: e.ID, Name: e.Name})
+		}
+	}
+
+	matches := append(prefix, substring...)
+	if len(matches) > truncateLimit {
+		return matches[:truncateLimit], true
+	}
+	return matches, false
+}
+
+// FuzzySearch is used to list the objects registered in the system whose name
+// or ID contains the given text. Objects are allocations, deployments, jobs
+// and/or nodes.
+func (s *Search) FuzzySearch(args *structs.FuzzySearchRequest,
+	reply *structs.FuzzySearchResponse) error {
+	if done, err := s.srv.forward("Search.FuzzySearch", args, args, reply); done {
+		return err
+	}
+
+	if args.Text == "" {
+		return fmt.Errorf("missing search text")
+	}
+
+	aclObj, err := s.srv.ResolveToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Nodes require node read permissions and the other objects read
+	// permissions on their namespace
+	allow := func(e *fuzzyEntry) bool {
+		if e.Namespace == "" {
+			return aclObj.AllowNodeRead()
+		}
+		return aclObj.AllowNamespaceRead(e.Namespace)
+	}
+
+	contexts := fuzzyContextNames()
+	if args.Context != "" {
+		if _, ok := fuzzyContexts[args.Context]; !ok {
+			return fmt.Errorf("context must be one of %v; got %q", contexts, args.Context)
+		}
+		contexts = []string{args.Context}
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			reply.Matches = make(map[string][]structs.FuzzyMatch, len(contexts))
+			reply.Truncations = make(map[string]bool, len(contexts))
+			reply.Index = 0
+
+			for _, context := range contexts {
+				idx, err := s.contextIndex(context, args.MinQueryIndex, ws, state)
+				if err != nil {
+					return err
+				}
+
+				matches, truncated := idx.fuzzyMatches(args.Text, allow)
+				reply.Matches[context] = matches
+				reply.Truncations[context] = truncated
+
+				// Use the maximum index of the searched tables
+				if idx.index > reply.Index {
+					reply.Index = idx.index
+				}
+			}
+
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
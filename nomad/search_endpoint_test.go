@@ -0,0 +1,179 @@
+package nomad
+
+import (
+	"testing"
+
+	"github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchEndpoint_FuzzySearch(t *testing.T) {
+	assert := assert.New(t)
+	t.Parallel()
+	s := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0
+	})
+
+	defer s.Shutdown()
+	codec := rpcClient(t, s)
+	testutil.WaitForLeader(t, s.RPC)
+
+	// Register a job whose name matches by prefix and one whose name matches
+	// by substring
+	state := s.fsm.State()
+	job1, job2, job3 := mock.Job(), mock.Job(), mock.Job()
+	job1.Name = "frontend-web"
+	job2.Name = "web-api"
+	job3.Name = "cache"
+	for i, job := range []*structs.Job{job1, job2, job3} {
+		assert.Nil(state.UpsertJob(uint64(1000+i), job), "UpsertJob")
+	}
+
+	req := &structs.FuzzySearchRequest{
+		Text:    "WEB",
+		Context: "jobs",
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.FuzzySearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.FuzzySearch", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	matches := resp.Matches["jobs"]
+	if assert.Len(matches, 2) {
+		assert.Equal(job2.ID, matches[0].ID)
+		assert.Equal(job1.ID, matches[1].ID)
+	}
+	assert.False(resp.Truncations["jobs"])
+	assert.Equal(uint64(1002), resp.Index)
+
+	// Registering another job should update the index
+	job4 := mock.Job()
+	job4.Name = "web-worker"
+	assert.Nil(state.UpsertJob(1003, job4), "UpsertJob")
+
+	var resp2 structs.FuzzySearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.FuzzySearch", req, &resp2); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assert.Len(resp2.Matches["jobs"], 3)
+	assert.Equal(uint64(1003), resp2.Index)
+}
+
+func TestSearchEndpoint_FuzzySearch_AllContexts(t *testing.T) {
+	assert := assert.New(t)
+	t.Parallel()
+	s := testServer(t, func(c *Config) {
+		c.NumSchedulers = 0
+	})
+
+	defer s.Shutdown()
+	codec := rpcClient(t, s)
+	testutil.WaitForLeader(t, s.RPC)
+
+	state := s.fsm.State()
+	node := mock.Node()
+	node.Name = "web-node"
+	assert.Nil(state.UpsertNode(1000, node), "UpsertNode")
+
+	alloc := mock.Alloc()
+	state.UpsertJobSummary(1001, mock.JobSummary(alloc.JobID))
+	assert.Nil(state.UpsertAllocs(1002, []*structs.Allocation{alloc}), "UpsertAllocs")
+
+	req := &structs.FuzzySearchRequest{
+		Text: alloc.ID[2:10],
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.FuzzySearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.FuzzySearch", req, &resp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assert.Len(resp.Matches, len(fuzzyContexts))
+	if assert.Len(resp.Matches["allocs"], 1) {
+		assert.Equal(alloc.ID, resp.Matches["allocs"][0].ID)
+	}
+	assert.Len(resp.Matches["nodes"], 0)
+}
+
+func TestSearchEndpoint_FuzzySearch_InvalidContext(t *testing.T) {
+	t.Parallel()
+	s := testServer(t, nil)
+	defer s.Shutdown()
+	codec := rpcClient(t, s)
+	testutil.WaitForLeader(t, s.RPC)
+
+	req := &structs.FuzzySearchRequest{
+		Text:    "foo",
+		Context: "bad",
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+
+	var resp structs.FuzzySearchResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Search.FuzzySearch", req, &resp); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestFuzzyContextIndex_Matches(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	idx := newFuzzyContextIndex(1)
+	for _, job := range []*structs.Job{
+		{ID: "api-server", Name: "API Server", Namespace: "default"},
+		{ID: "cache", Name: "cache-api", Namespace: "default"},
+		{ID: "web", Name: "frontend", Namespace: "web"},
+	} {
+		e, ok, err := newFuzzyEntry(nil, job)
+		if err != nil || !ok {
+			t.Fatalf("no entry for %q", job.ID)
+		}
+		idx.add(e)
+	}
+
+	names := func(matches []structs.FuzzyMatch) []string {
+		var out []string
+		for _, m := range matches {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+
+	all := func(*fuzzyEntry) bool { return true }
+
+	// Prefix matches come before substring matches
+	matches, truncated := idx.fuzzyMatches("API", all)
+	assert.Equal([]string{"api-server", "cache"}, names(matches))
+	assert.False(truncated)
+
+	// Texts shorter than a trigram are matched against every entry
+	matches, _ = idx.fuzzyMatches("e", all)
+	assert.Equal([]string{"api-server", "cache", "web"}, names(matches))
+
+	matches, _ = idx.fuzzyMatches("tend", all)
+	assert.Equal([]string{"web"}, names(matches))
+
+	// A text with a trigram missing from the index has no candidates
+	matches, _ = idx.fuzzyMatches("apx", all)
+	assert.Empty(matches)
+
+	// Every trigram must be contained, not only the rarest
+	matches, _ = idx.fuzzyMatches("cache-server", all)
+	assert.Empty(matches)
+
+	// The entries not allowed are skipped
+	matches, _ = idx.fuzzyMatches("e", func(e *fuzzyEntry) bool { return e.Namespace == "web" })
+	assert.Equal([]string{"web"}, names(matches))
+}
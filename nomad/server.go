@@ -20,7 +20,9 @@ import (
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/go-multierror"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/nomad/command/agent/consul"
+	"github.com/hashicorp/nomad/helper/ratelimit"
 	"github.com/hashicorp/nomad/helper/tlsutil"
 	"github.com/hashicorp/nomad/nomad/deploymentwatcher"
 	"github.com/hashicorp/nomad/nomad/state"
@@ -28,6 +30,7 @@ import (
 	"github.com/hashicorp/raft"
 	"github.com/hashicorp/raft-boltdb"
 	"github.com/hashicorp/serf/serf"
+	"github.com/hashicorp/yamux"
 )
 
 const (
@@ -106,12 +109,25 @@ type Server struct {
 	// rpcTLS is the TLS config for incoming TLS requests
 	rpcTLS *tls.Config
 
+	// rpcLimiter limits the rate of RPC requests from clients. It is nil if
+	// no limit is configured.
+	rpcLimiter *ratelimit.Limiter
+
+	// rpcTokenLimiter limits the rate of RPC requests per ACL token. It is
+	// nil if no limit is configured.
+	rpcTokenLimiter *ratelimit.Limiter
+
 	// peers is used to track the known Nomad servers. This is
 	// used for region forwarding and clustering.
 	peers      map[string][]*serverParts
 	localPeers map[raft.ServerAddress]*serverParts
 	peerLock   sync.RWMutex
 
+	// nodeConns are the sessions of the connections clients opened to this
+	// server for the servers to make RPCs to their nodes, by node ID.
+	nodeConns     map[string]*yamux.Session
+	nodeConnsLock sync.Mutex
+
 	// serf is the Serf cluster containing only Nomad
 	// servers. This is used for multi-region federation
 	// and automatic clustering within regions.
@@ -129,6 +145,10 @@ type Server struct {
 	// capacity changes.
 	blockedEvals *BlockedEvals
 
+	// capacity tracks the resources used by the allocations and rolls up the
+	// capacity of the cluster while leader.
+	capacity *CapacityTracker
+
 	// deploymentWatcher is used to watch deployments and their allocations and
 	// make the required calls to continue to transition the deployment.
 	deploymentWatcher *deploymentwatcher.Watcher
@@ -152,9 +172,45 @@ type Server struct {
 	// consulCatalog is used for discovering other Nomad Servers via Consul
 	consulCatalog consul.CatalogAPI
 
+	// consulACLs is used for managing the Consul Service Identity tokens of
+	// the Connect sidecar proxies
+	consulACLs consul.ACLsAPI
+
+	// consulConfigEntries is used for writing the Consul configuration
+	// entries of the Connect gateways
+	consulConfigEntries consul.ConfigEntriesAPI
+
 	// vault is the client for communicating with Vault.
 	vault VaultClient
 
+	// vaultIdentities signs the workload identities tasks log in to Vault
+	// with. It is nil unless an identity signing key is configured.
+	vaultIdentities *vaultIdentitySigner
+
+	// encrypter encrypts the items of the variables. It is nil unless a
+	// variables encryption key is configured.
+	encrypter *encrypter
+
+	// workloadIdentities signs the workload identities tasks call the Nomad
+	// API with
+	workloadIdentities *workloadIdentitySigner
+
+	// aclCache caches the ACLs compiled from sets of ACL policies
+	aclCache *lru.TwoQueueCache
+
+	// oidcLogins are the logins started with the OIDC auth methods that
+	// this server is waiting to complete
+	oidcLogins *oidcLogins
+
+	// authKeySets caches the key sets and discovery documents of the
+	// identity providers of the auth methods
+	authKeySets *jwksCache
+
+	// leaderAcl is the management token the leader issues its own work with,
+	// such as the core jobs. It is regenerated on each leadership election.
+	leaderAcl     string
+	leaderAclLock sync.Mutex
+
 	// Worker used for processing
 	workers []*Worker
 
@@ -166,28 +222,39 @@ type Server struct {
 
 // Holds the RPC endpoints
 type endpoints struct {
-	Status     *Status
-	Node       *Node
-	Job        *Job
-	Eval       *Eval
-	Plan       *Plan
-	Alloc      *Alloc
-	Deployment *Deployment
-	Region     *Region
-	Resources  *Resources
-	Periodic   *Periodic
-	System     *System
-	Operator   *Operator
+	ACL                 *ACL
+	Status              *Status
+	Node                *Node
+	Job                 *Job
+	Eval                *Eval
+	Plan                *Plan
+	Alloc               *Alloc
+	Deployment          *Deployment
+	Namespace           *Namespace
+	ServiceRegistration *ServiceRegistration
+	Variables           *Variables
+	Region              *Region
+	Resources           *Resources
+	Search              *Search
+	Periodic            *Periodic
+	System              *System
+	Operator            *Operator
 }
 
 // NewServer is used to construct a new Nomad server from the
 // configuration, potentially returning an error
-func NewServer(config *Config, consulCatalog consul.CatalogAPI, logger *log.Logger) (*Server, error) {
+func NewServer(config *Config, consulCatalog consul.CatalogAPI, consulACLs consul.ACLsAPI,
+	consulConfigEntries consul.ConfigEntriesAPI, logger *log.Logger) (*Server, error) {
 	// Check the protocol version
 	if err := config.CheckVersion(); err != nil {
 		return nil, err
 	}
 
+	// Without another authoritative region the ACLs are managed locally
+	if config.AuthoritativeRegion == "" {
+		config.AuthoritativeRegion = config.Region
+	}
+
 	// Create an eval broker
 	evalBroker, err := NewEvalBroker(
 		config.EvalNackTimeout,
@@ -225,22 +292,37 @@ func NewServer(config *Config, consulCatalog consul.CatalogAPI, logger *log.Logg
 		incomingTLS = itls
 	}
 
+	// Create the ACL cache
+	aclCache, err := lru.New2Q(aclCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the server
 	s := &Server{
-		config:        config,
-		consulCatalog: consulCatalog,
-		connPool:      NewPool(config.LogOutput, serverRPCCache, serverMaxStreams, tlsWrap),
-		logger:        logger,
-		rpcServer:     rpc.NewServer(),
-		peers:         make(map[string][]*serverParts),
-		localPeers:    make(map[raft.ServerAddress]*serverParts),
-		reconcileCh:   make(chan serf.Member, 32),
-		eventCh:       make(chan serf.Event, 256),
-		evalBroker:    evalBroker,
-		blockedEvals:  blockedEvals,
-		planQueue:     planQueue,
-		rpcTLS:        incomingTLS,
-		shutdownCh:    make(chan struct{}),
+		config:              config,
+		consulCatalog:       consulCatalog,
+		consulACLs:          consulACLs,
+		consulConfigEntries: consulConfigEntries,
+		connPool:            NewPool(config.LogOutput, serverRPCCache, serverMaxStreams, tlsWrap),
+		logger:              logger,
+		rpcServer:           rpc.NewServer(),
+		peers:               make(map[string][]*serverParts),
+		localPeers:          make(map[raft.ServerAddress]*serverParts),
+		nodeConns:           make(map[string]*yamux.Session),
+		reconcileCh:         make(chan serf.Member, 32),
+		eventCh:             make(chan serf.Event, 256),
+		evalBroker:          evalBroker,
+		blockedEvals:        blockedEvals,
+		capacity:            NewCapacityTracker(),
+		planQueue:           planQueue,
+		rpcTLS:              incomingTLS,
+		rpcLimiter:          ratelimit.New(config.RPCRateLimit),
+		rpcTokenLimiter:     ratelimit.New(config.RPCTokenRateLimit),
+		aclCache:            aclCache,
+		oidcLogins:          newOIDCLogins(),
+		authKeySets:         newJWKSCache(),
+		shutdownCh:          make(chan struct{}),
 	}
 
 	// Create the periodic dispatcher for launching periodic jobs.
@@ -253,6 +335,27 @@ func NewServer(config *Config, consulCatalog consul.CatalogAPI, logger *log.Logg
 		return nil, fmt.Errorf("Failed to setup Vault client: %v", err)
 	}
 
+	// Setup the signer of the Vault workload identities
+	if err := s.setupVaultIdentities(); err != nil {
+		s.Shutdown()
+		s.logger.Printf("[ERR] nomad: failed to setup Vault identity signer: %v", err)
+		return nil, fmt.Errorf("Failed to setup Vault identity signer: %v", err)
+	}
+
+	// Setup the encrypter of the variables
+	if err := s.setupEncrypter(); err != nil {
+		s.Shutdown()
+		s.logger.Printf("[ERR] nomad: failed to setup variables encrypter: %v", err)
+		return nil, fmt.Errorf("Failed to setup variables encrypter: %v", err)
+	}
+
+	// Setup the signer of the workload identities
+	if err := s.setupWorkloadIdentities(); err != nil {
+		s.Shutdown()
+		s.logger.Printf("[ERR] nomad: failed to setup workload identity signer: %v", err)
+		return nil, fmt.Errorf("Failed to setup workload identity signer: %v", err)
+	}
+
 	// Initialize the RPC layer
 	if err := s.setupRPC(tlsWrap); err != nil {
 		s.Shutdown()
@@ -354,8 +457,9 @@ func (s *Server) Shutdown() error {
 		s.rpcListener.Close()
 	}
 
-	// Close the connection pool
+	// Close the connection pool and the node connections
 	s.connPool.Shutdown()
+	s.closeNodeConns()
 
 	// Close the fsm
 	if s.fsm != nil {
@@ -712,14 +816,60 @@ func (s *Server) setupVaultClient() error {
 	return nil
 }
 
+// setupVaultIdentities is used to set up the signer of the Vault workload
+// identities of tasks if enabled.
+func (s *Server) setupVaultIdentities() error {
+	if !s.config.VaultConfig.UsesIdentities() {
+		return nil
+	}
+	signer, err := newVaultIdentitySigner(s.config.VaultConfig, s.config.Region)
+	if err != nil {
+		return err
+	}
+	s.vaultIdentities = signer
+	return nil
+}
+
+// setupEncrypter is used to set up the encrypter of the variables if a key is
+// configured.
+func (s *Server) setupEncrypter() error {
+	if len(s.config.VariablesEncryptionKey) == 0 {
+		return nil
+	}
+	e, err := newEncrypter(s.config.VariablesEncryptionKey)
+	if err != nil {
+		return err
+	}
+	s.encrypter = e
+	return nil
+}
+
+// setupWorkloadIdentities is used to set up the signer of the workload
+// identities of the allocations if a signing key is configured.
+func (s *Server) setupWorkloadIdentities() error {
+	if len(s.config.WorkloadIdentitySigningKey) == 0 {
+		return nil
+	}
+	signer, err := newWorkloadIdentitySigner(s.config.WorkloadIdentitySigningKey, s.config.Region)
+	if err != nil {
+		return err
+	}
+	s.workloadIdentities = signer
+	return nil
+}
+
 // setupRPC is used to setup the RPC listener
 func (s *Server) setupRPC(tlsWrap tlsutil.RegionWrapper) error {
 	// Create endpoints
+	s.endpoints.ACL = &ACL{s}
 	s.endpoints.Alloc = &Alloc{s}
 	s.endpoints.Eval = &Eval{s}
-	s.endpoints.Job = &Job{s}
+	s.endpoints.Job = NewJobEndpoints(s)
 	s.endpoints.Node = &Node{srv: s}
 	s.endpoints.Deployment = &Deployment{srv: s}
+	s.endpoints.Namespace = &Namespace{srv: s}
+	s.endpoints.ServiceRegistration = &ServiceRegistration{srv: s}
+	s.endpoints.Variables = &Variables{srv: s}
 	s.endpoints.Operator = &Operator{s}
 	s.endpoints.Periodic = &Periodic{s}
 	s.endpoints.Plan = &Plan{s}
@@ -727,13 +877,18 @@ func (s *Server) setupRPC(tlsWrap tlsutil.RegionWrapper) error {
 	s.endpoints.Status = &Status{s}
 	s.endpoints.System = &System{s}
 	s.endpoints.Resources = &Resources{s}
+	s.endpoints.Search = &Search{srv: s}
 
 	// Register the handlers
+	s.rpcServer.Register(s.endpoints.ACL)
 	s.rpcServer.Register(s.endpoints.Alloc)
 	s.rpcServer.Register(s.endpoints.Eval)
 	s.rpcServer.Register(s.endpoints.Job)
 	s.rpcServer.Register(s.endpoints.Node)
 	s.rpcServer.Register(s.endpoints.Deployment)
+	s.rpcServer.Register(s.endpoints.Namespace)
+	s.rpcServer.Register(s.endpoints.ServiceRegistration)
+	s.rpcServer.Register(s.endpoints.Variables)
 	s.rpcServer.Register(s.endpoints.Operator)
 	s.rpcServer.Register(s.endpoints.Periodic)
 	s.rpcServer.Register(s.endpoints.Plan)
@@ -741,6 +896,7 @@ func (s *Server) setupRPC(tlsWrap tlsutil.RegionWrapper) error {
 	s.rpcServer.Register(s.endpoints.Status)
 	s.rpcServer.Register(s.endpoints.System)
 	s.rpcServer.Register(s.endpoints.Resources)
+	s.rpcServer.Register(s.endpoints.Search)
 
 	list, err := net.ListenTCP("tcp", s.config.RPCAddr)
 	if err != nil {
@@ -1001,6 +1157,45 @@ func (s *Server) IsLeader() bool {
 	return s.raft.State() == raft.Leader
 }
 
+// ServerHealth is the health of the components of a server.
+type ServerHealth struct {
+	// RaftState is the Raft state of the server: Leader, Follower,
+	// Candidate or Shutdown.
+	RaftState string
+
+	// Leader is the address of the known leader, if any.
+	Leader string
+
+	// LastLeaderContact is the time the server last heard from the leader.
+	// It is zero on the leader and if the server never heard from one.
+	LastLeaderContact time.Time
+
+	// VaultEnabled, VaultEstablished and VaultError are whether the Vault
+	// integration is enabled and whether, if not, why, the connection to
+	// Vault is established.
+	VaultEnabled     bool
+	VaultEstablished bool
+	VaultError       error
+}
+
+// Health returns the health of the Raft consensus and of the connection to
+// Vault of the server.
+func (s *Server) Health() *ServerHealth {
+	state := s.raft.State()
+	health := &ServerHealth{
+		RaftState: state.String(),
+		Leader:    string(s.raft.Leader()),
+	}
+	if state != raft.Leader {
+		health.LastLeaderContact = s.raft.LastContact()
+	}
+	if s.vault != nil && s.vault.Enabled() {
+		health.VaultEnabled = true
+		health.VaultEstablished, health.VaultError = s.vault.ConnectionEstablished()
+	}
+	return health
+}
+
 // Join is used to have Nomad join the gossip ring
 // The target address should be another node listening on the
 // Serf address
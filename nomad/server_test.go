@@ -79,6 +79,8 @@ func testServer(t *testing.T, cb func(*Config)) *Server {
 
 	logger := log.New(config.LogOutput, fmt.Sprintf("[%s] ", config.NodeName), log.LstdFlags)
 	catalog := consul.NewMockCatalog(logger)
+	acls := consul.NewMockACLs(logger)
+	configEntries := consul.NewMockConfigEntries(logger)
 
 	for i := 10; i >= 0; i-- {
 		// Get random ports
@@ -89,7 +91,7 @@ func testServer(t *testing.T, cb func(*Config)) *Server {
 		config.SerfConfig.MemberlistConfig.BindPort = getPort()
 
 		// Create server
-		server, err := NewServer(config, catalog, logger)
+		server, err := NewServer(config, catalog, acls, configEntries, logger)
 		if err == nil {
 			return server
 		} else if i == 0 {
@@ -0,0 +1,274 @@
+package nomad
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// ServiceRegistration endpoint is used to read and write the catalog of the
+// Nomad service discovery provider
+type ServiceRegistration struct {
+	srv *Server
+}
+
+// Upsert is used by the clients to create or update the registrations of
+// the services of their allocations
+func (s *ServiceRegistration) Upsert(args *structs.ServiceRegistrationUpsertRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.Upsert", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "upsert"}, time.Now())
+
+	// Validate the arguments
+	if len(args.Services) == 0 {
+		return fmt.Errorf("must specify at least one service registration")
+	}
+	for _, service := range args.Services {
+		if err := service.Validate(); err != nil {
+			return fmt.Errorf("Invalid service registration %q: %v", service.ID, err)
+		}
+	}
+
+	// Reject registrations for allocations that aren't running anymore so
+	// that they don't outlive them, and set the job from the allocation
+	snap, err := s.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	for _, service := range args.Services {
+		alloc, err := snap.AllocByID(nil, service.AllocID)
+		if err != nil {
+			return err
+		}
+		if alloc == nil {
+			return fmt.Errorf("allocation %q of service registration %q not found", service.AllocID, service.ID)
+		}
+		if alloc.Terminated() {
+			return fmt.Errorf("allocation %q of service registration %q is terminal", service.AllocID, service.ID)
+		}
+		if err := s.checkAllocWrite(args.AuthToken, alloc); err != nil {
+			return err
+		}
+		service.JobID = alloc.JobID
+	}
+
+	// Update via Raft
+	_, index, err := s.srv.raftApply(structs.ServiceRegistrationUpsertRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// DeleteByID is used to delete a set of service registrations
+func (s *ServiceRegistration) DeleteByID(args *structs.ServiceRegistrationDeleteByIDRequest,
+	reply *structs.GenericResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.DeleteByID", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "delete_by_id"}, time.Now())
+
+	// Validate the arguments
+	if len(args.IDs) == 0 {
+		return fmt.Errorf("must specify at least one service registration to delete")
+	}
+	snap, err := s.srv.fsm.State().Snapshot()
+	if err != nil {
+		return err
+	}
+	for _, id := range args.IDs {
+		service, err := snap.ServiceRegistrationByID(nil, id)
+		if err != nil {
+			return err
+		}
+		if service == nil {
+			continue
+		}
+		alloc, err := snap.AllocByID(nil, service.AllocID)
+		if err != nil {
+			return err
+		}
+		if alloc == nil {
+			// The registrations of purged allocations are in the namespace
+			// of their job
+			ns, err := jobNamespace(nil, &snap.StateStore, service.JobID)
+			if err != nil {
+				return err
+			}
+			alloc = &structs.Allocation{NodeID: service.NodeID, Job: &structs.Job{Namespace: ns}}
+		}
+		if err := s.checkAllocWrite(args.AuthToken, alloc); err != nil {
+			return err
+		}
+	}
+
+	// Update via Raft
+	_, index, err := s.srv.raftApply(structs.ServiceRegistrationDeleteByIDRequestType, args)
+	if err != nil {
+		return err
+	}
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// List is used to list the services of the catalog along with their tags
+func (s *ServiceRegistration) List(args *structs.ServiceRegistrationListRequest,
+	reply *structs.ServiceRegistrationListResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "list"}, time.Now())
+
+	// Clients read the services of the catalog to render their templates
+	_, aclObj, err := s.srv.resolveClientOrToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			iter, err := state.ServiceRegistrations(ws)
+			if err != nil {
+				return err
+			}
+
+			// Group the registrations by service
+			tags := make(map[string]map[string]struct{})
+			for {
+				raw := iter.Next()
+				if raw == nil {
+					break
+				}
+				service := raw.(*structs.ServiceRegistration)
+				if !strings.HasPrefix(service.ServiceName, args.QueryOptions.Prefix) {
+					continue
+				}
+				if ns, err := jobNamespace(nil, state, service.JobID); err != nil {
+					return err
+				} else if !aclObj.AllowNamespaceRead(ns) {
+					continue
+				}
+				if _, ok := tags[service.ServiceName]; !ok {
+					tags[service.ServiceName] = make(map[string]struct{})
+				}
+				for _, tag := range service.Tags {
+					tags[service.ServiceName][tag] = struct{}{}
+				}
+			}
+
+			services := make([]*structs.ServiceRegistrationListStub, 0, len(tags))
+			for name, set := range tags {
+				stub := &structs.ServiceRegistrationListStub{
+					ServiceName: name,
+					Tags:        make([]string, 0, len(set)),
+				}
+				for tag := range set {
+					stub.Tags = append(stub.Tags, tag)
+				}
+				sort.Strings(stub.Tags)
+				services = append(services, stub)
+			}
+			sort.Slice(services, func(i, j int) bool {
+				return services[i].ServiceName < services[j].ServiceName
+			})
+			reply.Services = services
+
+			// Use the last index that affected the service registrations table
+			index, err := state.Index("service_registrations")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
+
+// GetService is used to request the registrations of a service
+func (s *ServiceRegistration) GetService(args *structs.ServiceRegistrationByNameRequest,
+	reply *structs.ServiceRegistrationByNameResponse) error {
+	if done, err := s.srv.forward("ServiceRegistration.GetService", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "service_registration", "get_service"}, time.Now())
+
+	// Clients read the services of the catalog to render their templates
+	_, aclObj, err := s.srv.resolveClientOrToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			// Verify the arguments
+			if args.ServiceName == "" {
+				return fmt.Errorf("missing service name")
+			}
+
+			out, err := state.ServiceRegistrationsByName(ws, args.ServiceName)
+			if err != nil {
+				return err
+			}
+			reply.Services = nil
+			for _, service := range out {
+				if ns, err := jobNamespace(nil, state, service.JobID); err != nil {
+					return err
+				} else if aclObj.AllowNamespaceRead(ns) {
+					reply.Services = append(reply.Services, service)
+				}
+			}
+
+			// Use the last index that affected the service registrations table
+			index, err := state.Index("service_registrations")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			s.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return s.srv.blockingRPC(&opts)
+}
+
+// checkAllocWrite returns an error unless the secret ID is the one of the node
+// running the allocation, or of a token allowed to write its namespace
+func (s *ServiceRegistration) checkAllocWrite(secretID string, alloc *structs.Allocation) error {
+	node, aclObj, err := s.srv.resolveClientOrToken(secretID)
+	if err != nil {
+		return err
+	}
+	if node != nil {
+		if node.ID != alloc.NodeID {
+			return structs.ErrPermissionDenied
+		}
+		return nil
+	}
+	if !aclObj.AllowNamespaceWrite(allocNamespace(alloc)) {
+		return structs.ErrPermissionDenied
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	msgpackrpc "github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceRegistrationEndpoint_Upsert(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+	assert := assert.New(t)
+
+	// Create the allocation providing the service
+	alloc := mock.Alloc()
+	assert.Nil(state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID)), "UpsertJobSummary")
+	assert.Nil(state.UpsertAllocs(1000, []*structs.Allocation{alloc}), "UpsertAllocs")
+
+	service := mock.ServiceRegistration()
+	service.AllocID = alloc.ID
+	service.JobID = ""
+	req := &structs.ServiceRegistrationUpsertRequest{
+		Services:     []*structs.ServiceRegistration{service},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp), "RPC")
+	assert.NotEqual(uint64(0), resp.Index, "resp.Index")
+
+	out, err := state.ServiceRegistrationByID(nil, service.ID)
+	assert.Nil(err, "ServiceRegistrationByID")
+	assert.NotNil(out, "registration")
+	assert.Equal(alloc.JobID, out.JobID, "JobID")
+
+	// Registrations of unknown allocations are rejected
+	service = mock.ServiceRegistration()
+	req.Services = []*structs.ServiceRegistration{service}
+	err = msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected unknown allocation error; got %v", err)
+	}
+
+	// Invalid registrations are rejected
+	service = mock.ServiceRegistration()
+	service.ServiceName = ""
+	req.Services = []*structs.ServiceRegistration{service}
+	err = msgpackrpc.CallWithCodec(codec, "ServiceRegistration.Upsert", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "missing service name") {
+		t.Fatalf("expected validation error; got %v", err)
+	}
+}
+
+func TestServiceRegistrationEndpoint_DeleteByID(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+	assert := assert.New(t)
+
+	service := mock.ServiceRegistration()
+	assert.Nil(state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{service}), "UpsertServiceRegistrations")
+
+	req := &structs.ServiceRegistrationDeleteByIDRequest{
+		IDs:          []string{service.ID},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.GenericResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "ServiceRegistration.DeleteByID", req, &resp), "RPC")
+	assert.NotEqual(uint64(0), resp.Index, "resp.Index")
+
+	out, err := state.ServiceRegistrationByID(nil, service.ID)
+	assert.Nil(err, "ServiceRegistrationByID")
+	assert.Nil(out, "registration")
+}
+
+func TestServiceRegistrationEndpoint_List(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	s1a := mock.ServiceRegistration()
+	s1b := mock.ServiceRegistration()
+	s1b.Tags = []string{"canary"}
+	s2 := mock.ServiceRegistration()
+	s2.ServiceName = "api"
+	s2.Tags = nil
+	assert.Nil(s1.fsm.State().UpsertServiceRegistrations(1000,
+		[]*structs.ServiceRegistration{s1a, s1b, s2}), "UpsertServiceRegistrations")
+
+	req := &structs.ServiceRegistrationListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var resp structs.ServiceRegistrationListResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "ServiceRegistration.List", req, &resp), "RPC")
+	assert.EqualValues(1000, resp.Index, "resp.Index")
+	assert.Len(resp.Services, 2, "services")
+	assert.Equal("api", resp.Services[0].ServiceName, "first service")
+	assert.Equal("web", resp.Services[1].ServiceName, "second service")
+	assert.Equal([]string{"canary", "pci"}, resp.Services[1].Tags, "merged tags")
+
+	// Lookup the services by prefix
+	req.QueryOptions.Prefix = "we"
+	var resp2 structs.ServiceRegistrationListResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "ServiceRegistration.List", req, &resp2), "RPC")
+	assert.Len(resp2.Services, 1, "services")
+	assert.Equal("web", resp2.Services[0].ServiceName, "service")
+}
+
+func TestServiceRegistrationEndpoint_GetService_Blocking(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+	assert := assert.New(t)
+
+	// Register the service we are watching later
+	service := mock.ServiceRegistration()
+	time.AfterFunc(100*time.Millisecond, func() {
+		assert.Nil(state.UpsertServiceRegistrations(200, []*structs.ServiceRegistration{service}), "UpsertServiceRegistrations")
+	})
+
+	req := &structs.ServiceRegistrationByNameRequest{
+		ServiceName: service.ServiceName,
+		QueryOptions: structs.QueryOptions{
+			Region:        "global",
+			MinQueryIndex: 150,
+		},
+	}
+	var resp structs.ServiceRegistrationByNameResponse
+	start := time.Now()
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "ServiceRegistration.GetService", req, &resp), "RPC")
+	assert.False(time.Since(start) < 100*time.Millisecond, "should block")
+	assert.EqualValues(200, resp.Index, "resp.Index")
+	assert.Len(resp.Services, 1, "services")
+	assert.Equal(service.ID, resp.Services[0].ID, "registration")
+}
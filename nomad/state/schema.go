@@ -26,6 +26,16 @@ func stateStoreSchema() *memdb.DBSchema {
 		evalTableSchema,
 		allocTableSchema,
 		vaultAccessorTableSchema,
+		namespaceTableSchema,
+		schedulerConfigTableSchema,
+		siTokenAccessorTableSchema,
+		serviceRegistrationTableSchema,
+		variablesTableSchema,
+		aclPolicyTableSchema,
+		aclRoleTableSchema,
+		aclAuthMethodTableSchema,
+		aclBindingRuleTableSchema,
+		aclTokenTableSchema,
 	}
 
 	// Add each of the tables
@@ -74,6 +84,17 @@ func nodeTableSchema() *memdb.TableSchema {
 					Field: "ID",
 				},
 			},
+
+			// Secret index is used to authenticate the requests of the
+			// clients, made with the secret ID of their node
+			"secret_id": &memdb.IndexSchema{
+				Name:         "secret_id",
+				AllowMissing: true,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "SecretID",
+				},
+			},
 		},
 	}
 }
@@ -91,10 +112,7 @@ func jobTableSchema() *memdb.TableSchema {
 				Name:         "id",
 				AllowMissing: false,
 				Unique:       true,
-				Indexer: &memdb.StringFieldIndex{
-					Field:     "ID",
-					Lowercase: true,
-				},
+				Indexer:      newLowercaseStringFieldIndex("ID"),
 			},
 			"type": &memdb.IndexSchema{
 				Name:         "type",
@@ -105,6 +123,19 @@ func jobTableSchema() *memdb.TableSchema {
 					Lowercase: false,
 				},
 			},
+			"namespace": &memdb.IndexSchema{
+				Name:         "namespace",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						newLowercaseStringFieldIndex("ID"),
+					},
+				},
+			},
 			"gc": &memdb.IndexSchema{
 				Name:         "gc",
 				AllowMissing: false,
@@ -121,6 +152,14 @@ func jobTableSchema() *memdb.TableSchema {
 					Conditional: jobIsPeriodic,
 				},
 			},
+			"notify": &memdb.IndexSchema{
+				Name:         "notify",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: jobNeedsNotification,
+				},
+			},
 		},
 	}
 }
@@ -174,6 +213,23 @@ func jobVersionSchema() *memdb.TableSchema {
 	}
 }
 
+// jobNeedsNotification satisfies the ConditionalIndexFunc interface and
+// creates an index on whether the current run of a job reached a terminal
+// state without its notification being sent.
+func jobNeedsNotification(obj interface{}) (bool, error) {
+	j, ok := obj.(*structs.Job)
+	if !ok {
+		return false, fmt.Errorf("Unexpected type: %v", obj)
+	}
+
+	// Periodic and parameterized jobs never run themselves, their children
+	// are notified instead
+	if j.Notification == nil || j.IsPeriodic() || j.IsParameterized() {
+		return false, nil
+	}
+	return j.Status == structs.JobStatusDead && j.NotifiedIndex != j.JobModifyIndex, nil
+}
+
 // jobIsGCable satisfies the ConditionalIndexFunc interface and creates an index
 // on whether a job is eligible for garbage collection.
 func jobIsGCable(obj interface{}) (bool, error) {
@@ -309,10 +365,31 @@ func evalTableSchema() *memdb.TableSchema {
 					},
 				},
 			},
+
+			// Held index is used to lookup the evaluations held until the
+			// jobs their job depends on complete
+			"held": &memdb.IndexSchema{
+				Name:         "held",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: evalIsHeld,
+				},
+			},
 		},
 	}
 }
 
+// evalIsHeld satisfies the ConditionalIndexFunc interface and creates an index
+// on whether an evaluation is held.
+func evalIsHeld(obj interface{}) (bool, error) {
+	e, ok := obj.(*structs.Evaluation)
+	if !ok {
+		return false, fmt.Errorf("Unexpected type: %v", obj)
+	}
+	return e.Status == structs.EvalStatusHeld, nil
+}
+
 // allocTableSchema returns the MemDB schema for the allocation table.
 // This table is used to store all the task allocations between task groups
 // and nodes.
@@ -430,3 +507,279 @@ func vaultAccessorTableSchema() *memdb.TableSchema {
 		},
 	}
 }
+
+// siTokenAccessorTableSchema returns the MemDB schema for the Service Identity
+// token accessors table. This table tracks the accessors of the Consul tokens
+// created on behalf of the Connect sidecar proxy tasks.
+func siTokenAccessorTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "si_token_accessors",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the accessor id
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "AccessorID",
+				},
+			},
+
+			"alloc_id": &memdb.IndexSchema{
+				Name:         "alloc_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "AllocID",
+				},
+			},
+
+			"node_id": &memdb.IndexSchema{
+				Name:         "node_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "NodeID",
+				},
+			},
+		},
+	}
+}
+
+// serviceRegistrationTableSchema returns the MemDB schema for the service
+// registrations of the Nomad service discovery provider.
+func serviceRegistrationTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "service_registrations",
+		Indexes: map[string]*memdb.IndexSchema{
+			// The primary index is the registration id
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ID",
+				},
+			},
+
+			"service_name": &memdb.IndexSchema{
+				Name:         "service_name",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "ServiceName",
+				},
+			},
+
+			"alloc_id": &memdb.IndexSchema{
+				Name:         "alloc_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "AllocID",
+				},
+			},
+
+			"node_id": &memdb.IndexSchema{
+				Name:         "node_id",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "NodeID",
+				},
+			},
+		},
+	}
+}
+
+// namespaceTableSchema returns the MemDB schema for the namespaces table.
+func namespaceTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "namespaces",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Name",
+				},
+			},
+		},
+	}
+}
+
+// variablesTableSchema returns the MemDB schema for the variables table.
+// Variables are uniquely identified by their namespace and path.
+func variablesTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "variables",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.StringFieldIndex{
+							Field: "Namespace",
+						},
+						&memdb.StringFieldIndex{
+							Field: "Path",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// schedulerConfigTableSchema returns the MemDB schema for the scheduler
+// configuration table. The table holds a single configuration object.
+func schedulerConfigTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "scheduler_config",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: true,
+				Unique:       true,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: func(obj interface{}) (bool, error) { return true, nil },
+				},
+			},
+		},
+	}
+}
+
+// aclPolicyTableSchema returns the MemDB schema for the ACL policies table.
+func aclPolicyTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl_policy",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Name",
+				},
+			},
+		},
+	}
+}
+
+// aclRoleTableSchema returns the MemDB schema for the ACL roles table.
+func aclRoleTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl_role",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Name",
+				},
+			},
+		},
+	}
+}
+
+// aclAuthMethodTableSchema returns the MemDB schema for the ACL auth methods
+// table.
+func aclAuthMethodTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl_auth_method",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "Name",
+				},
+			},
+		},
+	}
+}
+
+// aclBindingRuleTableSchema returns the MemDB schema for the ACL binding rules
+// table. Binding rules are looked up by the auth method they apply to when
+// logging in.
+func aclBindingRuleTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl_binding_rule",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "ID",
+				},
+			},
+			"auth_method": &memdb.IndexSchema{
+				Name:         "auth_method",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "AuthMethod",
+				},
+			},
+		},
+	}
+}
+
+// aclTokenTableSchema returns the MemDB schema for the ACL tokens table.
+// Tokens are looked up by their accessor ID and, to authenticate requests,
+// by their secret ID.
+func aclTokenTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "acl_token",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "AccessorID",
+				},
+			},
+			"secret": &memdb.IndexSchema{
+				Name:         "secret",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.StringFieldIndex{
+					Field: "SecretID",
+				},
+			},
+			"global": &memdb.IndexSchema{
+				Name:         "global",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.FieldSetIndex{
+					Field: "Global",
+				},
+			},
+			"expiring": &memdb.IndexSchema{
+				Name:         "expiring",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.ConditionalIndex{
+					Conditional: aclTokenIsExpiring,
+				},
+			},
+		},
+	}
+}
+
+// aclTokenIsExpiring satisfies the ConditionalIndexFunc interface and
+// returns whether the token has an expiration time.
+func aclTokenIsExpiring(obj interface{}) (bool, error) {
+	token, ok := obj.(*structs.ACLToken)
+	if !ok {
+		return false, fmt.Errorf("Unexpected type: %v", obj)
+	}
+	return token.ExpirationTime != nil, nil
+}
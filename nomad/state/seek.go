@@ -0,0 +1,265 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+
+	memdb "github.com/hashicorp/go-memdb"
+)
+
+// seekScanLimit is the number of objects a seek skips over under a prefix
+// before it looks the following ones up by their next byte instead. Looking
+// up the bytes costs a prefix lookup each, so scanning is cheaper while few
+// objects precede the bound.
+const seekScanLimit = 256
+
+// lowercaseStringFieldIndex is a case insensitive StringFieldIndex that also
+// takes a byte slice argument as an already lowercased value. Lowercasing a
+// string replaces its bytes that aren't valid UTF-8, so seeks pass the byte
+// prefixes of keys as byte slices.
+type lowercaseStringFieldIndex struct {
+	*memdb.StringFieldIndex
+}
+
+// newLowercaseStringFieldIndex returns a case insensitive index of the field.
+func newLowercaseStringFieldIndex(field string) *lowercaseStringFieldIndex {
+	return &lowercaseStringFieldIndex{
+		StringFieldIndex: &memdb.StringFieldIndex{
+			Field:     field,
+			Lowercase: true,
+		},
+	}
+}
+
+func (l *lowercaseStringFieldIndex) FromArgs(args ...interface{}) ([]byte, error) {
+	if len(args) == 1 {
+		if arg, ok := args[0].([]byte); ok {
+			// Add the null character as a terminator
+			return append(append([]byte{}, arg...), 0), nil
+		}
+	}
+	return l.StringFieldIndex.FromArgs(args...)
+}
+
+func (l *lowercaseStringFieldIndex) PrefixFromArgs(args ...interface{}) ([]byte, error) {
+	if len(args) == 1 {
+		if arg, ok := args[0].([]byte); ok {
+			return arg, nil
+		}
+	}
+	return l.StringFieldIndex.PrefixFromArgs(args...)
+}
+
+// PrefixFrom returns an iterator over the objects of the table whose key in
+// the index starts with the prefix, in the order of their keys and starting
+// at the first one whose key is at least from. The index is a prefix index
+// given without its "_prefix" suffix, and args are the values of the leading
+// fields of a compound index. List queries use it to resume at the token of a
+// page without iterating over the previous pages.
+func (s *StateStore) PrefixFrom(ws memdb.WatchSet, table, index string, args []interface{}, prefix, from string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Watch the whole prefix, since any change to it may change the page
+	iter, err := txn.Get(table, index+"_prefix", append(args, prefix)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s lookup failed: %v", table, err)
+	}
+	ws.Add(iter.WatchCh())
+	if from == "" {
+		return iter, nil
+	}
+
+	seek, err := s.newSeekIterator(txn, table, index, args, prefix, from)
+	if err != nil {
+		return nil, fmt.Errorf("%s lookup failed: %v", table, err)
+	}
+	seek.watchCh = iter.WatchCh()
+	return seek, nil
+}
+
+// seekIterator iterates over the objects under a prefix whose key is at least
+// a bound. go-memdb can only look up prefixes, so the objects are looked up
+// level by level from the bound's full length: at each level, those under the
+// bound's prefix of that length whose next byte is greater than the bound's.
+type seekIterator struct {
+	txn     *memdb.Txn
+	table   string
+	index   string
+	args    []interface{}
+	watchCh <-chan struct{}
+
+	// arg converts a prefix of the last field's key to an argument of the
+	// index, and key returns the last field's key of an object.
+	arg func(prefix []byte) interface{}
+	key func(obj interface{}) ([]byte, error)
+
+	base  []byte
+	bound []byte
+
+	// level is the length of the bound's prefix being iterated over, and
+	// next is the next byte to look up at it or -1 while scanning the prefix.
+	level int
+	next  int
+	iter  memdb.ResultIterator
+	err   error
+}
+
+func (s *StateStore) newSeekIterator(txn *memdb.Txn, table, index string, args []interface{}, prefix, from string) (*seekIterator, error) {
+	tableSchema, ok := s.schema.Tables[table]
+	if !ok {
+		return nil, fmt.Errorf("invalid table %q", table)
+	}
+	indexSchema, ok := tableSchema.Indexes[index]
+	if !ok {
+		return nil, fmt.Errorf("invalid index %q", index)
+	}
+
+	// Seeks are done on the last field of compound indexes
+	indexer := indexSchema.Indexer
+	if compound, ok := indexer.(*memdb.CompoundIndex); ok {
+		if len(args) != len(compound.Indexes)-1 {
+			return nil, fmt.Errorf("index %q takes %d arguments before the prefix", index, len(compound.Indexes)-1)
+		}
+		indexer = compound.Indexes[len(args)]
+	}
+
+	it := &seekIterator{
+		txn:   txn,
+		table: table,
+		index: index,
+		args:  args,
+		key: func(obj interface{}) ([]byte, error) {
+			_, key, err := indexer.(memdb.SingleIndexer).FromObject(obj)
+			return key, err
+		},
+	}
+
+	var prefixer memdb.PrefixIndexer
+	switch idx := indexer.(type) {
+	case *memdb.UUIDFieldIndex:
+		prefixer = idx
+		it.arg = func(prefix []byte) interface{} { return prefix }
+	case *lowercaseStringFieldIndex:
+		prefixer = idx
+		it.arg = func(prefix []byte) interface{} { return prefix }
+	case *memdb.StringFieldIndex:
+		if idx.Lowercase {
+			return nil, fmt.Errorf("index %q can't be seeked", index)
+		}
+		prefixer = idx
+		it.arg = func(prefix []byte) interface{} { return string(prefix) }
+	default:
+		return nil, fmt.Errorf("index %q can't be seeked", index)
+	}
+
+	var err error
+	if it.base, err = prefixer.PrefixFromArgs(prefix); err != nil {
+		return nil, err
+	}
+	if it.bound, err = prefixer.PrefixFromArgs(from); err != nil {
+		return nil, fmt.Errorf("invalid token %q: %v", from, err)
+	}
+
+	switch {
+	case bytes.HasPrefix(it.bound, it.base):
+		it.level = len(it.bound)
+	case bytes.Compare(it.bound, it.base) < 0:
+		// The whole prefix is after the bound
+		it.bound = it.base
+		it.level = len(it.base)
+	default:
+		// The whole prefix is before the bound
+		it.level = -1
+		return it, nil
+	}
+	it.next = -1
+	if it.iter, err = it.lookup(it.bound[:it.level]); err != nil {
+		return nil, err
+	}
+	return it, nil
+}
+
+// lookup returns an iterator over the objects under the prefix of the last
+// field's key.
+func (it *seekIterator) lookup(prefix []byte) (memdb.ResultIterator, error) {
+	args := make([]interface{}, 0, len(it.args)+1)
+	args = append(args, it.args...)
+	args = append(args, it.arg(prefix))
+	return it.txn.Get(it.table, it.index+"_prefix", args...)
+}
+
+// WatchCh returns the watch channel of the whole prefix.
+func (it *seekIterator) WatchCh() <-chan struct{} {
+	return it.watchCh
+}
+
+// Next returns the next object or nil once they are exhausted. Lookup errors
+// end the iteration.
+func (it *seekIterator) Next() interface{} {
+	for it.err == nil && it.level >= len(it.base) && it.level >= 0 {
+		if obj := it.advance(); obj != nil {
+			return obj
+		}
+		if it.err != nil {
+			break
+		}
+
+		// Go up a level and scan the prefix for the greater next bytes
+		it.level--
+		if it.level < len(it.base) {
+			break
+		}
+		it.next = -1
+		it.iter, it.err = it.lookup(it.bound[:it.level])
+	}
+	return nil
+}
+
+// advance returns the next object of the current level or nil once it is
+// exhausted.
+func (it *seekIterator) advance() interface{} {
+	if it.level == len(it.bound) {
+		// All the objects under the bound itself are at least the bound
+		return it.iter.Next()
+	}
+
+	// Scan the prefix until an object's next byte is greater than the
+	// bound's, from which on all of them are
+	for skipped := 0; it.next < 0; skipped++ {
+		obj := it.iter.Next()
+		if obj == nil {
+			return nil
+		}
+		key, err := it.key(obj)
+		if err != nil {
+			it.err = err
+			return nil
+		}
+		if len(key) > it.level && key[it.level] > it.bound[it.level] {
+			it.next = 0x100
+			return obj
+		}
+		if skipped == seekScanLimit {
+			it.next = int(it.bound[it.level]) + 1
+			it.iter = nil
+		}
+	}
+
+	for {
+		if it.iter != nil {
+			if obj := it.iter.Next(); obj != nil {
+				return obj
+			}
+		}
+		if it.next > 0xff {
+			return nil
+		}
+
+		// Look up the objects under the next byte
+		prefix := append(append([]byte{}, it.bound[:it.level]...), byte(it.next))
+		it.next++
+		if it.iter, it.err = it.lookup(prefix); it.err != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package state
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// prefixFromIDs returns the IDs of the objects of the iterator.
+func prefixFromIDs(iter memdb.ResultIterator, idFn func(raw interface{}) string) []string {
+	var ids []string
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		ids = append(ids, idFn(raw))
+	}
+	return ids
+}
+
+// expectedFrom returns the IDs with the prefix that sort at or after from,
+// comparing the keys returned by keyFn.
+func expectedFrom(ids []string, prefix, from string, keyFn func(string) string) []string {
+	var out []string
+	for _, id := range ids {
+		if strings.HasPrefix(keyFn(id), keyFn(prefix)) && keyFn(id) >= keyFn(from) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func TestStateStore_PrefixFrom_UUID(t *testing.T) {
+	state := testStateStore(t)
+
+	// Enough evaluations for seeks to look up bytes rather than scan
+	var evals []*structs.Evaluation
+	var ids []string
+	for i := 0; i < 3*seekScanLimit; i++ {
+		eval := mock.Eval()
+		evals = append(evals, eval)
+		ids = append(ids, eval.ID)
+	}
+	if err := state.UpsertEvals(1000, evals); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sort.Strings(ids)
+
+	idFn := func(raw interface{}) string { return raw.(*structs.Evaluation).ID }
+	same := func(id string) string { return id }
+
+	cases := []struct {
+		Prefix string
+		From   string
+	}{
+		{"", ids[0]},
+		{"", ids[len(ids)/2]},
+		{"", ids[len(ids)-1]},
+		{ids[100][:2], ids[100]},
+		{ids[100][:2], "00000000-0000-0000-0000-000000000000"},
+		{ids[100][:2], "ffffffff-ffff-ffff-ffff-ffffffffffff"},
+		// Missing from the table
+		{"", "7fffffff-ffff-ffff-ffff-ffffffffffff"},
+	}
+	for _, tc := range cases {
+		iter, err := state.PrefixFrom(nil, "evals", "id", nil, tc.Prefix, tc.From)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := prefixFromIDs(iter, idFn)
+		expected := expectedFrom(ids, tc.Prefix, tc.From, same)
+		if !reflect.DeepEqual(out, expected) {
+			t.Fatalf("prefix %q from %q: got %d evals; want %d", tc.Prefix, tc.From, len(out), len(expected))
+		}
+	}
+
+	if _, err := state.PrefixFrom(nil, "evals", "id", nil, "", "foo"); err == nil {
+		t.Fatalf("expected an error for an invalid token")
+	}
+}
+
+func TestStateStore_PrefixFrom_Jobs(t *testing.T) {
+	state := testStateStore(t)
+
+	ids := []string{"Web", "web-2", "webapp", "zeta", "Ärger", "ünïcode", "ää"}
+	for i := 0; i < 3*seekScanLimit; i++ {
+		ids = append(ids, fmt.Sprintf("job-%04d", i))
+	}
+	for i, id := range ids {
+		job := mock.Job()
+		job.ID = id
+		if i%2 == 1 {
+			job.Namespace = "other"
+		}
+		if err := state.UpsertJob(uint64(1000+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Jobs are sorted case insensitively
+	lower := strings.ToLower
+	sort.Slice(ids, func(i, j int) bool { return lower(ids[i]) < lower(ids[j]) })
+	var defaultIDs []string
+	for _, id := range ids {
+		if job, _ := state.JobByID(nil, id); job.Namespace == structs.DefaultNamespace {
+			defaultIDs = append(defaultIDs, id)
+		}
+	}
+
+	idFn := func(raw interface{}) string { return raw.(*structs.Job).ID }
+	for _, from := range []string{"job-0300", "JOB-0300", "web", "webb", "Ä", "zzz", "a"} {
+		iter, err := state.PrefixFrom(nil, "jobs", "id", nil, "", from)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out := prefixFromIDs(iter, idFn)
+		if expected := expectedFrom(ids, "", from, lower); !reflect.DeepEqual(out, expected) {
+			t.Fatalf("from %q: got %v; want %v", from, out, expected)
+		}
+
+		iter, err = state.PrefixFrom(nil, "jobs", "namespace", []interface{}{structs.DefaultNamespace}, "", from)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		out = prefixFromIDs(iter, idFn)
+		if expected := expectedFrom(defaultIDs, "", from, lower); !reflect.DeepEqual(out, expected) {
+			t.Fatalf("namespace from %q: got %v; want %v", from, out, expected)
+		}
+	}
+}
@@ -28,6 +28,7 @@ type IndexEntry struct {
 type StateStore struct {
 	logger *log.Logger
 	db     *memdb.MemDB
+	schema *memdb.DBSchema
 
 	// abandonCh is used to signal watchers that this state store has been
 	// abandoned (usually during a restore). This is only ever closed.
@@ -37,7 +38,8 @@ type StateStore struct {
 // NewStateStore is used to create a new state store
 func NewStateStore(logOutput io.Writer) (*StateStore, error) {
 	// Create the MemDB
-	db, err := memdb.NewMemDB(stateStoreSchema())
+	schema := stateStoreSchema()
+	db, err := memdb.NewMemDB(schema)
 	if err != nil {
 		return nil, fmt.Errorf("state store setup failed: %v", err)
 	}
@@ -46,6 +48,7 @@ func NewStateStore(logOutput io.Writer) (*StateStore, error) {
 	s := &StateStore{
 		logger:    log.New(logOutput, "", log.LstdFlags),
 		db:        db,
+		schema:    schema,
 		abandonCh: make(chan struct{}),
 	}
 	return s, nil
@@ -59,6 +62,7 @@ func (s *StateStore) Snapshot() (*StateSnapshot, error) {
 		StateStore: StateStore{
 			logger: s.logger,
 			db:     s.db.Snapshot(),
+			schema: s.schema,
 		},
 	}
 	return snap, nil
@@ -217,10 +221,13 @@ func (s *StateStore) upsertDeploymentImpl(index uint64, deployment *structs.Depl
 		return fmt.Errorf("deployment lookup failed: %v", err)
 	}
 
-	// Setup the indexes correctly
+	// Setup the indexes correctly. The notified events are only updated by
+	// UpdateDeploymentNotified, so that an update planned before they were
+	// recorded doesn't notify them again.
 	if existing != nil {
 		deployment.CreateIndex = existing.(*structs.Deployment).CreateIndex
 		deployment.ModifyIndex = index
+		deployment.NotifiedEvents = existing.(*structs.Deployment).NotifiedEvents
 	} else {
 		deployment.CreateIndex = index
 		deployment.ModifyIndex = index
@@ -440,8 +447,9 @@ func (s *StateStore) DeleteNode(index uint64, nodeID string) error {
 	return nil
 }
 
-// UpdateNodeStatus is used to update the status of a node
-func (s *StateStore) UpdateNodeStatus(index uint64, nodeID, status string) error {
+// UpdateNodeStatus is used to update the status of a node. The time stamp of
+// the update is kept if it is set.
+func (s *StateStore) UpdateNodeStatus(index uint64, nodeID, status string, updatedAt int64) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
@@ -462,6 +470,9 @@ func (s *StateStore) UpdateNodeStatus(index uint64, nodeID, status string) error
 	// Update the status in the copy
 	copyNode.Status = status
 	copyNode.ModifyIndex = index
+	if updatedAt != 0 {
+		copyNode.StatusUpdatedAt = updatedAt
+	}
 
 	// Insert the node
 	if err := txn.Insert("nodes", copyNode); err != nil {
@@ -526,6 +537,22 @@ func (s *StateStore) NodeByID(ws memdb.WatchSet, nodeID string) (*structs.Node,
 	return nil, nil
 }
 
+// NodeBySecretID is used to lookup a node by its secret ID
+func (s *StateStore) NodeBySecretID(ws memdb.WatchSet, secretID string) (*structs.Node, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("nodes", "secret_id", secretID)
+	if err != nil {
+		return nil, fmt.Errorf("node lookup by secret ID failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.Node), nil
+	}
+	return nil, nil
+}
+
 // NodesByIDPrefix is used to lookup nodes by prefix
 func (s *StateStore) NodesByIDPrefix(ws memdb.WatchSet, nodeID string) (memdb.ResultIterator, error) {
 	txn := s.db.Txn(false)
@@ -565,6 +592,11 @@ func (s *StateStore) UpsertJob(index uint64, job *structs.Job) error {
 
 // upsertJobImpl is the implementation for registering a job or updating a job definition
 func (s *StateStore) upsertJobImpl(index uint64, job *structs.Job, keepVersion bool, txn *memdb.Txn) error {
+	// Jobs registered before namespaces belong to the default one
+	if job.Namespace == "" {
+		job.Namespace = structs.DefaultNamespace
+	}
+
 	// Check if the job already exists
 	existing, err := txn.First("jobs", "id", job.ID)
 	if err != nil {
@@ -829,6 +861,21 @@ func (s *StateStore) JobsByIDPrefix(ws memdb.WatchSet, id string) (memdb.ResultI
 	return iter, nil
 }
 
+// JobsByNamespace is used to lookup the jobs of a namespace by ID prefix,
+// all of them if the prefix is empty. The jobs are sorted by ID.
+func (s *StateStore) JobsByNamespace(ws memdb.WatchSet, namespace, prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("jobs", "namespace_prefix", namespace, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("job lookup failed: %v", err)
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
 // JobVersionsByID returns all the tracked versions of a job.
 func (s *StateStore) JobVersionsByID(ws memdb.WatchSet, id string) ([]*structs.Job, error) {
 	txn := s.db.Txn(false)
@@ -973,6 +1020,21 @@ func (s *StateStore) JobsByGC(ws memdb.WatchSet, gc bool) (memdb.ResultIterator,
 	return iter, nil
 }
 
+// JobsPendingNotification returns the jobs whose current run reached a
+// terminal state without its notification being sent.
+func (s *StateStore) JobsPendingNotification(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("jobs", "notify", true)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
 // JobSummary returns a job summary object which matches a specific id.
 func (s *StateStore) JobSummaryByID(ws memdb.WatchSet, jobID string) (*structs.JobSummary, error) {
 	txn := s.db.Txn(false)
@@ -1306,6 +1368,21 @@ func (s *StateStore) EvalsByIDPrefix(ws memdb.WatchSet, id string) (memdb.Result
 	return iter, nil
 }
 
+// EvalsHeld returns the evaluations held until the jobs their job depends on
+// complete.
+func (s *StateStore) EvalsHeld(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("evals", "held", true)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
 // EvalsByJob returns all the evaluations by job id
 func (s *StateStore) EvalsByJob(ws memdb.WatchSet, jobID string) ([]*structs.Evaluation, error) {
 	txn := s.db.Txn(false)
@@ -1399,6 +1476,17 @@ func (s *StateStore) nestedUpdateAllocFromClient(txn *memdb.Txn, index uint64, a
 	copyAlloc.ClientDescription = alloc.ClientDescription
 	copyAlloc.TaskStates = alloc.TaskStates
 	copyAlloc.DeploymentStatus = alloc.DeploymentStatus
+	copyAlloc.NetworkStatus = alloc.NetworkStatus
+
+	// The servers are the authority on whether the allocation is a canary
+	if exist.DeploymentStatus.IsCanary() != copyAlloc.DeploymentStatus.IsCanary() {
+		status := &structs.AllocDeploymentStatus{}
+		if copyAlloc.DeploymentStatus != nil {
+			*status = *copyAlloc.DeploymentStatus
+		}
+		status.Canary = exist.DeploymentStatus.IsCanary()
+		copyAlloc.DeploymentStatus = status
+	}
 
 	// Update the modify index
 	copyAlloc.ModifyIndex = index
@@ -1417,6 +1505,13 @@ func (s *StateStore) nestedUpdateAllocFromClient(txn *memdb.Txn, index uint64, a
 		return fmt.Errorf("alloc insert failed: %v", err)
 	}
 
+	// Remove the services of the allocation once it stopped running
+	if copyAlloc.Terminated() {
+		if err := s.deleteServiceRegistrationsByAllocTxn(txn, index, copyAlloc.ID); err != nil {
+			return err
+		}
+	}
+
 	// Set the job's status
 	forceStatus := ""
 	if !copyAlloc.TerminalStatus() {
@@ -1480,9 +1575,10 @@ func (s *StateStore) upsertAllocsImpl(index uint64, allocs []*structs.Allocation
 			// Keep the clients task states
 			alloc.TaskStates = exist.TaskStates
 
-			// If the scheduler is marking this allocation as lost we do not
-			// want to reuse the status of the existing allocation.
-			if alloc.ClientStatus != structs.AllocClientStatusLost {
+			// If the scheduler is marking this allocation as lost or unknown
+			// we do not want to reuse the status of the existing allocation.
+			if alloc.ClientStatus != structs.AllocClientStatusLost &&
+				alloc.ClientStatus != structs.AllocClientStatusUnknown {
 				alloc.ClientStatus = exist.ClientStatus
 				alloc.ClientDescription = exist.ClientDescription
 			}
@@ -1511,6 +1607,13 @@ func (s *StateStore) upsertAllocsImpl(index uint64, allocs []*structs.Allocation
 			return fmt.Errorf("alloc insert failed: %v", err)
 		}
 
+		// Remove the services of allocations marked as lost
+		if alloc.Terminated() {
+			if err := s.deleteServiceRegistrationsByAllocTxn(txn, index, alloc.ID); err != nil {
+				return err
+			}
+		}
+
 		// If the allocation is running, force the job to running status.
 		forceStatus := ""
 		if !alloc.TerminalStatus() {
@@ -1834,202 +1937,1578 @@ func (s *StateStore) VaultAccessorsByNode(ws memdb.WatchSet, nodeID string) ([]*
 	return out, nil
 }
 
-// UpdateDeploymentStatus is used to make deployment status updates and
-// potentially make a evaluation
-func (s *StateStore) UpdateDeploymentStatus(index uint64, req *structs.DeploymentStatusUpdateRequest) error {
+// UpsertSITokenAccessors is used to register a set of Service Identity token
+// accessors
+func (s *StateStore) UpsertSITokenAccessors(index uint64, accessors []*structs.SITokenAccessor) error {
 	txn := s.db.Txn(true)
 	defer txn.Abort()
 
-	if err := s.updateDeploymentStatusImpl(index, req.DeploymentUpdate, txn); err != nil {
-		return err
-	}
+	for _, accessor := range accessors {
+		// Set the create index
+		accessor.CreateIndex = index
 
-	// Upsert the job if necessary
-	if req.Job != nil {
-		if err := s.upsertJobImpl(index, req.Job, false, txn); err != nil {
-			return err
+		// Insert the accessor
+		if err := txn.Insert("si_token_accessors", accessor); err != nil {
+			return fmt.Errorf("accessor insert failed: %v", err)
 		}
 	}
 
-	// Upsert the optional eval
-	if req.Eval != nil {
-		if err := s.nestedUpsertEval(txn, index, req.Eval); err != nil {
-			return err
-		}
+	if err := txn.Insert("index", &IndexEntry{"si_token_accessors", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
 	}
 
 	txn.Commit()
 	return nil
 }
 
-// updateDeploymentStatusImpl is used to make deployment status updates
-func (s *StateStore) updateDeploymentStatusImpl(index uint64, u *structs.DeploymentStatusUpdate, txn *memdb.Txn) error {
-	// Retrieve deployment
-	ws := memdb.NewWatchSet()
-	deployment, err := s.deploymentByIDImpl(ws, u.DeploymentID, txn)
-	if err != nil {
-		return err
-	} else if deployment == nil {
-		return fmt.Errorf("Deployment ID %q couldn't be updated as it does not exist", u.DeploymentID)
-	} else if !deployment.Active() {
-		return fmt.Errorf("Deployment %q has terminal status %q:", deployment.ID, deployment.Status)
-	}
-
-	// Apply the new status
-	copy := deployment.Copy()
-	copy.Status = u.Status
-	copy.StatusDescription = u.StatusDescription
-	copy.ModifyIndex = index
-
-	// Insert the deployment
-	if err := txn.Insert("deployment", copy); err != nil {
-		return err
-	}
-
-	// Update the index
-	if err := txn.Insert("index", &IndexEntry{"deployment", index}); err != nil {
-		return fmt.Errorf("index update failed: %v", err)
-	}
+// DeleteSITokenAccessors is used to delete a set of Service Identity token
+// accessors
+func (s *StateStore) DeleteSITokenAccessors(index uint64, accessors []*structs.SITokenAccessor) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
 
-	// If the deployment is being marked as complete, set the job to stable.
-	if copy.Status == structs.DeploymentStatusSuccessful {
-		if err := s.updateJobStabilityImpl(index, copy.JobID, copy.JobVersion, true, txn); err != nil {
-			return fmt.Errorf("failed to update job stability: %v", err)
+	for _, accessor := range accessors {
+		// Delete the accessor
+		if err := txn.Delete("si_token_accessors", accessor); err != nil {
+			return fmt.Errorf("accessor delete failed: %v", err)
 		}
 	}
 
-	return nil
-}
-
-// UpdateJobStability updates the stability of the given job and version to the
-// desired status.
-func (s *StateStore) UpdateJobStability(index uint64, jobID string, jobVersion uint64, stable bool) error {
-	txn := s.db.Txn(true)
-	defer txn.Abort()
-
-	if err := s.updateJobStabilityImpl(index, jobID, jobVersion, stable, txn); err != nil {
-		return err
+	if err := txn.Insert("index", &IndexEntry{"si_token_accessors", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
 	}
 
 	txn.Commit()
 	return nil
 }
 
-// updateJobStabilityImpl updates the stability of the given job and version
-func (s *StateStore) updateJobStabilityImpl(index uint64, jobID string, jobVersion uint64, stable bool, txn *memdb.Txn) error {
-	// Get the job that is referenced
-	job, err := s.jobByIDAndVersionImpl(nil, jobID, jobVersion, txn)
+// SITokenAccessor returns the given Service Identity token accessor
+func (s *StateStore) SITokenAccessor(ws memdb.WatchSet, accessorID string) (*structs.SITokenAccessor, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("si_token_accessors", "id", accessorID)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("accessor lookup failed: %v", err)
 	}
 
-	// Has already been cleared, nothing to do
-	if job == nil {
-		return nil
-	}
+	ws.Add(watchCh)
 
-	// If the job already has the desired stability, nothing to do
-	if job.Stable == stable {
-		return nil
+	if existing != nil {
+		return existing.(*structs.SITokenAccessor), nil
 	}
 
-	copy := job.Copy()
-	copy.Stable = stable
-	return s.upsertJobImpl(index, copy, true, txn)
+	return nil, nil
 }
 
-// UpdateDeploymentPromotion is used to promote canaries in a deployment and
-// potentially make a evaluation
-func (s *StateStore) UpdateDeploymentPromotion(index uint64, req *structs.ApplyDeploymentPromoteRequest) error {
-	txn := s.db.Txn(true)
-	defer txn.Abort()
+// SITokenAccessors returns an iterator of Service Identity token accessors.
+func (s *StateStore) SITokenAccessors(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
 
-	// Retrieve deployment and ensure it is not terminal and is active
-	ws := memdb.NewWatchSet()
-	deployment, err := s.deploymentByIDImpl(ws, req.DeploymentID, txn)
+	iter, err := txn.Get("si_token_accessors", "id")
 	if err != nil {
-		return err
-	} else if deployment == nil {
-		return fmt.Errorf("Deployment ID %q couldn't be updated as it does not exist", req.DeploymentID)
-	} else if !deployment.Active() {
-		return fmt.Errorf("Deployment %q has terminal status %q:", deployment.ID, deployment.Status)
+		return nil, err
 	}
 
-	// Retrieve effected allocations
-	iter, err := txn.Get("allocs", "deployment", req.DeploymentID)
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
+// SITokenAccessorsByAlloc returns all the Service Identity token accessors by
+// alloc id
+func (s *StateStore) SITokenAccessorsByAlloc(ws memdb.WatchSet, allocID string) ([]*structs.SITokenAccessor, error) {
+	txn := s.db.Txn(false)
+
+	// Get an iterator over the accessors
+	iter, err := txn.Get("si_token_accessors", "alloc_id", allocID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	groupIndex := make(map[string]struct{}, len(req.Groups))
-	for _, g := range req.Groups {
-		groupIndex[g] = struct{}{}
-	}
+	ws.Add(iter.WatchCh())
 
-	canaryIndex := make(map[string]struct{}, len(deployment.TaskGroups))
-	for _, state := range deployment.TaskGroups {
-		for _, c := range state.PlacedCanaries {
-			canaryIndex[c] = struct{}{}
+	var out []*structs.SITokenAccessor
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
 		}
+		out = append(out, raw.(*structs.SITokenAccessor))
+	}
+	return out, nil
+}
+
+// SITokenAccessorsByNode returns all the Service Identity token accessors by
+// node id
+func (s *StateStore) SITokenAccessorsByNode(ws memdb.WatchSet, nodeID string) ([]*structs.SITokenAccessor, error) {
+	txn := s.db.Txn(false)
+
+	// Get an iterator over the accessors
+	iter, err := txn.Get("si_token_accessors", "node_id", nodeID)
+	if err != nil {
+		return nil, err
 	}
 
-	haveCanaries := false
-	var unhealthyErr multierror.Error
+	ws.Add(iter.WatchCh())
+
+	var out []*structs.SITokenAccessor
 	for {
 		raw := iter.Next()
 		if raw == nil {
 			break
 		}
+		out = append(out, raw.(*structs.SITokenAccessor))
+	}
+	return out, nil
+}
 
-		alloc := raw.(*structs.Allocation)
+// UpsertServiceRegistrations is used to register or update a set of service
+// registrations
+func (s *StateStore) UpsertServiceRegistrations(index uint64, services []*structs.ServiceRegistration) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
 
-		// Check that the alloc is a canary
-		if _, ok := canaryIndex[alloc.ID]; !ok {
-			continue
+	for _, service := range services {
+		// Check if the registration already exists
+		existing, err := txn.First("service_registrations", "id", service.ID)
+		if err != nil {
+			return fmt.Errorf("service registration lookup failed: %v", err)
 		}
 
-		// Check that the canary is part of a group being promoted
-		if _, ok := groupIndex[alloc.TaskGroup]; !req.All && !ok {
-			continue
+		// Setup the indexes correctly
+		if existing != nil {
+			service.CreateIndex = existing.(*structs.ServiceRegistration).CreateIndex
+			service.ModifyIndex = index
+		} else {
+			service.CreateIndex = index
+			service.ModifyIndex = index
 		}
 
-		// Ensure the canaries are healthy
-		if !alloc.DeploymentStatus.IsHealthy() {
-			multierror.Append(&unhealthyErr, fmt.Errorf("Canary allocation %q for group %q is not healthy", alloc.ID, alloc.TaskGroup))
-			continue
+		// Insert the registration
+		if err := txn.Insert("service_registrations", service); err != nil {
+			return fmt.Errorf("service registration insert failed: %v", err)
 		}
-
-		haveCanaries = true
 	}
 
-	if err := unhealthyErr.ErrorOrNil(); err != nil {
-		return err
+	// Update the indexes table for service registrations
+	if err := txn.Insert("index", &IndexEntry{"service_registrations", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
 	}
 
-	if !haveCanaries {
-		return fmt.Errorf("no canaries to promote")
-	}
+	txn.Commit()
+	return nil
+}
 
-	// Update deployment
-	copy := deployment.Copy()
-	copy.ModifyIndex = index
-	for tg, status := range copy.TaskGroups {
-		_, ok := groupIndex[tg]
-		if !req.All && !ok {
-			continue
-		}
+// DeleteServiceRegistrationsByID is used to delete a set of service
+// registrations by ID. Unknown registrations are ignored as they may have
+// been removed along with their allocation.
+func (s *StateStore) DeleteServiceRegistrationsByID(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
 
-		status.Promoted = true
+	for _, id := range ids {
+		if _, err := txn.DeleteAll("service_registrations", "id", id); err != nil {
+			return fmt.Errorf("service registration delete failed: %v", err)
+		}
 	}
 
-	// If the deployment no longer needs promotion, update its status
-	if !copy.RequiresPromotion() && copy.Status == structs.DeploymentStatusRunning {
-		copy.StatusDescription = structs.DeploymentStatusDescriptionRunning
+	if err := txn.Insert("index", &IndexEntry{"service_registrations", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
 	}
 
-	// Insert the deployment
-	if err := s.upsertDeploymentImpl(index, copy, txn); err != nil {
-		return err
+	txn.Commit()
+	return nil
+}
+
+// deleteServiceRegistrationsByAllocTxn deletes the service registrations of
+// the allocation within the given transaction.
+func (s *StateStore) deleteServiceRegistrationsByAllocTxn(txn *memdb.Txn, index uint64, allocID string) error {
+	num, err := txn.DeleteAll("service_registrations", "alloc_id", allocID)
+	if err != nil {
+		return fmt.Errorf("service registration delete failed: %v", err)
+	}
+	if num == 0 {
+		return nil
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"service_registrations", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	return nil
+}
+
+// ServiceRegistrations returns an iterator over all the service
+// registrations
+func (s *StateStore) ServiceRegistrations(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Walk the entire service registrations table
+	iter, err := txn.Get("service_registrations", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// ServiceRegistrationByID is used to lookup a service registration by ID
+func (s *StateStore) ServiceRegistrationByID(ws memdb.WatchSet, id string) (*structs.ServiceRegistration, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("service_registrations", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("service registration lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ServiceRegistration), nil
+	}
+	return nil, nil
+}
+
+// ServiceRegistrationsByName returns all the registrations of the service
+// with the given name
+func (s *StateStore) ServiceRegistrationsByName(ws memdb.WatchSet, name string) ([]*structs.ServiceRegistration, error) {
+	return s.serviceRegistrationsBy(ws, "service_name", name)
+}
+
+// ServiceRegistrationsByAllocID returns all the service registrations of an
+// allocation
+func (s *StateStore) ServiceRegistrationsByAllocID(ws memdb.WatchSet, allocID string) ([]*structs.ServiceRegistration, error) {
+	return s.serviceRegistrationsBy(ws, "alloc_id", allocID)
+}
+
+// ServiceRegistrationsByNodeID returns all the service registrations of the
+// allocations running on a node
+func (s *StateStore) ServiceRegistrationsByNodeID(ws memdb.WatchSet, nodeID string) ([]*structs.ServiceRegistration, error) {
+	return s.serviceRegistrationsBy(ws, "node_id", nodeID)
+}
+
+// serviceRegistrationsBy returns the service registrations matching the value
+// of the given index.
+func (s *StateStore) serviceRegistrationsBy(ws memdb.WatchSet, index, value string) ([]*structs.ServiceRegistration, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("service_registrations", index, value)
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+
+	var out []*structs.ServiceRegistration
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+		out = append(out, raw.(*structs.ServiceRegistration))
+	}
+	return out, nil
+}
+
+// UpsertNamespaces is used to register or update a set of namespaces
+func (s *StateStore) UpsertNamespaces(index uint64, namespaces []*structs.Namespace) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, ns := range namespaces {
+		// Check if the namespace already exists
+		existing, err := txn.First("namespaces", "id", ns.Name)
+		if err != nil {
+			return fmt.Errorf("namespace lookup failed: %v", err)
+		}
+
+		// Setup the indexes correctly
+		if existing != nil {
+			ns.CreateIndex = existing.(*structs.Namespace).CreateIndex
+			ns.ModifyIndex = index
+		} else {
+			ns.CreateIndex = index
+			ns.ModifyIndex = index
+		}
+
+		// Insert the namespace
+		if err := txn.Insert("namespaces", ns); err != nil {
+			return fmt.Errorf("namespace insert failed: %v", err)
+		}
+	}
+
+	// Update the indexes table for namespaces
+	if err := txn.Insert("index", &IndexEntry{"namespaces", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// DeleteNamespaces is used to delete a set of namespaces by name
+func (s *StateStore) DeleteNamespaces(index uint64, names []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	for _, name := range names {
+		// Lookup the namespace
+		existing, err := txn.First("namespaces", "id", name)
+		if err != nil {
+			return fmt.Errorf("namespace lookup failed: %v", err)
+		}
+		if existing == nil {
+			return fmt.Errorf("namespace %q not found", name)
+		}
+
+		// Delete the namespace
+		if err := txn.Delete("namespaces", existing); err != nil {
+			return fmt.Errorf("namespace delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"namespaces", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// NamespaceByName is used to lookup a namespace by name
+func (s *StateStore) NamespaceByName(ws memdb.WatchSet, name string) (*structs.Namespace, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("namespaces", "id", name)
+	if err != nil {
+		return nil, fmt.Errorf("namespace lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.Namespace), nil
+	}
+	return nil, nil
+}
+
+// NamespacesByNamePrefix is used to lookup namespaces by name prefix
+func (s *StateStore) NamespacesByNamePrefix(ws memdb.WatchSet, prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("namespaces", "id_prefix", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("namespace lookup failed: %v", err)
+	}
+
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// Namespaces returns an iterator over all the namespaces
+func (s *StateStore) Namespaces(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Walk the entire namespaces table
+	iter, err := txn.Get("namespaces", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// SchedulerConfig returns the scheduler configuration along with the index
+// it was last modified at. The configuration is nil if none was set.
+func (s *StateStore) SchedulerConfig() (uint64, *structs.SchedulerConfiguration, error) {
+	txn := s.db.Txn(false)
+	defer txn.Abort()
+
+	// Get the scheduler config
+	c, err := txn.First("scheduler_config", "id")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed scheduler config lookup: %v", err)
+	}
+
+	config, ok := c.(*structs.SchedulerConfiguration)
+	if !ok {
+		return 0, nil, nil
+	}
+
+	return config.ModifyIndex, config, nil
+}
+
+// SchedulerSetConfig is used to set the scheduler configuration
+func (s *StateStore) SchedulerSetConfig(index uint64, config *structs.SchedulerConfiguration) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	if err := s.schedulerSetConfigTxn(index, txn, config); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// SchedulerCASConfig is used to update the scheduler configuration with a
+// given Raft index. If the CAS index specified is not equal to the last
+// observed index for the config, then the call is a noop.
+func (s *StateStore) SchedulerCASConfig(index, cidx uint64, config *structs.SchedulerConfiguration) (bool, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	// Check for an existing config
+	existing, err := txn.First("scheduler_config", "id")
+	if err != nil {
+		return false, fmt.Errorf("failed scheduler config lookup: %v", err)
+	}
+
+	// If the existing index does not match the provided CAS
+	// index arg, then we shouldn't update anything and can safely
+	// return early here. An index of zero matches an unset config.
+	var existingIndex uint64
+	if existing != nil {
+		existingIndex = existing.(*structs.SchedulerConfiguration).ModifyIndex
+	}
+	if existingIndex != cidx {
+		return false, nil
+	}
+
+	if err := s.schedulerSetConfigTxn(index, txn, config); err != nil {
+		return false, err
+	}
+
+	txn.Commit()
+	return true, nil
+}
+
+func (s *StateStore) schedulerSetConfigTxn(index uint64, txn *memdb.Txn, config *structs.SchedulerConfiguration) error {
+	// Check for an existing config
+	existing, err := txn.First("scheduler_config", "id")
+	if err != nil {
+		return fmt.Errorf("failed scheduler config lookup: %v", err)
+	}
+
+	// Set the indexes
+	if existing != nil {
+		config.CreateIndex = existing.(*structs.SchedulerConfiguration).CreateIndex
+	} else {
+		config.CreateIndex = index
+	}
+	config.ModifyIndex = index
+
+	if err := txn.Insert("scheduler_config", config); err != nil {
+		return fmt.Errorf("failed updating scheduler config: %v", err)
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"scheduler_config", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	return nil
+}
+
+// UpsertVariable is used to create or update an encrypted variable. A
+// check-and-set update only applies if the ModifyIndex of the variable is the
+// one of the stored variable, or zero if it must not exist. It returns
+// whether the variable was written and, if not, the stored variable.
+func (s *StateStore) UpsertVariable(index uint64, v *structs.VariableEncrypted, cas bool) (bool, *structs.VariableEncrypted, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	// Check if the variable already exists
+	raw, err := txn.First("variables", "id", v.Namespace, v.Path)
+	if err != nil {
+		return false, nil, fmt.Errorf("variable lookup failed: %v", err)
+	}
+	var existing *structs.VariableEncrypted
+	if raw != nil {
+		existing = raw.(*structs.VariableEncrypted)
+	}
+
+	if cas {
+		var existingIndex uint64
+		if existing != nil {
+			existingIndex = existing.ModifyIndex
+		}
+		if existingIndex != v.ModifyIndex {
+			return false, existing, nil
+		}
+	}
+
+	// Setup the indexes correctly
+	if existing != nil {
+		v.CreateIndex = existing.CreateIndex
+	} else {
+		v.CreateIndex = index
+	}
+	v.ModifyIndex = index
+
+	// Insert the variable
+	if err := txn.Insert("variables", v); err != nil {
+		return false, nil, fmt.Errorf("variable insert failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"variables", index}); err != nil {
+		return false, nil, fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return true, nil, nil
+}
+
+// DeleteVariable is used to delete a variable. A check-and-set delete only
+// applies if checkIndex is the ModifyIndex of the stored variable. It returns
+// whether the variable was deleted and, if not, the stored variable.
+// Deleting a missing variable is a no-op.
+func (s *StateStore) DeleteVariable(index uint64, namespace, path string, cas bool, checkIndex uint64) (bool, *structs.VariableEncrypted, error) {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	// Lookup the variable
+	raw, err := txn.First("variables", "id", namespace, path)
+	if err != nil {
+		return false, nil, fmt.Errorf("variable lookup failed: %v", err)
+	}
+	if raw == nil {
+		return !cas || checkIndex == 0, nil, nil
+	}
+	existing := raw.(*structs.VariableEncrypted)
+	if cas && existing.ModifyIndex != checkIndex {
+		return false, existing, nil
+	}
+
+	// Delete the variable
+	if err := txn.Delete("variables", existing); err != nil {
+		return false, nil, fmt.Errorf("variable delete failed: %v", err)
+	}
+	if err := txn.Insert("index", &IndexEntry{"variables", index}); err != nil {
+		return false, nil, fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return true, nil, nil
+}
+
+// VariableByPath is used to lookup an encrypted variable by its namespace and
+// path
+func (s *StateStore) VariableByPath(ws memdb.WatchSet, namespace, path string) (*structs.VariableEncrypted, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("variables", "id", namespace, path)
+	if err != nil {
+		return nil, fmt.Errorf("variable lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.VariableEncrypted), nil
+	}
+	return nil, nil
+}
+
+// VariablesByPathPrefix is used to lookup the variables of a namespace by
+// path prefix
+func (s *StateStore) VariablesByPathPrefix(ws memdb.WatchSet, namespace, prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("variables", "id_prefix", namespace, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("variable lookup failed: %v", err)
+	}
+
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// Variables returns an iterator over all the variables
+func (s *StateStore) Variables(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Walk the entire variables table
+	iter, err := txn.Get("variables", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// UpsertACLPolicies is used to create or update a set of ACL policies
+func (s *StateStore) UpsertACLPolicies(index uint64, policies []*structs.ACLPolicy) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, policy := range policies {
+		// Ensure the policy hash is set
+		if policy.Hash == nil {
+			policy.SetHash()
+		}
+
+		// Check if the policy already exists
+		existing, err := txn.First("acl_policy", "id", policy.Name)
+		if err != nil {
+			return fmt.Errorf("policy lookup failed: %v", err)
+		}
+
+		// Setup the indexes correctly
+		if existing != nil {
+			policy.CreateIndex = existing.(*structs.ACLPolicy).CreateIndex
+			policy.ModifyIndex = index
+		} else {
+			policy.CreateIndex = index
+			policy.ModifyIndex = index
+		}
+
+		// Insert the policy
+		if err := txn.Insert("acl_policy", policy); err != nil {
+			return fmt.Errorf("policy insert failed: %v", err)
+		}
+	}
+
+	// Update the indexes table for policies
+	if err := txn.Insert("index", &IndexEntry{"acl_policy", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// DeleteACLPolicies is used to delete a set of ACL policies by name
+func (s *StateStore) DeleteACLPolicies(index uint64, names []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, name := range names {
+		if _, err := txn.DeleteAll("acl_policy", "id", name); err != nil {
+			return fmt.Errorf("policy delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"acl_policy", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// ACLPolicyByName is used to lookup an ACL policy by name
+func (s *StateStore) ACLPolicyByName(ws memdb.WatchSet, name string) (*structs.ACLPolicy, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("acl_policy", "id", name)
+	if err != nil {
+		return nil, fmt.Errorf("policy lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ACLPolicy), nil
+	}
+	return nil, nil
+}
+
+// ACLPolicyByNamePrefix is used to lookup ACL policies by name prefix
+func (s *StateStore) ACLPolicyByNamePrefix(ws memdb.WatchSet, prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_policy", "id_prefix", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("policy lookup failed: %v", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
+// ACLPolicies returns an iterator over all the ACL policies
+func (s *StateStore) ACLPolicies(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Walk the entire table
+	iter, err := txn.Get("acl_policy", "id")
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// UpsertACLRoles is used to create or update a set of ACL roles
+func (s *StateStore) UpsertACLRoles(index uint64, roles []*structs.ACLRole) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, role := range roles {
+		// Ensure the role hash is set
+		if role.Hash == nil {
+			role.SetHash()
+		}
+
+		// Check if the role already exists
+		existing, err := txn.First("acl_role", "id", role.Name)
+		if err != nil {
+			return fmt.Errorf("role lookup failed: %v", err)
+		}
+
+		// Setup the indexes correctly
+		if existing != nil {
+			role.CreateIndex = existing.(*structs.ACLRole).CreateIndex
+			role.ModifyIndex = index
+		} else {
+			role.CreateIndex = index
+			role.ModifyIndex = index
+		}
+
+		// Insert the role
+		if err := txn.Insert("acl_role", role); err != nil {
+			return fmt.Errorf("role insert failed: %v", err)
+		}
+	}
+
+	// Update the indexes table for roles
+	if err := txn.Insert("index", &IndexEntry{"acl_role", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// DeleteACLRoles is used to delete a set of ACL roles by name
+func (s *StateStore) DeleteACLRoles(index uint64, names []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, name := range names {
+		if _, err := txn.DeleteAll("acl_role", "id", name); err != nil {
+			return fmt.Errorf("role delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"acl_role", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// ACLRoleByName is used to lookup an ACL role by name
+func (s *StateStore) ACLRoleByName(ws memdb.WatchSet, name string) (*structs.ACLRole, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("acl_role", "id", name)
+	if err != nil {
+		return nil, fmt.Errorf("role lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ACLRole), nil
+	}
+	return nil, nil
+}
+
+// ACLRoles returns an iterator over all the ACL roles
+func (s *StateStore) ACLRoles(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Walk the entire table
+	iter, err := txn.Get("acl_role", "id")
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// UpsertACLAuthMethods is used to create or update a set of ACL auth methods.
+// Setting a default auth method unsets the previous default.
+func (s *StateStore) UpsertACLAuthMethods(index uint64, methods []*structs.ACLAuthMethod) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, method := range methods {
+		// Ensure the auth method hash is set
+		if method.Hash == nil {
+			method.SetHash()
+		}
+
+		// Check if the auth method already exists
+		existing, err := txn.First("acl_auth_method", "id", method.Name)
+		if err != nil {
+			return fmt.Errorf("auth method lookup failed: %v", err)
+		}
+
+		// Setup the indexes correctly
+		if existing != nil {
+			method.CreateIndex = existing.(*structs.ACLAuthMethod).CreateIndex
+			method.ModifyIndex = index
+		} else {
+			method.CreateIndex = index
+			method.ModifyIndex = index
+		}
+
+		if method.Default {
+			if err := unsetDefaultACLAuthMethod(txn, index, method.Name); err != nil {
+				return err
+			}
+		}
+
+		// Insert the auth method
+		if err := txn.Insert("acl_auth_method", method); err != nil {
+			return fmt.Errorf("auth method insert failed: %v", err)
+		}
+	}
+
+	// Update the indexes table for auth methods
+	if err := txn.Insert("index", &IndexEntry{"acl_auth_method", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// unsetDefaultACLAuthMethod unsets the default flag of the default auth
+// method, unless it is the named one
+func unsetDefaultACLAuthMethod(txn *memdb.Txn, index uint64, name string) error {
+	iter, err := txn.Get("acl_auth_method", "id")
+	if err != nil {
+		return fmt.Errorf("auth method lookup failed: %v", err)
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		method := raw.(*structs.ACLAuthMethod)
+		if !method.Default || method.Name == name {
+			continue
+		}
+		updated := method.Copy()
+		updated.Default = false
+		updated.SetHash()
+		updated.ModifyIndex = index
+		if err := txn.Insert("acl_auth_method", updated); err != nil {
+			return fmt.Errorf("auth method insert failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// DeleteACLAuthMethods is used to delete a set of ACL auth methods by name,
+// along with their binding rules
+func (s *StateStore) DeleteACLAuthMethods(index uint64, names []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	deletedRules := 0
+	for _, name := range names {
+		if _, err := txn.DeleteAll("acl_auth_method", "id", name); err != nil {
+			return fmt.Errorf("auth method delete failed: %v", err)
+		}
+		n, err := txn.DeleteAll("acl_binding_rule", "auth_method", name)
+		if err != nil {
+			return fmt.Errorf("binding rule delete failed: %v", err)
+		}
+		deletedRules += n
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"acl_auth_method", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	if deletedRules != 0 {
+		if err := txn.Insert("index", &IndexEntry{"acl_binding_rule", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// ACLAuthMethodByName is used to lookup an ACL auth method by name
+func (s *StateStore) ACLAuthMethodByName(ws memdb.WatchSet, name string) (*structs.ACLAuthMethod, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("acl_auth_method", "id", name)
+	if err != nil {
+		return nil, fmt.Errorf("auth method lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ACLAuthMethod), nil
+	}
+	return nil, nil
+}
+
+// DefaultACLAuthMethod returns the default ACL auth method, if there is one
+func (s *StateStore) DefaultACLAuthMethod(ws memdb.WatchSet) (*structs.ACLAuthMethod, error) {
+	iter, err := s.ACLAuthMethods(ws)
+	if err != nil {
+		return nil, err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		if method := raw.(*structs.ACLAuthMethod); method.Default {
+			return method, nil
+		}
+	}
+	return nil, nil
+}
+
+// ACLAuthMethods returns an iterator over all the ACL auth methods
+func (s *StateStore) ACLAuthMethods(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Walk the entire table
+	iter, err := txn.Get("acl_auth_method", "id")
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// UpsertACLBindingRules is used to create or update a set of ACL binding rules
+func (s *StateStore) UpsertACLBindingRules(index uint64, rules []*structs.ACLBindingRule) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, rule := range rules {
+		// Ensure the binding rule hash is set
+		if rule.Hash == nil {
+			rule.SetHash()
+		}
+
+		// Check if the binding rule already exists
+		existing, err := txn.First("acl_binding_rule", "id", rule.ID)
+		if err != nil {
+			return fmt.Errorf("binding rule lookup failed: %v", err)
+		}
+
+		// Setup the indexes correctly
+		if existing != nil {
+			rule.CreateIndex = existing.(*structs.ACLBindingRule).CreateIndex
+			rule.ModifyIndex = index
+		} else {
+			rule.CreateIndex = index
+			rule.ModifyIndex = index
+		}
+
+		// Insert the binding rule
+		if err := txn.Insert("acl_binding_rule", rule); err != nil {
+			return fmt.Errorf("binding rule insert failed: %v", err)
+		}
+	}
+
+	// Update the indexes table for binding rules
+	if err := txn.Insert("index", &IndexEntry{"acl_binding_rule", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// DeleteACLBindingRules is used to delete a set of ACL binding rules by ID
+func (s *StateStore) DeleteACLBindingRules(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, id := range ids {
+		if _, err := txn.DeleteAll("acl_binding_rule", "id", id); err != nil {
+			return fmt.Errorf("binding rule delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"acl_binding_rule", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// ACLBindingRuleByID is used to lookup an ACL binding rule by ID
+func (s *StateStore) ACLBindingRuleByID(ws memdb.WatchSet, id string) (*structs.ACLBindingRule, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("acl_binding_rule", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("binding rule lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ACLBindingRule), nil
+	}
+	return nil, nil
+}
+
+// ACLBindingRulesByAuthMethod returns an iterator over the ACL binding rules
+// of an auth method
+func (s *StateStore) ACLBindingRulesByAuthMethod(ws memdb.WatchSet, method string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_binding_rule", "auth_method", method)
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// ACLBindingRules returns an iterator over all the ACL binding rules
+func (s *StateStore) ACLBindingRules(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Walk the entire table
+	iter, err := txn.Get("acl_binding_rule", "id")
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// UpsertACLTokens is used to create or update a set of ACL tokens
+func (s *StateStore) UpsertACLTokens(index uint64, tokens []*structs.ACLToken) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	if err := s.upsertACLTokensTxn(index, tokens, txn); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// upsertACLTokensTxn is used to create or update a set of ACL tokens within
+// a transaction
+func (s *StateStore) upsertACLTokensTxn(index uint64, tokens []*structs.ACLToken, txn *memdb.Txn) error {
+	for _, token := range tokens {
+		// Ensure the token hash is set
+		if token.Hash == nil {
+			token.SetHash()
+		}
+
+		// Check if the token already exists
+		existing, err := txn.First("acl_token", "id", token.AccessorID)
+		if err != nil {
+			return fmt.Errorf("token lookup failed: %v", err)
+		}
+
+		// Setup the indexes correctly
+		if existing != nil {
+			token.CreateIndex = existing.(*structs.ACLToken).CreateIndex
+			token.ModifyIndex = index
+		} else {
+			token.CreateIndex = index
+			token.ModifyIndex = index
+		}
+
+		// Insert the token
+		if err := txn.Insert("acl_token", token); err != nil {
+			return fmt.Errorf("token insert failed: %v", err)
+		}
+	}
+
+	// Update the indexes table for tokens
+	if err := txn.Insert("index", &IndexEntry{"acl_token", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+	return nil
+}
+
+// DeleteACLTokens is used to delete a set of ACL tokens by accessor ID
+func (s *StateStore) DeleteACLTokens(index uint64, ids []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	for _, id := range ids {
+		if _, err := txn.DeleteAll("acl_token", "id", id); err != nil {
+			return fmt.Errorf("token delete failed: %v", err)
+		}
+	}
+
+	if err := txn.Insert("index", &IndexEntry{"acl_token", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// ACLTokenByAccessorID is used to lookup an ACL token by accessor ID
+func (s *StateStore) ACLTokenByAccessorID(ws memdb.WatchSet, id string) (*structs.ACLToken, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("acl_token", "id", id)
+	if err != nil {
+		return nil, fmt.Errorf("token lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ACLToken), nil
+	}
+	return nil, nil
+}
+
+// ACLTokenBySecretID is used to lookup an ACL token by secret ID
+func (s *StateStore) ACLTokenBySecretID(ws memdb.WatchSet, secretID string) (*structs.ACLToken, error) {
+	txn := s.db.Txn(false)
+
+	watchCh, existing, err := txn.FirstWatch("acl_token", "secret", secretID)
+	if err != nil {
+		return nil, fmt.Errorf("token lookup failed: %v", err)
+	}
+	ws.Add(watchCh)
+
+	if existing != nil {
+		return existing.(*structs.ACLToken), nil
+	}
+	return nil, nil
+}
+
+// ACLTokenByAccessorIDPrefix is used to lookup ACL tokens by accessor ID
+// prefix
+func (s *StateStore) ACLTokenByAccessorIDPrefix(ws memdb.WatchSet, prefix string) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_token", "id_prefix", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("token lookup failed: %v", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	return iter, nil
+}
+
+// ACLTokens returns an iterator over all the ACL tokens
+func (s *StateStore) ACLTokens(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	// Walk the entire table
+	iter, err := txn.Get("acl_token", "id")
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// ACLTokensByGlobal returns an iterator over the global or local ACL tokens
+func (s *StateStore) ACLTokensByGlobal(ws memdb.WatchSet, globalVal bool) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_token", "global", globalVal)
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// ACLTokensByExpiring returns an iterator over the ACL tokens that have an
+// expiration time
+func (s *StateStore) ACLTokensByExpiring(ws memdb.WatchSet) (memdb.ResultIterator, error) {
+	txn := s.db.Txn(false)
+
+	iter, err := txn.Get("acl_token", "expiring", true)
+	if err != nil {
+		return nil, err
+	}
+	ws.Add(iter.WatchCh())
+	return iter, nil
+}
+
+// CanBootstrapACLToken returns whether the initial management token can be
+// created, which is only the case once
+func (s *StateStore) CanBootstrapACLToken() (bool, error) {
+	txn := s.db.Txn(false)
+
+	existing, err := txn.First("index", "id", "acl_token_bootstrap")
+	if err != nil {
+		return false, fmt.Errorf("bootstrap check failed: %v", err)
+	}
+	return existing == nil, nil
+}
+
+// BootstrapACLTokens is used to create the initial management token. It
+// fails if the ACL system was already bootstrapped.
+func (s *StateStore) BootstrapACLTokens(index uint64, token *structs.ACLToken) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("index", "id", "acl_token_bootstrap")
+	if err != nil {
+		return fmt.Errorf("bootstrap check failed: %v", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("ACL bootstrap already done")
+	}
+
+	if err := s.upsertACLTokensTxn(index, []*structs.ACLToken{token}, txn); err != nil {
+		return err
+	}
+	if err := txn.Insert("index", &IndexEntry{"acl_token_bootstrap", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// UpdateDeploymentStatus is used to make deployment status updates and
+// potentially make a evaluation
+func (s *StateStore) UpdateDeploymentStatus(index uint64, req *structs.DeploymentStatusUpdateRequest) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	if err := s.updateDeploymentStatusImpl(index, req.DeploymentUpdate, txn); err != nil {
+		return err
+	}
+
+	// Upsert the job if necessary
+	if req.Job != nil {
+		if err := s.upsertJobImpl(index, req.Job, false, txn); err != nil {
+			return err
+		}
+	}
+
+	// Upsert the optional eval
+	if req.Eval != nil {
+		if err := s.nestedUpsertEval(txn, index, req.Eval); err != nil {
+			return err
+		}
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// updateDeploymentStatusImpl is used to make deployment status updates
+func (s *StateStore) updateDeploymentStatusImpl(index uint64, u *structs.DeploymentStatusUpdate, txn *memdb.Txn) error {
+	// Retrieve deployment
+	ws := memdb.NewWatchSet()
+	deployment, err := s.deploymentByIDImpl(ws, u.DeploymentID, txn)
+	if err != nil {
+		return err
+	} else if deployment == nil {
+		return fmt.Errorf("Deployment ID %q couldn't be updated as it does not exist", u.DeploymentID)
+	} else if !deployment.Active() {
+		return fmt.Errorf("Deployment %q has terminal status %q:", deployment.ID, deployment.Status)
+	}
+
+	// Apply the new status
+	copy := deployment.Copy()
+	copy.Status = u.Status
+	copy.StatusDescription = u.StatusDescription
+	if u.RevertedJobVersion != nil {
+		copy.RevertedJobVersion = u.RevertedJobVersion
+	}
+	copy.ModifyIndex = index
+
+	// Insert the deployment
+	if err := txn.Insert("deployment", copy); err != nil {
+		return err
+	}
+
+	// Update the index
+	if err := txn.Insert("index", &IndexEntry{"deployment", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	// If the deployment is being marked as complete, set the job to stable.
+	if copy.Status == structs.DeploymentStatusSuccessful {
+		if err := s.updateJobStabilityImpl(index, copy.JobID, copy.JobVersion, true, txn); err != nil {
+			return fmt.Errorf("failed to update job stability: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateJobStability updates the stability of the given job and version to the
+// desired status.
+func (s *StateStore) UpdateJobStability(index uint64, jobID string, jobVersion uint64, stable bool) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	if err := s.updateJobStabilityImpl(index, jobID, jobVersion, stable, txn); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// updateJobStabilityImpl updates the stability of the given job and version
+func (s *StateStore) updateJobStabilityImpl(index uint64, jobID string, jobVersion uint64, stable bool, txn *memdb.Txn) error {
+	// Get the job that is referenced
+	job, err := s.jobByIDAndVersionImpl(nil, jobID, jobVersion, txn)
+	if err != nil {
+		return err
+	}
+
+	// Has already been cleared, nothing to do
+	if job == nil {
+		return nil
+	}
+
+	// If the job already has the desired stability, nothing to do
+	if job.Stable == stable {
+		return nil
+	}
+
+	copy := job.Copy()
+	copy.Stable = stable
+	return s.upsertJobImpl(index, copy, true, txn)
+}
+
+// UpdateJobPriority is used to change the priority of a job without changing
+// its placements. The job is recorded as a new version with the job modify
+// index of the previous one, so that the schedulers leave its allocations
+// untouched, and the active deployment of the previous version tracks it.
+func (s *StateStore) UpdateJobPriority(index uint64, req *structs.JobUpdatePriorityRequest) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("jobs", "id", req.JobID)
+	if err != nil {
+		return fmt.Errorf("job lookup failed: %v", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("job %q not found", req.JobID)
+	}
+	job := existing.(*structs.Job)
+
+	copy := job.Copy()
+	copy.Priority = req.Priority
+	copy.Version = job.Version + 1
+	copy.Stable = false
+	copy.RevertVersion = nil
+	copy.RevertReason = ""
+	copy.PolicyOverrideJustification = ""
+	copy.NoPlacementChange = true
+	copy.SubmitTime = req.SubmitTime
+	if err := s.upsertJobImpl(index, copy, true, txn); err != nil {
+		return err
+	}
+
+	// Move the active deployment of the previous version to the new one so
+	// it isn't cancelled as outdated
+	iter, err := txn.Get("deployment", "job", req.JobID)
+	if err != nil {
+		return fmt.Errorf("deployment lookup failed: %v", err)
+	}
+	var deployments []*structs.Deployment
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		d := raw.(*structs.Deployment)
+		if d.Active() && d.JobCreateIndex == job.CreateIndex && d.JobVersion == job.Version {
+			deployments = append(deployments, d)
+		}
+	}
+	for _, d := range deployments {
+		dcopy := d.Copy()
+		dcopy.JobVersion = copy.Version
+		if err := s.upsertDeploymentImpl(index, dcopy, txn); err != nil {
+			return err
+		}
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// UpdateJobNotified records that the notification of the run of the job with
+// the job modify index was sent. Nothing is recorded if the job has been
+// deregistered since.
+func (s *StateStore) UpdateJobNotified(index uint64, jobID string, jobModifyIndex uint64) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("jobs", "id", jobID)
+	if err != nil {
+		return fmt.Errorf("job lookup failed: %v", err)
+	}
+	if existing == nil {
+		return nil
+	}
+	job := existing.(*structs.Job)
+	if job.NotifiedIndex == jobModifyIndex {
+		return nil
+	}
+
+	copy := job.Copy()
+	copy.NotifiedIndex = jobModifyIndex
+	if err := s.upsertJobImpl(index, copy, true, txn); err != nil {
+		return err
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// UpdateDeploymentNotified records that the webhooks were notified of the
+// events of the deployment.
+func (s *StateStore) UpdateDeploymentNotified(index uint64, deploymentID string, events []string) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	existing, err := txn.First("deployment", "id", deploymentID)
+	if err != nil {
+		return fmt.Errorf("deployment lookup failed: %v", err)
+	}
+	if existing == nil {
+		return nil
+	}
+	deployment := existing.(*structs.Deployment)
+
+	copy := deployment.Copy()
+	for _, event := range events {
+		if !helper.SliceStringContains(copy.NotifiedEvents, event) {
+			copy.NotifiedEvents = append(copy.NotifiedEvents, event)
+		}
+	}
+	if len(copy.NotifiedEvents) == len(deployment.NotifiedEvents) {
+		return nil
+	}
+	copy.ModifyIndex = index
+
+	if err := txn.Insert("deployment", copy); err != nil {
+		return err
+	}
+	if err := txn.Insert("index", &IndexEntry{"deployment", index}); err != nil {
+		return fmt.Errorf("index update failed: %v", err)
+	}
+
+	txn.Commit()
+	return nil
+}
+
+// UpdateDeploymentPromotion is used to promote canaries in a deployment and
+// potentially make a evaluation
+func (s *StateStore) UpdateDeploymentPromotion(index uint64, req *structs.ApplyDeploymentPromoteRequest) error {
+	txn := s.db.Txn(true)
+	defer txn.Abort()
+
+	// Retrieve deployment and ensure it is not terminal and is active
+	ws := memdb.NewWatchSet()
+	deployment, err := s.deploymentByIDImpl(ws, req.DeploymentID, txn)
+	if err != nil {
+		return err
+	} else if deployment == nil {
+		return fmt.Errorf("Deployment ID %q couldn't be updated as it does not exist", req.DeploymentID)
+	} else if !deployment.Active() {
+		return fmt.Errorf("Deployment %q has terminal status %q:", deployment.ID, deployment.Status)
+	}
+
+	// Retrieve effected allocations
+	iter, err := txn.Get("allocs", "deployment", req.DeploymentID)
+	if err != nil {
+		return err
+	}
+
+	groupIndex := make(map[string]struct{}, len(req.Groups))
+	for _, g := range req.Groups {
+		groupIndex[g] = struct{}{}
+	}
+
+	canaryIndex := make(map[string]struct{}, len(deployment.TaskGroups))
+	for _, state := range deployment.TaskGroups {
+		for _, c := range state.PlacedCanaries {
+			canaryIndex[c] = struct{}{}
+		}
+	}
+
+	var promoted []*structs.Allocation
+	var unhealthyErr multierror.Error
+	for {
+		raw := iter.Next()
+		if raw == nil {
+			break
+		}
+
+		alloc := raw.(*structs.Allocation)
+
+		// Check that the alloc is a canary
+		if _, ok := canaryIndex[alloc.ID]; !ok {
+			continue
+		}
+
+		// Check that the canary is part of a group being promoted
+		if _, ok := groupIndex[alloc.TaskGroup]; !req.All && !ok {
+			continue
+		}
+
+		// Ensure the canaries are healthy
+		if !alloc.DeploymentStatus.IsHealthy() {
+			multierror.Append(&unhealthyErr, fmt.Errorf("Canary allocation %q for group %q is not healthy", alloc.ID, alloc.TaskGroup))
+			continue
+		}
+
+		promoted = append(promoted, alloc)
+	}
+
+	if err := unhealthyErr.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	if len(promoted) == 0 {
+		return fmt.Errorf("no canaries to promote")
+	}
+
+	// Update deployment
+	copy := deployment.Copy()
+	copy.ModifyIndex = index
+	for tg, status := range copy.TaskGroups {
+		_, ok := groupIndex[tg]
+		if !req.All && !ok {
+			continue
+		}
+
+		status.Promoted = true
+	}
+
+	// If the deployment no longer needs promotion, update its status
+	if !copy.RequiresPromotion() && copy.Status == structs.DeploymentStatusRunning {
+		copy.StatusDescription = structs.DeploymentStatusDescriptionRunning
+	}
+
+	// Insert the deployment
+	if err := s.upsertDeploymentImpl(index, copy, txn); err != nil {
+		return err
+	}
+
+	// Clear the canary flag of the promoted allocations. The alloc modify
+	// index is updated so clients pick up the change, for example to
+	// register their services with their regular tags.
+	allocsUpdated := false
+	for _, alloc := range promoted {
+		if !alloc.DeploymentStatus.IsCanary() {
+			continue
+		}
+
+		copyAlloc := alloc.Copy()
+		copyAlloc.DeploymentStatus.Canary = false
+		copyAlloc.ModifyIndex = index
+		copyAlloc.AllocModifyIndex = index
+		if err := txn.Insert("allocs", copyAlloc); err != nil {
+			return fmt.Errorf("alloc insert failed: %v", err)
+		}
+		allocsUpdated = true
+	}
+	if allocsUpdated {
+		if err := txn.Insert("index", &IndexEntry{"allocs", index}); err != nil {
+			return fmt.Errorf("index update failed: %v", err)
+		}
 	}
 
 	// Upsert the optional eval
@@ -2256,6 +3735,8 @@ func (s *StateStore) ReconcileJobSummaries(index uint64) error {
 				tg.Failed += 1
 			case structs.AllocClientStatusLost:
 				tg.Lost += 1
+			case structs.AllocClientStatusUnknown:
+				tg.Unknown += 1
 			case structs.AllocClientStatusComplete:
 				tg.Complete += 1
 			case structs.AllocClientStatusRunning:
@@ -2689,6 +4170,8 @@ func (s *StateStore) updateSummaryWithAlloc(index uint64, alloc *structs.Allocat
 			tgSummary.Complete += 1
 		case structs.AllocClientStatusLost:
 			tgSummary.Lost += 1
+		case structs.AllocClientStatusUnknown:
+			tgSummary.Unknown += 1
 		}
 
 		// Decrementing the count of the bin of the last state
@@ -2699,6 +4182,8 @@ func (s *StateStore) updateSummaryWithAlloc(index uint64, alloc *structs.Allocat
 			tgSummary.Starting -= 1
 		case structs.AllocClientStatusLost:
 			tgSummary.Lost -= 1
+		case structs.AllocClientStatusUnknown:
+			tgSummary.Unknown -= 1
 		case structs.AllocClientStatusFailed, structs.AllocClientStatusComplete:
 		default:
 			s.logger.Printf("[ERR] state_store: invalid old state of allocation with id: %v, and state: %v",
@@ -2724,6 +4209,46 @@ func (s *StateStore) updateSummaryWithAlloc(index uint64, alloc *structs.Allocat
 	return nil
 }
 
+// ACLPolicyRestore is used to restore an ACL policy
+func (r *StateRestore) ACLPolicyRestore(policy *structs.ACLPolicy) error {
+	if err := r.txn.Insert("acl_policy", policy); err != nil {
+		return fmt.Errorf("inserting acl policy failed: %v", err)
+	}
+	return nil
+}
+
+// ACLRoleRestore is used to restore an ACL role
+func (r *StateRestore) ACLRoleRestore(role *structs.ACLRole) error {
+	if err := r.txn.Insert("acl_role", role); err != nil {
+		return fmt.Errorf("inserting acl role failed: %v", err)
+	}
+	return nil
+}
+
+// ACLAuthMethodRestore is used to restore an ACL auth method
+func (r *StateRestore) ACLAuthMethodRestore(method *structs.ACLAuthMethod) error {
+	if err := r.txn.Insert("acl_auth_method", method); err != nil {
+		return fmt.Errorf("inserting acl auth method failed: %v", err)
+	}
+	return nil
+}
+
+// ACLBindingRuleRestore is used to restore an ACL binding rule
+func (r *StateRestore) ACLBindingRuleRestore(rule *structs.ACLBindingRule) error {
+	if err := r.txn.Insert("acl_binding_rule", rule); err != nil {
+		return fmt.Errorf("inserting acl binding rule failed: %v", err)
+	}
+	return nil
+}
+
+// ACLTokenRestore is used to restore an ACL token
+func (r *StateRestore) ACLTokenRestore(token *structs.ACLToken) error {
+	if err := r.txn.Insert("acl_token", token); err != nil {
+		return fmt.Errorf("inserting acl token failed: %v", err)
+	}
+	return nil
+}
+
 // addEphemeralDiskToTaskGroups adds missing EphemeralDisk objects to TaskGroups
 func (s *StateStore) addEphemeralDiskToTaskGroups(job *structs.Job) {
 	for _, tg := range job.TaskGroups {
@@ -2775,6 +4300,11 @@ func (r *StateRestore) NodeRestore(node *structs.Node) error {
 
 // JobRestore is used to restore a job
 func (r *StateRestore) JobRestore(job *structs.Job) error {
+	// Jobs registered before namespaces belong to the default one
+	if job.Namespace == "" {
+		job.Namespace = structs.DefaultNamespace
+	}
+
 	// Create the EphemeralDisk if it's nil by adding up DiskMB from task resources.
 	// COMPAT 0.4.1 -> 0.5
 	r.addEphemeralDiskToTaskGroups(job)
@@ -2862,6 +4392,46 @@ func (r *StateRestore) VaultAccessorRestore(accessor *structs.VaultAccessor) err
 	return nil
 }
 
+// SITokenAccessorRestore is used to restore a Service Identity token accessor
+func (r *StateRestore) SITokenAccessorRestore(accessor *structs.SITokenAccessor) error {
+	if err := r.txn.Insert("si_token_accessors", accessor); err != nil {
+		return fmt.Errorf("si token accessor insert failed: %v", err)
+	}
+	return nil
+}
+
+// ServiceRegistrationRestore is used to restore a service registration
+func (r *StateRestore) ServiceRegistrationRestore(service *structs.ServiceRegistration) error {
+	if err := r.txn.Insert("service_registrations", service); err != nil {
+		return fmt.Errorf("service registration insert failed: %v", err)
+	}
+	return nil
+}
+
+// VariableRestore is used to restore an encrypted variable
+func (r *StateRestore) VariableRestore(v *structs.VariableEncrypted) error {
+	if err := r.txn.Insert("variables", v); err != nil {
+		return fmt.Errorf("variable insert failed: %v", err)
+	}
+	return nil
+}
+
+// SchedulerConfigRestore is used to restore the scheduler configuration
+func (r *StateRestore) SchedulerConfigRestore(config *structs.SchedulerConfiguration) error {
+	if err := r.txn.Insert("scheduler_config", config); err != nil {
+		return fmt.Errorf("inserting scheduler config failed: %s", err)
+	}
+	return nil
+}
+
+// NamespaceRestore is used to restore a namespace
+func (r *StateRestore) NamespaceRestore(ns *structs.Namespace) error {
+	if err := r.txn.Insert("namespaces", ns); err != nil {
+		return fmt.Errorf("namespace insert failed: %v", err)
+	}
+	return nil
+}
+
 // addEphemeralDiskToTaskGroups adds missing EphemeralDisk objects to TaskGroups
 func (r *StateRestore) addEphemeralDiskToTaskGroups(job *structs.Job) {
 	for _, tg := range job.TaskGroups {
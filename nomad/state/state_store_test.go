@@ -586,7 +586,7 @@ func TestStateStore_UpdateNodeStatus_Node(t *testing.T) {
 		t.Fatalf("bad: %v", err)
 	}
 
-	err = state.UpdateNodeStatus(801, node.ID, structs.NodeStatusReady)
+	err = state.UpdateNodeStatus(801, node.ID, structs.NodeStatusReady, 1000)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -607,6 +607,9 @@ func TestStateStore_UpdateNodeStatus_Node(t *testing.T) {
 	if out.ModifyIndex != 801 {
 		t.Fatalf("bad: %#v", out)
 	}
+	if out.StatusUpdatedAt != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
 
 	index, err := state.Index("nodes")
 	if err != nil {
@@ -1520,6 +1523,67 @@ func TestStateStore_JobsByIDPrefix(t *testing.T) {
 	}
 }
 
+func TestStateStore_JobsByNamespace(t *testing.T) {
+	state := testStateStore(t)
+
+	job1 := mock.Job()
+	job1.ID = "redis"
+	job2 := mock.Job()
+	job2.ID = "riak"
+	job2.Namespace = "web"
+	job3 := mock.Job()
+	job3.ID = "Redis-Web"
+	job3.Namespace = "web"
+	for i, job := range []*structs.Job{job1, job2, job3} {
+		if err := state.UpsertJob(uint64(1000+i), job); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	gatherIDs := func(iter memdb.ResultIterator) []string {
+		var ids []string
+		for raw := iter.Next(); raw != nil; raw = iter.Next() {
+			ids = append(ids, raw.(*structs.Job).ID)
+		}
+		return ids
+	}
+
+	cases := []struct {
+		Namespace string
+		Prefix    string
+		Expected  []string
+	}{
+		{structs.DefaultNamespace, "", []string{"redis"}},
+		{"web", "", []string{"Redis-Web", "riak"}},
+		{"web", "re", []string{"Redis-Web"}},
+		{"web", "x", nil},
+		{"unknown", "", nil},
+	}
+	for _, tc := range cases {
+		iter, err := state.JobsByNamespace(nil, tc.Namespace, tc.Prefix)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if ids := gatherIDs(iter); !reflect.DeepEqual(ids, tc.Expected) {
+			t.Fatalf("%s/%s: got %v; want %v", tc.Namespace, tc.Prefix, ids, tc.Expected)
+		}
+	}
+
+	// Jobs without a namespace are in the default namespace
+	job4 := mock.Job()
+	job4.Namespace = ""
+	if err := state.UpsertJob(1003, job4); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := state.JobByID(nil, job4.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Namespace != structs.DefaultNamespace {
+		t.Fatalf("bad namespace %q", out.Namespace)
+	}
+}
+
 func TestStateStore_JobsByPeriodic(t *testing.T) {
 	state := testStateStore(t)
 	var periodic, nonPeriodic []*structs.Job
@@ -3508,6 +3572,48 @@ func TestStateStore_UpdateAlloc_Lost(t *testing.T) {
 	}
 }
 
+// This test ensures that the state store will mark the clients status as
+// unknown when set and count it in the job summary.
+func TestStateStore_UpdateAlloc_Unknown(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+
+	if err := state.UpsertJob(999, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := state.UpsertAllocs(1000, []*structs.Allocation{alloc})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alloc2 := new(structs.Allocation)
+	*alloc2 = *alloc
+	alloc2.ClientStatus = structs.AllocClientStatusUnknown
+	if err := state.UpsertAllocs(1001, []*structs.Allocation{alloc2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ws := memdb.NewWatchSet()
+	out, err := state.AllocByID(ws, alloc2.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if out.ClientStatus != structs.AllocClientStatusUnknown {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	summary, err := state.JobSummaryByID(ws, alloc.Job.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tgSummary := summary.Summary["web"]
+	if tgSummary.Unknown != 1 || tgSummary.Starting != 0 {
+		t.Fatalf("bad: %#v", tgSummary)
+	}
+}
+
 // This test ensures an allocation can be updated when there is no job
 // associated with it. This will happen when a job is stopped by an user which
 // has non-terminal allocations on clients
@@ -4938,6 +5044,74 @@ func TestStateStore_UpdateJobStability(t *testing.T) {
 	}
 }
 
+func TestStateStore_UpdateJobPriority(t *testing.T) {
+	state := testStateStore(t)
+
+	// Insert a job and an active deployment of it
+	job := mock.Job()
+	if err := state.UpsertJob(1, job); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	d := mock.Deployment()
+	d.JobID = job.ID
+	d.JobVersion = job.Version
+	d.JobCreateIndex = job.CreateIndex
+	if err := state.UpsertDeployment(2, d); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	req := &structs.JobUpdatePriorityRequest{
+		JobID:      job.ID,
+		Priority:   80,
+		SubmitTime: 42,
+	}
+	if err := state.UpdateJobPriority(3, req); err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Check that a version was recorded without changing the job modify index
+	ws := memdb.NewWatchSet()
+	jout, err := state.JobByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if jout == nil {
+		t.Fatalf("expected job")
+	}
+	if jout.Priority != 80 || jout.Version != 1 || jout.SubmitTime != 42 {
+		t.Fatalf("bad: %#v", jout)
+	}
+	if !jout.NoPlacementChange {
+		t.Fatalf("job not marked without placement change %#v", jout)
+	}
+	if jout.JobModifyIndex != 1 || jout.ModifyIndex != 3 {
+		t.Fatalf("bad indexes: %d %d", jout.JobModifyIndex, jout.ModifyIndex)
+	}
+
+	versions, err := state.JobVersionsByID(ws, job.ID)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if len(versions) != 2 || versions[1].Priority != job.Priority || versions[1].NoPlacementChange {
+		t.Fatalf("bad: %#v", versions)
+	}
+
+	// Check that the deployment tracks the new version
+	dout, err := state.DeploymentByID(ws, d.ID)
+	if err != nil {
+		t.Fatalf("bad: %v", err)
+	}
+	if dout.JobVersion != 1 || dout.Status != structs.DeploymentStatusRunning {
+		t.Fatalf("bad: %#v", dout)
+	}
+
+	// Updating the priority of an unknown job fails
+	req.JobID = "foo"
+	if err := state.UpdateJobPriority(4, req); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
 // Test that non-existent deployment can't be promoted
 func TestStateStore_UpsertDeploymentPromotion_NonExistent(t *testing.T) {
 	state := testStateStore(t)
@@ -5102,6 +5276,7 @@ func TestStateStore_UpsertDeploymentPromotion_All(t *testing.T) {
 	d.TaskGroups[c1.TaskGroup].PlacedCanaries = append(d.TaskGroups[c1.TaskGroup].PlacedCanaries, c1.ID)
 	c1.DeploymentStatus = &structs.AllocDeploymentStatus{
 		Healthy: helper.BoolToPtr(true),
+		Canary:  true,
 	}
 	c2 := mock.Alloc()
 	c2.JobID = j.ID
@@ -5110,6 +5285,7 @@ func TestStateStore_UpsertDeploymentPromotion_All(t *testing.T) {
 	c2.TaskGroup = tg2.Name
 	c2.DeploymentStatus = &structs.AllocDeploymentStatus{
 		Healthy: helper.BoolToPtr(true),
+		Canary:  true,
 	}
 
 	if err := state.UpsertAllocs(3, []*structs.Allocation{c1, c2}); err != nil {
@@ -5158,6 +5334,17 @@ func TestStateStore_UpsertDeploymentPromotion_All(t *testing.T) {
 	if eout == nil {
 		t.Fatalf("bad: %#v", eout)
 	}
+
+	// Check that the allocations are no longer canaries
+	for _, id := range []string{c1.ID, c2.ID} {
+		out, err := state.AllocByID(ws, id)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out.DeploymentStatus.IsCanary() || !out.DeploymentStatus.IsHealthy() || out.AllocModifyIndex != 4 {
+			t.Fatalf("bad: %#v", out.DeploymentStatus)
+		}
+	}
 }
 
 // Test promoting a subset of canaries in a deployment.
@@ -5705,83 +5892,1448 @@ func TestStateStore_RestoreVaultAccessor(t *testing.T) {
 	}
 }
 
-func TestStateStore_Abandon(t *testing.T) {
-	s := testStateStore(t)
-	abandonCh := s.AbandonCh()
-	s.Abandon()
-	select {
-	case <-abandonCh:
-	default:
+func TestStateStore_UpsertSITokenAccessors(t *testing.T) {
+	state := testStateStore(t)
+	a1 := mock.SITokenAccessor()
+	a2 := mock.SITokenAccessor()
+
+	ws := memdb.NewWatchSet()
+	if _, err := state.SITokenAccessor(ws, a1.AccessorID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err := state.UpsertSITokenAccessors(1000, []*structs.SITokenAccessor{a1, a2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !watchFired(ws) {
 		t.Fatalf("bad")
 	}
-}
 
-// watchFired is a helper for unit tests that returns if the given watch set
-// fired (it doesn't care which watch actually fired). This uses a fixed
-// timeout since we already expect the event happened before calling this and
-// just need to distinguish a fire from a timeout. We do need a little time to
-// allow the watch to set up any goroutines, though.
-func watchFired(ws memdb.WatchSet) bool {
-	timedOut := ws.Watch(time.After(50 * time.Millisecond))
-	return !timedOut
-}
+	ws = memdb.NewWatchSet()
+	for _, a := range []*structs.SITokenAccessor{a1, a2} {
+		out, err := state.SITokenAccessor(ws, a.AccessorID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !reflect.DeepEqual(a, out) {
+			t.Fatalf("bad: %#v %#v", a, out)
+		}
+		if out.CreateIndex != 1000 {
+			t.Fatalf("bad: %#v", out)
+		}
+	}
 
-// NodeIDSort is used to sort nodes by ID
-type NodeIDSort []*structs.Node
+	iter, err := state.SITokenAccessors(ws)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	count := 0
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
+	}
 
-func (n NodeIDSort) Len() int {
-	return len(n)
+	index, err := state.Index("si_token_accessors")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
 }
 
-func (n NodeIDSort) Less(i, j int) bool {
-	return n[i].ID < n[j].ID
-}
+func TestStateStore_DeleteSITokenAccessors(t *testing.T) {
+	state := testStateStore(t)
+	a1 := mock.SITokenAccessor()
+	a2 := mock.SITokenAccessor()
+	accessors := []*structs.SITokenAccessor{a1, a2}
 
-func (n NodeIDSort) Swap(i, j int) {
-	n[i], n[j] = n[j], n[i]
-}
+	if err := state.UpsertSITokenAccessors(1000, accessors); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-// JobIDis used to sort jobs by id
-type JobIDSort []*structs.Job
+	ws := memdb.NewWatchSet()
+	if _, err := state.SITokenAccessor(ws, a1.AccessorID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-func (n JobIDSort) Len() int {
-	return len(n)
-}
+	if err := state.DeleteSITokenAccessors(1001, accessors); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-func (n JobIDSort) Less(i, j int) bool {
-	return n[i].ID < n[j].ID
-}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
 
-func (n JobIDSort) Swap(i, j int) {
-	n[i], n[j] = n[j], n[i]
+	for _, a := range accessors {
+		out, err := state.SITokenAccessor(nil, a.AccessorID)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if out != nil {
+			t.Fatalf("bad: %#v", out)
+		}
+	}
+
+	index, err := state.Index("si_token_accessors")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
 }
 
-// EvalIDis used to sort evals by id
-type EvalIDSort []*structs.Evaluation
+func TestStateStore_SITokenAccessorsByAllocAndNode(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	var accessors []*structs.SITokenAccessor
 
-func (n EvalIDSort) Len() int {
-	return len(n)
-}
+	for i := 0; i < 5; i++ {
+		accessor := mock.SITokenAccessor()
+		accessor.AllocID = alloc.ID
+		accessor.NodeID = alloc.NodeID
+		accessors = append(accessors, accessor)
+	}
+	for i := 0; i < 10; i++ {
+		accessors = append(accessors, mock.SITokenAccessor())
+	}
 
-func (n EvalIDSort) Less(i, j int) bool {
-	return n[i].ID < n[j].ID
-}
+	if err := state.UpsertSITokenAccessors(1000, accessors); err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-func (n EvalIDSort) Swap(i, j int) {
-	n[i], n[j] = n[j], n[i]
+	out, err := state.SITokenAccessorsByAlloc(nil, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 5 {
+		t.Fatalf("bad: %d", len(out))
+	}
+
+	out, err = state.SITokenAccessorsByNode(nil, alloc.NodeID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 5 {
+		t.Fatalf("bad: %d", len(out))
+	}
 }
 
-// AllocIDsort used to sort allocations by id
-type AllocIDSort []*structs.Allocation
+func TestStateStore_RestoreSITokenAccessor(t *testing.T) {
+	state := testStateStore(t)
+	a := mock.SITokenAccessor()
 
-func (n AllocIDSort) Len() int {
-	return len(n)
-}
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
 
-func (n AllocIDSort) Less(i, j int) bool {
-	return n[i].ID < n[j].ID
+	err = restore.SITokenAccessorRestore(a)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.SITokenAccessor(nil, a.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(out, a) {
+		t.Fatalf("Bad: %#v %#v", out, a)
+	}
 }
 
-func (n AllocIDSort) Swap(i, j int) {
+func TestStateStore_UpsertNamespaces(t *testing.T) {
+	state := testStateStore(t)
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
+
+	// Create a watchset so we can test that upsert fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.NamespaceByName(ws, ns1.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertNamespaces(1000, []*structs.Namespace{ns1, ns2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	ws = memdb.NewWatchSet()
+	out, err := state.NamespaceByName(ws, ns1.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(ns1, out) {
+		t.Fatalf("bad: %#v %#v", ns1, out)
+	}
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Update the namespace and check that the create index is kept
+	ns1 = ns1.Copy()
+	ns1.Description = "updated"
+	if err := state.UpsertNamespaces(1001, []*structs.Namespace{ns1}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err = state.NamespaceByName(nil, ns1.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Description != "updated" || out.CreateIndex != 1000 || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("namespaces")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_DeleteNamespaces(t *testing.T) {
+	state := testStateStore(t)
+	ns1 := mock.Namespace()
+	ns2 := mock.Namespace()
+
+	if err := state.UpsertNamespaces(1000, []*structs.Namespace{ns1, ns2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a watchset so we can test that delete fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.NamespaceByName(ws, ns1.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.DeleteNamespaces(1001, []string{ns1.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.NamespaceByName(nil, ns1.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	out, err = state.NamespaceByName(nil, ns2.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(ns2, out) {
+		t.Fatalf("bad: %#v %#v", ns2, out)
+	}
+
+	index, err := state.Index("namespaces")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	// Deleting an unknown namespace fails
+	if err := state.DeleteNamespaces(1002, []string{ns1.Name}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestStateStore_NamespacesByNamePrefix(t *testing.T) {
+	state := testStateStore(t)
+	ns1 := mock.Namespace()
+	ns1.Name = "web-frontend"
+	ns2 := mock.Namespace()
+	ns2.Name = "web-backend"
+	ns3 := mock.Namespace()
+	ns3.Name = "batch"
+
+	if err := state.UpsertNamespaces(1000, []*structs.Namespace{ns1, ns2, ns3}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	gatherNamespaces := func(iter memdb.ResultIterator) []string {
+		var names []string
+		for {
+			raw := iter.Next()
+			if raw == nil {
+				break
+			}
+			names = append(names, raw.(*structs.Namespace).Name)
+		}
+		return names
+	}
+
+	iter, err := state.NamespacesByNamePrefix(nil, "web")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if names := gatherNamespaces(iter); !reflect.DeepEqual(names, []string{"web-backend", "web-frontend"}) {
+		t.Fatalf("bad: %v", names)
+	}
+
+	iter, err = state.Namespaces(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if names := gatherNamespaces(iter); !reflect.DeepEqual(names, []string{"batch", "web-backend", "web-frontend"}) {
+		t.Fatalf("bad: %v", names)
+	}
+}
+
+func TestStateStore_RestoreNamespace(t *testing.T) {
+	state := testStateStore(t)
+	ns := mock.Namespace()
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = restore.NamespaceRestore(ns)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	ws := memdb.NewWatchSet()
+	out, err := state.NamespaceByName(ws, ns.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !reflect.DeepEqual(out, ns) {
+		t.Fatalf("Bad: %#v %#v", out, ns)
+	}
+}
+
+func TestStateStore_UpsertACLPolicies(t *testing.T) {
+	state := testStateStore(t)
+	policy := mock.ACLPolicy()
+	policy2 := mock.ACLPolicy()
+
+	// Create a watchset so we can test that upsert fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLPolicyByName(ws, policy.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy, policy2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLPolicyByName(nil, policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(policy, out) {
+		t.Fatalf("bad: %#v %#v", policy, out)
+	}
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("acl_policy")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	iter, err := state.ACLPolicies(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	count := 0
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
+	}
+}
+
+func TestStateStore_DeleteACLPolicies(t *testing.T) {
+	state := testStateStore(t)
+	policy := mock.ACLPolicy()
+
+	if err := state.UpsertACLPolicies(1000, []*structs.ACLPolicy{policy}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a watchset so we can test that delete fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLPolicyByName(ws, policy.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.DeleteACLPolicies(1001, []string{policy.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLPolicyByName(nil, policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("acl_policy")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_UpsertACLRoles(t *testing.T) {
+	state := testStateStore(t)
+	role := mock.ACLRole()
+	role2 := mock.ACLRole()
+
+	// Create a watchset so we can test that upsert fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLRoleByName(ws, role.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertACLRoles(1000, []*structs.ACLRole{role, role2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLRoleByName(nil, role.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(role, out) {
+		t.Fatalf("bad: %#v %#v", role, out)
+	}
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("acl_role")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	iter, err := state.ACLRoles(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	count := 0
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
+	}
+}
+
+func TestStateStore_DeleteACLRoles(t *testing.T) {
+	state := testStateStore(t)
+	role := mock.ACLRole()
+
+	if err := state.UpsertACLRoles(1000, []*structs.ACLRole{role}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a watchset so we can test that delete fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLRoleByName(ws, role.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.DeleteACLRoles(1001, []string{role.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLRoleByName(nil, role.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("acl_role")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_UpsertACLAuthMethods(t *testing.T) {
+	state := testStateStore(t)
+	method := mock.ACLAuthMethod()
+	method.Default = true
+	method2 := mock.ACLAuthMethod()
+
+	// Create a watchset so we can test that upsert fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLAuthMethodByName(ws, method.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{method, method2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLAuthMethodByName(nil, method.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(method, out) {
+		t.Fatalf("bad: %#v %#v", method, out)
+	}
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	def, err := state.DefaultACLAuthMethod(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if def == nil || def.Name != method.Name {
+		t.Fatalf("bad default: %#v", def)
+	}
+
+	// Making another auth method the default unsets the previous one
+	method2 = method2.Copy()
+	method2.Default = true
+	method2.SetHash()
+	if err := state.UpsertACLAuthMethods(1001, []*structs.ACLAuthMethod{method2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	def, err = state.DefaultACLAuthMethod(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if def == nil || def.Name != method2.Name {
+		t.Fatalf("bad default: %#v", def)
+	}
+	out, err = state.ACLAuthMethodByName(nil, method.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.Default || out.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("acl_auth_method")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	iter, err := state.ACLAuthMethods(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	count := 0
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
+	}
+}
+
+func TestStateStore_DeleteACLAuthMethods(t *testing.T) {
+	state := testStateStore(t)
+	method := mock.ACLAuthMethod()
+	rule := mock.ACLBindingRule()
+	rule.AuthMethod = method.Name
+
+	if err := state.UpsertACLAuthMethods(1000, []*structs.ACLAuthMethod{method}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertACLBindingRules(1001, []*structs.ACLBindingRule{rule}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a watchset so we can test that delete fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLAuthMethodByName(ws, method.Name); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.DeleteACLAuthMethods(1002, []string{method.Name}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLAuthMethodByName(nil, method.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// The binding rules of the auth method are deleted along with it
+	outRule, err := state.ACLBindingRuleByID(nil, rule.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if outRule != nil {
+		t.Fatalf("bad: %#v", outRule)
+	}
+
+	for _, table := range []string{"acl_auth_method", "acl_binding_rule"} {
+		index, err := state.Index(table)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if index != 1002 {
+			t.Fatalf("bad %s index: %d", table, index)
+		}
+	}
+}
+
+func TestStateStore_UpsertACLBindingRules(t *testing.T) {
+	state := testStateStore(t)
+	rule := mock.ACLBindingRule()
+	rule2 := mock.ACLBindingRule()
+	rule2.AuthMethod = "other"
+
+	// Create a watchset so we can test that upsert fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLBindingRuleByID(ws, rule.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertACLBindingRules(1000, []*structs.ACLBindingRule{rule, rule2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLBindingRuleByID(nil, rule.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(rule, out) {
+		t.Fatalf("bad: %#v %#v", rule, out)
+	}
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("acl_binding_rule")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+
+	iter, err := state.ACLBindingRulesByAuthMethod(nil, rule.AuthMethod)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var ids []string
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		ids = append(ids, raw.(*structs.ACLBindingRule).ID)
+	}
+	if len(ids) != 1 || ids[0] != rule.ID {
+		t.Fatalf("bad: %v", ids)
+	}
+}
+
+func TestStateStore_DeleteACLBindingRules(t *testing.T) {
+	state := testStateStore(t)
+	rule := mock.ACLBindingRule()
+
+	if err := state.UpsertACLBindingRules(1000, []*structs.ACLBindingRule{rule}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a watchset so we can test that delete fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLBindingRuleByID(ws, rule.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.DeleteACLBindingRules(1001, []string{rule.ID}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLBindingRuleByID(nil, rule.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("acl_binding_rule")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_UpsertACLTokens(t *testing.T) {
+	state := testStateStore(t)
+	token := mock.ACLToken()
+	token2 := mock.ACLToken()
+	token2.Global = true
+	expiration := time.Now().Add(time.Hour)
+	token2.ExpirationTime = &expiration
+
+	// Create a watchset so we can test that upsert fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLTokenByAccessorID(ws, token.AccessorID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertACLTokens(1000, []*structs.ACLToken{token, token2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLTokenByAccessorID(nil, token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(token, out) {
+		t.Fatalf("bad: %#v %#v", token, out)
+	}
+
+	out, err = state.ACLTokenBySecretID(nil, token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil || out.AccessorID != token.AccessorID {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	gatherTokens := func(iter memdb.ResultIterator) []string {
+		var ids []string
+		for raw := iter.Next(); raw != nil; raw = iter.Next() {
+			ids = append(ids, raw.(*structs.ACLToken).AccessorID)
+		}
+		return ids
+	}
+
+	// Only the second token is global and expiring
+	iter, err := state.ACLTokensByGlobal(nil, true)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ids := gatherTokens(iter); len(ids) != 1 || ids[0] != token2.AccessorID {
+		t.Fatalf("bad: %v", ids)
+	}
+	iter, err = state.ACLTokensByExpiring(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ids := gatherTokens(iter); len(ids) != 1 || ids[0] != token2.AccessorID {
+		t.Fatalf("bad: %v", ids)
+	}
+
+	index, err := state.Index("acl_token")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_DeleteACLTokens(t *testing.T) {
+	state := testStateStore(t)
+	token := mock.ACLToken()
+
+	if err := state.UpsertACLTokens(1000, []*structs.ACLToken{token}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a watchset so we can test that delete fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ACLTokenBySecretID(ws, token.SecretID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.DeleteACLTokens(1001, []string{token.AccessorID}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ACLTokenBySecretID(nil, token.SecretID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestStateStore_BootstrapACLTokens(t *testing.T) {
+	state := testStateStore(t)
+	token := mock.ACLManagementToken()
+
+	ok, err := state.CanBootstrapACLToken()
+	if err != nil || !ok {
+		t.Fatalf("expected to be able to bootstrap: %v %v", ok, err)
+	}
+
+	if err := state.BootstrapACLTokens(1000, token); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	out, err := state.ACLTokenByAccessorID(nil, token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(token, out) {
+		t.Fatalf("bad: %#v %#v", token, out)
+	}
+
+	// Bootstrapping can only be done once
+	ok, err = state.CanBootstrapACLToken()
+	if err != nil || ok {
+		t.Fatalf("expected not to be able to bootstrap: %v %v", ok, err)
+	}
+	if err := state.BootstrapACLTokens(1001, mock.ACLManagementToken()); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestStateStore_RestoreACLPolicy(t *testing.T) {
+	state := testStateStore(t)
+	policy := mock.ACLPolicy()
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := restore.ACLPolicyRestore(policy); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.ACLPolicyByName(nil, policy.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out, policy) {
+		t.Fatalf("Bad: %#v %#v", out, policy)
+	}
+}
+
+func TestStateStore_RestoreACLRole(t *testing.T) {
+	state := testStateStore(t)
+	role := mock.ACLRole()
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := restore.ACLRoleRestore(role); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.ACLRoleByName(nil, role.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out, role) {
+		t.Fatalf("Bad: %#v %#v", out, role)
+	}
+}
+
+func TestStateStore_RestoreACLAuthMethod(t *testing.T) {
+	state := testStateStore(t)
+	method := mock.ACLAuthMethod()
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := restore.ACLAuthMethodRestore(method); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.ACLAuthMethodByName(nil, method.Name)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out, method) {
+		t.Fatalf("Bad: %#v %#v", out, method)
+	}
+}
+
+func TestStateStore_RestoreACLBindingRule(t *testing.T) {
+	state := testStateStore(t)
+	rule := mock.ACLBindingRule()
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := restore.ACLBindingRuleRestore(rule); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.ACLBindingRuleByID(nil, rule.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out, rule) {
+		t.Fatalf("Bad: %#v %#v", out, rule)
+	}
+}
+
+func TestStateStore_RestoreACLToken(t *testing.T) {
+	state := testStateStore(t)
+	token := mock.ACLToken()
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := restore.ACLTokenRestore(token); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	out, err := state.ACLTokenByAccessorID(nil, token.AccessorID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(out, token) {
+		t.Fatalf("Bad: %#v %#v", out, token)
+	}
+}
+
+func TestStateStore_SchedulerConfig(t *testing.T) {
+	state := testStateStore(t)
+
+	// An unset config returns nil
+	index, config, err := state.SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 0 || config != nil {
+		t.Fatalf("bad: %d %#v", index, config)
+	}
+
+	expected := &structs.SchedulerConfiguration{
+		SchedulerAlgorithm: structs.SchedulerAlgorithmSpread,
+	}
+	if err := state.SchedulerSetConfig(1000, expected); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	index, config, err = state.SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1000 {
+		t.Fatalf("bad: %d", index)
+	}
+	if config.CreateIndex != 1000 || config.ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", config)
+	}
+	if !reflect.DeepEqual(expected, config) {
+		t.Fatalf("bad: %#v %#v", expected, config)
+	}
+
+	// Updating keeps the create index
+	update := *config
+	update.SchedulerAlgorithm = structs.SchedulerAlgorithmBinpack
+	if err := state.SchedulerSetConfig(1001, &update); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, config, err = state.SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if config.CreateIndex != 1000 || config.ModifyIndex != 1001 || config.SchedulerAlgorithm != structs.SchedulerAlgorithmBinpack {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	index, err = state.Index("scheduler_config")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_SchedulerCASConfig(t *testing.T) {
+	state := testStateStore(t)
+
+	// An index of zero matches an unset config
+	config := structs.DefaultSchedulerConfiguration()
+	ok, err := state.SchedulerCASConfig(1000, 0, config)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected the update to be applied")
+	}
+
+	// A stale index is a noop
+	update := &structs.SchedulerConfiguration{
+		SchedulerAlgorithm: structs.SchedulerAlgorithmSpread,
+	}
+	ok, err = state.SchedulerCASConfig(1001, 0, update)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected the update to be rejected")
+	}
+	_, out, err := state.SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.SchedulerAlgorithm != structs.SchedulerAlgorithmBinpack {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// The current index applies the update
+	ok, err = state.SchedulerCASConfig(1002, 1000, update)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected the update to be applied")
+	}
+	_, out, err = state.SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.SchedulerAlgorithm != structs.SchedulerAlgorithmSpread || out.ModifyIndex != 1002 {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestStateStore_RestoreSchedulerConfig(t *testing.T) {
+	state := testStateStore(t)
+	config := &structs.SchedulerConfiguration{
+		SchedulerAlgorithm: structs.SchedulerAlgorithmSpread,
+		CreateIndex:        10,
+		ModifyIndex:        20,
+	}
+
+	restore, err := state.Restore()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = restore.SchedulerConfigRestore(config)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	restore.Commit()
+
+	index, out, err := state.SchedulerConfig()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 20 {
+		t.Fatalf("bad: %d", index)
+	}
+	if !reflect.DeepEqual(out, config) {
+		t.Fatalf("Bad: %#v %#v", out, config)
+	}
+}
+
+func TestStateStore_Abandon(t *testing.T) {
+	s := testStateStore(t)
+	abandonCh := s.AbandonCh()
+	s.Abandon()
+	select {
+	case <-abandonCh:
+	default:
+		t.Fatalf("bad")
+	}
+}
+
+// watchFired is a helper for unit tests that returns if the given watch set
+// fired (it doesn't care which watch actually fired). This uses a fixed
+// timeout since we already expect the event happened before calling this and
+// just need to distinguish a fire from a timeout. We do need a little time to
+// allow the watch to set up any goroutines, though.
+func watchFired(ws memdb.WatchSet) bool {
+	timedOut := ws.Watch(time.After(50 * time.Millisecond))
+	return !timedOut
+}
+
+// NodeIDSort is used to sort nodes by ID
+type NodeIDSort []*structs.Node
+
+func (n NodeIDSort) Len() int {
+	return len(n)
+}
+
+func (n NodeIDSort) Less(i, j int) bool {
+	return n[i].ID < n[j].ID
+}
+
+func (n NodeIDSort) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// JobIDis used to sort jobs by id
+type JobIDSort []*structs.Job
+
+func (n JobIDSort) Len() int {
+	return len(n)
+}
+
+func (n JobIDSort) Less(i, j int) bool {
+	return n[i].ID < n[j].ID
+}
+
+func (n JobIDSort) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// EvalIDis used to sort evals by id
+type EvalIDSort []*structs.Evaluation
+
+func (n EvalIDSort) Len() int {
+	return len(n)
+}
+
+func (n EvalIDSort) Less(i, j int) bool {
+	return n[i].ID < n[j].ID
+}
+
+func (n EvalIDSort) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+// AllocIDsort used to sort allocations by id
+type AllocIDSort []*structs.Allocation
+
+func (n AllocIDSort) Len() int {
+	return len(n)
+}
+
+func (n AllocIDSort) Less(i, j int) bool {
+	return n[i].ID < n[j].ID
+}
+
+func (n AllocIDSort) Swap(i, j int) {
 	n[i], n[j] = n[j], n[i]
 }
+
+func TestStateStore_UpsertServiceRegistrations(t *testing.T) {
+	state := testStateStore(t)
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+	s2.ServiceName = "api"
+
+	// Create a watchset so we can test that upsert fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ServiceRegistrationsByName(ws, s1.ServiceName); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	out, err := state.ServiceRegistrationsByName(nil, s1.ServiceName)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 1 || !reflect.DeepEqual(s1, out[0]) {
+		t.Fatalf("bad: %#v %#v", s1, out)
+	}
+	if out[0].CreateIndex != 1000 || out[0].ModifyIndex != 1000 {
+		t.Fatalf("bad: %#v", out[0])
+	}
+
+	// Update the registration and check that the create index is kept
+	s1 = s1.Copy()
+	s1.Status = structs.ServiceRegistrationStatusPassing
+	if err := state.UpsertServiceRegistrations(1001, []*structs.ServiceRegistration{s1}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got, err := state.ServiceRegistrationByID(nil, s1.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got.Status != structs.ServiceRegistrationStatusPassing || got.CreateIndex != 1000 || got.ModifyIndex != 1001 {
+		t.Fatalf("bad: %#v", got)
+	}
+
+	byAlloc, err := state.ServiceRegistrationsByAllocID(nil, s2.AllocID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	byNode, err := state.ServiceRegistrationsByNodeID(nil, s2.NodeID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(byAlloc) != 1 || len(byNode) != 1 || byAlloc[0].ID != s2.ID || byNode[0].ID != s2.ID {
+		t.Fatalf("bad: %#v %#v", byAlloc, byNode)
+	}
+
+	index, err := state.Index("service_registrations")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_DeleteServiceRegistrationsByID(t *testing.T) {
+	state := testStateStore(t)
+	s1 := mock.ServiceRegistration()
+	s2 := mock.ServiceRegistration()
+
+	if err := state.UpsertServiceRegistrations(1000, []*structs.ServiceRegistration{s1, s2}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Create a watchset so we can test that delete fires the watch
+	ws := memdb.NewWatchSet()
+	if _, err := state.ServiceRegistrationByID(ws, s1.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Unknown IDs are ignored
+	if err := state.DeleteServiceRegistrationsByID(1001, []string{s1.ID, "unknown"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	iter, err := state.ServiceRegistrations(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var out []*structs.ServiceRegistration
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		out = append(out, raw.(*structs.ServiceRegistration))
+	}
+	if len(out) != 1 || out[0].ID != s2.ID {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("service_registrations")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1001 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_UpsertVariable_CAS(t *testing.T) {
+	state := testStateStore(t)
+	v := &structs.VariableEncrypted{
+		Namespace: structs.DefaultNamespace,
+		Path:      "app/db",
+		Data:      []byte("encrypted"),
+	}
+
+	// Create the variable, requiring that it doesn't exist
+	ok, conflict, err := state.UpsertVariable(1000, v, true)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !ok || conflict != nil {
+		t.Fatalf("expected variable to be created; got %v %#v", ok, conflict)
+	}
+
+	// Creating it again fails
+	v2 := &structs.VariableEncrypted{
+		Namespace: structs.DefaultNamespace,
+		Path:      "app/db",
+		Data:      []byte("other"),
+	}
+	ok, conflict, err = state.UpsertVariable(1001, v2, true)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ok || conflict == nil || conflict.ModifyIndex != 1000 {
+		t.Fatalf("expected a conflict; got %v %#v", ok, conflict)
+	}
+
+	// Updating it from its current index succeeds and keeps the create index
+	v2.ModifyIndex = 1000
+	if ok, _, err = state.UpsertVariable(1002, v2, true); err != nil || !ok {
+		t.Fatalf("expected variable to be updated; got %v %v", ok, err)
+	}
+	out, err := state.VariableByPath(nil, structs.DefaultNamespace, "app/db")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.CreateIndex != 1000 || out.ModifyIndex != 1002 || string(out.Data) != "other" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Deleting it from a stale index fails
+	ok, conflict, err = state.DeleteVariable(1003, structs.DefaultNamespace, "app/db", true, 1000)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ok || conflict == nil {
+		t.Fatalf("expected a conflict; got %v %#v", ok, conflict)
+	}
+	if ok, _, err = state.DeleteVariable(1003, structs.DefaultNamespace, "app/db", true, 1002); err != nil || !ok {
+		t.Fatalf("expected variable to be deleted; got %v %v", ok, err)
+	}
+
+	index, err := state.Index("variables")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1003 {
+		t.Fatalf("bad: %d", index)
+	}
+}
+
+func TestStateStore_VariablesByPathPrefix(t *testing.T) {
+	state := testStateStore(t)
+	for i, path := range []string{"app/db", "app/cache", "web/tls"} {
+		v := &structs.VariableEncrypted{Namespace: structs.DefaultNamespace, Path: path}
+		if _, _, err := state.UpsertVariable(uint64(1000+i), v, false); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	other := &structs.VariableEncrypted{Namespace: "web", Path: "app/db"}
+	if _, _, err := state.UpsertVariable(1003, other, false); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	iter, err := state.VariablesByPathPrefix(nil, structs.DefaultNamespace, "app/")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var paths []string
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		paths = append(paths, raw.(*structs.VariableEncrypted).Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"app/cache", "app/db"}) {
+		t.Fatalf("bad: %v", paths)
+	}
+}
+
+func TestStateStore_ServiceRegistrations_TerminalAlloc(t *testing.T) {
+	state := testStateStore(t)
+	alloc := mock.Alloc()
+	if err := state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(1000, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	service := mock.ServiceRegistration()
+	service.AllocID = alloc.ID
+	if err := state.UpsertServiceRegistrations(1001, []*structs.ServiceRegistration{service}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// A running allocation keeps its services
+	update := alloc.Copy()
+	update.ClientStatus = structs.AllocClientStatusRunning
+	if err := state.UpdateAllocsFromClient(1002, []*structs.Allocation{update}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err := state.ServiceRegistrationsByAllocID(nil, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// The services are removed once the allocation completes
+	update = alloc.Copy()
+	update.ClientStatus = structs.AllocClientStatusComplete
+	if err := state.UpdateAllocsFromClient(1003, []*structs.Allocation{update}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	out, err = state.ServiceRegistrationsByAllocID(nil, alloc.ID)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 0 {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	index, err := state.Index("service_registrations")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index != 1003 {
+		t.Fatalf("bad: %d", index)
+	}
+}
@@ -1,6 +1,10 @@
 package nomad
 
-import "github.com/hashicorp/nomad/nomad/structs"
+import (
+	"fmt"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
 
 // Status endpoint is used to check on server status
 type Status struct {
@@ -95,3 +99,13 @@ func (s *Status) Members(args *structs.GenericRequest, reply *structs.ServerMemb
 	}
 	return nil
 }
+
+// HasNodeConn is used by the other servers of the region to find the server a
+// node has its node connection to. It is answered locally.
+func (s *Status) HasNodeConn(args *structs.NodeSpecificRequest, reply *structs.NodeConnQueryResponse) error {
+	if args.NodeID == "" {
+		return fmt.Errorf("missing node ID")
+	}
+	_, reply.Connected = s.srv.nodeConn(args.NodeID)
+	return nil
+}
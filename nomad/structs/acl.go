@@ -0,0 +1,1223 @@
+package structs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/hashicorp/nomad/helper/filter"
+)
+
+const (
+	// ACLClientToken and ACLManagementToken are the types of ACL tokens.
+	// Client tokens are granted the permissions of their policies while
+	// management tokens are allowed everything.
+	ACLClientToken     = "client"
+	ACLManagementToken = "management"
+
+	// ACLAnonymousPolicy is the name of the policy granting the permissions
+	// of the requests made without a token
+	ACLAnonymousPolicy = "anonymous"
+
+	// maxACLPolicyDescriptionLength limits the size of a policy description
+	maxACLPolicyDescriptionLength = 256
+
+	// maxACLTokenNameLength limits the size of a token name
+	maxACLTokenNameLength = 256
+
+	// maxACLRoleDescriptionLength limits the size of a role description
+	maxACLRoleDescriptionLength = 256
+
+	// ACLAuthMethodTypeOIDC is the type of the auth methods logging users in
+	// with an OpenID Connect provider
+	ACLAuthMethodTypeOIDC = "OIDC"
+
+	// ACLAuthMethodTypeJWT is the type of the auth methods logging machines
+	// in with a JWT signed by a third party, such as a CI system
+	ACLAuthMethodTypeJWT = "JWT"
+
+	// ACLAuthMethodTokenLocalityLocal and ACLAuthMethodTokenLocalityGlobal
+	// are the localities of the tokens created by an auth method. Global
+	// tokens are created in the authoritative region.
+	ACLAuthMethodTokenLocalityLocal  = "local"
+	ACLAuthMethodTokenLocalityGlobal = "global"
+
+	// ACLBindingRuleBindTypeRole, ACLBindingRuleBindTypePolicy and
+	// ACLBindingRuleBindTypeManagement are the types of binding rules. They
+	// grant the tokens a role, a policy or make them management tokens.
+	ACLBindingRuleBindTypeRole       = "role"
+	ACLBindingRuleBindTypePolicy     = "policy"
+	ACLBindingRuleBindTypeManagement = "management"
+
+	// maxACLBindingRuleDescriptionLength limits the size of a binding rule
+	// description
+	maxACLBindingRuleDescriptionLength = 256
+)
+
+var (
+	// validACLPolicyName is used to validate a policy name
+	validACLPolicyName = regexp.MustCompile("^[a-zA-Z0-9-]{1,128}$")
+
+	// validACLRoleName is used to validate a role name
+	validACLRoleName = regexp.MustCompile("^[a-zA-Z0-9-]{1,128}$")
+
+	// validACLAuthMethodName is used to validate an auth method name
+	validACLAuthMethodName = regexp.MustCompile("^[a-zA-Z0-9-]{1,128}$")
+
+	// validACLAuthMethodSigningAlg is used to validate the signing
+	// algorithms of an auth method
+	validACLAuthMethodSigningAlg = regexp.MustCompile("^(RS|PS|ES)(256|384|512)$")
+
+	// bindNameVariable matches the ${value.<name>} variables of the bind
+	// name of a binding rule
+	bindNameVariable = regexp.MustCompile(`\$\{value\.([a-zA-Z0-9_-]+)\}`)
+
+	// AnonymousACLToken is the token of the requests made without one. It
+	// is granted the permissions of the anonymous policy, if there is one.
+	AnonymousACLToken = &ACLToken{
+		AccessorID: "anonymous",
+		Name:       "Anonymous Token",
+		Type:       ACLClientToken,
+		Policies:   []string{ACLAnonymousPolicy},
+	}
+)
+
+// ACLPolicy is a named set of ACL rules that can be attached to tokens
+type ACLPolicy struct {
+	// Name is the unique name of the policy
+	Name string
+
+	// Description is a human readable description of the policy
+	Description string
+
+	// Rules are the HCL or JSON rules of the policy
+	Rules string
+
+	// Hash is the hash of the policy, used to compare policies replicated
+	// across regions
+	Hash []byte
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLPolicyListStub is the stub of a policy returned by list requests
+type ACLPolicyListStub struct {
+	Name        string
+	Description string
+	Hash        []byte
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// SetHash computes and sets the hash of the policy
+func (a *ACLPolicy) SetHash() []byte {
+	hash := sha256.New()
+	hash.Write([]byte(a.Name))
+	hash.Write([]byte(a.Description))
+	hash.Write([]byte(a.Rules))
+	a.Hash = hash.Sum(nil)
+	return a.Hash
+}
+
+// Stub returns the stub of the policy
+func (a *ACLPolicy) Stub() *ACLPolicyListStub {
+	return &ACLPolicyListStub{
+		Name:        a.Name,
+		Description: a.Description,
+		Hash:        a.Hash,
+		CreateIndex: a.CreateIndex,
+		ModifyIndex: a.ModifyIndex,
+	}
+}
+
+// Validate returns an error if the policy is invalid. The rules are parsed
+// by the servers.
+func (a *ACLPolicy) Validate() error {
+	var mErr multierror.Error
+	if !validACLPolicyName.MatchString(a.Name) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid name %q. Must match regex %s", a.Name, validACLPolicyName))
+	}
+	if len(a.Description) > maxACLPolicyDescriptionLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("description longer than %d", maxACLPolicyDescriptionLength))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ACLRole is a named set of policies that can be attached to tokens in place
+// of the policies themselves
+type ACLRole struct {
+	// Name is the unique name of the role
+	Name string
+
+	// Description is a human readable description of the role
+	Description string
+
+	// Policies are the names of the policies of the role
+	Policies []string
+
+	// Hash is the hash of the role, used to compare roles replicated across
+	// regions
+	Hash []byte
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLRoleListStub is the stub of a role returned by list requests
+type ACLRoleListStub struct {
+	Name        string
+	Description string
+	Policies    []string
+	Hash        []byte
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// SetHash computes and sets the hash of the role
+func (a *ACLRole) SetHash() []byte {
+	hash := sha256.New()
+	hash.Write([]byte(a.Name))
+	hash.Write([]byte(a.Description))
+	for _, policy := range a.Policies {
+		hash.Write([]byte(policy))
+	}
+	a.Hash = hash.Sum(nil)
+	return a.Hash
+}
+
+// Stub returns the stub of the role
+func (a *ACLRole) Stub() *ACLRoleListStub {
+	return &ACLRoleListStub{
+		Name:        a.Name,
+		Description: a.Description,
+		Policies:    a.Policies,
+		Hash:        a.Hash,
+		CreateIndex: a.CreateIndex,
+		ModifyIndex: a.ModifyIndex,
+	}
+}
+
+// Validate returns an error if the role is invalid
+func (a *ACLRole) Validate() error {
+	var mErr multierror.Error
+	if !validACLRoleName.MatchString(a.Name) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid name %q. Must match regex %s", a.Name, validACLRoleName))
+	}
+	if len(a.Description) > maxACLRoleDescriptionLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("description longer than %d", maxACLRoleDescriptionLength))
+	}
+	if len(a.Policies) == 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("role missing policies"))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ACLAuthMethod is a method of logging in to exchange an identity asserted
+// by a third party for an ACL token. The binding rules of the method decide
+// what the tokens are granted.
+type ACLAuthMethod struct {
+	// Name is the unique name of the auth method
+	Name string
+
+	// Type is the type of the auth method
+	Type string
+
+	// TokenLocality is the locality of the tokens created by the auth
+	// method, either local or global
+	TokenLocality string
+
+	// MaxTokenTTL is the lifetime of the tokens created by the auth method
+	MaxTokenTTL time.Duration
+
+	// Default marks the auth method used by logins that don't name one.
+	// There is at most one default auth method.
+	Default bool
+
+	// Config is the configuration of the identity provider
+	Config *ACLAuthMethodConfig
+
+	// Hash is the hash of the auth method, used to compare auth methods
+	// replicated across regions
+	Hash []byte
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLAuthMethodConfig is the configuration of the identity provider of an auth
+// method
+type ACLAuthMethodConfig struct {
+	// OIDCDiscoveryURL is the issuer URL of the OIDC provider, which serves
+	// its discovery document under /.well-known/openid-configuration. JWT
+	// auth methods may use it to find the keys verifying the JWTs.
+	OIDCDiscoveryURL string
+
+	// OIDCClientID and OIDCClientSecret are the credentials of Nomad with
+	// the OIDC provider
+	OIDCClientID     string
+	OIDCClientSecret string
+
+	// OIDCScopes are the scopes requested besides the openid scope
+	OIDCScopes []string
+
+	// JWKSURL is the URL of the JSON Web Key Set verifying the JWTs of a
+	// JWT auth method
+	JWKSURL string
+
+	// JWTValidationPubKeys are the PEM-encoded public keys verifying the
+	// JWTs of a JWT auth method
+	JWTValidationPubKeys []string
+
+	// BoundIssuer is the issuer of the JWTs that are accepted by a JWT auth
+	// method. It defaults to the issuer of the OIDC provider if the keys are
+	// found with the discovery URL, and isn't checked otherwise.
+	BoundIssuer string
+
+	// BoundAudiences are the audiences of the ID tokens that are accepted.
+	// It defaults to the client ID for OIDC auth methods, and JWT auth
+	// methods don't check the audience without it.
+	BoundAudiences []string
+
+	// BoundClaims are the claims the identity must have. A claim is named
+	// like in the claim mappings and must have one of the values, or
+	// contain one of them if it is a list.
+	BoundClaims map[string][]string
+
+	// AllowedRedirectURIs are the URIs the OIDC provider may redirect the
+	// users back to once they have logged in
+	AllowedRedirectURIs []string
+
+	// DiscoveryCaPem are the PEM-encoded CA certificates used to reach the
+	// OIDC provider or the JWKS URL, in place of the system ones
+	DiscoveryCaPem []string
+
+	// SigningAlgs are the algorithms the ID tokens and JWTs may be signed
+	// with. It defaults to RS256.
+	SigningAlgs []string
+
+	// ClaimMappings and ListClaimMappings map the claims of the identity to
+	// the names the selectors and bind names of the binding rules refer to
+	// as value.<name> and list.<name>
+	ClaimMappings     map[string]string
+	ListClaimMappings map[string]string
+}
+
+// Copy returns a copy of the config
+func (c *ACLAuthMethodConfig) Copy() *ACLAuthMethodConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(ACLAuthMethodConfig)
+	*nc = *c
+	nc.OIDCScopes = helper.CopySliceString(c.OIDCScopes)
+	nc.JWTValidationPubKeys = helper.CopySliceString(c.JWTValidationPubKeys)
+	nc.BoundAudiences = helper.CopySliceString(c.BoundAudiences)
+	if c.BoundClaims != nil {
+		nc.BoundClaims = make(map[string][]string, len(c.BoundClaims))
+		for claim, values := range c.BoundClaims {
+			nc.BoundClaims[claim] = helper.CopySliceString(values)
+		}
+	}
+	nc.AllowedRedirectURIs = helper.CopySliceString(c.AllowedRedirectURIs)
+	nc.DiscoveryCaPem = helper.CopySliceString(c.DiscoveryCaPem)
+	nc.SigningAlgs = helper.CopySliceString(c.SigningAlgs)
+	nc.ClaimMappings = helper.CopyMapStringString(c.ClaimMappings)
+	nc.ListClaimMappings = helper.CopyMapStringString(c.ListClaimMappings)
+	return nc
+}
+
+// ACLAuthMethodStub is the stub of an auth method returned by list requests.
+// It is public so users can pick the auth method to log in with.
+type ACLAuthMethodStub struct {
+	Name        string
+	Type        string
+	Default     bool
+	Hash        []byte
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// SetHash computes and sets the hash of the auth method
+func (a *ACLAuthMethod) SetHash() []byte {
+	hash := sha256.New()
+	hash.Write([]byte(a.Name))
+	hash.Write([]byte(a.Type))
+	hash.Write([]byte(a.TokenLocality))
+	hash.Write([]byte(a.MaxTokenTTL.String()))
+	hash.Write([]byte(strconv.FormatBool(a.Default)))
+	if a.Config != nil {
+		// The config has maps, so it is hashed through its JSON encoding
+		// which sorts their keys
+		buf, _ := json.Marshal(a.Config)
+		hash.Write(buf)
+	}
+	a.Hash = hash.Sum(nil)
+	return a.Hash
+}
+
+// Stub returns the stub of the auth method
+func (a *ACLAuthMethod) Stub() *ACLAuthMethodStub {
+	return &ACLAuthMethodStub{
+		Name:        a.Name,
+		Type:        a.Type,
+		Default:     a.Default,
+		Hash:        a.Hash,
+		CreateIndex: a.CreateIndex,
+		ModifyIndex: a.ModifyIndex,
+	}
+}
+
+// Copy returns a copy of the auth method
+func (a *ACLAuthMethod) Copy() *ACLAuthMethod {
+	if a == nil {
+		return nil
+	}
+	c := new(ACLAuthMethod)
+	*c = *a
+	c.Config = a.Config.Copy()
+	c.Hash = append([]byte(nil), a.Hash...)
+	return c
+}
+
+// TokenGlobal returns whether the tokens created by the auth method are
+// global
+func (a *ACLAuthMethod) TokenGlobal() bool {
+	return a.TokenLocality == ACLAuthMethodTokenLocalityGlobal
+}
+
+// Validate returns an error if the auth method is invalid. The lifetime of its
+// tokens must be at least minTTL and at most maxTTL.
+func (a *ACLAuthMethod) Validate(minTTL, maxTTL time.Duration) error {
+	var mErr multierror.Error
+	if !validACLAuthMethodName.MatchString(a.Name) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid name %q. Must match regex %s", a.Name, validACLAuthMethodName))
+	}
+	switch a.TokenLocality {
+	case ACLAuthMethodTokenLocalityLocal, ACLAuthMethodTokenLocalityGlobal:
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("token locality must be %q or %q",
+			ACLAuthMethodTokenLocalityLocal, ACLAuthMethodTokenLocalityGlobal))
+	}
+	if a.MaxTokenTTL < minTTL {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("max token TTL must be at least %v", minTTL))
+	}
+	if a.MaxTokenTTL > maxTTL {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("max token TTL can't be more than %v", maxTTL))
+	}
+
+	if a.Config == nil {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("missing config"))
+		return mErr.ErrorOrNil()
+	}
+	switch a.Type {
+	case ACLAuthMethodTypeOIDC:
+		if a.Config.OIDCDiscoveryURL == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("missing OIDC discovery URL"))
+		}
+		if a.Config.OIDCClientID == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("missing OIDC client ID"))
+		}
+		if len(a.Config.AllowedRedirectURIs) == 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("missing allowed redirect URIs"))
+		}
+	case ACLAuthMethodTypeJWT:
+		// The keys verifying the JWTs come from exactly one source
+		sources := 0
+		for _, set := range []bool{
+			a.Config.OIDCDiscoveryURL != "",
+			a.Config.JWKSURL != "",
+			len(a.Config.JWTValidationPubKeys) != 0,
+		} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("exactly one of OIDC discovery URL, JWKS URL or JWT validation public keys must be set"))
+		}
+		for i, key := range a.Config.JWTValidationPubKeys {
+			if _, err := ParseJWTValidationPubKey(key); err != nil {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid JWT validation public key %d: %v", i+1, err))
+			}
+		}
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("type must be %q or %q", ACLAuthMethodTypeOIDC, ACLAuthMethodTypeJWT))
+	}
+	for _, alg := range a.Config.SigningAlgs {
+		if !validACLAuthMethodSigningAlg.MatchString(alg) {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("unsupported signing algorithm %q", alg))
+		}
+	}
+	for claim, values := range a.Config.BoundClaims {
+		if len(values) == 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("bound claim %q has no values", claim))
+		}
+	}
+
+	// A claim may only be mapped to a value or to a list, and the names are
+	// unique across both
+	names := make(map[string]struct{})
+	for claim, name := range a.Config.ClaimMappings {
+		if _, ok := a.Config.ListClaimMappings[claim]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("claim %q is mapped to both a value and a list", claim))
+		}
+		names[name] = struct{}{}
+	}
+	for _, name := range a.Config.ListClaimMappings {
+		if _, ok := names[name]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("claim mapping name %q is used twice", name))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ParseJWTValidationPubKey parses a PEM-encoded RSA or ECDSA public key, or
+// the public key of a PEM-encoded certificate
+func ParseJWTValidationPubKey(raw string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(raw))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	var key crypto.PublicKey
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	} else {
+		var err error
+		if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// ACLBindingRule binds the identities logged in with an auth method to the
+// roles or policies of the tokens they are exchanged for
+type ACLBindingRule struct {
+	// ID is the unique ID of the binding rule
+	ID string
+
+	// Description is a human readable description of the binding rule
+	Description string
+
+	// AuthMethod is the name of the auth method the binding rule applies to
+	AuthMethod string
+
+	// Selector is a filter expression matching the identities the binding
+	// rule applies to. It selects the mapped claims as value.<name> and
+	// list.<name>, and an empty selector matches every identity.
+	Selector string
+
+	// BindType is either role, policy or management
+	BindType string
+
+	// BindName is the name of the role or policy granted. It may refer to
+	// the mapped claims as ${value.<name>}.
+	BindName string
+
+	// Hash is the hash of the binding rule, used to compare binding rules
+	// replicated across regions
+	Hash []byte
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// SetHash computes and sets the hash of the binding rule
+func (a *ACLBindingRule) SetHash() []byte {
+	hash := sha256.New()
+	hash.Write([]byte(a.ID))
+	hash.Write([]byte(a.Description))
+	hash.Write([]byte(a.AuthMethod))
+	hash.Write([]byte(a.Selector))
+	hash.Write([]byte(a.BindType))
+	hash.Write([]byte(a.BindName))
+	a.Hash = hash.Sum(nil)
+	return a.Hash
+}
+
+// Validate returns an error if the binding rule is invalid
+func (a *ACLBindingRule) Validate() error {
+	var mErr multierror.Error
+	if a.AuthMethod == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("missing auth method"))
+	}
+	if len(a.Description) > maxACLBindingRuleDescriptionLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("description longer than %d", maxACLBindingRuleDescriptionLength))
+	}
+	if a.Selector != "" {
+		if _, err := filter.Parse(a.Selector); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid selector: %v", err))
+		}
+	}
+	switch a.BindType {
+	case ACLBindingRuleBindTypeRole, ACLBindingRuleBindTypePolicy:
+		if a.BindName == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("missing bind name"))
+		}
+	case ACLBindingRuleBindTypeManagement:
+		if a.BindName != "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("management binding rules cannot have a bind name"))
+		}
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("bind type must be %q, %q or %q",
+			ACLBindingRuleBindTypeRole, ACLBindingRuleBindTypePolicy, ACLBindingRuleBindTypeManagement))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ACLIdentity is an identity asserted by the identity provider of an auth
+// method, made of the claims mapped by the auth method
+type ACLIdentity struct {
+	// Values are the claims mapped by ClaimMappings
+	Values map[string]string
+
+	// Lists are the claims mapped by ListClaimMappings
+	Lists map[string][]string
+}
+
+// selectorObject returns the object the selectors of the binding rules are
+// evaluated against
+func (i *ACLIdentity) selectorObject() map[string]interface{} {
+	return map[string]interface{}{
+		"value": i.Values,
+		"list":  i.Lists,
+	}
+}
+
+// Bind returns the token granted to the identity by the binding rules, whose
+// roles and policies are the bind names of the matching rules. It returns a
+// nil token if no rule matches.
+func (i *ACLIdentity) Bind(rules []*ACLBindingRule) (*ACLToken, error) {
+	token := &ACLToken{Type: ACLClientToken}
+	matched := false
+	for _, rule := range rules {
+		if rule.Selector != "" {
+			expr, err := filter.Parse(rule.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector of binding rule %q: %v", rule.ID, err)
+			}
+			ok, err := expr.Match(i.selectorObject())
+			if err != nil || !ok {
+				continue
+			}
+		}
+
+		// Rules whose bind name refers to a missing value don't apply
+		name, ok := i.interpolate(rule.BindName)
+		if !ok {
+			continue
+		}
+
+		matched = true
+		switch rule.BindType {
+		case ACLBindingRuleBindTypeManagement:
+			token.Type = ACLManagementToken
+		case ACLBindingRuleBindTypeRole:
+			if !helper.SliceStringContains(token.Roles, name) {
+				token.Roles = append(token.Roles, name)
+			}
+		case ACLBindingRuleBindTypePolicy:
+			if !helper.SliceStringContains(token.Policies, name) {
+				token.Policies = append(token.Policies, name)
+			}
+		}
+	}
+	if !matched {
+		return nil, nil
+	}
+
+	// Management tokens are allowed everything, so they have no roles or
+	// policies
+	if token.Type == ACLManagementToken {
+		token.Roles = nil
+		token.Policies = nil
+	}
+	return token, nil
+}
+
+// interpolate replaces the ${value.<name>} variables of the bind name. It
+// returns false if a value is missing.
+func (i *ACLIdentity) interpolate(name string) (string, bool) {
+	ok := true
+	out := bindNameVariable.ReplaceAllStringFunc(name, func(m string) string {
+		value, found := i.Values[bindNameVariable.FindStringSubmatch(m)[1]]
+		if !found || value == "" {
+			ok = false
+		}
+		return value
+	})
+	return out, ok
+}
+
+// ACLToken is a token authenticating the requests made with its secret ID
+type ACLToken struct {
+	// AccessorID is the public ID of the token
+	AccessorID string
+
+	// SecretID is the secret sent along with the requests
+	SecretID string
+
+	// Name is a human readable name of the token
+	Name string
+
+	// Type is either a client or a management token
+	Type string
+
+	// Policies are the names of the policies of a client token
+	Policies []string
+
+	// Roles are the names of the roles of a client token, which grant the
+	// token the policies of each role
+	Roles []string
+
+	// Global tokens are created in the authoritative region and replicated
+	// to the other regions. Other tokens are local to their region.
+	Global bool
+
+	// AllowedCIDRs are the address ranges the requests made with the token
+	// may come from. The token may be used from anywhere if it has none.
+	AllowedCIDRs []string
+
+	// Hash is the hash of the token, used to compare tokens replicated
+	// across regions
+	Hash []byte
+
+	// CreateTime is the time the token was created at
+	CreateTime time.Time
+
+	// ExpirationTime is the time after which the token is no longer valid.
+	// Tokens without one never expire.
+	ExpirationTime *time.Time
+
+	// ExpirationTTL sets the expiration time of a new token relative to its
+	// creation time
+	ExpirationTTL time.Duration
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// ACLTokenListStub is the stub of a token returned by list requests
+type ACLTokenListStub struct {
+	AccessorID     string
+	Name           string
+	Type           string
+	Policies       []string
+	Roles          []string
+	Global         bool
+	Hash           []byte
+	CreateTime     time.Time
+	ExpirationTime *time.Time
+	CreateIndex    uint64
+	ModifyIndex    uint64
+}
+
+// SetHash computes and sets the hash of the token
+func (a *ACLToken) SetHash() []byte {
+	hash := sha256.New()
+	hash.Write([]byte(a.Name))
+	hash.Write([]byte(a.Type))
+	for _, policy := range a.Policies {
+		hash.Write([]byte(policy))
+	}
+	for _, role := range a.Roles {
+		hash.Write([]byte(role))
+	}
+	hash.Write([]byte(strconv.FormatBool(a.Global)))
+	for _, cidr := range a.AllowedCIDRs {
+		hash.Write([]byte(cidr))
+	}
+	if a.ExpirationTime != nil {
+		hash.Write([]byte(a.ExpirationTime.UTC().Format(time.RFC3339Nano)))
+	}
+	a.Hash = hash.Sum(nil)
+	return a.Hash
+}
+
+// Stub returns the stub of the token
+func (a *ACLToken) Stub() *ACLTokenListStub {
+	return &ACLTokenListStub{
+		AccessorID:     a.AccessorID,
+		Name:           a.Name,
+		Type:           a.Type,
+		Policies:       a.Policies,
+		Roles:          a.Roles,
+		Global:         a.Global,
+		Hash:           a.Hash,
+		CreateTime:     a.CreateTime,
+		ExpirationTime: a.ExpirationTime,
+		CreateIndex:    a.CreateIndex,
+		ModifyIndex:    a.ModifyIndex,
+	}
+}
+
+// Copy returns a copy of the token
+func (a *ACLToken) Copy() *ACLToken {
+	if a == nil {
+		return nil
+	}
+	c := new(ACLToken)
+	*c = *a
+	c.Policies = helper.CopySliceString(a.Policies)
+	c.Roles = helper.CopySliceString(a.Roles)
+	c.AllowedCIDRs = helper.CopySliceString(a.AllowedCIDRs)
+	c.Hash = append([]byte(nil), a.Hash...)
+	if a.ExpirationTime != nil {
+		t := *a.ExpirationTime
+		c.ExpirationTime = &t
+	}
+	return c
+}
+
+// AllowsSource returns whether the token may be used from the IP address
+func (a *ACLToken) AllowsSource(ip string) bool {
+	if len(a.AllowedCIDRs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range a.AllowedCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired returns whether the token has expired at the given time
+func (a *ACLToken) IsExpired(t time.Time) bool {
+	return a.ExpirationTime != nil && !t.Before(*a.ExpirationTime)
+}
+
+// Validate returns an error if the token is invalid. The expiration of the
+// tokens that set one must be at least minTTL and at most maxTTL after their
+// creation.
+func (a *ACLToken) Validate(minTTL, maxTTL time.Duration) error {
+	var mErr multierror.Error
+	if len(a.Name) > maxACLTokenNameLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("name longer than %d", maxACLTokenNameLength))
+	}
+	switch a.Type {
+	case ACLClientToken:
+		if len(a.Policies) == 0 && len(a.Roles) == 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("client token missing policies or roles"))
+		}
+	case ACLManagementToken:
+		if len(a.Policies) != 0 || len(a.Roles) != 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("management token cannot be associated with policies or roles"))
+		}
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("token type must be %q or %q", ACLClientToken, ACLManagementToken))
+	}
+
+	for _, cidr := range a.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid allowed CIDR %q", cidr))
+		}
+	}
+
+	if a.ExpirationTTL < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("expiration TTL can't be negative"))
+	}
+	if a.ExpirationTime != nil {
+		ttl := a.ExpirationTime.Sub(a.CreateTime)
+		if ttl < minTTL {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("expiration time must be at least %v after creation", minTTL))
+		}
+		if ttl > maxTTL {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("expiration time can't be more than %v after creation", maxTTL))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// CompileACLObject compiles the policies into an ACL, caching the ACL by the
+// hashes of the policies. It is shared by the servers and the clients.
+func CompileACLObject(cache *lru.TwoQueueCache, policies []*ACLPolicy) (*acl.ACL, error) {
+	// The cache key is the hash of the sorted hashes of the policies, which
+	// change whenever a policy does
+	hashes := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		hashes = append(hashes, string(policy.Hash))
+	}
+	sort.Strings(hashes)
+	hash := sha256.New()
+	for _, h := range hashes {
+		hash.Write([]byte(h))
+	}
+	key := string(hash.Sum(nil))
+
+	if cached, ok := cache.Get(key); ok {
+		return cached.(*acl.ACL), nil
+	}
+
+	parsed := make([]*acl.Policy, 0, len(policies))
+	for _, policy := range policies {
+		p, err := acl.Parse(policy.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse policy %q: %v", policy.Name, err)
+		}
+		parsed = append(parsed, p)
+	}
+
+	aclObj := acl.NewACL(false, parsed)
+	cache.Add(key, aclObj)
+	return aclObj, nil
+}
+
+// ACLPolicyUpsertRequest is used to create or update a set of policies
+type ACLPolicyUpsertRequest struct {
+	Policies []*ACLPolicy
+	WriteRequest
+}
+
+// ACLPolicyDeleteRequest is used to delete a set of policies
+type ACLPolicyDeleteRequest struct {
+	Names []string
+	WriteRequest
+}
+
+// ACLPolicyListRequest is used to list the policies
+type ACLPolicyListRequest struct {
+	QueryOptions
+}
+
+// ACLPolicyListResponse is used for a list request
+type ACLPolicyListResponse struct {
+	Policies []*ACLPolicyListStub
+	QueryMeta
+}
+
+// ACLPolicySpecificRequest is used to query a specific policy
+type ACLPolicySpecificRequest struct {
+	Name string
+	QueryOptions
+}
+
+// SingleACLPolicyResponse is used to return a single policy
+type SingleACLPolicyResponse struct {
+	Policy *ACLPolicy
+	QueryMeta
+}
+
+// ACLPolicySetRequest is used to query a set of policies
+type ACLPolicySetRequest struct {
+	Names []string
+	QueryOptions
+}
+
+// ACLPolicySetResponse is used to return a set of policies
+type ACLPolicySetResponse struct {
+	Policies map[string]*ACLPolicy
+	QueryMeta
+}
+
+// ACLRoleUpsertRequest is used to create or update a set of roles
+type ACLRoleUpsertRequest struct {
+	Roles []*ACLRole
+	WriteRequest
+}
+
+// ACLRoleDeleteRequest is used to delete a set of roles
+type ACLRoleDeleteRequest struct {
+	Names []string
+	WriteRequest
+}
+
+// ACLRoleListRequest is used to list the roles
+type ACLRoleListRequest struct {
+	QueryOptions
+}
+
+// ACLRoleListResponse is used for a list request
+type ACLRoleListResponse struct {
+	Roles []*ACLRoleListStub
+	QueryMeta
+}
+
+// ACLRoleSpecificRequest is used to query a specific role
+type ACLRoleSpecificRequest struct {
+	Name string
+	QueryOptions
+}
+
+// SingleACLRoleResponse is used to return a single role
+type SingleACLRoleResponse struct {
+	Role *ACLRole
+	QueryMeta
+}
+
+// ACLRoleSetRequest is used to query a set of roles
+type ACLRoleSetRequest struct {
+	Names []string
+	QueryOptions
+}
+
+// ACLRoleSetResponse is used to return a set of roles
+type ACLRoleSetResponse struct {
+	Roles map[string]*ACLRole
+	QueryMeta
+}
+
+// ACLAuthMethodUpsertRequest is used to create or update a set of auth methods
+type ACLAuthMethodUpsertRequest struct {
+	AuthMethods []*ACLAuthMethod
+	WriteRequest
+}
+
+// ACLAuthMethodDeleteRequest is used to delete a set of auth methods
+type ACLAuthMethodDeleteRequest struct {
+	Names []string
+	WriteRequest
+}
+
+// ACLAuthMethodListRequest is used to list the auth methods
+type ACLAuthMethodListRequest struct {
+	QueryOptions
+}
+
+// ACLAuthMethodListResponse is used for a list request
+type ACLAuthMethodListResponse struct {
+	AuthMethods []*ACLAuthMethodStub
+	QueryMeta
+}
+
+// ACLAuthMethodSpecificRequest is used to query a specific auth method
+type ACLAuthMethodSpecificRequest struct {
+	Name string
+	QueryOptions
+}
+
+// SingleACLAuthMethodResponse is used to return a single auth method
+type SingleACLAuthMethodResponse struct {
+	AuthMethod *ACLAuthMethod
+	QueryMeta
+}
+
+// ACLAuthMethodSetRequest is used to query a set of auth methods
+type ACLAuthMethodSetRequest struct {
+	Names []string
+	QueryOptions
+}
+
+// ACLAuthMethodSetResponse is used to return a set of auth methods
+type ACLAuthMethodSetResponse struct {
+	AuthMethods map[string]*ACLAuthMethod
+	QueryMeta
+}
+
+// ACLBindingRuleUpsertRequest is used to create or update a set of binding
+// rules
+type ACLBindingRuleUpsertRequest struct {
+	BindingRules []*ACLBindingRule
+	WriteRequest
+}
+
+// ACLBindingRuleUpsertResponse is used to return the binding rules of an
+// upsert
+type ACLBindingRuleUpsertResponse struct {
+	BindingRules []*ACLBindingRule
+	WriteMeta
+}
+
+// ACLBindingRuleDeleteRequest is used to delete a set of binding rules
+type ACLBindingRuleDeleteRequest struct {
+	IDs []string
+	WriteRequest
+}
+
+// ACLBindingRuleListRequest is used to list the binding rules
+type ACLBindingRuleListRequest struct {
+	QueryOptions
+}
+
+// ACLBindingRuleListResponse is used for a list request
+type ACLBindingRuleListResponse struct {
+	BindingRules []*ACLBindingRule
+	QueryMeta
+}
+
+// ACLBindingRuleSpecificRequest is used to query a specific binding rule
+type ACLBindingRuleSpecificRequest struct {
+	ID string
+	QueryOptions
+}
+
+// SingleACLBindingRuleResponse is used to return a single binding rule
+type SingleACLBindingRuleResponse struct {
+	BindingRule *ACLBindingRule
+	QueryMeta
+}
+
+// ACLBindingRuleSetRequest is used to query a set of binding rules
+type ACLBindingRuleSetRequest struct {
+	IDs []string
+	QueryOptions
+}
+
+// ACLBindingRuleSetResponse is used to return a set of binding rules
+type ACLBindingRuleSetResponse struct {
+	BindingRules map[string]*ACLBindingRule
+	QueryMeta
+}
+
+// ACLOIDCAuthURLRequest is used to start logging in with an OIDC auth method
+type ACLOIDCAuthURLRequest struct {
+	// AuthMethodName is the name of the auth method, or empty for the
+	// default one
+	AuthMethodName string
+
+	// RedirectURI is where the OIDC provider redirects the user to once
+	// logged in. It must be one of the allowed redirect URIs of the auth
+	// method.
+	RedirectURI string
+
+	// ClientNonce is a secret of the client completing the login, which
+	// binds the login to it
+	ClientNonce string
+
+	WriteRequest
+}
+
+// ACLOIDCAuthURLResponse returns the URL the user logs in at
+type ACLOIDCAuthURLResponse struct {
+	AuthURL string
+	WriteMeta
+}
+
+// ACLOIDCCompleteAuthRequest is used to exchange the authorization code the
+// OIDC provider redirected the user with for an ACL token
+type ACLOIDCCompleteAuthRequest struct {
+	AuthMethodName string
+	ClientNonce    string
+	State          string
+	Code           string
+	RedirectURI    string
+	WriteRequest
+}
+
+// ACLLoginRequest is used to exchange a JWT for an ACL token with a JWT auth
+// method
+type ACLLoginRequest struct {
+	// AuthMethodName is the name of the auth method, or empty for the
+	// default one
+	AuthMethodName string
+
+	// LoginToken is the JWT asserting the identity
+	LoginToken string
+
+	WriteRequest
+}
+
+// ACLLoginResponse returns the token created by a login
+type ACLLoginResponse struct {
+	Token *ACLToken
+	WriteMeta
+}
+
+// ACLTokenBootstrapRequest is used to create the initial management token
+type ACLTokenBootstrapRequest struct {
+	Token *ACLToken
+	WriteRequest
+}
+
+// ACLTokenUpsertRequest is used to create or update a set of tokens
+type ACLTokenUpsertRequest struct {
+	Tokens []*ACLToken
+	WriteRequest
+}
+
+// ACLTokenUpsertResponse is used to return the tokens of an upsert
+type ACLTokenUpsertResponse struct {
+	Tokens []*ACLToken
+	WriteMeta
+}
+
+// ACLTokenDeleteRequest is used to delete a set of tokens
+type ACLTokenDeleteRequest struct {
+	AccessorIDs []string
+	WriteRequest
+}
+
+// ACLTokenListRequest is used to list the tokens
+type ACLTokenListRequest struct {
+	GlobalOnly bool
+	QueryOptions
+}
+
+// ACLTokenListResponse is used for a list request
+type ACLTokenListResponse struct {
+	Tokens []*ACLTokenListStub
+	QueryMeta
+}
+
+// ACLTokenSpecificRequest is used to query a specific token
+type ACLTokenSpecificRequest struct {
+	AccessorID string
+	QueryOptions
+}
+
+// SingleACLTokenResponse is used to return a single token
+type SingleACLTokenResponse struct {
+	Token *ACLToken
+	QueryMeta
+}
+
+// ACLTokenSetRequest is used to query a set of tokens
+type ACLTokenSetRequest struct {
+	AccessorIDs []string
+	QueryOptions
+}
+
+// ACLTokenSetResponse is used to return a set of tokens
+type ACLTokenSetResponse struct {
+	Tokens map[string]*ACLToken
+	QueryMeta
+}
+
+// ResolveACLTokenRequest is used to resolve the secret ID of a token
+type ResolveACLTokenRequest struct {
+	SecretID string
+	QueryOptions
+}
+
+// ResolveACLTokenResponse is used to return the resolved token and its
+// policies, including the policies of its roles
+type ResolveACLTokenResponse struct {
+	Token    *ACLToken
+	Policies []*ACLPolicy
+	QueryMeta
+}
@@ -0,0 +1,158 @@
+package structs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestACLIdentity_Bind(t *testing.T) {
+	identity := &ACLIdentity{
+		Values: map[string]string{"team": "web"},
+		Lists:  map[string][]string{"groups": {"engineering", "oncall"}},
+	}
+
+	rules := []*ACLBindingRule{
+		{ID: "team", Selector: `"engineering" in list.groups`, BindType: ACLBindingRuleBindTypePolicy, BindName: "${value.team}-dev"},
+		{ID: "oncall", Selector: `"oncall" in list.groups`, BindType: ACLBindingRuleBindTypeRole, BindName: "oncall"},
+		{ID: "sales", Selector: `"sales" in list.groups`, BindType: ACLBindingRuleBindTypeRole, BindName: "sales"},
+		{ID: "missing", BindType: ACLBindingRuleBindTypePolicy, BindName: "${value.region}"},
+		{ID: "everyone", BindType: ACLBindingRuleBindTypePolicy, BindName: "web-dev"},
+	}
+	token, err := identity.Bind(rules)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if token == nil || token.Type != ACLClientToken {
+		t.Fatalf("bad: %#v", token)
+	}
+	if !reflect.DeepEqual(token.Policies, []string{"web-dev"}) || !reflect.DeepEqual(token.Roles, []string{"oncall"}) {
+		t.Fatalf("bad: %v %v", token.Policies, token.Roles)
+	}
+
+	// Management rules grant management tokens
+	rules = append(rules, &ACLBindingRule{ID: "admins", Selector: `value.team == "web"`, BindType: ACLBindingRuleBindTypeManagement})
+	token, err = identity.Bind(rules)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if token == nil || token.Type != ACLManagementToken || token.Policies != nil || token.Roles != nil {
+		t.Fatalf("bad: %#v", token)
+	}
+
+	// No token is granted if no rule matches
+	token, err = identity.Bind(rules[2:4])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if token != nil {
+		t.Fatalf("bad: %#v", token)
+	}
+}
+
+func TestACLAuthMethod_Validate(t *testing.T) {
+	method := &ACLAuthMethod{
+		Name:          "okta",
+		Type:          ACLAuthMethodTypeOIDC,
+		TokenLocality: ACLAuthMethodTokenLocalityGlobal,
+		MaxTokenTTL:   time.Hour,
+		Config: &ACLAuthMethodConfig{
+			OIDCDiscoveryURL:    "https://example.okta.com",
+			OIDCClientID:        "nomad",
+			AllowedRedirectURIs: []string{"http://localhost:4649/oidc/callback"},
+			ClaimMappings:       map[string]string{"team": "team"},
+			ListClaimMappings:   map[string]string{"groups": "groups"},
+		},
+	}
+	if err := method.Validate(time.Minute, 24*time.Hour); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	method.Type = "LDAP"
+	method.MaxTokenTTL = 48 * time.Hour
+	method.Config.ListClaimMappings["team"] = "teams"
+	method.Config.SigningAlgs = []string{"RS256", "HS256"}
+	err := method.Validate(time.Minute, 24*time.Hour)
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{"type must be", "max token TTL", "both a value and a list", "signing algorithm \"HS256\""} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q error, got %v", expected, err)
+		}
+	}
+}
+
+func TestACLAuthMethod_Validate_JWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pubKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	method := &ACLAuthMethod{
+		Name:          "ci",
+		Type:          ACLAuthMethodTypeJWT,
+		TokenLocality: ACLAuthMethodTokenLocalityLocal,
+		MaxTokenTTL:   time.Hour,
+		Config: &ACLAuthMethodConfig{
+			JWTValidationPubKeys: []string{pubKey},
+			BoundIssuer:          "https://ci.example.com",
+			BoundClaims:          map[string][]string{"ref": {"refs/heads/main"}},
+		},
+	}
+	if err := method.Validate(time.Minute, 24*time.Hour); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The keys come from exactly one source
+	method.Config.JWKSURL = "https://ci.example.com/keys"
+	method.Config.JWTValidationPubKeys = []string{"not a key"}
+	method.Config.BoundClaims["sub"] = nil
+	err = method.Validate(time.Minute, 24*time.Hour)
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{"exactly one of", "invalid JWT validation public key", "bound claim \"sub\""} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q error, got %v", expected, err)
+		}
+	}
+}
+
+func TestACLToken_AllowsSource(t *testing.T) {
+	t.Parallel()
+	token := &ACLToken{Name: "ci", Type: ACLClientToken, Policies: []string{"ci"}}
+	if !token.AllowsSource("192.168.1.1") {
+		t.Fatalf("token without allowed CIDRs denied")
+	}
+
+	token.AllowedCIDRs = []string{"10.0.0.0/8", "fd00::/8"}
+	if err := token.Validate(time.Minute, time.Hour); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, ip := range []string{"10.1.2.3", "fd00::1"} {
+		if !token.AllowsSource(ip) {
+			t.Fatalf("%s denied", ip)
+		}
+	}
+	for _, ip := range []string{"192.168.1.1", "fe80::1", "", "pipe"} {
+		if token.AllowsSource(ip) {
+			t.Fatalf("%q allowed", ip)
+		}
+	}
+
+	token.AllowedCIDRs = []string{"10.0.0.1"}
+	if err := token.Validate(time.Minute, time.Hour); err == nil || !strings.Contains(err.Error(), "invalid allowed CIDR") {
+		t.Fatalf("expected invalid CIDR error: %v", err)
+	}
+}
@@ -63,6 +63,13 @@ func (b Bitmap) Clear() {
 	}
 }
 
+// Union sets the indexes set in the other bitmap, which must not be larger
+func (b Bitmap) Union(other Bitmap) {
+	for i := range other {
+		b[i] |= other[i]
+	}
+}
+
 // IndexesInRange returns the indexes in which the values are either set or unset based
 // on the passed parameter in the passed range
 func (b Bitmap) IndexesInRange(set bool, from, to uint) []int {
@@ -0,0 +1,90 @@
+package structs
+
+import (
+	"time"
+)
+
+// AllocResourceUsed is the resources an allocation actually uses, as
+// reported by its node.
+type AllocResourceUsed struct {
+	// CPU is the CPU used in MHz.
+	CPU int
+
+	// MemoryMB is the resident memory used in MB.
+	MemoryMB int
+
+	// DiskMB is the size of the allocation directory in MB.
+	DiskMB int
+}
+
+// CapacityResources are amounts of CPU, in MHz, memory and disk, in MB.
+type CapacityResources struct {
+	CPU      int
+	MemoryMB int
+	DiskMB   int
+}
+
+// Add adds the resources to the amounts.
+func (c *CapacityResources) Add(cpu, memoryMB, diskMB int) {
+	c.CPU += cpu
+	c.MemoryMB += memoryMB
+	c.DiskMB += diskMB
+}
+
+// CapacityStats is the capacity of a set of nodes and the resources reserved
+// and used by the allocations running on them.
+type CapacityStats struct {
+	// Capacity is the resources of the ready nodes available to
+	// allocations. It is only set for the cluster and for node classes.
+	Capacity CapacityResources
+
+	// Reserved is the resources reserved by the non-terminal allocations.
+	Reserved CapacityResources
+
+	// Used is the resources the allocations used when their node last
+	// reported it.
+	Used CapacityResources
+
+	// Allocations is the number of non-terminal allocations.
+	Allocations int
+}
+
+// CapacityRollup is the capacity of the cluster at a point in time, or
+// averaged over an interval.
+type CapacityRollup struct {
+	// Time is the start of the interval of the rollup.
+	Time time.Time
+
+	// Total is the capacity of the cluster.
+	Total *CapacityStats
+
+	// NodeClasses is the capacity per node class. Nodes without a class are
+	// reported as "none".
+	NodeClasses map[string]*CapacityStats
+}
+
+// CapacityResponse is the capacity of the cluster and the resources reserved
+// and used per node class, namespace and job, along with the history of the
+// capacity of the cluster.
+type CapacityResponse struct {
+	// Total is the capacity of the cluster.
+	Total *CapacityStats
+
+	// NodeClasses is the capacity per node class. Nodes without a class are
+	// reported as "none".
+	NodeClasses map[string]*CapacityStats
+
+	// Namespaces and Jobs are the resources reserved and used per namespace
+	// and per job ID.
+	Namespaces map[string]*CapacityStats
+	Jobs       map[string]*CapacityStats
+
+	// MinuteRollups are the rollups of the last hour, one per minute, and
+	// HourlyRollups the averages of the last week, one per hour. Both are
+	// oldest first and kept in memory by the leader, so they restart from
+	// scratch when the leadership changes.
+	MinuteRollups []*CapacityRollup
+	HourlyRollups []*CapacityRollup
+
+	QueryMeta
+}
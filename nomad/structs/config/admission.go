@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	// AdmissionPolicyTypeOPA evaluates jobs with the Data API of an Open
+	// Policy Agent. The job is sent as the input document and the decision
+	// is read from the result.
+	AdmissionPolicyTypeOPA = "opa"
+
+	// AdmissionPolicyTypeWebhook evaluates jobs with a webhook receiving the
+	// job and responding with the decision.
+	AdmissionPolicyTypeWebhook = "webhook"
+
+	// AdmissionEnforcementHardMandatory rejects the jobs the policy denies.
+	AdmissionEnforcementHardMandatory = "hard-mandatory"
+
+	// AdmissionEnforcementSoftMandatory rejects the jobs the policy denies
+	// unless the submitter overrides the policy with a justification.
+	AdmissionEnforcementSoftMandatory = "soft-mandatory"
+
+	// DefaultAdmissionPolicyTimeout is the default time a policy has to
+	// evaluate a job
+	DefaultAdmissionPolicyTimeout = 5 * time.Second
+)
+
+// AdmissionPolicyConfig configures an external policy engine submitted jobs
+// are evaluated against before being admitted.
+type AdmissionPolicyConfig struct {
+	// Name is the name of the policy, shown in the messages of its
+	// decisions.
+	Name string `mapstructure:"-"`
+
+	// Type is the type of the policy engine, opa or webhook.
+	Type string `mapstructure:"type"`
+
+	// Address is the URL jobs are posted to. For OPA, it is the Data API
+	// URL of the decision document, for example
+	// http://127.0.0.1:8181/v1/data/nomad/admission.
+	Address string `mapstructure:"address"`
+
+	// Timeout is the time the policy engine has to respond.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// FailOpen admits jobs when the policy engine can't be reached or
+	// returns an invalid decision. Jobs are rejected by default.
+	FailOpen bool `mapstructure:"fail_open"`
+
+	// EnforcementLevel is whether the denials of the policy can be
+	// overridden, hard-mandatory or soft-mandatory. Denials are
+	// hard-mandatory by default.
+	EnforcementLevel string `mapstructure:"enforcement_level"`
+}
+
+// Validate returns an error if the policy is misconfigured.
+func (c *AdmissionPolicyConfig) Validate() error {
+	switch c.Type {
+	case AdmissionPolicyTypeOPA, AdmissionPolicyTypeWebhook:
+	default:
+		return fmt.Errorf("admission policy %q: invalid type %q", c.Name, c.Type)
+	}
+
+	switch c.EnforcementLevel {
+	case "", AdmissionEnforcementHardMandatory, AdmissionEnforcementSoftMandatory:
+	default:
+		return fmt.Errorf("admission policy %q: invalid enforcement_level %q", c.Name, c.EnforcementLevel)
+	}
+
+	u, err := url.Parse(c.Address)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("admission policy %q: invalid address %q", c.Name, c.Address)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("admission policy %q: timeout can't be negative", c.Name)
+	}
+	return nil
+}
+
+// SoftMandatory returns whether the denials of the policy can be overridden.
+func (c *AdmissionPolicyConfig) SoftMandatory() bool {
+	return c.EnforcementLevel == AdmissionEnforcementSoftMandatory
+}
+
+// Copy returns a copy of the policy configuration.
+func (c *AdmissionPolicyConfig) Copy() *AdmissionPolicyConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(AdmissionPolicyConfig)
+	*nc = *c
+	return nc
+}
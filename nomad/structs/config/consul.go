@@ -39,6 +39,10 @@ type ConsulConfig struct {
 	// Addr is the address of the local Consul agent
 	Addr string `mapstructure:"address"`
 
+	// GRPCAddr is the address of the gRPC interface of the local Consul
+	// agent the Connect sidecar proxies get their configuration from
+	GRPCAddr string `mapstructure:"grpc_address"`
+
 	// Timeout is used by Consul HTTP Client
 	Timeout time.Duration `mapstructure:"timeout"`
 
@@ -87,6 +91,7 @@ func DefaultConsulConfig() *ConsulConfig {
 		ServerAutoJoin:     helper.BoolToPtr(true),
 		ClientAutoJoin:     helper.BoolToPtr(true),
 		Timeout:            5 * time.Second,
+		GRPCAddr:           "127.0.0.1:8502",
 	}
 }
 
@@ -106,6 +111,9 @@ func (a *ConsulConfig) Merge(b *ConsulConfig) *ConsulConfig {
 	if b.Addr != "" {
 		result.Addr = b.Addr
 	}
+	if b.GRPCAddr != "" {
+		result.GRPCAddr = b.GRPCAddr
+	}
 	if b.Timeout != 0 {
 		result.Timeout = b.Timeout
 	}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/nomad/helper"
+)
+
+const (
+	// DeploymentEventStarted is sent when a deployment is created.
+	DeploymentEventStarted = "started"
+
+	// DeploymentEventCanariesHealthy is sent when the canaries of every
+	// task group of a deployment are healthy.
+	DeploymentEventCanariesHealthy = "canaries_healthy"
+
+	// DeploymentEventPromoted is sent when the canaries of a deployment are
+	// promoted.
+	DeploymentEventPromoted = "promoted"
+
+	// DeploymentEventFailed is sent when a deployment fails.
+	DeploymentEventFailed = "failed"
+
+	// DeploymentEventReverted is sent when the job of a failed deployment
+	// is reverted to its latest stable version.
+	DeploymentEventReverted = "reverted"
+
+	// DefaultDeploymentWebhookTimeout is the default time a webhook has to
+	// respond
+	DefaultDeploymentWebhookTimeout = 10 * time.Second
+)
+
+// DeploymentEvents are the events a deployment webhook can be sent.
+var DeploymentEvents = []string{
+	DeploymentEventStarted,
+	DeploymentEventCanariesHealthy,
+	DeploymentEventPromoted,
+	DeploymentEventFailed,
+	DeploymentEventReverted,
+}
+
+// deploymentWebhookFuncs are the functions available to payload templates.
+var deploymentWebhookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	},
+}
+
+// DeploymentWebhookConfig configures a webhook the leader calls when
+// deployments transition, so that external tooling can follow rollouts.
+type DeploymentWebhookConfig struct {
+	// Name is the name of the webhook, shown in the logs.
+	Name string `mapstructure:"-"`
+
+	// Address is the URL of the webhook.
+	Address string `mapstructure:"address"`
+
+	// Method is the HTTP method of the request, POST by default.
+	Method string `mapstructure:"method"`
+
+	// Headers are added to the request.
+	Headers map[string]string `mapstructure:"-"`
+
+	// Events are the deployment events sent to the webhook, all of them if
+	// empty.
+	Events []string `mapstructure:"events"`
+
+	// Payload is a template rendered with the event to build the body of
+	// the request. The event is sent as JSON if it is empty.
+	Payload string `mapstructure:"payload"`
+
+	// Timeout is the time the webhook has to respond.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// Validate returns an error if the webhook is misconfigured.
+func (c *DeploymentWebhookConfig) Validate() error {
+	u, err := url.Parse(c.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("deployment webhook %q: invalid address %q", c.Name, c.Address)
+	}
+	switch c.Method {
+	case "", "POST", "PUT":
+	default:
+		return fmt.Errorf("deployment webhook %q: method must be POST or PUT: %q", c.Name, c.Method)
+	}
+	for _, event := range c.Events {
+		if !helper.SliceStringContains(DeploymentEvents, event) {
+			return fmt.Errorf("deployment webhook %q: invalid event %q", c.Name, event)
+		}
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("deployment webhook %q: timeout can't be negative", c.Name)
+	}
+	if _, err := c.Template(); err != nil {
+		return fmt.Errorf("deployment webhook %q: invalid payload: %v", c.Name, err)
+	}
+	return nil
+}
+
+// Subscribed returns whether the webhook is sent the event.
+func (c *DeploymentWebhookConfig) Subscribed(event string) bool {
+	return len(c.Events) == 0 || helper.SliceStringContains(c.Events, event)
+}
+
+// Template parses the payload template.
+func (c *DeploymentWebhookConfig) Template() (*template.Template, error) {
+	return template.New("payload").Funcs(deploymentWebhookFuncs).Parse(c.Payload)
+}
+
+// Copy returns a copy of the webhook configuration.
+func (c *DeploymentWebhookConfig) Copy() *DeploymentWebhookConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(DeploymentWebhookConfig)
+	*nc = *c
+	nc.Headers = helper.CopyMapStringString(c.Headers)
+	nc.Events = helper.CopySliceString(c.Events)
+	return nc
+}
@@ -0,0 +1,50 @@
+package config
+
+import "github.com/hashicorp/nomad/helper"
+
+// ImageVerificationConfig requires the images of the jobs to be verified by
+// the clients running them. The jobs using the docker driver are constrained
+// to the clients verifying the images of every namespace, unless the jobs are
+// in an exempt namespace.
+type ImageVerificationConfig struct {
+	// Enabled requires the images of the jobs to be verified
+	Enabled bool `mapstructure:"enabled"`
+
+	// ExemptNamespaces are the namespaces whose jobs may run unverified
+	// images
+	ExemptNamespaces []string `mapstructure:"exempt_namespaces"`
+}
+
+// Required returns whether the images of the jobs of the namespace must be
+// verified.
+func (c *ImageVerificationConfig) Required(namespace string) bool {
+	if c == nil || !c.Enabled {
+		return false
+	}
+	return !helper.SliceStringContains(c.ExemptNamespaces, namespace)
+}
+
+// Merge returns the configuration enabled by either, with the namespaces
+// exempted by either.
+func (c *ImageVerificationConfig) Merge(b *ImageVerificationConfig) *ImageVerificationConfig {
+	result := c.Copy()
+	if result == nil {
+		result = &ImageVerificationConfig{}
+	}
+	if b != nil {
+		result.Enabled = result.Enabled || b.Enabled
+		result.ExemptNamespaces = append(result.ExemptNamespaces, b.ExemptNamespaces...)
+	}
+	return result
+}
+
+// Copy returns a copy of the configuration.
+func (c *ImageVerificationConfig) Copy() *ImageVerificationConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(ImageVerificationConfig)
+	*nc = *c
+	nc.ExemptNamespaces = helper.CopySliceString(c.ExemptNamespaces)
+	return nc
+}
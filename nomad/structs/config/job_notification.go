@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/nomad/helper"
+)
+
+// JobNotificationConfig restricts the webhooks the notifications of jobs may
+// call. Notifications are written by job submitters but sent by the leader,
+// so without it anyone able to submit a job could make the servers send
+// requests to any address they can reach.
+type JobNotificationConfig struct {
+	// AllowedWebhooks are the URLs notification webhooks must be within: a
+	// webhook must have the scheme and host of one of them and a path at or
+	// below its path. No webhook is allowed if it is empty.
+	AllowedWebhooks []string `mapstructure:"allowed_webhooks"`
+}
+
+// Validate returns an error if an allowed webhook isn't a valid URL.
+func (c *JobNotificationConfig) Validate() error {
+	for _, allowed := range c.AllowedWebhooks {
+		if _, err := parseWebhook(allowed); err != nil {
+			return fmt.Errorf("job_notification: invalid allowed webhook %q", allowed)
+		}
+	}
+	return nil
+}
+
+// Allows returns whether a notification may call the webhook.
+func (c *JobNotificationConfig) Allows(webhook string) bool {
+	if c == nil {
+		return false
+	}
+	u, err := parseWebhook(webhook)
+	if err != nil {
+		return false
+	}
+
+	// Dot segments could walk the path out of the allowed one on servers
+	// that resolve them
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+
+	for _, raw := range c.AllowedWebhooks {
+		allowed, err := parseWebhook(raw)
+		if err != nil {
+			continue
+		}
+		if u.Scheme != allowed.Scheme || !strings.EqualFold(u.Host, allowed.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(allowed.Path, "/")
+		if u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWebhook parses the URL of a webhook, which must be an http or https
+// URL without credentials.
+func parseWebhook(webhook string) (*url.URL, error) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return nil, fmt.Errorf("invalid webhook %q", webhook)
+	}
+	return u, nil
+}
+
+// Merge returns the configuration with the webhooks allowed by either.
+func (c *JobNotificationConfig) Merge(b *JobNotificationConfig) *JobNotificationConfig {
+	result := c.Copy()
+	if result == nil {
+		result = &JobNotificationConfig{}
+	}
+	if b != nil {
+		result.AllowedWebhooks = append(result.AllowedWebhooks, b.AllowedWebhooks...)
+	}
+	return result
+}
+
+// Copy returns a copy of the configuration.
+func (c *JobNotificationConfig) Copy() *JobNotificationConfig {
+	if c == nil {
+		return nil
+	}
+	nc := new(JobNotificationConfig)
+	*nc = *c
+	nc.AllowedWebhooks = helper.CopySliceString(c.AllowedWebhooks)
+	return nc
+}
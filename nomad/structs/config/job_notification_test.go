@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestJobNotificationConfig_Allows(t *testing.T) {
+	c := &JobNotificationConfig{
+		AllowedWebhooks: []string{"https://ci.example.com/hooks", "http://10.0.0.1:8080/"},
+	}
+
+	cases := map[string]bool{
+		"https://ci.example.com/hooks":              true,
+		"https://ci.example.com/hooks/build?x=1":    true,
+		"https://CI.example.com/hooks/build":        true,
+		"https://ci.example.com/hooksx":             false,
+		"https://ci.example.com/hooks/../admin":     false,
+		"https://ci.example.com/hooks/%2e%2e/admin": false,
+		"http://ci.example.com/hooks":               false,
+		"https://ci.example.com.evil.com/hooks":     false,
+		"https://ci.example.com@evil.com/hooks":     false,
+		"http://10.0.0.1:8080/anything":             true,
+		"http://10.0.0.1:8081/anything":             false,
+		"http://169.254.169.254/latest/meta-data":   false,
+		"file:///etc/passwd":                        false,
+	}
+	for webhook, expected := range cases {
+		if actual := c.Allows(webhook); actual != expected {
+			t.Errorf("Allows(%q) = %v; want %v", webhook, actual, expected)
+		}
+	}
+
+	var nilConfig *JobNotificationConfig
+	if nilConfig.Allows("https://ci.example.com/hooks") {
+		t.Fatalf("no webhook should be allowed without configuration")
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"time"
 
 	vault "github.com/hashicorp/vault/api"
@@ -10,6 +11,18 @@ const (
 	// DefaultVaultConnectRetryIntv is the retry interval between trying to
 	// connect to Vault
 	DefaultVaultConnectRetryIntv = 30 * time.Second
+
+	// DefaultVaultIdentityTTL is the default lifetime of the workload
+	// identities signed for tasks
+	DefaultVaultIdentityTTL = 15 * time.Minute
+
+	// DefaultVaultIdentityAudience is the default audience of the workload
+	// identities signed for tasks
+	DefaultVaultIdentityAudience = "vault.io"
+
+	// DefaultVaultJWTAuthBackendPath is the default mount path of the Vault
+	// JWT auth backend tasks log in to with their workload identity
+	DefaultVaultJWTAuthBackendPath = "jwt"
 )
 
 // VaultConfig contains the configuration information necessary to
@@ -74,6 +87,41 @@ type VaultConfig struct {
 
 	// TLSServerName, if set, is used to set the SNI host when connecting via TLS.
 	TLSServerName string `mapstructure:"tls_server_name"`
+
+	// IdentitySigningKeyFile is the path to the PEM-encoded RSA private key
+	// the Nomad Servers sign the workload identities of tasks with. Tasks
+	// with a Vault role exchange their identity for a Vault token with the
+	// Vault JWT auth backend. All the Servers of a region must use the same
+	// key.
+	IdentitySigningKeyFile string `mapstructure:"identity_signing_key_file"`
+
+	// IdentityTTL is the lifetime of the workload identities. The identities
+	// are only used to log in to Vault so they can be short lived.
+	IdentityTTL string `mapstructure:"identity_ttl"`
+
+	// IdentityAudience is the audience of the workload identities, which the
+	// roles of the Vault JWT auth backend must bind.
+	IdentityAudience string `mapstructure:"identity_audience"`
+
+	// JWTAuthBackendPath is the mount path of the Vault JWT auth backend the
+	// Nomad Clients log in to with the workload identities of tasks.
+	JWTAuthBackendPath string `mapstructure:"jwt_auth_backend_path"`
+
+	// AllowedPolicies is the list of Vault policies tasks may request. Entries
+	// ending with a "*" allow every policy with their prefix. All policies are
+	// allowed if empty.
+	AllowedPolicies []string `mapstructure:"allowed_policies"`
+
+	// AllowedRoles is the list of Vault JWT auth roles tasks may log in to.
+	// Entries ending with a "*" allow every role with their prefix. All roles
+	// are allowed if empty.
+	AllowedRoles []string `mapstructure:"allowed_roles"`
+
+	// AllowedNamespaces is the list of Vault Enterprise namespaces tasks may
+	// have their tokens created in. Entries ending with a "*" allow every
+	// namespace with their prefix. The namespace of the Nomad servers is
+	// always allowed, and all namespaces are allowed if empty.
+	AllowedNamespaces []string `mapstructure:"allowed_namespaces"`
 }
 
 // DefaultVaultConfig() returns the canonical defaults for the Nomad
@@ -93,6 +141,48 @@ func (a *VaultConfig) IsEnabled() bool {
 	return a.Enabled != nil && *a.Enabled
 }
 
+// UsesIdentities returns whether the config enables signing workload
+// identities for tasks
+func (a *VaultConfig) UsesIdentities() bool {
+	return a.IdentitySigningKeyFile != ""
+}
+
+// AllowsPolicy returns whether the config allows tasks to request the Vault
+// policy
+func (a *VaultConfig) AllowsPolicy(policy string) bool {
+	return allowedByList(a.AllowedPolicies, policy)
+}
+
+// AllowsRole returns whether the config allows tasks to log in to the Vault
+// role
+func (a *VaultConfig) AllowsRole(role string) bool {
+	return allowedByList(a.AllowedRoles, role)
+}
+
+// AllowsNamespace returns whether the config allows tasks to have their token
+// created in the Vault namespace. The empty namespace of the Nomad servers is
+// always allowed.
+func (a *VaultConfig) AllowsNamespace(namespace string) bool {
+	return namespace == "" || allowedByList(a.AllowedNamespaces, namespace)
+}
+
+// allowedByList returns whether the name is allowed by a list of names and
+// prefixes ending with "*". Every name is allowed by an empty list.
+func allowedByList(list []string, name string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, allowed := range list {
+		if allowed == name {
+			return true
+		}
+		if strings.HasSuffix(allowed, "*") && strings.HasPrefix(name, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
 // AllowsUnauthenticated returns whether the config allows unauthenticated
 // access to Vault
 func (a *VaultConfig) AllowsUnauthenticated() bool {
@@ -133,6 +223,27 @@ func (a *VaultConfig) Merge(b *VaultConfig) *VaultConfig {
 	if b.TLSServerName != "" {
 		result.TLSServerName = b.TLSServerName
 	}
+	if b.IdentitySigningKeyFile != "" {
+		result.IdentitySigningKeyFile = b.IdentitySigningKeyFile
+	}
+	if b.IdentityTTL != "" {
+		result.IdentityTTL = b.IdentityTTL
+	}
+	if b.IdentityAudience != "" {
+		result.IdentityAudience = b.IdentityAudience
+	}
+	if b.JWTAuthBackendPath != "" {
+		result.JWTAuthBackendPath = b.JWTAuthBackendPath
+	}
+	if len(b.AllowedPolicies) != 0 {
+		result.AllowedPolicies = b.AllowedPolicies
+	}
+	if len(b.AllowedRoles) != 0 {
+		result.AllowedRoles = b.AllowedRoles
+	}
+	if len(b.AllowedNamespaces) != 0 {
+		result.AllowedNamespaces = b.AllowedNamespaces
+	}
 	if b.AllowUnauthenticated != nil {
 		result.AllowUnauthenticated = b.AllowUnauthenticated
 	}
@@ -179,5 +290,17 @@ func (c *VaultConfig) Copy() *VaultConfig {
 
 	nc := new(VaultConfig)
 	*nc = *c
+	if c.AllowedPolicies != nil {
+		nc.AllowedPolicies = make([]string, len(c.AllowedPolicies))
+		copy(nc.AllowedPolicies, c.AllowedPolicies)
+	}
+	if c.AllowedRoles != nil {
+		nc.AllowedRoles = make([]string, len(c.AllowedRoles))
+		copy(nc.AllowedRoles, c.AllowedRoles)
+	}
+	if c.AllowedNamespaces != nil {
+		nc.AllowedNamespaces = make([]string, len(c.AllowedNamespaces))
+		copy(nc.AllowedNamespaces, c.AllowedNamespaces)
+	}
 	return nc
 }
@@ -8,48 +8,69 @@ import (
 func TestVaultConfig_Merge(t *testing.T) {
 	trueValue, falseValue := true, false
 	c1 := &VaultConfig{
-		Enabled:              &falseValue,
-		Token:                "1",
-		Role:                 "1",
-		AllowUnauthenticated: &trueValue,
-		TaskTokenTTL:         "1",
-		Addr:                 "1",
-		TLSCaFile:            "1",
-		TLSCaPath:            "1",
-		TLSCertFile:          "1",
-		TLSKeyFile:           "1",
-		TLSSkipVerify:        &trueValue,
-		TLSServerName:        "1",
+		Enabled:                &falseValue,
+		Token:                  "1",
+		Role:                   "1",
+		AllowUnauthenticated:   &trueValue,
+		TaskTokenTTL:           "1",
+		Addr:                   "1",
+		TLSCaFile:              "1",
+		TLSCaPath:              "1",
+		TLSCertFile:            "1",
+		TLSKeyFile:             "1",
+		TLSSkipVerify:          &trueValue,
+		TLSServerName:          "1",
+		IdentitySigningKeyFile: "1",
+		IdentityTTL:            "1",
+		IdentityAudience:       "1",
+		JWTAuthBackendPath:     "1",
+		AllowedPolicies:        []string{"1"},
+		AllowedRoles:           []string{"1"},
+		AllowedNamespaces:      []string{"1"},
 	}
 
 	c2 := &VaultConfig{
-		Enabled:              &trueValue,
-		Token:                "2",
-		Role:                 "2",
-		AllowUnauthenticated: &falseValue,
-		TaskTokenTTL:         "2",
-		Addr:                 "2",
-		TLSCaFile:            "2",
-		TLSCaPath:            "2",
-		TLSCertFile:          "2",
-		TLSKeyFile:           "2",
-		TLSSkipVerify:        nil,
-		TLSServerName:        "2",
+		Enabled:                &trueValue,
+		Token:                  "2",
+		Role:                   "2",
+		AllowUnauthenticated:   &falseValue,
+		TaskTokenTTL:           "2",
+		Addr:                   "2",
+		TLSCaFile:              "2",
+		TLSCaPath:              "2",
+		TLSCertFile:            "2",
+		TLSKeyFile:             "2",
+		TLSSkipVerify:          nil,
+		TLSServerName:          "2",
+		IdentitySigningKeyFile: "2",
+		IdentityTTL:            "2",
+		IdentityAudience:       "2",
+		JWTAuthBackendPath:     "2",
+		AllowedPolicies:        []string{"2"},
+		AllowedRoles:           []string{"2"},
+		AllowedNamespaces:      []string{"2"},
 	}
 
 	e := &VaultConfig{
-		Enabled:              &trueValue,
-		Token:                "2",
-		Role:                 "2",
-		AllowUnauthenticated: &falseValue,
-		TaskTokenTTL:         "2",
-		Addr:                 "2",
-		TLSCaFile:            "2",
-		TLSCaPath:            "2",
-		TLSCertFile:          "2",
-		TLSKeyFile:           "2",
-		TLSSkipVerify:        &trueValue,
-		TLSServerName:        "2",
+		Enabled:                &trueValue,
+		Token:                  "2",
+		Role:                   "2",
+		AllowUnauthenticated:   &falseValue,
+		TaskTokenTTL:           "2",
+		Addr:                   "2",
+		TLSCaFile:              "2",
+		TLSCaPath:              "2",
+		TLSCertFile:            "2",
+		TLSKeyFile:             "2",
+		TLSSkipVerify:          &trueValue,
+		TLSServerName:          "2",
+		IdentitySigningKeyFile: "2",
+		IdentityTTL:            "2",
+		IdentityAudience:       "2",
+		JWTAuthBackendPath:     "2",
+		AllowedPolicies:        []string{"2"},
+		AllowedRoles:           []string{"2"},
+		AllowedNamespaces:      []string{"2"},
 	}
 
 	result := c1.Merge(c2)
@@ -57,3 +78,55 @@ func TestVaultConfig_Merge(t *testing.T) {
 		t.Fatalf("bad:\n%#v\n%#v", result, e)
 	}
 }
+
+func TestVaultConfig_AllowsPolicy(t *testing.T) {
+	c := &VaultConfig{}
+	if !c.AllowsPolicy("foo") || !c.AllowsRole("foo") {
+		t.Fatalf("expected empty lists to allow everything")
+	}
+
+	c.AllowedPolicies = []string{"foo", "team-a-*"}
+	c.AllowedRoles = []string{"web"}
+	cases := []struct {
+		Name    string
+		Allowed bool
+	}{
+		{"foo", true},
+		{"foobar", false},
+		{"team-a-db", true},
+		{"team-a-", true},
+		{"team-b-db", false},
+	}
+	for _, tc := range cases {
+		if allowed := c.AllowsPolicy(tc.Name); allowed != tc.Allowed {
+			t.Errorf("policy %q: expected allowed %v; got %v", tc.Name, tc.Allowed, allowed)
+		}
+	}
+	if !c.AllowsRole("web") || c.AllowsRole("web-admin") {
+		t.Fatalf("bad role allowlist")
+	}
+}
+
+func TestVaultConfig_AllowsNamespace(t *testing.T) {
+	c := &VaultConfig{}
+	if !c.AllowsNamespace("team-a") {
+		t.Fatalf("expected empty list to allow every namespace")
+	}
+
+	c.AllowedNamespaces = []string{"team-a", "team-b/*"}
+	cases := []struct {
+		Name    string
+		Allowed bool
+	}{
+		{"", true},
+		{"team-a", true},
+		{"team-a/child", false},
+		{"team-b/child", true},
+		{"team-c", false},
+	}
+	for _, tc := range cases {
+		if allowed := c.AllowsNamespace(tc.Name); allowed != tc.Allowed {
+			t.Errorf("namespace %q: expected allowed %v; got %v", tc.Name, tc.Allowed, allowed)
+		}
+	}
+}
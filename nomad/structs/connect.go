@@ -0,0 +1,660 @@
+package structs
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/helper"
+	"github.com/mitchellh/copystructure"
+)
+
+const (
+	// ConnectProxyPrefix is the prefix of the kind and name of the sidecar
+	// proxy tasks injected for Consul Connect services.
+	ConnectProxyPrefix = "connect-proxy"
+
+	// ConnectIngressPrefix, ConnectTerminatingPrefix and ConnectMeshPrefix
+	// are the prefixes of the kinds of the Connect gateway tasks.
+	ConnectIngressPrefix     = "connect-ingress"
+	ConnectTerminatingPrefix = "connect-terminating"
+	ConnectMeshPrefix        = "connect-mesh"
+)
+
+// TaskKind identifies the special role of a task. Tasks injected by Nomad,
+// such as the Connect sidecar proxies, have a kind of the form
+// "<kind>:<value>" while the tasks defined by users have none.
+type TaskKind string
+
+// NewTaskKind returns the task kind for the given kind and value.
+func NewTaskKind(name, value string) TaskKind {
+	return TaskKind(fmt.Sprintf("%s:%s", name, value))
+}
+
+// Name returns the kind of the task.
+func (k TaskKind) Name() string {
+	return strings.Split(string(k), ":")[0]
+}
+
+// Value returns the value of the kind, such as the name of the service a
+// Connect proxy is a sidecar of.
+func (k TaskKind) Value() string {
+	if s := strings.SplitN(string(k), ":", 2); len(s) > 1 {
+		return s[1]
+	}
+	return ""
+}
+
+// IsConnectProxy returns whether the task is a Connect sidecar proxy.
+func (k TaskKind) IsConnectProxy() bool {
+	return strings.HasPrefix(string(k), ConnectProxyPrefix+":") && len(k) > len(ConnectProxyPrefix)+1
+}
+
+// IsConnectGateway returns whether the task is a Connect ingress, terminating
+// or mesh gateway.
+func (k TaskKind) IsConnectGateway() bool {
+	switch k.Name() {
+	case ConnectIngressPrefix, ConnectTerminatingPrefix, ConnectMeshPrefix:
+		return k.Value() != ""
+	}
+	return false
+}
+
+// ConnectProxyPortLabel returns the label of the port the sidecar proxy of the
+// given service listens on.
+func ConnectProxyPortLabel(service string) string {
+	return fmt.Sprintf("%s-%s", ConnectProxyPrefix, service)
+}
+
+// ConsulConnect enables a service to join the Consul Connect service mesh,
+// either through a sidecar proxy or as a gateway.
+type ConsulConnect struct {
+	// SidecarService is the definition of the sidecar proxy service that
+	// Nomad registers in Consul for the service.
+	SidecarService *ConsulSidecarService
+
+	// SidecarTask overrides the defaults of the sidecar proxy task Nomad
+	// injects in the task group.
+	SidecarTask *SidecarTask
+
+	// Gateway makes the task defining the service a Connect gateway.
+	Gateway *ConsulGateway
+}
+
+// IsGateway returns whether the service is a Connect gateway.
+func (c *ConsulConnect) IsGateway() bool {
+	return c != nil && c.Gateway != nil
+}
+
+// Copy returns a deep copy of the Connect block.
+func (c *ConsulConnect) Copy() *ConsulConnect {
+	if c == nil {
+		return nil
+	}
+	return &ConsulConnect{
+		SidecarService: c.SidecarService.Copy(),
+		SidecarTask:    c.SidecarTask.Copy(),
+		Gateway:        c.Gateway.Copy(),
+	}
+}
+
+// Validate returns an error if the Connect block is invalid.
+func (c *ConsulConnect) Validate() error {
+	if c.Gateway != nil {
+		if c.SidecarService != nil || c.SidecarTask != nil {
+			return fmt.Errorf("Consul Connect gateway can't have a sidecar_service or sidecar_task")
+		}
+		return c.Gateway.Validate()
+	}
+	if c.SidecarService == nil {
+		return fmt.Errorf("Consul Connect must have a sidecar_service or a gateway")
+	}
+
+	var mErr multierror.Error
+	if err := c.SidecarService.Validate(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+	if c.SidecarTask != nil {
+		if err := c.SidecarTask.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ConsulSidecarService is the sidecar proxy service registered in Consul.
+type ConsulSidecarService struct {
+	// Proxy configures the proxy, such as the upstream services it exposes
+	// to the task.
+	Proxy *ConsulProxy
+}
+
+// Copy returns a deep copy of the sidecar service.
+func (s *ConsulSidecarService) Copy() *ConsulSidecarService {
+	if s == nil {
+		return nil
+	}
+	return &ConsulSidecarService{
+		Proxy: s.Proxy.Copy(),
+	}
+}
+
+// Validate returns an error if the sidecar service is invalid.
+func (s *ConsulSidecarService) Validate() error {
+	if s.Proxy == nil {
+		return nil
+	}
+
+	var mErr multierror.Error
+	if err := s.Proxy.MeshGateway.Validate(); err != nil {
+		mErr.Errors = append(mErr.Errors, err)
+	}
+
+	ports := make(map[string]string, len(s.Proxy.Upstreams))
+	for i, u := range s.Proxy.Upstreams {
+		if u.DestinationName == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("upstream %d is missing a destination_name", i+1))
+		}
+		if u.LocalBindAddress != "" && net.ParseIP(u.LocalBindAddress) == nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("upstream %q has an invalid local_bind_address %q", u.DestinationName, u.LocalBindAddress))
+		}
+		if err := u.MeshGateway.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("upstream %q: %v", u.DestinationName, err))
+		}
+
+		// Upstreams may share a port as long as they bind distinct addresses
+		bind := net.JoinHostPort(u.LocalBindAddress, strconv.Itoa(u.LocalBindPort))
+		if u.LocalBindPort <= 0 || u.LocalBindPort > 65535 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("upstream %q has an invalid local_bind_port %d", u.DestinationName, u.LocalBindPort))
+		} else if other, ok := ports[bind]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("upstreams %q and %q use the same local_bind_port %d", other, u.DestinationName, u.LocalBindPort))
+		} else {
+			ports[bind] = u.DestinationName
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ConsulProxy is the configuration of a Connect sidecar proxy.
+type ConsulProxy struct {
+	// Upstreams are the services the proxy exposes locally to the task.
+	Upstreams []*ConsulUpstream
+
+	// MeshGateway is the default mesh gateway mode of the upstreams.
+	MeshGateway *ConsulMeshGateway
+}
+
+// Copy returns a deep copy of the proxy configuration.
+func (p *ConsulProxy) Copy() *ConsulProxy {
+	if p == nil {
+		return nil
+	}
+	np := &ConsulProxy{
+		MeshGateway: p.MeshGateway.Copy(),
+	}
+	if p.Upstreams != nil {
+		np.Upstreams = make([]*ConsulUpstream, len(p.Upstreams))
+		for i, u := range p.Upstreams {
+			np.Upstreams[i] = u.Copy()
+		}
+	}
+	return np
+}
+
+// ConsulUpstream is a service the sidecar proxy exposes locally to the task.
+type ConsulUpstream struct {
+	// DestinationName is the name of the upstream service.
+	DestinationName string
+
+	// DestinationNamespace is the Consul namespace of the upstream service.
+	// Empty uses the namespace of the sidecar service.
+	DestinationNamespace string
+
+	// Datacenter is the Consul datacenter of the upstream service. Empty
+	// uses the local datacenter.
+	Datacenter string
+
+	// LocalBindAddress is the address the proxy listens on for connections
+	// to the upstream service. Empty defaults to the loopback interface.
+	LocalBindAddress string
+
+	// LocalBindPort is the port the proxy listens on for connections to the
+	// upstream service.
+	LocalBindPort int
+
+	// MeshGateway overrides the mesh gateway mode of the proxy for this
+	// upstream.
+	MeshGateway *ConsulMeshGateway
+}
+
+// Copy returns a deep copy of the upstream.
+func (u *ConsulUpstream) Copy() *ConsulUpstream {
+	if u == nil {
+		return nil
+	}
+	nu := *u
+	nu.MeshGateway = u.MeshGateway.Copy()
+	return &nu
+}
+
+const (
+	// ConsulMeshGatewayModeLocal routes cross datacenter traffic through
+	// the mesh gateway of the local datacenter.
+	ConsulMeshGatewayModeLocal = "local"
+
+	// ConsulMeshGatewayModeRemote routes cross datacenter traffic through
+	// the mesh gateway of the destination datacenter.
+	ConsulMeshGatewayModeRemote = "remote"
+
+	// ConsulMeshGatewayModeNone connects directly to the destination
+	// without going through a mesh gateway.
+	ConsulMeshGatewayModeNone = "none"
+)
+
+// ConsulMeshGateway configures how a proxy reaches upstreams in other
+// datacenters.
+type ConsulMeshGateway struct {
+	// Mode is one of local, remote or none. Empty uses the Consul default.
+	Mode string
+}
+
+// Copy returns a copy of the mesh gateway configuration.
+func (g *ConsulMeshGateway) Copy() *ConsulMeshGateway {
+	if g == nil {
+		return nil
+	}
+	ng := *g
+	return &ng
+}
+
+// Validate returns an error if the mesh gateway mode is unknown.
+func (g *ConsulMeshGateway) Validate() error {
+	if g == nil {
+		return nil
+	}
+	switch g.Mode {
+	case "", ConsulMeshGatewayModeLocal, ConsulMeshGatewayModeRemote, ConsulMeshGatewayModeNone:
+		return nil
+	default:
+		return fmt.Errorf("invalid mesh_gateway mode %q", g.Mode)
+	}
+}
+
+// SidecarTask overrides the defaults of an injected sidecar proxy task.
+type SidecarTask struct {
+	Driver      string
+	User        string
+	Config      map[string]interface{}
+	Env         map[string]string
+	Resources   *Resources
+	Meta        map[string]string
+	KillTimeout *time.Duration
+	LogConfig   *LogConfig
+}
+
+// Copy returns a deep copy of the sidecar task.
+func (t *SidecarTask) Copy() *SidecarTask {
+	if t == nil {
+		return nil
+	}
+	nt := new(SidecarTask)
+	*nt = *t
+	if i, err := copystructure.Copy(nt.Config); err != nil {
+		panic(err.Error())
+	} else if i != nil {
+		nt.Config = i.(map[string]interface{})
+	}
+	nt.Env = helper.CopyMapStringString(nt.Env)
+	nt.Resources = nt.Resources.Copy()
+	nt.Meta = helper.CopyMapStringString(nt.Meta)
+	if t.KillTimeout != nil {
+		nt.KillTimeout = helper.TimeToPtr(*t.KillTimeout)
+	}
+	if t.LogConfig != nil {
+		lc := *t.LogConfig
+		nt.LogConfig = &lc
+	}
+	return nt
+}
+
+// Validate returns an error if the sidecar task is invalid.
+func (t *SidecarTask) Validate() error {
+	if t.KillTimeout != nil && *t.KillTimeout < 0 {
+		return fmt.Errorf("sidecar_task kill_timeout must be a positive value")
+	}
+	return nil
+}
+
+// MergeIntoTask overrides the fields of the task with the fields set on the
+// sidecar task. Config, Env and Meta are merged key by key.
+func (t *SidecarTask) MergeIntoTask(task *Task) {
+	if t.Driver != "" {
+		task.Driver = t.Driver
+	}
+	if t.User != "" {
+		task.User = t.User
+	}
+	if t.Config != nil {
+		if task.Config == nil {
+			task.Config = make(map[string]interface{}, len(t.Config))
+		}
+		for k, v := range t.Config {
+			task.Config[k] = v
+		}
+	}
+	if t.Env != nil {
+		if task.Env == nil {
+			task.Env = make(map[string]string, len(t.Env))
+		}
+		for k, v := range t.Env {
+			task.Env[k] = v
+		}
+	}
+	if t.Resources != nil {
+		task.Resources.Merge(t.Resources)
+	}
+	if t.Meta != nil {
+		if task.Meta == nil {
+			task.Meta = make(map[string]string, len(t.Meta))
+		}
+		for k, v := range t.Meta {
+			task.Meta[k] = v
+		}
+	}
+	if t.KillTimeout != nil {
+		task.KillTimeout = *t.KillTimeout
+	}
+	if t.LogConfig != nil {
+		if t.LogConfig.MaxFiles > 0 {
+			task.LogConfig.MaxFiles = t.LogConfig.MaxFiles
+		}
+		if t.LogConfig.MaxFileSizeMB > 0 {
+			task.LogConfig.MaxFileSizeMB = t.LogConfig.MaxFileSizeMB
+		}
+	}
+}
+
+// ConsulGateway configures a Connect ingress, terminating or mesh gateway.
+// Exactly one of Ingress, Terminating and Mesh must be set.
+type ConsulGateway struct {
+	// Proxy configures the Envoy proxy of the gateway.
+	Proxy *ConsulGatewayProxy
+
+	// Ingress is the configuration entry of an ingress gateway.
+	Ingress *ConsulIngressConfigEntry
+
+	// Terminating is the configuration entry of a terminating gateway.
+	Terminating *ConsulTerminatingConfigEntry
+
+	// Mesh makes the gateway a mesh gateway.
+	Mesh *ConsulMeshConfigEntry
+}
+
+// Prefix returns the prefix of the kind of the gateway task.
+func (g *ConsulGateway) Prefix() string {
+	switch {
+	case g.Ingress != nil:
+		return ConnectIngressPrefix
+	case g.Terminating != nil:
+		return ConnectTerminatingPrefix
+	case g.Mesh != nil:
+		return ConnectMeshPrefix
+	}
+	return ""
+}
+
+// Copy returns a deep copy of the gateway.
+func (g *ConsulGateway) Copy() *ConsulGateway {
+	if g == nil {
+		return nil
+	}
+	ng := &ConsulGateway{
+		Ingress:     g.Ingress.Copy(),
+		Terminating: g.Terminating.Copy(),
+	}
+	if g.Proxy != nil {
+		ng.Proxy = &ConsulGatewayProxy{}
+		if g.Proxy.ConnectTimeout != nil {
+			ng.Proxy.ConnectTimeout = helper.TimeToPtr(*g.Proxy.ConnectTimeout)
+		}
+	}
+	if g.Mesh != nil {
+		ng.Mesh = &ConsulMeshConfigEntry{}
+	}
+	return ng
+}
+
+// Validate returns an error if the gateway is invalid.
+func (g *ConsulGateway) Validate() error {
+	kinds := 0
+	for _, set := range []bool{g.Ingress != nil, g.Terminating != nil, g.Mesh != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("Consul gateway must have exactly one of ingress, terminating or mesh")
+	}
+
+	var mErr multierror.Error
+	if g.Proxy != nil && g.Proxy.ConnectTimeout != nil && *g.Proxy.ConnectTimeout < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("gateway proxy connect_timeout must be a positive value"))
+	}
+	if g.Ingress != nil {
+		if err := g.Ingress.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	if g.Terminating != nil {
+		if err := g.Terminating.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ConsulGatewayProxy configures the Envoy proxy of a gateway.
+type ConsulGatewayProxy struct {
+	// ConnectTimeout is the timeout of the connections the gateway opens to
+	// the upstream services.
+	ConnectTimeout *time.Duration
+}
+
+// ConsulIngressConfigEntry is the Consul configuration entry of an ingress
+// gateway.
+type ConsulIngressConfigEntry struct {
+	Listeners []*ConsulIngressListener
+}
+
+// Copy returns a deep copy of the configuration entry.
+func (e *ConsulIngressConfigEntry) Copy() *ConsulIngressConfigEntry {
+	if e == nil {
+		return nil
+	}
+	ne := &ConsulIngressConfigEntry{}
+	for _, l := range e.Listeners {
+		nl := &ConsulIngressListener{
+			Port:     l.Port,
+			Protocol: l.Protocol,
+		}
+		for _, s := range l.Services {
+			nl.Services = append(nl.Services, &ConsulIngressService{
+				Name:  s.Name,
+				Hosts: helper.CopySliceString(s.Hosts),
+			})
+		}
+		ne.Listeners = append(ne.Listeners, nl)
+	}
+	return ne
+}
+
+// Validate returns an error if the configuration entry is invalid.
+func (e *ConsulIngressConfigEntry) Validate() error {
+	if len(e.Listeners) == 0 {
+		return fmt.Errorf("ingress gateway must have at least one listener")
+	}
+
+	var mErr multierror.Error
+	ports := make(map[int]struct{}, len(e.Listeners))
+	for _, l := range e.Listeners {
+		if l.Port <= 0 || l.Port > 65535 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("ingress listener has an invalid port %d", l.Port))
+		} else if _, ok := ports[l.Port]; ok {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("ingress listener port %d is used more than once", l.Port))
+		}
+		ports[l.Port] = struct{}{}
+
+		switch l.Protocol {
+		case "tcp":
+			if len(l.Services) != 1 {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("tcp ingress listener on port %d must have exactly one service", l.Port))
+			}
+		case "http":
+			if len(l.Services) == 0 {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("http ingress listener on port %d must have at least one service", l.Port))
+			}
+		default:
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("ingress listener on port %d has an invalid protocol %q", l.Port, l.Protocol))
+		}
+
+		for _, s := range l.Services {
+			if s.Name == "" {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("ingress listener on port %d has a service without a name", l.Port))
+			} else if s.Name == "*" && l.Protocol != "http" {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("ingress listener on port %d can only use the wildcard service with http", l.Port))
+			}
+			if len(s.Hosts) != 0 && l.Protocol != "http" {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("ingress listener on port %d can only set hosts with http", l.Port))
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ConsulIngressListener is a port an ingress gateway listens on.
+type ConsulIngressListener struct {
+	Port     int
+	Protocol string
+	Services []*ConsulIngressService
+}
+
+// ConsulIngressService is a service exposed by an ingress listener.
+type ConsulIngressService struct {
+	Name  string
+	Hosts []string
+}
+
+// ConsulTerminatingConfigEntry is the Consul configuration entry of a
+// terminating gateway.
+type ConsulTerminatingConfigEntry struct {
+	Services []*ConsulLinkedService
+}
+
+// Copy returns a deep copy of the configuration entry.
+func (e *ConsulTerminatingConfigEntry) Copy() *ConsulTerminatingConfigEntry {
+	if e == nil {
+		return nil
+	}
+	ne := &ConsulTerminatingConfigEntry{}
+	for _, s := range e.Services {
+		ns := *s
+		ne.Services = append(ne.Services, &ns)
+	}
+	return ne
+}
+
+// Validate returns an error if the configuration entry is invalid.
+func (e *ConsulTerminatingConfigEntry) Validate() error {
+	if len(e.Services) == 0 {
+		return fmt.Errorf("terminating gateway must have at least one service")
+	}
+
+	var mErr multierror.Error
+	for _, s := range e.Services {
+		if s.Name == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("terminating gateway has a service without a name"))
+			continue
+		}
+		if (s.CertFile == "") != (s.KeyFile == "") {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("terminating gateway service %q must set both cert_file and key_file", s.Name))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ConsulLinkedService is an external service a terminating gateway routes
+// connections to.
+type ConsulLinkedService struct {
+	Name     string
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	SNI      string
+}
+
+// ConsulMeshConfigEntry makes a gateway a mesh gateway. Mesh gateways have no
+// configuration entry.
+type ConsulMeshConfigEntry struct{}
+
+// DeriveSITokenRequest is used to request Consul Service Identity tokens for
+// the Connect sidecar proxy tasks of an allocation, or for the Consul
+// services of its tasks.
+type DeriveSITokenRequest struct {
+	NodeID   string
+	SecretID string
+	AllocID  string
+	Tasks    []string
+
+	// Services requests a token for each Consul service of the tasks, used
+	// to register them, instead of a token for the tasks themselves
+	Services bool
+
+	QueryOptions
+}
+
+// DeriveSITokenResponse returns the Service Identity tokens of the tasks.
+type DeriveSITokenResponse struct {
+	// Tokens maps the task names to their Service Identity token
+	Tokens map[string]string
+
+	// ServiceTokens maps the task names to the Service Identity tokens of
+	// their services by service name when Services was requested
+	ServiceTokens map[string]map[string]string
+
+	// Error stores any error that occurred. Errors are stored here so we can
+	// communicate whether it is retriable
+	Error *RecoverableError
+
+	QueryMeta
+}
+
+// SITokenAccessor is a reference to a Consul Service Identity token created
+// for a task. Accessors are tracked so the tokens are revoked when the
+// allocation stops.
+type SITokenAccessor struct {
+	NodeID     string
+	AllocID    string
+	TaskName   string
+	AccessorID string
+
+	// ServiceName is the name of the service the token was created for when
+	// it is used to register a service rather than by the task
+	ServiceName string
+
+	// ConsulNamespace and ConsulPartition are the Consul namespace and
+	// admin partition the token was created in
+	ConsulNamespace string
+	ConsulPartition string
+
+	// Raft index
+	CreateIndex uint64
+}
+
+// SITokenAccessorsRequest is used to operate on a set of Service Identity
+// token accessors.
+type SITokenAccessorsRequest struct {
+	Accessors []*SITokenAccessor
+}
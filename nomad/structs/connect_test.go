@@ -0,0 +1,250 @@
+package structs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/helper"
+)
+
+func TestTaskKind(t *testing.T) {
+	kind := NewTaskKind(ConnectProxyPrefix, "web")
+	if kind != "connect-proxy:web" {
+		t.Fatalf("bad: %q", kind)
+	}
+	if kind.Name() != ConnectProxyPrefix || kind.Value() != "web" {
+		t.Fatalf("bad: %q %q", kind.Name(), kind.Value())
+	}
+	if !kind.IsConnectProxy() {
+		t.Fatalf("expected a connect proxy")
+	}
+
+	for _, k := range []TaskKind{"", "connect-proxy", "connect-proxy:", "other:web"} {
+		if k.IsConnectProxy() {
+			t.Fatalf("%q is not a connect proxy", k)
+		}
+	}
+
+	for _, prefix := range []string{ConnectIngressPrefix, ConnectTerminatingPrefix, ConnectMeshPrefix} {
+		k := NewTaskKind(prefix, "gateway")
+		if !k.IsConnectGateway() || k.IsConnectProxy() {
+			t.Fatalf("%q is a connect gateway", k)
+		}
+	}
+	for _, k := range []TaskKind{"", "connect-ingress", "connect-mesh:", "connect-proxy:web"} {
+		if k.IsConnectGateway() {
+			t.Fatalf("%q is not a connect gateway", k)
+		}
+	}
+}
+
+func TestConsulConnect_Validate(t *testing.T) {
+	c := &ConsulConnect{}
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "sidecar_service") {
+		t.Fatalf("expected missing sidecar_service error; got %v", err)
+	}
+
+	c.SidecarService = &ConsulSidecarService{
+		Proxy: &ConsulProxy{
+			Upstreams: []*ConsulUpstream{
+				{DestinationName: "db", LocalBindPort: 5432},
+				{DestinationName: "cache", LocalBindPort: 6379},
+				{
+					DestinationName:  "db",
+					Datacenter:       "dc2",
+					LocalBindAddress: "127.0.0.2",
+					LocalBindPort:    5432,
+					MeshGateway:      &ConsulMeshGateway{Mode: ConsulMeshGatewayModeRemote},
+				},
+			},
+			MeshGateway: &ConsulMeshGateway{Mode: ConsulMeshGatewayModeLocal},
+		},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c.SidecarService.Proxy.Upstreams = append(c.SidecarService.Proxy.Upstreams,
+		&ConsulUpstream{LocalBindPort: 5432},
+		&ConsulUpstream{DestinationName: "bad", LocalBindPort: 70000},
+		&ConsulUpstream{DestinationName: "addr", LocalBindAddress: "localhost", LocalBindPort: 8080},
+		&ConsulUpstream{DestinationName: "gw", LocalBindPort: 8081, MeshGateway: &ConsulMeshGateway{Mode: "foo"}},
+	)
+	c.SidecarTask = &SidecarTask{KillTimeout: helper.TimeToPtr(-1 * time.Second)}
+	err := c.Validate()
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{"missing a destination_name", "same local_bind_port", "invalid local_bind_port",
+		"invalid local_bind_address", "invalid mesh_gateway mode", "kill_timeout"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in error; got %v", expected, err)
+		}
+	}
+}
+
+func TestConsulGateway_Validate(t *testing.T) {
+	c := &ConsulConnect{
+		SidecarService: &ConsulSidecarService{},
+		Gateway: &ConsulGateway{
+			Mesh: &ConsulMeshConfigEntry{},
+		},
+	}
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "can't have a sidecar_service") {
+		t.Fatalf("expected sidecar_service error; got %v", err)
+	}
+
+	c.SidecarService = nil
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c.Gateway.Terminating = &ConsulTerminatingConfigEntry{}
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "exactly one") {
+		t.Fatalf("expected exactly one kind error; got %v", err)
+	}
+
+	c.Gateway = &ConsulGateway{
+		Proxy: &ConsulGatewayProxy{
+			ConnectTimeout: helper.TimeToPtr(-1 * time.Second),
+		},
+		Ingress: &ConsulIngressConfigEntry{
+			Listeners: []*ConsulIngressListener{
+				{
+					Port:     8080,
+					Protocol: "http",
+					Services: []*ConsulIngressService{
+						{Name: "*"},
+						{Name: "web", Hosts: []string{"web.example.com"}},
+					},
+				},
+				{
+					Port:     8080,
+					Protocol: "tcp",
+					Services: []*ConsulIngressService{
+						{Name: "db", Hosts: []string{"db.example.com"}},
+						{Name: "*"},
+					},
+				},
+				{
+					Port:     70000,
+					Protocol: "udp",
+				},
+			},
+		},
+	}
+	err := c.Validate()
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{"connect_timeout", "used more than once", "exactly one service",
+		"wildcard", "only set hosts", "invalid port", "invalid protocol"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in error; got %v", expected, err)
+		}
+	}
+
+	c.Gateway = &ConsulGateway{
+		Terminating: &ConsulTerminatingConfigEntry{
+			Services: []*ConsulLinkedService{
+				{Name: "billing", CAFile: "ca.pem", CertFile: "cert.pem"},
+				{CAFile: "ca.pem"},
+			},
+		},
+	}
+	err = c.Validate()
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{"cert_file and key_file", "without a name"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in error; got %v", expected, err)
+		}
+	}
+}
+
+func TestConsulConnect_Copy(t *testing.T) {
+	c := &ConsulConnect{
+		SidecarService: &ConsulSidecarService{
+			Proxy: &ConsulProxy{
+				Upstreams: []*ConsulUpstream{{
+					DestinationName: "db",
+					LocalBindPort:   5432,
+					MeshGateway:     &ConsulMeshGateway{Mode: ConsulMeshGatewayModeRemote},
+				}},
+			},
+		},
+		SidecarTask: &SidecarTask{
+			Config: map[string]interface{}{"image": "envoy"},
+			Env:    map[string]string{"FOO": "bar"},
+		},
+	}
+
+	n := c.Copy()
+	n.SidecarService.Proxy.Upstreams[0].LocalBindPort = 1
+	n.SidecarService.Proxy.Upstreams[0].MeshGateway.Mode = ConsulMeshGatewayModeNone
+	n.SidecarTask.Config["image"] = "other"
+	n.SidecarTask.Env["FOO"] = "baz"
+	if c.SidecarService.Proxy.Upstreams[0].LocalBindPort != 5432 {
+		t.Fatalf("upstreams not copied")
+	}
+	if c.SidecarService.Proxy.Upstreams[0].MeshGateway.Mode != ConsulMeshGatewayModeRemote {
+		t.Fatalf("mesh gateway not copied")
+	}
+	if c.SidecarTask.Config["image"] != "envoy" || c.SidecarTask.Env["FOO"] != "bar" {
+		t.Fatalf("sidecar task not copied: %#v", c.SidecarTask)
+	}
+}
+
+func TestSidecarTask_MergeIntoTask(t *testing.T) {
+	task := &Task{
+		Driver: "docker",
+		Config: map[string]interface{}{
+			"image":        "envoy",
+			"network_mode": "host",
+		},
+		Resources: &Resources{
+			CPU:      250,
+			MemoryMB: 128,
+		},
+		LogConfig:   DefaultLogConfig(),
+		KillTimeout: 5 * time.Second,
+	}
+
+	sidecar := &SidecarTask{
+		Config: map[string]interface{}{
+			"image": "custom",
+		},
+		Meta: map[string]string{
+			"foo": "bar",
+		},
+		Resources: &Resources{
+			MemoryMB: 256,
+		},
+		KillTimeout: helper.TimeToPtr(time.Second),
+		LogConfig: &LogConfig{
+			MaxFiles: 2,
+		},
+	}
+	sidecar.MergeIntoTask(task)
+
+	if task.Driver != "docker" {
+		t.Fatalf("bad driver: %q", task.Driver)
+	}
+	if task.Config["image"] != "custom" || task.Config["network_mode"] != "host" {
+		t.Fatalf("bad config: %#v", task.Config)
+	}
+	if task.Meta["foo"] != "bar" {
+		t.Fatalf("bad meta: %#v", task.Meta)
+	}
+	if task.Resources.CPU != 250 || task.Resources.MemoryMB != 256 {
+		t.Fatalf("bad resources: %#v", task.Resources)
+	}
+	if task.KillTimeout != time.Second {
+		t.Fatalf("bad kill timeout: %v", task.KillTimeout)
+	}
+	if task.LogConfig.MaxFiles != 2 || task.LogConfig.MaxFileSizeMB != DefaultLogConfig().MaxFileSizeMB {
+		t.Fatalf("bad log config: %#v", task.LogConfig)
+	}
+}
@@ -0,0 +1,77 @@
+package structs
+
+const (
+	// The status of a job other jobs depend on. A job that is missing,
+	// pending, running or failed holds its dependents.
+	JobDependencyStatusMissing  = "missing"
+	JobDependencyStatusPending  = "pending"
+	JobDependencyStatusRunning  = "running"
+	JobDependencyStatusComplete = "complete"
+	JobDependencyStatusFailed   = "failed"
+)
+
+// JobDependencyStatus returns the status of a job other jobs depend on, given
+// its allocations. A nil job is missing. A dead job is failed if it was
+// stopped or if the latest allocation of any of its instances failed or was
+// lost, and complete otherwise, including once its allocations were garbage
+// collected.
+func JobDependencyStatus(job *Job, allocs []*Allocation) string {
+	switch {
+	case job == nil:
+		return JobDependencyStatusMissing
+	case job.Status == JobStatusPending:
+		return JobDependencyStatusPending
+	case job.Status != JobStatusDead:
+		return JobDependencyStatusRunning
+	case job.Stop:
+		return JobDependencyStatusFailed
+	}
+
+	for _, alloc := range LatestAllocations(allocs) {
+		switch alloc.ClientStatus {
+		case AllocClientStatusFailed, AllocClientStatusLost:
+			return JobDependencyStatusFailed
+		}
+	}
+	return JobDependencyStatusComplete
+}
+
+// LatestAllocations returns the latest allocation of each instance of a job,
+// since the failed ones may have been replaced.
+func LatestAllocations(allocs []*Allocation) []*Allocation {
+	latest := make(map[string]*Allocation, len(allocs))
+	for _, alloc := range allocs {
+		if l, ok := latest[alloc.Name]; !ok || l.CreateIndex < alloc.CreateIndex {
+			latest[alloc.Name] = alloc
+		}
+	}
+
+	out := make([]*Allocation, 0, len(latest))
+	for _, alloc := range latest {
+		out = append(out, alloc)
+	}
+	return out
+}
+
+// JobDAGNode is a job of a graph of job dependencies.
+type JobDAGNode struct {
+	JobID string
+
+	// Status is the status of the job as a dependency.
+	Status string
+
+	// DependsOn are the jobs the job depends on.
+	DependsOn []string
+
+	// Held is whether the placement of the job is held until its
+	// dependencies complete.
+	Held bool
+}
+
+// JobDependenciesResponse is the graph of dependencies a job belongs to.
+type JobDependenciesResponse struct {
+	// Nodes are the jobs connected to the job by dependencies, ordered so
+	// that jobs come after their dependencies.
+	Nodes []*JobDAGNode
+	QueryMeta
+}
@@ -0,0 +1,85 @@
+package structs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJob_Validate_DependsOn(t *testing.T) {
+	j := testJob()
+	j.DependsOn = []string{"extract"}
+	err := j.Validate()
+	if err == nil || !strings.Contains(err.Error(), "can only be used with") {
+		t.Fatalf("expected error: %v", err)
+	}
+
+	j.Type = JobTypeBatch
+	if err := j.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	j.DependsOn = []string{"extract", j.ID, "", "extract"}
+	err = j.Validate()
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{"depend on itself", `"extract" is listed twice`} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q: %v", expected, err)
+		}
+	}
+}
+
+func TestJobDependencyStatus(t *testing.T) {
+	job := testJob()
+	job.Status = JobStatusDead
+
+	alloc := func(name string, index uint64, status string) *Allocation {
+		return &Allocation{Name: name, CreateIndex: index, ClientStatus: status}
+	}
+
+	cases := []struct {
+		name     string
+		job      *Job
+		stop     bool
+		status   string
+		allocs   []*Allocation
+		expected string
+	}{
+		{"missing", nil, false, "", nil, JobDependencyStatusMissing},
+		{"pending", job, false, JobStatusPending, nil, JobDependencyStatusPending},
+		{"running", job, false, JobStatusRunning, nil, JobDependencyStatusRunning},
+		{"stopped", job, true, JobStatusDead, nil, JobDependencyStatusFailed},
+		{"collected", job, false, JobStatusDead, nil, JobDependencyStatusComplete},
+		{
+			"failed",
+			job, false, JobStatusDead,
+			[]*Allocation{
+				alloc("a[0]", 1, AllocClientStatusComplete),
+				alloc("a[1]", 2, AllocClientStatusFailed),
+			},
+			JobDependencyStatusFailed,
+		},
+		{
+			"rescheduled",
+			job, false, JobStatusDead,
+			[]*Allocation{
+				alloc("a[0]", 3, AllocClientStatusComplete),
+				alloc("a[0]", 2, AllocClientStatusLost),
+			},
+			JobDependencyStatusComplete,
+		},
+	}
+
+	for _, c := range cases {
+		j := c.job
+		if j != nil {
+			j = j.Copy()
+			j.Stop = c.stop
+			j.Status = c.status
+		}
+		if status := JobDependencyStatus(j, c.allocs); status != c.expected {
+			t.Fatalf("%s: got %q; want %q", c.name, status, c.expected)
+		}
+	}
+}
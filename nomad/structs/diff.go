@@ -62,7 +62,8 @@ func (j *Job) Diff(other *Job, contextual bool) (*JobDiff, error) {
 	diff := &JobDiff{Type: DiffTypeNone}
 	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
 	filter := []string{"ID", "Status", "StatusDescription", "Version", "Stable", "CreateIndex",
-		"ModifyIndex", "JobModifyIndex", "Update", "SubmitTime"}
+		"ModifyIndex", "JobModifyIndex", "Update", "SubmitTime", "RevertVersion", "RevertReason",
+		"PolicyOverrideJustification", "NoPlacementChange", "NotifiedIndex"}
 
 	if j == nil && other == nil {
 		return diff, nil
@@ -94,6 +95,11 @@ func (j *Job) Diff(other *Job, contextual bool) (*JobDiff, error) {
 		diff.Objects = append(diff.Objects, setDiff)
 	}
 
+	// DependsOn diff
+	if setDiff := stringSetDiff(j.DependsOn, other.DependsOn, "DependsOn", contextual); setDiff != nil && setDiff.Type != DiffTypeNone {
+		diff.Objects = append(diff.Objects, setDiff)
+	}
+
 	// Constraints diff
 	conDiff := primitiveObjectSetDiff(
 		interfaceSlice(j.Constraints),
@@ -113,10 +119,20 @@ func (j *Job) Diff(other *Job, contextual bool) (*JobDiff, error) {
 	diff.TaskGroups = tgs
 
 	// Periodic diff
-	if pDiff := primitiveObjectDiff(j.Periodic, other.Periodic, nil, "Periodic", contextual); pDiff != nil {
+	if pDiff := periodicDiff(j.Periodic, other.Periodic, contextual); pDiff != nil {
 		diff.Objects = append(diff.Objects, pDiff)
 	}
 
+	// GC diff
+	if gDiff := primitiveObjectDiff(j.GC, other.GC, nil, "GC", contextual); gDiff != nil {
+		diff.Objects = append(diff.Objects, gDiff)
+	}
+
+	// Notification diff
+	if nDiff := primitiveObjectDiff(j.Notification, other.Notification, nil, "Notification", contextual); nDiff != nil {
+		diff.Objects = append(diff.Objects, nDiff)
+	}
+
 	// ParameterizedJob diff
 	if cDiff := parameterizedJobDiff(j.ParameterizedJob, other.ParameterizedJob, contextual); cDiff != nil {
 		diff.Objects = append(diff.Objects, cDiff)
@@ -214,6 +230,20 @@ func (tg *TaskGroup) Diff(other *TaskGroup, contextual bool) (*TaskGroupDiff, er
 		newPrimitiveFlat = flatmap.Flatten(other, filter, true)
 	}
 
+	// Flatten skips pointers so add the optional durations
+	if d := tg.StopAfterClientDisconnect; d != nil {
+		oldPrimitiveFlat["StopAfterClientDisconnect"] = fmt.Sprintf("%d", *d)
+	}
+	if d := other.StopAfterClientDisconnect; d != nil {
+		newPrimitiveFlat["StopAfterClientDisconnect"] = fmt.Sprintf("%d", *d)
+	}
+	if d := tg.MaxClientDisconnect; d != nil {
+		oldPrimitiveFlat["MaxClientDisconnect"] = fmt.Sprintf("%d", *d)
+	}
+	if d := other.MaxClientDisconnect; d != nil {
+		newPrimitiveFlat["MaxClientDisconnect"] = fmt.Sprintf("%d", *d)
+	}
+
 	// Diff the primitive fields.
 	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, false)
 
@@ -240,6 +270,16 @@ func (tg *TaskGroup) Diff(other *TaskGroup, contextual bool) (*TaskGroupDiff, er
 		diff.Objects = append(diff.Objects, diskDiff)
 	}
 
+	// Consul diff
+	if cDiff := primitiveObjectDiff(tg.Consul, other.Consul, nil, "Consul", contextual); cDiff != nil {
+		diff.Objects = append(diff.Objects, cDiff)
+	}
+
+	// Networks diff
+	if nDiffs := networkResourceDiffs(tg.Networks, other.Networks, contextual); nDiffs != nil {
+		diff.Objects = append(diff.Objects, nDiffs...)
+	}
+
 	// Update diff
 	// COMPAT: Remove "Stagger" in 0.7.0.
 	if uDiff := primitiveObjectDiff(tg.Update, other.Update, []string{"Stagger"}, "Update", contextual); uDiff != nil {
@@ -403,6 +443,11 @@ func (t *Task) Diff(other *Task, contextual bool) (*TaskDiff, error) {
 		diff.Objects = append(diff.Objects, dDiff)
 	}
 
+	// Lifecycle diff
+	if lDiff := primitiveObjectDiff(t.Lifecycle, other.Lifecycle, nil, "Lifecycle", contextual); lDiff != nil {
+		diff.Objects = append(diff.Objects, lDiff)
+	}
+
 	// Artifacts diff
 	diffs := primitiveObjectSetDiff(
 		interfaceSlice(t.Artifacts),
@@ -592,6 +637,12 @@ func serviceCheckDiff(old, new *ServiceCheck, contextual bool) *ObjectDiff {
 
 	// Diff the primitive fields.
 	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Diff the CheckRestart fields.
+	if crDiff := primitiveObjectDiff(old.CheckRestart, new.CheckRestart, nil, "CheckRestart", contextual); crDiff != nil {
+		diff.Objects = append(diff.Objects, crDiff)
+	}
+
 	return diff
 }
 
@@ -659,12 +710,65 @@ func vaultDiff(old, new *Vault, contextual bool) *ObjectDiff {
 		diff.Objects = append(diff.Objects, setDiff)
 	}
 
+	// Diff the ChangeScript fields.
+	if csDiff := primitiveObjectDiff(old.ChangeScript, new.ChangeScript, nil, "ChangeScript", contextual); csDiff != nil {
+		diff.Objects = append(diff.Objects, csDiff)
+	}
+
 	return diff
 }
 
 // parameterizedJobDiff returns the diff of two parameterized job objects. If
 // contextual diff is enabled, all fields will be returned, even if no diff
 // occurred.
+// periodicDiff returns the diff of two periodic configurations. If contextual
+// diff is enabled, all fields will be returned, even if no diff occurred.
+func periodicDiff(old, new *PeriodicConfig, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "Periodic"}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		old = &PeriodicConfig{}
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	} else if new == nil {
+		new = &PeriodicConfig{}
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+	} else {
+		diff.Type = DiffTypeEdited
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	}
+
+	// Diff the primitive fields.
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+
+	// Specs diff
+	if setDiff := stringSetDiff(old.Specs, new.Specs, "Specs", contextual); setDiff != nil {
+		diff.Objects = append(diff.Objects, setDiff)
+	}
+
+	// The configurations may only differ by their unexported location
+	if diff.Type == DiffTypeEdited {
+		for _, f := range diff.Fields {
+			if f.Type != DiffTypeNone {
+				return diff
+			}
+		}
+		for _, o := range diff.Objects {
+			if o.Type != DiffTypeNone {
+				return diff
+			}
+		}
+		return nil
+	}
+
+	return diff
+}
+
 func parameterizedJobDiff(old, new *ParameterizedJobConfig, contextual bool) *ObjectDiff {
 	diff := &ObjectDiff{Type: DiffTypeNone, Name: "ParameterizedJob"}
 	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
@@ -697,6 +801,90 @@ func parameterizedJobDiff(old, new *ParameterizedJobConfig, contextual bool) *Ob
 		diff.Objects = append(diff.Objects, requiredDiff)
 	}
 
+	// Meta schema diffs
+	keys := make(map[string]struct{})
+	for k := range old.MetaSchema {
+		keys[k] = struct{}{}
+	}
+	for k := range new.MetaSchema {
+		keys[k] = struct{}{}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+	for _, k := range sortedKeys {
+		if sDiff := dispatchMetaSchemaDiff(old.MetaSchema[k], new.MetaSchema[k], k, contextual); sDiff != nil {
+			diff.Objects = append(diff.Objects, sDiff)
+		}
+	}
+
+	// Payload schema diff
+	if sDiff := dispatchPayloadSchemaDiff(old.PayloadSchema, new.PayloadSchema, contextual); sDiff != nil {
+		diff.Objects = append(diff.Objects, sDiff)
+	}
+
+	return diff
+}
+
+// dispatchMetaSchemaDiff returns the diff of the schemas of a dispatch meta
+// key. If contextual diff is enabled, all fields will be returned, even if no
+// diff occurred.
+func dispatchMetaSchemaDiff(old, new *DispatchMetaSchema, key string, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: fmt.Sprintf("MetaSchema[%s]", key)}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		old = &DispatchMetaSchema{}
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	} else if new == nil {
+		new = &DispatchMetaSchema{}
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+	} else {
+		diff.Type = DiffTypeEdited
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	}
+
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+	if setDiff := stringSetDiff(old.Allowed, new.Allowed, "Allowed", contextual); setDiff != nil {
+		diff.Objects = append(diff.Objects, setDiff)
+	}
+	return diff
+}
+
+// dispatchPayloadSchemaDiff returns the diff of two dispatch payload schemas.
+// If contextual diff is enabled, all fields will be returned, even if no diff
+// occurred.
+func dispatchPayloadSchemaDiff(old, new *DispatchPayloadSchema, contextual bool) *ObjectDiff {
+	diff := &ObjectDiff{Type: DiffTypeNone, Name: "PayloadSchema"}
+	var oldPrimitiveFlat, newPrimitiveFlat map[string]string
+
+	if reflect.DeepEqual(old, new) {
+		return nil
+	} else if old == nil {
+		old = &DispatchPayloadSchema{}
+		diff.Type = DiffTypeAdded
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	} else if new == nil {
+		new = &DispatchPayloadSchema{}
+		diff.Type = DiffTypeDeleted
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+	} else {
+		diff.Type = DiffTypeEdited
+		oldPrimitiveFlat = flatmap.Flatten(old, nil, true)
+		newPrimitiveFlat = flatmap.Flatten(new, nil, true)
+	}
+
+	diff.Fields = fieldDiffs(oldPrimitiveFlat, newPrimitiveFlat, contextual)
+	if setDiff := stringSetDiff(old.RequiredFields, new.RequiredFields, "RequiredFields", contextual); setDiff != nil {
+		diff.Objects = append(diff.Objects, setDiff)
+	}
 	return diff
 }
 
@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/hashicorp/nomad/helper"
 )
 
 func TestJobDiff(t *testing.T) {
@@ -618,6 +620,12 @@ func TestJobDiff(t *testing.T) {
 						Type: DiffTypeEdited,
 						Name: "Periodic",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeNone,
+								Name: "DSTMode",
+								Old:  "",
+								New:  "",
+							},
 							{
 								Type: DiffTypeEdited,
 								Name: "Enabled",
@@ -1171,6 +1179,51 @@ func TestTaskGroupDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			// StopAfterClientDisconnect added
+			Old: &TaskGroup{
+				Name: "foo",
+			},
+			New: &TaskGroup{
+				Name:                      "foo",
+				StopAfterClientDisconnect: helper.TimeToPtr(time.Minute),
+			},
+			Expected: &TaskGroupDiff{
+				Type: DiffTypeEdited,
+				Name: "foo",
+				Fields: []*FieldDiff{
+					{
+						Type: DiffTypeAdded,
+						Name: "StopAfterClientDisconnect",
+						Old:  "",
+						New:  "60000000000",
+					},
+				},
+			},
+		},
+		{
+			// MaxClientDisconnect edited
+			Old: &TaskGroup{
+				Name:                "foo",
+				MaxClientDisconnect: helper.TimeToPtr(time.Minute),
+			},
+			New: &TaskGroup{
+				Name:                "foo",
+				MaxClientDisconnect: helper.TimeToPtr(time.Hour),
+			},
+			Expected: &TaskGroupDiff{
+				Type: DiffTypeEdited,
+				Name: "foo",
+				Fields: []*FieldDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "MaxClientDisconnect",
+						Old:  "60000000000",
+						New:  "3600000000000",
+					},
+				},
+			},
+		},
 		{
 			// Map diff
 			Old: &TaskGroup{
@@ -1509,6 +1562,12 @@ func TestTaskGroupDiff(t *testing.T) {
 						Type: DiffTypeDeleted,
 						Name: "Update",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeDeleted,
+								Name: "AutoPromote",
+								Old:  "false",
+								New:  "",
+							},
 							{
 								Type: DiffTypeDeleted,
 								Name: "AutoRevert",
@@ -1539,6 +1598,12 @@ func TestTaskGroupDiff(t *testing.T) {
 								Old:  "0",
 								New:  "",
 							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "ProgressDeadline",
+								Old:  "0",
+								New:  "",
+							},
 						},
 					},
 				},
@@ -1559,6 +1624,12 @@ func TestTaskGroupDiff(t *testing.T) {
 						Type: DiffTypeAdded,
 						Name: "Update",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeAdded,
+								Name: "AutoPromote",
+								Old:  "",
+								New:  "false",
+							},
 							{
 								Type: DiffTypeAdded,
 								Name: "AutoRevert",
@@ -1589,6 +1660,12 @@ func TestTaskGroupDiff(t *testing.T) {
 								Old:  "",
 								New:  "0",
 							},
+							{
+								Type: DiffTypeAdded,
+								Name: "ProgressDeadline",
+								Old:  "",
+								New:  "0",
+							},
 						},
 					},
 				},
@@ -1598,22 +1675,24 @@ func TestTaskGroupDiff(t *testing.T) {
 			// Update strategy edited
 			Old: &TaskGroup{
 				Update: &UpdateStrategy{
-					MaxParallel:     5,
-					HealthCheck:     "foo",
-					MinHealthyTime:  1 * time.Second,
-					HealthyDeadline: 30 * time.Second,
-					AutoRevert:      true,
-					Canary:          2,
+					MaxParallel:      5,
+					HealthCheck:      "foo",
+					MinHealthyTime:   1 * time.Second,
+					HealthyDeadline:  30 * time.Second,
+					AutoRevert:       true,
+					Canary:           2,
+					ProgressDeadline: 1 * time.Minute,
 				},
 			},
 			New: &TaskGroup{
 				Update: &UpdateStrategy{
-					MaxParallel:     7,
-					HealthCheck:     "bar",
-					MinHealthyTime:  2 * time.Second,
-					HealthyDeadline: 31 * time.Second,
-					AutoRevert:      false,
-					Canary:          1,
+					MaxParallel:      7,
+					HealthCheck:      "bar",
+					MinHealthyTime:   2 * time.Second,
+					HealthyDeadline:  31 * time.Second,
+					AutoRevert:       false,
+					Canary:           1,
+					ProgressDeadline: 2 * time.Minute,
 				},
 			},
 			Expected: &TaskGroupDiff{
@@ -1659,6 +1738,12 @@ func TestTaskGroupDiff(t *testing.T) {
 								Old:  "1000000000",
 								New:  "2000000000",
 							},
+							{
+								Type: DiffTypeEdited,
+								Name: "ProgressDeadline",
+								Old:  "60000000000",
+								New:  "120000000000",
+							},
 						},
 					},
 				},
@@ -1669,22 +1754,24 @@ func TestTaskGroupDiff(t *testing.T) {
 			Contextual: true,
 			Old: &TaskGroup{
 				Update: &UpdateStrategy{
-					MaxParallel:     5,
-					HealthCheck:     "foo",
-					MinHealthyTime:  1 * time.Second,
-					HealthyDeadline: 30 * time.Second,
-					AutoRevert:      true,
-					Canary:          2,
+					MaxParallel:      5,
+					HealthCheck:      "foo",
+					MinHealthyTime:   1 * time.Second,
+					HealthyDeadline:  30 * time.Second,
+					AutoRevert:       true,
+					Canary:           2,
+					ProgressDeadline: 1 * time.Minute,
 				},
 			},
 			New: &TaskGroup{
 				Update: &UpdateStrategy{
-					MaxParallel:     7,
-					HealthCheck:     "foo",
-					MinHealthyTime:  1 * time.Second,
-					HealthyDeadline: 30 * time.Second,
-					AutoRevert:      true,
-					Canary:          2,
+					MaxParallel:      7,
+					HealthCheck:      "foo",
+					MinHealthyTime:   1 * time.Second,
+					HealthyDeadline:  30 * time.Second,
+					AutoRevert:       true,
+					Canary:           2,
+					ProgressDeadline: 1 * time.Minute,
 				},
 			},
 			Expected: &TaskGroupDiff{
@@ -1694,6 +1781,12 @@ func TestTaskGroupDiff(t *testing.T) {
 						Type: DiffTypeEdited,
 						Name: "Update",
 						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeNone,
+								Name: "AutoPromote",
+								Old:  "false",
+								New:  "false",
+							},
 							{
 								Type: DiffTypeNone,
 								Name: "AutoRevert",
@@ -1730,6 +1823,42 @@ func TestTaskGroupDiff(t *testing.T) {
 								Old:  "1000000000",
 								New:  "1000000000",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "ProgressDeadline",
+								Old:  "60000000000",
+								New:  "60000000000",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			// Consul namespace edited
+			Old: &TaskGroup{
+				Consul: &Consul{
+					Namespace: "foo",
+				},
+			},
+			New: &TaskGroup{
+				Consul: &Consul{
+					Namespace: "bar",
+				},
+			},
+			Expected: &TaskGroupDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Consul",
+						Fields: []*FieldDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Namespace",
+								Old:  "foo",
+								New:  "bar",
+							},
 						},
 					},
 				},
@@ -2745,6 +2874,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "",
 												New:  "foo",
 											},
+											{
+												Type: DiffTypeAdded,
+												Name: "To",
+												Old:  "",
+												New:  "0",
+											},
 											{
 												Type: DiffTypeAdded,
 												Name: "Value",
@@ -2763,6 +2898,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "",
 												New:  "baz",
 											},
+											{
+												Type: DiffTypeAdded,
+												Name: "To",
+												Old:  "",
+												New:  "0",
+											},
 										},
 									},
 								},
@@ -2789,6 +2930,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "foo",
 												New:  "",
 											},
+											{
+												Type: DiffTypeDeleted,
+												Name: "To",
+												Old:  "0",
+												New:  "",
+											},
 											{
 												Type: DiffTypeDeleted,
 												Name: "Value",
@@ -2807,6 +2954,12 @@ func TestTaskDiff(t *testing.T) {
 												Old:  "bar",
 												New:  "",
 											},
+											{
+												Type: DiffTypeDeleted,
+												Name: "To",
+												Old:  "0",
+												New:  "",
+											},
 										},
 									},
 								},
@@ -3014,6 +3167,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "boom_port",
 								New:  "boom_port",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "boom.To",
+								Old:  "0",
+								New:  "0",
+							},
 							{
 								Type: DiffTypeNone,
 								Name: "boom.Value",
@@ -3163,6 +3322,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "foo",
 								New:  "bar",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "Provider",
+								Old:  "",
+								New:  "",
+							},
 						},
 					},
 				},
@@ -3448,6 +3613,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "",
 								New:  "",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "Provider",
+								Old:  "",
+								New:  "",
+							},
 						},
 						Objects: []*ObjectDiff{
 							{
@@ -3521,6 +3692,81 @@ func TestTaskDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "Service Check CheckRestart edited",
+			Old: &Task{
+				Services: []*Service{
+					{
+						Name: "foo",
+						Checks: []*ServiceCheck{
+							{
+								Name:     "foo",
+								Type:     "tcp",
+								Interval: 1 * time.Second,
+								Timeout:  1 * time.Second,
+								CheckRestart: &CheckRestart{
+									Limit: 2,
+								},
+							},
+						},
+					},
+				},
+			},
+			New: &Task{
+				Services: []*Service{
+					{
+						Name: "foo",
+						Checks: []*ServiceCheck{
+							{
+								Name:     "foo",
+								Type:     "tcp",
+								Interval: 1 * time.Second,
+								Timeout:  1 * time.Second,
+								CheckRestart: &CheckRestart{
+									Limit: 3,
+									Grace: 10 * time.Second,
+								},
+							},
+						},
+					},
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Service",
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "Check",
+								Objects: []*ObjectDiff{
+									{
+										Type: DiffTypeEdited,
+										Name: "CheckRestart",
+										Fields: []*FieldDiff{
+											{
+												Type: DiffTypeEdited,
+												Name: "Grace",
+												Old:  "0",
+												New:  "10000000000",
+											},
+											{
+												Type: DiffTypeEdited,
+												Name: "Limit",
+												Old:  "2",
+												New:  "3",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			Name: "Vault added",
 			Old:  &Task{},
@@ -3711,6 +3957,59 @@ func TestTaskDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name: "Vault ChangeScript edited",
+			Old: &Task{
+				Vault: &Vault{
+					Policies:   []string{"foo"},
+					ChangeMode: "script",
+					ChangeScript: &ChangeScript{
+						Command: "/bin/reload",
+						Timeout: time.Second,
+					},
+				},
+			},
+			New: &Task{
+				Vault: &Vault{
+					Policies:   []string{"foo"},
+					ChangeMode: "script",
+					ChangeScript: &ChangeScript{
+						Command:     "/bin/reload",
+						Timeout:     2 * time.Second,
+						FailOnError: true,
+					},
+				},
+			},
+			Expected: &TaskDiff{
+				Type: DiffTypeEdited,
+				Objects: []*ObjectDiff{
+					{
+						Type: DiffTypeEdited,
+						Name: "Vault",
+						Objects: []*ObjectDiff{
+							{
+								Type: DiffTypeEdited,
+								Name: "ChangeScript",
+								Fields: []*FieldDiff{
+									{
+										Type: DiffTypeEdited,
+										Name: "FailOnError",
+										Old:  "false",
+										New:  "true",
+									},
+									{
+										Type: DiffTypeEdited,
+										Name: "Timeout",
+										Old:  "1000000000",
+										New:  "2000000000",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:       "Vault edited with context",
 			Contextual: true,
@@ -3755,6 +4054,18 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "true",
 								New:  "true",
 							},
+							{
+								Type: DiffTypeNone,
+								Name: "Namespace",
+								Old:  "",
+								New:  "",
+							},
+							{
+								Type: DiffTypeNone,
+								Name: "Role",
+								Old:  "",
+								New:  "",
+							},
 						},
 						Objects: []*ObjectDiff{
 							{
@@ -3801,15 +4112,16 @@ func TestTaskDiff(t *testing.T) {
 						VaultGrace:   3 * time.Second,
 					},
 					{
-						SourcePath:   "foo2",
-						DestPath:     "bar2",
-						EmbeddedTmpl: "baz2",
-						ChangeMode:   "bam2",
-						ChangeSignal: "SIGHUP2",
-						Splay:        2,
-						Perms:        "0666",
-						Envvars:      true,
-						VaultGrace:   5 * time.Second,
+						SourcePath:    "foo2",
+						DestPath:      "bar2",
+						EmbeddedTmpl:  "baz2",
+						ChangeMode:    "bam2",
+						ChangeSignal:  "SIGHUP2",
+						Splay:         2,
+						Perms:         "0666",
+						Envvars:       true,
+						ErrMissingKey: true,
+						VaultGrace:    5 * time.Second,
 					},
 				},
 			},
@@ -3874,6 +4186,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "",
 								New:  "false",
 							},
+							{
+								Type: DiffTypeAdded,
+								Name: "ErrMissingKey",
+								Old:  "",
+								New:  "false",
+							},
 							{
 								Type: DiffTypeAdded,
 								Name: "Perms",
@@ -3934,6 +4252,12 @@ func TestTaskDiff(t *testing.T) {
 								Old:  "true",
 								New:  "",
 							},
+							{
+								Type: DiffTypeDeleted,
+								Name: "ErrMissingKey",
+								Old:  "true",
+								New:  "",
+							},
 							{
 								Type: DiffTypeDeleted,
 								Name: "Perms",
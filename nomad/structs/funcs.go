@@ -10,19 +10,17 @@ import (
 )
 
 // MergeMultierrorWarnings takes job warnings and canonicalize warnings and
-// merges them into a returnable string. Both the errors may be nil.
-func MergeMultierrorWarnings(warnings, canonicalizeWarnings error) string {
-	if warnings == nil && canonicalizeWarnings == nil {
-		return ""
-	}
-
+// merges them into a returnable string. Any of the errors may be nil.
+func MergeMultierrorWarnings(errs ...error) string {
 	var warningMsg multierror.Error
-	if canonicalizeWarnings != nil {
-		multierror.Append(&warningMsg, canonicalizeWarnings)
+	for _, err := range errs {
+		if err != nil {
+			multierror.Append(&warningMsg, err)
+		}
 	}
 
-	if warnings != nil {
-		multierror.Append(&warningMsg, warnings)
+	if len(warningMsg.Errors) == 0 {
+		return ""
 	}
 
 	// Set the formatter
@@ -157,19 +155,7 @@ func AllocsFit(node *Node, allocs []*Allocation, netIdx *NetworkIndex) (bool, st
 // http://www.columbia.edu/~cs2035/courses/ieor4405.S13/datacenter_scheduling.ppt
 // This is equivalent to their BestFit v3
 func ScoreFit(node *Node, util *Resources) float64 {
-	// Determine the node availability
-	nodeCpu := float64(node.Resources.CPU)
-	if node.Reserved != nil {
-		nodeCpu -= float64(node.Reserved.CPU)
-	}
-	nodeMem := float64(node.Resources.MemoryMB)
-	if node.Reserved != nil {
-		nodeMem -= float64(node.Reserved.MemoryMB)
-	}
-
-	// Compute the free percentage
-	freePctCpu := 1 - (float64(util.CPU) / nodeCpu)
-	freePctRam := 1 - (float64(util.MemoryMB) / nodeMem)
+	freePctCpu, freePctRam := computeFreePercentage(node, util)
 
 	// Total will be "maximized" the smaller the value is.
 	// At 100% utilization, the total is 2, while at 0% util it is 20.
@@ -190,6 +176,45 @@ func ScoreFit(node *Node, util *Resources) float64 {
 	return score
 }
 
+// ScoreFitSpread is the inverse of ScoreFit: it scores the least utilized
+// nodes the highest so that allocations are spread across the cluster.
+func ScoreFitSpread(node *Node, util *Resources) float64 {
+	freePctCpu, freePctRam := computeFreePercentage(node, util)
+
+	// At 100% utilization, the total is 2, while at 0% util it is 20.
+	total := math.Pow(10, freePctCpu) + math.Pow(10, freePctRam)
+
+	// Use the floor of 2 as an anchor so that an empty node scores 18.
+	score := total - 2.0
+
+	// Bound the score, just in case
+	if score > 18.0 {
+		score = 18.0
+	} else if score < 0 {
+		score = 0
+	}
+	return score
+}
+
+// computeFreePercentage returns the percentage of the CPU and memory of the
+// node that is free given the utilization.
+func computeFreePercentage(node *Node, util *Resources) (freePctCpu, freePctRam float64) {
+	// Determine the node availability
+	nodeCpu := float64(node.Resources.CPU)
+	if node.Reserved != nil {
+		nodeCpu -= float64(node.Reserved.CPU)
+	}
+	nodeMem := float64(node.Resources.MemoryMB)
+	if node.Reserved != nil {
+		nodeMem -= float64(node.Reserved.MemoryMB)
+	}
+
+	// Compute the free percentage
+	freePctCpu = 1 - (float64(util.CPU) / nodeCpu)
+	freePctRam = 1 - (float64(util.MemoryMB) / nodeMem)
+	return freePctCpu, freePctRam
+}
+
 // GenerateUUID is used to generate a random UUID
 func GenerateUUID() string {
 	buf := make([]byte, 16)
@@ -238,6 +263,26 @@ func VaultPoliciesSet(policies map[string]map[string]*Vault) []string {
 	return flattened
 }
 
+// VaultRolesSet takes the structure returned by VaultPolicies and returns a
+// set of required Vault JWT auth roles
+func VaultRolesSet(policies map[string]map[string]*Vault) []string {
+	set := make(map[string]struct{})
+
+	for _, tgp := range policies {
+		for _, tp := range tgp {
+			if tp.Role != "" {
+				set[tp.Role] = struct{}{}
+			}
+		}
+	}
+
+	flattened := make([]string, 0, len(set))
+	for r := range set {
+		flattened = append(flattened, r)
+	}
+	return flattened
+}
+
 // DenormalizeAllocationJobs is used to attach a job to all allocations that are
 // non-terminal and do not have a job already. This is useful in cases where the
 // job is normalized.
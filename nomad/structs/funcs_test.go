@@ -102,7 +102,7 @@ func TestAllocsFit_PortsOvercommitted(t *testing.T) {
 						Device:        "eth0",
 						IP:            "10.0.0.1",
 						MBits:         50,
-						ReservedPorts: []Port{{"main", 8000}},
+						ReservedPorts: []Port{{Label: "main", Value: 8000}},
 					},
 				},
 			},
@@ -153,7 +153,7 @@ func TestAllocsFit(t *testing.T) {
 					Device:        "eth0",
 					IP:            "10.0.0.1",
 					MBits:         50,
-					ReservedPorts: []Port{{"main", 80}},
+					ReservedPorts: []Port{{Label: "main", Value: 80}},
 				},
 			},
 		},
@@ -170,7 +170,7 @@ func TestAllocsFit(t *testing.T) {
 					Device:        "eth0",
 					IP:            "10.0.0.1",
 					MBits:         50,
-					ReservedPorts: []Port{{"main", 8000}},
+					ReservedPorts: []Port{{Label: "main", Value: 8000}},
 				},
 			},
 		},
@@ -254,6 +254,48 @@ func TestScoreFit(t *testing.T) {
 	}
 }
 
+func TestScoreFitSpread(t *testing.T) {
+	node := &Node{}
+	node.Resources = &Resources{
+		CPU:      4096,
+		MemoryMB: 8192,
+	}
+	node.Reserved = &Resources{
+		CPU:      2048,
+		MemoryMB: 4096,
+	}
+
+	// An empty node gets the best score
+	util := &Resources{
+		CPU:      0,
+		MemoryMB: 0,
+	}
+	score := ScoreFitSpread(node, util)
+	if score != 18.0 {
+		t.Fatalf("bad: %v", score)
+	}
+
+	// A full node gets the worst score
+	util = &Resources{
+		CPU:      2048,
+		MemoryMB: 4096,
+	}
+	score = ScoreFitSpread(node, util)
+	if score != 0.0 {
+		t.Fatalf("bad: %v", score)
+	}
+
+	// Test a mid-case scenario
+	util = &Resources{
+		CPU:      1024,
+		MemoryMB: 2048,
+	}
+	score = ScoreFitSpread(node, util)
+	if score < 2.0 || score > 8.0 {
+		t.Fatalf("bad: %v", score)
+	}
+}
+
 func TestGenerateUUID(t *testing.T) {
 	prev := GenerateUUID()
 	for i := 0; i < 100; i++ {
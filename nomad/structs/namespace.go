@@ -0,0 +1,144 @@
+package structs
+
+import (
+	"fmt"
+	"regexp"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/helper"
+)
+
+const (
+	// maxNamespaceDescriptionLength limits the size of a namespace description
+	maxNamespaceDescriptionLength = 256
+
+	// DefaultNamespace is the namespace of the objects created without one.
+	// It always exists and doesn't need to be created.
+	DefaultNamespace = "default"
+)
+
+var (
+	// validNamespaceName is used to validate a namespace name
+	validNamespaceName = regexp.MustCompile("^[a-zA-Z0-9-]{1,128}$")
+)
+
+// Namespace is a named grouping of the objects of the cluster. Namespaces are
+// defined by operators, usually from spec files kept in version control.
+type Namespace struct {
+	// Name is the unique name of the namespace
+	Name string
+
+	// Description is a human readable description of the namespace
+	Description string
+
+	// Quota is the name of the quota specification that limits the resources
+	// used by the namespace
+	Quota string
+
+	// Capabilities restricts what the jobs of the namespace can use
+	Capabilities *NamespaceCapabilities
+
+	// GC overrides the server's garbage collection thresholds for the jobs
+	// of the namespace. Jobs may override them in turn.
+	GC *JobGCConfig
+
+	// Meta is a set of user defined key/value pairs attached to the namespace
+	Meta map[string]string
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// NamespaceCapabilities restricts the features available to the jobs of a
+// namespace.
+type NamespaceCapabilities struct {
+	// EnabledTaskDrivers is the list of task drivers allowed in the
+	// namespace. If empty, every driver that isn't disabled is allowed.
+	EnabledTaskDrivers []string
+
+	// DisabledTaskDrivers is the list of task drivers denied in the namespace
+	DisabledTaskDrivers []string
+}
+
+// Validate returns an error if the namespace is invalid.
+func (n *Namespace) Validate() error {
+	var mErr multierror.Error
+
+	if !validNamespaceName.MatchString(n.Name) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid name %q. Must match regex %s", n.Name, validNamespaceName))
+	}
+	if len(n.Description) > maxNamespaceDescriptionLength {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("description longer than %d", maxNamespaceDescriptionLength))
+	}
+
+	if c := n.Capabilities; c != nil {
+		for _, driver := range c.EnabledTaskDrivers {
+			if helper.SliceStringContains(c.DisabledTaskDrivers, driver) {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("task driver %q can't be both enabled and disabled", driver))
+			}
+		}
+	}
+
+	if n.GC != nil {
+		if err := n.GC.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// Copy returns a deep copy of the namespace.
+func (n *Namespace) Copy() *Namespace {
+	if n == nil {
+		return nil
+	}
+
+	nc := new(Namespace)
+	*nc = *n
+	nc.Meta = helper.CopyMapStringString(n.Meta)
+	nc.GC = n.GC.Copy()
+	if n.Capabilities != nil {
+		nc.Capabilities = &NamespaceCapabilities{
+			EnabledTaskDrivers:  helper.CopySliceString(n.Capabilities.EnabledTaskDrivers),
+			DisabledTaskDrivers: helper.CopySliceString(n.Capabilities.DisabledTaskDrivers),
+		}
+	}
+	return nc
+}
+
+// NamespaceUpsertRequest is used to create or update a set of namespaces
+type NamespaceUpsertRequest struct {
+	Namespaces []*Namespace
+	WriteRequest
+}
+
+// NamespaceDeleteRequest is used to delete a set of namespaces
+type NamespaceDeleteRequest struct {
+	Namespaces []string
+	WriteRequest
+}
+
+// NamespaceListRequest is used to list the namespaces
+type NamespaceListRequest struct {
+	QueryOptions
+}
+
+// NamespaceListResponse is used for a list request
+type NamespaceListResponse struct {
+	Namespaces []*Namespace
+	QueryMeta
+}
+
+// NamespaceSpecificRequest is used to query a specific namespace
+type NamespaceSpecificRequest struct {
+	Name string
+	QueryOptions
+}
+
+// SingleNamespaceResponse is used to return a single namespace
+type SingleNamespaceResponse struct {
+	Namespace *Namespace
+	QueryMeta
+}
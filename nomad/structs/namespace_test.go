@@ -0,0 +1,108 @@
+package structs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNamespace_Validate(t *testing.T) {
+	cases := []struct {
+		ns  *Namespace
+		err string
+	}{
+		{
+			ns: &Namespace{Name: "web-prod"},
+		},
+		{
+			ns:  &Namespace{Name: ""},
+			err: "invalid name",
+		},
+		{
+			ns:  &Namespace{Name: "web prod"},
+			err: "invalid name",
+		},
+		{
+			ns:  &Namespace{Name: strings.Repeat("a", 129)},
+			err: "invalid name",
+		},
+		{
+			ns:  &Namespace{Name: "web", Description: strings.Repeat("a", 257)},
+			err: "description longer than 256",
+		},
+		{
+			ns: &Namespace{
+				Name: "web",
+				Capabilities: &NamespaceCapabilities{
+					EnabledTaskDrivers:  []string{"docker", "exec"},
+					DisabledTaskDrivers: []string{"exec"},
+				},
+			},
+			err: `task driver "exec" can't be both enabled and disabled`,
+		},
+		{
+			ns: &Namespace{
+				Name: "web",
+				GC:   &JobGCConfig{EvalThreshold: -time.Hour},
+			},
+			err: "Eval GC threshold must be non-negative",
+		},
+	}
+
+	for _, c := range cases {
+		err := c.ns.Validate()
+		if c.err == "" {
+			if err != nil {
+				t.Fatalf("unexpected error validating %q: %v", c.ns.Name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("expected error %q validating %q, got: %v", c.err, c.ns.Name, err)
+		}
+	}
+}
+
+func TestNamespace_Copy(t *testing.T) {
+	ns := &Namespace{
+		Name: "web",
+		Capabilities: &NamespaceCapabilities{
+			EnabledTaskDrivers: []string{"docker"},
+		},
+		GC:   &JobGCConfig{EvalThreshold: time.Hour},
+		Meta: map[string]string{"owner": "ops"},
+	}
+
+	c := ns.Copy()
+	if !reflect.DeepEqual(ns, c) {
+		t.Fatalf("bad: %#v %#v", ns, c)
+	}
+
+	c.Meta["owner"] = "dev"
+	c.Capabilities.EnabledTaskDrivers[0] = "exec"
+	c.GC.EvalThreshold = 0
+	if ns.Meta["owner"] != "ops" || ns.Capabilities.EnabledTaskDrivers[0] != "docker" || ns.GC.EvalThreshold != time.Hour {
+		t.Fatalf("copy isn't deep: %#v", ns)
+	}
+}
+
+func TestJobGCConfig_Merge(t *testing.T) {
+	job := &JobGCConfig{EvalThreshold: time.Hour}
+	ns := &JobGCConfig{EvalThreshold: 2 * time.Hour, AllocThreshold: 3 * time.Hour}
+
+	expected := &JobGCConfig{EvalThreshold: time.Hour, AllocThreshold: 3 * time.Hour}
+	if out := job.Merge(ns); !reflect.DeepEqual(out, expected) {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out := job.Merge(nil); !reflect.DeepEqual(out, job) {
+		t.Fatalf("bad: %#v", out)
+	}
+	var unset *JobGCConfig
+	if out := unset.Merge(ns); !reflect.DeepEqual(out, ns) || out == ns {
+		t.Fatalf("bad: %#v", out)
+	}
+	if out := unset.Merge(nil); out != nil {
+		t.Fatalf("bad: %#v", out)
+	}
+}
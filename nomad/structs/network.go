@@ -30,6 +30,24 @@ var (
 	bitmapPool = new(sync.Pool)
 )
 
+// ValidateDynamicPortRange returns an error if the dynamic port range of a
+// node is invalid. Zero values stand for the default bounds.
+func ValidateDynamicPortRange(minPort, maxPort int) error {
+	if minPort == 0 {
+		minPort = MinDynamicPort
+	}
+	if maxPort == 0 {
+		maxPort = MaxDynamicPort
+	}
+	if minPort < 0 || maxPort >= maxValidPort {
+		return fmt.Errorf("dynamic port range %d-%d is out of the valid ports", minPort, maxPort)
+	}
+	if minPort > maxPort {
+		return fmt.Errorf("min dynamic port %d is greater than max dynamic port %d", minPort, maxPort)
+	}
+	return nil
+}
+
 // NetworkIndex is used to index the available network resources
 // and the used network resources on a machine given allocations
 type NetworkIndex struct {
@@ -37,6 +55,8 @@ type NetworkIndex struct {
 	AvailBandwidth map[string]int     // Bandwidth by device
 	UsedPorts      map[string]Bitmap  // Ports by IP
 	UsedBandwidth  map[string]int     // Bandwidth by device
+	MinDynamicPort int                // Smallest dynamic port of the node
+	MaxDynamicPort int                // Largest dynamic port of the node
 }
 
 // NewNetworkIndex is used to construct a new network index
@@ -45,6 +65,8 @@ func NewNetworkIndex() *NetworkIndex {
 		AvailBandwidth: make(map[string]int),
 		UsedPorts:      make(map[string]Bitmap),
 		UsedBandwidth:  make(map[string]int),
+		MinDynamicPort: MinDynamicPort,
+		MaxDynamicPort: MaxDynamicPort,
 	}
 }
 
@@ -70,6 +92,14 @@ func (idx *NetworkIndex) Overcommitted() bool {
 // SetNode is used to setup the available network resources. Returns
 // true if there is a collision
 func (idx *NetworkIndex) SetNode(node *Node) (collide bool) {
+	// Use the dynamic port range of the node if it has one
+	if node.MinDynamicPort > 0 {
+		idx.MinDynamicPort = node.MinDynamicPort
+	}
+	if node.MaxDynamicPort > 0 {
+		idx.MaxDynamicPort = node.MaxDynamicPort
+	}
+
 	// Add the available CIDR blocks
 	for _, n := range node.Resources.Networks {
 		if n.Device != "" {
@@ -102,6 +132,15 @@ func (idx *NetworkIndex) AddAllocs(allocs []*Allocation) (collide bool) {
 				collide = true
 			}
 		}
+
+		// Add the ports of the group network
+		if alloc.SharedResources != nil {
+			for _, n := range alloc.SharedResources.Networks {
+				if idx.AddReserved(n) {
+					collide = true
+				}
+			}
+		}
 	}
 	return
 }
@@ -109,30 +148,36 @@ func (idx *NetworkIndex) AddAllocs(allocs []*Allocation) (collide bool) {
 // AddReserved is used to add a reserved network usage, returns true
 // if there is a port collision
 func (idx *NetworkIndex) AddReserved(n *NetworkResource) (collide bool) {
-	// Add the port usage
-	used := idx.UsedPorts[n.IP]
-	if used == nil {
-		// Try to get a bitmap from the pool, else create
-		raw := bitmapPool.Get()
-		if raw != nil {
-			used = raw.(Bitmap)
-			used.Clear()
-		} else {
-			used, _ = NewBitmap(maxValidPort)
-		}
-		idx.UsedPorts[n.IP] = used
+	// Add the port usage, on both addresses of dual stack networks
+	ips := []string{n.IP}
+	if n.IPv6 != "" {
+		ips = append(ips, n.IPv6)
 	}
-
-	for _, ports := range [][]Port{n.ReservedPorts, n.DynamicPorts} {
-		for _, port := range ports {
-			// Guard against invalid port
-			if port.Value < 0 || port.Value >= maxValidPort {
-				return true
-			}
-			if used.Check(uint(port.Value)) {
-				collide = true
+	for _, ip := range ips {
+		used := idx.UsedPorts[ip]
+		if used == nil {
+			// Try to get a bitmap from the pool, else create
+			raw := bitmapPool.Get()
+			if raw != nil {
+				used = raw.(Bitmap)
+				used.Clear()
 			} else {
-				used.Set(uint(port.Value))
+				used, _ = NewBitmap(maxValidPort)
+			}
+			idx.UsedPorts[ip] = used
+		}
+
+		for _, ports := range [][]Port{n.ReservedPorts, n.DynamicPorts} {
+			for _, port := range ports {
+				// Guard against invalid port
+				if port.Value < 0 || port.Value >= maxValidPort {
+					return true
+				}
+				if used.Check(uint(port.Value)) {
+					collide = true
+				} else {
+					used.Set(uint(port.Value))
+				}
 			}
 		}
 	}
@@ -169,80 +214,136 @@ func (idx *NetworkIndex) yieldIP(cb func(net *NetworkResource, ip net.IP) bool)
 
 // AssignNetwork is used to assign network resources given an ask.
 // If the ask cannot be satisfied, returns nil
+//
+// IPv4 addresses are offered first. On dual stack hosts the ports are also
+// reserved on an IPv6 address of the same device.
 func (idx *NetworkIndex) AssignNetwork(ask *NetworkResource) (out *NetworkResource, err error) {
 	err = fmt.Errorf("no networks available")
-	idx.yieldIP(func(n *NetworkResource, ip net.IP) (stop bool) {
-		// Convert the IP to a string
-		ipStr := ip.String()
-
-		// Check if we would exceed the bandwidth cap
-		availBandwidth := idx.AvailBandwidth[n.Device]
-		usedBandwidth := idx.UsedBandwidth[n.Device]
-		if usedBandwidth+ask.MBits > availBandwidth {
-			err = fmt.Errorf("bandwidth exceeded")
-			return
-		}
+	if ask.HostNetwork != "" {
+		err = fmt.Errorf("no networks available in host network %q", ask.HostNetwork)
+	}
+	for _, ipv6 := range []bool{false, true} {
+		idx.yieldIP(func(n *NetworkResource, ip net.IP) (stop bool) {
+			// Only the addresses of the requested host network are offered
+			if n.HostNetwork != ask.HostNetwork || (ip.To4() == nil) != ipv6 {
+				return
+			}
 
-		used := idx.UsedPorts[ipStr]
+			// Convert the IP to a string
+			ipStr := ip.String()
 
-		// Check if any of the reserved ports are in use
-		for _, port := range ask.ReservedPorts {
-			// Guard against invalid port
-			if port.Value < 0 || port.Value >= maxValidPort {
-				err = fmt.Errorf("invalid port %d (out of range)", port.Value)
+			// Check if we would exceed the bandwidth cap
+			availBandwidth := idx.AvailBandwidth[n.Device]
+			usedBandwidth := idx.UsedBandwidth[n.Device]
+			if usedBandwidth+ask.MBits > availBandwidth {
+				err = fmt.Errorf("bandwidth exceeded")
 				return
 			}
 
-			// Check if in use
-			if used != nil && used.Check(uint(port.Value)) {
-				err = fmt.Errorf("reserved port collision")
+			used := idx.UsedPorts[ipStr]
+
+			// Check if any of the reserved ports are in use
+			for _, port := range ask.ReservedPorts {
+				// Guard against invalid port
+				if port.Value < 0 || port.Value >= maxValidPort {
+					err = fmt.Errorf("invalid port %d (out of range)", port.Value)
+					return
+				}
+
+				// Check if in use
+				if used != nil && used.Check(uint(port.Value)) {
+					err = fmt.Errorf("reserved port collision")
+					return
+				}
+			}
+
+			// Reserve the ports on an IPv6 address of the device as well
+			var ipv6Str string
+			if !ipv6 {
+				ipv6Str, used = idx.dualStackIP(n, ask, used)
+			}
+
+			// Create the offer
+			offer := &NetworkResource{
+				HostNetwork:   n.HostNetwork,
+				Device:        n.Device,
+				IP:            ipStr,
+				IPv6:          ipv6Str,
+				MBits:         ask.MBits,
+				ReservedPorts: ask.ReservedPorts,
+				DynamicPorts:  ask.DynamicPorts,
+			}
+
+			// Try to stochastically pick the dynamic ports as it is faster and
+			// lower memory usage.
+			var dynPorts []int
+			var dynErr error
+			dynPorts, dynErr = getDynamicPortsStochastic(used, idx.MinDynamicPort, idx.MaxDynamicPort, ask)
+			if dynErr == nil {
+				goto BUILD_OFFER
+			}
+
+			// Fall back to the precise method if the random sampling failed.
+			dynPorts, dynErr = getDynamicPortsPrecise(used, idx.MinDynamicPort, idx.MaxDynamicPort, ask)
+			if dynErr != nil {
+				err = dynErr
 				return
 			}
-		}
 
-		// Create the offer
-		offer := &NetworkResource{
-			Device:        n.Device,
-			IP:            ipStr,
-			MBits:         ask.MBits,
-			ReservedPorts: ask.ReservedPorts,
-			DynamicPorts:  ask.DynamicPorts,
-		}
+		BUILD_OFFER:
+			for i, port := range dynPorts {
+				offer.DynamicPorts[i].Value = port
+			}
 
-		// Try to stochastically pick the dynamic ports as it is faster and
-		// lower memory usage.
-		var dynPorts []int
-		var dynErr error
-		dynPorts, dynErr = getDynamicPortsStochastic(used, ask)
-		if dynErr == nil {
-			goto BUILD_OFFER
+			// Stop, we have an offer!
+			out = offer
+			err = nil
+			return true
+		})
+		if out != nil {
+			break
 		}
+	}
+	return
+}
 
-		// Fall back to the precise method if the random sampling failed.
-		dynPorts, dynErr = getDynamicPortsPrecise(used, ask)
-		if dynErr != nil {
-			err = dynErr
-			return
+// dualStackIP returns the first IPv6 address of the device of the network the
+// reserved ports of the ask are free on, if any, along with the ports used on
+// either address.
+func (idx *NetworkIndex) dualStackIP(n *NetworkResource, ask *NetworkResource, used Bitmap) (string, Bitmap) {
+	var ipv6 string
+	idx.yieldIP(func(n6 *NetworkResource, ip net.IP) bool {
+		if n6.Device != n.Device || n6.HostNetwork != n.HostNetwork || ip.To4() != nil {
+			return false
 		}
 
-	BUILD_OFFER:
-		for i, port := range dynPorts {
-			offer.DynamicPorts[i].Value = port
+		used6 := idx.UsedPorts[ip.String()]
+		for _, port := range ask.ReservedPorts {
+			if used6 != nil && port.Value >= 0 && port.Value < maxValidPort && used6.Check(uint(port.Value)) {
+				return false
+			}
 		}
 
-		// Stop, we have an offer!
-		out = offer
-		err = nil
+		ipv6 = ip.String()
+		if used6 != nil {
+			if used == nil {
+				used = used6
+			} else {
+				used, _ = used.Copy()
+				used.Union(used6)
+			}
+		}
 		return true
 	})
-	return
+	return ipv6, used
 }
 
 // getDynamicPortsPrecise takes the nodes used port bitmap which may be nil if
-// no ports have been allocated yet, the network ask and returns a set of unused
-// ports to fullfil the ask's DynamicPorts or an error if it failed. An error
-// means the ask can not be satisfied as the method does a precise search.
-func getDynamicPortsPrecise(nodeUsed Bitmap, ask *NetworkResource) ([]int, error) {
+// no ports have been allocated yet, the node's dynamic port range, the network
+// ask and returns a set of unused ports to fullfil the ask's DynamicPorts or an
+// error if it failed. An error means the ask can not be satisfied as the method
+// does a precise search.
+func getDynamicPortsPrecise(nodeUsed Bitmap, minPort, maxPort int, ask *NetworkResource) ([]int, error) {
 	// Create a copy of the used ports and apply the new reserves
 	var usedSet Bitmap
 	var err error
@@ -263,7 +364,7 @@ func getDynamicPortsPrecise(nodeUsed Bitmap, ask *NetworkResource) ([]int, error
 	}
 
 	// Get the indexes of the unset
-	availablePorts := usedSet.IndexesInRange(false, MinDynamicPort, MaxDynamicPort)
+	availablePorts := usedSet.IndexesInRange(false, uint(minPort), uint(maxPort))
 
 	// Randomize the amount we need
 	numDyn := len(ask.DynamicPorts)
@@ -281,11 +382,12 @@ func getDynamicPortsPrecise(nodeUsed Bitmap, ask *NetworkResource) ([]int, error
 }
 
 // getDynamicPortsStochastic takes the nodes used port bitmap which may be nil if
-// no ports have been allocated yet, the network ask and returns a set of unused
-// ports to fullfil the ask's DynamicPorts or an error if it failed. An error
-// does not mean the ask can not be satisfied as the method has a fixed amount
-// of random probes and if these fail, the search is aborted.
-func getDynamicPortsStochastic(nodeUsed Bitmap, ask *NetworkResource) ([]int, error) {
+// no ports have been allocated yet, the node's dynamic port range, the network
+// ask and returns a set of unused ports to fullfil the ask's DynamicPorts or an
+// error if it failed. An error does not mean the ask can not be satisfied as
+// the method has a fixed amount of random probes and if these fail, the search
+// is aborted.
+func getDynamicPortsStochastic(nodeUsed Bitmap, minPort, maxPort int, ask *NetworkResource) ([]int, error) {
 	var reserved, dynamic []int
 	for _, port := range ask.ReservedPorts {
 		reserved = append(reserved, port.Value)
@@ -299,7 +401,7 @@ func getDynamicPortsStochastic(nodeUsed Bitmap, ask *NetworkResource) ([]int, er
 			return nil, fmt.Errorf("stochastic dynamic port selection failed")
 		}
 
-		randPort := MinDynamicPort + rand.Intn(MaxDynamicPort-MinDynamicPort)
+		randPort := minPort + rand.Intn(maxPort-minPort+1)
 		if nodeUsed != nil && nodeUsed.Check(uint(randPort)) {
 			goto PICK
 		}
@@ -3,6 +3,8 @@ package structs
 import (
 	"net"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -14,7 +16,7 @@ func TestNetworkIndex_Overcommitted(t *testing.T) {
 		Device:        "eth0",
 		IP:            "192.168.0.100",
 		MBits:         505,
-		ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+		ReservedPorts: []Port{{Label: "one", Value: 8000}, {Label: "two", Value: 9000}},
 	}
 	collide := idx.AddReserved(reserved)
 	if collide {
@@ -65,7 +67,7 @@ func TestNetworkIndex_SetNode(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{Label: "ssh", Value: 22}},
 					MBits:         1,
 				},
 			},
@@ -101,7 +103,7 @@ func TestNetworkIndex_AddAllocs(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         20,
-							ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+							ReservedPorts: []Port{{Label: "one", Value: 8000}, {Label: "two", Value: 9000}},
 						},
 					},
 				},
@@ -115,7 +117,7 @@ func TestNetworkIndex_AddAllocs(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         50,
-							ReservedPorts: []Port{{"one", 10000}},
+							ReservedPorts: []Port{{Label: "one", Value: 10000}},
 						},
 					},
 				},
@@ -148,7 +150,7 @@ func TestNetworkIndex_AddReserved(t *testing.T) {
 		Device:        "eth0",
 		IP:            "192.168.0.100",
 		MBits:         20,
-		ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+		ReservedPorts: []Port{{Label: "one", Value: 8000}, {Label: "two", Value: 9000}},
 	}
 	collide := idx.AddReserved(reserved)
 	if collide {
@@ -189,7 +191,7 @@ func TestNetworkIndex_yieldIP(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{Label: "ssh", Value: 22}},
 					MBits:         1,
 				},
 			},
@@ -227,7 +229,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 				&NetworkResource{
 					Device:        "eth0",
 					IP:            "192.168.0.100",
-					ReservedPorts: []Port{{"ssh", 22}},
+					ReservedPorts: []Port{{Label: "ssh", Value: 22}},
 					MBits:         1,
 				},
 			},
@@ -244,7 +246,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         20,
-							ReservedPorts: []Port{{"one", 8000}, {"two", 9000}},
+							ReservedPorts: []Port{{Label: "one", Value: 8000}, {Label: "two", Value: 9000}},
 						},
 					},
 				},
@@ -258,7 +260,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 							Device:        "eth0",
 							IP:            "192.168.0.100",
 							MBits:         50,
-							ReservedPorts: []Port{{"main", 10000}},
+							ReservedPorts: []Port{{Label: "main", Value: 10000}},
 						},
 					},
 				},
@@ -269,7 +271,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 
 	// Ask for a reserved port
 	ask := &NetworkResource{
-		ReservedPorts: []Port{{"main", 8000}},
+		ReservedPorts: []Port{{Label: "main", Value: 8000}},
 	}
 	offer, err := idx.AssignNetwork(ask)
 	if err != nil {
@@ -281,14 +283,14 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 	if offer.IP != "192.168.0.101" {
 		t.Fatalf("bad: %#v", offer)
 	}
-	rp := Port{"main", 8000}
+	rp := Port{Label: "main", Value: 8000}
 	if len(offer.ReservedPorts) != 1 || offer.ReservedPorts[0] != rp {
 		t.Fatalf("bad: %#v", offer)
 	}
 
 	// Ask for dynamic ports
 	ask = &NetworkResource{
-		DynamicPorts: []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+		DynamicPorts: []Port{{Label: "http", Value: 0}, {Label: "https", Value: 0}, {Label: "admin", Value: 0}},
 	}
 	offer, err = idx.AssignNetwork(ask)
 	if err != nil {
@@ -311,8 +313,8 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 
 	// Ask for reserved + dynamic ports
 	ask = &NetworkResource{
-		ReservedPorts: []Port{{"main", 2345}},
-		DynamicPorts:  []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+		ReservedPorts: []Port{{Label: "main", Value: 2345}},
+		DynamicPorts:  []Port{{Label: "http", Value: 0}, {Label: "https", Value: 0}, {Label: "admin", Value: 0}},
 	}
 	offer, err = idx.AssignNetwork(ask)
 	if err != nil {
@@ -325,7 +327,7 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 		t.Fatalf("bad: %#v", offer)
 	}
 
-	rp = Port{"main", 2345}
+	rp = Port{Label: "main", Value: 2345}
 	if len(offer.ReservedPorts) != 1 || offer.ReservedPorts[0] != rp {
 		t.Fatalf("bad: %#v", offer)
 	}
@@ -343,6 +345,118 @@ func TestNetworkIndex_AssignNetwork(t *testing.T) {
 	}
 }
 
+func TestNetworkIndex_AssignNetwork_HostNetwork(t *testing.T) {
+	idx := NewNetworkIndex()
+	n := &Node{
+		Resources: &Resources{
+			Networks: []*NetworkResource{
+				{
+					Device: "eth0",
+					CIDR:   "192.168.0.100/32",
+					MBits:  1000,
+				},
+				{
+					HostNetwork: "public",
+					Device:      "eth1",
+					CIDR:        "203.0.113.10/32",
+					MBits:       1000,
+				},
+			},
+		},
+	}
+	idx.SetNode(n)
+
+	// Asks without a host network use the default one
+	offer, err := idx.AssignNetwork(&NetworkResource{DynamicPorts: []Port{{Label: "http"}}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if offer.IP != "192.168.0.100" || offer.HostNetwork != "" {
+		t.Fatalf("bad: %#v", offer)
+	}
+
+	offer, err = idx.AssignNetwork(&NetworkResource{
+		HostNetwork:  "public",
+		DynamicPorts: []Port{{Label: "http"}},
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if offer.IP != "203.0.113.10" || offer.Device != "eth1" || offer.HostNetwork != "public" {
+		t.Fatalf("bad: %#v", offer)
+	}
+
+	offer, err = idx.AssignNetwork(&NetworkResource{HostNetwork: "private"})
+	if offer != nil || err == nil || !strings.Contains(err.Error(), `host network "private"`) {
+		t.Fatalf("bad: %#v %v", offer, err)
+	}
+}
+
+func TestNetworkIndex_AssignNetwork_DualStack(t *testing.T) {
+	idx := NewNetworkIndex()
+	n := &Node{
+		Resources: &Resources{
+			Networks: []*NetworkResource{
+				{
+					Device: "eth0",
+					CIDR:   "2001:db8::10/128",
+					MBits:  1000,
+				},
+				{
+					Device: "eth0",
+					CIDR:   "192.168.0.100/32",
+					MBits:  1000,
+				},
+			},
+		},
+	}
+	idx.SetNode(n)
+
+	// The port is used on the IPv6 address only
+	idx.AddReserved(&NetworkResource{
+		Device:        "eth0",
+		IP:            "2001:db8::10",
+		ReservedPorts: []Port{{Label: "main", Value: 8000}},
+	})
+
+	// The IPv4 address is offered first, along with the IPv6 one
+	ask := &NetworkResource{
+		ReservedPorts: []Port{{Label: "admin", Value: 8080}},
+		DynamicPorts:  []Port{{Label: "http"}},
+	}
+	offer, err := idx.AssignNetwork(ask)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if offer.IP != "192.168.0.100" || offer.IPv6 != "2001:db8::10" {
+		t.Fatalf("bad: %#v", offer)
+	}
+	if offer.IPv6Address() != "2001:db8::10" {
+		t.Fatalf("bad: %v", offer.IPv6Address())
+	}
+
+	// The ports are reserved on both addresses
+	if idx.AddReserved(offer) {
+		t.Fatalf("unexpected collision")
+	}
+	for _, ip := range []string{"192.168.0.100", "2001:db8::10"} {
+		for _, port := range []int{8080, offer.DynamicPorts[0].Value} {
+			if !idx.UsedPorts[ip].Check(uint(port)) {
+				t.Fatalf("port %d not reserved on %s", port, ip)
+			}
+		}
+	}
+
+	// A port used on the IPv6 address is only reserved on the IPv4 one
+	offer, err = idx.AssignNetwork(&NetworkResource{ReservedPorts: []Port{{Label: "main", Value: 8000}}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if offer.IP != "192.168.0.100" || offer.IPv6 != "" || offer.IPv6Address() != "" {
+		t.Fatalf("bad: %#v", offer)
+	}
+}
+
 // This test ensures that even with a small domain of available ports we are
 // able to make a dynamic port allocation.
 func TestNetworkIndex_AssignNetwork_Dynamic_Contention(t *testing.T) {
@@ -377,7 +491,7 @@ func TestNetworkIndex_AssignNetwork_Dynamic_Contention(t *testing.T) {
 
 	// Ask for dynamic ports
 	ask := &NetworkResource{
-		DynamicPorts: []Port{{"http", 0}},
+		DynamicPorts: []Port{{Label: "http", Value: 0}},
 	}
 	offer, err := idx.AssignNetwork(ask)
 	if err != nil {
@@ -397,6 +511,75 @@ func TestNetworkIndex_AssignNetwork_Dynamic_Contention(t *testing.T) {
 	}
 }
 
+func TestNetworkIndex_AssignNetwork_DynamicPortRange(t *testing.T) {
+	idx := NewNetworkIndex()
+	n := &Node{
+		Resources: &Resources{
+			Networks: []*NetworkResource{
+				&NetworkResource{
+					Device: "eth0",
+					CIDR:   "192.168.0.100/32",
+					MBits:  1000,
+				},
+			},
+		},
+		Reserved: &Resources{
+			Networks: []*NetworkResource{
+				&NetworkResource{
+					Device:        "eth0",
+					IP:            "192.168.0.100",
+					ReservedPorts: []Port{{Value: 40001}},
+				},
+			},
+		},
+		MinDynamicPort: 40000,
+		MaxDynamicPort: 40002,
+	}
+	idx.SetNode(n)
+
+	// The dynamic ports are picked in the range of the node around the
+	// reserved port
+	ask := &NetworkResource{
+		DynamicPorts: []Port{{Label: "http"}, {Label: "admin"}},
+	}
+	offer, err := idx.AssignNetwork(ask)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ports := []int{offer.DynamicPorts[0].Value, offer.DynamicPorts[1].Value}
+	sort.Ints(ports)
+	if !reflect.DeepEqual(ports, []int{40000, 40002}) {
+		t.Fatalf("bad dynamic ports: %v", ports)
+	}
+
+	// The range can not fit more ports
+	ask = &NetworkResource{
+		DynamicPorts: []Port{{Label: "http"}, {Label: "admin"}, {Label: "metrics"}},
+	}
+	if _, err := idx.AssignNetwork(ask); err == nil || !strings.Contains(err.Error(), "dynamic port selection failed") {
+		t.Fatalf("expected a dynamic port selection error; got %v", err)
+	}
+}
+
+func TestValidateDynamicPortRange(t *testing.T) {
+	for _, c := range []struct {
+		min, max int
+		valid    bool
+	}{
+		{0, 0, true},
+		{30000, 30000, true},
+		{10000, 0, true},
+		{40000, 0, false},
+		{0, 70000, false},
+		{-1, 100, false},
+		{200, 100, false},
+	} {
+		if err := ValidateDynamicPortRange(c.min, c.max); (err == nil) != c.valid {
+			t.Fatalf("range %d-%d: expected valid %v; got %v", c.min, c.max, c.valid, err)
+		}
+	}
+}
+
 func TestIntContains(t *testing.T) {
 	l := []int{1, 2, 10, 20}
 	if isPortReserved(l, 50) {
@@ -0,0 +1,153 @@
+package structs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"text/template"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/helper"
+)
+
+const (
+	// DefaultJobNotificationMethod is the HTTP method webhooks are called
+	// with by default.
+	DefaultJobNotificationMethod = "POST"
+
+	// DefaultJobNotificationTimeout is the time a webhook has to respond by
+	// default.
+	DefaultJobNotificationTimeout = 10 * time.Second
+)
+
+// JobNotification is a webhook called when a batch job, or a job dispatched
+// or launched from it, reaches a terminal state.
+type JobNotification struct {
+	// Webhook is the URL of the webhook.
+	Webhook string
+
+	// Method is the HTTP method of the request, POST by default.
+	Method string
+
+	// Headers are added to the request.
+	Headers map[string]string
+
+	// Payload is a template rendered with the JobNotificationEvent to build
+	// the body of the request. The event is sent as JSON if it is empty.
+	Payload string
+
+	// Timeout is the time the webhook has to respond.
+	Timeout time.Duration
+}
+
+// JobNotificationEvent is the event a notification is rendered with.
+type JobNotificationEvent struct {
+	Region   string
+	JobID    string
+	ParentID string
+	Name     string
+	Type     string
+	Version  uint64
+	Status   string
+	Meta     map[string]string
+
+	// Stopped is whether the job was stopped rather than ran to completion.
+	Stopped bool
+
+	// Failed is whether the latest allocation of any instance of the job
+	// failed or was lost.
+	Failed bool
+
+	// Summary is the summary of the allocations of each task group.
+	Summary map[string]TaskGroupSummary
+
+	// Time is the time the job was found to be terminal.
+	Time time.Time
+}
+
+// DeploymentEvent is the event deployment webhooks are called with when a
+// deployment transitions.
+type DeploymentEvent struct {
+	// Event is the transition, one of the deployment events of the webhook
+	// configuration.
+	Event string
+
+	Region            string
+	DeploymentID      string
+	JobID             string
+	JobVersion        uint64
+	Status            string
+	StatusDescription string
+
+	// RevertedJobVersion is the stable version the job is reverted to when
+	// the deployment fails.
+	RevertedJobVersion *uint64
+
+	// TaskGroups is the state of the deployment of each task group.
+	TaskGroups map[string]*DeploymentState
+
+	// Time is the time the transition was found.
+	Time time.Time
+}
+
+// jobNotificationFuncs are the functions available to payload templates.
+var jobNotificationFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	},
+}
+
+func (n *JobNotification) Copy() *JobNotification {
+	if n == nil {
+		return nil
+	}
+	nn := new(JobNotification)
+	*nn = *n
+	nn.Headers = helper.CopyMapStringString(n.Headers)
+	return nn
+}
+
+// Validate returns an error if the notification is misconfigured.
+func (n *JobNotification) Validate() error {
+	var mErr multierror.Error
+	if u, err := url.Parse(n.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		multierror.Append(&mErr, fmt.Errorf("Notification webhook must be an http or https URL: %q", n.Webhook))
+	}
+	switch n.Method {
+	case "", "POST", "PUT":
+	default:
+		multierror.Append(&mErr, fmt.Errorf("Notification method must be POST or PUT: %q", n.Method))
+	}
+	if n.Timeout < 0 {
+		multierror.Append(&mErr, fmt.Errorf("Notification timeout must be non-negative: %v", n.Timeout))
+	}
+	if _, err := n.template(); err != nil {
+		multierror.Append(&mErr, fmt.Errorf("Notification payload is not a valid template: %v", err))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// template parses the payload template.
+func (n *JobNotification) template() (*template.Template, error) {
+	return template.New("payload").Funcs(jobNotificationFuncs).Parse(n.Payload)
+}
+
+// Render returns the body of the request notifying the event.
+func (n *JobNotification) Render(event *JobNotificationEvent) ([]byte, error) {
+	if n.Payload == "" {
+		return json.Marshal(event)
+	}
+
+	tmpl, err := n.template()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,75 @@
+package structs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJobNotification_Validate(t *testing.T) {
+	n := &JobNotification{
+		Webhook: "https://example.com/hook",
+		Payload: `{"job": {{ json .JobID }}}`,
+	}
+	if err := n.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	n = &JobNotification{
+		Webhook: "ftp://example.com",
+		Method:  "GET",
+		Timeout: -1,
+		Payload: "{{ .JobID ",
+	}
+	err := n.Validate()
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{"http or https URL", "POST or PUT", "non-negative", "valid template"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q: %v", expected, err)
+		}
+	}
+
+	// Notifications can only be used by batch jobs
+	j := testJob()
+	j.Notification = &JobNotification{Webhook: "https://example.com/hook"}
+	err = j.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Notification can only be used") {
+		t.Fatalf("expected error: %v", err)
+	}
+	j.Type = JobTypeBatch
+	if err := j.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestJobNotification_Render(t *testing.T) {
+	event := &JobNotificationEvent{
+		JobID:  "example/dispatch-1",
+		Status: JobStatusDead,
+		Failed: true,
+		Meta:   map[string]string{"owner": "data"},
+	}
+
+	n := &JobNotification{
+		Payload: `{"job": {{ json .JobID }}, "failed": {{ .Failed }}, "owner": "{{ .Meta.owner }}"}`,
+	}
+	out, err := n.Render(event)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := `{"job": "example/dispatch-1", "failed": true, "owner": "data"}`
+	if string(out) != expected {
+		t.Fatalf("got %s; want %s", out, expected)
+	}
+
+	// The event is sent as JSON without a payload
+	n.Payload = ""
+	out, err = n.Render(event)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.Contains(string(out), `"JobID":"example/dispatch-1"`) {
+		t.Fatalf("bad: %s", out)
+	}
+}
@@ -1,6 +1,8 @@
 package structs
 
 import (
+	"fmt"
+
 	"github.com/hashicorp/raft"
 )
 
@@ -47,3 +49,86 @@ type RaftPeerByAddressRequest struct {
 	// WriteRequest holds the Region for this request.
 	WriteRequest
 }
+
+const (
+	// SchedulerAlgorithmBinpack places allocations on the most utilized
+	// nodes that fit them, leaving other nodes free.
+	SchedulerAlgorithmBinpack = "binpack"
+
+	// SchedulerAlgorithmSpread places allocations on the least utilized
+	// nodes, spreading them across the cluster.
+	SchedulerAlgorithmSpread = "spread"
+)
+
+// SchedulerConfiguration is the configuration of the schedulers that can be
+// changed at runtime, without restarting the servers.
+type SchedulerConfiguration struct {
+	// SchedulerAlgorithm is the algorithm used to score the nodes that fit
+	// an allocation, either binpack or spread.
+	SchedulerAlgorithm string
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// EffectiveSchedulerAlgorithm returns the scheduler algorithm to use,
+// defaulting to binpack if none is set.
+func (s *SchedulerConfiguration) EffectiveSchedulerAlgorithm() string {
+	if s == nil || s.SchedulerAlgorithm == "" {
+		return SchedulerAlgorithmBinpack
+	}
+	return s.SchedulerAlgorithm
+}
+
+// Validate returns an error if the scheduler configuration is invalid.
+func (s *SchedulerConfiguration) Validate() error {
+	switch s.SchedulerAlgorithm {
+	case "", SchedulerAlgorithmBinpack, SchedulerAlgorithmSpread:
+	default:
+		return fmt.Errorf("invalid scheduler algorithm %q, must be one of %q or %q",
+			s.SchedulerAlgorithm, SchedulerAlgorithmBinpack, SchedulerAlgorithmSpread)
+	}
+	return nil
+}
+
+// DefaultSchedulerConfiguration returns the scheduler configuration used
+// until an operator sets one.
+func DefaultSchedulerConfiguration() *SchedulerConfiguration {
+	return &SchedulerConfiguration{
+		SchedulerAlgorithm: SchedulerAlgorithmBinpack,
+	}
+}
+
+// SchedulerConfigurationResponse is returned when querying the scheduler
+// configuration.
+type SchedulerConfigurationResponse struct {
+	// SchedulerConfig is the current scheduler configuration
+	SchedulerConfig *SchedulerConfiguration
+
+	QueryMeta
+}
+
+// SchedulerSetConfigRequest is used by the Operator endpoint to update the
+// scheduler configuration.
+type SchedulerSetConfigRequest struct {
+	// Config is the new scheduler configuration
+	Config SchedulerConfiguration
+
+	// CAS controls whether to use check-and-set semantics for this request,
+	// in which case the configuration is only updated if its ModifyIndex
+	// matches the one of Config.
+	CAS bool
+
+	// WriteRequest holds the Region for this request.
+	WriteRequest
+}
+
+// SchedulerSetConfigurationResponse is returned when updating the scheduler
+// configuration.
+type SchedulerSetConfigurationResponse struct {
+	// Updated is false if a check-and-set update failed
+	Updated bool
+
+	WriteMeta
+}
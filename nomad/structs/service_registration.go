@@ -0,0 +1,146 @@
+package structs
+
+import (
+	"fmt"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/helper"
+)
+
+const (
+	// ServiceProviderConsul registers the service in Consul. It is the
+	// default provider.
+	ServiceProviderConsul = "consul"
+
+	// ServiceProviderNomad registers the service in the catalog of the Nomad
+	// servers.
+	ServiceProviderNomad = "nomad"
+)
+
+const (
+	// ServiceRegistrationStatusPassing and ServiceRegistrationStatusCritical
+	// are the health statuses of the registrations whose service has checks.
+	// Registrations without checks have no status.
+	ServiceRegistrationStatusPassing  = "passing"
+	ServiceRegistrationStatusCritical = "critical"
+)
+
+// ServiceRegistration is the registration of an instance of a service using
+// the Nomad service discovery provider. Registrations are written by the
+// clients running the allocations and removed when the allocations stop.
+type ServiceRegistration struct {
+	// ID is the unique ID of the registration, stable for a given service
+	// of a task of an allocation
+	ID string
+
+	// ServiceName is the name of the registered service
+	ServiceName string
+
+	// JobID, AllocID and TaskName identify the task providing the service
+	JobID    string
+	AllocID  string
+	TaskName string
+
+	// NodeID and Datacenter identify the client running the task
+	NodeID     string
+	Datacenter string
+
+	// Tags are the tags of the service
+	Tags []string
+
+	// Address and Port are where the service can be reached
+	Address string
+	Port    int
+
+	// AddressIPv6 is the IPv6 address the service can also be reached at on
+	// the port of dual stack hosts
+	AddressIPv6 string
+
+	// Status is the health of the service as determined by its checks
+	Status string
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Validate returns an error if the registration is invalid.
+func (s *ServiceRegistration) Validate() error {
+	var mErr multierror.Error
+	if s.ID == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("missing ID"))
+	}
+	if s.ServiceName == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("missing service name"))
+	}
+	if s.AllocID == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("missing allocation ID"))
+	}
+	if s.NodeID == "" {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("missing node ID"))
+	}
+	switch s.Status {
+	case "", ServiceRegistrationStatusPassing, ServiceRegistrationStatusCritical:
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid status %q", s.Status))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// Copy returns a deep copy of the registration.
+func (s *ServiceRegistration) Copy() *ServiceRegistration {
+	if s == nil {
+		return nil
+	}
+	ns := new(ServiceRegistration)
+	*ns = *s
+	ns.Tags = helper.CopySliceString(s.Tags)
+	return ns
+}
+
+// ServiceRegistrationListStub summarizes a service of the catalog.
+type ServiceRegistrationListStub struct {
+	ServiceName string
+
+	// Tags is the union of the tags of the instances of the service
+	Tags []string
+}
+
+// ServiceRegistrationUpsertRequest is used to create or update a set of
+// service registrations
+type ServiceRegistrationUpsertRequest struct {
+	Services []*ServiceRegistration
+	WriteRequest
+}
+
+// ServiceRegistrationDeleteByIDRequest is used to delete a set of service
+// registrations by ID
+type ServiceRegistrationDeleteByIDRequest struct {
+	IDs []string
+	WriteRequest
+}
+
+// ServiceRegistrationListRequest is used to list the services of the catalog
+type ServiceRegistrationListRequest struct {
+	QueryOptions
+}
+
+// ServiceRegistrationListResponse is used for a list request
+type ServiceRegistrationListResponse struct {
+	Services []*ServiceRegistrationListStub
+	QueryMeta
+}
+
+// ServiceRegistrationByNameRequest is used to query the registrations of a
+// service
+type ServiceRegistrationByNameRequest struct {
+	ServiceName string
+	QueryOptions
+}
+
+// ServiceRegistrationByNameResponse is used to return the registrations of a
+// service
+type ServiceRegistrationByNameResponse struct {
+	Services []*ServiceRegistration
+	QueryMeta
+}
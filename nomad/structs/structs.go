@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +20,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorhill/cronexpr"
 	"github.com/hashicorp/consul/api"
@@ -33,8 +35,13 @@ import (
 )
 
 var (
-	ErrNoLeader     = fmt.Errorf("No cluster leader")
-	ErrNoRegionPath = fmt.Errorf("No path to region")
+	ErrNoLeader         = fmt.Errorf("No cluster leader")
+	ErrNoRegionPath     = fmt.Errorf("No path to region")
+	ErrPermissionDenied = fmt.Errorf("Permission denied")
+	ErrTokenNotFound    = fmt.Errorf("ACL token not found")
+	ErrTokenExpired     = fmt.Errorf("ACL token expired")
+	ErrRateLimited      = fmt.Errorf("Rate limit exceeded")
+	ErrNoNodeConn       = fmt.Errorf("Node is not connected to any server")
 )
 
 type MessageType uint8
@@ -59,6 +66,29 @@ const (
 	DeploymentAllocHealthRequestType
 	DeploymentDeleteRequestType
 	JobStabilityRequestType
+	NamespaceUpsertRequestType
+	NamespaceDeleteRequestType
+	SchedulerConfigRequestType
+	ServiceIdentityAccessorRegisterRequestType
+	ServiceIdentityAccessorDeregisterRequestType
+	ServiceRegistrationUpsertRequestType
+	ServiceRegistrationDeleteByIDRequestType
+	VariablesUpsertRequestType
+	VariablesDeleteRequestType
+	JobUpdatePriorityRequestType
+	JobNotifiedRequestType
+	ACLPolicyUpsertRequestType
+	ACLPolicyDeleteRequestType
+	ACLTokenUpsertRequestType
+	ACLTokenDeleteRequestType
+	ACLTokenBootstrapRequestType
+	ACLRoleUpsertRequestType
+	ACLRoleDeleteRequestType
+	ACLAuthMethodUpsertRequestType
+	ACLAuthMethodDeleteRequestType
+	ACLBindingRuleUpsertRequestType
+	ACLBindingRuleDeleteRequestType
+	DeploymentNotifiedRequestType
 )
 
 const (
@@ -96,6 +126,12 @@ type RPCInfo interface {
 	AllowStaleRead() bool
 }
 
+// AuthenticatedRequest is implemented by the requests made with the secret ID
+// of an ACL token
+type AuthenticatedRequest interface {
+	RequestAuthToken() string
+}
+
 // QueryOptions is used to specify various flags for read queries
 type QueryOptions struct {
 	// The target region for this query
@@ -114,12 +150,35 @@ type QueryOptions struct {
 
 	// If set, used as prefix for resource list searches
 	Prefix string
+
+	// PerPage is the number of entries to be returned in a list query. If
+	// unset, all entries are returned.
+	PerPage int32
+
+	// NextToken is the token used to resume a paginated list query. It is
+	// the value of the QueryMeta.NextToken of the previous page.
+	NextToken string
+
+	// Filter is a boolean expression evaluated against the objects of a list
+	// query. Only matching objects are returned.
+	Filter string
+
+	// Namespace restricts a list query to the objects of the namespace. The
+	// objects of every namespace are returned if it is empty.
+	Namespace string
+
+	// AuthToken is the secret ID of the ACL token of the query
+	AuthToken string
 }
 
 func (q QueryOptions) RequestRegion() string {
 	return q.Region
 }
 
+func (q QueryOptions) RequestAuthToken() string {
+	return q.AuthToken
+}
+
 // QueryOption only applies to reads, so always true
 func (q QueryOptions) IsRead() bool {
 	return true
@@ -132,6 +191,13 @@ func (q QueryOptions) AllowStaleRead() bool {
 type WriteRequest struct {
 	// The target region for this write
 	Region string
+
+	// TraceParent is the W3C traceparent of the span that issued the write,
+	// so the spans of the servers handling it join the same trace.
+	TraceParent string
+
+	// AuthToken is the secret ID of the ACL token of the write
+	AuthToken string
 }
 
 func (w WriteRequest) RequestRegion() string {
@@ -139,6 +205,10 @@ func (w WriteRequest) RequestRegion() string {
 	return w.Region
 }
 
+func (w WriteRequest) RequestAuthToken() string {
+	return w.AuthToken
+}
+
 // WriteRequest only applies to writes, always false
 func (w WriteRequest) IsRead() bool {
 	return false
@@ -161,6 +231,10 @@ type QueryMeta struct {
 
 	// Used to indicate if there is a known leader node
 	KnownLeader bool
+
+	// NextToken is the token used to request the next page of a paginated
+	// list query. It is empty if there are no more results.
+	NextToken string
 }
 
 // WriteMeta allows a write response to include potentially
@@ -208,6 +282,14 @@ type NodeServerInfo struct {
 type NodeUpdateStatusRequest struct {
 	NodeID string
 	Status string
+
+	// UpdatedAt is the time stamp at which the status was updated
+	UpdatedAt int64
+
+	// AllocUsage is the resources used by the allocations of the node, per
+	// allocation ID. It is tracked by the leader and not persisted.
+	AllocUsage map[string]*AllocResourceUsed
+
 	WriteRequest
 }
 
@@ -231,6 +313,20 @@ type NodeSpecificRequest struct {
 	QueryOptions
 }
 
+// NodeConnRequest is sent by a client to identify its node when it opens a
+// connection for the servers to make RPCs to it
+type NodeConnRequest struct {
+	NodeID   string
+	SecretID string
+}
+
+// NodeConnQueryResponse is used to reply whether a node is connected to a
+// server
+type NodeConnQueryResponse struct {
+	Connected bool
+	QueryMeta
+}
+
 // ResourceListResponse is used to return matches and information about whether
 // the match list is truncated specific to each type of context.
 type ResourceListResponse struct {
@@ -256,6 +352,45 @@ type ResourceListRequest struct {
 	// evaluation, allocation, or empty (indicated every context should be
 	// matched)
 	Context string
+
+	QueryOptions
+}
+
+// FuzzySearchRequest is used to parameterize a fuzzy search request. Objects
+// whose name or ID contains the text are returned.
+type FuzzySearchRequest struct {
+	// Text is what object names and IDs are matched against. I.e, if the given
+	// text were "web", potential matches might be "web" or "frontend-web"
+	Text string
+
+	// Context is the object type to search. A context can be allocs,
+	// deployments, jobs, nodes or empty (indicating every context should be
+	// searched)
+	Context string
+
+	QueryOptions
+}
+
+// FuzzyMatch is an object matching a fuzzy search.
+type FuzzyMatch struct {
+	// ID is the ID of the matching object
+	ID string
+
+	// Name is the human readable name of the matching object
+	Name string
+}
+
+// FuzzySearchResponse is used to return fuzzy search matches and whether the
+// matches of each context have been truncated.
+type FuzzySearchResponse struct {
+	// Matches maps context types to the objects matching the search text
+	Matches map[string][]FuzzyMatch
+
+	// Truncations indicates whether the matches for a particular context have
+	// been truncated
+	Truncations map[string]bool
+
+	QueryMeta
 }
 
 // JobRegisterRequest is used for Job.Register endpoint
@@ -269,6 +404,12 @@ type JobRegisterRequest struct {
 	EnforceIndex   bool
 	JobModifyIndex uint64
 
+	// PolicyOverride admits the job despite the denials of soft-mandatory
+	// admission policies. The PolicyOverrideJustification is required and
+	// logged by the servers along with the overridden denials.
+	PolicyOverride              bool
+	PolicyOverrideJustification string
+
 	WriteRequest
 }
 
@@ -285,6 +426,14 @@ type JobDeregisterRequest struct {
 	WriteRequest
 }
 
+// JobScaleRequest is used to change the count of a task group of a job.
+type JobScaleRequest struct {
+	JobID     string
+	TaskGroup string
+	Count     int
+	WriteRequest
+}
+
 // JobEvaluateRequest is used when we just need to re-evaluate a target job
 type JobEvaluateRequest struct {
 	JobID string
@@ -322,6 +471,14 @@ type JobDispatchRequest struct {
 	JobID   string
 	Payload []byte
 	Meta    map[string]string
+
+	// PayloadCompressed marks the payload as already snappy compressed.
+	PayloadCompressed bool
+
+	// IdempotencyToken deduplicates dispatches. If a job was already
+	// dispatched with the token, it is returned instead of dispatching
+	// another one.
+	IdempotencyToken string
 	WriteRequest
 }
 
@@ -343,6 +500,10 @@ type JobRevertRequest struct {
 	// version before reverting.
 	EnforcePriorVersion *uint64
 
+	// RequireStable if set only allows reverting to a version of the job
+	// that is marked as stable.
+	RequireStable bool
+
 	WriteRequest
 }
 
@@ -362,6 +523,44 @@ type JobStabilityResponse struct {
 	WriteMeta
 }
 
+// JobUpdatePriorityRequest is used to change the priority of a job without
+// changing its placements.
+type JobUpdatePriorityRequest struct {
+	JobID    string
+	Priority int
+
+	// SubmitTime is the submit time of the new version of the job. It is
+	// set by the server.
+	SubmitTime int64
+	WriteRequest
+}
+
+// JobNotifiedRequest is used by the leader to record that the notification
+// of a run of a job was sent.
+type JobNotifiedRequest struct {
+	JobID string
+
+	// JobModifyIndex is the job modify index of the run that was notified.
+	JobModifyIndex uint64
+	WriteRequest
+}
+
+// DeploymentNotifiedRequest is used by the leader to record that the
+// webhooks were notified of events of a deployment.
+type DeploymentNotifiedRequest struct {
+	DeploymentID string
+	Events       []string
+	WriteRequest
+}
+
+// JobUpdatePriorityResponse is the response when changing the priority of a
+// job.
+type JobUpdatePriorityResponse struct {
+	// JobVersion is the version of the job with the new priority.
+	JobVersion uint64
+	WriteMeta
+}
+
 // NodeListRequest is used to parameterize a list request
 type NodeListRequest struct {
 	QueryOptions
@@ -441,6 +640,10 @@ type AllocUpdateRequest struct {
 	// It is pulled out since it is common to reduce payload size.
 	Job *Job
 
+	// Evals is the list of new evaluations to create along with the
+	// allocation changes.
+	Evals []*Evaluation
+
 	WriteRequest
 }
 
@@ -455,6 +658,26 @@ type AllocSpecificRequest struct {
 	QueryOptions
 }
 
+// AllocStopRequest is used to stop an allocation and have it rescheduled
+type AllocStopRequest struct {
+	AllocID string
+	WriteRequest
+}
+
+// AllocSignalRequest is used to signal the tasks of an allocation through
+// the client running it
+type AllocSignalRequest struct {
+	AllocID string
+
+	// Task is the task to signal. If empty, all tasks are signalled.
+	Task string
+
+	// Signal is the name of the signal to send, for example SIGHUP.
+	Signal string
+
+	WriteRequest
+}
+
 // AllocsGetRequest is used to query a set of allocations
 type AllocsGetRequest struct {
 	AllocIDs []string
@@ -500,6 +723,52 @@ type DeriveVaultTokenRequest struct {
 	QueryOptions
 }
 
+// SignVaultIdentitiesRequest is used to request the workload identities the
+// following tasks in the given allocation log in to Vault with
+type SignVaultIdentitiesRequest struct {
+	NodeID   string
+	SecretID string
+	AllocID  string
+	Tasks    []string
+	QueryOptions
+}
+
+// SignVaultIdentitiesResponse returns the signed workload identity of each
+// requested task
+type SignVaultIdentitiesResponse struct {
+	// Identities is a mapping between the task name and its signed JWT
+	Identities map[string]string
+
+	// Error stores any error that occurred. Errors are stored here so we can
+	// communicate whether it is retriable
+	Error *RecoverableError
+
+	QueryMeta
+}
+
+// SignWorkloadIdentityRequest is used to request the workload identity the
+// tasks of the given allocation call the Nomad API with
+type SignWorkloadIdentityRequest struct {
+	NodeID   string
+	SecretID string
+	AllocID  string
+	QueryOptions
+}
+
+// SignWorkloadIdentityResponse returns the signed workload identity of the
+// allocation
+type SignWorkloadIdentityResponse struct {
+	// Identity is the signed JWT. It is empty if workload identities are
+	// not enabled on the servers.
+	Identity string
+
+	// Error stores any error that occurred. Errors are stored here so we can
+	// communicate whether it is retriable
+	Error *RecoverableError
+
+	QueryMeta
+}
+
 // VaultAccessorsRequest is used to operate on a set of Vault accessors
 type VaultAccessorsRequest struct {
 	Accessors []*VaultAccessor
@@ -514,6 +783,9 @@ type VaultAccessor struct {
 	Accessor    string
 	CreationTTL int
 
+	// Namespace is the Vault namespace the token was created in
+	Namespace string
+
 	// Raft Indexes
 	CreateIndex uint64
 }
@@ -536,6 +808,15 @@ type GenericRequest struct {
 	QueryOptions
 }
 
+// GarbageCollectRequest is used to force a garbage collection
+type GarbageCollectRequest struct {
+	// Scopes limits the collection to the given object types. If empty,
+	// every type of object is collected.
+	Scopes []string
+
+	QueryOptions
+}
+
 // DeploymentListRequest is used to list the deployments
 type DeploymentListRequest struct {
 	QueryOptions
@@ -781,6 +1062,11 @@ type JobListResponse struct {
 type JobVersionsRequest struct {
 	JobID string
 	Diffs bool
+
+	// DiffVersion, if set along with Diffs, is the version every returned
+	// version is compared to instead of its predecessor.
+	DiffVersion *uint64
+
 	QueryOptions
 }
 
@@ -823,6 +1109,14 @@ type JobPlanResponse struct {
 	WriteMeta
 }
 
+// AllocStopResponse is the response to stopping an allocation
+type AllocStopResponse struct {
+	// EvalID is the evaluation that replaces the stopped allocation
+	EvalID          string
+	EvalCreateIndex uint64
+	WriteMeta
+}
+
 // SingleAllocResponse is used to return a single allocation
 type SingleAllocResponse struct {
 	Alloc *Allocation
@@ -894,6 +1188,9 @@ type EvalAllocationsResponse struct {
 type PeriodicForceResponse struct {
 	EvalID          string
 	EvalCreateIndex uint64
+
+	// JobID is the ID of the child job that was launched
+	JobID string
 	WriteMeta
 }
 
@@ -983,6 +1280,11 @@ type Node struct {
 	// consuming resources.
 	Reserved *Resources
 
+	// MinDynamicPort and MaxDynamicPort are the range of the dynamic ports
+	// assigned on the node. The defaults are used if they are zero.
+	MinDynamicPort int
+	MaxDynamicPort int
+
 	// Links are used to 'link' this client to external
 	// systems. For example 'consul=foo.dc1' 'aws=i-83212'
 	// 'ami=ami-123'
@@ -1099,6 +1401,17 @@ func (ns Networks) Port(label string) (string, int) {
 	return "", 0
 }
 
+// PortIPv6 returns the IPv6 address of the network of the port with the
+// given label, if it has one.
+func (ns Networks) PortIPv6(label string) string {
+	for _, n := range ns {
+		if _, ok := n.PortLabels()[label]; ok {
+			return n.IPv6Address()
+		}
+	}
+	return ""
+}
+
 // Resources is used to define the resources available
 // on a client
 type Resources struct {
@@ -1256,14 +1569,22 @@ func (r *Resources) GoString() string {
 type Port struct {
 	Label string
 	Value int
+
+	// To is the port in the network namespace of the allocation the host
+	// port of a group network in bridge mode is mapped to. The host port is
+	// mapped to itself if it is zero.
+	To int
 }
 
 // NetworkResource is used to represent available network
 // resources
 type NetworkResource struct {
+	Mode          string // Mode of a group network
+	HostNetwork   string // Name of the host network, the default one if empty
 	Device        string // Name of the device
 	CIDR          string // CIDR block of addresses
 	IP            string // Host IP address
+	IPv6          string // Host IPv6 address reserved along with IP on dual stack hosts
 	MBits         int    // Throughput
 	ReservedPorts []Port // Host Reserved ports
 	DynamicPorts  []Port // Host Dynamically assigned ports
@@ -1284,8 +1605,8 @@ func (n *NetworkResource) Canonicalize() {
 // the minimum allowed.
 func (n *NetworkResource) MeetsMinResources() error {
 	var mErr multierror.Error
-	if n.MBits < 1 {
-		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum MBits value is 1; got %d", n.MBits))
+	if n.MBits < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("minimum MBits value is 0; got %d", n.MBits))
 	}
 	return mErr.ErrorOrNil()
 }
@@ -1322,6 +1643,88 @@ func (n *NetworkResource) GoString() string {
 	return fmt.Sprintf("*%#v", *n)
 }
 
+// CNINetwork returns the name of the CNI network configuration of a group
+// network and whether the network is set up by CNI plugins.
+func (n *NetworkResource) CNINetwork() (string, bool) {
+	if !strings.HasPrefix(n.Mode, NetworkModeCNIPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(n.Mode, NetworkModeCNIPrefix), true
+}
+
+// validateGroupNetwork returns an error if the network can't be the network
+// of a task group.
+func (n *NetworkResource) validateGroupNetwork() error {
+	var mErr multierror.Error
+	switch {
+	case n.Mode == "" || n.Mode == NetworkModeHost:
+	case n.Mode == NetworkModeBridge:
+	case strings.HasPrefix(n.Mode, NetworkModeCNIPrefix):
+		if name, _ := n.CNINetwork(); name == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Network mode %q is missing the CNI network name", n.Mode))
+		}
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Invalid network mode %q", n.Mode))
+	}
+	if n.Device != "" || n.CIDR != "" || n.IP != "" || n.IPv6 != "" {
+		mErr.Errors = append(mErr.Errors, errors.New("Group networks can't set a device, CIDR or IP"))
+	}
+	if n.MBits < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Invalid bandwidth %d", n.MBits))
+	} else if n.MBits != 0 && n.Mode != NetworkModeBridge {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Only group networks in %q mode can reserve bandwidth", NetworkModeBridge))
+	}
+	if n.HostNetwork != "" && n.Mode != NetworkModeBridge {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Only group networks in %q mode can use a host network", NetworkModeBridge))
+	}
+
+	ports := len(n.ReservedPorts) + len(n.DynamicPorts)
+	if ports != 0 && n.Mode != NetworkModeBridge {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Only group networks in %q mode can reserve ports", NetworkModeBridge))
+	}
+	labels := make(map[string]struct{}, ports)
+	for _, list := range [][]Port{n.ReservedPorts, n.DynamicPorts} {
+		for _, port := range list {
+			if _, ok := labels[port.Label]; ok {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Port label %q is duplicate", port.Label))
+			}
+			labels[port.Label] = struct{}{}
+			if port.To < 0 || port.To >= maxValidPort {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Port %q maps to invalid port %d", port.Label, port.To))
+			}
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// PortMappings returns a map of the port labels of a group network to the
+// ports they are mapped to in the network namespace of the allocation.
+func (n *NetworkResource) PortMappings() map[string]int {
+	mappings := make(map[string]int, len(n.ReservedPorts)+len(n.DynamicPorts))
+	for _, list := range [][]Port{n.ReservedPorts, n.DynamicPorts} {
+		for _, port := range list {
+			if port.To != 0 {
+				mappings[port.Label] = port.To
+			} else {
+				mappings[port.Label] = port.Value
+			}
+		}
+	}
+	return mappings
+}
+
+// IPv6Address returns the IPv6 address of the network: the one reserved
+// along with its IP on dual stack hosts, or its IP if it is an IPv6 address.
+func (n *NetworkResource) IPv6Address() string {
+	if n.IPv6 != "" {
+		return n.IPv6
+	}
+	if ip := net.ParseIP(n.IP); ip != nil && ip.To4() == nil {
+		return n.IP
+	}
+	return ""
+}
+
 // PortLabels returns a map of port labels to their assigned host ports.
 func (n *NetworkResource) PortLabels() map[string]int {
 	num := len(n.ReservedPorts) + len(n.DynamicPorts)
@@ -1335,6 +1738,30 @@ func (n *NetworkResource) PortLabels() map[string]int {
 	return labelValues
 }
 
+const (
+	// NetworkModeHost is the default mode of a group network, in which the
+	// tasks use the network of the host.
+	NetworkModeHost = "host"
+
+	// NetworkModeBridge is the mode of a group network in which the client
+	// joins the network namespace of the allocation to its bridge and maps
+	// the ports of the group to it.
+	NetworkModeBridge = "bridge"
+
+	// NetworkModeCNIPrefix prefixes the mode of a group network set up by
+	// the CNI plugins of the named network configuration of the client, such
+	// as "cni/mynet".
+	NetworkModeCNIPrefix = "cni/"
+
+	// NodeCNIConfigAttributePrefix prefixes the node attributes naming the
+	// CNI network configurations of a client.
+	NodeCNIConfigAttributePrefix = "plugins.cni.config."
+
+	// NodeCNIBridgeAttribute is set on clients that have the CNI plugins of
+	// the bridge network mode.
+	NodeCNIBridgeAttribute = "plugins.cni.bridge"
+)
+
 const (
 	// JobTypeNomad is reserved for internal system tasks and is
 	// always handled by the CoreScheduler.
@@ -1392,6 +1819,10 @@ type Job struct {
 	// ParentID is the unique identifier of the job that spawned this job.
 	ParentID string
 
+	// Namespace is the namespace of the job. Job IDs are unique across
+	// namespaces, and a job can't be moved to another namespace.
+	Namespace string
+
 	// Name is the logical name of the job used to refer to it. This is unique
 	// per region, but not unique globally.
 	Name string
@@ -1432,9 +1863,24 @@ type Job struct {
 	// for dispatching.
 	ParameterizedJob *ParameterizedJobConfig
 
+	// GC is used to override the server's garbage collection thresholds for
+	// objects belonging to this job.
+	GC *JobGCConfig
+
+	// Notification is called when the job reaches a terminal state.
+	Notification *JobNotification
+
+	// DependsOn are the IDs of the batch jobs that must complete
+	// successfully before the job is placed.
+	DependsOn []string
+
 	// Payload is the payload supplied when the job was dispatched.
 	Payload []byte
 
+	// DispatchIdempotencyToken is the idempotency token supplied when the
+	// job was dispatched.
+	DispatchIdempotencyToken string
+
 	// Meta is used to associate arbitrary metadata with this
 	// job. This is opaque to Nomad.
 	Meta map[string]string
@@ -1463,6 +1909,29 @@ type Job struct {
 	// UTC
 	SubmitTime int64
 
+	// RevertVersion is set if this version of the job was created by
+	// reverting the job, to the version that was reverted to.
+	RevertVersion *uint64
+
+	// RevertReason is why the job was automatically reverted, such as the
+	// failure of a deployment of the previous version.
+	RevertReason string
+
+	// PolicyOverrideJustification is set if this version of the job was
+	// admitted by overriding the denials of soft-mandatory admission
+	// policies, to the justification of the override.
+	PolicyOverrideJustification string
+
+	// NoPlacementChange is set if this version of the job was created by a
+	// change, such as of the priority, that doesn't change the placements
+	// of the job. Its allocations and deployment are left untouched.
+	NoPlacementChange bool
+
+	// NotifiedIndex is the JobModifyIndex of the job when its notification
+	// was last sent, so that each run of the job is notified once even if
+	// the leader changes.
+	NotifiedIndex uint64
+
 	// Raft Indexes
 	CreateIndex    uint64
 	ModifyIndex    uint64
@@ -1474,6 +1943,10 @@ type Job struct {
 // in anyway that the user should be made aware of.
 func (j *Job) Canonicalize() (warnings error) {
 	var mErr multierror.Error
+	if j.Namespace == "" {
+		j.Namespace = DefaultNamespace
+	}
+
 	// Ensure that an empty and nil map are treated the same to avoid scheduling
 	// problems since we use reflect DeepEquals.
 	if len(j.Meta) == 0 {
@@ -1555,6 +2028,8 @@ func (j *Job) Canonicalize() (warnings error) {
 		j.Update.HealthyDeadline = 0
 		j.Update.AutoRevert = false
 		j.Update.Canary = 0
+		j.Update.AutoPromote = false
+		j.Update.ProgressDeadline = 0
 
 		// Remove any update spec from the task groups
 		for _, tg := range j.TaskGroups {
@@ -1596,6 +2071,9 @@ func (j *Job) Copy() *Job {
 	nj.Periodic = nj.Periodic.Copy()
 	nj.Meta = helper.CopyMapStringString(nj.Meta)
 	nj.ParameterizedJob = nj.ParameterizedJob.Copy()
+	nj.GC = nj.GC.Copy()
+	nj.Notification = nj.Notification.Copy()
+	nj.DependsOn = helper.CopySliceString(nj.DependsOn)
 	return nj
 }
 
@@ -1611,6 +2089,9 @@ func (j *Job) Validate() error {
 	} else if strings.Contains(j.ID, " ") {
 		mErr.Errors = append(mErr.Errors, errors.New("Job ID contains a space"))
 	}
+	if j.Namespace != "" && !validNamespaceName.MatchString(j.Namespace) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Invalid job namespace %q", j.Namespace))
+	}
 	if j.Name == "" {
 		mErr.Errors = append(mErr.Errors, errors.New("Missing job name"))
 	}
@@ -1686,6 +2167,40 @@ func (j *Job) Validate() error {
 		}
 	}
 
+	if j.GC != nil {
+		if err := j.GC.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+
+	if len(j.DependsOn) != 0 {
+		if j.Type != JobTypeBatch {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("Job dependencies can only be used with %q scheduler", JobTypeBatch))
+		}
+
+		seen := make(map[string]struct{}, len(j.DependsOn))
+		for _, id := range j.DependsOn {
+			if id == "" || id == j.ID {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Job can't depend on itself or an empty job ID"))
+			} else if _, ok := seen[id]; ok {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Job dependency %q is listed twice", id))
+			}
+			seen[id] = struct{}{}
+		}
+	}
+
+	if j.Notification != nil {
+		if j.Type != JobTypeBatch {
+			mErr.Errors = append(mErr.Errors,
+				fmt.Errorf("Notification can only be used with %q scheduler", JobTypeBatch))
+		}
+
+		if err := j.Notification.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+
 	return mErr.ErrorOrNil()
 }
 
@@ -1772,6 +2287,7 @@ func (j *Job) Stub(summary *JobSummary) *JobListStub {
 	return &JobListStub{
 		ID:                j.ID,
 		ParentID:          j.ParentID,
+		Namespace:         j.Namespace,
 		Name:              j.Name,
 		Type:              j.Type,
 		Priority:          j.Priority,
@@ -1889,6 +2405,10 @@ func (j *Job) SpecChanged(new *Job) bool {
 	c.ModifyIndex = j.ModifyIndex
 	c.JobModifyIndex = j.JobModifyIndex
 	c.SubmitTime = j.SubmitTime
+	c.RevertVersion = j.RevertVersion
+	c.RevertReason = j.RevertReason
+	c.PolicyOverrideJustification = j.PolicyOverrideJustification
+	c.NoPlacementChange = j.NoPlacementChange
 
 	// Deep equals the jobs
 	return !reflect.DeepEqual(j, c)
@@ -1903,6 +2423,7 @@ func (j *Job) SetSubmitTime() {
 type JobListStub struct {
 	ID                string
 	ParentID          string
+	Namespace         string
 	Name              string
 	Type              string
 	Priority          int
@@ -1973,6 +2494,7 @@ type TaskGroupSummary struct {
 	Running  int
 	Starting int
 	Lost     int
+	Unknown  int
 }
 
 const (
@@ -1994,13 +2516,15 @@ var (
 	// DefaultUpdateStrategy provides a baseline that can be used to upgrade
 	// jobs with the old policy or for populating field defaults.
 	DefaultUpdateStrategy = &UpdateStrategy{
-		Stagger:         30 * time.Second,
-		MaxParallel:     0,
-		HealthCheck:     UpdateStrategyHealthCheck_Checks,
-		MinHealthyTime:  10 * time.Second,
-		HealthyDeadline: 5 * time.Minute,
-		AutoRevert:      false,
-		Canary:          0,
+		Stagger:          30 * time.Second,
+		MaxParallel:      0,
+		HealthCheck:      UpdateStrategyHealthCheck_Checks,
+		MinHealthyTime:   10 * time.Second,
+		HealthyDeadline:  5 * time.Minute,
+		AutoRevert:       false,
+		Canary:           0,
+		AutoPromote:      false,
+		ProgressDeadline: 10 * time.Minute,
 	}
 )
 
@@ -2035,6 +2559,17 @@ type UpdateStrategy struct {
 	// Canary is the number of canaries to deploy when a change to the task
 	// group is detected.
 	Canary int
+
+	// AutoPromote declares that the deployment should be promoted once all
+	// the canaries of the task groups are healthy, without a manual
+	// promotion.
+	AutoPromote bool
+
+	// ProgressDeadline is the time in which an allocation of the task group
+	// must become healthy, since the deployment started or an allocation
+	// last became healthy, before the deployment is marked as failed. Zero
+	// disables the deadline.
+	ProgressDeadline time.Duration
 }
 
 func (u *UpdateStrategy) Copy() *UpdateStrategy {
@@ -2065,6 +2600,9 @@ func (u *UpdateStrategy) Validate() error {
 	if u.Canary < 0 {
 		multierror.Append(&mErr, fmt.Errorf("Canary count can not be less than zero: %d < 0", u.Canary))
 	}
+	if u.AutoPromote && u.Canary == 0 {
+		multierror.Append(&mErr, fmt.Errorf("Auto promote requires a canary count greater than zero"))
+	}
 	if u.MinHealthyTime < 0 {
 		multierror.Append(&mErr, fmt.Errorf("Minimum healthy time may not be less than zero: %v", u.MinHealthyTime))
 	}
@@ -2074,6 +2612,11 @@ func (u *UpdateStrategy) Validate() error {
 	if u.MinHealthyTime >= u.HealthyDeadline {
 		multierror.Append(&mErr, fmt.Errorf("Minimum healthy time must be less than healthy deadline: %v > %v", u.MinHealthyTime, u.HealthyDeadline))
 	}
+	if u.ProgressDeadline < 0 {
+		multierror.Append(&mErr, fmt.Errorf("Progress deadline may not be less than zero: %v", u.ProgressDeadline))
+	} else if u.ProgressDeadline != 0 && u.HealthyDeadline > u.ProgressDeadline {
+		multierror.Append(&mErr, fmt.Errorf("Healthy deadline may not be greater than progress deadline: %v > %v", u.HealthyDeadline, u.ProgressDeadline))
+	}
 	if u.Stagger <= 0 {
 		multierror.Append(&mErr, fmt.Errorf("Stagger must be greater than zero: %v", u.Stagger))
 	}
@@ -2094,6 +2637,19 @@ const (
 	// PeriodicSpecTest is only used by unit tests. It is a sorted, comma
 	// separated list of unix timestamps at which to launch.
 	PeriodicSpecTest = "_internal_test"
+
+	// PeriodicDSTRunOnce and PeriodicDSTSkip are the ways launch times made
+	// nondeterministic by daylight saving time changes are handled. With
+	// PeriodicDSTRunOnce, launch times skipped by the clock run once when the
+	// clock skips ahead and launch times repeated by the clock run once, the
+	// first time they occur. With PeriodicDSTSkip, both don't run.
+	PeriodicDSTRunOnce = "run_once"
+	PeriodicDSTSkip    = "skip"
+
+	// periodicMaxCronIterations bounds the number of cron launch times
+	// skipped looking for the next launch time, in case of a daylight saving
+	// time change.
+	periodicMaxCronIterations = 1 << 16
 )
 
 // Periodic defines the interval a job should be run at.
@@ -2105,6 +2661,10 @@ type PeriodicConfig struct {
 	// on the SpecType.
 	Spec string
 
+	// Specs is a list of cron specs the job is launched at, used instead of
+	// Spec to combine schedules.
+	Specs []string
+
 	// SpecType defines the format of the spec.
 	SpecType string
 
@@ -2118,6 +2678,10 @@ type PeriodicConfig struct {
 	// Reference: https://www.iana.org/time-zones
 	TimeZone string
 
+	// DSTMode is how launch times skipped or repeated by daylight saving time
+	// changes in the time zone are handled. Defaults to PeriodicDSTRunOnce.
+	DSTMode string
+
 	// location is the time zone to evaluate the launch time against
 	location *time.Location
 }
@@ -2128,6 +2692,7 @@ func (p *PeriodicConfig) Copy() *PeriodicConfig {
 	}
 	np := new(PeriodicConfig)
 	*np = *p
+	np.Specs = helper.CopySliceString(p.Specs)
 	return np
 }
 
@@ -2137,8 +2702,10 @@ func (p *PeriodicConfig) Validate() error {
 	}
 
 	var mErr multierror.Error
-	if p.Spec == "" {
+	if p.Spec == "" && len(p.Specs) == 0 {
 		multierror.Append(&mErr, fmt.Errorf("Must specify a spec"))
+	} else if p.Spec != "" && len(p.Specs) != 0 {
+		multierror.Append(&mErr, fmt.Errorf("Must specify either a spec or a list of specs, not both"))
 	}
 
 	// Check if we got a valid time zone
@@ -2148,14 +2715,24 @@ func (p *PeriodicConfig) Validate() error {
 		}
 	}
 
+	switch p.DSTMode {
+	case "", PeriodicDSTRunOnce, PeriodicDSTSkip:
+	default:
+		multierror.Append(&mErr, fmt.Errorf("Invalid DST mode %q, expected %q or %q", p.DSTMode, PeriodicDSTRunOnce, PeriodicDSTSkip))
+	}
+
 	switch p.SpecType {
 	case PeriodicSpecCron:
-		// Validate the cron spec
-		if _, err := cronexpr.Parse(p.Spec); err != nil {
-			multierror.Append(&mErr, fmt.Errorf("Invalid cron spec %q: %v", p.Spec, err))
+		// Validate the cron specs
+		for _, spec := range p.cronSpecs() {
+			if _, err := cronexpr.Parse(spec); err != nil {
+				multierror.Append(&mErr, fmt.Errorf("Invalid cron spec %q: %v", spec, err))
+			}
 		}
 	case PeriodicSpecTest:
-		// No-op
+		if len(p.Specs) != 0 {
+			multierror.Append(&mErr, fmt.Errorf("A list of specs is only supported for cron specs"))
+		}
 	default:
 		multierror.Append(&mErr, fmt.Errorf("Unknown periodic specification type %q", p.SpecType))
 	}
@@ -2163,6 +2740,14 @@ func (p *PeriodicConfig) Validate() error {
 	return mErr.ErrorOrNil()
 }
 
+// cronSpecs returns the cron specs the job is launched at.
+func (p *PeriodicConfig) cronSpecs() []string {
+	if len(p.Specs) != 0 {
+		return p.Specs
+	}
+	return []string{p.Spec}
+}
+
 func (p *PeriodicConfig) Canonicalize() {
 	// Load the location
 	l, err := time.LoadLocation(p.TimeZone)
@@ -2180,9 +2765,18 @@ func (p *PeriodicConfig) Canonicalize() {
 func (p *PeriodicConfig) Next(fromTime time.Time) time.Time {
 	switch p.SpecType {
 	case PeriodicSpecCron:
-		if e, err := cronexpr.Parse(p.Spec); err == nil {
-			return e.Next(fromTime)
+		// Launch at the closest time of any of the specs
+		var next time.Time
+		for _, spec := range p.cronSpecs() {
+			e, err := cronexpr.Parse(spec)
+			if err != nil {
+				continue
+			}
+			if n := p.nextCron(e, fromTime); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+				next = n
+			}
 		}
+		return next
 	case PeriodicSpecTest:
 		split := strings.Split(p.Spec, ",")
 		if len(split) == 1 && split[0] == "" {
@@ -2211,6 +2805,89 @@ func (p *PeriodicConfig) Next(fromTime time.Time) time.Time {
 	return time.Time{}
 }
 
+// nextCron returns the closest launch time of the cron expression after the
+// passed time. The expression is evaluated against the wall clock of the
+// time zone of the passed time, so that launch times skipped or repeated by
+// daylight saving time changes are handled according to the DST mode.
+func (p *PeriodicConfig) nextCron(e *cronexpr.Expression, fromTime time.Time) time.Time {
+	loc := fromTime.Location()
+	wall := wallClock(fromTime, time.UTC)
+	for i := 0; i < periodicMaxCronIterations; i++ {
+		wall = e.Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+
+		launch := wallClock(wall, loc)
+		if !wallClock(launch, time.UTC).Equal(wall) {
+			// The launch time doesn't exist since the clock skips ahead over
+			// it, so it runs once when the clock skips ahead
+			if p.DSTMode == PeriodicDSTSkip {
+				continue
+			}
+			launch = zoneTransition(launch.Add(-24*time.Hour), launch.Add(24*time.Hour))
+		} else if twin, ok := repeatedWallClock(launch); ok {
+			// The launch time occurs twice since the clock is set back over
+			// it, so it only runs the first time
+			if p.DSTMode == PeriodicDSTSkip {
+				continue
+			}
+			if twin.Before(launch) {
+				launch = twin
+			}
+		}
+
+		if !launch.IsZero() && launch.After(fromTime) {
+			return launch
+		}
+	}
+
+	return time.Time{}
+}
+
+// wallClock returns the time with the same wall clock as t in the location.
+// If the wall clock doesn't exist in the location, the time is normalized as
+// time.Date does.
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// zoneTransition returns the time at which the offset of the time zone of
+// start changes, searching until end, or the zero time if it doesn't.
+func zoneTransition(start, end time.Time) time.Time {
+	_, offset := start.Zone()
+	if _, o := end.Zone(); o == offset {
+		return time.Time{}
+	}
+
+	// Time zones change offset on whole seconds
+	for end.Sub(start) > time.Second {
+		mid := start.Add(end.Sub(start) / time.Second / 2 * time.Second)
+		if _, o := mid.Zone(); o == offset {
+			start = mid
+		} else {
+			end = mid
+		}
+	}
+	return end
+}
+
+// repeatedWallClock returns the other time with the same wall clock as t in
+// its time zone, if the clock is set back over it.
+func repeatedWallClock(t time.Time) (time.Time, bool) {
+	_, offset := t.Zone()
+	for _, other := range []time.Time{t.Add(-24 * time.Hour), t.Add(24 * time.Hour)} {
+		_, o := other.Zone()
+		if o == offset {
+			continue
+		}
+		if twin := t.Add(time.Duration(offset-o) * time.Second); wallClock(twin, time.UTC).Equal(wallClock(t, time.UTC)) {
+			return twin, true
+		}
+	}
+	return time.Time{}, false
+}
+
 // GetLocation returns the location to use for determining the time zone to run
 // the periodic job against.
 func (p *PeriodicConfig) GetLocation() *time.Location {
@@ -2238,6 +2915,69 @@ type PeriodicLaunch struct {
 	ModifyIndex uint64
 }
 
+// JobGCConfig allows a job or a namespace to override the server's garbage
+// collection thresholds. A zero threshold means the default is used: that of
+// the namespace for a job, and that of the server otherwise.
+type JobGCConfig struct {
+	// AllocThreshold is how long a terminal allocation must be before it is
+	// eligible for garbage collection. If unset, the evaluation threshold is
+	// used.
+	AllocThreshold time.Duration
+
+	// EvalThreshold is how long a terminal evaluation must be before it is
+	// eligible for garbage collection.
+	EvalThreshold time.Duration
+
+	// DeploymentThreshold is how long a terminal deployment must be before it
+	// is eligible for garbage collection.
+	DeploymentThreshold time.Duration
+}
+
+func (g *JobGCConfig) Copy() *JobGCConfig {
+	if g == nil {
+		return nil
+	}
+	ng := new(JobGCConfig)
+	*ng = *g
+	return ng
+}
+
+// Merge returns the thresholds of the config, taking those it leaves unset
+// from the passed defaults. Nil is returned if both are nil.
+func (g *JobGCConfig) Merge(defaults *JobGCConfig) *JobGCConfig {
+	if g == nil {
+		return defaults.Copy()
+	}
+	result := g.Copy()
+	if defaults == nil {
+		return result
+	}
+	if result.AllocThreshold == 0 {
+		result.AllocThreshold = defaults.AllocThreshold
+	}
+	if result.EvalThreshold == 0 {
+		result.EvalThreshold = defaults.EvalThreshold
+	}
+	if result.DeploymentThreshold == 0 {
+		result.DeploymentThreshold = defaults.DeploymentThreshold
+	}
+	return result
+}
+
+func (g *JobGCConfig) Validate() error {
+	var mErr multierror.Error
+	if g.AllocThreshold < 0 {
+		multierror.Append(&mErr, fmt.Errorf("Alloc GC threshold must be non-negative: %v", g.AllocThreshold))
+	}
+	if g.EvalThreshold < 0 {
+		multierror.Append(&mErr, fmt.Errorf("Eval GC threshold must be non-negative: %v", g.EvalThreshold))
+	}
+	if g.DeploymentThreshold < 0 {
+		multierror.Append(&mErr, fmt.Errorf("Deployment GC threshold must be non-negative: %v", g.DeploymentThreshold))
+	}
+	return mErr.ErrorOrNil()
+}
+
 const (
 	DispatchPayloadForbidden = "forbidden"
 	DispatchPayloadOptional  = "optional"
@@ -2246,6 +2986,17 @@ const (
 	// DispatchLaunchSuffix is the string appended to the parameterized job's ID
 	// when dispatching instances of it.
 	DispatchLaunchSuffix = "/dispatch-"
+
+	// The types the values of dispatch meta keys can be constrained to.
+	DispatchMetaTypeString = "string"
+	DispatchMetaTypeInt    = "int"
+	DispatchMetaTypeFloat  = "float"
+	DispatchMetaTypeBool   = "bool"
+
+	// The content types dispatch payloads can be constrained to: JSON or
+	// UTF-8 text.
+	DispatchPayloadContentTypeJSON = "application/json"
+	DispatchPayloadContentTypeText = "text/plain"
 )
 
 // ParameterizedJobConfig is used to configure the parameterized job
@@ -2258,6 +3009,13 @@ type ParameterizedJobConfig struct {
 
 	// MetaOptional is metadata keys that may be specified by the dispatcher
 	MetaOptional []string
+
+	// MetaSchema constrains the values of the metadata keys, keyed by the
+	// required or optional key it applies to.
+	MetaSchema map[string]*DispatchMetaSchema
+
+	// PayloadSchema constrains the payload, if it is not forbidden.
+	PayloadSchema *DispatchPayloadSchema
 }
 
 func (d *ParameterizedJobConfig) Validate() error {
@@ -2274,6 +3032,30 @@ func (d *ParameterizedJobConfig) Validate() error {
 		multierror.Append(&mErr, fmt.Errorf("Required and optional meta keys should be disjoint. Following keys exist in both: %v", offending))
 	}
 
+	// Check the meta schemas apply to declared keys
+	keys := make([]string, 0, len(d.MetaSchema))
+	for k := range d.MetaSchema {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !helper.SliceStringContains(d.MetaRequired, k) && !helper.SliceStringContains(d.MetaOptional, k) {
+			multierror.Append(&mErr, fmt.Errorf("Meta schema for key %q that is neither required nor optional", k))
+			continue
+		}
+		if err := d.MetaSchema[k].Validate(); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("Meta schema for key %q invalid: %v", k, err))
+		}
+	}
+
+	if d.PayloadSchema != nil {
+		if d.Payload == DispatchPayloadForbidden {
+			multierror.Append(&mErr, fmt.Errorf("Payload schema can't be set when the payload is forbidden"))
+		} else if err := d.PayloadSchema.Validate(); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("Payload schema invalid: %v", err))
+		}
+	}
+
 	return mErr.ErrorOrNil()
 }
 
@@ -2291,9 +3073,180 @@ func (d *ParameterizedJobConfig) Copy() *ParameterizedJobConfig {
 	*nd = *d
 	nd.MetaOptional = helper.CopySliceString(nd.MetaOptional)
 	nd.MetaRequired = helper.CopySliceString(nd.MetaRequired)
+	if d.MetaSchema != nil {
+		nd.MetaSchema = make(map[string]*DispatchMetaSchema, len(d.MetaSchema))
+		for k, v := range d.MetaSchema {
+			nd.MetaSchema[k] = v.Copy()
+		}
+	}
+	nd.PayloadSchema = nd.PayloadSchema.Copy()
 	return nd
 }
 
+// DispatchMetaSchema constrains the value of a dispatch metadata key.
+type DispatchMetaSchema struct {
+	// Type is the type the value must parse as. Defaults to string.
+	Type string
+
+	// Pattern is a regular expression the value must match, if set.
+	Pattern string
+
+	// Allowed is the list of values allowed, if not empty.
+	Allowed []string
+}
+
+func (s *DispatchMetaSchema) Copy() *DispatchMetaSchema {
+	if s == nil {
+		return nil
+	}
+	ns := new(DispatchMetaSchema)
+	*ns = *s
+	ns.Allowed = helper.CopySliceString(ns.Allowed)
+	return ns
+}
+
+func (s *DispatchMetaSchema) Validate() error {
+	if s == nil {
+		return fmt.Errorf("missing schema")
+	}
+
+	var mErr multierror.Error
+	switch s.Type {
+	case "", DispatchMetaTypeString, DispatchMetaTypeInt, DispatchMetaTypeFloat, DispatchMetaTypeBool:
+	default:
+		multierror.Append(&mErr, fmt.Errorf("unknown type %q", s.Type))
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("invalid pattern %q: %v", s.Pattern, err))
+		}
+	}
+	if mErr.ErrorOrNil() != nil {
+		return mErr.ErrorOrNil()
+	}
+
+	// The allowed values must be valid themselves
+	for _, v := range s.Allowed {
+		if err := s.checkValue(v); err != nil {
+			multierror.Append(&mErr, fmt.Errorf("allowed %v", err))
+		}
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ValidateValue returns an error describing why the value doesn't conform to
+// the schema, if it doesn't.
+func (s *DispatchMetaSchema) ValidateValue(value string) error {
+	if err := s.checkValue(value); err != nil {
+		return err
+	}
+	if len(s.Allowed) != 0 && !helper.SliceStringContains(s.Allowed, value) {
+		return fmt.Errorf("value %q is not one of the allowed values %v", value, s.Allowed)
+	}
+	return nil
+}
+
+// checkValue checks the type and the pattern of the value.
+func (s *DispatchMetaSchema) checkValue(value string) error {
+	var err error
+	switch s.Type {
+	case DispatchMetaTypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case DispatchMetaTypeFloat:
+		_, err = strconv.ParseFloat(value, 64)
+	case DispatchMetaTypeBool:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("value %q is not of type %s", value, s.Type)
+	}
+
+	if s.Pattern != "" {
+		if matched, _ := regexp.MatchString(s.Pattern, value); !matched {
+			return fmt.Errorf("value %q doesn't match the pattern %q", value, s.Pattern)
+		}
+	}
+	return nil
+}
+
+// DispatchPayloadSchema constrains the payload of dispatch requests.
+type DispatchPayloadSchema struct {
+	// MaxSize is the maximum size in bytes of the uncompressed payload, if
+	// not zero. It can't raise the limit of the servers.
+	MaxSize int
+
+	// ContentType is the content type the payload must have, if set.
+	ContentType string
+
+	// RequiredFields are the fields a JSON payload must be an object with.
+	RequiredFields []string
+}
+
+func (s *DispatchPayloadSchema) Copy() *DispatchPayloadSchema {
+	if s == nil {
+		return nil
+	}
+	ns := new(DispatchPayloadSchema)
+	*ns = *s
+	ns.RequiredFields = helper.CopySliceString(ns.RequiredFields)
+	return ns
+}
+
+func (s *DispatchPayloadSchema) Validate() error {
+	var mErr multierror.Error
+	if s.MaxSize < 0 {
+		multierror.Append(&mErr, fmt.Errorf("max size can't be negative"))
+	}
+	switch s.ContentType {
+	case "", DispatchPayloadContentTypeJSON, DispatchPayloadContentTypeText:
+	default:
+		multierror.Append(&mErr, fmt.Errorf("unknown content type %q, expected %q or %q",
+			s.ContentType, DispatchPayloadContentTypeJSON, DispatchPayloadContentTypeText))
+	}
+	if len(s.RequiredFields) != 0 && s.ContentType != DispatchPayloadContentTypeJSON {
+		multierror.Append(&mErr, fmt.Errorf("required fields are only supported for %q payloads", DispatchPayloadContentTypeJSON))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// ValidatePayload returns an error describing why the uncompressed payload
+// doesn't conform to the schema, if it doesn't.
+func (s *DispatchPayloadSchema) ValidatePayload(payload []byte) error {
+	if s.MaxSize != 0 && len(payload) > s.MaxSize {
+		return fmt.Errorf("Payload exceeds maximum size of the parameterized job; %d > %d", len(payload), s.MaxSize)
+	}
+
+	switch s.ContentType {
+	case DispatchPayloadContentTypeText:
+		if !utf8.Valid(payload) {
+			return fmt.Errorf("Payload is not valid UTF-8 text")
+		}
+	case DispatchPayloadContentTypeJSON:
+		var v interface{}
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return fmt.Errorf("Payload is not valid JSON: %v", err)
+		}
+		if len(s.RequiredFields) == 0 {
+			return nil
+		}
+
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Payload is not a JSON object with the required fields %v", s.RequiredFields)
+		}
+		var missing []string
+		for _, f := range s.RequiredFields {
+			if _, ok := obj[f]; !ok {
+				missing = append(missing, f)
+			}
+		}
+		if len(missing) != 0 {
+			return fmt.Errorf("Payload is missing the required fields %v", missing)
+		}
+	}
+	return nil
+}
+
 // DispatchedID returns an ID appropriate for a job dispatched against a
 // particular parameterized job
 func DispatchedID(templateID string, t time.Time) string {
@@ -2324,7 +3277,52 @@ func (d *DispatchPayloadConfig) Validate() error {
 	} else if escaped {
 		return fmt.Errorf("destination escapes allocation directory")
 	}
-
+
+	return nil
+}
+
+const (
+	// TaskLifecycleHookPrestart, TaskLifecycleHookPoststart and
+	// TaskLifecycleHookPoststop are the points in the lifecycle of the main
+	// tasks of a group at which a task with a lifecycle runs.
+	TaskLifecycleHookPrestart  = "prestart"
+	TaskLifecycleHookPoststart = "poststart"
+	TaskLifecycleHookPoststop  = "poststop"
+)
+
+// TaskLifecycleConfig orders a task relative to the main tasks of its group,
+// which are the tasks without a lifecycle.
+type TaskLifecycleConfig struct {
+	// Hook is when the task runs: prestart tasks run before the main tasks
+	// are started, poststart tasks once they are running and poststop tasks
+	// once they exited.
+	Hook string
+
+	// Sidecar marks a prestart or poststart task as running alongside the
+	// main tasks until they exit. Otherwise the task runs to completion, and
+	// prestart tasks must complete successfully before the main tasks start.
+	Sidecar bool
+}
+
+func (l *TaskLifecycleConfig) Copy() *TaskLifecycleConfig {
+	if l == nil {
+		return nil
+	}
+	nl := new(TaskLifecycleConfig)
+	*nl = *l
+	return nl
+}
+
+func (l *TaskLifecycleConfig) Validate() error {
+	switch l.Hook {
+	case TaskLifecycleHookPrestart, TaskLifecycleHookPoststart:
+	case TaskLifecycleHookPoststop:
+		if l.Sidecar {
+			return fmt.Errorf("poststop tasks can't be sidecars")
+		}
+	default:
+		return fmt.Errorf("invalid hook %q", l.Hook)
+	}
 	return nil
 }
 
@@ -2445,6 +3443,25 @@ type TaskGroup struct {
 	// EphemeralDisk is the disk resources that the task group requests
 	EphemeralDisk *EphemeralDisk
 
+	// Consul is the Consul namespace and admin partition the services of
+	// the tasks are registered in
+	Consul *Consul
+
+	// StopAfterClientDisconnect, if set, is the duration after which a
+	// client that lost contact with the servers stops the allocations of the
+	// group. The scheduler waits as long before replacing them.
+	StopAfterClientDisconnect *time.Duration
+
+	// MaxClientDisconnect, if set, is the duration during which the
+	// allocations of a down node are unknown rather than lost. They are
+	// replaced meanwhile and reconciled with their replacements if their
+	// client reconnects.
+	MaxClientDisconnect *time.Duration
+
+	// Networks are the networks of the allocations of the group, shared by
+	// its tasks.
+	Networks Networks
+
 	// Meta is used to associate arbitrary metadata with this
 	// task group. This is opaque to Nomad.
 	Meta map[string]string
@@ -2473,6 +3490,20 @@ func (tg *TaskGroup) Copy() *TaskGroup {
 	if tg.EphemeralDisk != nil {
 		ntg.EphemeralDisk = tg.EphemeralDisk.Copy()
 	}
+	ntg.Consul = tg.Consul.Copy()
+	if tg.StopAfterClientDisconnect != nil {
+		ntg.StopAfterClientDisconnect = helper.TimeToPtr(*tg.StopAfterClientDisconnect)
+	}
+	if tg.MaxClientDisconnect != nil {
+		ntg.MaxClientDisconnect = helper.TimeToPtr(*tg.MaxClientDisconnect)
+	}
+	if tg.Networks != nil {
+		networks := make(Networks, len(tg.Networks))
+		for i, n := range tg.Networks {
+			networks[i] = n.Copy()
+		}
+		ntg.Networks = networks
+	}
 	return ntg
 }
 
@@ -2489,6 +3520,10 @@ func (tg *TaskGroup) Canonicalize(job *Job) {
 		tg.RestartPolicy = NewRestartPolicy(job.Type)
 	}
 
+	for _, n := range tg.Networks {
+		n.Canonicalize()
+	}
+
 	// Set a default ephemeral disk object if the user has not requested for one
 	if tg.EphemeralDisk == nil {
 		tg.EphemeralDisk = DefaultEphemeralDisk()
@@ -2547,6 +3582,42 @@ func (tg *TaskGroup) Validate(j *Job) error {
 		mErr.Errors = append(mErr.Errors, fmt.Errorf("Task Group %v should have an ephemeral disk object", tg.Name))
 	}
 
+	if tg.Consul != nil {
+		if err := tg.Consul.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, err)
+		}
+	}
+
+	if d := tg.StopAfterClientDisconnect; d != nil {
+		if j.Type == JobTypeSystem {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Stop after client disconnect can't be used with %q jobs", JobTypeSystem))
+		}
+		if *d <= 0 {
+			mErr.Errors = append(mErr.Errors, errors.New("Stop after client disconnect must be positive"))
+		}
+	}
+
+	if d := tg.MaxClientDisconnect; d != nil {
+		if j.Type == JobTypeSystem {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Max client disconnect can't be used with %q jobs", JobTypeSystem))
+		}
+		if tg.StopAfterClientDisconnect != nil {
+			mErr.Errors = append(mErr.Errors, errors.New("Max client disconnect can't be used with stop after client disconnect"))
+		}
+		if *d <= 0 {
+			mErr.Errors = append(mErr.Errors, errors.New("Max client disconnect must be positive"))
+		}
+	}
+
+	if len(tg.Networks) > 1 {
+		mErr.Errors = append(mErr.Errors, errors.New("Only one group network is allowed"))
+	}
+	for _, n := range tg.Networks {
+		if err := n.validateGroupNetwork(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Group network validation failed: %v", err))
+		}
+	}
+
 	// Validate the update strategy
 	if u := tg.Update; u != nil {
 		switch j.Type {
@@ -2564,7 +3635,21 @@ func (tg *TaskGroup) Validate(j *Job) error {
 	// and no duplicated static ports
 	tasks := make(map[string]int)
 	staticPorts := make(map[int]string)
-	leaderTasks := 0
+	bridge := false
+	for _, net := range tg.Networks {
+		if net.Mode == NetworkModeBridge {
+			bridge = true
+		}
+		for _, port := range net.ReservedPorts {
+			if other, ok := staticPorts[port.Value]; ok {
+				err := fmt.Errorf("Static port %d already reserved by %s", port.Value, other)
+				mErr.Errors = append(mErr.Errors, err)
+			} else {
+				staticPorts[port.Value] = fmt.Sprintf("%s:%s", tg.Name, port.Label)
+			}
+		}
+	}
+	leaderTasks, mainTasks := 0, 0
 	for idx, task := range tg.Tasks {
 		if task.Name == "" {
 			mErr.Errors = append(mErr.Errors, fmt.Errorf("Task %d missing name", idx+1))
@@ -2576,6 +3661,12 @@ func (tg *TaskGroup) Validate(j *Job) error {
 
 		if task.Leader {
 			leaderTasks++
+			if task.Lifecycle != nil {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Task %q with a lifecycle can't be the leader", task.Name))
+			}
+		}
+		if task.Lifecycle == nil {
+			mainTasks++
 		}
 
 		if task.Resources == nil {
@@ -2583,6 +3674,10 @@ func (tg *TaskGroup) Validate(j *Job) error {
 		}
 
 		for _, net := range task.Resources.Networks {
+			if bridge && len(net.ReservedPorts)+len(net.DynamicPorts) != 0 {
+				err := fmt.Errorf("Task %q can't reserve ports in %q network mode, they must be declared by the group network", task.Name, NetworkModeBridge)
+				mErr.Errors = append(mErr.Errors, err)
+			}
 			for _, port := range net.ReservedPorts {
 				if other, ok := staticPorts[port.Value]; ok {
 					err := fmt.Errorf("Static port %d already reserved by %s", port.Value, other)
@@ -2597,10 +3692,13 @@ func (tg *TaskGroup) Validate(j *Job) error {
 	if leaderTasks > 1 {
 		mErr.Errors = append(mErr.Errors, fmt.Errorf("Only one task may be marked as leader"))
 	}
+	if len(tg.Tasks) != 0 && mainTasks == 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("At least one task must have no lifecycle"))
+	}
 
 	// Validate the tasks
 	for _, task := range tg.Tasks {
-		if err := task.Validate(tg.EphemeralDisk); err != nil {
+		if err := task.Validate(tg.EphemeralDisk, tg.Networks); err != nil {
 			outer := fmt.Errorf("Task %s validation failed: %v", task.Name, err)
 			mErr.Errors = append(mErr.Errors, outer)
 		}
@@ -2669,6 +3767,7 @@ type ServiceCheck struct {
 	Timeout       time.Duration // Timeout of the response from the check before consul fails the check
 	InitialStatus string        // Initial status of the check
 	TLSSkipVerify bool          // Skip TLS verification when Protocol=https
+	CheckRestart  *CheckRestart // If and when a task should be restarted based on checks
 }
 
 func (sc *ServiceCheck) Copy() *ServiceCheck {
@@ -2677,6 +3776,7 @@ func (sc *ServiceCheck) Copy() *ServiceCheck {
 	}
 	nsc := new(ServiceCheck)
 	*nsc = *sc
+	nsc.CheckRestart = sc.CheckRestart.Copy()
 	return nsc
 }
 
@@ -2739,6 +3839,10 @@ func (sc *ServiceCheck) validate() error {
 
 	}
 
+	if err := sc.CheckRestart.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2752,6 +3856,12 @@ func (sc *ServiceCheck) RequiresPort() bool {
 	}
 }
 
+// TriggersRestarts returns true if this check should be watched and trigger a
+// restart on failure.
+func (sc *ServiceCheck) TriggersRestarts() bool {
+	return sc.CheckRestart != nil && sc.CheckRestart.Limit > 0
+}
+
 // Hash all ServiceCheck fields and the check's corresponding service ID to
 // create an identifier. The identifier is not guaranteed to be unique as if
 // the PortLabel is blank, the Service's PortLabel will be used after Hash is
@@ -2775,6 +3885,41 @@ func (sc *ServiceCheck) Hash(serviceID string) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
+// CheckRestart describes if and when a task should be restarted based on
+// failing health checks.
+type CheckRestart struct {
+	Limit          int           // Restart task after this many unhealthy intervals
+	Grace          time.Duration // Grace time to give tasks after starting to get healthy
+	IgnoreWarnings bool          // If true treat checks in `warning` as passing
+}
+
+func (c *CheckRestart) Copy() *CheckRestart {
+	if c == nil {
+		return nil
+	}
+
+	nc := new(CheckRestart)
+	*nc = *c
+	return nc
+}
+
+func (c *CheckRestart) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	var mErr multierror.Error
+	if c.Limit < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("limit must be greater than or equal to 0 but found %d", c.Limit))
+	}
+
+	if c.Grace < 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("grace period must be greater than or equal to 0 but found %v", c.Grace))
+	}
+
+	return mErr.ErrorOrNil()
+}
+
 const (
 	AddressModeAuto   = "auto"
 	AddressModeHost   = "host"
@@ -2799,6 +3944,28 @@ type Service struct {
 
 	Tags   []string        // List of tags for the service
 	Checks []*ServiceCheck // List of checks associated with the service
+
+	// CanaryTags are the tags the service is registered with instead of
+	// Tags while its allocation is an unpromoted canary, so traffic can be
+	// shifted between the canaries and the previous allocations.
+	CanaryTags []string
+
+	// Connect enables the service to join the Consul Connect service mesh
+	// through a sidecar proxy.
+	Connect *ConsulConnect
+
+	// Provider is the service discovery provider the service is registered
+	// with. Services without a provider are registered in Consul.
+	Provider string
+
+	// Meta is the metadata of the service registered in Consul
+	Meta map[string]string
+
+	// TaggedAddresses are the additional addresses of the service
+	// registered in Consul, such as its "lan" and "wan" addresses, by tag.
+	// Addresses are of the form "address" or "address:port", the port of
+	// the service being used if unset.
+	TaggedAddresses map[string]string
 }
 
 func (s *Service) Copy() *Service {
@@ -2808,6 +3975,9 @@ func (s *Service) Copy() *Service {
 	ns := new(Service)
 	*ns = *s
 	ns.Tags = helper.CopySliceString(ns.Tags)
+	ns.CanaryTags = helper.CopySliceString(ns.CanaryTags)
+	ns.Meta = helper.CopyMapStringString(ns.Meta)
+	ns.TaggedAddresses = helper.CopyMapStringString(ns.TaggedAddresses)
 
 	if s.Checks != nil {
 		checks := make([]*ServiceCheck, len(ns.Checks))
@@ -2817,6 +3987,7 @@ func (s *Service) Copy() *Service {
 		ns.Checks = checks
 	}
 
+	ns.Connect = s.Connect.Copy()
 	return ns
 }
 
@@ -2828,9 +3999,18 @@ func (s *Service) Canonicalize(job string, taskGroup string, task string) {
 	if len(s.Tags) == 0 {
 		s.Tags = nil
 	}
+	if len(s.CanaryTags) == 0 {
+		s.CanaryTags = nil
+	}
 	if len(s.Checks) == 0 {
 		s.Checks = nil
 	}
+	if len(s.Meta) == 0 {
+		s.Meta = nil
+	}
+	if len(s.TaggedAddresses) == 0 {
+		s.TaggedAddresses = nil
+	}
 
 	s.Name = args.ReplaceEnv(s.Name, map[string]string{
 		"JOB":       job,
@@ -2866,6 +4046,20 @@ func (s *Service) Validate() error {
 		mErr.Errors = append(mErr.Errors, fmt.Errorf("service address_mode must be %q, %q, or %q; not %q", AddressModeAuto, AddressModeHost, AddressModeDriver, s.AddressMode))
 	}
 
+	switch s.Provider {
+	case "", ServiceProviderConsul:
+		// OK
+	case ServiceProviderNomad:
+		if s.Connect != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q can't use Consul Connect with the %q provider", s.Name, s.Provider))
+		}
+		if len(s.Meta) != 0 || len(s.TaggedAddresses) != 0 {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q can't set meta or tagged addresses with the %q provider", s.Name, s.Provider))
+		}
+	default:
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("service provider must be %q or %q; not %q", ServiceProviderConsul, ServiceProviderNomad, s.Provider))
+	}
+
 	for _, c := range s.Checks {
 		if s.PortLabel == "" && c.RequiresPort() {
 			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: check requires a port but the service %+q has no port", c.Name, s.Name))
@@ -2876,6 +4070,20 @@ func (s *Service) Validate() error {
 			mErr.Errors = append(mErr.Errors, fmt.Errorf("check %s invalid: %v", c.Name, err))
 		}
 	}
+
+	if s.Connect != nil {
+		// The sidecar proxy is identified by the name of the service so it
+		// can't depend on the task's environment.
+		if strings.Contains(s.Name, "${") {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q can't use interpolation with Consul Connect", s.Name))
+		}
+		if s.PortLabel == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q must have a port to use Consul Connect", s.Name))
+		}
+		if err := s.Connect.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("service %q Connect invalid: %v", s.Name, err))
+		}
+	}
 	return mErr.ErrorOrNil()
 }
 
@@ -2899,11 +4107,29 @@ func (s *Service) Hash() string {
 	h := sha1.New()
 	io.WriteString(h, s.Name)
 	io.WriteString(h, strings.Join(s.Tags, ""))
+	io.WriteString(h, strings.Join(s.CanaryTags, ""))
 	io.WriteString(h, s.PortLabel)
 	io.WriteString(h, s.AddressMode)
+	io.WriteString(h, s.Provider)
+	hashStringMap(h, s.Meta)
+	hashStringMap(h, s.TaggedAddresses)
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
+// hashStringMap writes the entries of the map to the hash in the order of
+// their keys.
+func hashStringMap(h io.Writer, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		io.WriteString(h, k)
+		io.WriteString(h, m[k])
+	}
+}
+
 const (
 	// DefaultKillTimeout is the default timeout between signaling a task it
 	// will be killed and killing it.
@@ -2993,6 +4219,29 @@ type Task struct {
 	// Leader marks the task as the leader within the group. When the leader
 	// task exits, other tasks will be gracefully terminated.
 	Leader bool
+
+	// Kind is set on the tasks Nomad injects, such as the Connect sidecar
+	// proxies, to identify their role.
+	Kind TaskKind
+
+	// Lifecycle orders the task relative to the main tasks of the group. It
+	// is nil for the main tasks.
+	Lifecycle *TaskLifecycleConfig
+}
+
+// LifecycleHook returns the hook the task runs at, or an empty string for the
+// main tasks of the group.
+func (t *Task) LifecycleHook() string {
+	if t.Lifecycle == nil {
+		return ""
+	}
+	return t.Lifecycle.Hook
+}
+
+// RunsWithMain returns whether the task is a main task or a sidecar, which
+// are expected to run until the main tasks exit.
+func (t *Task) RunsWithMain() bool {
+	return t.Lifecycle == nil || t.Lifecycle.Sidecar
 }
 
 func (t *Task) Copy() *Task {
@@ -3017,6 +4266,7 @@ func (t *Task) Copy() *Task {
 	nt.Resources = nt.Resources.Copy()
 	nt.Meta = helper.CopyMapStringString(nt.Meta)
 	nt.DispatchPayload = nt.DispatchPayload.Copy()
+	nt.Lifecycle = nt.Lifecycle.Copy()
 
 	if t.Artifacts != nil {
 		artifacts := make([]*TaskArtifact, 0, len(t.Artifacts))
@@ -3086,8 +4336,22 @@ func (t *Task) GoString() string {
 	return fmt.Sprintf("*%#v", *t)
 }
 
+// UsesChangeScripts returns whether the task runs change scripts when its
+// templates are re-rendered or its Vault token changes.
+func (t *Task) UsesChangeScripts() bool {
+	if t.Vault != nil && t.Vault.ChangeMode == VaultChangeModeScript {
+		return true
+	}
+	for _, tmpl := range t.Templates {
+		if tmpl.ChangeMode == TemplateChangeModeScript {
+			return true
+		}
+	}
+	return false
+}
+
 // Validate is used to sanity check a task
-func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
+func (t *Task) Validate(ephemeralDisk *EphemeralDisk, tgNetworks Networks) error {
 	var mErr multierror.Error
 	if t.Name == "" {
 		mErr.Errors = append(mErr.Errors, errors.New("Missing task name"))
@@ -3116,6 +4380,27 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
 		if t.Resources.DiskMB > 0 {
 			mErr.Errors = append(mErr.Errors, errors.New("Task can't ask for disk resources, they have to be specified at the task group level."))
 		}
+
+		// Bandwidth is only limited in the namespace of a bridge network, and
+		// only the ports of group networks are mapped
+		bridge := false
+		for _, n := range tgNetworks {
+			if n.Mode == NetworkModeBridge {
+				bridge = true
+			}
+		}
+		for _, n := range t.Resources.Networks {
+			if n.MBits != 0 && !bridge {
+				mErr.Errors = append(mErr.Errors, fmt.Errorf("Task networks can only reserve bandwidth in groups with a %q network", NetworkModeBridge))
+			}
+			for _, list := range [][]Port{n.ReservedPorts, n.DynamicPorts} {
+				for _, port := range list {
+					if port.To != 0 {
+						mErr.Errors = append(mErr.Errors, fmt.Errorf("Port %q can't be mapped, only the ports of group networks are", port.Label))
+					}
+				}
+			}
+		}
 	}
 
 	// Validate the log config
@@ -3139,7 +4424,7 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
 	}
 
 	// Validate Services
-	if err := validateServices(t); err != nil {
+	if err := validateServices(t, tgNetworks); err != nil {
 		mErr.Errors = append(mErr.Errors, err)
 	}
 
@@ -3166,6 +4451,7 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
 	}
 
 	destinations := make(map[string]int, len(t.Templates))
+	envNames := make(map[string]int)
 	for idx, tmpl := range t.Templates {
 		if err := tmpl.Validate(); err != nil {
 			outer := fmt.Errorf("Template %d validation failed: %s", idx+1, err)
@@ -3178,6 +4464,23 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
 		} else {
 			destinations[tmpl.DestPath] = idx + 1
 		}
+
+		// Env templates can't set the same variable. The variables of
+		// templates without selected keys are only known once rendered.
+		keys := make([]string, 0, len(tmpl.EnvKeys))
+		for key := range tmpl.EnvKeys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			name := tmpl.EnvKeys[key]
+			if other, ok := envNames[name]; ok {
+				outer := fmt.Errorf("Template %d sets env var %q already set by template %d", idx+1, name, other)
+				mErr.Errors = append(mErr.Errors, outer)
+			} else {
+				envNames[name] = idx + 1
+			}
+		}
 	}
 
 	// Validate the dispatch payload block if there
@@ -3187,12 +4490,19 @@ func (t *Task) Validate(ephemeralDisk *EphemeralDisk) error {
 		}
 	}
 
+	if t.Lifecycle != nil {
+		if err := t.Lifecycle.Validate(); err != nil {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("Lifecycle validation failed: %v", err))
+		}
+	}
+
 	return mErr.ErrorOrNil()
 }
 
 // validateServices takes a task and validates the services within it are valid
-// and reference ports that exist.
-func validateServices(t *Task) error {
+// and reference ports that exist, either in the task or in the network of its
+// group.
+func validateServices(t *Task, tgNetworks Networks) error {
 	var mErr multierror.Error
 
 	// Ensure that services don't ask for non-existent ports and their names are
@@ -3236,6 +4546,11 @@ func validateServices(t *Task) error {
 			}
 		}
 	}
+	for _, network := range tgNetworks {
+		for portLabel := range network.PortLabels() {
+			portLabels[portLabel] = struct{}{}
+		}
+	}
 
 	// Ensure all ports referenced in services exist.
 	for servicePort, services := range servicePorts {
@@ -3263,14 +4578,90 @@ const (
 	// TemplateChangeModeRestart marks that the task should be restarted if the
 	// template is re-rendered
 	TemplateChangeModeRestart = "restart"
+
+	// TemplateChangeModeScript marks that the change script should be run
+	// inside the task if the template is re-rendered
+	TemplateChangeModeScript = "script"
 )
 
 var (
 	// TemplateChangeModeInvalidError is the error for when an invalid change
 	// mode is given
-	TemplateChangeModeInvalidError = errors.New("Invalid change mode. Must be one of the following: noop, signal, restart")
+	TemplateChangeModeInvalidError = errors.New("Invalid change mode. Must be one of the following: noop, signal, restart, script")
 )
 
+// ChangeScript is a command run inside the task when a template is
+// re-rendered or a new Vault token is retrieved.
+type ChangeScript struct {
+	// Command is the command to run
+	Command string
+
+	// Args are the arguments of the command
+	Args []string
+
+	// Timeout is how long the command may run before being canceled
+	Timeout time.Duration
+
+	// FailOnError kills the task if the command fails or times out
+	FailOnError bool
+}
+
+// Copy returns a copy of the change script.
+func (c *ChangeScript) Copy() *ChangeScript {
+	if c == nil {
+		return nil
+	}
+	nc := new(ChangeScript)
+	*nc = *c
+	nc.Args = helper.CopySliceString(c.Args)
+	return nc
+}
+
+// Validate returns if the change script is valid.
+func (c *ChangeScript) Validate() error {
+	var mErr multierror.Error
+	if c.Command == "" {
+		multierror.Append(&mErr, fmt.Errorf("Must specify a change script command"))
+	}
+	if c.Timeout <= 0 {
+		multierror.Append(&mErr, fmt.Errorf("Change script timeout must be greater than zero: %v", c.Timeout))
+	}
+	return mErr.ErrorOrNil()
+}
+
+// WaitConfig is the minimum and maximum amount of time to wait for data to
+// settle before rendering a template.
+type WaitConfig struct {
+	// Min is the time the data must stay unchanged before rendering
+	Min time.Duration
+
+	// Max is the time after which the template is rendered even if the data
+	// keeps changing. Defaults to four times Min if unset.
+	Max time.Duration
+}
+
+// Copy returns a copy of the wait configuration.
+func (w *WaitConfig) Copy() *WaitConfig {
+	if w == nil {
+		return nil
+	}
+	nw := new(WaitConfig)
+	*nw = *w
+	return nw
+}
+
+// Validate returns if the wait configuration is valid.
+func (w *WaitConfig) Validate() error {
+	var mErr multierror.Error
+	if w.Min <= 0 {
+		multierror.Append(&mErr, fmt.Errorf("Wait min must be greater than zero: %v", w.Min))
+	}
+	if w.Max != 0 && w.Max < w.Min {
+		multierror.Append(&mErr, fmt.Errorf("Wait max must be greater than min: %v < %v", w.Max, w.Min))
+	}
+	return mErr.ErrorOrNil()
+}
+
 // Template represents a template configuration to be rendered for a given task
 type Template struct {
 	// SourcePath is the path to the template to be rendered
@@ -3290,11 +4681,20 @@ type Template struct {
 	// requires it.
 	ChangeSignal string
 
+	// ChangeScript is the script that should be run if the change mode
+	// requires it.
+	ChangeScript *ChangeScript
+
 	// Splay is used to avoid coordinated restarts of processes by applying a
 	// random wait between 0 and the given splay value before signalling the
 	// application of a change
 	Splay time.Duration
 
+	// Wait is the minimum and maximum amount of time to wait for the
+	// template's data to settle before rendering it. The client's default
+	// wait is used if unset.
+	Wait *WaitConfig
+
 	// Perms is the permission the file should be written out with.
 	Perms string
 
@@ -3315,6 +4715,16 @@ type Template struct {
 	// escaping issues #s within lines will not be treated as comments.
 	Envvars bool
 
+	// EnvKeys selects the variables of an env template exposed to the task,
+	// mapping the key in the template to the name of the environment
+	// variable. All variables are exposed if empty.
+	EnvKeys map[string]string
+
+	// ErrMissingKey makes rendering fail when the template references a
+	// map key, such as the field of a secret, that is absent instead of
+	// rendering "<no value>".
+	ErrMissingKey bool
+
 	// VaultGrace is the grace duration between lease renewal and reacquiring a
 	// secret. If the lease of a secret is less than the grace, a new secret is
 	// acquired.
@@ -3336,6 +4746,9 @@ func (t *Template) Copy() *Template {
 	}
 	copy := new(Template)
 	*copy = *t
+	copy.ChangeScript = t.ChangeScript.Copy()
+	copy.Wait = t.Wait.Copy()
+	copy.EnvKeys = helper.CopyMapStringString(t.EnvKeys)
 	return copy
 }
 
@@ -3376,6 +4789,15 @@ func (t *Template) Validate() error {
 		if t.Envvars {
 			multierror.Append(&mErr, fmt.Errorf("cannot use signals with env var templates"))
 		}
+	case TemplateChangeModeScript:
+		if t.ChangeScript == nil {
+			multierror.Append(&mErr, fmt.Errorf("Must specify change script when change mode is script"))
+		} else if err := t.ChangeScript.Validate(); err != nil {
+			multierror.Append(&mErr, err)
+		}
+		if t.Envvars {
+			multierror.Append(&mErr, fmt.Errorf("cannot use change scripts with env var templates"))
+		}
 	default:
 		multierror.Append(&mErr, TemplateChangeModeInvalidError)
 	}
@@ -3385,6 +4807,23 @@ func (t *Template) Validate() error {
 		multierror.Append(&mErr, fmt.Errorf("Must specify positive splay value"))
 	}
 
+	// Verify the selected env keys
+	if len(t.EnvKeys) != 0 && !t.Envvars {
+		multierror.Append(&mErr, fmt.Errorf("env keys can only be selected for env var templates"))
+	}
+	for key, name := range t.EnvKeys {
+		if key == "" || name == "" {
+			multierror.Append(&mErr, fmt.Errorf("env key %q must be renamed to a non-empty variable name", key))
+		}
+	}
+
+	// Verify the wait
+	if t.Wait != nil {
+		if err := t.Wait.Validate(); err != nil {
+			multierror.Append(&mErr, err)
+		}
+	}
+
 	// Verify the permissions
 	if t.Perms != "" {
 		if _, err := strconv.ParseUint(t.Perms, 8, 12); err != nil {
@@ -3539,6 +4978,14 @@ const (
 
 	// TaskLeaderDead indicates that the leader task within the has finished.
 	TaskLeaderDead = "Leader Task Dead"
+
+	// TaskMainDead indicates that the main tasks of the group have finished,
+	// which stops the sidecar tasks.
+	TaskMainDead = "Main Tasks Dead"
+
+	// TaskChangeScript indicates that a change script was run inside the
+	// task.
+	TaskChangeScript = "Change Script"
 )
 
 // TaskEvent is an event that effects the state of a task and contains meta-data
@@ -3997,6 +5444,58 @@ func (d *EphemeralDisk) Copy() *EphemeralDisk {
 	return ld
 }
 
+// validConsulNamespace matches the names of Consul namespaces and admin
+// partitions
+var validConsulNamespace = regexp.MustCompile("^[a-zA-Z0-9-_]{1,64}$")
+
+// Consul is the Consul namespace and admin partition the services of a task
+// group are registered in. Both require Consul Enterprise; empty values use
+// the namespace and partition of the token of the Nomad agent.
+type Consul struct {
+	Namespace string
+	Partition string
+}
+
+// Copy returns a copy of the Consul configuration.
+func (c *Consul) Copy() *Consul {
+	if c == nil {
+		return nil
+	}
+	nc := new(Consul)
+	*nc = *c
+	return nc
+}
+
+// GetNamespace returns the Consul namespace, or an empty string for the
+// default namespace.
+func (c *Consul) GetNamespace() string {
+	if c == nil {
+		return ""
+	}
+	return c.Namespace
+}
+
+// GetPartition returns the Consul admin partition, or an empty string for
+// the default partition.
+func (c *Consul) GetPartition() string {
+	if c == nil {
+		return ""
+	}
+	return c.Partition
+}
+
+// Validate validates the Consul configuration.
+func (c *Consul) Validate() error {
+	var mErr multierror.Error
+	if c.Namespace != "" && !validConsulNamespace.MatchString(c.Namespace) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Invalid Consul namespace %q", c.Namespace))
+	}
+	if c.Partition != "" && !validConsulNamespace.MatchString(c.Partition) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("Invalid Consul partition %q", c.Partition))
+	}
+	return mErr.ErrorOrNil()
+}
+
 const (
 	// VaultChangeModeNoop takes no action when a new token is retrieved.
 	VaultChangeModeNoop = "noop"
@@ -4006,6 +5505,10 @@ const (
 
 	// VaultChangeModeRestart restarts the task when a new token is retrieved.
 	VaultChangeModeRestart = "restart"
+
+	// VaultChangeModeScript runs the change script inside the task when a new
+	// token is retrieved.
+	VaultChangeModeScript = "script"
 )
 
 // Vault stores the set of permissions a task needs access to from Vault.
@@ -4013,6 +5516,15 @@ type Vault struct {
 	// Policies is the set of policies that the task needs access to
 	Policies []string
 
+	// Role is the role of the Vault JWT auth backend the task logs in to
+	// with its workload identity. The role defines the policies of the
+	// token, so it can't be used along with Policies.
+	Role string
+
+	// Namespace is the Vault Enterprise namespace the token of the task is
+	// created in. An empty namespace uses the one of the Nomad servers.
+	Namespace string
+
 	// Env marks whether the Vault Token should be exposed as an environment
 	// variable
 	Env bool
@@ -4024,8 +5536,16 @@ type Vault struct {
 	// ChangeSignal is the signal sent to the task when a new token is
 	// retrieved. This is only valid when using the signal change mode.
 	ChangeSignal string
+
+	// ChangeScript is the script run inside the task when a new token is
+	// retrieved. This is only valid when using the script change mode.
+	ChangeScript *ChangeScript
 }
 
+// validVaultNamespace matches the paths of Vault namespaces, which may be
+// nested within each other
+var validVaultNamespace = regexp.MustCompile("^[a-zA-Z0-9-_]+(/[a-zA-Z0-9-_]+)*$")
+
 func DefaultVaultBlock() *Vault {
 	return &Vault{
 		Env:        true,
@@ -4041,6 +5561,7 @@ func (v *Vault) Copy() *Vault {
 
 	nv := new(Vault)
 	*nv = *v
+	nv.ChangeScript = v.ChangeScript.Copy()
 	return nv
 }
 
@@ -4057,7 +5578,11 @@ func (v *Vault) Validate() error {
 	}
 
 	var mErr multierror.Error
-	if len(v.Policies) == 0 {
+	if v.Role != "" {
+		if len(v.Policies) != 0 {
+			multierror.Append(&mErr, fmt.Errorf("Policies cannot be set along with role %q", v.Role))
+		}
+	} else if len(v.Policies) == 0 {
 		multierror.Append(&mErr, fmt.Errorf("Policy list cannot be empty"))
 	}
 
@@ -4067,11 +5592,21 @@ func (v *Vault) Validate() error {
 		}
 	}
 
+	if v.Namespace != "" && !validVaultNamespace.MatchString(v.Namespace) {
+		multierror.Append(&mErr, fmt.Errorf("Invalid Vault namespace %q", v.Namespace))
+	}
+
 	switch v.ChangeMode {
 	case VaultChangeModeSignal:
 		if v.ChangeSignal == "" {
 			multierror.Append(&mErr, fmt.Errorf("Signal must be specified when using change mode %q", VaultChangeModeSignal))
 		}
+	case VaultChangeModeScript:
+		if v.ChangeScript == nil {
+			multierror.Append(&mErr, fmt.Errorf("Change script must be specified when using change mode %q", VaultChangeModeScript))
+		} else if err := v.ChangeScript.Validate(); err != nil {
+			multierror.Append(&mErr, err)
+		}
 	case VaultChangeModeNoop, VaultChangeModeRestart:
 	default:
 		multierror.Append(&mErr, fmt.Errorf("Unknown change mode %q", v.ChangeMode))
@@ -4098,6 +5633,7 @@ const (
 	DeploymentStatusDescriptionNewerJob              = "Cancelled due to newer version of job"
 	DeploymentStatusDescriptionFailedAllocations     = "Failed due to unhealthy allocations"
 	DeploymentStatusDescriptionFailedByUser          = "Deployment marked as failed"
+	DeploymentStatusDescriptionProgressDeadline      = "Failed due to progress deadline"
 )
 
 // DeploymentStatusDescriptionRollback is used to get the status description of
@@ -4106,6 +5642,13 @@ func DeploymentStatusDescriptionRollback(baseDescription string, jobVersion uint
 	return fmt.Sprintf("%s - rolling back to job version %d", baseDescription, jobVersion)
 }
 
+// DeploymentStatusDescriptionNoRollbackTarget is used to get the status
+// description of a deployment that should have been rolled back but for which
+// there is no stable job version to roll back to.
+func DeploymentStatusDescriptionNoRollbackTarget(baseDescription string) string {
+	return fmt.Sprintf("%s - no stable job version to auto revert to", baseDescription)
+}
+
 // Deployment is the object that represents a job deployment which is used to
 // transition a job between versions.
 type Deployment struct {
@@ -4137,6 +5680,14 @@ type Deployment struct {
 	// status.
 	StatusDescription string
 
+	// RevertedJobVersion is the version of the job the deployment rolled back
+	// to when it failed, if it did.
+	RevertedJobVersion *uint64
+
+	// NotifiedEvents are the events of the deployment the webhooks were
+	// notified of.
+	NotifiedEvents []string
+
 	CreateIndex uint64
 	ModifyIndex uint64
 }
@@ -4170,6 +5721,10 @@ func (d *Deployment) Copy() *Deployment {
 			c.TaskGroups[tg] = s.Copy()
 		}
 	}
+	if d.RevertedJobVersion != nil {
+		c.RevertedJobVersion = helper.Uint64ToPtr(*d.RevertedJobVersion)
+	}
+	c.NotifiedEvents = helper.CopySliceString(d.NotifiedEvents)
 
 	return c
 }
@@ -4233,6 +5788,15 @@ type DeploymentState struct {
 	// reverted on failure
 	AutoRevert bool
 
+	// AutoPromote marks whether the task group has indicated the deployment
+	// should be promoted once its canaries are healthy
+	AutoPromote bool
+
+	// ProgressDeadline is the time in which an allocation of the task group
+	// must become healthy before the deployment is marked as failed. Zero
+	// disables the deadline.
+	ProgressDeadline time.Duration
+
 	// Promoted marks whether the canaries have been promoted
 	Promoted bool
 
@@ -4265,6 +5829,8 @@ func (d *DeploymentState) GoString() string {
 	base += fmt.Sprintf("\n\tHealthy: %d", d.HealthyAllocs)
 	base += fmt.Sprintf("\n\tUnhealthy: %d", d.UnhealthyAllocs)
 	base += fmt.Sprintf("\n\tAutoRevert: %v", d.AutoRevert)
+	base += fmt.Sprintf("\n\tAutoPromote: %v", d.AutoPromote)
+	base += fmt.Sprintf("\n\tProgressDeadline: %v", d.ProgressDeadline)
 	return base
 }
 
@@ -4285,6 +5851,10 @@ type DeploymentStatusUpdate struct {
 
 	// StatusDescription is the new status description of the deployment.
 	StatusDescription string
+
+	// RevertedJobVersion is the version of the job the deployment rolls back
+	// to, if it does.
+	RevertedJobVersion *uint64
 }
 
 const (
@@ -4299,6 +5869,7 @@ const (
 	AllocClientStatusComplete = "complete"
 	AllocClientStatusFailed   = "failed"
 	AllocClientStatusLost     = "lost"
+	AllocClientStatusUnknown  = "unknown"
 )
 
 // Allocation is used to allocate the placement of a task group to a node.
@@ -4357,6 +5928,11 @@ type Allocation struct {
 	// PreviousAllocation is the allocation that this allocation is replacing
 	PreviousAllocation string
 
+	// ReplacedAllocation is the allocation of a disconnected client that
+	// this allocation is replacing. Unlike the previous allocation it may
+	// still be running.
+	ReplacedAllocation string
+
 	// DeploymentID identifies an allocation as being created from a
 	// particular deployment
 	DeploymentID string
@@ -4365,6 +5941,10 @@ type Allocation struct {
 	// given deployment
 	DeploymentStatus *AllocDeploymentStatus
 
+	// NetworkStatus is the status of the group network of the allocation,
+	// reported by the client that set it up.
+	NetworkStatus *AllocNetworkStatus
+
 	// Raft Indexes
 	CreateIndex uint64
 	ModifyIndex uint64
@@ -4425,6 +6005,7 @@ func (a *Allocation) copyImpl(job bool) *Allocation {
 
 	na.Metrics = na.Metrics.Copy()
 	na.DeploymentStatus = na.DeploymentStatus.Copy()
+	na.NetworkStatus = na.NetworkStatus.Copy()
 
 	if a.TaskStates != nil {
 		ts := make(map[string]*TaskState, len(na.TaskStates))
@@ -4650,6 +6231,30 @@ func (a *AllocMetric) ScoreNode(node *Node, name string, score float64) {
 	a.Scores[key] = score
 }
 
+// AllocNetworkStatus is the status of the group network of an allocation.
+type AllocNetworkStatus struct {
+	// InterfaceName is the name of the interface of the allocation in its
+	// network namespace.
+	InterfaceName string
+
+	// Address is the IP address assigned to the allocation.
+	Address string
+
+	// AddressIPv6 is the IPv6 address assigned to the allocation along with
+	// its IPv4 Address on dual stack networks.
+	AddressIPv6 string
+}
+
+func (a *AllocNetworkStatus) Copy() *AllocNetworkStatus {
+	if a == nil {
+		return nil
+	}
+
+	c := new(AllocNetworkStatus)
+	*c = *a
+	return c
+}
+
 // AllocDeploymentStatus captures the status of the allocation as part of the
 // deployment. This can include things like if the allocation has been marked as
 // heatlhy.
@@ -4659,11 +6264,24 @@ type AllocDeploymentStatus struct {
 	// healthy or unhealthy.
 	Healthy *bool
 
+	// Canary marks whether the allocation is a canary that hasn't been
+	// promoted yet. It is set by the scheduler and cleared on promotion.
+	Canary bool
+
 	// ModifyIndex is the raft index in which the deployment status was last
 	// changed.
 	ModifyIndex uint64
 }
 
+// IsCanary returns if the allocation is an unpromoted canary of a deployment
+func (a *AllocDeploymentStatus) IsCanary() bool {
+	if a == nil {
+		return false
+	}
+
+	return a.Canary
+}
+
 // IsHealthy returns if the allocation is marked as healthy as part of a
 // deployment
 func (a *AllocDeploymentStatus) IsHealthy() bool {
@@ -4705,6 +6323,11 @@ const (
 	EvalStatusComplete  = "complete"
 	EvalStatusFailed    = "failed"
 	EvalStatusCancelled = "canceled"
+
+	// EvalStatusHeld is the status of the evaluations of batch jobs whose
+	// dependencies haven't completed. The leader releases them once the
+	// dependencies complete.
+	EvalStatusHeld = "held"
 )
 
 const (
@@ -4717,6 +6340,9 @@ const (
 	EvalTriggerDeploymentWatcher = "deployment-watcher"
 	EvalTriggerFailedFollowUp    = "failed-follow-up"
 	EvalTriggerMaxPlans          = "max-plan-attempts"
+	EvalTriggerAllocStop         = "alloc-stop"
+	EvalTriggerJobDependencies   = "job-dependencies"
+	EvalTriggerReconnect         = "reconnect"
 )
 
 const (
@@ -4742,10 +6368,64 @@ const (
 	// check if they are terminal. If so, we delete these out of the system.
 	CoreJobDeploymentGC = "deployment-gc"
 
+	// CoreJobExpiredACLTokenGC is used for the garbage collection of the
+	// expired ACL tokens. Global tokens are only collected in the
+	// authoritative region, from which the deletions are replicated.
+	CoreJobExpiredACLTokenGC = "expired-acl-token-gc"
+
 	// CoreJobForceGC is used to force garbage collection of all GCable objects.
 	CoreJobForceGC = "force-gc"
 )
 
+const (
+	// GCScopeJobs, GCScopeEvals, GCScopeDeployments and GCScopeNodes are the
+	// types of objects a forced garbage collection can be limited to.
+	GCScopeJobs        = "jobs"
+	GCScopeEvals       = "evals"
+	GCScopeDeployments = "deployments"
+	GCScopeNodes       = "nodes"
+)
+
+// GCScopes is the list of garbage collection scopes, in the order they are
+// collected. Nodes are collected last so that their allocations have been
+// cleared by the other collections.
+var GCScopes = []string{GCScopeJobs, GCScopeEvals, GCScopeDeployments, GCScopeNodes}
+
+// ForceGCJobID returns the ID of the core job forcing a garbage collection of
+// the given scopes. An error is returned if a scope is unknown.
+func ForceGCJobID(scopes []string) (string, error) {
+	if len(scopes) == 0 {
+		return CoreJobForceGC, nil
+	}
+
+	if ok, unknown := helper.SliceStringIsSubset(GCScopes, scopes); !ok {
+		return "", fmt.Errorf("unknown garbage collection scopes %q; must be one of %s",
+			unknown, strings.Join(GCScopes, ", "))
+	}
+
+	// Order the scopes so that the collections run in a safe order
+	var ordered []string
+	for _, scope := range GCScopes {
+		if ok, _ := helper.SliceStringIsSubset(scopes, []string{scope}); ok {
+			ordered = append(ordered, scope)
+		}
+	}
+	return CoreJobForceGC + ":" + strings.Join(ordered, ","), nil
+}
+
+// ParseForceGCJobID returns whether the core job ID is a forced garbage
+// collection and the scopes it is limited to. The scopes are nil if every
+// type of object is collected.
+func ParseForceGCJobID(id string) (forced bool, scopes []string) {
+	if id == CoreJobForceGC {
+		return true, nil
+	}
+	if !strings.HasPrefix(id, CoreJobForceGC+":") {
+		return false, nil
+	}
+	return true, strings.Split(strings.TrimPrefix(id, CoreJobForceGC+":"), ",")
+}
+
 // Evaluation is used anytime we need to apply business logic as a result
 // of a change to our desired state (job specification) or the emergent state
 // (registered nodes). When the inputs change, we need to "evaluate" them,
@@ -4835,6 +6515,15 @@ type Evaluation struct {
 	// scheduler.
 	SnapshotIndex uint64
 
+	// TraceParent is the W3C traceparent of the span that created the
+	// evaluation. The scheduler processing it continues the trace.
+	TraceParent string
+
+	// LeaderACL is the management token the core jobs issue their RPCs to
+	// the leader with. It is only valid as long as the leader that created
+	// the evaluation is, and must never be exposed via the API.
+	LeaderACL string
+
 	// Raft Indexes
 	CreateIndex uint64
 	ModifyIndex uint64
@@ -4898,7 +6587,7 @@ func (e *Evaluation) ShouldEnqueue() bool {
 	switch e.Status {
 	case EvalStatusPending:
 		return true
-	case EvalStatusComplete, EvalStatusFailed, EvalStatusBlocked, EvalStatusCancelled:
+	case EvalStatusComplete, EvalStatusFailed, EvalStatusBlocked, EvalStatusCancelled, EvalStatusHeld:
 		return false
 	default:
 		panic(fmt.Sprintf("unhandled evaluation (%s) status %s", e.ID, e.Status))
@@ -4911,7 +6600,7 @@ func (e *Evaluation) ShouldBlock() bool {
 	switch e.Status {
 	case EvalStatusBlocked:
 		return true
-	case EvalStatusComplete, EvalStatusFailed, EvalStatusPending, EvalStatusCancelled:
+	case EvalStatusComplete, EvalStatusFailed, EvalStatusPending, EvalStatusCancelled, EvalStatusHeld:
 		return false
 	default:
 		panic(fmt.Sprintf("unhandled evaluation (%s) status %s", e.ID, e.Status))
@@ -5032,6 +6721,10 @@ type Plan struct {
 	// deployments. This allows the scheduler to cancel any unneeded deployment
 	// because the job is stopped or the update block is removed.
 	DeploymentUpdates []*DeploymentStatusUpdate
+
+	// TraceParent is the W3C traceparent of the span that submitted the plan,
+	// so evaluating and applying it is traced along with the evaluation.
+	TraceParent string
 }
 
 // AppendUpdate marks the allocation for eviction. The clientStatus of the
@@ -9,6 +9,7 @@ import (
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/helper"
 	"github.com/kr/pretty"
 )
 
@@ -46,6 +47,14 @@ func TestJob_Validate(t *testing.T) {
 		t.Errorf("expected %s but found: %v", expected, err)
 	}
 
+	j = &Job{
+		Namespace: "invalid namespace",
+	}
+	err = j.Validate()
+	if expected := `Invalid job namespace "invalid namespace"`; !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected %s but found: %v", expected, err)
+	}
+
 	j = &Job{
 		Type: JobTypeService,
 		Periodic: &PeriodicConfig{
@@ -173,7 +182,8 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 				},
 			},
 			Expected: &Job{
-				Type: JobTypeService,
+				Namespace: DefaultNamespace,
+				Type:      JobTypeService,
 				Update: UpdateStrategy{
 					MaxParallel: 2,
 					Stagger:     10 * time.Second,
@@ -185,13 +195,14 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 						RestartPolicy: NewRestartPolicy(JobTypeService),
 						EphemeralDisk: DefaultEphemeralDisk(),
 						Update: &UpdateStrategy{
-							Stagger:         30 * time.Second,
-							MaxParallel:     2,
-							HealthCheck:     UpdateStrategyHealthCheck_Checks,
-							MinHealthyTime:  10 * time.Second,
-							HealthyDeadline: 5 * time.Minute,
-							AutoRevert:      false,
-							Canary:          0,
+							Stagger:          30 * time.Second,
+							MaxParallel:      2,
+							HealthCheck:      UpdateStrategyHealthCheck_Checks,
+							MinHealthyTime:   10 * time.Second,
+							HealthyDeadline:  5 * time.Minute,
+							AutoRevert:       false,
+							Canary:           0,
+							ProgressDeadline: 10 * time.Minute,
 						},
 					},
 				},
@@ -214,8 +225,9 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 				},
 			},
 			Expected: &Job{
-				Type:   JobTypeBatch,
-				Update: UpdateStrategy{},
+				Namespace: DefaultNamespace,
+				Type:      JobTypeBatch,
+				Update:    UpdateStrategy{},
 				TaskGroups: []*TaskGroup{
 					{
 						Name:          "foo",
@@ -255,8 +267,9 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 				},
 			},
 			Expected: &Job{
-				Type:   JobTypeBatch,
-				Update: UpdateStrategy{},
+				Namespace: DefaultNamespace,
+				Type:      JobTypeBatch,
+				Update:    UpdateStrategy{},
 				TaskGroups: []*TaskGroup{
 					{
 						Name:          "foo",
@@ -295,7 +308,8 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 				},
 			},
 			Expected: &Job{
-				Type: JobTypeService,
+				Namespace: DefaultNamespace,
+				Type:      JobTypeService,
 				Update: UpdateStrategy{
 					Stagger:         2 * time.Second,
 					MaxParallel:     2,
@@ -339,7 +353,8 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 				},
 			},
 			Expected: &Job{
-				Type: JobTypeService,
+				Namespace: DefaultNamespace,
+				Type:      JobTypeService,
 				Update: UpdateStrategy{
 					MaxParallel: 200,
 					Stagger:     10 * time.Second,
@@ -351,13 +366,14 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 						RestartPolicy: NewRestartPolicy(JobTypeService),
 						EphemeralDisk: DefaultEphemeralDisk(),
 						Update: &UpdateStrategy{
-							Stagger:         30 * time.Second,
-							MaxParallel:     2,
-							HealthCheck:     UpdateStrategyHealthCheck_Checks,
-							MinHealthyTime:  10 * time.Second,
-							HealthyDeadline: 5 * time.Minute,
-							AutoRevert:      false,
-							Canary:          0,
+							Stagger:          30 * time.Second,
+							MaxParallel:      2,
+							HealthCheck:      UpdateStrategyHealthCheck_Checks,
+							MinHealthyTime:   10 * time.Second,
+							HealthyDeadline:  5 * time.Minute,
+							AutoRevert:       false,
+							Canary:           0,
+							ProgressDeadline: 10 * time.Minute,
 						},
 					},
 				},
@@ -388,7 +404,8 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 				},
 			},
 			Expected: &Job{
-				Type: JobTypeService,
+				Namespace: DefaultNamespace,
+				Type:      JobTypeService,
 				Update: UpdateStrategy{
 					MaxParallel: 2,
 					Stagger:     10 * time.Second,
@@ -400,13 +417,14 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 						RestartPolicy: NewRestartPolicy(JobTypeService),
 						EphemeralDisk: DefaultEphemeralDisk(),
 						Update: &UpdateStrategy{
-							Stagger:         30 * time.Second,
-							MaxParallel:     1,
-							HealthCheck:     UpdateStrategyHealthCheck_Checks,
-							MinHealthyTime:  10 * time.Second,
-							HealthyDeadline: 5 * time.Minute,
-							AutoRevert:      false,
-							Canary:          0,
+							Stagger:          30 * time.Second,
+							MaxParallel:      1,
+							HealthCheck:      UpdateStrategyHealthCheck_Checks,
+							MinHealthyTime:   10 * time.Second,
+							HealthyDeadline:  5 * time.Minute,
+							AutoRevert:       false,
+							Canary:           0,
+							ProgressDeadline: 10 * time.Minute,
 						},
 					},
 					{
@@ -415,13 +433,14 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 						RestartPolicy: NewRestartPolicy(JobTypeService),
 						EphemeralDisk: DefaultEphemeralDisk(),
 						Update: &UpdateStrategy{
-							Stagger:         30 * time.Second,
-							MaxParallel:     1,
-							HealthCheck:     UpdateStrategyHealthCheck_Checks,
-							MinHealthyTime:  10 * time.Second,
-							HealthyDeadline: 5 * time.Minute,
-							AutoRevert:      false,
-							Canary:          0,
+							Stagger:          30 * time.Second,
+							MaxParallel:      1,
+							HealthCheck:      UpdateStrategyHealthCheck_Checks,
+							MinHealthyTime:   10 * time.Second,
+							HealthyDeadline:  5 * time.Minute,
+							AutoRevert:       false,
+							Canary:           0,
+							ProgressDeadline: 10 * time.Minute,
 						},
 					},
 					{
@@ -430,13 +449,14 @@ func TestJob_Canonicalize_Update(t *testing.T) {
 						EphemeralDisk: DefaultEphemeralDisk(),
 						RestartPolicy: NewRestartPolicy(JobTypeService),
 						Update: &UpdateStrategy{
-							Stagger:         30 * time.Second,
-							MaxParallel:     3,
-							HealthCheck:     UpdateStrategyHealthCheck_Checks,
-							MinHealthyTime:  10 * time.Second,
-							HealthyDeadline: 5 * time.Minute,
-							AutoRevert:      false,
-							Canary:          0,
+							Stagger:          30 * time.Second,
+							MaxParallel:      3,
+							HealthCheck:      UpdateStrategyHealthCheck_Checks,
+							MinHealthyTime:   10 * time.Second,
+							HealthyDeadline:  5 * time.Minute,
+							AutoRevert:       false,
+							Canary:           0,
+							ProgressDeadline: 10 * time.Minute,
 						},
 					},
 				},
@@ -476,6 +496,7 @@ func TestJob_SpecChanged(t *testing.T) {
 	mutatedBase := base.Copy()
 	mutatedBase.Status = "foo"
 	mutatedBase.ModifyIndex = base.ModifyIndex + 100
+	mutatedBase.RevertVersion = helper.Uint64ToPtr(1)
 
 	// changed contains a spec change that should be detected
 	change := base.Copy()
@@ -566,7 +587,6 @@ func testJob() *Job {
 							MemoryMB: 256,
 							Networks: []*NetworkResource{
 								&NetworkResource{
-									MBits:        50,
 									DynamicPorts: []Port{{Label: "http"}},
 								},
 							},
@@ -929,7 +949,7 @@ func TestTaskGroup_Validate(t *testing.T) {
 func TestTask_Validate(t *testing.T) {
 	task := &Task{}
 	ephemeralDisk := DefaultEphemeralDisk()
-	err := task.Validate(ephemeralDisk)
+	err := task.Validate(ephemeralDisk, nil)
 	mErr := err.(*multierror.Error)
 	if !strings.Contains(mErr.Errors[0].Error(), "task name") {
 		t.Fatalf("err: %s", err)
@@ -942,7 +962,7 @@ func TestTask_Validate(t *testing.T) {
 	}
 
 	task = &Task{Name: "web/foo"}
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	mErr = err.(*multierror.Error)
 	if !strings.Contains(mErr.Errors[0].Error(), "slashes") {
 		t.Fatalf("err: %s", err)
@@ -959,7 +979,7 @@ func TestTask_Validate(t *testing.T) {
 		LogConfig: DefaultLogConfig(),
 	}
 	ephemeralDisk.SizeMB = 200
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -973,7 +993,7 @@ func TestTask_Validate(t *testing.T) {
 			LTarget: "${meta.rack}",
 		})
 
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	mErr = err.(*multierror.Error)
 	if !strings.Contains(mErr.Errors[0].Error(), "task level: distinct_hosts") {
 		t.Fatalf("err: %s", err)
@@ -1042,7 +1062,6 @@ func TestTask_Validate_Services(t *testing.T) {
 	}
 	task1.Resources.Networks = []*NetworkResource{
 		&NetworkResource{
-			MBits: 10,
 			DynamicPorts: []Port{
 				Port{
 					Label: "a",
@@ -1056,7 +1075,7 @@ func TestTask_Validate_Services(t *testing.T) {
 		},
 	}
 
-	err := task.Validate(ephemeralDisk)
+	err := task.Validate(ephemeralDisk, nil)
 	if err == nil {
 		t.Fatal("expected an error")
 	}
@@ -1077,7 +1096,7 @@ func TestTask_Validate_Services(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 
-	if err = task1.Validate(ephemeralDisk); err != nil {
+	if err = task1.Validate(ephemeralDisk, nil); err != nil {
 		t.Fatalf("err : %v", err)
 	}
 }
@@ -1133,13 +1152,181 @@ func TestTask_Validate_LogConfig(t *testing.T) {
 		SizeMB: 1,
 	}
 
-	err := task.Validate(ephemeralDisk)
+	err := task.Validate(ephemeralDisk, nil)
 	mErr := err.(*multierror.Error)
 	if !strings.Contains(mErr.Errors[3].Error(), "log storage") {
 		t.Fatalf("err: %s", err)
 	}
 }
 
+func TestTask_Validate_Bandwidth(t *testing.T) {
+	task := &Task{
+		Name:      "web",
+		Driver:    "docker",
+		Resources: DefaultResources(),
+		LogConfig: DefaultLogConfig(),
+	}
+	task.Resources.Networks = []*NetworkResource{{MBits: 10}}
+	ephemeralDisk := DefaultEphemeralDisk()
+
+	// The bandwidth of tasks sharing the host network isn't enforced
+	err := task.Validate(ephemeralDisk, nil)
+	if err == nil || !strings.Contains(err.Error(), "Task networks can only reserve bandwidth in groups with a \"bridge\" network") {
+		t.Fatalf("err: %v", err)
+	}
+	err = task.Validate(ephemeralDisk, []*NetworkResource{{Mode: NetworkModeHost}})
+	if err == nil || !strings.Contains(err.Error(), "Task networks can only reserve bandwidth") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The namespace of a bridge network limits it
+	if err := task.Validate(ephemeralDisk, []*NetworkResource{{Mode: NetworkModeBridge}}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	task.Resources.Networks[0].MBits = 0
+	if err := task.Validate(ephemeralDisk, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTask_Validate_Lifecycle(t *testing.T) {
+	ephemeralDisk := DefaultEphemeralDisk()
+	task := &Task{
+		Name:      "cleanup",
+		Driver:    "exec",
+		Resources: DefaultResources(),
+		LogConfig: DefaultLogConfig(),
+		Lifecycle: &TaskLifecycleConfig{
+			Hook: TaskLifecycleHookPoststop,
+		},
+	}
+	if err := task.Validate(ephemeralDisk, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	task.Lifecycle.Sidecar = true
+	err := task.Validate(ephemeralDisk, nil)
+	if err == nil || !strings.Contains(err.Error(), "can't be sidecars") {
+		t.Fatalf("err: %v", err)
+	}
+
+	task.Lifecycle = &TaskLifecycleConfig{Hook: "foo"}
+	err = task.Validate(ephemeralDisk, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid hook") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTaskGroup_Validate_Lifecycle(t *testing.T) {
+	j := testJob()
+	tg := j.TaskGroups[0]
+	tg.Tasks[0].Lifecycle = &TaskLifecycleConfig{
+		Hook: TaskLifecycleHookPrestart,
+	}
+
+	err := tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "At least one task must have no lifecycle") {
+		t.Fatalf("err: %v", err)
+	}
+
+	tg.Tasks[0].Leader = true
+	err = tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "can't be the leader") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTaskGroup_Validate_StopAfterClientDisconnect(t *testing.T) {
+	j := testJob()
+	tg := j.TaskGroups[0]
+	tg.StopAfterClientDisconnect = helper.TimeToPtr(-time.Second)
+
+	err := tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "must be positive") {
+		t.Fatalf("err: %v", err)
+	}
+
+	j.Type = JobTypeSystem
+	tg.StopAfterClientDisconnect = helper.TimeToPtr(time.Minute)
+	err = tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "can't be used with \"system\" jobs") {
+		t.Fatalf("err: %v", err)
+	}
+
+	j.Type = JobTypeService
+	if err := tg.Validate(j); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTaskGroup_Validate_MaxClientDisconnect(t *testing.T) {
+	j := testJob()
+	tg := j.TaskGroups[0]
+	tg.MaxClientDisconnect = helper.TimeToPtr(-time.Second)
+
+	err := tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "must be positive") {
+		t.Fatalf("err: %v", err)
+	}
+
+	tg.MaxClientDisconnect = helper.TimeToPtr(time.Hour)
+	tg.StopAfterClientDisconnect = helper.TimeToPtr(time.Minute)
+	err = tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "can't be used with stop after client disconnect") {
+		t.Fatalf("err: %v", err)
+	}
+
+	j.Type = JobTypeSystem
+	tg.StopAfterClientDisconnect = nil
+	err = tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "can't be used with \"system\" jobs") {
+		t.Fatalf("err: %v", err)
+	}
+
+	j.Type = JobTypeService
+	if err := tg.Validate(j); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestTaskGroup_Validate_BridgeNetwork(t *testing.T) {
+	j := testJob()
+	tg := j.TaskGroups[0]
+	tg.Networks = []*NetworkResource{
+		{
+			Mode:          NetworkModeBridge,
+			ReservedPorts: []Port{{Label: "http", Value: 80, To: 8080}},
+			DynamicPorts:  []Port{{Label: "admin", To: 70000}},
+		},
+	}
+
+	err := tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "maps to invalid port 70000") {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.Contains(err.Error(), "can't reserve ports in \"bridge\" network mode") {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The services of the tasks can use the ports of the group
+	tg.Networks[0].DynamicPorts[0].To = 9000
+	tg.Networks[0].MBits = 100
+	tg.Tasks[0].Resources.Networks = nil
+	if err := tg.Validate(j); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tg.Networks[0].Mode = "cni/mynet"
+	err = tg.Validate(j)
+	if err == nil || !strings.Contains(err.Error(), "Only group networks in \"bridge\" mode can reserve ports") {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.Contains(err.Error(), "Only group networks in \"bridge\" mode can reserve bandwidth") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
 func TestTask_Validate_Template(t *testing.T) {
 
 	bad := &Template{}
@@ -1150,7 +1337,7 @@ func TestTask_Validate_Template(t *testing.T) {
 		SizeMB: 1,
 	}
 
-	err := task.Validate(ephemeralDisk)
+	err := task.Validate(ephemeralDisk, nil)
 	if !strings.Contains(err.Error(), "Template 1 validation failed") {
 		t.Fatalf("err: %s", err)
 	}
@@ -1163,7 +1350,7 @@ func TestTask_Validate_Template(t *testing.T) {
 	}
 
 	task.Templates = []*Template{good, good}
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	if !strings.Contains(err.Error(), "same destination as") {
 		t.Fatalf("err: %s", err)
 	}
@@ -1176,13 +1363,39 @@ func TestTask_Validate_Template(t *testing.T) {
 		},
 	}
 
-	err = task.Validate(ephemeralDisk)
+	err = task.Validate(ephemeralDisk, nil)
 	if err == nil {
 		t.Fatalf("expected error from Template.Validate")
 	}
 	if expected := "cannot use signals"; !strings.Contains(err.Error(), expected) {
 		t.Errorf("expected to find %q but found %v", expected, err)
 	}
+
+	// Env templates can't set the same selected variable
+	task.Templates = []*Template{
+		{
+			SourcePath: "foo",
+			DestPath:   "local/foo.env",
+			ChangeMode: "noop",
+			Envvars:    true,
+			EnvKeys:    map[string]string{"USER": "PGUSER"},
+		},
+		{
+			SourcePath: "bar",
+			DestPath:   "local/bar.env",
+			ChangeMode: "noop",
+			Envvars:    true,
+			EnvKeys:    map[string]string{"NAME": "PGUSER"},
+		},
+	}
+
+	err = task.Validate(ephemeralDisk, nil)
+	if err == nil {
+		t.Fatalf("expected error from Task.Validate")
+	}
+	if expected := `Template 2 sets env var "PGUSER" already set by template 1`; !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected to find %q but found %v", expected, err)
+	}
 }
 
 func TestTemplate_Validate(t *testing.T) {
@@ -1267,6 +1480,94 @@ func TestTemplate_Validate(t *testing.T) {
 				"as octal",
 			},
 		},
+		{
+			Tmpl: &Template{
+				SourcePath: "foo",
+				DestPath:   "local/foo",
+				ChangeMode: "script",
+			},
+			Fail: true,
+			ContainsErrs: []string{
+				"specify change script",
+			},
+		},
+		{
+			Tmpl: &Template{
+				SourcePath:   "foo",
+				DestPath:     "local/foo",
+				ChangeMode:   "script",
+				ChangeScript: &ChangeScript{},
+				Envvars:      true,
+			},
+			Fail: true,
+			ContainsErrs: []string{
+				"change script command",
+				"timeout must be greater than zero",
+				"env var templates",
+			},
+		},
+		{
+			Tmpl: &Template{
+				SourcePath: "foo",
+				DestPath:   "local/foo",
+				ChangeMode: "script",
+				ChangeScript: &ChangeScript{
+					Command: "/bin/reload",
+					Timeout: 5 * time.Second,
+				},
+			},
+			Fail: false,
+		},
+		{
+			Tmpl: &Template{
+				SourcePath: "foo",
+				DestPath:   "local/foo",
+				ChangeMode: "noop",
+				EnvKeys:    map[string]string{"USER": ""},
+			},
+			Fail: true,
+			ContainsErrs: []string{
+				"only be selected for env var templates",
+				"non-empty variable name",
+			},
+		},
+		{
+			Tmpl: &Template{
+				SourcePath: "foo",
+				DestPath:   "local/foo.env",
+				ChangeMode: "noop",
+				Envvars:    true,
+				EnvKeys:    map[string]string{"USER": "PGUSER"},
+			},
+			Fail: false,
+		},
+		{
+			Tmpl: &Template{
+				SourcePath: "foo",
+				DestPath:   "local/foo",
+				ChangeMode: "noop",
+				Wait: &WaitConfig{
+					Min: 10 * time.Second,
+					Max: 5 * time.Second,
+				},
+			},
+			Fail: true,
+			ContainsErrs: []string{
+				"max must be greater than min",
+			},
+		},
+		{
+			Tmpl: &Template{
+				SourcePath: "foo",
+				DestPath:   "local/foo",
+				ChangeMode: "noop",
+				Wait: &WaitConfig{
+					Min: 5 * time.Second,
+					Max: 10 * time.Second,
+				},
+			},
+			Fail: false,
+		},
 	}
 
 	for i, c := range cases {
@@ -1382,12 +1683,13 @@ func TestConstraint_Validate(t *testing.T) {
 
 func TestUpdateStrategy_Validate(t *testing.T) {
 	u := &UpdateStrategy{
-		MaxParallel:     -1,
-		HealthCheck:     "foo",
-		MinHealthyTime:  -10,
-		HealthyDeadline: -15,
-		AutoRevert:      false,
-		Canary:          -1,
+		MaxParallel:      -1,
+		HealthCheck:      "foo",
+		MinHealthyTime:   -10,
+		HealthyDeadline:  -15,
+		AutoRevert:       false,
+		Canary:           -1,
+		ProgressDeadline: -20,
 	}
 
 	err := u.Validate()
@@ -1410,6 +1712,21 @@ func TestUpdateStrategy_Validate(t *testing.T) {
 	if !strings.Contains(mErr.Errors[5].Error(), "Minimum healthy time must be less than healthy deadline") {
 		t.Fatalf("err: %s", err)
 	}
+	if !strings.Contains(mErr.Errors[6].Error(), "Progress deadline may not be less than zero") {
+		t.Fatalf("err: %s", err)
+	}
+
+	u = &UpdateStrategy{
+		HealthCheck:      UpdateStrategyHealthCheck_Checks,
+		Stagger:          30 * time.Second,
+		MinHealthyTime:   10 * time.Second,
+		HealthyDeadline:  10 * time.Minute,
+		ProgressDeadline: 5 * time.Minute,
+	}
+	err = u.Validate()
+	if err == nil || !strings.Contains(err.Error(), "Healthy deadline may not be greater than progress deadline") {
+		t.Fatalf("err: %v", err)
+	}
 }
 
 func TestResource_NetIndex(t *testing.T) {
@@ -1469,7 +1786,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				CIDR:          "10.0.0.0/8",
 				MBits:         100,
-				ReservedPorts: []Port{{"ssh", 22}},
+				ReservedPorts: []Port{{Label: "ssh", Value: 22}},
 			},
 		},
 	}
@@ -1482,7 +1799,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				IP:            "10.0.0.1",
 				MBits:         50,
-				ReservedPorts: []Port{{"web", 80}},
+				ReservedPorts: []Port{{Label: "web", Value: 80}},
 			},
 		},
 	}
@@ -1501,7 +1818,7 @@ func TestResource_Add(t *testing.T) {
 			&NetworkResource{
 				CIDR:          "10.0.0.0/8",
 				MBits:         150,
-				ReservedPorts: []Port{{"ssh", 22}, {"web", 80}},
+				ReservedPorts: []Port{{Label: "ssh", Value: 22}, {Label: "web", Value: 80}},
 			},
 		},
 	}
@@ -1517,7 +1834,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        50,
-				DynamicPorts: []Port{{"http", 0}, {"https", 0}},
+				DynamicPorts: []Port{{Label: "http", Value: 0}, {Label: "https", Value: 0}},
 			},
 		},
 	}
@@ -1525,7 +1842,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        25,
-				DynamicPorts: []Port{{"admin", 0}},
+				DynamicPorts: []Port{{Label: "admin", Value: 0}},
 			},
 		},
 	}
@@ -1543,7 +1860,7 @@ func TestResource_Add_Network(t *testing.T) {
 		Networks: []*NetworkResource{
 			&NetworkResource{
 				MBits:        75,
-				DynamicPorts: []Port{{"http", 0}, {"https", 0}, {"admin", 0}},
+				DynamicPorts: []Port{{Label: "http", Value: 0}, {Label: "https", Value: 0}, {Label: "admin", Value: 0}},
 			},
 		},
 	}
@@ -1751,6 +2068,31 @@ func TestService_Canonicalize(t *testing.T) {
 
 }
 
+func TestService_Meta(t *testing.T) {
+	s := &Service{
+		Name:            "web",
+		Meta:            map[string]string{"version": "1"},
+		TaggedAddresses: map[string]string{"wan": "203.0.113.10:8080"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The hash changes with the metadata
+	other := s.Copy()
+	other.Meta["version"] = "2"
+	if s.Hash() == other.Hash() {
+		t.Fatalf("expected the hash to change with the metadata")
+	}
+
+	// The Nomad provider doesn't support them
+	s.Provider = ServiceProviderNomad
+	err := s.Validate()
+	if err == nil || !strings.Contains(err.Error(), "meta or tagged addresses") {
+		t.Fatalf("expected meta error: %v", err)
+	}
+}
+
 func TestJob_ExpandServiceNames(t *testing.T) {
 	j := &Job{
 		Name: "my-job",
@@ -1882,9 +2224,10 @@ func TestPeriodicConfig_DST(t *testing.T) {
 	t1 := time.Date(2017, time.March, 11, 1, 0, 0, 0, p.location)
 	t2 := time.Date(2017, time.March, 12, 1, 0, 0, 0, p.location)
 
-	// E1 is an 8 hour adjustment, E2 is a 7 hour adjustment
+	// E1 is an 8 hour adjustment, E2 runs when the clock skips from 2:00 am
+	// to 3:00 am since 2:00 am doesn't exist
 	e1 := time.Date(2017, time.March, 11, 10, 0, 0, 0, time.UTC)
-	e2 := time.Date(2017, time.March, 12, 9, 0, 0, 0, time.UTC)
+	e2 := time.Date(2017, time.March, 12, 10, 0, 0, 0, time.UTC)
 
 	n1 := p.Next(t1).UTC()
 	n2 := p.Next(t2).UTC()
@@ -1895,6 +2238,96 @@ func TestPeriodicConfig_DST(t *testing.T) {
 	if !reflect.DeepEqual(e2, n2) {
 		t.Fatalf("Got %v; want %v", n1, e1)
 	}
+
+	// The launch skipped by the clock doesn't run in skip mode
+	p.DSTMode = PeriodicDSTSkip
+	if n := p.Next(t2); !n.IsZero() {
+		t.Fatalf("Got %v; want no launch", n)
+	}
+}
+
+func TestPeriodicConfig_DST_Repeated(t *testing.T) {
+	// On Sun, Nov 5, 2:00 am 2017 the clock is set back to 1:00 am
+	p := &PeriodicConfig{
+		Enabled:  true,
+		SpecType: PeriodicSpecCron,
+		Spec:     "30 * * * *",
+		TimeZone: "America/Los_Angeles",
+	}
+	p.Canonicalize()
+	from := time.Date(2017, time.November, 5, 7, 0, 0, 0, time.UTC).In(p.location)
+
+	// 1:30 am runs once, the first time it occurs
+	expected := []time.Time{
+		time.Date(2017, time.November, 5, 7, 30, 0, 0, time.UTC),
+		time.Date(2017, time.November, 5, 8, 30, 0, 0, time.UTC),
+		time.Date(2017, time.November, 5, 10, 30, 0, 0, time.UTC),
+	}
+	next := from
+	for _, e := range expected {
+		next = p.Next(next)
+		if !next.Equal(e) {
+			t.Fatalf("Got %v; want %v", next.UTC(), e)
+		}
+	}
+
+	// 1:30 am doesn't run in skip mode
+	p.DSTMode = PeriodicDSTSkip
+	expected = []time.Time{
+		time.Date(2017, time.November, 5, 7, 30, 0, 0, time.UTC),
+		time.Date(2017, time.November, 5, 10, 30, 0, 0, time.UTC),
+	}
+	next = from
+	for _, e := range expected {
+		next = p.Next(next)
+		if !next.Equal(e) {
+			t.Fatalf("Got %v; want %v", next.UTC(), e)
+		}
+	}
+}
+
+func TestPeriodicConfig_Specs(t *testing.T) {
+	p := &PeriodicConfig{
+		Enabled:  true,
+		SpecType: PeriodicSpecCron,
+		Specs:    []string{"0 13 * * *", "0 9 * * *"},
+		TimeZone: "America/New_York",
+	}
+	p.Canonicalize()
+	if err := p.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The closest launch time of the specs is used
+	from := time.Date(2017, time.June, 1, 10, 0, 0, 0, p.location)
+	expected := []time.Time{
+		time.Date(2017, time.June, 1, 13, 0, 0, 0, p.location),
+		time.Date(2017, time.June, 2, 9, 0, 0, 0, p.location),
+	}
+	next := from
+	for _, e := range expected {
+		next = p.Next(next)
+		if !next.Equal(e) {
+			t.Fatalf("Got %v; want %v", next, e)
+		}
+	}
+
+	p.Spec = "0 10 * * *"
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "not both") {
+		t.Fatalf("expected error with a spec and specs: %v", err)
+	}
+
+	p.Spec = ""
+	p.Specs = append(p.Specs, "foo")
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "Invalid cron spec \"foo\"") {
+		t.Fatalf("expected error with invalid spec: %v", err)
+	}
+
+	p.Specs = []string{"0 9 * * *"}
+	p.DSTMode = "foo"
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "Invalid DST mode") {
+		t.Fatalf("expected error with invalid DST mode: %v", err)
+	}
 }
 
 func TestRestartPolicy_Validate(t *testing.T) {
@@ -1951,6 +2384,62 @@ func TestRestartPolicy_Validate(t *testing.T) {
 	}
 }
 
+func TestCheckRestart_Validate(t *testing.T) {
+	c := &CheckRestart{
+		Limit: 3,
+		Grace: 10 * time.Second,
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c.Limit = -1
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Fatalf("expect limit error, got: %v", err)
+	}
+
+	c.Limit = 0
+	c.Grace = -1
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "grace") {
+		t.Fatalf("expect grace error, got: %v", err)
+	}
+
+	check := &ServiceCheck{CheckRestart: &CheckRestart{}}
+	if check.TriggersRestarts() {
+		t.Fatalf("check with a limit of 0 shouldn't trigger restarts")
+	}
+	check.CheckRestart.Limit = 1
+	if !check.TriggersRestarts() {
+		t.Fatalf("check with a limit should trigger restarts")
+	}
+}
+
+func TestConsul_Validate(t *testing.T) {
+	c := &Consul{
+		Namespace: "web-team",
+		Partition: "billing",
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c.Namespace = "web/team"
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "namespace") {
+		t.Fatalf("expect namespace error, got: %v", err)
+	}
+
+	c.Namespace = ""
+	c.Partition = strings.Repeat("a", 65)
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "partition") {
+		t.Fatalf("expect partition error, got: %v", err)
+	}
+
+	var nilConsul *Consul
+	if ns := nilConsul.GetNamespace(); ns != "" {
+		t.Fatalf("expect default namespace, got: %q", ns)
+	}
+}
+
 func TestAllocation_Index(t *testing.T) {
 	a1 := Allocation{
 		Name:      "example.cache[1]",
@@ -2177,6 +2666,38 @@ func TestVault_Validate(t *testing.T) {
 	if !strings.Contains(err.Error(), "root") {
 		t.Fatalf("Expected root error")
 	}
+
+	v.Policies = []string{"foo"}
+	v.ChangeMode = VaultChangeModeScript
+	if err := v.Validate(); err == nil || !strings.Contains(err.Error(), "Change script must") {
+		t.Fatalf("Expected change script empty error: %v", err)
+	}
+
+	v.ChangeScript = &ChangeScript{Command: "/bin/reload", Timeout: time.Second}
+	if err := v.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Roles define the policies of the token
+	v.Role = "app"
+	if err := v.Validate(); err == nil || !strings.Contains(err.Error(), "along with role") {
+		t.Fatalf("Expected policies with role error: %v", err)
+	}
+
+	v.Policies = nil
+	if err := v.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Namespaces may be nested
+	v.Namespace = "team-a/db"
+	if err := v.Validate(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	v.Namespace = "/team-a/"
+	if err := v.Validate(); err == nil || !strings.Contains(err.Error(), "Invalid Vault namespace") {
+		t.Fatalf("Expected invalid namespace error: %v", err)
+	}
 }
 
 func TestParameterizedJobConfig_Validate(t *testing.T) {
@@ -2197,6 +2718,104 @@ func TestParameterizedJobConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestParameterizedJobConfig_Validate_Schema(t *testing.T) {
+	d := &ParameterizedJobConfig{
+		Payload:      DispatchPayloadForbidden,
+		MetaRequired: []string{"foo"},
+		MetaSchema: map[string]*DispatchMetaSchema{
+			"foo": {Type: "list"},
+			"bar": {},
+			"foo2": {
+				Type:    DispatchMetaTypeInt,
+				Allowed: []string{"1", "a"},
+			},
+		},
+		PayloadSchema: &DispatchPayloadSchema{},
+	}
+	d.MetaOptional = []string{"foo2"}
+
+	err := d.Validate()
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{
+		`key "bar" that is neither required nor optional`,
+		`unknown type "list"`,
+		`allowed value "a" is not of type int`,
+		"payload is forbidden",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error %q: %v", expected, err)
+		}
+	}
+
+	d = &ParameterizedJobConfig{
+		Payload: DispatchPayloadOptional,
+		PayloadSchema: &DispatchPayloadSchema{
+			MaxSize:        -1,
+			ContentType:    DispatchPayloadContentTypeText,
+			RequiredFields: []string{"id"},
+		},
+	}
+	err = d.Validate()
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, expected := range []string{"max size", "required fields are only supported"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected error %q: %v", expected, err)
+		}
+	}
+}
+
+func TestDispatchMetaSchema_ValidateValue(t *testing.T) {
+	s := &DispatchMetaSchema{
+		Type:    DispatchMetaTypeInt,
+		Pattern: "^[0-9]{2}$",
+		Allowed: []string{"10", "20"},
+	}
+	if err := s.ValidateValue("10"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	cases := map[string]string{
+		"a":   "not of type int",
+		"100": "doesn't match the pattern",
+		"30":  "not one of the allowed values",
+	}
+	for value, expected := range cases {
+		if err := s.ValidateValue(value); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("value %q: expected error %q: %v", value, expected, err)
+		}
+	}
+}
+
+func TestDispatchPayloadSchema_ValidatePayload(t *testing.T) {
+	s := &DispatchPayloadSchema{
+		MaxSize:        32,
+		ContentType:    DispatchPayloadContentTypeJSON,
+		RequiredFields: []string{"id"},
+	}
+	if err := s.ValidatePayload([]byte(`{"id": 1}`)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	cases := map[string]string{
+		`{"id": 1, "name": "a very long name"}`: "exceeds maximum size",
+		`{"id":`:                                "not valid JSON",
+		`[1]`:                                   "not a JSON object",
+		`{"name": 1}`:                           "missing the required fields [id]",
+	}
+	for payload, expected := range cases {
+		if err := s.ValidatePayload([]byte(payload)); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("payload %q: expected error %q: %v", payload, expected, err)
+		}
+	}
+
+	s = &DispatchPayloadSchema{ContentType: DispatchPayloadContentTypeText}
+	if err := s.ValidatePayload([]byte{0xff, 0xfe}); err == nil || !strings.Contains(err.Error(), "UTF-8") {
+		t.Fatalf("expected invalid UTF-8 error: %v", err)
+	}
+}
+
 func TestParameterizedJobConfig_Validate_NonBatch(t *testing.T) {
 	job := testJob()
 	job.ParameterizedJob = &ParameterizedJobConfig{
@@ -2257,3 +2876,56 @@ func TestIsRecoverable(t *testing.T) {
 		t.Errorf("Explicitly recoverable errors *should* be recoverable")
 	}
 }
+
+func TestForceGCJobID(t *testing.T) {
+	cases := []struct {
+		Scopes   []string
+		Expected string
+		Err      bool
+	}{
+		{
+			Scopes:   nil,
+			Expected: CoreJobForceGC,
+		},
+		{
+			Scopes:   []string{GCScopeNodes, GCScopeJobs},
+			Expected: "force-gc:jobs,nodes",
+		},
+		{
+			Scopes:   []string{GCScopeEvals, GCScopeEvals},
+			Expected: "force-gc:evals",
+		},
+		{
+			Scopes: []string{GCScopeJobs, "allocs"},
+			Err:    true,
+		},
+	}
+
+	for _, c := range cases {
+		id, err := ForceGCJobID(c.Scopes)
+		if c.Err {
+			if err == nil {
+				t.Fatalf("%v: expected error", c.Scopes)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", c.Scopes, err)
+		}
+		if id != c.Expected {
+			t.Fatalf("%v: expected %q; got %q", c.Scopes, c.Expected, id)
+		}
+
+		forced, scopes := ParseForceGCJobID(id)
+		if !forced {
+			t.Fatalf("%v: %q not parsed as a forced GC", c.Scopes, id)
+		}
+		if len(c.Scopes) == 0 && scopes != nil {
+			t.Fatalf("%v: expected every scope; got %v", c.Scopes, scopes)
+		}
+	}
+
+	if forced, _ := ParseForceGCJobID(CoreJobJobGC); forced {
+		t.Fatalf("%q should not be a forced GC", CoreJobJobGC)
+	}
+}
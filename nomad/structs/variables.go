@@ -0,0 +1,195 @@
+package structs
+
+import (
+	"fmt"
+	"regexp"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/helper"
+)
+
+const (
+	// maxVariablePathLength limits the length of the path of a variable
+	maxVariablePathLength = 128
+
+	// maxVariableSize limits the size of the items of a variable, keys and
+	// values included, so that variables stay small enough to be replicated
+	// through Raft
+	maxVariableSize = 64 * 1024
+)
+
+var (
+	// validVariablePath is used to validate the path of a variable. Paths
+	// are made of slash separated segments.
+	validVariablePath = regexp.MustCompile(`^[a-zA-Z0-9-_.~]+(/[a-zA-Z0-9-_.~]+)*$`)
+)
+
+// Variable is a set of key/value items stored at a path of a namespace. The
+// items are encrypted by the servers before being written to Raft, and are
+// only decrypted when the variable is read.
+type Variable struct {
+	// Namespace is the namespace of the variable. Defaults to the default
+	// namespace.
+	Namespace string
+
+	// Path is the unique path of the variable in its namespace
+	Path string
+
+	// Items are the key/value pairs of the variable
+	Items map[string]string
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Validate returns an error if the variable is invalid.
+func (v *Variable) Validate() error {
+	var mErr multierror.Error
+
+	if v.Namespace != DefaultNamespace && !validNamespaceName.MatchString(v.Namespace) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid namespace %q", v.Namespace))
+	}
+	if len(v.Path) > maxVariablePathLength || !validVariablePath.MatchString(v.Path) {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("invalid path %q. Must match regex %s and be at most %d characters",
+			v.Path, validVariablePath, maxVariablePathLength))
+	}
+
+	if len(v.Items) == 0 {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("variable must have at least one item"))
+	}
+	size := 0
+	for k, val := range v.Items {
+		if k == "" {
+			mErr.Errors = append(mErr.Errors, fmt.Errorf("item keys can't be empty"))
+		}
+		size += len(k) + len(val)
+	}
+	if size > maxVariableSize {
+		mErr.Errors = append(mErr.Errors, fmt.Errorf("items larger than %d bytes", maxVariableSize))
+	}
+
+	return mErr.ErrorOrNil()
+}
+
+// Copy returns a deep copy of the variable.
+func (v *Variable) Copy() *Variable {
+	if v == nil {
+		return nil
+	}
+	nv := new(Variable)
+	*nv = *v
+	nv.Items = helper.CopyMapStringString(v.Items)
+	return nv
+}
+
+// Metadata returns the metadata of the variable, without its items.
+func (v *Variable) Metadata() *VariableMetadata {
+	return &VariableMetadata{
+		Namespace:   v.Namespace,
+		Path:        v.Path,
+		CreateIndex: v.CreateIndex,
+		ModifyIndex: v.ModifyIndex,
+	}
+}
+
+// VariableMetadata describes a variable without exposing its items. It is
+// returned by list requests.
+type VariableMetadata struct {
+	Namespace   string
+	Path        string
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// VariableEncrypted is a variable as stored in the state store, with its
+// items encrypted.
+type VariableEncrypted struct {
+	Namespace string
+	Path      string
+
+	// KeyID identifies the key the items were encrypted with
+	KeyID string
+
+	// Nonce is the nonce the items were encrypted with
+	Nonce []byte
+
+	// Data are the encrypted items
+	Data []byte
+
+	// Raft indexes
+	CreateIndex uint64
+	ModifyIndex uint64
+}
+
+// Metadata returns the metadata of the encrypted variable.
+func (v *VariableEncrypted) Metadata() *VariableMetadata {
+	return &VariableMetadata{
+		Namespace:   v.Namespace,
+		Path:        v.Path,
+		CreateIndex: v.CreateIndex,
+		ModifyIndex: v.ModifyIndex,
+	}
+}
+
+// VariablesUpsertRequest is used to create or update a variable. Check-and-set
+// updates only apply if the variable's ModifyIndex is the one of the stored
+// variable, or zero if it must not exist yet.
+type VariablesUpsertRequest struct {
+	Variable *Variable
+	CAS      bool
+	WriteRequest
+}
+
+// VariablesEncryptedUpsertRequest is the Raft request writing an encrypted
+// variable.
+type VariablesEncryptedUpsertRequest struct {
+	Variable *VariableEncrypted
+	CAS      bool
+	WriteRequest
+}
+
+// VariablesDeleteRequest is used to delete a variable. Check-and-set deletes
+// only apply if CheckIndex is the ModifyIndex of the stored variable.
+type VariablesDeleteRequest struct {
+	Namespace  string
+	Path       string
+	CAS        bool
+	CheckIndex uint64
+	WriteRequest
+}
+
+// VariablesWriteResponse is the response of a write. Updated is false if a
+// check-and-set write didn't apply, in which case Conflict is the metadata of
+// the stored variable, if any.
+type VariablesWriteResponse struct {
+	Updated  bool
+	Conflict *VariableMetadata
+	WriteMeta
+}
+
+// VariablesReadRequest is used to read a variable
+type VariablesReadRequest struct {
+	Namespace string
+	Path      string
+	QueryOptions
+}
+
+// VariablesReadResponse is used to return a variable
+type VariablesReadResponse struct {
+	Variable *Variable
+	QueryMeta
+}
+
+// VariablesListRequest is used to list the variables of a namespace. The
+// prefix of the query options filters the variables by path.
+type VariablesListRequest struct {
+	Namespace string
+	QueryOptions
+}
+
+// VariablesListResponse is used for a list request
+type VariablesListResponse struct {
+	Variables []*VariableMetadata
+	QueryMeta
+}
@@ -0,0 +1,63 @@
+package structs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVariable_Validate(t *testing.T) {
+	items := map[string]string{"password": "secret"}
+	cases := []struct {
+		v   *Variable
+		err string
+	}{
+		{
+			v: &Variable{Namespace: DefaultNamespace, Path: "app/db", Items: items},
+		},
+		{
+			v:   &Variable{Namespace: "web prod", Path: "app/db", Items: items},
+			err: "invalid namespace",
+		},
+		{
+			v:   &Variable{Namespace: DefaultNamespace, Path: "app//db", Items: items},
+			err: "invalid path",
+		},
+		{
+			v:   &Variable{Namespace: DefaultNamespace, Path: "/app", Items: items},
+			err: "invalid path",
+		},
+		{
+			v:   &Variable{Namespace: DefaultNamespace, Path: strings.Repeat("a", 129), Items: items},
+			err: "invalid path",
+		},
+		{
+			v:   &Variable{Namespace: DefaultNamespace, Path: "app/db"},
+			err: "at least one item",
+		},
+		{
+			v:   &Variable{Namespace: DefaultNamespace, Path: "app/db", Items: map[string]string{"": "v"}},
+			err: "can't be empty",
+		},
+		{
+			v: &Variable{
+				Namespace: DefaultNamespace,
+				Path:      "app/db",
+				Items:     map[string]string{"cert": strings.Repeat("a", maxVariableSize)},
+			},
+			err: "items larger than",
+		},
+	}
+
+	for _, c := range cases {
+		err := c.v.Validate()
+		if c.err == "" {
+			if err != nil {
+				t.Fatalf("unexpected error validating %q: %v", c.v.Path, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("expected error %q validating %q, got: %v", c.err, c.v.Path, err)
+		}
+	}
+}
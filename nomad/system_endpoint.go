@@ -11,20 +11,31 @@ type System struct {
 	srv *Server
 }
 
-// GarbageCollect is used to trigger the system to immediately garbage collect nodes, evals
-// and jobs.
-func (s *System) GarbageCollect(args *structs.GenericRequest, reply *structs.GenericResponse) error {
+// GarbageCollect is used to trigger the system to immediately garbage collect nodes, evals,
+// deployments and jobs, or only the object types of the requested scopes.
+func (s *System) GarbageCollect(args *structs.GarbageCollectRequest, reply *structs.GenericResponse) error {
 	if done, err := s.srv.forward("System.GarbageCollect", args, args, reply); done {
 		return err
 	}
 
+	if aclObj, err := s.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
+	jobID, err := structs.ForceGCJobID(args.Scopes)
+	if err != nil {
+		return err
+	}
+
 	// Get the states current index
 	snapshotIndex, err := s.srv.fsm.State().LatestIndex()
 	if err != nil {
 		return fmt.Errorf("failed to determine state store's index: %v", err)
 	}
 
-	s.srv.evalBroker.Enqueue(s.srv.coreJobEval(structs.CoreJobForceGC, snapshotIndex))
+	s.srv.evalBroker.Enqueue(s.srv.coreJobEval(jobID, snapshotIndex))
 	return nil
 }
 
@@ -35,6 +46,12 @@ func (s *System) ReconcileJobSummaries(args *structs.GenericRequest, reply *stru
 		return err
 	}
 
+	if aclObj, err := s.srv.ResolveToken(args.AuthToken); err != nil {
+		return err
+	} else if !aclObj.IsManagement() {
+		return structs.ErrPermissionDenied
+	}
+
 	_, index, err := s.srv.raftApply(structs.ReconcileJobSummariesRequestType, args)
 	if err != nil {
 		return fmt.Errorf("reconciliation of job summaries failed: %v", err)
@@ -3,6 +3,7 @@ package nomad
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	memdb "github.com/hashicorp/go-memdb"
@@ -36,7 +37,7 @@ func TestSystemEndpoint_GarbageCollect(t *testing.T) {
 	}
 
 	// Make the GC request
-	req := &structs.GenericRequest{
+	req := &structs.GarbageCollectRequest{
 		QueryOptions: structs.QueryOptions{
 			Region: "global",
 		},
@@ -62,6 +63,26 @@ func TestSystemEndpoint_GarbageCollect(t *testing.T) {
 	})
 }
 
+func TestSystemEndpoint_GarbageCollect_InvalidScope(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	req := &structs.GarbageCollectRequest{
+		Scopes: []string{structs.GCScopeJobs, "allocs"},
+		QueryOptions: structs.QueryOptions{
+			Region: "global",
+		},
+	}
+	var resp structs.GenericResponse
+	err := msgpackrpc.CallWithCodec(codec, "System.GarbageCollect", req, &resp)
+	if err == nil || !strings.Contains(err.Error(), "allocs") {
+		t.Fatalf("expected unknown scope error; got %v", err)
+	}
+}
+
 func TestSystemEndpoint_ReconcileSummaries(t *testing.T) {
 	t.Parallel()
 	s1 := testServer(t, nil)
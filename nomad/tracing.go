@@ -0,0 +1,25 @@
+package nomad
+
+import (
+	"github.com/hashicorp/nomad/helper/tracing"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// startRPCSpan starts the span of an RPC handled by this server, continuing
+// the trace of the caller.
+func (s *Server) startRPCSpan(method, traceParent string) *tracing.Span {
+	span := s.config.Tracer.Start(method, traceParent)
+	span.SetKind(tracing.SpanKindServer)
+	return span
+}
+
+// tracedRaftApply is raftApply recording the time taken to commit and apply
+// the message as a child span of parent.
+func (s *Server) tracedRaftApply(parent *tracing.Span, t structs.MessageType, msg interface{}) (interface{}, uint64, error) {
+	span := s.config.Tracer.Start("raft.apply", parent.TraceParent())
+	span.SetAttribute("raft.message_type", int(t))
+	resp, index, err := s.raftApply(t, msg)
+	span.SetError(err)
+	span.End()
+	return resp, index, err
+}
@@ -0,0 +1,277 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	memdb "github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+var (
+	// errVariablesDisabled is returned when the servers have no key to
+	// encrypt the variables with
+	errVariablesDisabled = fmt.Errorf("variables are disabled: servers have no variables_encryption_key")
+)
+
+// Variables endpoint is used to read and write the encrypted variables
+type Variables struct {
+	srv *Server
+}
+
+// Upsert is used to create or update a variable
+func (v *Variables) Upsert(args *structs.VariablesUpsertRequest,
+	reply *structs.VariablesWriteResponse) error {
+	if done, err := v.srv.forward("Variables.Upsert", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "variables", "upsert"}, time.Now())
+
+	if v.srv.encrypter == nil {
+		return errVariablesDisabled
+	}
+
+	// Validate the arguments
+	if args.Variable == nil {
+		return fmt.Errorf("missing variable")
+	}
+	variable := args.Variable.Copy()
+	if variable.Namespace == "" {
+		variable.Namespace = structs.DefaultNamespace
+	}
+	if err := variable.Validate(); err != nil {
+		return fmt.Errorf("Invalid variable %q: %v", variable.Path, err)
+	}
+	if err := v.srv.checkNamespaceWrite(args.AuthToken, variable.Namespace); err != nil {
+		return err
+	}
+	if err := v.checkNamespace(variable.Namespace); err != nil {
+		return err
+	}
+
+	// Encrypt the items before they are written to Raft
+	encrypted, err := v.srv.encrypter.Encrypt(variable)
+	if err != nil {
+		return err
+	}
+	req := &structs.VariablesEncryptedUpsertRequest{
+		Variable:     encrypted,
+		CAS:          args.CAS,
+		WriteRequest: args.WriteRequest,
+	}
+
+	// Update via Raft
+	resp, index, err := v.srv.raftApply(structs.VariablesUpsertRequestType, req)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.variables: Apply failed: %v", err)
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+	*reply = *resp.(*structs.VariablesWriteResponse)
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// Delete is used to delete a variable
+func (v *Variables) Delete(args *structs.VariablesDeleteRequest,
+	reply *structs.VariablesWriteResponse) error {
+	if done, err := v.srv.forward("Variables.Delete", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "variables", "delete"}, time.Now())
+
+	// Validate the arguments
+	if args.Path == "" {
+		return fmt.Errorf("missing variable path")
+	}
+	if args.Namespace == "" {
+		args.Namespace = structs.DefaultNamespace
+	}
+	if err := v.srv.checkNamespaceWrite(args.AuthToken, args.Namespace); err != nil {
+		return err
+	}
+
+	// Update via Raft
+	resp, index, err := v.srv.raftApply(structs.VariablesDeleteRequestType, args)
+	if err != nil {
+		v.srv.logger.Printf("[ERR] nomad.variables: Apply failed: %v", err)
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+	*reply = *resp.(*structs.VariablesWriteResponse)
+
+	// Update the index
+	reply.Index = index
+	return nil
+}
+
+// Read is used to read a variable and its decrypted items
+func (v *Variables) Read(args *structs.VariablesReadRequest,
+	reply *structs.VariablesReadResponse) error {
+	if done, err := v.srv.forward("Variables.Read", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "variables", "read"}, time.Now())
+
+	if v.srv.encrypter == nil {
+		return errVariablesDisabled
+	}
+	if args.Namespace == "" {
+		args.Namespace = structs.DefaultNamespace
+	}
+
+	// Clients read the variables of the namespaces of their allocations to
+	// render their templates
+	node, aclObj, err := v.srv.resolveClientOrToken(args.AuthToken)
+	if err != nil {
+		return err
+	}
+	if !aclObj.AllowVariableRead(args.Namespace, args.Path) {
+		return structs.ErrPermissionDenied
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			if node != nil {
+				if ok, err := nodeRunsNamespace(ws, state, node.ID, args.Namespace); err != nil {
+					return err
+				} else if !ok {
+					return structs.ErrPermissionDenied
+				}
+			}
+
+			// Verify the arguments
+			if args.Path == "" {
+				return fmt.Errorf("missing variable path")
+			}
+
+			out, err := state.VariableByPath(ws, args.Namespace, args.Path)
+			if err != nil {
+				return err
+			}
+
+			// Setup the output
+			reply.Variable = nil
+			if out != nil {
+				variable, err := v.srv.encrypter.Decrypt(out)
+				if err != nil {
+					return err
+				}
+				reply.Variable = variable
+				reply.Index = out.ModifyIndex
+			} else {
+				// Use the last index that affected the variables table
+				index, err := state.Index("variables")
+				if err != nil {
+					return err
+				}
+				reply.Index = index
+			}
+
+			// Set the query response
+			v.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return v.srv.blockingRPC(&opts)
+}
+
+// List is used to list the variables of a namespace. Only the metadata of
+// the variables is returned, without their items.
+func (v *Variables) List(args *structs.VariablesListRequest,
+	reply *structs.VariablesListResponse) error {
+	if done, err := v.srv.forward("Variables.List", args, args, reply); done {
+		return err
+	}
+	defer metrics.MeasureSince([]string{"nomad", "variables", "list"}, time.Now())
+
+	if args.Namespace == "" {
+		args.Namespace = structs.DefaultNamespace
+	}
+	if err := v.srv.checkNamespaceRead(args.AuthToken, args.Namespace); err != nil {
+		return err
+	}
+
+	// Setup the blocking query
+	opts := blockingOptions{
+		queryOpts: &args.QueryOptions,
+		queryMeta: &reply.QueryMeta,
+		run: func(ws memdb.WatchSet, state *state.StateStore) error {
+			iter, err := state.PrefixFrom(ws, "variables", "id", []interface{}{args.Namespace}, args.QueryOptions.Prefix, args.QueryOptions.NextToken)
+			if err != nil {
+				return err
+			}
+
+			variables := []*structs.VariableMetadata{}
+			paginator, err := newPaginator(iter, args.QueryOptions, func(raw interface{}) string {
+				return raw.(*structs.VariableEncrypted).Path
+			}, func(raw interface{}) (interface{}, error) {
+				return raw.(*structs.VariableEncrypted).Metadata(), nil
+			})
+			if err != nil {
+				return err
+			}
+			nextToken, err := paginator.Page(func(obj interface{}) error {
+				variables = append(variables, obj.(*structs.VariableMetadata))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			reply.Variables = variables
+			reply.NextToken = nextToken
+
+			// Use the last index that affected the variables table
+			index, err := state.Index("variables")
+			if err != nil {
+				return err
+			}
+			reply.Index = index
+
+			// Set the query response
+			v.srv.setQueryMeta(&reply.QueryMeta)
+			return nil
+		}}
+	return v.srv.blockingRPC(&opts)
+}
+
+// checkNamespace returns an error if the namespace doesn't exist. The default
+// namespace always exists.
+func (v *Variables) checkNamespace(name string) error {
+	if name == structs.DefaultNamespace {
+		return nil
+	}
+	ns, err := v.srv.fsm.State().NamespaceByName(nil, name)
+	if err != nil {
+		return err
+	}
+	if ns == nil {
+		return fmt.Errorf("namespace %q not found", name)
+	}
+	return nil
+}
+
+// nodeRunsNamespace returns whether the node runs non-terminal allocations of
+// the namespace
+func nodeRunsNamespace(ws memdb.WatchSet, state *state.StateStore, nodeID, namespace string) (bool, error) {
+	allocs, err := state.AllocsByNode(ws, nodeID)
+	if err != nil {
+		return false, err
+	}
+	for _, alloc := range allocs {
+		if !alloc.TerminalStatus() && allocNamespace(alloc) == namespace {
+			return true, nil
+		}
+	}
+	return false, nil
+}
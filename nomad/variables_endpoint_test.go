@@ -0,0 +1,131 @@
+package nomad
+
+import (
+	"strings"
+	"testing"
+
+	msgpackrpc "github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func testVariablesServer(t *testing.T) *Server {
+	return testServer(t, func(c *Config) {
+		c.VariablesEncryptionKey = make([]byte, VariablesKeySize)
+	})
+}
+
+func TestVariablesEndpoint_UpsertRead(t *testing.T) {
+	t.Parallel()
+	s1 := testVariablesServer(t)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	// Write a variable
+	upsert := &structs.VariablesUpsertRequest{
+		Variable: &structs.Variable{
+			Path:  "app/db",
+			Items: map[string]string{"password": "secret"},
+		},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.VariablesWriteResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Variables.Upsert", upsert, &resp), "RPC")
+	assert.True(resp.Updated, "Updated")
+	assert.NotZero(resp.Index, "resp.Index")
+
+	// The items are encrypted in the state store
+	stored, err := s1.fsm.State().VariableByPath(nil, structs.DefaultNamespace, "app/db")
+	assert.Nil(err, "VariableByPath")
+	assert.NotNil(stored, "stored variable")
+	assert.False(strings.Contains(string(stored.Data), "secret"), "items encrypted")
+
+	// Read the variable back
+	read := &structs.VariablesReadRequest{
+		Path:         "app/db",
+		QueryOptions: structs.QueryOptions{Region: "global"},
+	}
+	var readResp structs.VariablesReadResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Variables.Read", read, &readResp), "RPC")
+	assert.NotNil(readResp.Variable, "Variable")
+	assert.Equal(structs.DefaultNamespace, readResp.Variable.Namespace)
+	assert.Equal("secret", readResp.Variable.Items["password"])
+	assert.Equal(resp.Index, readResp.Variable.ModifyIndex)
+
+	// A check-and-set write based on a stale index fails
+	upsert.CAS = true
+	upsert.Variable.Items["password"] = "rotated"
+	var casResp structs.VariablesWriteResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Variables.Upsert", upsert, &casResp), "RPC")
+	assert.False(casResp.Updated, "Updated")
+	assert.NotNil(casResp.Conflict, "Conflict")
+	assert.Equal(resp.Index, casResp.Conflict.ModifyIndex)
+
+	// Writing to a missing namespace fails
+	upsert.CAS = false
+	upsert.Variable.Namespace = "missing"
+	err = msgpackrpc.CallWithCodec(codec, "Variables.Upsert", upsert, &casResp)
+	assert.NotNil(err, "expected missing namespace error")
+}
+
+func TestVariablesEndpoint_ListDelete(t *testing.T) {
+	t.Parallel()
+	s1 := testVariablesServer(t)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	assert := assert.New(t)
+
+	for _, path := range []string{"app/db", "app/cache", "web/tls"} {
+		upsert := &structs.VariablesUpsertRequest{
+			Variable:     &structs.Variable{Path: path, Items: map[string]string{"k": "v"}},
+			WriteRequest: structs.WriteRequest{Region: "global"},
+		}
+		var resp structs.VariablesWriteResponse
+		assert.Nil(msgpackrpc.CallWithCodec(codec, "Variables.Upsert", upsert, &resp), "RPC")
+	}
+
+	// List by prefix
+	list := &structs.VariablesListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", Prefix: "app/"},
+	}
+	var listResp structs.VariablesListResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Variables.List", list, &listResp), "RPC")
+	assert.Len(listResp.Variables, 2, "Variables")
+	assert.Equal("app/cache", listResp.Variables[0].Path)
+
+	// Delete a variable
+	del := &structs.VariablesDeleteRequest{
+		Path:         "app/db",
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var delResp structs.VariablesWriteResponse
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Variables.Delete", del, &delResp), "RPC")
+	assert.True(delResp.Updated, "Updated")
+
+	var listResp2 structs.VariablesListResponse
+	list.Prefix = ""
+	assert.Nil(msgpackrpc.CallWithCodec(codec, "Variables.List", list, &listResp2), "RPC")
+	assert.Len(listResp2.Variables, 2, "Variables")
+}
+
+func TestVariablesEndpoint_Disabled(t *testing.T) {
+	t.Parallel()
+	s1 := testServer(t, nil)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+
+	upsert := &structs.VariablesUpsertRequest{
+		Variable:     &structs.Variable{Path: "app/db", Items: map[string]string{"k": "v"}},
+		WriteRequest: structs.WriteRequest{Region: "global"},
+	}
+	var resp structs.VariablesWriteResponse
+	err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", upsert, &resp)
+	if err == nil || !strings.Contains(err.Error(), "variables are disabled") {
+		t.Fatalf("expected variables to be disabled; got %v", err)
+	}
+}
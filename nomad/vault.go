@@ -15,6 +15,7 @@ import (
 
 	metrics "github.com/armon/go-metrics"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/nomad/helper/vaultutil"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/nomad/structs/config"
 	vapi "github.com/hashicorp/vault/api"
@@ -137,6 +138,13 @@ type VaultClient interface {
 	// Running returns whether the Vault client is running
 	Running() bool
 
+	// Enabled returns whether the Vault integration is enabled
+	Enabled() bool
+
+	// ConnectionEstablished returns whether a connection to Vault has been
+	// established and any error that potentially caused it to be false
+	ConnectionEstablished() (bool, error)
+
 	// Stats returns the Vault clients statistics
 	Stats() *VaultStats
 
@@ -897,10 +905,18 @@ func (v *vaultClient) CreateToken(ctx context.Context, a *structs.Allocation, ta
 	role := v.getRole()
 	if v.tokenData.Root && role == "" {
 		req.Period = v.childTTL
-		secret, err = v.auth.Create(req)
+		if taskVault.Namespace != "" {
+			secret, err = vaultutil.Write(v.client, taskVault.Namespace, "auth/token/create", req)
+		} else {
+			secret, err = v.auth.Create(req)
+		}
 	} else {
 		// Make the token using the role
-		secret, err = v.auth.CreateWithRole(req, v.getRole())
+		if taskVault.Namespace != "" {
+			secret, err = vaultutil.Write(v.client, taskVault.Namespace, fmt.Sprintf("auth/token/create/%s", role), req)
+		} else {
+			secret, err = v.auth.CreateWithRole(req, role)
+		}
 	}
 
 	// Determine whether it is unrecoverable
@@ -1072,7 +1088,15 @@ func (v *vaultClient) parallelRevoke(ctx context.Context, accessors []*structs.V
 						return nil
 					}
 
-					if err := v.auth.RevokeAccessor(va.Accessor); err != nil {
+					var err error
+					if va.Namespace != "" {
+						_, err = vaultutil.Write(v.client, va.Namespace, "auth/token/revoke-accessor", map[string]interface{}{
+							"accessor": va.Accessor,
+						})
+					} else {
+						err = v.auth.RevokeAccessor(va.Accessor)
+					}
+					if err != nil {
 						return fmt.Errorf("failed to revoke token (alloc: %q, node: %q, task: %q): %v", va.AllocID, va.NodeID, va.Task, err)
 					}
 				case <-pCtx.Done():
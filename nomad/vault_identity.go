@@ -0,0 +1,198 @@
+package nomad
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+const (
+	// vaultIdentityIssuer is the issuer of the workload identities
+	vaultIdentityIssuer = "nomad"
+)
+
+// vaultIdentityHeader is the JOSE header of the workload identities.
+type vaultIdentityHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// vaultIdentityClaims are the claims of the workload identity of a task. The
+// roles of the Vault JWT auth backend can bind any of the nomad_ claims.
+type vaultIdentityClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	NotBefore int64  `json:"nbf"`
+	Expiry    int64  `json:"exp"`
+
+	Region    string `json:"nomad_region"`
+	JobID     string `json:"nomad_job_id"`
+	TaskGroup string `json:"nomad_task_group"`
+	AllocID   string `json:"nomad_allocation_id"`
+	Task      string `json:"nomad_task"`
+	Role      string `json:"nomad_vault_role"`
+}
+
+// vaultIdentitySigner signs the workload identities tasks exchange for Vault
+// tokens with the Vault JWT auth backend, so the Servers don't have to hold a
+// Vault token to derive task tokens from.
+type vaultIdentitySigner struct {
+	key      *rsa.PrivateKey
+	keyID    string
+	ttl      time.Duration
+	audience string
+	region   string
+}
+
+// newVaultIdentitySigner returns a signer using the signing key of the Vault
+// config.
+func newVaultIdentitySigner(vconf *config.VaultConfig, region string) (*vaultIdentitySigner, error) {
+	raw, err := ioutil.ReadFile(vconf.IdentitySigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity signing key: %v", err)
+	}
+	key, err := parseIdentitySigningKey(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := config.DefaultVaultIdentityTTL
+	if vconf.IdentityTTL != "" {
+		ttl, err = time.ParseDuration(vconf.IdentityTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IdentityTTL %q: %v", vconf.IdentityTTL, err)
+		}
+		if ttl <= 0 {
+			return nil, fmt.Errorf("IdentityTTL %q must be positive", vconf.IdentityTTL)
+		}
+	}
+
+	audience := vconf.IdentityAudience
+	if audience == "" {
+		audience = config.DefaultVaultIdentityAudience
+	}
+
+	// The key ID lets Vault pick the key when several are configured while
+	// rotating keys
+	keyID, err := identityKeyID(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vaultIdentitySigner{
+		key:      key,
+		keyID:    keyID,
+		ttl:      ttl,
+		audience: audience,
+		region:   region,
+	}, nil
+}
+
+// parseIdentitySigningKey parses a PEM-encoded PKCS #1 or PKCS #8 RSA private
+// key.
+func parseIdentitySigningKey(raw []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("identity signing key is not PEM-encoded")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("identity signing key must be an RSA key")
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported identity signing key type %q", block.Type)
+	}
+}
+
+// identityKeyID returns the ID of the signing key, derived from its public
+// key.
+func identityKeyID(key *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode identity public key: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// Sign returns the workload identity of the task of the allocation, valid
+// from now, as a JWT signed with RS256.
+func (s *vaultIdentitySigner) Sign(alloc *structs.Allocation, task *structs.Task, now time.Time) (string, error) {
+	claims := vaultIdentityClaims{
+		Issuer:    vaultIdentityIssuer,
+		Subject:   fmt.Sprintf("%s:%s:%s:%s", s.region, alloc.JobID, alloc.TaskGroup, task.Name),
+		Audience:  s.audience,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		Expiry:    now.Add(s.ttl).Unix(),
+		Region:    s.region,
+		JobID:     alloc.JobID,
+		TaskGroup: alloc.TaskGroup,
+		AllocID:   alloc.ID,
+		Task:      task.Name,
+	}
+	if task.Vault != nil {
+		claims.Role = task.Vault.Role
+	}
+	return signIdentity(s.key, s.keyID, claims)
+}
+
+// signIdentity returns the claims as a JWT signed with RS256 by the key.
+func signIdentity(key *rsa.PrivateKey, keyID string, claims interface{}) (string, error) {
+	header := vaultIdentityHeader{
+		Algorithm: "RS256",
+		Type:      "JWT",
+		KeyID:     keyID,
+	}
+	encodedHeader, err := encodeIdentitySegment(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encodeIdentitySegment(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := encodedHeader + "." + encodedClaims
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign identity: %v", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// encodeIdentitySegment encodes a segment of a JWT.
+func encodeIdentitySegment(v interface{}) (string, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode identity: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,133 @@
+package nomad
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/nomad/structs/config"
+)
+
+// testVaultIdentitySigner returns a signer using a generated key along with
+// the key.
+func testVaultIdentitySigner(t *testing.T) (*vaultIdentitySigner, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "nomad-identity")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "signing.pem")
+	raw := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	signer, err := newVaultIdentitySigner(&config.VaultConfig{
+		IdentitySigningKeyFile: path,
+		IdentityTTL:            "10m",
+	}, "global")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return signer, key
+}
+
+// testVerifyVaultIdentity verifies the signature of the identity and returns
+// its claims.
+func testVerifyVaultIdentity(t *testing.T, key *rsa.PrivateKey, jwt string) *vaultIdentityClaims {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("bad identity: %q", jwt)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Fatalf("bad signature: %v", err)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var claims vaultIdentityClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return &claims
+}
+
+func TestVaultIdentitySigner_Sign(t *testing.T) {
+	t.Parallel()
+	signer, key := testVaultIdentitySigner(t)
+
+	alloc := mock.Alloc()
+	task := alloc.Job.TaskGroups[0].Tasks[0]
+	task.Vault = &structs.Vault{Role: "web"}
+
+	now := time.Now()
+	jwt, err := signer.Sign(alloc, task, now)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	claims := testVerifyVaultIdentity(t, key, jwt)
+	expected := &vaultIdentityClaims{
+		Issuer:    vaultIdentityIssuer,
+		Subject:   "global:" + alloc.JobID + ":web:web",
+		Audience:  config.DefaultVaultIdentityAudience,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		Expiry:    now.Add(10 * time.Minute).Unix(),
+		Region:    "global",
+		JobID:     alloc.JobID,
+		TaskGroup: "web",
+		AllocID:   alloc.ID,
+		Task:      "web",
+		Role:      "web",
+	}
+	if *claims != *expected {
+		t.Fatalf("bad claims:\n%#v\n%#v", claims, expected)
+	}
+}
+
+func TestVaultIdentitySigner_BadKey(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "nomad-identity")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "signing.pem")
+	if err := ioutil.WriteFile(path, []byte("not a key"), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, err = newVaultIdentitySigner(&config.VaultConfig{IdentitySigningKeyFile: path}, "global")
+	if err == nil || !strings.Contains(err.Error(), "not PEM-encoded") {
+		t.Fatalf("expected PEM error: %v", err)
+	}
+}
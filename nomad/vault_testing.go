@@ -139,5 +139,7 @@ func (v *TestVaultClient) Stop()
 func (v *TestVaultClient) SetActive(enabled bool)                               {}
 func (v *TestVaultClient) SetConfig(config *config.VaultConfig) error           { return nil }
 func (v *TestVaultClient) Running() bool                                        { return true }
+func (v *TestVaultClient) Enabled() bool                                        { return true }
+func (v *TestVaultClient) ConnectionEstablished() (bool, error)                 { return true, nil }
 func (v *TestVaultClient) Stats() *VaultStats                                   { return new(VaultStats) }
 func (v *TestVaultClient) EmitStats(period time.Duration, stopCh chan struct{}) {}
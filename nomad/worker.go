@@ -65,6 +65,16 @@ type Worker struct {
 
 	evalToken string
 
+	// evalTriggeredBy is the trigger of the evaluation being processed. The
+	// placements of evaluations triggered by a job registration are measured
+	// from the time the job was submitted.
+	evalTriggeredBy string
+
+	// traceParent is the traceparent of the span of the evaluation being
+	// processed. Plans and evaluations created by the scheduler continue its
+	// trace.
+	traceParent string
+
 	// snapshotIndex is the index of the snapshot in which the scheduler was
 	// first envoked. It is used to mark the SnapshotIndex of evaluations
 	// Created, Updated or Reblocked.
@@ -263,6 +273,17 @@ func (w *Worker) invokeScheduler(eval *structs.Evaluation, token string) error {
 	defer metrics.MeasureSince([]string{"nomad", "worker", "invoke_scheduler", eval.Type}, time.Now())
 	// Store the evaluation token
 	w.evalToken = token
+	w.evalTriggeredBy = eval.TriggeredBy
+
+	// Trace the processing of the evaluation as part of the trace of the
+	// operation that created it
+	span := w.srv.config.Tracer.Start("scheduler.process", eval.TraceParent)
+	span.SetAttribute("eval_id", eval.ID)
+	span.SetAttribute("job_id", eval.JobID)
+	span.SetAttribute("eval_type", eval.Type)
+	span.SetAttribute("triggered_by", eval.TriggeredBy)
+	defer span.End()
+	w.traceParent = span.TraceParent()
 
 	// Snapshot the current state
 	snap, err := w.srv.fsm.State().Snapshot()
@@ -290,6 +311,7 @@ func (w *Worker) invokeScheduler(eval *structs.Evaluation, token string) error {
 	// Process the evaluation
 	err = sched.Process(eval)
 	if err != nil {
+		span.SetError(err)
 		return fmt.Errorf("failed to process evaluation: %v", err)
 	}
 	return nil
@@ -304,14 +326,20 @@ func (w *Worker) SubmitPlan(plan *structs.Plan) (*structs.PlanResult, scheduler.
 	}
 	defer metrics.MeasureSince([]string{"nomad", "worker", "submit_plan"}, time.Now())
 
+	span := w.srv.config.Tracer.Start("worker.submit_plan", w.traceParent)
+	span.SetAttribute("eval_id", plan.EvalID)
+	defer span.End()
+
 	// Add the evaluation token to the plan
 	plan.EvalToken = w.evalToken
+	plan.TraceParent = span.TraceParent()
 
 	// Setup the request
 	req := structs.PlanRequest{
 		Plan: plan,
 		WriteRequest: structs.WriteRequest{
-			Region: w.srv.config.Region,
+			Region:      w.srv.config.Region,
+			TraceParent: span.TraceParent(),
 		},
 	}
 	var resp structs.PlanResponse
@@ -324,6 +352,7 @@ SUBMIT:
 		if w.shouldResubmit(err) && !w.backoffErr(backoffBaselineSlow, backoffLimitSlow) {
 			goto SUBMIT
 		}
+		span.SetError(err)
 		return nil, nil, err
 	} else {
 		w.logger.Printf("[DEBUG] worker: submitted plan for evaluation %s", plan.EvalID)
@@ -336,6 +365,14 @@ SUBMIT:
 		return nil, nil, fmt.Errorf("missing result")
 	}
 
+	// Measure the time from the submission of the job to the placement of
+	// its allocations
+	if w.evalTriggeredBy == structs.EvalTriggerJobRegister && len(result.NodeAllocation) != 0 &&
+		plan.Job != nil && plan.Job.SubmitTime != 0 {
+		metrics.MeasureSince([]string{"nomad", "worker", "submit_to_placement", plan.Job.Type},
+			time.Unix(0, plan.Job.SubmitTime))
+	}
+
 	// Check if a state update is required. This could be required if we
 	// planning based on stale data, which is causing issues. For example, a
 	// node failure since the time we've started planning or conflicting task
@@ -410,6 +447,12 @@ func (w *Worker) CreateEval(eval *structs.Evaluation) error {
 	// Store the snapshot index in the eval
 	eval.SnapshotIndex = w.snapshotIndex
 
+	// Follow-up and blocked evaluations continue the trace of the evaluation
+	// that created them
+	if eval.TraceParent == "" {
+		eval.TraceParent = w.traceParent
+	}
+
 	// Setup the request
 	req := structs.EvalUpdateRequest{
 		Evals:     []*structs.Evaluation{eval},
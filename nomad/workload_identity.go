@@ -0,0 +1,138 @@
+package nomad
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/acl"
+	"github.com/hashicorp/nomad/nomad/state"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+const (
+	// workloadIdentityAudience is the audience of the workload identities
+	// tasks call the Nomad API with
+	workloadIdentityAudience = "nomadproject.io"
+)
+
+// workloadIdentityClaims are the claims of the workload identity of an
+// allocation
+type workloadIdentityClaims struct {
+	Issuer   string `json:"iss"`
+	Subject  string `json:"sub"`
+	Audience string `json:"aud"`
+	IssuedAt int64  `json:"iat"`
+
+	Region    string `json:"nomad_region"`
+	Namespace string `json:"nomad_namespace"`
+	JobID     string `json:"nomad_job_id"`
+	TaskGroup string `json:"nomad_task_group"`
+	AllocID   string `json:"nomad_allocation_id"`
+}
+
+// workloadIdentitySigner signs and verifies the workload identities the tasks
+// of the allocations call the Nomad API with. The identities don't expire,
+// they are valid as long as their allocation isn't terminal.
+type workloadIdentitySigner struct {
+	key    *rsa.PrivateKey
+	keyID  string
+	region string
+}
+
+// newWorkloadIdentitySigner returns a signer using the PEM-encoded signing
+// key, which must be the same on all the servers of the region.
+func newWorkloadIdentitySigner(raw []byte, region string) (*workloadIdentitySigner, error) {
+	key, err := parseIdentitySigningKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := identityKeyID(key)
+	if err != nil {
+		return nil, err
+	}
+	return &workloadIdentitySigner{
+		key:    key,
+		keyID:  keyID,
+		region: region,
+	}, nil
+}
+
+// Sign returns the workload identity of the allocation as a JWT signed with
+// RS256. It is issued at the creation of the allocation so that the
+// allocation has a single identity however many times it is signed.
+func (s *workloadIdentitySigner) Sign(alloc *structs.Allocation) (string, error) {
+	ns := allocNamespace(alloc)
+	claims := workloadIdentityClaims{
+		Issuer:    vaultIdentityIssuer,
+		Subject:   fmt.Sprintf("%s:%s:%s:%s:%s", s.region, ns, alloc.JobID, alloc.TaskGroup, alloc.ID),
+		Audience:  workloadIdentityAudience,
+		IssuedAt:  alloc.CreateTime / 1e9,
+		Region:    s.region,
+		Namespace: ns,
+		JobID:     alloc.JobID,
+		TaskGroup: alloc.TaskGroup,
+		AllocID:   alloc.ID,
+	}
+	return signIdentity(s.key, s.keyID, claims)
+}
+
+// Verify verifies the signature of the workload identity and returns its
+// claims.
+func (s *workloadIdentitySigner) Verify(raw string) (*workloadIdentityClaims, error) {
+	jwt, err := parseJWT(raw)
+	if err != nil {
+		return nil, err
+	}
+	if jwt.header.Algorithm != "RS256" || jwt.header.KeyID != s.keyID {
+		return nil, fmt.Errorf("workload identity not signed by this region")
+	}
+	if err := verifyJWTSignature(jwt.header.Algorithm, &s.key.PublicKey, jwt.signed, jwt.signature); err != nil {
+		return nil, fmt.Errorf("invalid workload identity signature")
+	}
+
+	// The claims were signed by the servers, so they are decoded as is
+	buf, err := json.Marshal(jwt.claims)
+	if err != nil {
+		return nil, err
+	}
+	var claims workloadIdentityClaims
+	if err := json.Unmarshal(buf, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Audience != workloadIdentityAudience || claims.Region != s.region {
+		return nil, fmt.Errorf("workload identity not issued for the Nomad API of this region")
+	}
+	return &claims, nil
+}
+
+// isWorkloadIdentity returns whether the secret ID of a request is a workload
+// identity rather than the secret ID of an ACL token
+func isWorkloadIdentity(secretID string) bool {
+	return strings.Count(secretID, ".") == 2
+}
+
+// resolveWorkloadIdentity resolves the workload identity to the ACL of its
+// job. The identity expires once its allocation is terminal.
+func (s *Server) resolveWorkloadIdentity(snap *state.StateSnapshot, secretID string) (*acl.ACL, error) {
+	if s.workloadIdentities == nil {
+		return nil, structs.ErrTokenNotFound
+	}
+	claims, err := s.workloadIdentities.Verify(secretID)
+	if err != nil {
+		return nil, structs.ErrTokenNotFound
+	}
+
+	alloc, err := snap.AllocByID(nil, claims.AllocID)
+	if err != nil {
+		return nil, err
+	}
+	if alloc == nil || alloc.TerminalStatus() {
+		return nil, structs.ErrTokenExpired
+	}
+	if alloc.JobID != claims.JobID || allocNamespace(alloc) != claims.Namespace {
+		return nil, structs.ErrTokenNotFound
+	}
+	return acl.NewWorkloadACL(claims.Namespace, claims.JobID), nil
+}
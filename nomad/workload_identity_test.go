@@ -0,0 +1,173 @@
+package nomad
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	msgpackrpc "github.com/hashicorp/net-rpc-msgpackrpc"
+	"github.com/hashicorp/nomad/nomad/mock"
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/nomad/testutil"
+)
+
+// testWorkloadIdentityKey returns a PEM-encoded workload identity signing key
+func testWorkloadIdentityKey(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestWorkloadIdentitySigner_SignVerify(t *testing.T) {
+	t.Parallel()
+	signer, err := newWorkloadIdentitySigner(testWorkloadIdentityKey(t), "global")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	alloc := mock.Alloc()
+	jwt, err := signer.Sign(alloc)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !isWorkloadIdentity(jwt) || isWorkloadIdentity(structs.GenerateUUID()) {
+		t.Fatalf("bad workload identity detection")
+	}
+
+	// The allocation has a single identity
+	again, err := signer.Sign(alloc)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if again != jwt {
+		t.Fatalf("identities differ:\n%s\n%s", jwt, again)
+	}
+
+	claims, err := signer.Verify(jwt)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if claims.AllocID != alloc.ID || claims.JobID != alloc.JobID || claims.Namespace != structs.DefaultNamespace {
+		t.Fatalf("bad claims: %#v", claims)
+	}
+
+	// Identities signed by another key or for another region are rejected
+	other, err := newWorkloadIdentitySigner(testWorkloadIdentityKey(t), "global")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := other.Verify(jwt); err == nil {
+		t.Fatalf("expected error verifying with another key")
+	}
+	signer.region = "east"
+	if _, err := signer.Verify(jwt); err == nil {
+		t.Fatalf("expected error verifying in another region")
+	}
+}
+
+func TestWorkloadIdentity_ResolveToken(t *testing.T) {
+	t.Parallel()
+	s1, root := testACLServer(t, func(c *Config) {
+		c.WorkloadIdentitySigningKey = testWorkloadIdentityKey(t)
+		c.VariablesEncryptionKey = make([]byte, VariablesKeySize)
+	})
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	testutil.WaitForLeader(t, s1.RPC)
+	state := s1.fsm.State()
+
+	alloc := mock.Alloc()
+	other := mock.Job()
+	if err := state.UpsertJob(10, alloc.Job); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertJob(11, other); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := state.UpsertAllocs(12, []*structs.Allocation{alloc}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	identity, err := s1.workloadIdentities.Sign(alloc)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Write the variables of both jobs
+	for _, path := range []string{"nomad/jobs/" + alloc.JobID + "/db", "nomad/jobs/" + other.ID} {
+		req := &structs.VariablesUpsertRequest{
+			Variable: &structs.Variable{
+				Path:  path,
+				Items: map[string]string{"password": "secret"},
+			},
+			WriteRequest: structs.WriteRequest{Region: "global", AuthToken: root.SecretID},
+		}
+		var resp structs.VariablesWriteResponse
+		if err := msgpackrpc.CallWithCodec(codec, "Variables.Upsert", req, &resp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// The identity reads its own job and its variables
+	get := &structs.JobSpecificRequest{
+		JobID:        alloc.JobID,
+		QueryOptions: structs.QueryOptions{Region: "global", AuthToken: identity},
+	}
+	var getResp structs.SingleJobResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.GetJob", get, &getResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if getResp.Job == nil || getResp.Job.ID != alloc.JobID {
+		t.Fatalf("bad job: %#v", getResp.Job)
+	}
+	read := &structs.VariablesReadRequest{
+		Path:         "nomad/jobs/" + alloc.JobID + "/db",
+		QueryOptions: structs.QueryOptions{Region: "global", AuthToken: identity},
+	}
+	var readResp structs.VariablesReadResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Variables.Read", read, &readResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if readResp.Variable == nil || readResp.Variable.Items["password"] != "secret" {
+		t.Fatalf("bad variable: %#v", readResp.Variable)
+	}
+
+	// But not the other job or its variables
+	get.JobID = other.ID
+	err = msgpackrpc.CallWithCodec(codec, "Job.GetJob", get, &getResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+	read.Path = "nomad/jobs/" + other.ID
+	err = msgpackrpc.CallWithCodec(codec, "Variables.Read", read, &readResp)
+	if err == nil || err.Error() != structs.ErrPermissionDenied.Error() {
+		t.Fatalf("expected permission denied: %v", err)
+	}
+
+	// Only its own job is listed
+	list := &structs.JobListRequest{
+		QueryOptions: structs.QueryOptions{Region: "global", AuthToken: identity},
+	}
+	var listResp structs.JobListResponse
+	if err := msgpackrpc.CallWithCodec(codec, "Job.List", list, &listResp); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(listResp.Jobs) != 1 || listResp.Jobs[0].ID != alloc.JobID {
+		t.Fatalf("bad jobs: %#v", listResp.Jobs)
+	}
+
+	// The identity expires with its allocation
+	stopped := alloc.Copy()
+	stopped.DesiredStatus = structs.AllocDesiredStatusStop
+	if err := state.UpsertAllocs(13, []*structs.Allocation{stopped}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	get.JobID = alloc.JobID
+	err = msgpackrpc.CallWithCodec(codec, "Job.GetJob", get, &getResp)
+	if err == nil || err.Error() != structs.ErrTokenExpired.Error() {
+		t.Fatalf("expected token expired: %v", err)
+	}
+}
@@ -142,6 +142,70 @@ func (c *DriverChecker) hasDrivers(option *structs.Node) bool {
 	return true
 }
 
+// NetworkChecker is a FeasibilityChecker which returns whether a node can set
+// up the network of a task group.
+type NetworkChecker struct {
+	ctx      Context
+	networks []*structs.NetworkResource
+}
+
+// NewNetworkChecker creates a NetworkChecker from the networks of a task group
+// and its tasks
+func NewNetworkChecker(ctx Context, networks []*structs.NetworkResource) *NetworkChecker {
+	return &NetworkChecker{
+		ctx:      ctx,
+		networks: networks,
+	}
+}
+
+func (c *NetworkChecker) SetNetworks(networks []*structs.NetworkResource) {
+	c.networks = networks
+}
+
+func (c *NetworkChecker) Feasible(option *structs.Node) bool {
+	// Group networks set up by CNI plugins need the network configuration,
+	// or the plugins of the bridge for the bridge mode, which the client
+	// fingerprints as node attributes
+	for _, n := range c.networks {
+		if n.HostNetwork != "" && !nodeHasHostNetwork(option, n.HostNetwork) {
+			c.ctx.Metrics().FilterNode(option, fmt.Sprintf("missing host network %q", n.HostNetwork))
+			return false
+		}
+
+		if n.Mode == structs.NetworkModeBridge {
+			if _, ok := option.Attributes[structs.NodeCNIBridgeAttribute]; !ok {
+				c.ctx.Metrics().FilterNode(option, "missing bridge network plugins")
+				return false
+			}
+			continue
+		}
+
+		name, ok := n.CNINetwork()
+		if !ok {
+			continue
+		}
+		if _, ok := option.Attributes[structs.NodeCNIConfigAttributePrefix+name]; !ok {
+			c.ctx.Metrics().FilterNode(option, fmt.Sprintf("missing CNI network %q", name))
+			return false
+		}
+	}
+	return true
+}
+
+// nodeHasHostNetwork returns whether the node has addresses in the named host
+// network.
+func nodeHasHostNetwork(node *structs.Node, name string) bool {
+	if node.Resources == nil {
+		return false
+	}
+	for _, n := range node.Resources.Networks {
+		if n.HostNetwork == name {
+			return true
+		}
+	}
+	return false
+}
+
 // DistinctHostsIterator is a FeasibleIterator which returns nodes that pass the
 // distinct_hosts constraint. The constraint ensures that multiple allocations
 // do not exist on the same node.
@@ -124,6 +124,66 @@ func TestDriverChecker(t *testing.T) {
 	}
 }
 
+func TestNetworkChecker(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*structs.Node{
+		mock.Node(),
+		mock.Node(),
+	}
+	nodes[0].Attributes["plugins.cni.config.mynet"] = "1"
+	nodes[1].Attributes["plugins.cni.bridge"] = "1"
+	nodes[1].Resources.Networks = append(nodes[1].Resources.Networks, &structs.NetworkResource{
+		HostNetwork: "public",
+		Device:      "eth1",
+		CIDR:        "203.0.113.10/32",
+		MBits:       1000,
+	})
+
+	cases := []struct {
+		Networks []*structs.NetworkResource
+		Results  []bool
+	}{
+		{
+			Networks: nil,
+			Results:  []bool{true, true},
+		},
+		{
+			Networks: []*structs.NetworkResource{{Mode: structs.NetworkModeHost}},
+			Results:  []bool{true, true},
+		},
+		{
+			Networks: []*structs.NetworkResource{{Mode: "cni/mynet"}},
+			Results:  []bool{true, false},
+		},
+		{
+			Networks: []*structs.NetworkResource{{Mode: "cni/other"}},
+			Results:  []bool{false, false},
+		},
+		{
+			Networks: []*structs.NetworkResource{{Mode: structs.NetworkModeBridge}},
+			Results:  []bool{false, true},
+		},
+		{
+			Networks: []*structs.NetworkResource{{HostNetwork: "public"}},
+			Results:  []bool{false, true},
+		},
+		{
+			Networks: []*structs.NetworkResource{{HostNetwork: "private"}},
+			Results:  []bool{false, false},
+		},
+	}
+
+	checker := NewNetworkChecker(ctx, nil)
+	for i, c := range cases {
+		checker.SetNetworks(c.Networks)
+		for j, node := range nodes {
+			if act := checker.Feasible(node); act != c.Results[j] {
+				t.Fatalf("case(%d) node %d failed: got %v; want %v", i, j, act, c.Results[j])
+			}
+		}
+	}
+}
+
 func TestConstraintChecker(t *testing.T) {
 	_, ctx := testContext(t)
 	nodes := []*structs.Node{
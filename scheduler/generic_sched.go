@@ -3,6 +3,7 @@ package scheduler
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	memdb "github.com/hashicorp/go-memdb"
@@ -31,6 +32,14 @@ const (
 	// allocLost is the status used when an allocation is lost
 	allocLost = "alloc is lost since its node is down"
 
+	// allocUnknown is the status used when an allocation is unknown since its
+	// node is disconnected
+	allocUnknown = "alloc is unknown since its node is disconnected"
+
+	// allocReconnected is the status used when an allocation is not needed
+	// after its client reconnected since another one replaced it
+	allocReconnected = "alloc not needed after its client reconnected"
+
 	// allocInPlace is the status used when speculating on an in-place update
 	allocInPlace = "alloc updating in-place"
 
@@ -113,7 +122,8 @@ func (s *GenericScheduler) Process(eval *structs.Evaluation) error {
 	case structs.EvalTriggerJobRegister, structs.EvalTriggerNodeUpdate,
 		structs.EvalTriggerJobDeregister, structs.EvalTriggerRollingUpdate,
 		structs.EvalTriggerPeriodicJob, structs.EvalTriggerMaxPlans,
-		structs.EvalTriggerDeploymentWatcher:
+		structs.EvalTriggerDeploymentWatcher, structs.EvalTriggerJobDependencies,
+		structs.EvalTriggerReconnect:
 	default:
 		desc := fmt.Sprintf("scheduler cannot handle '%s' evaluation reason",
 			eval.TriggeredBy)
@@ -122,6 +132,21 @@ func (s *GenericScheduler) Process(eval *structs.Evaluation) error {
 			s.deployment.GetID())
 	}
 
+	// Hold batch jobs until the jobs they depend on complete. The leader
+	// releases the evaluation once they do.
+	if s.batch {
+		pending, err := s.pendingDependencies()
+		if err != nil {
+			return err
+		}
+		if len(pending) != 0 {
+			desc := fmt.Sprintf("held until dependencies complete: %s", strings.Join(pending, ", "))
+			return setStatus(s.logger, s.planner, s.eval, s.nextEval, s.blocked,
+				s.failedTGAllocs, structs.EvalStatusHeld, desc, s.queuedAllocs,
+				s.deployment.GetID())
+		}
+	}
+
 	// Retry up to the maxScheduleAttempts and reset if progress is made.
 	progress := func() bool { return progressMade(s.planResult) }
 	limit := maxServiceScheduleAttempts
@@ -162,6 +187,49 @@ func (s *GenericScheduler) Process(eval *structs.Evaluation) error {
 		s.deployment.GetID())
 }
 
+// pendingDependencies returns the jobs the job depends on that haven't
+// completed, along with their status. Jobs whose current version was already
+// placed aren't held again.
+func (s *GenericScheduler) pendingDependencies() ([]string, error) {
+	ws := memdb.NewWatchSet()
+	job, err := s.state.JobByID(ws, s.eval.JobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job %q: %v", s.eval.JobID, err)
+	}
+	if job == nil || job.Stopped() || len(job.DependsOn) == 0 {
+		return nil, nil
+	}
+
+	allocs, err := s.state.AllocsByJob(ws, job.ID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allocs for job %q: %v", job.ID, err)
+	}
+	for _, alloc := range allocs {
+		if alloc.Job != nil && alloc.Job.JobModifyIndex == job.JobModifyIndex {
+			return nil, nil
+		}
+	}
+
+	var pending []string
+	for _, id := range job.DependsOn {
+		dep, err := s.state.JobByID(ws, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get job %q: %v", id, err)
+		}
+		var depAllocs []*structs.Allocation
+		if dep != nil {
+			depAllocs, err = s.state.AllocsByJob(ws, id, false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get allocs for job %q: %v", id, err)
+			}
+		}
+		if status := structs.JobDependencyStatus(dep, depAllocs); status != structs.JobDependencyStatusComplete {
+			pending = append(pending, fmt.Sprintf("%s (%s)", id, status))
+		}
+	}
+	return pending, nil
+}
+
 // createBlockedEval creates a blocked eval and submits it to the planner. If
 // failure is set to true, the eval's trigger reason reflects that.
 func (s *GenericScheduler) createBlockedEval(planFailure bool) error {
@@ -243,20 +311,21 @@ func (s *GenericScheduler) process() (bool, error) {
 		s.logger.Printf("[DEBUG] sched: %#v: failed to place all allocations, blocked eval '%s' created", s.eval, s.blocked.ID)
 	}
 
-	// If the plan is a no-op, we can bail. If AnnotatePlan is set submit the plan
-	// anyways to get the annotations.
-	if s.plan.IsNoOp() && !s.eval.AnnotatePlan {
-		return true, nil
-	}
-
-	// If we need a followup eval and we haven't created one, do so.
+	// If we need a followup eval and we haven't created one, do so, even if
+	// the plan is a no-op.
 	if s.followupEvalWait != 0 && s.nextEval == nil {
 		s.nextEval = s.eval.NextRollingEval(s.followupEvalWait)
 		if err := s.planner.CreateEval(s.nextEval); err != nil {
-			s.logger.Printf("[ERR] sched: %#v failed to make next eval for rolling migration: %v", s.eval, err)
+			s.logger.Printf("[ERR] sched: %#v failed to make followup eval: %v", s.eval, err)
 			return false, err
 		}
-		s.logger.Printf("[DEBUG] sched: %#v: rolling migration limit reached, next eval '%s' created", s.eval, s.nextEval.ID)
+		s.logger.Printf("[DEBUG] sched: %#v: followup eval '%s' created in %v", s.eval, s.nextEval.ID, s.followupEvalWait)
+	}
+
+	// If the plan is a no-op, we can bail. If AnnotatePlan is set submit the plan
+	// anyways to get the annotations.
+	if s.plan.IsNoOp() && !s.eval.AnnotatePlan {
+		return true, nil
 	}
 
 	// Submit the plan and store the results.
@@ -415,6 +484,11 @@ func (s *GenericScheduler) computeJobAllocs() error {
 		s.plan.AppendUpdate(stop.alloc, structs.AllocDesiredStatusStop, stop.statusDescription, stop.clientStatus)
 	}
 
+	// Mark the allocations of disconnected clients unknown
+	for _, alloc := range results.disconnectUpdates {
+		s.plan.AppendUpdate(alloc, structs.AllocDesiredStatusRun, allocUnknown, structs.AllocClientStatusUnknown)
+	}
+
 	// Handle the in-place updates
 	for _, update := range results.inplaceUpdate {
 		if update.DeploymentID != s.deployment.GetID() {
@@ -529,7 +603,8 @@ func (s *GenericScheduler) computePlacements(destructive, place []placementResul
 					ClientStatus:  structs.AllocClientStatusPending,
 
 					SharedResources: &structs.Resources{
-						DiskMB: tg.EphemeralDisk.SizeMB,
+						DiskMB:   tg.EphemeralDisk.SizeMB,
+						Networks: option.GroupNetworks,
 					},
 				}
 
@@ -539,12 +614,22 @@ func (s *GenericScheduler) computePlacements(destructive, place []placementResul
 					alloc.PreviousAllocation = prev.ID
 				}
 
+				// Record the allocation of a disconnected client it replaces
+				// to reconcile them if the client reconnects
+				if p, ok := missing.(allocPlaceResult); ok && p.replacedAlloc != nil {
+					alloc.ReplacedAllocation = p.replacedAlloc.ID
+				}
+
 				// If we are placing a canary and we found a match, add the canary
-				// to the deployment state object.
+				// to the deployment state object and mark the allocation as a
+				// canary.
 				if missing.Canary() {
 					if state, ok := s.deployment.TaskGroups[tg.Name]; ok {
 						state.PlacedCanaries = append(state.PlacedCanaries, alloc.ID)
 					}
+					alloc.DeploymentStatus = &structs.AllocDeploymentStatus{
+						Canary: true,
+					}
 				}
 
 				// Track the placement
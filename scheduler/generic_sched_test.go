@@ -2164,7 +2164,7 @@ func TestServiceSched_NodeDown(t *testing.T) {
 	}
 
 	// Mark the node as down
-	noErr(t, h.State.UpdateNodeStatus(h.NextIndex(), node.ID, structs.NodeStatusDown))
+	noErr(t, h.State.UpdateNodeStatus(h.NextIndex(), node.ID, structs.NodeStatusDown, 0))
 
 	// Create a mock evaluation to deal with drain
 	eval := &structs.Evaluation{
@@ -3306,3 +3306,69 @@ func TestServiceSched_CancelDeployment_NewerJob(t *testing.T) {
 
 	h.AssertEvalStatus(t, structs.EvalStatusComplete)
 }
+
+func TestBatchSched_Run_HeldDependencies(t *testing.T) {
+	h := NewHarness(t)
+
+	// Create some nodes
+	for i := 0; i < 10; i++ {
+		node := mock.Node()
+		noErr(t, h.State.UpsertNode(h.NextIndex(), node))
+	}
+
+	// Create a job and the job it depends on
+	dep := mock.Job()
+	dep.Type = structs.JobTypeBatch
+	noErr(t, h.State.UpsertJob(h.NextIndex(), dep))
+
+	job := mock.Job()
+	job.Type = structs.JobTypeBatch
+	job.DependsOn = []string{dep.ID}
+	noErr(t, h.State.UpsertJob(h.NextIndex(), job))
+
+	// Create a mock evaluation to register the job
+	eval := &structs.Evaluation{
+		ID:          structs.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerJobRegister,
+		JobID:       job.ID,
+	}
+
+	// Process the evaluation
+	err := h.Process(NewBatchScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure the job is held without a plan
+	if len(h.Plans) != 0 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+	h.AssertEvalStatus(t, structs.EvalStatusHeld)
+
+	// Complete the job it depends on
+	alloc := mock.Alloc()
+	alloc.Job = dep
+	alloc.JobID = dep.ID
+	alloc.ClientStatus = structs.AllocClientStatusComplete
+	noErr(t, h.State.UpsertAllocs(h.NextIndex(), []*structs.Allocation{alloc}))
+
+	// Process the evaluation releasing the job
+	h.Evals = nil
+	eval = &structs.Evaluation{
+		ID:          structs.GenerateUUID(),
+		Priority:    job.Priority,
+		TriggeredBy: structs.EvalTriggerJobDependencies,
+		JobID:       job.ID,
+	}
+	err = h.Process(NewBatchScheduler, eval)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Ensure the job is placed
+	if len(h.Plans) != 1 {
+		t.Fatalf("bad: %#v", h.Plans)
+	}
+	h.AssertEvalStatus(t, structs.EvalStatusComplete)
+}
@@ -14,6 +14,10 @@ type RankedNode struct {
 	Score         float64
 	TaskResources map[string]*structs.Resources
 
+	// GroupNetworks are the networks of the task group with their ports
+	// assigned on the node.
+	GroupNetworks []*structs.NetworkResource
+
 	// Allocs is used to cache the proposed allocations on the
 	// node. This can be shared between iterators that require it.
 	Proposed []*structs.Allocation
@@ -136,16 +140,26 @@ type BinPackIterator struct {
 	evict     bool
 	priority  int
 	taskGroup *structs.TaskGroup
+	scoreFit  func(*structs.Node, *structs.Resources) float64
 }
 
 // NewBinPackIterator returns a BinPackIterator which tries to fit tasks
-// potentially evicting other tasks based on a given priority.
-func NewBinPackIterator(ctx Context, source RankIterator, evict bool, priority int) *BinPackIterator {
+// potentially evicting other tasks based on a given priority. The scheduler
+// configuration determines whether the fittest nodes are the most utilized
+// ones, or the least utilized ones with the spread algorithm.
+func NewBinPackIterator(ctx Context, source RankIterator, evict bool, priority int,
+	schedConfig *structs.SchedulerConfiguration) *BinPackIterator {
+	scoreFn := structs.ScoreFit
+	if schedConfig.EffectiveSchedulerAlgorithm() == structs.SchedulerAlgorithmSpread {
+		scoreFn = structs.ScoreFitSpread
+	}
+
 	iter := &BinPackIterator{
 		ctx:      ctx,
 		source:   source,
 		evict:    evict,
 		priority: priority,
+		scoreFit: scoreFn,
 	}
 	return iter
 }
@@ -185,6 +199,30 @@ OUTER:
 		total := &structs.Resources{
 			DiskMB: iter.taskGroup.EphemeralDisk.SizeMB,
 		}
+
+		// Assign the ports and bandwidth of the group network
+		var groupNetworks []*structs.NetworkResource
+		for _, n := range iter.taskGroup.Networks {
+			if len(n.ReservedPorts) == 0 && len(n.DynamicPorts) == 0 && n.MBits == 0 {
+				continue
+			}
+			offer, err := netIdx.AssignNetwork(n.Copy())
+			if offer == nil {
+				iter.ctx.Metrics().ExhaustedNode(option.Node,
+					fmt.Sprintf("network: %s", err))
+				netIdx.Release()
+				continue OUTER
+			}
+
+			// Reserve this to prevent a task from colliding
+			netIdx.AddReserved(offer)
+
+			offer.Mode = n.Mode
+			groupNetworks = append(groupNetworks, offer)
+			total.Add(&structs.Resources{Networks: []*structs.NetworkResource{offer}})
+		}
+		option.GroupNetworks = groupNetworks
+
 		for _, task := range iter.taskGroup.Tasks {
 			taskResources := task.Resources.Copy()
 
@@ -230,7 +268,7 @@ OUTER:
 		// carefully.
 
 		// Score the fit normally otherwise
-		fitness := structs.ScoreFit(option.Node, util)
+		fitness := iter.scoreFit(option.Node, util)
 		option.Score += fitness
 		iter.ctx.Metrics().ScoreNode(option.Node, "binpack", fitness)
 		return option
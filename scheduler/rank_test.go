@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/hashicorp/nomad/nomad/mock"
@@ -80,7 +81,7 @@ func TestBinPackIterator_NoExistingAlloc(t *testing.T) {
 			},
 		},
 	}
-	binp := NewBinPackIterator(ctx, static, false, 0)
+	binp := NewBinPackIterator(ctx, static, false, 0, nil)
 	binp.SetTaskGroup(taskGroup)
 
 	out := collectRanked(binp)
@@ -99,6 +100,66 @@ func TestBinPackIterator_NoExistingAlloc(t *testing.T) {
 	}
 }
 
+func TestBinPackIterator_Spread(t *testing.T) {
+	_, ctx := testContext(t)
+	nodes := []*RankedNode{
+		&RankedNode{
+			Node: &structs.Node{
+				// Perfect fit
+				Resources: &structs.Resources{
+					CPU:      2048,
+					MemoryMB: 2048,
+				},
+				Reserved: &structs.Resources{
+					CPU:      1024,
+					MemoryMB: 1024,
+				},
+			},
+		},
+		&RankedNode{
+			Node: &structs.Node{
+				// 25% fit
+				Resources: &structs.Resources{
+					CPU:      4096,
+					MemoryMB: 4096,
+				},
+			},
+		},
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	taskGroup := &structs.TaskGroup{
+		EphemeralDisk: &structs.EphemeralDisk{},
+		Tasks: []*structs.Task{
+			{
+				Name: "web",
+				Resources: &structs.Resources{
+					CPU:      1024,
+					MemoryMB: 1024,
+				},
+			},
+		},
+	}
+	schedConfig := &structs.SchedulerConfiguration{
+		SchedulerAlgorithm: structs.SchedulerAlgorithmSpread,
+	}
+	binp := NewBinPackIterator(ctx, static, false, 0, schedConfig)
+	binp.SetTaskGroup(taskGroup)
+
+	out := collectRanked(binp)
+	if len(out) != 2 {
+		t.Fatalf("Bad: %v", out)
+	}
+
+	// The least utilized node scores the highest
+	if out[0].Score != 0 {
+		t.Fatalf("Bad: %v", out[0])
+	}
+	if out[1].Score <= out[0].Score {
+		t.Fatalf("Bad: %v", out[1])
+	}
+}
+
 func TestBinPackIterator_PlannedAlloc(t *testing.T) {
 	_, ctx := testContext(t)
 	nodes := []*RankedNode{
@@ -159,7 +220,7 @@ func TestBinPackIterator_PlannedAlloc(t *testing.T) {
 		},
 	}
 
-	binp := NewBinPackIterator(ctx, static, false, 0)
+	binp := NewBinPackIterator(ctx, static, false, 0, nil)
 	binp.SetTaskGroup(taskGroup)
 
 	out := collectRanked(binp)
@@ -247,7 +308,7 @@ func TestBinPackIterator_ExistingAlloc(t *testing.T) {
 			},
 		},
 	}
-	binp := NewBinPackIterator(ctx, static, false, 0)
+	binp := NewBinPackIterator(ctx, static, false, 0, nil)
 	binp.SetTaskGroup(taskGroup)
 
 	out := collectRanked(binp)
@@ -262,6 +323,190 @@ func TestBinPackIterator_ExistingAlloc(t *testing.T) {
 	}
 }
 
+func TestBinPackIterator_GroupNetwork(t *testing.T) {
+	state, ctx := testContext(t)
+	nodes := []*RankedNode{}
+	for i := 0; i < 2; i++ {
+		nodes = append(nodes, &RankedNode{
+			Node: &structs.Node{
+				ID: structs.GenerateUUID(),
+				Resources: &structs.Resources{
+					CPU:      2048,
+					MemoryMB: 2048,
+					Networks: []*structs.NetworkResource{
+						{
+							Device: "eth0",
+							CIDR:   fmt.Sprintf("192.168.0.%d/32", 100+i),
+							MBits:  100,
+						},
+					},
+				},
+			},
+		})
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	// The group network of an existing allocation uses the static port
+	j := mock.Job()
+	alloc := &structs.Allocation{
+		ID:     structs.GenerateUUID(),
+		EvalID: structs.GenerateUUID(),
+		NodeID: nodes[0].Node.ID,
+		JobID:  j.ID,
+		Job:    j,
+		Resources: &structs.Resources{
+			CPU:      512,
+			MemoryMB: 512,
+		},
+		SharedResources: &structs.Resources{
+			Networks: []*structs.NetworkResource{
+				{
+					Mode:          structs.NetworkModeBridge,
+					Device:        "eth0",
+					IP:            "192.168.0.100",
+					ReservedPorts: []structs.Port{{Label: "http", Value: 8080, To: 80}},
+				},
+			},
+		},
+		DesiredStatus: structs.AllocDesiredStatusRun,
+		ClientStatus:  structs.AllocClientStatusPending,
+		TaskGroup:     "web",
+	}
+	noErr(t, state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID)))
+	noErr(t, state.UpsertAllocs(1000, []*structs.Allocation{alloc}))
+
+	taskGroup := &structs.TaskGroup{
+		EphemeralDisk: &structs.EphemeralDisk{},
+		Networks: []*structs.NetworkResource{
+			{
+				Mode:          structs.NetworkModeBridge,
+				ReservedPorts: []structs.Port{{Label: "http", Value: 8080, To: 80}},
+				DynamicPorts:  []structs.Port{{Label: "admin", To: 9000}},
+			},
+		},
+		Tasks: []*structs.Task{
+			{
+				Name: "web",
+				Resources: &structs.Resources{
+					CPU:      1024,
+					MemoryMB: 1024,
+				},
+			},
+		},
+	}
+	binp := NewBinPackIterator(ctx, static, false, 0, nil)
+	binp.SetTaskGroup(taskGroup)
+
+	out := collectRanked(binp)
+	if len(out) != 1 || out[0] != nodes[1] {
+		t.Fatalf("Bad: %#v", out)
+	}
+	if len(out[0].GroupNetworks) != 1 {
+		t.Fatalf("Bad: %#v", out[0].GroupNetworks)
+	}
+	n := out[0].GroupNetworks[0]
+	if n.Mode != structs.NetworkModeBridge || n.IP != "192.168.0.101" {
+		t.Fatalf("Bad: %#v", n)
+	}
+	if len(n.ReservedPorts) != 1 || n.ReservedPorts[0] != taskGroup.Networks[0].ReservedPorts[0] {
+		t.Fatalf("Bad: %#v", n.ReservedPorts)
+	}
+	if len(n.DynamicPorts) != 1 || n.DynamicPorts[0].Value == 0 || n.DynamicPorts[0].To != 9000 {
+		t.Fatalf("Bad: %#v", n.DynamicPorts)
+	}
+
+	// The ask isn't modified
+	if taskGroup.Networks[0].DynamicPorts[0].Value != 0 {
+		t.Fatalf("Bad: %#v", taskGroup.Networks[0])
+	}
+}
+
+func TestBinPackIterator_GroupNetwork_Bandwidth(t *testing.T) {
+	state, ctx := testContext(t)
+	nodes := []*RankedNode{}
+	for i := 0; i < 2; i++ {
+		nodes = append(nodes, &RankedNode{
+			Node: &structs.Node{
+				ID: structs.GenerateUUID(),
+				Resources: &structs.Resources{
+					CPU:      2048,
+					MemoryMB: 2048,
+					Networks: []*structs.NetworkResource{
+						{
+							Device: "eth0",
+							CIDR:   fmt.Sprintf("192.168.0.%d/32", 100+i),
+							MBits:  100,
+						},
+					},
+				},
+			},
+		})
+	}
+	static := NewStaticRankIterator(ctx, nodes)
+
+	// The group network of an existing allocation uses most of the link
+	j := mock.Job()
+	alloc := &structs.Allocation{
+		ID:     structs.GenerateUUID(),
+		EvalID: structs.GenerateUUID(),
+		NodeID: nodes[0].Node.ID,
+		JobID:  j.ID,
+		Job:    j,
+		Resources: &structs.Resources{
+			CPU:      512,
+			MemoryMB: 512,
+		},
+		SharedResources: &structs.Resources{
+			Networks: []*structs.NetworkResource{
+				{
+					Mode:   structs.NetworkModeBridge,
+					Device: "eth0",
+					IP:     "192.168.0.100",
+					MBits:  60,
+				},
+			},
+		},
+		DesiredStatus: structs.AllocDesiredStatusRun,
+		ClientStatus:  structs.AllocClientStatusPending,
+		TaskGroup:     "web",
+	}
+	noErr(t, state.UpsertJobSummary(999, mock.JobSummary(alloc.JobID)))
+	noErr(t, state.UpsertAllocs(1000, []*structs.Allocation{alloc}))
+
+	// The bandwidth of a group network is booked even without ports
+	taskGroup := &structs.TaskGroup{
+		EphemeralDisk: &structs.EphemeralDisk{},
+		Networks: []*structs.NetworkResource{
+			{
+				Mode:  structs.NetworkModeBridge,
+				MBits: 50,
+			},
+		},
+		Tasks: []*structs.Task{
+			{
+				Name: "web",
+				Resources: &structs.Resources{
+					CPU:      1024,
+					MemoryMB: 1024,
+				},
+			},
+		},
+	}
+	binp := NewBinPackIterator(ctx, static, false, 0, nil)
+	binp.SetTaskGroup(taskGroup)
+
+	out := collectRanked(binp)
+	if len(out) != 1 || out[0] != nodes[1] {
+		t.Fatalf("Bad: %#v", out)
+	}
+	if len(out[0].GroupNetworks) != 1 || out[0].GroupNetworks[0].MBits != 50 {
+		t.Fatalf("Bad: %#v", out[0].GroupNetworks)
+	}
+	if ctx.Metrics().DimensionExhausted["network: bandwidth exceeded"] != 1 {
+		t.Fatalf("Bad: %#v", ctx.Metrics().DimensionExhausted)
+	}
+}
+
 func TestBinPackIterator_ExistingAlloc_PlannedEvict(t *testing.T) {
 	state, ctx := testContext(t)
 	nodes := []*RankedNode{
@@ -339,7 +584,7 @@ func TestBinPackIterator_ExistingAlloc_PlannedEvict(t *testing.T) {
 		},
 	}
 
-	binp := NewBinPackIterator(ctx, static, false, 0)
+	binp := NewBinPackIterator(ctx, static, false, 0, nil)
 	binp.SetTaskGroup(taskGroup)
 
 	out := collectRanked(binp)
@@ -55,6 +55,9 @@ type allocReconciler struct {
 	// taintedNodes contains a map of nodes that are tainted
 	taintedNodes map[string]*structs.Node
 
+	// now is the time at which the reconciliation happens
+	now time.Time
+
 	// existingAllocs is non-terminal existing allocations
 	existingAllocs []*structs.Allocation
 
@@ -93,11 +96,15 @@ type reconcileResults struct {
 	// followupEvalWait is set if there should be a followup eval run after the
 	// given duration
 	followupEvalWait time.Duration
+
+	// disconnectUpdates is the set of allocations of disconnected clients to
+	// mark unknown
+	disconnectUpdates []*structs.Allocation
 }
 
 func (r *reconcileResults) GoString() string {
-	base := fmt.Sprintf("Total changes: (place %d) (destructive %d) (inplace %d) (stop %d)",
-		len(r.place), len(r.destructiveUpdate), len(r.inplaceUpdate), len(r.stop))
+	base := fmt.Sprintf("Total changes: (place %d) (destructive %d) (inplace %d) (stop %d) (unknown %d)",
+		len(r.place), len(r.destructiveUpdate), len(r.inplaceUpdate), len(r.stop), len(r.disconnectUpdates))
 
 	if r.deployment != nil {
 		base += fmt.Sprintf("\nCreated Deployment: %q", r.deployment.ID)
@@ -135,6 +142,7 @@ func NewAllocReconciler(logger *log.Logger, allocUpdateFn allocUpdateType, batch
 		deployment:     deployment.Copy(),
 		existingAllocs: existingAllocs,
 		taintedNodes:   taintedNodes,
+		now:            time.Now(),
 		result: &reconcileResults{
 			desiredTGUpdates: make(map[string]*structs.DesiredUpdates),
 		},
@@ -287,12 +295,11 @@ func (a *allocReconciler) computeGroup(group string, all allocSet) bool {
 		dstate, existingDeployment = a.deployment.TaskGroups[group]
 	}
 	if !existingDeployment {
-		autorevert := false
-		if tg.Update != nil && tg.Update.AutoRevert {
-			autorevert = true
-		}
-		dstate = &structs.DeploymentState{
-			AutoRevert: autorevert,
+		dstate = &structs.DeploymentState{}
+		if tg.Update != nil {
+			dstate.AutoRevert = tg.Update.AutoRevert
+			dstate.AutoPromote = tg.Update.AutoPromote
+			dstate.ProgressDeadline = tg.Update.ProgressDeadline
 		}
 	}
 
@@ -301,9 +308,35 @@ func (a *allocReconciler) computeGroup(group string, all allocSet) bool {
 	// Determine what set of allocations are on tainted nodes
 	untainted, migrate, lost := all.filterByTainted(a.taintedNodes)
 
+	// Leave the allocations of clients that lost contact with the servers
+	// until the clients stop them and check them again then
+	lost, disconnecting, wait := lost.filterByClientDisconnect(tg.StopAfterClientDisconnect, a.taintedNodes, a.now)
+	desiredChanges.Ignore += uint64(len(disconnecting))
+	a.followupIn(wait)
+
+	// The allocations of clients disconnected for less than the max client
+	// disconnect are unknown rather than lost. Mark them and replace the ones
+	// that weren't already, and check them again once they are lost.
+	lost, unknown, wait := lost.filterByClientDisconnect(tg.MaxClientDisconnect, a.taintedNodes, a.now)
+	_, unreplaced := unknown.filterByReplaced(all)
+	for _, alloc := range unknown {
+		if alloc.ClientStatus != structs.AllocClientStatusUnknown {
+			a.result.disconnectUpdates = append(a.result.disconnectUpdates, alloc)
+		}
+	}
+	desiredChanges.Ignore += uint64(len(unknown))
+	a.followupIn(wait)
+
+	// Reconcile the allocations of clients that reconnected with their
+	// replacements
+	untainted, reconnectStop, reconnectIgnore := a.computeReconnecting(untainted, all)
+	desiredChanges.Stop += uint64(len(reconnectStop))
+	desiredChanges.Ignore += uint64(len(reconnectIgnore))
+
 	// Create a structure for choosing names. Seed with the taken names which is
-	// the union of untainted and migrating nodes (includes canaries)
-	nameIndex := newAllocNameIndex(a.jobID, group, tg.Count, untainted.union(migrate))
+	// the union of untainted, migrating, disconnecting and unreplaced unknown
+	// nodes (includes canaries)
+	nameIndex := newAllocNameIndex(a.jobID, group, tg.Count, untainted.union(migrate, disconnecting, unreplaced))
 
 	// Stop any unneeded allocations and update the untainted set to not
 	// included stopped allocations.
@@ -360,11 +393,22 @@ func (a *allocReconciler) computeGroup(group string, all allocSet) bool {
 	// * The deployment is not paused or failed
 	// * Not placing any canaries
 	// * If there are any canaries that they have been promoted
-	place := a.computePlacements(tg, nameIndex, untainted, migrate)
+	place := a.computePlacements(tg, nameIndex, untainted.union(disconnecting, unreplaced), migrate)
 	if !existingDeployment {
 		dstate.DesiredTotal += len(place)
 	}
 
+	// Replace the unknown allocations like lost ones regardless of the
+	// deployment
+	desiredChanges.Place += uint64(len(unreplaced))
+	for _, alloc := range unreplaced.nameOrder() {
+		a.result.place = append(a.result.place, allocPlaceResult{
+			name:          alloc.Name,
+			taskGroup:     tg,
+			replacedAlloc: alloc,
+		})
+	}
+
 	// deploymentPlaceReady tracks whether the deployment is in a state where
 	// placements can be made without any other consideration.
 	deploymentPlaceReady := !a.deploymentPaused && !a.deploymentFailed && !canaryState
@@ -481,6 +525,86 @@ func (a *allocReconciler) computeGroup(group string, all allocSet) bool {
 	return deploymentComplete
 }
 
+// followupIn makes sure there is a followup eval within the given duration, if
+// it is set.
+func (a *allocReconciler) followupIn(wait time.Duration) {
+	if wait != 0 && (a.result.followupEvalWait == 0 || wait < a.result.followupEvalWait) {
+		a.result.followupEvalWait = wait
+	}
+}
+
+// computeReconnecting reconciles the allocations of clients that reconnected
+// with the allocations that replaced them while the clients were
+// disconnected. The better allocation of each pair is kept and the other one
+// is stopped. The allocations whose client didn't report their status since
+// reconnecting are ignored until it does. The untainted allocations to keep
+// are returned with the stopped and ignored ones.
+func (a *allocReconciler) computeReconnecting(untainted, all allocSet) (keep, stop, ignore allocSet) {
+	keep = untainted.union()
+	stop = make(map[string]*structs.Allocation)
+	ignore = make(map[string]*structs.Allocation)
+	for _, replacement := range untainted {
+		if replacement.ReplacedAllocation == "" || replacement.TerminalStatus() {
+			continue
+		}
+
+		// Follow the chain of replacements back to an allocation whose
+		// client reconnected
+		var original *structs.Allocation
+		id := replacement.ReplacedAllocation
+		for i := 0; i < len(all) && id != ""; i++ {
+			if alloc, ok := untainted[id]; ok {
+				original = alloc
+				break
+			}
+			alloc, ok := all[id]
+			if !ok {
+				break
+			}
+			id = alloc.ReplacedAllocation
+		}
+		if original == nil || original.TerminalStatus() {
+			continue
+		}
+
+		if original.ClientStatus == structs.AllocClientStatusUnknown {
+			ignore[original.ID] = original
+			delete(keep, original.ID)
+			continue
+		}
+
+		_, other := pickReconnected(original, replacement)
+		if _, ok := stop[other.ID]; ok {
+			continue
+		}
+		stop[other.ID] = other
+		delete(keep, other.ID)
+		a.result.stop = append(a.result.stop, allocStopResult{
+			alloc:             other,
+			statusDescription: allocReconnected,
+		})
+	}
+	return
+}
+
+// pickReconnected picks which of an allocation whose client reconnected and
+// its replacement to keep. It prefers the allocation of the latest version of
+// the job, then a running one, and then the original one which may hold data.
+func pickReconnected(original, replacement *structs.Allocation) (keep, stop *structs.Allocation) {
+	if original.Job.Version != replacement.Job.Version {
+		if original.Job.Version > replacement.Job.Version {
+			return original, replacement
+		}
+		return replacement, original
+	}
+
+	if original.ClientStatus != structs.AllocClientStatusRunning &&
+		replacement.ClientStatus == structs.AllocClientStatusRunning {
+		return replacement, original
+	}
+	return original, replacement
+}
+
 // handleGroupCanaries handles the canaries for the group by stopping the
 // unneeded ones and returning the current set of canaries and the updated total
 // set of allocs for the group
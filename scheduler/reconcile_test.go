@@ -731,6 +731,218 @@ func TestReconciler_LostNode(t *testing.T) {
 	assertNamesHaveIndexes(t, intRange(0, 1), placeResultsToNames(r.place))
 }
 
+// Tests the reconciler waits for the clients that lost contact with the servers
+// to stop their allocations before replacing them
+func TestReconciler_LostNode_StopAfterClientDisconnect(t *testing.T) {
+	job := mock.Job()
+	job.TaskGroups[0].StopAfterClientDisconnect = helper.TimeToPtr(time.Minute)
+
+	// Create 10 existing allocations
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = structs.GenerateUUID()
+		alloc.Name = structs.AllocName(job.ID, job.TaskGroups[0].Name, uint(i))
+		allocs = append(allocs, alloc)
+	}
+
+	// Build a map of tainted nodes, the first went down a while ago and the
+	// second just now
+	now := time.Unix(time.Now().Unix(), 0)
+	downSince := []time.Duration{2 * time.Minute, 30 * time.Second}
+	tainted := make(map[string]*structs.Node, 2)
+	for i := 0; i < 2; i++ {
+		n := mock.Node()
+		n.ID = allocs[i].NodeID
+		n.Status = structs.NodeStatusDown
+		n.StatusUpdatedAt = now.Add(-downSince[i]).Unix()
+		tainted[n.ID] = n
+	}
+
+	reconciler := NewAllocReconciler(testLogger(), allocUpdateFnIgnore, false, job.ID, job, nil, allocs, tainted)
+	reconciler.now = now
+	r := reconciler.Compute()
+
+	// Assert the correct results
+	assertResults(t, r, &resultExpectation{
+		createDeployment:  nil,
+		deploymentUpdates: nil,
+		place:             1,
+		inplace:           0,
+		stop:              1,
+		desiredTGUpdates: map[string]*structs.DesiredUpdates{
+			job.TaskGroups[0].Name: {
+				Place:  1,
+				Stop:   1,
+				Ignore: 9,
+			},
+		},
+		followupEvalWait: 30 * time.Second,
+	})
+
+	assertNamesHaveIndexes(t, intRange(0, 0), stopResultsToNames(r.stop))
+	assertNamesHaveIndexes(t, intRange(0, 0), placeResultsToNames(r.place))
+}
+
+// Tests the reconciler marks the allocations of nodes down for less than the
+// max client disconnect unknown and replaces them
+func TestReconciler_LostNode_MaxClientDisconnect(t *testing.T) {
+	job := mock.Job()
+	job.TaskGroups[0].MaxClientDisconnect = helper.TimeToPtr(time.Hour)
+
+	// Create 10 existing allocations
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = structs.GenerateUUID()
+		alloc.Name = structs.AllocName(job.ID, job.TaskGroups[0].Name, uint(i))
+		allocs = append(allocs, alloc)
+	}
+
+	// Build a map of tainted nodes, the first went down a while ago and the
+	// second just now
+	now := time.Unix(time.Now().Unix(), 0)
+	downSince := []time.Duration{2 * time.Hour, 30 * time.Minute}
+	tainted := make(map[string]*structs.Node, 2)
+	for i := 0; i < 2; i++ {
+		n := mock.Node()
+		n.ID = allocs[i].NodeID
+		n.Status = structs.NodeStatusDown
+		n.StatusUpdatedAt = now.Add(-downSince[i]).Unix()
+		tainted[n.ID] = n
+	}
+
+	reconciler := NewAllocReconciler(testLogger(), allocUpdateFnIgnore, false, job.ID, job, nil, allocs, tainted)
+	reconciler.now = now
+	r := reconciler.Compute()
+
+	// Assert the correct results
+	assertResults(t, r, &resultExpectation{
+		createDeployment:  nil,
+		deploymentUpdates: nil,
+		place:             2,
+		inplace:           0,
+		stop:              1,
+		desiredTGUpdates: map[string]*structs.DesiredUpdates{
+			job.TaskGroups[0].Name: {
+				Place:  2,
+				Stop:   1,
+				Ignore: 9,
+			},
+		},
+		followupEvalWait: 30 * time.Minute,
+	})
+
+	assertNamesHaveIndexes(t, intRange(0, 0), stopResultsToNames(r.stop))
+	assertNamesHaveIndexes(t, intRange(0, 1), placeResultsToNames(r.place))
+
+	if l := len(r.disconnectUpdates); l != 1 || r.disconnectUpdates[0].ID != allocs[1].ID {
+		t.Fatalf("expected alloc %q to be marked unknown; got %d updates", allocs[1].ID, l)
+	}
+	for _, p := range r.place {
+		if p.name == allocs[1].Name && p.replacedAlloc != allocs[1] {
+			t.Fatalf("expected the placement of %q to replace the unknown alloc", p.name)
+		}
+	}
+}
+
+// Tests the reconciler stops the replacement of an allocation whose client
+// reconnected
+func TestReconciler_Reconnect_KeepOriginal(t *testing.T) {
+	job := mock.Job()
+	job.TaskGroups[0].MaxClientDisconnect = helper.TimeToPtr(time.Hour)
+
+	// Create 10 existing allocations
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = structs.GenerateUUID()
+		alloc.Name = structs.AllocName(job.ID, job.TaskGroups[0].Name, uint(i))
+		alloc.ClientStatus = structs.AllocClientStatusRunning
+		allocs = append(allocs, alloc)
+	}
+
+	// Add the replacement of the first allocation
+	replacement := allocs[0].Copy()
+	replacement.ID = structs.GenerateUUID()
+	replacement.NodeID = structs.GenerateUUID()
+	replacement.ReplacedAllocation = allocs[0].ID
+	allocs = append(allocs, replacement)
+
+	reconciler := NewAllocReconciler(testLogger(), allocUpdateFnIgnore, false, job.ID, job, nil, allocs, nil)
+	r := reconciler.Compute()
+
+	// Assert the correct results
+	assertResults(t, r, &resultExpectation{
+		createDeployment:  nil,
+		deploymentUpdates: nil,
+		place:             0,
+		inplace:           0,
+		stop:              1,
+		desiredTGUpdates: map[string]*structs.DesiredUpdates{
+			job.TaskGroups[0].Name: {
+				Stop:   1,
+				Ignore: 10,
+			},
+		},
+	})
+
+	if r.stop[0].alloc.ID != replacement.ID {
+		t.Fatalf("expected the replacement to be stopped; got %q", r.stop[0].alloc.ID)
+	}
+}
+
+// Tests the reconciler waits for a reconnected client to report the status
+// of an unknown allocation
+func TestReconciler_Reconnect_Unknown(t *testing.T) {
+	job := mock.Job()
+	job.TaskGroups[0].MaxClientDisconnect = helper.TimeToPtr(time.Hour)
+
+	// Create 10 existing allocations, the first is unknown
+	var allocs []*structs.Allocation
+	for i := 0; i < 10; i++ {
+		alloc := mock.Alloc()
+		alloc.Job = job
+		alloc.JobID = job.ID
+		alloc.NodeID = structs.GenerateUUID()
+		alloc.Name = structs.AllocName(job.ID, job.TaskGroups[0].Name, uint(i))
+		alloc.ClientStatus = structs.AllocClientStatusRunning
+		allocs = append(allocs, alloc)
+	}
+	allocs[0].ClientStatus = structs.AllocClientStatusUnknown
+
+	// Add the replacement of the first allocation
+	replacement := allocs[0].Copy()
+	replacement.ID = structs.GenerateUUID()
+	replacement.NodeID = structs.GenerateUUID()
+	replacement.ClientStatus = structs.AllocClientStatusRunning
+	replacement.ReplacedAllocation = allocs[0].ID
+	allocs = append(allocs, replacement)
+
+	reconciler := NewAllocReconciler(testLogger(), allocUpdateFnIgnore, false, job.ID, job, nil, allocs, nil)
+	r := reconciler.Compute()
+
+	// Assert the correct results
+	assertResults(t, r, &resultExpectation{
+		createDeployment:  nil,
+		deploymentUpdates: nil,
+		place:             0,
+		inplace:           0,
+		stop:              0,
+		desiredTGUpdates: map[string]*structs.DesiredUpdates{
+			job.TaskGroups[0].Name: {
+				Ignore: 11,
+			},
+		},
+	})
+}
+
 // Tests the reconciler properly handles lost nodes with allocations while
 // scaling up
 func TestReconciler_LostNode_ScaleUp(t *testing.T) {
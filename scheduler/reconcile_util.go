@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/nomad/nomad/structs"
 )
@@ -45,6 +46,10 @@ type allocPlaceResult struct {
 	canary        bool
 	taskGroup     *structs.TaskGroup
 	previousAlloc *structs.Allocation
+
+	// replacedAlloc is the allocation of a disconnected client the placement
+	// replaces
+	replacedAlloc *structs.Allocation
 }
 
 func (a allocPlaceResult) TaskGroup() *structs.TaskGroup           { return a.taskGroup }
@@ -227,6 +232,54 @@ func (a allocSet) filterByTainted(nodes map[string]*structs.Node) (untainted, mi
 	return
 }
 
+// filterByClientDisconnect filters the lost allocations into those that are
+// lost and those whose node went down less than the given window ago. The
+// time until the window of the first of them ends is returned.
+func (a allocSet) filterByClientDisconnect(window *time.Duration, nodes map[string]*structs.Node, now time.Time) (lost, disconnected allocSet, wait time.Duration) {
+	lost = make(map[string]*structs.Allocation)
+	disconnected = make(map[string]*structs.Allocation)
+	for _, alloc := range a {
+		n := nodes[alloc.NodeID]
+		if window == nil || n == nil || n.Status != structs.NodeStatusDown {
+			lost[alloc.ID] = alloc
+			continue
+		}
+
+		end := time.Unix(n.StatusUpdatedAt, 0).Add(*window)
+		if !now.Before(end) {
+			lost[alloc.ID] = alloc
+			continue
+		}
+
+		disconnected[alloc.ID] = alloc
+		if d := end.Sub(now); wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return
+}
+
+// filterByReplaced filters allocations into those that were replaced by an
+// allocation of the other set and those that weren't.
+func (a allocSet) filterByReplaced(others allocSet) (replaced, unreplaced allocSet) {
+	replaced = make(map[string]*structs.Allocation)
+	unreplaced = make(map[string]*structs.Allocation)
+	replacedIDs := make(map[string]struct{})
+	for _, alloc := range others {
+		if alloc.ReplacedAllocation != "" {
+			replacedIDs[alloc.ReplacedAllocation] = struct{}{}
+		}
+	}
+	for id, alloc := range a {
+		if _, ok := replacedIDs[id]; ok {
+			replaced[id] = alloc
+		} else {
+			unreplaced[id] = alloc
+		}
+	}
+	return
+}
+
 // filterByDeployment filters allocations into two sets, those that match the
 // given deployment ID and those that don't
 func (a allocSet) filterByDeployment(id string) (match, nonmatch allocSet) {
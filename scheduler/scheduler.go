@@ -83,6 +83,10 @@ type State interface {
 	// LatestDeploymentByJobID returns the latest deployment matching the given
 	// job ID
 	LatestDeploymentByJobID(ws memdb.WatchSet, jobID string) (*structs.Deployment, error)
+
+	// SchedulerConfig returns the scheduler configuration, or nil if none
+	// was set
+	SchedulerConfig() (uint64, *structs.SchedulerConfiguration, error)
 }
 
 // Planner interface is used to submit a task allocation plan.
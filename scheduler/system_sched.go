@@ -318,7 +318,8 @@ func (s *SystemScheduler) computePlacements(place []allocTuple) error {
 				ClientStatus:  structs.AllocClientStatusPending,
 
 				SharedResources: &structs.Resources{
-					DiskMB: missing.TaskGroup.EphemeralDisk.SizeMB,
+					DiskMB:   missing.TaskGroup.EphemeralDisk.SizeMB,
+					Networks: option.GroupNetworks,
 				},
 			}
 
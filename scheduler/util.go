@@ -348,6 +348,17 @@ func tasksUpdated(jobA, jobB *structs.Job, taskGroup string) bool {
 		return true
 	}
 
+	// Check the Consul namespace and partition since the services and the
+	// Service Identity tokens of the tasks are bound to them
+	if !reflect.DeepEqual(a.Consul, b.Consul) {
+		return true
+	}
+
+	// Check the group network since the tasks run in it
+	if !reflect.DeepEqual(a.Networks, b.Networks) {
+		return true
+	}
+
 	// Check each task
 	for _, at := range a.Tasks {
 		bt := b.LookupTask(at.Name)
@@ -598,6 +609,9 @@ type tgConstrainTuple struct {
 
 	// The combined resources of all tasks within the task group.
 	size *structs.Resources
+
+	// The networks of the task group and its tasks.
+	networks []*structs.NetworkResource
 }
 
 // taskGroupConstraints collects the constraints, drivers and resources required by each
@@ -610,10 +624,14 @@ func taskGroupConstraints(tg *structs.TaskGroup) tgConstrainTuple {
 	}
 
 	c.constraints = append(c.constraints, tg.Constraints...)
+	c.networks = append(c.networks, tg.Networks...)
 	for _, task := range tg.Tasks {
 		c.drivers[task.Driver] = struct{}{}
 		c.constraints = append(c.constraints, task.Constraints...)
 		c.size.Add(task.Resources)
+		if task.Resources != nil {
+			c.networks = append(c.networks, task.Resources.Networks...)
+		}
 	}
 
 	return c
@@ -571,6 +571,20 @@ func TestTasksUpdated(t *testing.T) {
 	if !tasksUpdated(j1, j18, name) {
 		t.Fatal("bad")
 	}
+
+	// Change Consul namespace
+	j19 := mock.Job()
+	j19.TaskGroups[0].Consul = &structs.Consul{Namespace: "web"}
+	if !tasksUpdated(j1, j19, name) {
+		t.Fatal("bad")
+	}
+
+	// Change the group network
+	j20 := mock.Job()
+	j20.TaskGroups[0].Networks = []*structs.NetworkResource{{Mode: "cni/mynet"}}
+	if !tasksUpdated(j1, j20, name) {
+		t.Fatal("bad")
+	}
 }
 
 func TestEvictAndPlace_LimitLessThanAllocs(t *testing.T) {
@@ -944,12 +958,14 @@ func TestTaskGroupConstraints(t *testing.T) {
 	constr := &structs.Constraint{RTarget: "bar"}
 	constr2 := &structs.Constraint{LTarget: "foo"}
 	constr3 := &structs.Constraint{Operand: "<"}
+	network := &structs.NetworkResource{Mode: structs.NetworkModeBridge, HostNetwork: "public"}
 
 	tg := &structs.TaskGroup{
 		Name:          "web",
 		Count:         10,
 		Constraints:   []*structs.Constraint{constr},
 		EphemeralDisk: &structs.EphemeralDisk{},
+		Networks:      []*structs.NetworkResource{network},
 		Tasks: []*structs.Task{
 			&structs.Task{
 				Driver: "exec",
@@ -977,6 +993,7 @@ func TestTaskGroupConstraints(t *testing.T) {
 		CPU:      1000,
 		MemoryMB: 512,
 	}
+	expNetworks := []*structs.NetworkResource{network}
 
 	actConstrains := taskGroupConstraints(tg)
 	if !reflect.DeepEqual(actConstrains.constraints, expConstr) {
@@ -988,6 +1005,9 @@ func TestTaskGroupConstraints(t *testing.T) {
 	if !reflect.DeepEqual(actConstrains.size, expSize) {
 		t.Fatalf("taskGroupConstraints(%v) returned %v; want %v", tg, actConstrains.size, expSize)
 	}
+	if !reflect.DeepEqual(actConstrains.networks, expNetworks) {
+		t.Fatalf("taskGroupConstraints(%v) returned %v; want %v", tg, actConstrains.networks, expNetworks)
+	}
 
 }
 
@@ -23,6 +23,9 @@ if [ -z $NOTAG ]; then
   git tag -a -m "Version $VERSION" -s -u 348FFC4C "v${VERSION}" master
 fi
 
+# Include the OpenAPI specification of the HTTP API
+cp ./api/openapi/openapi.json ./pkg/openapi.json
+
 # Zip all the files
 rm -rf ./pkg/dist
 mkdir -p ./pkg/dist
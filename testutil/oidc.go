@@ -0,0 +1,221 @@
+package testutil
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/mitchellh/go-testing-interface"
+)
+
+// TestOIDCProvider is a fake OIDC provider for testing logging in with OIDC
+// auth methods. Its authorization endpoint logs the user in right away and
+// redirects back with an authorization code, which its token endpoint
+// exchanges for an RS256 signed ID token asserting the claims set. It also
+// signs the JWTs logging in with JWT auth methods.
+type TestOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	ClientID     string
+	ClientSecret string
+
+	claims map[string]interface{}
+	codes  map[string]*oidcCode
+	l      sync.Mutex
+}
+
+// oidcCode is an authorization code issued by the authorization endpoint
+type oidcCode struct {
+	nonce       string
+	redirectURI string
+}
+
+// NewTestOIDCProvider starts a fake OIDC provider
+func NewTestOIDCProvider(t testing.T) *TestOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	p := &TestOIDCProvider{
+		key:          key,
+		ClientID:     "nomad",
+		ClientSecret: structs.GenerateUUID(),
+		claims:       make(map[string]interface{}),
+		codes:        make(map[string]*oidcCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/token", p.handleToken)
+	mux.HandleFunc("/keys", p.handleKeys)
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+// URL returns the issuer URL of the provider, which is its discovery URL
+func (p *TestOIDCProvider) URL() string {
+	return p.server.URL
+}
+
+// SetClaims sets the claims of the user logging in
+func (p *TestOIDCProvider) SetClaims(claims map[string]interface{}) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.claims = claims
+}
+
+// KeysURL returns the URL of the JSON Web Key Set of the provider
+func (p *TestOIDCProvider) KeysURL() string {
+	return p.server.URL + "/keys"
+}
+
+// PublicKeyPEM returns the PEM-encoded public key of the provider
+func (p *TestOIDCProvider) PublicKeyPEM() string {
+	der, err := x509.MarshalPKIXPublicKey(&p.key.PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// SignJWT returns the claims as a JWT signed by the provider
+func (p *TestOIDCProvider) SignJWT(claims map[string]interface{}) (string, error) {
+	return p.sign(claims)
+}
+
+// Stop stops the provider
+func (p *TestOIDCProvider) Stop() {
+	p.server.Close()
+}
+
+func (p *TestOIDCProvider) handleDiscovery(w http.ResponseWriter, req *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 p.URL(),
+		"authorization_endpoint": p.URL() + "/authorize",
+		"token_endpoint":         p.URL() + "/token",
+		"jwks_uri":               p.URL() + "/keys",
+	})
+}
+
+func (p *TestOIDCProvider) handleAuthorize(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "bad authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code := structs.GenerateUUID()
+	p.l.Lock()
+	p.codes[code] = &oidcCode{nonce: q.Get("nonce"), redirectURI: q.Get("redirect_uri")}
+	p.l.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, req, redirect.String(), http.StatusFound)
+}
+
+func (p *TestOIDCProvider) handleToken(w http.ResponseWriter, req *http.Request) {
+	id, secret, _ := req.BasicAuth()
+	if id != p.ClientID || secret != p.ClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.l.Lock()
+	code, ok := p.codes[req.FormValue("code")]
+	delete(p.codes, req.FormValue("code"))
+	claims := make(map[string]interface{}, len(p.claims))
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	p.l.Unlock()
+
+	if !ok || code.redirectURI != req.FormValue("redirect_uri") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims["iss"] = p.URL()
+	claims["aud"] = p.ClientID
+	claims["nonce"] = code.nonce
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(5 * time.Minute).Unix()
+	if _, ok := claims["sub"]; !ok {
+		claims["sub"] = "test-user"
+	}
+
+	idToken, err := p.sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": structs.GenerateUUID(),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (p *TestOIDCProvider) handleKeys(w http.ResponseWriter, req *http.Request) {
+	pub := p.key.PublicKey
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// sign returns the claims as an RS256 signed JWT
+func (p *TestOIDCProvider) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString(header),
+		base64.RawURLEncoding.EncodeToString(payload),
+	}, ".")
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign ID token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
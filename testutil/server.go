@@ -42,6 +42,7 @@ type TestServerConfig struct {
 	Server            *ServerConfig `json:"server,omitempty"`
 	Client            *ClientConfig `json:"client,omitempty"`
 	Vault             *VaultConfig  `json:"vault,omitempty"`
+	ACL               *ACLConfig    `json:"acl,omitempty"`
 	DevMode           bool          `json:"-"`
 	Stdout, Stderr    io.Writer     `json:"-"`
 }
@@ -62,8 +63,9 @@ type PortsConfig struct {
 
 // ServerConfig is used to configure the nomad server.
 type ServerConfig struct {
-	Enabled         bool `json:"enabled"`
-	BootstrapExpect int  `json:"bootstrap_expect"`
+	Enabled                bool   `json:"enabled"`
+	BootstrapExpect        int    `json:"bootstrap_expect"`
+	VariablesEncryptionKey string `json:"variables_encryption_key,omitempty"`
 }
 
 // ClientConfig is used to configure the client
@@ -71,6 +73,11 @@ type ClientConfig struct {
 	Enabled bool `json:"enabled"`
 }
 
+// ACLConfig is used to configure the ACLs
+type ACLConfig struct {
+	Enabled bool `json:"enabled"`
+}
+
 // VaultConfig is used to configure Vault
 type VaultConfig struct {
 	Enabled bool `json:"enabled"`